@@ -61,6 +61,7 @@ type ExampleOptions struct {
 	ImageContentSources              []hyperv1.ImageContentSource
 	InfraID                          string
 	MachineCIDR                      string
+	MachineIPv6CIDR                  string
 	ServiceCIDR                      string
 	ClusterCIDR                      string
 	BaseDomain                       string
@@ -77,6 +78,7 @@ type ExampleOptions struct {
 	Kubevirt                         *ExampleKubevirtOptions
 	Azure                            *ExampleAzureOptions
 	PowerVS                          *ExamplePowerVSOptions
+	IBMCloudVPC                      *ExampleIBMCloudVPCOptions
 	NetworkType                      hyperv1.NetworkType
 	ControlPlaneAvailabilityPolicy   hyperv1.AvailabilityPolicy
 	InfrastructureAvailabilityPolicy hyperv1.AvailabilityPolicy
@@ -296,6 +298,31 @@ web_identity_token_file = /var/run/secrets/openshift/serviceaccount/token
 		platformSpec = hyperv1.PlatformSpec{
 			Type: hyperv1.KubevirtPlatform,
 		}
+		if len(o.Kubevirt.InfraKubeConfig) > 0 {
+			infraKubeConfigSecret := &corev1.Secret{
+				TypeMeta: metav1.TypeMeta{
+					APIVersion: "v1",
+					Kind:       "Secret",
+				},
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      o.Name + "-infra-kubeconfig",
+					Namespace: namespace.Name,
+				},
+				Data: map[string][]byte{
+					"kubeconfig": o.Kubevirt.InfraKubeConfig,
+				},
+			}
+			resources = append(resources, infraKubeConfigSecret)
+			platformSpec.Kubevirt = &hyperv1.KubevirtPlatformSpec{
+				Credentials: &hyperv1.KubevirtPlatformCredentials{
+					InfraKubeConfigSecret: &hyperv1.KubeconfigSecretRef{
+						Name: infraKubeConfigSecret.Name,
+						Key:  "kubeconfig",
+					},
+					InfraNamespace: o.Kubevirt.InfraNamespace,
+				},
+			}
+		}
 		switch o.Kubevirt.ServicePublishingStrategy {
 		case "NodePort":
 			services = getServicePublishingStrategyMappingByAPIServerAddress(o.Kubevirt.APIServerAddress, o.NetworkType)
@@ -340,34 +367,12 @@ web_identity_token_file = /var/run/secrets/openshift/serviceaccount/token
 		services = getIngressServicePublishingStrategyMapping(o.NetworkType, o.ExternalDNSDomain != "")
 
 	case o.PowerVS != nil:
-		buildIBMCloudCreds := func(name, apikey string) *corev1.Secret {
-			data := map[string][]byte{
-				"ibm-credentials.env": []byte(fmt.Sprintf(`IBMCLOUD_AUTH_TYPE=iam
- IBMCLOUD_APIKEY=%s
- IBMCLOUD_AUTH_URL=https://iam.cloud.ibm.com
- `, apikey)),
-				"ibmcloud_api_key": []byte(apikey),
-			}
-
-			return &corev1.Secret{
-				TypeMeta: metav1.TypeMeta{
-					Kind:       "Secret",
-					APIVersion: corev1.SchemeGroupVersion.String(),
-				},
-				ObjectMeta: metav1.ObjectMeta{
-					Namespace: namespace.Name,
-					Name:      name,
-				},
-				Data: data,
-			}
-		}
-
 		// TODO(dharaneeshvrd): Need exploration to use granular permissions
 		powerVSResources := &ExamplePowerVSResources{
-			buildIBMCloudCreds(o.Name+"-cloud-ctrl-creds", o.PowerVS.ApiKey),
-			buildIBMCloudCreds(o.Name+"-node-mgmt-creds", o.PowerVS.ApiKey),
-			buildIBMCloudCreds(o.Name+"-cpo-creds", o.PowerVS.ApiKey),
-			buildIBMCloudCreds(o.Name+"-ingress-creds", o.PowerVS.ApiKey),
+			buildIBMCloudCreds(namespace.Name, o.Name+"-cloud-ctrl-creds", o.PowerVS.ApiKey),
+			buildIBMCloudCreds(namespace.Name, o.Name+"-node-mgmt-creds", o.PowerVS.ApiKey),
+			buildIBMCloudCreds(namespace.Name, o.Name+"-cpo-creds", o.PowerVS.ApiKey),
+			buildIBMCloudCreds(namespace.Name, o.Name+"-ingress-creds", o.PowerVS.ApiKey),
 		}
 		resources = powerVSResources.AsObjects()
 		platformSpec = hyperv1.PlatformSpec{
@@ -395,6 +400,38 @@ web_identity_token_file = /var/run/secrets/openshift/serviceaccount/token
 			},
 		}
 		services = getIngressServicePublishingStrategyMapping(o.NetworkType, o.ExternalDNSDomain != "")
+	case o.IBMCloudVPC != nil:
+		ibmCloudVPCResources := &ExampleIBMCloudVPCResources{
+			buildIBMCloudCreds(namespace.Name, o.Name+"-cloud-ctrl-creds", o.IBMCloudVPC.ApiKey),
+			buildIBMCloudCreds(namespace.Name, o.Name+"-node-mgmt-creds", o.IBMCloudVPC.ApiKey),
+			buildIBMCloudCreds(namespace.Name, o.Name+"-cpo-creds", o.IBMCloudVPC.ApiKey),
+			buildIBMCloudCreds(namespace.Name, o.Name+"-ingress-creds", o.IBMCloudVPC.ApiKey),
+		}
+		resources = ibmCloudVPCResources.AsObjects()
+		platformSpec = hyperv1.PlatformSpec{
+			Type: hyperv1.IBMCloudVPCPlatform,
+			IBMCloudVPC: &hyperv1.IBMCloudVPCPlatformSpec{
+				AccountID:       o.IBMCloudVPC.AccountID,
+				CISInstanceCRN:  o.IBMCloudVPC.CISInstanceCRN,
+				ResourceGroup:   o.IBMCloudVPC.ResourceGroup,
+				ResourceGroupID: o.IBMCloudVPC.ResourceGroupID,
+				Region:          o.IBMCloudVPC.Region,
+				Zone:            o.IBMCloudVPC.Zone,
+				VPC: &hyperv1.IBMCloudVPCNetwork{
+					Name: o.IBMCloudVPC.Vpc,
+					ID:   o.IBMCloudVPC.VpcID,
+					Subnet: hyperv1.IBMCloudVPCSubnet{
+						Name: o.IBMCloudVPC.VpcSubnet,
+						ID:   o.IBMCloudVPC.VpcSubnetID,
+					},
+				},
+				KubeCloudControllerCreds:  corev1.LocalObjectReference{Name: ibmCloudVPCResources.KubeCloudControllerCreds.Name},
+				NodePoolManagementCreds:   corev1.LocalObjectReference{Name: ibmCloudVPCResources.NodePoolManagementCreds.Name},
+				ControlPlaneOperatorCreds: corev1.LocalObjectReference{Name: ibmCloudVPCResources.ControlPlaneOperatorCreds.Name},
+				IngressOperatorCloudCreds: corev1.LocalObjectReference{Name: ibmCloudVPCResources.IngressOperatorCloudCreds.Name},
+			},
+		}
+		services = getIngressServicePublishingStrategyMapping(o.NetworkType, o.ExternalDNSDomain != "")
 	default:
 		panic("no platform specified")
 	}
@@ -473,6 +510,9 @@ web_identity_token_file = /var/run/secrets/openshift/serviceaccount/token
 	if o.MachineCIDR != "" {
 		cluster.Spec.Networking.MachineNetwork = []hyperv1.MachineNetworkEntry{{CIDR: *ipnet.MustParseCIDR(o.MachineCIDR)}}
 	}
+	if o.MachineIPv6CIDR != "" {
+		cluster.Spec.Networking.MachineNetwork = append(cluster.Spec.Networking.MachineNetwork, hyperv1.MachineNetworkEntry{CIDR: *ipnet.MustParseCIDR(o.MachineIPv6CIDR)})
+	}
 
 	if len(globalOpts) > 0 {
 		cluster.Spec.Configuration = &hyperv1.ClusterConfiguration{Items: globalOpts}
@@ -561,6 +601,12 @@ web_identity_token_file = /var/run/secrets/openshift/serviceaccount/token
 					IOPS: o.AWS.RootVolumeIOPS,
 				},
 			}
+			if o.AWS.RootVolumeThroughput > 0 {
+				nodePool.Spec.Platform.AWS.RootVolume.Throughput = &o.AWS.RootVolumeThroughput
+			}
+			if o.AWS.RootVolumeKMSKey != "" {
+				nodePool.Spec.Platform.AWS.RootVolume.EncryptionKey = o.AWS.RootVolumeKMSKey
+			}
 			nodePools = append(nodePools, nodePool)
 		}
 	case hyperv1.KubevirtPlatform:
@@ -574,10 +620,11 @@ web_identity_token_file = /var/run/secrets/openshift/serviceaccount/token
 			for _, availabilityZone := range o.Azure.AvailabilityZones {
 				nodePool := defaultNodePool(fmt.Sprintf("%s-%s", cluster.Name, availabilityZone))
 				nodePool.Spec.Platform.Azure = &hyperv1.AzureNodePoolPlatform{
-					VMSize:           o.Azure.InstanceType,
-					ImageID:          o.Azure.BootImageID,
-					DiskSizeGB:       o.Azure.DiskSizeGB,
-					AvailabilityZone: availabilityZone,
+					VMSize:              o.Azure.InstanceType,
+					ImageID:             o.Azure.BootImageID,
+					DiskSizeGB:          o.Azure.DiskSizeGB,
+					DiskEncryptionSetID: o.Azure.DiskEncryptionSetID,
+					AvailabilityZone:    availabilityZone,
 				}
 				nodePools = append(nodePools, nodePool)
 			}
@@ -585,9 +632,10 @@ web_identity_token_file = /var/run/secrets/openshift/serviceaccount/token
 		} else {
 			nodePool := defaultNodePool(cluster.Name)
 			nodePool.Spec.Platform.Azure = &hyperv1.AzureNodePoolPlatform{
-				VMSize:     o.Azure.InstanceType,
-				ImageID:    o.Azure.BootImageID,
-				DiskSizeGB: o.Azure.DiskSizeGB,
+				VMSize:              o.Azure.InstanceType,
+				ImageID:             o.Azure.BootImageID,
+				DiskSizeGB:          o.Azure.DiskSizeGB,
+				DiskEncryptionSetID: o.Azure.DiskEncryptionSetID,
 			}
 			nodePools = append(nodePools, nodePool)
 		}
@@ -599,6 +647,16 @@ web_identity_token_file = /var/run/secrets/openshift/serviceaccount/token
 			Processors:    intstr.FromString(o.PowerVS.Processors),
 			MemoryGiB:     o.PowerVS.Memory,
 		}
+		if o.PowerVS.SMTLevel > 0 {
+			nodePool.Spec.Platform.PowerVS.SMTLevel = &o.PowerVS.SMTLevel
+		}
+		nodePools = append(nodePools, nodePool)
+	case hyperv1.IBMCloudVPCPlatform:
+		nodePool := defaultNodePool(cluster.Name)
+		nodePool.Spec.Platform.IBMCloudVPC = &hyperv1.IBMCloudVPCNodePoolPlatform{
+			Profile: o.IBMCloudVPC.Profile,
+			Image:   o.IBMCloudVPC.Image,
+		}
 		nodePools = append(nodePools, nodePool)
 	default:
 		panic("Unsupported platform")
@@ -615,6 +673,30 @@ web_identity_token_file = /var/run/secrets/openshift/serviceaccount/token
 	}
 }
 
+// buildIBMCloudCreds builds a secret holding the IBM Cloud API key in the formats
+// the IBM Cloud components of the cluster consume it in.
+func buildIBMCloudCreds(namespace, name, apikey string) *corev1.Secret {
+	data := map[string][]byte{
+		"ibm-credentials.env": []byte(fmt.Sprintf(`IBMCLOUD_AUTH_TYPE=iam
+ IBMCLOUD_APIKEY=%s
+ IBMCLOUD_AUTH_URL=https://iam.cloud.ibm.com
+ `, apikey)),
+		"ibmcloud_api_key": []byte(apikey),
+	}
+
+	return &corev1.Secret{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "Secret",
+			APIVersion: corev1.SchemeGroupVersion.String(),
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: namespace,
+			Name:      name,
+		},
+		Data: data,
+	}
+}
+
 func getIngressServicePublishingStrategyMapping(netType hyperv1.NetworkType, usesExternalDNS bool) []hyperv1.ServicePublishingStrategyMapping {
 
 	apiServiceStrategy := hyperv1.LoadBalancer
@@ -7,21 +7,23 @@ import (
 )
 
 type ExampleAWSOptions struct {
-	Region             string
-	Zones              []ExampleAWSOptionsZones
-	VPCID              string
-	SecurityGroupID    string
-	InstanceProfile    string
-	InstanceType       string
-	Roles              hyperv1.AWSRolesRef
-	KMSProviderRoleARN string
-	KMSKeyARN          string
-	RootVolumeSize     int64
-	RootVolumeType     string
-	RootVolumeIOPS     int64
-	ResourceTags       []hyperv1.AWSResourceTag
-	EndpointAccess     string
-	ProxyAddress       string
+	Region               string
+	Zones                []ExampleAWSOptionsZones
+	VPCID                string
+	SecurityGroupID      string
+	InstanceProfile      string
+	InstanceType         string
+	Roles                hyperv1.AWSRolesRef
+	KMSProviderRoleARN   string
+	KMSKeyARN            string
+	RootVolumeSize       int64
+	RootVolumeType       string
+	RootVolumeIOPS       int64
+	RootVolumeThroughput int64
+	RootVolumeKMSKey     string
+	ResourceTags         []hyperv1.AWSResourceTag
+	EndpointAccess       string
+	ProxyAddress         string
 }
 
 type ExampleAWSOptionsZones struct {
@@ -1,18 +1,19 @@
 package fixtures
 
 type ExampleAzureOptions struct {
-	Creds             AzureCreds
-	Location          string
-	ResourceGroupName string
-	VnetName          string
-	VnetID            string
-	SubnetName        string
-	BootImageID       string
-	MachineIdentityID string
-	InstanceType      string
-	SecurityGroupName string
-	DiskSizeGB        int32
-	AvailabilityZones []string
+	Creds               AzureCreds
+	Location            string
+	ResourceGroupName   string
+	VnetName            string
+	VnetID              string
+	SubnetName          string
+	BootImageID         string
+	MachineIdentityID   string
+	InstanceType        string
+	SecurityGroupName   string
+	DiskSizeGB          int32
+	AvailabilityZones   []string
+	DiskEncryptionSetID string
 }
 
 // AzureCreds is the fileformat we expect for credentials. It is copied from the installer
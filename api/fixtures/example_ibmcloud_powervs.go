@@ -25,6 +25,7 @@ type ExamplePowerVSOptions struct {
 	ProcType   string
 	Processors string
 	Memory     int32
+	SMTLevel   int32
 }
 
 type ExamplePowerVSResources struct {
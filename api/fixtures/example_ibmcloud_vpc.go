@@ -0,0 +1,48 @@
+package fixtures
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	crclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+type ExampleIBMCloudVPCOptions struct {
+	ApiKey          string
+	AccountID       string
+	ResourceGroup   string
+	ResourceGroupID string
+	Region          string
+	Zone            string
+	CISInstanceCRN  string
+	Vpc             string
+	VpcID           string
+	VpcSubnet       string
+	VpcSubnetID     string
+
+	// nodepool related options
+	Profile string
+	Image   string
+}
+
+type ExampleIBMCloudVPCResources struct {
+	KubeCloudControllerCreds  *corev1.Secret
+	NodePoolManagementCreds   *corev1.Secret
+	ControlPlaneOperatorCreds *corev1.Secret
+	IngressOperatorCloudCreds *corev1.Secret
+}
+
+func (o *ExampleIBMCloudVPCResources) AsObjects() []crclient.Object {
+	var objects []crclient.Object
+	if o.KubeCloudControllerCreds != nil {
+		objects = append(objects, o.KubeCloudControllerCreds)
+	}
+	if o.NodePoolManagementCreds != nil {
+		objects = append(objects, o.NodePoolManagementCreds)
+	}
+	if o.ControlPlaneOperatorCreds != nil {
+		objects = append(objects, o.ControlPlaneOperatorCreds)
+	}
+	if o.IngressOperatorCloudCreds != nil {
+		objects = append(objects, o.IngressOperatorCloudCreds)
+	}
+	return objects
+}
@@ -17,6 +17,17 @@ type ExampleKubevirtOptions struct {
 	RootVolumeSize            uint32
 	RootVolumeStorageClass    string
 	RootVolumeAccessModes     string
+	// AdditionalNetworks are the names of the NetworkAttachmentDefinitions
+	// the VMs are attached to next to the pod network.
+	AdditionalNetworks []string
+	// GPUs and HostDevices are the resource names of the devices passed
+	// through to the VMs, repeated for each device a VM gets.
+	GPUs        []string
+	HostDevices []string
+	// InfraKubeConfig is the kubeconfig of the cluster the VMs are placed on
+	// if it is not the management cluster.
+	InfraKubeConfig []byte
+	InfraNamespace  string
 }
 
 func ExampleKubeVirtTemplate(o *ExampleKubevirtOptions) *hyperv1.KubevirtNodePoolPlatform {
@@ -60,6 +71,16 @@ func ExampleKubeVirtTemplate(o *ExampleKubevirtOptions) *hyperv1.KubevirtNodePoo
 		exampleTemplate.Compute.Cores = &o.Cores
 	}
 
+	for _, network := range o.AdditionalNetworks {
+		exampleTemplate.AdditionalNetworks = append(exampleTemplate.AdditionalNetworks, hyperv1.KubevirtNetwork{
+			Name:    network,
+			Binding: hyperv1.KubevirtNetworkBindingBridge,
+		})
+	}
+
+	exampleTemplate.HostDevices = append(exampleKubevirtHostDevices(o.GPUs, hyperv1.KubevirtHostDeviceTypeGPU),
+		exampleKubevirtHostDevices(o.HostDevices, hyperv1.KubevirtHostDeviceTypeHostDevice)...)
+
 	if o.Image != "" {
 		exampleTemplate.RootVolume.Image = &hyperv1.KubevirtDiskImage{
 			ContainerDiskImage: &o.Image,
@@ -68,3 +89,23 @@ func ExampleKubeVirtTemplate(o *ExampleKubevirtOptions) *hyperv1.KubevirtNodePoo
 
 	return exampleTemplate
 }
+
+// exampleKubevirtHostDevices counts the repeated device names into host devices
+// of the type, keeping the order the names are first given in.
+func exampleKubevirtHostDevices(deviceNames []string, deviceType hyperv1.KubevirtHostDeviceType) []hyperv1.KubevirtHostDevice {
+	var devices []hyperv1.KubevirtHostDevice
+	index := map[string]int{}
+	for _, name := range deviceNames {
+		if i, ok := index[name]; ok {
+			devices[i].Count++
+			continue
+		}
+		index[name] = len(devices)
+		devices = append(devices, hyperv1.KubevirtHostDevice{
+			DeviceName: name,
+			Type:       deviceType,
+			Count:      1,
+		})
+	}
+	return devices
+}
@@ -15,6 +15,7 @@ import (
 	"k8s.io/apimachinery/pkg/runtime/serializer/json"
 	kasv1beta1 "k8s.io/apiserver/pkg/apis/apiserver/v1beta1"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	kubevirtv1 "kubevirt.io/api/core/v1"
 	capiaws "sigs.k8s.io/cluster-api-provider-aws/api/v1beta1"
 	capiazure "sigs.k8s.io/cluster-api-provider-azure/api/v1beta1"
 	capiibm "sigs.k8s.io/cluster-api-provider-ibmcloud/api/v1beta1"
@@ -58,4 +59,5 @@ func init() {
 	agentv1.AddToScheme(Scheme)
 	capikubevirt.AddToScheme(Scheme)
 	capiazure.AddToScheme(Scheme)
+	kubevirtv1.AddToScheme(Scheme)
 }
@@ -52,6 +52,9 @@ const (
 	// PortierisImageAnnotation is an annotation that allows the specification of the portieries component
 	// (performs container image verification).
 	PortierisImageAnnotation = "hypershift.openshift.io/portieris-image"
+	// OCICloudControllerManagerImage is an annotation that allows the specification of the OCI cloud controller
+	// manager image, which is not part of the OpenShift release payload.
+	OCICloudControllerManagerImage = "hypershift.openshift.io/oci-cloud-controller-manager-image"
 
 	// ClusterAPIProviderAWSImage overrides the CAPI AWS provider image to use for
 	// a HostedControlPlane.
@@ -573,7 +576,7 @@ const (
 
 // PlatformType is a specific supported infrastructure provider.
 //
-// +kubebuilder:validation:Enum=AWS;None;IBMCloud;Agent;KubeVirt;Azure;PowerVS
+// +kubebuilder:validation:Enum=AWS;None;IBMCloud;Agent;KubeVirt;Azure;PowerVS;IBMCloudVPC;GCP;OpenStack;Nutanix;OCI
 type PlatformType string
 
 const (
@@ -597,6 +600,22 @@ const (
 
 	// PowerVSPlatform represents PowerVS infrastructure.
 	PowerVSPlatform PlatformType = "PowerVS"
+
+	// IBMCloudVPCPlatform represents IBM Cloud VPC infrastructure with x86 nodes
+	// managed by HyperShift.
+	IBMCloudVPCPlatform PlatformType = "IBMCloudVPC"
+
+	// GCPPlatform represents Google Cloud infrastructure.
+	GCPPlatform PlatformType = "GCP"
+
+	// OpenStackPlatform represents OpenStack infrastructure.
+	OpenStackPlatform PlatformType = "OpenStack"
+
+	// NutanixPlatform represents Nutanix AHV infrastructure.
+	NutanixPlatform PlatformType = "Nutanix"
+
+	// OCIPlatform represents Oracle Cloud Infrastructure.
+	OCIPlatform PlatformType = "OCI"
 )
 
 // PlatformSpec specifies the underlying infrastructure provider for the cluster
@@ -626,12 +645,84 @@ type PlatformSpec struct {
 	// Azure defines azure specific settings
 	Azure *AzurePlatformSpec `json:"azure,omitempty"`
 
+	// Kubevirt defines KubeVirt specific settings for the cluster.
+	//
+	// +optional
+	// +immutable
+	Kubevirt *KubevirtPlatformSpec `json:"kubevirt,omitempty"`
+
 	// PowerVS specifies configuration for clusters running on IBMCloud Power VS Service.
 	// This field is immutable. Once set, It can't be changed.
 	//
 	// +optional
 	// +immutable
 	PowerVS *PowerVSPlatformSpec `json:"powervs,omitempty"`
+
+	// IBMCloudVPC specifies configuration for clusters running on IBM Cloud VPC
+	// infrastructure.
+	// This field is immutable. Once set, It can't be changed.
+	//
+	// +optional
+	// +immutable
+	IBMCloudVPC *IBMCloudVPCPlatformSpec `json:"ibmcloudvpc,omitempty"`
+
+	// GCP specifies configuration for clusters running on Google Cloud.
+	// This field is immutable. Once set, It can't be changed.
+	//
+	// +optional
+	// +immutable
+	GCP *GCPPlatformSpec `json:"gcp,omitempty"`
+
+	// OpenStack specifies configuration for clusters running on OpenStack.
+	// This field is immutable. Once set, It can't be changed.
+	//
+	// +optional
+	// +immutable
+	OpenStack *OpenStackPlatformSpec `json:"openstack,omitempty"`
+
+	// Nutanix specifies configuration for clusters running on Nutanix AHV.
+	// This field is immutable. Once set, It can't be changed.
+	//
+	// +optional
+	// +immutable
+	Nutanix *NutanixPlatformSpec `json:"nutanix,omitempty"`
+
+	// OCI specifies configuration for clusters running on Oracle Cloud
+	// Infrastructure.
+	// This field is immutable. Once set, It can't be changed.
+	//
+	// +optional
+	// +immutable
+	OCI *OCIPlatformSpec `json:"oci,omitempty"`
+}
+
+// KubevirtPlatformSpec specifies configuration for KubeVirt guest clusters.
+type KubevirtPlatformSpec struct {
+	// Credentials are the credentials of the infrastructure cluster the worker
+	// VMs are placed on. They are only needed when the VMs are placed on a
+	// cluster other than the management cluster.
+	//
+	// +optional
+	// +immutable
+	Credentials *KubevirtPlatformCredentials `json:"credentials,omitempty"`
+}
+
+// KubevirtPlatformCredentials references the kubeconfig of an external
+// infrastructure cluster for the worker VMs.
+type KubevirtPlatformCredentials struct {
+	// InfraKubeConfigSecret is a reference to a secret in the namespace of the
+	// HostedCluster that contains the kubeconfig of the infrastructure cluster.
+	//
+	// +immutable
+	InfraKubeConfigSecret *KubeconfigSecretRef `json:"infraKubeConfigSecret"`
+
+	// InfraNamespace is the namespace of the infrastructure cluster the worker
+	// VMs are created in. The kubeconfig must allow managing VMs, their volumes
+	// and services in it.
+	//
+	// +kubebuilder:validation:MinLength=1
+	// +immutable
+	InfraNamespace string `json:"infraNamespace"`
 }
 
 // AgentPlatformSpec specifies configuration for agent-based installations.
@@ -774,6 +865,355 @@ type PowerVSVPC struct {
 	Subnet string `json:"subnet,omitempty"`
 }
 
+// IBMCloudVPCPlatformSpec defines IBM Cloud VPC specific settings for components
+type IBMCloudVPCPlatformSpec struct {
+	// AccountID is the IBMCloud account id.
+	// This field is immutable. Once set, It can't be changed.
+	//
+	// +immutable
+	AccountID string `json:"accountID"`
+
+	// CISInstanceCRN is the IBMCloud CIS Service Instance's Cloud Resource Name
+	// This field is immutable. Once set, It can't be changed.
+	//
+	// +kubebuilder:validation:Pattern=`^crn:`
+	// +immutable
+	CISInstanceCRN string `json:"cisInstanceCRN"`
+
+	// ResourceGroup is the name of the IBMCloud Resource Group in which the
+	// cluster resides.
+	// This field is immutable. Once set, It can't be changed.
+	//
+	// +immutable
+	ResourceGroup string `json:"resourceGroup"`
+
+	// ResourceGroupID is the ID of the IBMCloud Resource Group in which the
+	// cluster resides.
+	// This field is immutable. Once set, It can't be changed.
+	//
+	// +immutable
+	ResourceGroupID string `json:"resourceGroupID"`
+
+	// Region is the IBMCloud region in which the cluster resides. This configures the
+	// OCP control plane cloud integrations.
+	// This field is immutable. Once set, It can't be changed.
+	//
+	// +immutable
+	Region string `json:"region"`
+
+	// Zone is the availability zone where the nodes and load balancers are
+	// created.
+	// This field is immutable. Once set, It can't be changed.
+	//
+	// +immutable
+	Zone string `json:"zone"`
+
+	// VPC is the VPC the nodes and load balancers of the cluster are created
+	// in.
+	// This field is immutable. Once set, It can't be changed.
+	//
+	// +immutable
+	VPC *IBMCloudVPCNetwork `json:"vpc"`
+
+	// KubeCloudControllerCreds is a reference to a secret containing cloud
+	// credentials with permissions matching the cloud controller policy.
+	// This field is immutable. Once set, It can't be changed.
+	//
+	// +immutable
+	KubeCloudControllerCreds corev1.LocalObjectReference `json:"kubeCloudControllerCreds"`
+
+	// NodePoolManagementCreds is a reference to a secret containing cloud
+	// credentials with permissions matching the node pool management policy.
+	// This field is immutable. Once set, It can't be changed.
+	//
+	// +immutable
+	NodePoolManagementCreds corev1.LocalObjectReference `json:"nodePoolManagementCreds"`
+
+	// ControlPlaneOperatorCreds is a reference to a secret containing cloud
+	// credentials with permissions matching the control-plane-operator policy.
+	// This field is immutable. Once set, It can't be changed.
+	//
+	// +immutable
+	ControlPlaneOperatorCreds corev1.LocalObjectReference `json:"controlPlaneOperatorCreds"`
+
+	// IngressOperatorCloudCreds is a reference to a secret containing ibm cloud
+	// credentials for ingress operator to get authenticated with ibm cloud.
+	//
+	// +immutable
+	IngressOperatorCloudCreds corev1.LocalObjectReference `json:"ingressOperatorCloudCreds"`
+}
+
+// IBMCloudVPCNetwork specifies the IBM Cloud VPC of a cluster.
+type IBMCloudVPCNetwork struct {
+	// Name of the VPC.
+	// This field is immutable. Once set, It can't be changed.
+	//
+	// +immutable
+	Name string `json:"name"`
+
+	// ID of the VPC.
+	// This field is immutable. Once set, It can't be changed.
+	//
+	// +immutable
+	ID string `json:"id"`
+
+	// Subnet is the subnet of the VPC the nodes and load balancers are created
+	// in. It must be in the availability zone of the cluster.
+	// This field is immutable. Once set, It can't be changed.
+	//
+	// +immutable
+	Subnet IBMCloudVPCSubnet `json:"subnet"`
+}
+
+// IBMCloudVPCSubnet is a subnet of an IBM Cloud VPC.
+type IBMCloudVPCSubnet struct {
+	// Name of the subnet.
+	Name string `json:"name"`
+
+	// ID of the subnet.
+	ID string `json:"id"`
+}
+
+// GCPPlatformSpec defines Google Cloud specific settings for components
+type GCPPlatformSpec struct {
+	// Project is the ID of the Google Cloud project in which the cluster
+	// resides.
+	// This field is immutable. Once set, It can't be changed.
+	//
+	// +kubebuilder:validation:MinLength=1
+	// +immutable
+	Project string `json:"project"`
+
+	// Region is the Google Cloud region in which the cluster resides. This
+	// configures the OCP control plane cloud integrations.
+	// This field is immutable. Once set, It can't be changed.
+	//
+	// +kubebuilder:validation:MinLength=1
+	// +immutable
+	Region string `json:"region"`
+
+	// Network is the VPC network the nodes and load balancers of the cluster
+	// are created in.
+	// This field is immutable. Once set, It can't be changed.
+	//
+	// +immutable
+	Network GCPNetwork `json:"network"`
+
+	// KubeCloudControllerCreds is a reference to a secret containing the
+	// credentials of the Google service account of the cloud controller in the
+	// key service_account.json, either a service account key or, with workload
+	// identity, a credential configuration file.
+	// This field is immutable. Once set, It can't be changed.
+	//
+	// +immutable
+	KubeCloudControllerCreds corev1.LocalObjectReference `json:"kubeCloudControllerCreds"`
+
+	// NodePoolManagementCreds is a reference to a secret containing the
+	// credentials of the Google service account that manages the instances of
+	// the NodePools in the key service_account.json.
+	// This field is immutable. Once set, It can't be changed.
+	//
+	// +immutable
+	NodePoolManagementCreds corev1.LocalObjectReference `json:"nodePoolManagementCreds"`
+
+	// ControlPlaneOperatorCreds is a reference to a secret containing the
+	// credentials of the Google service account of the control-plane-operator
+	// in the key service_account.json.
+	// This field is immutable. Once set, It can't be changed.
+	//
+	// +immutable
+	ControlPlaneOperatorCreds corev1.LocalObjectReference `json:"controlPlaneOperatorCreds"`
+}
+
+// GCPNetwork specifies the VPC network of a cluster on Google Cloud.
+type GCPNetwork struct {
+	// Name of the VPC network.
+	// This field is immutable. Once set, It can't be changed.
+	//
+	// +kubebuilder:validation:MinLength=1
+	// +immutable
+	Name string `json:"name"`
+
+	// Subnet is the name of the subnet of the network the nodes and load
+	// balancers are created in. It must be in the region of the cluster.
+	// This field is immutable. Once set, It can't be changed.
+	//
+	// +kubebuilder:validation:MinLength=1
+	// +immutable
+	Subnet string `json:"subnet"`
+}
+
+// OpenStackPlatformSpec defines OpenStack specific settings for components
+type OpenStackPlatformSpec struct {
+	// CloudName is the name of the cloud in the clouds.yaml of the
+	// credentials secrets.
+	// This field is immutable. Once set, It can't be changed.
+	//
+	// +optional
+	// +kubebuilder:default=openstack
+	// +immutable
+	CloudName string `json:"cloudName,omitempty"`
+
+	// ExternalNetworkID is the ID of the external network the router of the
+	// cluster network is connected to, and the floating IPs of load balancers
+	// are allocated from.
+	// This field is immutable. Once set, It can't be changed.
+	//
+	// +kubebuilder:validation:MinLength=1
+	// +immutable
+	ExternalNetworkID string `json:"externalNetworkID"`
+
+	// NetworkID is the ID of the network the nodes are attached to.
+	// This field is immutable. Once set, It can't be changed.
+	//
+	// +kubebuilder:validation:MinLength=1
+	// +immutable
+	NetworkID string `json:"networkID"`
+
+	// SubnetID is the ID of the subnet of the network the nodes and the load
+	// balancers of services are created in.
+	// This field is immutable. Once set, It can't be changed.
+	//
+	// +kubebuilder:validation:MinLength=1
+	// +immutable
+	SubnetID string `json:"subnetID"`
+
+	// SecurityGroupID is the ID of the security group of the nodes.
+	// This field is immutable. Once set, It can't be changed.
+	//
+	// +optional
+	// +immutable
+	SecurityGroupID string `json:"securityGroupID,omitempty"`
+
+	// KubeCloudControllerCreds is a reference to a secret containing the
+	// clouds.yaml used by the cloud controller manager and the Cinder CSI
+	// driver in the key clouds.yaml.
+	// This field is immutable. Once set, It can't be changed.
+	//
+	// +immutable
+	KubeCloudControllerCreds corev1.LocalObjectReference `json:"kubeCloudControllerCreds"`
+
+	// NodePoolManagementCreds is a reference to a secret containing the
+	// clouds.yaml used to manage the instances of the NodePools in the key
+	// clouds.yaml.
+	// This field is immutable. Once set, It can't be changed.
+	//
+	// +immutable
+	NodePoolManagementCreds corev1.LocalObjectReference `json:"nodePoolManagementCreds"`
+}
+
+// NutanixPlatformSpec defines Nutanix specific settings for components
+type NutanixPlatformSpec struct {
+	// PrismCentral is the endpoint of the Prism Central that manages the
+	// Prism Elements the nodes run on.
+	// This field is immutable. Once set, It can't be changed.
+	//
+	// +immutable
+	PrismCentral NutanixPrismEndpoint `json:"prismCentral"`
+
+	// KubeCloudControllerCreds is a reference to a secret containing the
+	// Prism Central credentials of the cloud controller manager in the key
+	// credentials, in the JSON format of the Nutanix cloud provider.
+	// This field is immutable. Once set, It can't be changed.
+	//
+	// +immutable
+	KubeCloudControllerCreds corev1.LocalObjectReference `json:"kubeCloudControllerCreds"`
+
+	// NodePoolManagementCreds is a reference to a secret containing the
+	// Prism Central credentials used to manage the virtual machines of the
+	// NodePools in the key credentials.
+	// This field is immutable. Once set, It can't be changed.
+	//
+	// +immutable
+	NodePoolManagementCreds corev1.LocalObjectReference `json:"nodePoolManagementCreds"`
+}
+
+// NutanixPrismEndpoint is the endpoint of a Prism Central.
+type NutanixPrismEndpoint struct {
+	// Address is the DNS name or IP address of the Prism Central.
+	// This field is immutable. Once set, It can't be changed.
+	//
+	// +kubebuilder:validation:MinLength=1
+	// +immutable
+	Address string `json:"address"`
+
+	// Port is the port of the Prism Central API.
+	// This field is immutable. Once set, It can't be changed.
+	//
+	// +optional
+	// +kubebuilder:default=9440
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	// +immutable
+	Port int32 `json:"port,omitempty"`
+}
+
+// OCIPlatformSpec defines OCI specific settings for components
+type OCIPlatformSpec struct {
+	// Region is the OCI region of the cluster, e.g. us-ashburn-1.
+	// This field is immutable. Once set, It can't be changed.
+	//
+	// +kubebuilder:validation:MinLength=1
+	// +immutable
+	Region string `json:"region"`
+
+	// CompartmentID is the OCID of the compartment the resources of the
+	// cluster are created in.
+	// This field is immutable. Once set, It can't be changed.
+	//
+	// +kubebuilder:validation:MinLength=1
+	// +immutable
+	CompartmentID string `json:"compartmentID"`
+
+	// VCNID is the OCID of the VCN of the cluster.
+	// This field is immutable. Once set, It can't be changed.
+	//
+	// +kubebuilder:validation:MinLength=1
+	// +immutable
+	VCNID string `json:"vcnID"`
+
+	// SubnetID is the OCID of the subnet of the VCN the nodes are created in.
+	// This field is immutable. Once set, It can't be changed.
+	//
+	// +kubebuilder:validation:MinLength=1
+	// +immutable
+	SubnetID string `json:"subnetID"`
+
+	// LoadBalancerSubnetID is the OCID of the subnet of the VCN the load
+	// balancers of services are created in. When omitted, they are created in
+	// the subnet of the nodes.
+	// This field is immutable. Once set, It can't be changed.
+	//
+	// +optional
+	// +immutable
+	LoadBalancerSubnetID string `json:"loadBalancerSubnetID,omitempty"`
+
+	// NetworkSecurityGroupID is the OCID of the network security group of the
+	// nodes.
+	// This field is immutable. Once set, It can't be changed.
+	//
+	// +optional
+	// +immutable
+	NetworkSecurityGroupID string `json:"networkSecurityGroupID,omitempty"`
+
+	// KubeCloudControllerCreds is a reference to a secret containing the API
+	// signing key of the cloud controller manager and the block volume CSI
+	// driver, in the keys tenancy, user, fingerprint, privateKey and the
+	// optional passphrase.
+	// This field is immutable. Once set, It can't be changed.
+	//
+	// +immutable
+	KubeCloudControllerCreds corev1.LocalObjectReference `json:"kubeCloudControllerCreds"`
+
+	// NodePoolManagementCreds is a reference to a secret containing the API
+	// signing key used to manage the instance pools of the NodePools, in the
+	// same keys as KubeCloudControllerCreds.
+	// This field is immutable. Once set, It can't be changed.
+	//
+	// +immutable
+	NodePoolManagementCreds corev1.LocalObjectReference `json:"nodePoolManagementCreds"`
+}
+
 // PowerVSResourceReference is a reference to a specific IBMCloud PowerVS resource by ID, or Name.
 // Only one of ID, or Name may be specified. Specifying more than one will result in
 // a validation error.
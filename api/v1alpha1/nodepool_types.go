@@ -8,22 +8,38 @@ import (
 )
 
 const (
-	NodePoolValidHostedClusterConditionType      = "ValidHostedCluster"
-	NodePoolValidReleaseImageConditionType       = "ValidReleaseImage"
-	NodePoolValidAMIConditionType                = "ValidAMI"
-	NodePoolValidPowerVSImageConditionType       = "ValidPowerVSImage"
-	NodePoolValidKubeVirtImageConditionType      = "ValidKubeVirtImage"
-	NodePoolValidMachineConfigConditionType      = "ValidMachineConfig"
-	NodePoolValidKubevirtConfigConditionType     = "ValidKubevirtConfig"
-	NodePoolUpdateManagementEnabledConditionType = "UpdateManagementEnabled"
-	NodePoolAutoscalingEnabledConditionType      = "AutoscalingEnabled"
-	NodePoolReadyConditionType                   = "Ready"
-	NodePoolAutorepairEnabledConditionType       = "AutorepairEnabled"
-	NodePoolUpdatingVersionConditionType         = "UpdatingVersion"
-	NodePoolUpdatingConfigConditionType          = "UpdatingConfig"
-	NodePoolAsExpectedConditionReason            = "AsExpected"
-	NodePoolValidationFailedConditionReason      = "ValidationFailed"
-	NodePoolInplaceUpgradeFailedConditionReason  = "InplaceUpgradeFailed"
+	NodePoolValidHostedClusterConditionType            = "ValidHostedCluster"
+	NodePoolValidReleaseImageConditionType             = "ValidReleaseImage"
+	NodePoolValidAMIConditionType                      = "ValidAMI"
+	NodePoolValidPowerVSImageConditionType             = "ValidPowerVSImage"
+	NodePoolValidKubeVirtImageConditionType            = "ValidKubeVirtImage"
+	NodePoolValidMachineConfigConditionType            = "ValidMachineConfig"
+	NodePoolValidKubevirtConfigConditionType           = "ValidKubevirtConfig"
+	NodePoolValidPowerVSConfigConditionType            = "ValidPowerVSConfig"
+	NodePoolValidAzureConfigConditionType              = "ValidAzureConfig"
+	NodePoolValidAWSConfigConditionType                = "ValidAWSConfig"
+	NodePoolValidNodeLabelsAndTaintsConditionType      = "ValidNodeLabelsAndTaints"
+	NodePoolKubevirtLiveMigratableConditionType        = "KubevirtLiveMigratable"
+	NodePoolAgentBareMetalHostsAvailableConditionType  = "AgentBareMetalHostsAvailable"
+	NodePoolUpdateManagementEnabledConditionType       = "UpdateManagementEnabled"
+	NodePoolAutoscalingEnabledConditionType            = "AutoscalingEnabled"
+	NodePoolReadyConditionType                         = "Ready"
+	NodePoolAutorepairEnabledConditionType             = "AutorepairEnabled"
+	NodePoolUpdatingVersionConditionType               = "UpdatingVersion"
+	NodePoolUpdatingConfigConditionType                = "UpdatingConfig"
+	NodePoolNodesUpToDateConditionType                 = "NodesUpToDate"
+	NodePoolFallbackInstanceTypeConditionType          = "FallbackInstanceType"
+	NodePoolHibernatingConditionType                   = "Hibernating"
+	NodePoolAsExpectedConditionReason                  = "AsExpected"
+	NodePoolValidationFailedConditionReason            = "ValidationFailed"
+	NodePoolInplaceUpgradeFailedConditionReason        = "InplaceUpgradeFailed"
+	NodePoolNotLiveMigratableConditionReason           = "NotLiveMigratable"
+	NodePoolExternalInfraClusterConditionReason        = "ExternalInfraCluster"
+	NodePoolNotEnoughBareMetalHostsConditionReason     = "NotEnoughBareMetalHosts"
+	NodePoolConfigUpdatePendingApprovalConditionReason = "ConfigUpdatePendingApproval"
+	NodePoolNodesOutOfDateConditionReason              = "NodesOutOfDate"
+	NodePoolInsufficientCapacityConditionReason        = "InsufficientCapacity"
+	NodePoolHibernationInProgressConditionReason       = "HibernationInProgress"
 )
 
 // The following are reasons for the IgnitionEndpointAvailable condition.
@@ -36,6 +52,10 @@ const (
 	// IgnitionServerTokenExpirationTimestampAnnotation holds the time that a ignition token expires and should be
 	// removed from the cluster.
 	IgnitionServerTokenExpirationTimestampAnnotation = "hypershift.openshift.io/ignition-token-expiration-timestamp"
+
+	// ApproveConfigAnnotation approves rolling out a config update to a NodePool with a Manual
+	// ConfigUpdatePolicy. Its value must be the target config hash reported in the NodePool status.
+	ApproveConfigAnnotation = "hypershift.openshift.io/approve-config"
 )
 
 func init() {
@@ -126,6 +146,41 @@ type NodePoolSpec struct {
 	// +kubebuilder:validation:Optional
 	Config []corev1.LocalObjectReference `json:"config,omitempty"`
 
+	// KubeletConfig tunes the kubelet of the nodes of the NodePool. It is
+	// rendered as a KubeletConfig into the ignition configuration of the nodes,
+	// so it cannot be combined with a KubeletConfig referenced by Config.
+	// Changing it replaces the nodes, or reboots them for in-place upgrades.
+	//
+	// +optional
+	KubeletConfig *NodePoolKubeletConfig `json:"kubeletConfig,omitempty"`
+
+	// PerformanceProfile tunes the nodes of the NodePool for latency sensitive
+	// workloads with hugepages, isolated CPUs and a realtime kernel. It is
+	// rendered into the kernel arguments and the kubelet configuration of the
+	// nodes, so changing it replaces the nodes, or reboots them for in-place
+	// upgrades.
+	//
+	// +optional
+	PerformanceProfile *NodePoolPerformanceProfile `json:"performanceProfile,omitempty"`
+
+	// RegistryMirrors are image mirrors the nodes of the NodePool can pull
+	// content from, in addition to the ImageContentSources of the HostedCluster.
+	// They are rendered as an ImageContentSourcePolicy into the container
+	// runtime configuration of the nodes.
+	//
+	// +optional
+	RegistryMirrors []ImageContentSource `json:"registryMirrors,omitempty"`
+
+	// PullSecret is a reference to a Secret in the NodePool namespace with a
+	// .dockerconfigjson key whose registry credentials are merged with the pull
+	// secret of the HostedCluster on the nodes of the NodePool. Its credentials
+	// replace the ones of the HostedCluster pull secret for the same registries.
+	// Changing the Secret or the HostedCluster pull secret replaces the nodes,
+	// or reboots them for in-place upgrades.
+	//
+	// +optional
+	PullSecret *corev1.LocalObjectReference `json:"pullSecret,omitempty"`
+
 	// NodeDrainTimeout is the total amount of time that the controller will spend on draining a node.
 	// The default value is 0, meaning that the node can be drained without any time limitations.
 	// NOTE: NodeDrainTimeout is different from `kubectl drain --timeout`
@@ -134,6 +189,209 @@ type NodePoolSpec struct {
 	// https://github.com/kubernetes-sigs/cluster-api/issues/5880
 	// +optional
 	NodeDrainTimeout *metav1.Duration `json:"nodeDrainTimeout,omitempty"`
+
+	// NodeLabels are labels applied to the nodes of the NodePool as soon as they
+	// register with the cluster, while they are still NotReady, and kept in sync
+	// afterwards. Labels removed from NodeLabels are removed from the nodes.
+	//
+	// +optional
+	NodeLabels map[string]string `json:"nodeLabels,omitempty"`
+
+	// Taints are taints applied to the nodes of the NodePool when they join the
+	// cluster and kept in sync afterwards. They are registered by the kubelet, so
+	// changing them also rolls out a new kubelet configuration to the nodes. Taints
+	// removed from Taints are removed from the nodes.
+	//
+	// +optional
+	Taints []Taint `json:"taints,omitempty"`
+
+	// GPU configures the nodes of the NodePool to run workloads on their NVIDIA
+	// GPUs. The nodes are labeled with nvidia.com/gpu.present=true and the
+	// autoscaler is told about their GPUs, so the NodePool can scale from zero
+	// for pods requesting nvidia.com/gpu. On AWS, the instance type and fallback
+	// instance types must be NVIDIA GPU instance types (e.g. g4dn.xlarge).
+	//
+	// +optional
+	GPU *NodePoolGPU `json:"gpu,omitempty"`
+
+	// PowerState is the desired power state of the nodes of the NodePool.
+	// Hibernating scales the NodePool to zero nodes, e.g. to save costs while
+	// the cluster is not used, and keeps its spec, machine templates and other
+	// resources, so that setting it back to Running brings the nodes back as
+	// specified by Replicas or AutoScaling. Autoscaling is disabled while the
+	// NodePool is hibernating.
+	//
+	// +kubebuilder:validation:Enum=Running;Hibernating
+	// +kubebuilder:default=Running
+	// +optional
+	PowerState NodePoolPowerState `json:"powerState,omitempty"`
+}
+
+// NodePoolKubeletConfig specifies the kubelet configuration of the nodes of a
+// NodePool. Unset fields keep the kubelet defaults of the release.
+type NodePoolKubeletConfig struct {
+	// MaxPods is the maximum number of pods that can run on a node.
+	//
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	MaxPods *int32 `json:"maxPods,omitempty"`
+
+	// SystemReserved are the CPU, memory and ephemeral storage reserved for the
+	// system daemons of a node, e.g. 500m of cpu and 1Gi of memory.
+	//
+	// +optional
+	SystemReserved corev1.ResourceList `json:"systemReserved,omitempty"`
+
+	// EvictionHard are the thresholds of eviction signals, e.g. memory.available
+	// or nodefs.available, below which pods are evicted immediately. Thresholds
+	// are quantities (e.g. 500Mi) or percentages (e.g. 10%).
+	//
+	// +optional
+	EvictionHard map[string]string `json:"evictionHard,omitempty"`
+
+	// EvictionSoft are the thresholds of eviction signals below which pods are
+	// evicted once the signal stays below the threshold for its grace period in
+	// EvictionSoftGracePeriod.
+	//
+	// +optional
+	EvictionSoft map[string]string `json:"evictionSoft,omitempty"`
+
+	// EvictionSoftGracePeriod are the grace periods of the eviction signals of
+	// EvictionSoft, e.g. 1m30s. Every signal of EvictionSoft needs one.
+	//
+	// +optional
+	EvictionSoftGracePeriod map[string]string `json:"evictionSoftGracePeriod,omitempty"`
+
+	// TopologyManagerPolicy is the policy of the kubelet topology manager,
+	// which aligns the CPUs and devices allocated to pods on NUMA nodes.
+	//
+	// +kubebuilder:validation:Enum=none;best-effort;restricted;single-numa-node
+	// +optional
+	TopologyManagerPolicy string `json:"topologyManagerPolicy,omitempty"`
+}
+
+// NodePoolPerformanceProfile specifies the performance tuning of the nodes of a
+// NodePool.
+type NodePoolPerformanceProfile struct {
+	// HugePages are the hugepages allocated on the nodes when they boot.
+	//
+	// +optional
+	HugePages []NodePoolHugePages `json:"hugePages,omitempty"`
+
+	// DefaultHugePagesSize is the size of the hugepages allocated when no size
+	// is requested, e.g. for hugetlbfs mounts without a pagesize option. It
+	// must be one of the sizes of HugePages.
+	//
+	// +kubebuilder:validation:Enum="2M";"1G"
+	// +optional
+	DefaultHugePagesSize string `json:"defaultHugePagesSize,omitempty"`
+
+	// ReservedCPUs is the set of CPUs reserved for the system daemons and the
+	// pods that don't request exclusive CPUs, e.g. 0-1. The kubelet CPU manager
+	// runs with the static policy, so guaranteed pods requesting whole CPUs get
+	// exclusive CPUs out of the other ones.
+	//
+	// +optional
+	ReservedCPUs string `json:"reservedCPUs,omitempty"`
+
+	// IsolatedCPUs is the set of CPUs isolated from the kernel scheduler, timer
+	// ticks and RCU callbacks for latency sensitive pods, e.g. 2-15. It
+	// requires ReservedCPUs and must not overlap with it.
+	//
+	// +optional
+	IsolatedCPUs string `json:"isolatedCPUs,omitempty"`
+
+	// RealtimeKernel boots the nodes with the realtime kernel.
+	//
+	// +optional
+	RealtimeKernel bool `json:"realtimeKernel,omitempty"`
+}
+
+// NodePoolHugePages specifies a number of hugepages of a given size.
+type NodePoolHugePages struct {
+	// Size is the size of the hugepages.
+	//
+	// +kubebuilder:validation:Enum="2M";"1G"
+	Size string `json:"size"`
+
+	// Count is the number of hugepages of the size allocated on each node.
+	//
+	// +kubebuilder:validation:Minimum=1
+	Count int32 `json:"count"`
+}
+
+// NodePoolPowerState is the power state of the nodes of a NodePool.
+type NodePoolPowerState string
+
+const (
+	// NodePoolPowerStateRunning runs the nodes of the NodePool.
+	NodePoolPowerStateRunning NodePoolPowerState = "Running"
+
+	// NodePoolPowerStateHibernating scales the NodePool to zero nodes.
+	NodePoolPowerStateHibernating NodePoolPowerState = "Hibernating"
+)
+
+// NodePoolGPU configures the nodes of a NodePool to run GPU workloads.
+type NodePoolGPU struct {
+	// Count is the number of GPUs of a node.
+	//
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:default=1
+	// +optional
+	Count int32 `json:"count,omitempty"`
+
+	// Dedicated taints the nodes with nvidia.com/gpu=present:NoSchedule, so that
+	// only pods tolerating the taint, e.g. pods requesting GPUs, are scheduled
+	// on them.
+	//
+	// +optional
+	Dedicated bool `json:"dedicated,omitempty"`
+
+	// Drivers makes the nodes load the NVIDIA driver and run the NVIDIA device
+	// plugin when they boot, so that their GPUs can be requested as soon as
+	// they join the cluster. When not set, the driver and device plugin must be
+	// installed in the hosted cluster, e.g. with the NVIDIA GPU Operator.
+	// Changing Drivers replaces the nodes, or reboots them for in-place
+	// upgrades.
+	//
+	// +optional
+	Drivers *NodePoolGPUDrivers `json:"drivers,omitempty"`
+}
+
+// NodePoolGPUDrivers specifies the NVIDIA driver and device plugin of the nodes
+// of a NodePool.
+type NodePoolGPUDrivers struct {
+	// DriverImage is the NVIDIA driver container image for the RHCOS version of
+	// the nodes, which builds and loads the driver and exposes it under
+	// /run/nvidia/driver, e.g. nvcr.io/nvidia/driver:525.105.17-rhcos4.12.
+	//
+	// +kubebuilder:validation:MinLength=1
+	DriverImage string `json:"driverImage"`
+
+	// DevicePluginImage is the NVIDIA device plugin image, which advertises the
+	// GPUs of the node as nvidia.com/gpu resources. It defaults to
+	// nvcr.io/nvidia/k8s-device-plugin:v0.14.0.
+	//
+	// +optional
+	DevicePluginImage string `json:"devicePluginImage,omitempty"`
+}
+
+// Taint is a taint applied to the nodes of a NodePool.
+type Taint struct {
+	// Key is the taint key to be applied to a node.
+	//
+	// +kubebuilder:validation:MinLength=1
+	Key string `json:"key"`
+
+	// Value is the taint value corresponding to the taint key.
+	//
+	// +optional
+	Value string `json:"value,omitempty"`
+
+	// Effect is the effect of the taint on pods that do not tolerate the taint.
+	//
+	// +kubebuilder:validation:Enum=NoSchedule;PreferNoSchedule;NoExecute
+	Effect corev1.TaintEffect `json:"effect"`
 }
 
 // NodePoolStatus is the latest observed status of a NodePool.
@@ -149,11 +407,56 @@ type NodePoolStatus struct {
 	// +kubebuilder:validation:Optional
 	Version string `json:"version,omitempty"`
 
+	// Config reports the configuration applied to the nodes of the NodePool and
+	// the configuration they are updated to.
+	//
+	// +optional
+	Config *NodePoolConfigStatus `json:"config,omitempty"`
+
+	// SpotInterruptions is the number of nodes of the NodePool that were
+	// replaced because their spot instance received an interruption notice.
+	//
+	// +optional
+	SpotInterruptions int32 `json:"spotInterruptions,omitempty"`
+
+	// Zones reports the number of node instances of the NodePool in each
+	// availability zone. It is only reported on AWS, and instances that are
+	// still being created are not counted.
+	//
+	// +optional
+	Zones []NodePoolZoneStatus `json:"zones,omitempty"`
+
 	// Conditions represents the latest available observations of the node pool's
 	// current state.
 	Conditions []NodePoolCondition `json:"conditions"`
 }
 
+// NodePoolConfigStatus reports the hashes of the configuration of a NodePool.
+type NodePoolConfigStatus struct {
+	// CurrentHash is the hash of the configuration rolled out to the nodes of the
+	// NodePool.
+	//
+	// +optional
+	CurrentHash string `json:"currentHash,omitempty"`
+
+	// TargetHash is the hash of the configuration computed from the config
+	// referenced by the NodePool. It differs from CurrentHash while a
+	// configuration update rolls out or waits for approval.
+	//
+	// +optional
+	TargetHash string `json:"targetHash,omitempty"`
+}
+
+// NodePoolZoneStatus reports the node instances of a NodePool in an availability
+// zone.
+type NodePoolZoneStatus struct {
+	// Name is the name of the availability zone.
+	Name string `json:"name"`
+
+	// Replicas is the number of node instances in the availability zone.
+	Replicas int32 `json:"replicas"`
+}
+
 // NodePoolList contains a list of NodePools.
 //
 // +kubebuilder:object:root=true
@@ -252,7 +555,16 @@ type RollingUpdate struct {
 
 // InPlaceUpgrade specifies an upgrade strategy which upgrades nodes in-place
 // without any new nodes being created or any old nodes being deleted.
-type InPlaceUpgrade struct{}
+type InPlaceUpgrade struct {
+	// MaxUnavailable is the maximum number of nodes that can be updated at the
+	// same time. Each node being updated is cordoned, drained, updated, rebooted
+	// and uncordoned. Value can be an absolute number (ex: 5) or a percentage of
+	// the nodes of the NodePool (ex: 10%). Absolute number is calculated from
+	// percentage by rounding down, with a minimum of 1. Defaults to 1.
+	//
+	// +optional
+	MaxUnavailable *intstr.IntOrString `json:"maxUnavailable,omitempty"`
+}
 
 // NodePoolManagement specifies behavior for managing nodes in a NodePool, such
 // as upgrade strategies and auto-repair behaviors.
@@ -278,19 +590,131 @@ type NodePoolManagement struct {
 	//
 	// +optional
 	AutoRepair bool `json:"autoRepair"`
+
+	// AutoRepairConfig tunes when the machines of the NodePool are considered
+	// unhealthy and replaced while AutoRepair is enabled. Unset fields keep their
+	// defaults.
+	//
+	// +optional
+	AutoRepairConfig *AutoRepairConfig `json:"autoRepairConfig,omitempty"`
+
+	// ConfigUpdatePolicy specifies how updates of the config referenced by the
+	// NodePool are rolled out. With Automatic, the default, they roll out
+	// immediately. With Manual, they are held until the NodePool is annotated with
+	// hypershift.openshift.io/approve-config set to the target config hash.
+	//
+	// +kubebuilder:validation:Enum=Automatic;Manual
+	// +optional
+	ConfigUpdatePolicy ConfigUpdatePolicy `json:"configUpdatePolicy,omitempty"`
+}
+
+// ConfigUpdatePolicy specifies how config updates are rolled out to a NodePool.
+type ConfigUpdatePolicy string
+
+const (
+	// ConfigUpdatePolicyAutomatic rolls out config updates immediately.
+	ConfigUpdatePolicyAutomatic = ConfigUpdatePolicy("Automatic")
+
+	// ConfigUpdatePolicyManual holds config updates until they are approved.
+	ConfigUpdatePolicyManual = ConfigUpdatePolicy("Manual")
+)
+
+// AutoRepairConfig specifies when the machines of a NodePool are considered
+// unhealthy and replaced.
+type AutoRepairConfig struct {
+	// UnhealthyConditions are the node conditions that make a machine unhealthy
+	// when they hold for longer than their timeout. Defaults to the Ready
+	// condition being False or Unknown for 8 minutes.
+	//
+	// +optional
+	UnhealthyConditions []UnhealthyCondition `json:"unhealthyConditions,omitempty"`
+
+	// NodeStartupTimeout is how long a machine may take to join the cluster as a
+	// node before it is considered unhealthy. Defaults to 20 minutes.
+	//
+	// +optional
+	NodeStartupTimeout *metav1.Duration `json:"nodeStartupTimeout,omitempty"`
+
+	// MaxUnhealthy is the number or percentage of the machines of the NodePool
+	// that may be unhealthy at once. No machines are replaced while more are
+	// unhealthy, to not make an outage worse. Defaults to 2.
+	//
+	// +optional
+	MaxUnhealthy *intstr.IntOrString `json:"maxUnhealthy,omitempty"`
+}
+
+// UnhealthyCondition is a node condition that makes a machine unhealthy when it
+// holds for longer than the timeout.
+type UnhealthyCondition struct {
+	// Type is the type of the node condition, e.g. Ready.
+	//
+	// +kubebuilder:validation:Type=string
+	// +kubebuilder:validation:MinLength=1
+	Type corev1.NodeConditionType `json:"type"`
+
+	// Status is the status of the node condition.
+	//
+	// +kubebuilder:validation:Enum=True;False;Unknown
+	Status corev1.ConditionStatus `json:"status"`
+
+	// Timeout is how long the node condition has to hold for the machine to be
+	// unhealthy.
+	Timeout metav1.Duration `json:"timeout"`
 }
 
 // NodePoolAutoScaling specifies auto-scaling behavior for a NodePool.
 type NodePoolAutoScaling struct {
-	// Min is the minimum number of nodes to maintain in the pool. Must be >= 1.
+	// Min is the minimum number of nodes to maintain in the pool. Must be >= 0.
+	// A pool with a Min of 0 can scale down to no nodes, scaling it up again
+	// requires the CPU and memory of its nodes to be known.
 	//
-	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Minimum=0
 	Min int32 `json:"min"`
 
 	// Max is the maximum number of nodes allowed in the pool. Must be >= 1.
 	//
 	// +kubebuilder:validation:Minimum=1
 	Max int32 `json:"max"`
+
+	// Capacity is the capacity of a node of the pool, which the autoscaler uses
+	// to decide whether scaling up the pool from no nodes lets pending pods be
+	// scheduled. It is derived from the platform for KubeVirt, the fields set
+	// here take precedence.
+	//
+	// +optional
+	Capacity *NodePoolCapacity `json:"capacity,omitempty"`
+}
+
+// NodePoolCapacity specifies the resources of a node of a NodePool.
+type NodePoolCapacity struct {
+	// CPU is the number of CPUs of a node.
+	//
+	// +optional
+	CPU *resource.Quantity `json:"cpu,omitempty"`
+
+	// Memory is the memory of a node.
+	//
+	// +optional
+	Memory *resource.Quantity `json:"memory,omitempty"`
+
+	// GPU is the number of GPUs of a node.
+	//
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	GPU int32 `json:"gpu,omitempty"`
+
+	// GPUType is the resource name the GPUs of a node are requested as, e.g.
+	// nvidia.com/gpu.
+	//
+	// +optional
+	GPUType string `json:"gpuType,omitempty"`
+
+	// Architecture is the CPU architecture of a node. If unset, it is ppc64le
+	// on PowerVS and amd64 otherwise.
+	//
+	// +optional
+	// +kubebuilder:validation:Enum=amd64;arm64;ppc64le;s390x
+	Architecture string `json:"architecture,omitempty"`
 }
 
 // NodePoolPlatform specifies the underlying infrastructure provider for the
@@ -326,6 +750,252 @@ type NodePoolPlatform struct {
 	//
 	// +optional
 	PowerVS *PowerVSNodePoolPlatform `json:"powervs,omitempty"`
+
+	// IBMCloudVPC specifies the configuration used when using IBM Cloud VPC
+	// platform.
+	//
+	// +optional
+	IBMCloudVPC *IBMCloudVPCNodePoolPlatform `json:"ibmcloudvpc,omitempty"`
+
+	// GCP specifies the configuration used when using Google Cloud platform.
+	//
+	// +optional
+	GCP *GCPNodePoolPlatform `json:"gcp,omitempty"`
+
+	// OpenStack specifies the configuration used when using OpenStack platform.
+	//
+	// +optional
+	OpenStack *OpenStackNodePoolPlatform `json:"openstack,omitempty"`
+
+	// Nutanix specifies the configuration used when using Nutanix AHV
+	// platform.
+	//
+	// +optional
+	Nutanix *NutanixNodePoolPlatform `json:"nutanix,omitempty"`
+
+	// OCI specifies the configuration used when using Oracle Cloud
+	// Infrastructure platform.
+	//
+	// +optional
+	OCI *OCINodePoolPlatform `json:"oci,omitempty"`
+}
+
+// IBMCloudVPCNodePoolPlatform specifies the configuration of a NodePool when
+// operating on IBM Cloud VPC platform.
+type IBMCloudVPCNodePoolPlatform struct {
+	// Profile is the instance profile of the nodes, which determines their
+	// number of vCPUs and memory. E.g. bx2-4x16 has 4 vCPUs and 16 GiB of memory.
+	// When omitted, this means that the user has no opinion and the platform is left to choose a
+	// reasonable default. The current default is bx2-4x16.
+	//
+	// +optional
+	// +kubebuilder:default=bx2-4x16
+	Profile string `json:"profile,omitempty"`
+
+	// Image is the ID of the RHCOS image the nodes boot from. RHCOS images
+	// for IBM Cloud are not public, the image must be imported as a custom
+	// image into the region of the cluster.
+	//
+	// +kubebuilder:validation:MinLength=1
+	Image string `json:"image"`
+}
+
+// GCPNodePoolPlatform specifies the configuration of a NodePool when
+// operating on Google Cloud platform.
+type GCPNodePoolPlatform struct {
+	// MachineType is the machine type of the instances, which determines their
+	// number of vCPUs and memory. E.g. n2-standard-4 has 4 vCPUs and 16 GiB of
+	// memory.
+	// When omitted, this means that the user has no opinion and the platform is left to choose a
+	// reasonable default. The current default is n2-standard-4.
+	//
+	// +optional
+	// +kubebuilder:default=n2-standard-4
+	MachineType string `json:"machineType,omitempty"`
+
+	// Zone is the zone of the region of the cluster the instances are created
+	// in, e.g. us-central1-a.
+	//
+	// +kubebuilder:validation:MinLength=1
+	Zone string `json:"zone"`
+
+	// Image is the RHCOS image the instances boot from, as the path of the
+	// image relative to the compute API, e.g.
+	// projects/rhcos-cloud/global/images/rhcos-412-86-202212081411-0-gcp-x86-64.
+	//
+	// +kubebuilder:validation:MinLength=1
+	Image string `json:"image"`
+
+	// DiskSizeGB is the size of the boot disk of the instances in GiB.
+	//
+	// +optional
+	// +kubebuilder:default=128
+	// +kubebuilder:validation:Minimum=16
+	DiskSizeGB int64 `json:"diskSizeGB,omitempty"`
+
+	// DiskType is the type of the boot disk of the instances.
+	//
+	// +optional
+	// +kubebuilder:default=pd-ssd
+	// +kubebuilder:validation:Enum=pd-standard;pd-balanced;pd-ssd
+	DiskType string `json:"diskType,omitempty"`
+
+	// ServiceAccount is the email of the Google service account the instances
+	// run as. When omitted, the instances run without a service account. The
+	// create iam gcp command creates a service account for the nodes.
+	//
+	// +optional
+	ServiceAccount string `json:"serviceAccount,omitempty"`
+}
+
+// OpenStackNodePoolPlatform specifies the configuration of a NodePool when
+// operating on OpenStack.
+type OpenStackNodePoolPlatform struct {
+	// Flavor is the Nova flavor of the instances, which determines their
+	// number of vCPUs, memory and ephemeral disk.
+	//
+	// +kubebuilder:validation:MinLength=1
+	Flavor string `json:"flavor"`
+
+	// Image is the name of the RHCOS Glance image the instances boot from.
+	//
+	// +kubebuilder:validation:MinLength=1
+	Image string `json:"image"`
+
+	// AvailabilityZone is the Nova availability zone the instances are created
+	// in. When omitted, the instances are scheduled in the default zone.
+	//
+	// +optional
+	AvailabilityZone string `json:"availabilityZone,omitempty"`
+
+	// RootVolumeSizeGB is the size in GiB of a Cinder volume the instances boot
+	// from. When omitted, the instances boot from the ephemeral disk of the
+	// flavor.
+	//
+	// +optional
+	// +kubebuilder:validation:Minimum=16
+	RootVolumeSizeGB int64 `json:"rootVolumeSizeGB,omitempty"`
+}
+
+// NutanixNodePoolPlatform specifies the configuration of a NodePool when
+// operating on Nutanix AHV.
+type NutanixNodePoolPlatform struct {
+	// Cluster is the Prism Element cluster the virtual machines are created
+	// in.
+	Cluster NutanixResourceReference `json:"cluster"`
+
+	// Subnet is the subnet of the Prism Element cluster the virtual machines
+	// are attached to.
+	Subnet NutanixResourceReference `json:"subnet"`
+
+	// Image is the RHCOS image the virtual machines boot from.
+	Image NutanixResourceReference `json:"image"`
+
+	// VCPUSockets is the number of vCPU sockets of the virtual machines.
+	//
+	// +optional
+	// +kubebuilder:default=4
+	// +kubebuilder:validation:Minimum=1
+	VCPUSockets int32 `json:"vcpuSockets,omitempty"`
+
+	// VCPUsPerSocket is the number of vCPUs of each socket of the virtual
+	// machines.
+	//
+	// +optional
+	// +kubebuilder:default=1
+	// +kubebuilder:validation:Minimum=1
+	VCPUsPerSocket int32 `json:"vcpusPerSocket,omitempty"`
+
+	// MemorySizeMiB is the memory of the virtual machines in MiB.
+	//
+	// +optional
+	// +kubebuilder:default=16384
+	// +kubebuilder:validation:Minimum=2048
+	MemorySizeMiB int64 `json:"memorySizeMiB,omitempty"`
+
+	// SystemDiskSizeGiB is the size of the system disk of the virtual
+	// machines in GiB.
+	//
+	// +optional
+	// +kubebuilder:default=120
+	// +kubebuilder:validation:Minimum=20
+	SystemDiskSizeGiB int64 `json:"systemDiskSizeGiB,omitempty"`
+}
+
+// OCINodePoolPlatform specifies the configuration of a NodePool when
+// operating on Oracle Cloud Infrastructure. The instances of the NodePool are
+// managed as an instance pool.
+type OCINodePoolPlatform struct {
+	// Shape is the shape of the instances.
+	//
+	// +optional
+	// +kubebuilder:default=VM.Standard.E4.Flex
+	Shape string `json:"shape,omitempty"`
+
+	// OCPUs is the number of OCPUs of the instances. Only used with flexible
+	// shapes.
+	//
+	// +optional
+	// +kubebuilder:default=4
+	// +kubebuilder:validation:Minimum=1
+	OCPUs int32 `json:"ocpus,omitempty"`
+
+	// MemoryGB is the memory of the instances in GB. Only used with flexible
+	// shapes.
+	//
+	// +optional
+	// +kubebuilder:default=16
+	// +kubebuilder:validation:Minimum=1
+	MemoryGB int32 `json:"memoryGB,omitempty"`
+
+	// ImageID is the OCID of the RHCOS image the instances boot from.
+	//
+	// +kubebuilder:validation:MinLength=1
+	ImageID string `json:"imageID"`
+
+	// AvailabilityDomain is the availability domain the instances are created
+	// in, e.g. Uocm:US-ASHBURN-AD-1. When omitted, the instances are spread
+	// across the availability domains of the region.
+	//
+	// +optional
+	AvailabilityDomain string `json:"availabilityDomain,omitempty"`
+
+	// BootVolumeSizeGB is the size of the boot volume of the instances in GB.
+	//
+	// +optional
+	// +kubebuilder:default=120
+	// +kubebuilder:validation:Minimum=50
+	BootVolumeSizeGB int64 `json:"bootVolumeSizeGB,omitempty"`
+}
+
+// NutanixIdentifierType is how a Nutanix resource is identified.
+//
+// +kubebuilder:validation:Enum=uuid;name
+type NutanixIdentifierType string
+
+const (
+	// NutanixIdentifierUUID identifies a resource by its UUID.
+	NutanixIdentifierUUID NutanixIdentifierType = "uuid"
+
+	// NutanixIdentifierName identifies a resource by its name.
+	NutanixIdentifierName NutanixIdentifierType = "name"
+)
+
+// NutanixResourceReference is a reference to a Nutanix resource by UUID or
+// name.
+type NutanixResourceReference struct {
+	// Type is how the resource is identified.
+	Type NutanixIdentifierType `json:"type"`
+
+	// UUID of the resource. Required when type is uuid.
+	//
+	// +optional
+	UUID string `json:"uuid,omitempty"`
+
+	// Name of the resource. Required when type is name.
+	//
+	// +optional
+	Name string `json:"name,omitempty"`
 }
 
 // PowerVSNodePoolPlatform specifies the configuration of a NodePool when operating
@@ -376,6 +1046,17 @@ type PowerVSNodePoolPlatform struct {
 	// +kubebuilder:default="0.5"
 	Processors intstr.IntOrString `json:"processors,omitempty"`
 
+	// SMTLevel is the number of hardware threads each virtual processor runs
+	// (simultaneous multithreading). Lower levels trade throughput for better
+	// per-thread performance, which some licensed or latency sensitive
+	// workloads require.
+	// When omitted, this means that the user has no opinion and the operating
+	// system default is used, which is currently 8.
+	//
+	// +optional
+	// +kubebuilder:validation:Enum=1;2;4;8
+	SMTLevel *int32 `json:"smtLevel,omitempty"`
+
 	// MemoryGiB is the size of a virtual machine's memory, in GiB.
 	// maximum value for the MemoryGiB depends on the selected SystemType.
 	// when SystemType is set to e880 maximum MemoryGiB value is 7463 GiB.
@@ -514,6 +1195,87 @@ type KubevirtNodePoolPlatform struct {
 	// +optional
 	// +kubebuilder:default={memory: "4Gi", cores: 2}
 	Compute *KubevirtCompute `json:"compute"`
+
+	// AdditionalNetworks are the networks the VMs are attached to next to the
+	// pod network, e.g. to reach VLAN-backed networks of the datacenter. The
+	// guest configures the interfaces of the networks using DHCP.
+	//
+	// +optional
+	AdditionalNetworks []KubevirtNetwork `json:"additionalNetworks,omitempty"`
+
+	// HostDevices are the GPUs and other devices of the infra nodes passed
+	// through to the VMs. The VMs are only scheduled onto infra nodes whose
+	// device plugins expose the devices.
+	//
+	// +optional
+	HostDevices []KubevirtHostDevice `json:"hostDevices,omitempty"`
+}
+
+// KubevirtHostDeviceType is the kind of device passed through to a VM.
+//
+// +kubebuilder:validation:Enum=GPU;HostDevice
+type KubevirtHostDeviceType string
+
+const (
+	// KubevirtHostDeviceTypeGPU is a GPU, either passed through as a whole or
+	// as a mediated vGPU.
+	KubevirtHostDeviceTypeGPU KubevirtHostDeviceType = "GPU"
+
+	// KubevirtHostDeviceTypeHostDevice is any other PCI or mediated device.
+	KubevirtHostDeviceTypeHostDevice KubevirtHostDeviceType = "HostDevice"
+)
+
+// KubevirtHostDevice specifies devices of the infra nodes passed through to the
+// VMs of a NodePool.
+type KubevirtHostDevice struct {
+	// DeviceName is the resource name the device plugin of the infra nodes
+	// exposes the device as, e.g. nvidia.com/GA102GL_A10.
+	//
+	// +kubebuilder:validation:MinLength=1
+	DeviceName string `json:"deviceName"`
+
+	// Type is the kind of the device.
+	//
+	// +optional
+	// +kubebuilder:default=GPU
+	Type KubevirtHostDeviceType `json:"type,omitempty"`
+
+	// Count is how many of the devices each VM gets.
+	//
+	// +optional
+	// +kubebuilder:default=1
+	// +kubebuilder:validation:Minimum=1
+	Count int32 `json:"count,omitempty"`
+}
+
+// KubevirtNetworkBinding is how the interface of a VM is bound to a network.
+//
+// +kubebuilder:validation:Enum=Bridge;SRIOV
+type KubevirtNetworkBinding string
+
+const (
+	// KubevirtNetworkBindingBridge connects the interface to the network
+	// through a bridge, keeping the MAC address KubeVirt assigns to it.
+	KubevirtNetworkBindingBridge KubevirtNetworkBinding = "Bridge"
+
+	// KubevirtNetworkBindingSRIOV passes an SR-IOV virtual function of the
+	// network through to the VM.
+	KubevirtNetworkBindingSRIOV KubevirtNetworkBinding = "SRIOV"
+)
+
+// KubevirtNetwork specifies an additional network of the VMs of a NodePool.
+type KubevirtNetwork struct {
+	// Name is the name of the NetworkAttachmentDefinition of the network, in
+	// the form <name> for one in the namespace of the VMs or <namespace>/<name>.
+	//
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+
+	// Binding is how the interfaces of the VMs are bound to the network.
+	//
+	// +optional
+	// +kubebuilder:default=Bridge
+	Binding KubevirtNetworkBinding `json:"binding,omitempty"`
 }
 
 // AWSNodePoolPlatform specifies the configuration of a NodePool when operating
@@ -522,14 +1284,40 @@ type AWSNodePoolPlatform struct {
 	// InstanceType is an ec2 instance type for node instances (e.g. m5.large).
 	InstanceType string `json:"instanceType"`
 
+	// FallbackInstanceTypes is an ordered list of ec2 instance types to use when
+	// EC2 has no capacity for InstanceType. When node instances fail to be
+	// created for lack of capacity, the whole NodePool falls back to the next
+	// instance type of the list. The Machines that could not be created are
+	// replaced, and with the Replace upgrade type the existing nodes are
+	// replaced too. The NodePool doesn't go back to InstanceType when capacity
+	// returns, only when InstanceType or FallbackInstanceTypes change.
+	//
+	// +kubebuilder:validation:MaxItems=10
+	// +optional
+	FallbackInstanceTypes []string `json:"fallbackInstanceTypes,omitempty"`
+
 	// InstanceProfile is the AWS EC2 instance profile, which is a container for an IAM role that the EC2 instance uses.
 	InstanceProfile string `json:"instanceProfile,omitempty"`
 
-	// Subnet is the subnet to use for node instances.
+	// Subnet is the subnet to use for node instances. Only one of Subnet or
+	// Subnets may be set.
 	//
 	// +optional
 	Subnet *AWSResourceReference `json:"subnet,omitempty"`
 
+	// Subnets is a list of subnets, typically in different availability zones,
+	// to spread node instances across. A MachineDeployment is created for each
+	// subnet, and the replicas of the NodePool, or its autoscaling minimum and
+	// maximum, are divided between them as evenly as possible, the first
+	// subnets of the list getting the remainder.
+	//
+	// Subnets cannot be used with the InPlace upgrade type. The instances of a
+	// subnet are replaced when the subnet at its position in the list changes.
+	//
+	// +kubebuilder:validation:MaxItems=16
+	// +optional
+	Subnets []AWSResourceReference `json:"subnets,omitempty"`
+
 	// AMI is the image id to use for node instances. If unspecified, the default
 	// is chosen based on the NodePool release payload image.
 	//
@@ -561,6 +1349,72 @@ type AWSNodePoolPlatform struct {
 	// +kubebuilder:validation:MaxItems=25
 	// +optional
 	ResourceTags []AWSResourceTag `json:"resourceTags,omitempty"`
+
+	// MarketType is the EC2 purchasing option of the node instances. When
+	// omitted, on-demand instances are used.
+	//
+	// Spot instances can be interrupted by AWS at any time. Their nodes are
+	// labeled with cluster.x-k8s.io/interruptible, and when the instance of a
+	// node receives an interruption notice the node is drained and replaced.
+	// Capacity rebalance recommendations are not acted on.
+	//
+	// +kubebuilder:validation:Enum=OnDemand;Spot
+	// +optional
+	MarketType AWSMarketType `json:"marketType,omitempty"`
+
+	// Spot configures the spot instances of the NodePool. It can only be set
+	// when MarketType is Spot.
+	//
+	// +optional
+	Spot *AWSSpotOptions `json:"spot,omitempty"`
+
+	// Tenancy indicates whether node instances run on shared or single-tenant
+	// hardware. When omitted, the tenancy of the VPC is used.
+	//
+	// Dedicated instances run on hardware dedicated to the AWS account. Host
+	// instances run on a Dedicated Host of the account with auto-placement
+	// enabled in the availability zone of the subnet, e.g. for licenses bound
+	// to physical cores or sockets. Instances on Dedicated Hosts cannot be spot
+	// instances.
+	//
+	// +kubebuilder:validation:Enum=default;dedicated;host
+	// +optional
+	Tenancy AWSTenancy `json:"tenancy,omitempty"`
+}
+
+// AWSTenancy indicates whether instances run on shared or single-tenant hardware.
+type AWSTenancy string
+
+const (
+	// AWSTenancyDefault runs instances on shared hardware.
+	AWSTenancyDefault AWSTenancy = "default"
+
+	// AWSTenancyDedicated runs instances on hardware dedicated to the AWS account.
+	AWSTenancyDedicated AWSTenancy = "dedicated"
+
+	// AWSTenancyHost runs instances on Dedicated Hosts of the AWS account.
+	AWSTenancyHost AWSTenancy = "host"
+)
+
+// AWSMarketType is the EC2 purchasing option of instances.
+type AWSMarketType string
+
+const (
+	// AWSMarketTypeOnDemand uses on-demand instances.
+	AWSMarketTypeOnDemand AWSMarketType = "OnDemand"
+
+	// AWSMarketTypeSpot uses spot instances.
+	AWSMarketTypeSpot AWSMarketType = "Spot"
+)
+
+// AWSSpotOptions configures spot instances.
+type AWSSpotOptions struct {
+	// MaxPrice is the maximum hourly price, in USD, to pay for a spot instance.
+	// When omitted, the price is capped at the on-demand price.
+	//
+	// +kubebuilder:validation:Pattern=`^[0-9]+(\.[0-9]+)?$`
+	// +optional
+	MaxPrice *string `json:"maxPrice,omitempty"`
 }
 
 // AWSResourceReference is a reference to a specific AWS resource by ID, ARN, or filters.
@@ -600,14 +1454,36 @@ type Volume struct {
 	// +kubebuilder:validation:Minimum=8
 	Size int64 `json:"size"`
 
-	// Type is the type of the volume.
+	// Type is the type of the volume (e.g. gp3, io2).
 	Type string `json:"type"`
 
 	// IOPS is the number of IOPS requested for the disk. This is only valid
-	// for type io1.
+	// for types io1, io2 and gp3.
 	//
 	// +optional
 	IOPS int64 `json:"iops,omitempty"`
+
+	// Throughput is the throughput to provision for the disk, in MiB/s. This
+	// is only valid for type gp3.
+	//
+	// +kubebuilder:validation:Minimum=125
+	// +optional
+	Throughput *int64 `json:"throughput,omitempty"`
+
+	// Encrypted indicates whether the disk is encrypted. When omitted, the disk
+	// is encrypted if EncryptionKey is set or if EBS encryption by default is
+	// enabled for the AWS account in the region.
+	//
+	// +optional
+	Encrypted *bool `json:"encrypted,omitempty"`
+
+	// EncryptionKey is the ID or ARN of the KMS key the disk is encrypted
+	// with. When omitted, encrypted disks use the default EBS key of the AWS
+	// account. The key policy must allow the NodePool management role to use
+	// the key.
+	//
+	// +optional
+	EncryptionKey string `json:"encryptionKey,omitempty"`
 }
 
 // AgentNodePoolPlatform specifies the configuration of a NodePool when operating
@@ -617,6 +1493,13 @@ type AgentNodePoolPlatform struct {
 	// be selected for a Machine.
 	// +optional
 	AgentLabelSelector *metav1.LabelSelector `json:"agentLabelSelector,omitempty"`
+
+	// BareMetalHostSelector selects the BareMetalHosts of the Agent namespace
+	// that are powered on through their BMC to boot the discovery image when the
+	// Machines of the NodePool need Agents, and powered off again when they are no
+	// longer needed. If unset, the hosts have to be booted manually.
+	// +optional
+	BareMetalHostSelector *metav1.LabelSelector `json:"bareMetalHostSelector,omitempty"`
 }
 
 type AzureNodePoolPlatform struct {
@@ -642,6 +1525,16 @@ type AzureNodePoolPlatform struct {
 	// +kubebuilder:validation:Enum=Standard_LRS;StandardSSD_LRS;Premium_LRS;UltraSSD_LRS
 	// +optional
 	DiskStorageAccountType string `json:"diskStorageAccountType,omitempty"`
+	// DiskEncryptionSetID is the ID of a DiskEncryptionSet the disks of the machines are
+	// encrypted with, so that they are encrypted with a customer-managed key rather than
+	// a platform-managed key. It must be in the subscription of the HostedCluster, and its
+	// identity must be allowed to get, wrap and unwrap the key in its Key Vault.
+	//
+	// Changing it replaces the machines of the NodePool.
+	//
+	// +kubebuilder:validation:Pattern=`^/subscriptions/[^/]+/resourceGroups/[^/]+/providers/Microsoft\.Compute/diskEncryptionSets/[^/]+$`
+	// +optional
+	DiskEncryptionSetID string `json:"diskEncryptionSetID,omitempty"`
 	// AvailabilityZone of the nodepool. Must not be specified for clusters
 	// in a location that does not support AvailabilityZone.
 	// +optional
@@ -282,11 +282,23 @@ func (in *AWSKMSSpec) DeepCopy() *AWSKMSSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *AWSNodePoolPlatform) DeepCopyInto(out *AWSNodePoolPlatform) {
 	*out = *in
+	if in.FallbackInstanceTypes != nil {
+		in, out := &in.FallbackInstanceTypes, &out.FallbackInstanceTypes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	if in.Subnet != nil {
 		in, out := &in.Subnet, &out.Subnet
 		*out = new(AWSResourceReference)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.Subnets != nil {
+		in, out := &in.Subnets, &out.Subnets
+		*out = make([]AWSResourceReference, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	if in.SecurityGroups != nil {
 		in, out := &in.SecurityGroups, &out.SecurityGroups
 		*out = make([]AWSResourceReference, len(*in))
@@ -297,13 +309,18 @@ func (in *AWSNodePoolPlatform) DeepCopyInto(out *AWSNodePoolPlatform) {
 	if in.RootVolume != nil {
 		in, out := &in.RootVolume, &out.RootVolume
 		*out = new(Volume)
-		**out = **in
+		(*in).DeepCopyInto(*out)
 	}
 	if in.ResourceTags != nil {
 		in, out := &in.ResourceTags, &out.ResourceTags
 		*out = make([]AWSResourceTag, len(*in))
 		copy(*out, *in)
 	}
+	if in.Spot != nil {
+		in, out := &in.Spot, &out.Spot
+		*out = new(AWSSpotOptions)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AWSNodePoolPlatform.
@@ -447,6 +464,26 @@ func (in *AWSServiceEndpoint) DeepCopy() *AWSServiceEndpoint {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AWSSpotOptions) DeepCopyInto(out *AWSSpotOptions) {
+	*out = *in
+	if in.MaxPrice != nil {
+		in, out := &in.MaxPrice, &out.MaxPrice
+		*out = new(string)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AWSSpotOptions.
+func (in *AWSSpotOptions) DeepCopy() *AWSSpotOptions {
+	if in == nil {
+		return nil
+	}
+	out := new(AWSSpotOptions)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *AgentNodePoolPlatform) DeepCopyInto(out *AgentNodePoolPlatform) {
 	*out = *in
@@ -455,6 +492,11 @@ func (in *AgentNodePoolPlatform) DeepCopyInto(out *AgentNodePoolPlatform) {
 		*out = new(v1.LabelSelector)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.BareMetalHostSelector != nil {
+		in, out := &in.BareMetalHostSelector, &out.BareMetalHostSelector
+		*out = new(v1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AgentNodePoolPlatform.
@@ -482,6 +524,36 @@ func (in *AgentPlatformSpec) DeepCopy() *AgentPlatformSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AutoRepairConfig) DeepCopyInto(out *AutoRepairConfig) {
+	*out = *in
+	if in.UnhealthyConditions != nil {
+		in, out := &in.UnhealthyConditions, &out.UnhealthyConditions
+		*out = make([]UnhealthyCondition, len(*in))
+		copy(*out, *in)
+	}
+	if in.NodeStartupTimeout != nil {
+		in, out := &in.NodeStartupTimeout, &out.NodeStartupTimeout
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.MaxUnhealthy != nil {
+		in, out := &in.MaxUnhealthy, &out.MaxUnhealthy
+		*out = new(intstr.IntOrString)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AutoRepairConfig.
+func (in *AutoRepairConfig) DeepCopy() *AutoRepairConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(AutoRepairConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *AzureNodePoolPlatform) DeepCopyInto(out *AzureNodePoolPlatform) {
 	*out = *in
@@ -776,6 +848,55 @@ func (in *Filter) DeepCopy() *Filter {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GCPNetwork) DeepCopyInto(out *GCPNetwork) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GCPNetwork.
+func (in *GCPNetwork) DeepCopy() *GCPNetwork {
+	if in == nil {
+		return nil
+	}
+	out := new(GCPNetwork)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GCPNodePoolPlatform) DeepCopyInto(out *GCPNodePoolPlatform) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GCPNodePoolPlatform.
+func (in *GCPNodePoolPlatform) DeepCopy() *GCPNodePoolPlatform {
+	if in == nil {
+		return nil
+	}
+	out := new(GCPNodePoolPlatform)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GCPPlatformSpec) DeepCopyInto(out *GCPPlatformSpec) {
+	*out = *in
+	out.Network = in.Network
+	out.KubeCloudControllerCreds = in.KubeCloudControllerCreds
+	out.NodePoolManagementCreds = in.NodePoolManagementCreds
+	out.ControlPlaneOperatorCreds = in.ControlPlaneOperatorCreds
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GCPPlatformSpec.
+func (in *GCPPlatformSpec) DeepCopy() *GCPPlatformSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(GCPPlatformSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *HostedCluster) DeepCopyInto(out *HostedCluster) {
 	*out = *in
@@ -1247,6 +1368,76 @@ func (in *IBMCloudPlatformSpec) DeepCopy() *IBMCloudPlatformSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IBMCloudVPCNetwork) DeepCopyInto(out *IBMCloudVPCNetwork) {
+	*out = *in
+	out.Subnet = in.Subnet
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IBMCloudVPCNetwork.
+func (in *IBMCloudVPCNetwork) DeepCopy() *IBMCloudVPCNetwork {
+	if in == nil {
+		return nil
+	}
+	out := new(IBMCloudVPCNetwork)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IBMCloudVPCNodePoolPlatform) DeepCopyInto(out *IBMCloudVPCNodePoolPlatform) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IBMCloudVPCNodePoolPlatform.
+func (in *IBMCloudVPCNodePoolPlatform) DeepCopy() *IBMCloudVPCNodePoolPlatform {
+	if in == nil {
+		return nil
+	}
+	out := new(IBMCloudVPCNodePoolPlatform)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IBMCloudVPCPlatformSpec) DeepCopyInto(out *IBMCloudVPCPlatformSpec) {
+	*out = *in
+	if in.VPC != nil {
+		in, out := &in.VPC, &out.VPC
+		*out = new(IBMCloudVPCNetwork)
+		**out = **in
+	}
+	out.KubeCloudControllerCreds = in.KubeCloudControllerCreds
+	out.NodePoolManagementCreds = in.NodePoolManagementCreds
+	out.ControlPlaneOperatorCreds = in.ControlPlaneOperatorCreds
+	out.IngressOperatorCloudCreds = in.IngressOperatorCloudCreds
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IBMCloudVPCPlatformSpec.
+func (in *IBMCloudVPCPlatformSpec) DeepCopy() *IBMCloudVPCPlatformSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(IBMCloudVPCPlatformSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IBMCloudVPCSubnet) DeepCopyInto(out *IBMCloudVPCSubnet) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IBMCloudVPCSubnet.
+func (in *IBMCloudVPCSubnet) DeepCopy() *IBMCloudVPCSubnet {
+	if in == nil {
+		return nil
+	}
+	out := new(IBMCloudVPCSubnet)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ImageContentSource) DeepCopyInto(out *ImageContentSource) {
 	*out = *in
@@ -1270,6 +1461,11 @@ func (in *ImageContentSource) DeepCopy() *ImageContentSource {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *InPlaceUpgrade) DeepCopyInto(out *InPlaceUpgrade) {
 	*out = *in
+	if in.MaxUnavailable != nil {
+		in, out := &in.MaxUnavailable, &out.MaxUnavailable
+		*out = new(intstr.IntOrString)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InPlaceUpgrade.
@@ -1367,6 +1563,36 @@ func (in *KubevirtDiskImage) DeepCopy() *KubevirtDiskImage {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KubevirtHostDevice) DeepCopyInto(out *KubevirtHostDevice) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KubevirtHostDevice.
+func (in *KubevirtHostDevice) DeepCopy() *KubevirtHostDevice {
+	if in == nil {
+		return nil
+	}
+	out := new(KubevirtHostDevice)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KubevirtNetwork) DeepCopyInto(out *KubevirtNetwork) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KubevirtNetwork.
+func (in *KubevirtNetwork) DeepCopy() *KubevirtNetwork {
+	if in == nil {
+		return nil
+	}
+	out := new(KubevirtNetwork)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *KubevirtNodePoolPlatform) DeepCopyInto(out *KubevirtNodePoolPlatform) {
 	*out = *in
@@ -1380,6 +1606,16 @@ func (in *KubevirtNodePoolPlatform) DeepCopyInto(out *KubevirtNodePoolPlatform)
 		*out = new(KubevirtCompute)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.AdditionalNetworks != nil {
+		in, out := &in.AdditionalNetworks, &out.AdditionalNetworks
+		*out = make([]KubevirtNetwork, len(*in))
+		copy(*out, *in)
+	}
+	if in.HostDevices != nil {
+		in, out := &in.HostDevices, &out.HostDevices
+		*out = make([]KubevirtHostDevice, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KubevirtNodePoolPlatform.
@@ -1422,6 +1658,46 @@ func (in *KubevirtPersistentVolume) DeepCopy() *KubevirtPersistentVolume {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KubevirtPlatformCredentials) DeepCopyInto(out *KubevirtPlatformCredentials) {
+	*out = *in
+	if in.InfraKubeConfigSecret != nil {
+		in, out := &in.InfraKubeConfigSecret, &out.InfraKubeConfigSecret
+		*out = new(KubeconfigSecretRef)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KubevirtPlatformCredentials.
+func (in *KubevirtPlatformCredentials) DeepCopy() *KubevirtPlatformCredentials {
+	if in == nil {
+		return nil
+	}
+	out := new(KubevirtPlatformCredentials)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KubevirtPlatformSpec) DeepCopyInto(out *KubevirtPlatformSpec) {
+	*out = *in
+	if in.Credentials != nil {
+		in, out := &in.Credentials, &out.Credentials
+		*out = new(KubevirtPlatformCredentials)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KubevirtPlatformSpec.
+func (in *KubevirtPlatformSpec) DeepCopy() *KubevirtPlatformSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(KubevirtPlatformSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *KubevirtRootVolume) DeepCopyInto(out *KubevirtRootVolume) {
 	*out = *in
@@ -1565,6 +1841,11 @@ func (in *NodePool) DeepCopyObject() runtime.Object {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *NodePoolAutoScaling) DeepCopyInto(out *NodePoolAutoScaling) {
 	*out = *in
+	if in.Capacity != nil {
+		in, out := &in.Capacity, &out.Capacity
+		*out = new(NodePoolCapacity)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodePoolAutoScaling.
@@ -1577,6 +1858,31 @@ func (in *NodePoolAutoScaling) DeepCopy() *NodePoolAutoScaling {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodePoolCapacity) DeepCopyInto(out *NodePoolCapacity) {
+	*out = *in
+	if in.CPU != nil {
+		in, out := &in.CPU, &out.CPU
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.Memory != nil {
+		in, out := &in.Memory, &out.Memory
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodePoolCapacity.
+func (in *NodePoolCapacity) DeepCopy() *NodePoolCapacity {
+	if in == nil {
+		return nil
+	}
+	out := new(NodePoolCapacity)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *NodePoolCondition) DeepCopyInto(out *NodePoolCondition) {
 	*out = *in
@@ -1593,6 +1899,119 @@ func (in *NodePoolCondition) DeepCopy() *NodePoolCondition {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodePoolConfigStatus) DeepCopyInto(out *NodePoolConfigStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodePoolConfigStatus.
+func (in *NodePoolConfigStatus) DeepCopy() *NodePoolConfigStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(NodePoolConfigStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodePoolGPU) DeepCopyInto(out *NodePoolGPU) {
+	*out = *in
+	if in.Drivers != nil {
+		in, out := &in.Drivers, &out.Drivers
+		*out = new(NodePoolGPUDrivers)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodePoolGPU.
+func (in *NodePoolGPU) DeepCopy() *NodePoolGPU {
+	if in == nil {
+		return nil
+	}
+	out := new(NodePoolGPU)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodePoolGPUDrivers) DeepCopyInto(out *NodePoolGPUDrivers) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodePoolGPUDrivers.
+func (in *NodePoolGPUDrivers) DeepCopy() *NodePoolGPUDrivers {
+	if in == nil {
+		return nil
+	}
+	out := new(NodePoolGPUDrivers)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodePoolHugePages) DeepCopyInto(out *NodePoolHugePages) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodePoolHugePages.
+func (in *NodePoolHugePages) DeepCopy() *NodePoolHugePages {
+	if in == nil {
+		return nil
+	}
+	out := new(NodePoolHugePages)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodePoolKubeletConfig) DeepCopyInto(out *NodePoolKubeletConfig) {
+	*out = *in
+	if in.MaxPods != nil {
+		in, out := &in.MaxPods, &out.MaxPods
+		*out = new(int32)
+		**out = **in
+	}
+	if in.SystemReserved != nil {
+		in, out := &in.SystemReserved, &out.SystemReserved
+		*out = make(corev1.ResourceList, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val.DeepCopy()
+		}
+	}
+	if in.EvictionHard != nil {
+		in, out := &in.EvictionHard, &out.EvictionHard
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.EvictionSoft != nil {
+		in, out := &in.EvictionSoft, &out.EvictionSoft
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.EvictionSoftGracePeriod != nil {
+		in, out := &in.EvictionSoftGracePeriod, &out.EvictionSoftGracePeriod
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodePoolKubeletConfig.
+func (in *NodePoolKubeletConfig) DeepCopy() *NodePoolKubeletConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(NodePoolKubeletConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *NodePoolList) DeepCopyInto(out *NodePoolList) {
 	*out = *in
@@ -1636,7 +2055,12 @@ func (in *NodePoolManagement) DeepCopyInto(out *NodePoolManagement) {
 	if in.InPlace != nil {
 		in, out := &in.InPlace, &out.InPlace
 		*out = new(InPlaceUpgrade)
-		**out = **in
+		(*in).DeepCopyInto(*out)
+	}
+	if in.AutoRepairConfig != nil {
+		in, out := &in.AutoRepairConfig, &out.AutoRepairConfig
+		*out = new(AutoRepairConfig)
+		(*in).DeepCopyInto(*out)
 	}
 }
 
@@ -1650,6 +2074,26 @@ func (in *NodePoolManagement) DeepCopy() *NodePoolManagement {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodePoolPerformanceProfile) DeepCopyInto(out *NodePoolPerformanceProfile) {
+	*out = *in
+	if in.HugePages != nil {
+		in, out := &in.HugePages, &out.HugePages
+		*out = make([]NodePoolHugePages, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodePoolPerformanceProfile.
+func (in *NodePoolPerformanceProfile) DeepCopy() *NodePoolPerformanceProfile {
+	if in == nil {
+		return nil
+	}
+	out := new(NodePoolPerformanceProfile)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *NodePoolPlatform) DeepCopyInto(out *NodePoolPlatform) {
 	*out = *in
@@ -1683,6 +2127,31 @@ func (in *NodePoolPlatform) DeepCopyInto(out *NodePoolPlatform) {
 		*out = new(PowerVSNodePoolPlatform)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.IBMCloudVPC != nil {
+		in, out := &in.IBMCloudVPC, &out.IBMCloudVPC
+		*out = new(IBMCloudVPCNodePoolPlatform)
+		**out = **in
+	}
+	if in.GCP != nil {
+		in, out := &in.GCP, &out.GCP
+		*out = new(GCPNodePoolPlatform)
+		**out = **in
+	}
+	if in.OpenStack != nil {
+		in, out := &in.OpenStack, &out.OpenStack
+		*out = new(OpenStackNodePoolPlatform)
+		**out = **in
+	}
+	if in.Nutanix != nil {
+		in, out := &in.Nutanix, &out.Nutanix
+		*out = new(NutanixNodePoolPlatform)
+		**out = **in
+	}
+	if in.OCI != nil {
+		in, out := &in.OCI, &out.OCI
+		*out = new(OCINodePoolPlatform)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodePoolPlatform.
@@ -1714,18 +2183,57 @@ func (in *NodePoolSpec) DeepCopyInto(out *NodePoolSpec) {
 	if in.AutoScaling != nil {
 		in, out := &in.AutoScaling, &out.AutoScaling
 		*out = new(NodePoolAutoScaling)
-		**out = **in
+		(*in).DeepCopyInto(*out)
 	}
 	if in.Config != nil {
 		in, out := &in.Config, &out.Config
 		*out = make([]corev1.LocalObjectReference, len(*in))
 		copy(*out, *in)
 	}
+	if in.KubeletConfig != nil {
+		in, out := &in.KubeletConfig, &out.KubeletConfig
+		*out = new(NodePoolKubeletConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PerformanceProfile != nil {
+		in, out := &in.PerformanceProfile, &out.PerformanceProfile
+		*out = new(NodePoolPerformanceProfile)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.RegistryMirrors != nil {
+		in, out := &in.RegistryMirrors, &out.RegistryMirrors
+		*out = make([]ImageContentSource, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.PullSecret != nil {
+		in, out := &in.PullSecret, &out.PullSecret
+		*out = new(corev1.LocalObjectReference)
+		**out = **in
+	}
 	if in.NodeDrainTimeout != nil {
 		in, out := &in.NodeDrainTimeout, &out.NodeDrainTimeout
 		*out = new(v1.Duration)
 		**out = **in
 	}
+	if in.NodeLabels != nil {
+		in, out := &in.NodeLabels, &out.NodeLabels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Taints != nil {
+		in, out := &in.Taints, &out.Taints
+		*out = make([]Taint, len(*in))
+		copy(*out, *in)
+	}
+	if in.GPU != nil {
+		in, out := &in.GPU, &out.GPU
+		*out = new(NodePoolGPU)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodePoolSpec.
@@ -1741,6 +2249,16 @@ func (in *NodePoolSpec) DeepCopy() *NodePoolSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *NodePoolStatus) DeepCopyInto(out *NodePoolStatus) {
 	*out = *in
+	if in.Config != nil {
+		in, out := &in.Config, &out.Config
+		*out = new(NodePoolConfigStatus)
+		**out = **in
+	}
+	if in.Zones != nil {
+		in, out := &in.Zones, &out.Zones
+		*out = make([]NodePoolZoneStatus, len(*in))
+		copy(*out, *in)
+	}
 	if in.Conditions != nil {
 		in, out := &in.Conditions, &out.Conditions
 		*out = make([]NodePoolCondition, len(*in))
@@ -1760,6 +2278,21 @@ func (in *NodePoolStatus) DeepCopy() *NodePoolStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodePoolZoneStatus) DeepCopyInto(out *NodePoolZoneStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodePoolZoneStatus.
+func (in *NodePoolZoneStatus) DeepCopy() *NodePoolZoneStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(NodePoolZoneStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *NodePortPublishingStrategy) DeepCopyInto(out *NodePortPublishingStrategy) {
 	*out = *in
@@ -1775,6 +2308,136 @@ func (in *NodePortPublishingStrategy) DeepCopy() *NodePortPublishingStrategy {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NutanixNodePoolPlatform) DeepCopyInto(out *NutanixNodePoolPlatform) {
+	*out = *in
+	out.Cluster = in.Cluster
+	out.Subnet = in.Subnet
+	out.Image = in.Image
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NutanixNodePoolPlatform.
+func (in *NutanixNodePoolPlatform) DeepCopy() *NutanixNodePoolPlatform {
+	if in == nil {
+		return nil
+	}
+	out := new(NutanixNodePoolPlatform)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NutanixPlatformSpec) DeepCopyInto(out *NutanixPlatformSpec) {
+	*out = *in
+	out.PrismCentral = in.PrismCentral
+	out.KubeCloudControllerCreds = in.KubeCloudControllerCreds
+	out.NodePoolManagementCreds = in.NodePoolManagementCreds
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NutanixPlatformSpec.
+func (in *NutanixPlatformSpec) DeepCopy() *NutanixPlatformSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(NutanixPlatformSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NutanixPrismEndpoint) DeepCopyInto(out *NutanixPrismEndpoint) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NutanixPrismEndpoint.
+func (in *NutanixPrismEndpoint) DeepCopy() *NutanixPrismEndpoint {
+	if in == nil {
+		return nil
+	}
+	out := new(NutanixPrismEndpoint)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NutanixResourceReference) DeepCopyInto(out *NutanixResourceReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NutanixResourceReference.
+func (in *NutanixResourceReference) DeepCopy() *NutanixResourceReference {
+	if in == nil {
+		return nil
+	}
+	out := new(NutanixResourceReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OCINodePoolPlatform) DeepCopyInto(out *OCINodePoolPlatform) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OCINodePoolPlatform.
+func (in *OCINodePoolPlatform) DeepCopy() *OCINodePoolPlatform {
+	if in == nil {
+		return nil
+	}
+	out := new(OCINodePoolPlatform)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OCIPlatformSpec) DeepCopyInto(out *OCIPlatformSpec) {
+	*out = *in
+	out.KubeCloudControllerCreds = in.KubeCloudControllerCreds
+	out.NodePoolManagementCreds = in.NodePoolManagementCreds
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OCIPlatformSpec.
+func (in *OCIPlatformSpec) DeepCopy() *OCIPlatformSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(OCIPlatformSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OpenStackNodePoolPlatform) DeepCopyInto(out *OpenStackNodePoolPlatform) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OpenStackNodePoolPlatform.
+func (in *OpenStackNodePoolPlatform) DeepCopy() *OpenStackNodePoolPlatform {
+	if in == nil {
+		return nil
+	}
+	out := new(OpenStackNodePoolPlatform)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OpenStackPlatformSpec) DeepCopyInto(out *OpenStackPlatformSpec) {
+	*out = *in
+	out.KubeCloudControllerCreds = in.KubeCloudControllerCreds
+	out.NodePoolManagementCreds = in.NodePoolManagementCreds
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OpenStackPlatformSpec.
+func (in *OpenStackPlatformSpec) DeepCopy() *OpenStackPlatformSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(OpenStackPlatformSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PersistentVolumeEtcdStorageSpec) DeepCopyInto(out *PersistentVolumeEtcdStorageSpec) {
 	*out = *in
@@ -1823,11 +2486,41 @@ func (in *PlatformSpec) DeepCopyInto(out *PlatformSpec) {
 		*out = new(AzurePlatformSpec)
 		**out = **in
 	}
+	if in.Kubevirt != nil {
+		in, out := &in.Kubevirt, &out.Kubevirt
+		*out = new(KubevirtPlatformSpec)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.PowerVS != nil {
 		in, out := &in.PowerVS, &out.PowerVS
 		*out = new(PowerVSPlatformSpec)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.IBMCloudVPC != nil {
+		in, out := &in.IBMCloudVPC, &out.IBMCloudVPC
+		*out = new(IBMCloudVPCPlatformSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.GCP != nil {
+		in, out := &in.GCP, &out.GCP
+		*out = new(GCPPlatformSpec)
+		**out = **in
+	}
+	if in.OpenStack != nil {
+		in, out := &in.OpenStack, &out.OpenStack
+		*out = new(OpenStackPlatformSpec)
+		**out = **in
+	}
+	if in.Nutanix != nil {
+		in, out := &in.Nutanix, &out.Nutanix
+		*out = new(NutanixPlatformSpec)
+		**out = **in
+	}
+	if in.OCI != nil {
+		in, out := &in.OCI, &out.OCI
+		*out = new(OCIPlatformSpec)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PlatformSpec.
@@ -1844,6 +2537,11 @@ func (in *PlatformSpec) DeepCopy() *PlatformSpec {
 func (in *PowerVSNodePoolPlatform) DeepCopyInto(out *PowerVSNodePoolPlatform) {
 	*out = *in
 	out.Processors = in.Processors
+	if in.SMTLevel != nil {
+		in, out := &in.SMTLevel, &out.SMTLevel
+		*out = new(int32)
+		**out = **in
+	}
 	if in.Image != nil {
 		in, out := &in.Image, &out.Image
 		*out = new(PowerVSResourceReference)
@@ -2092,6 +2790,37 @@ func (in *ServicePublishingStrategyMapping) DeepCopy() *ServicePublishingStrateg
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Taint) DeepCopyInto(out *Taint) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Taint.
+func (in *Taint) DeepCopy() *Taint {
+	if in == nil {
+		return nil
+	}
+	out := new(Taint)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UnhealthyCondition) DeepCopyInto(out *UnhealthyCondition) {
+	*out = *in
+	out.Timeout = in.Timeout
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UnhealthyCondition.
+func (in *UnhealthyCondition) DeepCopy() *UnhealthyCondition {
+	if in == nil {
+		return nil
+	}
+	out := new(UnhealthyCondition)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *UnmanagedEtcdSpec) DeepCopyInto(out *UnmanagedEtcdSpec) {
 	*out = *in
@@ -2111,6 +2840,16 @@ func (in *UnmanagedEtcdSpec) DeepCopy() *UnmanagedEtcdSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Volume) DeepCopyInto(out *Volume) {
 	*out = *in
+	if in.Throughput != nil {
+		in, out := &in.Throughput, &out.Throughput
+		*out = new(int64)
+		**out = **in
+	}
+	if in.Encrypted != nil {
+		in, out := &in.Encrypted, &out.Encrypted
+		*out = new(bool)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Volume.
@@ -23,15 +23,17 @@ import (
 )
 
 type CreateBastionOpts struct {
-	Namespace          string
-	Name               string
-	InfraID            string
-	Region             string
-	SSHKeyFile         string
-	AWSCredentialsFile string
-	AWSKey             string
-	AWSSecretKey       string
-	Wait               bool
+	Namespace            string
+	Name                 string
+	InfraID              string
+	Region               string
+	SSHKeyFile           string
+	AWSCredentialsFile   string
+	WebIdentityTokenFile string
+	WebIdentityRoleARN   string
+	AWSKey               string
+	AWSSecretKey         string
+	Wait                 bool
 }
 
 func NewCreateCommand() *cobra.Command {
@@ -50,11 +52,11 @@ func NewCreateCommand() *cobra.Command {
 	cmd.Flags().StringVar(&opts.InfraID, "infra-id", opts.InfraID, "The infra ID to use for creating the bastion")
 	cmd.Flags().StringVar(&opts.Region, "region", opts.Region, "The region to use for creating the bastion")
 	cmd.Flags().StringVar(&opts.SSHKeyFile, "ssh-key-file", opts.SSHKeyFile, "File with public SSH key to use for bastion instance")
-	cmd.Flags().StringVar(&opts.AWSCredentialsFile, "aws-creds", opts.AWSCredentialsFile, "File with AWS credentials")
+	cmd.Flags().StringVar(&opts.AWSCredentialsFile, "aws-creds", opts.AWSCredentialsFile, "File with AWS credentials (required unless --web-identity-token-file is specified)")
+	cmd.Flags().StringVar(&opts.WebIdentityTokenFile, "web-identity-token-file", opts.WebIdentityTokenFile, "Path to a web identity token, e.g. a projected service account token, to assume the role given by --web-identity-role-arn with instead of using --aws-creds. The token is read again whenever the temporary credentials are refreshed")
+	cmd.Flags().StringVar(&opts.WebIdentityRoleARN, "web-identity-role-arn", opts.WebIdentityRoleARN, "The ARN of the role to assume with the token given by --web-identity-token-file")
 	cmd.Flags().BoolVar(&opts.Wait, "wait", opts.Wait, "Wait for instance to be running")
 
-	cmd.MarkFlagRequired("aws-creds")
-
 	cmd.MarkFlagFilename("ssh-key-file")
 	cmd.MarkFlagFilename("aws-creds")
 
@@ -77,6 +79,9 @@ func NewCreateCommand() *cobra.Command {
 }
 
 func (o *CreateBastionOpts) Validate() error {
+	if err := awsutil.ValidateCredentials(o.AWSCredentialsFile, o.AWSKey, o.WebIdentityTokenFile, o.WebIdentityRoleARN); err != nil {
+		return err
+	}
 	if len(o.Name) > 0 {
 		if len(o.Namespace) == 0 {
 			return fmt.Errorf("a namespace must be specified if specifying a hosted cluster name")
@@ -143,7 +148,7 @@ func (o *CreateBastionOpts) Run(ctx context.Context) (string, string, error) {
 		}
 	}
 
-	awsSession := awsutil.NewSession("cli-create-bastion", o.AWSCredentialsFile, o.AWSKey, o.AWSSecretKey, region)
+	awsSession := awsutil.NewSessionWithWebIdentity("cli-create-bastion", o.AWSCredentialsFile, o.AWSKey, o.AWSSecretKey, o.WebIdentityTokenFile, o.WebIdentityRoleARN, region)
 	awsConfig := awsutil.NewConfig()
 	ec2Client := ec2.New(awsSession, awsConfig)
 
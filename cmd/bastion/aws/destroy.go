@@ -18,13 +18,15 @@ import (
 )
 
 type DestroyBastionOpts struct {
-	Namespace          string
-	Name               string
-	InfraID            string
-	Region             string
-	AWSCredentialsFile string
-	AWSKey             string
-	AWSSecretKey       string
+	Namespace            string
+	Name                 string
+	InfraID              string
+	Region               string
+	AWSCredentialsFile   string
+	WebIdentityTokenFile string
+	WebIdentityRoleARN   string
+	AWSKey               string
+	AWSSecretKey         string
 }
 
 func NewDestroyCommand() *cobra.Command {
@@ -41,9 +43,10 @@ func NewDestroyCommand() *cobra.Command {
 	cmd.Flags().StringVar(&opts.Name, "name", opts.Name, "The name of the hostedcluster")
 	cmd.Flags().StringVar(&opts.InfraID, "infra-id", opts.InfraID, "The infra ID to use for creating the bastion")
 	cmd.Flags().StringVar(&opts.Region, "region", opts.Region, "The region to use for creating the bastion")
-	cmd.Flags().StringVar(&opts.AWSCredentialsFile, "aws-creds", opts.AWSCredentialsFile, "File with AWS credentials")
+	cmd.Flags().StringVar(&opts.AWSCredentialsFile, "aws-creds", opts.AWSCredentialsFile, "File with AWS credentials (required unless --web-identity-token-file is specified)")
+	cmd.Flags().StringVar(&opts.WebIdentityTokenFile, "web-identity-token-file", opts.WebIdentityTokenFile, "Path to a web identity token, e.g. a projected service account token, to assume the role given by --web-identity-role-arn with instead of using --aws-creds. The token is read again whenever the temporary credentials are refreshed")
+	cmd.Flags().StringVar(&opts.WebIdentityRoleARN, "web-identity-role-arn", opts.WebIdentityRoleARN, "The ARN of the role to assume with the token given by --web-identity-token-file")
 
-	cmd.MarkFlagRequired("aws-creds")
 	cmd.MarkFlagFilename("aws-creds")
 
 	cmd.RunE = func(cmd *cobra.Command, args []string) error {
@@ -65,6 +68,9 @@ func NewDestroyCommand() *cobra.Command {
 }
 
 func (o *DestroyBastionOpts) Validate() error {
+	if err := awsutil.ValidateCredentials(o.AWSCredentialsFile, o.AWSKey, o.WebIdentityTokenFile, o.WebIdentityRoleARN); err != nil {
+		return err
+	}
 	if len(o.Name) > 0 {
 		if len(o.Namespace) == 0 {
 			return fmt.Errorf("a namespace must be specified if specifying a hosted cluster name")
@@ -107,7 +113,7 @@ func (o *DestroyBastionOpts) Run(ctx context.Context) error {
 		region = o.Region
 	}
 
-	awsSession := awsutil.NewSession("cli-destroy-bastion", o.AWSCredentialsFile, o.AWSKey, o.AWSSecretKey, region)
+	awsSession := awsutil.NewSessionWithWebIdentity("cli-destroy-bastion", o.AWSCredentialsFile, o.AWSKey, o.AWSSecretKey, o.WebIdentityTokenFile, o.WebIdentityRoleARN, region)
 	awsConfig := awsutil.NewConfig()
 	ec2Client := ec2.New(awsSession, awsConfig)
 
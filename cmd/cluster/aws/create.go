@@ -32,7 +32,9 @@ func NewCreateCommand(opts *core.CreateOptions) *cobra.Command {
 		EndpointAccess:     string(hyperv1.Public),
 	}
 
-	cmd.Flags().StringVar(&opts.AWSPlatform.AWSCredentialsFile, "aws-creds", opts.AWSPlatform.AWSCredentialsFile, "Path to an AWS credentials file (required)")
+	cmd.Flags().StringVar(&opts.AWSPlatform.AWSCredentialsFile, "aws-creds", opts.AWSPlatform.AWSCredentialsFile, "Path to an AWS credentials file (required unless --web-identity-token-file is specified)")
+	cmd.Flags().StringVar(&opts.AWSPlatform.WebIdentityTokenFile, "web-identity-token-file", opts.AWSPlatform.WebIdentityTokenFile, "Path to a web identity token, e.g. a projected service account token, to assume the role given by --web-identity-role-arn with instead of using --aws-creds. The token is read again whenever the temporary credentials are refreshed")
+	cmd.Flags().StringVar(&opts.AWSPlatform.WebIdentityRoleARN, "web-identity-role-arn", opts.AWSPlatform.WebIdentityRoleARN, "The ARN of the role to assume with the token given by --web-identity-token-file")
 	cmd.Flags().StringVar(&opts.AWSPlatform.IAMJSON, "iam-json", opts.AWSPlatform.IAMJSON, "Path to file containing IAM information for the cluster. If not specified, IAM will be created")
 	cmd.Flags().StringVar(&opts.AWSPlatform.Region, "region", opts.AWSPlatform.Region, "Region to use for AWS infrastructure.")
 	cmd.Flags().StringSliceVar(&opts.AWSPlatform.Zones, "zones", opts.AWSPlatform.Zones, "The availablity zones in which NodePools will be created")
@@ -40,12 +42,17 @@ func NewCreateCommand(opts *core.CreateOptions) *cobra.Command {
 	cmd.Flags().StringVar(&opts.AWSPlatform.RootVolumeType, "root-volume-type", opts.AWSPlatform.RootVolumeType, "The type of the root volume (e.g. gp3, io2) for machines in the NodePool")
 	cmd.Flags().Int64Var(&opts.AWSPlatform.RootVolumeIOPS, "root-volume-iops", opts.AWSPlatform.RootVolumeIOPS, "The iops of the root volume when specifying type:io1 for machines in the NodePool")
 	cmd.Flags().Int64Var(&opts.AWSPlatform.RootVolumeSize, "root-volume-size", opts.AWSPlatform.RootVolumeSize, "The size of the root volume (min: 8) for machines in the NodePool")
+	cmd.Flags().Int64Var(&opts.AWSPlatform.RootVolumeThroughput, "root-volume-throughput", opts.AWSPlatform.RootVolumeThroughput, "The throughput in MiB/s of the root volume when specifying type:gp3 for machines in the NodePool")
+	cmd.Flags().StringVar(&opts.AWSPlatform.RootVolumeKMSKey, "root-volume-kms-key", opts.AWSPlatform.RootVolumeKMSKey, "The ID or ARN of the KMS key to encrypt the root volume of machines in the NodePool with. If not supplied, the KMS key of the infrastructure is used if there is one.")
 	cmd.Flags().StringSliceVar(&opts.AWSPlatform.AdditionalTags, "additional-tags", opts.AWSPlatform.AdditionalTags, "Additional tags to set on AWS resources")
 	cmd.Flags().StringVar(&opts.AWSPlatform.EndpointAccess, "endpoint-access", opts.AWSPlatform.EndpointAccess, "Access for control plane endpoints (Public, PublicAndPrivate, Private)")
 	cmd.Flags().StringVar(&opts.AWSPlatform.EtcdKMSKeyARN, "kms-key-arn", opts.AWSPlatform.EtcdKMSKeyARN, "The ARN of the KMS key to use for Etcd encryption. If not supplied, etcd encryption will default to using a generated AESCBC key.")
+	cmd.Flags().BoolVar(&opts.AWSPlatform.CreateKMSKey, "create-kms-key", opts.AWSPlatform.CreateKMSKey, "If true, a KMS key is created with the infrastructure and used for etcd encryption unless --kms-key-arn is specified")
 	cmd.Flags().BoolVar(&opts.AWSPlatform.EnableProxy, "enable-proxy", opts.AWSPlatform.EnableProxy, "If a proxy should be set up, rather than allowing direct internet access from the nodes")
-
-	cmd.MarkFlagRequired("aws-creds")
+	cmd.Flags().StringVar(&opts.AWSPlatform.NATTopology, "nat-topology", awsinfra.NATTopologyPerZone, fmt.Sprintf("The NAT gateways to create for egress from the private subnets, one of %q (one per zone), %q (one shared by all zones) or %q (no internet egress). Ignored with --enable-proxy", awsinfra.NATTopologyPerZone, awsinfra.NATTopologySingle, awsinfra.NATTopologyNone))
+	cmd.Flags().BoolVar(&opts.AWSPlatform.EnableIPv6, "enable-ipv6", opts.AWSPlatform.EnableIPv6, "If true, the infrastructure is created with dual-stack subnets and the IPv6 CIDR of the VPC is added to the machine network")
+	cmd.Flags().StringVar(&opts.AWSPlatform.PermissionsBoundaryARN, "permissions-boundary-arn", opts.AWSPlatform.PermissionsBoundaryARN, "The ARN of a managed policy to set as the permissions boundary of all created roles. Ignored with --iam-json")
+	cmd.Flags().StringVar(&opts.AWSPlatform.IAMPath, "iam-path", opts.AWSPlatform.IAMPath, "The path to create the roles and the instance profile under, e.g. /hypershift/. It must begin and end with a slash. Ignored with --iam-json")
 
 	cmd.RunE = func(cmd *cobra.Command, args []string) error {
 		ctx := cmd.Context()
@@ -103,15 +110,22 @@ func applyPlatformSpecificsValues(ctx context.Context, exampleOptions *apifixtur
 			infraID = infraid.New(opts.Name)
 		}
 		opt := awsinfra.CreateInfraOptions{
-			Region:             opts.AWSPlatform.Region,
-			InfraID:            infraID,
-			AWSCredentialsFile: opts.AWSPlatform.AWSCredentialsFile,
-			Name:               opts.Name,
-			BaseDomain:         opts.BaseDomain,
-			AdditionalTags:     opts.AWSPlatform.AdditionalTags,
-			Zones:              opts.AWSPlatform.Zones,
-			EnableProxy:        opts.AWSPlatform.EnableProxy,
-			SSHKeyFile:         opts.SSHKeyFile,
+			Region:               opts.AWSPlatform.Region,
+			InfraID:              infraID,
+			AWSCredentialsFile:   opts.AWSPlatform.AWSCredentialsFile,
+			WebIdentityTokenFile: opts.AWSPlatform.WebIdentityTokenFile,
+			WebIdentityRoleARN:   opts.AWSPlatform.WebIdentityRoleARN,
+			Name:                 opts.Name,
+			BaseDomain:           opts.BaseDomain,
+			AdditionalTags:       opts.AWSPlatform.AdditionalTags,
+			Zones:                opts.AWSPlatform.Zones,
+			EnableProxy:          opts.AWSPlatform.EnableProxy,
+			SSHKeyFile:           opts.SSHKeyFile,
+			ClusterCIDR:          opts.ClusterCIDR,
+			ServiceCIDR:          opts.ServiceCIDR,
+			EnableIPv6:           opts.AWSPlatform.EnableIPv6,
+			CreateKMSKey:         opts.AWSPlatform.CreateKMSKey,
+			NATTopology:          opts.AWSPlatform.NATTopology,
 		}
 		infra, err = opt.CreateInfra(ctx, opts.Log)
 		if err != nil {
@@ -130,16 +144,24 @@ func applyPlatformSpecificsValues(ctx context.Context, exampleOptions *apifixtur
 			return fmt.Errorf("failed to load infra json: %w", err)
 		}
 	} else {
+		etcdKMSKeyARN := opts.AWSPlatform.EtcdKMSKeyARN
+		if len(etcdKMSKeyARN) == 0 {
+			etcdKMSKeyARN = infra.KMSKeyARN
+		}
 		opt := awsinfra.CreateIAMOptions{
-			Region:             opts.AWSPlatform.Region,
-			AWSCredentialsFile: opts.AWSPlatform.AWSCredentialsFile,
-			InfraID:            infra.InfraID,
-			IssuerURL:          opts.AWSPlatform.IssuerURL,
-			AdditionalTags:     opts.AWSPlatform.AdditionalTags,
-			PrivateZoneID:      infra.PrivateZoneID,
-			PublicZoneID:       infra.PublicZoneID,
-			LocalZoneID:        infra.LocalZoneID,
-			KMSKeyARN:          opts.AWSPlatform.EtcdKMSKeyARN,
+			Region:                 opts.AWSPlatform.Region,
+			AWSCredentialsFile:     opts.AWSPlatform.AWSCredentialsFile,
+			WebIdentityTokenFile:   opts.AWSPlatform.WebIdentityTokenFile,
+			WebIdentityRoleARN:     opts.AWSPlatform.WebIdentityRoleARN,
+			InfraID:                infra.InfraID,
+			IssuerURL:              opts.AWSPlatform.IssuerURL,
+			AdditionalTags:         opts.AWSPlatform.AdditionalTags,
+			PrivateZoneID:          infra.PrivateZoneID,
+			PublicZoneID:           infra.PublicZoneID,
+			LocalZoneID:            infra.LocalZoneID,
+			KMSKeyARN:              etcdKMSKeyARN,
+			PermissionsBoundaryARN: opts.AWSPlatform.PermissionsBoundaryARN,
+			IAMPath:                opts.AWSPlatform.IAMPath,
 		}
 		iamInfo, err = opt.CreateIAM(ctx, client)
 		if err != nil {
@@ -158,6 +180,7 @@ func applyPlatformSpecificsValues(ctx context.Context, exampleOptions *apifixtur
 
 	exampleOptions.BaseDomain = infra.BaseDomain
 	exampleOptions.MachineCIDR = infra.MachineCIDR
+	exampleOptions.MachineIPv6CIDR = infra.IPv6CIDR
 	exampleOptions.IssuerURL = iamInfo.IssuerURL
 	exampleOptions.PrivateZoneID = infra.PrivateZoneID
 	exampleOptions.PublicZoneID = infra.PublicZoneID
@@ -171,21 +194,33 @@ func applyPlatformSpecificsValues(ctx context.Context, exampleOptions *apifixtur
 		})
 	}
 	exampleOptions.AWS = &apifixtures.ExampleAWSOptions{
-		Region:             infra.Region,
-		Zones:              zones,
-		VPCID:              infra.VPCID,
-		SecurityGroupID:    infra.SecurityGroupID,
-		InstanceProfile:    iamInfo.ProfileName,
-		InstanceType:       opts.AWSPlatform.InstanceType,
-		Roles:              iamInfo.Roles,
-		KMSProviderRoleARN: iamInfo.KMSProviderRoleARN,
-		KMSKeyARN:          iamInfo.KMSKeyARN,
-		RootVolumeSize:     opts.AWSPlatform.RootVolumeSize,
-		RootVolumeType:     opts.AWSPlatform.RootVolumeType,
-		RootVolumeIOPS:     opts.AWSPlatform.RootVolumeIOPS,
-		ResourceTags:       tags,
-		EndpointAccess:     opts.AWSPlatform.EndpointAccess,
-		ProxyAddress:       infra.ProxyAddr,
+		Region:               infra.Region,
+		Zones:                zones,
+		VPCID:                infra.VPCID,
+		SecurityGroupID:      infra.SecurityGroupID,
+		InstanceProfile:      iamInfo.ProfileName,
+		InstanceType:         opts.AWSPlatform.InstanceType,
+		Roles:                iamInfo.Roles,
+		KMSProviderRoleARN:   iamInfo.KMSProviderRoleARN,
+		KMSKeyARN:            iamInfo.KMSKeyARN,
+		RootVolumeSize:       opts.AWSPlatform.RootVolumeSize,
+		RootVolumeType:       opts.AWSPlatform.RootVolumeType,
+		RootVolumeIOPS:       opts.AWSPlatform.RootVolumeIOPS,
+		RootVolumeThroughput: opts.AWSPlatform.RootVolumeThroughput,
+		RootVolumeKMSKey:     rootVolumeKMSKey(opts, infra),
+		ResourceTags:         tags,
+		EndpointAccess:       opts.AWSPlatform.EndpointAccess,
+		ProxyAddress:         infra.ProxyAddr,
 	}
 	return nil
 }
+
+// rootVolumeKMSKey returns the KMS key to encrypt the root volumes of the
+// NodePool with, which defaults to the KMS key of the infrastructure like the
+// etcd encryption key does.
+func rootVolumeKMSKey(opts *core.CreateOptions, infra *awsinfra.CreateInfraOutput) string {
+	if len(opts.AWSPlatform.RootVolumeKMSKey) > 0 {
+		return opts.AWSPlatform.RootVolumeKMSKey
+	}
+	return infra.KMSKeyARN
+}
@@ -0,0 +1,43 @@
+package aws
+
+import (
+	"testing"
+
+	"github.com/openshift/hypershift/cmd/cluster/core"
+	awsinfra "github.com/openshift/hypershift/cmd/infra/aws"
+)
+
+func TestRootVolumeKMSKey(t *testing.T) {
+	testCases := []struct {
+		name     string
+		opts     *core.CreateOptions
+		infra    *awsinfra.CreateInfraOutput
+		expected string
+	}{
+		{
+			name:     "Configured key is used",
+			opts:     &core.CreateOptions{AWSPlatform: core.AWSPlatformOptions{RootVolumeKMSKey: "root-volume-key"}},
+			infra:    &awsinfra.CreateInfraOutput{KMSKeyARN: "infra-key"},
+			expected: "root-volume-key",
+		},
+		{
+			name:     "Defaults to the infra key",
+			opts:     &core.CreateOptions{},
+			infra:    &awsinfra.CreateInfraOutput{KMSKeyARN: "infra-key"},
+			expected: "infra-key",
+		},
+		{
+			name:     "No key",
+			opts:     &core.CreateOptions{},
+			infra:    &awsinfra.CreateInfraOutput{},
+			expected: "",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if actual := rootVolumeKMSKey(tc.opts, tc.infra); actual != tc.expected {
+				t.Errorf("expected %q, got %q", tc.expected, actual)
+			}
+		})
+	}
+}
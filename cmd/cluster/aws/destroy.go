@@ -25,13 +25,13 @@ func NewDestroyCommand(opts *core.DestroyOptions) *cobra.Command {
 		Region:             "us-east-1",
 	}
 
-	cmd.Flags().StringVar(&opts.AWSPlatform.AWSCredentialsFile, "aws-creds", opts.AWSPlatform.AWSCredentialsFile, "Path to an AWS credentials file (required)")
+	cmd.Flags().StringVar(&opts.AWSPlatform.AWSCredentialsFile, "aws-creds", opts.AWSPlatform.AWSCredentialsFile, "Path to an AWS credentials file (required unless --web-identity-token-file is specified)")
+	cmd.Flags().StringVar(&opts.AWSPlatform.WebIdentityTokenFile, "web-identity-token-file", opts.AWSPlatform.WebIdentityTokenFile, "Path to a web identity token, e.g. a projected service account token, to assume the role given by --web-identity-role-arn with instead of using --aws-creds. The token is read again whenever the temporary credentials are refreshed")
+	cmd.Flags().StringVar(&opts.AWSPlatform.WebIdentityRoleARN, "web-identity-role-arn", opts.AWSPlatform.WebIdentityRoleARN, "The ARN of the role to assume with the token given by --web-identity-token-file")
 	cmd.Flags().BoolVar(&opts.AWSPlatform.PreserveIAM, "preserve-iam", opts.AWSPlatform.PreserveIAM, "If true, skip deleting IAM. Otherwise destroy any default generated IAM along with other infra.")
 	cmd.Flags().StringVar(&opts.AWSPlatform.Region, "region", opts.AWSPlatform.Region, "Cluster's region; inferred from the hosted cluster by default")
 	cmd.Flags().StringVar(&opts.AWSPlatform.BaseDomain, "base-domain", opts.AWSPlatform.BaseDomain, "Cluster's base domain; inferred from the hosted cluster by default")
 
-	cmd.MarkFlagRequired("aws-creds")
-
 	cmd.RunE = func(cmd *cobra.Command, args []string) error {
 		if err := DestroyCluster(cmd.Context(), opts); err != nil {
 			log.Log.Error(err, "Failed to destroy cluster")
@@ -51,12 +51,14 @@ func destroyPlatformSpecifics(ctx context.Context, o *core.DestroyOptions) error
 
 	o.Log.Info("Destroying infrastructure", "infraID", infraID)
 	destroyInfraOpts := awsinfra.DestroyInfraOptions{
-		Region:             region,
-		InfraID:            infraID,
-		AWSCredentialsFile: o.AWSPlatform.AWSCredentialsFile,
-		Name:               o.Name,
-		BaseDomain:         baseDomain,
-		Log:                o.Log,
+		Region:               region,
+		InfraID:              infraID,
+		AWSCredentialsFile:   o.AWSPlatform.AWSCredentialsFile,
+		WebIdentityTokenFile: o.AWSPlatform.WebIdentityTokenFile,
+		WebIdentityRoleARN:   o.AWSPlatform.WebIdentityRoleARN,
+		Name:                 o.Name,
+		BaseDomain:           baseDomain,
+		Log:                  o.Log,
 	}
 	if err := destroyInfraOpts.Run(ctx); err != nil {
 		return fmt.Errorf("failed to destroy infrastructure: %w", err)
@@ -65,10 +67,12 @@ func destroyPlatformSpecifics(ctx context.Context, o *core.DestroyOptions) error
 	if !o.AWSPlatform.PreserveIAM {
 		o.Log.Info("Destroying IAM", "infraID", infraID)
 		destroyOpts := awsinfra.DestroyIAMOptions{
-			Region:             region,
-			AWSCredentialsFile: o.AWSPlatform.AWSCredentialsFile,
-			InfraID:            infraID,
-			Log:                o.Log,
+			Region:               region,
+			AWSCredentialsFile:   o.AWSPlatform.AWSCredentialsFile,
+			WebIdentityTokenFile: o.AWSPlatform.WebIdentityTokenFile,
+			WebIdentityRoleARN:   o.AWSPlatform.WebIdentityRoleARN,
+			InfraID:              infraID,
+			Log:                  o.Log,
 		}
 		if err := destroyOpts.Run(ctx); err != nil {
 			return fmt.Errorf("failed to destroy IAM: %w", err)
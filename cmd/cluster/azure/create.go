@@ -32,6 +32,8 @@ func NewCreateCommand(opts *core.CreateOptions) *cobra.Command {
 	cmd.Flags().Int32Var(&opts.AzurePlatform.DiskSizeGB, "root-disk-size", opts.AzurePlatform.DiskSizeGB, "The size of the root disk for machines in the NodePool (minimum 16)")
 	cmd.Flags().StringSliceVar(&opts.AzurePlatform.AvailabilityZones, "availablity-zones", opts.AzurePlatform.AvailabilityZones, "The availablity zones in which NodePools will be created. Must be left unspecified if the region does not support AZs. If set, one nodepool per zone will be created.")
 
+	cmd.Flags().StringVar(&opts.AzurePlatform.DiskEncryptionSetID, "disk-encryption-set-id", opts.AzurePlatform.DiskEncryptionSetID, "The ID of a DiskEncryptionSet to encrypt the disks of the nodes with a customer-managed key. Its identity must be allowed to get, wrap and unwrap the key in its Key Vault")
+
 	cmd.MarkFlagRequired("azure-creds")
 
 	cmd.Run = func(cmd *cobra.Command, args []string) {
@@ -94,17 +96,18 @@ func applyPlatformSpecificsValues(ctx context.Context, exampleOptions *apifixtur
 	exampleOptions.PrivateZoneID = infra.PrivateZoneID
 	exampleOptions.InfraID = infra.InfraID
 	exampleOptions.Azure = &apifixtures.ExampleAzureOptions{
-		Location:          infra.Location,
-		ResourceGroupName: infra.ResourceGroupName,
-		VnetName:          infra.VnetName,
-		VnetID:            infra.VNetID,
-		SubnetName:        infra.SubnetName,
-		BootImageID:       infra.BootImageID,
-		MachineIdentityID: infra.MachineIdentityID,
-		InstanceType:      opts.AzurePlatform.InstanceType,
-		SecurityGroupName: infra.SecurityGroupName,
-		DiskSizeGB:        opts.AzurePlatform.DiskSizeGB,
-		AvailabilityZones: opts.AzurePlatform.AvailabilityZones,
+		Location:            infra.Location,
+		ResourceGroupName:   infra.ResourceGroupName,
+		VnetName:            infra.VnetName,
+		VnetID:              infra.VNetID,
+		SubnetName:          infra.SubnetName,
+		BootImageID:         infra.BootImageID,
+		MachineIdentityID:   infra.MachineIdentityID,
+		InstanceType:        opts.AzurePlatform.InstanceType,
+		SecurityGroupName:   infra.SecurityGroupName,
+		DiskSizeGB:          opts.AzurePlatform.DiskSizeGB,
+		AvailabilityZones:   opts.AzurePlatform.AvailabilityZones,
+		DiskEncryptionSetID: opts.AzurePlatform.DiskEncryptionSetID,
 	}
 
 	azureCredsRaw, err := ioutil.ReadFile(opts.AzurePlatform.CredentialsFile)
@@ -114,5 +117,11 @@ func applyPlatformSpecificsValues(ctx context.Context, exampleOptions *apifixtur
 	if err := yaml.Unmarshal(azureCredsRaw, &exampleOptions.Azure.Creds); err != nil {
 		return fmt.Errorf("failed to unmarshal --azure-creds file: %w", err)
 	}
+
+	if len(opts.AzurePlatform.DiskEncryptionSetID) > 0 {
+		if err := azureinfra.ValidateDiskEncryptionSet(ctx, &exampleOptions.Azure.Creds, opts.AzurePlatform.DiskEncryptionSetID); err != nil {
+			return fmt.Errorf("invalid --disk-encryption-set-id: %w", err)
+		}
+	}
 	return nil
 }
@@ -9,6 +9,7 @@ import (
 	"github.com/openshift/hypershift/cmd/cluster/aws"
 	"github.com/openshift/hypershift/cmd/cluster/azure"
 	"github.com/openshift/hypershift/cmd/cluster/core"
+	"github.com/openshift/hypershift/cmd/cluster/ibmcloudvpc"
 	"github.com/openshift/hypershift/cmd/cluster/kubevirt"
 	"github.com/openshift/hypershift/cmd/cluster/none"
 	"github.com/openshift/hypershift/cmd/cluster/powervs"
@@ -75,6 +76,7 @@ func NewCreateCommands() *cobra.Command {
 	cmd.AddCommand(kubevirt.NewCreateCommand(opts))
 	cmd.AddCommand(azure.NewCreateCommand(opts))
 	cmd.AddCommand(powervs.NewCreateCommand(opts))
+	cmd.AddCommand(ibmcloudvpc.NewCreateCommand(opts))
 
 	return cmd
 }
@@ -106,6 +108,7 @@ func NewDestroyCommands() *cobra.Command {
 	cmd.AddCommand(kubevirt.NewDestroyCommand(opts))
 	cmd.AddCommand(azure.NewDestroyCommand(opts))
 	cmd.AddCommand(powervs.NewDestroyCommand(opts))
+	cmd.AddCommand(ibmcloudvpc.NewDestroyCommand(opts))
 
 	return cmd
 }
@@ -66,6 +66,7 @@ type CreateOptions struct {
 	AgentPlatform                    AgentPlatformCreateOptions
 	AzurePlatform                    AzurePlatformOptions
 	PowerVSPlatform                  PowerVSPlatformOptions
+	IBMCloudVPCPlatform              IBMCloudVPCPlatformOptions
 	Wait                             bool
 	Timeout                          time.Duration
 	Log                              logr.Logger
@@ -92,6 +93,18 @@ type PowerVSPlatformOptions struct {
 	ProcType   string
 	Processors string
 	Memory     int32
+	SMTLevel   int32
+}
+
+type IBMCloudVPCPlatformOptions struct {
+	APIKey        string
+	ResourceGroup string
+	Region        string
+	Zone          string
+
+	// nodepool related options
+	Profile string
+	Image   string
 }
 
 type AgentPlatformCreateOptions struct {
@@ -113,32 +126,45 @@ type KubevirtPlatformCreateOptions struct {
 	RootVolumeSize            uint32
 	RootVolumeStorageClass    string
 	RootVolumeAccessModes     string
+	InfraKubeConfigFile       string
+	InfraNamespace            string
+	AdditionalNetworks        []string
 }
 
 type AWSPlatformOptions struct {
-	AWSCredentialsFile string
-	AdditionalTags     []string
-	IAMJSON            string
-	InstanceType       string
-	IssuerURL          string
-	PrivateZoneID      string
-	PublicZoneID       string
-	Region             string
-	RootVolumeIOPS     int64
-	RootVolumeSize     int64
-	RootVolumeType     string
-	EndpointAccess     string
-	Zones              []string
-	EtcdKMSKeyARN      string
-	EnableProxy        bool
+	AWSCredentialsFile     string
+	WebIdentityTokenFile   string
+	WebIdentityRoleARN     string
+	AdditionalTags         []string
+	IAMJSON                string
+	InstanceType           string
+	IssuerURL              string
+	PrivateZoneID          string
+	PublicZoneID           string
+	Region                 string
+	RootVolumeIOPS         int64
+	RootVolumeThroughput   int64
+	RootVolumeKMSKey       string
+	RootVolumeSize         int64
+	RootVolumeType         string
+	EndpointAccess         string
+	Zones                  []string
+	EtcdKMSKeyARN          string
+	CreateKMSKey           bool
+	EnableProxy            bool
+	EnableIPv6             bool
+	NATTopology            string
+	PermissionsBoundaryARN string
+	IAMPath                string
 }
 
 type AzurePlatformOptions struct {
-	CredentialsFile   string
-	Location          string
-	InstanceType      string
-	DiskSizeGB        int32
-	AvailabilityZones []string
+	CredentialsFile     string
+	Location            string
+	InstanceType        string
+	DiskSizeGB          int32
+	AvailabilityZones   []string
+	DiskEncryptionSetID string
 }
 
 func createCommonFixture(ctx context.Context, opts *CreateOptions) (*apifixtures.ExampleOptions, error) {
@@ -26,21 +26,24 @@ const (
 type DestroyPlatformSpecifics = func(ctx context.Context, options *DestroyOptions) error
 
 type DestroyOptions struct {
-	ClusterGracePeriod time.Duration
-	Name               string
-	Namespace          string
-	AWSPlatform        AWSPlatformDestroyOptions
-	AzurePlatform      AzurePlatformDestroyOptions
-	PowerVSPlatform    PowerVSPlatformDestroyOptions
-	InfraID            string
-	Log                logr.Logger
+	ClusterGracePeriod  time.Duration
+	Name                string
+	Namespace           string
+	AWSPlatform         AWSPlatformDestroyOptions
+	AzurePlatform       AzurePlatformDestroyOptions
+	PowerVSPlatform     PowerVSPlatformDestroyOptions
+	IBMCloudVPCPlatform IBMCloudVPCPlatformDestroyOptions
+	InfraID             string
+	Log                 logr.Logger
 }
 
 type AWSPlatformDestroyOptions struct {
-	AWSCredentialsFile string
-	BaseDomain         string
-	PreserveIAM        bool
-	Region             string
+	AWSCredentialsFile   string
+	WebIdentityTokenFile string
+	WebIdentityRoleARN   string
+	BaseDomain           string
+	PreserveIAM          bool
+	Region               string
 }
 
 type AzurePlatformDestroyOptions struct {
@@ -58,6 +61,14 @@ type PowerVSPlatformDestroyOptions struct {
 	VPCRegion     string
 }
 
+type IBMCloudVPCPlatformDestroyOptions struct {
+	BaseDomain    string
+	ResourceGroup string
+	CISCRN        string
+	CISDomainID   string
+	Region        string
+}
+
 func GetCluster(ctx context.Context, o *DestroyOptions) (*hyperv1.HostedCluster, error) {
 	c, err := util.GetClient()
 	if err != nil {
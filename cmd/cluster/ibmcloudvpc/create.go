@@ -0,0 +1,158 @@
+package ibmcloudvpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"syscall"
+
+	apifixtures "github.com/openshift/hypershift/api/fixtures"
+	"github.com/openshift/hypershift/cmd/cluster/core"
+	ibmcloudvpcinfra "github.com/openshift/hypershift/cmd/infra/ibmcloudvpc"
+	powervsinfra "github.com/openshift/hypershift/cmd/infra/powervs"
+	"github.com/openshift/hypershift/support/infraid"
+	"github.com/spf13/cobra"
+)
+
+const (
+	defaultCIDRBlock = "10.0.0.0/16"
+)
+
+func NewCreateCommand(opts *core.CreateOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:          "ibmcloudvpc",
+		Short:        "Creates basic functional HostedCluster resources on IBM Cloud VPC",
+		SilenceUsage: true,
+	}
+
+	opts.IBMCloudVPCPlatform = core.IBMCloudVPCPlatformOptions{
+		Region:  "us-south",
+		Zone:    "us-south-1",
+		Profile: "bx2-4x16",
+	}
+
+	cmd.Flags().StringVar(&opts.IBMCloudVPCPlatform.ResourceGroup, "resource-group", "", "IBM Cloud Resource group")
+	cmd.Flags().StringVar(&opts.IBMCloudVPCPlatform.Region, "region", opts.IBMCloudVPCPlatform.Region, "IBM Cloud VPC region. Default is us-south")
+	cmd.Flags().StringVar(&opts.IBMCloudVPCPlatform.Zone, "zone", opts.IBMCloudVPCPlatform.Zone, "IBM Cloud VPC zone of the nodes. Default is us-south-1")
+	cmd.Flags().StringVar(&opts.IBMCloudVPCPlatform.Profile, "profile", opts.IBMCloudVPCPlatform.Profile, "Instance profile of the nodes. Default is bx2-4x16")
+	cmd.Flags().StringVar(&opts.IBMCloudVPCPlatform.Image, "image", opts.IBMCloudVPCPlatform.Image, "ID of the RHCOS custom image the nodes boot from")
+
+	cmd.MarkFlagRequired("resource-group")
+	cmd.MarkFlagRequired("image")
+
+	cmd.PreRunE = func(cmd *cobra.Command, args []string) error {
+		if opts.BaseDomain == "" {
+			return fmt.Errorf("--base-domain can't be empty")
+		}
+		return nil
+	}
+	cmd.Run = func(cmd *cobra.Command, args []string) {
+		ctx, cancel := context.WithCancel(context.Background())
+		sigs := make(chan os.Signal, 1)
+		signal.Notify(sigs, syscall.SIGINT)
+		go func() {
+			<-sigs
+			cancel()
+		}()
+
+		if err := CreateCluster(ctx, opts); err != nil {
+			opts.Log.Error(err, "Failed to create cluster")
+			os.Exit(1)
+		}
+	}
+
+	return cmd
+}
+
+func CreateCluster(ctx context.Context, opts *core.CreateOptions) error {
+	var err error
+	opts.IBMCloudVPCPlatform.APIKey, err = powervsinfra.GetAPIKey()
+	if err != nil {
+		return fmt.Errorf("error retrieving IBM Cloud API Key %w", err)
+	}
+
+	if err := validate(opts); err != nil {
+		return err
+	}
+	if err := core.Validate(ctx, opts); err != nil {
+		return err
+	}
+	return core.CreateCluster(ctx, opts, applyPlatformSpecificsValues)
+}
+
+func validate(opts *core.CreateOptions) error {
+	if opts.BaseDomain == "" {
+		return fmt.Errorf("--base-domain can't be empty")
+	}
+
+	if opts.IBMCloudVPCPlatform.Image == "" {
+		return fmt.Errorf("--image can't be empty")
+	}
+
+	if opts.IBMCloudVPCPlatform.APIKey == "" {
+		return fmt.Errorf("cloud API Key not set. Set it with IBMCLOUD_API_KEY env var or set file path containing API Key credential in IBMCLOUD_CREDENTIALS")
+	}
+
+	return nil
+}
+
+func applyPlatformSpecificsValues(ctx context.Context, exampleOptions *apifixtures.ExampleOptions, opts *core.CreateOptions) (err error) {
+	infraID := opts.InfraID
+	if len(infraID) == 0 {
+		infraID = infraid.New(opts.Name)
+	}
+
+	// Load or create infrastructure for the cluster
+	var infra *ibmcloudvpcinfra.Infra
+	if len(opts.InfrastructureJSON) > 0 {
+		rawInfra, err := ioutil.ReadFile(opts.InfrastructureJSON)
+		if err != nil {
+			return fmt.Errorf("failed to read infra json file: %w", err)
+		}
+		infra = &ibmcloudvpcinfra.Infra{}
+		if err = json.Unmarshal(rawInfra, infra); err != nil {
+			return fmt.Errorf("failed to load infra json: %w", err)
+		}
+	}
+
+	if infra == nil {
+		opt := &ibmcloudvpcinfra.CreateInfraOptions{
+			Name:          opts.Name,
+			BaseDomain:    opts.BaseDomain,
+			ResourceGroup: opts.IBMCloudVPCPlatform.ResourceGroup,
+			InfraID:       infraID,
+			Region:        opts.IBMCloudVPCPlatform.Region,
+			Zone:          opts.IBMCloudVPCPlatform.Zone,
+		}
+		infra = &ibmcloudvpcinfra.Infra{ID: infraID}
+		err = infra.SetupInfra(opt)
+		if err != nil {
+			return fmt.Errorf("failed to create infra: %w", err)
+		}
+	}
+
+	exampleOptions.BaseDomain = opts.BaseDomain
+	exampleOptions.MachineCIDR = defaultCIDRBlock
+	exampleOptions.PrivateZoneID = infra.CisDomainID
+	exampleOptions.PublicZoneID = infra.CisDomainID
+	exampleOptions.InfraID = infraID
+	exampleOptions.IBMCloudVPC = &apifixtures.ExampleIBMCloudVPCOptions{
+		ApiKey:          opts.IBMCloudVPCPlatform.APIKey,
+		AccountID:       infra.AccountID,
+		ResourceGroup:   opts.IBMCloudVPCPlatform.ResourceGroup,
+		ResourceGroupID: infra.ResourceGroupID,
+		Region:          opts.IBMCloudVPCPlatform.Region,
+		Zone:            opts.IBMCloudVPCPlatform.Zone,
+		CISInstanceCRN:  infra.CisCrn,
+		Vpc:             infra.VpcName,
+		VpcID:           infra.VpcID,
+		VpcSubnet:       infra.VpcSubnetName,
+		VpcSubnetID:     infra.VpcSubnetID,
+		Profile:         opts.IBMCloudVPCPlatform.Profile,
+		Image:           opts.IBMCloudVPCPlatform.Image,
+	}
+	return nil
+}
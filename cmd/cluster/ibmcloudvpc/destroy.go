@@ -0,0 +1,96 @@
+package ibmcloudvpc
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/spf13/cobra"
+
+	"k8s.io/apimachinery/pkg/util/errors"
+
+	"github.com/openshift/hypershift/cmd/cluster/core"
+	ibmcloudvpcinfra "github.com/openshift/hypershift/cmd/infra/ibmcloudvpc"
+	"github.com/openshift/hypershift/cmd/log"
+)
+
+func NewDestroyCommand(opts *core.DestroyOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:          "ibmcloudvpc",
+		Short:        "Destroys a HostedCluster and its resources on IBM Cloud VPC",
+		SilenceUsage: true,
+	}
+
+	opts.IBMCloudVPCPlatform = core.IBMCloudVPCPlatformDestroyOptions{
+		Region: "us-south",
+	}
+
+	cmd.Flags().StringVar(&opts.IBMCloudVPCPlatform.ResourceGroup, "resource-group", opts.IBMCloudVPCPlatform.ResourceGroup, "IBM Cloud Resource group")
+	cmd.Flags().StringVar(&opts.InfraID, "infra-id", opts.InfraID, "Cluster ID with which to tag IBM Cloud resources")
+	cmd.Flags().StringVar(&opts.IBMCloudVPCPlatform.BaseDomain, "base-domain", opts.IBMCloudVPCPlatform.BaseDomain, "Cluster's base domain")
+	cmd.Flags().StringVar(&opts.IBMCloudVPCPlatform.Region, "region", opts.IBMCloudVPCPlatform.Region, "IBM Cloud VPC region. Default is us-south")
+
+	cmd.Run = func(cmd *cobra.Command, args []string) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		sigs := make(chan os.Signal, 1)
+		signal.Notify(sigs, syscall.SIGINT)
+		go func() {
+			<-sigs
+			cancel()
+		}()
+
+		if err := DestroyCluster(ctx, opts); err != nil {
+			log.Log.Error(err, "Failed to destroy cluster")
+			os.Exit(1)
+		}
+	}
+
+	return cmd
+}
+
+func DestroyCluster(ctx context.Context, o *core.DestroyOptions) error {
+	hostedCluster, err := core.GetCluster(ctx, o)
+	if err != nil {
+		return err
+	}
+	if hostedCluster != nil {
+		o.InfraID = hostedCluster.Spec.InfraID
+		o.IBMCloudVPCPlatform.BaseDomain = hostedCluster.Spec.DNS.BaseDomain
+		o.IBMCloudVPCPlatform.ResourceGroup = hostedCluster.Spec.Platform.IBMCloudVPC.ResourceGroup
+		o.IBMCloudVPCPlatform.Region = hostedCluster.Spec.Platform.IBMCloudVPC.Region
+		o.IBMCloudVPCPlatform.CISCRN = hostedCluster.Spec.Platform.IBMCloudVPC.CISInstanceCRN
+		o.IBMCloudVPCPlatform.CISDomainID = hostedCluster.Spec.DNS.PrivateZoneID
+	}
+
+	var inputErrors []error
+	if o.InfraID == "" {
+		inputErrors = append(inputErrors, fmt.Errorf("infrastructure ID is required"))
+	}
+	if o.IBMCloudVPCPlatform.Region == "" {
+		inputErrors = append(inputErrors, fmt.Errorf("region is required"))
+	}
+	if o.IBMCloudVPCPlatform.ResourceGroup == "" {
+		inputErrors = append(inputErrors, fmt.Errorf("resource group is required"))
+	}
+	if err := errors.NewAggregate(inputErrors); err != nil {
+		return fmt.Errorf("required inputs are missing: %w", err)
+	}
+
+	return core.DestroyCluster(ctx, hostedCluster, o, destroyPlatformSpecifics)
+}
+
+func destroyPlatformSpecifics(ctx context.Context, o *core.DestroyOptions) error {
+	return (&ibmcloudvpcinfra.DestroyInfraOptions{
+		Name:          o.Name,
+		InfraID:       o.InfraID,
+		BaseDomain:    o.IBMCloudVPCPlatform.BaseDomain,
+		CISCRN:        o.IBMCloudVPCPlatform.CISCRN,
+		CISDomainID:   o.IBMCloudVPCPlatform.CISDomainID,
+		ResourceGroup: o.IBMCloudVPCPlatform.ResourceGroup,
+		Region:        o.IBMCloudVPCPlatform.Region,
+	}).Run(ctx)
+}
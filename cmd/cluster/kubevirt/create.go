@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io/ioutil"
 
 	"github.com/spf13/cobra"
 
@@ -40,6 +41,10 @@ func NewCreateCommand(opts *core.CreateOptions) *cobra.Command {
 	cmd.Flags().StringVar(&opts.KubevirtPlatform.ContainerDiskImage, "containerdisk", opts.KubevirtPlatform.ContainerDiskImage, "A reference to docker image with the embedded disk to be used to create the machines")
 	cmd.Flags().StringVar(&opts.KubevirtPlatform.ServicePublishingStrategy, "service-publishing-strategy", opts.KubevirtPlatform.ServicePublishingStrategy, fmt.Sprintf("Define how to expose the cluster services. Supported options: %s (Use LoadBalancer and Route to expose services), %s (Select a random node to expose service access through)", IngressServicePublishingStrategy, NodePortServicePublishingStrategy))
 
+	cmd.Flags().StringArrayVar(&opts.KubevirtPlatform.AdditionalNetworks, "additional-network", opts.KubevirtPlatform.AdditionalNetworks, "The name of a NetworkAttachmentDefinition, as <name> or <namespace>/<name>, to attach the machines in the NodePool to next to the pod network. Can be specified multiple times")
+	cmd.Flags().StringVar(&opts.KubevirtPlatform.InfraKubeConfigFile, "infra-kubeconfig-file", opts.KubevirtPlatform.InfraKubeConfigFile, "Path to a kubeconfig file of an external infra cluster to place the VMs on instead of the management cluster")
+	cmd.Flags().StringVar(&opts.KubevirtPlatform.InfraNamespace, "infra-namespace", opts.KubevirtPlatform.InfraNamespace, "The namespace of the external infra cluster to place the VMs in. Required with --infra-kubeconfig-file")
+
 	cmd.RunE = func(cmd *cobra.Command, args []string) error {
 		ctx := cmd.Context()
 		if opts.Timeout > 0 {
@@ -83,6 +88,19 @@ func applyPlatformSpecificsValues(ctx context.Context, exampleOptions *apifixtur
 		return fmt.Errorf("the root volume size [%d] must be greater than or equal to 8", opts.KubevirtPlatform.RootVolumeSize)
 	}
 
+	var infraKubeConfig []byte
+	if opts.KubevirtPlatform.InfraKubeConfigFile != "" {
+		if opts.KubevirtPlatform.InfraNamespace == "" {
+			return errors.New("--infra-namespace is required with --infra-kubeconfig-file")
+		}
+		infraKubeConfig, err = ioutil.ReadFile(opts.KubevirtPlatform.InfraKubeConfigFile)
+		if err != nil {
+			return fmt.Errorf("failed to read infra kubeconfig file: %w", err)
+		}
+	} else if opts.KubevirtPlatform.InfraNamespace != "" {
+		return errors.New("--infra-namespace is only supported with --infra-kubeconfig-file")
+	}
+
 	infraID := opts.InfraID
 	exampleOptions.InfraID = infraID
 
@@ -101,6 +119,9 @@ func applyPlatformSpecificsValues(ctx context.Context, exampleOptions *apifixtur
 		RootVolumeSize:            opts.KubevirtPlatform.RootVolumeSize,
 		RootVolumeStorageClass:    opts.KubevirtPlatform.RootVolumeStorageClass,
 		RootVolumeAccessModes:     opts.KubevirtPlatform.RootVolumeAccessModes,
+		AdditionalNetworks:        opts.KubevirtPlatform.AdditionalNetworks,
+		InfraKubeConfig:           infraKubeConfig,
+		InfraNamespace:            opts.KubevirtPlatform.InfraNamespace,
 	}
 	return nil
 }
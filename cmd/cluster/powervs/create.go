@@ -48,6 +48,7 @@ func NewCreateCommand(opts *core.CreateOptions) *cobra.Command {
 	cmd.Flags().StringVar(&opts.PowerVSPlatform.ProcType, "proc-type", opts.PowerVSPlatform.ProcType, "Processor type (dedicated, shared, capped). Default is shared")
 	cmd.Flags().StringVar(&opts.PowerVSPlatform.Processors, "processors", opts.PowerVSPlatform.Processors, "Number of processors allocated. Default is 0.5")
 	cmd.Flags().Int32Var(&opts.PowerVSPlatform.Memory, "memory", opts.PowerVSPlatform.Memory, "Amount of memory allocated (in GB). Default is 32")
+	cmd.Flags().Int32Var(&opts.PowerVSPlatform.SMTLevel, "smt-level", opts.PowerVSPlatform.SMTLevel, "Simultaneous multithreading level of the nodes (1, 2, 4, 8). Default is the operating system default")
 
 	cmd.MarkFlagRequired("resource-group")
 
@@ -173,6 +174,7 @@ func applyPlatformSpecificsValues(ctx context.Context, exampleOptions *apifixtur
 		ProcType:        opts.PowerVSPlatform.ProcType,
 		Processors:      opts.PowerVSPlatform.Processors,
 		Memory:          opts.PowerVSPlatform.Memory,
+		SMTLevel:        opts.PowerVSPlatform.SMTLevel,
 	}
 	return nil
 }
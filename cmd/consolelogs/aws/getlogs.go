@@ -22,12 +22,14 @@ import (
 )
 
 type ConsoleLogOpts struct {
-	Name               string
-	Namespace          string
-	AWSCredentialsFile string
-	AWSKey             string
-	AWSSecretKey       string
-	OutputDir          string
+	Name                 string
+	Namespace            string
+	AWSCredentialsFile   string
+	WebIdentityTokenFile string
+	WebIdentityRoleARN   string
+	AWSKey               string
+	AWSSecretKey         string
+	OutputDir            string
 }
 
 func NewCommand() *cobra.Command {
@@ -44,11 +46,12 @@ func NewCommand() *cobra.Command {
 
 	cmd.Flags().StringVarP(&opts.Namespace, "namespace", "n", opts.Namespace, "A cluster namespace")
 	cmd.Flags().StringVar(&opts.Name, "name", opts.Name, "A cluster name")
-	cmd.Flags().StringVar(&opts.AWSCredentialsFile, "aws-creds", opts.AWSCredentialsFile, "Path to an AWS credentials file (required)")
+	cmd.Flags().StringVar(&opts.AWSCredentialsFile, "aws-creds", opts.AWSCredentialsFile, "Path to an AWS credentials file (required unless --web-identity-token-file is specified)")
+	cmd.Flags().StringVar(&opts.WebIdentityTokenFile, "web-identity-token-file", opts.WebIdentityTokenFile, "Path to a web identity token, e.g. a projected service account token, to assume the role given by --web-identity-role-arn with instead of using --aws-creds. The token is read again whenever the temporary credentials are refreshed")
+	cmd.Flags().StringVar(&opts.WebIdentityRoleARN, "web-identity-role-arn", opts.WebIdentityRoleARN, "The ARN of the role to assume with the token given by --web-identity-token-file")
 	cmd.Flags().StringVar(&opts.OutputDir, "output-dir", opts.OutputDir, "Directory where to place console logs (required)")
 
 	cmd.MarkFlagRequired("name")
-	cmd.MarkFlagRequired("aws-creds")
 	cmd.MarkFlagRequired("output-dir")
 
 	cmd.RunE = func(cmd *cobra.Command, args []string) error {
@@ -64,6 +67,9 @@ func NewCommand() *cobra.Command {
 }
 
 func (o *ConsoleLogOpts) Run(ctx context.Context) error {
+	if err := awsutil.ValidateCredentials(o.AWSCredentialsFile, o.AWSKey, o.WebIdentityTokenFile, o.WebIdentityRoleARN); err != nil {
+		return err
+	}
 	c, err := util.GetClient()
 	if err != nil {
 		return err
@@ -75,7 +81,7 @@ func (o *ConsoleLogOpts) Run(ctx context.Context) error {
 	}
 	infraID := hostedCluster.Spec.InfraID
 	region := hostedCluster.Spec.Platform.AWS.Region
-	awsSession := awsutil.NewSession("cli-console-logs", o.AWSCredentialsFile, o.AWSKey, o.AWSSecretKey, region)
+	awsSession := awsutil.NewSessionWithWebIdentity("cli-console-logs", o.AWSCredentialsFile, o.AWSKey, o.AWSSecretKey, o.WebIdentityTokenFile, o.WebIdentityRoleARN, region)
 	awsConfig := awsutil.NewConfig()
 	ec2Client := ec2.New(awsSession, awsConfig)
 
@@ -0,0 +1,350 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/aws/aws-sdk-go/service/kms/kmsiface"
+	"github.com/aws/aws-sdk-go/service/route53"
+	"github.com/aws/aws-sdk-go/service/route53/route53iface"
+	"github.com/aws/aws-sdk-go/service/sts/stsiface"
+	"github.com/go-logr/logr"
+
+	"github.com/openshift/hypershift/cmd/util"
+)
+
+// validateAdoptOptions validates the flags for adopting existing infrastructure.
+// Adopting only discovers resources, so none of the flags that create resources
+// can be given.
+func (o *CreateInfraOptions) validateAdoptOptions() error {
+	if !o.Adopt {
+		if len(o.AdoptTags) > 0 {
+			return errors.New("--adopt-tags can only be specified together with --adopt")
+		}
+		return nil
+	}
+	if len(o.VPCID) == 0 {
+		return errors.New("--vpc-id is required with --adopt")
+	}
+	if len(o.AdoptTags) == 0 {
+		if len(o.SubnetIDs) == 0 {
+			return errors.New("--subnet-ids or --adopt-tags is required with --adopt to select the private subnets")
+		}
+		if len(o.SecurityGroupID) == 0 {
+			return errors.New("--security-group-id or --adopt-tags is required with --adopt to select the worker security group")
+		}
+	}
+	if _, err := util.ParseAWSTags(o.AdoptTags); err != nil {
+		return fmt.Errorf("invalid --adopt-tags: %w", err)
+	}
+	var unsupported []string
+	for _, flag := range []struct {
+		name string
+		set  bool
+	}{
+		{"--dry-run", o.DryRun},
+		{"--output-format", o.OutputFormat != "" && o.OutputFormat != OutputFormatJSON},
+		{"--bastion", o.Bastion},
+		{"--instance-connect-endpoint", o.InstanceConnectEndpoint},
+		{"--enable-flow-logs", o.EnableFlowLogs},
+		{"--outpost-arn", len(o.OutpostARN) > 0},
+		{"--transit-gateway-id", len(o.TransitGatewayID) > 0},
+		{"--private-link-nlb-arn", len(o.PrivateLinkNLBARN) > 0},
+		{"--create-vpc-endpoints", o.CreateVPCEndpoints},
+		{"--create-kms-key", o.CreateKMSKey},
+		{"--parent-zone-id", len(o.ParentZoneID) > 0},
+		{"--check-quotas", o.CheckQuotas},
+		{"--state-file", len(o.StateFile) > 0},
+	} {
+		if flag.set {
+			unsupported = append(unsupported, flag.name)
+		}
+	}
+	if len(unsupported) > 0 {
+		return fmt.Errorf("%s cannot be specified together with --adopt, which does not create or modify any resources", strings.Join(unsupported, ", "))
+	}
+	return nil
+}
+
+// adoptInfra discovers the existing infrastructure of a cluster in the VPC given
+// by VPCID and validates it against the requirements of a cluster, without
+// creating, modifying or tagging anything. The private subnets are those given
+// by SubnetIDs, or the subnets of the VPC with the AdoptTags that have no route
+// to an internet gateway; the subnets of the VPC that have one are the public
+// subnets of the zones. The worker security group is the one given by
+// SecurityGroupID or the only security group of the VPC with the AdoptTags. The
+// hosted zones are looked up by name unless their IDs are given.
+func (o *CreateInfraOptions) adoptInfra(ctx context.Context, l logr.Logger, ec2Client ec2iface.EC2API, route53Client, privateZoneClient route53iface.Route53API, kmsClient kmsiface.KMSAPI, stsClient stsiface.STSAPI) (*CreateInfraOutput, error) {
+	result := &CreateInfraOutput{
+		InfraID:    o.InfraID,
+		Region:     o.Region,
+		Name:       o.Name,
+		BaseDomain: o.BaseDomain,
+	}
+	var err error
+	if o.accountID, err = callerAccountID(ctx, stsClient); err != nil {
+		return nil, err
+	}
+	if err := o.validateExistingVPC(ctx, l, ec2Client); err != nil {
+		return nil, err
+	}
+	result.VPCID = o.VPCID
+	result.MachineCIDR = o.VPCCIDR
+	if result.Zones, err = o.adoptSubnets(ctx, l, ec2Client); err != nil {
+		return nil, err
+	}
+	for _, zone := range result.Zones {
+		o.Zones = append(o.Zones, zone.Name)
+	}
+	if result.SecurityGroupID, err = o.adoptSecurityGroup(ctx, l, ec2Client); err != nil {
+		return nil, err
+	}
+	if result.PublicZoneID, err = o.publicZone(ctx, route53Client, nil); err != nil {
+		return nil, err
+	}
+	if result.PrivateZoneID, err = o.adoptPrivateZone(ctx, l, privateZoneClient, fmt.Sprintf("%s.%s", o.Name, o.BaseDomain), o.PrivateZoneID); err != nil {
+		return nil, err
+	}
+	if result.LocalZoneID, err = o.adoptPrivateZone(ctx, l, privateZoneClient, fmt.Sprintf("%s.%s", o.Name, hypershiftLocalZoneName), ""); err != nil {
+		return nil, err
+	}
+	if result.KMSKeyARN, err = o.kmsKey(ctx, l, kmsClient, stsClient); err != nil {
+		return nil, err
+	}
+	if o.isSharedVPC() {
+		result.SharedVPC = o.sharedVPCOutput(result)
+	}
+	return result, nil
+}
+
+// adoptSubnets returns the zones of the private subnets with the public subnet
+// of each zone, if there is exactly one.
+func (o *CreateInfraOptions) adoptSubnets(ctx context.Context, l logr.Logger, client ec2iface.EC2API) ([]*CreateInfraOutputZone, error) {
+	tags, err := util.ParseAWSTags(o.AdoptTags)
+	if err != nil {
+		return nil, err
+	}
+	var subnets []*ec2.Subnet
+	err = client.DescribeSubnetsPagesWithContext(ctx, &ec2.DescribeSubnetsInput{Filters: append(vpcFilter(aws.String(o.VPCID)), tagFilters(tags)...)}, func(out *ec2.DescribeSubnetsOutput, _ bool) bool {
+		subnets = append(subnets, out.Subnets...)
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cannot list subnets of VPC %s: %w", o.VPCID, err)
+	}
+	if len(o.SubnetIDs) > 0 {
+		given, err := client.DescribeSubnetsWithContext(ctx, &ec2.DescribeSubnetsInput{SubnetIds: aws.StringSlice(o.SubnetIDs)})
+		if err != nil {
+			return nil, fmt.Errorf("cannot find subnets %v: %w", o.SubnetIDs, err)
+		}
+		for _, subnet := range given.Subnets {
+			if aws.StringValue(subnet.VpcId) != o.VPCID {
+				return nil, fmt.Errorf("subnet %s does not belong to VPC %s", aws.StringValue(subnet.SubnetId), o.VPCID)
+			}
+		}
+		subnets = append(subnets, given.Subnets...)
+	}
+	public, err := o.publicSubnets(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+
+	privateByZone := map[string][]string{}
+	publicByZone := map[string][]string{}
+	seen := map[string]bool{}
+	given := map[string]bool{}
+	for _, id := range o.SubnetIDs {
+		given[id] = true
+	}
+	for _, subnet := range subnets {
+		id, zone := aws.StringValue(subnet.SubnetId), aws.StringValue(subnet.AvailabilityZone)
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		switch {
+		case public[id] && given[id]:
+			return nil, fmt.Errorf("subnet %s routes to an internet gateway, --subnet-ids must be private subnets", id)
+		case public[id]:
+			publicByZone[zone] = append(publicByZone[zone], id)
+		case len(given) == 0 || given[id]:
+			privateByZone[zone] = append(privateByZone[zone], id)
+		}
+	}
+	if len(privateByZone) == 0 {
+		return nil, fmt.Errorf("no private subnets found in VPC %s", o.VPCID)
+	}
+
+	var zoneNames []string
+	for zone := range privateByZone {
+		zoneNames = append(zoneNames, zone)
+	}
+	sort.Strings(zoneNames)
+	var zones []*CreateInfraOutputZone
+	for _, zone := range zoneNames {
+		if len(privateByZone[zone]) > 1 {
+			return nil, fmt.Errorf("found private subnets %s in zone %s, only one subnet per zone is supported, select them with --subnet-ids", strings.Join(privateByZone[zone], ", "), zone)
+		}
+		outputZone := &CreateInfraOutputZone{Name: zone, SubnetID: privateByZone[zone][0]}
+		switch len(publicByZone[zone]) {
+		case 0:
+		case 1:
+			outputZone.PublicSubnetID = publicByZone[zone][0]
+		default:
+			l.Info("Found several public subnets in zone, none is used", "zone", zone, "ids", publicByZone[zone])
+		}
+		l.Info("Adopting subnets", "zone", zone, "id", outputZone.SubnetID, "publicID", outputZone.PublicSubnetID)
+		zones = append(zones, outputZone)
+	}
+	return zones, nil
+}
+
+// publicSubnets returns the subnets of the VPC whose route table has a default
+// route to an internet gateway. Subnets without an explicitly associated route
+// table use the main route table of the VPC.
+func (o *CreateInfraOptions) publicSubnets(ctx context.Context, client ec2iface.EC2API) (map[string]bool, error) {
+	var tables []*ec2.RouteTable
+	err := client.DescribeRouteTablesPagesWithContext(ctx, &ec2.DescribeRouteTablesInput{Filters: vpcFilter(aws.String(o.VPCID))}, func(out *ec2.DescribeRouteTablesOutput, _ bool) bool {
+		tables = append(tables, out.RouteTables...)
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cannot list route tables of VPC %s: %w", o.VPCID, err)
+	}
+	public := map[string]bool{}
+	explicit := map[string]bool{}
+	mainIsPublic := false
+	for _, table := range tables {
+		route := ipv4DefaultRoute(table)
+		isPublic := route != nil && strings.HasPrefix(aws.StringValue(route.GatewayId), "igw-")
+		for _, association := range table.Associations {
+			if aws.BoolValue(association.Main) {
+				mainIsPublic = isPublic
+			}
+			if subnetID := aws.StringValue(association.SubnetId); len(subnetID) > 0 {
+				explicit[subnetID] = true
+				public[subnetID] = isPublic
+			}
+		}
+	}
+	if !mainIsPublic {
+		return public, nil
+	}
+	err = client.DescribeSubnetsPagesWithContext(ctx, &ec2.DescribeSubnetsInput{Filters: vpcFilter(aws.String(o.VPCID))}, func(out *ec2.DescribeSubnetsOutput, _ bool) bool {
+		for _, subnet := range out.Subnets {
+			if id := aws.StringValue(subnet.SubnetId); !explicit[id] {
+				public[id] = true
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cannot list subnets of VPC %s: %w", o.VPCID, err)
+	}
+	return public, nil
+}
+
+// adoptSecurityGroup returns the security group given by SecurityGroupID or the
+// only security group of the VPC with the AdoptTags, after validating its rules.
+func (o *CreateInfraOptions) adoptSecurityGroup(ctx context.Context, l logr.Logger, client ec2iface.EC2API) (string, error) {
+	if len(o.SecurityGroupID) > 0 {
+		return o.workerSecurityGroup(ctx, l, client, o.VPCID)
+	}
+	tags, err := util.ParseAWSTags(o.AdoptTags)
+	if err != nil {
+		return "", err
+	}
+	var securityGroups []*ec2.SecurityGroup
+	err = client.DescribeSecurityGroupsPagesWithContext(ctx, &ec2.DescribeSecurityGroupsInput{Filters: append(vpcFilter(aws.String(o.VPCID)), tagFilters(tags)...)}, func(out *ec2.DescribeSecurityGroupsOutput, _ bool) bool {
+		for _, sg := range out.SecurityGroups {
+			if aws.StringValue(sg.GroupName) != "default" {
+				securityGroups = append(securityGroups, sg)
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return "", fmt.Errorf("cannot list security groups of VPC %s: %w", o.VPCID, err)
+	}
+	if len(securityGroups) != 1 {
+		var ids []string
+		for _, sg := range securityGroups {
+			ids = append(ids, aws.StringValue(sg.GroupId))
+		}
+		return "", fmt.Errorf("found %d security groups with the adopt tags in VPC %s %v, select the worker security group with --security-group-id", len(securityGroups), o.VPCID, ids)
+	}
+	securityGroup := securityGroups[0]
+	if err := o.validateExistingSecurityGroup(securityGroup); err != nil {
+		return "", err
+	}
+	l.Info("Adopting security group", "id", aws.StringValue(securityGroup.GroupId))
+	return aws.StringValue(securityGroup.GroupId), nil
+}
+
+// adoptPrivateZone returns the private hosted zone with the given name that is
+// associated with the VPC. If an ID is given, that zone must have the name and
+// be associated with the VPC.
+func (o *CreateInfraOptions) adoptPrivateZone(ctx context.Context, l logr.Logger, client route53iface.Route53API, name, id string) (string, error) {
+	if len(id) > 0 {
+		zone, err := getExistingZone(ctx, client, id, name, true)
+		if err != nil {
+			return "", err
+		}
+		for _, vpc := range zone.VPCs {
+			if aws.StringValue(vpc.VPCId) == o.VPCID {
+				l.Info("Adopting private zone", "name", name, "id", id)
+				return id, nil
+			}
+		}
+		return "", fmt.Errorf("private zone %s is not associated with VPC %s", id, o.VPCID)
+	}
+	var zoneID string
+	input := &route53.ListHostedZonesByVPCInput{VPCId: aws.String(o.VPCID), VPCRegion: aws.String(o.Region)}
+	for {
+		var out *route53.ListHostedZonesByVPCOutput
+		if err := retryRoute53WithBackoff(ctx, func() error {
+			var err error
+			out, err = client.ListHostedZonesByVPCWithContext(ctx, input)
+			return err
+		}); err != nil {
+			return "", fmt.Errorf("cannot list hosted zones of VPC %s: %w", o.VPCID, err)
+		}
+		for _, zone := range out.HostedZoneSummaries {
+			if strings.TrimSuffix(aws.StringValue(zone.Name), ".") == name {
+				zoneID = cleanZoneID(aws.StringValue(zone.HostedZoneId))
+			}
+		}
+		if len(zoneID) > 0 || out.NextToken == nil {
+			break
+		}
+		input.NextToken = out.NextToken
+	}
+	if len(zoneID) == 0 {
+		return "", fmt.Errorf("no private zone %s is associated with VPC %s", name, o.VPCID)
+	}
+	l.Info("Adopting private zone", "name", name, "id", zoneID)
+	return zoneID, nil
+}
+
+// tagFilters returns filters that match resources with all of the given tags.
+func tagFilters(tags map[string]string) []*ec2.Filter {
+	var keys []string
+	for key := range tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var filters []*ec2.Filter
+	for _, key := range keys {
+		filters = append(filters, &ec2.Filter{
+			Name:   aws.String(fmt.Sprintf("tag:%s", key)),
+			Values: []*string{aws.String(tags[key])},
+		})
+	}
+	return filters
+}
@@ -0,0 +1,207 @@
+package aws
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/aws/aws-sdk-go/service/route53"
+	"github.com/aws/aws-sdk-go/service/route53/route53iface"
+	"github.com/go-logr/logr"
+	. "github.com/onsi/gomega"
+)
+
+func TestValidateAdoptOptions(t *testing.T) {
+	testCases := []struct {
+		name          string
+		options       CreateInfraOptions
+		expectedError string
+	}{
+		{
+			name:    "not adopting",
+			options: CreateInfraOptions{},
+		},
+		{
+			name:          "adopt tags without adopt",
+			options:       CreateInfraOptions{AdoptTags: []string{"team=network"}},
+			expectedError: "--adopt-tags can only be specified together with --adopt",
+		},
+		{
+			name:          "no vpc",
+			options:       CreateInfraOptions{Adopt: true, AdoptTags: []string{"team=network"}},
+			expectedError: "--vpc-id is required with --adopt",
+		},
+		{
+			name:    "tags",
+			options: CreateInfraOptions{Adopt: true, VPCID: "vpc-1", AdoptTags: []string{"team=network"}},
+		},
+		{
+			name:    "subnet and security group IDs",
+			options: CreateInfraOptions{Adopt: true, VPCID: "vpc-1", SubnetIDs: []string{"subnet-1"}, SecurityGroupID: "sg-1"},
+		},
+		{
+			name:          "no security group",
+			options:       CreateInfraOptions{Adopt: true, VPCID: "vpc-1", SubnetIDs: []string{"subnet-1"}},
+			expectedError: "--security-group-id or --adopt-tags is required with --adopt to select the worker security group",
+		},
+		{
+			name:          "invalid tags",
+			options:       CreateInfraOptions{Adopt: true, VPCID: "vpc-1", AdoptTags: []string{"team"}},
+			expectedError: "invalid --adopt-tags",
+		},
+		{
+			name:          "flags that create resources",
+			options:       CreateInfraOptions{Adopt: true, VPCID: "vpc-1", AdoptTags: []string{"team=network"}, Bastion: true, CreateKMSKey: true},
+			expectedError: "--bastion, --create-kms-key cannot be specified together with --adopt",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewGomegaWithT(t)
+			err := tc.options.validateAdoptOptions()
+			if tc.expectedError == "" {
+				g.Expect(err).ToNot(HaveOccurred())
+				return
+			}
+			g.Expect(err).To(HaveOccurred())
+			g.Expect(err.Error()).To(ContainSubstring(tc.expectedError))
+		})
+	}
+}
+
+type fakeAdoptClient struct {
+	ec2iface.EC2API
+	subnets     []*ec2.Subnet
+	routeTables []*ec2.RouteTable
+}
+
+func (f *fakeAdoptClient) DescribeSubnetsPagesWithContext(_ aws.Context, _ *ec2.DescribeSubnetsInput, fn func(*ec2.DescribeSubnetsOutput, bool) bool, _ ...request.Option) error {
+	fn(&ec2.DescribeSubnetsOutput{Subnets: f.subnets}, true)
+	return nil
+}
+
+func (f *fakeAdoptClient) DescribeSubnetsWithContext(_ aws.Context, input *ec2.DescribeSubnetsInput, _ ...request.Option) (*ec2.DescribeSubnetsOutput, error) {
+	out := &ec2.DescribeSubnetsOutput{}
+	for _, subnet := range f.subnets {
+		for _, id := range input.SubnetIds {
+			if aws.StringValue(id) == aws.StringValue(subnet.SubnetId) {
+				out.Subnets = append(out.Subnets, subnet)
+			}
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeAdoptClient) DescribeRouteTablesPagesWithContext(_ aws.Context, _ *ec2.DescribeRouteTablesInput, fn func(*ec2.DescribeRouteTablesOutput, bool) bool, _ ...request.Option) error {
+	fn(&ec2.DescribeRouteTablesOutput{RouteTables: f.routeTables}, true)
+	return nil
+}
+
+func adoptSubnet(id, zone string) *ec2.Subnet {
+	return &ec2.Subnet{SubnetId: aws.String(id), AvailabilityZone: aws.String(zone), VpcId: aws.String("vpc-1")}
+}
+
+func TestAdoptSubnets(t *testing.T) {
+	routeTables := []*ec2.RouteTable{
+		{
+			RouteTableId: aws.String("rtb-main"),
+			Associations: []*ec2.RouteTableAssociation{{Main: aws.Bool(true)}},
+			Routes:       []*ec2.Route{{DestinationCidrBlock: aws.String("0.0.0.0/0"), GatewayId: aws.String("igw-1")}},
+		},
+		{
+			RouteTableId: aws.String("rtb-private"),
+			Associations: []*ec2.RouteTableAssociation{{SubnetId: aws.String("subnet-private-a")}, {SubnetId: aws.String("subnet-private-b")}, {SubnetId: aws.String("subnet-private-b2")}},
+			Routes:       []*ec2.Route{{DestinationCidrBlock: aws.String("0.0.0.0/0"), NatGatewayId: aws.String("nat-1")}},
+		},
+	}
+	subnets := []*ec2.Subnet{
+		adoptSubnet("subnet-private-a", "us-east-1a"),
+		adoptSubnet("subnet-public-a", "us-east-1a"),
+		adoptSubnet("subnet-private-b", "us-east-1b"),
+		adoptSubnet("subnet-public-b", "us-east-1b"),
+	}
+	testCases := []struct {
+		name          string
+		subnets       []*ec2.Subnet
+		subnetIDs     []string
+		expected      []*CreateInfraOutputZone
+		expectedError string
+	}{
+		{
+			name:    "private and public subnets by route",
+			subnets: subnets,
+			expected: []*CreateInfraOutputZone{
+				{Name: "us-east-1a", SubnetID: "subnet-private-a", PublicSubnetID: "subnet-public-a"},
+				{Name: "us-east-1b", SubnetID: "subnet-private-b", PublicSubnetID: "subnet-public-b"},
+			},
+		},
+		{
+			name:          "several private subnets in a zone",
+			subnets:       append(subnets, adoptSubnet("subnet-private-b2", "us-east-1b")),
+			expectedError: "found private subnets subnet-private-b, subnet-private-b2 in zone us-east-1b",
+		},
+		{
+			name:      "selected by subnet IDs",
+			subnets:   append(subnets, adoptSubnet("subnet-private-b2", "us-east-1b")),
+			subnetIDs: []string{"subnet-private-b2"},
+			expected: []*CreateInfraOutputZone{
+				{Name: "us-east-1b", SubnetID: "subnet-private-b2", PublicSubnetID: "subnet-public-b"},
+			},
+		},
+		{
+			name:          "public subnet ID",
+			subnets:       subnets,
+			subnetIDs:     []string{"subnet-public-a"},
+			expectedError: "subnet subnet-public-a routes to an internet gateway",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewGomegaWithT(t)
+			o := &CreateInfraOptions{VPCID: "vpc-1", SubnetIDs: tc.subnetIDs}
+			zones, err := o.adoptSubnets(context.Background(), logr.Discard(), &fakeAdoptClient{subnets: tc.subnets, routeTables: routeTables})
+			if tc.expectedError != "" {
+				g.Expect(err).To(HaveOccurred())
+				g.Expect(err.Error()).To(ContainSubstring(tc.expectedError))
+				return
+			}
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(zones).To(Equal(tc.expected))
+		})
+	}
+}
+
+type fakeAdoptRoute53Client struct {
+	route53iface.Route53API
+	pages [][]*route53.HostedZoneSummary
+}
+
+func (f *fakeAdoptRoute53Client) ListHostedZonesByVPCWithContext(_ aws.Context, input *route53.ListHostedZonesByVPCInput, _ ...request.Option) (*route53.ListHostedZonesByVPCOutput, error) {
+	page := 0
+	if input.NextToken != nil {
+		page = 1
+	}
+	out := &route53.ListHostedZonesByVPCOutput{HostedZoneSummaries: f.pages[page]}
+	if page+1 < len(f.pages) {
+		out.NextToken = aws.String("next")
+	}
+	return out, nil
+}
+
+func TestAdoptPrivateZone(t *testing.T) {
+	g := NewGomegaWithT(t)
+	client := &fakeAdoptRoute53Client{pages: [][]*route53.HostedZoneSummary{
+		{{HostedZoneId: aws.String("Z1"), Name: aws.String("other.example.com.")}},
+		{{HostedZoneId: aws.String("/hostedzone/Z2"), Name: aws.String("test.example.com.")}},
+	}}
+	o := &CreateInfraOptions{VPCID: "vpc-1", Region: "us-east-1"}
+	id, err := o.adoptPrivateZone(context.Background(), logr.Discard(), client, "test.example.com", "")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(id).To(Equal("Z2"))
+
+	_, err = o.adoptPrivateZone(context.Background(), logr.Discard(), client, "test.hypershift.local", "")
+	g.Expect(err).To(MatchError("no private zone test.hypershift.local is associated with VPC vpc-1"))
+}
@@ -0,0 +1,46 @@
+package aws
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws/arn"
+	"github.com/aws/aws-sdk-go/aws/session"
+
+	awsutil "github.com/openshift/hypershift/cmd/infra/aws/util"
+)
+
+// infraIDSessionTag is the session tag that carries the infra ID when a role is
+// assumed, so that the policies of the role can be restricted to a single cluster.
+const infraIDSessionTag = "hypershift-infra-id"
+
+// validateAssumeRoleOptions validates the flags for assuming a role. Commands that
+// retry on errors validate them upfront, since invalid flags never succeed.
+func validateAssumeRoleOptions(roleARN, externalID string) error {
+	if len(roleARN) == 0 {
+		if len(externalID) > 0 {
+			return errors.New("--external-id can only be specified together with --role-arn")
+		}
+		return nil
+	}
+	if _, err := arn.Parse(roleARN); err != nil {
+		return fmt.Errorf("invalid --role-arn %q: %w", roleARN, err)
+	}
+	return nil
+}
+
+// assumeRole returns a session that assumes the given role in the target account
+// using the credentials of awsSession. If no role is given, awsSession is returned.
+func assumeRole(awsSession *session.Session, agent, roleARN, externalID, infraID string) (*session.Session, error) {
+	if err := validateAssumeRoleOptions(roleARN, externalID); err != nil {
+		return nil, err
+	}
+	if len(roleARN) == 0 {
+		return awsSession, nil
+	}
+	sessionTags := map[string]string{}
+	if len(infraID) > 0 {
+		sessionTags[infraIDSessionTag] = infraID
+	}
+	return awsutil.AssumeRole(awsSession, roleARN, externalID, fmt.Sprintf("hypershift-%s", agent), sessionTags), nil
+}
@@ -0,0 +1,28 @@
+package aws
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws/session"
+	. "github.com/onsi/gomega"
+)
+
+func TestAssumeRole(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	g.Expect(validateAssumeRoleOptions("", "")).To(Succeed())
+	g.Expect(validateAssumeRoleOptions("arn:aws:iam::123456789012:role/provisioner", "")).To(Succeed())
+	g.Expect(validateAssumeRoleOptions("arn:aws:iam::123456789012:role/provisioner", "external")).To(Succeed())
+	g.Expect(validateAssumeRoleOptions("", "external")).ToNot(Succeed())
+	g.Expect(validateAssumeRoleOptions("provisioner", "")).ToNot(Succeed())
+
+	awsSession := session.Must(session.NewSession())
+	unchanged, err := assumeRole(awsSession, "test", "", "", "infra")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(unchanged).To(BeIdenticalTo(awsSession))
+
+	assumed, err := assumeRole(awsSession, "test", "arn:aws:iam::123456789012:role/provisioner", "external", "infra")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(assumed).ToNot(BeIdenticalTo(awsSession))
+	g.Expect(assumed.Config.Credentials).ToNot(BeIdenticalTo(awsSession.Config.Credentials))
+}
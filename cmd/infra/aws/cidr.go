@@ -0,0 +1,204 @@
+package aws
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"net"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/go-logr/logr"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+)
+
+const (
+	// subnetPrefixIncrement is the number of bits added to the VPC prefix to get
+	// the subnet prefix, e.g. a /16 VPC has /20 subnets.
+	subnetPrefixIncrement = 4
+	// maxVPCPrefixLength is the longest VPC prefix that still leaves room for
+	// subnets of a usable size.
+	maxVPCPrefixLength = 24
+	// minVPCPrefixLength is the largest VPC allowed by AWS.
+	minVPCPrefixLength = 16
+	// maxZones is the number of zones that fit into the public or private half
+	// of the VPC CIDR.
+	maxZones = 1 << (subnetPrefixIncrement - 1)
+)
+
+func (o *CreateInfraOptions) vpcCIDR() string {
+	if len(o.VPCCIDR) == 0 {
+		return DefaultCIDRBlock
+	}
+	return o.VPCCIDR
+}
+
+// machineCIDRs returns the primary and secondary CIDRs of the VPC, i.e. the
+// networks the workers may have addresses in.
+func (o *CreateInfraOptions) machineCIDRs() []string {
+	return append([]string{o.vpcCIDR()}, o.SecondaryCIDRs...)
+}
+
+// sshIngressCIDRs returns the networks SSH to the workers is allowed from, which
+// default to the machine CIDRs.
+func (o *CreateInfraOptions) sshIngressCIDRs() []string {
+	if len(o.SSHIngressCIDRs) > 0 {
+		return o.SSHIngressCIDRs
+	}
+	return o.machineCIDRs()
+}
+
+// validateCIDRs validates the VPC CIDRs and that none of them overlap with each
+// other or with the cluster and service networks.
+func (o *CreateInfraOptions) validateCIDRs() error {
+	type namedNetwork struct {
+		name    string
+		network *net.IPNet
+	}
+	var errs []error
+	var networks []namedNetwork
+	parse := func(name, cidr string) *net.IPNet {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid %s %q: %w", name, cidr, err))
+			return nil
+		}
+		if network.IP.To4() == nil {
+			errs = append(errs, fmt.Errorf("%s %s must be an IPv4 CIDR", name, cidr))
+			return nil
+		}
+		networks = append(networks, namedNetwork{name: fmt.Sprintf("%s %s", name, network), network: network})
+		return network
+	}
+
+	// The subnets of an existing VPC are not carved out of its CIDR, so any size is fine
+	if vpcNetwork := parse("VPC CIDR", o.vpcCIDR()); vpcNetwork != nil && len(o.VPCID) == 0 {
+		if ones, _ := vpcNetwork.Mask.Size(); ones < minVPCPrefixLength || ones > maxVPCPrefixLength {
+			errs = append(errs, fmt.Errorf("VPC CIDR %s must have a prefix length between /%d and /%d", vpcNetwork, minVPCPrefixLength, maxVPCPrefixLength))
+		}
+	}
+	if o.EnableIPv6 && o.EnableProxy {
+		errs = append(errs, fmt.Errorf("IPv6 is not supported together with the proxy host"))
+	}
+	if len(o.VPCID) > 0 && len(o.SecondaryCIDRs) > 0 {
+		errs = append(errs, fmt.Errorf("secondary CIDRs cannot be associated with an existing VPC"))
+	}
+	for _, cidr := range o.SecondaryCIDRs {
+		parse("secondary CIDR", cidr)
+	}
+	if len(o.ClusterCIDR) > 0 {
+		parse("cluster CIDR", o.ClusterCIDR)
+	}
+	if len(o.ServiceCIDR) > 0 {
+		parse("service CIDR", o.ServiceCIDR)
+	}
+	// SSH may be allowed from any network, so these aren't checked for overlaps
+	for _, cidr := range o.SSHIngressCIDRs {
+		if _, network, err := net.ParseCIDR(cidr); err != nil {
+			errs = append(errs, fmt.Errorf("invalid SSH ingress CIDR %q: %w", cidr, err))
+		} else if network.IP.To4() == nil {
+			errs = append(errs, fmt.Errorf("SSH ingress CIDR %s must be an IPv4 CIDR", cidr))
+		}
+	}
+	if len(o.EgressCIDRs) > 0 && !o.RestrictEgress {
+		errs = append(errs, fmt.Errorf("--egress-cidr can only be specified together with --restrict-egress"))
+	}
+	for _, cidr := range o.EgressCIDRs {
+		if _, _, err := net.ParseCIDR(cidr); err != nil {
+			errs = append(errs, fmt.Errorf("invalid egress CIDR %q: %w", cidr, err))
+		}
+	}
+
+	for i := range networks {
+		for j := i + 1; j < len(networks); j++ {
+			if networksOverlap(networks[i].network, networks[j].network) {
+				errs = append(errs, fmt.Errorf("%s overlaps with %s", networks[i].name, networks[j].name))
+			}
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+func networksOverlap(a, b *net.IPNet) bool {
+	return a.Contains(b.IP) || b.Contains(a.IP)
+}
+
+// subnetCIDRs returns the private and public subnet CIDRs for the given number of
+// zones. The public subnets are allocated from the start of the first half of the
+// VPC CIDR and the private subnets from the start of the second half. Each subnet
+// is 1/16th of the VPC CIDR, e.g. a /20 for a /16 VPC.
+func subnetCIDRs(vpcCIDR string, zoneCount int) (private []string, public []string, err error) {
+	_, vpcNetwork, err := net.ParseCIDR(vpcCIDR)
+	if err != nil {
+		return nil, nil, err
+	}
+	ip := vpcNetwork.IP.To4()
+	if ip == nil {
+		return nil, nil, fmt.Errorf("VPC CIDR %s must be an IPv4 CIDR", vpcCIDR)
+	}
+	ones, bits := vpcNetwork.Mask.Size()
+	if ones > maxVPCPrefixLength {
+		return nil, nil, fmt.Errorf("VPC CIDR %s is too small, the prefix length must be at most /%d", vpcCIDR, maxVPCPrefixLength)
+	}
+	if zoneCount > maxZones {
+		return nil, nil, fmt.Errorf("at most %d zones are supported, got %d", maxZones, zoneCount)
+	}
+	subnetOnes := ones + subnetPrefixIncrement
+	subnetSize := uint32(1) << (bits - subnetOnes)
+	half := uint32(1) << (bits - ones - 1)
+	base := binary.BigEndian.Uint32(ip)
+	subnet := func(start uint32) string {
+		subnetIP := make(net.IP, net.IPv4len)
+		binary.BigEndian.PutUint32(subnetIP, start)
+		return (&net.IPNet{IP: subnetIP, Mask: net.CIDRMask(subnetOnes, bits)}).String()
+	}
+	for i := 0; i < zoneCount; i++ {
+		public = append(public, subnet(base+uint32(i)*subnetSize))
+		private = append(private, subnet(base+half+uint32(i)*subnetSize))
+	}
+	return private, public, nil
+}
+
+// associateSecondaryCIDRs associates the secondary CIDRs with the VPC, unless
+// they are already associated.
+func (o *CreateInfraOptions) associateSecondaryCIDRs(ctx context.Context, l logr.Logger, client ec2iface.EC2API, vpcID string) error {
+	if len(o.SecondaryCIDRs) == 0 {
+		return nil
+	}
+	associated := map[string]bool{}
+	if !isPlannedID(vpcID) {
+		result, err := client.DescribeVpcsWithContext(ctx, &ec2.DescribeVpcsInput{VpcIds: []*string{aws.String(vpcID)}})
+		if err != nil {
+			return fmt.Errorf("cannot describe VPC %s: %w", vpcID, err)
+		}
+		for _, vpc := range result.Vpcs {
+			for _, association := range vpc.CidrBlockAssociationSet {
+				if association.CidrBlockState == nil {
+					continue
+				}
+				if state := aws.StringValue(association.CidrBlockState.State); state == ec2.VpcCidrBlockStateCodeAssociated || state == ec2.VpcCidrBlockStateCodeAssociating {
+					associated[aws.StringValue(association.CidrBlock)] = true
+				}
+			}
+		}
+	}
+	for _, cidr := range o.SecondaryCIDRs {
+		if associated[cidr] {
+			l.Info("Found existing secondary CIDR association", "vpc", vpcID, "cidr", cidr)
+			continue
+		}
+		if o.DryRun {
+			o.planModify(l, "vpc", vpcID, fmt.Sprintf("associate secondary CIDR %s", cidr))
+			continue
+		}
+		if _, err := client.AssociateVpcCidrBlockWithContext(ctx, &ec2.AssociateVpcCidrBlockInput{
+			VpcId:     aws.String(vpcID),
+			CidrBlock: aws.String(cidr),
+		}); err != nil {
+			return fmt.Errorf("cannot associate secondary CIDR %s with VPC %s: %w", cidr, vpcID, err)
+		}
+		l.Info("Associated secondary CIDR", "vpc", vpcID, "cidr", cidr)
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package aws
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestSubnetCIDRs(t *testing.T) {
+	testCases := []struct {
+		name            string
+		vpcCIDR         string
+		zoneCount       int
+		expectedPrivate []string
+		expectedPublic  []string
+		expectError     bool
+	}{
+		{
+			name:            "default VPC CIDR",
+			vpcCIDR:         DefaultCIDRBlock,
+			zoneCount:       3,
+			expectedPrivate: []string{"10.0.128.0/20", "10.0.144.0/20", "10.0.160.0/20"},
+			expectedPublic:  []string{"10.0.0.0/20", "10.0.16.0/20", "10.0.32.0/20"},
+		},
+		{
+			name:            "smaller VPC CIDR",
+			vpcCIDR:         "192.168.4.0/22",
+			zoneCount:       2,
+			expectedPrivate: []string{"192.168.6.0/26", "192.168.6.64/26"},
+			expectedPublic:  []string{"192.168.4.0/26", "192.168.4.64/26"},
+		},
+		{
+			name:        "too many zones",
+			vpcCIDR:     DefaultCIDRBlock,
+			zoneCount:   9,
+			expectError: true,
+		},
+		{
+			name:        "VPC CIDR too small",
+			vpcCIDR:     "10.0.0.0/26",
+			zoneCount:   1,
+			expectError: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewGomegaWithT(t)
+			private, public, err := subnetCIDRs(tc.vpcCIDR, tc.zoneCount)
+			if tc.expectError {
+				g.Expect(err).To(HaveOccurred())
+				return
+			}
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(private).To(Equal(tc.expectedPrivate))
+			g.Expect(public).To(Equal(tc.expectedPublic))
+		})
+	}
+}
+
+func TestValidateCIDRs(t *testing.T) {
+	testCases := []struct {
+		name        string
+		options     CreateInfraOptions
+		expectError bool
+	}{
+		{
+			name:    "defaults",
+			options: CreateInfraOptions{},
+		},
+		{
+			name: "custom VPC CIDR with secondary CIDRs and networks",
+			options: CreateInfraOptions{
+				VPCCIDR:        "10.1.0.0/16",
+				SecondaryCIDRs: []string{"100.64.0.0/16"},
+				ClusterCIDR:    "10.132.0.0/14",
+				ServiceCIDR:    "172.31.0.0/16",
+			},
+		},
+		{
+			name:        "VPC CIDR too large",
+			options:     CreateInfraOptions{VPCCIDR: "10.0.0.0/8"},
+			expectError: true,
+		},
+		{
+			name:        "IPv6 VPC CIDR",
+			options:     CreateInfraOptions{VPCCIDR: "fd00::/56"},
+			expectError: true,
+		},
+		{
+			name:        "secondary CIDR overlaps VPC CIDR",
+			options:     CreateInfraOptions{SecondaryCIDRs: []string{"10.0.128.0/17"}},
+			expectError: true,
+		},
+		{
+			name:        "cluster CIDR overlaps VPC CIDR",
+			options:     CreateInfraOptions{ClusterCIDR: "10.0.0.0/14"},
+			expectError: true,
+		},
+		{
+			name:        "secondary CIDRs with existing VPC",
+			options:     CreateInfraOptions{VPCID: "vpc-1", SecondaryCIDRs: []string{"100.64.0.0/16"}},
+			expectError: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewGomegaWithT(t)
+			err := tc.options.validateCIDRs()
+			if tc.expectError {
+				g.Expect(err).To(HaveOccurred())
+			} else {
+				g.Expect(err).ToNot(HaveOccurred())
+			}
+		})
+	}
+}
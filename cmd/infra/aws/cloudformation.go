@@ -0,0 +1,340 @@
+package aws
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+const (
+	// OutputFormatJSON writes the infra output of the created resources as JSON.
+	OutputFormatJSON = "json"
+	// OutputFormatCloudFormation writes a CloudFormation template describing the
+	// resources instead of creating them.
+	OutputFormatCloudFormation = "cloudformation"
+	// OutputFormatTerraform writes a Terraform configuration describing the
+	// resources instead of creating them.
+	OutputFormatTerraform = "terraform"
+)
+
+type cfnTemplate struct {
+	AWSTemplateFormatVersion string                 `json:"AWSTemplateFormatVersion"`
+	Description              string                 `json:"Description"`
+	Resources                map[string]cfnResource `json:"Resources"`
+	Outputs                  map[string]cfnOutput   `json:"Outputs"`
+}
+
+type cfnResource struct {
+	Type       string                 `json:"Type"`
+	DependsOn  []string               `json:"DependsOn,omitempty"`
+	Properties map[string]interface{} `json:"Properties"`
+}
+
+type cfnOutput struct {
+	Description string      `json:"Description,omitempty"`
+	Value       interface{} `json:"Value"`
+}
+
+func cfnRef(name string) map[string]interface{} {
+	return map[string]interface{}{"Ref": name}
+}
+
+func cfnGetAtt(name, attribute string) map[string]interface{} {
+	return map[string]interface{}{"Fn::GetAtt": []string{name, attribute}}
+}
+
+// cfnTags converts the tags of a resource with the given name to CloudFormation tags.
+func (o *CreateInfraOptions) cfnTags(name string) []map[string]string {
+	var tags []map[string]string
+	for _, tag := range append(ec2Tags(o.InfraID, name), o.additionalEC2Tags...) {
+		tags = append(tags, map[string]string{"Key": aws.StringValue(tag.Key), "Value": aws.StringValue(tag.Value)})
+	}
+	return tags
+}
+
+// cfnPermission converts an EC2 permission to the properties of a CloudFormation
+// security group rule. Each IP range and group pair results in one rule, as
+// CloudFormation rules only have a single source.
+func cfnPermission(permission *ec2.IpPermission, securityGroup string) []map[string]interface{} {
+	base := func() map[string]interface{} {
+		rule := map[string]interface{}{"IpProtocol": aws.StringValue(permission.IpProtocol)}
+		if permission.FromPort != nil {
+			rule["FromPort"] = aws.Int64Value(permission.FromPort)
+		}
+		if permission.ToPort != nil {
+			rule["ToPort"] = aws.Int64Value(permission.ToPort)
+		}
+		return rule
+	}
+	var rules []map[string]interface{}
+	for _, ipRange := range permission.IpRanges {
+		rule := base()
+		rule["CidrIp"] = aws.StringValue(ipRange.CidrIp)
+		rules = append(rules, rule)
+	}
+	for _, ipRange := range permission.Ipv6Ranges {
+		rule := base()
+		rule["CidrIpv6"] = aws.StringValue(ipRange.CidrIpv6)
+		rules = append(rules, rule)
+	}
+	for range permission.UserIdGroupPairs {
+		rule := base()
+		rule["SourceSecurityGroupId"] = cfnRef(securityGroup)
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+// CloudFormationTemplate renders a CloudFormation template that creates the same
+// resources as CreateInfra. No AWS APIs are called. The public hosted zone is not
+// part of the template since it must already exist.
+func (o *CreateInfraOptions) CloudFormationTemplate() ([]byte, error) {
+	if o.EnableProxy {
+		return nil, fmt.Errorf("the proxy host is not supported with the %s output format", OutputFormatCloudFormation)
+	}
+	if len(o.VPCID) > 0 {
+		return nil, fmt.Errorf("an existing VPC is not supported with the %s output format", OutputFormatCloudFormation)
+	}
+	if o.EnableIPv6 {
+		return nil, fmt.Errorf("IPv6 is not supported with the %s output format", OutputFormatCloudFormation)
+	}
+	if len(o.PrivateLinkNLBARN) > 0 {
+		return nil, fmt.Errorf("PrivateLink is not supported with the %s output format", OutputFormatCloudFormation)
+	}
+	if o.CreateKMSKey || len(o.KMSKeyARN) > 0 {
+		return nil, fmt.Errorf("KMS keys are not supported with the %s output format", OutputFormatCloudFormation)
+	}
+	if len(o.PublicZoneID) > 0 || len(o.PrivateZoneID) > 0 || len(o.ParentZoneID) > 0 {
+		return nil, fmt.Errorf("existing and delegated hosted zones are not supported with the %s output format", OutputFormatCloudFormation)
+	}
+	if o.Bastion {
+		return nil, fmt.Errorf("the bastion host is not supported with the %s output format", OutputFormatCloudFormation)
+	}
+	if len(o.OutpostARN) > 0 {
+		return nil, fmt.Errorf("Outposts are not supported with the %s output format", OutputFormatCloudFormation)
+	}
+	if len(o.TransitGatewayID) > 0 {
+		return nil, fmt.Errorf("transit gateway attachments are not supported with the %s output format", OutputFormatCloudFormation)
+	}
+	if o.InstanceConnectEndpoint {
+		return nil, fmt.Errorf("EC2 Instance Connect Endpoints are not supported with the %s output format", OutputFormatCloudFormation)
+	}
+	if o.EnableFlowLogs {
+		return nil, fmt.Errorf("flow logs are not supported with the %s output format", OutputFormatCloudFormation)
+	}
+	if o.CreateVPCEndpoints {
+		return nil, fmt.Errorf("interface VPC endpoints are not supported with the %s output format", OutputFormatCloudFormation)
+	}
+	if o.CheckQuotas {
+		return nil, fmt.Errorf("the service quota check is not supported with the %s output format", OutputFormatCloudFormation)
+	}
+	if o.RestrictEgress {
+		return nil, fmt.Errorf("restricted egress is not supported with the %s output format", OutputFormatCloudFormation)
+	}
+	if err := o.parseAdditionalTags(); err != nil {
+		return nil, err
+	}
+	if err := o.parseAdditionalIngressRules(); err != nil {
+		return nil, err
+	}
+	if err := o.validateCIDRs(); err != nil {
+		return nil, err
+	}
+
+	template := cfnTemplate{
+		AWSTemplateFormatVersion: "2010-09-09",
+		Description:              fmt.Sprintf("HyperShift infrastructure for cluster %s (infra ID %s)", o.Name, o.InfraID),
+		Resources:                map[string]cfnResource{},
+		Outputs:                  map[string]cfnOutput{},
+	}
+	add := func(name, resourceType string, properties map[string]interface{}, dependsOn ...string) {
+		template.Resources[name] = cfnResource{Type: resourceType, Properties: properties, DependsOn: dependsOn}
+	}
+
+	// VPC resources
+	add("VPC", "AWS::EC2::VPC", map[string]interface{}{
+		"CidrBlock":          o.vpcCIDR(),
+		"EnableDnsSupport":   true,
+		"EnableDnsHostnames": true,
+		"Tags":               o.cfnTags(fmt.Sprintf("%s-vpc", o.InfraID)),
+	})
+	for i, cidr := range o.SecondaryCIDRs {
+		add(fmt.Sprintf("VPCSecondaryCIDR%d", i), "AWS::EC2::VPCCidrBlock", map[string]interface{}{
+			"VpcId":     cfnRef("VPC"),
+			"CidrBlock": cidr,
+		})
+	}
+	domainName := "ec2.internal"
+	if o.Region != "us-east-1" {
+		domainName = fmt.Sprintf("%s.compute.internal", o.Region)
+	}
+	add("DHCPOptions", "AWS::EC2::DHCPOptions", map[string]interface{}{
+		"DomainName":        domainName,
+		"DomainNameServers": []string{"AmazonProvidedDNS"},
+		"Tags":              o.cfnTags(""),
+	})
+	add("DHCPOptionsAssociation", "AWS::EC2::VPCDHCPOptionsAssociation", map[string]interface{}{
+		"DhcpOptionsId": cfnRef("DHCPOptions"),
+		"VpcId":         cfnRef("VPC"),
+	})
+	add("InternetGateway", "AWS::EC2::InternetGateway", map[string]interface{}{
+		"Tags": o.cfnTags(fmt.Sprintf("%s-igw", o.InfraID)),
+	})
+	add("InternetGatewayAttachment", "AWS::EC2::VPCGatewayAttachment", map[string]interface{}{
+		"InternetGatewayId": cfnRef("InternetGateway"),
+		"VpcId":             cfnRef("VPC"),
+	})
+
+	// Worker security group. Rules referencing the group itself must be separate
+	// resources to avoid a circular dependency.
+	groupName := fmt.Sprintf("%s-worker-sg", o.InfraID)
+	var egress, ingress []map[string]interface{}
+	for _, permission := range workerSecurityGroupEgressPermissions(false) {
+		egress = append(egress, cfnPermission(permission, "WorkerSecurityGroup")...)
+	}
+	selfRules := 0
+	for _, permission := range append(workerSecurityGroupIngressPermissions(o.machineCIDRs(), o.sshIngressCIDRs(), "", ""), o.additionalIngressPermissions...) {
+		for _, rule := range cfnPermission(permission, "WorkerSecurityGroup") {
+			if _, isSelf := rule["SourceSecurityGroupId"]; !isSelf {
+				ingress = append(ingress, rule)
+				continue
+			}
+			rule["GroupId"] = cfnRef("WorkerSecurityGroup")
+			add(fmt.Sprintf("WorkerSecurityGroupIngress%d", selfRules), "AWS::EC2::SecurityGroupIngress", rule)
+			selfRules++
+		}
+	}
+	add("WorkerSecurityGroup", "AWS::EC2::SecurityGroup", map[string]interface{}{
+		"GroupName":            groupName,
+		"GroupDescription":     "worker security group",
+		"VpcId":                cfnRef("VPC"),
+		"SecurityGroupEgress":  egress,
+		"SecurityGroupIngress": ingress,
+		"Tags":                 o.cfnTags(groupName),
+	})
+
+	// Per zone resources
+	if err := o.validateZones(); err != nil {
+		return nil, err
+	}
+	if err := o.validateNATTopology(); err != nil {
+		return nil, err
+	}
+	if err := o.validatePublicIPv4PoolOptions(); err != nil {
+		return nil, err
+	}
+	zones := o.Zones
+	if len(zones) == 0 {
+		zones = make([]string, o.ZoneCount)
+		if len(zones) == 0 {
+			zones = []string{""}
+		}
+	}
+	privateCIDRs, publicCIDRs, err := subnetCIDRs(o.vpcCIDR(), len(zones))
+	if err != nil {
+		return nil, err
+	}
+	add("PublicRouteTable", "AWS::EC2::RouteTable", map[string]interface{}{
+		"VpcId": cfnRef("VPC"),
+		"Tags":  o.cfnTags(fmt.Sprintf("%s-public", o.InfraID)),
+	})
+	add("PublicRoute", "AWS::EC2::Route", map[string]interface{}{
+		"RouteTableId":         cfnRef("PublicRouteTable"),
+		"DestinationCidrBlock": "0.0.0.0/0",
+		"GatewayId":            cfnRef("InternetGateway"),
+	}, "InternetGatewayAttachment")
+	endpointRouteTables := []interface{}{cfnRef("PublicRouteTable")}
+	for i, zone := range zones {
+		// Without explicit zones, use the first zones of the region like CreateInfra does
+		var availabilityZone interface{} = zone
+		zoneName := zone
+		if zone == "" {
+			availabilityZone = map[string]interface{}{"Fn::Select": []interface{}{i, map[string]interface{}{"Fn::GetAZs": ""}}}
+			zoneName = fmt.Sprintf("az%d", i)
+		}
+		privateSubnet := fmt.Sprintf("PrivateSubnet%d", i)
+		publicSubnet := fmt.Sprintf("PublicSubnet%d", i)
+		natGateway := fmt.Sprintf("NATGateway%d", i)
+		natEIP := fmt.Sprintf("NATGatewayEIP%d", i)
+		privateRouteTable := fmt.Sprintf("PrivateRouteTable%d", i)
+
+		add(privateSubnet, "AWS::EC2::Subnet", map[string]interface{}{
+			"AvailabilityZone": availabilityZone,
+			"CidrBlock":        privateCIDRs[i],
+			"VpcId":            cfnRef("VPC"),
+			"Tags":             o.cfnTags(fmt.Sprintf("%s-private-%s", o.InfraID, zoneName)),
+		})
+		add(publicSubnet, "AWS::EC2::Subnet", map[string]interface{}{
+			"AvailabilityZone": availabilityZone,
+			"CidrBlock":        publicCIDRs[i],
+			"VpcId":            cfnRef("VPC"),
+			"Tags":             o.cfnTags(fmt.Sprintf("%s-public-%s", o.InfraID, zoneName)),
+		})
+		if o.natTopology() == NATTopologyPerZone || (o.natTopology() == NATTopologySingle && i == 0) {
+			eip := map[string]interface{}{
+				"Domain": "vpc",
+				"Tags":   o.cfnTags(fmt.Sprintf("%s-eip-%s", o.InfraID, zoneName)),
+			}
+			if len(o.PublicIPv4Pool) > 0 {
+				eip["PublicIpv4Pool"] = o.PublicIPv4Pool
+			}
+			add(natEIP, "AWS::EC2::EIP", eip, "InternetGatewayAttachment")
+			add(natGateway, "AWS::EC2::NatGateway", map[string]interface{}{
+				"AllocationId": cfnGetAtt(natEIP, "AllocationId"),
+				"SubnetId":     cfnRef(publicSubnet),
+				"Tags":         o.cfnTags(fmt.Sprintf("%s-nat-%s", o.InfraID, zoneName)),
+			})
+		}
+		add(privateRouteTable, "AWS::EC2::RouteTable", map[string]interface{}{
+			"VpcId": cfnRef("VPC"),
+			"Tags":  o.cfnTags(fmt.Sprintf("%s-private-%s", o.InfraID, zoneName)),
+		})
+		if o.natTopology() != NATTopologyNone {
+			if o.natTopology() == NATTopologySingle {
+				natGateway = "NATGateway0"
+			}
+			add(fmt.Sprintf("PrivateRoute%d", i), "AWS::EC2::Route", map[string]interface{}{
+				"RouteTableId":         cfnRef(privateRouteTable),
+				"DestinationCidrBlock": "0.0.0.0/0",
+				"NatGatewayId":         cfnRef(natGateway),
+			})
+		}
+		add(fmt.Sprintf("PrivateSubnetRouteTableAssociation%d", i), "AWS::EC2::SubnetRouteTableAssociation", map[string]interface{}{
+			"RouteTableId": cfnRef(privateRouteTable),
+			"SubnetId":     cfnRef(privateSubnet),
+		})
+		add(fmt.Sprintf("PublicSubnetRouteTableAssociation%d", i), "AWS::EC2::SubnetRouteTableAssociation", map[string]interface{}{
+			"RouteTableId": cfnRef("PublicRouteTable"),
+			"SubnetId":     cfnRef(publicSubnet),
+		})
+		endpointRouteTables = append(endpointRouteTables, cfnRef(privateRouteTable))
+
+		template.Outputs[privateSubnet+"ID"] = cfnOutput{Description: fmt.Sprintf("Private subnet in zone %s", zoneName), Value: cfnRef(privateSubnet)}
+		template.Outputs[privateSubnet+"Zone"] = cfnOutput{Value: cfnGetAtt(privateSubnet, "AvailabilityZone")}
+	}
+	add("S3VPCEndpoint", "AWS::EC2::VPCEndpoint", map[string]interface{}{
+		"ServiceName":   vpcEndpointServiceName(o.Region, "s3"),
+		"VpcId":         cfnRef("VPC"),
+		"RouteTableIds": endpointRouteTables,
+	})
+
+	// Private hosted zones
+	for resourceName, zoneName := range map[string]string{
+		"PrivateZone": fmt.Sprintf("%s.%s", o.Name, o.BaseDomain),
+		"LocalZone":   fmt.Sprintf("%s.%s", o.Name, hypershiftLocalZoneName),
+	} {
+		add(resourceName, "AWS::Route53::HostedZone", map[string]interface{}{
+			"Name": zoneName,
+			"VPCs": []map[string]interface{}{{"VPCId": cfnRef("VPC"), "VPCRegion": o.Region}},
+		})
+		template.Outputs[resourceName+"ID"] = cfnOutput{Description: fmt.Sprintf("Private hosted zone %s", zoneName), Value: cfnRef(resourceName)}
+	}
+
+	template.Outputs["VPCID"] = cfnOutput{Value: cfnRef("VPC")}
+	template.Outputs["SecurityGroupID"] = cfnOutput{Value: cfnRef("WorkerSecurityGroup")}
+	template.Outputs["MachineCIDR"] = cfnOutput{Value: o.vpcCIDR()}
+
+	return json.MarshalIndent(template, "", "  ")
+}
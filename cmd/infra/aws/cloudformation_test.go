@@ -0,0 +1,107 @@
+package aws
+
+import (
+	"encoding/json"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestCloudFormationTemplate(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	o := &CreateInfraOptions{
+		Region:         "us-east-2",
+		InfraID:        "test-infra",
+		Name:           "test",
+		BaseDomain:     "example.com",
+		Zones:          []string{"us-east-2a", "us-east-2b"},
+		AdditionalTags: []string{"team=hypershift"},
+	}
+	out, err := o.CloudFormationTemplate()
+	g.Expect(err).ToNot(HaveOccurred())
+
+	template := cfnTemplate{}
+	g.Expect(json.Unmarshal(out, &template)).To(Succeed())
+
+	for _, name := range []string{"VPC", "WorkerSecurityGroup", "PrivateSubnet0", "PrivateSubnet1", "NATGateway1", "S3VPCEndpoint", "PrivateZone", "LocalZone"} {
+		g.Expect(template.Resources).To(HaveKey(name))
+	}
+	g.Expect(template.Resources["PrivateSubnet1"].Properties["CidrBlock"]).To(Equal("10.0.144.0/20"))
+	g.Expect(template.Resources["VPC"].Properties["Tags"]).To(ContainElement(map[string]interface{}{"Key": "team", "Value": "hypershift"}))
+	g.Expect(template.Outputs).To(HaveKey("VPCID"))
+	g.Expect(template.Outputs).To(HaveKey("PrivateSubnet1ID"))
+
+	o.EnableProxy = true
+	_, err = o.CloudFormationTemplate()
+	g.Expect(err).To(HaveOccurred())
+}
+
+func TestCloudFormationTemplateNATTopology(t *testing.T) {
+	testCases := []struct {
+		topology            string
+		expectedNATGateways []string
+		expectedRouteTarget interface{}
+	}{
+		{
+			topology:            NATTopologyPerZone,
+			expectedNATGateways: []string{"NATGateway0", "NATGateway1"},
+			expectedRouteTarget: cfnRef("NATGateway1"),
+		},
+		{
+			topology:            NATTopologySingle,
+			expectedNATGateways: []string{"NATGateway0"},
+			expectedRouteTarget: cfnRef("NATGateway0"),
+		},
+		{
+			topology: NATTopologyNone,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.topology, func(t *testing.T) {
+			g := NewGomegaWithT(t)
+			o := &CreateInfraOptions{
+				Region:      "us-east-2",
+				InfraID:     "test-infra",
+				Name:        "test",
+				BaseDomain:  "example.com",
+				Zones:       []string{"us-east-2a", "us-east-2b"},
+				NATTopology: tc.topology,
+			}
+			out, err := o.CloudFormationTemplate()
+			g.Expect(err).ToNot(HaveOccurred())
+			template := cfnTemplate{}
+			g.Expect(json.Unmarshal(out, &template)).To(Succeed())
+
+			var natGateways []string
+			for name, resource := range template.Resources {
+				if resource.Type == "AWS::EC2::NatGateway" {
+					natGateways = append(natGateways, name)
+				}
+			}
+			g.Expect(natGateways).To(ConsistOf(tc.expectedNATGateways))
+			if tc.expectedRouteTarget == nil {
+				g.Expect(template.Resources).ToNot(HaveKey("PrivateRoute1"))
+				return
+			}
+			g.Expect(template.Resources["PrivateRoute1"].Properties["NatGatewayId"]).To(BeEquivalentTo(tc.expectedRouteTarget))
+		})
+	}
+}
+
+func TestCloudFormationTemplatePublicIPv4Pool(t *testing.T) {
+	g := NewGomegaWithT(t)
+	o := &CreateInfraOptions{
+		Region:         "us-east-2",
+		InfraID:        "test-infra",
+		Name:           "test",
+		BaseDomain:     "example.com",
+		Zones:          []string{"us-east-2a"},
+		PublicIPv4Pool: "ipv4pool-ec2-0123456789abcdef0",
+	}
+	out, err := o.CloudFormationTemplate()
+	g.Expect(err).ToNot(HaveOccurred())
+	template := cfnTemplate{}
+	g.Expect(json.Unmarshal(out, &template)).To(Succeed())
+	g.Expect(template.Resources["NATGatewayEIP0"].Properties["PublicIpv4Pool"]).To(Equal("ipv4pool-ec2-0123456789abcdef0"))
+}
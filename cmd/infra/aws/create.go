@@ -7,43 +7,124 @@ import (
 	"errors"
 	"fmt"
 	"io/ioutil"
-	"net"
 	"os"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/aws/aws-sdk-go/service/kms"
 	"github.com/aws/aws-sdk-go/service/route53"
+	"github.com/aws/aws-sdk-go/service/sts"
 	"github.com/go-logr/logr"
 	"github.com/spf13/cobra"
 
 	awsutil "github.com/openshift/hypershift/cmd/infra/aws/util"
 	"github.com/openshift/hypershift/cmd/log"
-	"k8s.io/apimachinery/pkg/util/wait"
-	"k8s.io/client-go/util/retry"
 )
 
 type CreateInfraOptions struct {
-	Region             string
-	InfraID            string
-	AWSCredentialsFile string
-	AWSKey             string
-	AWSSecretKey       string
-	Name               string
-	BaseDomain         string
-	Zones              []string
-	OutputFile         string
-	AdditionalTags     []string
-	EnableProxy        bool
-	SSHKeyFile         string
-
-	additionalEC2Tags []*ec2.Tag
+	Region                     string
+	InfraID                    string
+	AWSCredentialsFile         string
+	WebIdentityTokenFile       string
+	WebIdentityRoleARN         string
+	AWSKey                     string
+	AWSSecretKey               string
+	RoleARN                    string
+	ExternalID                 string
+	Name                       string
+	BaseDomain                 string
+	Zones                      []string
+	ZoneCount                  int
+	OutputFile                 string
+	AdditionalTags             []string
+	EnableProxy                bool
+	SSHKeyFile                 string
+	DryRun                     bool
+	OutputFormat               string
+	VPCID                      string
+	SubnetIDs                  []string
+	SecurityGroupID            string
+	Adopt                      bool
+	AdoptTags                  []string
+	VPCOwnerRoleARN            string
+	VPCOwnerExternalID         string
+	VPCCIDR                    string
+	SecondaryCIDRs             []string
+	ClusterCIDR                string
+	ServiceCIDR                string
+	EnableIPv6                 bool
+	AdditionalIngressRules     []string
+	SSHIngressCIDRs            []string
+	RestrictEgress             bool
+	EgressCIDRs                []string
+	PrivateLinkNLBARN          string
+	PrivateLinkPrincipals      []string
+	CreateVPCEndpoints         bool
+	CreateKMSKey               bool
+	KMSKeyARN                  string
+	PublicZoneID               string
+	PrivateZoneID              string
+	ParentZoneID               string
+	ParentZoneRoleARN          string
+	ParentZoneExternalID       string
+	NATTopology                string
+	PublicIPv4Pool             string
+	Bastion                    bool
+	BastionAllowedSSHCIDRs     []string
+	InstanceConnectEndpoint    bool
+	EnableFlowLogs             bool
+	FlowLogsDestination        string
+	OutpostARN                 string
+	OutpostZone                string
+	TransitGatewayID           string
+	TransitGatewayRouteTableID string
+	TransitGatewayRoutes       []string
+	CheckQuotas                bool
+	ProgressOutput             string
+	StateFile                  string
+	Parallelism                int
+	Timeout                    time.Duration
+
+	additionalEC2Tags            []*ec2.Tag
+	additionalIngressPermissions []*ec2.IpPermission
+	s3PrefixListID               string
+	accountID                    string
+	vpcOwnerAccountID            string
+	plan                         InfraPlan
+	progress                     *progressReporter
+	state                        *InfraState
 }
 
 type CreateInfraOutputZone struct {
-	Name     string `json:"name"`
-	SubnetID string `json:"subnetID"`
+	Name           string `json:"name"`
+	SubnetID       string `json:"subnetID"`
+	PublicSubnetID string `json:"publicSubnetID,omitempty"`
+}
+
+type CreateInfraOutputOutpost struct {
+	ARN                      string `json:"arn"`
+	Zone                     string `json:"zone"`
+	SubnetID                 string `json:"subnetID"`
+	LocalGatewayID           string `json:"localGatewayID"`
+	LocalGatewayRouteTableID string `json:"localGatewayRouteTableID"`
+}
+
+type CreateInfraOutputTransitGateway struct {
+	ID           string   `json:"id"`
+	AttachmentID string   `json:"attachmentID"`
+	RouteTableID string   `json:"routeTableID,omitempty"`
+	Routes       []string `json:"routes,omitempty"`
+}
+
+type CreateInfraOutputSharedVPC struct {
+	OwnerAccountID       string   `json:"ownerAccountID"`
+	ParticipantAccountID string   `json:"participantAccountID"`
+	OwnerResources       []string `json:"ownerResources"`
+	ParticipantResources []string `json:"participantResources"`
 }
 
 type CreateInfraOutput struct {
@@ -60,14 +141,29 @@ type CreateInfraOutput struct {
 	PrivateZoneID   string                   `json:"privateZoneID"`
 	LocalZoneID     string                   `json:"localZoneID"`
 	ProxyAddr       string                   `json:"proxyAddr"`
+	IPv6CIDR        string                   `json:"ipv6CIDR,omitempty"`
+
+	PrivateLinkEndpointServiceName string `json:"privateLinkEndpointServiceName,omitempty"`
+	PrivateLinkEndpointID          string `json:"privateLinkEndpointID,omitempty"`
+	KMSKeyARN                      string `json:"kmsKeyARN,omitempty"`
+	BastionInstanceID              string `json:"bastionInstanceID,omitempty"`
+	BastionPublicIP                string `json:"bastionPublicIP,omitempty"`
+	BastionProxyAddr               string `json:"bastionProxyAddr,omitempty"`
+	InstanceConnectEndpointID      string `json:"instanceConnectEndpointID,omitempty"`
+	FlowLogID                      string `json:"flowLogID,omitempty"`
+
+	VPCEndpoints map[string]string `json:"vpcEndpoints,omitempty"`
+
+	Outpost        *CreateInfraOutputOutpost        `json:"outpost,omitempty"`
+	TransitGateway *CreateInfraOutputTransitGateway `json:"transitGateway,omitempty"`
+	SharedVPC      *CreateInfraOutputSharedVPC      `json:"sharedVPC,omitempty"`
 }
 
 const (
-	DefaultCIDRBlock      = "10.0.0.0/16"
-	basePrivateSubnetCIDR = "10.0.128.0/20"
-	basePublicSubnetCIDR  = "10.0.0.0/20"
+	DefaultCIDRBlock = "10.0.0.0/16"
 
 	clusterTagValue         = "owned"
+	sharedClusterTagValue   = "shared"
 	hypershiftLocalZoneName = "hypershift.local"
 )
 
@@ -79,30 +175,98 @@ func NewCreateCommand() *cobra.Command {
 	}
 
 	opts := CreateInfraOptions{
-		Region: "us-east-1",
-		Name:   "example",
+		Region:       "us-east-1",
+		Name:         "example",
+		OutputFormat: OutputFormatJSON,
+		VPCCIDR:      DefaultCIDRBlock,
+		NATTopology:  NATTopologyPerZone,
+		Parallelism:  DefaultParallelism,
 	}
 
 	cmd.Flags().StringVar(&opts.InfraID, "infra-id", opts.InfraID, "Cluster ID with which to tag AWS resources (required)")
-	cmd.Flags().StringVar(&opts.AWSCredentialsFile, "aws-creds", opts.AWSCredentialsFile, "Path to an AWS credentials file (required)")
+	cmd.Flags().StringVar(&opts.AWSCredentialsFile, "aws-creds", opts.AWSCredentialsFile, "Path to an AWS credentials file (required unless --web-identity-token-file is specified)")
+	cmd.Flags().StringVar(&opts.WebIdentityTokenFile, "web-identity-token-file", opts.WebIdentityTokenFile, "Path to a web identity token, e.g. a projected service account token, to assume the role given by --web-identity-role-arn with instead of using --aws-creds. The token is read again whenever the temporary credentials are refreshed")
+	cmd.Flags().StringVar(&opts.WebIdentityRoleARN, "web-identity-role-arn", opts.WebIdentityRoleARN, "The ARN of the role to assume with the token given by --web-identity-token-file")
+	cmd.Flags().StringVar(&opts.RoleARN, "role-arn", opts.RoleARN, "The ARN of a role to assume with the given credentials, e.g. to create the resources in another account. The infra ID is passed as the hypershift-infra-id session tag")
+	cmd.Flags().StringVar(&opts.ExternalID, "external-id", opts.ExternalID, "The external ID to pass when assuming the role given by --role-arn")
 	cmd.Flags().StringVar(&opts.OutputFile, "output-file", opts.OutputFile, "Path to file that will contain output information from infra resources (optional)")
 	cmd.Flags().StringVar(&opts.Region, "region", opts.Region, "Region where cluster infra should be created")
-	cmd.Flags().StringSliceVar(&opts.AdditionalTags, "additional-tags", opts.AdditionalTags, "Additional tags to set on AWS resources")
+	cmd.Flags().StringSliceVar(&opts.AdditionalTags, "additional-tags", opts.AdditionalTags, "Additional tags to set on AWS resources, in the form key=value. Tags missing on resources created by a previous run are added")
 	cmd.Flags().StringVar(&opts.Name, "name", opts.Name, "A name for the cluster")
 	cmd.Flags().StringVar(&opts.BaseDomain, "base-domain", opts.BaseDomain, "The ingress base domain for the cluster")
-	cmd.Flags().StringSliceVar(&opts.Zones, "zones", opts.Zones, "The availablity zones in which NodePool can be created")
+	cmd.Flags().StringSliceVar(&opts.Zones, "zones", opts.Zones, "The availablity zones in which NodePool can be created. A private and public subnet and a NAT gateway are created in each zone")
+	cmd.Flags().IntVar(&opts.ZoneCount, "zone-count", opts.ZoneCount, "If --zones is not specified, the number of availability zones of the region to create subnets in. Defaults to 1")
 	cmd.Flags().BoolVar(&opts.EnableProxy, "enable-proxy", opts.EnableProxy, "If a proxy should be set up, rather than allowing direct internet access from the nodes")
+	cmd.Flags().StringVar(&opts.NATTopology, "nat-topology", opts.NATTopology, fmt.Sprintf("The NAT gateways to create for egress from the private subnets, one of %q (one per zone), %q (one shared by all zones) or %q (no internet egress). Ignored with --enable-proxy", NATTopologyPerZone, NATTopologySingle, NATTopologyNone))
+	cmd.Flags().StringVar(&opts.PublicIPv4Pool, "public-ipv4-pool", opts.PublicIPv4Pool, "The ID of a public IPv4 pool brought to AWS with BYOIP to allocate the elastic IPs of the NAT gateways from, so that egress from the private subnets originates from its addresses. Elastic IPs of existing NAT gateways are not changed")
+	cmd.Flags().StringVar(&opts.OutputFormat, "output-format", opts.OutputFormat, fmt.Sprintf("Output format, one of %q, %q or %q. With %q or %q, a template for the infrastructure is written instead of creating it", OutputFormatJSON, OutputFormatCloudFormation, OutputFormatTerraform, OutputFormatCloudFormation, OutputFormatTerraform))
+	cmd.Flags().StringVar(&opts.VPCID, "vpc-id", opts.VPCID, "The ID of an existing VPC to use. No VPC, subnets, gateways or route tables are created; only the security group, S3 endpoint and private zones are added (requires --subnet-ids)")
+	cmd.Flags().StringSliceVar(&opts.SubnetIDs, "subnet-ids", opts.SubnetIDs, "The IDs of existing private subnets in the VPC given by --vpc-id, one per availability zone")
+	cmd.Flags().StringVar(&opts.SecurityGroupID, "security-group-id", opts.SecurityGroupID, "The ID of an existing security group in the VPC given by --vpc-id to use for workers instead of creating one. It must allow at least the traffic the created worker security group would allow")
+	cmd.Flags().BoolVar(&opts.Adopt, "adopt", opts.Adopt, "If true, discover the existing subnets, security group and hosted zones of the VPC given by --vpc-id, validate them and write the infrastructure output without creating, modifying or tagging any resource. The private subnets and security group are selected by --subnet-ids and --security-group-id or by --adopt-tags")
+	cmd.Flags().StringSliceVar(&opts.AdoptTags, "adopt-tags", opts.AdoptTags, "Tags in the form key=value that the subnets and the worker security group to adopt with --adopt carry")
+	cmd.Flags().StringVar(&opts.VPCOwnerRoleARN, "vpc-owner-role-arn", opts.VPCOwnerRoleARN, "The ARN of a role in the account that owns the VPC given by --vpc-id to assume with the given credentials, required when the VPC is shared with the account of the cluster through AWS Resource Access Manager. The private hosted zones are created in that account")
+	cmd.Flags().StringVar(&opts.VPCOwnerExternalID, "vpc-owner-external-id", opts.VPCOwnerExternalID, "The external ID to pass when assuming the role given by --vpc-owner-role-arn")
+	cmd.Flags().StringVar(&opts.VPCCIDR, "vpc-cidr", opts.VPCCIDR, "The primary IPv4 CIDR of the VPC. Must be between /16 and /24; the subnets are carved out of it. Ignored with --vpc-id")
+	cmd.Flags().StringSliceVar(&opts.SecondaryCIDRs, "secondary-cidrs", opts.SecondaryCIDRs, "Additional IPv4 CIDR blocks to associate with the VPC")
+	cmd.Flags().StringVar(&opts.ClusterCIDR, "cluster-cidr", opts.ClusterCIDR, "The CIDR of the cluster network. If set, it is validated to not overlap with the VPC CIDRs")
+	cmd.Flags().StringVar(&opts.ServiceCIDR, "service-cidr", opts.ServiceCIDR, "The CIDR of the service network. If set, it is validated to not overlap with the VPC CIDRs")
+	cmd.Flags().BoolVar(&opts.EnableIPv6, "enable-ipv6", opts.EnableIPv6, "If true, associate an Amazon provided IPv6 CIDR with the VPC and create dual-stack subnets with IPv6 routes to the internet")
+	cmd.Flags().StringArrayVar(&opts.AdditionalIngressRules, "additional-ingress-rule", opts.AdditionalIngressRules, "An additional ingress rule for the worker security group of the form protocol:port:cidr, e.g. tcp:443:192.168.0.0/16. The protocol is one of tcp, udp, icmp or all, the port may be a range such as 8000-8100. Can be specified multiple times")
+	cmd.Flags().StringSliceVar(&opts.SSHIngressCIDRs, "ssh-ingress-cidr", opts.SSHIngressCIDRs, "The CIDRs to allow SSH to the workers and the proxy host from. Defaults to the VPC CIDRs. Rules allowing SSH or ICMP from other networks, e.g. left behind by a previous run, are revoked")
+	cmd.Flags().BoolVar(&opts.RestrictEgress, "restrict-egress", opts.RestrictEgress, "If true, the worker security group only allows egress to the VPC CIDRs, to the CIDRs given by --egress-cidr and HTTPS to the S3 gateway endpoint instead of to 0.0.0.0/0. An existing rule allowing all egress is revoked. The AWS APIs must be reachable through the VPC, e.g. with --create-vpc-endpoints or a proxy")
+	cmd.Flags().StringSliceVar(&opts.EgressCIDRs, "egress-cidr", opts.EgressCIDRs, "Additional CIDRs the workers may reach with --restrict-egress, e.g. that of an HTTP proxy outside of the VPC. Can be specified multiple times")
+	cmd.Flags().StringVar(&opts.PrivateLinkNLBARN, "private-link-nlb-arn", opts.PrivateLinkNLBARN, "The ARN of a network load balancer in the region to expose through a VPC endpoint service. An interface endpoint for the service is created in the private subnets")
+	cmd.Flags().StringSliceVar(&opts.PrivateLinkPrincipals, "private-link-allowed-principals", opts.PrivateLinkPrincipals, "The ARNs of the principals allowed to connect to the endpoint service created for --private-link-nlb-arn")
+	cmd.Flags().BoolVar(&opts.CreateVPCEndpoints, "create-vpc-endpoints", opts.CreateVPCEndpoints, "If true, create interface endpoints with private DNS for EC2, Elastic Load Balancing, STS and ECR in the private subnets, so that together with the S3 gateway endpoint the AWS APIs can be reached without NAT gateways or a proxy, e.g. with --nat-topology none. The endpoint IDs are written to the output")
+	cmd.Flags().BoolVar(&opts.CreateKMSKey, "create-kms-key", opts.CreateKMSKey, "If true, create a customer managed KMS key for the cluster that can be used for EBS volume and etcd encryption. Its ARN is written to the output")
+	cmd.Flags().StringVar(&opts.KMSKeyARN, "kms-key-arn", opts.KMSKeyARN, "The ARN of an existing KMS key to write to the output instead of creating one. The key must be enabled")
+	cmd.Flags().StringVar(&opts.PublicZoneID, "public-zone-id", opts.PublicZoneID, "The ID of an existing public hosted zone for the base domain to use instead of looking it up by name")
+	cmd.Flags().StringVar(&opts.PrivateZoneID, "private-zone-id", opts.PrivateZoneID, "The ID of an existing private hosted zone for <name>.<base domain> to use instead of creating one. The VPC is associated with it")
+	cmd.Flags().StringVar(&opts.ParentZoneID, "parent-zone-id", opts.ParentZoneID, "The ID of a public hosted zone of a parent domain of the base domain. A public zone for the base domain is created if it does not exist, and NS records delegating to it are created in the parent zone")
+	cmd.Flags().StringVar(&opts.ParentZoneRoleARN, "parent-zone-role-arn", opts.ParentZoneRoleARN, "The ARN of a role to assume with the given credentials to change the zone given by --parent-zone-id, e.g. when it is owned by another account")
+	cmd.Flags().StringVar(&opts.ParentZoneExternalID, "parent-zone-external-id", opts.ParentZoneExternalID, "The external ID to pass when assuming the role given by --parent-zone-role-arn")
+	cmd.Flags().StringVar(&opts.SSHKeyFile, "ssh-key-file", opts.SSHKeyFile, "Path to a file with an SSH public key that is authorized on the proxy and bastion hosts")
+	cmd.Flags().BoolVar(&opts.Bastion, "bastion", opts.Bastion, "If true, create a bastion host in the public subnet of the first zone for debugging private clusters. It also runs an HTTP proxy on port 3128 that can be reached from the VPC (requires --ssh-key-file and --bastion-allowed-ssh-cidrs)")
+	cmd.Flags().StringSliceVar(&opts.BastionAllowedSSHCIDRs, "bastion-allowed-ssh-cidrs", opts.BastionAllowedSSHCIDRs, "The CIDRs from which SSH to the bastion host is allowed")
+	cmd.Flags().BoolVar(&opts.InstanceConnectEndpoint, "instance-connect-endpoint", opts.InstanceConnectEndpoint, "If true, create an EC2 Instance Connect Endpoint in the private subnet of the first zone, through which the workers can be reached with SSH for debugging without a bastion host")
+	cmd.Flags().BoolVar(&opts.EnableFlowLogs, "enable-flow-logs", opts.EnableFlowLogs, "If true, create a flow log that captures all traffic of the VPC (requires --flow-logs-destination)")
+	cmd.Flags().StringVar(&opts.FlowLogsDestination, "flow-logs-destination", opts.FlowLogsDestination, "The ARN of an existing CloudWatch Logs log group in the region or S3 bucket, optionally with a prefix, to deliver the flow log to. A role that allows delivery to a log group is created")
+	cmd.Flags().StringVar(&opts.OutpostARN, "outpost-arn", opts.OutpostARN, "The ARN of an AWS Outpost to create an additional private subnet on, whose default route targets the local gateway of the Outpost. The subnet is written to the output so that NodePools can be placed on the Outpost (requires --outpost-zone)")
+	cmd.Flags().StringVar(&opts.OutpostZone, "outpost-zone", opts.OutpostZone, "The availability zone the Outpost given by --outpost-arn is anchored to")
+	cmd.Flags().StringVar(&opts.TransitGatewayID, "transit-gateway-id", opts.TransitGatewayID, "The ID of an existing transit gateway to attach the VPC to through the private subnets, e.g. to reach corporate networks")
+	cmd.Flags().StringVar(&opts.TransitGatewayRouteTableID, "transit-gateway-route-table-id", opts.TransitGatewayRouteTableID, "The ID of a route table of the transit gateway given by --transit-gateway-id to associate the VPC attachment with and propagate the VPC routes to. Defaults to the default route table settings of the transit gateway")
+	cmd.Flags().StringSliceVar(&opts.TransitGatewayRoutes, "transit-gateway-routes", opts.TransitGatewayRoutes, "The CIDRs, e.g. of on-premises networks, to route from the private subnets to the transit gateway given by --transit-gateway-id")
+	cmd.Flags().BoolVar(&opts.CheckQuotas, "check-quotas", opts.CheckQuotas, "If true, check the VPC and EC2 service quotas of the account before creating anything, and fail with a report of the quotas that the infrastructure would exceed")
+	cmd.Flags().StringVar(&opts.ProgressOutput, "progress-output", opts.ProgressOutput, "Path to a file to write progress events to as JSON lines, or - for stdout. Each phase emits a started and a completed or failed event with the resource ID, duration and error")
+	cmd.Flags().StringVar(&opts.StateFile, "state-file", opts.StateFile, "Path to a file to record the IDs of the created resources in after every completed phase. If it exists, a previous run that failed is resumed by skipping the phases it completed. The file can be passed to destroy infra aws. Ignored with --dry-run")
+	cmd.Flags().IntVar(&opts.Parallelism, "parallelism", opts.Parallelism, "The maximum number of resources to create concurrently. Independent resources, such as the subnets and NAT gateways of different zones, are created in parallel; 1 creates them one at a time")
+	cmd.Flags().BoolVar(&opts.DryRun, "dry-run", opts.DryRun, "If true, only resolve existing resources and print a plan of the resources that would be created or modified, without changing anything")
+	cmd.Flags().DurationVar(&opts.Timeout, "timeout", opts.Timeout, "The maximum duration of the command, e.g. 30m. In-flight AWS operations are aborted when it is exceeded or the command is interrupted; 0 means no timeout")
 
 	cmd.MarkFlagRequired("infra-id")
-	cmd.MarkFlagRequired("aws-creds")
 	cmd.MarkFlagRequired("base-domain")
 
 	l := log.Log
 	cmd.RunE = func(cmd *cobra.Command, args []string) error {
-		if err := opts.Run(cmd.Context(), l); err != nil {
+		ctx := cmd.Context()
+		if opts.Timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+			defer cancel()
+		}
+		if err := opts.Run(ctx, l); err != nil {
 			l.Error(err, "Failed to create infrastructure")
 			return err
 		}
+		if opts.DryRun || opts.OutputFormat == OutputFormatCloudFormation || opts.OutputFormat == OutputFormatTerraform {
+			l.Info("Successfully planned infrastructure")
+			return nil
+		}
+		if opts.Adopt {
+			l.Info("Successfully adopted infrastructure")
+			return nil
+		}
 		l.Info("Successfully created infrastructure")
 		return nil
 	}
@@ -111,9 +275,43 @@ func NewCreateCommand() *cobra.Command {
 }
 
 func (o *CreateInfraOptions) Run(ctx context.Context, l logr.Logger) error {
-	result, err := o.CreateInfra(ctx, l)
-	if err != nil {
-		return err
+	defer logThrottledRequests(l)
+	var outputBytes []byte
+	switch o.OutputFormat {
+	case OutputFormatJSON, "":
+		var closeProgress func() error
+		var err error
+		o.progress, closeProgress, err = openProgressOutput("create", o.InfraID, o.ProgressOutput)
+		if err != nil {
+			return err
+		}
+		defer closeProgress()
+		result, err := o.CreateInfra(ctx, l)
+		if err != nil {
+			return err
+		}
+		var output interface{} = result
+		if o.DryRun {
+			output = o.plan
+		}
+		outputBytes, err = json.MarshalIndent(output, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to serialize result: %w", err)
+		}
+	case OutputFormatCloudFormation:
+		var err error
+		outputBytes, err = o.CloudFormationTemplate()
+		if err != nil {
+			return fmt.Errorf("failed to render CloudFormation template: %w", err)
+		}
+	case OutputFormatTerraform:
+		var err error
+		outputBytes, err = o.TerraformConfiguration()
+		if err != nil {
+			return fmt.Errorf("failed to render terraform configuration: %w", err)
+		}
+	default:
+		return fmt.Errorf("unsupported output format %q", o.OutputFormat)
 	}
 	out := os.Stdout
 	if len(o.OutputFile) > 0 {
@@ -124,11 +322,7 @@ func (o *CreateInfraOptions) Run(ctx context.Context, l logr.Logger) error {
 		}
 		defer out.Close()
 	}
-	outputBytes, err := json.MarshalIndent(result, "", "  ")
-	if err != nil {
-		return fmt.Errorf("failed to serialize result: %w", err)
-	}
-	_, err = out.Write(outputBytes)
+	_, err := out.Write(outputBytes)
 	if err != nil {
 		return fmt.Errorf("failed to write result: %w", err)
 	}
@@ -136,108 +330,189 @@ func (o *CreateInfraOptions) Run(ctx context.Context, l logr.Logger) error {
 }
 
 func (o *CreateInfraOptions) CreateInfra(ctx context.Context, l logr.Logger) (*CreateInfraOutput, error) {
-	l.Info("Creating infrastructure", "id", o.InfraID)
+	l.Info("Creating infrastructure", "id", o.InfraID, "dryRun", o.DryRun)
+	o.plan = InfraPlan{}
 
-	awsSession := awsutil.NewSession("cli-create-infra", o.AWSCredentialsFile, o.AWSKey, o.AWSSecretKey, o.Region)
+	if err := awsutil.ValidateCredentials(o.AWSCredentialsFile, o.AWSKey, o.WebIdentityTokenFile, o.WebIdentityRoleARN); err != nil {
+		return nil, err
+	}
+	baseSession := awsutil.NewSessionWithWebIdentity("cli-create-infra", o.AWSCredentialsFile, o.AWSKey, o.AWSSecretKey, o.WebIdentityTokenFile, o.WebIdentityRoleARN, o.Region)
+	awsSession, err := assumeRole(baseSession, "cli-create-infra", o.RoleARN, o.ExternalID, o.InfraID)
+	if err != nil {
+		return nil, err
+	}
 	ec2Client := ec2.New(awsSession, awsutil.NewConfig())
 	route53Client := route53.New(awsSession, awsutil.NewAWSRoute53Config())
+	kmsClient := kms.New(awsSession, awsutil.NewConfig())
+	stsClient := sts.New(awsSession, awsutil.NewConfig())
+	iamClient := iam.New(awsSession, awsutil.NewConfig())
+	parentRoute53Client := parentZoneClient(baseSession, "cli-create-infra", o.ParentZoneRoleARN, o.ParentZoneExternalID)
+	privateZoneClient := vpcOwnerZoneClient(awsSession, "cli-create-infra", o.VPCOwnerRoleARN, o.VPCOwnerExternalID)
 
-	var err error
 	if err = o.parseAdditionalTags(); err != nil {
 		return nil, err
 	}
-	result := &CreateInfraOutput{
-		InfraID:     o.InfraID,
-		MachineCIDR: DefaultCIDRBlock,
-		Region:      o.Region,
-		Name:        o.Name,
-		BaseDomain:  o.BaseDomain,
+	if err = o.parseAdditionalIngressRules(); err != nil {
+		return nil, err
 	}
-	if len(o.Zones) == 0 {
-		zone, err := o.firstZone(l, ec2Client)
-		if err != nil {
-			return nil, err
-		}
-		o.Zones = append(o.Zones, zone)
+	if err = o.validateExistingVPCOptions(); err != nil {
+		return nil, err
 	}
-
-	// VPC resources
-	result.VPCID, err = o.createVPC(l, ec2Client)
-	if err != nil {
+	if err = o.validateAdoptOptions(); err != nil {
 		return nil, err
 	}
-	if err = o.CreateDHCPOptions(l, ec2Client, result.VPCID); err != nil {
+	if err = o.validateSharedVPCOptions(); err != nil {
 		return nil, err
 	}
-	igwID, err := o.CreateInternetGateway(l, ec2Client, result.VPCID)
-	if err != nil {
+	if err = o.validatePrivateLinkOptions(); err != nil {
 		return nil, err
 	}
-	result.SecurityGroupID, err = o.CreateWorkerSecurityGroup(ec2Client, result.VPCID)
-	if err != nil {
+	if err = o.validateVPCEndpointsOptions(); err != nil {
 		return nil, err
 	}
-
-	// Per zone resources
-	var endpointRouteTableIds []*string
-	var publicSubnetIDs []string
-	_, privateNetwork, err := net.ParseCIDR(basePrivateSubnetCIDR)
-	if err != nil {
+	if err = o.validateKMSKeyOptions(); err != nil {
 		return nil, err
 	}
-	_, publicNetwork, err := net.ParseCIDR(basePublicSubnetCIDR)
-	if err != nil {
+	if err = o.validateHostedZoneOptions(); err != nil {
 		return nil, err
 	}
-	for _, zone := range o.Zones {
-		privateSubnetID, err := o.CreatePrivateSubnet(l, ec2Client, result.VPCID, zone, privateNetwork.String())
-		if err != nil {
+	if err = o.validateNATTopology(); err != nil {
+		return nil, err
+	}
+	if err = o.validatePublicIPv4PoolOptions(); err != nil {
+		return nil, err
+	}
+	if err = o.validateBastionOptions(); err != nil {
+		return nil, err
+	}
+	if err = o.validateInstanceConnectEndpointOptions(); err != nil {
+		return nil, err
+	}
+	if err = o.validateFlowLogsOptions(); err != nil {
+		return nil, err
+	}
+	if err = o.validateOutpostOptions(); err != nil {
+		return nil, err
+	}
+	if err = o.validateTransitGatewayOptions(); err != nil {
+		return nil, err
+	}
+	if err = o.validateCIDRs(); err != nil {
+		return nil, err
+	}
+	if err = o.validateZones(); err != nil {
+		return nil, err
+	}
+	if err = o.validateParallelism(); err != nil {
+		return nil, err
+	}
+	if err = o.lookupS3PrefixList(ctx, ec2Client); err != nil {
+		return nil, err
+	}
+	if err = o.checkPublicIPv4Pool(ctx, l, ec2Client); err != nil {
+		return nil, err
+	}
+	if o.Adopt {
+		return o.adoptInfra(ctx, l, ec2Client, route53Client, privateZoneClient, kmsClient, stsClient)
+	}
+	if o.CheckQuotas {
+		if err = o.progress.run("quotas", "", func() (string, error) {
+			return "", o.CheckServiceQuotas(ctx, l, ec2Client, newServiceQuotasClient(awsSession))
+		}); err != nil {
 			return nil, err
 		}
-		publicSubnetID, err := o.CreatePublicSubnet(l, ec2Client, result.VPCID, zone, publicNetwork.String())
-		if err != nil {
-			return nil, err
+	}
+	result, err := o.loadState()
+	if err != nil {
+		return nil, err
+	}
+	if result == nil {
+		result = &CreateInfraOutput{
+			InfraID:     o.InfraID,
+			MachineCIDR: o.vpcCIDR(),
+			Region:      o.Region,
+			Name:        o.Name,
+			BaseDomain:  o.BaseDomain,
 		}
-		var natGatewayID string
-		publicSubnetIDs = append(publicSubnetIDs, publicSubnetID)
-		if !o.EnableProxy {
-			natGatewayID, err = o.CreateNATGateway(l, ec2Client, publicSubnetID, zone)
-			if err != nil {
-				return nil, err
+	}
+	runVPCPhase := o.runPhase
+	if len(o.VPCID) > 0 {
+		// Augmenting an existing VPC also resolves the accounts of a shared VPC,
+		// which later phases depend on, so it is never skipped
+		runVPCPhase = func(_ *CreateInfraOutput, phase, resourceType string, fn func() (string, error)) error {
+			return o.runPhase(result, phase, resourceType, fn)
+		}
+	}
+	if err = runVPCPhase(result, "vpc", "vpc", func() (string, error) {
+		var err error
+		if len(o.VPCID) > 0 {
+			if o.accountID, err = callerAccountID(ctx, stsClient); err != nil {
+				return "", err
 			}
+			err = o.augmentExistingVPC(ctx, l, ec2Client, result)
+		} else {
+			err = o.createVPCResources(ctx, l, ec2Client, result)
 		}
-		privateRouteTable, err := o.CreatePrivateRouteTable(l, ec2Client, result.VPCID, natGatewayID, privateSubnetID, zone)
-		if err != nil {
+		return result.VPCID, err
+	}); err != nil {
+		return nil, err
+	}
+	if len(o.TransitGatewayID) > 0 {
+		if err = o.runPhase(result, "transit-gateway", "transit-gateway-attachment", func() (string, error) {
+			err := o.createTransitGatewayResources(ctx, l, ec2Client, result)
+			if result.TransitGateway == nil {
+				return "", err
+			}
+			return result.TransitGateway.AttachmentID, err
+		}); err != nil {
 			return nil, err
 		}
-		endpointRouteTableIds = append(endpointRouteTableIds, aws.String(privateRouteTable))
-		result.Zones = append(result.Zones, &CreateInfraOutputZone{
-			Name:     zone,
-			SubnetID: privateSubnetID,
-		})
-		// increment each subnet by /20
-		privateNetwork.IP[2] = privateNetwork.IP[2] + 16
-		publicNetwork.IP[2] = publicNetwork.IP[2] + 16
 	}
-	publicRouteTable, err := o.CreatePublicRouteTable(l, ec2Client, result.VPCID, igwID, publicSubnetIDs)
-	if err != nil {
-		return nil, err
+	if o.EnableFlowLogs {
+		if err = o.runPhase(result, "flow-logs", "vpc-flow-log", func() (string, error) {
+			err := o.createFlowLogResources(ctx, l, ec2Client, iamClient, result)
+			return result.FlowLogID, err
+		}); err != nil {
+			return nil, err
+		}
 	}
-	endpointRouteTableIds = append(endpointRouteTableIds, aws.String(publicRouteTable))
-	err = o.CreateVPCS3Endpoint(l, ec2Client, result.VPCID, endpointRouteTableIds)
-	if err != nil {
+	if err = o.runPhase(result, "kms-key", "kms-key", func() (string, error) {
+		result.KMSKeyARN, err = o.kmsKey(ctx, l, kmsClient, stsClient)
+		return result.KMSKeyARN, err
+	}); err != nil {
 		return nil, err
 	}
-	result.PublicZoneID, err = o.LookupPublicZone(ctx, route53Client)
-	if err != nil {
+	if len(o.PrivateLinkNLBARN) > 0 {
+		if err = o.runPhase(result, "private-link", "vpc-endpoint", func() (string, error) {
+			err := o.createPrivateLinkResources(ctx, l, ec2Client, result)
+			return result.PrivateLinkEndpointID, err
+		}); err != nil {
+			return nil, err
+		}
+	}
+	if o.CreateVPCEndpoints {
+		if err = o.runPhase(result, "vpc-endpoints", "vpc-endpoint", func() (string, error) {
+			return "", o.createVPCEndpointResources(ctx, l, ec2Client, result)
+		}); err != nil {
+			return nil, err
+		}
+	}
+	if err = o.runPhase(result, "public-zone", "hosted-zone", func() (string, error) {
+		result.PublicZoneID, err = o.publicZone(ctx, route53Client, parentRoute53Client)
+		return result.PublicZoneID, err
+	}); err != nil {
 		return nil, err
 	}
-	result.PrivateZoneID, err = o.CreatePrivateZone(ctx, route53Client, fmt.Sprintf("%s.%s", o.Name, o.BaseDomain), result.VPCID)
-	if err != nil {
+	if err = o.runPhase(result, "private-zone", "hosted-zone", func() (string, error) {
+		result.PrivateZoneID, err = o.privateZone(ctx, privateZoneClient, result.VPCID)
+		return result.PrivateZoneID, err
+	}); err != nil {
 		return nil, err
 	}
-	result.LocalZoneID, err = o.CreatePrivateZone(ctx, route53Client, fmt.Sprintf("%s.%s", o.Name, hypershiftLocalZoneName), result.VPCID)
-	if err != nil {
+	if err = o.runPhase(result, "local-zone", "hosted-zone", func() (string, error) {
+		result.LocalZoneID, err = o.CreatePrivateZone(ctx, privateZoneClient, fmt.Sprintf("%s.%s", o.Name, hypershiftLocalZoneName), result.VPCID)
+		return result.LocalZoneID, err
+	}); err != nil {
 		return nil, err
 	}
 
@@ -249,101 +524,385 @@ func (o *CreateInfraOptions) CreateInfra(ctx context.Context, l logr.Logger) (*C
 				return nil, fmt.Errorf("failed to read ssh-key-file from %s: %w", o.SSHKeyFile, err)
 			}
 		}
-		result.ProxyAddr, err = o.createProxyHost(ctx, l, ec2Client, result.Zones[0].SubnetID, result.VPCID, string(sshKeyFile))
-		if err != nil {
+		if err = o.runPhase(result, "proxy", "http-proxy", func() (string, error) {
+			result.ProxyAddr, err = o.createProxyHost(ctx, l, ec2Client, result.Zones[0].SubnetID, result.VPCID, string(sshKeyFile))
+			return result.ProxyAddr, err
+		}); err != nil {
 			return nil, fmt.Errorf("failed to create proxy host: %w", err)
 		}
 
 	}
+	if err = o.runPhase(result, "tags", "", func() (string, error) {
+		return "", o.ReconcileEC2Tags(ctx, l, ec2Client)
+	}); err != nil {
+		return nil, err
+	}
+	if o.Bastion {
+		if err = o.runPhase(result, "bastion", "instance", func() (string, error) {
+			err := o.CreateBastion(ctx, l, ec2Client, result.VPCID, result.Zones[0].PublicSubnetID, result)
+			return result.BastionInstanceID, err
+		}); err != nil {
+			return nil, fmt.Errorf("failed to create bastion host: %w", err)
+		}
+	}
+	if o.InstanceConnectEndpoint {
+		if err = o.runPhase(result, "instance-connect-endpoint", "instance-connect-endpoint", func() (string, error) {
+			err := o.CreateInstanceConnectEndpoint(ctx, l, ec2Client, newInstanceConnectEndpointClient(ec2Client), result)
+			return result.InstanceConnectEndpointID, err
+		}); err != nil {
+			return nil, err
+		}
+	}
+	if o.isSharedVPC() {
+		result.SharedVPC = o.sharedVPCOutput(result)
+	}
 	return result, nil
 }
 
-func (o *CreateInfraOptions) createProxyHost(ctx context.Context, l logr.Logger, client ec2iface.EC2API, subnetID, vpcID string, sshKeys string) (string, error) {
-	const securityGroupName = "proxy-sg"
-	sgCreateResult, err := client.CreateSecurityGroupWithContext(ctx, &ec2.CreateSecurityGroupInput{
-		GroupName:         aws.String(securityGroupName),
-		Description:       aws.String("proxy security group"),
-		VpcId:             aws.String(vpcID),
-		TagSpecifications: o.ec2TagSpecifications("security-group", securityGroupName),
-	})
-	if err != nil {
-		return "", fmt.Errorf("failed to create bastion security group: %w", err)
+// createVPCResources creates the VPC, worker security group, and the subnets, NAT
+// gateways and route tables of each zone.
+func (o *CreateInfraOptions) createVPCResources(ctx context.Context, l logr.Logger, ec2Client ec2iface.EC2API, result *CreateInfraOutput) error {
+	var err error
+	if o.Zones, err = o.selectZones(ctx, l, ec2Client); err != nil {
+		return err
 	}
 
-	var sgResult *ec2.DescribeSecurityGroupsOutput
-	err = retry.OnError(ec2Backoff(), func(error) bool { return true }, func() error {
-		var err error
-		sgResult, err = client.DescribeSecurityGroupsWithContext(ctx, &ec2.DescribeSecurityGroupsInput{
-			GroupIds: []*string{sgCreateResult.GroupId},
-		})
-		if err != nil || len(sgResult.SecurityGroups) == 0 {
-			return errors.New("not found yet")
+	// VPC resources
+	result.VPCID, err = o.createVPC(ctx, l, ec2Client)
+	if err != nil {
+		return err
+	}
+	// The resources in the VPC are created as a graph of tasks, so that the
+	// independent ones, such as the subnets and NAT gateways of different zones,
+	// are created concurrently
+	var (
+		igwID, egressOnlyIGWID string
+		singleNATGatewayID     string
+		publicRouteTableID     string
+		outpostRouteTableID    string
+	)
+	privateCIDRs, publicCIDRs, err := subnetCIDRs(o.vpcCIDR(), len(o.Zones))
+	if err != nil {
+		return err
+	}
+	zones := make([]*CreateInfraOutputZone, len(o.Zones))
+	natGatewayIDs := make([]string, len(o.Zones))
+	privateRouteTableIDs := make([]string, len(o.Zones))
+	tasks := []dagTask{
+		{name: "dhcp-options", run: func(ctx context.Context) error {
+			return o.CreateDHCPOptions(ctx, l, ec2Client, result.VPCID)
+		}},
+		{name: "internet-gateway", run: func(ctx context.Context) (err error) {
+			igwID, err = o.CreateInternetGateway(ctx, l, ec2Client, result.VPCID)
+			return err
+		}},
+	}
+	if o.EnableIPv6 {
+		tasks = append(tasks,
+			dagTask{name: "ipv6-cidr", run: func(ctx context.Context) (err error) {
+				result.IPv6CIDR, err = o.ensureVPCIPv6CIDR(ctx, l, ec2Client, result.VPCID)
+				return err
+			}},
+			dagTask{name: "egress-only-internet-gateway", run: func(ctx context.Context) (err error) {
+				egressOnlyIGWID, err = o.CreateEgressOnlyInternetGateway(ctx, l, ec2Client, result.VPCID)
+				return err
+			}},
+		)
+	}
+	tasks = append(tasks, dagTask{name: "security-group", run: func(ctx context.Context) (err error) {
+		result.SecurityGroupID, err = o.CreateWorkerSecurityGroup(ctx, ec2Client, result.VPCID)
+		return err
+	}})
+	publicRouteTableDeps := []string{"internet-gateway"}
+	s3EndpointDeps := []string{"public-route-table"}
+	for i, zone := range o.Zones {
+		i, zone := i, zone
+		zones[i] = &CreateInfraOutputZone{Name: zone}
+		privateSubnet, publicSubnet := "private-subnet-"+zone, "public-subnet-"+zone
+		tasks = append(tasks,
+			dagTask{name: privateSubnet, run: func(ctx context.Context) (err error) {
+				zones[i].SubnetID, err = o.CreatePrivateSubnet(ctx, l, ec2Client, result.VPCID, zone, privateCIDRs[i])
+				return err
+			}},
+			dagTask{name: publicSubnet, run: func(ctx context.Context) (err error) {
+				zones[i].PublicSubnetID, err = o.CreatePublicSubnet(ctx, l, ec2Client, result.VPCID, zone, publicCIDRs[i])
+				return err
+			}},
+		)
+		if o.EnableIPv6 {
+			tasks = append(tasks, dagTask{name: "subnet-ipv6-" + zone, deps: []string{"ipv6-cidr", privateSubnet, publicSubnet}, run: func(ctx context.Context) error {
+				return o.enableZoneIPv6(ctx, l, ec2Client, result.IPv6CIDR, i, zones[i].SubnetID, zones[i].PublicSubnetID)
+			}})
+		}
+		privateRouteTableDeps := []string{privateSubnet}
+		if o.EnableIPv6 {
+			privateRouteTableDeps = append(privateRouteTableDeps, "egress-only-internet-gateway")
+		}
+		switch o.natTopology() {
+		case NATTopologyPerZone:
+			tasks = append(tasks, dagTask{name: "nat-gateway-" + zone, deps: []string{"internet-gateway", publicSubnet}, run: func(ctx context.Context) (err error) {
+				natGatewayIDs[i], err = o.CreateNATGateway(ctx, l, ec2Client, zones[i].PublicSubnetID, zone)
+				return err
+			}})
+			privateRouteTableDeps = append(privateRouteTableDeps, "nat-gateway-"+zone)
+		case NATTopologySingle:
+			// The NAT gateway of the first zone is shared by all zones
+			if i == 0 {
+				tasks = append(tasks, dagTask{name: "nat-gateway", deps: []string{"internet-gateway", publicSubnet}, run: func(ctx context.Context) (err error) {
+					singleNATGatewayID, err = o.CreateNATGateway(ctx, l, ec2Client, zones[i].PublicSubnetID, zone)
+					return err
+				}})
+			}
+			privateRouteTableDeps = append(privateRouteTableDeps, "nat-gateway")
 		}
+		tasks = append(tasks, dagTask{name: "private-route-table-" + zone, deps: privateRouteTableDeps, run: func(ctx context.Context) error {
+			natGatewayID := natGatewayIDs[i]
+			if o.natTopology() == NATTopologySingle {
+				natGatewayID = singleNATGatewayID
+			}
+			routeTableID, err := o.CreatePrivateRouteTable(ctx, l, ec2Client, result.VPCID, natGatewayID, zones[i].SubnetID, zone)
+			if err != nil {
+				return err
+			}
+			privateRouteTableIDs[i] = routeTableID
+			if o.EnableIPv6 {
+				return o.ensureIPv6DefaultRoute(ctx, l, ec2Client, routeTableID, "", egressOnlyIGWID)
+			}
+			return nil
+		}})
+		publicRouteTableDeps = append(publicRouteTableDeps, publicSubnet)
+		s3EndpointDeps = append(s3EndpointDeps, "private-route-table-"+zone)
+	}
+	tasks = append(tasks, dagTask{name: "public-route-table", deps: publicRouteTableDeps, run: func(ctx context.Context) error {
+		var publicSubnetIDs []string
+		for _, zone := range zones {
+			publicSubnetIDs = append(publicSubnetIDs, zone.PublicSubnetID)
+		}
+		routeTableID, err := o.CreatePublicRouteTable(ctx, l, ec2Client, result.VPCID, igwID, publicSubnetIDs)
+		if err != nil {
+			return err
+		}
+		publicRouteTableID = routeTableID
+		if o.EnableIPv6 {
+			return o.ensureIPv6DefaultRoute(ctx, l, ec2Client, routeTableID, igwID, "")
+		}
+		return nil
+	}})
+	if len(o.OutpostARN) > 0 {
+		tasks = append(tasks, dagTask{name: "outpost", run: func(ctx context.Context) (err error) {
+			outpostRouteTableID, err = o.createOutpostResources(ctx, l, ec2Client, result)
+			return err
+		}})
+		s3EndpointDeps = append(s3EndpointDeps, "outpost")
+	}
+	tasks = append(tasks, dagTask{name: "s3-endpoint", deps: s3EndpointDeps, run: func(ctx context.Context) error {
+		var endpointRouteTableIds []*string
+		for _, routeTableID := range privateRouteTableIDs {
+			endpointRouteTableIds = append(endpointRouteTableIds, aws.String(routeTableID))
+		}
+		endpointRouteTableIds = append(endpointRouteTableIds, aws.String(publicRouteTableID))
+		if len(outpostRouteTableID) > 0 {
+			endpointRouteTableIds = append(endpointRouteTableIds, aws.String(outpostRouteTableID))
+		}
+		return o.CreateVPCS3Endpoint(ctx, l, ec2Client, result.VPCID, endpointRouteTableIds)
+	}})
+	if err := runDAG(ctx, o.parallelism(), tasks); err != nil {
+		return err
+	}
+	result.Zones = append(result.Zones, zones...)
+	return nil
+}
+
+// augmentExistingVPC validates the existing VPC and subnets and adds the S3
+// endpoint and, unless an existing one is given, the worker security group to the
+// VPC. No subnets, gateways or route tables are created. The S3 endpoint is left
+// to the owner of a shared VPC.
+func (o *CreateInfraOptions) augmentExistingVPC(ctx context.Context, l logr.Logger, ec2Client ec2iface.EC2API, result *CreateInfraOutput) error {
+	routeTableIDs, err := o.useExistingVPC(ctx, l, ec2Client, result)
+	if err != nil {
+		return err
+	}
+	result.SecurityGroupID, err = o.workerSecurityGroup(ctx, l, ec2Client, result.VPCID)
+	if err != nil {
+		return err
+	}
+	if o.isSharedVPC() {
+		// Gateway endpoints change the route tables, which only the VPC owner can do
+		l.Info("Skipping S3 endpoint of VPC shared by another account", "id", result.VPCID, "owner", o.vpcOwnerAccountID)
 		return nil
-	})
+	}
+	endpointID, err := o.existingVPCS3EndpointInVPC(ctx, ec2Client, result.VPCID)
 	if err != nil {
-		return "", fmt.Errorf("cannot find security group that was just created (%s)", aws.StringValue(sgCreateResult.GroupId))
+		return err
 	}
-	sg := sgResult.SecurityGroups[0]
-	l.Info("Created security group", "name", securityGroupName, "id", aws.StringValue(sg.GroupId))
+	if len(endpointID) > 0 {
+		l.Info("Found existing s3 VPC endpoint in VPC", "id", endpointID)
+		return nil
+	}
+	return o.CreateVPCS3Endpoint(ctx, l, ec2Client, result.VPCID, routeTableIDs)
+}
 
-	permissions := []*ec2.IpPermission{
-		{
-			IpProtocol: aws.String("tcp"),
-			IpRanges: []*ec2.IpRange{{
-				CidrIp: aws.String("0.0.0.0/0"),
-			}},
-			FromPort: aws.Int64(22),
-			ToPort:   aws.Int64(22),
-		},
-		{
-			IpProtocol: aws.String("-1"),
-			IpRanges: []*ec2.IpRange{{
-				CidrIp: aws.String("10.0.0.0/8"),
-			}},
-			FromPort: aws.Int64(-1),
-			ToPort:   aws.Int64(-1),
-		},
+const proxySecurityGroupName = "proxy-sg"
+
+func (o *CreateInfraOptions) proxyName() string {
+	return o.Name + "-" + o.InfraID + "-http-proxy"
+}
+
+func (o *CreateInfraOptions) createProxyHost(ctx context.Context, l logr.Logger, client ec2iface.EC2API, subnetID, vpcID string, sshKeys string) (string, error) {
+	securityGroupID, err := o.ensureProxySecurityGroup(ctx, l, client, vpcID)
+	if err != nil {
+		return "", err
 	}
-	_, err = client.AuthorizeSecurityGroupIngressWithContext(ctx, &ec2.AuthorizeSecurityGroupIngressInput{
-		GroupId:       sg.GroupId,
-		IpPermissions: permissions,
-	})
+	instance, err := o.existingInstance(ctx, client, o.proxyName())
 	if err != nil {
-		return "", fmt.Errorf("failed to authorize security group: %w", err)
-	}
-	l.Info("Authorized security group for proxy")
-
-	result, err := client.RunInstancesWithContext(ctx, &ec2.RunInstancesInput{
-		ImageId:      aws.String("resolve:ssm:/aws/service/ami-amazon-linux-latest/amzn2-ami-hvm-x86_64-gp2"),
-		MaxCount:     aws.Int64(1),
-		MinCount:     aws.Int64(1),
-		InstanceType: aws.String("t2.micro"),
-		UserData:     aws.String(base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf(proxyConfigurationScript, sshKeys)))),
-		NetworkInterfaces: []*ec2.InstanceNetworkInterfaceSpecification{
-			{
-				DeviceIndex:              aws.Int64(0),
-				AssociatePublicIpAddress: aws.Bool(true),
-				SubnetId:                 aws.String(subnetID),
-				Groups:                   []*string{sg.GroupId},
+		return "", err
+	}
+	if instance == nil && o.DryRun {
+		return o.planCreate(l, "instance", o.proxyName()), nil
+	}
+	if instance == nil {
+		result, err := client.RunInstancesWithContext(ctx, &ec2.RunInstancesInput{
+			ImageId:      aws.String("resolve:ssm:/aws/service/ami-amazon-linux-latest/amzn2-ami-hvm-x86_64-gp2"),
+			MaxCount:     aws.Int64(1),
+			MinCount:     aws.Int64(1),
+			InstanceType: aws.String("t2.micro"),
+			UserData:     aws.String(base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf(proxyConfigurationScript, sshKeys)))),
+			NetworkInterfaces: []*ec2.InstanceNetworkInterfaceSpecification{
+				{
+					DeviceIndex:              aws.Int64(0),
+					AssociatePublicIpAddress: aws.Bool(true),
+					SubnetId:                 aws.String(subnetID),
+					Groups:                   []*string{aws.String(securityGroupID)},
+				},
 			},
-		},
-		TagSpecifications: o.ec2TagSpecifications("instance", o.Name+"-"+o.InfraID+"-http-proxy"),
-	})
+			TagSpecifications: o.ec2TagSpecifications("instance", o.proxyName()),
+		})
+		if err != nil {
+			return "", fmt.Errorf("failed to launch proxy host: %w", err)
+		}
+		instance = result.Instances[0]
+		l.Info("Created proxy host")
+	} else {
+		l.Info("Found existing proxy host", "id", aws.StringValue(instance.InstanceId))
+	}
+
+	return fmt.Sprintf("http://%s:3128", aws.StringValue(instance.PrivateIpAddress)), nil
+}
+
+// ensureProxySecurityGroup creates the security group of the proxy host if it does
+// not exist yet, authorizes its ingress rules and revokes the ones that are stale,
+// e.g. all traffic from the CIDRs of a previous VPC or SSH from anywhere.
+func (o *CreateInfraOptions) ensureProxySecurityGroup(ctx context.Context, l logr.Logger, client ec2iface.EC2API, vpcID string) (string, error) {
+	securityGroup, err := o.existingSecurityGroup(ctx, client, proxySecurityGroupName)
 	if err != nil {
-		return "", fmt.Errorf("failed to launch proxy host: %w", err)
+		return "", err
+	}
+	if securityGroup == nil && o.DryRun {
+		return o.planCreate(l, "security-group", proxySecurityGroupName), nil
+	}
+	if securityGroup == nil {
+		sgCreateResult, err := client.CreateSecurityGroupWithContext(ctx, &ec2.CreateSecurityGroupInput{
+			GroupName:         aws.String(proxySecurityGroupName),
+			Description:       aws.String("proxy security group"),
+			VpcId:             aws.String(vpcID),
+			TagSpecifications: o.ec2TagSpecifications("security-group", proxySecurityGroupName),
+		})
+		if err != nil {
+			return "", fmt.Errorf("failed to create proxy security group: %w", err)
+		}
+		var sgResult *ec2.DescribeSecurityGroupsOutput
+		err = retryOnError(ctx, ec2Backoff(), isNotFoundYet, func() error {
+			var err error
+			sgResult, err = client.DescribeSecurityGroupsWithContext(ctx, &ec2.DescribeSecurityGroupsInput{
+				GroupIds: []*string{sgCreateResult.GroupId},
+			})
+			if err == nil && len(sgResult.SecurityGroups) == 0 {
+				err = errNotFoundYet
+			}
+			return err
+		})
+		if err != nil {
+			return "", fmt.Errorf("cannot find security group that was just created (%s): %w", aws.StringValue(sgCreateResult.GroupId), err)
+		}
+		securityGroup = sgResult.SecurityGroups[0]
+		l.Info("Created security group", "name", proxySecurityGroupName, "id", aws.StringValue(securityGroup.GroupId))
+	} else {
+		l.Info("Found existing security group", "name", proxySecurityGroupName, "id", aws.StringValue(securityGroup.GroupId))
+	}
+	securityGroupID := aws.StringValue(securityGroup.GroupId)
+
+	permissions := o.proxyIngressPermissions()
+	var ingressToAuthorize []*ec2.IpPermission
+	for _, permission := range permissions {
+		if !includesPermission(securityGroup.IpPermissions, permission) {
+			ingressToAuthorize = append(ingressToAuthorize, permission)
+		}
+	}
+	ingressToRevoke := staleIngressPermissions(securityGroup.IpPermissions, permissions, isProxyManagedPermission)
+	if o.DryRun {
+		if len(ingressToAuthorize) > 0 {
+			o.planModify(l, "security-group", securityGroupID, fmt.Sprintf("authorize %d ingress rules", len(ingressToAuthorize)))
+		}
+		if len(ingressToRevoke) > 0 {
+			o.planModify(l, "security-group", securityGroupID, fmt.Sprintf("revoke %d stale ingress rules", len(ingressToRevoke)))
+		}
+		return securityGroupID, nil
 	}
-	l.Info("Created proxy host")
+	if len(ingressToAuthorize) > 0 {
+		_, err = client.AuthorizeSecurityGroupIngressWithContext(ctx, &ec2.AuthorizeSecurityGroupIngressInput{
+			GroupId:       aws.String(securityGroupID),
+			IpPermissions: ingressToAuthorize,
+		})
+		var awsErr awserr.Error
+		if err != nil && (!errors.As(err, &awsErr) || awsErr.Code() != duplicatePermissionErrorCode) {
+			return "", fmt.Errorf("failed to authorize security group: %w", err)
+		}
+		l.Info("Authorized security group for proxy")
+	}
+	// Stale rules are only revoked once the expected ones are in place, so that
+	// access from networks that are still allowed is never interrupted
+	if len(ingressToRevoke) > 0 {
+		if _, err = client.RevokeSecurityGroupIngressWithContext(ctx, &ec2.RevokeSecurityGroupIngressInput{
+			GroupId:       aws.String(securityGroupID),
+			IpPermissions: ingressToRevoke,
+		}); err != nil {
+			return "", fmt.Errorf("cannot revoke stale proxy security group ingress permissions: %w", err)
+		}
+		l.Info("Revoked stale ingress rules on security group", "id", securityGroupID)
+	}
+	return securityGroupID, nil
+}
 
-	return fmt.Sprintf("http://%s:3128", *result.Instances[0].PrivateIpAddress), nil
+// isProxyManagedPermission returns true for the ingress rules of the proxy security
+// group that are reconciled, i.e. SSH and all traffic.
+func isProxyManagedPermission(permission *ec2.IpPermission) bool {
+	return isAllTrafficPermission(permission) || isSSHPermission(permission)
 }
 
-func ec2Backoff() wait.Backoff {
-	return wait.Backoff{
-		Steps:    10,
-		Duration: 3 * time.Second,
-		Factor:   1.0,
-		Jitter:   0.1,
+// proxyIngressPermissions returns the ingress rules of the proxy security group:
+// SSH from the SSH ingress CIDRs, like for the workers, and all traffic from the
+// machine CIDRs, i.e. the VPC CIDR and its secondary CIDRs, so that the workers can
+// reach the proxy.
+func (o *CreateInfraOptions) proxyIngressPermissions() []*ec2.IpPermission {
+	var machineIPRanges []*ec2.IpRange
+	for _, cidr := range o.machineCIDRs() {
+		machineIPRanges = append(machineIPRanges, &ec2.IpRange{CidrIp: aws.String(cidr)})
+	}
+	var sshIPRanges []*ec2.IpRange
+	for _, cidr := range o.sshIngressCIDRs() {
+		sshIPRanges = append(sshIPRanges, &ec2.IpRange{CidrIp: aws.String(cidr)})
+	}
+	return []*ec2.IpPermission{
+		{
+			IpProtocol: aws.String("tcp"),
+			IpRanges:   sshIPRanges,
+			FromPort:   aws.Int64(22),
+			ToPort:     aws.Int64(22),
+		},
+		{
+			IpProtocol: aws.String("-1"),
+			IpRanges:   machineIPRanges,
+		},
 	}
 }
 
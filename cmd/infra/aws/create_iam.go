@@ -9,6 +9,7 @@ import (
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/spf13/cobra"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -25,10 +26,16 @@ import (
 type CreateIAMOptions struct {
 	Region                          string
 	AWSCredentialsFile              string
+	WebIdentityTokenFile            string
+	WebIdentityRoleARN              string
 	AWSKey                          string
 	AWSSecretKey                    string
+	RoleARN                         string
+	ExternalID                      string
 	OIDCStorageProviderS3BucketName string
 	OIDCStorageProviderS3Region     string
+	OIDCBucket                      string
+	OIDCPublicKeyFile               string
 	PublicZoneID                    string
 	PrivateZoneID                   string
 	LocalZoneID                     string
@@ -37,6 +44,13 @@ type CreateIAMOptions struct {
 	OutputFile                      string
 	KMSKeyARN                       string
 	AdditionalTags                  []string
+	OutputFormat                    string
+	PolicyMode                      string
+	PermissionsBoundaryARN          string
+	IAMPath                         string
+	InstanceProfileName             string
+	WorkerRoleName                  string
+	RequireIMDSv2                   bool
 
 	additionalIAMTags []*iam.Tag
 }
@@ -62,21 +76,37 @@ func NewCreateIAMCommand() *cobra.Command {
 		Region:             "us-east-1",
 		AWSCredentialsFile: "",
 		InfraID:            "",
+		OutputFormat:       OutputFormatJSON,
+		PolicyMode:         PolicyModeCompatible,
+		IAMPath:            "/",
 	}
 
-	cmd.Flags().StringVar(&opts.AWSCredentialsFile, "aws-creds", opts.AWSCredentialsFile, "Path to an AWS credentials file (required)")
+	cmd.Flags().StringVar(&opts.AWSCredentialsFile, "aws-creds", opts.AWSCredentialsFile, "Path to an AWS credentials file (required unless --web-identity-token-file is specified)")
+	cmd.Flags().StringVar(&opts.WebIdentityTokenFile, "web-identity-token-file", opts.WebIdentityTokenFile, "Path to a web identity token, e.g. a projected service account token, to assume the role given by --web-identity-role-arn with instead of using --aws-creds. The token is read again whenever the temporary credentials are refreshed")
+	cmd.Flags().StringVar(&opts.WebIdentityRoleARN, "web-identity-role-arn", opts.WebIdentityRoleARN, "The ARN of the role to assume with the token given by --web-identity-token-file")
 	cmd.Flags().StringVar(&opts.InfraID, "infra-id", opts.InfraID, "Infrastructure ID to use for AWS resources.")
 	cmd.Flags().StringVar(&opts.OIDCStorageProviderS3BucketName, "oidc-storage-provider-s3-bucket-name", "", "The name of the bucket in which the OIDC discovery document is stored")
 	cmd.Flags().StringVar(&opts.OIDCStorageProviderS3Region, "oidc-storage-provider-s3-region", "", "The region of the bucket in which the OIDC discovery document is stored")
+	cmd.Flags().StringVar(&opts.OIDCBucket, "oidc-bucket", opts.OIDCBucket, "The name of an S3 bucket for the OIDC discovery document. The bucket is created in the region of --oidc-storage-provider-s3-region, or --region, if it does not exist. An existing bucket must allow public-read objects")
+	cmd.Flags().StringVar(&opts.OIDCPublicKeyFile, "oidc-public-key-file", opts.OIDCPublicKeyFile, "Path to the PEM encoded public key of the service account signing key of the cluster. If set, the discovery document and the JWKS are uploaded to the bucket given by --oidc-bucket")
 	cmd.Flags().StringVar(&opts.Region, "region", opts.Region, "Region where cluster infra should be created")
+	cmd.Flags().StringVar(&opts.RoleARN, "role-arn", opts.RoleARN, "The ARN of a role to assume with the given credentials, e.g. to create the resources in another account. The infra ID is passed as the hypershift-infra-id session tag")
+	cmd.Flags().StringVar(&opts.ExternalID, "external-id", opts.ExternalID, "The external ID to pass when assuming the role given by --role-arn")
 	cmd.Flags().StringVar(&opts.OutputFile, "output-file", opts.OutputFile, "Path to file that will contain output information from infra resources (optional)")
 	cmd.Flags().StringVar(&opts.PublicZoneID, "public-zone-id", opts.PublicZoneID, "The id of the clusters public route53 zone")
 	cmd.Flags().StringVar(&opts.PrivateZoneID, "private-zone-id", opts.PrivateZoneID, "The id of the cluters private route53 zone")
 	cmd.Flags().StringVar(&opts.LocalZoneID, "local-zone-id", opts.LocalZoneID, "The id of the clusters local route53 zone")
 	cmd.Flags().StringVar(&opts.KMSKeyARN, "kms-key-arn", opts.KMSKeyARN, "The ARN of the KMS key to use for Etcd encryption. If not supplied, etcd encryption will default to using a generated AESCBC key.")
 	cmd.Flags().StringSliceVar(&opts.AdditionalTags, "additional-tags", opts.AdditionalTags, "Additional tags to set on AWS resources")
+	cmd.Flags().StringVar(&opts.OutputFormat, "output-format", opts.OutputFormat, fmt.Sprintf("Output format, one of %q or %q. With %q, a Terraform configuration for the IAM resources is written instead of creating them", OutputFormatJSON, OutputFormatTerraform, OutputFormatTerraform))
+
+	cmd.Flags().StringVar(&opts.PolicyMode, "policy-mode", opts.PolicyMode, fmt.Sprintf("The mode of the permission policies of the component roles, one of %q or %q. With %q, the policies of the control plane operator, node pool management, storage, ingress and image registry roles are scoped to the resources of the region tagged for the cluster, which requires the VPC and subnets to be tagged as done by create infra", PolicyModeCompatible, PolicyModeStrict, PolicyModeStrict))
+	cmd.Flags().StringVar(&opts.PermissionsBoundaryARN, "permissions-boundary-arn", opts.PermissionsBoundaryARN, "The ARN of a managed policy to set as the permissions boundary of all created roles")
+	cmd.Flags().StringVar(&opts.IAMPath, "iam-path", opts.IAMPath, "The path to create the roles and the instance profile under, e.g. /hypershift/. It must begin and end with a slash")
+	cmd.Flags().StringVar(&opts.InstanceProfileName, "instance-profile-name", opts.InstanceProfileName, "The name of the worker instance profile. Defaults to <infra-id>-worker")
+	cmd.Flags().StringVar(&opts.WorkerRoleName, "worker-role-name", opts.WorkerRoleName, "The name of the role of the worker instance profile. Defaults to the instance profile name with a -role suffix")
+	cmd.Flags().BoolVar(&opts.RequireIMDSv2, "require-imdsv2", opts.RequireIMDSv2, "If true, add a policy to the worker role that denies all actions with credentials retrieved from the instance metadata service without a session token (IMDSv1), so that the workers must use IMDSv2")
 
-	cmd.MarkFlagRequired("aws-creds")
 	cmd.MarkFlagRequired("infra-id")
 	cmd.MarkFlagRequired("public-zone-id")
 	cmd.MarkFlagRequired("private-zone-id")
@@ -101,9 +131,28 @@ func NewCreateIAMCommand() *cobra.Command {
 }
 
 func (o *CreateIAMOptions) Run(ctx context.Context, client crclient.Client) error {
-	results, err := o.CreateIAM(ctx, client)
-	if err != nil {
-		return err
+	var outputBytes []byte
+	switch o.OutputFormat {
+	case OutputFormatJSON, "":
+		results, err := o.CreateIAM(ctx, client)
+		if err != nil {
+			return err
+		}
+		outputBytes, err = json.MarshalIndent(results, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to serialize result: %w", err)
+		}
+	case OutputFormatTerraform:
+		if err := o.resolveIssuerURL(ctx, client); err != nil {
+			return err
+		}
+		var err error
+		outputBytes, err = o.TerraformConfiguration()
+		if err != nil {
+			return fmt.Errorf("failed to render terraform configuration: %w", err)
+		}
+	default:
+		return fmt.Errorf("unsupported output format %q", o.OutputFormat)
 	}
 	// Write out stateful information
 	out := os.Stdout
@@ -115,12 +164,7 @@ func (o *CreateIAMOptions) Run(ctx context.Context, client crclient.Client) erro
 		}
 		defer out.Close()
 	}
-	outputBytes, err := json.MarshalIndent(results, "", "  ")
-	if err != nil {
-		return fmt.Errorf("failed to serialize result: %w", err)
-	}
-	_, err = out.Write(outputBytes)
-	if err != nil {
+	if _, err := out.Write(outputBytes); err != nil {
 		return fmt.Errorf("failed to write result: %w", err)
 	}
 	return nil
@@ -128,15 +172,72 @@ func (o *CreateIAMOptions) Run(ctx context.Context, client crclient.Client) erro
 
 func (o *CreateIAMOptions) CreateIAM(ctx context.Context, client crclient.Client) (*CreateIAMOutput, error) {
 	var err error
+	if err = awsutil.ValidateCredentials(o.AWSCredentialsFile, o.AWSKey, o.WebIdentityTokenFile, o.WebIdentityRoleARN); err != nil {
+		return nil, err
+	}
+	if err = o.validatePolicyMode(); err != nil {
+		return nil, err
+	}
+	if err = o.validateIAMPathOptions(); err != nil {
+		return nil, err
+	}
+	if err = validateWorkerNames(o.InstanceProfileName, o.WorkerRoleName); err != nil {
+		return nil, err
+	}
 	if err = o.parseAdditionalTags(); err != nil {
 		return nil, err
 	}
+	if err = o.validateOIDCBucketOptions(); err != nil {
+		return nil, err
+	}
+	if err = o.resolveIssuerURL(ctx, client); err != nil {
+		return nil, err
+	}
+
+	awsSession, err := assumeRole(awsutil.NewSessionWithWebIdentity("cli-create-iam", o.AWSCredentialsFile, o.AWSKey, o.AWSSecretKey, o.WebIdentityTokenFile, o.WebIdentityRoleARN, o.Region), "cli-create-iam", o.RoleARN, o.ExternalID, o.InfraID)
+	if err != nil {
+		return nil, err
+	}
+	awsConfig := awsutil.NewConfig()
+	iamClient := iam.New(awsSession, awsConfig)
+
+	if len(o.OIDCBucket) > 0 {
+		s3Client := s3.New(awsSession, awsConfig.Copy().WithRegion(o.OIDCStorageProviderS3Region))
+		if err = o.ensureOIDCBucket(log.Log, s3Client); err != nil {
+			return nil, err
+		}
+		if len(o.OIDCPublicKeyFile) > 0 {
+			if err = o.UploadOIDCDocuments(log.Log, s3Client); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	results, err := o.CreateOIDCResources(iamClient)
+	if err != nil {
+		return nil, err
+	}
+	profileName := o.profileName()
+	results.ProfileName = profileName
+	results.KMSKeyARN = o.KMSKeyARN
+	err = o.CreateWorkerInstanceProfile(iamClient, profileName)
+	if err != nil {
+		return nil, err
+	}
+	log.Log.Info("Created IAM profile", "name", profileName, "region", o.Region)
+
+	return results, nil
+}
+
+// resolveIssuerURL sets the issuer URL from the OIDC bucket configuration. If the
+// bucket is not configured explicitly, it is discovered from the management cluster.
+func (o *CreateIAMOptions) resolveIssuerURL(ctx context.Context, client crclient.Client) error {
 	if o.OIDCStorageProviderS3BucketName == "" || o.OIDCStorageProviderS3Region == "" {
 		cm := &corev1.ConfigMap{
 			ObjectMeta: metav1.ObjectMeta{Namespace: "kube-public", Name: "oidc-storage-provider-s3-config"},
 		}
 		if err := client.Get(ctx, crclient.ObjectKeyFromObject(cm), cm); err != nil {
-			return nil, fmt.Errorf("failed to discover OIDC bucket configuration: failed to get the %s/%s configmap: %w", cm.Namespace, cm.Name, err)
+			return fmt.Errorf("failed to discover OIDC bucket configuration: failed to get the %s/%s configmap: %w", cm.Namespace, cm.Name, err)
 		}
 		// Set both, doesn't make sense to only get one from the configmap
 		o.OIDCStorageProviderS3BucketName = cm.Data["name"]
@@ -151,30 +252,12 @@ func (o *CreateIAMOptions) CreateIAM(ctx context.Context, client crclient.Client
 		errs = append(errs, errors.New("mandatory --oidc-storage-provider-s3-region could not be discovered from cluster's  ConfigMap in 'kube-public' and wasn't explicitly passed either"))
 	}
 	if err := utilerrors.NewAggregate(errs); err != nil {
-		return nil, err
+		return err
 	}
 
 	o.IssuerURL = oidcDiscoveryURL(o.OIDCStorageProviderS3BucketName, o.OIDCStorageProviderS3Region, o.InfraID)
 	log.Log.Info("Detected Issuer URL", "issuer", o.IssuerURL)
-
-	awsSession := awsutil.NewSession("cli-create-iam", o.AWSCredentialsFile, o.AWSKey, o.AWSSecretKey, o.Region)
-	awsConfig := awsutil.NewConfig()
-	iamClient := iam.New(awsSession, awsConfig)
-
-	results, err := o.CreateOIDCResources(iamClient)
-	if err != nil {
-		return nil, err
-	}
-	profileName := DefaultProfileName(o.InfraID)
-	results.ProfileName = profileName
-	results.KMSKeyARN = o.KMSKeyARN
-	err = o.CreateWorkerInstanceProfile(iamClient, profileName)
-	if err != nil {
-		return nil, err
-	}
-	log.Log.Info("Created IAM profile", "name", profileName, "region", o.Region)
-
-	return results, nil
+	return nil
 }
 
 func (o *CreateIAMOptions) parseAdditionalTags() error {
@@ -195,5 +278,5 @@ func oidcDiscoveryURL(bucketName, region, infraID string) string {
 	if bucketName == "" || region == "" || infraID == "" {
 		panic(fmt.Sprintf("bucket: %q, region: %q, infraID: %q", bucketName, region, infraID))
 	}
-	return fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s", bucketName, region, infraID)
+	return fmt.Sprintf("https://%s.s3.%s.%s/%s", bucketName, region, partitionForRegion(region).DNSSuffix(), infraID)
 }
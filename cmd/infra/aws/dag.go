@@ -0,0 +1,129 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// DefaultParallelism is the number of resources created concurrently by create
+// infra unless --parallelism is given.
+const DefaultParallelism = 4
+
+// dagTask is a node of the dependency graph of the resources created by create
+// infra. It is run once all the tasks named by deps have completed.
+type dagTask struct {
+	name string
+	deps []string
+	run  func(ctx context.Context) error
+}
+
+type dagResult struct {
+	name string
+	err  error
+}
+
+// runDAG runs the tasks in the order given by their dependencies, running at
+// most parallelism independent tasks at a time. Tasks that are ready at the same
+// time are started in the order they are given, so a parallelism of 1 runs them
+// in that order. After the first failure no further tasks are started; the tasks
+// that are running are waited for and the error of the failed task is returned.
+func runDAG(ctx context.Context, parallelism int, tasks []dagTask) error {
+	if parallelism < 1 {
+		parallelism = 1
+	}
+	index := map[string]int{}
+	for i, task := range tasks {
+		if _, exists := index[task.name]; exists {
+			return fmt.Errorf("duplicate task %s", task.name)
+		}
+		index[task.name] = i
+	}
+	pending := make([]int, len(tasks))
+	dependents := make([][]int, len(tasks))
+	for i, task := range tasks {
+		for _, dep := range task.deps {
+			j, exists := index[dep]
+			if !exists {
+				return fmt.Errorf("task %s depends on unknown task %s", task.name, dep)
+			}
+			pending[i]++
+			dependents[j] = append(dependents[j], i)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	results := make(chan dagResult)
+	started := make([]bool, len(tasks))
+	running, completed := 0, 0
+	var firstErr error
+	for {
+		if firstErr == nil {
+			for i := range tasks {
+				if running == parallelism {
+					break
+				}
+				if started[i] || pending[i] > 0 {
+					continue
+				}
+				started[i] = true
+				running++
+				go func(task dagTask) {
+					results <- dagResult{name: task.name, err: task.run(ctx)}
+				}(tasks[i])
+			}
+		}
+		if running == 0 {
+			break
+		}
+		result := <-results
+		running--
+		if result.err != nil {
+			if firstErr == nil {
+				firstErr = result.err
+				cancel()
+			}
+			continue
+		}
+		completed++
+		for _, i := range dependents[index[result.name]] {
+			pending[i]--
+		}
+	}
+	if firstErr != nil {
+		return firstErr
+	}
+	if completed < len(tasks) {
+		var blocked []string
+		for i, task := range tasks {
+			if !started[i] {
+				blocked = append(blocked, task.name)
+			}
+		}
+		sort.Strings(blocked)
+		return fmt.Errorf("dependency cycle between tasks %s", strings.Join(blocked, ", "))
+	}
+	return nil
+}
+
+// parallelism returns the number of resources to create concurrently. A dry run
+// creates nothing and plans the resources one at a time, so that the plan is
+// always in the same order.
+func (o *CreateInfraOptions) parallelism() int {
+	if o.DryRun {
+		return 1
+	}
+	if o.Parallelism == 0 {
+		return DefaultParallelism
+	}
+	return o.Parallelism
+}
+
+func (o *CreateInfraOptions) validateParallelism() error {
+	if o.Parallelism < 0 {
+		return fmt.Errorf("--parallelism must not be negative")
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestRunDAG(t *testing.T) {
+	testCases := []struct {
+		name          string
+		parallelism   int
+		tasks         func(record func(string) func(context.Context) error) []dagTask
+		expectedOrder []string
+		expectedError string
+	}{
+		{
+			name:        "one at a time in dependency order",
+			parallelism: 1,
+			tasks: func(record func(string) func(context.Context) error) []dagTask {
+				return []dagTask{
+					{name: "route-table", deps: []string{"subnet-a", "subnet-b"}, run: record("route-table")},
+					{name: "subnet-a", run: record("subnet-a")},
+					{name: "subnet-b", deps: []string{"subnet-a"}, run: record("subnet-b")},
+				}
+			},
+			expectedOrder: []string{"subnet-a", "subnet-b", "route-table"},
+		},
+		{
+			name:        "dependents of a failed task are not run",
+			parallelism: 1,
+			tasks: func(record func(string) func(context.Context) error) []dagTask {
+				return []dagTask{
+					{name: "subnet", run: func(context.Context) error { return errors.New("access denied") }},
+					{name: "route-table", deps: []string{"subnet"}, run: record("route-table")},
+				}
+			},
+			expectedError: "access denied",
+		},
+		{
+			name:        "unknown dependency",
+			parallelism: 1,
+			tasks: func(record func(string) func(context.Context) error) []dagTask {
+				return []dagTask{{name: "route-table", deps: []string{"subnet"}, run: record("route-table")}}
+			},
+			expectedError: "task route-table depends on unknown task subnet",
+		},
+		{
+			name:        "dependency cycle",
+			parallelism: 2,
+			tasks: func(record func(string) func(context.Context) error) []dagTask {
+				return []dagTask{
+					{name: "vpc", run: record("vpc")},
+					{name: "a", deps: []string{"b"}, run: record("a")},
+					{name: "b", deps: []string{"a", "vpc"}, run: record("b")},
+				}
+			},
+			expectedOrder: []string{"vpc"},
+			expectedError: "dependency cycle between tasks a, b",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewGomegaWithT(t)
+			var order []string
+			record := func(name string) func(context.Context) error {
+				return func(context.Context) error {
+					order = append(order, name)
+					return nil
+				}
+			}
+			err := runDAG(context.Background(), tc.parallelism, tc.tasks(record))
+			if tc.expectedError != "" {
+				g.Expect(err).To(MatchError(tc.expectedError))
+			} else {
+				g.Expect(err).ToNot(HaveOccurred())
+			}
+			g.Expect(order).To(Equal(tc.expectedOrder))
+		})
+	}
+}
+
+func TestRunDAGParallelism(t *testing.T) {
+	g := NewGomegaWithT(t)
+	var lock sync.Mutex
+	running, maxRunning := 0, 0
+	run := func(context.Context) error {
+		lock.Lock()
+		running++
+		if running > maxRunning {
+			maxRunning = running
+		}
+		lock.Unlock()
+		time.Sleep(10 * time.Millisecond)
+		lock.Lock()
+		running--
+		lock.Unlock()
+		return nil
+	}
+	var tasks []dagTask
+	for _, name := range []string{"a", "b", "c", "d", "e"} {
+		tasks = append(tasks, dagTask{name: name, run: run})
+	}
+	g.Expect(runDAG(context.Background(), 2, tasks)).To(Succeed())
+	g.Expect(maxRunning).To(Equal(2))
+}
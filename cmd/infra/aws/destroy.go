@@ -2,7 +2,10 @@ package aws
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"os"
 	"strings"
 	"time"
 
@@ -14,6 +17,8 @@ import (
 	"github.com/aws/aws-sdk-go/service/elb/elbiface"
 	"github.com/aws/aws-sdk-go/service/elbv2"
 	"github.com/aws/aws-sdk-go/service/elbv2/elbv2iface"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/aws/aws-sdk-go/service/kms"
 	"github.com/aws/aws-sdk-go/service/route53"
 	"github.com/aws/aws-sdk-go/service/route53/route53iface"
 	"github.com/aws/aws-sdk-go/service/s3"
@@ -23,6 +28,7 @@ import (
 	"github.com/spf13/cobra"
 
 	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/apimachinery/pkg/util/wait"
 
 	awsutil "github.com/openshift/hypershift/cmd/infra/aws/util"
@@ -30,14 +36,29 @@ import (
 )
 
 type DestroyInfraOptions struct {
-	Region             string
-	InfraID            string
-	AWSCredentialsFile string
-	AWSKey             string
-	AWSSecretKey       string
-	Name               string
-	BaseDomain         string
-	Log                logr.Logger
+	Region               string
+	InfraID              string
+	AWSCredentialsFile   string
+	WebIdentityTokenFile string
+	WebIdentityRoleARN   string
+	AWSKey               string
+	AWSSecretKey         string
+	RoleARN              string
+	ExternalID           string
+	Name                 string
+	BaseDomain           string
+	ParentZoneID         string
+	ParentZoneRoleARN    string
+	ParentZoneExternalID string
+	ProgressOutput       string
+	StateFile            string
+	DryRun               bool
+	Timeout              time.Duration
+	Log                  logr.Logger
+
+	progress *progressReporter
+	plan     DestroyPlan
+	state    *InfraState
 }
 
 func NewDestroyCommand() *cobra.Command {
@@ -54,20 +75,40 @@ func NewDestroyCommand() *cobra.Command {
 	}
 
 	cmd.Flags().StringVar(&opts.InfraID, "infra-id", opts.InfraID, "Cluster ID with which to tag AWS resources (required)")
-	cmd.Flags().StringVar(&opts.AWSCredentialsFile, "aws-creds", opts.AWSCredentialsFile, "Path to an AWS credentials file (required)")
+	cmd.Flags().StringVar(&opts.AWSCredentialsFile, "aws-creds", opts.AWSCredentialsFile, "Path to an AWS credentials file (required unless --web-identity-token-file is specified)")
+	cmd.Flags().StringVar(&opts.WebIdentityTokenFile, "web-identity-token-file", opts.WebIdentityTokenFile, "Path to a web identity token, e.g. a projected service account token, to assume the role given by --web-identity-role-arn with instead of using --aws-creds. The token is read again whenever the temporary credentials are refreshed")
+	cmd.Flags().StringVar(&opts.WebIdentityRoleARN, "web-identity-role-arn", opts.WebIdentityRoleARN, "The ARN of the role to assume with the token given by --web-identity-token-file")
 	cmd.Flags().StringVar(&opts.Region, "region", opts.Region, "Region where cluster infra should be created")
+	cmd.Flags().StringVar(&opts.RoleARN, "role-arn", opts.RoleARN, "The ARN of a role to assume with the given credentials, e.g. to destroy the resources in another account. The infra ID is passed as the hypershift-infra-id session tag")
+	cmd.Flags().StringVar(&opts.ExternalID, "external-id", opts.ExternalID, "The external ID to pass when assuming the role given by --role-arn")
 	cmd.Flags().StringVar(&opts.Name, "name", opts.Name, "A name for the cluster")
 	cmd.Flags().StringVar(&opts.BaseDomain, "base-domain", opts.BaseDomain, "The ingress base domain for the cluster")
+	cmd.Flags().StringVar(&opts.ParentZoneID, "parent-zone-id", opts.ParentZoneID, "The ID of the parent hosted zone given on creation. If set, a public zone for the base domain created for the cluster is deleted together with its delegation from the parent zone")
+	cmd.Flags().StringVar(&opts.ParentZoneRoleARN, "parent-zone-role-arn", opts.ParentZoneRoleARN, "The ARN of a role to assume with the given credentials to change the zone given by --parent-zone-id")
+	cmd.Flags().StringVar(&opts.ParentZoneExternalID, "parent-zone-external-id", opts.ParentZoneExternalID, "The external ID to pass when assuming the role given by --parent-zone-role-arn")
+	cmd.Flags().StringVar(&opts.ProgressOutput, "progress-output", opts.ProgressOutput, "Path to a file to write progress events to as JSON lines, or - for stdout. Each phase emits a started and a completed or failed event with the duration and error. Failed phases are retried")
+	cmd.Flags().StringVar(&opts.StateFile, "state-file", opts.StateFile, "Path to the state file written by create infra aws with --state-file. Only the VPC recorded in it is deleted, and the file is removed once all resources are deleted")
+	cmd.Flags().BoolVar(&opts.DryRun, "dry-run", opts.DryRun, "If true, only print a plan of the resources that would be deleted in deletion order, including the load balancers and endpoints created by the cluster, and the resources that would block their deletion, without changing anything")
+	cmd.Flags().DurationVar(&opts.Timeout, "timeout", opts.Timeout, "The maximum duration of the command including retries, e.g. 30m. In-flight AWS operations are aborted when it is exceeded or the command is interrupted; 0 means no timeout")
 
 	cmd.MarkFlagRequired("infra-id")
-	cmd.MarkFlagRequired("aws-creds")
 	cmd.MarkFlagRequired("base-domain")
 
 	cmd.RunE = func(cmd *cobra.Command, args []string) error {
-		if err := opts.Run(cmd.Context()); err != nil {
+		ctx := cmd.Context()
+		if opts.Timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+			defer cancel()
+		}
+		if err := opts.Run(ctx); err != nil {
 			opts.Log.Error(err, "Failed to destroy infrastructure")
 			return err
 		}
+		if opts.DryRun {
+			opts.Log.Info("Successfully planned destruction of infrastructure")
+			return nil
+		}
 		opts.Log.Info("Successfully destroyed infrastructure")
 		return nil
 	}
@@ -76,7 +117,33 @@ func NewDestroyCommand() *cobra.Command {
 }
 
 func (o *DestroyInfraOptions) Run(ctx context.Context) error {
-	return wait.PollImmediateUntil(5*time.Second, func() (bool, error) {
+	if err := awsutil.ValidateCredentials(o.AWSCredentialsFile, o.AWSKey, o.WebIdentityTokenFile, o.WebIdentityRoleARN); err != nil {
+		return err
+	}
+	if err := validateAssumeRoleOptions(o.RoleARN, o.ExternalID); err != nil {
+		return err
+	}
+	if len(o.ParentZoneID) == 0 && len(o.ParentZoneRoleARN) > 0 {
+		return errors.New("--parent-zone-role-arn can only be specified together with --parent-zone-id")
+	}
+	if err := validateParentZoneRoleOptions(o.ParentZoneRoleARN, o.ParentZoneExternalID); err != nil {
+		return err
+	}
+	if err := o.loadState(); err != nil {
+		return err
+	}
+	if o.DryRun {
+		return o.writeDestroyPlan(ctx)
+	}
+	var closeProgress func() error
+	var err error
+	o.progress, closeProgress, err = openProgressOutput("destroy", o.InfraID, o.ProgressOutput)
+	if err != nil {
+		return err
+	}
+	defer closeProgress()
+	defer logThrottledRequests(o.Log)
+	err = wait.PollImmediateUntil(5*time.Second, func() (bool, error) {
 		err := o.DestroyInfra(ctx)
 		if err != nil {
 			if !awsutil.IsErrorRetryable(err) {
@@ -87,28 +154,72 @@ func (o *DestroyInfraOptions) Run(ctx context.Context) error {
 		}
 		return true, nil
 	}, ctx.Done())
+	if err == wait.ErrWaitTimeout && ctx.Err() != nil {
+		return fmt.Errorf("destroy was aborted: %w", ctx.Err())
+	}
+	if err == nil && o.state != nil {
+		// The recorded resources are gone, so a later create must not resume
+		if err := os.Remove(o.StateFile); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to remove state file: %w", err)
+		}
+	}
+	return err
+}
+
+// writeDestroyPlan writes the plan of a dry run to stdout. Blockers only make
+// destroy retry until they are removed, so they do not fail the dry run.
+func (o *DestroyInfraOptions) writeDestroyPlan(ctx context.Context) error {
+	defer logThrottledRequests(o.Log)
+	plan, err := o.PlanDestroy(ctx)
+	if err != nil {
+		return err
+	}
+	if len(plan.Blockers) > 0 {
+		o.Log.Info("WARNING: found resources that would block deletion", "count", len(plan.Blockers))
+	}
+	outputBytes, err := json.MarshalIndent(plan, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to serialize plan: %w", err)
+	}
+	if _, err := os.Stdout.Write(outputBytes); err != nil {
+		return fmt.Errorf("failed to write plan: %w", err)
+	}
+	return nil
 }
 
 func (o *DestroyInfraOptions) DestroyInfra(ctx context.Context) error {
-	awsSession := awsutil.NewSession("cli-destroy-infra", o.AWSCredentialsFile, o.AWSKey, o.AWSSecretKey, o.Region)
+	baseSession := awsutil.NewSessionWithWebIdentity("cli-destroy-infra", o.AWSCredentialsFile, o.AWSKey, o.AWSSecretKey, o.WebIdentityTokenFile, o.WebIdentityRoleARN, o.Region)
+	awsSession, err := assumeRole(baseSession, "cli-destroy-infra", o.RoleARN, o.ExternalID, o.InfraID)
+	if err != nil {
+		return err
+	}
 	awsConfig := awsutil.NewConfig()
 	ec2Client := ec2.New(awsSession, awsConfig)
 	elbClient := elb.New(awsSession, awsConfig)
 	elbv2Client := elbv2.New(awsSession, awsConfig)
 	route53Client := route53.New(awsSession, awsutil.NewAWSRoute53Config())
 	s3Client := s3.New(awsSession, awsConfig)
-
-	errs := o.destroyInstances(ctx, ec2Client)
-	errs = append(errs, o.DestroyInternetGateways(ctx, ec2Client)...)
-	errs = append(errs, o.DestroyDNS(ctx, route53Client)...)
-	errs = append(errs, o.DestroyS3Buckets(ctx, s3Client)...)
-	errs = append(errs, o.DestroyVPCEndpointServices(ctx, ec2Client)...)
-	errs = append(errs, o.DestroyVPCs(ctx, ec2Client, elbClient, elbv2Client, route53Client)...)
+	kmsClient := kms.New(awsSession, awsConfig)
+	iamClient := iam.New(awsSession, awsConfig)
+	instanceConnectEndpointClient := newInstanceConnectEndpointClient(ec2Client)
+	parentRoute53Client := parentZoneClient(baseSession, "cli-destroy-infra", o.ParentZoneRoleARN, o.ParentZoneExternalID)
+
+	errs := o.progress.runAll("instances", func() []error { return o.destroyInstances(ctx, ec2Client) })
+	errs = append(errs, o.progress.runAll("internet-gateways", func() []error { return o.DestroyInternetGateways(ctx, ec2Client) })...)
+	errs = append(errs, o.progress.runAll("egress-only-internet-gateways", func() []error { return o.DestroyEgressOnlyInternetGateways(ctx, ec2Client) })...)
+	errs = append(errs, o.progress.runAll("dns", func() []error { return o.DestroyDNS(ctx, route53Client, parentRoute53Client) })...)
+	errs = append(errs, o.progress.runAll("s3-buckets", func() []error { return o.DestroyS3Buckets(ctx, s3Client) })...)
+	errs = append(errs, o.progress.runAll("vpc-endpoint-services", func() []error { return o.DestroyVPCEndpointServices(ctx, ec2Client) })...)
+	errs = append(errs, o.progress.runAll("kms-key", func() []error { return o.DestroyKMSKey(ctx, kmsClient) })...)
+	errs = append(errs, o.progress.runAll("instance-connect-endpoints", func() []error { return o.DestroyInstanceConnectEndpoints(ctx, instanceConnectEndpointClient) })...)
+	errs = append(errs, o.progress.runAll("flow-logs", func() []error { return o.DestroyFlowLogs(ctx, ec2Client, iamClient) })...)
+	errs = append(errs, o.progress.runAll("vpcs", func() []error { return o.DestroyVPCs(ctx, ec2Client, elbClient, elbv2Client, route53Client) })...)
 	if err := utilerrors.NewAggregate(errs); err != nil {
 		return err
 	}
-	errs = append(errs, o.DestroyEIPs(ctx, ec2Client)...)
-	errs = append(errs, o.DestroyDHCPOptions(ctx, ec2Client)...)
+	errs = append(errs, o.progress.runAll("eips", func() []error { return o.DestroyEIPs(ctx, ec2Client) })...)
+	errs = append(errs, o.progress.runAll("dhcp-options", func() []error { return o.DestroyDHCPOptions(ctx, ec2Client) })...)
+	errs = append(errs, o.progress.runAll("key-pairs", func() []error { return o.DestroyKeyPairs(ctx, ec2Client) })...)
 
 	return utilerrors.NewAggregate(errs)
 }
@@ -217,7 +328,19 @@ func (o *DestroyInfraOptions) DestroyVPCEndpoints(ctx context.Context, client ec
 	deleteVPCEndpoints := func(out *ec2.DescribeVpcEndpointsOutput, _ bool) bool {
 		ids := make([]*string, 0, len(out.VpcEndpoints))
 		for _, ep := range out.VpcEndpoints {
+			switch aws.StringValue(ep.State) {
+			case "deleted":
+				continue
+			case "deleting":
+				// The network interfaces of interface endpoints keep the subnets and
+				// security groups in use until the endpoints are gone
+				errs = append(errs, fmt.Errorf("VPC endpoint %s still deleting", aws.StringValue(ep.VpcEndpointId)))
+				continue
+			}
 			ids = append(ids, ep.VpcEndpointId)
+			if aws.StringValue(ep.VpcEndpointType) == ec2.VpcEndpointTypeInterface {
+				errs = append(errs, fmt.Errorf("deleting VPC endpoint %s", aws.StringValue(ep.VpcEndpointId)))
+			}
 		}
 		if len(ids) > 0 {
 			_, err := client.DeleteVpcEndpointsWithContext(ctx, &ec2.DeleteVpcEndpointsInput{
@@ -255,21 +378,24 @@ func (o *DestroyInfraOptions) DestroyVPCEndpointServices(ctx context.Context, cl
 			return true
 		}
 
-		endpointConnections, err := client.DescribeVpcEndpointConnections(&ec2.DescribeVpcEndpointConnectionsInput{Filters: []*ec2.Filter{{Name: aws.String("service-id"), Values: aws.StringSlice(ids)}}})
+		endpointConnectionsByServiceID := map[string][]*string{}
+		err := client.DescribeVpcEndpointConnectionsPagesWithContext(ctx, &ec2.DescribeVpcEndpointConnectionsInput{Filters: []*ec2.Filter{{Name: aws.String("service-id"), Values: aws.StringSlice(ids)}}}, func(out *ec2.DescribeVpcEndpointConnectionsOutput, _ bool) bool {
+			for _, endpointConnection := range out.VpcEndpointConnections {
+				serviceID := aws.StringValue(endpointConnection.ServiceId)
+				endpointConnectionsByServiceID[serviceID] = append(endpointConnectionsByServiceID[serviceID], endpointConnection.VpcEndpointId)
+			}
+			return true
+		})
 		if err != nil {
 			errs = append(errs, fmt.Errorf("failed to list endpoint conncetions: %w", err))
 			return false
 		}
-		endpointConnectionsByServiceID := map[*string][]*string{}
-		for _, endpointConnection := range endpointConnections.VpcEndpointConnections {
-			endpointConnectionsByServiceID[endpointConnection.ServiceId] = append(endpointConnectionsByServiceID[endpointConnection.ServiceId], endpointConnection.VpcEndpointId)
-		}
 		for service, endpoints := range endpointConnectionsByServiceID {
-			if _, err := client.RejectVpcEndpointConnectionsWithContext(ctx, &ec2.RejectVpcEndpointConnectionsInput{ServiceId: service, VpcEndpointIds: endpoints}); err != nil {
-				errs = append(errs, fmt.Errorf("failed to reject endpoint connections for service %s endpoints %v", aws.StringValue(service), aws.StringValueSlice(endpoints)))
+			if _, err := client.RejectVpcEndpointConnectionsWithContext(ctx, &ec2.RejectVpcEndpointConnectionsInput{ServiceId: aws.String(service), VpcEndpointIds: endpoints}); err != nil {
+				errs = append(errs, fmt.Errorf("failed to reject endpoint connections for service %s endpoints %v", service, aws.StringValueSlice(endpoints)))
 				return false
 			}
-			o.Log.Info("Deleted endpoint connections", "serviceID", aws.StringValue(service), "endpoints", fmt.Sprintf("%v", aws.StringValueSlice(endpoints)))
+			o.Log.Info("Deleted endpoint connections", "serviceID", service, "endpoints", fmt.Sprintf("%v", aws.StringValueSlice(endpoints)))
 		}
 
 		if _, err := client.DeleteVpcEndpointServiceConfigurationsWithContext(ctx, &ec2.DeleteVpcEndpointServiceConfigurationsInput{
@@ -361,61 +487,231 @@ func (o *DestroyInfraOptions) DestroyRouteTables(ctx context.Context, client ec2
 	return errs
 }
 
+// DestroySecurityGroups deletes the security groups of the VPC. All their rules,
+// and the rules of security groups outside the VPC that reference them, are
+// revoked first, so that no security group is kept from being deleted by a
+// reference. Deletion is retried with backoff while network interfaces that are
+// being deleted still use a security group.
 func (o *DestroyInfraOptions) DestroySecurityGroups(ctx context.Context, client ec2iface.EC2API, vpcID *string) []error {
+	var securityGroups []*ec2.SecurityGroup
+	err := client.DescribeSecurityGroupsPagesWithContext(ctx,
+		&ec2.DescribeSecurityGroupsInput{Filters: vpcFilter(vpcID)},
+		func(out *ec2.DescribeSecurityGroupsOutput, _ bool) bool {
+			securityGroups = append(securityGroups, out.SecurityGroups...)
+			return true
+		})
+	if err != nil {
+		return []error{fmt.Errorf("failed to describe security groups: %w", err)}
+	}
+
 	var errs []error
-	deleteSecurityGroups := func(out *ec2.DescribeSecurityGroupsOutput, _ bool) bool {
-		for _, sg := range out.SecurityGroups {
-			var permissionErrs []error
-			if len(sg.IpPermissions) > 0 {
-				_, err := client.RevokeSecurityGroupIngressWithContext(ctx, &ec2.RevokeSecurityGroupIngressInput{
-					GroupId:       sg.GroupId,
-					IpPermissions: sg.IpPermissions,
-				})
-				if err != nil {
-					permissionErrs = append(permissionErrs, err)
-				} else {
-					o.Log.Info("Revoked security group ingress permissions", "group", aws.StringValue(sg.GroupId))
+	groupIDs := sets.NewString()
+	revokeFailed := sets.NewString()
+	for _, sg := range securityGroups {
+		groupID := aws.StringValue(sg.GroupId)
+		groupIDs.Insert(groupID)
+		if revokeErrs := o.revokeSecurityGroupRules(ctx, client, sg.GroupId, sg.IpPermissions, sg.IpPermissionsEgress); len(revokeErrs) > 0 {
+			errs = append(errs, revokeErrs...)
+			revokeFailed.Insert(groupID)
+		}
+	}
+	if groupIDs.Len() == 0 {
+		return errs
+	}
+	errs = append(errs, o.revokeSecurityGroupReferences(ctx, client, groupIDs)...)
+
+	for _, sg := range securityGroups {
+		if aws.StringValue(sg.GroupName) == "default" || revokeFailed.Has(aws.StringValue(sg.GroupId)) {
+			continue
+		}
+		err := retryOnError(ctx, dependencyBackoff(), isDependencyViolation, func() error {
+			_, err := client.DeleteSecurityGroupWithContext(ctx, &ec2.DeleteSecurityGroupInput{
+				GroupId: sg.GroupId,
+			})
+			return err
+		})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to delete security group %s: %w", aws.StringValue(sg.GroupId), err))
+		} else {
+			o.Log.Info("Deleted security group", "group", aws.StringValue(sg.GroupId))
+		}
+	}
+	return errs
+}
+
+// revokeSecurityGroupRules revokes the given ingress and egress rules of a
+// security group.
+func (o *DestroyInfraOptions) revokeSecurityGroupRules(ctx context.Context, client ec2iface.EC2API, groupID *string, ingress, egress []*ec2.IpPermission) []error {
+	var errs []error
+	if len(ingress) > 0 {
+		_, err := client.RevokeSecurityGroupIngressWithContext(ctx, &ec2.RevokeSecurityGroupIngressInput{
+			GroupId:       groupID,
+			IpPermissions: ingress,
+		})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to revoke ingress rules of security group %s: %w", aws.StringValue(groupID), err))
+		} else {
+			o.Log.Info("Revoked security group ingress permissions", "group", aws.StringValue(groupID))
+		}
+	}
+	if len(egress) > 0 {
+		_, err := client.RevokeSecurityGroupEgressWithContext(ctx, &ec2.RevokeSecurityGroupEgressInput{
+			GroupId:       groupID,
+			IpPermissions: egress,
+		})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to revoke egress rules of security group %s: %w", aws.StringValue(groupID), err))
+		} else {
+			o.Log.Info("Revoked security group egress permissions", "group", aws.StringValue(groupID))
+		}
+	}
+	return errs
+}
+
+// revokeSecurityGroupReferences revokes the rules of security groups outside the
+// VPC, e.g. of a peered VPC, that reference the given security groups.
+func (o *DestroyInfraOptions) revokeSecurityGroupReferences(ctx context.Context, client ec2iface.EC2API, groupIDs sets.String) []error {
+	var errs []error
+	for _, filter := range []string{"ip-permission.group-id", "egress.ip-permission.group-id"} {
+		egress := strings.HasPrefix(filter, "egress.")
+		err := client.DescribeSecurityGroupsPagesWithContext(ctx,
+			&ec2.DescribeSecurityGroupsInput{Filters: []*ec2.Filter{{Name: aws.String(filter), Values: aws.StringSlice(groupIDs.List())}}},
+			func(out *ec2.DescribeSecurityGroupsOutput, _ bool) bool {
+				for _, sg := range out.SecurityGroups {
+					if groupIDs.Has(aws.StringValue(sg.GroupId)) {
+						continue
+					}
+					if egress {
+						errs = append(errs, o.revokeSecurityGroupRules(ctx, client, sg.GroupId, nil, referencingPermissions(sg.IpPermissionsEgress, groupIDs))...)
+					} else {
+						errs = append(errs, o.revokeSecurityGroupRules(ctx, client, sg.GroupId, referencingPermissions(sg.IpPermissions, groupIDs), nil)...)
+					}
 				}
+				return true
+			})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to describe security groups referencing the security groups of the VPC: %w", err))
+		}
+	}
+	return errs
+}
+
+// referencingPermissions returns the parts of the rules that reference one of
+// the given security groups.
+func referencingPermissions(permissions []*ec2.IpPermission, groupIDs sets.String) []*ec2.IpPermission {
+	var referencing []*ec2.IpPermission
+	for _, permission := range permissions {
+		var pairs []*ec2.UserIdGroupPair
+		for _, pair := range permission.UserIdGroupPairs {
+			if groupIDs.Has(aws.StringValue(pair.GroupId)) {
+				pairs = append(pairs, &ec2.UserIdGroupPair{GroupId: pair.GroupId, UserId: pair.UserId, VpcPeeringConnectionId: pair.VpcPeeringConnectionId})
 			}
+		}
+		if len(pairs) > 0 {
+			referencing = append(referencing, &ec2.IpPermission{
+				IpProtocol:       permission.IpProtocol,
+				FromPort:         permission.FromPort,
+				ToPort:           permission.ToPort,
+				UserIdGroupPairs: pairs,
+			})
+		}
+	}
+	return referencing
+}
 
-			if len(sg.IpPermissionsEgress) > 0 {
-				_, err := client.RevokeSecurityGroupEgressWithContext(ctx, &ec2.RevokeSecurityGroupEgressInput{
-					GroupId:       sg.GroupId,
-					IpPermissions: sg.IpPermissionsEgress,
-				})
-				if err != nil {
-					permissionErrs = append(permissionErrs, err)
-				} else {
-					o.Log.Info("Revoked security group egress permissions", "group", aws.StringValue(sg.GroupId))
-				}
+// DestroyNetworkInterfaces deletes the network interfaces that are left behind in
+// the VPC, such as interfaces that were not deleted on termination of their
+// instance or were created manually. Secondary interfaces of instances are
+// detached first. Interfaces that are deleted together with the resource that
+// created them are skipped, and interfaces managed by other AWS services are
+// reported, since only the service can delete them.
+func (o *DestroyInfraOptions) DestroyNetworkInterfaces(ctx context.Context, client ec2iface.EC2API, vpcID *string) []error {
+	var networkInterfaces []*ec2.NetworkInterface
+	err := client.DescribeNetworkInterfacesPagesWithContext(ctx,
+		&ec2.DescribeNetworkInterfacesInput{Filters: vpcFilter(vpcID)},
+		func(out *ec2.DescribeNetworkInterfacesOutput, _ bool) bool {
+			networkInterfaces = append(networkInterfaces, out.NetworkInterfaces...)
+			return true
+		})
+	if err != nil {
+		return []error{fmt.Errorf("failed to describe network interfaces: %w", err)}
+	}
+
+	var errs []error
+	for _, networkInterface := range networkInterfaces {
+		id := aws.StringValue(networkInterface.NetworkInterfaceId)
+		if deletedWithOwner(networkInterface) {
+			continue
+		}
+		if aws.BoolValue(networkInterface.RequesterManaged) {
+			errs = append(errs, fmt.Errorf("network interface %s is managed by %s and can only be deleted by it", id, aws.StringValue(networkInterface.RequesterId)))
+			continue
+		}
+		if attachment := networkInterface.Attachment; attachment != nil && aws.StringValue(networkInterface.Status) != ec2.NetworkInterfaceStatusAvailable {
+			instanceID := aws.StringValue(attachment.InstanceId)
+			if len(instanceID) == 0 {
+				errs = append(errs, fmt.Errorf("network interface %s is in use and not attached to an instance", id))
+				continue
 			}
-			if len(permissionErrs) > 0 {
-				errs = append(errs, permissionErrs...)
+			// The primary interface is deleted on termination of the instance or
+			// becomes available once the instance is terminated
+			if aws.Int64Value(attachment.DeviceIndex) == 0 {
 				continue
 			}
-			if aws.StringValue(sg.GroupName) == "default" {
+			if _, err := client.DetachNetworkInterfaceWithContext(ctx, &ec2.DetachNetworkInterfaceInput{
+				AttachmentId: attachment.AttachmentId,
+				Force:        aws.Bool(true),
+			}); err != nil {
+				errs = append(errs, fmt.Errorf("failed to detach network interface %s from instance %s: %w", id, instanceID, err))
 				continue
 			}
-			_, err := client.DeleteSecurityGroupWithContext(ctx, &ec2.DeleteSecurityGroupInput{
-				GroupId: sg.GroupId,
+			o.Log.Info("Detached network interface", "id", id, "instance", instanceID)
+		}
+		err := retryOnError(ctx, dependencyBackoff(), isNetworkInterfaceInUse, func() error {
+			_, err := client.DeleteNetworkInterfaceWithContext(ctx, &ec2.DeleteNetworkInterfaceInput{
+				NetworkInterfaceId: networkInterface.NetworkInterfaceId,
 			})
-			if err != nil {
-				errs = append(errs, err)
-			} else {
-				o.Log.Info("Deleted security group", "group", aws.StringValue(sg.GroupId))
-			}
+			return err
+		})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to delete network interface %s: %w", id, err))
+		} else {
+			o.Log.Info("Deleted network interface", "id", id)
 		}
+	}
+	return errs
+}
 
+// deletedWithOwner returns true for network interfaces that are deleted together
+// with the resource that created them, e.g. a NAT gateway or load balancer, all
+// of which destroy deletes.
+func deletedWithOwner(networkInterface *ec2.NetworkInterface) bool {
+	if managedNetworkInterfaceTypes.Has(aws.StringValue(networkInterface.InterfaceType)) {
 		return true
 	}
+	return strings.HasPrefix(aws.StringValue(networkInterface.Description), "ELB ")
+}
 
-	err := client.DescribeSecurityGroupsPagesWithContext(ctx,
-		&ec2.DescribeSecurityGroupsInput{Filters: vpcFilter(vpcID)},
-		deleteSecurityGroups)
-	if err != nil {
-		errs = append(errs, err)
+// dependencyBackoff is the backoff for waiting on resources that are still used by
+// resources being deleted, e.g. network interfaces of terminating instances.
+func dependencyBackoff() wait.Backoff {
+	return wait.Backoff{
+		Steps:    6,
+		Duration: 5 * time.Second,
+		Factor:   1.5,
+		Jitter:   0.1,
+		Cap:      30 * time.Second,
 	}
-	return errs
+}
+
+func isDependencyViolation(err error) bool {
+	var awsErr awserr.Error
+	return errors.As(err, &awsErr) && awsErr.Code() == "DependencyViolation"
+}
+
+func isNetworkInterfaceInUse(err error) bool {
+	var awsErr awserr.Error
+	return errors.As(err, &awsErr) && awsErr.Code() == "InvalidNetworkInterface.InUse"
 }
 
 func (o *DestroyInfraOptions) DestroyNATGateways(ctx context.Context, client ec2iface.EC2API, vpcID *string) []error {
@@ -459,7 +755,7 @@ func (o *DestroyInfraOptions) destroyInstances(ctx context.Context, client ec2if
 			}
 		}
 		if len(instanceIDs) > 0 {
-			if _, err := client.TerminateInstances(&ec2.TerminateInstancesInput{InstanceIds: instanceIDs}); err != nil {
+			if _, err := client.TerminateInstancesWithContext(ctx, &ec2.TerminateInstancesInput{InstanceIds: instanceIDs}); err != nil {
 				errs = append(errs, fmt.Errorf("failed to terminate instances: %w", err))
 			}
 		}
@@ -515,6 +811,30 @@ func (o *DestroyInfraOptions) DestroyInternetGateways(ctx context.Context, clien
 	return nil
 }
 
+func (o *DestroyInfraOptions) DestroyEgressOnlyInternetGateways(ctx context.Context, client ec2iface.EC2API) []error {
+	var errs []error
+	deleteGateways := func(out *ec2.DescribeEgressOnlyInternetGatewaysOutput, _ bool) bool {
+		for _, gateway := range out.EgressOnlyInternetGateways {
+			_, err := client.DeleteEgressOnlyInternetGatewayWithContext(ctx, &ec2.DeleteEgressOnlyInternetGatewayInput{
+				EgressOnlyInternetGatewayId: gateway.EgressOnlyInternetGatewayId,
+			})
+			if err != nil {
+				errs = append(errs, err)
+			} else {
+				o.Log.Info("Deleted egress only internet gateway", "id", aws.StringValue(gateway.EgressOnlyInternetGatewayId))
+			}
+		}
+		return true
+	}
+	err := client.DescribeEgressOnlyInternetGatewaysPagesWithContext(ctx,
+		&ec2.DescribeEgressOnlyInternetGatewaysInput{Filters: o.ec2Filters()},
+		deleteGateways)
+	if err != nil {
+		errs = append(errs, err)
+	}
+	return errs
+}
+
 func (o *DestroyInfraOptions) DestroySubnets(ctx context.Context, client ec2iface.EC2API, vpcID *string) []error {
 	var errs []error
 	deleteSubnets := func(out *ec2.DescribeSubnetsOutput, _ bool) bool {
@@ -550,10 +870,13 @@ func (o *DestroyInfraOptions) DestroyVPCs(ctx context.Context, ec2client ec2ifac
 			childErrs = append(childErrs, o.DestroyPrivateZones(ctx, route53client, vpc.VpcId)...)
 			childErrs = append(childErrs, o.DestroyRouteTables(ctx, ec2client, vpc.VpcId)...)
 			childErrs = append(childErrs, o.DestroyNATGateways(ctx, ec2client, vpc.VpcId)...)
+			childErrs = append(childErrs, o.DestroyLocalGatewayRouteTableVPCAssociations(ctx, ec2client, vpc.VpcId)...)
+			childErrs = append(childErrs, o.DestroyTransitGatewayAttachments(ctx, ec2client, vpc.VpcId)...)
 			if len(childErrs) > 0 {
 				errs = append(errs, childErrs...)
 				continue
 			}
+			childErrs = append(childErrs, o.DestroyNetworkInterfaces(ctx, ec2client, vpc.VpcId)...)
 			childErrs = append(childErrs, o.DestroySecurityGroups(ctx, ec2client, vpc.VpcId)...)
 			childErrs = append(childErrs, o.DestroySubnets(ctx, ec2client, vpc.VpcId)...)
 			if len(childErrs) > 0 {
@@ -572,7 +895,7 @@ func (o *DestroyInfraOptions) DestroyVPCs(ctx context.Context, ec2client ec2ifac
 		return true
 	}
 	err := ec2client.DescribeVpcsPagesWithContext(ctx,
-		&ec2.DescribeVpcsInput{Filters: o.ec2Filters()},
+		&ec2.DescribeVpcsInput{Filters: o.vpcFilters()},
 		deleteVPC)
 
 	if err != nil {
@@ -10,6 +10,8 @@ import (
 	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/service/iam"
 	"github.com/aws/aws-sdk-go/service/iam/iamiface"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
 	"github.com/go-logr/logr"
 	"github.com/spf13/cobra"
 	"k8s.io/apimachinery/pkg/util/wait"
@@ -19,12 +21,19 @@ import (
 )
 
 type DestroyIAMOptions struct {
-	Region             string
-	AWSCredentialsFile string
-	AWSKey             string
-	AWSSecretKey       string
-	InfraID            string
-	Log                logr.Logger
+	Region               string
+	AWSCredentialsFile   string
+	WebIdentityTokenFile string
+	WebIdentityRoleARN   string
+	AWSKey               string
+	AWSSecretKey         string
+	RoleARN              string
+	ExternalID           string
+	InfraID              string
+	OIDCBucket           string
+	InstanceProfileName  string
+	WorkerRoleName       string
+	Log                  logr.Logger
 }
 
 func NewDestroyIAMCommand() *cobra.Command {
@@ -41,11 +50,17 @@ func NewDestroyIAMCommand() *cobra.Command {
 		Log:                log.Log,
 	}
 
-	cmd.Flags().StringVar(&opts.AWSCredentialsFile, "aws-creds", opts.AWSCredentialsFile, "Path to an AWS credentials file (required)")
+	cmd.Flags().StringVar(&opts.AWSCredentialsFile, "aws-creds", opts.AWSCredentialsFile, "Path to an AWS credentials file (required unless --web-identity-token-file is specified)")
+	cmd.Flags().StringVar(&opts.WebIdentityTokenFile, "web-identity-token-file", opts.WebIdentityTokenFile, "Path to a web identity token, e.g. a projected service account token, to assume the role given by --web-identity-role-arn with instead of using --aws-creds. The token is read again whenever the temporary credentials are refreshed")
+	cmd.Flags().StringVar(&opts.WebIdentityRoleARN, "web-identity-role-arn", opts.WebIdentityRoleARN, "The ARN of the role to assume with the token given by --web-identity-token-file")
 	cmd.Flags().StringVar(&opts.InfraID, "infra-id", opts.InfraID, "Infrastructure ID to use for AWS resources.")
 	cmd.Flags().StringVar(&opts.Region, "region", opts.Region, "Region where cluster infra lives")
+	cmd.Flags().StringVar(&opts.OIDCBucket, "oidc-bucket", opts.OIDCBucket, "The name of the S3 bucket with the OIDC discovery documents of the cluster. If set, the documents are deleted from the bucket")
+	cmd.Flags().StringVar(&opts.RoleARN, "role-arn", opts.RoleARN, "The ARN of a role to assume with the given credentials, e.g. to destroy the resources in another account. The infra ID is passed as the hypershift-infra-id session tag")
+	cmd.Flags().StringVar(&opts.ExternalID, "external-id", opts.ExternalID, "The external ID to pass when assuming the role given by --role-arn")
+	cmd.Flags().StringVar(&opts.InstanceProfileName, "instance-profile-name", opts.InstanceProfileName, "The name of the worker instance profile, if it was created with a name other than <infra-id>-worker")
+	cmd.Flags().StringVar(&opts.WorkerRoleName, "worker-role-name", opts.WorkerRoleName, "The name of the role of the worker instance profile, if it was created with a name other than the instance profile name with a -role suffix")
 
-	cmd.MarkFlagRequired("aws-creds")
 	cmd.MarkFlagRequired("infra-id")
 
 	cmd.RunE = func(cmd *cobra.Command, args []string) error {
@@ -60,6 +75,12 @@ func NewDestroyIAMCommand() *cobra.Command {
 }
 
 func (o *DestroyIAMOptions) Run(ctx context.Context) error {
+	if err := awsutil.ValidateCredentials(o.AWSCredentialsFile, o.AWSKey, o.WebIdentityTokenFile, o.WebIdentityRoleARN); err != nil {
+		return err
+	}
+	if err := validateAssumeRoleOptions(o.RoleARN, o.ExternalID); err != nil {
+		return err
+	}
 	return wait.PollImmediateUntil(5*time.Second, func() (bool, error) {
 		err := o.DestroyIAM(ctx)
 		if err != nil {
@@ -74,11 +95,13 @@ func (o *DestroyIAMOptions) Run(ctx context.Context) error {
 }
 
 func (o *DestroyIAMOptions) DestroyIAM(ctx context.Context) error {
-	awsSession := awsutil.NewSession("cli-destroy-iam", o.AWSCredentialsFile, o.AWSKey, o.AWSSecretKey, o.Region)
+	awsSession, err := assumeRole(awsutil.NewSessionWithWebIdentity("cli-destroy-iam", o.AWSCredentialsFile, o.AWSKey, o.AWSSecretKey, o.WebIdentityTokenFile, o.WebIdentityRoleARN, o.Region), "cli-destroy-iam", o.RoleARN, o.ExternalID, o.InfraID)
+	if err != nil {
+		return err
+	}
 	awsConfig := awsutil.NewConfig()
 	iamClient := iam.New(awsSession, awsConfig)
 
-	var err error
 	err = o.DestroyOIDCResources(ctx, iamClient)
 	if err != nil {
 		return err
@@ -87,6 +110,18 @@ func (o *DestroyIAMOptions) DestroyIAM(ctx context.Context) error {
 	if err != nil {
 		return err
 	}
+	if len(o.OIDCBucket) > 0 {
+		bucketRegion, err := s3manager.GetBucketRegion(ctx, awsSession, o.OIDCBucket, o.Region)
+		if err != nil {
+			if aerr, ok := err.(awserr.Error); ok && aerr.Code() == s3ErrCodeNotFound {
+				return nil
+			}
+			return fmt.Errorf("cannot get region of OIDC bucket %s: %w", o.OIDCBucket, err)
+		}
+		if err := o.DestroyOIDCDocuments(s3.New(awsSession, awsConfig.Copy().WithRegion(bucketRegion))); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -186,7 +221,7 @@ func (o *DestroyIAMOptions) DestroyOIDCRole(client iamiface.IAMAPI, name string)
 }
 
 func (o *DestroyIAMOptions) DestroyWorkerInstanceProfile(client iamiface.IAMAPI) error {
-	profileName := DefaultProfileName(o.InfraID)
+	profileName := workerProfileName(o.InfraID, o.InstanceProfileName)
 	instanceProfile, err := existingInstanceProfile(client, profileName)
 	if err != nil {
 		return fmt.Errorf("cannot check for existing instance profile: %w", err)
@@ -210,26 +245,27 @@ func (o *DestroyIAMOptions) DestroyWorkerInstanceProfile(client iamiface.IAMAPI)
 		}
 		o.Log.Info("Deleted instance profile", "profile", profileName)
 	}
-	roleName := fmt.Sprintf("%s-role", profileName)
-	policyName := fmt.Sprintf("%s-policy", profileName)
+	roleName := workerRoleName(profileName, o.WorkerRoleName)
 	role, err := existingRole(client, roleName)
 	if err != nil {
 		return fmt.Errorf("cannot check for existing role: %w", err)
 	}
 	if role != nil {
-		hasPolicy, err := existingRolePolicy(client, roleName, policyName)
-		if err != nil {
-			return fmt.Errorf("cannot check for existing role policy: %w", err)
-		}
-		if hasPolicy {
-			_, err := client.DeleteRolePolicy(&iam.DeleteRolePolicyInput{
-				PolicyName: aws.String(policyName),
-				RoleName:   aws.String(roleName),
-			})
+		for _, policyName := range []string{fmt.Sprintf("%s-policy", profileName), workerIMDSv2PolicyName(profileName)} {
+			hasPolicy, err := existingRolePolicy(client, roleName, policyName)
 			if err != nil {
-				return fmt.Errorf("cannot delete role policy %s from role %s: %w", policyName, roleName, err)
+				return fmt.Errorf("cannot check for existing role policy: %w", err)
+			}
+			if hasPolicy {
+				_, err := client.DeleteRolePolicy(&iam.DeleteRolePolicyInput{
+					PolicyName: aws.String(policyName),
+					RoleName:   aws.String(roleName),
+				})
+				if err != nil {
+					return fmt.Errorf("cannot delete role policy %s from role %s: %w", policyName, roleName, err)
+				}
+				o.Log.Info("Deleted role policy", "role", roleName, "policy", policyName)
 			}
-			o.Log.Info("Deleted role policy", "role", roleName, "policy", policyName)
 		}
 		_, err = client.DeleteRole(&iam.DeleteRoleInput{
 			RoleName: aws.String(roleName),
@@ -0,0 +1,173 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/go-logr/logr"
+	"github.com/spf13/cobra"
+
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	hyperv1 "github.com/openshift/hypershift/api/v1alpha1"
+	awsutil "github.com/openshift/hypershift/cmd/infra/aws/util"
+	"github.com/openshift/hypershift/cmd/log"
+	"github.com/openshift/hypershift/cmd/util"
+)
+
+type DestroyOrphansOptions struct {
+	Region               string
+	AWSCredentialsFile   string
+	WebIdentityTokenFile string
+	WebIdentityRoleARN   string
+	AWSKey               string
+	AWSSecretKey         string
+	InfraIDs             []string
+	DryRun               bool
+	Timeout              time.Duration
+	Log                  logr.Logger
+}
+
+func NewDestroyOrphansCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:          "aws-orphans",
+		Short:        "Destroys AWS infrastructure resources of clusters that no longer exist",
+		SilenceUsage: true,
+	}
+
+	opts := DestroyOrphansOptions{
+		Region: "us-east-1",
+		Log:    log.Log,
+	}
+
+	cmd.Flags().StringVar(&opts.AWSCredentialsFile, "aws-creds", opts.AWSCredentialsFile, "Path to an AWS credentials file (required unless --web-identity-token-file is specified)")
+	cmd.Flags().StringVar(&opts.WebIdentityTokenFile, "web-identity-token-file", opts.WebIdentityTokenFile, "Path to a web identity token, e.g. a projected service account token, to assume the role given by --web-identity-role-arn with instead of using --aws-creds. The token is read again whenever the temporary credentials are refreshed")
+	cmd.Flags().StringVar(&opts.WebIdentityRoleARN, "web-identity-role-arn", opts.WebIdentityRoleARN, "The ARN of the role to assume with the token given by --web-identity-token-file")
+	cmd.Flags().StringVar(&opts.Region, "region", opts.Region, "Region to scan for orphaned infrastructure")
+	cmd.Flags().StringSliceVar(&opts.InfraIDs, "infra-ids", opts.InfraIDs, "If set, only the infrastructure of these infra IDs is considered")
+	cmd.Flags().BoolVar(&opts.DryRun, "dry-run", opts.DryRun, "If true, only report the orphaned infrastructure without deleting it")
+	cmd.Flags().DurationVar(&opts.Timeout, "timeout", opts.Timeout, "The maximum duration of the command, e.g. 1h. In-flight AWS operations are aborted when it is exceeded or the command is interrupted; 0 means no timeout")
+
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		if opts.Timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+			defer cancel()
+		}
+		if err := opts.Run(ctx); err != nil {
+			opts.Log.Error(err, "Failed to destroy orphaned infrastructure")
+			return err
+		}
+		return nil
+	}
+
+	return cmd
+}
+
+// Run destroys the infrastructure of every infra ID that EC2 resources are
+// tagged as owned by, but that no HostedCluster of the management cluster the
+// current kubeconfig points to refers to. Infrastructure created for a cluster
+// that is not created yet is indistinguishable from orphaned infrastructure, so
+// this must not run concurrently with cluster creation.
+func (o *DestroyOrphansOptions) Run(ctx context.Context) error {
+	if err := awsutil.ValidateCredentials(o.AWSCredentialsFile, o.AWSKey, o.WebIdentityTokenFile, o.WebIdentityRoleARN); err != nil {
+		return err
+	}
+	c, err := util.GetClient()
+	if err != nil {
+		return err
+	}
+	var hostedClusters hyperv1.HostedClusterList
+	if err := c.List(ctx, &hostedClusters); err != nil {
+		return fmt.Errorf("failed to list hosted clusters: %w", err)
+	}
+	existing := sets.NewString()
+	for _, hostedCluster := range hostedClusters.Items {
+		existing.Insert(hostedCluster.Spec.InfraID)
+	}
+
+	awsSession := awsutil.NewSessionWithWebIdentity("cli-destroy-orphans", o.AWSCredentialsFile, o.AWSKey, o.AWSSecretKey, o.WebIdentityTokenFile, o.WebIdentityRoleARN, o.Region)
+	ec2Client := ec2.New(awsSession, awsutil.NewConfig())
+	orphans, err := o.orphanedInfraIDs(ctx, ec2Client, existing)
+	if err != nil {
+		return err
+	}
+	if len(orphans) == 0 {
+		o.Log.Info("No orphaned infrastructure found", "region", o.Region)
+		return nil
+	}
+
+	infraIDs := make([]string, 0, len(orphans))
+	for infraID := range orphans {
+		infraIDs = append(infraIDs, infraID)
+	}
+	sort.Strings(infraIDs)
+	var errs []error
+	for _, infraID := range infraIDs {
+		if ctx.Err() != nil {
+			errs = append(errs, fmt.Errorf("destroying orphaned infrastructure was aborted: %w", ctx.Err()))
+			break
+		}
+		o.Log.Info("Found orphaned infrastructure", "infraID", infraID, "resources", orphans[infraID])
+		if o.DryRun {
+			continue
+		}
+		destroyOpts := DestroyInfraOptions{
+			Region:               o.Region,
+			InfraID:              infraID,
+			AWSCredentialsFile:   o.AWSCredentialsFile,
+			AWSKey:               o.AWSKey,
+			AWSSecretKey:         o.AWSSecretKey,
+			WebIdentityTokenFile: o.WebIdentityTokenFile,
+			WebIdentityRoleARN:   o.WebIdentityRoleARN,
+			Log:                  o.Log.WithValues("infraID", infraID),
+		}
+		if err := destroyOpts.Run(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("failed to destroy infrastructure of %s: %w", infraID, err))
+			continue
+		}
+		o.Log.Info("Destroyed orphaned infrastructure", "infraID", infraID)
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// orphanedInfraIDs returns the IDs of the EC2 resources owned by each infra ID
+// that is not in existing. If InfraIDs is set, only those infra IDs are returned.
+func (o *DestroyOrphansOptions) orphanedInfraIDs(ctx context.Context, client ec2iface.EC2API, existing sets.String) (map[string][]string, error) {
+	only := sets.NewString(o.InfraIDs...)
+	clusterTagPrefix := clusterTag("")
+	orphans := map[string][]string{}
+	err := client.DescribeTagsPagesWithContext(ctx, &ec2.DescribeTagsInput{
+		Filters: []*ec2.Filter{
+			{
+				Name:   aws.String("key"),
+				Values: []*string{aws.String(clusterTagPrefix + "*")},
+			},
+			{
+				Name:   aws.String("value"),
+				Values: []*string{aws.String(clusterTagValue)},
+			},
+		},
+	}, func(out *ec2.DescribeTagsOutput, _ bool) bool {
+		for _, tag := range out.Tags {
+			infraID := strings.TrimPrefix(aws.StringValue(tag.Key), clusterTagPrefix)
+			if len(infraID) == 0 || existing.Has(infraID) || (only.Len() > 0 && !only.Has(infraID)) {
+				continue
+			}
+			orphans[infraID] = append(orphans[infraID], aws.StringValue(tag.ResourceId))
+		}
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tagged resources: %w", err)
+	}
+	return orphans, nil
+}
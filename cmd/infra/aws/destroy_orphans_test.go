@@ -0,0 +1,69 @@
+package aws
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+type fakeTagsClient struct {
+	ec2iface.EC2API
+	tags []*ec2.TagDescription
+}
+
+func (f *fakeTagsClient) DescribeTagsPagesWithContext(_ aws.Context, _ *ec2.DescribeTagsInput, fn func(*ec2.DescribeTagsOutput, bool) bool, _ ...request.Option) error {
+	fn(&ec2.DescribeTagsOutput{Tags: f.tags}, true)
+	return nil
+}
+
+func ownedTag(infraID, resourceID string) *ec2.TagDescription {
+	return &ec2.TagDescription{
+		Key:        aws.String(clusterTag(infraID)),
+		Value:      aws.String(clusterTagValue),
+		ResourceId: aws.String(resourceID),
+	}
+}
+
+func TestOrphanedInfraIDs(t *testing.T) {
+	client := &fakeTagsClient{tags: []*ec2.TagDescription{
+		ownedTag("existing", "vpc-1"),
+		ownedTag("orphan", "vpc-2"),
+		ownedTag("orphan", "subnet-2"),
+		ownedTag("other", "vpc-3"),
+	}}
+	testCases := []struct {
+		name     string
+		infraIDs []string
+		expected map[string][]string
+	}{
+		{
+			name: "all infra IDs",
+			expected: map[string][]string{
+				"orphan": {"vpc-2", "subnet-2"},
+				"other":  {"vpc-3"},
+			},
+		},
+		{
+			name:     "selected infra IDs",
+			infraIDs: []string{"orphan", "existing"},
+			expected: map[string][]string{
+				"orphan": {"vpc-2", "subnet-2"},
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewGomegaWithT(t)
+			o := &DestroyOrphansOptions{InfraIDs: tc.infraIDs}
+			orphans, err := o.orphanedInfraIDs(context.Background(), client, sets.NewString("existing"))
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(orphans).To(Equal(tc.expected))
+		})
+	}
+}
@@ -0,0 +1,638 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/aws/aws-sdk-go/service/elb"
+	"github.com/aws/aws-sdk-go/service/elb/elbiface"
+	"github.com/aws/aws-sdk-go/service/elbv2"
+	"github.com/aws/aws-sdk-go/service/elbv2/elbv2iface"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/aws/aws-sdk-go/service/iam/iamiface"
+	"github.com/aws/aws-sdk-go/service/kms"
+	"github.com/aws/aws-sdk-go/service/kms/kmsiface"
+	"github.com/aws/aws-sdk-go/service/route53"
+	"github.com/aws/aws-sdk-go/service/route53/route53iface"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	awsutil "github.com/openshift/hypershift/cmd/infra/aws/util"
+)
+
+// asyncDeletionDetails is set on planned deletions that complete in the
+// background. Destroy retries until they are gone before it continues with the
+// resources that depend on them.
+const asyncDeletionDetails = "deleted asynchronously, dependent resources wait for it"
+
+// managedNetworkInterfaceTypes are the interface types of network interfaces
+// that are deleted together with the resource that created them.
+var managedNetworkInterfaceTypes = sets.NewString(
+	"nat_gateway",
+	"vpc_endpoint",
+	"transit_gateway",
+	"network_load_balancer",
+	"gateway_load_balancer",
+	"gateway_load_balancer_endpoint",
+	"ec2_instance_connect_endpoint",
+)
+
+// PlannedDeletion is a single change that a dry run of destroy infra would make.
+type PlannedDeletion struct {
+	Phase        string `json:"phase"`
+	Action       string `json:"action"`
+	ResourceType string `json:"resourceType"`
+	ID           string `json:"id"`
+	Name         string `json:"name,omitempty"`
+	Details      string `json:"details,omitempty"`
+}
+
+// DeletionBlocker is a resource that destroy infra does not delete, but that
+// keeps other resources from being deleted.
+type DeletionBlocker struct {
+	ResourceType string   `json:"resourceType"`
+	ID           string   `json:"id"`
+	Blocks       []string `json:"blocks"`
+	Reason       string   `json:"reason"`
+}
+
+// DestroyPlan lists all changes a dry run of destroy infra would make, in the
+// order destroy makes them, and the resources that would block it.
+type DestroyPlan struct {
+	InfraID   string            `json:"infraID"`
+	Resources []PlannedDeletion `json:"resources"`
+	Blockers  []DeletionBlocker `json:"blockers,omitempty"`
+}
+
+// planDelete records that a resource would be deleted in the given phase.
+func (o *DestroyInfraOptions) planDelete(phase, resourceType, id, name, details string) {
+	o.plan.Resources = append(o.plan.Resources, PlannedDeletion{Phase: phase, Action: "delete", ResourceType: resourceType, ID: id, Name: name, Details: details})
+}
+
+// planned returns true if the plan deletes the resource.
+func (p *DestroyPlan) planned(resourceType, id string) bool {
+	for _, resource := range p.Resources {
+		if resource.ResourceType == resourceType && resource.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// PlanDestroy enumerates every resource destroy infra would delete without
+// changing anything. Resources that are not deleted by destroy infra but would
+// keep the VPC resources from being deleted are reported as blockers.
+func (o *DestroyInfraOptions) PlanDestroy(ctx context.Context) (*DestroyPlan, error) {
+	baseSession := awsutil.NewSessionWithWebIdentity("cli-destroy-infra", o.AWSCredentialsFile, o.AWSKey, o.AWSSecretKey, o.WebIdentityTokenFile, o.WebIdentityRoleARN, o.Region)
+	awsSession, err := assumeRole(baseSession, "cli-destroy-infra", o.RoleARN, o.ExternalID, o.InfraID)
+	if err != nil {
+		return nil, err
+	}
+	awsConfig := awsutil.NewConfig()
+	ec2Client := ec2.New(awsSession, awsConfig)
+	elbClient := elb.New(awsSession, awsConfig)
+	elbv2Client := elbv2.New(awsSession, awsConfig)
+	route53Client := route53.New(awsSession, awsutil.NewAWSRoute53Config())
+	s3Client := s3.New(awsSession, awsConfig)
+	kmsClient := kms.New(awsSession, awsConfig)
+	iamClient := iam.New(awsSession, awsConfig)
+	instanceConnectEndpointClient := newInstanceConnectEndpointClient(ec2Client)
+	parentRoute53Client := parentZoneClient(baseSession, "cli-destroy-infra", o.ParentZoneRoleARN, o.ParentZoneExternalID)
+
+	o.plan = DestroyPlan{InfraID: o.InfraID}
+	for _, step := range []func() error{
+		func() error { return o.planInstances(ctx, ec2Client) },
+		func() error { return o.planInternetGateways(ctx, ec2Client) },
+		func() error { return o.planDNS(ctx, route53Client, parentRoute53Client) },
+		func() error { return o.planS3Buckets(ctx, s3Client) },
+		func() error { return o.planVPCEndpointServices(ctx, ec2Client) },
+		func() error { return o.planKMSKey(ctx, kmsClient) },
+		func() error { return o.planInstanceConnectEndpoints(ctx, instanceConnectEndpointClient) },
+		func() error { return o.planFlowLogs(ctx, ec2Client, iamClient) },
+		func() error { return o.planVPCs(ctx, ec2Client, elbClient, elbv2Client, route53Client) },
+		func() error { return o.planEC2Leftovers(ctx, ec2Client) },
+	} {
+		if err := step(); err != nil {
+			return nil, err
+		}
+	}
+	return &o.plan, nil
+}
+
+func (o *DestroyInfraOptions) planInstances(ctx context.Context, client ec2iface.EC2API) error {
+	err := client.DescribeInstancesPagesWithContext(ctx, &ec2.DescribeInstancesInput{Filters: o.ec2Filters()}, func(out *ec2.DescribeInstancesOutput, _ bool) bool {
+		for _, reservation := range out.Reservations {
+			for _, instance := range reservation.Instances {
+				if instance.State != nil && aws.StringValue(instance.State.Name) == ec2.InstanceStateNameTerminated {
+					continue
+				}
+				o.planDelete("instances", "instance", aws.StringValue(instance.InstanceId), ec2TagValue(instance.Tags, "Name"), "")
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return fmt.Errorf("failed to describe instances: %w", err)
+	}
+	return nil
+}
+
+func (o *DestroyInfraOptions) planInternetGateways(ctx context.Context, client ec2iface.EC2API) error {
+	err := client.DescribeInternetGatewaysPagesWithContext(ctx, &ec2.DescribeInternetGatewaysInput{Filters: o.ec2Filters()}, func(out *ec2.DescribeInternetGatewaysOutput, _ bool) bool {
+		for _, igw := range out.InternetGateways {
+			var vpcIDs []string
+			for _, attachment := range igw.Attachments {
+				vpcIDs = append(vpcIDs, aws.StringValue(attachment.VpcId))
+			}
+			details := ""
+			if len(vpcIDs) > 0 {
+				details = fmt.Sprintf("detach from %s", strings.Join(vpcIDs, ", "))
+			}
+			o.planDelete("internet-gateways", "internet-gateway", aws.StringValue(igw.InternetGatewayId), ec2TagValue(igw.Tags, "Name"), details)
+		}
+		return true
+	})
+	if err != nil {
+		return fmt.Errorf("failed to describe internet gateways: %w", err)
+	}
+	err = client.DescribeEgressOnlyInternetGatewaysPagesWithContext(ctx, &ec2.DescribeEgressOnlyInternetGatewaysInput{Filters: o.ec2Filters()}, func(out *ec2.DescribeEgressOnlyInternetGatewaysOutput, _ bool) bool {
+		for _, gateway := range out.EgressOnlyInternetGateways {
+			o.planDelete("egress-only-internet-gateways", "egress-only-internet-gateway", aws.StringValue(gateway.EgressOnlyInternetGatewayId), ec2TagValue(gateway.Tags, "Name"), "")
+		}
+		return true
+	})
+	if err != nil {
+		return fmt.Errorf("failed to describe egress only internet gateways: %w", err)
+	}
+	return nil
+}
+
+func (o *DestroyInfraOptions) planDNS(ctx context.Context, client, parentClient route53iface.Route53API) error {
+	id, err := lookupZone(ctx, client, o.BaseDomain, false)
+	if err != nil {
+		if errors.Is(err, errHostedZoneNotFound) {
+			return nil
+		}
+		return err
+	}
+	recordName := fmt.Sprintf("*.apps.%s.%s", o.Name, o.BaseDomain)
+	if _, err := findRecord(ctx, client, id, recordName, "A"); err != nil {
+		if !isRoute53RecordNotFoundErr(err) {
+			return fmt.Errorf("cannot find wildcard record in public zone %s: %w", id, err)
+		}
+	} else {
+		o.planDelete("dns", "route53-record", id, recordName, "wildcard record in the public zone")
+	}
+	if len(o.ParentZoneID) == 0 {
+		return nil
+	}
+	owned, err := zoneHasTag(ctx, client, id, clusterTag(o.InfraID), clusterTagValue)
+	if err != nil || !owned {
+		return err
+	}
+	parent, err := getParentZone(ctx, parentClient, o.ParentZoneID, o.BaseDomain)
+	if err != nil {
+		return err
+	}
+	if _, err := findRecord(ctx, parentClient, parent, o.BaseDomain, route53.RRTypeNs); err != nil {
+		if !isRoute53RecordNotFoundErr(err) {
+			return fmt.Errorf("cannot find delegation of %s in hosted zone %s: %w", o.BaseDomain, parent, err)
+		}
+	} else {
+		o.planDelete("dns", "route53-record", parent, o.BaseDomain, "delegation in the parent zone")
+	}
+	o.planDelete("dns", "hosted-zone", id, o.BaseDomain, "public zone")
+	return nil
+}
+
+func (o *DestroyInfraOptions) planS3Buckets(ctx context.Context, client s3iface.S3API) error {
+	result, err := client.ListBucketsWithContext(ctx, &s3.ListBucketsInput{})
+	if err != nil {
+		return fmt.Errorf("failed to list buckets: %w", err)
+	}
+	for _, bucket := range result.Buckets {
+		if strings.HasPrefix(aws.StringValue(bucket.Name), fmt.Sprintf("%s-image-registry-", o.InfraID)) {
+			o.planDelete("s3-buckets", "s3-bucket", aws.StringValue(bucket.Name), "", "all objects are deleted first")
+		}
+	}
+	return nil
+}
+
+func (o *DestroyInfraOptions) planVPCEndpointServices(ctx context.Context, client ec2iface.EC2API) error {
+	var ids []string
+	err := client.DescribeVpcEndpointServiceConfigurationsPagesWithContext(ctx, &ec2.DescribeVpcEndpointServiceConfigurationsInput{Filters: o.ec2Filters()}, func(out *ec2.DescribeVpcEndpointServiceConfigurationsOutput, _ bool) bool {
+		for _, cfg := range out.ServiceConfigurations {
+			ids = append(ids, aws.StringValue(cfg.ServiceId))
+		}
+		return true
+	})
+	if err != nil {
+		return fmt.Errorf("failed to describe vpc endpoint services: %w", err)
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	connections := map[string][]string{}
+	err = client.DescribeVpcEndpointConnectionsPagesWithContext(ctx, &ec2.DescribeVpcEndpointConnectionsInput{Filters: []*ec2.Filter{{Name: aws.String("service-id"), Values: aws.StringSlice(ids)}}}, func(out *ec2.DescribeVpcEndpointConnectionsOutput, _ bool) bool {
+		for _, connection := range out.VpcEndpointConnections {
+			serviceID := aws.StringValue(connection.ServiceId)
+			connections[serviceID] = append(connections[serviceID], aws.StringValue(connection.VpcEndpointId))
+		}
+		return true
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list endpoint connections: %w", err)
+	}
+	for _, id := range ids {
+		details := ""
+		if endpoints := connections[id]; len(endpoints) > 0 {
+			details = fmt.Sprintf("reject connections of endpoints %s", strings.Join(endpoints, ", "))
+		}
+		o.planDelete("vpc-endpoint-services", "vpc-endpoint-service", id, "", details)
+	}
+	return nil
+}
+
+func (o *DestroyInfraOptions) planKMSKey(ctx context.Context, client kmsiface.KMSAPI) error {
+	alias := kmsKeyAlias(o.InfraID)
+	result, err := client.DescribeKeyWithContext(ctx, &kms.DescribeKeyInput{KeyId: aws.String(alias)})
+	if err != nil {
+		var notFound *kms.NotFoundException
+		if errors.As(err, &notFound) {
+			return nil
+		}
+		return fmt.Errorf("cannot find KMS key %s: %w", alias, err)
+	}
+	keyID := aws.StringValue(result.KeyMetadata.KeyId)
+	tags, err := client.ListResourceTagsWithContext(ctx, &kms.ListResourceTagsInput{KeyId: aws.String(keyID)})
+	if err != nil {
+		return fmt.Errorf("cannot list tags of KMS key %s: %w", keyID, err)
+	}
+	for _, tag := range tags.Tags {
+		if aws.StringValue(tag.TagKey) == clusterTag(o.InfraID) && aws.StringValue(tag.TagValue) == clusterTagValue {
+			o.planDelete("kms-key", "kms-key", keyID, alias, fmt.Sprintf("scheduled for deletion in %d days", kmsKeyDeletionWindowDays))
+			return nil
+		}
+	}
+	return nil
+}
+
+func (o *DestroyInfraOptions) planInstanceConnectEndpoints(ctx context.Context, client instanceConnectEndpointAPI) error {
+	endpoints, err := client.describeInstanceConnectEndpoints(ctx, o.ec2Filters())
+	if err != nil {
+		return fmt.Errorf("cannot list instance connect endpoints: %w", err)
+	}
+	for _, endpoint := range endpoints {
+		if aws.StringValue(endpoint.State) == instanceConnectEndpointStateDeleteComplete {
+			continue
+		}
+		o.planDelete("instance-connect-endpoints", "instance-connect-endpoint", aws.StringValue(endpoint.InstanceConnectEndpointId), ec2TagValue(endpoint.Tags, "Name"), asyncDeletionDetails)
+	}
+	return nil
+}
+
+func (o *DestroyInfraOptions) planFlowLogs(ctx context.Context, ec2Client ec2iface.EC2API, iamClient iamiface.IAMAPI) error {
+	err := ec2Client.DescribeFlowLogsPagesWithContext(ctx, &ec2.DescribeFlowLogsInput{Filter: o.ec2Filters()}, func(out *ec2.DescribeFlowLogsOutput, _ bool) bool {
+		for _, flowLog := range out.FlowLogs {
+			o.planDelete("flow-logs", "flow-log", aws.StringValue(flowLog.FlowLogId), ec2TagValue(flowLog.Tags, "Name"), "")
+		}
+		return true
+	})
+	if err != nil {
+		return fmt.Errorf("cannot list flow logs: %w", err)
+	}
+	roleName := flowLogsRoleName(o.InfraID)
+	if _, err := iamClient.GetRoleWithContext(ctx, &iam.GetRoleInput{RoleName: aws.String(roleName)}); err != nil {
+		var awsErr awserr.Error
+		if errors.As(err, &awsErr) && awsErr.Code() == iam.ErrCodeNoSuchEntityException {
+			return nil
+		}
+		return fmt.Errorf("cannot get role %s: %w", roleName, err)
+	}
+	o.planDelete("flow-logs", "iam-role", roleName, roleName, "")
+	return nil
+}
+
+func (o *DestroyInfraOptions) planVPCs(ctx context.Context, ec2Client ec2iface.EC2API, elbClient elbiface.ELBAPI, elbv2Client elbv2iface.ELBV2API, route53Client route53iface.Route53API) error {
+	var vpcs []*ec2.Vpc
+	err := ec2Client.DescribeVpcsPagesWithContext(ctx, &ec2.DescribeVpcsInput{Filters: o.vpcFilters()}, func(out *ec2.DescribeVpcsOutput, _ bool) bool {
+		vpcs = append(vpcs, out.Vpcs...)
+		return true
+	})
+	if err != nil {
+		return fmt.Errorf("failed to describe vpcs: %w", err)
+	}
+	for _, vpc := range vpcs {
+		for _, step := range []func() error{
+			func() error { return o.planLoadBalancers(ctx, elbClient, elbv2Client, vpc.VpcId) },
+			func() error { return o.planVPCChildren(ctx, ec2Client, vpc.VpcId) },
+			func() error { return o.planPrivateZones(ctx, route53Client, vpc.VpcId) },
+			func() error { return o.planVPCGateways(ctx, ec2Client, vpc.VpcId) },
+			func() error { return o.planVPCNetwork(ctx, ec2Client, vpc.VpcId) },
+		} {
+			if err := step(); err != nil {
+				return err
+			}
+		}
+		o.planDelete("vpcs", "vpc", aws.StringValue(vpc.VpcId), ec2TagValue(vpc.Tags, "Name"), "")
+		if err := o.planBlockers(ctx, ec2Client, vpc.VpcId); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// planLoadBalancers records the load balancers in the VPC. These are usually
+// created by the cluster for services of type LoadBalancer and not tagged with
+// the infra ID.
+func (o *DestroyInfraOptions) planLoadBalancers(ctx context.Context, elbClient elbiface.ELBAPI, elbv2Client elbv2iface.ELBV2API, vpcID *string) error {
+	err := elbClient.DescribeLoadBalancersPagesWithContext(ctx, &elb.DescribeLoadBalancersInput{}, func(out *elb.DescribeLoadBalancersOutput, _ bool) bool {
+		for _, lb := range out.LoadBalancerDescriptions {
+			if aws.StringValue(lb.VPCId) == aws.StringValue(vpcID) {
+				o.planDelete("vpcs", "load-balancer", aws.StringValue(lb.LoadBalancerName), aws.StringValue(lb.LoadBalancerName), "classic")
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return fmt.Errorf("failed to describe load balancers: %w", err)
+	}
+	err = elbv2Client.DescribeLoadBalancersPagesWithContext(ctx, &elbv2.DescribeLoadBalancersInput{}, func(out *elbv2.DescribeLoadBalancersOutput, _ bool) bool {
+		for _, lb := range out.LoadBalancers {
+			if aws.StringValue(lb.VpcId) == aws.StringValue(vpcID) {
+				o.planDelete("vpcs", "load-balancer", aws.StringValue(lb.LoadBalancerArn), aws.StringValue(lb.LoadBalancerName), aws.StringValue(lb.Type))
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return fmt.Errorf("failed to describe load balancers: %w", err)
+	}
+	return nil
+}
+
+// planVPCChildren records the VPC endpoints in the VPC, which are deleted
+// before the private zones of the VPC.
+func (o *DestroyInfraOptions) planVPCChildren(ctx context.Context, client ec2iface.EC2API, vpcID *string) error {
+	err := client.DescribeVpcEndpointsPagesWithContext(ctx, &ec2.DescribeVpcEndpointsInput{Filters: vpcFilter(vpcID)}, func(out *ec2.DescribeVpcEndpointsOutput, _ bool) bool {
+		for _, endpoint := range out.VpcEndpoints {
+			if aws.StringValue(endpoint.State) == "deleted" {
+				continue
+			}
+			details := aws.StringValue(endpoint.ServiceName)
+			if aws.StringValue(endpoint.VpcEndpointType) == ec2.VpcEndpointTypeInterface {
+				details = fmt.Sprintf("%s, %s", details, asyncDeletionDetails)
+			}
+			o.planDelete("vpcs", "vpc-endpoint", aws.StringValue(endpoint.VpcEndpointId), ec2TagValue(endpoint.Tags, "Name"), details)
+		}
+		return true
+	})
+	if err != nil {
+		return fmt.Errorf("failed to describe vpc endpoints: %w", err)
+	}
+	return nil
+}
+
+func (o *DestroyInfraOptions) planPrivateZones(ctx context.Context, client route53iface.Route53API, vpcID *string) error {
+	zones, err := o.privateZones(ctx, client, vpcID)
+	if err != nil {
+		return err
+	}
+	for _, zone := range zones {
+		id := cleanZoneID(aws.StringValue(zone.HostedZoneId))
+		shared, err := zoneHasTag(ctx, client, id, clusterTag(o.InfraID), sharedClusterTagValue)
+		if err != nil {
+			return err
+		}
+		if shared {
+			o.plan.Resources = append(o.plan.Resources, PlannedDeletion{Phase: "vpcs", Action: "disassociate", ResourceType: "hosted-zone", ID: id, Name: aws.StringValue(zone.Name), Details: fmt.Sprintf("shared zone is disassociated from %s", aws.StringValue(vpcID))})
+			continue
+		}
+		o.planDelete("vpcs", "hosted-zone", id, aws.StringValue(zone.Name), "private zone with all its records")
+	}
+	return nil
+}
+
+// planVPCGateways records the route tables, NAT gateways, local gateway route
+// table associations and transit gateway attachments of the VPC.
+func (o *DestroyInfraOptions) planVPCGateways(ctx context.Context, client ec2iface.EC2API, vpcID *string) error {
+	err := client.DescribeRouteTablesPagesWithContext(ctx, &ec2.DescribeRouteTablesInput{Filters: vpcFilter(vpcID)}, func(out *ec2.DescribeRouteTablesOutput, _ bool) bool {
+		for _, routeTable := range out.RouteTables {
+			main := false
+			for _, assoc := range routeTable.Associations {
+				main = main || aws.BoolValue(assoc.Main)
+			}
+			if main {
+				// The main route table is deleted together with the VPC
+				continue
+			}
+			o.planDelete("vpcs", "route-table", aws.StringValue(routeTable.RouteTableId), ec2TagValue(routeTable.Tags, "Name"), "")
+		}
+		return true
+	})
+	if err != nil {
+		return fmt.Errorf("failed to describe route tables: %w", err)
+	}
+	err = client.DescribeNatGatewaysPagesWithContext(ctx, &ec2.DescribeNatGatewaysInput{Filter: vpcFilter(vpcID)}, func(out *ec2.DescribeNatGatewaysOutput, _ bool) bool {
+		for _, natGateway := range out.NatGateways {
+			if aws.StringValue(natGateway.State) == ec2.NatGatewayStateDeleted {
+				continue
+			}
+			o.planDelete("vpcs", "nat-gateway", aws.StringValue(natGateway.NatGatewayId), ec2TagValue(natGateway.Tags, "Name"), asyncDeletionDetails)
+		}
+		return true
+	})
+	if err != nil {
+		return fmt.Errorf("failed to describe nat gateways: %w", err)
+	}
+	err = client.DescribeLocalGatewayRouteTableVpcAssociationsPagesWithContext(ctx, &ec2.DescribeLocalGatewayRouteTableVpcAssociationsInput{Filters: vpcFilter(vpcID)}, func(out *ec2.DescribeLocalGatewayRouteTableVpcAssociationsOutput, _ bool) bool {
+		for _, association := range out.LocalGatewayRouteTableVpcAssociations {
+			o.planDelete("vpcs", "local-gateway-route-table-vpc-association", aws.StringValue(association.LocalGatewayRouteTableVpcAssociationId), "", "")
+		}
+		return true
+	})
+	if err != nil {
+		return fmt.Errorf("failed to describe local gateway route table vpc associations: %w", err)
+	}
+	err = client.DescribeTransitGatewayVpcAttachmentsPagesWithContext(ctx, &ec2.DescribeTransitGatewayVpcAttachmentsInput{Filters: vpcFilter(vpcID)}, func(out *ec2.DescribeTransitGatewayVpcAttachmentsOutput, _ bool) bool {
+		for _, attachment := range out.TransitGatewayVpcAttachments {
+			switch aws.StringValue(attachment.State) {
+			case ec2.TransitGatewayAttachmentStateDeleted, ec2.TransitGatewayAttachmentStateRejected, ec2.TransitGatewayAttachmentStateFailed:
+				continue
+			}
+			o.planDelete("vpcs", "transit-gateway-attachment", aws.StringValue(attachment.TransitGatewayAttachmentId), ec2TagValue(attachment.Tags, "Name"), asyncDeletionDetails)
+		}
+		return true
+	})
+	if err != nil {
+		return fmt.Errorf("failed to describe transit gateway attachments: %w", err)
+	}
+	return nil
+}
+
+// planVPCNetwork records the network interfaces left behind in the VPC, and the
+// security groups and subnets of the VPC, which are only deleted once all other
+// resources in the VPC are gone.
+func (o *DestroyInfraOptions) planVPCNetwork(ctx context.Context, client ec2iface.EC2API, vpcID *string) error {
+	err := client.DescribeNetworkInterfacesPagesWithContext(ctx, &ec2.DescribeNetworkInterfacesInput{Filters: vpcFilter(vpcID)}, func(out *ec2.DescribeNetworkInterfacesOutput, _ bool) bool {
+		for _, networkInterface := range out.NetworkInterfaces {
+			if deleted, details := o.networkInterfaceDeletion(networkInterface); deleted {
+				o.planDelete("vpcs", "network-interface", aws.StringValue(networkInterface.NetworkInterfaceId), aws.StringValue(networkInterface.Description), details)
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return fmt.Errorf("failed to describe network interfaces: %w", err)
+	}
+	err = client.DescribeSecurityGroupsPagesWithContext(ctx, &ec2.DescribeSecurityGroupsInput{Filters: vpcFilter(vpcID)}, func(out *ec2.DescribeSecurityGroupsOutput, _ bool) bool {
+		for _, sg := range out.SecurityGroups {
+			if aws.StringValue(sg.GroupName) == "default" {
+				continue
+			}
+			o.planDelete("vpcs", "security-group", aws.StringValue(sg.GroupId), aws.StringValue(sg.GroupName), "")
+		}
+		return true
+	})
+	if err != nil {
+		return fmt.Errorf("failed to describe security groups: %w", err)
+	}
+	err = client.DescribeSubnetsPagesWithContext(ctx, &ec2.DescribeSubnetsInput{Filters: vpcFilter(vpcID)}, func(out *ec2.DescribeSubnetsOutput, _ bool) bool {
+		for _, subnet := range out.Subnets {
+			o.planDelete("vpcs", "subnet", aws.StringValue(subnet.SubnetId), ec2TagValue(subnet.Tags, "Name"), "")
+		}
+		return true
+	})
+	if err != nil {
+		return fmt.Errorf("failed to describe subnets: %w", err)
+	}
+	return nil
+}
+
+// planBlockers records the resources in the VPC that destroy does not delete,
+// but that keep its subnets, security groups or the VPC itself from being
+// deleted, such as network interfaces managed by other AWS services or the
+// primary interfaces of instances that are not terminated by destroy.
+func (o *DestroyInfraOptions) planBlockers(ctx context.Context, client ec2iface.EC2API, vpcID *string) error {
+	err := client.DescribeNetworkInterfacesPagesWithContext(ctx, &ec2.DescribeNetworkInterfacesInput{Filters: vpcFilter(vpcID)}, func(out *ec2.DescribeNetworkInterfacesOutput, _ bool) bool {
+		for _, networkInterface := range out.NetworkInterfaces {
+			reason := o.networkInterfaceBlockReason(networkInterface)
+			if len(reason) == 0 {
+				continue
+			}
+			blocks := []string{aws.StringValue(networkInterface.SubnetId)}
+			for _, group := range networkInterface.Groups {
+				blocks = append(blocks, aws.StringValue(group.GroupId))
+			}
+			sort.Strings(blocks[1:])
+			o.plan.Blockers = append(o.plan.Blockers, DeletionBlocker{
+				ResourceType: "network-interface",
+				ID:           aws.StringValue(networkInterface.NetworkInterfaceId),
+				Blocks:       blocks,
+				Reason:       reason,
+			})
+		}
+		return true
+	})
+	if err != nil {
+		return fmt.Errorf("failed to describe network interfaces: %w", err)
+	}
+	out, err := client.DescribeVpnGatewaysWithContext(ctx, &ec2.DescribeVpnGatewaysInput{Filters: []*ec2.Filter{
+		{Name: aws.String("attachment.vpc-id"), Values: []*string{vpcID}},
+		{Name: aws.String("attachment.state"), Values: aws.StringSlice([]string{ec2.AttachmentStatusAttaching, ec2.AttachmentStatusAttached})},
+	}})
+	if err != nil {
+		return fmt.Errorf("failed to describe vpn gateways: %w", err)
+	}
+	for _, gateway := range out.VpnGateways {
+		o.plan.Blockers = append(o.plan.Blockers, DeletionBlocker{
+			ResourceType: "vpn-gateway",
+			ID:           aws.StringValue(gateway.VpnGatewayId),
+			Blocks:       []string{aws.StringValue(vpcID)},
+			Reason:       "attached to the VPC and not detached by destroy",
+		})
+	}
+	return nil
+}
+
+// networkInterfaceBlockReason returns why the network interface would keep its
+// subnet and security groups from being deleted, or an empty string if it is
+// deleted together with a resource of the plan or by destroy itself.
+func (o *DestroyInfraOptions) networkInterfaceBlockReason(networkInterface *ec2.NetworkInterface) string {
+	if deletedWithOwner(networkInterface) {
+		return ""
+	}
+	if aws.BoolValue(networkInterface.RequesterManaged) {
+		return fmt.Sprintf("managed by %s and not deleted by destroy", aws.StringValue(networkInterface.RequesterId))
+	}
+	attachment := networkInterface.Attachment
+	if attachment == nil || aws.StringValue(networkInterface.Status) == ec2.NetworkInterfaceStatusAvailable {
+		return ""
+	}
+	instanceID := aws.StringValue(attachment.InstanceId)
+	if len(instanceID) == 0 {
+		return "in use and not attached to an instance"
+	}
+	if aws.Int64Value(attachment.DeviceIndex) == 0 && !o.plan.planned("instance", instanceID) {
+		return fmt.Sprintf("primary interface of instance %s, which is not tagged with the infra ID", instanceID)
+	}
+	return ""
+}
+
+// networkInterfaceDeletion returns whether destroy deletes the network interface
+// itself rather than together with the resource that created it, and the details
+// of the deletion.
+func (o *DestroyInfraOptions) networkInterfaceDeletion(networkInterface *ec2.NetworkInterface) (bool, string) {
+	if deletedWithOwner(networkInterface) || len(o.networkInterfaceBlockReason(networkInterface)) > 0 {
+		return false, ""
+	}
+	attachment := networkInterface.Attachment
+	if attachment == nil || aws.StringValue(networkInterface.Status) == ec2.NetworkInterfaceStatusAvailable {
+		return true, ""
+	}
+	instanceID := aws.StringValue(attachment.InstanceId)
+	if aws.Int64Value(attachment.DeviceIndex) > 0 {
+		return true, fmt.Sprintf("detached from instance %s first", instanceID)
+	}
+	if !aws.BoolValue(attachment.DeleteOnTermination) {
+		return true, fmt.Sprintf("deleted once instance %s is terminated", instanceID)
+	}
+	return false, ""
+}
+
+// planEC2Leftovers records the resources that are deleted after the VPCs.
+func (o *DestroyInfraOptions) planEC2Leftovers(ctx context.Context, client ec2iface.EC2API) error {
+	addresses, err := client.DescribeAddressesWithContext(ctx, &ec2.DescribeAddressesInput{Filters: o.ec2Filters()})
+	if err != nil {
+		return fmt.Errorf("failed to describe addresses: %w", err)
+	}
+	for _, address := range addresses.Addresses {
+		o.planDelete("eips", "eip", aws.StringValue(address.AllocationId), ec2TagValue(address.Tags, "Name"), aws.StringValue(address.PublicIp))
+	}
+	err = client.DescribeDhcpOptionsPagesWithContext(ctx, &ec2.DescribeDhcpOptionsInput{Filters: o.ec2Filters()}, func(out *ec2.DescribeDhcpOptionsOutput, _ bool) bool {
+		for _, dhcpOptions := range out.DhcpOptions {
+			o.planDelete("dhcp-options", "dhcp-options", aws.StringValue(dhcpOptions.DhcpOptionsId), ec2TagValue(dhcpOptions.Tags, "Name"), "")
+		}
+		return true
+	})
+	if err != nil {
+		return fmt.Errorf("failed to describe dhcp options: %w", err)
+	}
+	keyPairs, err := client.DescribeKeyPairsWithContext(ctx, &ec2.DescribeKeyPairsInput{Filters: o.ec2Filters()})
+	if err != nil {
+		return fmt.Errorf("failed to describe key pairs: %w", err)
+	}
+	for _, keyPair := range keyPairs.KeyPairs {
+		o.planDelete("key-pairs", "key-pair", aws.StringValue(keyPair.KeyPairId), aws.StringValue(keyPair.KeyName), "")
+	}
+	return nil
+}
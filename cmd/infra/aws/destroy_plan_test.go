@@ -0,0 +1,210 @@
+package aws
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	. "github.com/onsi/gomega"
+)
+
+type fakeDestroyPlanClient struct {
+	ec2iface.EC2API
+	routeTables       []*ec2.RouteTable
+	natGateways       []*ec2.NatGateway
+	securityGroups    []*ec2.SecurityGroup
+	subnets           []*ec2.Subnet
+	networkInterfaces []*ec2.NetworkInterface
+	vpnGateways       []*ec2.VpnGateway
+}
+
+func (f *fakeDestroyPlanClient) DescribeRouteTablesPagesWithContext(_ aws.Context, _ *ec2.DescribeRouteTablesInput, fn func(*ec2.DescribeRouteTablesOutput, bool) bool, _ ...request.Option) error {
+	fn(&ec2.DescribeRouteTablesOutput{RouteTables: f.routeTables}, true)
+	return nil
+}
+
+func (f *fakeDestroyPlanClient) DescribeNatGatewaysPagesWithContext(_ aws.Context, _ *ec2.DescribeNatGatewaysInput, fn func(*ec2.DescribeNatGatewaysOutput, bool) bool, _ ...request.Option) error {
+	fn(&ec2.DescribeNatGatewaysOutput{NatGateways: f.natGateways}, true)
+	return nil
+}
+
+func (f *fakeDestroyPlanClient) DescribeLocalGatewayRouteTableVpcAssociationsPagesWithContext(_ aws.Context, _ *ec2.DescribeLocalGatewayRouteTableVpcAssociationsInput, fn func(*ec2.DescribeLocalGatewayRouteTableVpcAssociationsOutput, bool) bool, _ ...request.Option) error {
+	fn(&ec2.DescribeLocalGatewayRouteTableVpcAssociationsOutput{}, true)
+	return nil
+}
+
+func (f *fakeDestroyPlanClient) DescribeTransitGatewayVpcAttachmentsPagesWithContext(_ aws.Context, _ *ec2.DescribeTransitGatewayVpcAttachmentsInput, fn func(*ec2.DescribeTransitGatewayVpcAttachmentsOutput, bool) bool, _ ...request.Option) error {
+	fn(&ec2.DescribeTransitGatewayVpcAttachmentsOutput{}, true)
+	return nil
+}
+
+func (f *fakeDestroyPlanClient) DescribeSecurityGroupsPagesWithContext(_ aws.Context, _ *ec2.DescribeSecurityGroupsInput, fn func(*ec2.DescribeSecurityGroupsOutput, bool) bool, _ ...request.Option) error {
+	fn(&ec2.DescribeSecurityGroupsOutput{SecurityGroups: f.securityGroups}, true)
+	return nil
+}
+
+func (f *fakeDestroyPlanClient) DescribeSubnetsPagesWithContext(_ aws.Context, _ *ec2.DescribeSubnetsInput, fn func(*ec2.DescribeSubnetsOutput, bool) bool, _ ...request.Option) error {
+	fn(&ec2.DescribeSubnetsOutput{Subnets: f.subnets}, true)
+	return nil
+}
+
+func (f *fakeDestroyPlanClient) DescribeNetworkInterfacesPagesWithContext(_ aws.Context, _ *ec2.DescribeNetworkInterfacesInput, fn func(*ec2.DescribeNetworkInterfacesOutput, bool) bool, _ ...request.Option) error {
+	fn(&ec2.DescribeNetworkInterfacesOutput{NetworkInterfaces: f.networkInterfaces}, true)
+	return nil
+}
+
+func (f *fakeDestroyPlanClient) DescribeVpnGatewaysWithContext(_ aws.Context, _ *ec2.DescribeVpnGatewaysInput, _ ...request.Option) (*ec2.DescribeVpnGatewaysOutput, error) {
+	return &ec2.DescribeVpnGatewaysOutput{VpnGateways: f.vpnGateways}, nil
+}
+
+func TestPlanVPCResources(t *testing.T) {
+	g := NewGomegaWithT(t)
+	client := &fakeDestroyPlanClient{
+		routeTables: []*ec2.RouteTable{
+			{RouteTableId: aws.String("rtb-main"), Associations: []*ec2.RouteTableAssociation{{Main: aws.Bool(true)}}},
+			{RouteTableId: aws.String("rtb-private")},
+		},
+		natGateways: []*ec2.NatGateway{
+			{NatGatewayId: aws.String("nat-1"), State: aws.String(ec2.NatGatewayStateAvailable)},
+			{NatGatewayId: aws.String("nat-deleted"), State: aws.String(ec2.NatGatewayStateDeleted)},
+		},
+		securityGroups: []*ec2.SecurityGroup{
+			{GroupId: aws.String("sg-default"), GroupName: aws.String("default")},
+			{GroupId: aws.String("sg-worker"), GroupName: aws.String("test-worker-sg")},
+		},
+		subnets: []*ec2.Subnet{testSubnet("subnet-1", "test-private-us-east-1a", "us-east-1a", "10.0.128.0/20")},
+		networkInterfaces: []*ec2.NetworkInterface{
+			{NetworkInterfaceId: aws.String("eni-nat"), InterfaceType: aws.String("nat_gateway")},
+			{NetworkInterfaceId: aws.String("eni-manual"), InterfaceType: aws.String("interface"), Status: aws.String("available")},
+		},
+	}
+	o := &DestroyInfraOptions{InfraID: "test"}
+	g.Expect(o.planVPCGateways(context.Background(), client, aws.String("vpc-1"))).To(Succeed())
+	g.Expect(o.planVPCNetwork(context.Background(), client, aws.String("vpc-1"))).To(Succeed())
+
+	var ids []string
+	for _, resource := range o.plan.Resources {
+		g.Expect(resource.Phase).To(Equal("vpcs"))
+		g.Expect(resource.Action).To(Equal("delete"))
+		ids = append(ids, resource.ID)
+	}
+	g.Expect(ids).To(Equal([]string{"rtb-private", "nat-1", "eni-manual", "sg-worker", "subnet-1"}))
+	g.Expect(o.plan.Resources[1].Details).To(Equal(asyncDeletionDetails))
+	g.Expect(o.plan.Resources[4].Name).To(Equal("test-private-us-east-1a"))
+}
+
+func TestNetworkInterfaceBlockReason(t *testing.T) {
+	testCases := []struct {
+		name             string
+		networkInterface *ec2.NetworkInterface
+		expectBlocking   bool
+		expectDeletion   bool
+		expectedDeletion string
+	}{
+		{
+			name:             "nat gateway",
+			networkInterface: &ec2.NetworkInterface{InterfaceType: aws.String("nat_gateway")},
+		},
+		{
+			name:             "load balancer",
+			networkInterface: &ec2.NetworkInterface{InterfaceType: aws.String("interface"), Description: aws.String("ELB app/router/1234")},
+		},
+		{
+			name: "planned instance",
+			networkInterface: &ec2.NetworkInterface{Attachment: &ec2.NetworkInterfaceAttachment{
+				InstanceId:          aws.String("i-planned"),
+				DeleteOnTermination: aws.Bool(true),
+			}},
+		},
+		{
+			name: "planned instance without delete on termination",
+			networkInterface: &ec2.NetworkInterface{Attachment: &ec2.NetworkInterfaceAttachment{
+				InstanceId:          aws.String("i-planned"),
+				DeleteOnTermination: aws.Bool(false),
+			}},
+			expectedDeletion: "deleted once instance i-planned is terminated",
+		},
+		{
+			name: "instance of another cluster",
+			networkInterface: &ec2.NetworkInterface{Attachment: &ec2.NetworkInterfaceAttachment{
+				InstanceId:          aws.String("i-other"),
+				DeleteOnTermination: aws.Bool(true),
+			}},
+			expectBlocking: true,
+		},
+		{
+			name: "secondary interface of instance of another cluster",
+			networkInterface: &ec2.NetworkInterface{Attachment: &ec2.NetworkInterfaceAttachment{
+				InstanceId:  aws.String("i-other"),
+				DeviceIndex: aws.Int64(1),
+			}},
+			expectedDeletion: "detached from instance i-other first",
+		},
+		{
+			name:             "detached",
+			networkInterface: &ec2.NetworkInterface{InterfaceType: aws.String("interface"), Status: aws.String("available")},
+			expectedDeletion: "",
+			expectDeletion:   true,
+		},
+		{
+			name:             "in use without instance",
+			networkInterface: &ec2.NetworkInterface{Status: aws.String("in-use"), Attachment: &ec2.NetworkInterfaceAttachment{}},
+			expectBlocking:   true,
+		},
+		{
+			name:             "requester managed",
+			networkInterface: &ec2.NetworkInterface{InterfaceType: aws.String("lambda"), RequesterManaged: aws.Bool(true), RequesterId: aws.String("AROA1234")},
+			expectBlocking:   true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewGomegaWithT(t)
+			o := &DestroyInfraOptions{InfraID: "test"}
+			o.planDelete("instances", "instance", "i-planned", "", "")
+			reason := o.networkInterfaceBlockReason(tc.networkInterface)
+			if tc.expectBlocking {
+				g.Expect(reason).ToNot(BeEmpty())
+			} else {
+				g.Expect(reason).To(BeEmpty())
+			}
+			deleted, details := o.networkInterfaceDeletion(tc.networkInterface)
+			g.Expect(deleted).To(Equal(tc.expectDeletion || len(tc.expectedDeletion) > 0))
+			g.Expect(details).To(Equal(tc.expectedDeletion))
+		})
+	}
+}
+
+func TestPlanBlockers(t *testing.T) {
+	g := NewGomegaWithT(t)
+	client := &fakeDestroyPlanClient{
+		networkInterfaces: []*ec2.NetworkInterface{
+			{NetworkInterfaceId: aws.String("eni-nat"), InterfaceType: aws.String("nat_gateway"), SubnetId: aws.String("subnet-1")},
+			{
+				NetworkInterfaceId: aws.String("eni-manual"),
+				InterfaceType:      aws.String("interface"),
+				Status:             aws.String("available"),
+				SubnetId:           aws.String("subnet-1"),
+			},
+			{
+				NetworkInterfaceId: aws.String("eni-lambda"),
+				InterfaceType:      aws.String("lambda"),
+				RequesterManaged:   aws.Bool(true),
+				RequesterId:        aws.String("AROA1234"),
+				SubnetId:           aws.String("subnet-1"),
+				Groups:             []*ec2.GroupIdentifier{{GroupId: aws.String("sg-2")}, {GroupId: aws.String("sg-1")}},
+			},
+		},
+		vpnGateways: []*ec2.VpnGateway{{VpnGatewayId: aws.String("vgw-1")}},
+	}
+	o := &DestroyInfraOptions{InfraID: "test"}
+	g.Expect(o.planBlockers(context.Background(), client, aws.String("vpc-1"))).To(Succeed())
+	g.Expect(o.plan.Blockers).To(HaveLen(2))
+	g.Expect(o.plan.Blockers[0].ID).To(Equal("eni-lambda"))
+	g.Expect(o.plan.Blockers[0].Blocks).To(Equal([]string{"subnet-1", "sg-1", "sg-2"}))
+	g.Expect(o.plan.Blockers[1].ID).To(Equal("vgw-1"))
+	g.Expect(o.plan.Blockers[1].Blocks).To(Equal([]string{"vpc-1"}))
+}
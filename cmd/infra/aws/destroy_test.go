@@ -0,0 +1,146 @@
+package aws
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/go-logr/logr"
+	. "github.com/onsi/gomega"
+)
+
+type fakeDestroyNetworkClient struct {
+	ec2iface.EC2API
+	securityGroups     []*ec2.SecurityGroup
+	referencingGroups  []*ec2.SecurityGroup
+	networkInterfaces  []*ec2.NetworkInterface
+	deleteErrs         map[string]error
+	revokedIngress     map[string][]*ec2.IpPermission
+	revokedEgress      map[string][]*ec2.IpPermission
+	deletedGroups      []string
+	detached           []string
+	deletedInterfaces  []string
+	referencingFilters []string
+}
+
+func (f *fakeDestroyNetworkClient) DescribeSecurityGroupsPagesWithContext(_ aws.Context, input *ec2.DescribeSecurityGroupsInput, fn func(*ec2.DescribeSecurityGroupsOutput, bool) bool, _ ...request.Option) error {
+	if aws.StringValue(input.Filters[0].Name) == "vpc-id" {
+		fn(&ec2.DescribeSecurityGroupsOutput{SecurityGroups: f.securityGroups}, true)
+		return nil
+	}
+	f.referencingFilters = append(f.referencingFilters, aws.StringValue(input.Filters[0].Name))
+	fn(&ec2.DescribeSecurityGroupsOutput{SecurityGroups: append(f.securityGroups, f.referencingGroups...)}, true)
+	return nil
+}
+
+func (f *fakeDestroyNetworkClient) RevokeSecurityGroupIngressWithContext(_ aws.Context, input *ec2.RevokeSecurityGroupIngressInput, _ ...request.Option) (*ec2.RevokeSecurityGroupIngressOutput, error) {
+	f.revokedIngress[aws.StringValue(input.GroupId)] = input.IpPermissions
+	return &ec2.RevokeSecurityGroupIngressOutput{}, nil
+}
+
+func (f *fakeDestroyNetworkClient) RevokeSecurityGroupEgressWithContext(_ aws.Context, input *ec2.RevokeSecurityGroupEgressInput, _ ...request.Option) (*ec2.RevokeSecurityGroupEgressOutput, error) {
+	f.revokedEgress[aws.StringValue(input.GroupId)] = input.IpPermissions
+	return &ec2.RevokeSecurityGroupEgressOutput{}, nil
+}
+
+func (f *fakeDestroyNetworkClient) DeleteSecurityGroupWithContext(_ aws.Context, input *ec2.DeleteSecurityGroupInput, _ ...request.Option) (*ec2.DeleteSecurityGroupOutput, error) {
+	if err := f.deleteErrs[aws.StringValue(input.GroupId)]; err != nil {
+		return nil, err
+	}
+	f.deletedGroups = append(f.deletedGroups, aws.StringValue(input.GroupId))
+	return &ec2.DeleteSecurityGroupOutput{}, nil
+}
+
+func (f *fakeDestroyNetworkClient) DescribeNetworkInterfacesPagesWithContext(_ aws.Context, _ *ec2.DescribeNetworkInterfacesInput, fn func(*ec2.DescribeNetworkInterfacesOutput, bool) bool, _ ...request.Option) error {
+	fn(&ec2.DescribeNetworkInterfacesOutput{NetworkInterfaces: f.networkInterfaces}, true)
+	return nil
+}
+
+func (f *fakeDestroyNetworkClient) DetachNetworkInterfaceWithContext(_ aws.Context, input *ec2.DetachNetworkInterfaceInput, _ ...request.Option) (*ec2.DetachNetworkInterfaceOutput, error) {
+	f.detached = append(f.detached, aws.StringValue(input.AttachmentId))
+	return &ec2.DetachNetworkInterfaceOutput{}, nil
+}
+
+func (f *fakeDestroyNetworkClient) DeleteNetworkInterfaceWithContext(_ aws.Context, input *ec2.DeleteNetworkInterfaceInput, _ ...request.Option) (*ec2.DeleteNetworkInterfaceOutput, error) {
+	if err := f.deleteErrs[aws.StringValue(input.NetworkInterfaceId)]; err != nil {
+		return nil, err
+	}
+	f.deletedInterfaces = append(f.deletedInterfaces, aws.StringValue(input.NetworkInterfaceId))
+	return &ec2.DeleteNetworkInterfaceOutput{}, nil
+}
+
+func groupReference(protocol string, port int64, groupID string) *ec2.IpPermission {
+	return &ec2.IpPermission{
+		IpProtocol:       aws.String(protocol),
+		FromPort:         aws.Int64(port),
+		ToPort:           aws.Int64(port),
+		UserIdGroupPairs: []*ec2.UserIdGroupPair{{GroupId: aws.String(groupID)}},
+	}
+}
+
+func TestDestroySecurityGroups(t *testing.T) {
+	g := NewGomegaWithT(t)
+	client := &fakeDestroyNetworkClient{
+		securityGroups: []*ec2.SecurityGroup{
+			{GroupId: aws.String("sg-default"), GroupName: aws.String("default")},
+			{GroupId: aws.String("sg-worker"), GroupName: aws.String("test-worker-sg"), IpPermissions: []*ec2.IpPermission{groupReference("tcp", 443, "sg-endpoint")}},
+			{GroupId: aws.String("sg-endpoint"), GroupName: aws.String("test-vpce-sg")},
+			{GroupId: aws.String("sg-stuck"), GroupName: aws.String("test-stuck")},
+		},
+		referencingGroups: []*ec2.SecurityGroup{{
+			GroupId: aws.String("sg-peer"),
+			IpPermissions: []*ec2.IpPermission{
+				groupReference("tcp", 22, "sg-worker"),
+				groupReference("tcp", 80, "sg-peer"),
+			},
+			IpPermissionsEgress: []*ec2.IpPermission{groupReference("tcp", 6443, "sg-worker")},
+		}},
+		deleteErrs:     map[string]error{"sg-stuck": awserr.New("InvalidGroup.InUse", "in use", nil)},
+		revokedIngress: map[string][]*ec2.IpPermission{},
+		revokedEgress:  map[string][]*ec2.IpPermission{},
+	}
+	o := &DestroyInfraOptions{InfraID: "test", Log: logr.Discard()}
+	errs := o.DestroySecurityGroups(context.Background(), client, aws.String("vpc-1"))
+
+	g.Expect(errs).To(HaveLen(1))
+	g.Expect(errs[0].Error()).To(ContainSubstring("failed to delete security group sg-stuck"))
+	g.Expect(client.deletedGroups).To(Equal([]string{"sg-worker", "sg-endpoint"}))
+	g.Expect(client.revokedIngress["sg-worker"]).To(HaveLen(1))
+	g.Expect(client.referencingFilters).To(Equal([]string{"ip-permission.group-id", "egress.ip-permission.group-id"}))
+	g.Expect(client.revokedIngress["sg-peer"]).To(Equal([]*ec2.IpPermission{groupReference("tcp", 22, "sg-worker")}))
+	g.Expect(client.revokedEgress["sg-peer"]).To(Equal([]*ec2.IpPermission{groupReference("tcp", 6443, "sg-worker")}))
+}
+
+func TestDestroyNetworkInterfaces(t *testing.T) {
+	g := NewGomegaWithT(t)
+	client := &fakeDestroyNetworkClient{
+		networkInterfaces: []*ec2.NetworkInterface{
+			{NetworkInterfaceId: aws.String("eni-nat"), InterfaceType: aws.String("nat_gateway"), Status: aws.String("in-use")},
+			{NetworkInterfaceId: aws.String("eni-available"), InterfaceType: aws.String("interface"), Status: aws.String("available")},
+			{
+				NetworkInterfaceId: aws.String("eni-secondary"),
+				InterfaceType:      aws.String("interface"),
+				Status:             aws.String("in-use"),
+				Attachment:         &ec2.NetworkInterfaceAttachment{AttachmentId: aws.String("eni-attach-1"), InstanceId: aws.String("i-1"), DeviceIndex: aws.Int64(1)},
+			},
+			{
+				NetworkInterfaceId: aws.String("eni-primary"),
+				InterfaceType:      aws.String("interface"),
+				Status:             aws.String("in-use"),
+				Attachment:         &ec2.NetworkInterfaceAttachment{AttachmentId: aws.String("eni-attach-2"), InstanceId: aws.String("i-1"), DeviceIndex: aws.Int64(0)},
+			},
+			{NetworkInterfaceId: aws.String("eni-lambda"), InterfaceType: aws.String("lambda"), Status: aws.String("in-use"), RequesterManaged: aws.Bool(true), RequesterId: aws.String("AROA1234")},
+		},
+	}
+	o := &DestroyInfraOptions{InfraID: "test", Log: logr.Discard()}
+	errs := o.DestroyNetworkInterfaces(context.Background(), client, aws.String("vpc-1"))
+
+	g.Expect(errs).To(HaveLen(1))
+	g.Expect(errs[0].Error()).To(ContainSubstring("eni-lambda"))
+	g.Expect(client.detached).To(Equal([]string{"eni-attach-1"}))
+	g.Expect(client.deletedInterfaces).To(Equal([]string{"eni-available", "eni-secondary"}))
+}
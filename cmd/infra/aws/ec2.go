@@ -1,7 +1,9 @@
 package aws
 
 import (
+	"context"
 	"fmt"
+	"sort"
 	"strings"
 	"time"
 
@@ -13,7 +15,6 @@ import (
 	"github.com/openshift/hypershift/cmd/util"
 
 	"k8s.io/apimachinery/pkg/util/wait"
-	"k8s.io/client-go/util/retry"
 )
 
 const (
@@ -23,6 +24,18 @@ const (
 	invalidSubnet            = "InvalidSubnet"
 )
 
+const (
+	// NATTopologyPerZone creates a NAT gateway in every zone, so egress of a zone
+	// does not depend on other zones
+	NATTopologyPerZone = "per-zone"
+	// NATTopologySingle creates a single NAT gateway in the first zone that is
+	// shared by all zones
+	NATTopologySingle = "single"
+	// NATTopologyNone creates no NAT gateways, private subnets have no route to the
+	// internet
+	NATTopologyNone = "none"
+)
+
 var (
 	retryBackoff = wait.Backoff{
 		Steps:    5,
@@ -32,29 +45,85 @@ var (
 	}
 )
 
-func (o *CreateInfraOptions) firstZone(l logr.Logger, client ec2iface.EC2API) (string, error) {
-	result, err := client.DescribeAvailabilityZones(&ec2.DescribeAvailabilityZonesInput{})
+// selectZones returns the zones to create subnets in. Explicitly requested zones are
+// validated to exist in the region; otherwise the first ZoneCount (at least one)
+// available zones of the region are used.
+func (o *CreateInfraOptions) selectZones(ctx context.Context, l logr.Logger, client ec2iface.EC2API) ([]string, error) {
+	input := &ec2.DescribeAvailabilityZonesInput{
+		Filters: []*ec2.Filter{
+			{Name: aws.String("zone-type"), Values: []*string{aws.String("availability-zone")}},
+		},
+	}
+	if len(o.Zones) > 0 {
+		input.ZoneNames = aws.StringSlice(o.Zones)
+	}
+	result, err := client.DescribeAvailabilityZonesWithContext(ctx, input)
 	if err != nil {
-		return "", fmt.Errorf("failed to list availability zones: %w", err)
+		return nil, fmt.Errorf("failed to list availability zones: %w", err)
+	}
+	available := map[string]bool{}
+	var availableZones []string
+	for _, zone := range result.AvailabilityZones {
+		if aws.StringValue(zone.State) != ec2.AvailabilityZoneStateAvailable {
+			continue
+		}
+		available[aws.StringValue(zone.ZoneName)] = true
+		availableZones = append(availableZones, aws.StringValue(zone.ZoneName))
 	}
-	if len(result.AvailabilityZones) == 0 {
-		return "", fmt.Errorf("no availability zones found")
+	if len(o.Zones) > 0 {
+		for _, zone := range o.Zones {
+			if !available[zone] {
+				return nil, fmt.Errorf("zone %s is not an available availability zone in region %s", zone, o.Region)
+			}
+		}
+		return o.Zones, nil
+	}
+	count := o.ZoneCount
+	if count == 0 {
+		count = 1
+	}
+	if len(availableZones) < count {
+		return nil, fmt.Errorf("%d zones requested, but region %s only has %d available zones", count, o.Region, len(availableZones))
+	}
+	sort.Strings(availableZones)
+	zones := availableZones[:count]
+	l.Info("Using zones", "zones", zones)
+	return zones, nil
+}
 
+// validateZones validates the requested zones independently of the region.
+func (o *CreateInfraOptions) validateZones() error {
+	if len(o.Zones) > 0 && o.ZoneCount > 0 && o.ZoneCount != len(o.Zones) {
+		return fmt.Errorf("--zone-count %d does not match the %d zones given by --zones", o.ZoneCount, len(o.Zones))
+	}
+	if o.ZoneCount < 0 {
+		return fmt.Errorf("--zone-count must not be negative")
+	}
+	seen := map[string]bool{}
+	for _, zone := range o.Zones {
+		if seen[zone] {
+			return fmt.Errorf("zone %s is specified more than once", zone)
+		}
+		seen[zone] = true
 	}
-	zone := aws.StringValue(result.AvailabilityZones[0].ZoneName)
-	l.Info("Using zone", "zone", zone)
-	return zone, nil
+	if count := len(o.Zones) + o.ZoneCount; count > maxZones {
+		return fmt.Errorf("at most %d zones are supported", maxZones)
+	}
+	return nil
 }
 
-func (o *CreateInfraOptions) createVPC(l logr.Logger, client ec2iface.EC2API) (string, error) {
+func (o *CreateInfraOptions) createVPC(ctx context.Context, l logr.Logger, client ec2iface.EC2API) (string, error) {
 	vpcName := fmt.Sprintf("%s-vpc", o.InfraID)
-	vpcID, err := o.existingVPC(client, vpcName)
+	vpcID, err := o.existingVPC(ctx, client, vpcName)
 	if err != nil {
 		return "", err
 	}
+	if len(vpcID) == 0 && o.DryRun {
+		return o.planCreate(l, "vpc", vpcName), nil
+	}
 	if len(vpcID) == 0 {
-		createResult, err := client.CreateVpc(&ec2.CreateVpcInput{
-			CidrBlock:         aws.String(DefaultCIDRBlock),
+		createResult, err := client.CreateVpcWithContext(ctx, &ec2.CreateVpcInput{
+			CidrBlock:         aws.String(o.vpcCIDR()),
 			TagSpecifications: o.ec2TagSpecifications("vpc", vpcName),
 		})
 		if err != nil {
@@ -65,7 +134,24 @@ func (o *CreateInfraOptions) createVPC(l logr.Logger, client ec2iface.EC2API) (s
 	} else {
 		l.Info("Found existing VPC", "id", vpcID)
 	}
-	_, err = client.ModifyVpcAttribute(&ec2.ModifyVpcAttributeInput{
+	if o.DryRun {
+		if !isPlannedID(vpcID) {
+			dnsSupport, err := vpcAttributeEnabled(ctx, client, vpcID, ec2.VpcAttributeNameEnableDnsSupport)
+			if err != nil {
+				return "", err
+			}
+			dnsHostnames, err := vpcAttributeEnabled(ctx, client, vpcID, ec2.VpcAttributeNameEnableDnsHostnames)
+			if err != nil {
+				return "", err
+			}
+			if dnsSupport && dnsHostnames {
+				return vpcID, o.associateSecondaryCIDRs(ctx, l, client, vpcID)
+			}
+		}
+		o.planModify(l, "vpc", vpcID, "enable DNS support and DNS hostnames")
+		return vpcID, o.associateSecondaryCIDRs(ctx, l, client, vpcID)
+	}
+	_, err = client.ModifyVpcAttributeWithContext(ctx, &ec2.ModifyVpcAttributeInput{
 		VpcId:            aws.String(vpcID),
 		EnableDnsSupport: &ec2.AttributeBooleanValue{Value: aws.Bool(true)},
 	})
@@ -73,7 +159,7 @@ func (o *CreateInfraOptions) createVPC(l logr.Logger, client ec2iface.EC2API) (s
 		return "", fmt.Errorf("failed to modify VPC attributes: %w", err)
 	}
 	l.Info("Enabled DNS support on VPC", "id", vpcID)
-	_, err = client.ModifyVpcAttribute(&ec2.ModifyVpcAttributeInput{
+	_, err = client.ModifyVpcAttributeWithContext(ctx, &ec2.ModifyVpcAttributeInput{
 		VpcId:              aws.String(vpcID),
 		EnableDnsHostnames: &ec2.AttributeBooleanValue{Value: aws.Bool(true)},
 	})
@@ -81,24 +167,29 @@ func (o *CreateInfraOptions) createVPC(l logr.Logger, client ec2iface.EC2API) (s
 		return "", fmt.Errorf("failed to modify VPC attributes: %w", err)
 	}
 	l.Info("Enabled DNS hostnames on VPC", "id", vpcID)
+	if err := o.associateSecondaryCIDRs(ctx, l, client, vpcID); err != nil {
+		return "", err
+	}
 	return vpcID, nil
 }
 
-func (o *CreateInfraOptions) existingVPC(client ec2iface.EC2API, vpcName string) (string, error) {
+func (o *CreateInfraOptions) existingVPC(ctx context.Context, client ec2iface.EC2API, vpcName string) (string, error) {
 	var vpcID string
-	result, err := client.DescribeVpcs(&ec2.DescribeVpcsInput{Filters: o.ec2Filters(vpcName)})
+	err := client.DescribeVpcsPagesWithContext(ctx, &ec2.DescribeVpcsInput{Filters: o.ec2Filters(vpcName)}, func(out *ec2.DescribeVpcsOutput, _ bool) bool {
+		for _, vpc := range out.Vpcs {
+			vpcID = aws.StringValue(vpc.VpcId)
+			return false
+		}
+		return true
+	})
 	if err != nil {
 		return "", fmt.Errorf("cannot list vpcs: %w", err)
 	}
-	for _, vpc := range result.Vpcs {
-		vpcID = aws.StringValue(vpc.VpcId)
-		break
-	}
 	return vpcID, nil
 }
 
-func (o *CreateInfraOptions) CreateVPCS3Endpoint(l logr.Logger, client ec2iface.EC2API, vpcID string, routeTableIds []*string) error {
-	existingEndpoint, err := o.existingVPCS3Endpoint(client)
+func (o *CreateInfraOptions) CreateVPCS3Endpoint(ctx context.Context, l logr.Logger, client ec2iface.EC2API, vpcID string, routeTableIds []*string) error {
+	existingEndpoint, err := o.existingVPCS3Endpoint(ctx, client)
 	if err != nil {
 		return err
 	}
@@ -106,16 +197,20 @@ func (o *CreateInfraOptions) CreateVPCS3Endpoint(l logr.Logger, client ec2iface.
 		l.Info("Found existing s3 VPC endpoint", "id", existingEndpoint)
 		return nil
 	}
+	if o.DryRun {
+		o.planCreate(l, "vpc-endpoint", vpcEndpointServiceName(o.Region, "s3"))
+		return nil
+	}
 	isRetriable := func(err error) bool {
 		if awsErr, ok := err.(awserr.Error); ok {
 			return strings.EqualFold(awsErr.Code(), invalidRouteTableID)
 		}
 		return false
 	}
-	if err = retry.OnError(retryBackoff, isRetriable, func() error {
-		result, err := client.CreateVpcEndpoint(&ec2.CreateVpcEndpointInput{
+	if err = retryOnError(ctx, retryBackoff, isRetriable, func() error {
+		result, err := client.CreateVpcEndpointWithContext(ctx, &ec2.CreateVpcEndpointInput{
 			VpcId:             aws.String(vpcID),
-			ServiceName:       aws.String(fmt.Sprintf("com.amazonaws.%s.s3", o.Region)),
+			ServiceName:       aws.String(vpcEndpointServiceName(o.Region, "s3")),
 			RouteTableIds:     routeTableIds,
 			TagSpecifications: o.ec2TagSpecifications("vpc-endpoint", ""),
 		})
@@ -129,29 +224,39 @@ func (o *CreateInfraOptions) CreateVPCS3Endpoint(l logr.Logger, client ec2iface.
 	return nil
 }
 
-func (o *CreateInfraOptions) existingVPCS3Endpoint(client ec2iface.EC2API) (string, error) {
+func (o *CreateInfraOptions) existingVPCS3Endpoint(ctx context.Context, client ec2iface.EC2API) (string, error) {
 	var endpointID string
-	result, err := client.DescribeVpcEndpoints(&ec2.DescribeVpcEndpointsInput{Filters: o.ec2Filters("")})
+	// Other endpoints of the cluster, e.g. for PrivateLink, are tagged the same way
+	filters := append(o.ec2Filters(""), &ec2.Filter{
+		Name:   aws.String("service-name"),
+		Values: []*string{aws.String(vpcEndpointServiceName(o.Region, "s3"))},
+	})
+	err := client.DescribeVpcEndpointsPagesWithContext(ctx, &ec2.DescribeVpcEndpointsInput{Filters: filters}, func(out *ec2.DescribeVpcEndpointsOutput, _ bool) bool {
+		for _, endpoint := range out.VpcEndpoints {
+			endpointID = aws.StringValue(endpoint.VpcEndpointId)
+			return false
+		}
+		return true
+	})
 	if err != nil {
 		return "", fmt.Errorf("cannot list vpc endpoints: %w", err)
 	}
-	for _, endpoint := range result.VpcEndpoints {
-		endpointID = aws.StringValue(endpoint.VpcEndpointId)
-	}
 	return endpointID, nil
 }
 
-func (o *CreateInfraOptions) CreateDHCPOptions(l logr.Logger, client ec2iface.EC2API, vpcID string) error {
+func (o *CreateInfraOptions) CreateDHCPOptions(ctx context.Context, l logr.Logger, client ec2iface.EC2API, vpcID string) error {
 	domainName := "ec2.internal"
 	if o.Region != "us-east-1" {
 		domainName = fmt.Sprintf("%s.compute.internal", o.Region)
 	}
-	optID, err := o.existingDHCPOptions(client)
+	optID, err := o.existingDHCPOptions(ctx, client)
 	if err != nil {
 		return err
 	}
-	if len(optID) == 0 {
-		result, err := client.CreateDhcpOptions(&ec2.CreateDhcpOptionsInput{
+	if len(optID) == 0 && o.DryRun {
+		optID = o.planCreate(l, "dhcp-options", domainName)
+	} else if len(optID) == 0 {
+		result, err := client.CreateDhcpOptionsWithContext(ctx, &ec2.CreateDhcpOptionsInput{
 			DhcpConfigurations: []*ec2.NewDhcpConfiguration{
 				{
 					Key:    aws.String("domain-name"),
@@ -172,7 +277,20 @@ func (o *CreateInfraOptions) CreateDHCPOptions(l logr.Logger, client ec2iface.EC
 	} else {
 		l.Info("Found existing DHCP options", "id", optID)
 	}
-	_, err = client.AssociateDhcpOptions(&ec2.AssociateDhcpOptionsInput{
+	if o.DryRun {
+		if !isPlannedID(vpcID) && !isPlannedID(optID) {
+			result, err := client.DescribeVpcsWithContext(ctx, &ec2.DescribeVpcsInput{VpcIds: []*string{aws.String(vpcID)}})
+			if err != nil {
+				return fmt.Errorf("cannot describe VPC %s: %w", vpcID, err)
+			}
+			if len(result.Vpcs) > 0 && aws.StringValue(result.Vpcs[0].DhcpOptionsId) == optID {
+				return nil
+			}
+		}
+		o.planModify(l, "vpc", vpcID, fmt.Sprintf("associate DHCP options %s", optID))
+		return nil
+	}
+	_, err = client.AssociateDhcpOptionsWithContext(ctx, &ec2.AssociateDhcpOptionsInput{
 		DhcpOptionsId: aws.String(optID),
 		VpcId:         aws.String(vpcID),
 	})
@@ -183,29 +301,36 @@ func (o *CreateInfraOptions) CreateDHCPOptions(l logr.Logger, client ec2iface.EC
 	return nil
 }
 
-func (o *CreateInfraOptions) existingDHCPOptions(client ec2iface.EC2API) (string, error) {
+func (o *CreateInfraOptions) existingDHCPOptions(ctx context.Context, client ec2iface.EC2API) (string, error) {
 	var optID string
-	result, err := client.DescribeDhcpOptions(&ec2.DescribeDhcpOptionsInput{Filters: o.ec2Filters("")})
+	err := client.DescribeDhcpOptionsPagesWithContext(ctx, &ec2.DescribeDhcpOptionsInput{Filters: o.ec2Filters("")}, func(out *ec2.DescribeDhcpOptionsOutput, _ bool) bool {
+		for _, opt := range out.DhcpOptions {
+			optID = aws.StringValue(opt.DhcpOptionsId)
+			return false
+		}
+		return true
+	})
 	if err != nil {
 		return "", fmt.Errorf("cannot list dhcp options: %w", err)
 	}
-	for _, opt := range result.DhcpOptions {
-		optID = aws.StringValue(opt.DhcpOptionsId)
-		break
-	}
 	return optID, nil
 }
 
-func (o *CreateInfraOptions) CreatePrivateSubnet(l logr.Logger, client ec2iface.EC2API, vpcID string, zone string, cidr string) (string, error) {
-	return o.CreateSubnet(l, client, vpcID, zone, cidr, fmt.Sprintf("%s-private-%s", o.InfraID, zone))
+func (o *CreateInfraOptions) CreatePrivateSubnet(ctx context.Context, l logr.Logger, client ec2iface.EC2API, vpcID string, zone string, cidr string) (string, error) {
+	return o.CreateSubnet(ctx, l, client, vpcID, zone, cidr, fmt.Sprintf("%s-private-%s", o.InfraID, zone))
 }
 
-func (o *CreateInfraOptions) CreatePublicSubnet(l logr.Logger, client ec2iface.EC2API, vpcID string, zone string, cidr string) (string, error) {
-	return o.CreateSubnet(l, client, vpcID, zone, cidr, fmt.Sprintf("%s-public-%s", o.InfraID, zone))
+func (o *CreateInfraOptions) CreatePublicSubnet(ctx context.Context, l logr.Logger, client ec2iface.EC2API, vpcID string, zone string, cidr string) (string, error) {
+	return o.CreateSubnet(ctx, l, client, vpcID, zone, cidr, fmt.Sprintf("%s-public-%s", o.InfraID, zone))
 }
 
-func (o *CreateInfraOptions) CreateSubnet(l logr.Logger, client ec2iface.EC2API, vpcID, zone, cidr, name string) (string, error) {
-	subnetID, err := o.existingSubnet(client, name)
+func (o *CreateInfraOptions) CreateSubnet(ctx context.Context, l logr.Logger, client ec2iface.EC2API, vpcID, zone, cidr, name string) (string, error) {
+	return o.createSubnet(ctx, l, client, vpcID, zone, cidr, "", name)
+}
+
+// createSubnet creates a subnet, on the given Outpost if outpostARN is set.
+func (o *CreateInfraOptions) createSubnet(ctx context.Context, l logr.Logger, client ec2iface.EC2API, vpcID, zone, cidr, outpostARN, name string) (string, error) {
+	subnetID, err := o.existingSubnet(ctx, client, name)
 	if err != nil {
 		return "", err
 	}
@@ -213,61 +338,69 @@ func (o *CreateInfraOptions) CreateSubnet(l logr.Logger, client ec2iface.EC2API,
 		l.Info("Found existing subnet", "name", name, "id", subnetID)
 		return subnetID, nil
 	}
-	result, err := client.CreateSubnet(&ec2.CreateSubnetInput{
+	if o.DryRun {
+		return o.planCreate(l, "subnet", name), nil
+	}
+	input := &ec2.CreateSubnetInput{
 		AvailabilityZone:  aws.String(zone),
 		VpcId:             aws.String(vpcID),
 		CidrBlock:         aws.String(cidr),
 		TagSpecifications: o.ec2TagSpecifications("subnet", name),
-	})
+	}
+	if len(outpostARN) > 0 {
+		input.OutpostArn = aws.String(outpostARN)
+	}
+	result, err := client.CreateSubnetWithContext(ctx, input)
 	if err != nil {
 		return "", fmt.Errorf("cannot create public subnet: %w", err)
 	}
-	backoff := wait.Backoff{
-		Steps:    10,
-		Duration: 3 * time.Second,
-		Factor:   1.0,
-		Jitter:   0.1,
-	}
 	var subnetResult *ec2.DescribeSubnetsOutput
-	err = retry.OnError(backoff, func(error) bool { return true }, func() error {
+	err = retryOnError(ctx, ec2Backoff(), isNotFoundYet, func() error {
 		var err error
-		subnetResult, err = client.DescribeSubnets(&ec2.DescribeSubnetsInput{
+		subnetResult, err = client.DescribeSubnetsWithContext(ctx, &ec2.DescribeSubnetsInput{
 			SubnetIds: []*string{result.Subnet.SubnetId},
 		})
-		if err != nil || len(subnetResult.Subnets) == 0 {
-			return fmt.Errorf("not found yet")
+		if err == nil && len(subnetResult.Subnets) == 0 {
+			err = errNotFoundYet
 		}
-		return nil
+		return err
 	})
 	if err != nil {
-		return "", fmt.Errorf("cannot find subnet that was just created (%s)", aws.StringValue(result.Subnet.SubnetId))
+		return "", fmt.Errorf("cannot find subnet that was just created (%s): %w", aws.StringValue(result.Subnet.SubnetId), err)
 	}
 	subnetID = aws.StringValue(result.Subnet.SubnetId)
 	l.Info("Created subnet", "name", name, "id", subnetID)
 	return subnetID, nil
 }
 
-func (o *CreateInfraOptions) existingSubnet(client ec2iface.EC2API, name string) (string, error) {
+func (o *CreateInfraOptions) existingSubnet(ctx context.Context, client ec2iface.EC2API, name string) (string, error) {
 	var subnetID string
-	result, err := client.DescribeSubnets(&ec2.DescribeSubnetsInput{Filters: o.ec2Filters(name)})
+	err := client.DescribeSubnetsPagesWithContext(ctx, &ec2.DescribeSubnetsInput{Filters: o.ec2Filters(name)}, func(out *ec2.DescribeSubnetsOutput, _ bool) bool {
+		for _, subnet := range out.Subnets {
+			subnetID = aws.StringValue(subnet.SubnetId)
+			return false
+		}
+		return true
+	})
 	if err != nil {
 		return "", fmt.Errorf("cannot list subnets: %w", err)
 	}
-	for _, subnet := range result.Subnets {
-		subnetID = aws.StringValue(subnet.SubnetId)
-		break
-	}
 	return subnetID, nil
 }
 
-func (o *CreateInfraOptions) CreateInternetGateway(l logr.Logger, client ec2iface.EC2API, vpcID string) (string, error) {
+func (o *CreateInfraOptions) CreateInternetGateway(ctx context.Context, l logr.Logger, client ec2iface.EC2API, vpcID string) (string, error) {
 	gatewayName := fmt.Sprintf("%s-igw", o.InfraID)
-	igw, err := o.existingInternetGateway(client, gatewayName)
+	igw, err := o.existingInternetGateway(ctx, client, gatewayName)
 	if err != nil {
 		return "", err
 	}
+	if igw == nil && o.DryRun {
+		igwID := o.planCreate(l, "internet-gateway", gatewayName)
+		o.planModify(l, "internet-gateway", igwID, fmt.Sprintf("attach to VPC %s", vpcID))
+		return igwID, nil
+	}
 	if igw == nil {
-		result, err := client.CreateInternetGateway(&ec2.CreateInternetGatewayInput{
+		result, err := client.CreateInternetGatewayWithContext(ctx, &ec2.CreateInternetGatewayInput{
 			TagSpecifications: o.ec2TagSpecifications("internet-gateway", fmt.Sprintf("%s-igw", o.InfraID)),
 		})
 		if err != nil {
@@ -285,8 +418,10 @@ func (o *CreateInfraOptions) CreateInternetGateway(l logr.Logger, client ec2ifac
 			break
 		}
 	}
-	if !attached {
-		_, err = client.AttachInternetGateway(&ec2.AttachInternetGatewayInput{
+	if !attached && o.DryRun {
+		o.planModify(l, "internet-gateway", aws.StringValue(igw.InternetGatewayId), fmt.Sprintf("attach to VPC %s", vpcID))
+	} else if !attached {
+		_, err = client.AttachInternetGatewayWithContext(ctx, &ec2.AttachInternetGatewayInput{
 			InternetGatewayId: igw.InternetGatewayId,
 			VpcId:             aws.String(vpcID),
 		})
@@ -298,33 +433,106 @@ func (o *CreateInfraOptions) CreateInternetGateway(l logr.Logger, client ec2ifac
 	return aws.StringValue(igw.InternetGatewayId), nil
 }
 
-func (o *CreateInfraOptions) existingInternetGateway(client ec2iface.EC2API, name string) (*ec2.InternetGateway, error) {
-	result, err := client.DescribeInternetGateways(&ec2.DescribeInternetGatewaysInput{Filters: o.ec2Filters(name)})
+func (o *CreateInfraOptions) existingInternetGateway(ctx context.Context, client ec2iface.EC2API, name string) (*ec2.InternetGateway, error) {
+	var gateway *ec2.InternetGateway
+	err := client.DescribeInternetGatewaysPagesWithContext(ctx, &ec2.DescribeInternetGatewaysInput{Filters: o.ec2Filters(name)}, func(out *ec2.DescribeInternetGatewaysOutput, _ bool) bool {
+		for _, igw := range out.InternetGateways {
+			gateway = igw
+			return false
+		}
+		return true
+	})
 	if err != nil {
 		return nil, fmt.Errorf("cannot list internet gateways: %w", err)
 	}
-	for _, igw := range result.InternetGateways {
-		return igw, nil
+	return gateway, nil
+}
+
+// validateNATTopology validates the NAT topology flag.
+func (o *CreateInfraOptions) validateNATTopology() error {
+	switch o.NATTopology {
+	case "", NATTopologyPerZone, NATTopologySingle, NATTopologyNone:
+		return nil
+	default:
+		return fmt.Errorf("invalid --nat-topology %q, must be one of %s, %s or %s", o.NATTopology, NATTopologyPerZone, NATTopologySingle, NATTopologyNone)
+	}
+}
+
+// validatePublicIPv4PoolOptions validates the flag for allocating the elastic IPs
+// of the NAT gateways from a BYOIP pool.
+func (o *CreateInfraOptions) validatePublicIPv4PoolOptions() error {
+	if len(o.PublicIPv4Pool) == 0 {
+		return nil
+	}
+	if !strings.HasPrefix(o.PublicIPv4Pool, "ipv4pool-") {
+		return fmt.Errorf("invalid --public-ipv4-pool %q, expected the ID of a public IPv4 pool such as ipv4pool-ec2-0123456789abcdef0", o.PublicIPv4Pool)
+	}
+	if len(o.VPCID) > 0 || o.natTopology() == NATTopologyNone {
+		return fmt.Errorf("--public-ipv4-pool requires NAT gateways to be created, which is not the case with --vpc-id, --enable-proxy or --nat-topology %s", NATTopologyNone)
+	}
+	return nil
+}
+
+// checkPublicIPv4Pool checks that the public IPv4 pool given by --public-ipv4-pool
+// exists in the region before any resources are created.
+func (o *CreateInfraOptions) checkPublicIPv4Pool(ctx context.Context, l logr.Logger, client ec2iface.EC2API) error {
+	if len(o.PublicIPv4Pool) == 0 {
+		return nil
+	}
+	result, err := client.DescribePublicIpv4PoolsWithContext(ctx, &ec2.DescribePublicIpv4PoolsInput{
+		PoolIds: []*string{aws.String(o.PublicIPv4Pool)},
+	})
+	if err != nil {
+		return fmt.Errorf("cannot describe public IPv4 pool %s: %w", o.PublicIPv4Pool, err)
+	}
+	if len(result.PublicIpv4Pools) == 0 {
+		return fmt.Errorf("public IPv4 pool %s not found in region %s", o.PublicIPv4Pool, o.Region)
+	}
+	available := aws.Int64Value(result.PublicIpv4Pools[0].TotalAvailableAddressCount)
+	if available == 0 {
+		l.Info("WARNING: public IPv4 pool has no available addresses, NAT gateways that do not exist yet cannot be created", "pool", o.PublicIPv4Pool)
+	} else {
+		l.Info("Allocating NAT gateway elastic IPs from public IPv4 pool", "pool", o.PublicIPv4Pool, "available", available)
+	}
+	return nil
+}
+
+// natTopology returns the NAT topology to create. No NAT gateways are created when
+// egress goes through the proxy host.
+func (o *CreateInfraOptions) natTopology() string {
+	if o.EnableProxy {
+		return NATTopologyNone
+	}
+	if o.NATTopology == "" {
+		return NATTopologyPerZone
 	}
-	return nil, nil
+	return o.NATTopology
 }
 
-func (o *CreateInfraOptions) CreateNATGateway(l logr.Logger, client ec2iface.EC2API, publicSubnetID, availabilityZone string) (string, error) {
+func (o *CreateInfraOptions) CreateNATGateway(ctx context.Context, l logr.Logger, client ec2iface.EC2API, publicSubnetID, availabilityZone string) (string, error) {
 	natGatewayName := fmt.Sprintf("%s-nat-%s", o.InfraID, availabilityZone)
-	natGateway, _ := o.existingNATGateway(client, natGatewayName)
+	natGateway, _ := o.existingNATGateway(ctx, client, natGatewayName)
 	if natGateway != nil {
 		l.Info("Found existing NAT gateway", "id", aws.StringValue(natGateway.NatGatewayId))
 		return *natGateway.NatGatewayId, nil
 	}
+	if o.DryRun {
+		o.planCreate(l, "elastic-ip", fmt.Sprintf("%s-eip-%s", o.InfraID, availabilityZone))
+		return o.planCreate(l, "natgateway", natGatewayName), nil
+	}
 
-	eipResult, err := client.AllocateAddress(&ec2.AllocateAddressInput{
+	allocateInput := &ec2.AllocateAddressInput{
 		Domain: aws.String("vpc"),
-	})
+	}
+	if len(o.PublicIPv4Pool) > 0 {
+		allocateInput.PublicIpv4Pool = aws.String(o.PublicIPv4Pool)
+	}
+	eipResult, err := client.AllocateAddressWithContext(ctx, allocateInput)
 	if err != nil {
 		return "", fmt.Errorf("cannot allocate EIP for NAT gateway: %w", err)
 	}
 	allocationID := aws.StringValue(eipResult.AllocationId)
-	l.Info("Created elastic IP for NAT gateway", "id", allocationID)
+	l.Info("Created elastic IP for NAT gateway", "id", allocationID, "ip", aws.StringValue(eipResult.PublicIp))
 
 	// NOTE: there's a potential to leak EIP addresses if the following tag operation fails, since we have no way of
 	// recognizing the EIP as belonging to the cluster
@@ -334,8 +542,8 @@ func (o *CreateInfraOptions) CreateNATGateway(l logr.Logger, client ec2iface.EC2
 		}
 		return false
 	}
-	err = retry.OnError(retryBackoff, isRetriable, func() error {
-		_, err = client.CreateTags(&ec2.CreateTagsInput{
+	err = retryOnError(ctx, retryBackoff, isRetriable, func() error {
+		_, err = client.CreateTagsWithContext(ctx, &ec2.CreateTagsInput{
 			Resources: []*string{aws.String(allocationID)},
 			Tags:      append(ec2Tags(o.InfraID, fmt.Sprintf("%s-eip-%s", o.InfraID, availabilityZone)), o.additionalEC2Tags...),
 		})
@@ -352,8 +560,8 @@ func (o *CreateInfraOptions) CreateNATGateway(l logr.Logger, client ec2iface.EC2
 		}
 		return false
 	}
-	err = retry.OnError(retryBackoff, isNATGatewayRetriable, func() error {
-		gatewayResult, err := client.CreateNatGateway(&ec2.CreateNatGatewayInput{
+	err = retryOnError(ctx, retryBackoff, isNATGatewayRetriable, func() error {
+		gatewayResult, err := client.CreateNatGatewayWithContext(ctx, &ec2.CreateNatGatewayInput{
 			AllocationId:      aws.String(allocationID),
 			SubnetId:          aws.String(publicSubnetID),
 			TagSpecifications: o.ec2TagSpecifications("natgateway", natGatewayName),
@@ -373,29 +581,35 @@ func (o *CreateInfraOptions) CreateNATGateway(l logr.Logger, client ec2iface.EC2
 	return natGatewayID, nil
 }
 
-func (o *CreateInfraOptions) existingNATGateway(client ec2iface.EC2API, name string) (*ec2.NatGateway, error) {
-	result, err := client.DescribeNatGateways(&ec2.DescribeNatGatewaysInput{Filter: o.ec2Filters(name)})
+func (o *CreateInfraOptions) existingNATGateway(ctx context.Context, client ec2iface.EC2API, name string) (*ec2.NatGateway, error) {
+	var natGateway *ec2.NatGateway
+	err := client.DescribeNatGatewaysPagesWithContext(ctx, &ec2.DescribeNatGatewaysInput{Filter: o.ec2Filters(name)}, func(out *ec2.DescribeNatGatewaysOutput, _ bool) bool {
+		for _, gateway := range out.NatGateways {
+			state := aws.StringValue(gateway.State)
+			if state == "deleted" || state == "deleting" || state == "failed" {
+				continue
+			}
+			natGateway = gateway
+			return false
+		}
+		return true
+	})
 	if err != nil {
 		return nil, fmt.Errorf("cannot list NAT gateways: %w", err)
 	}
-	for _, gateway := range result.NatGateways {
-		state := aws.StringValue(gateway.State)
-		if state == "deleted" || state == "deleting" || state == "failed" {
-			continue
-		}
-		return gateway, nil
-	}
-	return nil, nil
+	return natGateway, nil
 }
 
-func (o *CreateInfraOptions) CreatePrivateRouteTable(l logr.Logger, client ec2iface.EC2API, vpcID, natGatewayID, subnetID, zone string) (string, error) {
+func (o *CreateInfraOptions) CreatePrivateRouteTable(ctx context.Context, l logr.Logger, client ec2iface.EC2API, vpcID, natGatewayID, subnetID, zone string) (string, error) {
 	tableName := fmt.Sprintf("%s-private-%s", o.InfraID, zone)
-	routeTable, err := o.existingRouteTable(l, client, tableName)
+	routeTable, err := o.existingRouteTable(ctx, l, client, tableName)
 	if err != nil {
 		return "", err
 	}
-	if routeTable == nil {
-		routeTable, err = o.createRouteTable(l, client, vpcID, tableName)
+	if routeTable == nil && o.DryRun {
+		routeTable = &ec2.RouteTable{RouteTableId: aws.String(o.planCreate(l, "route-table", tableName))}
+	} else if routeTable == nil {
+		routeTable, err = o.createRouteTable(ctx, l, client, vpcID, tableName)
 		if err != nil {
 			return "", err
 		}
@@ -406,15 +620,38 @@ func (o *CreateInfraOptions) CreatePrivateRouteTable(l logr.Logger, client ec2if
 		return aws.StringValue(routeTable.RouteTableId), nil
 	}
 
-	if !o.hasNATGatewayRoute(routeTable, natGatewayID) {
+	// A default route to another NAT gateway exists if the NAT topology changed
+	defaultRoute := ipv4DefaultRoute(routeTable)
+	if o.DryRun {
+		switch {
+		case len(natGatewayID) > 0 && !o.hasNATGatewayRoute(routeTable, natGatewayID):
+			o.planModify(l, "route-table", aws.StringValue(routeTable.RouteTableId), fmt.Sprintf("add route 0.0.0.0/0 to NAT gateway %s", natGatewayID))
+		case len(natGatewayID) == 0 && defaultRoute != nil:
+			o.planModify(l, "route-table", aws.StringValue(routeTable.RouteTableId), "delete route 0.0.0.0/0")
+		}
+		if !o.hasAssociatedSubnet(routeTable, subnetID) {
+			o.planModify(l, "route-table", aws.StringValue(routeTable.RouteTableId), fmt.Sprintf("associate subnet %s", subnetID))
+		}
+		return aws.StringValue(routeTable.RouteTableId), nil
+	}
+	switch {
+	case len(natGatewayID) > 0 && !o.hasNATGatewayRoute(routeTable, natGatewayID):
 		isRetriable := func(err error) bool {
 			if awsErr, ok := err.(awserr.Error); ok {
 				return strings.EqualFold(awsErr.Code(), invalidNATGatewayError)
 			}
 			return false
 		}
-		err = retry.OnError(retryBackoff, isRetriable, func() error {
-			_, err = client.CreateRoute(&ec2.CreateRouteInput{
+		err = retryOnError(ctx, retryBackoff, isRetriable, func() error {
+			if defaultRoute != nil {
+				_, err = client.ReplaceRouteWithContext(ctx, &ec2.ReplaceRouteInput{
+					RouteTableId:         routeTable.RouteTableId,
+					NatGatewayId:         aws.String(natGatewayID),
+					DestinationCidrBlock: aws.String("0.0.0.0/0"),
+				})
+				return err
+			}
+			_, err = client.CreateRouteWithContext(ctx, &ec2.CreateRouteInput{
 				RouteTableId:         routeTable.RouteTableId,
 				NatGatewayId:         aws.String(natGatewayID),
 				DestinationCidrBlock: aws.String("0.0.0.0/0"),
@@ -425,11 +662,19 @@ func (o *CreateInfraOptions) CreatePrivateRouteTable(l logr.Logger, client ec2if
 			return "", fmt.Errorf("cannot create nat gateway route in private route table: %w", err)
 		}
 		l.Info("Created route to NAT gateway", "route table", aws.StringValue(routeTable.RouteTableId), "nat gateway", natGatewayID)
-	} else {
+	case len(natGatewayID) > 0:
 		l.Info("Found existing route to NAT gateway", "route table", aws.StringValue(routeTable.RouteTableId), "nat gateway", natGatewayID)
+	case defaultRoute != nil:
+		if _, err = client.DeleteRouteWithContext(ctx, &ec2.DeleteRouteInput{
+			RouteTableId:         routeTable.RouteTableId,
+			DestinationCidrBlock: aws.String("0.0.0.0/0"),
+		}); err != nil {
+			return "", fmt.Errorf("cannot delete default route from private route table: %w", err)
+		}
+		l.Info("Deleted default route", "route table", aws.StringValue(routeTable.RouteTableId))
 	}
 	if !o.hasAssociatedSubnet(routeTable, subnetID) {
-		_, err = client.AssociateRouteTable(&ec2.AssociateRouteTableInput{
+		_, err = client.AssociateRouteTableWithContext(ctx, &ec2.AssociateRouteTableInput{
 			RouteTableId: routeTable.RouteTableId,
 			SubnetId:     aws.String(subnetID),
 		})
@@ -443,48 +688,43 @@ func (o *CreateInfraOptions) CreatePrivateRouteTable(l logr.Logger, client ec2if
 	return aws.StringValue(routeTable.RouteTableId), nil
 }
 
-func (o *CreateInfraOptions) CreatePublicRouteTable(l logr.Logger, client ec2iface.EC2API, vpcID, igwID string, subnetIDs []string) (string, error) {
+func (o *CreateInfraOptions) CreatePublicRouteTable(ctx context.Context, l logr.Logger, client ec2iface.EC2API, vpcID, igwID string, subnetIDs []string) (string, error) {
 	tableName := fmt.Sprintf("%s-public", o.InfraID)
-	routeTable, err := o.existingRouteTable(l, client, tableName)
+	routeTable, err := o.existingRouteTable(ctx, l, client, tableName)
 	if err != nil {
 		return "", err
 	}
-	if routeTable == nil {
-		routeTable, err = o.createRouteTable(l, client, vpcID, tableName)
+	if routeTable == nil && o.DryRun {
+		routeTable = &ec2.RouteTable{RouteTableId: aws.String(o.planCreate(l, "route-table", tableName))}
+	} else if routeTable == nil {
+		routeTable, err = o.createRouteTable(ctx, l, client, vpcID, tableName)
 		if err != nil {
 			return "", err
 		}
 	}
 	tableID := aws.StringValue(routeTable.RouteTableId)
+	if o.DryRun {
+		o.planPublicRouteTable(ctx, l, client, routeTable, vpcID, igwID, subnetIDs)
+		return tableID, nil
+	}
 	// Replace the VPC's main route table
-	routeTableInfo, err := client.DescribeRouteTables(&ec2.DescribeRouteTablesInput{
-		Filters: []*ec2.Filter{
-			{
-				Name:   aws.String("vpc-id"),
-				Values: []*string{aws.String(vpcID)},
-			},
-			{
-				Name:   aws.String("association.main"),
-				Values: []*string{aws.String("true")},
-			},
-		},
-	})
+	mainTable, err := mainRouteTable(ctx, client, vpcID)
 	if err != nil {
 		return "", err
 	}
-	if len(routeTableInfo.RouteTables) == 0 {
+	if mainTable == nil {
 		return "", fmt.Errorf("no route tables associated with the vpc")
 	}
 	// Replace route table association only if it's not the associated route table already
-	if aws.StringValue(routeTableInfo.RouteTables[0].RouteTableId) != tableID {
+	if aws.StringValue(mainTable.RouteTableId) != tableID {
 		var associationID string
-		for _, assoc := range routeTableInfo.RouteTables[0].Associations {
+		for _, assoc := range mainTable.Associations {
 			if aws.BoolValue(assoc.Main) {
 				associationID = aws.StringValue(assoc.RouteTableAssociationId)
 				break
 			}
 		}
-		_, err = client.ReplaceRouteTableAssociation(&ec2.ReplaceRouteTableAssociationInput{
+		_, err = client.ReplaceRouteTableAssociationWithContext(ctx, &ec2.ReplaceRouteTableAssociationInput{
 			RouteTableId:  aws.String(tableID),
 			AssociationId: aws.String(associationID),
 		})
@@ -496,7 +736,7 @@ func (o *CreateInfraOptions) CreatePublicRouteTable(l logr.Logger, client ec2ifa
 
 	// Create route to internet gateway
 	if !o.hasInternetGatewayRoute(routeTable, igwID) {
-		_, err = client.CreateRoute(&ec2.CreateRouteInput{
+		_, err = client.CreateRouteWithContext(ctx, &ec2.CreateRouteInput{
 			DestinationCidrBlock: aws.String("0.0.0.0/0"),
 			RouteTableId:         aws.String(tableID),
 			GatewayId:            aws.String(igwID),
@@ -512,7 +752,7 @@ func (o *CreateInfraOptions) CreatePublicRouteTable(l logr.Logger, client ec2ifa
 	// Associate the route table with the public subnet ID
 	for _, subnetID := range subnetIDs {
 		if !o.hasAssociatedSubnet(routeTable, subnetID) {
-			_, err = client.AssociateRouteTable(&ec2.AssociateRouteTableInput{
+			_, err = client.AssociateRouteTableWithContext(ctx, &ec2.AssociateRouteTableInput{
 				RouteTableId: aws.String(tableID),
 				SubnetId:     aws.String(subnetID),
 			})
@@ -527,8 +767,30 @@ func (o *CreateInfraOptions) CreatePublicRouteTable(l logr.Logger, client ec2ifa
 	return tableID, nil
 }
 
-func (o *CreateInfraOptions) createRouteTable(l logr.Logger, client ec2iface.EC2API, vpcID, name string) (*ec2.RouteTable, error) {
-	result, err := client.CreateRouteTable(&ec2.CreateRouteTableInput{
+// planPublicRouteTable records the changes CreatePublicRouteTable would make to the
+// given route table.
+func (o *CreateInfraOptions) planPublicRouteTable(ctx context.Context, l logr.Logger, client ec2iface.EC2API, routeTable *ec2.RouteTable, vpcID, igwID string, subnetIDs []string) {
+	tableID := aws.StringValue(routeTable.RouteTableId)
+	isMain := false
+	if !isPlannedID(vpcID) {
+		mainTable, err := mainRouteTable(ctx, client, vpcID)
+		isMain = err == nil && mainTable != nil && aws.StringValue(mainTable.RouteTableId) == tableID
+	}
+	if !isMain {
+		o.planModify(l, "vpc", vpcID, fmt.Sprintf("set main route table to %s", tableID))
+	}
+	if !o.hasInternetGatewayRoute(routeTable, igwID) {
+		o.planModify(l, "route-table", tableID, fmt.Sprintf("add route 0.0.0.0/0 to internet gateway %s", igwID))
+	}
+	for _, subnetID := range subnetIDs {
+		if !o.hasAssociatedSubnet(routeTable, subnetID) {
+			o.planModify(l, "route-table", tableID, fmt.Sprintf("associate subnet %s", subnetID))
+		}
+	}
+}
+
+func (o *CreateInfraOptions) createRouteTable(ctx context.Context, l logr.Logger, client ec2iface.EC2API, vpcID, name string) (*ec2.RouteTable, error) {
+	result, err := client.CreateRouteTableWithContext(ctx, &ec2.CreateRouteTableInput{
 		VpcId:             aws.String(vpcID),
 		TagSpecifications: o.ec2TagSpecifications("route-table", name),
 	})
@@ -539,16 +801,43 @@ func (o *CreateInfraOptions) createRouteTable(l logr.Logger, client ec2iface.EC2
 	return result.RouteTable, nil
 }
 
-func (o *CreateInfraOptions) existingRouteTable(l logr.Logger, client ec2iface.EC2API, name string) (*ec2.RouteTable, error) {
-	result, err := client.DescribeRouteTables(&ec2.DescribeRouteTablesInput{Filters: o.ec2Filters(name)})
+func (o *CreateInfraOptions) existingRouteTable(ctx context.Context, l logr.Logger, client ec2iface.EC2API, name string) (*ec2.RouteTable, error) {
+	routeTable, err := firstRouteTable(ctx, client, o.ec2Filters(name))
 	if err != nil {
 		return nil, fmt.Errorf("cannot list route tables: %w", err)
 	}
-	if len(result.RouteTables) > 0 {
-		l.Info("Found existing route table", "name", name, "id", aws.StringValue(result.RouteTables[0].RouteTableId))
-		return result.RouteTables[0], nil
+	if routeTable != nil {
+		l.Info("Found existing route table", "name", name, "id", aws.StringValue(routeTable.RouteTableId))
 	}
-	return nil, nil
+	return routeTable, nil
+}
+
+// mainRouteTable returns the main route table of the VPC.
+func mainRouteTable(ctx context.Context, client ec2iface.EC2API, vpcID string) (*ec2.RouteTable, error) {
+	return firstRouteTable(ctx, client, []*ec2.Filter{
+		{
+			Name:   aws.String("vpc-id"),
+			Values: []*string{aws.String(vpcID)},
+		},
+		{
+			Name:   aws.String("association.main"),
+			Values: []*string{aws.String("true")},
+		},
+	})
+}
+
+// firstRouteTable returns the first route table matching the filters, or nil if
+// there is none.
+func firstRouteTable(ctx context.Context, client ec2iface.EC2API, filters []*ec2.Filter) (*ec2.RouteTable, error) {
+	var routeTable *ec2.RouteTable
+	err := client.DescribeRouteTablesPagesWithContext(ctx, &ec2.DescribeRouteTablesInput{Filters: filters}, func(out *ec2.DescribeRouteTablesOutput, _ bool) bool {
+		for _, table := range out.RouteTables {
+			routeTable = table
+			return false
+		}
+		return true
+	})
+	return routeTable, err
 }
 
 func (o *CreateInfraOptions) hasNATGatewayRoute(table *ec2.RouteTable, natGatewayID string) bool {
@@ -561,6 +850,15 @@ func (o *CreateInfraOptions) hasNATGatewayRoute(table *ec2.RouteTable, natGatewa
 	return false
 }
 
+func ipv4DefaultRoute(table *ec2.RouteTable) *ec2.Route {
+	for _, route := range table.Routes {
+		if aws.StringValue(route.DestinationCidrBlock) == "0.0.0.0/0" {
+			return route
+		}
+	}
+	return nil
+}
+
 func (o *CreateInfraOptions) hasInternetGatewayRoute(table *ec2.RouteTable, igwID string) bool {
 	for _, route := range table.Routes {
 		if aws.StringValue(route.GatewayId) == igwID &&
@@ -573,7 +871,7 @@ func (o *CreateInfraOptions) hasInternetGatewayRoute(table *ec2.RouteTable, igwI
 
 func (o *CreateInfraOptions) hasAssociatedSubnet(table *ec2.RouteTable, subnetID string) bool {
 	for _, assoc := range table.Associations {
-		if aws.StringValue(assoc.RouteTableId) == subnetID {
+		if aws.StringValue(assoc.SubnetId) == subnetID {
 			return true
 		}
 	}
@@ -594,6 +892,7 @@ func (o *CreateInfraOptions) parseAdditionalTags() error {
 	if err != nil {
 		return err
 	}
+	o.additionalEC2Tags = nil
 	for k, v := range parsed {
 		o.additionalEC2Tags = append(o.additionalEC2Tags, &ec2.Tag{
 			Key:   aws.String(k),
@@ -0,0 +1,292 @@
+package aws
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/go-logr/logr"
+)
+
+const (
+	bastionInstanceType = "t2.micro"
+	bastionImageID      = "resolve:ssm:/aws/service/ami-amazon-linux-latest/amzn2-ami-hvm-x86_64-gp2"
+	bastionProxyPort    = 3128
+)
+
+// validateBastionOptions validates the flags for the bastion host.
+func (o *CreateInfraOptions) validateBastionOptions() error {
+	if !o.Bastion {
+		if len(o.BastionAllowedSSHCIDRs) > 0 {
+			return errors.New("--bastion-allowed-ssh-cidrs can only be specified together with --bastion")
+		}
+		return nil
+	}
+	if len(o.VPCID) > 0 {
+		return errors.New("--bastion is not supported with an existing VPC")
+	}
+	if len(o.SSHKeyFile) == 0 {
+		return errors.New("--ssh-key-file is required when --bastion is specified")
+	}
+	if len(o.BastionAllowedSSHCIDRs) == 0 {
+		return errors.New("--bastion-allowed-ssh-cidrs is required when --bastion is specified")
+	}
+	for _, cidr := range o.BastionAllowedSSHCIDRs {
+		if _, _, err := net.ParseCIDR(cidr); err != nil {
+			return fmt.Errorf("invalid CIDR %q in --bastion-allowed-ssh-cidrs: %w", cidr, err)
+		}
+	}
+	return nil
+}
+
+func (o *CreateInfraOptions) bastionName() string {
+	return fmt.Sprintf("%s-bastion", o.InfraID)
+}
+
+// CreateBastion creates a bastion host in the given public subnet. SSH access is
+// limited to BastionAllowedSSHCIDRs, and the host runs an HTTP proxy on port 3128
+// that can be reached from the VPC. The key pair, security group and instance are
+// tagged with the infra ID so that they are removed when the infrastructure is
+// destroyed.
+func (o *CreateInfraOptions) CreateBastion(ctx context.Context, l logr.Logger, client ec2iface.EC2API, vpcID, subnetID string, result *CreateInfraOutput) error {
+	sshKey, err := os.ReadFile(o.SSHKeyFile)
+	if err != nil {
+		return fmt.Errorf("cannot read SSH public key from %s: %w", o.SSHKeyFile, err)
+	}
+	keyName, err := o.ensureBastionKeyPair(ctx, l, client, sshKey)
+	if err != nil {
+		return err
+	}
+	securityGroupID, err := o.ensureBastionSecurityGroup(ctx, l, client, vpcID)
+	if err != nil {
+		return err
+	}
+	instance, err := o.existingInstance(ctx, client, o.bastionName())
+	if err != nil {
+		return err
+	}
+	if instance == nil && o.DryRun {
+		result.BastionInstanceID = o.planCreate(l, "instance", o.bastionName())
+		return nil
+	}
+	if instance == nil {
+		runResult, err := client.RunInstancesWithContext(ctx, &ec2.RunInstancesInput{
+			ImageId:      aws.String(bastionImageID),
+			MaxCount:     aws.Int64(1),
+			MinCount:     aws.Int64(1),
+			InstanceType: aws.String(bastionInstanceType),
+			KeyName:      aws.String(keyName),
+			UserData:     aws.String(base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf(proxyConfigurationScript, sshKey)))),
+			NetworkInterfaces: []*ec2.InstanceNetworkInterfaceSpecification{
+				{
+					DeviceIndex:              aws.Int64(0),
+					AssociatePublicIpAddress: aws.Bool(true),
+					SubnetId:                 aws.String(subnetID),
+					Groups:                   []*string{aws.String(securityGroupID)},
+				},
+			},
+			TagSpecifications: o.ec2TagSpecifications("instance", o.bastionName()),
+		})
+		if err != nil {
+			return fmt.Errorf("cannot launch bastion instance: %w", err)
+		}
+		if len(runResult.Instances) == 0 {
+			return errors.New("no bastion instance was launched")
+		}
+		instance = runResult.Instances[0]
+		l.Info("Created bastion instance", "id", aws.StringValue(instance.InstanceId), "name", o.bastionName())
+	} else {
+		l.Info("Found existing bastion instance", "id", aws.StringValue(instance.InstanceId), "name", o.bastionName())
+	}
+	result.BastionInstanceID = aws.StringValue(instance.InstanceId)
+	if o.DryRun {
+		return nil
+	}
+
+	// The public IP is only assigned once the instance is running
+	instanceIDs := []*string{instance.InstanceId}
+	if err := client.WaitUntilInstanceRunningWithContext(ctx, &ec2.DescribeInstancesInput{InstanceIds: instanceIDs}); err != nil {
+		return fmt.Errorf("bastion instance %s did not become ready: %w", result.BastionInstanceID, err)
+	}
+	described, err := client.DescribeInstancesWithContext(ctx, &ec2.DescribeInstancesInput{InstanceIds: instanceIDs})
+	if err != nil {
+		return fmt.Errorf("cannot describe bastion instance %s: %w", result.BastionInstanceID, err)
+	}
+	for _, reservation := range described.Reservations {
+		for _, running := range reservation.Instances {
+			result.BastionPublicIP = aws.StringValue(running.PublicIpAddress)
+			result.BastionProxyAddr = fmt.Sprintf("http://%s:%d", aws.StringValue(running.PrivateIpAddress), bastionProxyPort)
+		}
+	}
+	l.Info("Bastion instance is running", "id", result.BastionInstanceID, "publicIP", result.BastionPublicIP)
+	return nil
+}
+
+// ensureBastionKeyPair imports the SSH public key as key pair for the bastion if
+// it does not exist yet.
+func (o *CreateInfraOptions) ensureBastionKeyPair(ctx context.Context, l logr.Logger, client ec2iface.EC2API, sshKey []byte) (string, error) {
+	name := o.bastionName()
+	existing, err := client.DescribeKeyPairsWithContext(ctx, &ec2.DescribeKeyPairsInput{Filters: o.ec2Filters(name)})
+	if err != nil {
+		return "", fmt.Errorf("cannot list key pairs: %w", err)
+	}
+	if len(existing.KeyPairs) > 0 {
+		l.Info("Found existing key pair", "name", aws.StringValue(existing.KeyPairs[0].KeyName))
+		return aws.StringValue(existing.KeyPairs[0].KeyName), nil
+	}
+	if o.DryRun {
+		o.planCreate(l, "key-pair", name)
+		return name, nil
+	}
+	if _, err := client.ImportKeyPairWithContext(ctx, &ec2.ImportKeyPairInput{
+		KeyName:           aws.String(name),
+		PublicKeyMaterial: sshKey,
+		TagSpecifications: o.ec2TagSpecifications("key-pair", name),
+	}); err != nil {
+		return "", fmt.Errorf("cannot import bastion key pair: %w", err)
+	}
+	l.Info("Created key pair", "name", name)
+	return name, nil
+}
+
+// ensureBastionSecurityGroup creates the security group of the bastion and
+// authorizes SSH from the allowed CIDRs and proxy traffic from the VPC.
+func (o *CreateInfraOptions) ensureBastionSecurityGroup(ctx context.Context, l logr.Logger, client ec2iface.EC2API, vpcID string) (string, error) {
+	groupName := fmt.Sprintf("%s-bastion-sg", o.InfraID)
+	securityGroup, err := o.existingSecurityGroup(ctx, client, groupName)
+	if err != nil {
+		return "", err
+	}
+	if securityGroup == nil && o.DryRun {
+		return o.planCreate(l, "security-group", groupName), nil
+	}
+	if securityGroup == nil {
+		result, err := client.CreateSecurityGroupWithContext(ctx, &ec2.CreateSecurityGroupInput{
+			GroupName:         aws.String(groupName),
+			Description:       aws.String("bastion security group"),
+			VpcId:             aws.String(vpcID),
+			TagSpecifications: o.ec2TagSpecifications("security-group", groupName),
+		})
+		if err != nil {
+			return "", fmt.Errorf("cannot create bastion security group: %w", err)
+		}
+		var sgResult *ec2.DescribeSecurityGroupsOutput
+		err = retryOnError(ctx, ec2Backoff(), isNotFoundYet, func() error {
+			var err error
+			sgResult, err = client.DescribeSecurityGroupsWithContext(ctx, &ec2.DescribeSecurityGroupsInput{
+				GroupIds: []*string{result.GroupId},
+			})
+			if err == nil && len(sgResult.SecurityGroups) == 0 {
+				err = errNotFoundYet
+			}
+			return err
+		})
+		if err != nil {
+			return "", fmt.Errorf("cannot find security group that was just created (%s): %w", aws.StringValue(result.GroupId), err)
+		}
+		securityGroup = sgResult.SecurityGroups[0]
+		l.Info("Created security group", "name", groupName, "id", aws.StringValue(securityGroup.GroupId))
+	} else {
+		l.Info("Found existing security group", "name", groupName, "id", aws.StringValue(securityGroup.GroupId))
+	}
+	securityGroupID := aws.StringValue(securityGroup.GroupId)
+
+	var ingressToAuthorize []*ec2.IpPermission
+	for _, permission := range o.bastionIngressPermissions() {
+		if !includesPermission(securityGroup.IpPermissions, permission) {
+			ingressToAuthorize = append(ingressToAuthorize, permission)
+		}
+	}
+	if len(ingressToAuthorize) == 0 {
+		return securityGroupID, nil
+	}
+	if o.DryRun {
+		o.planModify(l, "security-group", securityGroupID, fmt.Sprintf("authorize %d ingress rules", len(ingressToAuthorize)))
+		return securityGroupID, nil
+	}
+	_, err = client.AuthorizeSecurityGroupIngressWithContext(ctx, &ec2.AuthorizeSecurityGroupIngressInput{
+		GroupId:       aws.String(securityGroupID),
+		IpPermissions: ingressToAuthorize,
+	})
+	var awsErr awserr.Error
+	if err != nil && (!errors.As(err, &awsErr) || awsErr.Code() != duplicatePermissionErrorCode) {
+		return "", fmt.Errorf("cannot apply bastion security group ingress permissions: %w", err)
+	}
+	l.Info("Authorized ingress rules on security group", "id", securityGroupID)
+	return securityGroupID, nil
+}
+
+// bastionIngressPermissions returns the ingress rules of the bastion security
+// group: SSH from each allowed CIDR and the proxy port from the VPC CIDRs.
+func (o *CreateInfraOptions) bastionIngressPermissions() []*ec2.IpPermission {
+	var permissions []*ec2.IpPermission
+	for _, cidr := range o.BastionAllowedSSHCIDRs {
+		permissions = append(permissions, &ec2.IpPermission{
+			IpProtocol: aws.String("tcp"),
+			IpRanges:   []*ec2.IpRange{{CidrIp: aws.String(cidr)}},
+			FromPort:   aws.Int64(22),
+			ToPort:     aws.Int64(22),
+		})
+	}
+	for _, cidr := range o.machineCIDRs() {
+		permissions = append(permissions, &ec2.IpPermission{
+			IpProtocol: aws.String("tcp"),
+			IpRanges:   []*ec2.IpRange{{CidrIp: aws.String(cidr)}},
+			FromPort:   aws.Int64(bastionProxyPort),
+			ToPort:     aws.Int64(bastionProxyPort),
+		})
+	}
+	return permissions
+}
+
+// existingInstance returns the instance with the given name that is not
+// terminated, or nil if there is none.
+func (o *CreateInfraOptions) existingInstance(ctx context.Context, client ec2iface.EC2API, name string) (*ec2.Instance, error) {
+	filters := append(o.ec2Filters(name), &ec2.Filter{
+		Name:   aws.String("instance-state-name"),
+		Values: aws.StringSlice([]string{ec2.InstanceStateNamePending, ec2.InstanceStateNameRunning, ec2.InstanceStateNameStopping, ec2.InstanceStateNameStopped}),
+	})
+	var existing *ec2.Instance
+	err := client.DescribeInstancesPagesWithContext(ctx, &ec2.DescribeInstancesInput{Filters: filters}, func(out *ec2.DescribeInstancesOutput, _ bool) bool {
+		for _, reservation := range out.Reservations {
+			for _, instance := range reservation.Instances {
+				existing = instance
+				return false
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cannot list instances: %w", err)
+	}
+	return existing, nil
+}
+
+// DestroyKeyPairs deletes the key pairs tagged with the infra ID.
+func (o *DestroyInfraOptions) DestroyKeyPairs(ctx context.Context, client ec2iface.EC2API) []error {
+	var errs []error
+	out, err := client.DescribeKeyPairsWithContext(ctx, &ec2.DescribeKeyPairsInput{
+		Filters: o.ec2Filters(),
+	})
+	if err != nil {
+		return append(errs, err)
+	}
+	for _, keyPair := range out.KeyPairs {
+		_, err := client.DeleteKeyPairWithContext(ctx, &ec2.DeleteKeyPairInput{
+			KeyPairId: keyPair.KeyPairId,
+		})
+		if err != nil {
+			errs = append(errs, err)
+		} else {
+			o.Log.Info("Deleted key pair", "name", aws.StringValue(keyPair.KeyName))
+		}
+	}
+	return errs
+}
@@ -0,0 +1,221 @@
+package aws
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/go-logr/logr"
+	. "github.com/onsi/gomega"
+)
+
+type fakeBastionClient struct {
+	ec2iface.EC2API
+	keyPairs       []*ec2.KeyPairInfo
+	securityGroups []*ec2.SecurityGroup
+	instances      []*ec2.Instance
+
+	imported   *ec2.ImportKeyPairInput
+	authorized []*ec2.IpPermission
+	revoked    []*ec2.IpPermission
+	launched   *ec2.RunInstancesInput
+}
+
+func (f *fakeBastionClient) DescribeKeyPairsWithContext(_ aws.Context, _ *ec2.DescribeKeyPairsInput, _ ...request.Option) (*ec2.DescribeKeyPairsOutput, error) {
+	return &ec2.DescribeKeyPairsOutput{KeyPairs: f.keyPairs}, nil
+}
+
+func (f *fakeBastionClient) ImportKeyPairWithContext(_ aws.Context, in *ec2.ImportKeyPairInput, _ ...request.Option) (*ec2.ImportKeyPairOutput, error) {
+	f.imported = in
+	return &ec2.ImportKeyPairOutput{KeyName: in.KeyName}, nil
+}
+
+func (f *fakeBastionClient) DescribeSecurityGroupsWithContext(_ aws.Context, _ *ec2.DescribeSecurityGroupsInput, _ ...request.Option) (*ec2.DescribeSecurityGroupsOutput, error) {
+	return &ec2.DescribeSecurityGroupsOutput{SecurityGroups: f.securityGroups}, nil
+}
+
+func (f *fakeBastionClient) DescribeSecurityGroupsPagesWithContext(ctx aws.Context, in *ec2.DescribeSecurityGroupsInput, fn func(*ec2.DescribeSecurityGroupsOutput, bool) bool, _ ...request.Option) error {
+	out, _ := f.DescribeSecurityGroupsWithContext(ctx, in)
+	fn(out, true)
+	return nil
+}
+
+func (f *fakeBastionClient) CreateSecurityGroupWithContext(_ aws.Context, _ *ec2.CreateSecurityGroupInput, _ ...request.Option) (*ec2.CreateSecurityGroupOutput, error) {
+	f.securityGroups = append(f.securityGroups, &ec2.SecurityGroup{GroupId: aws.String("sg-bastion")})
+	return &ec2.CreateSecurityGroupOutput{GroupId: aws.String("sg-bastion")}, nil
+}
+
+func (f *fakeBastionClient) AuthorizeSecurityGroupIngressWithContext(_ aws.Context, in *ec2.AuthorizeSecurityGroupIngressInput, _ ...request.Option) (*ec2.AuthorizeSecurityGroupIngressOutput, error) {
+	f.authorized = append(f.authorized, in.IpPermissions...)
+	return &ec2.AuthorizeSecurityGroupIngressOutput{}, nil
+}
+
+func (f *fakeBastionClient) RevokeSecurityGroupIngressWithContext(_ aws.Context, in *ec2.RevokeSecurityGroupIngressInput, _ ...request.Option) (*ec2.RevokeSecurityGroupIngressOutput, error) {
+	f.revoked = append(f.revoked, in.IpPermissions...)
+	return &ec2.RevokeSecurityGroupIngressOutput{}, nil
+}
+
+func (f *fakeBastionClient) DescribeInstancesWithContext(_ aws.Context, _ *ec2.DescribeInstancesInput, _ ...request.Option) (*ec2.DescribeInstancesOutput, error) {
+	if len(f.instances) == 0 {
+		return &ec2.DescribeInstancesOutput{}, nil
+	}
+	return &ec2.DescribeInstancesOutput{Reservations: []*ec2.Reservation{{Instances: f.instances}}}, nil
+}
+
+func (f *fakeBastionClient) DescribeInstancesPagesWithContext(ctx aws.Context, in *ec2.DescribeInstancesInput, fn func(*ec2.DescribeInstancesOutput, bool) bool, _ ...request.Option) error {
+	out, _ := f.DescribeInstancesWithContext(ctx, in)
+	fn(out, true)
+	return nil
+}
+
+func (f *fakeBastionClient) RunInstancesWithContext(_ aws.Context, in *ec2.RunInstancesInput, _ ...request.Option) (*ec2.Reservation, error) {
+	f.launched = in
+	instance := &ec2.Instance{InstanceId: aws.String("i-bastion"), PrivateIpAddress: aws.String("10.0.128.10"), PublicIpAddress: aws.String("203.0.113.10")}
+	f.instances = append(f.instances, instance)
+	return &ec2.Reservation{Instances: []*ec2.Instance{instance}}, nil
+}
+
+func (f *fakeBastionClient) WaitUntilInstanceRunningWithContext(aws.Context, *ec2.DescribeInstancesInput, ...request.WaiterOption) error {
+	return nil
+}
+
+func TestValidateBastionOptions(t *testing.T) {
+	testCases := []struct {
+		name      string
+		options   CreateInfraOptions
+		expectErr bool
+	}{
+		{
+			name:    "no bastion",
+			options: CreateInfraOptions{},
+		},
+		{
+			name:    "bastion",
+			options: CreateInfraOptions{Bastion: true, SSHKeyFile: "id_rsa.pub", BastionAllowedSSHCIDRs: []string{"192.0.2.0/24"}},
+		},
+		{
+			name:      "allowed CIDRs without bastion",
+			options:   CreateInfraOptions{BastionAllowedSSHCIDRs: []string{"192.0.2.0/24"}},
+			expectErr: true,
+		},
+		{
+			name:      "no SSH key",
+			options:   CreateInfraOptions{Bastion: true, BastionAllowedSSHCIDRs: []string{"192.0.2.0/24"}},
+			expectErr: true,
+		},
+		{
+			name:      "no allowed CIDRs",
+			options:   CreateInfraOptions{Bastion: true, SSHKeyFile: "id_rsa.pub"},
+			expectErr: true,
+		},
+		{
+			name:      "invalid CIDR",
+			options:   CreateInfraOptions{Bastion: true, SSHKeyFile: "id_rsa.pub", BastionAllowedSSHCIDRs: []string{"192.0.2.1"}},
+			expectErr: true,
+		},
+		{
+			name:      "existing VPC",
+			options:   CreateInfraOptions{Bastion: true, SSHKeyFile: "id_rsa.pub", BastionAllowedSSHCIDRs: []string{"192.0.2.0/24"}, VPCID: "vpc-1"},
+			expectErr: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewGomegaWithT(t)
+			err := tc.options.validateBastionOptions()
+			if tc.expectErr {
+				g.Expect(err).To(HaveOccurred())
+			} else {
+				g.Expect(err).ToNot(HaveOccurred())
+			}
+		})
+	}
+}
+
+func TestCreateBastion(t *testing.T) {
+	g := NewGomegaWithT(t)
+	keyFile := filepath.Join(t.TempDir(), "id_rsa.pub")
+	g.Expect(os.WriteFile(keyFile, []byte("ssh-rsa AAAA test"), 0600)).To(Succeed())
+	o := &CreateInfraOptions{InfraID: "test", SSHKeyFile: keyFile, Bastion: true, BastionAllowedSSHCIDRs: []string{"192.0.2.0/24"}}
+
+	client := &fakeBastionClient{}
+	result := &CreateInfraOutput{}
+	g.Expect(o.CreateBastion(context.Background(), logr.Discard(), client, "vpc-1", "subnet-public", result)).To(Succeed())
+	g.Expect(aws.StringValue(client.imported.KeyName)).To(Equal("test-bastion"))
+	g.Expect(client.authorized).To(ConsistOf(
+		&ec2.IpPermission{IpProtocol: aws.String("tcp"), IpRanges: []*ec2.IpRange{{CidrIp: aws.String("192.0.2.0/24")}}, FromPort: aws.Int64(22), ToPort: aws.Int64(22)},
+		&ec2.IpPermission{IpProtocol: aws.String("tcp"), IpRanges: []*ec2.IpRange{{CidrIp: aws.String(DefaultCIDRBlock)}}, FromPort: aws.Int64(3128), ToPort: aws.Int64(3128)},
+	))
+	g.Expect(aws.StringValue(client.launched.KeyName)).To(Equal("test-bastion"))
+	g.Expect(aws.StringValue(client.launched.NetworkInterfaces[0].SubnetId)).To(Equal("subnet-public"))
+	g.Expect(aws.BoolValue(client.launched.NetworkInterfaces[0].AssociatePublicIpAddress)).To(BeTrue())
+	g.Expect(result.BastionInstanceID).To(Equal("i-bastion"))
+	g.Expect(result.BastionPublicIP).To(Equal("203.0.113.10"))
+	g.Expect(result.BastionProxyAddr).To(Equal("http://10.0.128.10:3128"))
+
+	// A second run reuses the existing resources
+	client.keyPairs = []*ec2.KeyPairInfo{{KeyName: aws.String("test-bastion")}}
+	client.securityGroups[0].IpPermissions = client.authorized
+	client.imported, client.authorized, client.launched = nil, nil, nil
+	g.Expect(o.CreateBastion(context.Background(), logr.Discard(), client, "vpc-1", "subnet-public", result)).To(Succeed())
+	g.Expect(client.imported).To(BeNil())
+	g.Expect(client.authorized).To(BeEmpty())
+	g.Expect(client.launched).To(BeNil())
+	g.Expect(result.BastionInstanceID).To(Equal("i-bastion"))
+}
+
+func TestCreateBastionDryRun(t *testing.T) {
+	g := NewGomegaWithT(t)
+	keyFile := filepath.Join(t.TempDir(), "id_rsa.pub")
+	g.Expect(os.WriteFile(keyFile, []byte("ssh-rsa AAAA test"), 0600)).To(Succeed())
+	o := &CreateInfraOptions{InfraID: "test", SSHKeyFile: keyFile, Bastion: true, BastionAllowedSSHCIDRs: []string{"192.0.2.0/24"}, DryRun: true}
+
+	client := &fakeBastionClient{}
+	g.Expect(o.CreateBastion(context.Background(), logr.Discard(), client, "vpc-1", "subnet-public", &CreateInfraOutput{})).To(Succeed())
+	g.Expect(client.imported).To(BeNil())
+	g.Expect(client.securityGroups).To(BeEmpty())
+	g.Expect(client.launched).To(BeNil())
+	g.Expect(o.plan.Actions).To(HaveLen(3))
+}
+
+func TestCreateProxyHost(t *testing.T) {
+	g := NewGomegaWithT(t)
+	o := &CreateInfraOptions{Name: "test", InfraID: "test-infra", VPCCIDR: "172.16.0.0/16", SecondaryCIDRs: []string{"172.17.0.0/16"}}
+	allTraffic := &ec2.IpPermission{IpProtocol: aws.String("-1"), IpRanges: []*ec2.IpRange{{CidrIp: aws.String("172.16.0.0/16")}, {CidrIp: aws.String("172.17.0.0/16")}}}
+	ssh := func(cidr string) *ec2.IpPermission {
+		return &ec2.IpPermission{IpProtocol: aws.String("tcp"), FromPort: aws.Int64(22), ToPort: aws.Int64(22), IpRanges: []*ec2.IpRange{{CidrIp: aws.String(cidr)}}}
+	}
+
+	client := &fakeBastionClient{}
+	proxyAddr, err := o.createProxyHost(context.Background(), logr.Discard(), client, "subnet-public", "vpc-1", "ssh-rsa AAAA test")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(proxyAddr).To(Equal("http://10.0.128.10:3128"))
+	g.Expect(client.authorized).To(ConsistOf(
+		&ec2.IpPermission{IpProtocol: aws.String("tcp"), FromPort: aws.Int64(22), ToPort: aws.Int64(22), IpRanges: allTraffic.IpRanges},
+		allTraffic,
+	))
+	g.Expect(aws.StringValue(client.launched.NetworkInterfaces[0].Groups[0])).To(Equal("sg-bastion"))
+
+	// A second run reuses the existing resources and revokes the SSH rule from
+	// anywhere and the all traffic rule for 10.0.0.0/8 of a previous version
+	client.securityGroups[0].IpPermissions = append(client.authorized, ssh("0.0.0.0/0"), &ec2.IpPermission{IpProtocol: aws.String("-1"), IpRanges: []*ec2.IpRange{{CidrIp: aws.String("10.0.0.0/8")}}})
+	client.authorized, client.launched = nil, nil
+	proxyAddr, err = o.createProxyHost(context.Background(), logr.Discard(), client, "subnet-public", "vpc-1", "ssh-rsa AAAA test")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(proxyAddr).To(Equal("http://10.0.128.10:3128"))
+	g.Expect(client.authorized).To(BeEmpty())
+	g.Expect(client.launched).To(BeNil())
+	g.Expect(client.revoked).To(ConsistOf(ssh("0.0.0.0/0"), &ec2.IpPermission{IpProtocol: aws.String("-1"), IpRanges: []*ec2.IpRange{{CidrIp: aws.String("10.0.0.0/8")}}}))
+
+	// SSH is allowed from the SSH ingress CIDRs instead of the machine CIDRs if given
+	o.SSHIngressCIDRs = []string{"192.168.0.0/24"}
+	client.revoked = nil
+	_, err = o.createProxyHost(context.Background(), logr.Discard(), client, "subnet-public", "vpc-1", "ssh-rsa AAAA test")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(client.authorized).To(ConsistOf(ssh("192.168.0.0/24")))
+}
@@ -0,0 +1,384 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/go-logr/logr"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+)
+
+// validateExistingVPCOptions validates the flags for bringing an existing VPC.
+func (o *CreateInfraOptions) validateExistingVPCOptions() error {
+	if len(o.VPCID) == 0 {
+		if len(o.SubnetIDs) > 0 {
+			return errors.New("--subnet-ids can only be specified together with --vpc-id")
+		}
+		if len(o.SecurityGroupID) > 0 {
+			return errors.New("--security-group-id can only be specified together with --vpc-id")
+		}
+		return nil
+	}
+	if len(o.SubnetIDs) == 0 && !o.Adopt {
+		return errors.New("--subnet-ids is required when --vpc-id is specified")
+	}
+	if len(o.Zones) > 0 {
+		return errors.New("--zones cannot be specified together with --vpc-id, zones are determined by --subnet-ids")
+	}
+	if o.EnableProxy {
+		return errors.New("--enable-proxy is not supported with an existing VPC")
+	}
+	if o.EnableIPv6 {
+		return errors.New("--enable-ipv6 is not supported with an existing VPC")
+	}
+	return nil
+}
+
+// useExistingVPC validates the VPC and private subnets given by VPCID and SubnetIDs
+// and fills in the VPC, machine CIDR and zones of the result. The VPC and subnets
+// are tagged as shared with the cluster, which keeps them from being deleted when
+// the cluster infrastructure is destroyed. The route tables of the subnets are
+// returned so the S3 endpoint can be attached to them. Neither is done for a VPC
+// shared by another account.
+func (o *CreateInfraOptions) useExistingVPC(ctx context.Context, l logr.Logger, client ec2iface.EC2API, result *CreateInfraOutput) ([]*string, error) {
+	if err := o.validateExistingVPC(ctx, l, client); err != nil {
+		return nil, err
+	}
+	result.VPCID = o.VPCID
+	result.MachineCIDR = o.VPCCIDR
+
+	subnets, err := client.DescribeSubnetsWithContext(ctx, &ec2.DescribeSubnetsInput{SubnetIds: aws.StringSlice(o.SubnetIDs)})
+	if err != nil {
+		return nil, fmt.Errorf("cannot find subnets %v: %w", o.SubnetIDs, err)
+	}
+	subnetZones := map[string]string{}
+	for _, subnet := range subnets.Subnets {
+		if aws.StringValue(subnet.VpcId) != o.VPCID {
+			return nil, fmt.Errorf("subnet %s does not belong to VPC %s", aws.StringValue(subnet.SubnetId), o.VPCID)
+		}
+		subnetZones[aws.StringValue(subnet.SubnetId)] = aws.StringValue(subnet.AvailabilityZone)
+	}
+	seenZones := map[string]string{}
+	for _, subnetID := range o.SubnetIDs {
+		zone, found := subnetZones[subnetID]
+		if !found {
+			return nil, fmt.Errorf("subnet %s not found", subnetID)
+		}
+		if other, duplicate := seenZones[zone]; duplicate {
+			return nil, fmt.Errorf("subnets %s and %s are both in zone %s, only one subnet per zone is supported", other, subnetID, zone)
+		}
+		seenZones[zone] = subnetID
+		o.Zones = append(o.Zones, zone)
+		result.Zones = append(result.Zones, &CreateInfraOutputZone{
+			Name:     zone,
+			SubnetID: subnetID,
+		})
+		l.Info("Using existing subnet", "id", subnetID, "zone", zone)
+	}
+
+	if o.isSharedVPC() {
+		// The VPC, subnets and route tables can only be changed by the VPC owner
+		l.Info("Skipping tags of VPC and subnets shared by another account", "owner", o.vpcOwnerAccountID)
+		return nil, nil
+	}
+	sharedResources := append([]string{o.VPCID}, o.SubnetIDs...)
+	if o.DryRun {
+		for _, id := range sharedResources {
+			o.planModify(l, "tag", id, fmt.Sprintf("tag %s=%s", clusterTag(o.InfraID), sharedClusterTagValue))
+		}
+	} else {
+		if _, err := client.CreateTagsWithContext(ctx, &ec2.CreateTagsInput{
+			Resources: aws.StringSlice(sharedResources),
+			Tags:      []*ec2.Tag{{Key: aws.String(clusterTag(o.InfraID)), Value: aws.String(sharedClusterTagValue)}},
+		}); err != nil {
+			return nil, fmt.Errorf("cannot tag existing VPC and subnets: %w", err)
+		}
+		l.Info("Tagged existing VPC and subnets as shared", "ids", sharedResources)
+	}
+
+	return o.subnetRouteTables(ctx, client, o.SubnetIDs)
+}
+
+// validateExistingVPC validates the VPC given by VPCID and replaces VPCCIDR with its
+// CIDR. The VPC must have DNS support and hostnames enabled.
+func (o *CreateInfraOptions) validateExistingVPC(ctx context.Context, l logr.Logger, client ec2iface.EC2API) error {
+	vpcs, err := client.DescribeVpcsWithContext(ctx, &ec2.DescribeVpcsInput{VpcIds: []*string{aws.String(o.VPCID)}})
+	if err != nil {
+		return fmt.Errorf("cannot find VPC %s: %w", o.VPCID, err)
+	}
+	if len(vpcs.Vpcs) == 0 {
+		return fmt.Errorf("VPC %s not found", o.VPCID)
+	}
+	vpc := vpcs.Vpcs[0]
+	if err := o.detectSharedVPC(l, vpc); err != nil {
+		return err
+	}
+	for _, attribute := range []string{ec2.VpcAttributeNameEnableDnsSupport, ec2.VpcAttributeNameEnableDnsHostnames} {
+		enabled, err := vpcAttributeEnabled(ctx, client, o.VPCID, attribute)
+		if err != nil {
+			return err
+		}
+		if !enabled {
+			return fmt.Errorf("VPC %s must have %s enabled", o.VPCID, attribute)
+		}
+	}
+	l.Info("Using existing VPC", "id", o.VPCID)
+	// The security group rules and overlap validation must use the CIDR of the
+	// existing VPC rather than the requested one
+	o.VPCCIDR = aws.StringValue(vpc.CidrBlock)
+	return o.validateCIDRs()
+}
+
+// subnetRouteTables returns the IDs of the route tables associated with the given
+// subnets. Subnets without an explicit association use the main route table of the VPC.
+func (o *CreateInfraOptions) subnetRouteTables(ctx context.Context, client ec2iface.EC2API, subnetIDs []string) ([]*string, error) {
+	var routeTableIDs []*string
+	seen := map[string]bool{}
+	add := func(routeTableID string) {
+		if !seen[routeTableID] {
+			seen[routeTableID] = true
+			routeTableIDs = append(routeTableIDs, aws.String(routeTableID))
+		}
+	}
+	associated := map[string]bool{}
+	err := client.DescribeRouteTablesPagesWithContext(ctx, &ec2.DescribeRouteTablesInput{Filters: []*ec2.Filter{
+		{Name: aws.String("association.subnet-id"), Values: aws.StringSlice(subnetIDs)},
+	}}, func(out *ec2.DescribeRouteTablesOutput, _ bool) bool {
+		for _, table := range out.RouteTables {
+			for _, association := range table.Associations {
+				if association.SubnetId != nil {
+					associated[aws.StringValue(association.SubnetId)] = true
+				}
+			}
+			add(aws.StringValue(table.RouteTableId))
+		}
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cannot list route tables of subnets: %w", err)
+	}
+	if len(associated) < len(subnetIDs) {
+		mainTable, err := mainRouteTable(ctx, client, o.VPCID)
+		if err != nil {
+			return nil, fmt.Errorf("cannot find main route table of VPC %s: %w", o.VPCID, err)
+		}
+		if mainTable != nil {
+			add(aws.StringValue(mainTable.RouteTableId))
+		}
+	}
+	return routeTableIDs, nil
+}
+
+// existingVPCS3EndpointInVPC returns the ID of any S3 endpoint in the VPC,
+// whether or not it was created for the cluster.
+func (o *CreateInfraOptions) existingVPCS3EndpointInVPC(ctx context.Context, client ec2iface.EC2API, vpcID string) (string, error) {
+	var endpointID string
+	err := client.DescribeVpcEndpointsPagesWithContext(ctx, &ec2.DescribeVpcEndpointsInput{Filters: []*ec2.Filter{
+		{Name: aws.String("vpc-id"), Values: []*string{aws.String(vpcID)}},
+		{Name: aws.String("service-name"), Values: []*string{aws.String(vpcEndpointServiceName(o.Region, "s3"))}},
+	}}, func(out *ec2.DescribeVpcEndpointsOutput, _ bool) bool {
+		for _, endpoint := range out.VpcEndpoints {
+			endpointID = aws.StringValue(endpoint.VpcEndpointId)
+			return false
+		}
+		return true
+	})
+	if err != nil {
+		return "", fmt.Errorf("cannot list vpc endpoints: %w", err)
+	}
+	return endpointID, nil
+}
+
+// workerSecurityGroup returns the existing security group given by SecurityGroupID
+// after validating it, or creates the worker security group otherwise.
+func (o *CreateInfraOptions) workerSecurityGroup(ctx context.Context, l logr.Logger, client ec2iface.EC2API, vpcID string) (string, error) {
+	if len(o.SecurityGroupID) == 0 {
+		return o.CreateWorkerSecurityGroup(ctx, client, vpcID)
+	}
+	result, err := client.DescribeSecurityGroupsWithContext(ctx, &ec2.DescribeSecurityGroupsInput{GroupIds: []*string{aws.String(o.SecurityGroupID)}})
+	if err != nil {
+		return "", fmt.Errorf("cannot find security group %s: %w", o.SecurityGroupID, err)
+	}
+	if len(result.SecurityGroups) == 0 {
+		return "", fmt.Errorf("security group %s not found", o.SecurityGroupID)
+	}
+	securityGroup := result.SecurityGroups[0]
+	if aws.StringValue(securityGroup.VpcId) != vpcID {
+		return "", fmt.Errorf("security group %s does not belong to VPC %s", o.SecurityGroupID, vpcID)
+	}
+	if owner := aws.StringValue(securityGroup.OwnerId); o.isSharedVPC() && owner != o.accountID {
+		return "", fmt.Errorf("security group %s is owned by account %s, participants of a shared VPC can only use their own security groups", o.SecurityGroupID, owner)
+	}
+	if err := o.validateExistingSecurityGroup(securityGroup); err != nil {
+		return "", err
+	}
+	l.Info("Using existing security group", "id", o.SecurityGroupID)
+	return o.SecurityGroupID, nil
+}
+
+// validateExistingSecurityGroup checks that the rules of the security group allow at
+// least the traffic allowed by the worker security group that would otherwise be
+// created, including any additional ingress rules. All missing rules are reported.
+func (o *CreateInfraOptions) validateExistingSecurityGroup(securityGroup *ec2.SecurityGroup) error {
+	securityGroupID := aws.StringValue(securityGroup.GroupId)
+	var errs []error
+	for _, missing := range missingPermissions(securityGroup.IpPermissionsEgress, o.workerEgressPermissions()) {
+		errs = append(errs, fmt.Errorf("security group %s is missing egress rule %s", securityGroupID, missing))
+	}
+	if o.RestrictEgress {
+		if len(unrestrictedEgressPermissions(securityGroup.IpPermissionsEgress)) > 0 {
+			errs = append(errs, fmt.Errorf("security group %s allows egress to all destinations, which is not allowed with --restrict-egress", securityGroupID))
+		}
+	}
+	ingress := append(workerSecurityGroupIngressPermissions(o.machineCIDRs(), o.sshIngressCIDRs(), securityGroupID, aws.StringValue(securityGroup.OwnerId)), o.additionalIngressPermissions...)
+	for _, missing := range missingPermissions(securityGroup.IpPermissions, ingress) {
+		errs = append(errs, fmt.Errorf("security group %s is missing ingress rule %s", securityGroupID, missing))
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// missingPermissions returns a description of every source of the required
+// permissions that is not allowed by any of the existing permissions. Unlike
+// includesPermission, an existing rule with a wider port range, a wider CIDR or
+// all protocols also covers the required rule.
+func missingPermissions(existing, required []*ec2.IpPermission) []string {
+	var missing []string
+	for _, permission := range required {
+		covering := func(matchesSource func(*ec2.IpPermission) bool) bool {
+			for _, p := range existing {
+				if permissionCoversPorts(p, permission) && matchesSource(p) {
+					return true
+				}
+			}
+			return false
+		}
+		for _, ipRange := range permission.IpRanges {
+			cidr := aws.StringValue(ipRange.CidrIp)
+			if !covering(func(p *ec2.IpPermission) bool {
+				for _, r := range p.IpRanges {
+					if cidrContains(aws.StringValue(r.CidrIp), cidr) {
+						return true
+					}
+				}
+				return false
+			}) {
+				missing = append(missing, fmt.Sprintf("%s from %s", describePorts(permission), cidr))
+			}
+		}
+		for _, ipRange := range permission.Ipv6Ranges {
+			cidr := aws.StringValue(ipRange.CidrIpv6)
+			if !covering(func(p *ec2.IpPermission) bool {
+				for _, r := range p.Ipv6Ranges {
+					if cidrContains(aws.StringValue(r.CidrIpv6), cidr) {
+						return true
+					}
+				}
+				return false
+			}) {
+				missing = append(missing, fmt.Sprintf("%s from %s", describePorts(permission), cidr))
+			}
+		}
+		for _, prefixList := range permission.PrefixListIds {
+			prefixListID := aws.StringValue(prefixList.PrefixListId)
+			if !covering(func(p *ec2.IpPermission) bool {
+				for _, other := range p.PrefixListIds {
+					if aws.StringValue(other.PrefixListId) == prefixListID {
+						return true
+					}
+				}
+				return false
+			}) {
+				missing = append(missing, fmt.Sprintf("%s to prefix list %s", describePorts(permission), prefixListID))
+			}
+		}
+		for _, pair := range permission.UserIdGroupPairs {
+			groupID := aws.StringValue(pair.GroupId)
+			if !covering(func(p *ec2.IpPermission) bool {
+				for _, other := range p.UserIdGroupPairs {
+					if aws.StringValue(other.GroupId) == groupID {
+						return true
+					}
+				}
+				return false
+			}) {
+				missing = append(missing, fmt.Sprintf("%s from security group %s", describePorts(permission), groupID))
+			}
+		}
+	}
+	return missing
+}
+
+// permissionCoversPorts returns whether the protocol and ports of the existing
+// permission include those of the required one.
+func permissionCoversPorts(existing, required *ec2.IpPermission) bool {
+	protocol := aws.StringValue(existing.IpProtocol)
+	if protocol == "-1" {
+		return true
+	}
+	if protocol != aws.StringValue(required.IpProtocol) {
+		return false
+	}
+	if required.FromPort == nil || existing.FromPort == nil {
+		return existing.FromPort == nil
+	}
+	from, to := aws.Int64Value(existing.FromPort), aws.Int64Value(existing.ToPort)
+	requiredFrom, requiredTo := aws.Int64Value(required.FromPort), aws.Int64Value(required.ToPort)
+	if protocol == "icmp" {
+		// The ports of ICMP rules are the type and code, -1 means all
+		return (from == -1 || from == requiredFrom) && (to == -1 || to == requiredTo)
+	}
+	return from <= requiredFrom && to >= requiredTo
+}
+
+func describePorts(permission *ec2.IpPermission) string {
+	protocol := aws.StringValue(permission.IpProtocol)
+	switch {
+	case protocol == "-1":
+		return "all traffic"
+	case permission.FromPort == nil:
+		return fmt.Sprintf("protocol %s", protocol)
+	case aws.Int64Value(permission.FromPort) == -1:
+		return protocol
+	case aws.Int64Value(permission.FromPort) == aws.Int64Value(permission.ToPort):
+		return fmt.Sprintf("%s %d", protocol, aws.Int64Value(permission.FromPort))
+	default:
+		return fmt.Sprintf("%s %d-%d", protocol, aws.Int64Value(permission.FromPort), aws.Int64Value(permission.ToPort))
+	}
+}
+
+// cidrContains returns whether the network of outer contains all of inner.
+func cidrContains(outer, inner string) bool {
+	_, outerNetwork, err := net.ParseCIDR(outer)
+	if err != nil {
+		return false
+	}
+	_, innerNetwork, err := net.ParseCIDR(inner)
+	if err != nil {
+		return false
+	}
+	outerOnes, outerBits := outerNetwork.Mask.Size()
+	innerOnes, innerBits := innerNetwork.Mask.Size()
+	return outerBits == innerBits && outerOnes <= innerOnes && outerNetwork.Contains(innerNetwork.IP)
+}
+
+func vpcAttributeEnabled(ctx context.Context, client ec2iface.EC2API, vpcID, attribute string) (bool, error) {
+	result, err := client.DescribeVpcAttributeWithContext(ctx, &ec2.DescribeVpcAttributeInput{
+		VpcId:     aws.String(vpcID),
+		Attribute: aws.String(attribute),
+	})
+	if err != nil {
+		return false, fmt.Errorf("cannot get %s attribute of VPC %s: %w", attribute, vpcID, err)
+	}
+	switch attribute {
+	case ec2.VpcAttributeNameEnableDnsSupport:
+		return result.EnableDnsSupport != nil && aws.BoolValue(result.EnableDnsSupport.Value), nil
+	case ec2.VpcAttributeNameEnableDnsHostnames:
+		return result.EnableDnsHostnames != nil && aws.BoolValue(result.EnableDnsHostnames.Value), nil
+	}
+	return false, fmt.Errorf("unsupported VPC attribute %s", attribute)
+}
@@ -0,0 +1,168 @@
+package aws
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/go-logr/logr"
+	. "github.com/onsi/gomega"
+)
+
+type fakeExistingVPCClient struct {
+	ec2iface.EC2API
+	vpcOwner    string
+	subnets     []*ec2.Subnet
+	routeTables []*ec2.RouteTable
+	tagged      []string
+}
+
+func (f *fakeExistingVPCClient) DescribeVpcsWithContext(_ aws.Context, in *ec2.DescribeVpcsInput, _ ...request.Option) (*ec2.DescribeVpcsOutput, error) {
+	return &ec2.DescribeVpcsOutput{Vpcs: []*ec2.Vpc{{VpcId: in.VpcIds[0], CidrBlock: aws.String("10.1.0.0/16"), OwnerId: aws.String(f.vpcOwner)}}}, nil
+}
+
+func (f *fakeExistingVPCClient) DescribeVpcAttributeWithContext(_ aws.Context, in *ec2.DescribeVpcAttributeInput, _ ...request.Option) (*ec2.DescribeVpcAttributeOutput, error) {
+	enabled := &ec2.AttributeBooleanValue{Value: aws.Bool(true)}
+	return &ec2.DescribeVpcAttributeOutput{EnableDnsSupport: enabled, EnableDnsHostnames: enabled}, nil
+}
+
+func (f *fakeExistingVPCClient) DescribeSubnetsWithContext(_ aws.Context, in *ec2.DescribeSubnetsInput, _ ...request.Option) (*ec2.DescribeSubnetsOutput, error) {
+	return &ec2.DescribeSubnetsOutput{Subnets: f.subnets}, nil
+}
+
+func (f *fakeExistingVPCClient) DescribeRouteTablesPagesWithContext(_ aws.Context, in *ec2.DescribeRouteTablesInput, fn func(*ec2.DescribeRouteTablesOutput, bool) bool, _ ...request.Option) error {
+	fn(&ec2.DescribeRouteTablesOutput{RouteTables: f.routeTables}, true)
+	return nil
+}
+
+func (f *fakeExistingVPCClient) CreateTagsWithContext(_ aws.Context, in *ec2.CreateTagsInput, _ ...request.Option) (*ec2.CreateTagsOutput, error) {
+	f.tagged = append(f.tagged, aws.StringValueSlice(in.Resources)...)
+	return &ec2.CreateTagsOutput{}, nil
+}
+
+func TestValidateExistingVPCOptions(t *testing.T) {
+	testCases := []struct {
+		name        string
+		options     CreateInfraOptions
+		expectError bool
+	}{
+		{
+			name:    "no existing VPC",
+			options: CreateInfraOptions{Zones: []string{"us-east-1a"}},
+		},
+		{
+			name:    "existing VPC and subnets",
+			options: CreateInfraOptions{VPCID: "vpc-1", SubnetIDs: []string{"subnet-1"}},
+		},
+		{
+			name:        "subnets without VPC",
+			options:     CreateInfraOptions{SubnetIDs: []string{"subnet-1"}},
+			expectError: true,
+		},
+		{
+			name:        "security group without VPC",
+			options:     CreateInfraOptions{SecurityGroupID: "sg-1"},
+			expectError: true,
+		},
+		{
+			name:        "VPC without subnets",
+			options:     CreateInfraOptions{VPCID: "vpc-1"},
+			expectError: true,
+		},
+		{
+			name:        "VPC with zones",
+			options:     CreateInfraOptions{VPCID: "vpc-1", SubnetIDs: []string{"subnet-1"}, Zones: []string{"us-east-1a"}},
+			expectError: true,
+		},
+		{
+			name:        "VPC with proxy",
+			options:     CreateInfraOptions{VPCID: "vpc-1", SubnetIDs: []string{"subnet-1"}, EnableProxy: true},
+			expectError: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewGomegaWithT(t)
+			err := tc.options.validateExistingVPCOptions()
+			if tc.expectError {
+				g.Expect(err).To(HaveOccurred())
+			} else {
+				g.Expect(err).ToNot(HaveOccurred())
+			}
+		})
+	}
+}
+
+func TestUseExistingVPC(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	client := &fakeExistingVPCClient{
+		subnets: []*ec2.Subnet{
+			{SubnetId: aws.String("subnet-b"), VpcId: aws.String("vpc-1"), AvailabilityZone: aws.String("us-east-1b")},
+			{SubnetId: aws.String("subnet-a"), VpcId: aws.String("vpc-1"), AvailabilityZone: aws.String("us-east-1a")},
+		},
+		routeTables: []*ec2.RouteTable{
+			{RouteTableId: aws.String("rtb-1"), Associations: []*ec2.RouteTableAssociation{{SubnetId: aws.String("subnet-a")}, {SubnetId: aws.String("subnet-b")}}},
+		},
+	}
+	o := &CreateInfraOptions{InfraID: "test", VPCID: "vpc-1", SubnetIDs: []string{"subnet-a", "subnet-b"}}
+	result := &CreateInfraOutput{}
+	routeTables, err := o.useExistingVPC(context.Background(), logr.Discard(), client, result)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(aws.StringValueSlice(routeTables)).To(Equal([]string{"rtb-1"}))
+	g.Expect(result.VPCID).To(Equal("vpc-1"))
+	g.Expect(result.MachineCIDR).To(Equal("10.1.0.0/16"))
+	g.Expect(result.Zones).To(Equal([]*CreateInfraOutputZone{{Name: "us-east-1a", SubnetID: "subnet-a"}, {Name: "us-east-1b", SubnetID: "subnet-b"}}))
+	g.Expect(client.tagged).To(ConsistOf("vpc-1", "subnet-a", "subnet-b"))
+
+	// Subnets must belong to the VPC
+	client.subnets[0].VpcId = aws.String("vpc-2")
+	_, err = (&CreateInfraOptions{InfraID: "test", VPCID: "vpc-1", SubnetIDs: []string{"subnet-a", "subnet-b"}}).useExistingVPC(context.Background(), logr.Discard(), client, &CreateInfraOutput{})
+	g.Expect(err).To(HaveOccurred())
+
+	// Only one subnet per zone is supported
+	client.subnets[0].VpcId = aws.String("vpc-1")
+	client.subnets[0].AvailabilityZone = aws.String("us-east-1a")
+	_, err = (&CreateInfraOptions{InfraID: "test", VPCID: "vpc-1", SubnetIDs: []string{"subnet-a", "subnet-b"}}).useExistingVPC(context.Background(), logr.Discard(), client, &CreateInfraOutput{})
+	g.Expect(err).To(HaveOccurred())
+}
+
+func TestValidateExistingSecurityGroup(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	self := []*ec2.UserIdGroupPair{{GroupId: aws.String("sg-1"), UserId: aws.String("123")}}
+	securityGroup := &ec2.SecurityGroup{
+		GroupId: aws.String("sg-1"),
+		OwnerId: aws.String("123"),
+		IpPermissionsEgress: []*ec2.IpPermission{
+			{IpProtocol: aws.String("-1"), IpRanges: []*ec2.IpRange{{CidrIp: aws.String("0.0.0.0/0")}}},
+		},
+		IpPermissions: []*ec2.IpPermission{
+			{IpProtocol: aws.String("-1"), UserIdGroupPairs: self},
+			{IpProtocol: aws.String("icmp"), FromPort: aws.Int64(-1), ToPort: aws.Int64(-1), IpRanges: []*ec2.IpRange{{CidrIp: aws.String("10.0.0.0/8")}}},
+			{IpProtocol: aws.String("tcp"), FromPort: aws.Int64(0), ToPort: aws.Int64(1024), IpRanges: []*ec2.IpRange{{CidrIp: aws.String("10.0.0.0/8")}}},
+		},
+	}
+	o := &CreateInfraOptions{VPCCIDR: "10.1.0.0/16"}
+	g.Expect(o.validateExistingSecurityGroup(securityGroup)).To(Succeed())
+
+	// SSH from a secondary CIDR outside of 10.0.0.0/8 and an additional rule are missing
+	o.SecondaryCIDRs = []string{"192.168.0.0/24"}
+	o.AdditionalIngressRules = []string{"tcp:8443:172.16.0.0/12"}
+	g.Expect(o.parseAdditionalIngressRules()).To(Succeed())
+	err := o.validateExistingSecurityGroup(securityGroup)
+	g.Expect(err).To(HaveOccurred())
+	g.Expect(err.Error()).To(ContainSubstring("icmp from 192.168.0.0/24"))
+	g.Expect(err.Error()).To(ContainSubstring("tcp 22 from 192.168.0.0/24"))
+	g.Expect(err.Error()).To(ContainSubstring("tcp 8443 from 172.16.0.0/12"))
+	g.Expect(err.Error()).ToNot(ContainSubstring("security group sg-1 is missing ingress rule udp"))
+
+	// IPv6 egress is required when IPv6 is enabled
+	o = &CreateInfraOptions{EnableIPv6: true}
+	err = o.validateExistingSecurityGroup(securityGroup)
+	g.Expect(err).To(HaveOccurred())
+	g.Expect(err.Error()).To(ContainSubstring("missing egress rule all traffic from ::/0"))
+}
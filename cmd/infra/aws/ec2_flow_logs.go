@@ -0,0 +1,284 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/arn"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/aws/aws-sdk-go/service/iam/iamiface"
+	"github.com/go-logr/logr"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+)
+
+const flowLogsTrustPolicy = `{
+	"Version": "2012-10-17",
+	"Statement": [
+		{
+			"Effect": "Allow",
+			"Principal": {
+				"Service": "vpc-flow-logs.amazonaws.com"
+			},
+			"Action": "sts:AssumeRole"
+		}
+	]
+}`
+
+const flowLogsPolicyTemplate = `{
+	"Version": "2012-10-17",
+	"Statement": [
+		{
+			"Effect": "Allow",
+			"Action": [
+				"logs:CreateLogStream",
+				"logs:PutLogEvents",
+				"logs:DescribeLogGroups",
+				"logs:DescribeLogStreams"
+			],
+			"Resource": [
+				%q,
+				%q
+			]
+		}
+	]
+}`
+
+// errFlowLogNotCreated is returned while the flow log cannot be created, which
+// happens until a role that was just created can be assumed by the service.
+var errFlowLogNotCreated = errors.New("flow log was not created")
+
+func flowLogsRoleName(infraID string) string {
+	return fmt.Sprintf("%s-flow-logs", infraID)
+}
+
+// flowLogsDestinationType returns the type of the flow log destination given by
+// its ARN, which is either a CloudWatch Logs log group or an S3 bucket with an
+// optional prefix.
+func flowLogsDestinationType(destination string) (string, error) {
+	parsed, err := arn.Parse(destination)
+	if err != nil {
+		return "", fmt.Errorf("invalid --flow-logs-destination %q: %w", destination, err)
+	}
+	switch {
+	case parsed.Service == "logs" && strings.HasPrefix(parsed.Resource, "log-group:"):
+		return ec2.LogDestinationTypeCloudWatchLogs, nil
+	case parsed.Service == "s3" && len(parsed.Resource) > 0:
+		return ec2.LogDestinationTypeS3, nil
+	}
+	return "", fmt.Errorf("--flow-logs-destination %s must be the ARN of a CloudWatch Logs log group or an S3 bucket", destination)
+}
+
+// validateFlowLogsOptions validates the flags for the VPC flow log.
+func (o *CreateInfraOptions) validateFlowLogsOptions() error {
+	if !o.EnableFlowLogs {
+		if len(o.FlowLogsDestination) > 0 {
+			return errors.New("--flow-logs-destination can only be specified together with --enable-flow-logs")
+		}
+		return nil
+	}
+	if len(o.FlowLogsDestination) == 0 {
+		return errors.New("--flow-logs-destination is required when --enable-flow-logs is specified")
+	}
+	destinationType, err := flowLogsDestinationType(o.FlowLogsDestination)
+	if err != nil {
+		return err
+	}
+	// Log groups cannot receive flow logs from other regions
+	if parsed, _ := arn.Parse(o.FlowLogsDestination); destinationType == ec2.LogDestinationTypeCloudWatchLogs && parsed.Region != o.Region {
+		return fmt.Errorf("log group %s must be in region %s", o.FlowLogsDestination, o.Region)
+	}
+	return nil
+}
+
+// createFlowLogResources creates a flow log that captures all traffic of the VPC
+// and delivers it to FlowLogsDestination. Delivery to a log group requires a role
+// that the flow logs service assumes, which is created as well.
+func (o *CreateInfraOptions) createFlowLogResources(ctx context.Context, l logr.Logger, ec2Client ec2iface.EC2API, iamClient iamiface.IAMAPI, result *CreateInfraOutput) error {
+	if o.isSharedVPC() {
+		return fmt.Errorf("--enable-flow-logs is not supported with VPC %s shared by account %s, flow logs must be created by the VPC owner", o.VPCID, o.vpcOwnerAccountID)
+	}
+	name := fmt.Sprintf("%s-flow-log", o.InfraID)
+	destinationType, err := flowLogsDestinationType(o.FlowLogsDestination)
+	if err != nil {
+		return err
+	}
+	var flowLog *ec2.FlowLog
+	if !isPlannedID(result.VPCID) {
+		if flowLog, err = o.existingFlowLog(ctx, ec2Client, name, result.VPCID); err != nil {
+			return err
+		}
+	}
+	if flowLog != nil {
+		l.Info("Found existing flow log", "id", aws.StringValue(flowLog.FlowLogId))
+		result.FlowLogID = aws.StringValue(flowLog.FlowLogId)
+		return nil
+	}
+
+	input := &ec2.CreateFlowLogsInput{
+		ResourceIds:        []*string{aws.String(result.VPCID)},
+		ResourceType:       aws.String(ec2.FlowLogsResourceTypeVpc),
+		TrafficType:        aws.String(ec2.TrafficTypeAll),
+		LogDestinationType: aws.String(destinationType),
+		LogDestination:     aws.String(o.FlowLogsDestination),
+		TagSpecifications:  o.ec2TagSpecifications("vpc-flow-log", name),
+	}
+	if destinationType == ec2.LogDestinationTypeCloudWatchLogs {
+		roleARN, err := o.ensureFlowLogsRole(ctx, l, iamClient)
+		if err != nil {
+			return err
+		}
+		input.DeliverLogsPermissionArn = aws.String(roleARN)
+	}
+	if o.DryRun {
+		result.FlowLogID = o.planCreate(l, "vpc-flow-log", name)
+		return nil
+	}
+	err = retryOnError(ctx, ec2Backoff(), func(err error) bool { return errors.Is(err, errFlowLogNotCreated) }, func() error {
+		output, err := ec2Client.CreateFlowLogsWithContext(ctx, input)
+		if err != nil {
+			return err
+		}
+		for _, unsuccessful := range output.Unsuccessful {
+			if unsuccessful.Error != nil {
+				return fmt.Errorf("%w: %s: %s", errFlowLogNotCreated, aws.StringValue(unsuccessful.Error.Code), aws.StringValue(unsuccessful.Error.Message))
+			}
+		}
+		if len(output.FlowLogIds) == 0 {
+			return errFlowLogNotCreated
+		}
+		result.FlowLogID = aws.StringValue(output.FlowLogIds[0])
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("cannot create flow log for vpc %s: %w", result.VPCID, err)
+	}
+	l.Info("Created flow log", "id", result.FlowLogID, "destination", o.FlowLogsDestination)
+	return nil
+}
+
+func (o *CreateInfraOptions) existingFlowLog(ctx context.Context, client ec2iface.EC2API, name, vpcID string) (*ec2.FlowLog, error) {
+	filters := append(o.ec2Filters(name), &ec2.Filter{Name: aws.String("resource-id"), Values: []*string{aws.String(vpcID)}})
+	var existing *ec2.FlowLog
+	err := client.DescribeFlowLogsPagesWithContext(ctx, &ec2.DescribeFlowLogsInput{Filter: filters}, func(out *ec2.DescribeFlowLogsOutput, _ bool) bool {
+		for _, flowLog := range out.FlowLogs {
+			existing = flowLog
+			return false
+		}
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cannot list flow logs: %w", err)
+	}
+	return existing, nil
+}
+
+// ensureFlowLogsRole creates the role that allows the flow logs service to
+// deliver to the log group, and returns its ARN.
+func (o *CreateInfraOptions) ensureFlowLogsRole(ctx context.Context, l logr.Logger, client iamiface.IAMAPI) (string, error) {
+	roleName := flowLogsRoleName(o.InfraID)
+	var roleARN string
+	role, err := client.GetRoleWithContext(ctx, &iam.GetRoleInput{RoleName: aws.String(roleName)})
+	var awsErr awserr.Error
+	switch {
+	case err == nil:
+		roleARN = aws.StringValue(role.Role.Arn)
+		l.Info("Found existing role", "name", roleName)
+	case !errors.As(err, &awsErr) || awsErr.Code() != iam.ErrCodeNoSuchEntityException:
+		return "", fmt.Errorf("cannot get existing role: %w", err)
+	case o.DryRun:
+		return o.planCreate(l, "iam-role", roleName), nil
+	default:
+		tags := []*iam.Tag{{Key: aws.String(clusterTag(o.InfraID)), Value: aws.String(clusterTagValue)}}
+		for _, tag := range o.additionalEC2Tags {
+			tags = append(tags, &iam.Tag{Key: tag.Key, Value: tag.Value})
+		}
+		output, err := client.CreateRoleWithContext(ctx, &iam.CreateRoleInput{
+			RoleName:                 aws.String(roleName),
+			AssumeRolePolicyDocument: aws.String(flowLogsTrustPolicy),
+			Tags:                     tags,
+		})
+		if err != nil {
+			return "", fmt.Errorf("cannot create flow logs role: %w", err)
+		}
+		roleARN = aws.StringValue(output.Role.Arn)
+		l.Info("Created role", "name", roleName)
+	}
+
+	// The policy is put whenever the flow log is created, so that it allows a changed
+	// destination
+	logGroupARN := strings.TrimSuffix(o.FlowLogsDestination, ":*")
+	policy := fmt.Sprintf(flowLogsPolicyTemplate, logGroupARN, logGroupARN+":*")
+	if o.DryRun {
+		o.planModify(l, "iam-role", roleName, "put flow logs policy")
+		return roleARN, nil
+	}
+	if _, err := client.PutRolePolicyWithContext(ctx, &iam.PutRolePolicyInput{
+		RoleName:       aws.String(roleName),
+		PolicyName:     aws.String(roleName),
+		PolicyDocument: aws.String(policy),
+	}); err != nil {
+		return "", fmt.Errorf("cannot put flow logs role policy: %w", err)
+	}
+	return roleARN, nil
+}
+
+// DestroyFlowLogs deletes the flow logs tagged with the infra ID and the role
+// they deliver to CloudWatch Logs with.
+func (o *DestroyInfraOptions) DestroyFlowLogs(ctx context.Context, ec2Client ec2iface.EC2API, iamClient iamiface.IAMAPI) []error {
+	var flowLogIDs []*string
+	err := ec2Client.DescribeFlowLogsPagesWithContext(ctx, &ec2.DescribeFlowLogsInput{Filter: o.ec2Filters()}, func(out *ec2.DescribeFlowLogsOutput, _ bool) bool {
+		for _, flowLog := range out.FlowLogs {
+			flowLogIDs = append(flowLogIDs, flowLog.FlowLogId)
+		}
+		return true
+	})
+	if err != nil {
+		return []error{fmt.Errorf("cannot list flow logs: %w", err)}
+	}
+	if len(flowLogIDs) > 0 {
+		output, err := ec2Client.DeleteFlowLogsWithContext(ctx, &ec2.DeleteFlowLogsInput{FlowLogIds: flowLogIDs})
+		if err != nil {
+			return []error{fmt.Errorf("cannot delete flow logs: %w", err)}
+		}
+		var errs []error
+		for _, unsuccessful := range output.Unsuccessful {
+			if unsuccessful.Error != nil {
+				errs = append(errs, fmt.Errorf("cannot delete flow log %s: %s", aws.StringValue(unsuccessful.ResourceId), aws.StringValue(unsuccessful.Error.Message)))
+			}
+		}
+		if len(errs) > 0 {
+			return errs
+		}
+		o.Log.Info("Deleted flow logs", "ids", aws.StringValueSlice(flowLogIDs))
+	}
+	if err := o.destroyFlowLogsRole(ctx, iamClient); err != nil {
+		return []error{err}
+	}
+	return nil
+}
+
+func (o *DestroyInfraOptions) destroyFlowLogsRole(ctx context.Context, client iamiface.IAMAPI) error {
+	roleName := flowLogsRoleName(o.InfraID)
+	var errs []error
+	var awsErr awserr.Error
+	if _, err := client.DeleteRolePolicyWithContext(ctx, &iam.DeleteRolePolicyInput{
+		RoleName:   aws.String(roleName),
+		PolicyName: aws.String(roleName),
+	}); err != nil && (!errors.As(err, &awsErr) || awsErr.Code() != iam.ErrCodeNoSuchEntityException) {
+		errs = append(errs, fmt.Errorf("cannot delete role policy %s: %w", roleName, err))
+	}
+	_, err := client.DeleteRoleWithContext(ctx, &iam.DeleteRoleInput{RoleName: aws.String(roleName)})
+	switch {
+	case err == nil:
+		o.Log.Info("Deleted role", "name", roleName)
+	case !errors.As(err, &awsErr) || awsErr.Code() != iam.ErrCodeNoSuchEntityException:
+		errs = append(errs, fmt.Errorf("cannot delete role %s: %w", roleName, err))
+	}
+	return utilerrors.NewAggregate(errs)
+}
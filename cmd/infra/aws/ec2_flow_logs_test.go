@@ -0,0 +1,237 @@
+package aws
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/aws/aws-sdk-go/service/iam/iamiface"
+	"github.com/go-logr/logr"
+	. "github.com/onsi/gomega"
+)
+
+const (
+	testLogGroupARN = "arn:aws:logs:us-east-1:123456789012:log-group:flow-logs"
+	testBucketARN   = "arn:aws:s3:::flow-logs/prefix"
+)
+
+type fakeFlowLogsEC2Client struct {
+	ec2iface.EC2API
+	flowLogs []*ec2.FlowLog
+	created  *ec2.CreateFlowLogsInput
+	deleted  []string
+}
+
+func (f *fakeFlowLogsEC2Client) DescribeFlowLogsPagesWithContext(_ aws.Context, _ *ec2.DescribeFlowLogsInput, fn func(*ec2.DescribeFlowLogsOutput, bool) bool, _ ...request.Option) error {
+	fn(&ec2.DescribeFlowLogsOutput{FlowLogs: f.flowLogs}, true)
+	return nil
+}
+
+func (f *fakeFlowLogsEC2Client) CreateFlowLogsWithContext(_ aws.Context, in *ec2.CreateFlowLogsInput, _ ...request.Option) (*ec2.CreateFlowLogsOutput, error) {
+	f.created = in
+	return &ec2.CreateFlowLogsOutput{FlowLogIds: []*string{aws.String("fl-1")}}, nil
+}
+
+func (f *fakeFlowLogsEC2Client) DeleteFlowLogsWithContext(_ aws.Context, in *ec2.DeleteFlowLogsInput, _ ...request.Option) (*ec2.DeleteFlowLogsOutput, error) {
+	f.deleted = append(f.deleted, aws.StringValueSlice(in.FlowLogIds)...)
+	return &ec2.DeleteFlowLogsOutput{}, nil
+}
+
+type fakeFlowLogsIAMClient struct {
+	iamiface.IAMAPI
+	roleExists    bool
+	createdRole   *iam.CreateRoleInput
+	policy        *iam.PutRolePolicyInput
+	deletedPolicy bool
+	deletedRole   bool
+}
+
+func (f *fakeFlowLogsIAMClient) GetRoleWithContext(_ aws.Context, in *iam.GetRoleInput, _ ...request.Option) (*iam.GetRoleOutput, error) {
+	if !f.roleExists {
+		return nil, awserr.New(iam.ErrCodeNoSuchEntityException, "not found", nil)
+	}
+	return &iam.GetRoleOutput{Role: &iam.Role{Arn: aws.String("arn:aws:iam::123456789012:role/" + aws.StringValue(in.RoleName))}}, nil
+}
+
+func (f *fakeFlowLogsIAMClient) CreateRoleWithContext(_ aws.Context, in *iam.CreateRoleInput, _ ...request.Option) (*iam.CreateRoleOutput, error) {
+	f.createdRole = in
+	return &iam.CreateRoleOutput{Role: &iam.Role{Arn: aws.String("arn:aws:iam::123456789012:role/" + aws.StringValue(in.RoleName))}}, nil
+}
+
+func (f *fakeFlowLogsIAMClient) PutRolePolicyWithContext(_ aws.Context, in *iam.PutRolePolicyInput, _ ...request.Option) (*iam.PutRolePolicyOutput, error) {
+	f.policy = in
+	return &iam.PutRolePolicyOutput{}, nil
+}
+
+func (f *fakeFlowLogsIAMClient) DeleteRolePolicyWithContext(_ aws.Context, _ *iam.DeleteRolePolicyInput, _ ...request.Option) (*iam.DeleteRolePolicyOutput, error) {
+	if !f.roleExists {
+		return nil, awserr.New(iam.ErrCodeNoSuchEntityException, "not found", nil)
+	}
+	f.deletedPolicy = true
+	return &iam.DeleteRolePolicyOutput{}, nil
+}
+
+func (f *fakeFlowLogsIAMClient) DeleteRoleWithContext(_ aws.Context, _ *iam.DeleteRoleInput, _ ...request.Option) (*iam.DeleteRoleOutput, error) {
+	if !f.roleExists {
+		return nil, awserr.New(iam.ErrCodeNoSuchEntityException, "not found", nil)
+	}
+	f.deletedRole = true
+	return &iam.DeleteRoleOutput{}, nil
+}
+
+func TestValidateFlowLogsOptions(t *testing.T) {
+	testCases := []struct {
+		name        string
+		options     CreateInfraOptions
+		expectError bool
+	}{
+		{
+			name:    "no flow logs",
+			options: CreateInfraOptions{Region: "us-east-1"},
+		},
+		{
+			name:    "log group",
+			options: CreateInfraOptions{Region: "us-east-1", EnableFlowLogs: true, FlowLogsDestination: testLogGroupARN},
+		},
+		{
+			name:    "bucket",
+			options: CreateInfraOptions{Region: "us-west-2", EnableFlowLogs: true, FlowLogsDestination: testBucketARN},
+		},
+		{
+			name:        "destination without flow logs",
+			options:     CreateInfraOptions{Region: "us-east-1", FlowLogsDestination: testBucketARN},
+			expectError: true,
+		},
+		{
+			name:        "flow logs without destination",
+			options:     CreateInfraOptions{Region: "us-east-1", EnableFlowLogs: true},
+			expectError: true,
+		},
+		{
+			name:        "not an ARN",
+			options:     CreateInfraOptions{Region: "us-east-1", EnableFlowLogs: true, FlowLogsDestination: "flow-logs"},
+			expectError: true,
+		},
+		{
+			name:        "unsupported destination",
+			options:     CreateInfraOptions{Region: "us-east-1", EnableFlowLogs: true, FlowLogsDestination: testNLBARN},
+			expectError: true,
+		},
+		{
+			name:        "log group in other region",
+			options:     CreateInfraOptions{Region: "us-west-2", EnableFlowLogs: true, FlowLogsDestination: testLogGroupARN},
+			expectError: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewGomegaWithT(t)
+			err := tc.options.validateFlowLogsOptions()
+			if tc.expectError {
+				g.Expect(err).To(HaveOccurred())
+			} else {
+				g.Expect(err).ToNot(HaveOccurred())
+			}
+		})
+	}
+}
+
+func TestCreateFlowLogResources(t *testing.T) {
+	testCases := []struct {
+		name                  string
+		destination           string
+		roleExists            bool
+		existing              []*ec2.FlowLog
+		expectFlowLog         string
+		expectCreated         bool
+		expectRole            bool
+		expectNewRole         bool
+		expectDestinationType string
+	}{
+		{
+			name:                  "log group",
+			destination:           testLogGroupARN,
+			expectFlowLog:         "fl-1",
+			expectCreated:         true,
+			expectRole:            true,
+			expectNewRole:         true,
+			expectDestinationType: ec2.LogDestinationTypeCloudWatchLogs,
+		},
+		{
+			name:                  "log group with existing role",
+			destination:           testLogGroupARN,
+			roleExists:            true,
+			expectFlowLog:         "fl-1",
+			expectCreated:         true,
+			expectRole:            true,
+			expectDestinationType: ec2.LogDestinationTypeCloudWatchLogs,
+		},
+		{
+			name:                  "bucket",
+			destination:           testBucketARN,
+			expectFlowLog:         "fl-1",
+			expectCreated:         true,
+			expectDestinationType: ec2.LogDestinationTypeS3,
+		},
+		{
+			name:          "existing flow log",
+			destination:   testLogGroupARN,
+			existing:      []*ec2.FlowLog{{FlowLogId: aws.String("fl-existing")}},
+			expectFlowLog: "fl-existing",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewGomegaWithT(t)
+			ec2Client := &fakeFlowLogsEC2Client{flowLogs: tc.existing}
+			iamClient := &fakeFlowLogsIAMClient{roleExists: tc.roleExists}
+			o := &CreateInfraOptions{InfraID: "test", Region: "us-east-1", EnableFlowLogs: true, FlowLogsDestination: tc.destination}
+			result := &CreateInfraOutput{VPCID: "vpc-1"}
+			err := o.createFlowLogResources(context.Background(), logr.Discard(), ec2Client, iamClient, result)
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(result.FlowLogID).To(Equal(tc.expectFlowLog))
+			g.Expect(iamClient.createdRole != nil).To(Equal(tc.expectNewRole))
+			g.Expect(iamClient.policy != nil).To(Equal(tc.expectRole))
+			if !tc.expectCreated {
+				g.Expect(ec2Client.created).To(BeNil())
+				return
+			}
+			g.Expect(aws.StringValueSlice(ec2Client.created.ResourceIds)).To(ConsistOf("vpc-1"))
+			g.Expect(aws.StringValue(ec2Client.created.TrafficType)).To(Equal(ec2.TrafficTypeAll))
+			g.Expect(aws.StringValue(ec2Client.created.LogDestination)).To(Equal(tc.destination))
+			g.Expect(aws.StringValue(ec2Client.created.LogDestinationType)).To(Equal(tc.expectDestinationType))
+			if tc.expectRole {
+				g.Expect(aws.StringValue(ec2Client.created.DeliverLogsPermissionArn)).To(Equal("arn:aws:iam::123456789012:role/test-flow-logs"))
+				g.Expect(aws.StringValue(iamClient.policy.PolicyDocument)).To(ContainSubstring(testLogGroupARN + ":*"))
+			} else {
+				g.Expect(ec2Client.created.DeliverLogsPermissionArn).To(BeNil())
+			}
+		})
+	}
+}
+
+func TestCreateFlowLogResourcesSharedVPC(t *testing.T) {
+	g := NewGomegaWithT(t)
+	o := &CreateInfraOptions{InfraID: "test", Region: "us-east-1", VPCID: "vpc-1", EnableFlowLogs: true, FlowLogsDestination: testBucketARN, vpcOwnerAccountID: "111111111111"}
+	err := o.createFlowLogResources(context.Background(), logr.Discard(), &fakeFlowLogsEC2Client{}, &fakeFlowLogsIAMClient{}, &CreateInfraOutput{VPCID: "vpc-1"})
+	g.Expect(err).To(HaveOccurred())
+}
+
+func TestDestroyFlowLogs(t *testing.T) {
+	g := NewGomegaWithT(t)
+	ec2Client := &fakeFlowLogsEC2Client{flowLogs: []*ec2.FlowLog{{FlowLogId: aws.String("fl-1")}}}
+	iamClient := &fakeFlowLogsIAMClient{roleExists: true}
+	o := &DestroyInfraOptions{InfraID: "test", Log: logr.Discard()}
+	g.Expect(o.DestroyFlowLogs(context.Background(), ec2Client, iamClient)).To(BeEmpty())
+	g.Expect(ec2Client.deleted).To(ConsistOf("fl-1"))
+	g.Expect(iamClient.deletedPolicy).To(BeTrue())
+	g.Expect(iamClient.deletedRole).To(BeTrue())
+
+	// Nothing to delete
+	g.Expect(o.DestroyFlowLogs(context.Background(), &fakeFlowLogsEC2Client{}, &fakeFlowLogsIAMClient{})).To(BeEmpty())
+}
@@ -0,0 +1,288 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/go-logr/logr"
+)
+
+// The EC2 Instance Connect Endpoint API is newer than the vendored SDK, so its
+// operations are sent as raw EC2 query requests with the shapes below.
+const (
+	opCreateInstanceConnectEndpoint    = "CreateInstanceConnectEndpoint"
+	opDescribeInstanceConnectEndpoints = "DescribeInstanceConnectEndpoints"
+	opDeleteInstanceConnectEndpoint    = "DeleteInstanceConnectEndpoint"
+
+	instanceConnectEndpointStateCreateInProgress = "create-in-progress"
+	instanceConnectEndpointStateCreateComplete   = "create-complete"
+	instanceConnectEndpointStateDeleteInProgress = "delete-in-progress"
+	instanceConnectEndpointStateDeleteComplete   = "delete-complete"
+)
+
+type instanceConnectEndpoint struct {
+	_ struct{} `type:"structure"`
+
+	InstanceConnectEndpointId *string    `locationName:"instanceConnectEndpointId" type:"string"`
+	State                     *string    `locationName:"state" type:"string"`
+	StateMessage              *string    `locationName:"stateMessage" type:"string"`
+	SubnetId                  *string    `locationName:"subnetId" type:"string"`
+	VpcId                     *string    `locationName:"vpcId" type:"string"`
+	DnsName                   *string    `locationName:"dnsName" type:"string"`
+	Tags                      []*ec2.Tag `locationName:"tagSet" locationNameList:"item" type:"list"`
+}
+
+type createInstanceConnectEndpointInput struct {
+	_ struct{} `type:"structure"`
+
+	SubnetId          *string                 `type:"string" required:"true"`
+	SecurityGroupIds  []*string               `locationName:"SecurityGroupId" locationNameList:"SecurityGroupId" type:"list"`
+	PreserveClientIp  *bool                   `type:"boolean"`
+	TagSpecifications []*ec2.TagSpecification `locationName:"TagSpecification" locationNameList:"item" type:"list"`
+}
+
+type createInstanceConnectEndpointOutput struct {
+	_ struct{} `type:"structure"`
+
+	InstanceConnectEndpoint *instanceConnectEndpoint `locationName:"instanceConnectEndpoint" type:"structure"`
+}
+
+type describeInstanceConnectEndpointsInput struct {
+	_ struct{} `type:"structure"`
+
+	Filters                    []*ec2.Filter `locationName:"Filter" locationNameList:"Filter" type:"list"`
+	InstanceConnectEndpointIds []*string     `locationName:"InstanceConnectEndpointId" locationNameList:"item" type:"list"`
+	NextToken                  *string       `type:"string"`
+}
+
+type describeInstanceConnectEndpointsOutput struct {
+	_ struct{} `type:"structure"`
+
+	InstanceConnectEndpoints []*instanceConnectEndpoint `locationName:"instanceConnectEndpointSet" locationNameList:"item" type:"list"`
+	NextToken                *string                    `locationName:"nextToken" type:"string"`
+}
+
+type deleteInstanceConnectEndpointInput struct {
+	_ struct{} `type:"structure"`
+
+	InstanceConnectEndpointId *string `type:"string" required:"true"`
+}
+
+type deleteInstanceConnectEndpointOutput struct {
+	_ struct{} `type:"structure"`
+
+	InstanceConnectEndpoint *instanceConnectEndpoint `locationName:"instanceConnectEndpoint" type:"structure"`
+}
+
+// instanceConnectEndpointAPI is the subset of the EC2 Instance Connect Endpoint
+// API used to create and destroy the endpoint.
+type instanceConnectEndpointAPI interface {
+	createInstanceConnectEndpoint(ctx context.Context, input *createInstanceConnectEndpointInput) (*instanceConnectEndpoint, error)
+	describeInstanceConnectEndpoints(ctx context.Context, filters []*ec2.Filter) ([]*instanceConnectEndpoint, error)
+	deleteInstanceConnectEndpoint(ctx context.Context, id string) error
+}
+
+type instanceConnectEndpointClient struct {
+	client *ec2.EC2
+}
+
+func newInstanceConnectEndpointClient(client *ec2.EC2) instanceConnectEndpointAPI {
+	return &instanceConnectEndpointClient{client: client}
+}
+
+func (c *instanceConnectEndpointClient) send(ctx context.Context, name string, input, output interface{}) error {
+	req := c.client.NewRequest(&request.Operation{Name: name, HTTPMethod: "POST", HTTPPath: "/"}, input, output)
+	req.SetContext(ctx)
+	return req.Send()
+}
+
+func (c *instanceConnectEndpointClient) createInstanceConnectEndpoint(ctx context.Context, input *createInstanceConnectEndpointInput) (*instanceConnectEndpoint, error) {
+	output := &createInstanceConnectEndpointOutput{}
+	if err := c.send(ctx, opCreateInstanceConnectEndpoint, input, output); err != nil {
+		return nil, err
+	}
+	if output.InstanceConnectEndpoint == nil {
+		return nil, errors.New("no instance connect endpoint was returned")
+	}
+	return output.InstanceConnectEndpoint, nil
+}
+
+func (c *instanceConnectEndpointClient) describeInstanceConnectEndpoints(ctx context.Context, filters []*ec2.Filter) ([]*instanceConnectEndpoint, error) {
+	var endpoints []*instanceConnectEndpoint
+	input := &describeInstanceConnectEndpointsInput{Filters: filters}
+	for {
+		output := &describeInstanceConnectEndpointsOutput{}
+		if err := c.send(ctx, opDescribeInstanceConnectEndpoints, input, output); err != nil {
+			return nil, err
+		}
+		endpoints = append(endpoints, output.InstanceConnectEndpoints...)
+		if len(aws.StringValue(output.NextToken)) == 0 {
+			return endpoints, nil
+		}
+		input.NextToken = output.NextToken
+	}
+}
+
+func (c *instanceConnectEndpointClient) deleteInstanceConnectEndpoint(ctx context.Context, id string) error {
+	return c.send(ctx, opDeleteInstanceConnectEndpoint, &deleteInstanceConnectEndpointInput{InstanceConnectEndpointId: aws.String(id)}, &deleteInstanceConnectEndpointOutput{})
+}
+
+// validateInstanceConnectEndpointOptions validates the flags for the instance
+// connect endpoint. Its security group could not be deleted with an existing VPC,
+// whose security groups are left alone on destroy.
+func (o *CreateInfraOptions) validateInstanceConnectEndpointOptions() error {
+	if o.InstanceConnectEndpoint && len(o.VPCID) > 0 {
+		return errors.New("--instance-connect-endpoint is not supported with an existing VPC")
+	}
+	return nil
+}
+
+func (o *CreateInfraOptions) instanceConnectEndpointName() string {
+	return fmt.Sprintf("%s-instance-connect-endpoint", o.InfraID)
+}
+
+// CreateInstanceConnectEndpoint creates an EC2 Instance Connect Endpoint in the
+// private subnet of the first zone, so that workers of private clusters can be
+// reached through SSH without a bastion. The endpoint connects from its own
+// network interface in the subnet, which the worker security group allows SSH
+// from. The endpoint and its security group are tagged with the infra ID so
+// that they are removed when the infrastructure is destroyed.
+func (o *CreateInfraOptions) CreateInstanceConnectEndpoint(ctx context.Context, l logr.Logger, ec2Client ec2iface.EC2API, client instanceConnectEndpointAPI, result *CreateInfraOutput) error {
+	securityGroupID, err := o.ensureInstanceConnectEndpointSecurityGroup(ctx, l, ec2Client, result.VPCID)
+	if err != nil {
+		return err
+	}
+	name := o.instanceConnectEndpointName()
+	var endpoint *instanceConnectEndpoint
+	if !isPlannedID(result.VPCID) {
+		if endpoint, err = existingInstanceConnectEndpoint(ctx, client, o.ec2Filters(name)); err != nil {
+			return err
+		}
+	}
+	if endpoint == nil && o.DryRun {
+		result.InstanceConnectEndpointID = o.planCreate(l, "instance-connect-endpoint", name)
+		return nil
+	}
+	if endpoint == nil {
+		endpoint, err = client.createInstanceConnectEndpoint(ctx, &createInstanceConnectEndpointInput{
+			SubnetId:          aws.String(result.Zones[0].SubnetID),
+			SecurityGroupIds:  []*string{aws.String(securityGroupID)},
+			TagSpecifications: o.ec2TagSpecifications("instance-connect-endpoint", name),
+		})
+		if err != nil {
+			return fmt.Errorf("cannot create instance connect endpoint: %w", err)
+		}
+		l.Info("Created instance connect endpoint", "id", aws.StringValue(endpoint.InstanceConnectEndpointId), "subnet", result.Zones[0].SubnetID)
+	} else {
+		l.Info("Found existing instance connect endpoint", "id", aws.StringValue(endpoint.InstanceConnectEndpointId))
+	}
+	result.InstanceConnectEndpointID = aws.StringValue(endpoint.InstanceConnectEndpointId)
+	if o.DryRun {
+		return nil
+	}
+	return waitForInstanceConnectEndpoint(ctx, client, result.InstanceConnectEndpointID)
+}
+
+// ensureInstanceConnectEndpointSecurityGroup creates the security group of the
+// instance connect endpoint. It has no ingress rules; the default egress rule
+// allows the endpoint to connect to the workers.
+func (o *CreateInfraOptions) ensureInstanceConnectEndpointSecurityGroup(ctx context.Context, l logr.Logger, client ec2iface.EC2API, vpcID string) (string, error) {
+	groupName := fmt.Sprintf("%s-instance-connect-endpoint-sg", o.InfraID)
+	securityGroup, err := o.existingSecurityGroup(ctx, client, groupName)
+	if err != nil {
+		return "", err
+	}
+	if securityGroup != nil {
+		l.Info("Found existing security group", "name", groupName, "id", aws.StringValue(securityGroup.GroupId))
+		return aws.StringValue(securityGroup.GroupId), nil
+	}
+	if o.DryRun {
+		return o.planCreate(l, "security-group", groupName), nil
+	}
+	result, err := client.CreateSecurityGroupWithContext(ctx, &ec2.CreateSecurityGroupInput{
+		GroupName:         aws.String(groupName),
+		Description:       aws.String("instance connect endpoint security group"),
+		VpcId:             aws.String(vpcID),
+		TagSpecifications: o.ec2TagSpecifications("security-group", groupName),
+	})
+	if err != nil {
+		return "", fmt.Errorf("cannot create instance connect endpoint security group: %w", err)
+	}
+	l.Info("Created security group", "name", groupName, "id", aws.StringValue(result.GroupId))
+	return aws.StringValue(result.GroupId), nil
+}
+
+// existingInstanceConnectEndpoint returns the first endpoint matching the filters
+// that is not being deleted, or nil if there is none.
+func existingInstanceConnectEndpoint(ctx context.Context, client instanceConnectEndpointAPI, filters []*ec2.Filter) (*instanceConnectEndpoint, error) {
+	endpoints, err := client.describeInstanceConnectEndpoints(ctx, filters)
+	if err != nil {
+		return nil, fmt.Errorf("cannot list instance connect endpoints: %w", err)
+	}
+	for _, endpoint := range endpoints {
+		switch aws.StringValue(endpoint.State) {
+		case instanceConnectEndpointStateCreateInProgress, instanceConnectEndpointStateCreateComplete:
+			return endpoint, nil
+		}
+	}
+	return nil, nil
+}
+
+// waitForInstanceConnectEndpoint waits for the endpoint to be created, which
+// takes a few minutes.
+func waitForInstanceConnectEndpoint(ctx context.Context, client instanceConnectEndpointAPI, id string) error {
+	errCreating := errors.New("instance connect endpoint is still being created")
+	err := retryOnError(ctx, provisioningBackoff(), func(err error) bool { return errors.Is(err, errCreating) }, func() error {
+		endpoints, err := client.describeInstanceConnectEndpoints(ctx, []*ec2.Filter{{Name: aws.String("instance-connect-endpoint-id"), Values: []*string{aws.String(id)}}})
+		if err != nil {
+			return err
+		}
+		if len(endpoints) == 0 {
+			return fmt.Errorf("instance connect endpoint %s not found", id)
+		}
+		switch state := aws.StringValue(endpoints[0].State); state {
+		case instanceConnectEndpointStateCreateComplete:
+			return nil
+		case instanceConnectEndpointStateCreateInProgress:
+			return errCreating
+		default:
+			return fmt.Errorf("instance connect endpoint %s is %s: %s", id, state, aws.StringValue(endpoints[0].StateMessage))
+		}
+	})
+	if err != nil {
+		return fmt.Errorf("instance connect endpoint %s did not become ready: %w", id, err)
+	}
+	return nil
+}
+
+// DestroyInstanceConnectEndpoints deletes the instance connect endpoints tagged
+// with the infra ID. As long as endpoints are being deleted an error is returned,
+// because their network interfaces keep the security groups and subnets in use.
+func (o *DestroyInfraOptions) DestroyInstanceConnectEndpoints(ctx context.Context, client instanceConnectEndpointAPI) []error {
+	endpoints, err := client.describeInstanceConnectEndpoints(ctx, o.ec2Filters())
+	if err != nil {
+		return []error{fmt.Errorf("cannot list instance connect endpoints: %w", err)}
+	}
+	var errs []error
+	for _, endpoint := range endpoints {
+		id := aws.StringValue(endpoint.InstanceConnectEndpointId)
+		switch aws.StringValue(endpoint.State) {
+		case instanceConnectEndpointStateDeleteComplete:
+			continue
+		case instanceConnectEndpointStateDeleteInProgress:
+			errs = append(errs, fmt.Errorf("instance connect endpoint %s still deleting", id))
+			continue
+		}
+		if err := client.deleteInstanceConnectEndpoint(ctx, id); err != nil {
+			errs = append(errs, err)
+		} else {
+			errs = append(errs, fmt.Errorf("deleting instance connect endpoint %s", id))
+		}
+	}
+	return errs
+}
@@ -0,0 +1,199 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/go-logr/logr"
+	. "github.com/onsi/gomega"
+)
+
+type fakeInstanceConnectEndpointClient struct {
+	endpoints []*instanceConnectEndpoint
+	created   *createInstanceConnectEndpointInput
+	deleted   []string
+}
+
+func (f *fakeInstanceConnectEndpointClient) createInstanceConnectEndpoint(_ context.Context, input *createInstanceConnectEndpointInput) (*instanceConnectEndpoint, error) {
+	f.created = input
+	endpoint := &instanceConnectEndpoint{
+		InstanceConnectEndpointId: aws.String("eice-1"),
+		State:                     aws.String(instanceConnectEndpointStateCreateInProgress),
+		SubnetId:                  input.SubnetId,
+	}
+	// The endpoint is complete by the time it is described
+	f.endpoints = append(f.endpoints, &instanceConnectEndpoint{
+		InstanceConnectEndpointId: endpoint.InstanceConnectEndpointId,
+		State:                     aws.String(instanceConnectEndpointStateCreateComplete),
+	})
+	return endpoint, nil
+}
+
+func (f *fakeInstanceConnectEndpointClient) describeInstanceConnectEndpoints(_ context.Context, filters []*ec2.Filter) ([]*instanceConnectEndpoint, error) {
+	for _, filter := range filters {
+		if aws.StringValue(filter.Name) != "instance-connect-endpoint-id" {
+			continue
+		}
+		var endpoints []*instanceConnectEndpoint
+		for _, endpoint := range f.endpoints {
+			if aws.StringValue(endpoint.InstanceConnectEndpointId) == aws.StringValue(filter.Values[0]) {
+				endpoints = append(endpoints, endpoint)
+			}
+		}
+		return endpoints, nil
+	}
+	return f.endpoints, nil
+}
+
+func (f *fakeInstanceConnectEndpointClient) deleteInstanceConnectEndpoint(_ context.Context, id string) error {
+	f.deleted = append(f.deleted, id)
+	return nil
+}
+
+type fakeInstanceConnectEndpointEC2Client struct {
+	ec2iface.EC2API
+	createdSecurityGroup *ec2.CreateSecurityGroupInput
+}
+
+func (f *fakeInstanceConnectEndpointEC2Client) DescribeSecurityGroupsPagesWithContext(_ aws.Context, _ *ec2.DescribeSecurityGroupsInput, fn func(*ec2.DescribeSecurityGroupsOutput, bool) bool, _ ...request.Option) error {
+	fn(&ec2.DescribeSecurityGroupsOutput{}, true)
+	return nil
+}
+
+func (f *fakeInstanceConnectEndpointEC2Client) CreateSecurityGroupWithContext(_ aws.Context, in *ec2.CreateSecurityGroupInput, _ ...request.Option) (*ec2.CreateSecurityGroupOutput, error) {
+	f.createdSecurityGroup = in
+	return &ec2.CreateSecurityGroupOutput{GroupId: aws.String("sg-eice")}, nil
+}
+
+func TestValidateInstanceConnectEndpointOptions(t *testing.T) {
+	g := NewGomegaWithT(t)
+	g.Expect((&CreateInfraOptions{InstanceConnectEndpoint: true}).validateInstanceConnectEndpointOptions()).To(Succeed())
+	g.Expect((&CreateInfraOptions{VPCID: "vpc-1"}).validateInstanceConnectEndpointOptions()).To(Succeed())
+	g.Expect((&CreateInfraOptions{InstanceConnectEndpoint: true, VPCID: "vpc-1"}).validateInstanceConnectEndpointOptions()).ToNot(Succeed())
+}
+
+func TestCreateInstanceConnectEndpoint(t *testing.T) {
+	testCases := []struct {
+		name           string
+		existing       []*instanceConnectEndpoint
+		expectCreated  bool
+		expectEndpoint string
+	}{
+		{
+			name:           "new endpoint",
+			expectCreated:  true,
+			expectEndpoint: "eice-1",
+		},
+		{
+			name: "existing endpoint",
+			existing: []*instanceConnectEndpoint{
+				{InstanceConnectEndpointId: aws.String("eice-deleting"), State: aws.String(instanceConnectEndpointStateDeleteInProgress)},
+				{InstanceConnectEndpointId: aws.String("eice-existing"), State: aws.String(instanceConnectEndpointStateCreateComplete)},
+			},
+			expectEndpoint: "eice-existing",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewGomegaWithT(t)
+			ec2Client := &fakeInstanceConnectEndpointEC2Client{}
+			client := &fakeInstanceConnectEndpointClient{endpoints: tc.existing}
+			o := &CreateInfraOptions{InfraID: "test", InstanceConnectEndpoint: true}
+			result := &CreateInfraOutput{VPCID: "vpc-1", Zones: []*CreateInfraOutputZone{{Name: "us-east-1a", SubnetID: "subnet-private"}}}
+			err := o.CreateInstanceConnectEndpoint(context.Background(), logr.Discard(), ec2Client, client, result)
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(result.InstanceConnectEndpointID).To(Equal(tc.expectEndpoint))
+			g.Expect(aws.StringValue(ec2Client.createdSecurityGroup.GroupName)).To(Equal("test-instance-connect-endpoint-sg"))
+			if !tc.expectCreated {
+				g.Expect(client.created).To(BeNil())
+				return
+			}
+			g.Expect(aws.StringValue(client.created.SubnetId)).To(Equal("subnet-private"))
+			g.Expect(aws.StringValueSlice(client.created.SecurityGroupIds)).To(ConsistOf("sg-eice"))
+			g.Expect(aws.StringValue(client.created.TagSpecifications[0].ResourceType)).To(Equal("instance-connect-endpoint"))
+		})
+	}
+}
+
+func TestCreateInstanceConnectEndpointDryRun(t *testing.T) {
+	g := NewGomegaWithT(t)
+	client := &fakeInstanceConnectEndpointClient{}
+	o := &CreateInfraOptions{InfraID: "test", InstanceConnectEndpoint: true, DryRun: true}
+	result := &CreateInfraOutput{VPCID: "vpc-1", Zones: []*CreateInfraOutputZone{{Name: "us-east-1a", SubnetID: "subnet-private"}}}
+	err := o.CreateInstanceConnectEndpoint(context.Background(), logr.Discard(), &fakeInstanceConnectEndpointEC2Client{}, client, result)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(client.created).To(BeNil())
+	g.Expect(isPlannedID(result.InstanceConnectEndpointID)).To(BeTrue())
+}
+
+func TestDestroyInstanceConnectEndpoints(t *testing.T) {
+	g := NewGomegaWithT(t)
+	client := &fakeInstanceConnectEndpointClient{endpoints: []*instanceConnectEndpoint{
+		{InstanceConnectEndpointId: aws.String("eice-complete"), State: aws.String(instanceConnectEndpointStateCreateComplete)},
+		{InstanceConnectEndpointId: aws.String("eice-deleting"), State: aws.String(instanceConnectEndpointStateDeleteInProgress)},
+		{InstanceConnectEndpointId: aws.String("eice-deleted"), State: aws.String(instanceConnectEndpointStateDeleteComplete)},
+	}}
+	o := &DestroyInfraOptions{InfraID: "test", Log: logr.Discard()}
+	errs := o.DestroyInstanceConnectEndpoints(context.Background(), client)
+	g.Expect(client.deleted).To(ConsistOf("eice-complete"))
+	// Both the deleted and the deleting endpoint keep the destroy retrying
+	g.Expect(errs).To(HaveLen(2))
+}
+
+func TestInstanceConnectEndpointClient(t *testing.T) {
+	g := NewGomegaWithT(t)
+	var requests []url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		g.Expect(r.ParseForm()).To(Succeed())
+		requests = append(requests, r.PostForm)
+		switch r.PostForm.Get("Action") {
+		case opCreateInstanceConnectEndpoint:
+			fmt.Fprint(w, `<CreateInstanceConnectEndpointResponse><instanceConnectEndpoint><instanceConnectEndpointId>eice-1</instanceConnectEndpointId><state>create-in-progress</state></instanceConnectEndpoint></CreateInstanceConnectEndpointResponse>`)
+		case opDescribeInstanceConnectEndpoints:
+			fmt.Fprint(w, `<DescribeInstanceConnectEndpointsResponse><instanceConnectEndpointSet><item><instanceConnectEndpointId>eice-1</instanceConnectEndpointId><state>create-complete</state><tagSet><item><key>Name</key><value>test</value></item></tagSet></item></instanceConnectEndpointSet></DescribeInstanceConnectEndpointsResponse>`)
+		default:
+			fmt.Fprint(w, `<DeleteInstanceConnectEndpointResponse/>`)
+		}
+	}))
+	defer server.Close()
+	awsSession := session.Must(session.NewSession(&aws.Config{
+		Endpoint:    aws.String(server.URL),
+		Region:      aws.String("us-east-1"),
+		Credentials: credentials.NewStaticCredentials("key", "secret", ""),
+	}))
+	client := newInstanceConnectEndpointClient(ec2.New(awsSession))
+	ctx := context.Background()
+
+	created, err := client.createInstanceConnectEndpoint(ctx, &createInstanceConnectEndpointInput{
+		SubnetId:          aws.String("subnet-1"),
+		SecurityGroupIds:  aws.StringSlice([]string{"sg-1"}),
+		TagSpecifications: []*ec2.TagSpecification{{ResourceType: aws.String("instance-connect-endpoint"), Tags: []*ec2.Tag{{Key: aws.String("Name"), Value: aws.String("test")}}}},
+	})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(aws.StringValue(created.InstanceConnectEndpointId)).To(Equal("eice-1"))
+	g.Expect(requests[0].Get("SubnetId")).To(Equal("subnet-1"))
+	g.Expect(requests[0].Get("SecurityGroupId.1")).To(Equal("sg-1"))
+	g.Expect(requests[0].Get("TagSpecification.1.ResourceType")).To(Equal("instance-connect-endpoint"))
+	g.Expect(requests[0].Get("TagSpecification.1.Tag.1.Key")).To(Equal("Name"))
+
+	endpoints, err := client.describeInstanceConnectEndpoints(ctx, []*ec2.Filter{{Name: aws.String("tag:Name"), Values: aws.StringSlice([]string{"test"})}})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(endpoints).To(HaveLen(1))
+	g.Expect(aws.StringValue(endpoints[0].State)).To(Equal(instanceConnectEndpointStateCreateComplete))
+	g.Expect(aws.StringValue(endpoints[0].Tags[0].Value)).To(Equal("test"))
+	g.Expect(requests[1].Get("Filter.1.Name")).To(Equal("tag:Name"))
+	g.Expect(requests[1].Get("Filter.1.Value.1")).To(Equal("test"))
+
+	g.Expect(client.deleteInstanceConnectEndpoint(ctx, "eice-1")).To(Succeed())
+	g.Expect(requests[2].Get("InstanceConnectEndpointId")).To(Equal("eice-1"))
+}
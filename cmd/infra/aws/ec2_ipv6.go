@@ -0,0 +1,235 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"net"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/go-logr/logr"
+)
+
+const (
+	ipv6DefaultRoute = "::/0"
+	// privateIPv6SubnetOffset is the index of the first /64 of the VPC IPv6 CIDR used
+	// for private subnets. Public subnets start at index 0.
+	privateIPv6SubnetOffset = 128
+)
+
+// ensureVPCIPv6CIDR associates an Amazon provided IPv6 CIDR with the VPC, unless it
+// already has one, and returns it. In a dry run, an empty CIDR is returned if the
+// CIDR would be associated.
+func (o *CreateInfraOptions) ensureVPCIPv6CIDR(ctx context.Context, l logr.Logger, client ec2iface.EC2API, vpcID string) (string, error) {
+	if isPlannedID(vpcID) {
+		o.planModify(l, "vpc", vpcID, "associate Amazon provided IPv6 CIDR")
+		return "", nil
+	}
+	cidr, err := vpcIPv6CIDR(ctx, client, vpcID)
+	if err != nil {
+		return "", err
+	}
+	if len(cidr) > 0 {
+		l.Info("Found existing IPv6 CIDR association", "vpc", vpcID, "cidr", cidr)
+		return cidr, nil
+	}
+	if o.DryRun {
+		o.planModify(l, "vpc", vpcID, "associate Amazon provided IPv6 CIDR")
+		return "", nil
+	}
+	if _, err := client.AssociateVpcCidrBlockWithContext(ctx, &ec2.AssociateVpcCidrBlockInput{
+		VpcId:                       aws.String(vpcID),
+		AmazonProvidedIpv6CidrBlock: aws.Bool(true),
+	}); err != nil {
+		return "", fmt.Errorf("cannot associate IPv6 CIDR with VPC %s: %w", vpcID, err)
+	}
+	err = retryOnError(ctx, ec2Backoff(), isNotFoundYet, func() error {
+		var err error
+		cidr, err = vpcIPv6CIDR(ctx, client, vpcID)
+		if err == nil && len(cidr) == 0 {
+			err = errNotFoundYet
+		}
+		return err
+	})
+	if err != nil {
+		return "", fmt.Errorf("IPv6 CIDR of VPC %s was not associated: %w", vpcID, err)
+	}
+	l.Info("Associated IPv6 CIDR", "vpc", vpcID, "cidr", cidr)
+	return cidr, nil
+}
+
+func vpcIPv6CIDR(ctx context.Context, client ec2iface.EC2API, vpcID string) (string, error) {
+	result, err := client.DescribeVpcsWithContext(ctx, &ec2.DescribeVpcsInput{VpcIds: []*string{aws.String(vpcID)}})
+	if err != nil {
+		return "", fmt.Errorf("cannot describe VPC %s: %w", vpcID, err)
+	}
+	for _, vpc := range result.Vpcs {
+		for _, association := range vpc.Ipv6CidrBlockAssociationSet {
+			if association.Ipv6CidrBlockState != nil && aws.StringValue(association.Ipv6CidrBlockState.State) == ec2.VpcCidrBlockStateCodeAssociated {
+				return aws.StringValue(association.Ipv6CidrBlock), nil
+			}
+		}
+	}
+	return "", nil
+}
+
+// ipv6SubnetCIDR returns the index-th /64 of the VPC IPv6 CIDR.
+func ipv6SubnetCIDR(vpcIPv6CIDR string, index int) (string, error) {
+	_, network, err := net.ParseCIDR(vpcIPv6CIDR)
+	if err != nil {
+		return "", err
+	}
+	ones, bits := network.Mask.Size()
+	if network.IP.To4() != nil || bits != 8*net.IPv6len {
+		return "", fmt.Errorf("%s is not an IPv6 CIDR", vpcIPv6CIDR)
+	}
+	if ones > 64 || index >= 1<<(64-ones) {
+		return "", fmt.Errorf("IPv6 CIDR %s has no room for subnet %d", vpcIPv6CIDR, index)
+	}
+	ip := make(net.IP, net.IPv6len)
+	copy(ip, network.IP)
+	// The subnet index occupies the bits between the VPC prefix and /64
+	for i := 7; index > 0; i-- {
+		ip[i] |= byte(index)
+		index >>= 8
+	}
+	return (&net.IPNet{IP: ip, Mask: net.CIDRMask(64, bits)}).String(), nil
+}
+
+// enableZoneIPv6 makes the private and public subnet of the zone with the given
+// index dual-stack.
+func (o *CreateInfraOptions) enableZoneIPv6(ctx context.Context, l logr.Logger, client ec2iface.EC2API, vpcIPv6CIDR string, zoneIndex int, privateSubnetID, publicSubnetID string) error {
+	for _, subnet := range []struct {
+		id    string
+		index int
+	}{
+		{id: privateSubnetID, index: privateIPv6SubnetOffset + zoneIndex},
+		{id: publicSubnetID, index: zoneIndex},
+	} {
+		// In a dry run, the VPC IPv6 CIDR may not be known yet
+		cidr := fmt.Sprintf("/64 number %d of the VPC IPv6 CIDR", subnet.index)
+		if len(vpcIPv6CIDR) > 0 {
+			var err error
+			if cidr, err = ipv6SubnetCIDR(vpcIPv6CIDR, subnet.index); err != nil {
+				return err
+			}
+		}
+		if err := o.enableSubnetIPv6(ctx, l, client, subnet.id, cidr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// enableSubnetIPv6 associates the IPv6 CIDR with the subnet and makes instances in
+// the subnet get an IPv6 address on creation.
+func (o *CreateInfraOptions) enableSubnetIPv6(ctx context.Context, l logr.Logger, client ec2iface.EC2API, subnetID, cidr string) error {
+	if o.DryRun {
+		o.planModify(l, "subnet", subnetID, fmt.Sprintf("associate IPv6 CIDR %s and assign IPv6 addresses on creation", cidr))
+		return nil
+	}
+	result, err := client.DescribeSubnetsWithContext(ctx, &ec2.DescribeSubnetsInput{SubnetIds: []*string{aws.String(subnetID)}})
+	if err != nil {
+		return fmt.Errorf("cannot describe subnet %s: %w", subnetID, err)
+	}
+	associated := false
+	for _, subnet := range result.Subnets {
+		for _, association := range subnet.Ipv6CidrBlockAssociationSet {
+			if aws.StringValue(association.Ipv6CidrBlock) == cidr {
+				associated = true
+			}
+		}
+	}
+	if !associated {
+		if _, err := client.AssociateSubnetCidrBlockWithContext(ctx, &ec2.AssociateSubnetCidrBlockInput{
+			SubnetId:      aws.String(subnetID),
+			Ipv6CidrBlock: aws.String(cidr),
+		}); err != nil {
+			return fmt.Errorf("cannot associate IPv6 CIDR %s with subnet %s: %w", cidr, subnetID, err)
+		}
+		l.Info("Associated IPv6 CIDR with subnet", "subnet", subnetID, "cidr", cidr)
+	}
+	if _, err := client.ModifySubnetAttributeWithContext(ctx, &ec2.ModifySubnetAttributeInput{
+		SubnetId:                    aws.String(subnetID),
+		AssignIpv6AddressOnCreation: &ec2.AttributeBooleanValue{Value: aws.Bool(true)},
+	}); err != nil {
+		return fmt.Errorf("cannot enable IPv6 address assignment on subnet %s: %w", subnetID, err)
+	}
+	return nil
+}
+
+// CreateEgressOnlyInternetGateway creates the gateway that gives the private
+// subnets outbound IPv6 access, the IPv6 counterpart of the NAT gateways.
+func (o *CreateInfraOptions) CreateEgressOnlyInternetGateway(ctx context.Context, l logr.Logger, client ec2iface.EC2API, vpcID string) (string, error) {
+	gatewayName := fmt.Sprintf("%s-eigw", o.InfraID)
+	var gatewayID string
+	err := client.DescribeEgressOnlyInternetGatewaysPagesWithContext(ctx, &ec2.DescribeEgressOnlyInternetGatewaysInput{Filters: o.ec2Filters(gatewayName)}, func(out *ec2.DescribeEgressOnlyInternetGatewaysOutput, _ bool) bool {
+		for _, gateway := range out.EgressOnlyInternetGateways {
+			gatewayID = aws.StringValue(gateway.EgressOnlyInternetGatewayId)
+			return false
+		}
+		return true
+	})
+	if err != nil {
+		return "", fmt.Errorf("cannot list egress only internet gateways: %w", err)
+	}
+	if len(gatewayID) > 0 {
+		l.Info("Found existing egress only internet gateway", "id", gatewayID)
+		return gatewayID, nil
+	}
+	if o.DryRun {
+		return o.planCreate(l, "egress-only-internet-gateway", gatewayName), nil
+	}
+	createResult, err := client.CreateEgressOnlyInternetGatewayWithContext(ctx, &ec2.CreateEgressOnlyInternetGatewayInput{
+		VpcId:             aws.String(vpcID),
+		TagSpecifications: o.ec2TagSpecifications("egress-only-internet-gateway", gatewayName),
+	})
+	if err != nil {
+		return "", fmt.Errorf("cannot create egress only internet gateway: %w", err)
+	}
+	gatewayID = aws.StringValue(createResult.EgressOnlyInternetGateway.EgressOnlyInternetGatewayId)
+	l.Info("Created egress only internet gateway", "id", gatewayID)
+	return gatewayID, nil
+}
+
+// ensureIPv6DefaultRoute adds a ::/0 route to the route table, targeting either an
+// internet gateway or an egress only internet gateway.
+func (o *CreateInfraOptions) ensureIPv6DefaultRoute(ctx context.Context, l logr.Logger, client ec2iface.EC2API, routeTableID, igwID, egressOnlyIGWID string) error {
+	target := igwID
+	if len(egressOnlyIGWID) > 0 {
+		target = egressOnlyIGWID
+	}
+	if !isPlannedID(routeTableID) {
+		result, err := client.DescribeRouteTablesWithContext(ctx, &ec2.DescribeRouteTablesInput{RouteTableIds: []*string{aws.String(routeTableID)}})
+		if err != nil {
+			return fmt.Errorf("cannot describe route table %s: %w", routeTableID, err)
+		}
+		for _, table := range result.RouteTables {
+			for _, route := range table.Routes {
+				if aws.StringValue(route.DestinationIpv6CidrBlock) == ipv6DefaultRoute {
+					l.Info("Found existing IPv6 default route", "route table", routeTableID)
+					return nil
+				}
+			}
+		}
+	}
+	if o.DryRun {
+		o.planModify(l, "route-table", routeTableID, fmt.Sprintf("add route %s to %s", ipv6DefaultRoute, target))
+		return nil
+	}
+	input := &ec2.CreateRouteInput{
+		RouteTableId:             aws.String(routeTableID),
+		DestinationIpv6CidrBlock: aws.String(ipv6DefaultRoute),
+	}
+	if len(egressOnlyIGWID) > 0 {
+		input.EgressOnlyInternetGatewayId = aws.String(egressOnlyIGWID)
+	} else {
+		input.GatewayId = aws.String(igwID)
+	}
+	if _, err := client.CreateRouteWithContext(ctx, input); err != nil {
+		return fmt.Errorf("cannot create IPv6 default route in route table %s: %w", routeTableID, err)
+	}
+	l.Info("Created IPv6 default route", "route table", routeTableID, "target", target)
+	return nil
+}
@@ -0,0 +1,53 @@
+package aws
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestIPv6SubnetCIDR(t *testing.T) {
+	testCases := []struct {
+		name        string
+		vpcCIDR     string
+		index       int
+		expected    string
+		expectError bool
+	}{
+		{
+			name:     "first subnet",
+			vpcCIDR:  "2600:1f18:abc:de00::/56",
+			index:    0,
+			expected: "2600:1f18:abc:de00::/64",
+		},
+		{
+			name:     "first private subnet",
+			vpcCIDR:  "2600:1f18:abc:de00::/56",
+			index:    privateIPv6SubnetOffset + 2,
+			expected: "2600:1f18:abc:de82::/64",
+		},
+		{
+			name:        "index out of range",
+			vpcCIDR:     "2600:1f18:abc:de00::/56",
+			index:       256,
+			expectError: true,
+		},
+		{
+			name:        "IPv4 CIDR",
+			vpcCIDR:     DefaultCIDRBlock,
+			expectError: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewGomegaWithT(t)
+			cidr, err := ipv6SubnetCIDR(tc.vpcCIDR, tc.index)
+			if tc.expectError {
+				g.Expect(err).To(HaveOccurred())
+				return
+			}
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(cidr).To(Equal(tc.expected))
+		})
+	}
+}
@@ -0,0 +1,276 @@
+package aws
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/arn"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/go-logr/logr"
+)
+
+// validateOutpostOptions validates the flags for the Outpost subnet.
+func (o *CreateInfraOptions) validateOutpostOptions() error {
+	if len(o.OutpostARN) == 0 {
+		if len(o.OutpostZone) > 0 {
+			return errors.New("--outpost-zone can only be specified together with --outpost-arn")
+		}
+		return nil
+	}
+	outpostARN, err := arn.Parse(o.OutpostARN)
+	if err != nil {
+		return fmt.Errorf("invalid --outpost-arn %q: %w", o.OutpostARN, err)
+	}
+	if outpostARN.Service != "outposts" || !strings.HasPrefix(outpostARN.Resource, "outpost/") {
+		return fmt.Errorf("%s is not the ARN of an Outpost", o.OutpostARN)
+	}
+	if outpostARN.Region != o.Region || outpostARN.Partition != partitionID(o.Region) {
+		return fmt.Errorf("Outpost %s must be in region %s", o.OutpostARN, o.Region)
+	}
+	if len(o.OutpostZone) == 0 {
+		return errors.New("--outpost-zone is required when --outpost-arn is specified")
+	}
+	if len(o.VPCID) > 0 {
+		return errors.New("--outpost-arn is not supported with an existing VPC")
+	}
+	if o.EnableIPv6 {
+		return errors.New("--outpost-arn is not supported with --enable-ipv6")
+	}
+	// The Outpost subnet takes the last subnet CIDR of the VPC
+	if len(o.Zones) >= maxZones || o.ZoneCount >= maxZones {
+		return fmt.Errorf("at most %d zones are supported together with --outpost-arn", maxZones-1)
+	}
+	return nil
+}
+
+// outpostSubnetCIDR returns the CIDR of the Outpost subnet, which is the last
+// subnet of the VPC CIDR and thus never used by the subnets of the zones.
+func outpostSubnetCIDR(vpcCIDR string) (string, error) {
+	_, vpcNetwork, err := net.ParseCIDR(vpcCIDR)
+	if err != nil {
+		return "", err
+	}
+	ip := vpcNetwork.IP.To4()
+	if ip == nil {
+		return "", fmt.Errorf("VPC CIDR %s must be an IPv4 CIDR", vpcCIDR)
+	}
+	ones, bits := vpcNetwork.Mask.Size()
+	subnetOnes := ones + subnetPrefixIncrement
+	vpcSize := uint32(1) << (bits - ones)
+	subnetSize := uint32(1) << (bits - subnetOnes)
+	subnetIP := make(net.IP, net.IPv4len)
+	binary.BigEndian.PutUint32(subnetIP, binary.BigEndian.Uint32(ip)+vpcSize-subnetSize)
+	return (&net.IPNet{IP: subnetIP, Mask: net.CIDRMask(subnetOnes, bits)}).String(), nil
+}
+
+// createOutpostResources creates a private subnet on the Outpost given by
+// OutpostARN whose default route targets the local gateway of the Outpost, and
+// associates the VPC with the route table of the local gateway. It returns the ID
+// of the route table of the subnet.
+func (o *CreateInfraOptions) createOutpostResources(ctx context.Context, l logr.Logger, client ec2iface.EC2API, result *CreateInfraOutput) (string, error) {
+	localGateway, err := outpostLocalGateway(ctx, client, o.OutpostARN)
+	if err != nil {
+		return "", err
+	}
+	localGatewayID := aws.StringValue(localGateway.LocalGatewayId)
+	localGatewayRouteTableID, err := localGatewayRouteTable(ctx, client, localGatewayID)
+	if err != nil {
+		return "", err
+	}
+	if err := o.ensureLocalGatewayRouteTableVPCAssociation(ctx, l, client, localGatewayRouteTableID, result.VPCID); err != nil {
+		return "", err
+	}
+	cidr, err := outpostSubnetCIDR(o.vpcCIDR())
+	if err != nil {
+		return "", err
+	}
+	subnetID, err := o.createSubnet(ctx, l, client, result.VPCID, o.OutpostZone, cidr, o.OutpostARN, fmt.Sprintf("%s-private-outpost", o.InfraID))
+	if err != nil {
+		return "", err
+	}
+	routeTableID, err := o.createOutpostRouteTable(ctx, l, client, result.VPCID, localGatewayID, subnetID)
+	if err != nil {
+		return "", err
+	}
+	result.Outpost = &CreateInfraOutputOutpost{
+		ARN:                      o.OutpostARN,
+		Zone:                     o.OutpostZone,
+		SubnetID:                 subnetID,
+		LocalGatewayID:           localGatewayID,
+		LocalGatewayRouteTableID: localGatewayRouteTableID,
+	}
+	return routeTableID, nil
+}
+
+// outpostLocalGateway returns the local gateway of the Outpost.
+func outpostLocalGateway(ctx context.Context, client ec2iface.EC2API, outpostARN string) (*ec2.LocalGateway, error) {
+	var localGateway *ec2.LocalGateway
+	err := client.DescribeLocalGatewaysPagesWithContext(ctx, &ec2.DescribeLocalGatewaysInput{Filters: []*ec2.Filter{
+		{Name: aws.String("outpost-arn"), Values: []*string{aws.String(outpostARN)}},
+	}}, func(out *ec2.DescribeLocalGatewaysOutput, _ bool) bool {
+		for _, gateway := range out.LocalGateways {
+			localGateway = gateway
+			return false
+		}
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cannot list local gateways: %w", err)
+	}
+	if localGateway == nil {
+		return nil, fmt.Errorf("Outpost %s has no local gateway", outpostARN)
+	}
+	return localGateway, nil
+}
+
+// localGatewayRouteTable returns the ID of the route table of the local gateway.
+func localGatewayRouteTable(ctx context.Context, client ec2iface.EC2API, localGatewayID string) (string, error) {
+	var routeTableID string
+	err := client.DescribeLocalGatewayRouteTablesPagesWithContext(ctx, &ec2.DescribeLocalGatewayRouteTablesInput{Filters: []*ec2.Filter{
+		{Name: aws.String("local-gateway-id"), Values: []*string{aws.String(localGatewayID)}},
+	}}, func(out *ec2.DescribeLocalGatewayRouteTablesOutput, _ bool) bool {
+		for _, table := range out.LocalGatewayRouteTables {
+			routeTableID = aws.StringValue(table.LocalGatewayRouteTableId)
+			return false
+		}
+		return true
+	})
+	if err != nil {
+		return "", fmt.Errorf("cannot list local gateway route tables: %w", err)
+	}
+	if len(routeTableID) == 0 {
+		return "", fmt.Errorf("local gateway %s has no route table", localGatewayID)
+	}
+	return routeTableID, nil
+}
+
+// ensureLocalGatewayRouteTableVPCAssociation associates the VPC with the route table
+// of the local gateway, which is required to route traffic of the VPC to it.
+func (o *CreateInfraOptions) ensureLocalGatewayRouteTableVPCAssociation(ctx context.Context, l logr.Logger, client ec2iface.EC2API, localGatewayRouteTableID, vpcID string) error {
+	var associationID string
+	if !isPlannedID(vpcID) {
+		err := client.DescribeLocalGatewayRouteTableVpcAssociationsPagesWithContext(ctx, &ec2.DescribeLocalGatewayRouteTableVpcAssociationsInput{Filters: []*ec2.Filter{
+			{Name: aws.String("local-gateway-route-table-id"), Values: []*string{aws.String(localGatewayRouteTableID)}},
+			{Name: aws.String("vpc-id"), Values: []*string{aws.String(vpcID)}},
+		}}, func(out *ec2.DescribeLocalGatewayRouteTableVpcAssociationsOutput, _ bool) bool {
+			for _, association := range out.LocalGatewayRouteTableVpcAssociations {
+				associationID = aws.StringValue(association.LocalGatewayRouteTableVpcAssociationId)
+				return false
+			}
+			return true
+		})
+		if err != nil {
+			return fmt.Errorf("cannot list local gateway route table vpc associations: %w", err)
+		}
+	}
+	if len(associationID) > 0 {
+		l.Info("Found existing local gateway route table vpc association", "id", associationID)
+		return nil
+	}
+	if o.DryRun {
+		o.planModify(l, "local-gateway-route-table", localGatewayRouteTableID, fmt.Sprintf("associate vpc %s", vpcID))
+		return nil
+	}
+	result, err := client.CreateLocalGatewayRouteTableVpcAssociationWithContext(ctx, &ec2.CreateLocalGatewayRouteTableVpcAssociationInput{
+		LocalGatewayRouteTableId: aws.String(localGatewayRouteTableID),
+		VpcId:                    aws.String(vpcID),
+		TagSpecifications:        o.ec2TagSpecifications("local-gateway-route-table-vpc-association", ""),
+	})
+	if err != nil {
+		return fmt.Errorf("cannot associate vpc with local gateway route table %s: %w", localGatewayRouteTableID, err)
+	}
+	l.Info("Associated vpc with local gateway route table", "id", aws.StringValue(result.LocalGatewayRouteTableVpcAssociation.LocalGatewayRouteTableVpcAssociationId), "route table", localGatewayRouteTableID)
+	return nil
+}
+
+// createOutpostRouteTable creates the route table of the Outpost subnet with a
+// default route to the local gateway.
+func (o *CreateInfraOptions) createOutpostRouteTable(ctx context.Context, l logr.Logger, client ec2iface.EC2API, vpcID, localGatewayID, subnetID string) (string, error) {
+	tableName := fmt.Sprintf("%s-private-outpost", o.InfraID)
+	routeTable, err := o.existingRouteTable(ctx, l, client, tableName)
+	if err != nil {
+		return "", err
+	}
+	if routeTable == nil && o.DryRun {
+		routeTable = &ec2.RouteTable{RouteTableId: aws.String(o.planCreate(l, "route-table", tableName))}
+	} else if routeTable == nil {
+		routeTable, err = o.createRouteTable(ctx, l, client, vpcID, tableName)
+		if err != nil {
+			return "", err
+		}
+	}
+	tableID := aws.StringValue(routeTable.RouteTableId)
+	hasRoute := hasLocalGatewayRoute(routeTable, localGatewayID)
+	hasSubnet := o.hasAssociatedSubnet(routeTable, subnetID)
+	if o.DryRun {
+		if !hasRoute {
+			o.planModify(l, "route-table", tableID, fmt.Sprintf("add route 0.0.0.0/0 to local gateway %s", localGatewayID))
+		}
+		if !hasSubnet {
+			o.planModify(l, "route-table", tableID, fmt.Sprintf("associate subnet %s", subnetID))
+		}
+		return tableID, nil
+	}
+	if !hasRoute {
+		if _, err := client.CreateRouteWithContext(ctx, &ec2.CreateRouteInput{
+			RouteTableId:         aws.String(tableID),
+			LocalGatewayId:       aws.String(localGatewayID),
+			DestinationCidrBlock: aws.String("0.0.0.0/0"),
+		}); err != nil {
+			return "", fmt.Errorf("cannot create route to local gateway: %w", err)
+		}
+		l.Info("Created route to local gateway", "route table", tableID, "local gateway", localGatewayID)
+	}
+	if !hasSubnet {
+		if _, err := client.AssociateRouteTableWithContext(ctx, &ec2.AssociateRouteTableInput{
+			RouteTableId: aws.String(tableID),
+			SubnetId:     aws.String(subnetID),
+		}); err != nil {
+			return "", fmt.Errorf("cannot associate outpost route table with subnet: %w", err)
+		}
+		l.Info("Associated subnet with route table", "route table", tableID, "subnet", subnetID)
+	}
+	return tableID, nil
+}
+
+func hasLocalGatewayRoute(table *ec2.RouteTable, localGatewayID string) bool {
+	for _, route := range table.Routes {
+		if aws.StringValue(route.LocalGatewayId) == localGatewayID &&
+			aws.StringValue(route.DestinationCidrBlock) == "0.0.0.0/0" {
+			return true
+		}
+	}
+	return false
+}
+
+// DestroyLocalGatewayRouteTableVPCAssociations removes the associations of the VPC
+// with local gateway route tables, which prevent the VPC from being deleted.
+func (o *DestroyInfraOptions) DestroyLocalGatewayRouteTableVPCAssociations(ctx context.Context, client ec2iface.EC2API, vpcID *string) []error {
+	var errs []error
+	deleteAssociations := func(out *ec2.DescribeLocalGatewayRouteTableVpcAssociationsOutput, _ bool) bool {
+		for _, association := range out.LocalGatewayRouteTableVpcAssociations {
+			_, err := client.DeleteLocalGatewayRouteTableVpcAssociationWithContext(ctx, &ec2.DeleteLocalGatewayRouteTableVpcAssociationInput{
+				LocalGatewayRouteTableVpcAssociationId: association.LocalGatewayRouteTableVpcAssociationId,
+			})
+			if err != nil {
+				errs = append(errs, err)
+			} else {
+				o.Log.Info("Deleted local gateway route table vpc association", "id", aws.StringValue(association.LocalGatewayRouteTableVpcAssociationId))
+			}
+		}
+		return true
+	}
+	err := client.DescribeLocalGatewayRouteTableVpcAssociationsPagesWithContext(ctx,
+		&ec2.DescribeLocalGatewayRouteTableVpcAssociationsInput{Filters: vpcFilter(vpcID)},
+		deleteAssociations)
+	if err != nil {
+		errs = append(errs, err)
+	}
+	return errs
+}
@@ -0,0 +1,179 @@
+package aws
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/go-logr/logr"
+	. "github.com/onsi/gomega"
+)
+
+const testOutpostARN = "arn:aws:outposts:us-east-1:123456789012:outpost/op-0123456789abcdef0"
+
+type fakeOutpostClient struct {
+	ec2iface.EC2API
+	createdSubnet *ec2.CreateSubnetInput
+	associatedVPC string
+	routes        []*ec2.CreateRouteInput
+	associations  []*ec2.AssociateRouteTableInput
+}
+
+func (f *fakeOutpostClient) DescribeLocalGatewaysPagesWithContext(_ aws.Context, _ *ec2.DescribeLocalGatewaysInput, fn func(*ec2.DescribeLocalGatewaysOutput, bool) bool, _ ...request.Option) error {
+	fn(&ec2.DescribeLocalGatewaysOutput{LocalGateways: []*ec2.LocalGateway{{LocalGatewayId: aws.String("lgw-1")}}}, true)
+	return nil
+}
+
+func (f *fakeOutpostClient) DescribeLocalGatewayRouteTablesPagesWithContext(_ aws.Context, _ *ec2.DescribeLocalGatewayRouteTablesInput, fn func(*ec2.DescribeLocalGatewayRouteTablesOutput, bool) bool, _ ...request.Option) error {
+	fn(&ec2.DescribeLocalGatewayRouteTablesOutput{LocalGatewayRouteTables: []*ec2.LocalGatewayRouteTable{{LocalGatewayRouteTableId: aws.String("lgw-rtb-1")}}}, true)
+	return nil
+}
+
+func (f *fakeOutpostClient) DescribeLocalGatewayRouteTableVpcAssociationsPagesWithContext(_ aws.Context, _ *ec2.DescribeLocalGatewayRouteTableVpcAssociationsInput, fn func(*ec2.DescribeLocalGatewayRouteTableVpcAssociationsOutput, bool) bool, _ ...request.Option) error {
+	fn(&ec2.DescribeLocalGatewayRouteTableVpcAssociationsOutput{}, true)
+	return nil
+}
+
+func (f *fakeOutpostClient) CreateLocalGatewayRouteTableVpcAssociationWithContext(_ aws.Context, in *ec2.CreateLocalGatewayRouteTableVpcAssociationInput, _ ...request.Option) (*ec2.CreateLocalGatewayRouteTableVpcAssociationOutput, error) {
+	f.associatedVPC = aws.StringValue(in.VpcId)
+	return &ec2.CreateLocalGatewayRouteTableVpcAssociationOutput{LocalGatewayRouteTableVpcAssociation: &ec2.LocalGatewayRouteTableVpcAssociation{
+		LocalGatewayRouteTableVpcAssociationId: aws.String("lgw-vpc-assoc-1"),
+	}}, nil
+}
+
+func (f *fakeOutpostClient) DescribeSubnetsPagesWithContext(_ aws.Context, _ *ec2.DescribeSubnetsInput, fn func(*ec2.DescribeSubnetsOutput, bool) bool, _ ...request.Option) error {
+	fn(&ec2.DescribeSubnetsOutput{}, true)
+	return nil
+}
+
+func (f *fakeOutpostClient) CreateSubnetWithContext(_ aws.Context, in *ec2.CreateSubnetInput, _ ...request.Option) (*ec2.CreateSubnetOutput, error) {
+	f.createdSubnet = in
+	return &ec2.CreateSubnetOutput{Subnet: &ec2.Subnet{SubnetId: aws.String("subnet-outpost")}}, nil
+}
+
+func (f *fakeOutpostClient) DescribeSubnetsWithContext(_ aws.Context, in *ec2.DescribeSubnetsInput, _ ...request.Option) (*ec2.DescribeSubnetsOutput, error) {
+	return &ec2.DescribeSubnetsOutput{Subnets: []*ec2.Subnet{{SubnetId: in.SubnetIds[0]}}}, nil
+}
+
+func (f *fakeOutpostClient) DescribeRouteTablesPagesWithContext(_ aws.Context, _ *ec2.DescribeRouteTablesInput, fn func(*ec2.DescribeRouteTablesOutput, bool) bool, _ ...request.Option) error {
+	fn(&ec2.DescribeRouteTablesOutput{}, true)
+	return nil
+}
+
+func (f *fakeOutpostClient) CreateRouteTableWithContext(_ aws.Context, _ *ec2.CreateRouteTableInput, _ ...request.Option) (*ec2.CreateRouteTableOutput, error) {
+	return &ec2.CreateRouteTableOutput{RouteTable: &ec2.RouteTable{RouteTableId: aws.String("rtb-outpost")}}, nil
+}
+
+func (f *fakeOutpostClient) CreateRouteWithContext(_ aws.Context, in *ec2.CreateRouteInput, _ ...request.Option) (*ec2.CreateRouteOutput, error) {
+	f.routes = append(f.routes, in)
+	return &ec2.CreateRouteOutput{}, nil
+}
+
+func (f *fakeOutpostClient) AssociateRouteTableWithContext(_ aws.Context, in *ec2.AssociateRouteTableInput, _ ...request.Option) (*ec2.AssociateRouteTableOutput, error) {
+	f.associations = append(f.associations, in)
+	return &ec2.AssociateRouteTableOutput{}, nil
+}
+
+func TestValidateOutpostOptions(t *testing.T) {
+	testCases := []struct {
+		name        string
+		options     CreateInfraOptions
+		expectError bool
+	}{
+		{
+			name:    "no outpost",
+			options: CreateInfraOptions{Region: "us-east-1"},
+		},
+		{
+			name:    "outpost and zone",
+			options: CreateInfraOptions{Region: "us-east-1", OutpostARN: testOutpostARN, OutpostZone: "us-east-1a"},
+		},
+		{
+			name:        "zone without outpost",
+			options:     CreateInfraOptions{Region: "us-east-1", OutpostZone: "us-east-1a"},
+			expectError: true,
+		},
+		{
+			name:        "outpost without zone",
+			options:     CreateInfraOptions{Region: "us-east-1", OutpostARN: testOutpostARN},
+			expectError: true,
+		},
+		{
+			name:        "not an outpost",
+			options:     CreateInfraOptions{Region: "us-east-1", OutpostARN: testNLBARN, OutpostZone: "us-east-1a"},
+			expectError: true,
+		},
+		{
+			name:        "outpost in other region",
+			options:     CreateInfraOptions{Region: "us-west-2", OutpostARN: testOutpostARN, OutpostZone: "us-west-2a"},
+			expectError: true,
+		},
+		{
+			name:        "existing VPC",
+			options:     CreateInfraOptions{Region: "us-east-1", OutpostARN: testOutpostARN, OutpostZone: "us-east-1a", VPCID: "vpc-1"},
+			expectError: true,
+		},
+		{
+			name:        "too many zones",
+			options:     CreateInfraOptions{Region: "us-east-1", OutpostARN: testOutpostARN, OutpostZone: "us-east-1a", ZoneCount: maxZones},
+			expectError: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewGomegaWithT(t)
+			err := tc.options.validateOutpostOptions()
+			if tc.expectError {
+				g.Expect(err).To(HaveOccurred())
+			} else {
+				g.Expect(err).ToNot(HaveOccurred())
+			}
+		})
+	}
+}
+
+func TestOutpostSubnetCIDR(t *testing.T) {
+	g := NewGomegaWithT(t)
+	cidr, err := outpostSubnetCIDR("10.0.0.0/16")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(cidr).To(Equal("10.0.240.0/20"))
+
+	// The Outpost subnet must not overlap with the subnets of the zones
+	private, public, err := subnetCIDRs("10.0.0.0/16", maxZones-1)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(append(private, public...)).ToNot(ContainElement(cidr))
+}
+
+func TestCreateOutpostResources(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	client := &fakeOutpostClient{}
+	o := &CreateInfraOptions{
+		InfraID:     "test",
+		Region:      "us-east-1",
+		OutpostARN:  testOutpostARN,
+		OutpostZone: "us-east-1a",
+	}
+	result := &CreateInfraOutput{VPCID: "vpc-1"}
+	routeTableID, err := o.createOutpostResources(context.Background(), logr.Discard(), client, result)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(routeTableID).To(Equal("rtb-outpost"))
+	g.Expect(client.associatedVPC).To(Equal("vpc-1"))
+	g.Expect(aws.StringValue(client.createdSubnet.OutpostArn)).To(Equal(testOutpostARN))
+	g.Expect(aws.StringValue(client.createdSubnet.AvailabilityZone)).To(Equal("us-east-1a"))
+	g.Expect(aws.StringValue(client.createdSubnet.CidrBlock)).To(Equal("10.0.240.0/20"))
+	g.Expect(client.routes).To(HaveLen(1))
+	g.Expect(aws.StringValue(client.routes[0].LocalGatewayId)).To(Equal("lgw-1"))
+	g.Expect(client.associations).To(HaveLen(1))
+	g.Expect(aws.StringValue(client.associations[0].SubnetId)).To(Equal("subnet-outpost"))
+	g.Expect(result.Outpost).To(Equal(&CreateInfraOutputOutpost{
+		ARN:                      testOutpostARN,
+		Zone:                     "us-east-1a",
+		SubnetID:                 "subnet-outpost",
+		LocalGatewayID:           "lgw-1",
+		LocalGatewayRouteTableID: "lgw-rtb-1",
+	}))
+}
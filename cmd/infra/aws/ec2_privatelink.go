@@ -0,0 +1,183 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/arn"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+// validatePrivateLinkOptions validates the flags for the PrivateLink endpoint service.
+func (o *CreateInfraOptions) validatePrivateLinkOptions() error {
+	if len(o.PrivateLinkNLBARN) == 0 {
+		if len(o.PrivateLinkPrincipals) > 0 {
+			return errors.New("--private-link-allowed-principals can only be specified together with --private-link-nlb-arn")
+		}
+		return nil
+	}
+	nlbARN, err := arn.Parse(o.PrivateLinkNLBARN)
+	if err != nil {
+		return fmt.Errorf("invalid --private-link-nlb-arn %q: %w", o.PrivateLinkNLBARN, err)
+	}
+	if nlbARN.Service != "elasticloadbalancing" || !strings.HasPrefix(nlbARN.Resource, "loadbalancer/net/") {
+		return fmt.Errorf("%s is not the ARN of a network load balancer", o.PrivateLinkNLBARN)
+	}
+	if nlbARN.Region != o.Region || nlbARN.Partition != partitionID(o.Region) {
+		return fmt.Errorf("network load balancer %s must be in region %s", o.PrivateLinkNLBARN, o.Region)
+	}
+	for _, principal := range o.PrivateLinkPrincipals {
+		if principal == "*" {
+			continue
+		}
+		if _, err := arn.Parse(principal); err != nil {
+			return fmt.Errorf("invalid allowed principal %q, must be an ARN or *: %w", principal, err)
+		}
+	}
+	return nil
+}
+
+// createPrivateLinkResources creates an endpoint service for the network load
+// balancer given by PrivateLinkNLBARN, restricts it to the allowed principals and
+// connects the private subnets of the VPC to it through an interface endpoint.
+func (o *CreateInfraOptions) createPrivateLinkResources(ctx context.Context, l logr.Logger, client ec2iface.EC2API, result *CreateInfraOutput) error {
+	serviceID, serviceName, err := o.CreatePrivateLinkEndpointService(ctx, l, client)
+	if err != nil {
+		return err
+	}
+	if err := o.reconcileEndpointServicePermissions(ctx, l, client, serviceID); err != nil {
+		return err
+	}
+	var subnetIDs []string
+	for _, zone := range result.Zones {
+		subnetIDs = append(subnetIDs, zone.SubnetID)
+	}
+	endpointID, err := o.CreatePrivateLinkEndpoint(ctx, l, client, result.VPCID, serviceName, subnetIDs)
+	if err != nil {
+		return err
+	}
+	result.PrivateLinkEndpointServiceName = serviceName
+	result.PrivateLinkEndpointID = endpointID
+	return nil
+}
+
+// CreatePrivateLinkEndpointService creates the endpoint service of the network load
+// balancer and returns its ID and name. Connections are accepted automatically.
+func (o *CreateInfraOptions) CreatePrivateLinkEndpointService(ctx context.Context, l logr.Logger, client ec2iface.EC2API) (string, string, error) {
+	serviceConfigName := fmt.Sprintf("%s-private-link", o.InfraID)
+	var existing *ec2.ServiceConfiguration
+	err := client.DescribeVpcEndpointServiceConfigurationsPagesWithContext(ctx, &ec2.DescribeVpcEndpointServiceConfigurationsInput{Filters: o.ec2Filters(serviceConfigName)}, func(out *ec2.DescribeVpcEndpointServiceConfigurationsOutput, _ bool) bool {
+		for _, service := range out.ServiceConfigurations {
+			existing = service
+			return false
+		}
+		return true
+	})
+	if err != nil {
+		return "", "", fmt.Errorf("cannot list vpc endpoint services: %w", err)
+	}
+	if service := existing; service != nil {
+		serviceID := aws.StringValue(service.ServiceId)
+		if !sets.NewString(aws.StringValueSlice(service.NetworkLoadBalancerArns)...).Has(o.PrivateLinkNLBARN) {
+			return "", "", fmt.Errorf("existing vpc endpoint service %s does not use network load balancer %s", serviceID, o.PrivateLinkNLBARN)
+		}
+		l.Info("Found existing vpc endpoint service", "id", serviceID, "name", aws.StringValue(service.ServiceName))
+		return serviceID, aws.StringValue(service.ServiceName), nil
+	}
+	if o.DryRun {
+		serviceID := o.planCreate(l, "vpc-endpoint-service", serviceConfigName)
+		return serviceID, serviceID, nil
+	}
+	createResult, err := client.CreateVpcEndpointServiceConfigurationWithContext(ctx, &ec2.CreateVpcEndpointServiceConfigurationInput{
+		AcceptanceRequired:      aws.Bool(false),
+		NetworkLoadBalancerArns: []*string{aws.String(o.PrivateLinkNLBARN)},
+		TagSpecifications:       o.ec2TagSpecifications("vpc-endpoint-service", serviceConfigName),
+	})
+	if err != nil {
+		return "", "", fmt.Errorf("cannot create vpc endpoint service: %w", err)
+	}
+	serviceID := aws.StringValue(createResult.ServiceConfiguration.ServiceId)
+	serviceName := aws.StringValue(createResult.ServiceConfiguration.ServiceName)
+	l.Info("Created vpc endpoint service", "id", serviceID, "name", serviceName)
+	return serviceID, serviceName, nil
+}
+
+// reconcileEndpointServicePermissions makes the allowed principals of the endpoint
+// service match PrivateLinkPrincipals.
+func (o *CreateInfraOptions) reconcileEndpointServicePermissions(ctx context.Context, l logr.Logger, client ec2iface.EC2API, serviceID string) error {
+	desired := sets.NewString(o.PrivateLinkPrincipals...)
+	existing := sets.NewString()
+	if !isPlannedID(serviceID) {
+		err := client.DescribeVpcEndpointServicePermissionsPagesWithContext(ctx, &ec2.DescribeVpcEndpointServicePermissionsInput{ServiceId: aws.String(serviceID)}, func(out *ec2.DescribeVpcEndpointServicePermissionsOutput, _ bool) bool {
+			for _, allowed := range out.AllowedPrincipals {
+				existing.Insert(aws.StringValue(allowed.Principal))
+			}
+			return true
+		})
+		if err != nil {
+			return fmt.Errorf("cannot get allowed principals of vpc endpoint service %s: %w", serviceID, err)
+		}
+	}
+	if desired.Equal(existing) {
+		return nil
+	}
+	input := &ec2.ModifyVpcEndpointServicePermissionsInput{ServiceId: aws.String(serviceID)}
+	if added := desired.Difference(existing).List(); len(added) > 0 {
+		input.AddAllowedPrincipals = aws.StringSlice(added)
+	}
+	if removed := existing.Difference(desired).List(); len(removed) > 0 {
+		input.RemoveAllowedPrincipals = aws.StringSlice(removed)
+	}
+	if o.DryRun {
+		o.planModify(l, "vpc-endpoint-service", serviceID, fmt.Sprintf("allow principals %v, disallow principals %v", aws.StringValueSlice(input.AddAllowedPrincipals), aws.StringValueSlice(input.RemoveAllowedPrincipals)))
+		return nil
+	}
+	if _, err := client.ModifyVpcEndpointServicePermissionsWithContext(ctx, input); err != nil {
+		return fmt.Errorf("cannot update allowed principals of vpc endpoint service %s: %w", serviceID, err)
+	}
+	l.Info("Updated allowed principals of vpc endpoint service", "id", serviceID, "principals", desired.List())
+	return nil
+}
+
+// CreatePrivateLinkEndpoint creates an interface endpoint for the endpoint service
+// in the given subnets.
+func (o *CreateInfraOptions) CreatePrivateLinkEndpoint(ctx context.Context, l logr.Logger, client ec2iface.EC2API, vpcID, serviceName string, subnetIDs []string) (string, error) {
+	endpointName := fmt.Sprintf("%s-private-link-vpce", o.InfraID)
+	var endpointID string
+	err := client.DescribeVpcEndpointsPagesWithContext(ctx, &ec2.DescribeVpcEndpointsInput{Filters: o.ec2Filters(endpointName)}, func(out *ec2.DescribeVpcEndpointsOutput, _ bool) bool {
+		for _, endpoint := range out.VpcEndpoints {
+			endpointID = aws.StringValue(endpoint.VpcEndpointId)
+			return false
+		}
+		return true
+	})
+	if err != nil {
+		return "", fmt.Errorf("cannot list vpc endpoints: %w", err)
+	}
+	if len(endpointID) > 0 {
+		l.Info("Found existing private link vpc endpoint", "id", endpointID)
+		return endpointID, nil
+	}
+	if o.DryRun {
+		return o.planCreate(l, "vpc-endpoint", endpointName), nil
+	}
+	createResult, err := client.CreateVpcEndpointWithContext(ctx, &ec2.CreateVpcEndpointInput{
+		VpcId:             aws.String(vpcID),
+		ServiceName:       aws.String(serviceName),
+		VpcEndpointType:   aws.String(ec2.VpcEndpointTypeInterface),
+		SubnetIds:         aws.StringSlice(subnetIDs),
+		TagSpecifications: o.ec2TagSpecifications("vpc-endpoint", endpointName),
+	})
+	if err != nil {
+		return "", fmt.Errorf("cannot create private link vpc endpoint: %w", err)
+	}
+	endpointID = aws.StringValue(createResult.VpcEndpoint.VpcEndpointId)
+	l.Info("Created private link vpc endpoint", "id", endpointID)
+	return endpointID, nil
+}
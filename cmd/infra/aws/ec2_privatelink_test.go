@@ -0,0 +1,132 @@
+package aws
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/go-logr/logr"
+	. "github.com/onsi/gomega"
+)
+
+const testNLBARN = "arn:aws:elasticloadbalancing:us-east-1:123456789012:loadbalancer/net/test/50dc6c495c0c9188"
+
+type fakePrivateLinkClient struct {
+	ec2iface.EC2API
+	principals     []string
+	modifications  []*ec2.ModifyVpcEndpointServicePermissionsInput
+	createdSubnets []string
+}
+
+func (f *fakePrivateLinkClient) DescribeVpcEndpointServiceConfigurationsPagesWithContext(_ aws.Context, in *ec2.DescribeVpcEndpointServiceConfigurationsInput, fn func(*ec2.DescribeVpcEndpointServiceConfigurationsOutput, bool) bool, _ ...request.Option) error {
+	fn(&ec2.DescribeVpcEndpointServiceConfigurationsOutput{}, true)
+	return nil
+}
+
+func (f *fakePrivateLinkClient) CreateVpcEndpointServiceConfigurationWithContext(_ aws.Context, in *ec2.CreateVpcEndpointServiceConfigurationInput, _ ...request.Option) (*ec2.CreateVpcEndpointServiceConfigurationOutput, error) {
+	return &ec2.CreateVpcEndpointServiceConfigurationOutput{ServiceConfiguration: &ec2.ServiceConfiguration{
+		ServiceId:               aws.String("vpce-svc-1"),
+		ServiceName:             aws.String("com.amazonaws.vpce.us-east-1.vpce-svc-1"),
+		NetworkLoadBalancerArns: in.NetworkLoadBalancerArns,
+	}}, nil
+}
+
+func (f *fakePrivateLinkClient) DescribeVpcEndpointServicePermissionsPagesWithContext(_ aws.Context, in *ec2.DescribeVpcEndpointServicePermissionsInput, fn func(*ec2.DescribeVpcEndpointServicePermissionsOutput, bool) bool, _ ...request.Option) error {
+	// Every principal is returned on its own page
+	for i, principal := range f.principals {
+		out := &ec2.DescribeVpcEndpointServicePermissionsOutput{AllowedPrincipals: []*ec2.AllowedPrincipal{{Principal: aws.String(principal)}}}
+		if !fn(out, i == len(f.principals)-1) {
+			break
+		}
+	}
+	return nil
+}
+
+func (f *fakePrivateLinkClient) ModifyVpcEndpointServicePermissionsWithContext(_ aws.Context, in *ec2.ModifyVpcEndpointServicePermissionsInput, _ ...request.Option) (*ec2.ModifyVpcEndpointServicePermissionsOutput, error) {
+	f.modifications = append(f.modifications, in)
+	return &ec2.ModifyVpcEndpointServicePermissionsOutput{}, nil
+}
+
+func (f *fakePrivateLinkClient) DescribeVpcEndpointsPagesWithContext(_ aws.Context, in *ec2.DescribeVpcEndpointsInput, fn func(*ec2.DescribeVpcEndpointsOutput, bool) bool, _ ...request.Option) error {
+	fn(&ec2.DescribeVpcEndpointsOutput{}, true)
+	return nil
+}
+
+func (f *fakePrivateLinkClient) CreateVpcEndpointWithContext(_ aws.Context, in *ec2.CreateVpcEndpointInput, _ ...request.Option) (*ec2.CreateVpcEndpointOutput, error) {
+	f.createdSubnets = aws.StringValueSlice(in.SubnetIds)
+	return &ec2.CreateVpcEndpointOutput{VpcEndpoint: &ec2.VpcEndpoint{VpcEndpointId: aws.String("vpce-1")}}, nil
+}
+
+func TestValidatePrivateLinkOptions(t *testing.T) {
+	testCases := []struct {
+		name        string
+		options     CreateInfraOptions
+		expectError bool
+	}{
+		{
+			name:    "no private link",
+			options: CreateInfraOptions{Region: "us-east-1"},
+		},
+		{
+			name:    "network load balancer and principals",
+			options: CreateInfraOptions{Region: "us-east-1", PrivateLinkNLBARN: testNLBARN, PrivateLinkPrincipals: []string{"arn:aws:iam::123456789012:root"}},
+		},
+		{
+			name:        "principals without network load balancer",
+			options:     CreateInfraOptions{Region: "us-east-1", PrivateLinkPrincipals: []string{"*"}},
+			expectError: true,
+		},
+		{
+			name:        "application load balancer",
+			options:     CreateInfraOptions{Region: "us-east-1", PrivateLinkNLBARN: "arn:aws:elasticloadbalancing:us-east-1:123456789012:loadbalancer/app/test/50dc6c495c0c9188"},
+			expectError: true,
+		},
+		{
+			name:        "network load balancer in other region",
+			options:     CreateInfraOptions{Region: "us-west-2", PrivateLinkNLBARN: testNLBARN},
+			expectError: true,
+		},
+		{
+			name:        "invalid principal",
+			options:     CreateInfraOptions{Region: "us-east-1", PrivateLinkNLBARN: testNLBARN, PrivateLinkPrincipals: []string{"123456789012"}},
+			expectError: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewGomegaWithT(t)
+			err := tc.options.validatePrivateLinkOptions()
+			if tc.expectError {
+				g.Expect(err).To(HaveOccurred())
+			} else {
+				g.Expect(err).ToNot(HaveOccurred())
+			}
+		})
+	}
+}
+
+func TestCreatePrivateLinkResources(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	client := &fakePrivateLinkClient{principals: []string{"arn:aws:iam::123456789012:role/old"}}
+	o := &CreateInfraOptions{
+		InfraID:               "test",
+		Region:                "us-east-1",
+		PrivateLinkNLBARN:     testNLBARN,
+		PrivateLinkPrincipals: []string{"arn:aws:iam::123456789012:role/new"},
+	}
+	result := &CreateInfraOutput{
+		VPCID: "vpc-1",
+		Zones: []*CreateInfraOutputZone{{Name: "us-east-1a", SubnetID: "subnet-a"}, {Name: "us-east-1b", SubnetID: "subnet-b"}},
+	}
+	g.Expect(o.createPrivateLinkResources(context.Background(), logr.Discard(), client, result)).To(Succeed())
+	g.Expect(result.PrivateLinkEndpointServiceName).To(Equal("com.amazonaws.vpce.us-east-1.vpce-svc-1"))
+	g.Expect(result.PrivateLinkEndpointID).To(Equal("vpce-1"))
+	g.Expect(client.createdSubnets).To(Equal([]string{"subnet-a", "subnet-b"}))
+	g.Expect(client.modifications).To(HaveLen(1))
+	g.Expect(aws.StringValueSlice(client.modifications[0].AddAllowedPrincipals)).To(Equal([]string{"arn:aws:iam::123456789012:role/new"}))
+	g.Expect(aws.StringValueSlice(client.modifications[0].RemoveAllowedPrincipals)).To(Equal([]string{"arn:aws:iam::123456789012:role/old"}))
+}
@@ -1,9 +1,12 @@
 package aws
 
 import (
+	"context"
 	"errors"
 	"fmt"
-	"time"
+	"net"
+	"strconv"
+	"strings"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
@@ -11,20 +14,23 @@ import (
 	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
 	"github.com/openshift/hypershift/cmd/log"
 
-	"k8s.io/apimachinery/pkg/util/wait"
-	"k8s.io/client-go/util/retry"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
 )
 
 const duplicatePermissionErrorCode = "InvalidPermission.Duplicate"
 
-func (o *CreateInfraOptions) CreateWorkerSecurityGroup(client ec2iface.EC2API, vpcID string) (string, error) {
+func (o *CreateInfraOptions) CreateWorkerSecurityGroup(ctx context.Context, client ec2iface.EC2API, vpcID string) (string, error) {
 	groupName := fmt.Sprintf("%s-worker-sg", o.InfraID)
-	securityGroup, err := o.existingSecurityGroup(client, groupName)
+	securityGroup, err := o.existingSecurityGroup(ctx, client, groupName)
 	if err != nil {
 		return "", err
 	}
+	if securityGroup == nil && o.DryRun {
+		return o.planCreate(log.Log, "security-group", groupName), nil
+	}
 	if securityGroup == nil {
-		result, err := client.CreateSecurityGroup(&ec2.CreateSecurityGroupInput{
+		result, err := client.CreateSecurityGroupWithContext(ctx, &ec2.CreateSecurityGroupInput{
 			GroupName:         aws.String(groupName),
 			Description:       aws.String("worker security group"),
 			VpcId:             aws.String(vpcID),
@@ -33,25 +39,19 @@ func (o *CreateInfraOptions) CreateWorkerSecurityGroup(client ec2iface.EC2API, v
 		if err != nil {
 			return "", fmt.Errorf("cannot create worker security group: %w", err)
 		}
-		backoff := wait.Backoff{
-			Steps:    10,
-			Duration: 3 * time.Second,
-			Factor:   1.0,
-			Jitter:   0.1,
-		}
 		var sgResult *ec2.DescribeSecurityGroupsOutput
-		err = retry.OnError(backoff, func(error) bool { return true }, func() error {
+		err = retryOnError(ctx, ec2Backoff(), isNotFoundYet, func() error {
 			var err error
-			sgResult, err = client.DescribeSecurityGroups(&ec2.DescribeSecurityGroupsInput{
+			sgResult, err = client.DescribeSecurityGroupsWithContext(ctx, &ec2.DescribeSecurityGroupsInput{
 				GroupIds: []*string{result.GroupId},
 			})
-			if err != nil || len(sgResult.SecurityGroups) == 0 {
-				return fmt.Errorf("not found yet")
+			if err == nil && len(sgResult.SecurityGroups) == 0 {
+				err = errNotFoundYet
 			}
-			return nil
+			return err
 		})
 		if err != nil {
-			return "", fmt.Errorf("cannot find security group that was just created (%s)", aws.StringValue(result.GroupId))
+			return "", fmt.Errorf("cannot find security group that was just created (%s): %w", aws.StringValue(result.GroupId), err)
 		}
 		securityGroup = sgResult.SecurityGroups[0]
 		log.Log.Info("Created security group", "name", groupName, "id", aws.StringValue(securityGroup.GroupId))
@@ -60,36 +60,278 @@ func (o *CreateInfraOptions) CreateWorkerSecurityGroup(client ec2iface.EC2API, v
 	}
 	securityGroupID := aws.StringValue(securityGroup.GroupId)
 	sgUserID := aws.StringValue(securityGroup.OwnerId)
-	egressPermissions := []*ec2.IpPermission{
-		{
-			IpProtocol: aws.String("-1"),
-			IpRanges: []*ec2.IpRange{
-				{
-					CidrIp: aws.String("0.0.0.0/0"),
-				},
+	egressPermissions := o.workerEgressPermissions()
+	ingressPermissions := append(workerSecurityGroupIngressPermissions(o.machineCIDRs(), o.sshIngressCIDRs(), securityGroupID, sgUserID), o.additionalIngressPermissions...)
+
+	var egressToAuthorize []*ec2.IpPermission
+	var ingressToAuthorize []*ec2.IpPermission
+	ingressToRevoke := staleIngressPermissions(securityGroup.IpPermissions, ingressPermissions, isICMPOrSSHPermission)
+	var egressToRevoke []*ec2.IpPermission
+	if o.RestrictEgress {
+		egressToRevoke = unrestrictedEgressPermissions(securityGroup.IpPermissionsEgress)
+	}
+
+	for _, permission := range egressPermissions {
+		if !includesPermission(securityGroup.IpPermissionsEgress, permission) {
+			egressToAuthorize = append(egressToAuthorize, permission)
+		}
+	}
+
+	for _, permission := range ingressPermissions {
+		if !includesPermission(securityGroup.IpPermissions, permission) {
+			ingressToAuthorize = append(ingressToAuthorize, permission)
+		}
+	}
+
+	if o.DryRun {
+		if len(egressToAuthorize) > 0 || len(ingressToAuthorize) > 0 {
+			o.planModify(log.Log, "security-group", securityGroupID, fmt.Sprintf("authorize %d egress and %d ingress rules", len(egressToAuthorize), len(ingressToAuthorize)))
+		}
+		if len(ingressToRevoke) > 0 {
+			o.planModify(log.Log, "security-group", securityGroupID, fmt.Sprintf("revoke %d stale ingress rules", len(ingressToRevoke)))
+		}
+		if len(egressToRevoke) > 0 {
+			o.planModify(log.Log, "security-group", securityGroupID, fmt.Sprintf("revoke %d unrestricted egress rules", len(egressToRevoke)))
+		}
+		return securityGroupID, nil
+	}
+	if len(egressToAuthorize) > 0 {
+		_, err = client.AuthorizeSecurityGroupEgressWithContext(ctx, &ec2.AuthorizeSecurityGroupEgressInput{
+			GroupId:       aws.String(securityGroupID),
+			IpPermissions: egressToAuthorize,
+		})
+		var awsErr awserr.Error
+		if err != nil {
+			if errors.As(err, &awsErr) {
+				// only return an error if the permission has not already been set
+				if awsErr.Code() != duplicatePermissionErrorCode {
+					return "", fmt.Errorf("cannot apply security group egress permissions: %w", err)
+				}
+			}
+		}
+		log.Log.Info("Authorized egress rules on security group", "id", securityGroupID)
+	}
+	if len(ingressToAuthorize) > 0 {
+		_, err = client.AuthorizeSecurityGroupIngressWithContext(ctx, &ec2.AuthorizeSecurityGroupIngressInput{
+			GroupId:       aws.String(securityGroupID),
+			IpPermissions: ingressToAuthorize,
+		})
+		var awsErr awserr.Error
+		if err != nil {
+			if errors.As(err, &awsErr) {
+				// only return an error if the permission has not already been set
+				if awsErr.Code() != duplicatePermissionErrorCode {
+					return "", fmt.Errorf("cannot apply security group ingress permissions: %w", err)
+				}
+			}
+		}
+		log.Log.Info("Authorized ingress rules on security group", "id", securityGroupID)
+	}
+	// Stale rules are only revoked once the expected ones are in place, so that
+	// access from networks that are still allowed is never interrupted
+	if len(ingressToRevoke) > 0 {
+		if _, err = client.RevokeSecurityGroupIngressWithContext(ctx, &ec2.RevokeSecurityGroupIngressInput{
+			GroupId:       aws.String(securityGroupID),
+			IpPermissions: ingressToRevoke,
+		}); err != nil {
+			return "", fmt.Errorf("cannot revoke stale security group ingress permissions: %w", err)
+		}
+		log.Log.Info("Revoked stale ingress rules on security group", "id", securityGroupID)
+	}
+	if len(egressToRevoke) > 0 {
+		if _, err = client.RevokeSecurityGroupEgressWithContext(ctx, &ec2.RevokeSecurityGroupEgressInput{
+			GroupId:       aws.String(securityGroupID),
+			IpPermissions: egressToRevoke,
+		}); err != nil {
+			return "", fmt.Errorf("cannot revoke unrestricted security group egress permissions: %w", err)
+		}
+		log.Log.Info("Revoked unrestricted egress rules on security group", "id", securityGroupID)
+	}
+	return securityGroupID, nil
+}
+
+// staleIngressPermissions returns the CIDRs of the managed rules of a security group
+// that none of the expected rules allow, e.g. the CIDR of a VPC a previous version
+// created the rules for, or an SSH ingress CIDR that is no longer given. Rules the
+// managed function doesn't match, such as the ones of additional ingress rules, are
+// left alone.
+func staleIngressPermissions(existing, expected []*ec2.IpPermission, managed func(*ec2.IpPermission) bool) []*ec2.IpPermission {
+	var stale []*ec2.IpPermission
+	for _, permission := range existing {
+		if !managed(permission) {
+			continue
+		}
+		allowed := sets.NewString()
+		for _, e := range expected {
+			if aws.StringValue(e.IpProtocol) == aws.StringValue(permission.IpProtocol) &&
+				aws.Int64Value(e.FromPort) == aws.Int64Value(permission.FromPort) &&
+				aws.Int64Value(e.ToPort) == aws.Int64Value(permission.ToPort) {
+				for _, ipRange := range e.IpRanges {
+					allowed.Insert(aws.StringValue(ipRange.CidrIp))
+				}
+			}
+		}
+		var ipRanges []*ec2.IpRange
+		for _, ipRange := range permission.IpRanges {
+			if !allowed.Has(aws.StringValue(ipRange.CidrIp)) {
+				ipRanges = append(ipRanges, &ec2.IpRange{CidrIp: ipRange.CidrIp})
+			}
+		}
+		if len(ipRanges) > 0 {
+			stale = append(stale, &ec2.IpPermission{
+				IpProtocol: permission.IpProtocol,
+				FromPort:   permission.FromPort,
+				ToPort:     permission.ToPort,
+				IpRanges:   ipRanges,
+			})
+		}
+	}
+	return stale
+}
+
+// isAllTrafficPermission returns true if the rule allows all protocols and ports.
+func isAllTrafficPermission(permission *ec2.IpPermission) bool {
+	return aws.StringValue(permission.IpProtocol) == "-1"
+}
+
+func isICMPOrSSHPermission(permission *ec2.IpPermission) bool {
+	if aws.StringValue(permission.IpProtocol) == "icmp" {
+		return aws.Int64Value(permission.FromPort) == -1 && aws.Int64Value(permission.ToPort) == -1
+	}
+	return isSSHPermission(permission)
+}
+
+// isSSHPermission returns true if the rule allows TCP port 22 only.
+func isSSHPermission(permission *ec2.IpPermission) bool {
+	return aws.StringValue(permission.IpProtocol) == "tcp" && aws.Int64Value(permission.FromPort) == 22 && aws.Int64Value(permission.ToPort) == 22
+}
+
+// workerSecurityGroupEgressPermissions returns the egress rules of the worker security group.
+func workerSecurityGroupEgressPermissions(enableIPv6 bool) []*ec2.IpPermission {
+	permission := &ec2.IpPermission{
+		IpProtocol: aws.String("-1"),
+		IpRanges: []*ec2.IpRange{
+			{
+				CidrIp: aws.String("0.0.0.0/0"),
 			},
 		},
 	}
-	ingressPermissions := []*ec2.IpPermission{
+	if enableIPv6 {
+		permission.Ipv6Ranges = []*ec2.Ipv6Range{
+			{
+				CidrIpv6: aws.String(ipv6DefaultRoute),
+			},
+		}
+	}
+	return []*ec2.IpPermission{permission}
+}
+
+// workerEgressPermissions returns the egress rules of the worker security group,
+// which only allow the required destinations with --restrict-egress.
+func (o *CreateInfraOptions) workerEgressPermissions() []*ec2.IpPermission {
+	if !o.RestrictEgress {
+		return workerSecurityGroupEgressPermissions(o.EnableIPv6)
+	}
+	return restrictedEgressPermissions(o.machineCIDRs(), o.EgressCIDRs, o.s3PrefixListID)
+}
+
+// restrictedEgressPermissions returns the egress rules of the worker security group
+// when unrestricted egress is not allowed: all traffic to the VPC, which includes
+// the DNS resolver, the interface VPC endpoints and the proxy host, all traffic to
+// the additional egress CIDRs, e.g. of a proxy outside of the VPC, and HTTPS to the
+// S3 gateway endpoint, which is only reachable through the prefix list of S3.
+func restrictedEgressPermissions(machineCIDRs, egressCIDRs []string, s3PrefixListID string) []*ec2.IpPermission {
+	permission := &ec2.IpPermission{IpProtocol: aws.String("-1")}
+	for _, cidr := range append(append([]string{}, machineCIDRs...), egressCIDRs...) {
+		if strings.Contains(cidr, ":") {
+			permission.Ipv6Ranges = append(permission.Ipv6Ranges, &ec2.Ipv6Range{CidrIpv6: aws.String(cidr)})
+		} else {
+			permission.IpRanges = append(permission.IpRanges, &ec2.IpRange{CidrIp: aws.String(cidr)})
+		}
+	}
+	permissions := []*ec2.IpPermission{permission}
+	if len(s3PrefixListID) > 0 {
+		permissions = append(permissions, &ec2.IpPermission{
+			IpProtocol:    aws.String("tcp"),
+			FromPort:      aws.Int64(443),
+			ToPort:        aws.Int64(443),
+			PrefixListIds: []*ec2.PrefixListId{{PrefixListId: aws.String(s3PrefixListID)}},
+		})
+	}
+	return permissions
+}
+
+// unrestrictedEgressPermissions returns the rules of the given egress rules that
+// allow all traffic to 0.0.0.0/0 or ::/0, such as the rule AWS adds to every new
+// security group.
+func unrestrictedEgressPermissions(existing []*ec2.IpPermission) []*ec2.IpPermission {
+	var unrestricted []*ec2.IpPermission
+	for _, permission := range existing {
+		if aws.StringValue(permission.IpProtocol) != "-1" {
+			continue
+		}
+		for _, ipRange := range permission.IpRanges {
+			if aws.StringValue(ipRange.CidrIp) == "0.0.0.0/0" {
+				unrestricted = append(unrestricted, &ec2.IpPermission{IpProtocol: aws.String("-1"), IpRanges: []*ec2.IpRange{{CidrIp: ipRange.CidrIp}}})
+			}
+		}
+		for _, ipRange := range permission.Ipv6Ranges {
+			if aws.StringValue(ipRange.CidrIpv6) == ipv6DefaultRoute {
+				unrestricted = append(unrestricted, &ec2.IpPermission{IpProtocol: aws.String("-1"), Ipv6Ranges: []*ec2.Ipv6Range{{CidrIpv6: ipRange.CidrIpv6}}})
+			}
+		}
+	}
+	return unrestricted
+}
+
+// lookupS3PrefixList looks up the AWS managed prefix list of S3 in the region
+// with --restrict-egress, so that egress to the S3 gateway endpoint can be allowed.
+func (o *CreateInfraOptions) lookupS3PrefixList(ctx context.Context, client ec2iface.EC2API) error {
+	if !o.RestrictEgress {
+		return nil
+	}
+	name := vpcEndpointServiceName(o.Region, "s3")
+	err := client.DescribePrefixListsPagesWithContext(ctx, &ec2.DescribePrefixListsInput{Filters: []*ec2.Filter{
+		{Name: aws.String("prefix-list-name"), Values: []*string{aws.String(name)}},
+	}}, func(out *ec2.DescribePrefixListsOutput, _ bool) bool {
+		for _, prefixList := range out.PrefixLists {
+			o.s3PrefixListID = aws.StringValue(prefixList.PrefixListId)
+			return false
+		}
+		return true
+	})
+	if err != nil {
+		return fmt.Errorf("cannot look up prefix list %s: %w", name, err)
+	}
+	if len(o.s3PrefixListID) == 0 {
+		return fmt.Errorf("prefix list %s not found", name)
+	}
+	return nil
+}
+
+// workerSecurityGroupIngressPermissions returns the ingress rules of the worker security group.
+// Rules that allow traffic between workers reference the security group itself, ICMP is allowed
+// from the machine CIDRs and SSH from the given SSH CIDRs.
+func workerSecurityGroupIngressPermissions(machineCIDRs, sshCIDRs []string, securityGroupID, sgUserID string) []*ec2.IpPermission {
+	var machineIPRanges, sshIPRanges []*ec2.IpRange
+	for _, cidr := range machineCIDRs {
+		machineIPRanges = append(machineIPRanges, &ec2.IpRange{CidrIp: aws.String(cidr)})
+	}
+	for _, cidr := range sshCIDRs {
+		sshIPRanges = append(sshIPRanges, &ec2.IpRange{CidrIp: aws.String(cidr)})
+	}
+	return []*ec2.IpPermission{
 		{
 			IpProtocol: aws.String("icmp"),
-			IpRanges: []*ec2.IpRange{
-				{
-					CidrIp: aws.String(DefaultCIDRBlock),
-				},
-			},
-			FromPort: aws.Int64(-1),
-			ToPort:   aws.Int64(-1),
+			IpRanges:   machineIPRanges,
+			FromPort:   aws.Int64(-1),
+			ToPort:     aws.Int64(-1),
 		},
 		{
 			IpProtocol: aws.String("tcp"),
-			IpRanges: []*ec2.IpRange{
-				{
-					CidrIp: aws.String(DefaultCIDRBlock),
-				},
-			},
-			FromPort: aws.Int64(22),
-			ToPort:   aws.Int64(22),
+			IpRanges:   sshIPRanges,
+			FromPort:   aws.Int64(22),
+			ToPort:     aws.Int64(22),
 		},
 		{
 			FromPort:   aws.Int64(4789),
@@ -200,66 +442,101 @@ func (o *CreateInfraOptions) CreateWorkerSecurityGroup(client ec2iface.EC2API, v
 			},
 		},
 	}
+}
 
-	var egressToAuthorize []*ec2.IpPermission
-	var ingressToAuthorize []*ec2.IpPermission
-
-	for _, permission := range egressPermissions {
-		if !includesPermission(securityGroup.IpPermissionsEgress, permission) {
-			egressToAuthorize = append(egressToAuthorize, permission)
+// parseAdditionalIngressRules parses the AdditionalIngressRules of the form
+// protocol:port:cidr. The protocol is one of tcp, udp, icmp or all. The port is
+// a single port or a range such as 8000-8100; for icmp it is the ICMP type or -1
+// for all types, and it must be empty for all.
+func (o *CreateInfraOptions) parseAdditionalIngressRules() error {
+	o.additionalIngressPermissions = nil
+	var errs []error
+	for _, rule := range o.AdditionalIngressRules {
+		permission, err := parseIngressRule(rule)
+		if err != nil {
+			errs = append(errs, err)
+			continue
 		}
+		o.additionalIngressPermissions = append(o.additionalIngressPermissions, permission)
 	}
+	return utilerrors.NewAggregate(errs)
+}
 
-	for _, permission := range ingressPermissions {
-		if !includesPermission(securityGroup.IpPermissions, permission) {
-			ingressToAuthorize = append(ingressToAuthorize, permission)
-		}
+func parseIngressRule(rule string) (*ec2.IpPermission, error) {
+	parts := strings.SplitN(rule, ":", 3)
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("invalid ingress rule %q, expected protocol:port:cidr", rule)
 	}
-
-	if len(egressToAuthorize) > 0 {
-		_, err = client.AuthorizeSecurityGroupEgress(&ec2.AuthorizeSecurityGroupEgressInput{
-			GroupId:       aws.String(securityGroupID),
-			IpPermissions: egressToAuthorize,
-		})
-		var awsErr awserr.Error
+	protocol, ports, cidr := parts[0], parts[1], parts[2]
+	permission := &ec2.IpPermission{}
+	switch protocol {
+	case "tcp", "udp":
+		from, to, err := parsePortRange(ports)
 		if err != nil {
-			if errors.As(err, &awsErr) {
-				// only return an error if the permission has not already been set
-				if awsErr.Code() != duplicatePermissionErrorCode {
-					return "", fmt.Errorf("cannot apply security group egress permissions: %w", err)
-				}
-			}
+			return nil, fmt.Errorf("invalid ingress rule %q: %w", rule, err)
 		}
-		log.Log.Info("Authorized egress rules on security group", "id", securityGroupID)
-	}
-	if len(ingressToAuthorize) > 0 {
-		_, err = client.AuthorizeSecurityGroupIngress(&ec2.AuthorizeSecurityGroupIngressInput{
-			GroupId:       aws.String(securityGroupID),
-			IpPermissions: ingressToAuthorize,
-		})
-		var awsErr awserr.Error
-		if err != nil {
-			if errors.As(err, &awsErr) {
-				// only return an error if the permission has not already been set
-				if awsErr.Code() != duplicatePermissionErrorCode {
-					return "", fmt.Errorf("cannot apply security group ingress permissions: %w", err)
-				}
-			}
+		permission.FromPort, permission.ToPort = aws.Int64(from), aws.Int64(to)
+	case "icmp":
+		icmpType, err := strconv.ParseInt(ports, 10, 64)
+		if err != nil || icmpType < -1 || icmpType > 255 {
+			return nil, fmt.Errorf("invalid ingress rule %q: ICMP type must be between 0 and 255 or -1 for all types", rule)
 		}
-		log.Log.Info("Authorized ingress rules on security group", "id", securityGroupID)
+		permission.FromPort, permission.ToPort = aws.Int64(icmpType), aws.Int64(-1)
+	case "all":
+		if len(ports) > 0 {
+			return nil, fmt.Errorf("invalid ingress rule %q: a port cannot be specified for all protocols", rule)
+		}
+		protocol = "-1"
+	default:
+		return nil, fmt.Errorf("invalid ingress rule %q: protocol must be one of tcp, udp, icmp or all", rule)
 	}
-	return securityGroupID, nil
+	permission.IpProtocol = aws.String(protocol)
+
+	_, network, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ingress rule %q: %w", rule, err)
+	}
+	if network.IP.To4() != nil {
+		permission.IpRanges = []*ec2.IpRange{{CidrIp: aws.String(network.String())}}
+	} else {
+		permission.Ipv6Ranges = []*ec2.Ipv6Range{{CidrIpv6: aws.String(network.String())}}
+	}
+	return permission, nil
 }
 
-func (o *CreateInfraOptions) existingSecurityGroup(client ec2iface.EC2API, name string) (*ec2.SecurityGroup, error) {
-	result, err := client.DescribeSecurityGroups(&ec2.DescribeSecurityGroupsInput{Filters: o.ec2Filters(name)})
+// parsePortRange parses a single port or a range of ports such as 8000-8100.
+func parsePortRange(ports string) (int64, int64, error) {
+	fromPort, toPort := ports, ports
+	if i := strings.Index(ports, "-"); i > 0 {
+		fromPort, toPort = ports[:i], ports[i+1:]
+	}
+	from, err := strconv.ParseInt(fromPort, 10, 64)
 	if err != nil {
-		return nil, fmt.Errorf("cannot list security groups: %w", err)
+		return 0, 0, fmt.Errorf("invalid port %q", fromPort)
+	}
+	to, err := strconv.ParseInt(toPort, 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid port %q", toPort)
+	}
+	if from < 0 || to > 65535 || from > to {
+		return 0, 0, fmt.Errorf("invalid port range %s, ports must be between 0 and 65535", ports)
 	}
-	for _, sg := range result.SecurityGroups {
-		return sg, nil
+	return from, to, nil
+}
+
+func (o *CreateInfraOptions) existingSecurityGroup(ctx context.Context, client ec2iface.EC2API, name string) (*ec2.SecurityGroup, error) {
+	var securityGroup *ec2.SecurityGroup
+	err := client.DescribeSecurityGroupsPagesWithContext(ctx, &ec2.DescribeSecurityGroupsInput{Filters: o.ec2Filters(name)}, func(out *ec2.DescribeSecurityGroupsOutput, _ bool) bool {
+		for _, sg := range out.SecurityGroups {
+			securityGroup = sg
+			return false
+		}
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cannot list security groups: %w", err)
 	}
-	return nil, nil
+	return securityGroup, nil
 }
 
 func includesPermission(list []*ec2.IpPermission, permission *ec2.IpPermission) bool {
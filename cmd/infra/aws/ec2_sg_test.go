@@ -0,0 +1,225 @@
+package aws
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	. "github.com/onsi/gomega"
+)
+
+func TestParseIngressRule(t *testing.T) {
+	testCases := []struct {
+		rule        string
+		expected    *ec2.IpPermission
+		expectError bool
+	}{
+		{
+			rule: "tcp:443:192.168.0.0/16",
+			expected: &ec2.IpPermission{
+				IpProtocol: aws.String("tcp"),
+				FromPort:   aws.Int64(443),
+				ToPort:     aws.Int64(443),
+				IpRanges:   []*ec2.IpRange{{CidrIp: aws.String("192.168.0.0/16")}},
+			},
+		},
+		{
+			rule: "udp:8000-8100:2001:db8::/32",
+			expected: &ec2.IpPermission{
+				IpProtocol: aws.String("udp"),
+				FromPort:   aws.Int64(8000),
+				ToPort:     aws.Int64(8100),
+				Ipv6Ranges: []*ec2.Ipv6Range{{CidrIpv6: aws.String("2001:db8::/32")}},
+			},
+		},
+		{
+			rule: "icmp:-1:10.1.0.0/16",
+			expected: &ec2.IpPermission{
+				IpProtocol: aws.String("icmp"),
+				FromPort:   aws.Int64(-1),
+				ToPort:     aws.Int64(-1),
+				IpRanges:   []*ec2.IpRange{{CidrIp: aws.String("10.1.0.0/16")}},
+			},
+		},
+		{
+			rule: "all::10.1.2.3/16",
+			expected: &ec2.IpPermission{
+				IpProtocol: aws.String("-1"),
+				IpRanges:   []*ec2.IpRange{{CidrIp: aws.String("10.1.0.0/16")}},
+			},
+		},
+		{rule: "tcp:443", expectError: true},
+		{rule: "sctp:443:10.0.0.0/8", expectError: true},
+		{rule: "tcp:70000:10.0.0.0/8", expectError: true},
+		{rule: "tcp:100-10:10.0.0.0/8", expectError: true},
+		{rule: "all:22:10.0.0.0/8", expectError: true},
+		{rule: "tcp:22:10.0.0.0", expectError: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.rule, func(t *testing.T) {
+			g := NewGomegaWithT(t)
+			permission, err := parseIngressRule(tc.rule)
+			if tc.expectError {
+				g.Expect(err).To(HaveOccurred())
+				return
+			}
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(permission).To(Equal(tc.expected))
+		})
+	}
+}
+
+func TestAdditionalIngressRulesInTemplates(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	o := &CreateInfraOptions{
+		Region:                 "us-east-2",
+		InfraID:                "test-infra",
+		Name:                   "test",
+		BaseDomain:             "example.com",
+		AdditionalIngressRules: []string{"tcp:443:172.16.0.0/12"},
+	}
+	template, err := o.CloudFormationTemplate()
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(string(template)).To(ContainSubstring(`"CidrIp": "172.16.0.0/12"`))
+
+	config, err := o.TerraformConfiguration()
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(string(config)).To(MatchRegexp(`cidr_blocks\s*=\s*\["172\.16\.0\.0/12"\]`))
+
+	o.AdditionalIngressRules = []string{"tcp:https:172.16.0.0/12"}
+	_, err = o.CloudFormationTemplate()
+	g.Expect(err).To(HaveOccurred())
+}
+
+type fakePagedSecurityGroupsClient struct {
+	ec2iface.EC2API
+	pages [][]*ec2.SecurityGroup
+}
+
+func (f *fakePagedSecurityGroupsClient) DescribeSecurityGroupsPagesWithContext(_ aws.Context, _ *ec2.DescribeSecurityGroupsInput, fn func(*ec2.DescribeSecurityGroupsOutput, bool) bool, _ ...request.Option) error {
+	for i, page := range f.pages {
+		if !fn(&ec2.DescribeSecurityGroupsOutput{SecurityGroups: page}, i == len(f.pages)-1) {
+			break
+		}
+	}
+	return nil
+}
+
+func TestExistingSecurityGroupPaginated(t *testing.T) {
+	g := NewGomegaWithT(t)
+	client := &fakePagedSecurityGroupsClient{pages: [][]*ec2.SecurityGroup{
+		{},
+		{{GroupId: aws.String("sg-1")}},
+	}}
+	o := &CreateInfraOptions{InfraID: "test"}
+	sg, err := o.existingSecurityGroup(context.Background(), client, "test-worker-sg")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(sg).ToNot(BeNil())
+	g.Expect(aws.StringValue(sg.GroupId)).To(Equal("sg-1"))
+}
+
+func TestStaleIngressPermissions(t *testing.T) {
+	ipRanges := func(cidrs ...string) []*ec2.IpRange {
+		var ranges []*ec2.IpRange
+		for _, cidr := range cidrs {
+			ranges = append(ranges, &ec2.IpRange{CidrIp: aws.String(cidr)})
+		}
+		return ranges
+	}
+	ssh := func(cidrs ...string) *ec2.IpPermission {
+		return &ec2.IpPermission{IpProtocol: aws.String("tcp"), FromPort: aws.Int64(22), ToPort: aws.Int64(22), IpRanges: ipRanges(cidrs...)}
+	}
+	icmp := func(cidrs ...string) *ec2.IpPermission {
+		return &ec2.IpPermission{IpProtocol: aws.String("icmp"), FromPort: aws.Int64(-1), ToPort: aws.Int64(-1), IpRanges: ipRanges(cidrs...)}
+	}
+	testCases := []struct {
+		name     string
+		existing []*ec2.IpPermission
+		expected []*ec2.IpPermission
+		stale    []*ec2.IpPermission
+	}{
+		{
+			name:     "rules match",
+			existing: []*ec2.IpPermission{ssh("10.1.0.0/16"), icmp("10.1.0.0/16")},
+			expected: []*ec2.IpPermission{ssh("10.1.0.0/16"), icmp("10.1.0.0/16")},
+		},
+		{
+			name:     "default CIDR left behind with a different VPC CIDR",
+			existing: []*ec2.IpPermission{ssh("10.0.0.0/16", "10.1.0.0/16"), icmp("10.0.0.0/16", "10.1.0.0/16")},
+			expected: []*ec2.IpPermission{ssh("10.1.0.0/16"), icmp("10.1.0.0/16")},
+			stale:    []*ec2.IpPermission{ssh("10.0.0.0/16"), icmp("10.0.0.0/16")},
+		},
+		{
+			name:     "SSH from an additional ingress rule is kept",
+			existing: []*ec2.IpPermission{ssh("10.0.0.0/16", "192.168.0.0/24")},
+			expected: []*ec2.IpPermission{ssh("10.0.0.0/16"), ssh("192.168.0.0/24")},
+		},
+		{
+			name: "other rules are ignored",
+			existing: []*ec2.IpPermission{
+				{IpProtocol: aws.String("tcp"), FromPort: aws.Int64(443), ToPort: aws.Int64(443), IpRanges: ipRanges("0.0.0.0/0")},
+			},
+			expected: []*ec2.IpPermission{ssh("10.0.0.0/16")},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewGomegaWithT(t)
+			g.Expect(staleIngressPermissions(tc.existing, tc.expected, isICMPOrSSHPermission)).To(Equal(tc.stale))
+		})
+	}
+}
+
+func TestSSHIngressCIDRs(t *testing.T) {
+	g := NewGomegaWithT(t)
+	o := &CreateInfraOptions{VPCCIDR: "10.1.0.0/16", SecondaryCIDRs: []string{"10.2.0.0/16"}}
+	g.Expect(o.sshIngressCIDRs()).To(Equal([]string{"10.1.0.0/16", "10.2.0.0/16"}))
+
+	o.SSHIngressCIDRs = []string{"192.168.0.0/24"}
+	g.Expect(o.validateCIDRs()).To(Succeed())
+	g.Expect(o.sshIngressCIDRs()).To(Equal([]string{"192.168.0.0/24"}))
+	permissions := workerSecurityGroupIngressPermissions(o.machineCIDRs(), o.sshIngressCIDRs(), "sg-1", "123456789012")
+	g.Expect(aws.StringValue(permissions[0].IpRanges[0].CidrIp)).To(Equal("10.1.0.0/16"))
+	g.Expect(aws.StringValue(permissions[1].IpRanges[0].CidrIp)).To(Equal("192.168.0.0/24"))
+
+	o.SSHIngressCIDRs = []string{"192.168.0.0"}
+	g.Expect(o.validateCIDRs()).ToNot(Succeed())
+	o.SSHIngressCIDRs = []string{"2001:db8::/32"}
+	g.Expect(o.validateCIDRs()).ToNot(Succeed())
+}
+
+func TestRestrictedEgress(t *testing.T) {
+	g := NewGomegaWithT(t)
+	o := &CreateInfraOptions{VPCCIDR: "10.1.0.0/16", EgressCIDRs: []string{"192.168.0.0/24"}}
+	g.Expect(o.validateCIDRs()).To(MatchError(ContainSubstring("--egress-cidr can only be specified together with --restrict-egress")))
+	g.Expect(o.workerEgressPermissions()).To(Equal(workerSecurityGroupEgressPermissions(false)))
+
+	o.RestrictEgress = true
+	o.EgressCIDRs = []string{"192.168.0.0/24", "2001:db8::/32"}
+	o.s3PrefixListID = "pl-63a5400a"
+	g.Expect(o.validateCIDRs()).To(Succeed())
+	permissions := o.workerEgressPermissions()
+	g.Expect(permissions).To(HaveLen(2))
+	g.Expect(permissions[0].IpRanges).To(Equal([]*ec2.IpRange{{CidrIp: aws.String("10.1.0.0/16")}, {CidrIp: aws.String("192.168.0.0/24")}}))
+	g.Expect(permissions[0].Ipv6Ranges).To(Equal([]*ec2.Ipv6Range{{CidrIpv6: aws.String("2001:db8::/32")}}))
+	g.Expect(aws.Int64Value(permissions[1].FromPort)).To(Equal(int64(443)))
+	g.Expect(aws.StringValue(permissions[1].PrefixListIds[0].PrefixListId)).To(Equal("pl-63a5400a"))
+
+	existing := append(workerSecurityGroupEgressPermissions(true), permissions...)
+	g.Expect(unrestrictedEgressPermissions(existing)).To(Equal([]*ec2.IpPermission{
+		{IpProtocol: aws.String("-1"), IpRanges: []*ec2.IpRange{{CidrIp: aws.String("0.0.0.0/0")}}},
+		{IpProtocol: aws.String("-1"), Ipv6Ranges: []*ec2.Ipv6Range{{CidrIpv6: aws.String("::/0")}}},
+	}))
+	g.Expect(unrestrictedEgressPermissions(permissions)).To(BeEmpty())
+
+	// An existing security group must not allow all egress and must allow HTTPS to S3
+	securityGroup := &ec2.SecurityGroup{GroupId: aws.String("sg-1"), IpPermissionsEgress: existing[:1]}
+	err := o.validateExistingSecurityGroup(securityGroup)
+	g.Expect(err).To(HaveOccurred())
+	g.Expect(err.Error()).To(ContainSubstring("security group sg-1 allows egress to all destinations"))
+	g.Expect(err.Error()).To(ContainSubstring("missing egress rule tcp 443 to prefix list pl-63a5400a"))
+}
@@ -0,0 +1,104 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/arn"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/route53"
+	"github.com/aws/aws-sdk-go/service/route53/route53iface"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/aws/aws-sdk-go/service/sts/stsiface"
+	"github.com/go-logr/logr"
+
+	awsutil "github.com/openshift/hypershift/cmd/infra/aws/util"
+)
+
+// validateSharedVPCOptions validates the flags for a VPC shared by another account
+// through AWS Resource Access Manager.
+func (o *CreateInfraOptions) validateSharedVPCOptions() error {
+	if len(o.VPCOwnerRoleARN) == 0 {
+		if len(o.VPCOwnerExternalID) > 0 {
+			return errors.New("--vpc-owner-external-id can only be specified together with --vpc-owner-role-arn")
+		}
+		return nil
+	}
+	if len(o.VPCID) == 0 {
+		return errors.New("--vpc-owner-role-arn can only be specified together with --vpc-id")
+	}
+	if _, err := arn.Parse(o.VPCOwnerRoleARN); err != nil {
+		return fmt.Errorf("invalid --vpc-owner-role-arn %q: %w", o.VPCOwnerRoleARN, err)
+	}
+	return nil
+}
+
+// callerAccountID returns the ID of the account the credentials belong to.
+func callerAccountID(ctx context.Context, client stsiface.STSAPI) (string, error) {
+	identity, err := client.GetCallerIdentityWithContext(ctx, &sts.GetCallerIdentityInput{})
+	if err != nil {
+		return "", fmt.Errorf("cannot get caller identity: %w", err)
+	}
+	return aws.StringValue(identity.Account), nil
+}
+
+// detectSharedVPC records the owner of the existing VPC if it is owned by another
+// account than the one the infrastructure is created in, which makes the latter a
+// participant of the shared VPC. A participant cannot modify the VPC, its subnets
+// or its route tables, and can only associate private hosted zones with the VPC
+// from the owner account, which requires VPCOwnerRoleARN.
+func (o *CreateInfraOptions) detectSharedVPC(l logr.Logger, vpc *ec2.Vpc) error {
+	owner := aws.StringValue(vpc.OwnerId)
+	if len(o.accountID) == 0 || len(owner) == 0 || owner == o.accountID {
+		if len(o.VPCOwnerRoleARN) > 0 {
+			return fmt.Errorf("--vpc-owner-role-arn can only be specified with a VPC shared by another account, VPC %s is owned by account %s", o.VPCID, owner)
+		}
+		return nil
+	}
+	if len(o.VPCOwnerRoleARN) == 0 {
+		return fmt.Errorf("VPC %s is shared by account %s, --vpc-owner-role-arn is required to create the private hosted zones in that account", o.VPCID, owner)
+	}
+	o.vpcOwnerAccountID = owner
+	l.Info("Using VPC shared by another account", "id", o.VPCID, "owner", owner, "participant", o.accountID)
+	return nil
+}
+
+// isSharedVPC returns whether the existing VPC is shared by another account.
+func (o *CreateInfraOptions) isSharedVPC() bool {
+	return len(o.vpcOwnerAccountID) > 0
+}
+
+// vpcOwnerZoneClient returns a client for the private hosted zones of the cluster.
+// If a VPC owner role is given, it is assumed with the credentials of awsSession,
+// so that the zones are created in the account that owns the shared VPC.
+func vpcOwnerZoneClient(awsSession *session.Session, agent, roleARN, externalID string) route53iface.Route53API {
+	if len(roleARN) > 0 {
+		awsSession = awsutil.AssumeRole(awsSession, roleARN, externalID, fmt.Sprintf("hypershift-%s", agent), nil)
+	}
+	return route53.New(awsSession, awsutil.NewAWSRoute53Config())
+}
+
+// sharedVPCOutput lists the resources used by the cluster by the account that
+// owns them.
+func (o *CreateInfraOptions) sharedVPCOutput(result *CreateInfraOutput) *CreateInfraOutputSharedVPC {
+	output := &CreateInfraOutputSharedVPC{
+		OwnerAccountID:       o.vpcOwnerAccountID,
+		ParticipantAccountID: o.accountID,
+		OwnerResources:       []string{result.VPCID},
+	}
+	for _, zone := range result.Zones {
+		output.OwnerResources = append(output.OwnerResources, zone.SubnetID)
+	}
+	output.OwnerResources = append(output.OwnerResources, result.PrivateZoneID, result.LocalZoneID)
+	output.ParticipantResources = append(output.ParticipantResources, result.SecurityGroupID, result.PublicZoneID)
+	if o.CreateKMSKey {
+		output.ParticipantResources = append(output.ParticipantResources, result.KMSKeyARN)
+	}
+	if len(result.PrivateLinkEndpointID) > 0 {
+		output.ParticipantResources = append(output.ParticipantResources, result.PrivateLinkEndpointID)
+	}
+	return output
+}
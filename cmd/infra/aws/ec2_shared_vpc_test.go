@@ -0,0 +1,108 @@
+package aws
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/go-logr/logr"
+	. "github.com/onsi/gomega"
+)
+
+func TestValidateSharedVPCOptions(t *testing.T) {
+	testCases := []struct {
+		name        string
+		options     CreateInfraOptions
+		expectError bool
+	}{
+		{
+			name:    "no role",
+			options: CreateInfraOptions{VPCID: "vpc-1"},
+		},
+		{
+			name:    "role with existing VPC",
+			options: CreateInfraOptions{VPCID: "vpc-1", VPCOwnerRoleARN: "arn:aws:iam::111111111111:role/network", VPCOwnerExternalID: "id"},
+		},
+		{
+			name:        "role without existing VPC",
+			options:     CreateInfraOptions{VPCOwnerRoleARN: "arn:aws:iam::111111111111:role/network"},
+			expectError: true,
+		},
+		{
+			name:        "external ID without role",
+			options:     CreateInfraOptions{VPCID: "vpc-1", VPCOwnerExternalID: "id"},
+			expectError: true,
+		},
+		{
+			name:        "invalid role",
+			options:     CreateInfraOptions{VPCID: "vpc-1", VPCOwnerRoleARN: "network"},
+			expectError: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewGomegaWithT(t)
+			err := tc.options.validateSharedVPCOptions()
+			if tc.expectError {
+				g.Expect(err).To(HaveOccurred())
+			} else {
+				g.Expect(err).ToNot(HaveOccurred())
+			}
+		})
+	}
+}
+
+func TestUseSharedVPC(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	client := &fakeExistingVPCClient{
+		vpcOwner: "111111111111",
+		subnets: []*ec2.Subnet{
+			{SubnetId: aws.String("subnet-a"), VpcId: aws.String("vpc-1"), AvailabilityZone: aws.String("us-east-1a"), OwnerId: aws.String("111111111111")},
+		},
+	}
+	newOptions := func() *CreateInfraOptions {
+		return &CreateInfraOptions{
+			InfraID:         "test",
+			VPCID:           "vpc-1",
+			SubnetIDs:       []string{"subnet-a"},
+			VPCOwnerRoleARN: "arn:aws:iam::111111111111:role/network",
+			accountID:       "222222222222",
+		}
+	}
+
+	// The VPC and subnets of the owner are neither tagged nor their route tables changed
+	o := newOptions()
+	result := &CreateInfraOutput{}
+	routeTables, err := o.useExistingVPC(context.Background(), logr.Discard(), client, result)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(o.isSharedVPC()).To(BeTrue())
+	g.Expect(routeTables).To(BeEmpty())
+	g.Expect(client.tagged).To(BeEmpty())
+	g.Expect(result.Zones).To(Equal([]*CreateInfraOutputZone{{Name: "us-east-1a", SubnetID: "subnet-a"}}))
+
+	result.SecurityGroupID = "sg-1"
+	result.PublicZoneID = "Z1"
+	result.PrivateZoneID = "Z2"
+	result.LocalZoneID = "Z3"
+	g.Expect(o.sharedVPCOutput(result)).To(Equal(&CreateInfraOutputSharedVPC{
+		OwnerAccountID:       "111111111111",
+		ParticipantAccountID: "222222222222",
+		OwnerResources:       []string{"vpc-1", "subnet-a", "Z2", "Z3"},
+		ParticipantResources: []string{"sg-1", "Z1"},
+	}))
+
+	// The private hosted zones can only be associated from the owner account
+	o = newOptions()
+	o.VPCOwnerRoleARN = ""
+	_, err = o.useExistingVPC(context.Background(), logr.Discard(), client, &CreateInfraOutput{})
+	g.Expect(err).To(MatchError(ContainSubstring("--vpc-owner-role-arn is required")))
+
+	// The role is rejected for a VPC owned by the same account
+	o = newOptions()
+	o.accountID = "111111111111"
+	_, err = o.useExistingVPC(context.Background(), logr.Discard(), client, &CreateInfraOutput{})
+	g.Expect(err).To(HaveOccurred())
+	g.Expect(o.isSharedVPC()).To(BeFalse())
+}
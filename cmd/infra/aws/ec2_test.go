@@ -0,0 +1,253 @@
+package aws
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/go-logr/logr"
+	. "github.com/onsi/gomega"
+)
+
+type fakeZonesClient struct {
+	ec2iface.EC2API
+	zones []*ec2.AvailabilityZone
+}
+
+func (f *fakeZonesClient) DescribeAvailabilityZonesWithContext(_ aws.Context, in *ec2.DescribeAvailabilityZonesInput, _ ...request.Option) (*ec2.DescribeAvailabilityZonesOutput, error) {
+	requested := map[string]bool{}
+	for _, name := range in.ZoneNames {
+		requested[aws.StringValue(name)] = true
+	}
+	out := &ec2.DescribeAvailabilityZonesOutput{}
+	for _, zone := range f.zones {
+		if len(requested) == 0 || requested[aws.StringValue(zone.ZoneName)] {
+			out.AvailabilityZones = append(out.AvailabilityZones, zone)
+		}
+	}
+	return out, nil
+}
+
+func TestSelectZones(t *testing.T) {
+	client := &fakeZonesClient{zones: []*ec2.AvailabilityZone{
+		{ZoneName: aws.String("us-east-1c"), State: aws.String(ec2.AvailabilityZoneStateAvailable)},
+		{ZoneName: aws.String("us-east-1a"), State: aws.String(ec2.AvailabilityZoneStateAvailable)},
+		{ZoneName: aws.String("us-east-1b"), State: aws.String(ec2.AvailabilityZoneStateImpaired)},
+	}}
+	testCases := []struct {
+		name        string
+		options     CreateInfraOptions
+		expected    []string
+		expectError bool
+	}{
+		{
+			name:     "defaults to the first zone",
+			options:  CreateInfraOptions{},
+			expected: []string{"us-east-1a"},
+		},
+		{
+			name:     "zone count",
+			options:  CreateInfraOptions{ZoneCount: 2},
+			expected: []string{"us-east-1a", "us-east-1c"},
+		},
+		{
+			name:        "zone count exceeds available zones",
+			options:     CreateInfraOptions{ZoneCount: 3},
+			expectError: true,
+		},
+		{
+			name:     "explicit zones",
+			options:  CreateInfraOptions{Zones: []string{"us-east-1c"}},
+			expected: []string{"us-east-1c"},
+		},
+		{
+			name:        "unavailable explicit zone",
+			options:     CreateInfraOptions{Zones: []string{"us-east-1b"}},
+			expectError: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewGomegaWithT(t)
+			zones, err := tc.options.selectZones(context.Background(), logr.Discard(), client)
+			if tc.expectError {
+				g.Expect(err).To(HaveOccurred())
+				return
+			}
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(zones).To(Equal(tc.expected))
+		})
+	}
+}
+
+func TestValidateZones(t *testing.T) {
+	g := NewGomegaWithT(t)
+	g.Expect((&CreateInfraOptions{Zones: []string{"a", "b"}}).validateZones()).To(Succeed())
+	g.Expect((&CreateInfraOptions{Zones: []string{"a", "a"}}).validateZones()).ToNot(Succeed())
+	g.Expect((&CreateInfraOptions{Zones: []string{"a"}, ZoneCount: 2}).validateZones()).ToNot(Succeed())
+	g.Expect((&CreateInfraOptions{ZoneCount: maxZones + 1}).validateZones()).ToNot(Succeed())
+}
+
+type fakeRouteTableClient struct {
+	ec2iface.EC2API
+	table   *ec2.RouteTable
+	actions []string
+}
+
+func (f *fakeRouteTableClient) DescribeRouteTablesWithContext(_ aws.Context, in *ec2.DescribeRouteTablesInput, _ ...request.Option) (*ec2.DescribeRouteTablesOutput, error) {
+	return &ec2.DescribeRouteTablesOutput{RouteTables: []*ec2.RouteTable{f.table}}, nil
+}
+
+func (f *fakeRouteTableClient) DescribeRouteTablesPagesWithContext(ctx aws.Context, in *ec2.DescribeRouteTablesInput, fn func(*ec2.DescribeRouteTablesOutput, bool) bool, _ ...request.Option) error {
+	out, _ := f.DescribeRouteTablesWithContext(ctx, in)
+	fn(out, true)
+	return nil
+}
+
+func (f *fakeRouteTableClient) CreateRouteWithContext(_ aws.Context, in *ec2.CreateRouteInput, _ ...request.Option) (*ec2.CreateRouteOutput, error) {
+	f.actions = append(f.actions, "create "+aws.StringValue(in.NatGatewayId))
+	return &ec2.CreateRouteOutput{}, nil
+}
+
+func (f *fakeRouteTableClient) ReplaceRouteWithContext(_ aws.Context, in *ec2.ReplaceRouteInput, _ ...request.Option) (*ec2.ReplaceRouteOutput, error) {
+	f.actions = append(f.actions, "replace "+aws.StringValue(in.NatGatewayId))
+	return &ec2.ReplaceRouteOutput{}, nil
+}
+
+func (f *fakeRouteTableClient) DeleteRouteWithContext(_ aws.Context, in *ec2.DeleteRouteInput, _ ...request.Option) (*ec2.DeleteRouteOutput, error) {
+	f.actions = append(f.actions, "delete")
+	return &ec2.DeleteRouteOutput{}, nil
+}
+
+func (f *fakeRouteTableClient) AssociateRouteTableWithContext(_ aws.Context, in *ec2.AssociateRouteTableInput, _ ...request.Option) (*ec2.AssociateRouteTableOutput, error) {
+	return &ec2.AssociateRouteTableOutput{}, nil
+}
+
+func TestCreatePrivateRouteTableNATRoute(t *testing.T) {
+	natRoute := func(natGatewayID string) []*ec2.Route {
+		return []*ec2.Route{{DestinationCidrBlock: aws.String("0.0.0.0/0"), NatGatewayId: aws.String(natGatewayID)}}
+	}
+	testCases := []struct {
+		name            string
+		routes          []*ec2.Route
+		natGatewayID    string
+		expectedActions []string
+	}{
+		{
+			name:            "new route",
+			natGatewayID:    "nat-1",
+			expectedActions: []string{"create nat-1"},
+		},
+		{
+			name:         "existing route",
+			routes:       natRoute("nat-1"),
+			natGatewayID: "nat-1",
+		},
+		{
+			name:            "route to another NAT gateway",
+			routes:          natRoute("nat-0"),
+			natGatewayID:    "nat-1",
+			expectedActions: []string{"replace nat-1"},
+		},
+		{
+			name:            "no NAT gateway",
+			routes:          natRoute("nat-0"),
+			expectedActions: []string{"delete"},
+		},
+		{
+			name: "no NAT gateway and no route",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewGomegaWithT(t)
+			client := &fakeRouteTableClient{table: &ec2.RouteTable{RouteTableId: aws.String("rtb-1"), Routes: tc.routes}}
+			o := &CreateInfraOptions{InfraID: "test"}
+			id, err := o.CreatePrivateRouteTable(context.Background(), logr.Discard(), client, "vpc-1", tc.natGatewayID, "subnet-1", "us-east-1a")
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(id).To(Equal("rtb-1"))
+			g.Expect(client.actions).To(Equal(tc.expectedActions))
+		})
+	}
+}
+
+func TestValidateNATTopology(t *testing.T) {
+	g := NewGomegaWithT(t)
+	for _, topology := range []string{"", NATTopologyPerZone, NATTopologySingle, NATTopologyNone} {
+		g.Expect((&CreateInfraOptions{NATTopology: topology}).validateNATTopology()).To(Succeed())
+	}
+	g.Expect((&CreateInfraOptions{NATTopology: "per-az"}).validateNATTopology()).ToNot(Succeed())
+	g.Expect((&CreateInfraOptions{NATTopology: NATTopologySingle, EnableProxy: true}).natTopology()).To(Equal(NATTopologyNone))
+}
+
+func TestValidatePublicIPv4PoolOptions(t *testing.T) {
+	testCases := []struct {
+		name          string
+		options       CreateInfraOptions
+		expectedError string
+	}{
+		{
+			name: "no pool",
+		},
+		{
+			name:    "pool with NAT gateways",
+			options: CreateInfraOptions{PublicIPv4Pool: "ipv4pool-ec2-0123456789abcdef0", NATTopology: NATTopologySingle},
+		},
+		{
+			name:          "not a pool ID",
+			options:       CreateInfraOptions{PublicIPv4Pool: "203.0.113.0/24"},
+			expectedError: "invalid --public-ipv4-pool",
+		},
+		{
+			name:          "no NAT gateways",
+			options:       CreateInfraOptions{PublicIPv4Pool: "ipv4pool-ec2-0123456789abcdef0", EnableProxy: true},
+			expectedError: "--public-ipv4-pool requires NAT gateways",
+		},
+		{
+			name:          "existing VPC",
+			options:       CreateInfraOptions{PublicIPv4Pool: "ipv4pool-ec2-0123456789abcdef0", VPCID: "vpc-1"},
+			expectedError: "--public-ipv4-pool requires NAT gateways",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewGomegaWithT(t)
+			err := tc.options.validatePublicIPv4PoolOptions()
+			if tc.expectedError == "" {
+				g.Expect(err).ToNot(HaveOccurred())
+				return
+			}
+			g.Expect(err).To(MatchError(ContainSubstring(tc.expectedError)))
+		})
+	}
+}
+
+type fakePublicIPv4PoolClient struct {
+	ec2iface.EC2API
+	pools []*ec2.PublicIpv4Pool
+}
+
+func (f *fakePublicIPv4PoolClient) DescribePublicIpv4PoolsWithContext(_ aws.Context, in *ec2.DescribePublicIpv4PoolsInput, _ ...request.Option) (*ec2.DescribePublicIpv4PoolsOutput, error) {
+	out := &ec2.DescribePublicIpv4PoolsOutput{}
+	for _, pool := range f.pools {
+		if aws.StringValue(pool.PoolId) == aws.StringValue(in.PoolIds[0]) {
+			out.PublicIpv4Pools = append(out.PublicIpv4Pools, pool)
+		}
+	}
+	return out, nil
+}
+
+func TestCheckPublicIPv4Pool(t *testing.T) {
+	g := NewGomegaWithT(t)
+	client := &fakePublicIPv4PoolClient{pools: []*ec2.PublicIpv4Pool{
+		{PoolId: aws.String("ipv4pool-ec2-1"), TotalAvailableAddressCount: aws.Int64(2)},
+	}}
+	o := &CreateInfraOptions{Region: "us-east-1", PublicIPv4Pool: "ipv4pool-ec2-1"}
+	g.Expect(o.checkPublicIPv4Pool(context.Background(), logr.Discard(), client)).To(Succeed())
+
+	o.PublicIPv4Pool = "ipv4pool-ec2-2"
+	g.Expect(o.checkPublicIPv4Pool(context.Background(), logr.Discard(), client)).To(MatchError("public IPv4 pool ipv4pool-ec2-2 not found in region us-east-1"))
+}
@@ -0,0 +1,408 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/go-logr/logr"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+)
+
+// validateTransitGatewayOptions validates the flags for attaching the VPC to a
+// transit gateway.
+func (o *CreateInfraOptions) validateTransitGatewayOptions() error {
+	if len(o.TransitGatewayID) == 0 {
+		if len(o.TransitGatewayRouteTableID) > 0 {
+			return errors.New("--transit-gateway-route-table-id can only be specified together with --transit-gateway-id")
+		}
+		if len(o.TransitGatewayRoutes) > 0 {
+			return errors.New("--transit-gateway-routes can only be specified together with --transit-gateway-id")
+		}
+		return nil
+	}
+	if !strings.HasPrefix(o.TransitGatewayID, "tgw-") {
+		return fmt.Errorf("invalid --transit-gateway-id %q", o.TransitGatewayID)
+	}
+	if len(o.TransitGatewayRouteTableID) > 0 && !strings.HasPrefix(o.TransitGatewayRouteTableID, "tgw-rtb-") {
+		return fmt.Errorf("invalid --transit-gateway-route-table-id %q", o.TransitGatewayRouteTableID)
+	}
+	if len(o.VPCID) > 0 {
+		return errors.New("--transit-gateway-id is not supported with an existing VPC")
+	}
+	var errs []error
+	for _, cidr := range o.TransitGatewayRoutes {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid transit gateway route %q: %w", cidr, err))
+			continue
+		}
+		if network.IP.To4() == nil {
+			errs = append(errs, fmt.Errorf("transit gateway route %s must be an IPv4 CIDR", cidr))
+			continue
+		}
+		// Routes within the VPC are shadowed by its local routes
+		for _, machineCIDR := range o.machineCIDRs() {
+			if cidrContains(machineCIDR, network.String()) {
+				errs = append(errs, fmt.Errorf("transit gateway route %s is within VPC CIDR %s", network, machineCIDR))
+			}
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// createTransitGatewayResources attaches the VPC to the transit gateway given by
+// TransitGatewayID through the private subnets, associates the attachment with the
+// route table given by TransitGatewayRouteTableID and propagates the VPC routes to
+// it, and routes TransitGatewayRoutes from the private route tables to the transit
+// gateway. Without a route table, the association and propagation are left to the
+// defaults of the transit gateway.
+func (o *CreateInfraOptions) createTransitGatewayResources(ctx context.Context, l logr.Logger, client ec2iface.EC2API, result *CreateInfraOutput) error {
+	gateway, err := transitGateway(ctx, client, o.TransitGatewayID)
+	if err != nil {
+		return err
+	}
+	var subnetIDs []string
+	for _, zone := range result.Zones {
+		subnetIDs = append(subnetIDs, zone.SubnetID)
+	}
+	attachmentID, err := o.ensureTransitGatewayAttachment(ctx, l, client, result.VPCID, subnetIDs)
+	if err != nil {
+		return err
+	}
+	routeTableID := o.TransitGatewayRouteTableID
+	if len(routeTableID) > 0 {
+		if err := o.ensureTransitGatewayRouteTableAssociation(ctx, l, client, attachmentID, routeTableID); err != nil {
+			return err
+		}
+		if err := o.ensureTransitGatewayRouteTablePropagation(ctx, l, client, attachmentID, routeTableID); err != nil {
+			return err
+		}
+	} else if gateway.Options != nil && aws.StringValue(gateway.Options.DefaultRouteTableAssociation) == ec2.DefaultRouteTableAssociationValueEnable {
+		routeTableID = aws.StringValue(gateway.Options.AssociationDefaultRouteTableId)
+	}
+	for _, zone := range result.Zones {
+		if err := o.ensureTransitGatewayRoutes(ctx, l, client, fmt.Sprintf("%s-private-%s", o.InfraID, zone.Name)); err != nil {
+			return err
+		}
+	}
+	result.TransitGateway = &CreateInfraOutputTransitGateway{
+		ID:           o.TransitGatewayID,
+		AttachmentID: attachmentID,
+		RouteTableID: routeTableID,
+		Routes:       o.TransitGatewayRoutes,
+	}
+	return nil
+}
+
+// transitGateway returns the transit gateway with the given ID after validating
+// that it is available.
+func transitGateway(ctx context.Context, client ec2iface.EC2API, id string) (*ec2.TransitGateway, error) {
+	// Transit gateway IDs are given by the user, a missing gateway is not retried
+	output, err := client.DescribeTransitGatewaysWithContext(ctx, &ec2.DescribeTransitGatewaysInput{TransitGatewayIds: []*string{aws.String(id)}})
+	if err != nil {
+		return nil, fmt.Errorf("cannot find transit gateway %s: %w", id, err)
+	}
+	if len(output.TransitGateways) == 0 {
+		return nil, fmt.Errorf("transit gateway %s not found", id)
+	}
+	gateway := output.TransitGateways[0]
+	if state := aws.StringValue(gateway.State); state != ec2.TransitGatewayStateAvailable {
+		return nil, fmt.Errorf("transit gateway %s is %s, it must be available", id, state)
+	}
+	return gateway, nil
+}
+
+// ensureTransitGatewayAttachment attaches the VPC to the transit gateway through the
+// given subnets, or adds missing subnets to an existing attachment, and waits for
+// the attachment to become available. It returns the ID of the attachment.
+func (o *CreateInfraOptions) ensureTransitGatewayAttachment(ctx context.Context, l logr.Logger, client ec2iface.EC2API, vpcID string, subnetIDs []string) (string, error) {
+	name := fmt.Sprintf("%s-tgw-attachment", o.InfraID)
+	var attachment *ec2.TransitGatewayVpcAttachment
+	if !isPlannedID(vpcID) {
+		err := client.DescribeTransitGatewayVpcAttachmentsPagesWithContext(ctx, &ec2.DescribeTransitGatewayVpcAttachmentsInput{Filters: []*ec2.Filter{
+			{Name: aws.String("transit-gateway-id"), Values: []*string{aws.String(o.TransitGatewayID)}},
+			{Name: aws.String("vpc-id"), Values: []*string{aws.String(vpcID)}},
+			{Name: aws.String("state"), Values: aws.StringSlice([]string{
+				ec2.TransitGatewayAttachmentStatePendingAcceptance,
+				ec2.TransitGatewayAttachmentStatePending,
+				ec2.TransitGatewayAttachmentStateAvailable,
+				ec2.TransitGatewayAttachmentStateModifying,
+			})},
+		}}, func(out *ec2.DescribeTransitGatewayVpcAttachmentsOutput, _ bool) bool {
+			for _, existing := range out.TransitGatewayVpcAttachments {
+				attachment = existing
+				return false
+			}
+			return true
+		})
+		if err != nil {
+			return "", fmt.Errorf("cannot list transit gateway attachments: %w", err)
+		}
+	}
+	if attachment == nil {
+		if o.DryRun {
+			return o.planCreate(l, "transit-gateway-attachment", name), nil
+		}
+		output, err := client.CreateTransitGatewayVpcAttachmentWithContext(ctx, &ec2.CreateTransitGatewayVpcAttachmentInput{
+			TransitGatewayId:  aws.String(o.TransitGatewayID),
+			VpcId:             aws.String(vpcID),
+			SubnetIds:         aws.StringSlice(subnetIDs),
+			TagSpecifications: o.ec2TagSpecifications("transit-gateway-attachment", name),
+		})
+		if err != nil {
+			return "", fmt.Errorf("cannot attach vpc to transit gateway %s: %w", o.TransitGatewayID, err)
+		}
+		attachment = output.TransitGatewayVpcAttachment
+		l.Info("Created transit gateway attachment", "id", aws.StringValue(attachment.TransitGatewayAttachmentId), "transit gateway", o.TransitGatewayID)
+	} else {
+		l.Info("Found existing transit gateway attachment", "id", aws.StringValue(attachment.TransitGatewayAttachmentId))
+	}
+	attachmentID := aws.StringValue(attachment.TransitGatewayAttachmentId)
+
+	attached := map[string]bool{}
+	for _, subnetID := range attachment.SubnetIds {
+		attached[aws.StringValue(subnetID)] = true
+	}
+	var missing []string
+	for _, subnetID := range subnetIDs {
+		if !attached[subnetID] {
+			missing = append(missing, subnetID)
+		}
+	}
+	if len(missing) > 0 && o.DryRun {
+		o.planModify(l, "transit-gateway-attachment", attachmentID, fmt.Sprintf("add subnets %v", missing))
+	} else if len(missing) > 0 {
+		if err := waitForTransitGatewayAttachment(ctx, client, attachmentID); err != nil {
+			return "", err
+		}
+		if _, err := client.ModifyTransitGatewayVpcAttachmentWithContext(ctx, &ec2.ModifyTransitGatewayVpcAttachmentInput{
+			TransitGatewayAttachmentId: aws.String(attachmentID),
+			AddSubnetIds:               aws.StringSlice(missing),
+		}); err != nil {
+			return "", fmt.Errorf("cannot add subnets to transit gateway attachment %s: %w", attachmentID, err)
+		}
+		l.Info("Added subnets to transit gateway attachment", "id", attachmentID, "subnets", missing)
+	}
+	if o.DryRun {
+		return attachmentID, nil
+	}
+	return attachmentID, waitForTransitGatewayAttachment(ctx, client, attachmentID)
+}
+
+// waitForTransitGatewayAttachment waits until the attachment is available. An
+// attachment to a transit gateway of another account that does not accept
+// attachments automatically fails the wait until it is accepted by that account.
+func waitForTransitGatewayAttachment(ctx context.Context, client ec2iface.EC2API, attachmentID string) error {
+	err := retryOnError(ctx, provisioningBackoff(), isNotFoundYet, func() error {
+		output, err := client.DescribeTransitGatewayVpcAttachmentsWithContext(ctx, &ec2.DescribeTransitGatewayVpcAttachmentsInput{
+			TransitGatewayAttachmentIds: []*string{aws.String(attachmentID)},
+		})
+		if err != nil {
+			return err
+		}
+		if len(output.TransitGatewayVpcAttachments) == 0 {
+			return errNotFoundYet
+		}
+		switch state := aws.StringValue(output.TransitGatewayVpcAttachments[0].State); state {
+		case ec2.TransitGatewayAttachmentStateAvailable:
+			return nil
+		case ec2.TransitGatewayAttachmentStatePending, ec2.TransitGatewayAttachmentStateModifying:
+			return errNotFoundYet
+		case ec2.TransitGatewayAttachmentStatePendingAcceptance:
+			return fmt.Errorf("transit gateway attachment %s must be accepted by the owner of the transit gateway", attachmentID)
+		default:
+			return fmt.Errorf("transit gateway attachment %s is %s", attachmentID, state)
+		}
+	})
+	if err != nil {
+		return fmt.Errorf("transit gateway attachment %s did not become available: %w", attachmentID, err)
+	}
+	return nil
+}
+
+// ensureTransitGatewayRouteTableAssociation associates the attachment with the
+// transit gateway route table. An attachment can only be associated with a single
+// route table, so an association with another route table, e.g. the default route
+// table of the transit gateway, is replaced.
+func (o *CreateInfraOptions) ensureTransitGatewayRouteTableAssociation(ctx context.Context, l logr.Logger, client ec2iface.EC2API, attachmentID, routeTableID string) error {
+	var association *ec2.TransitGatewayAttachmentAssociation
+	if !isPlannedID(attachmentID) {
+		output, err := client.DescribeTransitGatewayAttachmentsWithContext(ctx, &ec2.DescribeTransitGatewayAttachmentsInput{
+			TransitGatewayAttachmentIds: []*string{aws.String(attachmentID)},
+		})
+		if err != nil {
+			return fmt.Errorf("cannot describe transit gateway attachment %s: %w", attachmentID, err)
+		}
+		if len(output.TransitGatewayAttachments) > 0 {
+			association = output.TransitGatewayAttachments[0].Association
+		}
+	}
+	if association != nil && aws.StringValue(association.TransitGatewayRouteTableId) == routeTableID {
+		l.Info("Found existing transit gateway route table association", "route table", routeTableID, "attachment", attachmentID)
+		return nil
+	}
+	if o.DryRun {
+		if association != nil {
+			o.planModify(l, "transit-gateway-route-table", aws.StringValue(association.TransitGatewayRouteTableId), fmt.Sprintf("disassociate attachment %s", attachmentID))
+		}
+		o.planModify(l, "transit-gateway-route-table", routeTableID, fmt.Sprintf("associate attachment %s", attachmentID))
+		return nil
+	}
+	if association != nil && aws.StringValue(association.State) != ec2.TransitGatewayAssociationStateDisassociating {
+		otherRouteTableID := aws.StringValue(association.TransitGatewayRouteTableId)
+		if _, err := client.DisassociateTransitGatewayRouteTableWithContext(ctx, &ec2.DisassociateTransitGatewayRouteTableInput{
+			TransitGatewayAttachmentId: aws.String(attachmentID),
+			TransitGatewayRouteTableId: aws.String(otherRouteTableID),
+		}); err != nil {
+			return fmt.Errorf("cannot disassociate transit gateway attachment %s from route table %s: %w", attachmentID, otherRouteTableID, err)
+		}
+		l.Info("Disassociated transit gateway attachment from route table", "route table", otherRouteTableID, "attachment", attachmentID)
+	}
+	// The previous association is removed asynchronously
+	isRetriable := func(err error) bool {
+		var awsErr awserr.Error
+		return errors.As(err, &awsErr) && (awsErr.Code() == "Resource.AlreadyAssociated" || awsErr.Code() == "IncorrectState")
+	}
+	if err := retryOnError(ctx, ec2Backoff(), isRetriable, func() error {
+		_, err := client.AssociateTransitGatewayRouteTableWithContext(ctx, &ec2.AssociateTransitGatewayRouteTableInput{
+			TransitGatewayAttachmentId: aws.String(attachmentID),
+			TransitGatewayRouteTableId: aws.String(routeTableID),
+		})
+		return err
+	}); err != nil {
+		return fmt.Errorf("cannot associate transit gateway attachment %s with route table %s: %w", attachmentID, routeTableID, err)
+	}
+	l.Info("Associated transit gateway attachment with route table", "route table", routeTableID, "attachment", attachmentID)
+	return nil
+}
+
+// ensureTransitGatewayRouteTablePropagation propagates the routes of the VPC to the
+// transit gateway route table.
+func (o *CreateInfraOptions) ensureTransitGatewayRouteTablePropagation(ctx context.Context, l logr.Logger, client ec2iface.EC2API, attachmentID, routeTableID string) error {
+	propagated := false
+	if !isPlannedID(attachmentID) {
+		err := client.GetTransitGatewayRouteTablePropagationsPagesWithContext(ctx, &ec2.GetTransitGatewayRouteTablePropagationsInput{
+			TransitGatewayRouteTableId: aws.String(routeTableID),
+			Filters: []*ec2.Filter{
+				{Name: aws.String("transit-gateway-attachment-id"), Values: []*string{aws.String(attachmentID)}},
+			},
+		}, func(out *ec2.GetTransitGatewayRouteTablePropagationsOutput, _ bool) bool {
+			for _, propagation := range out.TransitGatewayRouteTablePropagations {
+				if state := aws.StringValue(propagation.State); state == ec2.TransitGatewayPropagationStateEnabled || state == ec2.TransitGatewayPropagationStateEnabling {
+					propagated = true
+					return false
+				}
+			}
+			return true
+		})
+		if err != nil {
+			return fmt.Errorf("cannot list propagations of transit gateway route table %s: %w", routeTableID, err)
+		}
+	}
+	if propagated {
+		l.Info("Found existing transit gateway route table propagation", "route table", routeTableID, "attachment", attachmentID)
+		return nil
+	}
+	if o.DryRun {
+		o.planModify(l, "transit-gateway-route-table", routeTableID, fmt.Sprintf("enable propagation of attachment %s", attachmentID))
+		return nil
+	}
+	if _, err := client.EnableTransitGatewayRouteTablePropagationWithContext(ctx, &ec2.EnableTransitGatewayRouteTablePropagationInput{
+		TransitGatewayAttachmentId: aws.String(attachmentID),
+		TransitGatewayRouteTableId: aws.String(routeTableID),
+	}); err != nil {
+		return fmt.Errorf("cannot enable propagation of transit gateway attachment %s to route table %s: %w", attachmentID, routeTableID, err)
+	}
+	l.Info("Enabled transit gateway route table propagation", "route table", routeTableID, "attachment", attachmentID)
+	return nil
+}
+
+// ensureTransitGatewayRoutes routes TransitGatewayRoutes from the route table with
+// the given name to the transit gateway.
+func (o *CreateInfraOptions) ensureTransitGatewayRoutes(ctx context.Context, l logr.Logger, client ec2iface.EC2API, tableName string) error {
+	routeTable, err := o.existingRouteTable(ctx, l, client, tableName)
+	if err != nil {
+		return err
+	}
+	if routeTable == nil && !o.DryRun {
+		return fmt.Errorf("route table %s not found", tableName)
+	}
+	for _, cidr := range o.TransitGatewayRoutes {
+		_, network, _ := net.ParseCIDR(cidr)
+		destination := network.String()
+		if routeTable == nil {
+			o.planModify(l, "route-table", tableName, fmt.Sprintf("add route %s to transit gateway %s", destination, o.TransitGatewayID))
+			continue
+		}
+		tableID := aws.StringValue(routeTable.RouteTableId)
+		if hasTransitGatewayRoute(routeTable, o.TransitGatewayID, destination) {
+			l.Info("Found existing route to transit gateway", "route table", tableID, "destination", destination)
+			continue
+		}
+		if o.DryRun {
+			o.planModify(l, "route-table", tableID, fmt.Sprintf("add route %s to transit gateway %s", destination, o.TransitGatewayID))
+			continue
+		}
+		if _, err := client.CreateRouteWithContext(ctx, &ec2.CreateRouteInput{
+			RouteTableId:         aws.String(tableID),
+			TransitGatewayId:     aws.String(o.TransitGatewayID),
+			DestinationCidrBlock: aws.String(destination),
+		}); err != nil {
+			return fmt.Errorf("cannot create route %s to transit gateway in route table %s: %w", destination, tableID, err)
+		}
+		l.Info("Created route to transit gateway", "route table", tableID, "destination", destination, "transit gateway", o.TransitGatewayID)
+	}
+	return nil
+}
+
+func hasTransitGatewayRoute(table *ec2.RouteTable, transitGatewayID, destination string) bool {
+	for _, route := range table.Routes {
+		if aws.StringValue(route.TransitGatewayId) == transitGatewayID &&
+			aws.StringValue(route.DestinationCidrBlock) == destination {
+			return true
+		}
+	}
+	return false
+}
+
+// DestroyTransitGatewayAttachments deletes the attachments of the VPC to transit
+// gateways, whose network interfaces keep the subnets from being deleted. The
+// associations and propagations of the attachments are removed with them.
+func (o *DestroyInfraOptions) DestroyTransitGatewayAttachments(ctx context.Context, client ec2iface.EC2API, vpcID *string) []error {
+	var errs []error
+	deleteAttachments := func(out *ec2.DescribeTransitGatewayVpcAttachmentsOutput, _ bool) bool {
+		for _, attachment := range out.TransitGatewayVpcAttachments {
+			id := aws.StringValue(attachment.TransitGatewayAttachmentId)
+			switch aws.StringValue(attachment.State) {
+			case ec2.TransitGatewayAttachmentStateDeleted, ec2.TransitGatewayAttachmentStateRejected, ec2.TransitGatewayAttachmentStateFailed:
+				continue
+			case ec2.TransitGatewayAttachmentStateDeleting:
+				errs = append(errs, fmt.Errorf("transit gateway attachment %s still deleting", id))
+				continue
+			}
+			_, err := client.DeleteTransitGatewayVpcAttachmentWithContext(ctx, &ec2.DeleteTransitGatewayVpcAttachmentInput{
+				TransitGatewayAttachmentId: attachment.TransitGatewayAttachmentId,
+			})
+			if err != nil {
+				errs = append(errs, err)
+			} else {
+				errs = append(errs, fmt.Errorf("deleting transit gateway attachment %s", id))
+			}
+		}
+		return true
+	}
+	err := client.DescribeTransitGatewayVpcAttachmentsPagesWithContext(ctx,
+		&ec2.DescribeTransitGatewayVpcAttachmentsInput{Filters: vpcFilter(vpcID)},
+		deleteAttachments)
+	if err != nil {
+		errs = append(errs, err)
+	}
+	return errs
+}
@@ -0,0 +1,198 @@
+package aws
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/go-logr/logr"
+	. "github.com/onsi/gomega"
+)
+
+type fakeTransitGatewayClient struct {
+	ec2iface.EC2API
+	attachment    *ec2.TransitGatewayVpcAttachment
+	association   *ec2.TransitGatewayAttachmentAssociation
+	propagated    bool
+	routeTable    *ec2.RouteTable
+	disassociated []string
+	routes        []*ec2.CreateRouteInput
+}
+
+func (f *fakeTransitGatewayClient) DescribeTransitGatewaysWithContext(_ aws.Context, in *ec2.DescribeTransitGatewaysInput, _ ...request.Option) (*ec2.DescribeTransitGatewaysOutput, error) {
+	return &ec2.DescribeTransitGatewaysOutput{TransitGateways: []*ec2.TransitGateway{{
+		TransitGatewayId: in.TransitGatewayIds[0],
+		State:            aws.String(ec2.TransitGatewayStateAvailable),
+		Options: &ec2.TransitGatewayOptions{
+			DefaultRouteTableAssociation:   aws.String(ec2.DefaultRouteTableAssociationValueEnable),
+			AssociationDefaultRouteTableId: aws.String("tgw-rtb-default"),
+		},
+	}}}, nil
+}
+
+func (f *fakeTransitGatewayClient) DescribeTransitGatewayVpcAttachmentsPagesWithContext(_ aws.Context, _ *ec2.DescribeTransitGatewayVpcAttachmentsInput, fn func(*ec2.DescribeTransitGatewayVpcAttachmentsOutput, bool) bool, _ ...request.Option) error {
+	output := &ec2.DescribeTransitGatewayVpcAttachmentsOutput{}
+	if f.attachment != nil {
+		output.TransitGatewayVpcAttachments = []*ec2.TransitGatewayVpcAttachment{f.attachment}
+	}
+	fn(output, true)
+	return nil
+}
+
+func (f *fakeTransitGatewayClient) CreateTransitGatewayVpcAttachmentWithContext(_ aws.Context, in *ec2.CreateTransitGatewayVpcAttachmentInput, _ ...request.Option) (*ec2.CreateTransitGatewayVpcAttachmentOutput, error) {
+	f.attachment = &ec2.TransitGatewayVpcAttachment{
+		TransitGatewayAttachmentId: aws.String("tgw-attach-1"),
+		SubnetIds:                  in.SubnetIds,
+		State:                      aws.String(ec2.TransitGatewayAttachmentStateAvailable),
+	}
+	// Transit gateways with default route table association associate new attachments
+	f.association = &ec2.TransitGatewayAttachmentAssociation{TransitGatewayRouteTableId: aws.String("tgw-rtb-default"), State: aws.String(ec2.TransitGatewayAssociationStateAssociated)}
+	return &ec2.CreateTransitGatewayVpcAttachmentOutput{TransitGatewayVpcAttachment: f.attachment}, nil
+}
+
+func (f *fakeTransitGatewayClient) DescribeTransitGatewayVpcAttachmentsWithContext(_ aws.Context, _ *ec2.DescribeTransitGatewayVpcAttachmentsInput, _ ...request.Option) (*ec2.DescribeTransitGatewayVpcAttachmentsOutput, error) {
+	return &ec2.DescribeTransitGatewayVpcAttachmentsOutput{TransitGatewayVpcAttachments: []*ec2.TransitGatewayVpcAttachment{f.attachment}}, nil
+}
+
+func (f *fakeTransitGatewayClient) DescribeTransitGatewayAttachmentsWithContext(_ aws.Context, _ *ec2.DescribeTransitGatewayAttachmentsInput, _ ...request.Option) (*ec2.DescribeTransitGatewayAttachmentsOutput, error) {
+	return &ec2.DescribeTransitGatewayAttachmentsOutput{TransitGatewayAttachments: []*ec2.TransitGatewayAttachment{{
+		TransitGatewayAttachmentId: f.attachment.TransitGatewayAttachmentId,
+		Association:                f.association,
+	}}}, nil
+}
+
+func (f *fakeTransitGatewayClient) DisassociateTransitGatewayRouteTableWithContext(_ aws.Context, in *ec2.DisassociateTransitGatewayRouteTableInput, _ ...request.Option) (*ec2.DisassociateTransitGatewayRouteTableOutput, error) {
+	f.disassociated = append(f.disassociated, aws.StringValue(in.TransitGatewayRouteTableId))
+	f.association = nil
+	return &ec2.DisassociateTransitGatewayRouteTableOutput{}, nil
+}
+
+func (f *fakeTransitGatewayClient) AssociateTransitGatewayRouteTableWithContext(_ aws.Context, in *ec2.AssociateTransitGatewayRouteTableInput, _ ...request.Option) (*ec2.AssociateTransitGatewayRouteTableOutput, error) {
+	f.association = &ec2.TransitGatewayAttachmentAssociation{TransitGatewayRouteTableId: in.TransitGatewayRouteTableId}
+	return &ec2.AssociateTransitGatewayRouteTableOutput{}, nil
+}
+
+func (f *fakeTransitGatewayClient) GetTransitGatewayRouteTablePropagationsPagesWithContext(_ aws.Context, _ *ec2.GetTransitGatewayRouteTablePropagationsInput, fn func(*ec2.GetTransitGatewayRouteTablePropagationsOutput, bool) bool, _ ...request.Option) error {
+	output := &ec2.GetTransitGatewayRouteTablePropagationsOutput{}
+	if f.propagated {
+		output.TransitGatewayRouteTablePropagations = []*ec2.TransitGatewayRouteTablePropagation{{State: aws.String(ec2.TransitGatewayPropagationStateEnabled)}}
+	}
+	fn(output, true)
+	return nil
+}
+
+func (f *fakeTransitGatewayClient) EnableTransitGatewayRouteTablePropagationWithContext(_ aws.Context, _ *ec2.EnableTransitGatewayRouteTablePropagationInput, _ ...request.Option) (*ec2.EnableTransitGatewayRouteTablePropagationOutput, error) {
+	f.propagated = true
+	return &ec2.EnableTransitGatewayRouteTablePropagationOutput{}, nil
+}
+
+func (f *fakeTransitGatewayClient) DescribeRouteTablesPagesWithContext(_ aws.Context, _ *ec2.DescribeRouteTablesInput, fn func(*ec2.DescribeRouteTablesOutput, bool) bool, _ ...request.Option) error {
+	fn(&ec2.DescribeRouteTablesOutput{RouteTables: []*ec2.RouteTable{f.routeTable}}, true)
+	return nil
+}
+
+func (f *fakeTransitGatewayClient) CreateRouteWithContext(_ aws.Context, in *ec2.CreateRouteInput, _ ...request.Option) (*ec2.CreateRouteOutput, error) {
+	f.routes = append(f.routes, in)
+	f.routeTable.Routes = append(f.routeTable.Routes, &ec2.Route{DestinationCidrBlock: in.DestinationCidrBlock, TransitGatewayId: in.TransitGatewayId})
+	return &ec2.CreateRouteOutput{}, nil
+}
+
+func TestValidateTransitGatewayOptions(t *testing.T) {
+	testCases := []struct {
+		name        string
+		options     CreateInfraOptions
+		expectError bool
+	}{
+		{
+			name: "no transit gateway",
+		},
+		{
+			name:    "transit gateway with route table and routes",
+			options: CreateInfraOptions{TransitGatewayID: "tgw-1", TransitGatewayRouteTableID: "tgw-rtb-1", TransitGatewayRoutes: []string{"192.168.0.0/16", "10.0.0.0/8"}},
+		},
+		{
+			name:        "route table without transit gateway",
+			options:     CreateInfraOptions{TransitGatewayRouteTableID: "tgw-rtb-1"},
+			expectError: true,
+		},
+		{
+			name:        "routes without transit gateway",
+			options:     CreateInfraOptions{TransitGatewayRoutes: []string{"192.168.0.0/16"}},
+			expectError: true,
+		},
+		{
+			name:        "invalid transit gateway",
+			options:     CreateInfraOptions{TransitGatewayID: "vpc-1"},
+			expectError: true,
+		},
+		{
+			name:        "existing VPC",
+			options:     CreateInfraOptions{TransitGatewayID: "tgw-1", VPCID: "vpc-1"},
+			expectError: true,
+		},
+		{
+			name:        "invalid route",
+			options:     CreateInfraOptions{TransitGatewayID: "tgw-1", TransitGatewayRoutes: []string{"192.168.0.0"}},
+			expectError: true,
+		},
+		{
+			name:        "route within the VPC",
+			options:     CreateInfraOptions{TransitGatewayID: "tgw-1", TransitGatewayRoutes: []string{"10.0.1.0/24"}},
+			expectError: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewGomegaWithT(t)
+			err := tc.options.validateTransitGatewayOptions()
+			if tc.expectError {
+				g.Expect(err).To(HaveOccurred())
+			} else {
+				g.Expect(err).ToNot(HaveOccurred())
+			}
+		})
+	}
+}
+
+func TestCreateTransitGatewayResources(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	client := &fakeTransitGatewayClient{routeTable: &ec2.RouteTable{RouteTableId: aws.String("rtb-1")}}
+	o := &CreateInfraOptions{
+		InfraID:                    "test",
+		TransitGatewayID:           "tgw-1",
+		TransitGatewayRouteTableID: "tgw-rtb-1",
+		TransitGatewayRoutes:       []string{"192.168.0.0/16"},
+	}
+	result := &CreateInfraOutput{VPCID: "vpc-1", Zones: []*CreateInfraOutputZone{{Name: "us-east-1a", SubnetID: "subnet-a"}}}
+	g.Expect(o.createTransitGatewayResources(context.Background(), logr.Discard(), client, result)).To(Succeed())
+	g.Expect(aws.StringValueSlice(client.attachment.SubnetIds)).To(Equal([]string{"subnet-a"}))
+	g.Expect(client.disassociated).To(Equal([]string{"tgw-rtb-default"}))
+	g.Expect(aws.StringValue(client.association.TransitGatewayRouteTableId)).To(Equal("tgw-rtb-1"))
+	g.Expect(client.propagated).To(BeTrue())
+	g.Expect(client.routes).To(HaveLen(1))
+	g.Expect(aws.StringValue(client.routes[0].TransitGatewayId)).To(Equal("tgw-1"))
+	g.Expect(aws.StringValue(client.routes[0].DestinationCidrBlock)).To(Equal("192.168.0.0/16"))
+	g.Expect(result.TransitGateway).To(Equal(&CreateInfraOutputTransitGateway{
+		ID:           "tgw-1",
+		AttachmentID: "tgw-attach-1",
+		RouteTableID: "tgw-rtb-1",
+		Routes:       []string{"192.168.0.0/16"},
+	}))
+
+	// A second run changes nothing
+	g.Expect(o.createTransitGatewayResources(context.Background(), logr.Discard(), client, result)).To(Succeed())
+	g.Expect(client.disassociated).To(HaveLen(1))
+	g.Expect(client.routes).To(HaveLen(1))
+
+	// Without a route table, the defaults of the transit gateway are used
+	client = &fakeTransitGatewayClient{routeTable: &ec2.RouteTable{RouteTableId: aws.String("rtb-1")}}
+	o.TransitGatewayRouteTableID = ""
+	g.Expect(o.createTransitGatewayResources(context.Background(), logr.Discard(), client, result)).To(Succeed())
+	g.Expect(client.disassociated).To(BeEmpty())
+	g.Expect(client.propagated).To(BeFalse())
+	g.Expect(result.TransitGateway.RouteTableID).To(Equal("tgw-rtb-default"))
+}
@@ -0,0 +1,168 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/go-logr/logr"
+)
+
+// interfaceEndpointServices are the AWS services that a cluster without internet
+// access needs to reach through interface endpoints. S3, which also serves the
+// image layers of ECR, is reached through the gateway endpoint of the VPC.
+var interfaceEndpointServices = []string{"ec2", "elasticloadbalancing", "sts", "ecr.api", "ecr.dkr"}
+
+// validateVPCEndpointsOptions validates the flags for the interface endpoints.
+// Their security group could not be deleted with an existing VPC, whose security
+// groups are left alone on destroy.
+func (o *CreateInfraOptions) validateVPCEndpointsOptions() error {
+	if o.CreateVPCEndpoints && len(o.VPCID) > 0 {
+		return errors.New("--create-vpc-endpoints is not supported with an existing VPC")
+	}
+	return nil
+}
+
+// createVPCEndpointResources creates interface endpoints with private DNS for the
+// interfaceEndpointServices in the private subnets, so that the AWS APIs resolve
+// to addresses in the VPC and can be reached without NAT gateways or a proxy. The
+// IDs of the interface endpoints and the S3 gateway endpoint are written to the
+// output by service.
+func (o *CreateInfraOptions) createVPCEndpointResources(ctx context.Context, l logr.Logger, client ec2iface.EC2API, result *CreateInfraOutput) error {
+	securityGroupID, err := o.ensureVPCEndpointSecurityGroup(ctx, l, client, result.VPCID)
+	if err != nil {
+		return err
+	}
+	var subnetIDs []string
+	for _, zone := range result.Zones {
+		subnetIDs = append(subnetIDs, zone.SubnetID)
+	}
+	result.VPCEndpoints = map[string]string{}
+	for _, service := range interfaceEndpointServices {
+		endpointID, err := o.ensureInterfaceEndpoint(ctx, l, client, result.VPCID, service, securityGroupID, subnetIDs)
+		if err != nil {
+			return err
+		}
+		result.VPCEndpoints[service] = endpointID
+	}
+	if !isPlannedID(result.VPCID) {
+		s3EndpointID, err := o.existingVPCS3EndpointInVPC(ctx, client, result.VPCID)
+		if err != nil {
+			return err
+		}
+		if len(s3EndpointID) > 0 {
+			result.VPCEndpoints["s3"] = s3EndpointID
+		}
+	}
+	return nil
+}
+
+// ensureInterfaceEndpoint creates an interface endpoint with private DNS for the
+// service in the given subnets if it does not exist yet, and returns its ID.
+func (o *CreateInfraOptions) ensureInterfaceEndpoint(ctx context.Context, l logr.Logger, client ec2iface.EC2API, vpcID, service, securityGroupID string, subnetIDs []string) (string, error) {
+	endpointName := fmt.Sprintf("%s-vpce-%s", o.InfraID, service)
+	serviceName := vpcEndpointServiceName(o.Region, service)
+	var endpointID string
+	if !isPlannedID(vpcID) {
+		filters := append(o.ec2Filters(endpointName), &ec2.Filter{Name: aws.String("vpc-endpoint-state"), Values: aws.StringSlice([]string{"pendingAcceptance", "pending", "available"})})
+		err := client.DescribeVpcEndpointsPagesWithContext(ctx, &ec2.DescribeVpcEndpointsInput{Filters: filters}, func(out *ec2.DescribeVpcEndpointsOutput, _ bool) bool {
+			for _, endpoint := range out.VpcEndpoints {
+				endpointID = aws.StringValue(endpoint.VpcEndpointId)
+				return false
+			}
+			return true
+		})
+		if err != nil {
+			return "", fmt.Errorf("cannot list vpc endpoints: %w", err)
+		}
+	}
+	if len(endpointID) > 0 {
+		l.Info("Found existing vpc endpoint", "id", endpointID, "service", serviceName)
+		return endpointID, nil
+	}
+	if o.DryRun {
+		return o.planCreate(l, "vpc-endpoint", endpointName), nil
+	}
+	createResult, err := client.CreateVpcEndpointWithContext(ctx, &ec2.CreateVpcEndpointInput{
+		VpcId:             aws.String(vpcID),
+		ServiceName:       aws.String(serviceName),
+		VpcEndpointType:   aws.String(ec2.VpcEndpointTypeInterface),
+		SubnetIds:         aws.StringSlice(subnetIDs),
+		SecurityGroupIds:  []*string{aws.String(securityGroupID)},
+		PrivateDnsEnabled: aws.Bool(true),
+		TagSpecifications: o.ec2TagSpecifications("vpc-endpoint", endpointName),
+	})
+	if err != nil {
+		return "", fmt.Errorf("cannot create vpc endpoint for %s: %w", serviceName, err)
+	}
+	endpointID = aws.StringValue(createResult.VpcEndpoint.VpcEndpointId)
+	l.Info("Created vpc endpoint", "id", endpointID, "service", serviceName)
+	return endpointID, nil
+}
+
+// ensureVPCEndpointSecurityGroup creates the security group of the interface
+// endpoints and authorizes HTTPS from the VPC CIDRs.
+func (o *CreateInfraOptions) ensureVPCEndpointSecurityGroup(ctx context.Context, l logr.Logger, client ec2iface.EC2API, vpcID string) (string, error) {
+	groupName := fmt.Sprintf("%s-vpce-sg", o.InfraID)
+	securityGroup, err := o.existingSecurityGroup(ctx, client, groupName)
+	if err != nil {
+		return "", err
+	}
+	if securityGroup == nil && o.DryRun {
+		return o.planCreate(l, "security-group", groupName), nil
+	}
+	if securityGroup == nil {
+		result, err := client.CreateSecurityGroupWithContext(ctx, &ec2.CreateSecurityGroupInput{
+			GroupName:         aws.String(groupName),
+			Description:       aws.String("vpc endpoint security group"),
+			VpcId:             aws.String(vpcID),
+			TagSpecifications: o.ec2TagSpecifications("security-group", groupName),
+		})
+		if err != nil {
+			return "", fmt.Errorf("cannot create vpc endpoint security group: %w", err)
+		}
+		securityGroup = &ec2.SecurityGroup{GroupId: result.GroupId}
+		l.Info("Created security group", "name", groupName, "id", aws.StringValue(securityGroup.GroupId))
+	} else {
+		l.Info("Found existing security group", "name", groupName, "id", aws.StringValue(securityGroup.GroupId))
+	}
+	securityGroupID := aws.StringValue(securityGroup.GroupId)
+
+	var ingressToAuthorize []*ec2.IpPermission
+	for _, cidr := range o.machineCIDRs() {
+		permission := &ec2.IpPermission{
+			IpProtocol: aws.String("tcp"),
+			IpRanges:   []*ec2.IpRange{{CidrIp: aws.String(cidr)}},
+			FromPort:   aws.Int64(443),
+			ToPort:     aws.Int64(443),
+		}
+		if !includesPermission(securityGroup.IpPermissions, permission) {
+			ingressToAuthorize = append(ingressToAuthorize, permission)
+		}
+	}
+	if len(ingressToAuthorize) == 0 {
+		return securityGroupID, nil
+	}
+	if o.DryRun {
+		o.planModify(l, "security-group", securityGroupID, fmt.Sprintf("authorize %d ingress rules", len(ingressToAuthorize)))
+		return securityGroupID, nil
+	}
+	// The security group may not be visible yet right after it was created
+	err = retryOnError(ctx, ec2Backoff(), isNotFoundYet, func() error {
+		_, err := client.AuthorizeSecurityGroupIngressWithContext(ctx, &ec2.AuthorizeSecurityGroupIngressInput{
+			GroupId:       aws.String(securityGroupID),
+			IpPermissions: ingressToAuthorize,
+		})
+		return err
+	})
+	var awsErr awserr.Error
+	if err != nil && (!errors.As(err, &awsErr) || awsErr.Code() != duplicatePermissionErrorCode) {
+		return "", fmt.Errorf("cannot apply vpc endpoint security group ingress permissions: %w", err)
+	}
+	l.Info("Authorized ingress rules on security group", "id", securityGroupID)
+	return securityGroupID, nil
+}
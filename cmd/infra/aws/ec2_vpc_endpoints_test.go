@@ -0,0 +1,145 @@
+package aws
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/go-logr/logr"
+	. "github.com/onsi/gomega"
+)
+
+type fakeVPCEndpointsClient struct {
+	ec2iface.EC2API
+	endpoints  []*ec2.VpcEndpoint
+	created    []*ec2.CreateVpcEndpointInput
+	authorized []*ec2.IpPermission
+	deleted    []string
+}
+
+func (f *fakeVPCEndpointsClient) DescribeSecurityGroupsPagesWithContext(_ aws.Context, _ *ec2.DescribeSecurityGroupsInput, fn func(*ec2.DescribeSecurityGroupsOutput, bool) bool, _ ...request.Option) error {
+	fn(&ec2.DescribeSecurityGroupsOutput{}, true)
+	return nil
+}
+
+func (f *fakeVPCEndpointsClient) CreateSecurityGroupWithContext(_ aws.Context, _ *ec2.CreateSecurityGroupInput, _ ...request.Option) (*ec2.CreateSecurityGroupOutput, error) {
+	return &ec2.CreateSecurityGroupOutput{GroupId: aws.String("sg-vpce")}, nil
+}
+
+func (f *fakeVPCEndpointsClient) AuthorizeSecurityGroupIngressWithContext(_ aws.Context, in *ec2.AuthorizeSecurityGroupIngressInput, _ ...request.Option) (*ec2.AuthorizeSecurityGroupIngressOutput, error) {
+	f.authorized = append(f.authorized, in.IpPermissions...)
+	return &ec2.AuthorizeSecurityGroupIngressOutput{}, nil
+}
+
+// DescribeVpcEndpointsPagesWithContext matches the endpoints by the Name tag and
+// service name filters only.
+func (f *fakeVPCEndpointsClient) DescribeVpcEndpointsPagesWithContext(_ aws.Context, in *ec2.DescribeVpcEndpointsInput, fn func(*ec2.DescribeVpcEndpointsOutput, bool) bool, _ ...request.Option) error {
+	var matching []*ec2.VpcEndpoint
+	for _, endpoint := range f.endpoints {
+		matches := true
+		for _, filter := range in.Filters {
+			switch aws.StringValue(filter.Name) {
+			case "tag:Name":
+				matches = matches && hasTag(endpoint.Tags, "Name", aws.StringValue(filter.Values[0]))
+			case "service-name":
+				matches = matches && aws.StringValue(endpoint.ServiceName) == aws.StringValue(filter.Values[0])
+			}
+		}
+		if matches {
+			matching = append(matching, endpoint)
+		}
+	}
+	fn(&ec2.DescribeVpcEndpointsOutput{VpcEndpoints: matching}, true)
+	return nil
+}
+
+func (f *fakeVPCEndpointsClient) CreateVpcEndpointWithContext(_ aws.Context, in *ec2.CreateVpcEndpointInput, _ ...request.Option) (*ec2.CreateVpcEndpointOutput, error) {
+	f.created = append(f.created, in)
+	return &ec2.CreateVpcEndpointOutput{VpcEndpoint: &ec2.VpcEndpoint{VpcEndpointId: aws.String("vpce-" + aws.StringValue(in.ServiceName))}}, nil
+}
+
+func (f *fakeVPCEndpointsClient) DeleteVpcEndpointsWithContext(_ aws.Context, in *ec2.DeleteVpcEndpointsInput, _ ...request.Option) (*ec2.DeleteVpcEndpointsOutput, error) {
+	f.deleted = append(f.deleted, aws.StringValueSlice(in.VpcEndpointIds)...)
+	return &ec2.DeleteVpcEndpointsOutput{}, nil
+}
+
+func hasTag(tags []*ec2.Tag, key, value string) bool {
+	for _, tag := range tags {
+		if aws.StringValue(tag.Key) == key && aws.StringValue(tag.Value) == value {
+			return true
+		}
+	}
+	return false
+}
+
+func TestValidateVPCEndpointsOptions(t *testing.T) {
+	g := NewGomegaWithT(t)
+	g.Expect((&CreateInfraOptions{CreateVPCEndpoints: true}).validateVPCEndpointsOptions()).To(Succeed())
+	g.Expect((&CreateInfraOptions{VPCID: "vpc-1"}).validateVPCEndpointsOptions()).To(Succeed())
+	g.Expect((&CreateInfraOptions{CreateVPCEndpoints: true, VPCID: "vpc-1"}).validateVPCEndpointsOptions()).ToNot(Succeed())
+}
+
+func TestCreateVPCEndpointResources(t *testing.T) {
+	g := NewGomegaWithT(t)
+	client := &fakeVPCEndpointsClient{endpoints: []*ec2.VpcEndpoint{
+		{VpcEndpointId: aws.String("vpce-s3"), ServiceName: aws.String("com.amazonaws.us-east-1.s3")},
+		{VpcEndpointId: aws.String("vpce-existing"), ServiceName: aws.String("com.amazonaws.us-east-1.sts"), Tags: []*ec2.Tag{{Key: aws.String("Name"), Value: aws.String("test-vpce-sts")}}},
+	}}
+	o := &CreateInfraOptions{InfraID: "test", Region: "us-east-1", CreateVPCEndpoints: true}
+	result := &CreateInfraOutput{VPCID: "vpc-1", Zones: []*CreateInfraOutputZone{
+		{Name: "us-east-1a", SubnetID: "subnet-a"},
+		{Name: "us-east-1b", SubnetID: "subnet-b"},
+	}}
+	err := o.createVPCEndpointResources(context.Background(), logr.Discard(), client, result)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(result.VPCEndpoints).To(Equal(map[string]string{
+		"ec2":                  "vpce-com.amazonaws.us-east-1.ec2",
+		"elasticloadbalancing": "vpce-com.amazonaws.us-east-1.elasticloadbalancing",
+		"sts":                  "vpce-existing",
+		"ecr.api":              "vpce-com.amazonaws.us-east-1.ecr.api",
+		"ecr.dkr":              "vpce-com.amazonaws.us-east-1.ecr.dkr",
+		"s3":                   "vpce-s3",
+	}))
+	g.Expect(client.created).To(HaveLen(4))
+	for _, input := range client.created {
+		g.Expect(aws.StringValue(input.VpcEndpointType)).To(Equal(ec2.VpcEndpointTypeInterface))
+		g.Expect(aws.BoolValue(input.PrivateDnsEnabled)).To(BeTrue())
+		g.Expect(aws.StringValueSlice(input.SubnetIds)).To(ConsistOf("subnet-a", "subnet-b"))
+		g.Expect(aws.StringValueSlice(input.SecurityGroupIds)).To(ConsistOf("sg-vpce"))
+	}
+	g.Expect(client.authorized).To(HaveLen(1))
+	g.Expect(aws.Int64Value(client.authorized[0].FromPort)).To(Equal(int64(443)))
+	g.Expect(aws.StringValue(client.authorized[0].IpRanges[0].CidrIp)).To(Equal(DefaultCIDRBlock))
+}
+
+func TestCreateVPCEndpointResourcesDryRun(t *testing.T) {
+	g := NewGomegaWithT(t)
+	client := &fakeVPCEndpointsClient{}
+	o := &CreateInfraOptions{InfraID: "test", Region: "us-east-1", CreateVPCEndpoints: true, DryRun: true}
+	result := &CreateInfraOutput{VPCID: "vpc-1", Zones: []*CreateInfraOutputZone{{Name: "us-east-1a", SubnetID: "subnet-a"}}}
+	err := o.createVPCEndpointResources(context.Background(), logr.Discard(), client, result)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(client.created).To(BeEmpty())
+	g.Expect(result.VPCEndpoints).To(HaveLen(len(interfaceEndpointServices)))
+	for _, id := range result.VPCEndpoints {
+		g.Expect(isPlannedID(id)).To(BeTrue())
+	}
+}
+
+func TestDestroyVPCEndpoints(t *testing.T) {
+	g := NewGomegaWithT(t)
+	client := &fakeVPCEndpointsClient{endpoints: []*ec2.VpcEndpoint{
+		{VpcEndpointId: aws.String("vpce-gateway"), VpcEndpointType: aws.String(ec2.VpcEndpointTypeGateway), State: aws.String("available")},
+		{VpcEndpointId: aws.String("vpce-interface"), VpcEndpointType: aws.String(ec2.VpcEndpointTypeInterface), State: aws.String("available")},
+		{VpcEndpointId: aws.String("vpce-deleting"), VpcEndpointType: aws.String(ec2.VpcEndpointTypeInterface), State: aws.String("deleting")},
+		{VpcEndpointId: aws.String("vpce-deleted"), VpcEndpointType: aws.String(ec2.VpcEndpointTypeInterface), State: aws.String("deleted")},
+	}}
+	o := &DestroyInfraOptions{InfraID: "test", Log: logr.Discard()}
+	errs := o.DestroyVPCEndpoints(context.Background(), client, aws.String("vpc-1"))
+	g.Expect(client.deleted).To(ConsistOf("vpce-gateway", "vpce-interface"))
+	// The deleted and the deleting interface endpoints keep the subnets in use
+	g.Expect(errs).To(HaveLen(2))
+}
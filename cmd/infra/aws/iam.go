@@ -3,14 +3,17 @@ package aws
 import (
 	"bytes"
 	"fmt"
+	"regexp"
 	"strings"
 	"text/template"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/arn"
 	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/service/iam"
 	"github.com/aws/aws-sdk-go/service/iam/iamiface"
 	jose "gopkg.in/square/go-jose.v2"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 
 	"github.com/openshift/hypershift/cmd/log"
 )
@@ -212,6 +215,39 @@ const (
       ],
       "Effect": "Allow"
     },
+    {
+      "Condition": {
+        "StringLike": {
+          "iam:AWSServiceName": "spot.amazonaws.com"
+        }
+      },
+      "Action": [
+        "iam:CreateServiceLinkedRole"
+      ],
+      "Resource": [
+        "arn:*:iam::*:role/aws-service-role/spot.amazonaws.com/AWSServiceRoleForEC2Spot"
+      ],
+      "Effect": "Allow"
+    },
+    {
+      "Condition": {
+        "StringLike": {
+          "kms:ViaService": "ec2.*.amazonaws.com"
+        }
+      },
+      "Action": [
+        "kms:CreateGrant",
+        "kms:Decrypt",
+        "kms:DescribeKey",
+        "kms:Encrypt",
+        "kms:GenerateDataKey*",
+        "kms:ReEncrypt*"
+      ],
+      "Resource": [
+        "*"
+      ],
+      "Effect": "Allow"
+    },
     {
       "Action": [
         "iam:PassRole"
@@ -244,9 +280,64 @@ const (
 		}
 	]
 }`
+
+	workerInstancePolicy = `{
+  "Version": "2012-10-17",
+  "Statement": [
+    {
+      "Effect": "Allow",
+      "Action": [
+        "ec2:DescribeInstances",
+        "ec2:DescribeRegions"
+      ],
+      "Resource": "*"
+    }
+  ]
+}`
+
+	// workerIMDSv2Policy denies all actions with credentials of the worker role
+	// that were retrieved from the instance metadata service with IMDSv1.
+	workerIMDSv2Policy = `{
+  "Version": "2012-10-17",
+  "Statement": [
+    {
+      "Effect": "Deny",
+      "Action": "*",
+      "Resource": "*",
+      "Condition": {
+        "NumericLessThan": {
+          "ec2:RoleDelivery": "2.0"
+        }
+      }
+    }
+  ]
+}`
+
+	// s3OIDCThumbprint is the root CA thumbprint for s3 (DigiCert). The AWS console
+	// mentions that this will be ignored for S3 buckets but creation fails if we
+	// don't pass a thumbprint.
+	s3OIDCThumbprint = "A9D53002E97E00E043244F3D170D6F4C414104FD"
 )
 
-func ingressPermPolicy(publicZone, privateZone string) string {
+// workerAssumeRolePolicy returns the trust policy of the worker role, which allows
+// EC2 instances in the partition of the region to assume it.
+func workerAssumeRolePolicy(region string) string {
+	return fmt.Sprintf(`{
+    "Version": "2012-10-17",
+    "Statement": [
+        {
+            "Action": "sts:AssumeRole",
+            "Principal": {
+                "Service": "%s"
+            },
+            "Effect": "Allow",
+            "Sid": ""
+        }
+    ]
+}`, servicePrincipal(region, "ec2"))
+}
+
+func ingressPermPolicy(partition, publicZone, privateZone string) string {
 	publicZone = ensureHostedZonePrefix(publicZone)
 	privateZone = ensureHostedZonePrefix(privateZone)
 	return fmt.Sprintf(`{
@@ -267,15 +358,15 @@ func ingressPermPolicy(publicZone, privateZone string) string {
 				"route53:ChangeResourceRecordSets"
 			],
 			"Resource": [
-				"arn:aws:route53:::%s",
-				"arn:aws:route53:::%s"
+				"arn:%[1]s:route53:::%[2]s",
+				"arn:%[1]s:route53:::%[3]s"
 			]
 		}
 	]
-}`, publicZone, privateZone)
+}`, partition, publicZone, privateZone)
 }
 
-func controlPlaneOperatorPolicy(hostedZone string) string {
+func controlPlaneOperatorPolicy(partition, hostedZone string) string {
 	hostedZone = ensureHostedZonePrefix(hostedZone)
 	return fmt.Sprintf(`{
 	"Version": "2012-10-17",
@@ -298,10 +389,10 @@ func controlPlaneOperatorPolicy(hostedZone string) string {
 				"route53:ChangeResourceRecordSets",
 				"route53:ListResourceRecordSets"
 			],
-			"Resource": "arn:aws:route53:::%s"
+			"Resource": "arn:%s:route53:::%s"
 		}
 	]
-}`, hostedZone)
+}`, partition, hostedZone)
 }
 
 func kmsProviderPolicy(kmsKeyARN string) string {
@@ -338,6 +429,130 @@ func DefaultProfileName(infraID string) string {
 	return infraID + "-worker"
 }
 
+// workerProfileName returns the name of the worker instance profile, which
+// defaults to DefaultProfileName.
+func workerProfileName(infraID, name string) string {
+	if len(name) > 0 {
+		return name
+	}
+	return DefaultProfileName(infraID)
+}
+
+// workerRoleName returns the name of the worker role, which defaults to the
+// name of the instance profile with a -role suffix.
+func workerRoleName(profileName, name string) string {
+	if len(name) > 0 {
+		return name
+	}
+	return fmt.Sprintf("%s-role", profileName)
+}
+
+// workerIMDSv2PolicyName returns the name of the inline policy of the worker
+// role that requires IMDSv2.
+func workerIMDSv2PolicyName(profileName string) string {
+	return fmt.Sprintf("%s-imdsv2", profileName)
+}
+
+// iamNamePattern matches the characters allowed in the names of IAM roles and
+// instance profiles.
+var iamNamePattern = regexp.MustCompile(`^[\w+=,.@-]+$`)
+
+// validateWorkerNames validates the names given for the worker instance profile
+// and role.
+func validateWorkerNames(profileName, roleName string) error {
+	var errs []error
+	if len(profileName) > 0 && (len(profileName) > 128 || !iamNamePattern.MatchString(profileName)) {
+		errs = append(errs, fmt.Errorf("invalid --instance-profile-name %q: it must be at most 128 alphanumeric or +=,.@_- characters long", profileName))
+	}
+	if len(roleName) > 0 && (len(roleName) > 64 || !iamNamePattern.MatchString(roleName)) {
+		errs = append(errs, fmt.Errorf("invalid --worker-role-name %q: it must be at most 64 alphanumeric or +=,.@_- characters long", roleName))
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// oidcRole describes an IAM role that is assumed through the OIDC provider by
+// the service accounts of a cluster component.
+type oidcRole struct {
+	name            string
+	serviceAccounts []string
+	permPolicy      string
+	setARN          func(output *CreateIAMOutput, arn string)
+}
+
+// oidcRoles returns the roles created for the OIDC provider.
+// TODO: The policies and secrets for these roles can be extracted from the
+// release payload, avoiding this current hardcoding.
+func (o *CreateIAMOptions) oidcRoles() []oidcRole {
+	partition := partitionID(o.Region)
+	imageRegistryPolicy := imageRegistryPermPolicy
+	storagePolicy := awsEBSCSIPermPolicy
+	nodePoolManagementPolicy := nodePoolPolicy
+	cpoPolicy := controlPlaneOperatorPolicy(partition, o.LocalZoneID)
+	if o.PolicyMode == PolicyModeStrict {
+		imageRegistryPolicy = strictImageRegistryPolicy(partition, o.InfraID)
+		storagePolicy = strictAWSEBSCSIPolicy(partition, o.Region, o.InfraID)
+		nodePoolManagementPolicy = strictNodePoolPolicy(partition, o.Region, o.InfraID, o.iamPath(), workerRoleName(o.profileName(), o.WorkerRoleName))
+		cpoPolicy = strictControlPlaneOperatorPolicy(partition, o.Region, o.InfraID, o.LocalZoneID)
+	}
+	roles := []oidcRole{
+		{
+			name:            "openshift-ingress",
+			serviceAccounts: []string{"system:serviceaccount:openshift-ingress-operator:ingress-operator"},
+			// The record changes are always scoped to the zones of the cluster, and
+			// the remaining actions do not support resource-level permissions
+			permPolicy: ingressPermPolicy(partition, o.PublicZoneID, o.PrivateZoneID),
+			setARN:     func(output *CreateIAMOutput, arn string) { output.Roles.IngressARN = arn },
+		},
+		{
+			name: "openshift-image-registry",
+			serviceAccounts: []string{
+				"system:serviceaccount:openshift-image-registry:cluster-image-registry-operator",
+				"system:serviceaccount:openshift-image-registry:registry",
+			},
+			permPolicy: imageRegistryPolicy,
+			setARN:     func(output *CreateIAMOutput, arn string) { output.Roles.ImageRegistryARN = arn },
+		},
+		{
+			name:            "aws-ebs-csi-driver-controller",
+			serviceAccounts: []string{"system:serviceaccount:openshift-cluster-csi-drivers:aws-ebs-csi-driver-controller-sa"},
+			permPolicy:      storagePolicy,
+			setARN:          func(output *CreateIAMOutput, arn string) { output.Roles.StorageARN = arn },
+		},
+		{
+			name:            "cloud-controller",
+			serviceAccounts: []string{"system:serviceaccount:kube-system:kube-controller-manager"},
+			permPolicy:      cloudControllerPolicy,
+			setARN:          func(output *CreateIAMOutput, arn string) { output.Roles.KubeCloudControllerARN = arn },
+		},
+		{
+			name:            "node-pool",
+			serviceAccounts: []string{"system:serviceaccount:kube-system:capa-controller-manager"},
+			permPolicy:      nodePoolManagementPolicy,
+			setARN:          func(output *CreateIAMOutput, arn string) { output.Roles.NodePoolManagementARN = arn },
+		},
+		{
+			name:            "control-plane-operator",
+			serviceAccounts: []string{"system:serviceaccount:kube-system:control-plane-operator"},
+			permPolicy:      cpoPolicy,
+			setARN:          func(output *CreateIAMOutput, arn string) { output.Roles.ControlPlaneOperatorARN = arn },
+		},
+	}
+	if len(o.KMSKeyARN) > 0 {
+		roles = append(roles, oidcRole{
+			name:            "kms-provider",
+			serviceAccounts: []string{"system:serviceaccount:kube-system:kms-provider"},
+			permPolicy:      kmsProviderPolicy(o.KMSKeyARN),
+			setARN:          func(output *CreateIAMOutput, arn string) { output.KMSProviderRoleARN = arn },
+		})
+	}
+	return append(roles, oidcRole{
+		name:            "cloud-network-config-controller",
+		serviceAccounts: []string{"system:serviceaccount:openshift-cloud-network-config-controller:cloud-network-config-controller"},
+		permPolicy:      cloudNetworkConfigControllerPolicy,
+		setARN:          func(output *CreateIAMOutput, arn string) { output.Roles.NetworkARN = arn },
+	})
+}
+
 // inputs: none
 // outputs rsa keypair
 func (o *CreateIAMOptions) CreateOIDCResources(iamClient iamiface.IAMAPI) (*CreateIAMOutput, error) {
@@ -372,10 +587,8 @@ func (o *CreateIAMOptions) CreateOIDCResources(iamClient iamiface.IAMAPI) (*Crea
 		ClientIDList: []*string{
 			aws.String("openshift"),
 		},
-		// The AWS console mentions that this will be ignored for S3 buckets but creation fails if we don't
-		// pass a thumbprint.
 		ThumbprintList: []*string{
-			aws.String("A9D53002E97E00E043244F3D170D6F4C414104FD"), // root CA thumbprint for s3 (DigiCert)
+			aws.String(s3OIDCThumbprint),
 		},
 		Url:  aws.String(o.IssuerURL),
 		Tags: o.additionalIAMTags,
@@ -387,68 +600,15 @@ func (o *CreateIAMOptions) CreateOIDCResources(iamClient iamiface.IAMAPI) (*Crea
 	providerARN := *oidcOutput.OpenIDConnectProviderArn
 	log.Log.Info("Created OIDC provider", "provider", providerARN)
 
-	// TODO: The policies and secrets for these roles can be extracted from the
-	// release payload, avoiding this current hardcoding.
-	ingressTrustPolicy := oidcTrustPolicy(providerARN, providerName, "system:serviceaccount:openshift-ingress-operator:ingress-operator")
-	arn, err := o.CreateOIDCRole(iamClient, "openshift-ingress", ingressTrustPolicy, ingressPermPolicy(o.PublicZoneID, o.PrivateZoneID))
-	if err != nil {
-		return nil, err
-	}
-	output.Roles.IngressARN = arn
-
-	registryTrustPolicy := oidcTrustPolicy(providerARN, providerName,
-		"system:serviceaccount:openshift-image-registry:cluster-image-registry-operator",
-		"system:serviceaccount:openshift-image-registry:registry")
-	arn, err = o.CreateOIDCRole(iamClient, "openshift-image-registry", registryTrustPolicy, imageRegistryPermPolicy)
-	if err != nil {
-		return nil, err
-	}
-	output.Roles.ImageRegistryARN = arn
-
-	csiTrustPolicy := oidcTrustPolicy(providerARN, providerName, "system:serviceaccount:openshift-cluster-csi-drivers:aws-ebs-csi-driver-controller-sa")
-	arn, err = o.CreateOIDCRole(iamClient, "aws-ebs-csi-driver-controller", csiTrustPolicy, awsEBSCSIPermPolicy)
-	if err != nil {
-		return nil, err
-	}
-	output.Roles.StorageARN = arn
-
-	kubeCloudControllerTrustPolicy := oidcTrustPolicy(providerARN, providerName, "system:serviceaccount:kube-system:kube-controller-manager")
-	arn, err = o.CreateOIDCRole(iamClient, "cloud-controller", kubeCloudControllerTrustPolicy, cloudControllerPolicy)
-	if err != nil {
-		return nil, err
-	}
-	output.Roles.KubeCloudControllerARN = arn
-
-	nodePoolManagementTrustPolicy := oidcTrustPolicy(providerARN, providerName, "system:serviceaccount:kube-system:capa-controller-manager")
-	arn, err = o.CreateOIDCRole(iamClient, "node-pool", nodePoolManagementTrustPolicy, nodePoolPolicy)
-	if err != nil {
-		return nil, err
-	}
-	output.Roles.NodePoolManagementARN = arn
-
-	controlPlaneOperatorTrustPolicy := oidcTrustPolicy(providerARN, providerName, "system:serviceaccount:kube-system:control-plane-operator")
-	arn, err = o.CreateOIDCRole(iamClient, "control-plane-operator", controlPlaneOperatorTrustPolicy, controlPlaneOperatorPolicy(o.LocalZoneID))
-	if err != nil {
-		return nil, err
-	}
-	output.Roles.ControlPlaneOperatorARN = arn
-
-	if len(o.KMSKeyARN) > 0 {
-		kmsProviderTrustPolicy := oidcTrustPolicy(providerARN, providerName, "system:serviceaccount:kube-system:kms-provider")
-		arn, err = o.CreateOIDCRole(iamClient, "kms-provider", kmsProviderTrustPolicy, kmsProviderPolicy(o.KMSKeyARN))
+	for _, role := range o.oidcRoles() {
+		trustPolicy := oidcTrustPolicy(providerARN, providerName, role.serviceAccounts...)
+		arn, err := o.CreateOIDCRole(iamClient, role.name, trustPolicy, role.permPolicy)
 		if err != nil {
 			return nil, err
 		}
-		output.KMSProviderRoleARN = arn
+		role.setARN(output, arn)
 	}
 
-	cloudNetworkConfigControllerTrustPolicy := oidcTrustPolicy(providerARN, providerName, "system:serviceaccount:openshift-cloud-network-config-controller:cloud-network-config-controller")
-	arn, err = o.CreateOIDCRole(iamClient, "cloud-network-config-controller", cloudNetworkConfigControllerTrustPolicy, cloudNetworkConfigControllerPolicy)
-	if err != nil {
-		return nil, err
-	}
-	output.Roles.NetworkARN = arn
-
 	return output, nil
 }
 
@@ -464,6 +624,8 @@ func (o *CreateIAMOptions) CreateOIDCRole(client iamiface.IAMAPI, name, trustPol
 		output, err := client.CreateRole(&iam.CreateRoleInput{
 			AssumeRolePolicyDocument: aws.String(trustPolicy),
 			RoleName:                 aws.String(roleName),
+			Path:                     aws.String(o.iamPath()),
+			PermissionsBoundary:      o.permissionsBoundary(),
 			Tags:                     o.additionalIAMTags,
 		})
 		if err != nil {
@@ -473,6 +635,9 @@ func (o *CreateIAMOptions) CreateOIDCRole(client iamiface.IAMAPI, name, trustPol
 		arn = *output.Role.Arn
 	} else {
 		log.Log.Info("Found existing role", "name", roleName)
+		if err := o.reconcileExistingRole(client, role); err != nil {
+			return "", err
+		}
 		arn = *role.Arn
 	}
 
@@ -497,43 +662,16 @@ func (o *CreateIAMOptions) CreateOIDCRole(client iamiface.IAMAPI, name, trustPol
 }
 
 func (o *CreateIAMOptions) CreateWorkerInstanceProfile(client iamiface.IAMAPI, profileName string) error {
-	const (
-		assumeRolePolicy = `{
-    "Version": "2012-10-17",
-    "Statement": [
-        {
-            "Action": "sts:AssumeRole",
-            "Principal": {
-                "Service": "ec2.amazonaws.com"
-            },
-            "Effect": "Allow",
-            "Sid": ""
-        }
-    ]
-}`
-		workerPolicy = `{
-  "Version": "2012-10-17",
-  "Statement": [
-    {
-      "Effect": "Allow",
-      "Action": [
-        "ec2:DescribeInstances",
-        "ec2:DescribeRegions"
-      ],
-      "Resource": "*"
-    }
-  ]
-}`
-	)
-	roleName := fmt.Sprintf("%s-role", profileName)
+	roleName := workerRoleName(profileName, o.WorkerRoleName)
 	role, err := existingRole(client, roleName)
 	if err != nil {
 		return err
 	}
 	if role == nil {
 		_, err := client.CreateRole(&iam.CreateRoleInput{
-			AssumeRolePolicyDocument: aws.String(assumeRolePolicy),
-			Path:                     aws.String("/"),
+			AssumeRolePolicyDocument: aws.String(workerAssumeRolePolicy(o.Region)),
+			Path:                     aws.String(o.iamPath()),
+			PermissionsBoundary:      o.permissionsBoundary(),
 			RoleName:                 aws.String(roleName),
 			Tags:                     o.additionalIAMTags,
 		})
@@ -543,6 +681,9 @@ func (o *CreateIAMOptions) CreateWorkerInstanceProfile(client iamiface.IAMAPI, p
 		log.Log.Info("Created role", "name", roleName)
 	} else {
 		log.Log.Info("Found existing role", "name", roleName)
+		if err := o.reconcileExistingRole(client, role); err != nil {
+			return err
+		}
 	}
 	instanceProfile, err := existingInstanceProfile(client, profileName)
 	if err != nil {
@@ -551,7 +692,7 @@ func (o *CreateIAMOptions) CreateWorkerInstanceProfile(client iamiface.IAMAPI, p
 	if instanceProfile == nil {
 		result, err := client.CreateInstanceProfile(&iam.CreateInstanceProfileInput{
 			InstanceProfileName: aws.String(profileName),
-			Path:                aws.String("/"),
+			Path:                aws.String(o.iamPath()),
 			Tags:                o.additionalIAMTags,
 		})
 		if err != nil {
@@ -586,7 +727,7 @@ func (o *CreateIAMOptions) CreateWorkerInstanceProfile(client iamiface.IAMAPI, p
 	if !hasPolicy {
 		_, err = client.PutRolePolicy(&iam.PutRolePolicyInput{
 			PolicyName:     aws.String(rolePolicyName),
-			PolicyDocument: aws.String(workerPolicy),
+			PolicyDocument: aws.String(workerInstancePolicy),
 			RoleName:       aws.String(roleName),
 		})
 		if err != nil {
@@ -594,6 +735,97 @@ func (o *CreateIAMOptions) CreateWorkerInstanceProfile(client iamiface.IAMAPI, p
 		}
 		log.Log.Info("Created role policy", "name", rolePolicyName)
 	}
+	return o.reconcileIMDSv2Policy(client, roleName, workerIMDSv2PolicyName(profileName))
+}
+
+// reconcileIMDSv2Policy adds the policy requiring IMDSv2 to the worker role with
+// --require-imdsv2, and removes it otherwise.
+func (o *CreateIAMOptions) reconcileIMDSv2Policy(client iamiface.IAMAPI, roleName, policyName string) error {
+	hasPolicy, err := existingRolePolicy(client, roleName, policyName)
+	if err != nil {
+		return err
+	}
+	switch {
+	case o.RequireIMDSv2 && !hasPolicy:
+		if _, err := client.PutRolePolicy(&iam.PutRolePolicyInput{
+			PolicyName:     aws.String(policyName),
+			PolicyDocument: aws.String(workerIMDSv2Policy),
+			RoleName:       aws.String(roleName),
+		}); err != nil {
+			return fmt.Errorf("cannot create IMDSv2 policy: %w", err)
+		}
+		log.Log.Info("Created role policy", "name", policyName)
+	case !o.RequireIMDSv2 && hasPolicy:
+		if _, err := client.DeleteRolePolicy(&iam.DeleteRolePolicyInput{
+			PolicyName: aws.String(policyName),
+			RoleName:   aws.String(roleName),
+		}); err != nil {
+			return fmt.Errorf("cannot delete IMDSv2 policy: %w", err)
+		}
+		log.Log.Info("Deleted role policy", "name", policyName)
+	}
+	return nil
+}
+
+// validateIAMPathOptions validates the flags for the path and the permissions
+// boundary of the created IAM resources.
+func (o *CreateIAMOptions) validateIAMPathOptions() error {
+	if path := o.iamPath(); !strings.HasPrefix(path, "/") || !strings.HasSuffix(path, "/") || len(path) > 512 {
+		return fmt.Errorf("invalid --iam-path %q: it must begin and end with a slash and be at most 512 characters long", o.IAMPath)
+	}
+	if len(o.PermissionsBoundaryARN) == 0 {
+		return nil
+	}
+	boundary, err := arn.Parse(o.PermissionsBoundaryARN)
+	if err != nil {
+		return fmt.Errorf("invalid --permissions-boundary-arn %q: %w", o.PermissionsBoundaryARN, err)
+	}
+	if boundary.Service != "iam" || !strings.HasPrefix(boundary.Resource, "policy/") {
+		return fmt.Errorf("invalid --permissions-boundary-arn %q: it must be the ARN of a managed policy", o.PermissionsBoundaryARN)
+	}
+	return nil
+}
+
+// profileName returns the name of the worker instance profile.
+func (o *CreateIAMOptions) profileName() string {
+	return workerProfileName(o.InfraID, o.InstanceProfileName)
+}
+
+// iamPath returns the path of the created roles and instance profile.
+func (o *CreateIAMOptions) iamPath() string {
+	if len(o.IAMPath) == 0 {
+		return "/"
+	}
+	return o.IAMPath
+}
+
+func (o *CreateIAMOptions) permissionsBoundary() *string {
+	if len(o.PermissionsBoundaryARN) == 0 {
+		return nil
+	}
+	return aws.String(o.PermissionsBoundaryARN)
+}
+
+// reconcileExistingRole sets the permissions boundary on a role that was created
+// without it. The path of a role cannot be changed, so a role under another path
+// is left in place.
+func (o *CreateIAMOptions) reconcileExistingRole(client iamiface.IAMAPI, role *iam.Role) error {
+	if path := aws.StringValue(role.Path); path != o.iamPath() {
+		log.Log.Info("WARNING: existing role is not under the IAM path and cannot be moved", "name", aws.StringValue(role.RoleName), "path", path)
+	}
+	if len(o.PermissionsBoundaryARN) == 0 {
+		return nil
+	}
+	if role.PermissionsBoundary != nil && aws.StringValue(role.PermissionsBoundary.PermissionsBoundaryArn) == o.PermissionsBoundaryARN {
+		return nil
+	}
+	if _, err := client.PutRolePermissionsBoundary(&iam.PutRolePermissionsBoundaryInput{
+		RoleName:            role.RoleName,
+		PermissionsBoundary: aws.String(o.PermissionsBoundaryARN),
+	}); err != nil {
+		return fmt.Errorf("cannot set permissions boundary of role %s: %w", aws.StringValue(role.RoleName), err)
+	}
+	log.Log.Info("Set permissions boundary of role", "name", aws.StringValue(role.RoleName), "boundary", o.PermissionsBoundaryARN)
 	return nil
 }
 
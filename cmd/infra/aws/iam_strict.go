@@ -0,0 +1,421 @@
+package aws
+
+import (
+	"fmt"
+)
+
+const (
+	// PolicyModeCompatible grants the components the actions they need on all
+	// resources, which works with any infrastructure.
+	PolicyModeCompatible = "compatible"
+	// PolicyModeStrict scopes the actions of the components to the resources of
+	// the region that are tagged for the cluster wherever AWS supports it.
+	PolicyModeStrict = "strict"
+)
+
+func (o *CreateIAMOptions) validatePolicyMode() error {
+	switch o.PolicyMode {
+	case PolicyModeCompatible, PolicyModeStrict, "":
+		return nil
+	}
+	return fmt.Errorf("unsupported policy mode %q, must be one of %q or %q", o.PolicyMode, PolicyModeCompatible, PolicyModeStrict)
+}
+
+// strictImageRegistryPolicy only allows access to the buckets the image registry
+// operator creates for the cluster, which are named after the infra ID.
+func strictImageRegistryPolicy(partition, infraID string) string {
+	return fmt.Sprintf(`{
+	"Version": "2012-10-17",
+	"Statement": [
+		{
+			"Effect": "Allow",
+			"Action": [
+				"s3:CreateBucket",
+				"s3:DeleteBucket",
+				"s3:PutBucketTagging",
+				"s3:GetBucketTagging",
+				"s3:PutBucketPublicAccessBlock",
+				"s3:GetBucketPublicAccessBlock",
+				"s3:PutEncryptionConfiguration",
+				"s3:GetEncryptionConfiguration",
+				"s3:PutLifecycleConfiguration",
+				"s3:GetLifecycleConfiguration",
+				"s3:GetBucketLocation",
+				"s3:ListBucket",
+				"s3:ListBucketMultipartUploads"
+			],
+			"Resource": "arn:%[1]s:s3:::%[2]s-image-registry-*"
+		},
+		{
+			"Effect": "Allow",
+			"Action": [
+				"s3:GetObject",
+				"s3:PutObject",
+				"s3:DeleteObject",
+				"s3:AbortMultipartUpload",
+				"s3:ListMultipartUploadParts"
+			],
+			"Resource": "arn:%[1]s:s3:::%[2]s-image-registry-*/*"
+		}
+	]
+}`, partition, infraID)
+}
+
+// strictAWSEBSCSIPolicy only allows the driver to change the volumes and
+// snapshots it tags with the cluster tag on creation, and to attach them to the
+// instances of the cluster.
+func strictAWSEBSCSIPolicy(partition, region, infraID string) string {
+	return fmt.Sprintf(`{
+	"Version": "2012-10-17",
+	"Statement": [
+		{
+			"Effect": "Allow",
+			"Action": [
+				"ec2:DescribeInstances",
+				"ec2:DescribeSnapshots",
+				"ec2:DescribeTags",
+				"ec2:DescribeVolumes",
+				"ec2:DescribeVolumesModifications"
+			],
+			"Resource": "*"
+		},
+		{
+			"Effect": "Allow",
+			"Action": [
+				"ec2:CreateVolume",
+				"ec2:CreateSnapshot"
+			],
+			"Resource": [
+				"arn:%[1]s:ec2:%[2]s:*:volume/*",
+				"arn:%[1]s:ec2:%[2]s:*:snapshot/*"
+			],
+			"Condition": {
+				"StringEquals": {
+					"aws:RequestTag/%[3]s": "owned"
+				}
+			}
+		},
+		{
+			"Effect": "Allow",
+			"Action": [
+				"ec2:CreateVolume"
+			],
+			"Resource": "arn:%[1]s:ec2:%[2]s:*:snapshot/*"
+		},
+		{
+			"Effect": "Allow",
+			"Action": [
+				"ec2:AttachVolume",
+				"ec2:DetachVolume",
+				"ec2:ModifyVolume",
+				"ec2:DeleteVolume",
+				"ec2:CreateSnapshot",
+				"ec2:DeleteSnapshot",
+				"ec2:CreateTags",
+				"ec2:DeleteTags"
+			],
+			"Resource": [
+				"arn:%[1]s:ec2:%[2]s:*:instance/*",
+				"arn:%[1]s:ec2:%[2]s:*:volume/*",
+				"arn:%[1]s:ec2:%[2]s:*:snapshot/*"
+			],
+			"Condition": {
+				"StringEquals": {
+					"ec2:ResourceTag/%[3]s": "owned"
+				}
+			}
+		},
+		{
+			"Effect": "Allow",
+			"Action": [
+				"ec2:CreateTags"
+			],
+			"Resource": [
+				"arn:%[1]s:ec2:%[2]s:*:volume/*",
+				"arn:%[1]s:ec2:%[2]s:*:snapshot/*"
+			],
+			"Condition": {
+				"StringEquals": {
+					"ec2:CreateAction": [
+						"CreateVolume",
+						"CreateSnapshot"
+					]
+				}
+			}
+		}
+	]
+}`, partition, region, clusterTag(infraID))
+}
+
+// strictNodePoolPolicy only allows CAPA to manage the instances and launch
+// templates of the node pools. The network is managed by create infra, so the
+// actions to create and delete network resources are not granted. Only the worker
+// role given by roleName may be passed to the instances.
+func strictNodePoolPolicy(partition, region, infraID, path, roleName string) string {
+	return fmt.Sprintf(`{
+	"Version": "2012-10-17",
+	"Statement": [
+		{
+			"Effect": "Allow",
+			"Action": [
+				"ec2:DescribeAccountAttributes",
+				"ec2:DescribeAddresses",
+				"ec2:DescribeAvailabilityZones",
+				"ec2:DescribeImages",
+				"ec2:DescribeInstances",
+				"ec2:DescribeInternetGateways",
+				"ec2:DescribeNatGateways",
+				"ec2:DescribeNetworkInterfaces",
+				"ec2:DescribeNetworkInterfaceAttribute",
+				"ec2:DescribeRouteTables",
+				"ec2:DescribeSecurityGroups",
+				"ec2:DescribeSubnets",
+				"ec2:DescribeVpcs",
+				"ec2:DescribeVpcAttribute",
+				"ec2:DescribeVolumes",
+				"ec2:DescribeLaunchTemplates",
+				"ec2:DescribeLaunchTemplateVersions",
+				"tag:GetResources"
+			],
+			"Resource": "*"
+		},
+		{
+			"Effect": "Allow",
+			"Action": [
+				"ec2:RunInstances"
+			],
+			"Resource": "arn:%[1]s:ec2:%[2]s:*:instance/*",
+			"Condition": {
+				"StringEquals": {
+					"aws:RequestTag/%[3]s": "owned"
+				}
+			}
+		},
+		{
+			"Effect": "Allow",
+			"Action": [
+				"ec2:RunInstances"
+			],
+			"Resource": "arn:%[1]s:ec2:%[2]s:*:subnet/*",
+			"Condition": {
+				"StringEquals": {
+					"ec2:ResourceTag/%[3]s": [
+						"owned",
+						"shared"
+					]
+				}
+			}
+		},
+		{
+			"Effect": "Allow",
+			"Action": [
+				"ec2:RunInstances"
+			],
+			"Resource": [
+				"arn:%[1]s:ec2:%[2]s::image/*",
+				"arn:%[1]s:ec2:%[2]s:*:key-pair/*",
+				"arn:%[1]s:ec2:%[2]s:*:launch-template/*",
+				"arn:%[1]s:ec2:%[2]s:*:network-interface/*",
+				"arn:%[1]s:ec2:%[2]s:*:security-group/*",
+				"arn:%[1]s:ec2:%[2]s:*:volume/*"
+			]
+		},
+		{
+			"Effect": "Allow",
+			"Action": [
+				"ec2:TerminateInstances",
+				"ec2:ModifyInstanceAttribute",
+				"ec2:CreateTags",
+				"ec2:DeleteTags"
+			],
+			"Resource": "arn:%[1]s:ec2:%[2]s:*:instance/*",
+			"Condition": {
+				"StringEquals": {
+					"ec2:ResourceTag/%[3]s": "owned"
+				}
+			}
+		},
+		{
+			"Effect": "Allow",
+			"Action": [
+				"ec2:CreateTags"
+			],
+			"Resource": [
+				"arn:%[1]s:ec2:%[2]s:*:instance/*",
+				"arn:%[1]s:ec2:%[2]s:*:launch-template/*",
+				"arn:%[1]s:ec2:%[2]s:*:network-interface/*",
+				"arn:%[1]s:ec2:%[2]s:*:volume/*"
+			],
+			"Condition": {
+				"StringEquals": {
+					"ec2:CreateAction": [
+						"RunInstances",
+						"CreateLaunchTemplate"
+					]
+				}
+			}
+		},
+		{
+			"Effect": "Allow",
+			"Action": [
+				"ec2:CreateLaunchTemplate",
+				"ec2:CreateLaunchTemplateVersion",
+				"ec2:DeleteLaunchTemplate",
+				"ec2:DeleteLaunchTemplateVersions"
+			],
+			"Resource": "arn:%[1]s:ec2:%[2]s:*:launch-template/*"
+		},
+		{
+			"Effect": "Allow",
+			"Action": [
+				"ec2:ModifyNetworkInterfaceAttribute"
+			],
+			"Resource": [
+				"arn:%[1]s:ec2:%[2]s:*:instance/*",
+				"arn:%[1]s:ec2:%[2]s:*:network-interface/*",
+				"arn:%[1]s:ec2:%[2]s:*:security-group/*"
+			]
+		},
+		{
+			"Effect": "Allow",
+			"Action": [
+				"iam:CreateServiceLinkedRole"
+			],
+			"Resource": "arn:%[1]s:iam::*:role/aws-service-role/elasticloadbalancing.amazonaws.com/AWSServiceRoleForElasticLoadBalancing",
+			"Condition": {
+				"StringLike": {
+					"iam:AWSServiceName": "elasticloadbalancing.amazonaws.com"
+				}
+			}
+		},
+		{
+			"Effect": "Allow",
+			"Action": [
+				"iam:CreateServiceLinkedRole"
+			],
+			"Resource": "arn:%[1]s:iam::*:role/aws-service-role/spot.amazonaws.com/AWSServiceRoleForEC2Spot",
+			"Condition": {
+				"StringLike": {
+					"iam:AWSServiceName": "spot.amazonaws.com"
+				}
+			}
+		},
+		{
+			"Effect": "Allow",
+			"Action": [
+				"kms:CreateGrant",
+				"kms:Decrypt",
+				"kms:DescribeKey",
+				"kms:Encrypt",
+				"kms:GenerateDataKey*",
+				"kms:ReEncrypt*"
+			],
+			"Resource": "arn:%[1]s:kms:%[2]s:*:key/*",
+			"Condition": {
+				"StringEquals": {
+					"kms:ViaService": "ec2.%[2]s.amazonaws.com"
+				}
+			}
+		},
+		{
+			"Effect": "Allow",
+			"Action": [
+				"iam:PassRole"
+			],
+			"Resource": "arn:%[1]s:iam::*:role%[5]s%[4]s"
+		}
+	]
+}`, partition, region, clusterTag(infraID), roleName, path)
+}
+
+// strictControlPlaneOperatorPolicy only allows the control plane operator to
+// change the VPC endpoints it tags for an AWSEndpointService, in the subnets
+// and VPC of the cluster.
+func strictControlPlaneOperatorPolicy(partition, region, infraID, hostedZone string) string {
+	hostedZone = ensureHostedZonePrefix(hostedZone)
+	return fmt.Sprintf(`{
+	"Version": "2012-10-17",
+	"Statement": [
+		{
+			"Effect": "Allow",
+			"Action": [
+				"ec2:DescribeVpcEndpoints",
+				"route53:ListHostedZones"
+			],
+			"Resource": "*"
+		},
+		{
+			"Effect": "Allow",
+			"Action": [
+				"ec2:CreateVpcEndpoint"
+			],
+			"Resource": "arn:%[1]s:ec2:%[2]s:*:vpc-endpoint/*",
+			"Condition": {
+				"Null": {
+					"aws:RequestTag/AWSEndpointService": "false"
+				}
+			}
+		},
+		{
+			"Effect": "Allow",
+			"Action": [
+				"ec2:ModifyVpcEndpoint",
+				"ec2:DeleteVpcEndpoints"
+			],
+			"Resource": "arn:%[1]s:ec2:%[2]s:*:vpc-endpoint/*",
+			"Condition": {
+				"Null": {
+					"ec2:ResourceTag/AWSEndpointService": "false"
+				}
+			}
+		},
+		{
+			"Effect": "Allow",
+			"Action": [
+				"ec2:CreateVpcEndpoint",
+				"ec2:ModifyVpcEndpoint"
+			],
+			"Resource": [
+				"arn:%[1]s:ec2:%[2]s:*:vpc/*",
+				"arn:%[1]s:ec2:%[2]s:*:subnet/*"
+			],
+			"Condition": {
+				"StringEquals": {
+					"ec2:ResourceTag/%[3]s": [
+						"owned",
+						"shared"
+					]
+				}
+			}
+		},
+		{
+			"Effect": "Allow",
+			"Action": [
+				"ec2:CreateVpcEndpoint",
+				"ec2:ModifyVpcEndpoint"
+			],
+			"Resource": "arn:%[1]s:ec2:%[2]s:*:security-group/*"
+		},
+		{
+			"Effect": "Allow",
+			"Action": [
+				"ec2:CreateTags"
+			],
+			"Resource": "arn:%[1]s:ec2:%[2]s:*:vpc-endpoint/*",
+			"Condition": {
+				"StringEquals": {
+					"ec2:CreateAction": "CreateVpcEndpoint"
+				}
+			}
+		},
+		{
+			"Effect": "Allow",
+			"Action": [
+				"route53:ChangeResourceRecordSets",
+				"route53:ListResourceRecordSets"
+			],
+			"Resource": "arn:%[1]s:route53:::%[4]s"
+		}
+	]
+}`, partition, region, clusterTag(infraID), hostedZone)
+}
@@ -0,0 +1,121 @@
+package aws
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestValidatePolicyMode(t *testing.T) {
+	g := NewGomegaWithT(t)
+	g.Expect((&CreateIAMOptions{}).validatePolicyMode()).To(Succeed())
+	g.Expect((&CreateIAMOptions{PolicyMode: PolicyModeCompatible}).validatePolicyMode()).To(Succeed())
+	g.Expect((&CreateIAMOptions{PolicyMode: PolicyModeStrict}).validatePolicyMode()).To(Succeed())
+	g.Expect((&CreateIAMOptions{PolicyMode: "permissive"}).validatePolicyMode()).ToNot(Succeed())
+}
+
+func TestOIDCRolePolicyModes(t *testing.T) {
+	type policyStatement struct {
+		Action    []string               `json:"Action"`
+		Resource  interface{}            `json:"Resource"`
+		Condition map[string]interface{} `json:"Condition"`
+	}
+	type policyDocument struct {
+		Statement []policyStatement `json:"Statement"`
+	}
+	testCases := []struct {
+		mode         string
+		expectScoped bool
+	}{
+		{
+			mode: PolicyModeCompatible,
+		},
+		{
+			mode:         PolicyModeStrict,
+			expectScoped: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.mode, func(t *testing.T) {
+			g := NewGomegaWithT(t)
+			o := &CreateIAMOptions{
+				Region:        "us-gov-west-1",
+				InfraID:       "test-infra",
+				PublicZoneID:  "PUBLIC",
+				PrivateZoneID: "PRIVATE",
+				LocalZoneID:   "LOCAL",
+				PolicyMode:    tc.mode,
+			}
+			scoped := map[string]bool{
+				"openshift-image-registry":      false,
+				"aws-ebs-csi-driver-controller": false,
+				"node-pool":                     false,
+				"control-plane-operator":        false,
+			}
+			for _, role := range o.oidcRoles() {
+				var policy policyDocument
+				g.Expect(json.Unmarshal([]byte(role.permPolicy), &policy)).To(Succeed(), role.name)
+				if _, ok := scoped[role.name]; !ok {
+					continue
+				}
+				// A policy is scoped if it only grants actions on all resources
+				// that are read-only
+				roleScoped := true
+				for _, statement := range policy.Statement {
+					resources, ok := statement.Resource.([]interface{})
+					if !ok {
+						resources = []interface{}{statement.Resource}
+					}
+					allResources := false
+					for _, resource := range resources {
+						allResources = allResources || resource == "*"
+					}
+					if !allResources || statement.Condition != nil {
+						continue
+					}
+					for _, action := range statement.Action {
+						if !readOnlyAction(action) {
+							roleScoped = false
+						}
+					}
+				}
+				scoped[role.name] = roleScoped
+			}
+			for name, roleScoped := range scoped {
+				g.Expect(roleScoped).To(Equal(tc.expectScoped), name)
+			}
+		})
+	}
+}
+
+func readOnlyAction(action string) bool {
+	for _, prefix := range []string{"ec2:Describe", "route53:List", "tag:Get"} {
+		if strings.HasPrefix(action, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestStrictPolicies(t *testing.T) {
+	g := NewGomegaWithT(t)
+	policy := strictImageRegistryPolicy("aws-us-gov", "test-infra")
+	g.Expect(policy).To(ContainSubstring(`"arn:aws-us-gov:s3:::test-infra-image-registry-*"`))
+	g.Expect(policy).To(ContainSubstring(`"arn:aws-us-gov:s3:::test-infra-image-registry-*/*"`))
+
+	policy = strictAWSEBSCSIPolicy("aws", "us-east-1", "test-infra")
+	g.Expect(policy).To(ContainSubstring(`"aws:RequestTag/kubernetes.io/cluster/test-infra": "owned"`))
+	g.Expect(policy).To(ContainSubstring(`"arn:aws:ec2:us-east-1:*:volume/*"`))
+
+	policy = strictNodePoolPolicy("aws", "us-east-1", "test-infra", "/", "test-infra-worker-role")
+	g.Expect(policy).To(ContainSubstring(`"arn:aws:iam::*:role/test-infra-worker-role"`))
+	policy = strictNodePoolPolicy("aws", "us-east-1", "test-infra", "/hypershift/", "test-infra-worker-role")
+	g.Expect(policy).To(ContainSubstring(`"arn:aws:iam::*:role/hypershift/test-infra-worker-role"`))
+	g.Expect(policy).ToNot(ContainSubstring("ec2:CreateSubnet"))
+
+	policy = strictControlPlaneOperatorPolicy("aws", "us-east-1", "test-infra", "Z1")
+	g.Expect(policy).To(ContainSubstring(`"arn:aws:route53:::hostedzone/Z1"`))
+	g.Expect(policy).To(ContainSubstring(`"ec2:ResourceTag/AWSEndpointService": "false"`))
+}
@@ -0,0 +1,158 @@
+package aws
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/aws/aws-sdk-go/service/iam/iamiface"
+	. "github.com/onsi/gomega"
+)
+
+type fakeIAMRoleClient struct {
+	iamiface.IAMAPI
+	role       *iam.Role
+	created    []*iam.CreateRoleInput
+	boundaries []*iam.PutRolePermissionsBoundaryInput
+}
+
+func (f *fakeIAMRoleClient) GetRole(in *iam.GetRoleInput) (*iam.GetRoleOutput, error) {
+	if f.role == nil {
+		return nil, awserr.New(iam.ErrCodeNoSuchEntityException, "not found", nil)
+	}
+	return &iam.GetRoleOutput{Role: f.role}, nil
+}
+
+func (f *fakeIAMRoleClient) CreateRole(in *iam.CreateRoleInput) (*iam.CreateRoleOutput, error) {
+	f.created = append(f.created, in)
+	return &iam.CreateRoleOutput{Role: &iam.Role{Arn: aws.String("arn:aws:iam::123456789012:role" + aws.StringValue(in.Path) + aws.StringValue(in.RoleName))}}, nil
+}
+
+func (f *fakeIAMRoleClient) PutRolePermissionsBoundary(in *iam.PutRolePermissionsBoundaryInput) (*iam.PutRolePermissionsBoundaryOutput, error) {
+	f.boundaries = append(f.boundaries, in)
+	return &iam.PutRolePermissionsBoundaryOutput{}, nil
+}
+
+func (f *fakeIAMRoleClient) GetRolePolicy(in *iam.GetRolePolicyInput) (*iam.GetRolePolicyOutput, error) {
+	return &iam.GetRolePolicyOutput{PolicyName: in.PolicyName}, nil
+}
+
+func TestValidateIAMPathOptions(t *testing.T) {
+	testCases := []struct {
+		name        string
+		options     CreateIAMOptions
+		expectError bool
+	}{
+		{
+			name: "defaults",
+		},
+		{
+			name:    "path and boundary",
+			options: CreateIAMOptions{IAMPath: "/hypershift/", PermissionsBoundaryARN: "arn:aws:iam::123456789012:policy/boundary"},
+		},
+		{
+			name:        "path without trailing slash",
+			options:     CreateIAMOptions{IAMPath: "/hypershift"},
+			expectError: true,
+		},
+		{
+			name:        "boundary is not an ARN",
+			options:     CreateIAMOptions{PermissionsBoundaryARN: "boundary"},
+			expectError: true,
+		},
+		{
+			name:        "boundary is a role",
+			options:     CreateIAMOptions{PermissionsBoundaryARN: "arn:aws:iam::123456789012:role/boundary"},
+			expectError: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewGomegaWithT(t)
+			err := tc.options.validateIAMPathOptions()
+			if tc.expectError {
+				g.Expect(err).To(HaveOccurred())
+			} else {
+				g.Expect(err).ToNot(HaveOccurred())
+			}
+		})
+	}
+}
+
+func TestCreateOIDCRolePathAndBoundary(t *testing.T) {
+	const boundary = "arn:aws:iam::123456789012:policy/boundary"
+	g := NewGomegaWithT(t)
+	o := &CreateIAMOptions{InfraID: "test", IAMPath: "/hypershift/", PermissionsBoundaryARN: boundary}
+
+	client := &fakeIAMRoleClient{}
+	arn, err := o.CreateOIDCRole(client, "node-pool", "{}", "{}")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(arn).To(Equal("arn:aws:iam::123456789012:role/hypershift/test-node-pool"))
+	g.Expect(client.created).To(HaveLen(1))
+	g.Expect(aws.StringValue(client.created[0].Path)).To(Equal("/hypershift/"))
+	g.Expect(aws.StringValue(client.created[0].PermissionsBoundary)).To(Equal(boundary))
+
+	// An existing role without the boundary gets it, one with the boundary is left alone
+	client = &fakeIAMRoleClient{role: &iam.Role{RoleName: aws.String("test-node-pool"), Path: aws.String("/"), Arn: aws.String("arn:aws:iam::123456789012:role/test-node-pool")}}
+	_, err = o.CreateOIDCRole(client, "node-pool", "{}", "{}")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(client.created).To(BeEmpty())
+	g.Expect(client.boundaries).To(HaveLen(1))
+	g.Expect(aws.StringValue(client.boundaries[0].PermissionsBoundary)).To(Equal(boundary))
+
+	client.role.PermissionsBoundary = &iam.AttachedPermissionsBoundary{PermissionsBoundaryArn: aws.String(boundary)}
+	client.boundaries = nil
+	_, err = o.CreateOIDCRole(client, "node-pool", "{}", "{}")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(client.boundaries).To(BeEmpty())
+}
+
+func TestValidateWorkerNames(t *testing.T) {
+	g := NewGomegaWithT(t)
+	g.Expect(validateWorkerNames("", "")).To(Succeed())
+	g.Expect(validateWorkerNames("corp-ec2-hcp-worker", "corp-ec2-hcp-worker-role")).To(Succeed())
+	g.Expect(validateWorkerNames("corp/worker", "")).To(MatchError(ContainSubstring("invalid --instance-profile-name")))
+	g.Expect(validateWorkerNames("", strings.Repeat("r", 65))).To(MatchError(ContainSubstring("invalid --worker-role-name")))
+
+	g.Expect(workerProfileName("test", "")).To(Equal("test-worker"))
+	g.Expect(workerProfileName("test", "corp-worker")).To(Equal("corp-worker"))
+	g.Expect(workerRoleName("corp-worker", "")).To(Equal("corp-worker-role"))
+	g.Expect(workerRoleName("corp-worker", "corp-role")).To(Equal("corp-role"))
+}
+
+type fakeIAMRolePolicyClient struct {
+	iamiface.IAMAPI
+	policies map[string]string
+}
+
+func (f *fakeIAMRolePolicyClient) GetRolePolicy(in *iam.GetRolePolicyInput) (*iam.GetRolePolicyOutput, error) {
+	if _, exists := f.policies[aws.StringValue(in.PolicyName)]; !exists {
+		return nil, awserr.New(iam.ErrCodeNoSuchEntityException, "not found", nil)
+	}
+	return &iam.GetRolePolicyOutput{PolicyName: in.PolicyName}, nil
+}
+
+func (f *fakeIAMRolePolicyClient) PutRolePolicy(in *iam.PutRolePolicyInput) (*iam.PutRolePolicyOutput, error) {
+	f.policies[aws.StringValue(in.PolicyName)] = aws.StringValue(in.PolicyDocument)
+	return &iam.PutRolePolicyOutput{}, nil
+}
+
+func (f *fakeIAMRolePolicyClient) DeleteRolePolicy(in *iam.DeleteRolePolicyInput) (*iam.DeleteRolePolicyOutput, error) {
+	delete(f.policies, aws.StringValue(in.PolicyName))
+	return &iam.DeleteRolePolicyOutput{}, nil
+}
+
+func TestReconcileIMDSv2Policy(t *testing.T) {
+	g := NewGomegaWithT(t)
+	client := &fakeIAMRolePolicyClient{policies: map[string]string{}}
+
+	o := &CreateIAMOptions{RequireIMDSv2: true}
+	g.Expect(o.reconcileIMDSv2Policy(client, "test-worker-role", "test-worker-imdsv2")).To(Succeed())
+	g.Expect(client.policies).To(HaveKeyWithValue("test-worker-imdsv2", ContainSubstring(`"ec2:RoleDelivery": "2.0"`)))
+
+	o.RequireIMDSv2 = false
+	g.Expect(o.reconcileIMDSv2Policy(client, "test-worker-role", "test-worker-imdsv2")).To(Succeed())
+	g.Expect(client.policies).To(BeEmpty())
+}
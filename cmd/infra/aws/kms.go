@@ -0,0 +1,193 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/arn"
+	"github.com/aws/aws-sdk-go/service/kms"
+	"github.com/aws/aws-sdk-go/service/kms/kmsiface"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/aws/aws-sdk-go/service/sts/stsiface"
+	"github.com/go-logr/logr"
+)
+
+// kmsKeyDeletionWindowDays is the waiting period before a key scheduled for
+// deletion on destroy is deleted. It is the minimum allowed by AWS.
+const kmsKeyDeletionWindowDays = 7
+
+func kmsKeyAlias(infraID string) string {
+	return fmt.Sprintf("alias/%s-key", infraID)
+}
+
+// validateKMSKeyOptions validates the flags for the KMS key.
+func (o *CreateInfraOptions) validateKMSKeyOptions() error {
+	if len(o.KMSKeyARN) == 0 {
+		return nil
+	}
+	if o.CreateKMSKey {
+		return errors.New("--create-kms-key and --kms-key-arn are mutually exclusive")
+	}
+	keyARN, err := arn.Parse(o.KMSKeyARN)
+	if err != nil {
+		return fmt.Errorf("invalid --kms-key-arn %q: %w", o.KMSKeyARN, err)
+	}
+	if keyARN.Service != "kms" {
+		return fmt.Errorf("%s is not the ARN of a KMS key", o.KMSKeyARN)
+	}
+	if keyARN.Region != o.Region {
+		return fmt.Errorf("KMS key %s must be in region %s", o.KMSKeyARN, o.Region)
+	}
+	return nil
+}
+
+// kmsKey returns the ARN of the KMS key of the cluster. An existing key given by
+// KMSKeyARN must be enabled. With CreateKMSKey, a customer managed key is created,
+// unless the alias of the cluster already refers to one.
+func (o *CreateInfraOptions) kmsKey(ctx context.Context, l logr.Logger, client kmsiface.KMSAPI, stsClient stsiface.STSAPI) (string, error) {
+	if len(o.KMSKeyARN) > 0 {
+		result, err := client.DescribeKeyWithContext(ctx, &kms.DescribeKeyInput{KeyId: aws.String(o.KMSKeyARN)})
+		if err != nil {
+			return "", fmt.Errorf("cannot find KMS key %s: %w", o.KMSKeyARN, err)
+		}
+		if state := aws.StringValue(result.KeyMetadata.KeyState); state != kms.KeyStateEnabled {
+			return "", fmt.Errorf("KMS key %s must be enabled, its state is %s", o.KMSKeyARN, state)
+		}
+		l.Info("Using existing KMS key", "arn", o.KMSKeyARN)
+		return o.KMSKeyARN, nil
+	}
+	if !o.CreateKMSKey {
+		return "", nil
+	}
+	return o.CreateClusterKMSKey(ctx, l, client, stsClient)
+}
+
+// CreateClusterKMSKey creates a customer managed KMS key for the cluster with the
+// alias alias/<infra id>-key. The key policy gives the account full control of the
+// key through IAM policies, and lets EC2 use the key for EBS volumes of the account.
+func (o *CreateInfraOptions) CreateClusterKMSKey(ctx context.Context, l logr.Logger, client kmsiface.KMSAPI, stsClient stsiface.STSAPI) (string, error) {
+	alias := kmsKeyAlias(o.InfraID)
+	existing, err := client.DescribeKeyWithContext(ctx, &kms.DescribeKeyInput{KeyId: aws.String(alias)})
+	if err == nil {
+		keyARN := aws.StringValue(existing.KeyMetadata.Arn)
+		l.Info("Found existing KMS key", "alias", alias, "arn", keyARN)
+		return keyARN, o.reconcileKMSKeyTags(ctx, l, client, aws.StringValue(existing.KeyMetadata.KeyId))
+	}
+	var notFound *kms.NotFoundException
+	if !errors.As(err, &notFound) {
+		return "", fmt.Errorf("cannot find KMS key %s: %w", alias, err)
+	}
+	if o.DryRun {
+		return o.planCreate(l, "kms-key", alias), nil
+	}
+
+	identity, err := stsClient.GetCallerIdentityWithContext(ctx, &sts.GetCallerIdentityInput{})
+	if err != nil {
+		return "", fmt.Errorf("cannot determine AWS account: %w", err)
+	}
+	tags := []*kms.Tag{{TagKey: aws.String(clusterTag(o.InfraID)), TagValue: aws.String(clusterTagValue)}}
+	for _, tag := range o.additionalEC2Tags {
+		tags = append(tags, &kms.Tag{TagKey: tag.Key, TagValue: tag.Value})
+	}
+	result, err := client.CreateKeyWithContext(ctx, &kms.CreateKeyInput{
+		Description: aws.String(fmt.Sprintf("Encryption key of cluster %s", o.InfraID)),
+		Policy:      aws.String(kmsKeyPolicy(o.Region, aws.StringValue(identity.Account))),
+		Tags:        tags,
+	})
+	if err != nil {
+		return "", fmt.Errorf("cannot create KMS key: %w", err)
+	}
+	keyID := aws.StringValue(result.KeyMetadata.KeyId)
+	keyARN := aws.StringValue(result.KeyMetadata.Arn)
+	if _, err := client.CreateAliasWithContext(ctx, &kms.CreateAliasInput{
+		AliasName:   aws.String(alias),
+		TargetKeyId: aws.String(keyID),
+	}); err != nil {
+		return "", fmt.Errorf("cannot create alias %s for KMS key %s: %w", alias, keyID, err)
+	}
+	l.Info("Created KMS key", "alias", alias, "arn", keyARN)
+	return keyARN, nil
+}
+
+func kmsKeyPolicy(region, accountID string) string {
+	return fmt.Sprintf(`{
+	"Version": "2012-10-17",
+	"Statement": [
+		{
+			"Sid": "Enable IAM policies",
+			"Effect": "Allow",
+			"Principal": {
+				"AWS": "arn:%[1]s:iam::%[2]s:root"
+			},
+			"Action": "kms:*",
+			"Resource": "*"
+		},
+		{
+			"Sid": "Allow EBS encryption",
+			"Effect": "Allow",
+			"Principal": {
+				"AWS": "*"
+			},
+			"Action": [
+				"kms:Encrypt",
+				"kms:Decrypt",
+				"kms:ReEncrypt*",
+				"kms:GenerateDataKey*",
+				"kms:CreateGrant",
+				"kms:DescribeKey"
+			],
+			"Resource": "*",
+			"Condition": {
+				"StringEquals": {
+					"kms:CallerAccount": "%[2]s",
+					"kms:ViaService": "ec2.%[3]s.%[4]s"
+				}
+			}
+		}
+	]
+}`, partitionID(region), accountID, region, partitionForRegion(region).DNSSuffix())
+}
+
+// DestroyKMSKey schedules the deletion of the KMS key created for the cluster and
+// deletes its alias. Keys without the cluster tag, i.e. keys that were referenced
+// rather than created, are left in place.
+func (o *DestroyInfraOptions) DestroyKMSKey(ctx context.Context, client kmsiface.KMSAPI) []error {
+	alias := kmsKeyAlias(o.InfraID)
+	result, err := client.DescribeKeyWithContext(ctx, &kms.DescribeKeyInput{KeyId: aws.String(alias)})
+	if err != nil {
+		var notFound *kms.NotFoundException
+		if errors.As(err, &notFound) {
+			return nil
+		}
+		return []error{fmt.Errorf("cannot find KMS key %s: %w", alias, err)}
+	}
+	keyID := aws.StringValue(result.KeyMetadata.KeyId)
+	tags, err := client.ListResourceTagsWithContext(ctx, &kms.ListResourceTagsInput{KeyId: aws.String(keyID)})
+	if err != nil {
+		return []error{fmt.Errorf("cannot list tags of KMS key %s: %w", keyID, err)}
+	}
+	owned := false
+	for _, tag := range tags.Tags {
+		if aws.StringValue(tag.TagKey) == clusterTag(o.InfraID) && aws.StringValue(tag.TagValue) == clusterTagValue {
+			owned = true
+		}
+	}
+	if !owned {
+		return nil
+	}
+	if _, err := client.DeleteAliasWithContext(ctx, &kms.DeleteAliasInput{AliasName: aws.String(alias)}); err != nil {
+		return []error{fmt.Errorf("cannot delete alias %s: %w", alias, err)}
+	}
+	if state := aws.StringValue(result.KeyMetadata.KeyState); state != kms.KeyStatePendingDeletion {
+		if _, err := client.ScheduleKeyDeletionWithContext(ctx, &kms.ScheduleKeyDeletionInput{
+			KeyId:               aws.String(keyID),
+			PendingWindowInDays: aws.Int64(kmsKeyDeletionWindowDays),
+		}); err != nil {
+			return []error{fmt.Errorf("cannot schedule deletion of KMS key %s: %w", keyID, err)}
+		}
+	}
+	o.Log.Info("Scheduled deletion of KMS key", "id", keyID, "days", kmsKeyDeletionWindowDays)
+	return nil
+}
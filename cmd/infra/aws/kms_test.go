@@ -0,0 +1,120 @@
+package aws
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/kms"
+	"github.com/aws/aws-sdk-go/service/kms/kmsiface"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/aws/aws-sdk-go/service/sts/stsiface"
+	"github.com/go-logr/logr"
+	. "github.com/onsi/gomega"
+)
+
+const testKMSKeyARN = "arn:aws:kms:us-east-1:123456789012:key/1234abcd-12ab-34cd-56ef-1234567890ab"
+
+type fakeKMSClient struct {
+	kmsiface.KMSAPI
+	keys      map[string]*kms.KeyMetadata
+	tags      []*kms.Tag
+	policy    string
+	scheduled []string
+}
+
+func (f *fakeKMSClient) DescribeKeyWithContext(_ aws.Context, in *kms.DescribeKeyInput, _ ...request.Option) (*kms.DescribeKeyOutput, error) {
+	key, ok := f.keys[aws.StringValue(in.KeyId)]
+	if !ok {
+		return nil, &kms.NotFoundException{Message_: aws.String("not found")}
+	}
+	return &kms.DescribeKeyOutput{KeyMetadata: key}, nil
+}
+
+func (f *fakeKMSClient) CreateKeyWithContext(_ aws.Context, in *kms.CreateKeyInput, _ ...request.Option) (*kms.CreateKeyOutput, error) {
+	f.policy = aws.StringValue(in.Policy)
+	f.tags = in.Tags
+	key := &kms.KeyMetadata{KeyId: aws.String("key-1"), Arn: aws.String(testKMSKeyARN), KeyState: aws.String(kms.KeyStateEnabled)}
+	f.keys["key-1"] = key
+	return &kms.CreateKeyOutput{KeyMetadata: key}, nil
+}
+
+func (f *fakeKMSClient) CreateAliasWithContext(_ aws.Context, in *kms.CreateAliasInput, _ ...request.Option) (*kms.CreateAliasOutput, error) {
+	f.keys[aws.StringValue(in.AliasName)] = f.keys[aws.StringValue(in.TargetKeyId)]
+	return &kms.CreateAliasOutput{}, nil
+}
+
+func (f *fakeKMSClient) ListResourceTagsWithContext(_ aws.Context, in *kms.ListResourceTagsInput, _ ...request.Option) (*kms.ListResourceTagsOutput, error) {
+	return &kms.ListResourceTagsOutput{Tags: f.tags}, nil
+}
+
+func (f *fakeKMSClient) DeleteAliasWithContext(_ aws.Context, in *kms.DeleteAliasInput, _ ...request.Option) (*kms.DeleteAliasOutput, error) {
+	delete(f.keys, aws.StringValue(in.AliasName))
+	return &kms.DeleteAliasOutput{}, nil
+}
+
+func (f *fakeKMSClient) ScheduleKeyDeletionWithContext(_ aws.Context, in *kms.ScheduleKeyDeletionInput, _ ...request.Option) (*kms.ScheduleKeyDeletionOutput, error) {
+	f.scheduled = append(f.scheduled, aws.StringValue(in.KeyId))
+	return &kms.ScheduleKeyDeletionOutput{}, nil
+}
+
+type fakeSTSClient struct {
+	stsiface.STSAPI
+}
+
+func (f *fakeSTSClient) GetCallerIdentityWithContext(_ aws.Context, _ *sts.GetCallerIdentityInput, _ ...request.Option) (*sts.GetCallerIdentityOutput, error) {
+	return &sts.GetCallerIdentityOutput{Account: aws.String("123456789012")}, nil
+}
+
+func TestKMSKey(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	client := &fakeKMSClient{keys: map[string]*kms.KeyMetadata{}}
+	o := &CreateInfraOptions{InfraID: "test", Region: "us-east-1", CreateKMSKey: true}
+	keyARN, err := o.kmsKey(context.Background(), logr.Discard(), client, &fakeSTSClient{})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(keyARN).To(Equal(testKMSKeyARN))
+	g.Expect(json.Valid([]byte(client.policy))).To(BeTrue())
+	g.Expect(client.policy).To(ContainSubstring("arn:aws:iam::123456789012:root"))
+	g.Expect(client.policy).To(ContainSubstring("ec2.us-east-1.amazonaws.com"))
+	g.Expect(client.keys).To(HaveKey("alias/test-key"))
+
+	// The key is found by its alias when running again
+	client.policy = ""
+	keyARN, err = o.kmsKey(context.Background(), logr.Discard(), client, &fakeSTSClient{})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(keyARN).To(Equal(testKMSKeyARN))
+	g.Expect(client.policy).To(BeEmpty())
+
+	// A referenced key must be enabled
+	client.keys[testKMSKeyARN] = &kms.KeyMetadata{Arn: aws.String(testKMSKeyARN), KeyState: aws.String(kms.KeyStateDisabled)}
+	o = &CreateInfraOptions{InfraID: "test", Region: "us-east-1", KMSKeyARN: testKMSKeyARN}
+	g.Expect(o.validateKMSKeyOptions()).To(Succeed())
+	_, err = o.kmsKey(context.Background(), logr.Discard(), client, &fakeSTSClient{})
+	g.Expect(err).To(HaveOccurred())
+
+	g.Expect((&CreateInfraOptions{Region: "us-east-1", KMSKeyARN: testKMSKeyARN, CreateKMSKey: true}).validateKMSKeyOptions()).ToNot(Succeed())
+	g.Expect((&CreateInfraOptions{Region: "us-west-2", KMSKeyARN: testKMSKeyARN}).validateKMSKeyOptions()).ToNot(Succeed())
+}
+
+func TestDestroyKMSKey(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	key := &kms.KeyMetadata{KeyId: aws.String("key-1"), KeyState: aws.String(kms.KeyStateEnabled)}
+	client := &fakeKMSClient{keys: map[string]*kms.KeyMetadata{"alias/test-key": key}}
+	o := &DestroyInfraOptions{InfraID: "test", Log: logr.Discard()}
+
+	// Keys without the cluster tag are not deleted
+	g.Expect(o.DestroyKMSKey(context.Background(), client)).To(BeEmpty())
+	g.Expect(client.scheduled).To(BeEmpty())
+
+	client.tags = []*kms.Tag{{TagKey: aws.String(clusterTag("test")), TagValue: aws.String(clusterTagValue)}}
+	g.Expect(o.DestroyKMSKey(context.Background(), client)).To(BeEmpty())
+	g.Expect(client.scheduled).To(Equal([]string{"key-1"}))
+	g.Expect(client.keys).ToNot(HaveKey("alias/test-key"))
+
+	// Nothing to do once the alias is gone
+	g.Expect(o.DestroyKMSKey(context.Background(), client)).To(BeEmpty())
+}
@@ -0,0 +1,197 @@
+package aws
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/go-logr/logr"
+
+	"github.com/openshift/hypershift/support/certs"
+)
+
+const (
+	oidcDiscoveryDocumentPath = "/.well-known/openid-configuration"
+	oidcJWKSPath              = "/openid/v1/jwks"
+	oidcDiscoveryTemplate     = `{
+	"issuer": "%s",
+	"jwks_uri": "%s%s",
+	"response_types_supported": [
+		"id_token"
+	],
+	"subject_types_supported": [
+		"public"
+	],
+	"id_token_signing_alg_values_supported": [
+		"RS256"
+	]
+}`
+
+	// s3ObjectOwnershipBucketOwnerEnforced disables ACLs on a bucket. It is not
+	// known to the vendored SDK yet.
+	s3ObjectOwnershipBucketOwnerEnforced = "BucketOwnerEnforced"
+	s3ErrCodeNotFound                    = "NotFound"
+	s3ErrCodeNoSuchPublicAccessBlock     = "NoSuchPublicAccessBlockConfiguration"
+	s3ErrCodeOwnershipControlsNotFound   = "OwnershipControlsNotFoundError"
+)
+
+// validateOIDCBucketOptions validates the flags for the OIDC bucket and applies
+// OIDCBucket to the bucket configuration the issuer URL is derived from.
+func (o *CreateIAMOptions) validateOIDCBucketOptions() error {
+	if len(o.OIDCBucket) == 0 {
+		if len(o.OIDCPublicKeyFile) > 0 {
+			return errors.New("--oidc-public-key-file can only be specified together with --oidc-bucket")
+		}
+		return nil
+	}
+	if len(o.OIDCStorageProviderS3BucketName) > 0 && o.OIDCStorageProviderS3BucketName != o.OIDCBucket {
+		return fmt.Errorf("--oidc-bucket %s conflicts with --oidc-storage-provider-s3-bucket-name %s", o.OIDCBucket, o.OIDCStorageProviderS3BucketName)
+	}
+	o.OIDCStorageProviderS3BucketName = o.OIDCBucket
+	if len(o.OIDCStorageProviderS3Region) == 0 {
+		o.OIDCStorageProviderS3Region = o.Region
+	}
+	return nil
+}
+
+// ensureOIDCBucket creates the OIDC bucket if it does not exist. An existing bucket
+// must be in the expected region and allow public-read objects, which is how the
+// discovery documents are served.
+func (o *CreateIAMOptions) ensureOIDCBucket(l logr.Logger, client s3iface.S3API) error {
+	bucket := o.OIDCStorageProviderS3BucketName
+	region := o.OIDCStorageProviderS3Region
+	_, err := client.HeadBucket(&s3.HeadBucketInput{Bucket: aws.String(bucket)})
+	if err == nil {
+		if err := validateOIDCBucket(client, bucket, region); err != nil {
+			return err
+		}
+		l.Info("Using existing OIDC bucket", "name", bucket)
+		return nil
+	}
+	if aerr, ok := err.(awserr.Error); !ok || aerr.Code() != s3ErrCodeNotFound {
+		return fmt.Errorf("cannot access OIDC bucket %s: %w", bucket, err)
+	}
+
+	input := &s3.CreateBucketInput{Bucket: aws.String(bucket)}
+	// us-east-1 is the default and must not be given as location constraint
+	if region != "us-east-1" {
+		input.CreateBucketConfiguration = &s3.CreateBucketConfiguration{LocationConstraint: aws.String(region)}
+	}
+	if _, err := client.CreateBucket(input); err != nil {
+		return fmt.Errorf("cannot create OIDC bucket %s: %w", bucket, err)
+	}
+	// New buckets block public ACLs by default
+	if _, err := client.PutPublicAccessBlock(&s3.PutPublicAccessBlockInput{
+		Bucket: aws.String(bucket),
+		PublicAccessBlockConfiguration: &s3.PublicAccessBlockConfiguration{
+			BlockPublicAcls:       aws.Bool(false),
+			IgnorePublicAcls:      aws.Bool(false),
+			BlockPublicPolicy:     aws.Bool(true),
+			RestrictPublicBuckets: aws.Bool(true),
+		},
+	}); err != nil {
+		return fmt.Errorf("cannot allow public ACLs on OIDC bucket %s: %w", bucket, err)
+	}
+	if len(o.additionalIAMTags) > 0 {
+		tagging := &s3.Tagging{}
+		for _, tag := range o.additionalIAMTags {
+			tagging.TagSet = append(tagging.TagSet, &s3.Tag{Key: tag.Key, Value: tag.Value})
+		}
+		if _, err := client.PutBucketTagging(&s3.PutBucketTaggingInput{Bucket: aws.String(bucket), Tagging: tagging}); err != nil {
+			return fmt.Errorf("cannot tag OIDC bucket %s: %w", bucket, err)
+		}
+	}
+	l.Info("Created OIDC bucket", "name", bucket, "region", region)
+	return nil
+}
+
+// validateOIDCBucket checks that an existing bucket can serve the public-read
+// discovery documents.
+func validateOIDCBucket(client s3iface.S3API, bucket, region string) error {
+	location, err := client.GetBucketLocation(&s3.GetBucketLocationInput{Bucket: aws.String(bucket)})
+	if err != nil {
+		return fmt.Errorf("cannot get region of OIDC bucket %s: %w", bucket, err)
+	}
+	if bucketRegion := s3.NormalizeBucketLocation(aws.StringValue(location.LocationConstraint)); bucketRegion != region {
+		return fmt.Errorf("OIDC bucket %s is in region %s, not %s", bucket, bucketRegion, region)
+	}
+
+	accessBlock, err := client.GetPublicAccessBlock(&s3.GetPublicAccessBlockInput{Bucket: aws.String(bucket)})
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); !ok || aerr.Code() != s3ErrCodeNoSuchPublicAccessBlock {
+			return fmt.Errorf("cannot get public access block of OIDC bucket %s: %w", bucket, err)
+		}
+	} else if config := accessBlock.PublicAccessBlockConfiguration; aws.BoolValue(config.BlockPublicAcls) || aws.BoolValue(config.IgnorePublicAcls) {
+		return fmt.Errorf("OIDC bucket %s blocks public ACLs, BlockPublicAcls and IgnorePublicAcls must be disabled", bucket)
+	}
+
+	ownership, err := client.GetBucketOwnershipControls(&s3.GetBucketOwnershipControlsInput{Bucket: aws.String(bucket)})
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); !ok || aerr.Code() != s3ErrCodeOwnershipControlsNotFound {
+			return fmt.Errorf("cannot get ownership controls of OIDC bucket %s: %w", bucket, err)
+		}
+		return nil
+	}
+	for _, rule := range ownership.OwnershipControls.Rules {
+		if aws.StringValue(rule.ObjectOwnership) == s3ObjectOwnershipBucketOwnerEnforced {
+			return fmt.Errorf("OIDC bucket %s has ACLs disabled, its object ownership must not be %s", bucket, s3ObjectOwnershipBucketOwnerEnforced)
+		}
+	}
+	return nil
+}
+
+// UploadOIDCDocuments uploads the discovery document and the JWKS of the
+// service account public key in OIDCPublicKeyFile below the infra ID in the
+// OIDC bucket.
+func (o *CreateIAMOptions) UploadOIDCDocuments(l logr.Logger, client s3iface.S3API) error {
+	pubKey, err := os.ReadFile(o.OIDCPublicKeyFile)
+	if err != nil {
+		return fmt.Errorf("cannot read OIDC public key file: %w", err)
+	}
+	jwks, err := certs.PemToJWKS(pubKey)
+	if err != nil {
+		return fmt.Errorf("cannot generate JWKS from %s: %w", o.OIDCPublicKeyFile, err)
+	}
+	documents := map[string][]byte{
+		oidcDiscoveryDocumentPath: []byte(fmt.Sprintf(oidcDiscoveryTemplate, o.IssuerURL, o.IssuerURL, oidcJWKSPath)),
+		oidcJWKSPath:              jwks,
+	}
+	for _, path := range []string{oidcDiscoveryDocumentPath, oidcJWKSPath} {
+		key := o.InfraID + path
+		if _, err := client.PutObject(&s3.PutObjectInput{
+			ACL:         aws.String(s3.ObjectCannedACLPublicRead),
+			Body:        bytes.NewReader(documents[path]),
+			Bucket:      aws.String(o.OIDCStorageProviderS3BucketName),
+			ContentType: aws.String("application/json"),
+			Key:         aws.String(key),
+		}); err != nil {
+			return fmt.Errorf("cannot upload %s to OIDC bucket %s: %w", key, o.OIDCStorageProviderS3BucketName, err)
+		}
+		l.Info("Uploaded OIDC document", "bucket", o.OIDCStorageProviderS3BucketName, "key", key)
+	}
+	return nil
+}
+
+// DestroyOIDCDocuments deletes the discovery documents of the cluster from the
+// OIDC bucket. The bucket itself is left in place, it may be shared by clusters.
+func (o *DestroyIAMOptions) DestroyOIDCDocuments(client s3iface.S3API) error {
+	for _, path := range []string{oidcDiscoveryDocumentPath, oidcJWKSPath} {
+		key := o.InfraID + path
+		if _, err := client.DeleteObject(&s3.DeleteObjectInput{
+			Bucket: aws.String(o.OIDCBucket),
+			Key:    aws.String(key),
+		}); err != nil {
+			if aerr, ok := err.(awserr.Error); ok && aerr.Code() == s3.ErrCodeNoSuchBucket {
+				return nil
+			}
+			return fmt.Errorf("cannot delete %s from OIDC bucket %s: %w", key, o.OIDCBucket, err)
+		}
+		o.Log.Info("Deleted OIDC document", "bucket", o.OIDCBucket, "key", key)
+	}
+	return nil
+}
@@ -0,0 +1,229 @@
+package aws
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/go-logr/logr"
+	. "github.com/onsi/gomega"
+)
+
+type fakeOIDCBucketClient struct {
+	s3iface.S3API
+	exists      bool
+	location    string
+	accessBlock *s3.PublicAccessBlockConfiguration
+	ownership   string
+
+	created    *s3.CreateBucketInput
+	putBlock   *s3.PublicAccessBlockConfiguration
+	objects    map[string]string
+	objectACLs map[string]string
+}
+
+func (f *fakeOIDCBucketClient) HeadBucket(in *s3.HeadBucketInput) (*s3.HeadBucketOutput, error) {
+	if !f.exists {
+		return nil, awserr.New(s3ErrCodeNotFound, "not found", nil)
+	}
+	return &s3.HeadBucketOutput{}, nil
+}
+
+func (f *fakeOIDCBucketClient) CreateBucket(in *s3.CreateBucketInput) (*s3.CreateBucketOutput, error) {
+	f.created = in
+	return &s3.CreateBucketOutput{}, nil
+}
+
+func (f *fakeOIDCBucketClient) PutPublicAccessBlock(in *s3.PutPublicAccessBlockInput) (*s3.PutPublicAccessBlockOutput, error) {
+	f.putBlock = in.PublicAccessBlockConfiguration
+	return &s3.PutPublicAccessBlockOutput{}, nil
+}
+
+func (f *fakeOIDCBucketClient) GetBucketLocation(in *s3.GetBucketLocationInput) (*s3.GetBucketLocationOutput, error) {
+	return &s3.GetBucketLocationOutput{LocationConstraint: aws.String(f.location)}, nil
+}
+
+func (f *fakeOIDCBucketClient) GetPublicAccessBlock(in *s3.GetPublicAccessBlockInput) (*s3.GetPublicAccessBlockOutput, error) {
+	if f.accessBlock == nil {
+		return nil, awserr.New(s3ErrCodeNoSuchPublicAccessBlock, "not found", nil)
+	}
+	return &s3.GetPublicAccessBlockOutput{PublicAccessBlockConfiguration: f.accessBlock}, nil
+}
+
+func (f *fakeOIDCBucketClient) GetBucketOwnershipControls(in *s3.GetBucketOwnershipControlsInput) (*s3.GetBucketOwnershipControlsOutput, error) {
+	if f.ownership == "" {
+		return nil, awserr.New(s3ErrCodeOwnershipControlsNotFound, "not found", nil)
+	}
+	return &s3.GetBucketOwnershipControlsOutput{OwnershipControls: &s3.OwnershipControls{
+		Rules: []*s3.OwnershipControlsRule{{ObjectOwnership: aws.String(f.ownership)}},
+	}}, nil
+}
+
+func (f *fakeOIDCBucketClient) PutObject(in *s3.PutObjectInput) (*s3.PutObjectOutput, error) {
+	body, err := io.ReadAll(in.Body)
+	if err != nil {
+		return nil, err
+	}
+	f.objects[aws.StringValue(in.Key)] = string(body)
+	f.objectACLs[aws.StringValue(in.Key)] = aws.StringValue(in.ACL)
+	return &s3.PutObjectOutput{}, nil
+}
+
+func TestValidateOIDCBucketOptions(t *testing.T) {
+	testCases := []struct {
+		name           string
+		options        CreateIAMOptions
+		expectedBucket string
+		expectedRegion string
+		expectErr      bool
+	}{
+		{
+			name:    "no bucket",
+			options: CreateIAMOptions{Region: "us-east-1"},
+		},
+		{
+			name:      "public key without bucket",
+			options:   CreateIAMOptions{Region: "us-east-1", OIDCPublicKeyFile: "key.pub"},
+			expectErr: true,
+		},
+		{
+			name:           "region defaults to cluster region",
+			options:        CreateIAMOptions{Region: "us-west-2", OIDCBucket: "oidc"},
+			expectedBucket: "oidc",
+			expectedRegion: "us-west-2",
+		},
+		{
+			name:           "explicit bucket region",
+			options:        CreateIAMOptions{Region: "us-west-2", OIDCBucket: "oidc", OIDCStorageProviderS3Region: "us-east-1"},
+			expectedBucket: "oidc",
+			expectedRegion: "us-east-1",
+		},
+		{
+			name:      "conflicting bucket name",
+			options:   CreateIAMOptions{Region: "us-east-1", OIDCBucket: "oidc", OIDCStorageProviderS3BucketName: "other"},
+			expectErr: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewGomegaWithT(t)
+			err := tc.options.validateOIDCBucketOptions()
+			if tc.expectErr {
+				g.Expect(err).To(HaveOccurred())
+				return
+			}
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(tc.options.OIDCStorageProviderS3BucketName).To(Equal(tc.expectedBucket))
+			g.Expect(tc.options.OIDCStorageProviderS3Region).To(Equal(tc.expectedRegion))
+		})
+	}
+}
+
+func TestEnsureOIDCBucket(t *testing.T) {
+	testCases := []struct {
+		name               string
+		region             string
+		client             *fakeOIDCBucketClient
+		expectCreate       bool
+		expectedConstraint string
+		expectErr          bool
+	}{
+		{
+			name:         "create in us-east-1",
+			region:       "us-east-1",
+			client:       &fakeOIDCBucketClient{},
+			expectCreate: true,
+		},
+		{
+			name:               "create in another region",
+			region:             "eu-west-1",
+			client:             &fakeOIDCBucketClient{},
+			expectCreate:       true,
+			expectedConstraint: "eu-west-1",
+		},
+		{
+			name:   "existing bucket allowing public ACLs",
+			region: "us-east-1",
+			client: &fakeOIDCBucketClient{exists: true, ownership: s3.ObjectOwnershipBucketOwnerPreferred},
+		},
+		{
+			name:      "existing bucket in another region",
+			region:    "us-east-1",
+			client:    &fakeOIDCBucketClient{exists: true, location: "us-west-2"},
+			expectErr: true,
+		},
+		{
+			name:   "existing bucket blocking public ACLs",
+			region: "us-east-1",
+			client: &fakeOIDCBucketClient{exists: true, accessBlock: &s3.PublicAccessBlockConfiguration{
+				BlockPublicAcls:  aws.Bool(true),
+				IgnorePublicAcls: aws.Bool(true),
+			}},
+			expectErr: true,
+		},
+		{
+			name:      "existing bucket with ACLs disabled",
+			region:    "us-east-1",
+			client:    &fakeOIDCBucketClient{exists: true, ownership: s3ObjectOwnershipBucketOwnerEnforced},
+			expectErr: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewGomegaWithT(t)
+			o := &CreateIAMOptions{OIDCStorageProviderS3BucketName: "oidc", OIDCStorageProviderS3Region: tc.region}
+			err := o.ensureOIDCBucket(logr.Discard(), tc.client)
+			if tc.expectErr {
+				g.Expect(err).To(HaveOccurred())
+				return
+			}
+			g.Expect(err).ToNot(HaveOccurred())
+			if !tc.expectCreate {
+				g.Expect(tc.client.created).To(BeNil())
+				return
+			}
+			g.Expect(tc.client.created).ToNot(BeNil())
+			if tc.expectedConstraint == "" {
+				g.Expect(tc.client.created.CreateBucketConfiguration).To(BeNil())
+			} else {
+				g.Expect(aws.StringValue(tc.client.created.CreateBucketConfiguration.LocationConstraint)).To(Equal(tc.expectedConstraint))
+			}
+			g.Expect(aws.BoolValue(tc.client.putBlock.BlockPublicAcls)).To(BeFalse())
+			g.Expect(aws.BoolValue(tc.client.putBlock.IgnorePublicAcls)).To(BeFalse())
+		})
+	}
+}
+
+func TestUploadOIDCDocuments(t *testing.T) {
+	g := NewGomegaWithT(t)
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	g.Expect(err).ToNot(HaveOccurred())
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	g.Expect(err).ToNot(HaveOccurred())
+	keyFile := filepath.Join(t.TempDir(), "sa.pub")
+	g.Expect(os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0600)).To(Succeed())
+
+	client := &fakeOIDCBucketClient{objects: map[string]string{}, objectACLs: map[string]string{}}
+	o := &CreateIAMOptions{
+		InfraID:                         "test",
+		IssuerURL:                       "https://oidc.s3.us-east-1.amazonaws.com/test",
+		OIDCStorageProviderS3BucketName: "oidc",
+		OIDCPublicKeyFile:               keyFile,
+	}
+	g.Expect(o.UploadOIDCDocuments(logr.Discard(), client)).To(Succeed())
+	g.Expect(client.objects).To(HaveLen(2))
+	g.Expect(client.objects["test/.well-known/openid-configuration"]).To(ContainSubstring(`"jwks_uri": "https://oidc.s3.us-east-1.amazonaws.com/test/openid/v1/jwks"`))
+	g.Expect(client.objects["test/openid/v1/jwks"]).To(ContainSubstring(`"kty": "RSA"`))
+	for _, acl := range client.objectACLs {
+		g.Expect(acl).To(Equal(s3.ObjectCannedACLPublicRead))
+	}
+}
@@ -0,0 +1,38 @@
+package aws
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws/endpoints"
+)
+
+// partitionForRegion returns the partition the region belongs to, e.g. aws-us-gov
+// for us-gov-west-1 or aws-cn for cn-north-1. Unknown regions are assumed to be in
+// the standard aws partition.
+func partitionForRegion(region string) endpoints.Partition {
+	if partition, ok := endpoints.PartitionForRegion(endpoints.DefaultPartitions(), region); ok {
+		return partition
+	}
+	return endpoints.AwsPartition()
+}
+
+// partitionID returns the ID of the partition of the region, as used in ARNs.
+func partitionID(region string) string {
+	return partitionForRegion(region).ID()
+}
+
+// servicePrincipal returns the IAM principal of an AWS service in the partition of
+// the region, e.g. ec2.amazonaws.com.cn in the China regions.
+func servicePrincipal(region, service string) string {
+	return fmt.Sprintf("%s.%s", service, partitionForRegion(region).DNSSuffix())
+}
+
+// vpcEndpointServiceName returns the name of the VPC endpoint service of an AWS
+// service in the region. The names are prefixed with cn in the China regions.
+func vpcEndpointServiceName(region, service string) string {
+	name := fmt.Sprintf("com.amazonaws.%s.%s", region, service)
+	if partitionID(region) == endpoints.AwsCnPartitionID {
+		name = "cn." + name
+	}
+	return name
+}
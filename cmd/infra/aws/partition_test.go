@@ -0,0 +1,63 @@
+package aws
+
+import (
+	"encoding/json"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestPartitionHelpers(t *testing.T) {
+	testCases := []struct {
+		region              string
+		partition           string
+		ec2Principal        string
+		s3EndpointService   string
+		oidcDiscoveryURL    string
+		route53ZoneResource string
+	}{
+		{
+			region:              "us-east-1",
+			partition:           "aws",
+			ec2Principal:        "ec2.amazonaws.com",
+			s3EndpointService:   "com.amazonaws.us-east-1.s3",
+			oidcDiscoveryURL:    "https://bucket.s3.us-east-1.amazonaws.com/infra",
+			route53ZoneResource: "arn:aws:route53:::hostedzone/Z1",
+		},
+		{
+			region:              "us-gov-west-1",
+			partition:           "aws-us-gov",
+			ec2Principal:        "ec2.amazonaws.com",
+			s3EndpointService:   "com.amazonaws.us-gov-west-1.s3",
+			oidcDiscoveryURL:    "https://bucket.s3.us-gov-west-1.amazonaws.com/infra",
+			route53ZoneResource: "arn:aws-us-gov:route53:::hostedzone/Z1",
+		},
+		{
+			region:              "cn-north-1",
+			partition:           "aws-cn",
+			ec2Principal:        "ec2.amazonaws.com.cn",
+			s3EndpointService:   "cn.com.amazonaws.cn-north-1.s3",
+			oidcDiscoveryURL:    "https://bucket.s3.cn-north-1.amazonaws.com.cn/infra",
+			route53ZoneResource: "arn:aws-cn:route53:::hostedzone/Z1",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.region, func(t *testing.T) {
+			g := NewGomegaWithT(t)
+			g.Expect(partitionID(tc.region)).To(Equal(tc.partition))
+			g.Expect(servicePrincipal(tc.region, "ec2")).To(Equal(tc.ec2Principal))
+			g.Expect(vpcEndpointServiceName(tc.region, "s3")).To(Equal(tc.s3EndpointService))
+			g.Expect(oidcDiscoveryURL("bucket", tc.region, "infra")).To(Equal(tc.oidcDiscoveryURL))
+
+			policy := controlPlaneOperatorPolicy(partitionID(tc.region), "Z1")
+			g.Expect(json.Valid([]byte(policy))).To(BeTrue())
+			g.Expect(policy).To(ContainSubstring(tc.route53ZoneResource))
+			policy = ingressPermPolicy(partitionID(tc.region), "Z1", "Z2")
+			g.Expect(json.Valid([]byte(policy))).To(BeTrue())
+			g.Expect(policy).To(ContainSubstring(tc.route53ZoneResource))
+			policy = workerAssumeRolePolicy(tc.region)
+			g.Expect(json.Valid([]byte(policy))).To(BeTrue())
+			g.Expect(policy).To(ContainSubstring(`"Service": "` + tc.ec2Principal + `"`))
+		})
+	}
+}
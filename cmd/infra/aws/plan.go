@@ -0,0 +1,45 @@
+package aws
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/go-logr/logr"
+)
+
+const plannedIDPrefix = "planned-"
+
+// PlannedAction is a single change that a dry run of create infra would make.
+type PlannedAction struct {
+	Action       string `json:"action"`
+	ResourceType string `json:"resourceType"`
+	Name         string `json:"name,omitempty"`
+	ID           string `json:"id,omitempty"`
+	Details      string `json:"details,omitempty"`
+}
+
+// InfraPlan lists all changes a dry run of create infra would make, in order.
+type InfraPlan struct {
+	Actions []PlannedAction `json:"actions"`
+}
+
+// planCreate records that a resource would be created and returns a placeholder
+// ID that can be passed to subsequent steps in place of the real ID.
+func (o *CreateInfraOptions) planCreate(l logr.Logger, resourceType, name string) string {
+	id := fmt.Sprintf("%s%s-%s", plannedIDPrefix, resourceType, name)
+	o.plan.Actions = append(o.plan.Actions, PlannedAction{Action: "create", ResourceType: resourceType, Name: name, ID: id})
+	l.Info("Would create resource", "type", resourceType, "name", name)
+	return id
+}
+
+// planModify records that an existing or planned resource would be modified.
+func (o *CreateInfraOptions) planModify(l logr.Logger, resourceType, id, details string) {
+	o.plan.Actions = append(o.plan.Actions, PlannedAction{Action: "modify", ResourceType: resourceType, ID: id, Details: details})
+	l.Info("Would modify resource", "type", resourceType, "id", id, "details", details)
+}
+
+// isPlannedID returns true if the ID is a placeholder for a resource that would
+// be created by a dry run and hence can't be looked up.
+func isPlannedID(id string) bool {
+	return strings.HasPrefix(id, plannedIDPrefix)
+}
@@ -0,0 +1,110 @@
+package aws
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+)
+
+const (
+	ProgressStatusStarted   = "started"
+	ProgressStatusCompleted = "completed"
+	ProgressStatusFailed    = "failed"
+	ProgressStatusSkipped   = "skipped"
+)
+
+// ProgressEvent is a machine readable progress event of create and destroy
+// infra. Every phase emits a started event followed by a completed or failed one,
+// or a single skipped event if it was completed by a previous run.
+type ProgressEvent struct {
+	Time            time.Time `json:"time"`
+	Command         string    `json:"command"`
+	InfraID         string    `json:"infraID"`
+	Phase           string    `json:"phase"`
+	Status          string    `json:"status"`
+	ResourceType    string    `json:"resourceType,omitempty"`
+	ResourceID      string    `json:"resourceID,omitempty"`
+	DurationSeconds float64   `json:"durationSeconds,omitempty"`
+	Error           string    `json:"error,omitempty"`
+}
+
+// progressReporter writes progress events as JSON lines. A nil reporter only
+// runs the phases.
+type progressReporter struct {
+	command string
+	infraID string
+	out     io.Writer
+	now     func() time.Time
+}
+
+// openProgressOutput opens the progress output given by path, which is either
+// a file or - for stdout. No reporter is returned for an empty path.
+func openProgressOutput(command, infraID, path string) (*progressReporter, func() error, error) {
+	switch path {
+	case "":
+		return nil, func() error { return nil }, nil
+	case "-":
+		return newProgressReporter(command, infraID, os.Stdout), func() error { return nil }, nil
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot create progress output file: %w", err)
+	}
+	return newProgressReporter(command, infraID, f), f.Close, nil
+}
+
+func newProgressReporter(command, infraID string, out io.Writer) *progressReporter {
+	return &progressReporter{command: command, infraID: infraID, out: out, now: time.Now}
+}
+
+// run runs a phase that creates or looks up a single resource, whose ID is
+// returned by fn.
+func (r *progressReporter) run(phase, resourceType string, fn func() (string, error)) error {
+	if r == nil {
+		_, err := fn()
+		return err
+	}
+	start := r.now()
+	r.emit(ProgressEvent{Time: start, Phase: phase, Status: ProgressStatusStarted, ResourceType: resourceType})
+	id, err := fn()
+	event := ProgressEvent{Phase: phase, Status: ProgressStatusCompleted, ResourceType: resourceType, ResourceID: id}
+	if err != nil {
+		event.Status = ProgressStatusFailed
+		event.Error = err.Error()
+	}
+	event.Time = r.now()
+	event.DurationSeconds = event.Time.Sub(start).Seconds()
+	r.emit(event)
+	return err
+}
+
+// skip reports a phase that is not run because a previous run completed it.
+func (r *progressReporter) skip(phase, resourceType string) {
+	if r == nil {
+		return
+	}
+	r.emit(ProgressEvent{Time: r.now(), Phase: phase, Status: ProgressStatusSkipped, ResourceType: resourceType})
+}
+
+// runAll runs a phase that deletes all resources of a type.
+func (r *progressReporter) runAll(phase string, fn func() []error) []error {
+	var errs []error
+	_ = r.run(phase, "", func() (string, error) {
+		errs = fn()
+		return "", utilerrors.NewAggregate(errs)
+	})
+	return errs
+}
+
+func (r *progressReporter) emit(event ProgressEvent) {
+	event.Command = r.command
+	event.InfraID = r.infraID
+	// Progress is best effort, failing to report it must not fail the command
+	if b, err := json.Marshal(event); err == nil {
+		r.out.Write(append(b, '\n'))
+	}
+}
@@ -0,0 +1,63 @@
+package aws
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+)
+
+func decodeProgressEvents(g *WithT, out string) []ProgressEvent {
+	var events []ProgressEvent
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		var event ProgressEvent
+		g.Expect(json.Unmarshal([]byte(line), &event)).To(Succeed())
+		events = append(events, event)
+	}
+	return events
+}
+
+func TestProgressReporter(t *testing.T) {
+	g := NewGomegaWithT(t)
+	out := &bytes.Buffer{}
+	r := newProgressReporter("create", "test", out)
+	start := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	calls := 0
+	r.now = func() time.Time {
+		calls++
+		return start.Add(time.Duration(calls) * time.Second)
+	}
+
+	g.Expect(r.run("vpc", "vpc", func() (string, error) { return "vpc-1", nil })).To(Succeed())
+	errs := r.runAll("instances", func() []error { return []error{errors.New("access denied")} })
+	g.Expect(errs).To(HaveLen(1))
+
+	events := decodeProgressEvents(g, out.String())
+	g.Expect(events).To(HaveLen(4))
+	for _, event := range events {
+		g.Expect(event.Command).To(Equal("create"))
+		g.Expect(event.InfraID).To(Equal("test"))
+	}
+	g.Expect(events[0].Phase).To(Equal("vpc"))
+	g.Expect(events[0].Status).To(Equal(ProgressStatusStarted))
+	g.Expect(events[1].Status).To(Equal(ProgressStatusCompleted))
+	g.Expect(events[1].ResourceType).To(Equal("vpc"))
+	g.Expect(events[1].ResourceID).To(Equal("vpc-1"))
+	g.Expect(events[1].DurationSeconds).To(Equal(1.0))
+	g.Expect(events[3].Phase).To(Equal("instances"))
+	g.Expect(events[3].Status).To(Equal(ProgressStatusFailed))
+	g.Expect(events[3].Error).To(Equal("access denied"))
+}
+
+func TestNilProgressReporter(t *testing.T) {
+	g := NewGomegaWithT(t)
+	var r *progressReporter
+	ran := false
+	g.Expect(r.run("vpc", "vpc", func() (string, error) { ran = true; return "vpc-1", nil })).To(Succeed())
+	g.Expect(ran).To(BeTrue())
+	g.Expect(r.runAll("instances", func() []error { return []error{errors.New("failed")} })).To(HaveLen(1))
+}
@@ -0,0 +1,386 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/client"
+	"github.com/aws/aws-sdk-go/aws/client/metadata"
+	"github.com/aws/aws-sdk-go/aws/request"
+	v4 "github.com/aws/aws-sdk-go/aws/signer/v4"
+	"github.com/aws/aws-sdk-go/private/protocol/jsonrpc"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	awsutil "github.com/openshift/hypershift/cmd/infra/aws/util"
+)
+
+// The Service Quotas API is not part of the vendored SDK, so its operations are
+// sent as raw JSON RPC requests with the shapes below.
+const (
+	opGetServiceQuota           = "GetServiceQuota"
+	opGetAWSDefaultServiceQuota = "GetAWSDefaultServiceQuota"
+
+	serviceQuotasNoSuchResourceErrorCode = "NoSuchResourceException"
+)
+
+// The quotas that the created infrastructure counts against, by service code and
+// quota code.
+const (
+	quotaServiceEC2 = "ec2"
+	quotaServiceVPC = "vpc"
+
+	quotaCodeVPCsPerRegion             = "L-F678F1CE"
+	quotaCodeInternetGatewaysPerRegion = "L-A4707A72"
+	quotaCodeNATGatewaysPerZone        = "L-FE5A380F"
+	quotaCodeSecurityGroupsPerRegion   = "L-E79EC296"
+	quotaCodeRulesPerSecurityGroup     = "L-0EA8095F"
+	quotaCodeElasticIPs                = "L-0263D0A3"
+	quotaCodeStandardOnDemandVCPUs     = "L-1216C47A"
+)
+
+// standardInstanceFamilies are the instance families whose vCPUs count against
+// the Running On-Demand Standard instances quota.
+var standardInstanceFamilies = sets.NewString("a", "c", "d", "h", "i", "im", "is", "m", "r", "t", "z")
+
+type getServiceQuotaInput struct {
+	_ struct{} `type:"structure"`
+
+	ServiceCode *string `type:"string" required:"true"`
+	QuotaCode   *string `type:"string" required:"true"`
+}
+
+type serviceQuota struct {
+	_ struct{} `type:"structure"`
+
+	QuotaName *string  `type:"string"`
+	Value     *float64 `type:"double"`
+}
+
+type getServiceQuotaOutput struct {
+	_ struct{} `type:"structure"`
+
+	Quota *serviceQuota `type:"structure"`
+}
+
+// serviceQuotasAPI is the subset of the Service Quotas API used to check the
+// quotas before creating the infrastructure.
+type serviceQuotasAPI interface {
+	getServiceQuota(ctx context.Context, serviceCode, quotaCode string) (float64, error)
+}
+
+type serviceQuotasClient struct {
+	client *client.Client
+}
+
+func newServiceQuotasClient(p client.ConfigProvider) serviceQuotasAPI {
+	c := p.ClientConfig("servicequotas", awsutil.NewConfig())
+	svc := client.New(
+		*c.Config,
+		metadata.ClientInfo{
+			ServiceName:   "servicequotas",
+			ServiceID:     "Service Quotas",
+			SigningName:   c.SigningName,
+			SigningRegion: c.SigningRegion,
+			PartitionID:   c.PartitionID,
+			Endpoint:      c.Endpoint,
+			APIVersion:    "2019-06-24",
+			JSONVersion:   "1.1",
+			TargetPrefix:  "ServiceQuotasV20190624",
+		},
+		c.Handlers,
+	)
+	svc.Handlers.Sign.PushBackNamed(v4.SignRequestHandler)
+	svc.Handlers.Build.PushBackNamed(jsonrpc.BuildHandler)
+	svc.Handlers.Unmarshal.PushBackNamed(jsonrpc.UnmarshalHandler)
+	svc.Handlers.UnmarshalMeta.PushBackNamed(jsonrpc.UnmarshalMetaHandler)
+	svc.Handlers.UnmarshalError.PushBackNamed(jsonrpc.UnmarshalErrorHandler)
+	return &serviceQuotasClient{client: svc}
+}
+
+func (c *serviceQuotasClient) send(ctx context.Context, name string, input, output interface{}) error {
+	req := c.client.NewRequest(&request.Operation{Name: name, HTTPMethod: "POST", HTTPPath: "/"}, input, output)
+	req.SetContext(ctx)
+	return req.Send()
+}
+
+// getServiceQuota returns the value of the quota applied to the account, or the
+// default value if the quota was never changed for the account.
+func (c *serviceQuotasClient) getServiceQuota(ctx context.Context, serviceCode, quotaCode string) (float64, error) {
+	input := &getServiceQuotaInput{ServiceCode: aws.String(serviceCode), QuotaCode: aws.String(quotaCode)}
+	output := &getServiceQuotaOutput{}
+	err := c.send(ctx, opGetServiceQuota, input, output)
+	var awsErr awserr.Error
+	if errors.As(err, &awsErr) && awsErr.Code() == serviceQuotasNoSuchResourceErrorCode {
+		err = c.send(ctx, opGetAWSDefaultServiceQuota, input, output)
+	}
+	if err != nil {
+		return 0, err
+	}
+	if output.Quota == nil || output.Quota.Value == nil {
+		return 0, errors.New("no quota value was returned")
+	}
+	return aws.Float64Value(output.Quota.Value), nil
+}
+
+// quotaCheck is a quota that the infrastructure counts against. The check fails
+// if the current usage plus the required amount exceeds the quota.
+type quotaCheck struct {
+	name        string
+	serviceCode string
+	quotaCode   string
+	required    float64
+	usage       func(ctx context.Context, client ec2iface.EC2API) (float64, error)
+}
+
+// quotaChecks returns the quotas that the requested infrastructure counts against.
+// If the VPC of the infrastructure already exists, a previous run created the
+// infrastructure and there is nothing to check.
+func (o *CreateInfraOptions) quotaChecks(ctx context.Context, client ec2iface.EC2API) ([]quotaCheck, error) {
+	newVPC := len(o.VPCID) == 0
+	if newVPC {
+		vpcID, err := o.existingVPC(ctx, client, fmt.Sprintf("%s-vpc", o.InfraID))
+		if err != nil {
+			return nil, err
+		}
+		if len(vpcID) > 0 {
+			return nil, nil
+		}
+	}
+
+	var checks []quotaCheck
+	securityGroups := 0
+	if newVPC {
+		zoneCount := len(o.Zones)
+		if zoneCount == 0 {
+			zoneCount = o.ZoneCount
+		}
+		if zoneCount == 0 {
+			zoneCount = 1
+		}
+		natGateways := 0
+		switch o.natTopology() {
+		case NATTopologyPerZone:
+			natGateways = zoneCount
+		case NATTopologySingle:
+			natGateways = 1
+		}
+		checks = append(checks,
+			quotaCheck{name: "VPCs per Region", serviceCode: quotaServiceVPC, quotaCode: quotaCodeVPCsPerRegion, required: 1, usage: vpcUsage},
+			quotaCheck{name: "Internet gateways per Region", serviceCode: quotaServiceVPC, quotaCode: quotaCodeInternetGatewaysPerRegion, required: 1, usage: internetGatewayUsage},
+		)
+		if natGateways > 0 {
+			checks = append(checks,
+				quotaCheck{name: "NAT gateways per Availability Zone", serviceCode: quotaServiceVPC, quotaCode: quotaCodeNATGatewaysPerZone, required: 1, usage: o.natGatewayUsage},
+				quotaCheck{name: "EC2-VPC Elastic IPs", serviceCode: quotaServiceEC2, quotaCode: quotaCodeElasticIPs, required: float64(natGateways), usage: elasticIPUsage},
+			)
+		}
+		// The default security group of the VPC
+		securityGroups++
+	}
+	if len(o.SecurityGroupID) == 0 {
+		securityGroups++
+		checks = append(checks, quotaCheck{name: "Inbound rules per security group", serviceCode: quotaServiceVPC, quotaCode: quotaCodeRulesPerSecurityGroup, required: float64(o.workerSecurityGroupIngressRuleCount())})
+	}
+	for _, enabled := range []bool{o.EnableProxy, o.Bastion, o.InstanceConnectEndpoint, o.CreateVPCEndpoints} {
+		if enabled {
+			securityGroups++
+		}
+	}
+	if securityGroups > 0 {
+		checks = append(checks, quotaCheck{name: "VPC security groups per Region", serviceCode: quotaServiceVPC, quotaCode: quotaCodeSecurityGroupsPerRegion, required: float64(securityGroups), usage: securityGroupUsage})
+	}
+	// The proxy and bastion hosts are t2.micro instances with a single vCPU
+	vCPUs := 0
+	for _, enabled := range []bool{o.EnableProxy, o.Bastion} {
+		if enabled {
+			vCPUs++
+		}
+	}
+	if vCPUs > 0 {
+		checks = append(checks, quotaCheck{name: "Running On-Demand Standard instances (vCPUs)", serviceCode: quotaServiceEC2, quotaCode: quotaCodeStandardOnDemandVCPUs, required: float64(vCPUs), usage: standardVCPUUsage})
+	}
+	return checks, nil
+}
+
+// workerSecurityGroupIngressRuleCount returns the number of ingress rules of the
+// worker security group. The quota applies to the IPv4 and IPv6 rules separately,
+// so the larger of the two counts is returned.
+func (o *CreateInfraOptions) workerSecurityGroupIngressRuleCount() int {
+	ipv4Rules, ipv6Rules := 0, 0
+	for _, permission := range append(workerSecurityGroupIngressPermissions(o.machineCIDRs(), o.sshIngressCIDRs(), "", ""), o.additionalIngressPermissions...) {
+		ipv4Rules += len(permission.IpRanges) + len(permission.UserIdGroupPairs)
+		ipv6Rules += len(permission.Ipv6Ranges) + len(permission.UserIdGroupPairs)
+	}
+	if ipv6Rules > ipv4Rules {
+		return ipv6Rules
+	}
+	return ipv4Rules
+}
+
+// CheckServiceQuotas compares the quotas of the account with their usage in the region
+// and the resources the infrastructure needs. All quotas that would be exceeded
+// are reported in a single error, before anything is created.
+func (o *CreateInfraOptions) CheckServiceQuotas(ctx context.Context, l logr.Logger, ec2Client ec2iface.EC2API, quotasClient serviceQuotasAPI) error {
+	checks, err := o.quotaChecks(ctx, ec2Client)
+	if err != nil {
+		return err
+	}
+	if len(checks) == 0 {
+		l.Info("No service quotas to check, the VPC already exists")
+		return nil
+	}
+	var exceeded []string
+	for _, check := range checks {
+		quota, err := quotasClient.getServiceQuota(ctx, check.serviceCode, check.quotaCode)
+		if err != nil {
+			return fmt.Errorf("cannot get service quota %s (%s/%s): %w", check.name, check.serviceCode, check.quotaCode, err)
+		}
+		var usage float64
+		if check.usage != nil {
+			if usage, err = check.usage(ctx, ec2Client); err != nil {
+				return err
+			}
+		}
+		l.Info("Checked service quota", "quota", check.name, "value", quota, "usage", usage, "required", check.required)
+		if usage+check.required > quota {
+			exceeded = append(exceeded, fmt.Sprintf("  - %s (%s/%s): %v in use and %v required, but the quota is %v", check.name, check.serviceCode, check.quotaCode, usage, check.required, quota))
+		}
+	}
+	if len(exceeded) > 0 {
+		return fmt.Errorf("the infrastructure would exceed the following service quotas in region %s, request an increase through Service Quotas:\n%s", o.Region, strings.Join(exceeded, "\n"))
+	}
+	return nil
+}
+
+func vpcUsage(ctx context.Context, client ec2iface.EC2API) (float64, error) {
+	count := 0
+	err := client.DescribeVpcsPagesWithContext(ctx, &ec2.DescribeVpcsInput{}, func(out *ec2.DescribeVpcsOutput, _ bool) bool {
+		count += len(out.Vpcs)
+		return true
+	})
+	if err != nil {
+		return 0, fmt.Errorf("cannot list vpcs: %w", err)
+	}
+	return float64(count), nil
+}
+
+func internetGatewayUsage(ctx context.Context, client ec2iface.EC2API) (float64, error) {
+	count := 0
+	err := client.DescribeInternetGatewaysPagesWithContext(ctx, &ec2.DescribeInternetGatewaysInput{}, func(out *ec2.DescribeInternetGatewaysOutput, _ bool) bool {
+		count += len(out.InternetGateways)
+		return true
+	})
+	if err != nil {
+		return 0, fmt.Errorf("cannot list internet gateways: %w", err)
+	}
+	return float64(count), nil
+}
+
+func elasticIPUsage(ctx context.Context, client ec2iface.EC2API) (float64, error) {
+	result, err := client.DescribeAddressesWithContext(ctx, &ec2.DescribeAddressesInput{
+		Filters: []*ec2.Filter{{Name: aws.String("domain"), Values: aws.StringSlice([]string{ec2.DomainTypeVpc})}},
+	})
+	if err != nil {
+		return 0, fmt.Errorf("cannot list elastic IPs: %w", err)
+	}
+	return float64(len(result.Addresses)), nil
+}
+
+func securityGroupUsage(ctx context.Context, client ec2iface.EC2API) (float64, error) {
+	count := 0
+	err := client.DescribeSecurityGroupsPagesWithContext(ctx, &ec2.DescribeSecurityGroupsInput{}, func(out *ec2.DescribeSecurityGroupsOutput, _ bool) bool {
+		count += len(out.SecurityGroups)
+		return true
+	})
+	if err != nil {
+		return 0, fmt.Errorf("cannot list security groups: %w", err)
+	}
+	return float64(count), nil
+}
+
+// natGatewayUsage returns the largest number of NAT gateways in any of the zones
+// of the infrastructure, or in any zone of the region if the zones are not given.
+func (o *CreateInfraOptions) natGatewayUsage(ctx context.Context, client ec2iface.EC2API) (float64, error) {
+	var subnetIDs []*string
+	err := client.DescribeNatGatewaysPagesWithContext(ctx, &ec2.DescribeNatGatewaysInput{
+		Filter: []*ec2.Filter{{Name: aws.String("state"), Values: aws.StringSlice([]string{ec2.NatGatewayStatePending, ec2.NatGatewayStateAvailable})}},
+	}, func(out *ec2.DescribeNatGatewaysOutput, _ bool) bool {
+		for _, natGateway := range out.NatGateways {
+			subnetIDs = append(subnetIDs, natGateway.SubnetId)
+		}
+		return true
+	})
+	if err != nil {
+		return 0, fmt.Errorf("cannot list NAT gateways: %w", err)
+	}
+	if len(subnetIDs) == 0 {
+		return 0, nil
+	}
+	subnetZones := map[string]string{}
+	err = client.DescribeSubnetsPagesWithContext(ctx, &ec2.DescribeSubnetsInput{SubnetIds: subnetIDs}, func(out *ec2.DescribeSubnetsOutput, _ bool) bool {
+		for _, subnet := range out.Subnets {
+			subnetZones[aws.StringValue(subnet.SubnetId)] = aws.StringValue(subnet.AvailabilityZone)
+		}
+		return true
+	})
+	if err != nil {
+		return 0, fmt.Errorf("cannot list subnets: %w", err)
+	}
+	countByZone := map[string]int{}
+	for _, subnetID := range subnetIDs {
+		countByZone[subnetZones[aws.StringValue(subnetID)]]++
+	}
+	zones := o.Zones
+	if len(zones) == 0 {
+		for zone := range countByZone {
+			zones = append(zones, zone)
+		}
+	}
+	max := 0
+	for _, zone := range zones {
+		if countByZone[zone] > max {
+			max = countByZone[zone]
+		}
+	}
+	return float64(max), nil
+}
+
+// standardVCPUUsage returns the number of vCPUs of the pending and running
+// instances of the standard instance families.
+func standardVCPUUsage(ctx context.Context, client ec2iface.EC2API) (float64, error) {
+	count := int64(0)
+	err := client.DescribeInstancesPagesWithContext(ctx, &ec2.DescribeInstancesInput{
+		Filters: []*ec2.Filter{{Name: aws.String("instance-state-name"), Values: aws.StringSlice([]string{ec2.InstanceStateNamePending, ec2.InstanceStateNameRunning})}},
+	}, func(out *ec2.DescribeInstancesOutput, _ bool) bool {
+		for _, reservation := range out.Reservations {
+			for _, instance := range reservation.Instances {
+				if !isStandardInstanceType(aws.StringValue(instance.InstanceType)) || instance.CpuOptions == nil {
+					continue
+				}
+				count += aws.Int64Value(instance.CpuOptions.CoreCount) * aws.Int64Value(instance.CpuOptions.ThreadsPerCore)
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return 0, fmt.Errorf("cannot list instances: %w", err)
+	}
+	return float64(count), nil
+}
+
+// isStandardInstanceType returns whether the family of the instance type, the
+// letters before the generation, is one of the standardInstanceFamilies.
+func isStandardInstanceType(instanceType string) bool {
+	family := instanceType
+	if i := strings.IndexAny(instanceType, "0123456789"); i >= 0 {
+		family = instanceType[:i]
+	}
+	return standardInstanceFamilies.Has(family)
+}
@@ -0,0 +1,278 @@
+package aws
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/go-logr/logr"
+	. "github.com/onsi/gomega"
+)
+
+type fakeServiceQuotasClient struct {
+	quotas    map[string]float64
+	requested []string
+}
+
+func (f *fakeServiceQuotasClient) getServiceQuota(_ context.Context, serviceCode, quotaCode string) (float64, error) {
+	f.requested = append(f.requested, quotaCode)
+	quota, ok := f.quotas[quotaCode]
+	if !ok {
+		return 0, fmt.Errorf("unknown quota %s/%s", serviceCode, quotaCode)
+	}
+	return quota, nil
+}
+
+type fakeQuotasEC2Client struct {
+	ec2iface.EC2API
+	existingVPC      bool
+	vpcs             int
+	internetGateways int
+	addresses        int
+	securityGroups   int
+	natGatewayZones  []string
+	instances        []*ec2.Instance
+}
+
+func (f *fakeQuotasEC2Client) DescribeVpcsPagesWithContext(_ aws.Context, in *ec2.DescribeVpcsInput, fn func(*ec2.DescribeVpcsOutput, bool) bool, _ ...request.Option) error {
+	out := &ec2.DescribeVpcsOutput{}
+	if len(in.Filters) > 0 {
+		if f.existingVPC {
+			out.Vpcs = append(out.Vpcs, &ec2.Vpc{VpcId: aws.String("vpc-existing")})
+		}
+	} else {
+		for i := 0; i < f.vpcs; i++ {
+			out.Vpcs = append(out.Vpcs, &ec2.Vpc{VpcId: aws.String(fmt.Sprintf("vpc-%d", i))})
+		}
+	}
+	fn(out, true)
+	return nil
+}
+
+func (f *fakeQuotasEC2Client) DescribeInternetGatewaysPagesWithContext(_ aws.Context, _ *ec2.DescribeInternetGatewaysInput, fn func(*ec2.DescribeInternetGatewaysOutput, bool) bool, _ ...request.Option) error {
+	fn(&ec2.DescribeInternetGatewaysOutput{InternetGateways: make([]*ec2.InternetGateway, f.internetGateways)}, true)
+	return nil
+}
+
+func (f *fakeQuotasEC2Client) DescribeAddressesWithContext(_ aws.Context, _ *ec2.DescribeAddressesInput, _ ...request.Option) (*ec2.DescribeAddressesOutput, error) {
+	return &ec2.DescribeAddressesOutput{Addresses: make([]*ec2.Address, f.addresses)}, nil
+}
+
+func (f *fakeQuotasEC2Client) DescribeSecurityGroupsPagesWithContext(_ aws.Context, _ *ec2.DescribeSecurityGroupsInput, fn func(*ec2.DescribeSecurityGroupsOutput, bool) bool, _ ...request.Option) error {
+	fn(&ec2.DescribeSecurityGroupsOutput{SecurityGroups: make([]*ec2.SecurityGroup, f.securityGroups)}, true)
+	return nil
+}
+
+// The NAT gateway in position i is in the subnet subnet-i of the zone natGatewayZones[i]
+func (f *fakeQuotasEC2Client) DescribeNatGatewaysPagesWithContext(_ aws.Context, _ *ec2.DescribeNatGatewaysInput, fn func(*ec2.DescribeNatGatewaysOutput, bool) bool, _ ...request.Option) error {
+	out := &ec2.DescribeNatGatewaysOutput{}
+	for i := range f.natGatewayZones {
+		out.NatGateways = append(out.NatGateways, &ec2.NatGateway{SubnetId: aws.String(fmt.Sprintf("subnet-%d", i))})
+	}
+	fn(out, true)
+	return nil
+}
+
+func (f *fakeQuotasEC2Client) DescribeSubnetsPagesWithContext(_ aws.Context, _ *ec2.DescribeSubnetsInput, fn func(*ec2.DescribeSubnetsOutput, bool) bool, _ ...request.Option) error {
+	out := &ec2.DescribeSubnetsOutput{}
+	for i, zone := range f.natGatewayZones {
+		out.Subnets = append(out.Subnets, &ec2.Subnet{SubnetId: aws.String(fmt.Sprintf("subnet-%d", i)), AvailabilityZone: aws.String(zone)})
+	}
+	fn(out, true)
+	return nil
+}
+
+func (f *fakeQuotasEC2Client) DescribeInstancesPagesWithContext(_ aws.Context, _ *ec2.DescribeInstancesInput, fn func(*ec2.DescribeInstancesOutput, bool) bool, _ ...request.Option) error {
+	fn(&ec2.DescribeInstancesOutput{Reservations: []*ec2.Reservation{{Instances: f.instances}}}, true)
+	return nil
+}
+
+func defaultTestQuotas() map[string]float64 {
+	return map[string]float64{
+		quotaCodeVPCsPerRegion:             5,
+		quotaCodeInternetGatewaysPerRegion: 5,
+		quotaCodeNATGatewaysPerZone:        5,
+		quotaCodeSecurityGroupsPerRegion:   2500,
+		quotaCodeRulesPerSecurityGroup:     60,
+		quotaCodeElasticIPs:                5,
+		quotaCodeStandardOnDemandVCPUs:     5,
+	}
+}
+
+func instanceWithVCPUs(instanceType string, cores int64) *ec2.Instance {
+	return &ec2.Instance{InstanceType: aws.String(instanceType), CpuOptions: &ec2.CpuOptions{CoreCount: aws.Int64(cores), ThreadsPerCore: aws.Int64(2)}}
+}
+
+func TestCheckServiceQuotas(t *testing.T) {
+	testCases := []struct {
+		name            string
+		options         CreateInfraOptions
+		client          fakeQuotasEC2Client
+		quotas          map[string]float64
+		expectExceeded  []string
+		expectRequested []string
+	}{
+		{
+			name:    "within quotas",
+			options: CreateInfraOptions{ZoneCount: 3},
+			client:  fakeQuotasEC2Client{vpcs: 4, internetGateways: 4, addresses: 2, natGatewayZones: []string{"us-east-1a", "us-east-1b"}},
+			expectRequested: []string{
+				quotaCodeVPCsPerRegion, quotaCodeInternetGatewaysPerRegion, quotaCodeNATGatewaysPerZone,
+				quotaCodeElasticIPs, quotaCodeRulesPerSecurityGroup, quotaCodeSecurityGroupsPerRegion,
+			},
+		},
+		{
+			name:           "VPCs and elastic IPs exceeded",
+			options:        CreateInfraOptions{ZoneCount: 3},
+			client:         fakeQuotasEC2Client{vpcs: 5, addresses: 3},
+			expectExceeded: []string{"VPCs per Region", "EC2-VPC Elastic IPs"},
+		},
+		{
+			name:    "single NAT gateway needs a single elastic IP",
+			options: CreateInfraOptions{ZoneCount: 3, NATTopology: NATTopologySingle},
+			client:  fakeQuotasEC2Client{addresses: 4},
+		},
+		{
+			name:           "NAT gateways per zone exceeded in a requested zone",
+			options:        CreateInfraOptions{Zones: []string{"us-east-1a"}},
+			client:         fakeQuotasEC2Client{natGatewayZones: []string{"us-east-1a", "us-east-1a", "us-east-1b"}},
+			quotas:         map[string]float64{quotaCodeNATGatewaysPerZone: 2},
+			expectExceeded: []string{"NAT gateways per Availability Zone"},
+		},
+		{
+			name:    "NAT gateways of other zones are ignored",
+			options: CreateInfraOptions{Zones: []string{"us-east-1c"}},
+			client:  fakeQuotasEC2Client{natGatewayZones: []string{"us-east-1a", "us-east-1a", "us-east-1b"}},
+			quotas:  map[string]float64{quotaCodeNATGatewaysPerZone: 2},
+		},
+		{
+			name:            "no NAT gateways",
+			options:         CreateInfraOptions{NATTopology: NATTopologyNone},
+			client:          fakeQuotasEC2Client{addresses: 5},
+			expectRequested: []string{quotaCodeVPCsPerRegion, quotaCodeInternetGatewaysPerRegion, quotaCodeRulesPerSecurityGroup, quotaCodeSecurityGroupsPerRegion},
+		},
+		{
+			name:           "rules per security group exceeded",
+			options:        CreateInfraOptions{},
+			quotas:         map[string]float64{quotaCodeRulesPerSecurityGroup: 5},
+			expectExceeded: []string{"Inbound rules per security group"},
+		},
+		{
+			name:           "security groups exceeded",
+			options:        CreateInfraOptions{Bastion: true},
+			client:         fakeQuotasEC2Client{securityGroups: 2498},
+			expectExceeded: []string{"VPC security groups per Region"},
+		},
+		{
+			name:    "vCPUs of other instance families are ignored",
+			options: CreateInfraOptions{Bastion: true, EnableProxy: true},
+			client:  fakeQuotasEC2Client{instances: []*ec2.Instance{instanceWithVCPUs("p3.2xlarge", 4), instanceWithVCPUs("inf1.xlarge", 2), instanceWithVCPUs("t3.small", 1)}},
+		},
+		{
+			name:           "vCPUs exceeded",
+			options:        CreateInfraOptions{Bastion: true, EnableProxy: true},
+			client:         fakeQuotasEC2Client{instances: []*ec2.Instance{instanceWithVCPUs("m5.large", 1), instanceWithVCPUs("i3en.large", 1)}},
+			expectExceeded: []string{"Running On-Demand Standard instances (vCPUs)"},
+		},
+		{
+			name:            "existing VPC and security group",
+			options:         CreateInfraOptions{VPCID: "vpc-1", SecurityGroupID: "sg-1"},
+			client:          fakeQuotasEC2Client{vpcs: 5},
+			expectRequested: []string{},
+		},
+		{
+			name:            "VPC created by a previous run",
+			options:         CreateInfraOptions{},
+			client:          fakeQuotasEC2Client{existingVPC: true, vpcs: 5},
+			expectRequested: []string{},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewGomegaWithT(t)
+			quotas := defaultTestQuotas()
+			for code, value := range tc.quotas {
+				quotas[code] = value
+			}
+			quotasClient := &fakeServiceQuotasClient{quotas: quotas}
+			tc.options.InfraID = "test"
+			tc.options.Region = "us-east-1"
+			err := tc.options.CheckServiceQuotas(context.Background(), logr.Discard(), &tc.client, quotasClient)
+			if tc.expectRequested != nil {
+				g.Expect(quotasClient.requested).To(ConsistOf(tc.expectRequested))
+			}
+			if len(tc.expectExceeded) == 0 {
+				g.Expect(err).ToNot(HaveOccurred())
+				return
+			}
+			g.Expect(err).To(HaveOccurred())
+			for _, name := range tc.expectExceeded {
+				g.Expect(err.Error()).To(ContainSubstring(name))
+			}
+		})
+	}
+}
+
+func TestIsStandardInstanceType(t *testing.T) {
+	g := NewGomegaWithT(t)
+	for _, instanceType := range []string{"t2.micro", "m5.large", "c6gn.xlarge", "i3en.large", "im4gn.large", "z1d.large"} {
+		g.Expect(isStandardInstanceType(instanceType)).To(BeTrue(), instanceType)
+	}
+	for _, instanceType := range []string{"p3.2xlarge", "g4dn.xlarge", "inf1.xlarge", "dl1.24xlarge", "x1e.xlarge", "mac1.metal"} {
+		g.Expect(isStandardInstanceType(instanceType)).To(BeFalse(), instanceType)
+	}
+}
+
+func TestServiceQuotasClient(t *testing.T) {
+	g := NewGomegaWithT(t)
+	var targets []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		target := r.Header.Get("X-Amz-Target")
+		targets = append(targets, target)
+		var input map[string]string
+		g.Expect(json.NewDecoder(r.Body).Decode(&input)).To(Succeed())
+		w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+		switch {
+		case input["QuotaCode"] == "L-UNSET" && target == "ServiceQuotasV20190624."+opGetServiceQuota:
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"__type":"NoSuchResourceException","message":"not found"}`)
+		case input["QuotaCode"] == "L-DENIED":
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"__type":"AccessDeniedException","message":"denied"}`)
+		default:
+			fmt.Fprintf(w, `{"Quota":{"ServiceCode":%q,"QuotaCode":%q,"Value":12.0}}`, input["ServiceCode"], input["QuotaCode"])
+		}
+	}))
+	defer server.Close()
+	awsSession := session.Must(session.NewSession(&aws.Config{
+		Endpoint:    aws.String(server.URL),
+		Region:      aws.String("us-east-1"),
+		Credentials: credentials.NewStaticCredentials("key", "secret", ""),
+		MaxRetries:  aws.Int(0),
+	}))
+	client := newServiceQuotasClient(awsSession)
+	ctx := context.Background()
+
+	quota, err := client.getServiceQuota(ctx, quotaServiceVPC, quotaCodeVPCsPerRegion)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(quota).To(Equal(12.0))
+	g.Expect(targets).To(Equal([]string{"ServiceQuotasV20190624.GetServiceQuota"}))
+
+	targets = nil
+	quota, err = client.getServiceQuota(ctx, quotaServiceVPC, "L-UNSET")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(quota).To(Equal(12.0))
+	g.Expect(targets).To(Equal([]string{"ServiceQuotasV20190624.GetServiceQuota", "ServiceQuotasV20190624.GetAWSDefaultServiceQuota"}))
+
+	_, err = client.getServiceQuota(ctx, quotaServiceVPC, "L-DENIED")
+	g.Expect(err).To(HaveOccurred())
+}
@@ -0,0 +1,93 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	awsutil "github.com/openshift/hypershift/cmd/infra/aws/util"
+	"github.com/openshift/hypershift/cmd/log"
+)
+
+// errNotFoundYet is returned when a resource that was just created is not visible
+// yet because of the eventual consistency of the AWS APIs.
+var errNotFoundYet = errors.New("not found yet")
+
+// retryOnError calls fn until it succeeds, returns an error that is neither
+// retriable nor a throttling error, or the steps of backoff are exhausted. The wait
+// after a throttling error is at least the Retry-After delay requested by AWS.
+// Waiting stops when ctx is done.
+func retryOnError(ctx context.Context, backoff wait.Backoff, retriable func(error) bool, fn func() error) error {
+	steps := backoff.Steps
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil {
+			return nil
+		}
+		throttled := awsutil.IsErrorThrottle(err)
+		if !throttled && !retriable(err) {
+			return err
+		}
+		if attempt >= steps {
+			return err
+		}
+		delay := backoff.Step()
+		if throttled {
+			if retryAfter, ok := awsutil.RetryAfter(err); ok && retryAfter > delay {
+				delay = retryAfter
+			}
+			log.Log.Info("AWS API request was throttled, will retry", "delay", delay.String(), "throttledRequests", awsutil.ThrottledRequests(), "error", err.Error())
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+// logThrottledRequests logs how many AWS API requests were throttled, which
+// explains slow runs in accounts close to their API rate limits.
+func logThrottledRequests(l logr.Logger) {
+	if count := awsutil.ThrottledRequests(); count > 0 {
+		l.Info("AWS API requests were throttled", "throttledRequests", count)
+	}
+}
+
+// isNotFoundYet returns true for errors of lookups of resources that were just
+// created, which succeed once the resources become visible.
+func isNotFoundYet(err error) bool {
+	if errors.Is(err, errNotFoundYet) {
+		return true
+	}
+	var awsErr awserr.Error
+	return errors.As(err, &awsErr) && strings.HasSuffix(awsErr.Code(), ".NotFound")
+}
+
+// ec2Backoff is the backoff for waiting on EC2 resources that were just created.
+func ec2Backoff() wait.Backoff {
+	return wait.Backoff{
+		Steps:    10,
+		Duration: 2 * time.Second,
+		Factor:   1.5,
+		Jitter:   0.1,
+		Cap:      20 * time.Second,
+	}
+}
+
+// provisioningBackoff is the backoff for waiting on resources that take minutes
+// to become available, such as transit gateway attachments.
+func provisioningBackoff() wait.Backoff {
+	return wait.Backoff{
+		Steps:    30,
+		Duration: 5 * time.Second,
+		Factor:   1.5,
+		Jitter:   0.1,
+		Cap:      30 * time.Second,
+	}
+}
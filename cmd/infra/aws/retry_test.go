@@ -0,0 +1,89 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+func TestRetryOnError(t *testing.T) {
+	throttled := awserr.New("RequestLimitExceeded", "Request limit exceeded.", nil)
+	notFound := awserr.New("InvalidGroup.NotFound", "The security group does not exist", nil)
+	denied := awserr.New("UnauthorizedOperation", "You are not authorized", nil)
+	testCases := []struct {
+		name          string
+		errs          []error
+		expectedCalls int
+		expectedErr   error
+	}{
+		{
+			name:          "success",
+			expectedCalls: 1,
+		},
+		{
+			name:          "throttling is retried even if not retriable",
+			errs:          []error{throttled, throttled},
+			expectedCalls: 3,
+		},
+		{
+			name:          "retriable error is retried",
+			errs:          []error{notFound},
+			expectedCalls: 2,
+		},
+		{
+			name:          "other error is returned",
+			errs:          []error{denied},
+			expectedCalls: 1,
+			expectedErr:   denied,
+		},
+		{
+			name:          "last error is returned when steps are exhausted",
+			errs:          []error{notFound, notFound, notFound, notFound},
+			expectedCalls: 3,
+			expectedErr:   notFound,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewGomegaWithT(t)
+			calls := 0
+			err := retryOnError(context.Background(), wait.Backoff{Steps: 3, Duration: time.Millisecond}, isNotFoundYet, func() error {
+				calls++
+				if calls <= len(tc.errs) {
+					return tc.errs[calls-1]
+				}
+				return nil
+			})
+			g.Expect(calls).To(Equal(tc.expectedCalls))
+			if tc.expectedErr != nil {
+				g.Expect(err).To(Equal(tc.expectedErr))
+			} else {
+				g.Expect(err).ToNot(HaveOccurred())
+			}
+		})
+	}
+}
+
+func TestRetryOnErrorCanceled(t *testing.T) {
+	g := NewGomegaWithT(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := retryOnError(ctx, wait.Backoff{Steps: 3, Duration: time.Hour}, isNotFoundYet, func() error {
+		return errNotFoundYet
+	})
+	g.Expect(err).To(MatchError(context.Canceled))
+}
+
+func TestIsNotFoundYet(t *testing.T) {
+	g := NewGomegaWithT(t)
+	g.Expect(isNotFoundYet(errNotFoundYet)).To(BeTrue())
+	g.Expect(isNotFoundYet(fmt.Errorf("lookup failed: %w", awserr.New("InvalidSubnetID.NotFound", "", nil)))).To(BeTrue())
+	g.Expect(isNotFoundYet(awserr.New("UnauthorizedOperation", "", nil))).To(BeFalse())
+	g.Expect(isNotFoundYet(errors.New("other"))).To(BeFalse())
+}
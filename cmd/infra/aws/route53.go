@@ -2,6 +2,7 @@ package aws
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strconv"
 	"strings"
@@ -13,7 +14,6 @@ import (
 	awsutil "github.com/openshift/hypershift/cmd/infra/aws/util"
 	"github.com/openshift/hypershift/cmd/log"
 	"k8s.io/apimachinery/pkg/util/wait"
-	"k8s.io/client-go/util/retry"
 )
 
 func (o *CreateInfraOptions) LookupPublicZone(ctx context.Context, client route53iface.Route53API) (string, error) {
@@ -27,6 +27,8 @@ func (o *CreateInfraOptions) LookupPublicZone(ctx context.Context, client route5
 	return id, nil
 }
 
+var errHostedZoneNotFound = errors.New("hosted zone not found")
+
 func lookupZone(ctx context.Context, client route53iface.Route53API, name string, isPrivateZone bool) (string, error) {
 	var res *route53.HostedZone
 	f := func(resp *route53.ListHostedZonesOutput, lastPage bool) (shouldContinue bool) {
@@ -47,20 +49,29 @@ func lookupZone(ctx context.Context, client route53iface.Route53API, name string
 		return "", fmt.Errorf("failed to list hosted zones: %w", err)
 	}
 	if res == nil {
-		return "", fmt.Errorf("hosted zone %s not found", name)
+		return "", fmt.Errorf("%w: %s", errHostedZoneNotFound, name)
 	}
 	return cleanZoneID(*res.Id), nil
 }
 
 func (o *CreateInfraOptions) CreatePrivateZone(ctx context.Context, client route53iface.Route53API, name, vpcID string) (string, error) {
 	id, err := lookupZone(ctx, client, name, true)
+	if err == nil && o.DryRun {
+		log.Log.Info("Found existing private zone", "name", name, "id", id)
+		o.planModify(log.Log, "hosted-zone", id, "set SOA minimum TTL to 60")
+		return id, o.reconcileZoneTags(ctx, log.Log, client, id)
+	}
 	if err == nil {
 		log.Log.Info("Found existing private zone", "name", name, "id", id)
 		err := setSOAMinimum(ctx, client, id, name)
 		if err != nil {
 			return "", err
 		}
-		return id, err
+		return id, o.reconcileZoneTags(ctx, log.Log, client, id)
+	}
+
+	if o.DryRun {
+		return o.planCreate(log.Log, "hosted-zone", name), nil
 	}
 
 	var res *route53.CreateHostedZoneOutput
@@ -96,27 +107,34 @@ func (o *CreateInfraOptions) CreatePrivateZone(ctx context.Context, client route
 		return "", err
 	}
 
-	return id, nil
+	return id, o.reconcileZoneTags(ctx, log.Log, client, id)
 }
 
-func (o *DestroyInfraOptions) DestroyDNS(ctx context.Context, client route53iface.Route53API) []error {
+func (o *DestroyInfraOptions) DestroyDNS(ctx context.Context, client, parentClient route53iface.Route53API) []error {
 	var errs []error
 	errs = append(errs, o.CleanupPublicZone(ctx, client))
+	if len(o.ParentZoneID) > 0 {
+		errs = append(errs, o.DestroyDelegatedPublicZone(ctx, client, parentClient))
+	}
 	return errs
 }
 
 func (o *DestroyInfraOptions) DestroyPrivateZones(ctx context.Context, client route53iface.Route53API, vpcID *string) []error {
-	var output *route53.ListHostedZonesByVPCOutput
-	if err := retryRoute53WithBackoff(ctx, func() (err error) {
-		output, err = client.ListHostedZonesByVPCWithContext(ctx, &route53.ListHostedZonesByVPCInput{VPCId: vpcID, VPCRegion: aws.String(o.Region)})
-		return err
-	}); err != nil {
-		return []error{fmt.Errorf("failed to list hosted zones for vpc %s: %w", *vpcID, err)}
+	zones, err := o.privateZones(ctx, client, vpcID)
+	if err != nil {
+		return []error{err}
 	}
 
 	var errs []error
-	for _, zone := range output.HostedZoneSummaries {
+	for _, zone := range zones {
 		id := cleanZoneID(*zone.HostedZoneId)
+		shared, err := o.disassociateSharedPrivateZone(ctx, client, id, vpcID)
+		if err != nil {
+			return []error{err}
+		}
+		if shared {
+			continue
+		}
 		if err := deleteZone(ctx, id, client); err != nil {
 			return []error{fmt.Errorf("failed to delete private hosted zones for vpc %s: %w", *vpcID, err)}
 		}
@@ -126,6 +144,28 @@ func (o *DestroyInfraOptions) DestroyPrivateZones(ctx context.Context, client ro
 	return errs
 }
 
+// privateZones returns the private hosted zones associated with the VPC.
+func (o *DestroyInfraOptions) privateZones(ctx context.Context, client route53iface.Route53API, vpcID *string) ([]*route53.HostedZoneSummary, error) {
+	// ListHostedZonesByVPC has no pages helper, so follow NextToken ourselves
+	var zones []*route53.HostedZoneSummary
+	input := &route53.ListHostedZonesByVPCInput{VPCId: vpcID, VPCRegion: aws.String(o.Region)}
+	for {
+		var output *route53.ListHostedZonesByVPCOutput
+		if err := retryRoute53WithBackoff(ctx, func() (err error) {
+			output, err = client.ListHostedZonesByVPCWithContext(ctx, input)
+			return err
+		}); err != nil {
+			return nil, fmt.Errorf("failed to list hosted zones for vpc %s: %w", *vpcID, err)
+		}
+		zones = append(zones, output.HostedZoneSummaries...)
+		if aws.StringValue(output.NextToken) == "" {
+			break
+		}
+		input.NextToken = output.NextToken
+	}
+	return zones, nil
+}
+
 func (o *DestroyInfraOptions) CleanupPublicZone(ctx context.Context, client route53iface.Route53API) error {
 	name := o.BaseDomain
 	id, err := lookupZone(ctx, client, name, false)
@@ -191,26 +231,23 @@ func deleteRecords(ctx context.Context, client route53iface.Route53API, id strin
 	lrrsi := &route53.ListResourceRecordSetsInput{
 		HostedZoneId: aws.String(id),
 	}
-	output, err := client.ListResourceRecordSetsWithContext(ctx, lrrsi)
-	if err != nil {
-		return err
-	}
-	if len(output.ResourceRecordSets) == 0 {
-		return nil
-	}
 	var changeBatch route53.ChangeBatch
-	var deleteRequired bool
-	for _, rrs := range output.ResourceRecordSets {
-		if *rrs.Type == "NS" || *rrs.Type == "SOA" {
-			continue
+	err := client.ListResourceRecordSetsPagesWithContext(ctx, lrrsi, func(output *route53.ListResourceRecordSetsOutput, _ bool) bool {
+		for _, rrs := range output.ResourceRecordSets {
+			if *rrs.Type == "NS" || *rrs.Type == "SOA" {
+				continue
+			}
+			changeBatch.Changes = append(changeBatch.Changes, &route53.Change{
+				Action:            aws.String("DELETE"),
+				ResourceRecordSet: rrs,
+			})
 		}
-		deleteRequired = true
-		changeBatch.Changes = append(changeBatch.Changes, &route53.Change{
-			Action:            aws.String("DELETE"),
-			ResourceRecordSet: rrs,
-		})
+		return true
+	})
+	if err != nil {
+		return err
 	}
-	if !deleteRequired {
+	if len(changeBatch.Changes) == 0 {
 		return nil
 	}
 	crrsi := &route53.ChangeResourceRecordSetsInput{
@@ -321,20 +358,10 @@ func retryRoute53WithBackoff(ctx context.Context, fn func() error) error {
 		Duration: 1 * time.Second,
 		Steps:    10,
 		Factor:   1.5,
-	}
-	retriable := func(e error) bool {
-		if !awsutil.IsErrorRetryable(e) {
-			return false
-		}
-		select {
-		case <-ctx.Done():
-			return false
-		default:
-			return true
-		}
+		Jitter:   0.1,
 	}
 	// TODO: inspect the error for throttling details?
-	return retry.OnError(backoff, retriable, fn)
+	return retryOnError(ctx, backoff, awsutil.IsErrorRetryable, fn)
 }
 
 func isRoute53RecordNotFoundErr(err error) bool {
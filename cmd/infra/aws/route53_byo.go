@@ -0,0 +1,360 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/arn"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/route53"
+	"github.com/aws/aws-sdk-go/service/route53/route53iface"
+
+	awsutil "github.com/openshift/hypershift/cmd/infra/aws/util"
+	"github.com/openshift/hypershift/cmd/log"
+)
+
+// delegationRecordTTL is the TTL of the NS records created in the parent zone.
+const delegationRecordTTL = 300
+
+// validateHostedZoneOptions validates the flags for existing and delegated hosted zones.
+func (o *CreateInfraOptions) validateHostedZoneOptions() error {
+	if len(o.ParentZoneID) == 0 {
+		if len(o.ParentZoneRoleARN) > 0 {
+			return errors.New("--parent-zone-role-arn can only be specified together with --parent-zone-id")
+		}
+		return nil
+	}
+	if len(o.PublicZoneID) > 0 {
+		return errors.New("--public-zone-id and --parent-zone-id are mutually exclusive")
+	}
+	return validateParentZoneRoleOptions(o.ParentZoneRoleARN, o.ParentZoneExternalID)
+}
+
+func validateParentZoneRoleOptions(roleARN, externalID string) error {
+	if len(roleARN) == 0 {
+		if len(externalID) > 0 {
+			return errors.New("--parent-zone-external-id can only be specified together with --parent-zone-role-arn")
+		}
+		return nil
+	}
+	if _, err := arn.Parse(roleARN); err != nil {
+		return fmt.Errorf("invalid --parent-zone-role-arn %q: %w", roleARN, err)
+	}
+	return nil
+}
+
+// parentZoneClient returns a client for the parent zone. If a role is given, it is
+// assumed with the credentials of awsSession, which allows the parent zone to be
+// owned by a different account than the cluster infrastructure.
+func parentZoneClient(awsSession *session.Session, agent, roleARN, externalID string) route53iface.Route53API {
+	if len(roleARN) > 0 {
+		awsSession = awsutil.AssumeRole(awsSession, roleARN, externalID, fmt.Sprintf("hypershift-%s", agent), nil)
+	}
+	return route53.New(awsSession, awsutil.NewAWSRoute53Config())
+}
+
+// publicZone returns the ID of the public zone of the base domain. It is the zone
+// given by PublicZoneID, a zone delegated from ParentZoneID, or the public zone
+// found by the name of the base domain.
+func (o *CreateInfraOptions) publicZone(ctx context.Context, client, parentClient route53iface.Route53API) (string, error) {
+	switch {
+	case len(o.PublicZoneID) > 0:
+		if _, err := getExistingZone(ctx, client, o.PublicZoneID, o.BaseDomain, false); err != nil {
+			return "", err
+		}
+		log.Log.Info("Using existing public zone", "name", o.BaseDomain, "id", o.PublicZoneID)
+		return o.PublicZoneID, nil
+	case len(o.ParentZoneID) > 0:
+		return o.CreateDelegatedPublicZone(ctx, client, parentClient)
+	default:
+		return o.LookupPublicZone(ctx, client)
+	}
+}
+
+// privateZone returns the ID of the private zone of the cluster, either the zone
+// given by PrivateZoneID or a zone created for the cluster.
+func (o *CreateInfraOptions) privateZone(ctx context.Context, client route53iface.Route53API, vpcID string) (string, error) {
+	name := fmt.Sprintf("%s.%s", o.Name, o.BaseDomain)
+	if len(o.PrivateZoneID) == 0 {
+		return o.CreatePrivateZone(ctx, client, name, vpcID)
+	}
+	if err := o.useExistingPrivateZone(ctx, client, name, vpcID); err != nil {
+		return "", err
+	}
+	return o.PrivateZoneID, nil
+}
+
+// getExistingZone returns the hosted zone with the given ID after validating its
+// name and visibility.
+func getExistingZone(ctx context.Context, client route53iface.Route53API, id, name string, isPrivateZone bool) (*route53.GetHostedZoneOutput, error) {
+	// Zone IDs are given by the user, a missing zone is not retried
+	zone, err := client.GetHostedZoneWithContext(ctx, &route53.GetHostedZoneInput{Id: aws.String(id)})
+	if err != nil {
+		return nil, fmt.Errorf("cannot find hosted zone %s: %w", id, err)
+	}
+	if zoneName := strings.TrimSuffix(aws.StringValue(zone.HostedZone.Name), "."); zoneName != strings.TrimSuffix(name, ".") {
+		return nil, fmt.Errorf("hosted zone %s is for %s, expected %s", id, zoneName, name)
+	}
+	if private := zone.HostedZone.Config != nil && aws.BoolValue(zone.HostedZone.Config.PrivateZone); private != isPrivateZone {
+		if isPrivateZone {
+			return nil, fmt.Errorf("hosted zone %s must be a private zone", id)
+		}
+		return nil, fmt.Errorf("hosted zone %s must be a public zone", id)
+	}
+	return zone, nil
+}
+
+// useExistingPrivateZone associates the VPC with the private zone given by
+// PrivateZoneID and tags the zone as shared with the cluster, which keeps it from
+// being deleted when the cluster infrastructure is destroyed.
+func (o *CreateInfraOptions) useExistingPrivateZone(ctx context.Context, client route53iface.Route53API, name, vpcID string) error {
+	id := o.PrivateZoneID
+	zone, err := getExistingZone(ctx, client, id, name, true)
+	if err != nil {
+		return err
+	}
+	log.Log.Info("Using existing private zone", "name", name, "id", id)
+	associated := false
+	for _, vpc := range zone.VPCs {
+		if aws.StringValue(vpc.VPCId) == vpcID {
+			associated = true
+		}
+	}
+	if !associated {
+		if o.DryRun {
+			o.planModify(log.Log, "hosted-zone", id, fmt.Sprintf("associate vpc %s", vpcID))
+		} else {
+			if err := retryRoute53WithBackoff(ctx, func() error {
+				_, err := client.AssociateVPCWithHostedZoneWithContext(ctx, &route53.AssociateVPCWithHostedZoneInput{
+					HostedZoneId: aws.String(id),
+					VPC:          &route53.VPC{VPCId: aws.String(vpcID), VPCRegion: aws.String(o.Region)},
+				})
+				return err
+			}); err != nil {
+				return fmt.Errorf("cannot associate vpc %s with hosted zone %s: %w", vpcID, id, err)
+			}
+			log.Log.Info("Associated vpc with private zone", "id", id, "vpc", vpcID)
+		}
+	}
+	shared, err := zoneHasTag(ctx, client, id, clusterTag(o.InfraID), sharedClusterTagValue)
+	if err != nil {
+		return err
+	}
+	if !shared {
+		if o.DryRun {
+			o.planModify(log.Log, "tag", id, fmt.Sprintf("tag %s=%s", clusterTag(o.InfraID), sharedClusterTagValue))
+		} else if err := tagZone(ctx, client, id, clusterTag(o.InfraID), sharedClusterTagValue); err != nil {
+			return err
+		}
+	}
+	if o.DryRun {
+		o.planModify(log.Log, "hosted-zone", id, "set SOA minimum TTL to 60")
+		return nil
+	}
+	return setSOAMinimum(ctx, client, id, name)
+}
+
+// CreateDelegatedPublicZone creates a public zone for the base domain, unless it
+// exists already, and delegates the base domain to it from the parent zone given by
+// ParentZoneID. A created zone is tagged as owned by the cluster, so that it is
+// deleted together with the delegation when the cluster infrastructure is destroyed.
+func (o *CreateInfraOptions) CreateDelegatedPublicZone(ctx context.Context, client, parentClient route53iface.Route53API) (string, error) {
+	parent, err := getParentZone(ctx, parentClient, o.ParentZoneID, o.BaseDomain)
+	if err != nil {
+		return "", err
+	}
+
+	id, err := lookupZone(ctx, client, o.BaseDomain, false)
+	if err != nil && !errors.Is(err, errHostedZoneNotFound) {
+		return "", err
+	}
+	if err == nil {
+		log.Log.Info("Found existing public zone", "name", o.BaseDomain, "id", id)
+	} else if o.DryRun {
+		id = o.planCreate(log.Log, "hosted-zone", o.BaseDomain)
+	} else {
+		var res *route53.CreateHostedZoneOutput
+		if err := retryRoute53WithBackoff(ctx, func() (err error) {
+			res, err = client.CreateHostedZoneWithContext(ctx, &route53.CreateHostedZoneInput{
+				CallerReference: aws.String(fmt.Sprintf("%d", time.Now().Unix())),
+				Name:            aws.String(o.BaseDomain),
+			})
+			return err
+		}); err != nil {
+			return "", fmt.Errorf("failed to create hosted zone: %w", err)
+		}
+		id = cleanZoneID(aws.StringValue(res.HostedZone.Id))
+		log.Log.Info("Created public zone", "name", o.BaseDomain, "id", id)
+		if err := tagZone(ctx, client, id, clusterTag(o.InfraID), clusterTagValue); err != nil {
+			return "", err
+		}
+		if err := o.reconcileZoneTags(ctx, log.Log, client, id); err != nil {
+			return "", err
+		}
+	}
+
+	if o.DryRun && isPlannedID(id) {
+		o.planModify(log.Log, "hosted-zone", parent, fmt.Sprintf("delegate %s to %s", o.BaseDomain, id))
+		return id, nil
+	}
+	var zone *route53.GetHostedZoneOutput
+	if err := retryRoute53WithBackoff(ctx, func() (err error) {
+		zone, err = client.GetHostedZoneWithContext(ctx, &route53.GetHostedZoneInput{Id: aws.String(id)})
+		return err
+	}); err != nil {
+		return "", fmt.Errorf("cannot get name servers of hosted zone %s: %w", id, err)
+	}
+	if zone.DelegationSet == nil || len(zone.DelegationSet.NameServers) == 0 {
+		return "", fmt.Errorf("hosted zone %s has no name servers", id)
+	}
+	if o.DryRun {
+		o.planModify(log.Log, "hosted-zone", parent, fmt.Sprintf("delegate %s to %v", o.BaseDomain, aws.StringValueSlice(zone.DelegationSet.NameServers)))
+		return id, nil
+	}
+	record := &route53.ResourceRecordSet{
+		Name: aws.String(fqdn(o.BaseDomain)),
+		Type: aws.String(route53.RRTypeNs),
+		TTL:  aws.Int64(delegationRecordTTL),
+	}
+	for _, nameServer := range zone.DelegationSet.NameServers {
+		record.ResourceRecords = append(record.ResourceRecords, &route53.ResourceRecord{Value: nameServer})
+	}
+	if err := changeRecord(ctx, parentClient, parent, route53.ChangeActionUpsert, record); err != nil {
+		return "", fmt.Errorf("cannot delegate %s from hosted zone %s: %w", o.BaseDomain, parent, err)
+	}
+	log.Log.Info("Delegated public zone from parent zone", "name", o.BaseDomain, "id", id, "parent", parent)
+	return id, nil
+}
+
+// getParentZone validates that the public zone with the given ID is a parent of
+// the domain and returns its ID.
+func getParentZone(ctx context.Context, client route53iface.Route53API, id, domain string) (string, error) {
+	zone, err := client.GetHostedZoneWithContext(ctx, &route53.GetHostedZoneInput{Id: aws.String(id)})
+	if err != nil {
+		return "", fmt.Errorf("cannot find parent hosted zone %s: %w", id, err)
+	}
+	if zone.HostedZone.Config != nil && aws.BoolValue(zone.HostedZone.Config.PrivateZone) {
+		return "", fmt.Errorf("parent hosted zone %s must be a public zone", id)
+	}
+	parentName := strings.TrimSuffix(aws.StringValue(zone.HostedZone.Name), ".")
+	if !strings.HasSuffix(strings.TrimSuffix(domain, "."), "."+parentName) {
+		return "", fmt.Errorf("%s is not a subdomain of %s, the domain of parent hosted zone %s", domain, parentName, id)
+	}
+	return cleanZoneID(aws.StringValue(zone.HostedZone.Id)), nil
+}
+
+func changeRecord(ctx context.Context, client route53iface.Route53API, id, action string, record *route53.ResourceRecordSet) error {
+	return retryRoute53WithBackoff(ctx, func() error {
+		_, err := client.ChangeResourceRecordSetsWithContext(ctx, &route53.ChangeResourceRecordSetsInput{
+			HostedZoneId: aws.String(id),
+			ChangeBatch: &route53.ChangeBatch{
+				Changes: []*route53.Change{{Action: aws.String(action), ResourceRecordSet: record}},
+			},
+		})
+		return err
+	})
+}
+
+func zoneHasTag(ctx context.Context, client route53iface.Route53API, id, key, value string) (bool, error) {
+	var output *route53.ListTagsForResourceOutput
+	if err := retryRoute53WithBackoff(ctx, func() (err error) {
+		output, err = client.ListTagsForResourceWithContext(ctx, &route53.ListTagsForResourceInput{
+			ResourceId:   aws.String(id),
+			ResourceType: aws.String(route53.TagResourceTypeHostedzone),
+		})
+		return err
+	}); err != nil {
+		return false, fmt.Errorf("cannot list tags of hosted zone %s: %w", id, err)
+	}
+	for _, tag := range output.ResourceTagSet.Tags {
+		if aws.StringValue(tag.Key) == key && aws.StringValue(tag.Value) == value {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func tagZone(ctx context.Context, client route53iface.Route53API, id, key, value string) error {
+	if err := retryRoute53WithBackoff(ctx, func() error {
+		_, err := client.ChangeTagsForResourceWithContext(ctx, &route53.ChangeTagsForResourceInput{
+			ResourceId:   aws.String(id),
+			ResourceType: aws.String(route53.TagResourceTypeHostedzone),
+			AddTags:      []*route53.Tag{{Key: aws.String(key), Value: aws.String(value)}},
+		})
+		return err
+	}); err != nil {
+		return fmt.Errorf("cannot tag hosted zone %s: %w", id, err)
+	}
+	log.Log.Info("Tagged hosted zone", "id", id, "tag", key, "value", value)
+	return nil
+}
+
+// DestroyDelegatedPublicZone deletes the public zone of the base domain and its
+// delegation from the parent zone given by ParentZoneID. Zones that are not owned by
+// the cluster are left in place.
+func (o *DestroyInfraOptions) DestroyDelegatedPublicZone(ctx context.Context, client, parentClient route53iface.Route53API) error {
+	id, err := lookupZone(ctx, client, o.BaseDomain, false)
+	if err != nil {
+		if errors.Is(err, errHostedZoneNotFound) {
+			return nil
+		}
+		return err
+	}
+	owned, err := zoneHasTag(ctx, client, id, clusterTag(o.InfraID), clusterTagValue)
+	if err != nil {
+		return err
+	}
+	if !owned {
+		return nil
+	}
+	parent, err := getParentZone(ctx, parentClient, o.ParentZoneID, o.BaseDomain)
+	if err != nil {
+		return err
+	}
+	record, err := findRecord(ctx, parentClient, parent, o.BaseDomain, route53.RRTypeNs)
+	if err != nil && !isRoute53RecordNotFoundErr(err) {
+		return fmt.Errorf("cannot find delegation of %s in hosted zone %s: %w", o.BaseDomain, parent, err)
+	}
+	if record != nil {
+		if err := changeRecord(ctx, parentClient, parent, route53.ChangeActionDelete, record); err != nil {
+			return fmt.Errorf("cannot delete delegation of %s from hosted zone %s: %w", o.BaseDomain, parent, err)
+		}
+		o.Log.Info("Deleted delegation from parent zone", "name", o.BaseDomain, "parent", parent)
+	}
+	if err := deleteZone(ctx, id, client); err != nil {
+		return fmt.Errorf("failed to delete public hosted zone %s: %w", id, err)
+	}
+	o.Log.Info("Deleted public hosted zone", "id", id, "name", o.BaseDomain)
+	return nil
+}
+
+// disassociateSharedPrivateZone disassociates the VPC from a private zone that was
+// shared with the cluster rather than created for it. It returns false if the zone
+// is not shared with the cluster.
+func (o *DestroyInfraOptions) disassociateSharedPrivateZone(ctx context.Context, client route53iface.Route53API, id string, vpcID *string) (bool, error) {
+	shared, err := zoneHasTag(ctx, client, id, clusterTag(o.InfraID), sharedClusterTagValue)
+	if err != nil || !shared {
+		return false, err
+	}
+	err = retryRoute53WithBackoff(ctx, func() error {
+		_, err := client.DisassociateVPCFromHostedZoneWithContext(ctx, &route53.DisassociateVPCFromHostedZoneInput{
+			HostedZoneId: aws.String(id),
+			VPC:          &route53.VPC{VPCId: vpcID, VPCRegion: aws.String(o.Region)},
+		})
+		return err
+	})
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == route53.ErrCodeLastVPCAssociation {
+		o.Log.Info("Leaving shared private hosted zone associated with its last vpc", "id", id, "vpc", aws.StringValue(vpcID))
+		return true, nil
+	}
+	if err != nil {
+		return true, fmt.Errorf("failed to disassociate vpc %s from private hosted zone %s: %w", aws.StringValue(vpcID), id, err)
+	}
+	o.Log.Info("Disassociated vpc from shared private hosted zone", "id", id, "vpc", aws.StringValue(vpcID))
+	return true, nil
+}
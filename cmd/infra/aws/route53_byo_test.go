@@ -0,0 +1,259 @@
+package aws
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/route53"
+	"github.com/aws/aws-sdk-go/service/route53/route53iface"
+	. "github.com/onsi/gomega"
+)
+
+type fakeRoute53Client struct {
+	route53iface.Route53API
+	zones      map[string]*route53.GetHostedZoneOutput
+	tags       map[string][]*route53.Tag
+	changes    map[string][]*route53.Change
+	associated []string
+}
+
+func (f *fakeRoute53Client) GetHostedZoneWithContext(_ aws.Context, in *route53.GetHostedZoneInput, _ ...request.Option) (*route53.GetHostedZoneOutput, error) {
+	zone, ok := f.zones[aws.StringValue(in.Id)]
+	if !ok {
+		return nil, awserr.New(route53.ErrCodeNoSuchHostedZone, "not found", nil)
+	}
+	return zone, nil
+}
+
+func (f *fakeRoute53Client) ListHostedZonesPagesWithContext(_ aws.Context, _ *route53.ListHostedZonesInput, fn func(*route53.ListHostedZonesOutput, bool) bool, _ ...request.Option) error {
+	output := &route53.ListHostedZonesOutput{}
+	for _, zone := range f.zones {
+		output.HostedZones = append(output.HostedZones, zone.HostedZone)
+	}
+	fn(output, true)
+	return nil
+}
+
+func (f *fakeRoute53Client) CreateHostedZoneWithContext(_ aws.Context, in *route53.CreateHostedZoneInput, _ ...request.Option) (*route53.CreateHostedZoneOutput, error) {
+	zone := publicHostedZone("/hostedzone/Z-created", aws.StringValue(in.Name))
+	zone.DelegationSet = &route53.DelegationSet{NameServers: aws.StringSlice([]string{"ns-1.example.net", "ns-2.example.org"})}
+	f.zones["Z-created"] = zone
+	return &route53.CreateHostedZoneOutput{HostedZone: zone.HostedZone}, nil
+}
+
+func (f *fakeRoute53Client) ChangeResourceRecordSetsWithContext(_ aws.Context, in *route53.ChangeResourceRecordSetsInput, _ ...request.Option) (*route53.ChangeResourceRecordSetsOutput, error) {
+	id := aws.StringValue(in.HostedZoneId)
+	f.changes[id] = append(f.changes[id], in.ChangeBatch.Changes...)
+	return &route53.ChangeResourceRecordSetsOutput{}, nil
+}
+
+func (f *fakeRoute53Client) ListTagsForResourceWithContext(_ aws.Context, in *route53.ListTagsForResourceInput, _ ...request.Option) (*route53.ListTagsForResourceOutput, error) {
+	return &route53.ListTagsForResourceOutput{ResourceTagSet: &route53.ResourceTagSet{Tags: f.tags[aws.StringValue(in.ResourceId)]}}, nil
+}
+
+func (f *fakeRoute53Client) ChangeTagsForResourceWithContext(_ aws.Context, in *route53.ChangeTagsForResourceInput, _ ...request.Option) (*route53.ChangeTagsForResourceOutput, error) {
+	id := aws.StringValue(in.ResourceId)
+	f.tags[id] = append(f.tags[id], in.AddTags...)
+	return &route53.ChangeTagsForResourceOutput{}, nil
+}
+
+func (f *fakeRoute53Client) AssociateVPCWithHostedZoneWithContext(_ aws.Context, in *route53.AssociateVPCWithHostedZoneInput, _ ...request.Option) (*route53.AssociateVPCWithHostedZoneOutput, error) {
+	f.associated = append(f.associated, aws.StringValue(in.VPC.VPCId))
+	return &route53.AssociateVPCWithHostedZoneOutput{}, nil
+}
+
+func newFakeRoute53Client(zones ...*route53.GetHostedZoneOutput) *fakeRoute53Client {
+	f := &fakeRoute53Client{
+		zones:   map[string]*route53.GetHostedZoneOutput{},
+		tags:    map[string][]*route53.Tag{},
+		changes: map[string][]*route53.Change{},
+	}
+	for _, zone := range zones {
+		f.zones[cleanZoneID(aws.StringValue(zone.HostedZone.Id))] = zone
+	}
+	return f
+}
+
+func publicHostedZone(id, name string) *route53.GetHostedZoneOutput {
+	return &route53.GetHostedZoneOutput{HostedZone: &route53.HostedZone{
+		Id:     aws.String(id),
+		Name:   aws.String(fqdn(name)),
+		Config: &route53.HostedZoneConfig{PrivateZone: aws.Bool(false)},
+	}}
+}
+
+func privateHostedZone(id, name string, vpcIDs ...string) *route53.GetHostedZoneOutput {
+	zone := publicHostedZone(id, name)
+	zone.HostedZone.Config.PrivateZone = aws.Bool(true)
+	for _, vpcID := range vpcIDs {
+		zone.VPCs = append(zone.VPCs, &route53.VPC{VPCId: aws.String(vpcID)})
+	}
+	return zone
+}
+
+func TestValidateHostedZoneOptions(t *testing.T) {
+	testCases := []struct {
+		name      string
+		options   CreateInfraOptions
+		expectErr bool
+	}{
+		{
+			name:    "no zones",
+			options: CreateInfraOptions{},
+		},
+		{
+			name:    "existing zones",
+			options: CreateInfraOptions{PublicZoneID: "Z1", PrivateZoneID: "Z2"},
+		},
+		{
+			name:    "parent zone with role",
+			options: CreateInfraOptions{ParentZoneID: "Z1", ParentZoneRoleARN: "arn:aws:iam::123456789012:role/dns", ParentZoneExternalID: "id"},
+		},
+		{
+			name:      "parent and public zone",
+			options:   CreateInfraOptions{PublicZoneID: "Z1", ParentZoneID: "Z2"},
+			expectErr: true,
+		},
+		{
+			name:      "role without parent zone",
+			options:   CreateInfraOptions{ParentZoneRoleARN: "arn:aws:iam::123456789012:role/dns"},
+			expectErr: true,
+		},
+		{
+			name:      "external ID without role",
+			options:   CreateInfraOptions{ParentZoneID: "Z1", ParentZoneExternalID: "id"},
+			expectErr: true,
+		},
+		{
+			name:      "invalid role",
+			options:   CreateInfraOptions{ParentZoneID: "Z1", ParentZoneRoleARN: "dns"},
+			expectErr: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewGomegaWithT(t)
+			err := tc.options.validateHostedZoneOptions()
+			if tc.expectErr {
+				g.Expect(err).To(HaveOccurred())
+			} else {
+				g.Expect(err).ToNot(HaveOccurred())
+			}
+		})
+	}
+}
+
+func TestGetExistingZone(t *testing.T) {
+	client := newFakeRoute53Client(
+		publicHostedZone("/hostedzone/Z-public", "example.com"),
+		privateHostedZone("/hostedzone/Z-private", "test.example.com"),
+	)
+	testCases := []struct {
+		name      string
+		id        string
+		zoneName  string
+		private   bool
+		expectErr bool
+	}{
+		{
+			name:     "public zone",
+			id:       "Z-public",
+			zoneName: "example.com",
+		},
+		{
+			name:     "private zone",
+			id:       "Z-private",
+			zoneName: "test.example.com",
+			private:  true,
+		},
+		{
+			name:      "wrong name",
+			id:        "Z-public",
+			zoneName:  "example.org",
+			expectErr: true,
+		},
+		{
+			name:      "private zone for public zone",
+			id:        "Z-private",
+			zoneName:  "test.example.com",
+			expectErr: true,
+		},
+		{
+			name:      "missing zone",
+			id:        "Z-missing",
+			zoneName:  "example.com",
+			expectErr: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewGomegaWithT(t)
+			_, err := getExistingZone(context.Background(), client, tc.id, tc.zoneName, tc.private)
+			if tc.expectErr {
+				g.Expect(err).To(HaveOccurred())
+			} else {
+				g.Expect(err).ToNot(HaveOccurred())
+			}
+		})
+	}
+}
+
+func TestUseExistingPrivateZone(t *testing.T) {
+	g := NewGomegaWithT(t)
+	client := newFakeRoute53Client(privateHostedZone("/hostedzone/Z-private", "test.example.com", "vpc-other"))
+	o := &CreateInfraOptions{InfraID: "test", Region: "us-east-1", PrivateZoneID: "Z-private", DryRun: true}
+	g.Expect(o.useExistingPrivateZone(context.Background(), client, "test.example.com", "vpc-1")).To(Succeed())
+	g.Expect(client.associated).To(BeEmpty())
+	g.Expect(o.plan.Actions).To(HaveLen(3))
+
+	// Once associated and tagged, only the SOA record is updated
+	client.zones["Z-private"].VPCs = append(client.zones["Z-private"].VPCs, &route53.VPC{VPCId: aws.String("vpc-1")})
+	client.tags["Z-private"] = []*route53.Tag{{Key: aws.String(clusterTag("test")), Value: aws.String(sharedClusterTagValue)}}
+	o.plan = InfraPlan{}
+	g.Expect(o.useExistingPrivateZone(context.Background(), client, "test.example.com", "vpc-1")).To(Succeed())
+	g.Expect(o.plan.Actions).To(HaveLen(1))
+}
+
+func TestCreateDelegatedPublicZone(t *testing.T) {
+	testCases := []struct {
+		name       string
+		baseDomain string
+		expectErr  bool
+	}{
+		{
+			name:       "subdomain of parent zone",
+			baseDomain: "clusters.example.com",
+		},
+		{
+			name:       "not a subdomain of parent zone",
+			baseDomain: "clusters.example.org",
+			expectErr:  true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewGomegaWithT(t)
+			client := newFakeRoute53Client()
+			parentClient := newFakeRoute53Client(publicHostedZone("/hostedzone/Z-parent", "example.com"))
+			o := &CreateInfraOptions{InfraID: "test", BaseDomain: tc.baseDomain, ParentZoneID: "Z-parent"}
+			id, err := o.CreateDelegatedPublicZone(context.Background(), client, parentClient)
+			if tc.expectErr {
+				g.Expect(err).To(HaveOccurred())
+				g.Expect(client.zones).To(BeEmpty())
+				return
+			}
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(id).To(Equal("Z-created"))
+			g.Expect(client.tags["Z-created"]).To(ConsistOf(&route53.Tag{Key: aws.String(clusterTag("test")), Value: aws.String(clusterTagValue)}))
+			g.Expect(parentClient.changes["Z-parent"]).To(HaveLen(1))
+			change := parentClient.changes["Z-parent"][0]
+			g.Expect(aws.StringValue(change.Action)).To(Equal(route53.ChangeActionUpsert))
+			g.Expect(aws.StringValue(change.ResourceRecordSet.Name)).To(Equal("clusters.example.com."))
+			g.Expect(aws.StringValue(change.ResourceRecordSet.Type)).To(Equal(route53.RRTypeNs))
+			g.Expect(change.ResourceRecordSet.ResourceRecords).To(HaveLen(2))
+		})
+	}
+}
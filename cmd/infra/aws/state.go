@@ -0,0 +1,144 @@
+package aws
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+// InfraState is written to the state file of create infra after every completed
+// phase. A failed run resumes from it by skipping the completed phases, whose
+// resource IDs are taken from the recorded output instead of being looked up
+// again, and destroy infra uses it to only delete the recorded VPC.
+type InfraState struct {
+	InfraID         string             `json:"infraID"`
+	Region          string             `json:"region"`
+	CompletedPhases []string           `json:"completedPhases"`
+	Output          *CreateInfraOutput `json:"output"`
+}
+
+// readInfraState reads the state file at path. A missing file yields no state
+// and no error, so that the first run of create infra starts from scratch.
+func readInfraState(path, infraID, region string) (*InfraState, error) {
+	b, err := ioutil.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot read state file: %w", err)
+	}
+	state := &InfraState{}
+	if err := json.Unmarshal(b, state); err != nil {
+		return nil, fmt.Errorf("cannot parse state file %s: %w", path, err)
+	}
+	if state.InfraID != infraID || state.Region != region {
+		return nil, fmt.Errorf("state file %s is for infra ID %q in region %q, not %q in %q", path, state.InfraID, state.Region, infraID, region)
+	}
+	return state, nil
+}
+
+// writeInfraState replaces the state file at path. The state is written to a
+// temporary file that is renamed, so an interrupted write never leaves a
+// truncated state behind.
+func writeInfraState(path string, state *InfraState) error {
+	b, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to serialize state: %w", err)
+	}
+	f, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return fmt.Errorf("cannot create state file: %w", err)
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+	if err := os.Rename(f.Name(), path); err != nil {
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+	return nil
+}
+
+func (s *InfraState) completed(phase string) bool {
+	if s == nil {
+		return false
+	}
+	for _, completed := range s.CompletedPhases {
+		if completed == phase {
+			return true
+		}
+	}
+	return false
+}
+
+// loadState reads the state file given by --state-file. The returned output is
+// the one recorded by a previous run, or nil if there is none.
+func (o *CreateInfraOptions) loadState() (*CreateInfraOutput, error) {
+	if len(o.StateFile) == 0 || o.DryRun {
+		return nil, nil
+	}
+	state, err := readInfraState(o.StateFile, o.InfraID, o.Region)
+	if err != nil {
+		return nil, err
+	}
+	if state == nil {
+		state = &InfraState{InfraID: o.InfraID, Region: o.Region}
+	}
+	o.state = state
+	return state.Output, nil
+}
+
+// runPhase runs a phase of create infra like progressReporter.run. With a state
+// file, phases completed by a previous run are skipped, and the output is
+// written to the state file after every completed phase.
+func (o *CreateInfraOptions) runPhase(result *CreateInfraOutput, phase, resourceType string, fn func() (string, error)) error {
+	if o.state.completed(phase) {
+		o.progress.skip(phase, resourceType)
+		return nil
+	}
+	if err := o.progress.run(phase, resourceType, fn); err != nil {
+		return err
+	}
+	if o.state == nil {
+		return nil
+	}
+	o.state.CompletedPhases = append(o.state.CompletedPhases, phase)
+	o.state.Output = result
+	return writeInfraState(o.StateFile, o.state)
+}
+
+// loadState reads the state file given by --state-file, which must exist.
+func (o *DestroyInfraOptions) loadState() error {
+	if len(o.StateFile) == 0 {
+		return nil
+	}
+	state, err := readInfraState(o.StateFile, o.InfraID, o.Region)
+	if err != nil {
+		return err
+	}
+	if state == nil {
+		return fmt.Errorf("state file %s does not exist", o.StateFile)
+	}
+	o.state = state
+	return nil
+}
+
+// vpcFilters returns the filters of the VPCs to delete. With a state file only
+// the VPC recorded in it is deleted, even if other VPCs carry the cluster tag.
+func (o *DestroyInfraOptions) vpcFilters() []*ec2.Filter {
+	filters := o.ec2Filters()
+	if o.state != nil && o.state.Output != nil && len(o.state.Output.VPCID) > 0 {
+		filters = append(filters, vpcFilter(aws.String(o.state.Output.VPCID))...)
+	}
+	return filters
+}
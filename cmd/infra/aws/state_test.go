@@ -0,0 +1,94 @@
+package aws
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestInfraState(t *testing.T) {
+	g := NewGomegaWithT(t)
+	path := filepath.Join(t.TempDir(), "state.json")
+
+	state, err := readInfraState(path, "test", "us-east-1")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(state).To(BeNil())
+
+	written := &InfraState{
+		InfraID:         "test",
+		Region:          "us-east-1",
+		CompletedPhases: []string{"vpc"},
+		Output:          &CreateInfraOutput{InfraID: "test", VPCID: "vpc-1"},
+	}
+	g.Expect(writeInfraState(path, written)).To(Succeed())
+	state, err = readInfraState(path, "test", "us-east-1")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(state).To(Equal(written))
+	g.Expect(state.completed("vpc")).To(BeTrue())
+	g.Expect(state.completed("kms-key")).To(BeFalse())
+
+	_, err = readInfraState(path, "other", "us-east-1")
+	g.Expect(err).To(HaveOccurred())
+	_, err = readInfraState(path, "test", "us-west-2")
+	g.Expect(err).To(HaveOccurred())
+}
+
+func TestRunPhaseResumesFromState(t *testing.T) {
+	g := NewGomegaWithT(t)
+	path := filepath.Join(t.TempDir(), "state.json")
+	g.Expect(writeInfraState(path, &InfraState{
+		InfraID:         "test",
+		Region:          "us-east-1",
+		CompletedPhases: []string{"vpc"},
+		Output:          &CreateInfraOutput{InfraID: "test", VPCID: "vpc-1"},
+	})).To(Succeed())
+
+	out := &bytes.Buffer{}
+	o := &CreateInfraOptions{InfraID: "test", Region: "us-east-1", StateFile: path}
+	o.progress = newProgressReporter("create", "test", out)
+	result, err := o.loadState()
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(result.VPCID).To(Equal("vpc-1"))
+
+	g.Expect(o.runPhase(result, "vpc", "vpc", func() (string, error) {
+		t.Fatal("completed phase was run again")
+		return "", nil
+	})).To(Succeed())
+	g.Expect(o.runPhase(result, "kms-key", "kms-key", func() (string, error) {
+		result.KMSKeyARN = "arn:aws:kms:us-east-1:123456789012:key/1"
+		return result.KMSKeyARN, nil
+	})).To(Succeed())
+	g.Expect(o.runPhase(result, "public-zone", "hosted-zone", func() (string, error) {
+		return "", errors.New("access denied")
+	})).ToNot(Succeed())
+
+	state, err := readInfraState(path, "test", "us-east-1")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(state.CompletedPhases).To(Equal([]string{"vpc", "kms-key"}))
+	g.Expect(state.Output.KMSKeyARN).To(Equal("arn:aws:kms:us-east-1:123456789012:key/1"))
+
+	events := decodeProgressEvents(g, out.String())
+	g.Expect(events[0].Phase).To(Equal("vpc"))
+	g.Expect(events[0].Status).To(Equal(ProgressStatusSkipped))
+}
+
+func TestDestroyVPCFiltersFromState(t *testing.T) {
+	g := NewGomegaWithT(t)
+	path := filepath.Join(t.TempDir(), "state.json")
+	o := &DestroyInfraOptions{InfraID: "test", Region: "us-east-1", StateFile: path}
+	g.Expect(o.loadState()).ToNot(Succeed())
+
+	g.Expect(writeInfraState(path, &InfraState{
+		InfraID: "test",
+		Region:  "us-east-1",
+		Output:  &CreateInfraOutput{VPCID: "vpc-1"},
+	})).To(Succeed())
+	g.Expect(o.loadState()).To(Succeed())
+	filters := o.vpcFilters()
+	g.Expect(filters).To(HaveLen(2))
+	g.Expect(*filters[1].Name).To(Equal("vpc-id"))
+	g.Expect(*filters[1].Values[0]).To(Equal("vpc-1"))
+}
@@ -0,0 +1,202 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/aws/aws-sdk-go/service/kms"
+	"github.com/aws/aws-sdk-go/service/kms/kmsiface"
+	"github.com/aws/aws-sdk-go/service/route53"
+	"github.com/aws/aws-sdk-go/service/route53/route53iface"
+	"github.com/go-logr/logr"
+)
+
+// ec2CreateTagsMaxResources is the maximum number of resources a single
+// CreateTags call may tag.
+const ec2CreateTagsMaxResources = 1000
+
+// missingTags returns the additional tags that are not set to the same value in
+// existing, which maps tag keys to values.
+func (o *CreateInfraOptions) missingTags(existing map[string]string) []*ec2.Tag {
+	var missing []*ec2.Tag
+	for _, tag := range o.additionalEC2Tags {
+		if value, ok := existing[aws.StringValue(tag.Key)]; !ok || value != aws.StringValue(tag.Value) {
+			missing = append(missing, tag)
+		}
+	}
+	return missing
+}
+
+// ReconcileEC2Tags adds the additional tags to all EC2 resources owned by the
+// cluster that lack them, e.g. because the resources were created before the tags
+// were given. Tags that are not given anymore are left in place.
+func (o *CreateInfraOptions) ReconcileEC2Tags(ctx context.Context, l logr.Logger, client ec2iface.EC2API) error {
+	if len(o.additionalEC2Tags) == 0 {
+		return nil
+	}
+	keys := []*string{aws.String(clusterTag(o.InfraID))}
+	for _, tag := range o.additionalEC2Tags {
+		keys = append(keys, tag.Key)
+	}
+	resourceTags := map[string]map[string]string{}
+	err := client.DescribeTagsPagesWithContext(ctx, &ec2.DescribeTagsInput{
+		Filters: []*ec2.Filter{{Name: aws.String("key"), Values: keys}},
+	}, func(out *ec2.DescribeTagsOutput, _ bool) bool {
+		for _, tag := range out.Tags {
+			id := aws.StringValue(tag.ResourceId)
+			if resourceTags[id] == nil {
+				resourceTags[id] = map[string]string{}
+			}
+			resourceTags[id][aws.StringValue(tag.Key)] = aws.StringValue(tag.Value)
+		}
+		return true
+	})
+	if err != nil {
+		return fmt.Errorf("cannot list tags: %w", err)
+	}
+
+	// Resources missing the same tags are tagged together
+	missingByTags := map[string][]*string{}
+	tagsByKey := map[string][]*ec2.Tag{}
+	for id, tags := range resourceTags {
+		if tags[clusterTag(o.InfraID)] != clusterTagValue {
+			continue
+		}
+		missing := o.missingTags(tags)
+		if len(missing) == 0 {
+			continue
+		}
+		key := fmt.Sprint(ec2TagsString(missing))
+		missingByTags[key] = append(missingByTags[key], aws.String(id))
+		tagsByKey[key] = missing
+	}
+	keysInOrder := make([]string, 0, len(missingByTags))
+	for key := range missingByTags {
+		keysInOrder = append(keysInOrder, key)
+	}
+	sort.Strings(keysInOrder)
+	for _, key := range keysInOrder {
+		ids := missingByTags[key]
+		sort.Slice(ids, func(i, j int) bool { return aws.StringValue(ids[i]) < aws.StringValue(ids[j]) })
+		if o.DryRun {
+			for _, id := range ids {
+				o.planModify(l, "tag", aws.StringValue(id), fmt.Sprintf("tag %s", key))
+			}
+			continue
+		}
+		for start := 0; start < len(ids); start += ec2CreateTagsMaxResources {
+			end := start + ec2CreateTagsMaxResources
+			if end > len(ids) {
+				end = len(ids)
+			}
+			if _, err := client.CreateTagsWithContext(ctx, &ec2.CreateTagsInput{Resources: ids[start:end], Tags: tagsByKey[key]}); err != nil {
+				return fmt.Errorf("cannot tag resources: %w", err)
+			}
+		}
+		l.Info("Added missing tags to resources", "tags", key, "resources", aws.StringValueSlice(ids))
+	}
+	return nil
+}
+
+func ec2TagsString(tags []*ec2.Tag) []string {
+	var result []string
+	for _, tag := range tags {
+		result = append(result, fmt.Sprintf("%s=%s", aws.StringValue(tag.Key), aws.StringValue(tag.Value)))
+	}
+	return result
+}
+
+// ec2TagValue returns the value of the tag with the given key, or an empty string
+// if there is none.
+func ec2TagValue(tags []*ec2.Tag, key string) string {
+	for _, tag := range tags {
+		if aws.StringValue(tag.Key) == key {
+			return aws.StringValue(tag.Value)
+		}
+	}
+	return ""
+}
+
+// reconcileZoneTags adds the additional tags that are missing on a hosted zone
+// created for the cluster.
+func (o *CreateInfraOptions) reconcileZoneTags(ctx context.Context, l logr.Logger, client route53iface.Route53API, id string) error {
+	if len(o.additionalEC2Tags) == 0 {
+		return nil
+	}
+	var output *route53.ListTagsForResourceOutput
+	if err := retryRoute53WithBackoff(ctx, func() (err error) {
+		output, err = client.ListTagsForResourceWithContext(ctx, &route53.ListTagsForResourceInput{
+			ResourceId:   aws.String(id),
+			ResourceType: aws.String(route53.TagResourceTypeHostedzone),
+		})
+		return err
+	}); err != nil {
+		return fmt.Errorf("cannot list tags of hosted zone %s: %w", id, err)
+	}
+	existing := map[string]string{}
+	for _, tag := range output.ResourceTagSet.Tags {
+		existing[aws.StringValue(tag.Key)] = aws.StringValue(tag.Value)
+	}
+	missing := o.missingTags(existing)
+	if len(missing) == 0 {
+		return nil
+	}
+	if o.DryRun {
+		o.planModify(l, "tag", id, fmt.Sprintf("tag %v", ec2TagsString(missing)))
+		return nil
+	}
+	var tags []*route53.Tag
+	for _, tag := range missing {
+		tags = append(tags, &route53.Tag{Key: tag.Key, Value: tag.Value})
+	}
+	if err := retryRoute53WithBackoff(ctx, func() error {
+		_, err := client.ChangeTagsForResourceWithContext(ctx, &route53.ChangeTagsForResourceInput{
+			ResourceId:   aws.String(id),
+			ResourceType: aws.String(route53.TagResourceTypeHostedzone),
+			AddTags:      tags,
+		})
+		return err
+	}); err != nil {
+		return fmt.Errorf("cannot tag hosted zone %s: %w", id, err)
+	}
+	l.Info("Added missing tags to hosted zone", "id", id, "tags", ec2TagsString(missing))
+	return nil
+}
+
+// reconcileKMSKeyTags adds the additional tags that are missing on the KMS key
+// created for the cluster.
+func (o *CreateInfraOptions) reconcileKMSKeyTags(ctx context.Context, l logr.Logger, client kmsiface.KMSAPI, keyID string) error {
+	if len(o.additionalEC2Tags) == 0 {
+		return nil
+	}
+	// A key has at most 50 tags, which fit a single page
+	output, err := client.ListResourceTagsWithContext(ctx, &kms.ListResourceTagsInput{KeyId: aws.String(keyID), Limit: aws.Int64(50)})
+	if err != nil {
+		return fmt.Errorf("cannot list tags of KMS key %s: %w", keyID, err)
+	}
+	existing := map[string]string{}
+	for _, tag := range output.Tags {
+		existing[aws.StringValue(tag.TagKey)] = aws.StringValue(tag.TagValue)
+	}
+	missing := o.missingTags(existing)
+	if len(missing) == 0 {
+		return nil
+	}
+	if o.DryRun {
+		o.planModify(l, "tag", keyID, fmt.Sprintf("tag %v", ec2TagsString(missing)))
+		return nil
+	}
+	var tags []*kms.Tag
+	for _, tag := range missing {
+		tags = append(tags, &kms.Tag{TagKey: tag.Key, TagValue: tag.Value})
+	}
+	if _, err := client.TagResourceWithContext(ctx, &kms.TagResourceInput{KeyId: aws.String(keyID), Tags: tags}); err != nil {
+		return fmt.Errorf("cannot tag KMS key %s: %w", keyID, err)
+	}
+	l.Info("Added missing tags to KMS key", "id", keyID, "tags", ec2TagsString(missing))
+	return nil
+}
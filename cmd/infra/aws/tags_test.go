@@ -0,0 +1,83 @@
+package aws
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/aws/aws-sdk-go/service/route53"
+	"github.com/go-logr/logr"
+	. "github.com/onsi/gomega"
+)
+
+type fakeReconcileTagsClient struct {
+	ec2iface.EC2API
+	tags    []*ec2.TagDescription
+	created []*ec2.CreateTagsInput
+}
+
+func (f *fakeReconcileTagsClient) DescribeTagsPagesWithContext(_ aws.Context, _ *ec2.DescribeTagsInput, fn func(*ec2.DescribeTagsOutput, bool) bool, _ ...request.Option) error {
+	fn(&ec2.DescribeTagsOutput{Tags: f.tags}, true)
+	return nil
+}
+
+func (f *fakeReconcileTagsClient) CreateTagsWithContext(_ aws.Context, in *ec2.CreateTagsInput, _ ...request.Option) (*ec2.CreateTagsOutput, error) {
+	f.created = append(f.created, in)
+	return &ec2.CreateTagsOutput{}, nil
+}
+
+func tagDescription(resourceID, key, value string) *ec2.TagDescription {
+	return &ec2.TagDescription{ResourceId: aws.String(resourceID), Key: aws.String(key), Value: aws.String(value)}
+}
+
+func TestReconcileEC2Tags(t *testing.T) {
+	g := NewGomegaWithT(t)
+	client := &fakeReconcileTagsClient{tags: []*ec2.TagDescription{
+		// Owned and tagged
+		tagDescription("vpc-1", clusterTag("test"), clusterTagValue),
+		tagDescription("vpc-1", "team", "a"),
+		// Owned with a different value
+		tagDescription("subnet-1", clusterTag("test"), clusterTagValue),
+		tagDescription("subnet-1", "team", "b"),
+		// Owned without the tag
+		tagDescription("subnet-2", clusterTag("test"), clusterTagValue),
+		// Shared
+		tagDescription("vpc-shared", clusterTag("test"), sharedClusterTagValue),
+		// Not owned by the cluster
+		tagDescription("vpc-other", "team", "b"),
+	}}
+	o := &CreateInfraOptions{InfraID: "test", AdditionalTags: []string{"team=a"}}
+	g.Expect(o.parseAdditionalTags()).To(Succeed())
+
+	o.DryRun = true
+	g.Expect(o.ReconcileEC2Tags(context.Background(), logr.Discard(), client)).To(Succeed())
+	g.Expect(client.created).To(BeEmpty())
+	g.Expect(o.plan.Actions).To(HaveLen(2))
+
+	o.DryRun = false
+	g.Expect(o.ReconcileEC2Tags(context.Background(), logr.Discard(), client)).To(Succeed())
+	g.Expect(client.created).To(HaveLen(1))
+	g.Expect(aws.StringValueSlice(client.created[0].Resources)).To(Equal([]string{"subnet-1", "subnet-2"}))
+	g.Expect(client.created[0].Tags).To(ConsistOf(&ec2.Tag{Key: aws.String("team"), Value: aws.String("a")}))
+}
+
+func TestReconcileZoneTags(t *testing.T) {
+	g := NewGomegaWithT(t)
+	client := newFakeRoute53Client(privateHostedZone("/hostedzone/Z-private", "test.example.com"))
+	client.tags["Z-private"] = []*route53.Tag{{Key: aws.String("team"), Value: aws.String("a")}}
+	o := &CreateInfraOptions{InfraID: "test", AdditionalTags: []string{"team=a", "env=dev"}}
+	g.Expect(o.parseAdditionalTags()).To(Succeed())
+
+	g.Expect(o.reconcileZoneTags(context.Background(), logr.Discard(), client, "Z-private")).To(Succeed())
+	g.Expect(client.tags["Z-private"]).To(ConsistOf(
+		&route53.Tag{Key: aws.String("team"), Value: aws.String("a")},
+		&route53.Tag{Key: aws.String("env"), Value: aws.String("dev")},
+	))
+
+	// Nothing is missing on a second run
+	g.Expect(o.reconcileZoneTags(context.Background(), logr.Discard(), client, "Z-private")).To(Succeed())
+	g.Expect(client.tags["Z-private"]).To(HaveLen(2))
+}
@@ -0,0 +1,483 @@
+package aws
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+// tfExpr is a raw HCL expression, such as a reference to another resource.
+type tfExpr string
+
+// tfHeredoc is a multi-line string template rendered as a heredoc. Unlike plain
+// strings it is not escaped, so it may contain interpolation sequences.
+type tfHeredoc string
+
+type tfAttribute struct {
+	name  string
+	value interface{}
+}
+
+// tfBlock is an HCL block, e.g. a resource with its attributes and nested blocks.
+type tfBlock struct {
+	blockType  string
+	labels     []string
+	attributes []tfAttribute
+	blocks     []*tfBlock
+}
+
+func newTFBlock(blockType string, labels ...string) *tfBlock {
+	return &tfBlock{blockType: blockType, labels: labels}
+}
+
+func (b *tfBlock) set(name string, value interface{}) *tfBlock {
+	b.attributes = append(b.attributes, tfAttribute{name: name, value: value})
+	return b
+}
+
+func (b *tfBlock) block(blockType string, labels ...string) *tfBlock {
+	child := newTFBlock(blockType, labels...)
+	b.blocks = append(b.blocks, child)
+	return child
+}
+
+func (b *tfBlock) render(out *bytes.Buffer, indent string) {
+	out.WriteString(indent + b.blockType)
+	for _, label := range b.labels {
+		out.WriteString(" " + strconv.Quote(label))
+	}
+	out.WriteString(" {\n")
+	width := 0
+	for _, attribute := range b.attributes {
+		if len(attribute.name) > width {
+			width = len(attribute.name)
+		}
+	}
+	for _, attribute := range b.attributes {
+		fmt.Fprintf(out, "%s  %-*s = %s\n", indent, width, attribute.name, tfValue(attribute.value, indent+"  "))
+	}
+	for _, child := range b.blocks {
+		out.WriteString("\n")
+		child.render(out, indent+"  ")
+	}
+	out.WriteString(indent + "}\n")
+}
+
+// tfEscape escapes the template sequences of HCL in a literal string.
+func tfEscape(value string) string {
+	return strings.NewReplacer("${", "$${", "%{", "%%{").Replace(value)
+}
+
+func tfRef(address, attribute string) tfExpr {
+	return tfExpr(address + "." + attribute)
+}
+
+func tfValue(value interface{}, indent string) string {
+	switch v := value.(type) {
+	case tfExpr:
+		return string(v)
+	case tfHeredoc:
+		return "<<EOT\n" + string(v) + "\nEOT"
+	case string:
+		return tfEscape(strconv.Quote(v))
+	case bool, int, int64:
+		return fmt.Sprint(v)
+	case []string:
+		var items []string
+		for _, item := range v {
+			items = append(items, tfValue(item, indent))
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	case []tfExpr:
+		var items []string
+		for _, item := range v {
+			items = append(items, string(item))
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	case map[string]string:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		out := &bytes.Buffer{}
+		out.WriteString("{\n")
+		for _, key := range keys {
+			fmt.Fprintf(out, "%s  %s = %s\n", indent, tfValue(key, indent), tfValue(v[key], indent))
+		}
+		out.WriteString(indent + "}")
+		return out.String()
+	default:
+		panic(fmt.Sprintf("programmer error, unsupported terraform value type %T", value))
+	}
+}
+
+func renderTerraform(header string, blocks []*tfBlock) []byte {
+	out := &bytes.Buffer{}
+	fmt.Fprintf(out, "# %s\n", header)
+	for _, block := range blocks {
+		out.WriteString("\n")
+		block.render(out, "")
+	}
+	return out.Bytes()
+}
+
+// tfTags converts the tags of a resource with the given name to Terraform tags.
+func (o *CreateInfraOptions) tfTags(name string) map[string]string {
+	tags := map[string]string{}
+	for _, tag := range append(ec2Tags(o.InfraID, name), o.additionalEC2Tags...) {
+		tags[aws.StringValue(tag.Key)] = aws.StringValue(tag.Value)
+	}
+	return tags
+}
+
+// tfPermission adds an EC2 permission as an ingress or egress block of a
+// Terraform security group. Rules referencing a security group refer to the
+// group itself.
+func tfPermission(securityGroup *tfBlock, direction string, permission *ec2.IpPermission) {
+	rule := securityGroup.block(direction)
+	// Terraform requires ports, use 0 when all ports are allowed
+	rule.set("from_port", aws.Int64Value(permission.FromPort))
+	rule.set("to_port", aws.Int64Value(permission.ToPort))
+	rule.set("protocol", aws.StringValue(permission.IpProtocol))
+	var cidrs []string
+	for _, ipRange := range permission.IpRanges {
+		cidrs = append(cidrs, aws.StringValue(ipRange.CidrIp))
+	}
+	if len(cidrs) > 0 {
+		rule.set("cidr_blocks", cidrs)
+	}
+	var ipv6CIDRs []string
+	for _, ipRange := range permission.Ipv6Ranges {
+		ipv6CIDRs = append(ipv6CIDRs, aws.StringValue(ipRange.CidrIpv6))
+	}
+	if len(ipv6CIDRs) > 0 {
+		rule.set("ipv6_cidr_blocks", ipv6CIDRs)
+	}
+	if len(permission.UserIdGroupPairs) > 0 {
+		rule.set("self", true)
+	}
+}
+
+// TerraformConfiguration renders a Terraform configuration that creates the same
+// resources as CreateInfra. No AWS APIs are called. The public hosted zone is not
+// part of the configuration since it must already exist.
+func (o *CreateInfraOptions) TerraformConfiguration() ([]byte, error) {
+	if o.EnableProxy {
+		return nil, fmt.Errorf("the proxy host is not supported with the %s output format", OutputFormatTerraform)
+	}
+	if len(o.VPCID) > 0 {
+		return nil, fmt.Errorf("an existing VPC is not supported with the %s output format", OutputFormatTerraform)
+	}
+	if o.EnableIPv6 {
+		return nil, fmt.Errorf("IPv6 is not supported with the %s output format", OutputFormatTerraform)
+	}
+	if len(o.PrivateLinkNLBARN) > 0 {
+		return nil, fmt.Errorf("PrivateLink is not supported with the %s output format", OutputFormatTerraform)
+	}
+	if o.CreateKMSKey || len(o.KMSKeyARN) > 0 {
+		return nil, fmt.Errorf("KMS keys are not supported with the %s output format", OutputFormatTerraform)
+	}
+	if len(o.PublicZoneID) > 0 || len(o.PrivateZoneID) > 0 || len(o.ParentZoneID) > 0 {
+		return nil, fmt.Errorf("existing and delegated hosted zones are not supported with the %s output format", OutputFormatTerraform)
+	}
+	if o.Bastion {
+		return nil, fmt.Errorf("the bastion host is not supported with the %s output format", OutputFormatTerraform)
+	}
+	if len(o.OutpostARN) > 0 {
+		return nil, fmt.Errorf("Outposts are not supported with the %s output format", OutputFormatTerraform)
+	}
+	if len(o.TransitGatewayID) > 0 {
+		return nil, fmt.Errorf("transit gateway attachments are not supported with the %s output format", OutputFormatTerraform)
+	}
+	if o.InstanceConnectEndpoint {
+		return nil, fmt.Errorf("EC2 Instance Connect Endpoints are not supported with the %s output format", OutputFormatTerraform)
+	}
+	if o.EnableFlowLogs {
+		return nil, fmt.Errorf("flow logs are not supported with the %s output format", OutputFormatTerraform)
+	}
+	if o.CreateVPCEndpoints {
+		return nil, fmt.Errorf("interface VPC endpoints are not supported with the %s output format", OutputFormatTerraform)
+	}
+	if o.CheckQuotas {
+		return nil, fmt.Errorf("the service quota check is not supported with the %s output format", OutputFormatTerraform)
+	}
+	if o.RestrictEgress {
+		return nil, fmt.Errorf("restricted egress is not supported with the %s output format", OutputFormatTerraform)
+	}
+	if err := o.parseAdditionalTags(); err != nil {
+		return nil, err
+	}
+	if err := o.parseAdditionalIngressRules(); err != nil {
+		return nil, err
+	}
+	if err := o.validateCIDRs(); err != nil {
+		return nil, err
+	}
+
+	var blocks []*tfBlock
+	resource := func(resourceType, name string) *tfBlock {
+		block := newTFBlock("resource", resourceType, name)
+		blocks = append(blocks, block)
+		return block
+	}
+	output := func(name string, value interface{}) {
+		blocks = append(blocks, newTFBlock("output", name).set("value", value))
+	}
+
+	// VPC resources
+	resource("aws_vpc", "vpc").
+		set("cidr_block", o.vpcCIDR()).
+		set("enable_dns_support", true).
+		set("enable_dns_hostnames", true).
+		set("tags", o.tfTags(fmt.Sprintf("%s-vpc", o.InfraID)))
+	for i, cidr := range o.SecondaryCIDRs {
+		resource("aws_vpc_ipv4_cidr_block_association", fmt.Sprintf("secondary_%d", i)).
+			set("vpc_id", tfRef("aws_vpc.vpc", "id")).
+			set("cidr_block", cidr)
+	}
+	domainName := "ec2.internal"
+	if o.Region != "us-east-1" {
+		domainName = fmt.Sprintf("%s.compute.internal", o.Region)
+	}
+	resource("aws_vpc_dhcp_options", "dhcp").
+		set("domain_name", domainName).
+		set("domain_name_servers", []string{"AmazonProvidedDNS"}).
+		set("tags", o.tfTags(""))
+	resource("aws_vpc_dhcp_options_association", "dhcp").
+		set("vpc_id", tfRef("aws_vpc.vpc", "id")).
+		set("dhcp_options_id", tfRef("aws_vpc_dhcp_options.dhcp", "id"))
+	resource("aws_internet_gateway", "igw").
+		set("vpc_id", tfRef("aws_vpc.vpc", "id")).
+		set("tags", o.tfTags(fmt.Sprintf("%s-igw", o.InfraID)))
+
+	groupName := fmt.Sprintf("%s-worker-sg", o.InfraID)
+	securityGroup := resource("aws_security_group", "worker").
+		set("name", groupName).
+		set("description", "worker security group").
+		set("vpc_id", tfRef("aws_vpc.vpc", "id")).
+		set("tags", o.tfTags(groupName))
+	for _, permission := range workerSecurityGroupEgressPermissions(false) {
+		tfPermission(securityGroup, "egress", permission)
+	}
+	for _, permission := range append(workerSecurityGroupIngressPermissions(o.machineCIDRs(), o.sshIngressCIDRs(), "", ""), o.additionalIngressPermissions...) {
+		tfPermission(securityGroup, "ingress", permission)
+	}
+
+	// Per zone resources
+	if err := o.validateZones(); err != nil {
+		return nil, err
+	}
+	if err := o.validateNATTopology(); err != nil {
+		return nil, err
+	}
+	if err := o.validatePublicIPv4PoolOptions(); err != nil {
+		return nil, err
+	}
+	zones := o.Zones
+	if len(zones) == 0 {
+		zones = make([]string, o.ZoneCount)
+		if len(zones) == 0 {
+			zones = []string{""}
+		}
+		blocks = append(blocks, newTFBlock("data", "aws_availability_zones", "available").set("state", "available"))
+	}
+	privateCIDRs, publicCIDRs, err := subnetCIDRs(o.vpcCIDR(), len(zones))
+	if err != nil {
+		return nil, err
+	}
+	publicRouteTable := resource("aws_route_table", "public").
+		set("vpc_id", tfRef("aws_vpc.vpc", "id")).
+		set("tags", o.tfTags(fmt.Sprintf("%s-public", o.InfraID)))
+	publicRouteTable.block("route").
+		set("cidr_block", "0.0.0.0/0").
+		set("gateway_id", tfRef("aws_internet_gateway.igw", "id"))
+	endpointRouteTables := []tfExpr{tfRef("aws_route_table.public", "id")}
+	for i, zone := range zones {
+		// Without explicit zones, use the first zones of the region like CreateInfra does
+		var availabilityZone interface{} = zone
+		zoneName := zone
+		if zone == "" {
+			availabilityZone = tfExpr(fmt.Sprintf("data.aws_availability_zones.available.names[%d]", i))
+			zoneName = fmt.Sprintf("az%d", i)
+		}
+		privateSubnet := fmt.Sprintf("private_%d", i)
+		publicSubnet := fmt.Sprintf("public_%d", i)
+		natGateway := fmt.Sprintf("nat_%d", i)
+
+		resource("aws_subnet", privateSubnet).
+			set("vpc_id", tfRef("aws_vpc.vpc", "id")).
+			set("cidr_block", privateCIDRs[i]).
+			set("availability_zone", availabilityZone).
+			set("tags", o.tfTags(fmt.Sprintf("%s-private-%s", o.InfraID, zoneName)))
+		resource("aws_subnet", publicSubnet).
+			set("vpc_id", tfRef("aws_vpc.vpc", "id")).
+			set("cidr_block", publicCIDRs[i]).
+			set("availability_zone", availabilityZone).
+			set("tags", o.tfTags(fmt.Sprintf("%s-public-%s", o.InfraID, zoneName)))
+		if o.natTopology() == NATTopologyPerZone || (o.natTopology() == NATTopologySingle && i == 0) {
+			eip := resource("aws_eip", natGateway).
+				set("vpc", true)
+			if len(o.PublicIPv4Pool) > 0 {
+				eip.set("public_ipv4_pool", o.PublicIPv4Pool)
+			}
+			eip.set("tags", o.tfTags(fmt.Sprintf("%s-eip-%s", o.InfraID, zoneName))).
+				set("depends_on", []tfExpr{"aws_internet_gateway.igw"})
+			resource("aws_nat_gateway", natGateway).
+				set("allocation_id", tfRef("aws_eip."+natGateway, "id")).
+				set("subnet_id", tfRef("aws_subnet."+publicSubnet, "id")).
+				set("tags", o.tfTags(fmt.Sprintf("%s-nat-%s", o.InfraID, zoneName)))
+		}
+		privateRouteTable := resource("aws_route_table", privateSubnet).
+			set("vpc_id", tfRef("aws_vpc.vpc", "id")).
+			set("tags", o.tfTags(fmt.Sprintf("%s-private-%s", o.InfraID, zoneName)))
+		if o.natTopology() != NATTopologyNone {
+			if o.natTopology() == NATTopologySingle {
+				natGateway = "nat_0"
+			}
+			privateRouteTable.block("route").
+				set("cidr_block", "0.0.0.0/0").
+				set("nat_gateway_id", tfRef("aws_nat_gateway."+natGateway, "id"))
+		}
+		resource("aws_route_table_association", privateSubnet).
+			set("subnet_id", tfRef("aws_subnet."+privateSubnet, "id")).
+			set("route_table_id", tfRef("aws_route_table."+privateSubnet, "id"))
+		resource("aws_route_table_association", publicSubnet).
+			set("subnet_id", tfRef("aws_subnet."+publicSubnet, "id")).
+			set("route_table_id", tfRef("aws_route_table.public", "id"))
+		endpointRouteTables = append(endpointRouteTables, tfRef("aws_route_table."+privateSubnet, "id"))
+
+		output(fmt.Sprintf("private_subnet_%d_id", i), tfRef("aws_subnet."+privateSubnet, "id"))
+		output(fmt.Sprintf("private_subnet_%d_zone", i), tfRef("aws_subnet."+privateSubnet, "availability_zone"))
+	}
+	resource("aws_vpc_endpoint", "s3").
+		set("vpc_id", tfRef("aws_vpc.vpc", "id")).
+		set("service_name", vpcEndpointServiceName(o.Region, "s3")).
+		set("route_table_ids", endpointRouteTables)
+
+	// Private hosted zones
+	for _, zone := range []struct{ resourceName, zoneName string }{
+		{resourceName: "private", zoneName: fmt.Sprintf("%s.%s", o.Name, o.BaseDomain)},
+		{resourceName: "local", zoneName: fmt.Sprintf("%s.%s", o.Name, hypershiftLocalZoneName)},
+	} {
+		hostedZone := resource("aws_route53_zone", zone.resourceName).set("name", zone.zoneName)
+		hostedZone.block("vpc").
+			set("vpc_id", tfRef("aws_vpc.vpc", "id")).
+			set("vpc_region", o.Region)
+		output(zone.resourceName+"_zone_id", tfRef("aws_route53_zone."+zone.resourceName, "zone_id"))
+	}
+
+	output("vpc_id", tfRef("aws_vpc.vpc", "id"))
+	output("security_group_id", tfRef("aws_security_group.worker", "id"))
+	output("machine_cidr", o.vpcCIDR())
+
+	return renderTerraform(fmt.Sprintf("HyperShift infrastructure for cluster %s (infra ID %s)", o.Name, o.InfraID), blocks), nil
+}
+
+// TerraformConfiguration renders a Terraform configuration that creates the same
+// OIDC provider, roles and worker instance profile as CreateIAM. No AWS APIs are
+// called. The issuer URL must already be set.
+func (o *CreateIAMOptions) TerraformConfiguration() ([]byte, error) {
+	if len(o.OIDCBucket) > 0 || len(o.OIDCPublicKeyFile) > 0 {
+		return nil, fmt.Errorf("OIDC bucket provisioning is not supported with the %s output format", OutputFormatTerraform)
+	}
+	if o.IssuerURL == "" {
+		return nil, fmt.Errorf("an issuer URL is required")
+	}
+	if err := o.validatePolicyMode(); err != nil {
+		return nil, err
+	}
+	if err := o.validateIAMPathOptions(); err != nil {
+		return nil, err
+	}
+	if err := validateWorkerNames(o.InstanceProfileName, o.WorkerRoleName); err != nil {
+		return nil, err
+	}
+	if err := o.parseAdditionalTags(); err != nil {
+		return nil, err
+	}
+	tags := map[string]string{}
+	for _, tag := range o.additionalIAMTags {
+		tags[aws.StringValue(tag.Key)] = aws.StringValue(tag.Value)
+	}
+	withTags := func(block *tfBlock) *tfBlock {
+		if len(tags) > 0 {
+			block.set("tags", tags)
+		}
+		return block
+	}
+	withBoundary := func(block *tfBlock) *tfBlock {
+		if len(o.PermissionsBoundaryARN) > 0 {
+			block.set("permissions_boundary", o.PermissionsBoundaryARN)
+		}
+		return block
+	}
+
+	var blocks []*tfBlock
+	resource := func(resourceType, name string) *tfBlock {
+		block := newTFBlock("resource", resourceType, name)
+		blocks = append(blocks, block)
+		return block
+	}
+	output := func(name string, value interface{}) {
+		blocks = append(blocks, newTFBlock("output", name).set("value", value))
+	}
+	// Terraform identifiers may not contain dashes
+	identifier := func(name string) string {
+		return strings.ReplaceAll(name, "-", "_")
+	}
+
+	withTags(resource("aws_iam_openid_connect_provider", "oidc").
+		set("url", o.IssuerURL).
+		set("client_id_list", []string{"openshift"}).
+		set("thumbprint_list", []string{s3OIDCThumbprint}))
+
+	providerARN := "${" + string(tfRef("aws_iam_openid_connect_provider.oidc", "arn")) + "}"
+	providerName := tfEscape(strings.TrimPrefix(o.IssuerURL, "https://"))
+	for _, role := range o.oidcRoles() {
+		roleName := fmt.Sprintf("%s-%s", o.InfraID, role.name)
+		var serviceAccounts []string
+		for _, serviceAccount := range role.serviceAccounts {
+			serviceAccounts = append(serviceAccounts, tfEscape(serviceAccount))
+		}
+		withTags(withBoundary(resource("aws_iam_role", identifier(role.name)).
+			set("name", roleName).
+			set("path", o.iamPath()).
+			set("assume_role_policy", tfHeredoc(oidcTrustPolicy(providerARN, providerName, serviceAccounts...)))))
+		resource("aws_iam_role_policy", identifier(role.name)).
+			set("name", roleName).
+			set("role", tfRef("aws_iam_role."+identifier(role.name), "id")).
+			set("policy", tfHeredoc(tfEscape(role.permPolicy)))
+		output(identifier(role.name)+"_role_arn", tfRef("aws_iam_role."+identifier(role.name), "arn"))
+	}
+
+	profileName := o.profileName()
+	withTags(withBoundary(resource("aws_iam_role", "worker").
+		set("name", workerRoleName(profileName, o.WorkerRoleName)).
+		set("path", o.iamPath()).
+		set("assume_role_policy", tfHeredoc(workerAssumeRolePolicy(o.Region)))))
+	resource("aws_iam_role_policy", "worker").
+		set("name", fmt.Sprintf("%s-policy", profileName)).
+		set("role", tfRef("aws_iam_role.worker", "id")).
+		set("policy", tfHeredoc(workerInstancePolicy))
+	if o.RequireIMDSv2 {
+		resource("aws_iam_role_policy", "worker_imdsv2").
+			set("name", workerIMDSv2PolicyName(profileName)).
+			set("role", tfRef("aws_iam_role.worker", "id")).
+			set("policy", tfHeredoc(workerIMDSv2Policy))
+	}
+	withTags(resource("aws_iam_instance_profile", "worker").
+		set("name", profileName).
+		set("path", o.iamPath()).
+		set("role", tfRef("aws_iam_role.worker", "name")))
+
+	output("issuer_url", o.IssuerURL)
+	output("profile_name", tfRef("aws_iam_instance_profile.worker", "name"))
+
+	return renderTerraform(fmt.Sprintf("HyperShift IAM resources for infra ID %s", o.InfraID), blocks), nil
+}
@@ -0,0 +1,114 @@
+package aws
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestInfraTerraformConfiguration(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	o := &CreateInfraOptions{
+		Region:         "us-east-2",
+		InfraID:        "test-infra",
+		Name:           "test",
+		BaseDomain:     "example.com",
+		Zones:          []string{"us-east-2a", "us-east-2b"},
+		AdditionalTags: []string{"team=hypershift"},
+	}
+	out, err := o.TerraformConfiguration()
+	g.Expect(err).ToNot(HaveOccurred())
+
+	config := string(out)
+	for _, resource := range []string{
+		`resource "aws_vpc" "vpc"`,
+		`resource "aws_security_group" "worker"`,
+		`resource "aws_subnet" "private_1"`,
+		`resource "aws_nat_gateway" "nat_1"`,
+		`resource "aws_vpc_endpoint" "s3"`,
+		`resource "aws_route53_zone" "local"`,
+		`output "private_subnet_1_id"`,
+	} {
+		g.Expect(config).To(ContainSubstring(resource))
+	}
+	g.Expect(config).To(ContainSubstring(`"10.0.144.0/20"`))
+	g.Expect(config).To(ContainSubstring(`"team" = "hypershift"`))
+	g.Expect(config).To(MatchRegexp(`self\s+= true`))
+
+	o.EnableProxy = true
+	_, err = o.TerraformConfiguration()
+	g.Expect(err).To(HaveOccurred())
+}
+
+func TestIAMTerraformConfiguration(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	o := &CreateIAMOptions{
+		InfraID:       "test-infra",
+		IssuerURL:     "https://bucket.s3.us-east-1.amazonaws.com/test-infra",
+		PublicZoneID:  "PUBLIC",
+		PrivateZoneID: "PRIVATE",
+		LocalZoneID:   "LOCAL",
+		KMSKeyARN:     "arn:aws:kms:us-east-1:123456789012:key/test",
+	}
+	out, err := o.TerraformConfiguration()
+	g.Expect(err).ToNot(HaveOccurred())
+
+	config := string(out)
+	g.Expect(config).To(ContainSubstring(`resource "aws_iam_openid_connect_provider" "oidc"`))
+	g.Expect(config).To(ContainSubstring(`"Federated": "${aws_iam_openid_connect_provider.oidc.arn}"`))
+	g.Expect(config).To(ContainSubstring(`"bucket.s3.us-east-1.amazonaws.com/test-infra:sub": "system:serviceaccount:kube-system:kms-provider"`))
+	g.Expect(config).To(ContainSubstring(`"arn:aws:route53:::hostedzone/PUBLIC"`))
+	g.Expect(config).To(ContainSubstring(`resource "aws_iam_instance_profile" "worker"`))
+	for _, role := range o.oidcRoles() {
+		g.Expect(config).To(MatchRegexp(`name\s+= "test-infra-` + role.name + `"`))
+	}
+	g.Expect(config).ToNot(ContainSubstring("permissions_boundary"))
+
+	o.IAMPath = "/hypershift/"
+	o.PermissionsBoundaryARN = "arn:aws:iam::123456789012:policy/boundary"
+	out, err = o.TerraformConfiguration()
+	g.Expect(err).ToNot(HaveOccurred())
+	config = string(out)
+	g.Expect(config).To(MatchRegexp(`permissions_boundary\s+= "arn:aws:iam::123456789012:policy/boundary"`))
+	g.Expect(config).ToNot(MatchRegexp(`path\s+= "/"\n`))
+	g.Expect(config).ToNot(ContainSubstring("ec2:RoleDelivery"))
+
+	o.InstanceProfileName = "corp-hcp-worker"
+	o.WorkerRoleName = "corp-hcp-node"
+	o.RequireIMDSv2 = true
+	out, err = o.TerraformConfiguration()
+	g.Expect(err).ToNot(HaveOccurred())
+	config = string(out)
+	g.Expect(config).To(MatchRegexp(`name\s+= "corp-hcp-worker"`))
+	g.Expect(config).To(MatchRegexp(`name\s+= "corp-hcp-node"`))
+	g.Expect(config).To(ContainSubstring(`resource "aws_iam_role_policy" "worker_imdsv2"`))
+	g.Expect(config).To(ContainSubstring(`"ec2:RoleDelivery": "2.0"`))
+
+	o.IssuerURL = ""
+	_, err = o.TerraformConfiguration()
+	g.Expect(err).To(HaveOccurred())
+}
+
+func TestInfraTerraformConfigurationZoneCount(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	o := &CreateInfraOptions{
+		Region:     "us-east-2",
+		InfraID:    "test-infra",
+		Name:       "test",
+		BaseDomain: "example.com",
+		ZoneCount:  3,
+	}
+	out, err := o.TerraformConfiguration()
+	g.Expect(err).ToNot(HaveOccurred())
+	config := string(out)
+	g.Expect(config).To(ContainSubstring(`data "aws_availability_zones" "available"`))
+	g.Expect(config).To(ContainSubstring("data.aws_availability_zones.available.names[2]"))
+	g.Expect(config).To(ContainSubstring(`"test-infra-private-az2"`))
+
+	o.Zones = []string{"us-east-2a"}
+	_, err = o.TerraformConfiguration()
+	g.Expect(err).To(HaveOccurred())
+}
@@ -0,0 +1,73 @@
+package util
+
+import (
+	"errors"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+)
+
+var throttledRequests int64
+
+// ThrottledRequests returns the number of AWS API requests of sessions created by
+// NewSession that were throttled, including those retried by the SDK.
+func ThrottledRequests() int64 {
+	return atomic.LoadInt64(&throttledRequests)
+}
+
+// IsErrorThrottle returns true if err, or an error it wraps, is a throttling error
+// such as RequestLimitExceeded.
+func IsErrorThrottle(err error) bool {
+	var awsErr awserr.Error
+	return errors.As(err, &awsErr) && request.IsErrorThrottle(awsErr)
+}
+
+// retryAfterError is a request failure that carries the Retry-After delay of the
+// response.
+type retryAfterError struct {
+	awserr.RequestFailure
+	retryAfter time.Duration
+}
+
+// RetryAfter returns the delay requested by the Retry-After header of the response
+// that err was returned for, if any.
+func RetryAfter(err error) (time.Duration, bool) {
+	var retryAfterErr *retryAfterError
+	if errors.As(err, &retryAfterErr) {
+		return retryAfterErr.retryAfter, true
+	}
+	return 0, false
+}
+
+// countThrottledRequests counts the requests that failed because of throttling.
+var countThrottledRequests = request.NamedHandler{
+	Name: "openshift.io/hypershift/count-throttled-requests",
+	Fn: func(r *request.Request) {
+		if r.IsErrorThrottle() {
+			atomic.AddInt64(&throttledRequests, 1)
+		}
+	},
+}
+
+// recordRetryAfter keeps the Retry-After header of a failed response in the error
+// of the request.
+var recordRetryAfter = request.NamedHandler{
+	Name: "openshift.io/hypershift/record-retry-after",
+	Fn: func(r *request.Request) {
+		if r.HTTPResponse == nil {
+			return
+		}
+		failure, ok := r.Error.(awserr.RequestFailure)
+		if !ok {
+			return
+		}
+		seconds, err := strconv.Atoi(r.HTTPResponse.Header.Get("Retry-After"))
+		if err != nil || seconds < 0 {
+			return
+		}
+		r.Error = &retryAfterError{RequestFailure: failure, retryAfter: time.Duration(seconds) * time.Second}
+	},
+}
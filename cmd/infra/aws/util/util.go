@@ -1,6 +1,9 @@
 package util
 
 import (
+	"errors"
+	"os"
+	"sort"
 	"time"
 
 	utilpointer "k8s.io/utils/pointer"
@@ -8,11 +11,71 @@ import (
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/client"
 	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
 	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/sts"
 )
 
+// webIdentityExpiryWindow is how long before they expire the temporary
+// credentials of a web identity session are refreshed, so that requests in
+// flight never use expired credentials.
+const webIdentityExpiryWindow = 5 * time.Minute
+
+// NewSession returns a session with the credentials in the given file or the
+// given keys. Without either, the credentials are resolved from the environment.
+// If AWS_ROLE_ARN and AWS_WEB_IDENTITY_TOKEN_FILE are set there, the role is
+// assumed with the token as in NewWebIdentitySession.
 func NewSession(agent string, credentialsFile string, credKey string, credSecretKey string, region string) *session.Session {
+	if credentialsFile == "" && credKey == "" && credSecretKey == "" {
+		roleARN, tokenFile := os.Getenv("AWS_ROLE_ARN"), os.Getenv("AWS_WEB_IDENTITY_TOKEN_FILE")
+		if roleARN != "" && tokenFile != "" {
+			return NewWebIdentitySession(agent, roleARN, tokenFile, region)
+		}
+	}
+	return newSession(agent, credentialsFile, credKey, credSecretKey, region)
+}
+
+// NewWebIdentitySession returns a session that assumes the given role with the
+// web identity token in tokenFile, e.g. a projected service account token. The
+// file is read again whenever the temporary credentials are refreshed, so rotated
+// tokens are picked up and no long-lived access keys are needed.
+func NewWebIdentitySession(agent, roleARN, tokenFile, region string) *session.Session {
+	awsSession := newSession(agent, "", "", "", region)
+	provider := stscreds.NewWebIdentityRoleProvider(sts.New(awsSession), roleARN, "hypershift-"+agent, tokenFile)
+	provider.ExpiryWindow = webIdentityExpiryWindow
+	return awsSession.Copy(&aws.Config{Credentials: credentials.NewCredentials(provider)})
+}
+
+// NewSessionWithWebIdentity returns a web identity session if a token file is
+// given and a session with the given credentials otherwise. It is meant for
+// commands that accept either.
+func NewSessionWithWebIdentity(agent, credentialsFile, credKey, credSecretKey, tokenFile, roleARN, region string) *session.Session {
+	if tokenFile != "" {
+		return NewWebIdentitySession(agent, roleARN, tokenFile, region)
+	}
+	return NewSession(agent, credentialsFile, credKey, credSecretKey, region)
+}
+
+// ValidateCredentials validates the credentials given to a command, which are
+// either a credentials file or keys, or a web identity token file together with
+// the role to assume with it.
+func ValidateCredentials(credentialsFile, credKey, tokenFile, roleARN string) error {
+	hasCredentials := credentialsFile != "" || credKey != ""
+	switch {
+	case tokenFile != "" && hasCredentials:
+		return errors.New("only one of --aws-creds or --web-identity-token-file is supported")
+	case tokenFile != "" && roleARN == "":
+		return errors.New("--web-identity-role-arn is required with --web-identity-token-file")
+	case tokenFile == "" && roleARN != "":
+		return errors.New("--web-identity-role-arn can only be specified together with --web-identity-token-file")
+	case tokenFile == "" && !hasCredentials:
+		return errors.New("either --aws-creds or --web-identity-token-file is required")
+	}
+	return nil
+}
+
+func newSession(agent string, credentialsFile string, credKey string, credSecretKey string, region string) *session.Session {
 	sessionOpts := session.Options{}
 	if credentialsFile != "" {
 		sessionOpts.SharedConfigFiles = append(sessionOpts.SharedConfigFiles, credentialsFile)
@@ -28,9 +91,33 @@ func NewSession(agent string, credentialsFile string, credKey string, credSecret
 		Name: "openshift.io/hypershift",
 		Fn:   request.MakeAddToUserAgentHandler("openshift.io hypershift", agent),
 	})
+	awsSession.Handlers.UnmarshalError.PushBackNamed(recordRetryAfter)
+	awsSession.Handlers.Retry.PushFrontNamed(countThrottledRequests)
 	return awsSession
 }
 
+// AssumeRole returns a copy of the session that uses the temporary credentials of
+// the given role, which are obtained with the credentials of the session. The
+// external ID is only passed if set. The session tags are attached to the role
+// session and can be used in the policies of the role.
+func AssumeRole(awsSession *session.Session, roleARN, externalID, sessionName string, sessionTags map[string]string) *session.Session {
+	keys := make([]string, 0, len(sessionTags))
+	for key := range sessionTags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	creds := stscreds.NewCredentials(awsSession, roleARN, func(p *stscreds.AssumeRoleProvider) {
+		p.RoleSessionName = sessionName
+		if externalID != "" {
+			p.ExternalID = aws.String(externalID)
+		}
+		for _, key := range keys {
+			p.Tags = append(p.Tags, &sts.Tag{Key: aws.String(key), Value: aws.String(sessionTags[key])})
+		}
+	})
+	return awsSession.Copy(&aws.Config{Credentials: creds})
+}
+
 // NewAWSRoute53Config generates an AWS config with slightly different Retryer timings
 func NewAWSRoute53Config() *aws.Config {
 	awsRoute53Config := NewConfig()
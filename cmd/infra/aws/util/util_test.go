@@ -0,0 +1,86 @@
+package util
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
+	. "github.com/onsi/gomega"
+)
+
+func TestValidateCredentials(t *testing.T) {
+	const roleARN = "arn:aws:iam::123456789012:role/provisioner"
+	testCases := []struct {
+		name            string
+		credentialsFile string
+		credKey         string
+		tokenFile       string
+		roleARN         string
+		expectError     bool
+	}{
+		{
+			name:            "credentials file",
+			credentialsFile: "/path/to/credentials",
+		},
+		{
+			name:    "keys",
+			credKey: "key",
+		},
+		{
+			name:      "web identity",
+			tokenFile: "/path/to/token",
+			roleARN:   roleARN,
+		},
+		{
+			name:        "no credentials",
+			expectError: true,
+		},
+		{
+			name:            "credentials file and web identity",
+			credentialsFile: "/path/to/credentials",
+			tokenFile:       "/path/to/token",
+			roleARN:         roleARN,
+			expectError:     true,
+		},
+		{
+			name:        "token file without role",
+			tokenFile:   "/path/to/token",
+			expectError: true,
+		},
+		{
+			name:            "role without token file",
+			credentialsFile: "/path/to/credentials",
+			roleARN:         roleARN,
+			expectError:     true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewGomegaWithT(t)
+			err := ValidateCredentials(tc.credentialsFile, tc.credKey, tc.tokenFile, tc.roleARN)
+			if tc.expectError {
+				g.Expect(err).To(HaveOccurred())
+			} else {
+				g.Expect(err).ToNot(HaveOccurred())
+			}
+		})
+	}
+}
+
+func TestNewSessionWebIdentity(t *testing.T) {
+	g := NewGomegaWithT(t)
+	// The token is read when credentials are retrieved, so a missing token file
+	// shows that the role is assumed with web identity without calling STS
+	tokenFile := filepath.Join(t.TempDir(), "token")
+
+	_, err := NewWebIdentitySession("test", "arn:aws:iam::123456789012:role/test", tokenFile, "us-east-1").Config.Credentials.Get()
+	g.Expect(err).To(HaveOccurred())
+	g.Expect(err.(awserr.Error).Code()).To(Equal(stscreds.ErrCodeWebIdentity))
+
+	t.Setenv("AWS_ROLE_ARN", "arn:aws:iam::123456789012:role/test")
+	t.Setenv("AWS_WEB_IDENTITY_TOKEN_FILE", tokenFile)
+	_, err = NewSession("test", "", "", "", "us-east-1").Config.Credentials.Get()
+	g.Expect(err).To(HaveOccurred())
+	g.Expect(err.(awserr.Error).Code()).To(Equal(stscreds.ErrCodeWebIdentity))
+}
@@ -0,0 +1,425 @@
+package aws
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/go-logr/logr"
+	"github.com/spf13/cobra"
+
+	awsutil "github.com/openshift/hypershift/cmd/infra/aws/util"
+	"github.com/openshift/hypershift/cmd/log"
+)
+
+// The kinds of drift of a resource.
+const (
+	// DriftMissing is a resource that does not exist.
+	DriftMissing = "missing"
+	// DriftChanged is a resource whose configuration, e.g. its security group
+	// rules, routes or tags, differs from the expected one.
+	DriftChanged = "changed"
+	// DriftMismatch is a resource that differs from the expected one in a way that
+	// can only be fixed by recreating it, e.g. a subnet with another CIDR.
+	DriftMismatch = "mismatch"
+)
+
+type VerifyInfraOptions struct {
+	Region                 string
+	InfraID                string
+	AWSCredentialsFile     string
+	WebIdentityTokenFile   string
+	WebIdentityRoleARN     string
+	AWSKey                 string
+	AWSSecretKey           string
+	RoleARN                string
+	ExternalID             string
+	Zones                  []string
+	AdditionalTags         []string
+	EnableProxy            bool
+	VPCCIDR                string
+	SecondaryCIDRs         []string
+	EnableIPv6             bool
+	AdditionalIngressRules []string
+	SSHIngressCIDRs        []string
+	RestrictEgress         bool
+	EgressCIDRs            []string
+	NATTopology            string
+	PublicIPv4Pool         string
+	OutputFile             string
+	Fix                    bool
+	Timeout                time.Duration
+}
+
+// InfraDrift is a difference between an AWS resource of the infrastructure and
+// the resource create infra would create.
+type InfraDrift struct {
+	Drift        string `json:"drift"`
+	ResourceType string `json:"resourceType"`
+	Name         string `json:"name,omitempty"`
+	ID           string `json:"id,omitempty"`
+	Details      string `json:"details,omitempty"`
+	Fixable      bool   `json:"fixable"`
+}
+
+// InfraDriftReport lists the drift of the infrastructure of a cluster. With --fix,
+// Remaining lists the drift that is left after fixing it.
+type InfraDriftReport struct {
+	InfraID   string       `json:"infraID"`
+	VPCID     string       `json:"vpcID"`
+	Zones     []string     `json:"zones"`
+	Drift     []InfraDrift `json:"drift"`
+	Fixed     bool         `json:"fixed,omitempty"`
+	Remaining []InfraDrift `json:"remaining,omitempty"`
+}
+
+func NewVerifyCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:          "aws",
+		Short:        "Verifies that the AWS infrastructure resources of a cluster match what create infra aws would create",
+		SilenceUsage: true,
+	}
+
+	opts := VerifyInfraOptions{
+		Region: "us-east-1",
+	}
+
+	cmd.Flags().StringVar(&opts.InfraID, "infra-id", opts.InfraID, "Cluster ID with which the AWS resources are tagged (required)")
+	cmd.Flags().StringVar(&opts.AWSCredentialsFile, "aws-creds", opts.AWSCredentialsFile, "Path to an AWS credentials file (required unless --web-identity-token-file is specified)")
+	cmd.Flags().StringVar(&opts.WebIdentityTokenFile, "web-identity-token-file", opts.WebIdentityTokenFile, "Path to a web identity token, e.g. a projected service account token, to assume the role given by --web-identity-role-arn with instead of using --aws-creds. The token is read again whenever the temporary credentials are refreshed")
+	cmd.Flags().StringVar(&opts.WebIdentityRoleARN, "web-identity-role-arn", opts.WebIdentityRoleARN, "The ARN of the role to assume with the token given by --web-identity-token-file")
+	cmd.Flags().StringVar(&opts.Region, "region", opts.Region, "Region where the cluster infra was created")
+	cmd.Flags().StringVar(&opts.RoleARN, "role-arn", opts.RoleARN, "The ARN of a role to assume with the given credentials, e.g. to verify the resources in another account. The infra ID is passed as the hypershift-infra-id session tag")
+	cmd.Flags().StringVar(&opts.ExternalID, "external-id", opts.ExternalID, "The external ID to pass when assuming the role given by --role-arn")
+	cmd.Flags().StringSliceVar(&opts.Zones, "zones", opts.Zones, "The availability zones the infra was created in. Defaults to the zones of the existing private subnets")
+	cmd.Flags().StringSliceVar(&opts.AdditionalTags, "additional-tags", opts.AdditionalTags, "Additional tags the AWS resources are expected to have, in the form key=value")
+	cmd.Flags().BoolVar(&opts.EnableProxy, "enable-proxy", opts.EnableProxy, "If the infra was created with a proxy instead of direct internet access from the nodes")
+	cmd.Flags().StringVar(&opts.VPCCIDR, "vpc-cidr", opts.VPCCIDR, "The primary IPv4 CIDR the VPC was created with. Defaults to the CIDR of the existing VPC")
+	cmd.Flags().StringSliceVar(&opts.SecondaryCIDRs, "secondary-cidrs", opts.SecondaryCIDRs, "Additional IPv4 CIDR blocks expected to be associated with the VPC")
+	cmd.Flags().BoolVar(&opts.EnableIPv6, "enable-ipv6", opts.EnableIPv6, "If the infra was created with an IPv6 CIDR and dual-stack subnets")
+	cmd.Flags().StringArrayVar(&opts.AdditionalIngressRules, "additional-ingress-rule", opts.AdditionalIngressRules, "An additional ingress rule of the form protocol:port:cidr expected on the worker security group. Can be specified multiple times")
+	cmd.Flags().StringSliceVar(&opts.SSHIngressCIDRs, "ssh-ingress-cidr", opts.SSHIngressCIDRs, "The CIDRs SSH to the workers is expected to be allowed from. Defaults to the VPC CIDRs")
+	cmd.Flags().BoolVar(&opts.RestrictEgress, "restrict-egress", opts.RestrictEgress, "If the infra was created with restricted egress from the worker security group")
+	cmd.Flags().StringSliceVar(&opts.EgressCIDRs, "egress-cidr", opts.EgressCIDRs, "Additional CIDRs egress to is expected to be allowed with --restrict-egress")
+	cmd.Flags().StringVar(&opts.NATTopology, "nat-topology", opts.NATTopology, fmt.Sprintf("The NAT topology the infra was created with, one of %q, %q or %q", NATTopologyPerZone, NATTopologySingle, NATTopologyNone))
+	cmd.Flags().StringVar(&opts.PublicIPv4Pool, "public-ipv4-pool", opts.PublicIPv4Pool, "The ID of the public IPv4 pool to allocate the elastic IPs of missing NAT gateways from with --fix")
+	cmd.Flags().StringVar(&opts.OutputFile, "output-file", opts.OutputFile, "Path to a file to write the drift report to. Defaults to stdout")
+	cmd.Flags().BoolVar(&opts.Fix, "fix", opts.Fix, "If true, create the missing resources and reconcile the changed ones the same way create infra aws does. Mismatched resources are only reported")
+	cmd.Flags().DurationVar(&opts.Timeout, "timeout", opts.Timeout, "The maximum duration of the command, e.g. 10m. In-flight AWS operations are aborted when it is exceeded or the command is interrupted; 0 means no timeout")
+
+	cmd.MarkFlagRequired("infra-id")
+
+	l := log.Log
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		if opts.Timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+			defer cancel()
+		}
+		if err := opts.Run(ctx, l); err != nil {
+			l.Error(err, "Failed to verify infrastructure")
+			return err
+		}
+		l.Info("Successfully verified infrastructure")
+		return nil
+	}
+
+	return cmd
+}
+
+// Run writes the drift report of the infrastructure and fails if drift remains.
+func (o *VerifyInfraOptions) Run(ctx context.Context, l logr.Logger) error {
+	defer logThrottledRequests(l)
+	report, err := o.VerifyInfra(ctx, l)
+	if err != nil {
+		return err
+	}
+	outputBytes, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to serialize drift report: %w", err)
+	}
+	out := os.Stdout
+	if len(o.OutputFile) > 0 {
+		out, err = os.Create(o.OutputFile)
+		if err != nil {
+			return fmt.Errorf("cannot create output file: %w", err)
+		}
+		defer out.Close()
+	}
+	if _, err = out.Write(outputBytes); err != nil {
+		return fmt.Errorf("failed to write drift report: %w", err)
+	}
+	switch {
+	case o.Fix && len(report.Remaining) > 0:
+		return fmt.Errorf("%d of %d drifted resources could not be fixed", len(report.Remaining), len(report.Drift))
+	case !o.Fix && len(report.Drift) > 0:
+		return fmt.Errorf("found %d drifted resources", len(report.Drift))
+	}
+	return nil
+}
+
+// createInfraOptions returns the options of create infra that describe the
+// expected shape of the infrastructure.
+func (o *VerifyInfraOptions) createInfraOptions() *CreateInfraOptions {
+	return &CreateInfraOptions{
+		Region:                 o.Region,
+		InfraID:                o.InfraID,
+		Zones:                  o.Zones,
+		AdditionalTags:         o.AdditionalTags,
+		EnableProxy:            o.EnableProxy,
+		VPCCIDR:                o.VPCCIDR,
+		SecondaryCIDRs:         o.SecondaryCIDRs,
+		EnableIPv6:             o.EnableIPv6,
+		AdditionalIngressRules: o.AdditionalIngressRules,
+		SSHIngressCIDRs:        o.SSHIngressCIDRs,
+		RestrictEgress:         o.RestrictEgress,
+		EgressCIDRs:            o.EgressCIDRs,
+		NATTopology:            o.NATTopology,
+		PublicIPv4Pool:         o.PublicIPv4Pool,
+		DryRun:                 true,
+	}
+}
+
+// VerifyInfra compares the VPC resources of the infrastructure with the resources
+// create infra would create by running it as a dry run: the resources it would
+// create are missing, and the ones it would modify have changed. With --fix, the
+// VPC resources are created again and the infrastructure is verified once more.
+func (o *VerifyInfraOptions) VerifyInfra(ctx context.Context, l logr.Logger) (*InfraDriftReport, error) {
+	if err := validateAssumeRoleOptions(o.RoleARN, o.ExternalID); err != nil {
+		return nil, err
+	}
+	createOptions := o.createInfraOptions()
+	if err := createOptions.parseAdditionalTags(); err != nil {
+		return nil, err
+	}
+	if err := createOptions.parseAdditionalIngressRules(); err != nil {
+		return nil, err
+	}
+	if err := createOptions.validateNATTopology(); err != nil {
+		return nil, err
+	}
+	if err := createOptions.validatePublicIPv4PoolOptions(); err != nil {
+		return nil, err
+	}
+	if err := createOptions.validateCIDRs(); err != nil {
+		return nil, err
+	}
+	if err := createOptions.validateZones(); err != nil {
+		return nil, err
+	}
+	if err := awsutil.ValidateCredentials(o.AWSCredentialsFile, o.AWSKey, o.WebIdentityTokenFile, o.WebIdentityRoleARN); err != nil {
+		return nil, err
+	}
+	baseSession := awsutil.NewSessionWithWebIdentity("cli-verify-infra", o.AWSCredentialsFile, o.AWSKey, o.AWSSecretKey, o.WebIdentityTokenFile, o.WebIdentityRoleARN, o.Region)
+	awsSession, err := assumeRole(baseSession, "cli-verify-infra", o.RoleARN, o.ExternalID, o.InfraID)
+	if err != nil {
+		return nil, err
+	}
+	ec2Client := ec2.New(awsSession, awsutil.NewConfig())
+	if err := createOptions.lookupS3PrefixList(ctx, ec2Client); err != nil {
+		return nil, err
+	}
+
+	report, err := createOptions.infraDrift(ctx, l, ec2Client)
+	if err != nil || !o.Fix || len(report.Drift) == 0 {
+		return report, err
+	}
+	l.Info("Fixing drifted resources", "count", len(report.Drift))
+	createOptions.DryRun = false
+	if err := createOptions.createVPCResources(ctx, l, ec2Client, &CreateInfraOutput{}); err != nil {
+		return nil, fmt.Errorf("failed to fix VPC resources: %w", err)
+	}
+	if err := createOptions.ReconcileEC2Tags(ctx, l, ec2Client); err != nil {
+		return nil, fmt.Errorf("failed to fix tags: %w", err)
+	}
+	createOptions.DryRun = true
+	remaining, err := createOptions.infraDrift(ctx, l, ec2Client)
+	if err != nil {
+		return nil, err
+	}
+	report.Fixed = len(remaining.Drift) == 0
+	report.Remaining = remaining.Drift
+	return report, nil
+}
+
+// infraDrift returns the drift of the VPC resources of the infrastructure. The
+// options must be a dry run.
+func (o *CreateInfraOptions) infraDrift(ctx context.Context, l logr.Logger, client ec2iface.EC2API) (*InfraDriftReport, error) {
+	vpcID, err := o.existingVPC(ctx, client, fmt.Sprintf("%s-vpc", o.InfraID))
+	if err != nil {
+		return nil, err
+	}
+	if len(vpcID) == 0 {
+		return nil, fmt.Errorf("no VPC of infra %s found", o.InfraID)
+	}
+	vpcDrift, err := o.vpcCIDRDrift(ctx, client, vpcID)
+	if err != nil {
+		return nil, err
+	}
+	subnets, err := infraSubnets(ctx, client, o.InfraID, vpcID)
+	if err != nil {
+		return nil, err
+	}
+	if len(o.Zones) == 0 {
+		if o.Zones = zonesFromSubnets(o.InfraID, subnets); len(o.Zones) == 0 {
+			return nil, fmt.Errorf("no private subnets of infra %s found, pass the zones with --zones", o.InfraID)
+		}
+	}
+
+	o.plan = InfraPlan{}
+	result := &CreateInfraOutput{}
+	if err := o.createVPCResources(ctx, l, client, result); err != nil {
+		return nil, err
+	}
+	if err := o.ReconcileEC2Tags(ctx, l, client); err != nil {
+		return nil, err
+	}
+	drift := append(driftFromPlan(o.plan), vpcDrift...)
+	subnetDrift, err := o.subnetDrift(subnets)
+	if err != nil {
+		return nil, err
+	}
+	drift = append(drift, subnetDrift...)
+	return &InfraDriftReport{
+		InfraID: o.InfraID,
+		VPCID:   vpcID,
+		Zones:   o.Zones,
+		Drift:   drift,
+	}, nil
+}
+
+// driftFromPlan converts the actions of a dry run into drift. All of them are fixed
+// by running create infra again.
+func driftFromPlan(plan InfraPlan) []InfraDrift {
+	var drift []InfraDrift
+	for _, action := range plan.Actions {
+		kind := DriftChanged
+		if action.Action == "create" {
+			kind = DriftMissing
+		}
+		drift = append(drift, InfraDrift{
+			Drift:        kind,
+			ResourceType: action.ResourceType,
+			Name:         action.Name,
+			ID:           action.ID,
+			Details:      action.Details,
+			Fixable:      true,
+		})
+	}
+	return drift
+}
+
+// infraSubnets returns the subnets of the VPC that are owned by the infrastructure.
+func infraSubnets(ctx context.Context, client ec2iface.EC2API, infraID, vpcID string) ([]*ec2.Subnet, error) {
+	var subnets []*ec2.Subnet
+	err := client.DescribeSubnetsPagesWithContext(ctx, &ec2.DescribeSubnetsInput{
+		Filters: []*ec2.Filter{
+			{Name: aws.String("vpc-id"), Values: []*string{aws.String(vpcID)}},
+			{Name: aws.String(fmt.Sprintf("tag:%s", clusterTag(infraID))), Values: []*string{aws.String(clusterTagValue)}},
+		},
+	}, func(out *ec2.DescribeSubnetsOutput, _ bool) bool {
+		subnets = append(subnets, out.Subnets...)
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cannot list subnets: %w", err)
+	}
+	return subnets, nil
+}
+
+// zonesFromSubnets returns the zones of the private subnets of the infrastructure
+// in the order they were given on creation, which is the order of the subnet CIDRs.
+func zonesFromSubnets(infraID string, subnets []*ec2.Subnet) []string {
+	type zoneSubnet struct {
+		zone string
+		ip   net.IP
+	}
+	var zoneSubnets []zoneSubnet
+	for _, subnet := range subnets {
+		zone := aws.StringValue(subnet.AvailabilityZone)
+		if subnetName(subnet) != fmt.Sprintf("%s-private-%s", infraID, zone) {
+			continue
+		}
+		ip, _, err := net.ParseCIDR(aws.StringValue(subnet.CidrBlock))
+		if err != nil {
+			continue
+		}
+		zoneSubnets = append(zoneSubnets, zoneSubnet{zone: zone, ip: ip.To16()})
+	}
+	sort.Slice(zoneSubnets, func(i, j int) bool { return bytes.Compare(zoneSubnets[i].ip, zoneSubnets[j].ip) < 0 })
+	var zones []string
+	for _, zoneSubnet := range zoneSubnets {
+		zones = append(zones, zoneSubnet.zone)
+	}
+	return zones
+}
+
+func subnetName(subnet *ec2.Subnet) string {
+	return ec2TagValue(subnet.Tags, "Name")
+}
+
+// subnetDrift returns the private and public subnets of the zones whose CIDR
+// differs from the one create infra would give them.
+func (o *CreateInfraOptions) subnetDrift(subnets []*ec2.Subnet) ([]InfraDrift, error) {
+	privateCIDRs, publicCIDRs, err := subnetCIDRs(o.vpcCIDR(), len(o.Zones))
+	if err != nil {
+		return nil, err
+	}
+	expected := map[string]string{}
+	for i, zone := range o.Zones {
+		expected[fmt.Sprintf("%s-private-%s", o.InfraID, zone)] = privateCIDRs[i]
+		expected[fmt.Sprintf("%s-public-%s", o.InfraID, zone)] = publicCIDRs[i]
+	}
+	var drift []InfraDrift
+	for _, subnet := range subnets {
+		name := subnetName(subnet)
+		cidr, ok := expected[name]
+		if !ok || aws.StringValue(subnet.CidrBlock) == cidr {
+			continue
+		}
+		drift = append(drift, InfraDrift{
+			Drift:        DriftMismatch,
+			ResourceType: "subnet",
+			Name:         name,
+			ID:           aws.StringValue(subnet.SubnetId),
+			Details:      fmt.Sprintf("CIDR is %s, expected %s", aws.StringValue(subnet.CidrBlock), cidr),
+		})
+	}
+	sort.Slice(drift, func(i, j int) bool { return drift[i].Name < drift[j].Name })
+	return drift, nil
+}
+
+// vpcCIDRDrift returns the VPC if its primary CIDR differs from the expected one.
+// If no CIDR is expected, the CIDR of the VPC is used to verify the subnets and
+// security group rules.
+func (o *CreateInfraOptions) vpcCIDRDrift(ctx context.Context, client ec2iface.EC2API, vpcID string) ([]InfraDrift, error) {
+	result, err := client.DescribeVpcsWithContext(ctx, &ec2.DescribeVpcsInput{VpcIds: []*string{aws.String(vpcID)}})
+	if err != nil {
+		return nil, fmt.Errorf("cannot describe VPC %s: %w", vpcID, err)
+	}
+	if len(result.Vpcs) == 0 {
+		return nil, fmt.Errorf("VPC %s not found", vpcID)
+	}
+	if len(o.VPCCIDR) == 0 {
+		o.VPCCIDR = aws.StringValue(result.Vpcs[0].CidrBlock)
+	}
+	if aws.StringValue(result.Vpcs[0].CidrBlock) == o.vpcCIDR() {
+		return nil, nil
+	}
+	return []InfraDrift{{
+		Drift:        DriftMismatch,
+		ResourceType: "vpc",
+		Name:         fmt.Sprintf("%s-vpc", o.InfraID),
+		ID:           vpcID,
+		Details:      fmt.Sprintf("CIDR is %s, expected %s", aws.StringValue(result.Vpcs[0].CidrBlock), o.vpcCIDR()),
+	}}, nil
+}
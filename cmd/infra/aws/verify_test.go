@@ -0,0 +1,135 @@
+package aws
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/go-logr/logr"
+	. "github.com/onsi/gomega"
+)
+
+func testSubnet(id, name, zone, cidr string) *ec2.Subnet {
+	return &ec2.Subnet{
+		SubnetId:         aws.String(id),
+		AvailabilityZone: aws.String(zone),
+		CidrBlock:        aws.String(cidr),
+		Tags:             []*ec2.Tag{{Key: aws.String("Name"), Value: aws.String(name)}},
+	}
+}
+
+func TestZonesFromSubnets(t *testing.T) {
+	g := NewGomegaWithT(t)
+	subnets := []*ec2.Subnet{
+		testSubnet("subnet-1", "test-private-us-east-1c", "us-east-1c", "10.0.144.0/20"),
+		testSubnet("subnet-2", "test-public-us-east-1a", "us-east-1a", "10.0.0.0/20"),
+		testSubnet("subnet-3", "test-private-us-east-1a", "us-east-1a", "10.0.128.0/20"),
+		testSubnet("subnet-4", "test-private-outpost", "us-east-1b", "10.0.112.0/20"),
+		testSubnet("subnet-5", "other-private-us-east-1b", "us-east-1b", "10.0.160.0/20"),
+	}
+	g.Expect(zonesFromSubnets("test", subnets)).To(Equal([]string{"us-east-1a", "us-east-1c"}))
+	g.Expect(zonesFromSubnets("test", nil)).To(BeEmpty())
+}
+
+func TestSubnetDrift(t *testing.T) {
+	g := NewGomegaWithT(t)
+	o := &CreateInfraOptions{InfraID: "test", Zones: []string{"us-east-1a", "us-east-1c"}}
+	subnets := []*ec2.Subnet{
+		testSubnet("subnet-1", "test-private-us-east-1a", "us-east-1a", "10.0.128.0/20"),
+		testSubnet("subnet-2", "test-public-us-east-1a", "us-east-1a", "10.0.0.0/20"),
+		testSubnet("subnet-3", "test-private-us-east-1c", "us-east-1c", "10.0.160.0/20"),
+		testSubnet("subnet-4", "test-private-outpost", "us-east-1b", "10.0.112.0/20"),
+	}
+	drift, err := o.subnetDrift(subnets)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(drift).To(Equal([]InfraDrift{{
+		Drift:        DriftMismatch,
+		ResourceType: "subnet",
+		Name:         "test-private-us-east-1c",
+		ID:           "subnet-3",
+		Details:      "CIDR is 10.0.160.0/20, expected 10.0.144.0/20",
+	}}))
+}
+
+func TestDriftFromPlan(t *testing.T) {
+	g := NewGomegaWithT(t)
+	drift := driftFromPlan(InfraPlan{Actions: []PlannedAction{
+		{Action: "create", ResourceType: "natgateway", Name: "test-nat-us-east-1a", ID: "planned-natgateway-test-nat-us-east-1a"},
+		{Action: "modify", ResourceType: "security-group", ID: "sg-1", Details: "authorize 0 egress and 1 ingress rules"},
+	}})
+	g.Expect(drift).To(HaveLen(2))
+	g.Expect(drift[0].Drift).To(Equal(DriftMissing))
+	g.Expect(drift[0].Name).To(Equal("test-nat-us-east-1a"))
+	g.Expect(drift[1].Drift).To(Equal(DriftChanged))
+	g.Expect(drift[1].ID).To(Equal("sg-1"))
+	for _, d := range drift {
+		g.Expect(d.Fixable).To(BeTrue())
+	}
+}
+
+type fakeVerifyVPCClient struct {
+	ec2iface.EC2API
+	vpc *ec2.Vpc
+}
+
+func (f *fakeVerifyVPCClient) DescribeVpcsWithContext(_ aws.Context, _ *ec2.DescribeVpcsInput, _ ...request.Option) (*ec2.DescribeVpcsOutput, error) {
+	return &ec2.DescribeVpcsOutput{Vpcs: []*ec2.Vpc{f.vpc}}, nil
+}
+
+func (f *fakeVerifyVPCClient) DescribeDhcpOptionsPagesWithContext(_ aws.Context, _ *ec2.DescribeDhcpOptionsInput, fn func(*ec2.DescribeDhcpOptionsOutput, bool) bool, _ ...request.Option) error {
+	fn(&ec2.DescribeDhcpOptionsOutput{DhcpOptions: []*ec2.DhcpOptions{{DhcpOptionsId: aws.String("dopt-1")}}}, true)
+	return nil
+}
+
+func TestVPCCIDRDrift(t *testing.T) {
+	g := NewGomegaWithT(t)
+	client := &fakeVerifyVPCClient{vpc: &ec2.Vpc{VpcId: aws.String("vpc-1"), CidrBlock: aws.String("10.1.0.0/16")}}
+
+	// Without an expected CIDR, the CIDR of the VPC is used
+	o := &CreateInfraOptions{InfraID: "test"}
+	drift, err := o.vpcCIDRDrift(context.Background(), client, "vpc-1")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(drift).To(BeEmpty())
+	g.Expect(o.vpcCIDR()).To(Equal("10.1.0.0/16"))
+
+	o = &CreateInfraOptions{InfraID: "test", VPCCIDR: DefaultCIDRBlock}
+	drift, err = o.vpcCIDRDrift(context.Background(), client, "vpc-1")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(drift).To(HaveLen(1))
+	g.Expect(drift[0].Drift).To(Equal(DriftMismatch))
+	g.Expect(drift[0].Fixable).To(BeFalse())
+}
+
+func TestCreateDHCPOptionsDryRun(t *testing.T) {
+	testCases := []struct {
+		name         string
+		dhcpOptionID string
+		expectModify bool
+	}{
+		{
+			name:         "associated",
+			dhcpOptionID: "dopt-1",
+		},
+		{
+			name:         "associated with other options",
+			dhcpOptionID: "dopt-default",
+			expectModify: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewGomegaWithT(t)
+			client := &fakeVerifyVPCClient{vpc: &ec2.Vpc{VpcId: aws.String("vpc-1"), DhcpOptionsId: aws.String(tc.dhcpOptionID)}}
+			o := &CreateInfraOptions{InfraID: "test", Region: "us-east-1", DryRun: true}
+			g.Expect(o.CreateDHCPOptions(context.Background(), logr.Discard(), client, "vpc-1")).To(Succeed())
+			if tc.expectModify {
+				g.Expect(o.plan.Actions).To(HaveLen(1))
+			} else {
+				g.Expect(o.plan.Actions).To(BeEmpty())
+			}
+		})
+	}
+}
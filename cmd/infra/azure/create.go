@@ -2,9 +2,11 @@ package azure
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io/ioutil"
+	"os"
 	"strings"
 	"time"
 
@@ -36,13 +38,19 @@ import (
 )
 
 type CreateInfraOptions struct {
-	Name            string
-	BaseDomain      string
-	Location        string
-	InfraID         string
-	CredentialsFile string
-	Credentials     *apifixtures.AzureCreds
-	OutputFile      string
+	Name              string
+	BaseDomain        string
+	Location          string
+	InfraID           string
+	CredentialsFile   string
+	Credentials       *apifixtures.AzureCreds
+	OutputFile        string
+	VNetCIDR          string
+	SubnetCIDR        string
+	ResourceGroupName string
+	VNetID            string
+	SubnetID          string
+	SecurityGroupID   string
 }
 
 func NewCreateCommand() *cobra.Command {
@@ -61,7 +69,14 @@ func NewCreateCommand() *cobra.Command {
 	cmd.Flags().StringVar(&opts.Location, "location", opts.Location, "Location where cluster infra should be created")
 	cmd.Flags().StringVar(&opts.BaseDomain, "base-domain", opts.BaseDomain, "The ingress base domain for the cluster")
 	cmd.Flags().StringVar(&opts.Name, "name", opts.Name, "A name for the cluster")
-	cmd.Flags().StringVar(&opts.OutputFile, "output-file", opts.OutputFile, "Path to file that will contain output information from infra resources (optional)")
+	cmd.Flags().StringVar(&opts.OutputFile, "output-file", opts.OutputFile, "Path to a file to write the infra output JSON to, which can be passed to create cluster azure with --infra-json. Defaults to stdout")
+	cmd.Flags().StringVar(&opts.VNetCIDR, "vnet-cidr", opts.VNetCIDR, fmt.Sprintf("The IPv4 address space of the VNet. Defaults to %s", DefaultVNetCIDR))
+	cmd.Flags().StringVar(&opts.ResourceGroupName, "resource-group-name", opts.ResourceGroupName, "An existing resource group to create the infrastructure in. The cluster does not own it, so destroying the infrastructure only deletes the resources tagged with the infra ID. Defaults to a new resource group named after the cluster")
+	cmd.Flags().StringVar(&opts.SubnetCIDR, "subnet-cidr", opts.SubnetCIDR, fmt.Sprintf("The IPv4 address prefix of the worker subnet, which must be part of the VNet address space. Defaults to %s", DefaultSubnetCIDR))
+
+	cmd.Flags().StringVar(&opts.VNetID, "vnet-id", opts.VNetID, "The ID of an existing VNet to use instead of creating one. It must be in the resource group given by --resource-group-name and requires --subnet-id")
+	cmd.Flags().StringVar(&opts.SubnetID, "subnet-id", opts.SubnetID, "The ID of an existing subnet of the VNet given by --vnet-id for the workers. It must not be delegated to a service and must have a network security group that allows the traffic between the workers")
+	cmd.Flags().StringVar(&opts.SecurityGroupID, "security-group-id", opts.SecurityGroupID, "The ID of the network security group of the subnet given by --subnet-id. Defaults to the network security group the subnet is associated with")
 
 	cmd.MarkFlagRequired("infra-id")
 	cmd.MarkFlagRequired("azure-creds")
@@ -69,10 +84,14 @@ func NewCreateCommand() *cobra.Command {
 
 	l := log.Log
 	cmd.RunE = func(cmd *cobra.Command, args []string) error {
-		if _, err := opts.Run(cmd.Context(), l); err != nil {
+		result, err := opts.Run(cmd.Context(), l)
+		if err != nil {
 			l.Error(err, "Failed to create infrastructure")
 			return err
 		}
+		if err := opts.writeOutput(l, result); err != nil {
+			return err
+		}
 		l.Info("Successfully created infrastructure")
 		return nil
 	}
@@ -114,6 +133,12 @@ func resourceGroupName(clusterName, infraID string) string {
 }
 
 func (o *CreateInfraOptions) Run(ctx context.Context, l logr.Logger) (*CreateInfraOutput, error) {
+	if err := o.validateCIDRs(); err != nil {
+		return nil, err
+	}
+	if err := o.validateExistingNetworkOptions(); err != nil {
+		return nil, err
+	}
 	creds := o.Credentials
 	if creds == nil {
 		var err error
@@ -152,18 +177,28 @@ func (o *CreateInfraOptions) Run(ctx context.Context, l logr.Logger) (*CreateInf
 	resourceGroupClient := resources.NewGroupsClient(creds.SubscriptionID)
 	resourceGroupClient.Authorizer = authorizer
 
-	resourceGroupName := resourceGroupName(o.Name, o.InfraID)
-	rg, err := resourceGroupClient.CreateOrUpdate(ctx, resourceGroupName, resources.Group{Location: utilpointer.String(o.Location)})
-	if err != nil {
-		return nil, fmt.Errorf("failed to create resource group: %w", err)
+	tags := clusterTags(o.InfraID)
+	var rg resources.Group
+	if len(o.ResourceGroupName) > 0 {
+		rg, err = resourceGroupClient.Get(ctx, o.ResourceGroupName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get resource group %s: %w", o.ResourceGroupName, err)
+		}
+		l.Info("Using existing resourceGroup", "name", *rg.Name)
+	} else {
+		rg, err = resourceGroupClient.CreateOrUpdate(ctx, resourceGroupName(o.Name, o.InfraID), resources.Group{Location: utilpointer.String(o.Location), Tags: tags})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create resource group: %w", err)
+		}
+		l.Info("Successfuly created resourceGroup", "name", *rg.Name)
 	}
-	result.ResourceGroupName = *rg.Name
-	l.Info("Successfuly created resourceGroup", "name", *rg.Name)
+	resourceGroupName := *rg.Name
+	result.ResourceGroupName = resourceGroupName
 
 	identityClient := msi.NewUserAssignedIdentitiesClient(creds.SubscriptionID)
 	identityClient.Authorizer = authorizer
 
-	identity, err := identityClient.CreateOrUpdate(ctx, resourceGroupName, o.Name+"-"+o.InfraID, msi.Identity{Location: &o.Location})
+	identity, err := identityClient.CreateOrUpdate(ctx, resourceGroupName, o.Name+"-"+o.InfraID, msi.Identity{Location: &o.Location, Tags: tags})
 	if err != nil {
 		return nil, fmt.Errorf("failed to create managed identity: %w", err)
 	}
@@ -203,65 +238,22 @@ func (o *CreateInfraOptions) Run(ctx context.Context, l logr.Logger) (*CreateInf
 		break
 	}
 
-	securityGroupClient := network.NewSecurityGroupsClient(creds.SubscriptionID)
-	securityGroupClient.Authorizer = authorizer
-
-	l.Info("Creating network security group")
-	securityGroupFuture, err := securityGroupClient.CreateOrUpdate(ctx, resourceGroupName, o.Name+"-"+o.InfraID+"-nsg", network.SecurityGroup{Location: &o.Location})
-	if err != nil {
-		return nil, fmt.Errorf("failed to create network security group: %w", err)
-	}
-	if err := securityGroupFuture.WaitForCompletionRef(ctx, securityGroupClient.Client); err != nil {
-		return nil, fmt.Errorf("failed waiting for network security group creation to finish: %w", err)
-	}
-	securityGroup, err := securityGroupFuture.Result(securityGroupClient)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get network security group creation result: %w", err)
-	}
-	result.SecurityGroupName = *securityGroup.Name
-	l.Info("Created network security group")
-
-	networksClient := network.NewVirtualNetworksClient(creds.SubscriptionID)
-	networksClient.Authorizer = authorizer
-
-	vnetFuture, err := networksClient.CreateOrUpdate(ctx, resourceGroupName, o.Name+"-"+o.InfraID, network.VirtualNetwork{
-		Location: &o.Location,
-		VirtualNetworkPropertiesFormat: &network.VirtualNetworkPropertiesFormat{
-			AddressSpace: &network.AddressSpace{
-				AddressPrefixes: &[]string{"10.0.0.0/16"},
-			},
-			Subnets: &[]network.Subnet{{
-				Name: utilpointer.String("default"),
-				SubnetPropertiesFormat: &network.SubnetPropertiesFormat{
-					AddressPrefix:        utilpointer.String("10.0.0.0/24"),
-					NetworkSecurityGroup: &network.SecurityGroup{ID: securityGroup.ID},
-				},
-			}},
-		},
-	})
-	if err != nil {
-		return nil, fmt.Errorf("failed to create vnet: %w", err)
-	}
-	if err := vnetFuture.WaitForCompletionRef(ctx, networksClient.Client); err != nil {
-		return nil, fmt.Errorf("failed to wait for vnet creation: %w", err)
-	}
-	vnet, err := vnetFuture.Result(networksClient)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get vnet creation result: %w", err)
-	}
-	if vnet.Subnets == nil || len(*vnet.Subnets) < 1 {
-		return nil, fmt.Errorf("created vnet has no subnets: %+v", vnet)
+	if len(o.VNetID) > 0 {
+		if err := o.useExistingNetwork(ctx, l, creds.SubscriptionID, authorizer, resourceGroupName, &result); err != nil {
+			return nil, err
+		}
+	} else {
+		if err := o.createNetwork(ctx, l, creds.SubscriptionID, authorizer, resourceGroupName, tags, &result); err != nil {
+			return nil, err
+		}
 	}
-	result.SubnetName = *(*vnet.Subnets)[0].Name
-	result.VNetID = *vnet.ID
-	result.VnetName = *vnet.Name
-	l.Info("Successfully created vnet", "name", *vnet.Name, "id", *vnet.ID)
 
 	privateZoneClient := privatedns.NewPrivateZonesClient(creds.SubscriptionID)
 	privateZoneClient.Authorizer = authorizer
 
 	privateZoneParams := privatedns.PrivateZone{
 		Location: utilpointer.String("global"),
+		Tags:     tags,
 	}
 	privateDNSZonePromise, err := privateZoneClient.CreateOrUpdate(ctx, *rg.Name, o.Name+"-azurecluster."+o.BaseDomain, privateZoneParams, "", "")
 	if err != nil {
@@ -282,8 +274,9 @@ func (o *CreateInfraOptions) Run(ctx context.Context, l logr.Logger) (*CreateInf
 
 	virtualNetworkLinkParams := privatedns.VirtualNetworkLink{
 		Location: utilpointer.String("global"),
+		Tags:     tags,
 		VirtualNetworkLinkProperties: &privatedns.VirtualNetworkLinkProperties{
-			VirtualNetwork:      &privatedns.SubResource{ID: vnet.ID},
+			VirtualNetwork:      &privatedns.SubResource{ID: utilpointer.String(result.VNetID)},
 			RegistrationEnabled: utilpointer.BoolPtr(false),
 		},
 	}
@@ -303,6 +296,7 @@ func (o *CreateInfraOptions) Run(ctx context.Context, l logr.Logger) (*CreateInf
 	storageAccountFuture, err := storageAccountClient.Create(ctx, *rg.Name, storageAccountName, storage.AccountCreateParameters{
 		Sku:      &storage.Sku{Name: storage.SkuNamePremiumLRS, Tier: storage.SkuTierStandard},
 		Location: utilpointer.String(o.Location),
+		Tags:     tags,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to create storage account: %w", err)
@@ -375,6 +369,7 @@ func (o *CreateInfraOptions) Run(ctx context.Context, l logr.Logger) (*CreateInf
 			HyperVGeneration: compute.HyperVGenerationTypesV1,
 		},
 		Location: utilpointer.String(o.Location),
+		Tags:     tags,
 	}
 	imageCreationFuture, err := imagesClient.CreateOrUpdate(ctx, resourceGroupName, blobName, imageInput)
 	if err != nil {
@@ -390,20 +385,93 @@ func (o *CreateInfraOptions) Run(ctx context.Context, l logr.Logger) (*CreateInf
 	result.BootImageID = *imageCreationResult.ID
 	l.Info("Successfully created image", "resourceID", *imageCreationResult.ID, "result", imageCreationResult)
 
-	if o.OutputFile != "" {
-		resultSerialized, err := yaml.Marshal(result)
-		if err != nil {
-			return nil, fmt.Errorf("failed to serialize result: %w", err)
-		}
-		if err := ioutil.WriteFile(o.OutputFile, resultSerialized, 0644); err != nil {
-			// Be nice and print the data so it doesn't get lost
-			l.Error(err, "Writing output file failed", "outputfile", o.OutputFile, "data", string(resultSerialized))
-			return nil, fmt.Errorf("failed to write result to --output-file: %w", err)
-		}
+	return &result, nil
+}
+
+// createNetwork creates the worker network security group and the VNet with the
+// worker subnet.
+func (o *CreateInfraOptions) createNetwork(ctx context.Context, l logr.Logger, subscriptionID string, authorizer autorest.Authorizer, resourceGroupName string, tags map[string]*string, result *CreateInfraOutput) error {
+	securityGroupClient := network.NewSecurityGroupsClient(subscriptionID)
+	securityGroupClient.Authorizer = authorizer
+
+	l.Info("Creating network security group")
+	securityRules := workerSecurityGroupRules(o.vnetCIDR())
+	securityGroupFuture, err := securityGroupClient.CreateOrUpdate(ctx, resourceGroupName, o.Name+"-"+o.InfraID+"-nsg", network.SecurityGroup{
+		Location: &o.Location,
+		Tags:     tags,
+		SecurityGroupPropertiesFormat: &network.SecurityGroupPropertiesFormat{
+			SecurityRules: &securityRules,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create network security group: %w", err)
+	}
+	if err := securityGroupFuture.WaitForCompletionRef(ctx, securityGroupClient.Client); err != nil {
+		return fmt.Errorf("failed waiting for network security group creation to finish: %w", err)
 	}
+	securityGroup, err := securityGroupFuture.Result(securityGroupClient)
+	if err != nil {
+		return fmt.Errorf("failed to get network security group creation result: %w", err)
+	}
+	result.SecurityGroupName = *securityGroup.Name
+	l.Info("Created network security group")
 
-	return &result, nil
+	networksClient := network.NewVirtualNetworksClient(subscriptionID)
+	networksClient.Authorizer = authorizer
 
+	vnetFuture, err := networksClient.CreateOrUpdate(ctx, resourceGroupName, o.Name+"-"+o.InfraID, network.VirtualNetwork{
+		Location: &o.Location,
+		Tags:     tags,
+		VirtualNetworkPropertiesFormat: &network.VirtualNetworkPropertiesFormat{
+			AddressSpace: &network.AddressSpace{
+				AddressPrefixes: &[]string{o.vnetCIDR()},
+			},
+			Subnets: &[]network.Subnet{{
+				Name: utilpointer.String("default"),
+				SubnetPropertiesFormat: &network.SubnetPropertiesFormat{
+					AddressPrefix:        utilpointer.String(o.subnetCIDR()),
+					NetworkSecurityGroup: &network.SecurityGroup{ID: securityGroup.ID},
+				},
+			}},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create vnet: %w", err)
+	}
+	if err := vnetFuture.WaitForCompletionRef(ctx, networksClient.Client); err != nil {
+		return fmt.Errorf("failed to wait for vnet creation: %w", err)
+	}
+	vnet, err := vnetFuture.Result(networksClient)
+	if err != nil {
+		return fmt.Errorf("failed to get vnet creation result: %w", err)
+	}
+	if vnet.Subnets == nil || len(*vnet.Subnets) < 1 {
+		return fmt.Errorf("created vnet has no subnets: %+v", vnet)
+	}
+	result.SubnetName = *(*vnet.Subnets)[0].Name
+	result.VNetID = *vnet.ID
+	result.VnetName = *vnet.Name
+	l.Info("Successfully created vnet", "name", *vnet.Name, "id", *vnet.ID)
+	return nil
+}
+
+// writeOutput writes the infra output as JSON to the output file, or to stdout
+// if none is given.
+func (o *CreateInfraOptions) writeOutput(l logr.Logger, result *CreateInfraOutput) error {
+	resultSerialized, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to serialize result: %w", err)
+	}
+	if o.OutputFile == "" {
+		_, err := os.Stdout.Write(resultSerialized)
+		return err
+	}
+	if err := ioutil.WriteFile(o.OutputFile, resultSerialized, 0644); err != nil {
+		// Be nice and print the data so it doesn't get lost
+		l.Error(err, "Writing output file failed", "outputfile", o.OutputFile, "data", string(resultSerialized))
+		return fmt.Errorf("failed to write result to --output-file: %w", err)
+	}
+	return nil
 }
 
 func findDNSZone(ctx context.Context, client dns.ZonesClient, name string) (*dns.Zone, error) {
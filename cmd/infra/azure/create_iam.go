@@ -0,0 +1,245 @@
+package azure
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/services/msi/mgmt/2018-11-30/msi"
+	"github.com/Azure/azure-sdk-for-go/services/preview/authorization/mgmt/2020-04-01-preview/authorization"
+	"github.com/Azure/azure-sdk-for-go/services/resources/mgmt/2020-10-01/resources"
+	"github.com/Azure/go-autorest/autorest/azure"
+	"github.com/Azure/go-autorest/autorest/azure/auth"
+	"github.com/go-logr/logr"
+	"github.com/spf13/cobra"
+	utilpointer "k8s.io/utils/pointer"
+	"sigs.k8s.io/yaml"
+
+	apifixtures "github.com/openshift/hypershift/api/fixtures"
+	"github.com/openshift/hypershift/cmd/log"
+)
+
+type CreateIAMOptions struct {
+	CredentialsFile    string
+	Credentials        *apifixtures.AzureCreds
+	InfrastructureJSON string
+	IssuerURL          string
+	OutputFile         string
+}
+
+// ManagedIdentity is a managed identity created for a cluster component.
+type ManagedIdentity struct {
+	ID          string `json:"id"`
+	ClientID    string `json:"clientID"`
+	PrincipalID string `json:"principalID"`
+}
+
+type CreateIAMOutput struct {
+	TenantID          string `json:"tenantID"`
+	ResourceGroupName string `json:"resourceGroupName"`
+	InfraID           string `json:"infraID"`
+	IssuerURL         string `json:"issuerURL,omitempty"`
+	// Identities are the identities of the components by component name
+	Identities map[string]ManagedIdentity `json:"identities"`
+}
+
+func NewCreateIAMCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:          "azure",
+		Short:        "Creates Azure managed identities for the cluster components",
+		SilenceUsage: true,
+	}
+
+	opts := CreateIAMOptions{}
+
+	cmd.Flags().StringVar(&opts.CredentialsFile, "azure-creds", opts.CredentialsFile, "Path to a credentials file (required)")
+	cmd.Flags().StringVar(&opts.InfrastructureJSON, "infra-json", opts.InfrastructureJSON, "Path to the output file of create infra azure. The identities are created in its resource group and their roles are scoped to its resources (required)")
+	cmd.Flags().StringVar(&opts.IssuerURL, "oidc-issuer-url", opts.IssuerURL, "The OIDC issuer URL of the cluster. If set, federated credentials are added to the identities so that the service accounts of the components can use them with workload identity")
+	cmd.Flags().StringVar(&opts.OutputFile, "output-file", opts.OutputFile, "Path to a file to write the client IDs of the identities to as JSON. Defaults to stdout")
+
+	cmd.MarkFlagRequired("azure-creds")
+	cmd.MarkFlagRequired("infra-json")
+
+	l := log.Log
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		result, err := opts.Run(cmd.Context(), l)
+		if err != nil {
+			l.Error(err, "Failed to create IAM")
+			return err
+		}
+		if err := opts.writeOutput(l, result); err != nil {
+			return err
+		}
+		l.Info("Successfully created IAM")
+		return nil
+	}
+
+	return cmd
+}
+
+func (o *CreateIAMOptions) Run(ctx context.Context, l logr.Logger) (*CreateIAMOutput, error) {
+	rawInfra, err := ioutil.ReadFile(o.InfrastructureJSON)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read infra json file: %w", err)
+	}
+	var infra CreateInfraOutput
+	if err := yaml.Unmarshal(rawInfra, &infra); err != nil {
+		return nil, fmt.Errorf("failed to deserialize infra json file: %w", err)
+	}
+	if len(infra.InfraID) == 0 || len(infra.ResourceGroupName) == 0 {
+		return nil, fmt.Errorf("infra json file %s has no infraID or resourceGroupName", o.InfrastructureJSON)
+	}
+
+	creds := o.Credentials
+	if creds == nil {
+		creds, err = readCredentials(o.CredentialsFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the credentials: %w", err)
+		}
+		l.Info("Using credentials from file", "path", o.CredentialsFile)
+	}
+
+	authorizer, err := auth.ClientCredentialsConfig{
+		TenantID:     creds.TenantID,
+		ClientID:     creds.ClientID,
+		ClientSecret: creds.ClientSecret,
+		AADEndpoint:  azure.PublicCloud.ActiveDirectoryEndpoint,
+		Resource:     azure.PublicCloud.ResourceManagerEndpoint,
+	}.Authorizer()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get azure authorizer: %w", err)
+	}
+
+	resourceGroupClient := resources.NewGroupsClient(creds.SubscriptionID)
+	resourceGroupClient.Authorizer = authorizer
+	rg, err := resourceGroupClient.Get(ctx, infra.ResourceGroupName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get resource group %s: %w", infra.ResourceGroupName, err)
+	}
+
+	identityClient := msi.NewUserAssignedIdentitiesClient(creds.SubscriptionID)
+	identityClient.Authorizer = authorizer
+	roleDefinitionClient := authorization.NewRoleDefinitionsClient(creds.SubscriptionID)
+	roleDefinitionClient.Authorizer = authorizer
+	roleAssignmentClient := authorization.NewRoleAssignmentsClient(creds.SubscriptionID)
+	roleAssignmentClient.Authorizer = authorizer
+	resourcesClient := resources.NewClient(creds.SubscriptionID)
+	resourcesClient.Authorizer = authorizer
+
+	result := &CreateIAMOutput{
+		TenantID:          creds.TenantID,
+		ResourceGroupName: infra.ResourceGroupName,
+		InfraID:           infra.InfraID,
+		IssuerURL:         o.IssuerURL,
+		Identities:        map[string]ManagedIdentity{},
+	}
+	roleDefinitionIDs := map[string]string{}
+	for _, component := range componentIdentities {
+		name := componentIdentityName(infra.InfraID, component.name)
+		identity, err := identityClient.CreateOrUpdate(ctx, infra.ResourceGroupName, name, msi.Identity{
+			Location: utilpointer.String(infra.Location),
+			Tags:     clusterTags(infra.InfraID),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create managed identity %s: %w", name, err)
+		}
+		l.Info("Created managed identity", "name", name)
+		principalID := identity.PrincipalID.String()
+
+		for _, role := range component.roles {
+			roleDefinitionID, ok := roleDefinitionIDs[role.roleName]
+			if !ok {
+				roleDefinitions, err := roleDefinitionClient.List(ctx, *rg.ID, fmt.Sprintf("roleName eq '%s'", role.roleName))
+				if err != nil {
+					return nil, fmt.Errorf("failed to list roleDefinitions: %w", err)
+				}
+				if len(roleDefinitions.Values()) != 1 {
+					return nil, fmt.Errorf("didn't get exactly one roledefinition for %s back: %+v", role.roleName, roleDefinitions.Values())
+				}
+				roleDefinitionID = *roleDefinitions.Values()[0].ID
+				roleDefinitionIDs[role.roleName] = roleDefinitionID
+			}
+			scope, err := scopeID(role.scope, &infra, *rg.ID)
+			if err != nil {
+				return nil, err
+			}
+			if err := createRoleAssignment(ctx, roleAssignmentClient, scope, roleDefinitionID, principalID); err != nil {
+				return nil, fmt.Errorf("failed to assign role %s to managed identity %s: %w", role.roleName, name, err)
+			}
+			l.Info("Assigned role to managed identity", "name", name, "role", role.roleName, "scope", scope)
+		}
+
+		if len(o.IssuerURL) > 0 {
+			for _, sa := range component.serviceAccounts {
+				if err := createFederatedCredential(ctx, resourcesClient, *identity.ID, sa.name, o.IssuerURL, sa.subject()); err != nil {
+					return nil, fmt.Errorf("failed to create federated credential for %s: %w", sa.subject(), err)
+				}
+				l.Info("Created federated credential", "identity", name, "subject", sa.subject())
+			}
+		}
+
+		result.Identities[component.name] = ManagedIdentity{
+			ID:          *identity.ID,
+			ClientID:    identity.ClientID.String(),
+			PrincipalID: principalID,
+		}
+	}
+
+	return result, nil
+}
+
+// createRoleAssignment assigns a role to a principal. A new principal takes a
+// while to be known to the role assignment API, so failures are retried.
+func createRoleAssignment(ctx context.Context, client authorization.RoleAssignmentsClient, scope, roleDefinitionID, principalID string) error {
+	name := roleAssignmentName(scope, roleDefinitionID, principalID)
+	var err error
+	for try := 0; try < 100; try++ {
+		_, err = client.Create(ctx, scope, name, authorization.RoleAssignmentCreateParameters{RoleAssignmentProperties: &authorization.RoleAssignmentProperties{
+			RoleDefinitionID: utilpointer.String(roleDefinitionID),
+			PrincipalID:      utilpointer.String(principalID),
+		}})
+		if err == nil {
+			return nil
+		}
+		time.Sleep(time.Second)
+	}
+	return err
+}
+
+// createFederatedCredential allows the service account tokens of the issuer
+// with the subject to be exchanged for tokens of the managed identity.
+func createFederatedCredential(ctx context.Context, client resources.Client, identityID, name, issuerURL, subject string) error {
+	future, err := client.CreateOrUpdateByID(ctx, identityID+"/federatedIdentityCredentials/"+name, federatedIdentityCredentialsAPIVersion, resources.GenericResource{
+		Properties: map[string]interface{}{
+			"issuer":    issuerURL,
+			"subject":   subject,
+			"audiences": []string{workloadIdentityAudience},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	return future.WaitForCompletionRef(ctx, client.Client)
+}
+
+// writeOutput writes the IAM output as JSON to the output file, or to stdout if
+// none is given.
+func (o *CreateIAMOptions) writeOutput(l logr.Logger, result *CreateIAMOutput) error {
+	resultSerialized, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to serialize result: %w", err)
+	}
+	if o.OutputFile == "" {
+		_, err := os.Stdout.Write(resultSerialized)
+		return err
+	}
+	if err := ioutil.WriteFile(o.OutputFile, resultSerialized, 0644); err != nil {
+		// Be nice and print the data so it doesn't get lost
+		l.Error(err, "Writing output file failed", "outputfile", o.OutputFile, "data", string(resultSerialized))
+		return fmt.Errorf("failed to write result to --output-file: %w", err)
+	}
+	return nil
+}
@@ -4,7 +4,11 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
+	"time"
 
+	"github.com/Azure/azure-sdk-for-go/services/msi/mgmt/2018-11-30/msi"
+	"github.com/Azure/azure-sdk-for-go/services/preview/authorization/mgmt/2020-04-01-preview/authorization"
 	"github.com/Azure/azure-sdk-for-go/services/resources/mgmt/2020-10-01/resources"
 	"github.com/Azure/go-autorest/autorest"
 	"github.com/Azure/go-autorest/autorest/azure"
@@ -12,14 +16,16 @@ import (
 	apifixtures "github.com/openshift/hypershift/api/fixtures"
 	"github.com/openshift/hypershift/cmd/log"
 	"github.com/spf13/cobra"
+	utilpointer "k8s.io/utils/pointer"
 )
 
 type DestroyInfraOptions struct {
-	Name            string
-	Location        string
-	InfraID         string
-	CredentialsFile string
-	Credentials     *apifixtures.AzureCreds
+	Name              string
+	Location          string
+	InfraID           string
+	CredentialsFile   string
+	Credentials       *apifixtures.AzureCreds
+	ResourceGroupName string
 }
 
 func NewDestroyCommand() *cobra.Command {
@@ -37,6 +43,7 @@ func NewDestroyCommand() *cobra.Command {
 	cmd.Flags().StringVar(&opts.CredentialsFile, "azure-creds", opts.CredentialsFile, "Path to a credentials file (required)")
 	cmd.Flags().StringVar(&opts.Location, "location", opts.Location, "Location where cluster infra should be created")
 	cmd.Flags().StringVar(&opts.Name, "name", opts.Name, "A name for the cluster")
+	cmd.Flags().StringVar(&opts.ResourceGroupName, "resource-group-name", opts.ResourceGroupName, "The existing resource group the infrastructure was created in. Only the resources tagged with the infra ID are deleted from it, unless the cluster owns the resource group")
 
 	cmd.MarkFlagRequired("infra-id")
 	cmd.MarkFlagRequired("azure-creds")
@@ -56,6 +63,7 @@ func NewDestroyCommand() *cobra.Command {
 }
 
 func (o *DestroyInfraOptions) Run(ctx context.Context) error {
+	l := log.Log
 	creds := o.Credentials
 	if creds == nil {
 		var err error
@@ -77,18 +85,177 @@ func (o *DestroyInfraOptions) Run(ctx context.Context) error {
 
 	resourceGroupClient := resources.NewGroupsClient(creds.SubscriptionID)
 	resourceGroupClient.Authorizer = authorizer
-	destroyFuture, err := resourceGroupClient.Delete(ctx, resourceGroupName(o.Name, o.InfraID))
+
+	rgName := o.resourceGroupName()
+	rg, err := resourceGroupClient.Get(ctx, rgName)
 	if err != nil {
-		if detailedErr := *new(autorest.DetailedError); errors.As(err, &detailedErr) {
-			if intStatusCode, isInt := detailedErr.StatusCode.(int); isInt && intStatusCode == 404 {
+		if isNotFound(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to get resourceGroup %s: %w", rgName, err)
+	}
+
+	// A resource group the cluster owns goes away with everything in it, an
+	// existing one only loses the resources created for the cluster
+	if len(o.ResourceGroupName) == 0 || isOwned(rg.Tags, o.InfraID) {
+		l.Info("Deleting resourceGroup", "name", rgName)
+		destroyFuture, err := resourceGroupClient.Delete(ctx, rgName)
+		if err != nil {
+			if isNotFound(err) {
 				return nil
 			}
+			return fmt.Errorf("failed to delete resourceGroup: %w", err)
+		}
+		if err := destroyFuture.WaitForCompletionRef(ctx, resourceGroupClient.Client); err != nil {
+			return fmt.Errorf("failed to wait for resourceGroup deletion: %w", err)
+		}
+		return nil
+	}
+
+	resourcesClient := resources.NewClient(creds.SubscriptionID)
+	resourcesClient.Authorizer = authorizer
+	providersClient := resources.NewProvidersClient(creds.SubscriptionID)
+	providersClient.Authorizer = authorizer
+	identityClient := msi.NewUserAssignedIdentitiesClient(creds.SubscriptionID)
+	identityClient.Authorizer = authorizer
+	roleAssignmentClient := authorization.NewRoleAssignmentsClient(creds.SubscriptionID)
+	roleAssignmentClient.Authorizer = authorizer
+
+	l.Info("Deleting resources owned by the cluster", "resourceGroup", rgName)
+	return destroyOwnedResources(ctx, l, &armOwnedResourcesClient{
+		resourceGroup:        rgName,
+		infraID:              o.InfraID,
+		resourcesClient:      resourcesClient,
+		providersClient:      providersClient,
+		identityClient:       identityClient,
+		roleAssignmentClient: roleAssignmentClient,
+		apiVersions:          map[string]string{},
+	}, 10*time.Second)
+}
+
+func (o *DestroyInfraOptions) resourceGroupName() string {
+	if len(o.ResourceGroupName) > 0 {
+		return o.ResourceGroupName
+	}
+	return resourceGroupName(o.Name, o.InfraID)
+}
+
+func isNotFound(err error) bool {
+	if detailedErr := *new(autorest.DetailedError); errors.As(err, &detailedErr) {
+		if intStatusCode, isInt := detailedErr.StatusCode.(int); isInt && intStatusCode == 404 {
+			return true
 		}
-		return fmt.Errorf("failed to delete resourceGroup: %w", err)
 	}
-	if err := destroyFuture.WaitForCompletionRef(ctx, resourceGroupClient.Client); err != nil {
-		return fmt.Errorf("failed to wait for resourceGroup deletion: %w", err)
+	return false
+}
+
+// armOwnedResourcesClient deletes the resources owned by the cluster through
+// the generic resources API.
+type armOwnedResourcesClient struct {
+	resourceGroup        string
+	infraID              string
+	resourcesClient      resources.Client
+	providersClient      resources.ProvidersClient
+	identityClient       msi.UserAssignedIdentitiesClient
+	roleAssignmentClient authorization.RoleAssignmentsClient
+	// apiVersions caches the API versions of the resource types by lowercase type
+	apiVersions map[string]string
+}
+
+func (c *armOwnedResourcesClient) listOwned(ctx context.Context) ([]resources.GenericResourceExpanded, error) {
+	var result []resources.GenericResourceExpanded
+	iter, err := c.resourcesClient.ListByResourceGroupComplete(ctx, c.resourceGroup, ownedResourcesFilter(c.infraID), "", nil)
+	if err != nil {
+		return nil, err
 	}
+	for ; iter.NotDone(); err = iter.NextWithContext(ctx) {
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, iter.Value())
+	}
+	return result, nil
+}
 
+func (c *armOwnedResourcesClient) delete(ctx context.Context, resource resources.GenericResourceExpanded) error {
+	resourceType := utilpointer.StringDeref(resource.Type, "")
+	if strings.EqualFold(resourceType, identityResourceType) {
+		// Role assignments outlive the identity they were granted to
+		if err := c.deleteRoleAssignments(ctx, utilpointer.StringDeref(resource.Name, "")); err != nil {
+			return err
+		}
+	}
+	apiVersion, err := c.apiVersion(ctx, resourceType)
+	if err != nil {
+		return err
+	}
+	future, err := c.resourcesClient.DeleteByID(ctx, *resource.ID, apiVersion)
+	if err != nil {
+		if isNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	if err := future.WaitForCompletionRef(ctx, c.resourcesClient.Client); err != nil {
+		return fmt.Errorf("failed to wait for deletion: %w", err)
+	}
 	return nil
 }
+
+func (c *armOwnedResourcesClient) deleteRoleAssignments(ctx context.Context, identityName string) error {
+	identity, err := c.identityClient.Get(ctx, c.resourceGroup, identityName)
+	if err != nil {
+		if isNotFound(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to get managed identity: %w", err)
+	}
+	if identity.UserAssignedIdentityProperties == nil || identity.PrincipalID == nil {
+		return nil
+	}
+	filter := fmt.Sprintf("principalId eq '%s'", identity.PrincipalID.String())
+	iter, err := c.roleAssignmentClient.ListForResourceGroupComplete(ctx, c.resourceGroup, filter, "")
+	if err != nil {
+		return fmt.Errorf("failed to list role assignments: %w", err)
+	}
+	for ; iter.NotDone(); err = iter.NextWithContext(ctx) {
+		if err != nil {
+			return fmt.Errorf("failed to list role assignments: %w", err)
+		}
+		assignment := iter.Value()
+		if assignment.ID == nil {
+			continue
+		}
+		if _, err := c.roleAssignmentClient.DeleteByID(ctx, *assignment.ID, ""); err != nil && !isNotFound(err) {
+			return fmt.Errorf("failed to delete role assignment %s: %w", *assignment.ID, err)
+		}
+	}
+	return nil
+}
+
+func (c *armOwnedResourcesClient) apiVersion(ctx context.Context, resourceType string) (string, error) {
+	key := strings.ToLower(resourceType)
+	if version, ok := knownAPIVersions[key]; ok {
+		return version, nil
+	}
+	if version, ok := c.apiVersions[key]; ok {
+		return version, nil
+	}
+	parts := strings.SplitN(resourceType, "/", 2)
+	if len(parts) != 2 {
+		return "", fmt.Errorf("invalid resource type %s", resourceType)
+	}
+	provider, err := c.providersClient.Get(ctx, parts[0], "")
+	if err != nil {
+		return "", fmt.Errorf("failed to get resource provider %s: %w", parts[0], err)
+	}
+	if provider.ResourceTypes == nil {
+		return "", fmt.Errorf("resource provider %s has no resource types", parts[0])
+	}
+	version, err := latestAPIVersion(*provider.ResourceTypes, parts[1])
+	if err != nil {
+		return "", err
+	}
+	c.apiVersions[key] = version
+	return version, nil
+}
@@ -0,0 +1,186 @@
+package azure
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/services/compute/mgmt/2021-11-01/compute"
+	"github.com/Azure/azure-sdk-for-go/services/preview/authorization/mgmt/2020-04-01-preview/authorization"
+	"github.com/Azure/azure-sdk-for-go/services/resources/mgmt/2020-10-01/resources"
+	"github.com/Azure/go-autorest/autorest/azure"
+	"github.com/Azure/go-autorest/autorest/azure/auth"
+	utilpointer "k8s.io/utils/pointer"
+
+	apifixtures "github.com/openshift/hypershift/api/fixtures"
+)
+
+// keyVaultAPIVersion is the API version the Key Vault of a disk encryption set
+// is read with through the generic resources API, the keyvault management
+// client is not vendored.
+const keyVaultAPIVersion = "2021-10-01"
+
+// diskEncryptionKeyPermissions are the key permissions the identity of a disk
+// encryption set needs in a Key Vault that uses access policies.
+var diskEncryptionKeyPermissions = []string{"get", "wrapKey", "unwrapKey"}
+
+// diskEncryptionRoleDefinitionIDs are the IDs of the built-in roles that allow
+// the identity of a disk encryption set to wrap and unwrap keys in a Key Vault
+// that uses Azure RBAC.
+var diskEncryptionRoleDefinitionIDs = []string{
+	// Key Vault Crypto Service Encryption User
+	"e147488a-f6f5-4113-8e2d-b22465e65bf6",
+	// Key Vault Crypto Officer
+	"14b46e9e-c2b7-41b4-b07b-48a6ebf60603",
+	// Key Vault Administrator
+	"00482a5a-887f-4fb3-b363-3b7fe8e74483",
+}
+
+// keyVaultProperties are the properties of a Key Vault that decide who may use
+// its keys.
+type keyVaultProperties struct {
+	EnableRbacAuthorization bool                   `json:"enableRbacAuthorization"`
+	AccessPolicies          []keyVaultAccessPolicy `json:"accessPolicies"`
+}
+
+type keyVaultAccessPolicy struct {
+	ObjectID    string `json:"objectId"`
+	Permissions struct {
+		Keys []string `json:"keys"`
+	} `json:"permissions"`
+}
+
+// ParseDiskEncryptionSetID validates that id is the ID of a disk encryption set
+// and returns it parsed.
+func ParseDiskEncryptionSetID(id string) (azure.Resource, error) {
+	resource, err := azure.ParseResourceID(id)
+	if err != nil {
+		return azure.Resource{}, fmt.Errorf("invalid disk encryption set ID %s: %w", id, err)
+	}
+	if !strings.EqualFold(resource.Provider, "Microsoft.Compute") || !strings.EqualFold(resource.ResourceType, "diskEncryptionSets") {
+		return azure.Resource{}, fmt.Errorf("%s is not the ID of a Microsoft.Compute/diskEncryptionSets resource", id)
+	}
+	return resource, nil
+}
+
+// ValidateDiskEncryptionSet checks that the disk encryption set with the given
+// ID is in the subscription of the credentials, since machines can only use disk
+// encryption sets of their own subscription, and that its identity is allowed to
+// use the key it encrypts disks with.
+func ValidateDiskEncryptionSet(ctx context.Context, creds *apifixtures.AzureCreds, id string) error {
+	resource, err := ParseDiskEncryptionSetID(id)
+	if err != nil {
+		return err
+	}
+	if !strings.EqualFold(resource.SubscriptionID, creds.SubscriptionID) {
+		return fmt.Errorf("disk encryption set %s is not in the subscription %s of the cluster", id, creds.SubscriptionID)
+	}
+
+	authorizer, err := auth.ClientCredentialsConfig{
+		TenantID:     creds.TenantID,
+		ClientID:     creds.ClientID,
+		ClientSecret: creds.ClientSecret,
+		AADEndpoint:  azure.PublicCloud.ActiveDirectoryEndpoint,
+		Resource:     azure.PublicCloud.ResourceManagerEndpoint,
+	}.Authorizer()
+	if err != nil {
+		return fmt.Errorf("failed to get azure authorizer: %w", err)
+	}
+
+	diskEncryptionSetsClient := compute.NewDiskEncryptionSetsClient(creds.SubscriptionID)
+	diskEncryptionSetsClient.Authorizer = authorizer
+	diskEncryptionSet, err := diskEncryptionSetsClient.Get(ctx, resource.ResourceGroup, resource.ResourceName)
+	if err != nil {
+		return fmt.Errorf("failed to get disk encryption set %s: %w", id, err)
+	}
+	if diskEncryptionSet.Identity == nil || utilpointer.StringDeref(diskEncryptionSet.Identity.PrincipalID, "") == "" {
+		return fmt.Errorf("disk encryption set %s has no managed identity to access its key with", id)
+	}
+	principalID := *diskEncryptionSet.Identity.PrincipalID
+	if diskEncryptionSet.EncryptionSetProperties == nil || diskEncryptionSet.ActiveKey == nil ||
+		diskEncryptionSet.ActiveKey.SourceVault == nil || utilpointer.StringDeref(diskEncryptionSet.ActiveKey.SourceVault.ID, "") == "" {
+		return fmt.Errorf("disk encryption set %s has no active key in a Key Vault", id)
+	}
+	vaultID := *diskEncryptionSet.ActiveKey.SourceVault.ID
+
+	resourcesClient := resources.NewClient(creds.SubscriptionID)
+	resourcesClient.Authorizer = authorizer
+	vault, err := resourcesClient.GetByID(ctx, vaultID, keyVaultAPIVersion)
+	if err != nil {
+		return fmt.Errorf("failed to get Key Vault %s of disk encryption set %s: %w", vaultID, id, err)
+	}
+	rawProperties, err := json.Marshal(vault.Properties)
+	if err != nil {
+		return fmt.Errorf("failed to read the properties of Key Vault %s: %w", vaultID, err)
+	}
+	var properties keyVaultProperties
+	if err := json.Unmarshal(rawProperties, &properties); err != nil {
+		return fmt.Errorf("failed to read the properties of Key Vault %s: %w", vaultID, err)
+	}
+
+	if !properties.EnableRbacAuthorization {
+		if missing := missingKeyPermissions(properties.AccessPolicies, principalID); len(missing) > 0 {
+			return fmt.Errorf("the access policies of Key Vault %s do not grant the identity %s of disk encryption set %s the key permissions %s", vaultID, principalID, id, strings.Join(missing, ", "))
+		}
+		return nil
+	}
+
+	roleAssignmentClient := authorization.NewRoleAssignmentsClient(creds.SubscriptionID)
+	roleAssignmentClient.Authorizer = authorizer
+	// Filtering by principal includes the assignments inherited from the
+	// resource group and subscription of the vault
+	iter, err := roleAssignmentClient.ListForScopeComplete(ctx, vaultID, fmt.Sprintf("principalId eq '%s'", principalID), "")
+	if err != nil {
+		return fmt.Errorf("failed to list the role assignments of Key Vault %s: %w", vaultID, err)
+	}
+	var roleDefinitionIDs []string
+	for ; iter.NotDone(); err = iter.NextWithContext(ctx) {
+		if err != nil {
+			return fmt.Errorf("failed to list the role assignments of Key Vault %s: %w", vaultID, err)
+		}
+		assignment := iter.Value()
+		if assignment.RoleAssignmentPropertiesWithScope != nil {
+			roleDefinitionIDs = append(roleDefinitionIDs, utilpointer.StringDeref(assignment.RoleDefinitionID, ""))
+		}
+	}
+	if !grantsDiskEncryptionRole(roleDefinitionIDs) {
+		return fmt.Errorf("the identity %s of disk encryption set %s has no role on Key Vault %s that allows it to wrap and unwrap keys, such as Key Vault Crypto Service Encryption User", principalID, id, vaultID)
+	}
+	return nil
+}
+
+// missingKeyPermissions returns the key permissions a disk encryption set needs
+// that the access policies do not grant its identity.
+func missingKeyPermissions(policies []keyVaultAccessPolicy, principalID string) []string {
+	granted := map[string]bool{}
+	for _, policy := range policies {
+		if !strings.EqualFold(policy.ObjectID, principalID) {
+			continue
+		}
+		for _, permission := range policy.Permissions.Keys {
+			granted[strings.ToLower(permission)] = true
+		}
+	}
+	var missing []string
+	for _, permission := range diskEncryptionKeyPermissions {
+		if !granted[strings.ToLower(permission)] {
+			missing = append(missing, permission)
+		}
+	}
+	return missing
+}
+
+// grantsDiskEncryptionRole returns whether one of the role definition IDs is a
+// role that allows wrapping and unwrapping keys.
+func grantsDiskEncryptionRole(roleDefinitionIDs []string) bool {
+	for _, roleDefinitionID := range roleDefinitionIDs {
+		for _, allowed := range diskEncryptionRoleDefinitionIDs {
+			if strings.EqualFold(path.Base(roleDefinitionID), allowed) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,93 @@
+package azure
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestParseDiskEncryptionSetID(t *testing.T) {
+	testCases := []struct {
+		name        string
+		id          string
+		expectError bool
+	}{
+		{
+			name: "disk encryption set",
+			id:   "/subscriptions/123-123/resourceGroups/rg-name/providers/Microsoft.Compute/diskEncryptionSets/des-name",
+		},
+		{
+			name:        "other resource type",
+			id:          "/subscriptions/123-123/resourceGroups/rg-name/providers/Microsoft.KeyVault/vaults/vault-name",
+			expectError: true,
+		},
+		{
+			name:        "not a resource ID",
+			id:          "des-name",
+			expectError: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewGomegaWithT(t)
+			resource, err := ParseDiskEncryptionSetID(tc.id)
+			if tc.expectError {
+				g.Expect(err).To(HaveOccurred())
+				return
+			}
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(resource.SubscriptionID).To(Equal("123-123"))
+			g.Expect(resource.ResourceGroup).To(Equal("rg-name"))
+			g.Expect(resource.ResourceName).To(Equal("des-name"))
+		})
+	}
+}
+
+func TestMissingKeyPermissions(t *testing.T) {
+	policy := func(objectID string, keys ...string) keyVaultAccessPolicy {
+		p := keyVaultAccessPolicy{ObjectID: objectID}
+		p.Permissions.Keys = keys
+		return p
+	}
+	testCases := []struct {
+		name     string
+		policies []keyVaultAccessPolicy
+		expected []string
+	}{
+		{
+			name:     "all permissions granted",
+			policies: []keyVaultAccessPolicy{policy("principal", "Get", "WrapKey", "UnwrapKey")},
+		},
+		{
+			name:     "permissions granted across policies",
+			policies: []keyVaultAccessPolicy{policy("principal", "get"), policy("PRINCIPAL", "wrapKey", "unwrapKey")},
+		},
+		{
+			name:     "permissions granted to another identity",
+			policies: []keyVaultAccessPolicy{policy("other", "get", "wrapKey", "unwrapKey"), policy("principal", "get")},
+			expected: []string{"wrapKey", "unwrapKey"},
+		},
+		{
+			name:     "no policies",
+			expected: diskEncryptionKeyPermissions,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewGomegaWithT(t)
+			g.Expect(missingKeyPermissions(tc.policies, "principal")).To(Equal(tc.expected))
+		})
+	}
+}
+
+func TestGrantsDiskEncryptionRole(t *testing.T) {
+	g := NewGomegaWithT(t)
+	g.Expect(grantsDiskEncryptionRole([]string{
+		"/subscriptions/123-123/providers/Microsoft.Authorization/roleDefinitions/acdd72a7-3385-48ef-bd42-f606fba81ae7",
+		"/subscriptions/123-123/providers/Microsoft.Authorization/roleDefinitions/e147488a-f6f5-4113-8e2d-b22465e65bf6",
+	})).To(BeTrue())
+	g.Expect(grantsDiskEncryptionRole([]string{
+		"/subscriptions/123-123/providers/Microsoft.Authorization/roleDefinitions/8e3af657-a8ff-443c-a75c-2fe8c4bcb635",
+	})).To(BeFalse())
+	g.Expect(grantsDiskEncryptionRole(nil)).To(BeFalse())
+}
@@ -0,0 +1,326 @@
+package azure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/services/network/mgmt/2021-05-01/network"
+	"github.com/Azure/go-autorest/autorest"
+	"github.com/Azure/go-autorest/autorest/azure"
+	"github.com/go-logr/logr"
+	utilpointer "k8s.io/utils/pointer"
+)
+
+// validateExistingNetworkOptions validates the IDs of an existing VNet, subnet
+// and network security group. The cloud provider looks the VNet and the network
+// security group up in the resource group of the cluster, so they must be in it.
+func (o *CreateInfraOptions) validateExistingNetworkOptions() error {
+	if len(o.VNetID) == 0 {
+		if len(o.SubnetID) > 0 || len(o.SecurityGroupID) > 0 {
+			return errors.New("--subnet-id and --security-group-id can only be specified together with --vnet-id")
+		}
+		return nil
+	}
+	if len(o.SubnetID) == 0 {
+		return errors.New("--subnet-id is required with --vnet-id")
+	}
+	if len(o.VNetCIDR) > 0 || len(o.SubnetCIDR) > 0 {
+		return errors.New("--vnet-cidr and --subnet-cidr cannot be specified together with --vnet-id")
+	}
+	if len(o.ResourceGroupName) == 0 {
+		return errors.New("--resource-group-name is required with --vnet-id, the VNet must be in the resource group of the cluster")
+	}
+	ids := []struct{ flag, id string }{
+		{flag: "--vnet-id", id: o.VNetID},
+		{flag: "--subnet-id", id: o.SubnetID},
+	}
+	if len(o.SecurityGroupID) > 0 {
+		ids = append(ids, struct{ flag, id string }{flag: "--security-group-id", id: o.SecurityGroupID})
+	}
+	for _, id := range ids {
+		resource, err := azure.ParseResourceID(id.id)
+		if err != nil {
+			return fmt.Errorf("invalid %s: %w", id.flag, err)
+		}
+		if !strings.EqualFold(resource.ResourceGroup, o.ResourceGroupName) {
+			return fmt.Errorf("%s %s is not in resource group %s", id.flag, id.id, o.ResourceGroupName)
+		}
+	}
+	if !strings.HasPrefix(strings.ToLower(o.SubnetID), strings.ToLower(o.VNetID)+"/subnets/") {
+		return fmt.Errorf("subnet %s is not part of VNet %s", o.SubnetID, o.VNetID)
+	}
+	return nil
+}
+
+// useExistingNetwork validates the existing VNet, subnet and network security
+// group and adds them to the output.
+func (o *CreateInfraOptions) useExistingNetwork(ctx context.Context, l logr.Logger, subscriptionID string, authorizer autorest.Authorizer, resourceGroupName string, result *CreateInfraOutput) error {
+	vnetResource, _ := azure.ParseResourceID(o.VNetID)
+	subnetResource, _ := azure.ParseResourceID(o.SubnetID)
+
+	networksClient := network.NewVirtualNetworksClient(subscriptionID)
+	networksClient.Authorizer = authorizer
+	vnet, err := networksClient.Get(ctx, resourceGroupName, vnetResource.ResourceName, "")
+	if err != nil {
+		return fmt.Errorf("failed to get vnet %s: %w", o.VNetID, err)
+	}
+	subnetsClient := network.NewSubnetsClient(subscriptionID)
+	subnetsClient.Authorizer = authorizer
+	subnet, err := subnetsClient.Get(ctx, resourceGroupName, vnetResource.ResourceName, subnetResource.ResourceName, "")
+	if err != nil {
+		return fmt.Errorf("failed to get subnet %s: %w", o.SubnetID, err)
+	}
+	securityGroupID, err := validateExistingSubnet(subnet, o.SecurityGroupID)
+	if err != nil {
+		return err
+	}
+
+	securityGroupResource, err := azure.ParseResourceID(securityGroupID)
+	if err != nil {
+		return fmt.Errorf("invalid network security group of subnet %s: %w", o.SubnetID, err)
+	}
+	if !strings.EqualFold(securityGroupResource.ResourceGroup, resourceGroupName) {
+		return fmt.Errorf("network security group %s of subnet %s is not in resource group %s", securityGroupID, o.SubnetID, resourceGroupName)
+	}
+	securityGroupClient := network.NewSecurityGroupsClient(subscriptionID)
+	securityGroupClient.Authorizer = authorizer
+	securityGroup, err := securityGroupClient.Get(ctx, resourceGroupName, securityGroupResource.ResourceName, "")
+	if err != nil {
+		return fmt.Errorf("failed to get network security group %s: %w", securityGroupID, err)
+	}
+	var vnetPrefixes []string
+	if vnet.AddressSpace != nil && vnet.AddressSpace.AddressPrefixes != nil {
+		vnetPrefixes = *vnet.AddressSpace.AddressPrefixes
+	}
+	var rules []network.SecurityRule
+	if securityGroup.SecurityGroupPropertiesFormat != nil {
+		if securityGroup.SecurityRules != nil {
+			rules = append(rules, *securityGroup.SecurityRules...)
+		}
+		if securityGroup.DefaultSecurityRules != nil {
+			rules = append(rules, *securityGroup.DefaultSecurityRules...)
+		}
+	}
+	if blocked := blockedWorkerTraffic(rules, vnetPrefixes); len(blocked) > 0 {
+		return fmt.Errorf("network security group %s does not allow the worker traffic %s from the VNet", securityGroupID, strings.Join(blocked, ", "))
+	}
+
+	result.VNetID = *vnet.ID
+	result.VnetName = *vnet.Name
+	result.SubnetName = *subnet.Name
+	result.SecurityGroupName = *securityGroup.Name
+	l.Info("Using existing vnet", "name", *vnet.Name, "subnet", *subnet.Name, "securityGroup", *securityGroup.Name)
+	return nil
+}
+
+// validateExistingSubnet validates that the workers can be placed in the subnet
+// and returns the ID of its network security group, which must be the given one
+// if any.
+func validateExistingSubnet(subnet network.Subnet, securityGroupID string) (string, error) {
+	name := utilpointer.StringDeref(subnet.Name, "")
+	if subnet.SubnetPropertiesFormat == nil {
+		return "", fmt.Errorf("subnet %s has no properties", name)
+	}
+	// Virtual machines cannot be placed in a subnet delegated to a service
+	if subnet.Delegations != nil && len(*subnet.Delegations) > 0 {
+		var services []string
+		for _, delegation := range *subnet.Delegations {
+			if delegation.ServiceDelegationPropertiesFormat != nil {
+				services = append(services, utilpointer.StringDeref(delegation.ServiceName, ""))
+			}
+		}
+		return "", fmt.Errorf("subnet %s is delegated to %s, the workers need a subnet without delegations", name, strings.Join(services, ", "))
+	}
+	if subnet.NetworkSecurityGroup == nil || subnet.NetworkSecurityGroup.ID == nil {
+		return "", fmt.Errorf("subnet %s has no network security group", name)
+	}
+	if len(securityGroupID) > 0 && !strings.EqualFold(*subnet.NetworkSecurityGroup.ID, securityGroupID) {
+		return "", fmt.Errorf("subnet %s is associated with network security group %s, not %s", name, *subnet.NetworkSecurityGroup.ID, securityGroupID)
+	}
+	return *subnet.NetworkSecurityGroup.ID, nil
+}
+
+// blockedWorkerTraffic returns the names of the worker security rules whose
+// traffic from the VNet the inbound rules of a network security group do not
+// allow. Like Azure, the rules are evaluated in order of priority and the first
+// matching rule decides: an allow rule must cover the whole VNet and all ports
+// of the traffic, while a deny rule blocks it if it matches any part.
+func blockedWorkerTraffic(rules []network.SecurityRule, vnetPrefixes []string) []string {
+	var inbound []network.SecurityRulePropertiesFormat
+	for _, rule := range rules {
+		if rule.SecurityRulePropertiesFormat != nil && rule.Direction == network.SecurityRuleDirectionInbound {
+			inbound = append(inbound, *rule.SecurityRulePropertiesFormat)
+		}
+	}
+	sort.SliceStable(inbound, func(i, j int) bool {
+		return utilpointer.Int32Deref(inbound[i].Priority, 0) < utilpointer.Int32Deref(inbound[j].Priority, 0)
+	})
+
+	var blocked []string
+	for _, required := range workerSecurityRules {
+		requiredPorts, _ := parsePortRange(required.ports)
+		allowed := false
+		for _, rule := range inbound {
+			if rule.Protocol != network.SecurityRuleProtocolAsterisk && !strings.EqualFold(string(rule.Protocol), string(required.protocol)) {
+				continue
+			}
+			ports := ruleDestinationPorts(rule)
+			sources := ruleAddresses(rule.SourceAddressPrefix, rule.SourceAddressPrefixes)
+			destinations := ruleAddresses(rule.DestinationAddressPrefix, rule.DestinationAddressPrefixes)
+			if rule.Access == network.SecurityRuleAccessDeny {
+				if portsOverlap(ports, requiredPorts) && addressesOverlap(sources, vnetPrefixes) && addressesOverlap(destinations, vnetPrefixes) {
+					break
+				}
+				continue
+			}
+			if portsContain(ports, requiredPorts) && addressesCover(sources, vnetPrefixes) && addressesCover(destinations, vnetPrefixes) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			blocked = append(blocked, required.name)
+		}
+	}
+	return blocked
+}
+
+type portRange struct {
+	from, to int
+}
+
+// parsePortRange parses a port range of a security rule: *, a port, or two
+// ports separated by a dash.
+func parsePortRange(s string) (portRange, error) {
+	s = strings.TrimSpace(s)
+	if s == "*" {
+		return portRange{from: 0, to: 65535}, nil
+	}
+	parts := strings.SplitN(s, "-", 2)
+	from, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return portRange{}, fmt.Errorf("invalid port range %q", s)
+	}
+	to := from
+	if len(parts) == 2 {
+		if to, err = strconv.Atoi(parts[1]); err != nil {
+			return portRange{}, fmt.Errorf("invalid port range %q", s)
+		}
+	}
+	return portRange{from: from, to: to}, nil
+}
+
+func ruleDestinationPorts(rule network.SecurityRulePropertiesFormat) []portRange {
+	var ranges []string
+	if rule.DestinationPortRange != nil {
+		ranges = append(ranges, *rule.DestinationPortRange)
+	}
+	if rule.DestinationPortRanges != nil {
+		ranges = append(ranges, *rule.DestinationPortRanges...)
+	}
+	var result []portRange
+	for _, r := range ranges {
+		if parsed, err := parsePortRange(r); err == nil {
+			result = append(result, parsed)
+		}
+	}
+	return result
+}
+
+func portsContain(ranges []portRange, required portRange) bool {
+	for _, r := range ranges {
+		if r.from <= required.from && r.to >= required.to {
+			return true
+		}
+	}
+	return false
+}
+
+func portsOverlap(ranges []portRange, required portRange) bool {
+	for _, r := range ranges {
+		if r.from <= required.to && r.to >= required.from {
+			return true
+		}
+	}
+	return false
+}
+
+func ruleAddresses(prefix *string, prefixes *[]string) []string {
+	var result []string
+	if prefix != nil && len(*prefix) > 0 {
+		result = append(result, *prefix)
+	}
+	if prefixes != nil {
+		result = append(result, *prefixes...)
+	}
+	return result
+}
+
+// matchesWholeVNet returns whether an address prefix of a rule matches any address
+// of the VNet.
+func matchesWholeVNet(address string) bool {
+	return address == "*" || strings.EqualFold(address, "VirtualNetwork")
+}
+
+// addressesCover returns whether the address prefixes of a rule cover all
+// prefixes of the VNet. Service tags other than VirtualNetwork and application
+// security groups are never assumed to.
+func addressesCover(addresses, vnetPrefixes []string) bool {
+	for _, address := range addresses {
+		if matchesWholeVNet(address) {
+			return true
+		}
+	}
+	for _, vnetPrefix := range vnetPrefixes {
+		_, vnetNet, err := net.ParseCIDR(vnetPrefix)
+		if err != nil {
+			return false
+		}
+		covered := false
+		for _, address := range addresses {
+			_, addressNet, err := net.ParseCIDR(address)
+			if err != nil {
+				continue
+			}
+			addressOnes, _ := addressNet.Mask.Size()
+			vnetOnes, _ := vnetNet.Mask.Size()
+			if addressNet.Contains(vnetNet.IP) && addressOnes <= vnetOnes {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			return false
+		}
+	}
+	return len(vnetPrefixes) > 0
+}
+
+// addressesOverlap returns whether the address prefixes of a rule match any
+// address of the VNet.
+func addressesOverlap(addresses, vnetPrefixes []string) bool {
+	for _, address := range addresses {
+		if matchesWholeVNet(address) {
+			return true
+		}
+		_, addressNet, err := net.ParseCIDR(address)
+		if err != nil {
+			continue
+		}
+		for _, vnetPrefix := range vnetPrefixes {
+			_, vnetNet, err := net.ParseCIDR(vnetPrefix)
+			if err != nil {
+				continue
+			}
+			if addressNet.Contains(vnetNet.IP) || vnetNet.Contains(addressNet.IP) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,227 @@
+package azure
+
+import (
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/services/network/mgmt/2021-05-01/network"
+	. "github.com/onsi/gomega"
+	utilpointer "k8s.io/utils/pointer"
+)
+
+const (
+	testVNetID          = "/subscriptions/s/resourceGroups/rg/providers/Microsoft.Network/virtualNetworks/vnet"
+	testSubnetID        = testVNetID + "/subnets/workers"
+	testSecurityGroupID = "/subscriptions/s/resourceGroups/rg/providers/Microsoft.Network/networkSecurityGroups/nsg"
+)
+
+func TestValidateExistingNetworkOptions(t *testing.T) {
+	testCases := []struct {
+		name        string
+		options     CreateInfraOptions
+		expectError bool
+	}{
+		{
+			name:    "new network",
+			options: CreateInfraOptions{},
+		},
+		{
+			name: "existing network",
+			options: CreateInfraOptions{
+				ResourceGroupName: "rg",
+				VNetID:            testVNetID,
+				SubnetID:          testSubnetID,
+				SecurityGroupID:   testSecurityGroupID,
+			},
+		},
+		{
+			name:        "subnet without vnet",
+			options:     CreateInfraOptions{SubnetID: testSubnetID},
+			expectError: true,
+		},
+		{
+			name:        "vnet without subnet",
+			options:     CreateInfraOptions{ResourceGroupName: "rg", VNetID: testVNetID},
+			expectError: true,
+		},
+		{
+			name:        "without resource group",
+			options:     CreateInfraOptions{VNetID: testVNetID, SubnetID: testSubnetID},
+			expectError: true,
+		},
+		{
+			name:        "vnet in another resource group",
+			options:     CreateInfraOptions{ResourceGroupName: "other", VNetID: testVNetID, SubnetID: testSubnetID},
+			expectError: true,
+		},
+		{
+			name: "subnet of another vnet",
+			options: CreateInfraOptions{
+				ResourceGroupName: "rg",
+				VNetID:            testVNetID,
+				SubnetID:          testVNetID + "2/subnets/workers",
+			},
+			expectError: true,
+		},
+		{
+			name: "vnet cidr with existing vnet",
+			options: CreateInfraOptions{
+				ResourceGroupName: "rg",
+				VNetID:            testVNetID,
+				SubnetID:          testSubnetID,
+				VNetCIDR:          "10.1.0.0/16",
+			},
+			expectError: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewGomegaWithT(t)
+			err := tc.options.validateExistingNetworkOptions()
+			if tc.expectError {
+				g.Expect(err).To(HaveOccurred())
+			} else {
+				g.Expect(err).ToNot(HaveOccurred())
+			}
+		})
+	}
+}
+
+func TestValidateExistingSubnet(t *testing.T) {
+	testCases := []struct {
+		name            string
+		subnet          network.SubnetPropertiesFormat
+		securityGroupID string
+		expectError     bool
+	}{
+		{
+			name:   "security group of the subnet",
+			subnet: network.SubnetPropertiesFormat{NetworkSecurityGroup: &network.SecurityGroup{ID: utilpointer.String(testSecurityGroupID)}},
+		},
+		{
+			name:            "given security group",
+			subnet:          network.SubnetPropertiesFormat{NetworkSecurityGroup: &network.SecurityGroup{ID: utilpointer.String(testSecurityGroupID)}},
+			securityGroupID: testSecurityGroupID,
+		},
+		{
+			name:            "other security group",
+			subnet:          network.SubnetPropertiesFormat{NetworkSecurityGroup: &network.SecurityGroup{ID: utilpointer.String(testSecurityGroupID)}},
+			securityGroupID: testSecurityGroupID + "2",
+			expectError:     true,
+		},
+		{
+			name:        "no security group",
+			subnet:      network.SubnetPropertiesFormat{},
+			expectError: true,
+		},
+		{
+			name: "delegated subnet",
+			subnet: network.SubnetPropertiesFormat{
+				NetworkSecurityGroup: &network.SecurityGroup{ID: utilpointer.String(testSecurityGroupID)},
+				Delegations: &[]network.Delegation{{
+					ServiceDelegationPropertiesFormat: &network.ServiceDelegationPropertiesFormat{ServiceName: utilpointer.String("Microsoft.Web/serverFarms")},
+				}},
+			},
+			expectError: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewGomegaWithT(t)
+			subnet := tc.subnet
+			id, err := validateExistingSubnet(network.Subnet{Name: utilpointer.String("workers"), SubnetPropertiesFormat: &subnet}, tc.securityGroupID)
+			if tc.expectError {
+				g.Expect(err).To(HaveOccurred())
+				return
+			}
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(id).To(Equal(testSecurityGroupID))
+		})
+	}
+}
+
+func inboundRule(access network.SecurityRuleAccess, priority int32, protocol network.SecurityRuleProtocol, source, ports string) network.SecurityRule {
+	return network.SecurityRule{
+		SecurityRulePropertiesFormat: &network.SecurityRulePropertiesFormat{
+			Protocol:                 protocol,
+			SourceAddressPrefix:      utilpointer.String(source),
+			DestinationAddressPrefix: utilpointer.String("*"),
+			DestinationPortRange:     utilpointer.String(ports),
+			Access:                   access,
+			Direction:                network.SecurityRuleDirectionInbound,
+			Priority:                 utilpointer.Int32(priority),
+		},
+	}
+}
+
+// defaultSecurityRules are the inbound rules every network security group has.
+var defaultSecurityRules = []network.SecurityRule{
+	inboundRule(network.SecurityRuleAccessAllow, 65000, network.SecurityRuleProtocolAsterisk, "VirtualNetwork", "*"),
+	inboundRule(network.SecurityRuleAccessAllow, 65001, network.SecurityRuleProtocolAsterisk, "AzureLoadBalancer", "*"),
+	inboundRule(network.SecurityRuleAccessDeny, 65500, network.SecurityRuleProtocolAsterisk, "*", "*"),
+}
+
+func TestBlockedWorkerTraffic(t *testing.T) {
+	vnetPrefixes := []string{"10.0.0.0/16"}
+	testCases := []struct {
+		name     string
+		rules    []network.SecurityRule
+		expected []string
+	}{
+		{
+			name:  "default rules",
+			rules: defaultSecurityRules,
+		},
+		{
+			name:  "rules of create infra",
+			rules: append(workerSecurityGroupRules("10.0.0.0/16"), defaultSecurityRules...),
+		},
+		{
+			name: "denied port",
+			rules: append([]network.SecurityRule{
+				inboundRule(network.SecurityRuleAccessDeny, 100, network.SecurityRuleProtocolTCP, "*", "22"),
+			}, defaultSecurityRules...),
+			expected: []string{"ssh"},
+		},
+		{
+			name: "denied part of a port range",
+			rules: append([]network.SecurityRule{
+				inboundRule(network.SecurityRuleAccessDeny, 100, network.SecurityRuleProtocolUDP, "10.0.1.0/24", "30000-30010"),
+			}, defaultSecurityRules...),
+			expected: []string{"node-ports-udp"},
+		},
+		{
+			name: "denied from another network",
+			rules: append([]network.SecurityRule{
+				inboundRule(network.SecurityRuleAccessDeny, 100, network.SecurityRuleProtocolAsterisk, "192.168.0.0/16", "*"),
+			}, defaultSecurityRules...),
+		},
+		{
+			name: "allowed before denied",
+			rules: append([]network.SecurityRule{
+				inboundRule(network.SecurityRuleAccessAllow, 100, network.SecurityRuleProtocolTCP, "10.0.0.0/8", "10250"),
+				inboundRule(network.SecurityRuleAccessDeny, 200, network.SecurityRuleProtocolTCP, "*", "10250"),
+			}, defaultSecurityRules...),
+		},
+		{
+			name: "allowed from part of the vnet only",
+			rules: append([]network.SecurityRule{
+				inboundRule(network.SecurityRuleAccessAllow, 100, network.SecurityRuleProtocolTCP, "10.0.0.0/24", "10250"),
+				inboundRule(network.SecurityRuleAccessDeny, 200, network.SecurityRuleProtocolTCP, "*", "10250"),
+			}, defaultSecurityRules...),
+			expected: []string{"kubelet"},
+		},
+		{
+			name: "all denied",
+			rules: append([]network.SecurityRule{
+				inboundRule(network.SecurityRuleAccessDeny, 4096, network.SecurityRuleProtocolAsterisk, "*", "*"),
+			}, defaultSecurityRules...),
+			expected: []string{"icmp", "ssh", "vxlan", "geneve", "ike", "ike-nat-t", "esp", "internal-tcp", "internal-udp", "kubelet", "node-ports-tcp", "node-ports-udp"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewGomegaWithT(t)
+			g.Expect(blockedWorkerTraffic(tc.rules, vnetPrefixes)).To(Equal(tc.expected))
+		})
+	}
+}
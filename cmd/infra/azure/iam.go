@@ -0,0 +1,130 @@
+package azure
+
+import (
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// federatedIdentityCredentialsAPIVersion is the API version of the federated
+	// identity credentials of managed identities, which the vendored msi client
+	// predates.
+	federatedIdentityCredentialsAPIVersion = "2022-01-31-preview"
+
+	// workloadIdentityAudience is the audience of the service account tokens
+	// exchanged for Azure AD tokens.
+	workloadIdentityAudience = "api://AzureADTokenExchange"
+)
+
+// roleScope is the resource a role is assigned on.
+type roleScope int
+
+const (
+	scopeResourceGroup roleScope = iota
+	scopePublicZone
+	scopePrivateZone
+)
+
+// roleAssignment is a built-in role assigned to a component identity.
+type roleAssignment struct {
+	roleName string
+	scope    roleScope
+}
+
+// serviceAccount is a service account of a component that is allowed to use
+// its identity through workload identity federation.
+type serviceAccount struct {
+	namespace string
+	name      string
+}
+
+func (sa serviceAccount) subject() string {
+	return fmt.Sprintf("system:serviceaccount:%s:%s", sa.namespace, sa.name)
+}
+
+// componentIdentity is a managed identity of a cluster component with the
+// roles it needs and the service accounts it is used from.
+type componentIdentity struct {
+	name            string
+	roles           []roleAssignment
+	serviceAccounts []serviceAccount
+}
+
+// componentIdentities are the identities create iam azure creates. Every role is
+// scoped to the resources of the cluster the component manages rather than the
+// subscription.
+var componentIdentities = []componentIdentity{
+	{
+		// The cloud provider manages the load balancers, public IPs and routes
+		// of the machines in the resource group and their subnet
+		name: "control-plane",
+		roles: []roleAssignment{
+			{roleName: "Network Contributor", scope: scopeResourceGroup},
+			{roleName: "Virtual Machine Contributor", scope: scopeResourceGroup},
+		},
+		serviceAccounts: []serviceAccount{
+			{namespace: "openshift-cloud-controller-manager", name: "cloud-controller-manager"},
+		},
+	},
+	{
+		name: "ingress",
+		roles: []roleAssignment{
+			{roleName: "DNS Zone Contributor", scope: scopePublicZone},
+			{roleName: "Private DNS Zone Contributor", scope: scopePrivateZone},
+		},
+		serviceAccounts: []serviceAccount{
+			{namespace: "openshift-ingress-operator", name: "ingress-operator"},
+		},
+	},
+	{
+		name: "disk-csi",
+		roles: []roleAssignment{
+			{roleName: "Virtual Machine Contributor", scope: scopeResourceGroup},
+			{roleName: "Disk Snapshot Contributor", scope: scopeResourceGroup},
+		},
+		serviceAccounts: []serviceAccount{
+			{namespace: "openshift-cluster-csi-drivers", name: "azure-disk-csi-driver-operator"},
+			{namespace: "openshift-cluster-csi-drivers", name: "azure-disk-csi-driver-controller-sa"},
+		},
+	},
+	{
+		name: "image-registry",
+		roles: []roleAssignment{
+			{roleName: "Storage Account Contributor", scope: scopeResourceGroup},
+			{roleName: "Storage Blob Data Contributor", scope: scopeResourceGroup},
+		},
+		serviceAccounts: []serviceAccount{
+			{namespace: "openshift-image-registry", name: "cluster-image-registry-operator"},
+			{namespace: "openshift-image-registry", name: "registry"},
+		},
+	},
+}
+
+// scopeID returns the ID of the resource a role is assigned on.
+func scopeID(scope roleScope, infra *CreateInfraOutput, resourceGroupID string) (string, error) {
+	var id string
+	switch scope {
+	case scopeResourceGroup:
+		id = resourceGroupID
+	case scopePublicZone:
+		id = infra.PublicZoneID
+	case scopePrivateZone:
+		id = infra.PrivateZoneID
+	}
+	if len(id) == 0 {
+		return "", fmt.Errorf("the infra output has no ID for role scope %d", scope)
+	}
+	return id, nil
+}
+
+func componentIdentityName(infraID, component string) string {
+	return infraID + "-" + component
+}
+
+// roleAssignmentName returns the name of the assignment of a role to a
+// principal on a scope. Names must be UUIDs, deriving them from the assignment
+// makes creating it again a no-op.
+func roleAssignmentName(scope, roleDefinitionID, principalID string) string {
+	return uuid.NewSHA1(uuid.NameSpaceURL, []byte(scope+"|"+roleDefinitionID+"|"+principalID)).String()
+}
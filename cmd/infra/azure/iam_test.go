@@ -0,0 +1,82 @@
+package azure
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestRoleAssignmentName(t *testing.T) {
+	g := NewGomegaWithT(t)
+	name := roleAssignmentName("/subscriptions/s/resourceGroups/rg", "/subscriptions/s/providers/Microsoft.Authorization/roleDefinitions/r", "p")
+	g.Expect(name).To(MatchRegexp("^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$"))
+	g.Expect(roleAssignmentName("/subscriptions/s/resourceGroups/rg", "/subscriptions/s/providers/Microsoft.Authorization/roleDefinitions/r", "p")).To(Equal(name))
+	g.Expect(roleAssignmentName("/subscriptions/s/resourceGroups/rg", "/subscriptions/s/providers/Microsoft.Authorization/roleDefinitions/r", "q")).ToNot(Equal(name))
+}
+
+func TestScopeID(t *testing.T) {
+	infra := &CreateInfraOutput{
+		PublicZoneID:  "public",
+		PrivateZoneID: "private",
+	}
+	testCases := []struct {
+		name        string
+		scope       roleScope
+		infra       *CreateInfraOutput
+		expected    string
+		expectError bool
+	}{
+		{
+			name:     "resource group",
+			scope:    scopeResourceGroup,
+			infra:    infra,
+			expected: "rg",
+		},
+		{
+			name:     "public zone",
+			scope:    scopePublicZone,
+			infra:    infra,
+			expected: "public",
+		},
+		{
+			name:     "private zone",
+			scope:    scopePrivateZone,
+			infra:    infra,
+			expected: "private",
+		},
+		{
+			name:        "missing zone",
+			scope:       scopePrivateZone,
+			infra:       &CreateInfraOutput{},
+			expectError: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewGomegaWithT(t)
+			id, err := scopeID(tc.scope, tc.infra, "rg")
+			if tc.expectError {
+				g.Expect(err).To(HaveOccurred())
+				return
+			}
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(id).To(Equal(tc.expected))
+		})
+	}
+}
+
+func TestComponentIdentities(t *testing.T) {
+	g := NewGomegaWithT(t)
+	names := map[string]bool{}
+	for _, component := range componentIdentities {
+		g.Expect(names).ToNot(HaveKey(component.name))
+		names[component.name] = true
+		g.Expect(component.roles).ToNot(BeEmpty())
+		g.Expect(component.serviceAccounts).ToNot(BeEmpty())
+		for _, role := range component.roles {
+			// No component gets the broad roles the machine identity has
+			g.Expect(role.roleName).ToNot(BeElementOf("Owner", "Contributor"))
+		}
+	}
+	g.Expect(serviceAccount{namespace: "ns", name: "sa"}.subject()).To(Equal("system:serviceaccount:ns:sa"))
+}
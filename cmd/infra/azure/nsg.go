@@ -0,0 +1,103 @@
+package azure
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/Azure/azure-sdk-for-go/services/network/mgmt/2021-05-01/network"
+	utilpointer "k8s.io/utils/pointer"
+)
+
+const (
+	// DefaultVNetCIDR is the address space of the VNet unless --vnet-cidr is given.
+	DefaultVNetCIDR = "10.0.0.0/16"
+	// DefaultSubnetCIDR is the address prefix of the worker subnet unless
+	// --subnet-cidr is given.
+	DefaultSubnetCIDR = "10.0.0.0/24"
+
+	// workerSecurityRulePriority is the priority of the first rule of the worker
+	// network security group. The rules added by the Azure cloud provider for load
+	// balancer services use priorities from 500 on.
+	workerSecurityRulePriority = 100
+)
+
+// workerSecurityRule is an inbound rule of the worker network security group that
+// allows traffic from the VNet.
+type workerSecurityRule struct {
+	name     string
+	protocol network.SecurityRuleProtocol
+	ports    string
+}
+
+// workerSecurityRules are the same rules the worker security group of the AWS
+// infrastructure has: ICMP and SSH, the overlay network and IPsec between the
+// workers, the host network services, the kubelet and node ports.
+var workerSecurityRules = []workerSecurityRule{
+	{name: "icmp", protocol: network.SecurityRuleProtocolIcmp, ports: "*"},
+	{name: "ssh", protocol: network.SecurityRuleProtocolTCP, ports: "22"},
+	{name: "vxlan", protocol: network.SecurityRuleProtocolUDP, ports: "4789"},
+	{name: "geneve", protocol: network.SecurityRuleProtocolUDP, ports: "6081"},
+	{name: "ike", protocol: network.SecurityRuleProtocolUDP, ports: "500"},
+	{name: "ike-nat-t", protocol: network.SecurityRuleProtocolUDP, ports: "4500"},
+	{name: "esp", protocol: network.SecurityRuleProtocolEsp, ports: "*"},
+	{name: "internal-tcp", protocol: network.SecurityRuleProtocolTCP, ports: "9000-9999"},
+	{name: "internal-udp", protocol: network.SecurityRuleProtocolUDP, ports: "9000-9999"},
+	{name: "kubelet", protocol: network.SecurityRuleProtocolTCP, ports: "10250"},
+	{name: "node-ports-tcp", protocol: network.SecurityRuleProtocolTCP, ports: "30000-32767"},
+	{name: "node-ports-udp", protocol: network.SecurityRuleProtocolUDP, ports: "30000-32767"},
+}
+
+// workerSecurityGroupRules returns the inbound rules of the worker network
+// security group for a VNet with the given address space.
+func workerSecurityGroupRules(vnetCIDR string) []network.SecurityRule {
+	var rules []network.SecurityRule
+	for i, rule := range workerSecurityRules {
+		rules = append(rules, network.SecurityRule{
+			Name: utilpointer.String(fmt.Sprintf("worker-%s", rule.name)),
+			SecurityRulePropertiesFormat: &network.SecurityRulePropertiesFormat{
+				Protocol:                 rule.protocol,
+				SourcePortRange:          utilpointer.String("*"),
+				DestinationPortRange:     utilpointer.String(rule.ports),
+				SourceAddressPrefix:      utilpointer.String(vnetCIDR),
+				DestinationAddressPrefix: utilpointer.String(vnetCIDR),
+				Access:                   network.SecurityRuleAccessAllow,
+				Direction:                network.SecurityRuleDirectionInbound,
+				Priority:                 utilpointer.Int32(int32(workerSecurityRulePriority + 10*i)),
+			},
+		})
+	}
+	return rules
+}
+
+func (o *CreateInfraOptions) vnetCIDR() string {
+	if len(o.VNetCIDR) == 0 {
+		return DefaultVNetCIDR
+	}
+	return o.VNetCIDR
+}
+
+func (o *CreateInfraOptions) subnetCIDR() string {
+	if len(o.SubnetCIDR) == 0 {
+		return DefaultSubnetCIDR
+	}
+	return o.SubnetCIDR
+}
+
+// validateCIDRs validates that the VNet and subnet CIDRs are IPv4 CIDRs and that
+// the subnet is part of the VNet.
+func (o *CreateInfraOptions) validateCIDRs() error {
+	_, vnet, err := net.ParseCIDR(o.vnetCIDR())
+	if err != nil || vnet.IP.To4() == nil {
+		return fmt.Errorf("invalid --vnet-cidr %q, expected an IPv4 CIDR", o.vnetCIDR())
+	}
+	_, subnet, err := net.ParseCIDR(o.subnetCIDR())
+	if err != nil || subnet.IP.To4() == nil {
+		return fmt.Errorf("invalid --subnet-cidr %q, expected an IPv4 CIDR", o.subnetCIDR())
+	}
+	vnetOnes, _ := vnet.Mask.Size()
+	subnetOnes, _ := subnet.Mask.Size()
+	if !vnet.Contains(subnet.IP) || subnetOnes < vnetOnes {
+		return fmt.Errorf("subnet CIDR %s is not part of VNet CIDR %s", subnet, vnet)
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package azure
+
+import (
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/services/network/mgmt/2021-05-01/network"
+	. "github.com/onsi/gomega"
+)
+
+func TestWorkerSecurityGroupRules(t *testing.T) {
+	g := NewGomegaWithT(t)
+	rules := workerSecurityGroupRules("10.1.0.0/16")
+	g.Expect(rules).To(HaveLen(len(workerSecurityRules)))
+	priorities := map[int32]bool{}
+	for _, rule := range rules {
+		g.Expect(*rule.SourceAddressPrefix).To(Equal("10.1.0.0/16"))
+		g.Expect(rule.Direction).To(Equal(network.SecurityRuleDirectionInbound))
+		g.Expect(priorities).ToNot(HaveKey(*rule.Priority))
+		priorities[*rule.Priority] = true
+	}
+	g.Expect(*rules[1].Name).To(Equal("worker-ssh"))
+	g.Expect(*rules[1].DestinationPortRange).To(Equal("22"))
+}
+
+func TestValidateCIDRs(t *testing.T) {
+	testCases := []struct {
+		name        string
+		options     CreateInfraOptions
+		expectError bool
+	}{
+		{
+			name: "defaults",
+		},
+		{
+			name:    "custom",
+			options: CreateInfraOptions{VNetCIDR: "192.168.0.0/20", SubnetCIDR: "192.168.4.0/22"},
+		},
+		{
+			name:        "subnet outside of the VNet",
+			options:     CreateInfraOptions{VNetCIDR: "192.168.0.0/20"},
+			expectError: true,
+		},
+		{
+			name:        "subnet larger than the VNet",
+			options:     CreateInfraOptions{VNetCIDR: "10.0.0.0/24", SubnetCIDR: "10.0.0.0/16"},
+			expectError: true,
+		},
+		{
+			name:        "IPv6",
+			options:     CreateInfraOptions{VNetCIDR: "fd00::/48", SubnetCIDR: "fd00::/64"},
+			expectError: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewGomegaWithT(t)
+			err := tc.options.validateCIDRs()
+			if tc.expectError {
+				g.Expect(err).To(HaveOccurred())
+			} else {
+				g.Expect(err).ToNot(HaveOccurred())
+			}
+		})
+	}
+}
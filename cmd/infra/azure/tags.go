@@ -0,0 +1,184 @@
+package azure
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/services/resources/mgmt/2020-10-01/resources"
+	"github.com/go-logr/logr"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	utilpointer "k8s.io/utils/pointer"
+)
+
+const (
+	// clusterTagValueOwned marks a resource as created for and removed with the
+	// cluster, the same convention the OpenShift installer uses.
+	clusterTagValueOwned = "owned"
+
+	identityResourceType = "Microsoft.ManagedIdentity/userAssignedIdentities"
+
+	// destroyPasses is how often deleting the tagged resources is attempted.
+	// Resources that fail to delete because something still depends on them
+	// are retried once their dependents are gone.
+	destroyPasses = 5
+)
+
+// clusterTagKey is the tag every resource of the cluster infrastructure carries.
+// Azure does not allow slashes in tag names, so the dots stand in for them.
+func clusterTagKey(infraID string) string {
+	return "kubernetes.io_cluster." + infraID
+}
+
+// clusterTags returns the tags of a resource owned by the cluster.
+func clusterTags(infraID string) map[string]*string {
+	return map[string]*string{
+		clusterTagKey(infraID): utilpointer.String(clusterTagValueOwned),
+	}
+}
+
+// isOwned returns whether the tags mark a resource as owned by the cluster.
+func isOwned(tags map[string]*string, infraID string) bool {
+	value, ok := tags[clusterTagKey(infraID)]
+	return ok && value != nil && *value == clusterTagValueOwned
+}
+
+// ownedResourcesFilter is the filter of a resource list call that only returns
+// the resources owned by the cluster.
+func ownedResourcesFilter(infraID string) string {
+	return fmt.Sprintf("tagName eq '%s' and tagValue eq '%s'", clusterTagKey(infraID), clusterTagValueOwned)
+}
+
+// knownAPIVersions are the API versions of the resource types create infra azure
+// creates, matching the clients it uses. The API version of any other type is
+// looked up from its resource provider.
+var knownAPIVersions = map[string]string{
+	"microsoft.compute/images":                              "2021-11-01",
+	"microsoft.storage/storageaccounts":                     "2021-04-01",
+	"microsoft.network/privatednszones/virtualnetworklinks": "2018-09-01",
+	"microsoft.network/privatednszones":                     "2018-09-01",
+	"microsoft.network/virtualnetworks":                     "2021-05-01",
+	"microsoft.network/networksecuritygroups":               "2021-05-01",
+	"microsoft.managedidentity/userassignedidentities":      "2018-11-30",
+}
+
+// deletionOrder lists the resource types create infra azure creates in the
+// order they have to be deleted, dependents first. Resources of other types,
+// like the machines and disks of the node pools, are deleted before all of them.
+var deletionOrder = []string{
+	"microsoft.compute/images",
+	"microsoft.storage/storageaccounts",
+	"microsoft.network/privatednszones/virtualnetworklinks",
+	"microsoft.network/privatednszones",
+	"microsoft.network/virtualnetworks",
+	"microsoft.network/networksecuritygroups",
+	"microsoft.managedidentity/userassignedidentities",
+}
+
+func deletionRank(resourceType string) int {
+	for i, t := range deletionOrder {
+		if strings.EqualFold(t, resourceType) {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// sortForDeletion sorts the resources so that dependents come before the
+// resources they depend on.
+func sortForDeletion(items []resources.GenericResourceExpanded) {
+	sort.SliceStable(items, func(i, j int) bool {
+		return deletionRank(utilpointer.StringDeref(items[i].Type, "")) < deletionRank(utilpointer.StringDeref(items[j].Type, ""))
+	})
+}
+
+// latestAPIVersion returns the API version to use for a resource type of a
+// resource provider: its default version, otherwise the newest stable one.
+func latestAPIVersion(providerResourceTypes []resources.ProviderResourceType, resourceType string) (string, error) {
+	for _, t := range providerResourceTypes {
+		if t.ResourceType == nil || !strings.EqualFold(*t.ResourceType, resourceType) {
+			continue
+		}
+		if t.DefaultAPIVersion != nil && *t.DefaultAPIVersion != "" {
+			return *t.DefaultAPIVersion, nil
+		}
+		var latest string
+		if t.APIVersions != nil {
+			for _, version := range *t.APIVersions {
+				if strings.Contains(version, "preview") {
+					continue
+				}
+				// API versions are dates, so they sort lexically
+				if version > latest {
+					latest = version
+				}
+			}
+		}
+		if latest == "" {
+			return "", fmt.Errorf("resource type %s has no stable API version", resourceType)
+		}
+		return latest, nil
+	}
+	return "", fmt.Errorf("resource type %s not found", resourceType)
+}
+
+// ownedResourcesClient lists and deletes the resources owned by the cluster in
+// a resource group.
+type ownedResourcesClient interface {
+	listOwned(ctx context.Context) ([]resources.GenericResourceExpanded, error)
+	// delete deletes the resource and waits for the deletion to finish.
+	delete(ctx context.Context, resource resources.GenericResourceExpanded) error
+}
+
+// destroyOwnedResources deletes all resources owned by the cluster. Deletions
+// that fail are retried in the next pass, and the resources left after the last
+// pass are reported as not removed.
+func destroyOwnedResources(ctx context.Context, l logr.Logger, client ownedResourcesClient, retryInterval time.Duration) error {
+	var errs map[string]error
+	for pass := 0; pass < destroyPasses; pass++ {
+		if pass > 0 {
+			select {
+			case <-ctx.Done():
+				return fmt.Errorf("destroy was aborted: %w", ctx.Err())
+			case <-time.After(retryInterval):
+			}
+		}
+		owned, err := client.listOwned(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to list resources: %w", err)
+		}
+		if len(owned) == 0 {
+			return nil
+		}
+		sortForDeletion(owned)
+		errs = map[string]error{}
+		for _, resource := range owned {
+			id := utilpointer.StringDeref(resource.ID, "")
+			if err := client.delete(ctx, resource); err != nil {
+				errs[id] = err
+				continue
+			}
+			l.Info("Deleted resource", "type", utilpointer.StringDeref(resource.Type, ""), "name", utilpointer.StringDeref(resource.Name, ""))
+		}
+		if len(errs) > 0 {
+			l.Info("WARNING: failed to delete resources, will retry", "count", len(errs))
+		}
+	}
+
+	remaining, err := client.listOwned(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list resources: %w", err)
+	}
+	var orphaned []error
+	for _, resource := range remaining {
+		id := utilpointer.StringDeref(resource.ID, "")
+		if err, ok := errs[id]; ok {
+			orphaned = append(orphaned, fmt.Errorf("failed to delete %s: %w", id, err))
+		} else {
+			orphaned = append(orphaned, fmt.Errorf("failed to delete %s", id))
+		}
+	}
+	return utilerrors.NewAggregate(orphaned)
+}
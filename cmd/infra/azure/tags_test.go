@@ -0,0 +1,180 @@
+package azure
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/services/resources/mgmt/2020-10-01/resources"
+	"github.com/go-logr/logr"
+	. "github.com/onsi/gomega"
+	utilpointer "k8s.io/utils/pointer"
+)
+
+func TestIsOwned(t *testing.T) {
+	g := NewGomegaWithT(t)
+	g.Expect(isOwned(clusterTags("abc"), "abc")).To(BeTrue())
+	g.Expect(isOwned(clusterTags("abc"), "xyz")).To(BeFalse())
+	g.Expect(isOwned(map[string]*string{clusterTagKey("abc"): utilpointer.String("shared")}, "abc")).To(BeFalse())
+	g.Expect(isOwned(nil, "abc")).To(BeFalse())
+	g.Expect(ownedResourcesFilter("abc")).To(Equal("tagName eq 'kubernetes.io_cluster.abc' and tagValue eq 'owned'"))
+}
+
+func TestLatestAPIVersion(t *testing.T) {
+	resourceTypes := []resources.ProviderResourceType{
+		{
+			ResourceType:      utilpointer.String("disks"),
+			APIVersions:       &[]string{"2021-04-01", "2022-07-02", "2023-01-01-preview"},
+			DefaultAPIVersion: nil,
+		},
+		{
+			ResourceType:      utilpointer.String("virtualMachines"),
+			APIVersions:       &[]string{"2021-04-01", "2022-03-01"},
+			DefaultAPIVersion: utilpointer.String("2021-04-01"),
+		},
+		{
+			ResourceType: utilpointer.String("snapshots"),
+			APIVersions:  &[]string{"2023-01-01-preview"},
+		},
+	}
+	testCases := []struct {
+		name         string
+		resourceType string
+		expected     string
+		expectError  bool
+	}{
+		{
+			name:         "newest stable version",
+			resourceType: "disks",
+			expected:     "2022-07-02",
+		},
+		{
+			name:         "default version",
+			resourceType: "virtualmachines",
+			expected:     "2021-04-01",
+		},
+		{
+			name:         "only preview versions",
+			resourceType: "snapshots",
+			expectError:  true,
+		},
+		{
+			name:         "unknown type",
+			resourceType: "images",
+			expectError:  true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewGomegaWithT(t)
+			version, err := latestAPIVersion(resourceTypes, tc.resourceType)
+			if tc.expectError {
+				g.Expect(err).To(HaveOccurred())
+				return
+			}
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(version).To(Equal(tc.expected))
+		})
+	}
+}
+
+type fakeOwnedResourcesClient struct {
+	resources []resources.GenericResourceExpanded
+	// dependencies maps a resource ID to the IDs that have to be deleted first
+	dependencies map[string][]string
+	// undeletable are the IDs whose deletion always fails
+	undeletable map[string]bool
+	deleted     []string
+}
+
+func (c *fakeOwnedResourcesClient) listOwned(ctx context.Context) ([]resources.GenericResourceExpanded, error) {
+	return append([]resources.GenericResourceExpanded(nil), c.resources...), nil
+}
+
+func (c *fakeOwnedResourcesClient) delete(ctx context.Context, resource resources.GenericResourceExpanded) error {
+	if c.undeletable[*resource.ID] {
+		return errors.New("forbidden")
+	}
+	for _, dependent := range c.dependencies[*resource.ID] {
+		for _, r := range c.resources {
+			if *r.ID == dependent {
+				return errors.New("in use")
+			}
+		}
+	}
+	for i, r := range c.resources {
+		if *r.ID == *resource.ID {
+			c.resources = append(c.resources[:i], c.resources[i+1:]...)
+			break
+		}
+	}
+	c.deleted = append(c.deleted, *resource.ID)
+	return nil
+}
+
+func ownedResource(id, resourceType string) resources.GenericResourceExpanded {
+	return resources.GenericResourceExpanded{
+		ID:   utilpointer.String(id),
+		Name: utilpointer.String(id),
+		Type: utilpointer.String(resourceType),
+	}
+}
+
+func TestDestroyOwnedResources(t *testing.T) {
+	testCases := []struct {
+		name            string
+		client          *fakeOwnedResourcesClient
+		expectedDeleted []string
+		expectError     bool
+	}{
+		{
+			name: "dependents are deleted first",
+			client: &fakeOwnedResourcesClient{
+				resources: []resources.GenericResourceExpanded{
+					ownedResource("identity", "Microsoft.ManagedIdentity/userAssignedIdentities"),
+					ownedResource("nsg", "Microsoft.Network/networkSecurityGroups"),
+					ownedResource("vnet", "Microsoft.Network/virtualNetworks"),
+					ownedResource("nic", "Microsoft.Network/networkInterfaces"),
+				},
+			},
+			expectedDeleted: []string{"nic", "vnet", "nsg", "identity"},
+		},
+		{
+			name: "failed deletions are retried",
+			client: &fakeOwnedResourcesClient{
+				resources: []resources.GenericResourceExpanded{
+					ownedResource("disk", "Microsoft.Compute/disks"),
+					ownedResource("vm", "Microsoft.Compute/virtualMachines"),
+				},
+				// The disk is still attached to the machine in the first pass
+				dependencies: map[string][]string{"disk": {"vm"}},
+			},
+			expectedDeleted: []string{"vm", "disk"},
+		},
+		{
+			name: "resources that cannot be removed are reported",
+			client: &fakeOwnedResourcesClient{
+				resources: []resources.GenericResourceExpanded{
+					ownedResource("storage", "Microsoft.Storage/storageAccounts"),
+					ownedResource("image", "Microsoft.Compute/images"),
+				},
+				undeletable: map[string]bool{"storage": true},
+			},
+			expectedDeleted: []string{"image"},
+			expectError:     true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewGomegaWithT(t)
+			err := destroyOwnedResources(context.Background(), logr.Discard(), tc.client, 0)
+			if tc.expectError {
+				g.Expect(err).To(HaveOccurred())
+				g.Expect(err.Error()).To(ContainSubstring("failed to delete storage: forbidden"))
+			} else {
+				g.Expect(err).ToNot(HaveOccurred())
+			}
+			g.Expect(tc.client.deleted).To(Equal(tc.expectedDeleted))
+		})
+	}
+}
@@ -5,6 +5,10 @@ import (
 
 	"github.com/openshift/hypershift/cmd/infra/aws"
 	"github.com/openshift/hypershift/cmd/infra/azure"
+	"github.com/openshift/hypershift/cmd/infra/gcp"
+	"github.com/openshift/hypershift/cmd/infra/ibmcloudvpc"
+	"github.com/openshift/hypershift/cmd/infra/oci"
+	"github.com/openshift/hypershift/cmd/infra/openstack"
 	"github.com/openshift/hypershift/cmd/infra/powervs"
 )
 
@@ -18,6 +22,10 @@ func NewCreateCommand() *cobra.Command {
 	cmd.AddCommand(aws.NewCreateCommand())
 	cmd.AddCommand(azure.NewCreateCommand())
 	cmd.AddCommand(powervs.NewCreateCommand())
+	cmd.AddCommand(ibmcloudvpc.NewCreateCommand())
+	cmd.AddCommand(gcp.NewCreateCommand())
+	cmd.AddCommand(openstack.NewCreateCommand())
+	cmd.AddCommand(oci.NewCreateCommand())
 
 	return cmd
 }
@@ -4,6 +4,8 @@ import (
 	"github.com/spf13/cobra"
 
 	"github.com/openshift/hypershift/cmd/infra/aws"
+	"github.com/openshift/hypershift/cmd/infra/azure"
+	"github.com/openshift/hypershift/cmd/infra/gcp"
 )
 
 func NewCreateIAMCommand() *cobra.Command {
@@ -14,6 +16,8 @@ func NewCreateIAMCommand() *cobra.Command {
 	}
 
 	cmd.AddCommand(aws.NewCreateIAMCommand())
+	cmd.AddCommand(azure.NewCreateIAMCommand())
+	cmd.AddCommand(gcp.NewCreateIAMCommand())
 
 	return cmd
 }
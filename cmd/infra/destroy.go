@@ -5,6 +5,10 @@ import (
 
 	"github.com/openshift/hypershift/cmd/infra/aws"
 	"github.com/openshift/hypershift/cmd/infra/azure"
+	"github.com/openshift/hypershift/cmd/infra/gcp"
+	"github.com/openshift/hypershift/cmd/infra/ibmcloudvpc"
+	"github.com/openshift/hypershift/cmd/infra/oci"
+	"github.com/openshift/hypershift/cmd/infra/openstack"
 	"github.com/openshift/hypershift/cmd/infra/powervs"
 )
 
@@ -16,8 +20,13 @@ func NewDestroyCommand() *cobra.Command {
 	}
 
 	cmd.AddCommand(aws.NewDestroyCommand())
+	cmd.AddCommand(aws.NewDestroyOrphansCommand())
 	cmd.AddCommand(azure.NewDestroyCommand())
 	cmd.AddCommand(powervs.NewDestroyCommand())
+	cmd.AddCommand(ibmcloudvpc.NewDestroyCommand())
+	cmd.AddCommand(gcp.NewDestroyCommand())
+	cmd.AddCommand(openstack.NewDestroyCommand())
+	cmd.AddCommand(oci.NewDestroyCommand())
 
 	return cmd
 }
@@ -4,6 +4,7 @@ import (
 	"github.com/spf13/cobra"
 
 	"github.com/openshift/hypershift/cmd/infra/aws"
+	"github.com/openshift/hypershift/cmd/infra/gcp"
 )
 
 func NewDestroyIAMCommand() *cobra.Command {
@@ -14,6 +15,7 @@ func NewDestroyIAMCommand() *cobra.Command {
 	}
 
 	cmd.AddCommand(aws.NewDestroyIAMCommand())
+	cmd.AddCommand(gcp.NewDestroyIAMCommand())
 
 	return cmd
 }
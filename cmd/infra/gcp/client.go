@@ -0,0 +1,200 @@
+package gcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+const (
+	computeURL          = "https://compute.googleapis.com/compute/v1"
+	iamURL              = "https://iam.googleapis.com/v1"
+	resourceManagerURL  = "https://cloudresourcemanager.googleapis.com/v1"
+	cloudPlatformScope  = "https://www.googleapis.com/auth/cloud-platform"
+	pollingInterval     = 5 * time.Second
+	operationTimeout    = 10 * time.Minute
+	operationStatusDone = "DONE"
+)
+
+// restClient sends requests to the Google Cloud REST APIs. The request body in is
+// marshalled to JSON, and the response is unmarshalled into out if it is not nil.
+type restClient interface {
+	do(ctx context.Context, method, url string, in, out interface{}) error
+}
+
+// apiError is the error returned by the Google Cloud REST APIs.
+type apiError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *apiError) Error() string {
+	return fmt.Sprintf("googleapi: error %d: %s", e.Code, e.Message)
+}
+
+func isNotFound(err error) bool {
+	var apiErr *apiError
+	return errors.As(err, &apiErr) && apiErr.Code == http.StatusNotFound
+}
+
+func isAlreadyExists(err error) bool {
+	var apiErr *apiError
+	return errors.As(err, &apiErr) && apiErr.Code == http.StatusConflict
+}
+
+func isBadRequest(err error) bool {
+	var apiErr *apiError
+	return errors.As(err, &apiErr) && apiErr.Code == http.StatusBadRequest
+}
+
+// httpRESTClient is a restClient that sends the requests with an authenticated
+// HTTP client.
+type httpRESTClient struct {
+	client *http.Client
+}
+
+// newRESTClient returns a client that authenticates with the service account key
+// or credential configuration in credentialsFile, or with the application
+// default credentials if it is empty.
+func newRESTClient(ctx context.Context, credentialsFile string) (restClient, error) {
+	if len(credentialsFile) == 0 {
+		client, err := google.DefaultClient(ctx, cloudPlatformScope)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get the application default credentials: %w", err)
+		}
+		return &httpRESTClient{client: client}, nil
+	}
+	raw, err := ioutil.ReadFile(credentialsFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read credentials file: %w", err)
+	}
+	credentials, err := google.CredentialsFromJSON(ctx, raw, cloudPlatformScope)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse credentials file: %w", err)
+	}
+	return &httpRESTClient{client: oauth2.NewClient(ctx, credentials.TokenSource)}, nil
+}
+
+func (c *httpRESTClient) do(ctx context.Context, method, url string, in, out interface{}) error {
+	var body []byte
+	if in != nil {
+		var err error
+		if body, err = json.Marshal(in); err != nil {
+			return fmt.Errorf("failed to serialize request: %w", err)
+		}
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		errResp := struct {
+			Error *apiError `json:"error"`
+		}{}
+		if err := json.Unmarshal(respBody, &errResp); err != nil || errResp.Error == nil {
+			return &apiError{Code: resp.StatusCode, Message: strings.TrimSpace(string(respBody))}
+		}
+		errResp.Error.Code = resp.StatusCode
+		return errResp.Error
+	}
+	if out != nil && len(respBody) > 0 {
+		if err := json.Unmarshal(respBody, out); err != nil {
+			return fmt.Errorf("failed to deserialize response: %w", err)
+		}
+	}
+	return nil
+}
+
+// computeOperation is a long-running operation of the compute API.
+type computeOperation struct {
+	Name     string `json:"name"`
+	Status   string `json:"status"`
+	SelfLink string `json:"selfLink"`
+	Error    *struct {
+		Errors []struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		} `json:"errors"`
+	} `json:"error,omitempty"`
+}
+
+// computeDo sends a request to the compute API that starts an operation and
+// waits for the operation to complete.
+func computeDo(ctx context.Context, client restClient, method, url string, in interface{}) error {
+	operation := &computeOperation{}
+	if err := client.do(ctx, method, url, in, operation); err != nil {
+		return err
+	}
+	err := wait.PollImmediate(pollingInterval, operationTimeout, func() (bool, error) {
+		if operation.Status != operationStatusDone {
+			if err := client.do(ctx, http.MethodGet, operation.SelfLink, nil, operation); err != nil {
+				return false, err
+			}
+		}
+		return operation.Status == operationStatusDone, nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to wait for operation %s: %w", operation.Name, err)
+	}
+	if operation.Error != nil && len(operation.Error.Errors) > 0 {
+		var messages []string
+		for _, e := range operation.Error.Errors {
+			messages = append(messages, fmt.Sprintf("%s: %s", e.Code, e.Message))
+		}
+		return fmt.Errorf("operation %s failed: %s", operation.Name, strings.Join(messages, ", "))
+	}
+	return nil
+}
+
+// iamOperation is a long-running operation of the IAM API.
+type iamOperation struct {
+	Name  string    `json:"name"`
+	Done  bool      `json:"done"`
+	Error *apiError `json:"error,omitempty"`
+}
+
+// iamDo sends a request to the IAM API that starts an operation and waits for
+// the operation to complete.
+func iamDo(ctx context.Context, client restClient, method, url string, in interface{}) error {
+	operation := &iamOperation{}
+	if err := client.do(ctx, method, url, in, operation); err != nil {
+		return err
+	}
+	err := wait.PollImmediate(pollingInterval, operationTimeout, func() (bool, error) {
+		if !operation.Done {
+			if err := client.do(ctx, http.MethodGet, fmt.Sprintf("%s/%s", iamURL, operation.Name), nil, operation); err != nil {
+				return false, err
+			}
+		}
+		return operation.Done, nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to wait for operation %s: %w", operation.Name, err)
+	}
+	if operation.Error != nil {
+		return fmt.Errorf("operation %s failed: %s", operation.Name, operation.Error.Message)
+	}
+	return nil
+}
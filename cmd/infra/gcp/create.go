@@ -0,0 +1,289 @@
+package gcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+
+	"github.com/go-logr/logr"
+	"github.com/spf13/cobra"
+
+	"github.com/openshift/hypershift/cmd/log"
+)
+
+const (
+	defaultMachineCIDR = "10.0.0.0/16"
+	// defaultRegion is the region used when none is given.
+	defaultRegion = "us-central1"
+)
+
+// healthCheckSourceRanges are the ranges Google Cloud load balancer health checks
+// come from.
+var healthCheckSourceRanges = []string{"35.191.0.0/16", "130.211.0.0/22", "209.85.152.0/22", "209.85.204.0/22"}
+
+// CreateInfraOptions command line options for setting up infra in Google Cloud
+type CreateInfraOptions struct {
+	Project         string
+	Region          string
+	InfraID         string
+	MachineCIDR     string
+	CredentialsFile string
+	OutputFile      string
+}
+
+// CreateInfraOutput is the infrastructure created for a cluster in Google Cloud
+type CreateInfraOutput struct {
+	Project     string `json:"project"`
+	Region      string `json:"region"`
+	InfraID     string `json:"infraID"`
+	MachineCIDR string `json:"machineCIDR"`
+	NetworkName string `json:"networkName"`
+	SubnetName  string `json:"subnetName"`
+	RouterName  string `json:"routerName"`
+	NATName     string `json:"natName"`
+	// FirewallNames are the names of the firewall rules of the network
+	FirewallNames []string `json:"firewallNames"`
+}
+
+func NewCreateCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:          "gcp",
+		Short:        "Creates Google Cloud infrastructure resources for a cluster",
+		SilenceUsage: true,
+	}
+
+	opts := CreateInfraOptions{
+		Region:      defaultRegion,
+		MachineCIDR: defaultMachineCIDR,
+	}
+
+	cmd.Flags().StringVar(&opts.Project, "project", opts.Project, "ID of the Google Cloud project the resources are created in (required)")
+	cmd.Flags().StringVar(&opts.Region, "region", opts.Region, "Google Cloud region of the cluster")
+	cmd.Flags().StringVar(&opts.InfraID, "infra-id", opts.InfraID, "Cluster ID with which to name the Google Cloud resources (required)")
+	cmd.Flags().StringVar(&opts.MachineCIDR, "machine-cidr", opts.MachineCIDR, "IP range of the subnet of the nodes")
+	cmd.Flags().StringVar(&opts.CredentialsFile, "gcp-creds", opts.CredentialsFile, "Path to a service account key or credential configuration file. Defaults to the application default credentials")
+	cmd.Flags().StringVar(&opts.OutputFile, "output-file", opts.OutputFile, "Path to file that will contain output information from infra resources (optional)")
+
+	cmd.MarkFlagRequired("project")
+	cmd.MarkFlagRequired("infra-id")
+
+	l := log.Log
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		if err := opts.Run(cmd.Context(), l); err != nil {
+			l.Error(err, "Failed to create infrastructure")
+			return err
+		}
+		l.Info("Successfully created infrastructure")
+		return nil
+	}
+
+	return cmd
+}
+
+func (o *CreateInfraOptions) Run(ctx context.Context, l logr.Logger) error {
+	if _, _, err := net.ParseCIDR(o.MachineCIDR); err != nil {
+		return fmt.Errorf("invalid machine CIDR %q: %w", o.MachineCIDR, err)
+	}
+	client, err := newRESTClient(ctx, o.CredentialsFile)
+	if err != nil {
+		return err
+	}
+	result, err := o.CreateInfra(ctx, l, client)
+	if err != nil {
+		return err
+	}
+	return o.Output(result)
+}
+
+func (o *CreateInfraOptions) Output(result *CreateInfraOutput) error {
+	out := os.Stdout
+	if len(o.OutputFile) > 0 {
+		var err error
+		out, err = os.Create(o.OutputFile)
+		if err != nil {
+			return fmt.Errorf("cannot create output file: %w", err)
+		}
+		defer out.Close()
+	}
+	outputBytes, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to serialize result: %w", err)
+	}
+	if _, err := out.Write(outputBytes); err != nil {
+		return fmt.Errorf("failed to write result: %w", err)
+	}
+	return nil
+}
+
+// network is a VPC network of the compute API.
+type network struct {
+	Name                  string `json:"name"`
+	Description           string `json:"description,omitempty"`
+	AutoCreateSubnetworks bool   `json:"autoCreateSubnetworks"`
+	RoutingConfig         struct {
+		RoutingMode string `json:"routingMode,omitempty"`
+	} `json:"routingConfig"`
+}
+
+// subnetwork is a subnet of a VPC network of the compute API.
+type subnetwork struct {
+	Name                  string `json:"name"`
+	Description           string `json:"description,omitempty"`
+	Network               string `json:"network"`
+	IPCIDRRange           string `json:"ipCidrRange"`
+	PrivateIPGoogleAccess bool   `json:"privateIpGoogleAccess"`
+}
+
+// router is a Cloud Router of the compute API.
+type router struct {
+	Name        string      `json:"name"`
+	Description string      `json:"description,omitempty"`
+	Network     string      `json:"network"`
+	NATs        []routerNAT `json:"nats,omitempty"`
+}
+
+// routerNAT is the configuration of a Cloud NAT gateway of a router.
+type routerNAT struct {
+	Name                          string `json:"name"`
+	NATIPAllocateOption           string `json:"natIpAllocateOption"`
+	SourceSubnetworkIPRangesToNAT string `json:"sourceSubnetworkIpRangesToNat"`
+}
+
+// firewall is a firewall rule of the compute API.
+type firewall struct {
+	Name         string            `json:"name"`
+	Description  string            `json:"description,omitempty"`
+	Network      string            `json:"network"`
+	Direction    string            `json:"direction"`
+	SourceRanges []string          `json:"sourceRanges"`
+	Allowed      []firewallAllowed `json:"allowed"`
+}
+
+// firewallAllowed is a protocol and the ports of it a firewall rule allows.
+type firewallAllowed struct {
+	IPProtocol string   `json:"IPProtocol"`
+	Ports      []string `json:"ports,omitempty"`
+}
+
+func (o *CreateInfraOptions) networkName() string {
+	return o.InfraID + "-network"
+}
+
+func (o *CreateInfraOptions) subnetName() string {
+	return o.InfraID + "-subnet"
+}
+
+func (o *CreateInfraOptions) routerName() string {
+	return o.InfraID + "-router"
+}
+
+func (o *CreateInfraOptions) natName() string {
+	return o.InfraID + "-nat"
+}
+
+func (o *CreateInfraOptions) description() string {
+	return fmt.Sprintf("Created by HyperShift for cluster %s", o.InfraID)
+}
+
+// CreateInfra creates the network, subnet, Cloud NAT and firewall rules of the
+// cluster, or reuses them if they already exist.
+func (o *CreateInfraOptions) CreateInfra(ctx context.Context, l logr.Logger, client restClient) (*CreateInfraOutput, error) {
+	result := &CreateInfraOutput{
+		Project:     o.Project,
+		Region:      o.Region,
+		InfraID:     o.InfraID,
+		MachineCIDR: o.MachineCIDR,
+		NetworkName: o.networkName(),
+		SubnetName:  o.subnetName(),
+		RouterName:  o.routerName(),
+		NATName:     o.natName(),
+	}
+	globalURL := fmt.Sprintf("%s/projects/%s/global", computeURL, o.Project)
+	regionURL := fmt.Sprintf("%s/projects/%s/regions/%s", computeURL, o.Project, o.Region)
+	networkURL := fmt.Sprintf("%s/networks/%s", globalURL, o.networkName())
+
+	n := &network{Name: o.networkName(), Description: o.description()}
+	n.RoutingConfig.RoutingMode = "REGIONAL"
+	if err := ensureResource(ctx, l, client, globalURL+"/networks", "network", n.Name, n); err != nil {
+		return nil, err
+	}
+
+	subnet := &subnetwork{
+		Name:                  o.subnetName(),
+		Description:           o.description(),
+		Network:               networkURL,
+		IPCIDRRange:           o.MachineCIDR,
+		PrivateIPGoogleAccess: true,
+	}
+	if err := ensureResource(ctx, l, client, regionURL+"/subnetworks", "subnet", subnet.Name, subnet); err != nil {
+		return nil, err
+	}
+
+	// The nodes have no external IPs, they reach the internet through Cloud NAT
+	r := &router{
+		Name:        o.routerName(),
+		Description: o.description(),
+		Network:     networkURL,
+		NATs: []routerNAT{{
+			Name:                          o.natName(),
+			NATIPAllocateOption:           "AUTO_ONLY",
+			SourceSubnetworkIPRangesToNAT: "ALL_SUBNETWORKS_ALL_IP_RANGES",
+		}},
+	}
+	if err := ensureResource(ctx, l, client, regionURL+"/routers", "router", r.Name, r); err != nil {
+		return nil, err
+	}
+
+	for _, rule := range o.firewallRules(networkURL) {
+		if err := ensureResource(ctx, l, client, globalURL+"/firewalls", "firewall rule", rule.Name, rule); err != nil {
+			return nil, err
+		}
+		result.FirewallNames = append(result.FirewallNames, rule.Name)
+	}
+
+	return result, nil
+}
+
+// firewallRules returns the firewall rules of the network: all traffic between
+// the nodes, and the traffic of the load balancer health checks to the nodes.
+func (o *CreateInfraOptions) firewallRules(networkURL string) []*firewall {
+	return []*firewall{
+		{
+			Name:         o.InfraID + "-internal",
+			Description:  o.description(),
+			Network:      networkURL,
+			Direction:    "INGRESS",
+			SourceRanges: []string{o.MachineCIDR},
+			Allowed:      []firewallAllowed{{IPProtocol: "all"}},
+		},
+		{
+			Name:         o.InfraID + "-health-checks",
+			Description:  o.description(),
+			Network:      networkURL,
+			Direction:    "INGRESS",
+			SourceRanges: healthCheckSourceRanges,
+			Allowed:      []firewallAllowed{{IPProtocol: "tcp"}},
+		},
+	}
+}
+
+// ensureResource creates the compute resource with the given name in the
+// collection, unless it already exists.
+func ensureResource(ctx context.Context, l logr.Logger, client restClient, collectionURL, kind, name string, resource interface{}) error {
+	err := client.do(ctx, http.MethodGet, collectionURL+"/"+name, nil, nil)
+	if err == nil {
+		l.Info("Found existing "+kind, "name", name)
+		return nil
+	}
+	if !isNotFound(err) {
+		return fmt.Errorf("failed to get %s %s: %w", kind, name, err)
+	}
+	if err := computeDo(ctx, client, http.MethodPost, collectionURL, resource); err != nil {
+		return fmt.Errorf("failed to create %s %s: %w", kind, name, err)
+	}
+	l.Info("Created "+kind, "name", name)
+	return nil
+}
@@ -0,0 +1,257 @@
+package gcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+
+	"github.com/go-logr/logr"
+	"github.com/spf13/cobra"
+
+	"github.com/openshift/hypershift/cmd/log"
+)
+
+type CreateIAMOptions struct {
+	Project               string
+	InfraID               string
+	CredentialsFile       string
+	IssuerURL             string
+	ControlPlaneNamespace string
+	OutputFile            string
+}
+
+type CreateIAMOutput struct {
+	Project       string `json:"project"`
+	ProjectNumber string `json:"projectNumber,omitempty"`
+	InfraID       string `json:"infraID"`
+	IssuerURL     string `json:"issuerURL,omitempty"`
+	// WorkloadIdentityProvider is the resource name of the provider the service
+	// account tokens of the issuer are exchanged with
+	WorkloadIdentityProvider string `json:"workloadIdentityProvider,omitempty"`
+	// ServiceAccounts are the emails of the service accounts by component name
+	ServiceAccounts map[string]string `json:"serviceAccounts"`
+}
+
+func NewCreateIAMCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:          "gcp",
+		Short:        "Creates Google Cloud service accounts for the cluster components",
+		SilenceUsage: true,
+	}
+
+	opts := CreateIAMOptions{}
+
+	cmd.Flags().StringVar(&opts.Project, "project", opts.Project, "ID of the Google Cloud project the service accounts are created in (required)")
+	cmd.Flags().StringVar(&opts.InfraID, "infra-id", opts.InfraID, "Cluster ID with which to name the service accounts (required)")
+	cmd.Flags().StringVar(&opts.CredentialsFile, "gcp-creds", opts.CredentialsFile, "Path to a service account key or credential configuration file. Defaults to the application default credentials")
+	cmd.Flags().StringVar(&opts.IssuerURL, "oidc-issuer-url", opts.IssuerURL, "The OIDC issuer URL of the cluster. If set, a workload identity pool is created for it so that the service accounts of the components can use the Google service accounts")
+	cmd.Flags().StringVar(&opts.ControlPlaneNamespace, "control-plane-namespace", opts.ControlPlaneNamespace, "Namespace of the hosted control plane, e.g. clusters-example. Required with --oidc-issuer-url to bind the service accounts of the control plane components")
+	cmd.Flags().StringVar(&opts.OutputFile, "output-file", opts.OutputFile, "Path to a file to write the emails of the service accounts to as JSON. Defaults to stdout")
+
+	cmd.MarkFlagRequired("project")
+	cmd.MarkFlagRequired("infra-id")
+
+	l := log.Log
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		result, err := opts.Run(cmd.Context(), l)
+		if err != nil {
+			l.Error(err, "Failed to create IAM")
+			return err
+		}
+		if err := opts.writeOutput(l, result); err != nil {
+			return err
+		}
+		l.Info("Successfully created IAM")
+		return nil
+	}
+
+	return cmd
+}
+
+func (o *CreateIAMOptions) Validate() error {
+	if err := validateAccountIDs(o.InfraID); err != nil {
+		return err
+	}
+	if len(o.IssuerURL) > 0 && len(o.ControlPlaneNamespace) == 0 {
+		return fmt.Errorf("--control-plane-namespace is required with --oidc-issuer-url")
+	}
+	return nil
+}
+
+func (o *CreateIAMOptions) Run(ctx context.Context, l logr.Logger) (*CreateIAMOutput, error) {
+	if err := o.Validate(); err != nil {
+		return nil, err
+	}
+	client, err := newRESTClient(ctx, o.CredentialsFile)
+	if err != nil {
+		return nil, err
+	}
+	return o.CreateIAM(ctx, l, client)
+}
+
+// CreateIAM creates a service account for each component, grants it the roles
+// of the component on the project, and, if an issuer URL is given, allows the
+// Kubernetes service accounts of the component to use it through workload
+// identity federation.
+func (o *CreateIAMOptions) CreateIAM(ctx context.Context, l logr.Logger, client restClient) (*CreateIAMOutput, error) {
+	result := &CreateIAMOutput{
+		Project:         o.Project,
+		InfraID:         o.InfraID,
+		IssuerURL:       o.IssuerURL,
+		ServiceAccounts: map[string]string{},
+	}
+
+	for _, component := range componentServiceAccounts {
+		email, err := o.ensureServiceAccount(ctx, l, client, component)
+		if err != nil {
+			return nil, err
+		}
+		result.ServiceAccounts[component.name] = email
+	}
+
+	projectURL := fmt.Sprintf("%s/projects/%s", resourceManagerURL, o.Project)
+	err := updateIAMPolicy(ctx, client, projectURL, func(policy *iamPolicy) bool {
+		changed := false
+		for _, component := range componentServiceAccounts {
+			for _, role := range component.roles {
+				if policy.addBinding(role, "serviceAccount:"+result.ServiceAccounts[component.name]) {
+					changed = true
+				}
+			}
+		}
+		return changed
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to grant the roles of the service accounts on project %s: %w", o.Project, err)
+	}
+	l.Info("Granted roles to service accounts", "project", o.Project)
+
+	if len(o.IssuerURL) == 0 {
+		return result, nil
+	}
+
+	project := struct {
+		ProjectNumber string `json:"projectNumber"`
+	}{}
+	if err := client.do(ctx, http.MethodGet, projectURL, nil, &project); err != nil {
+		return nil, fmt.Errorf("failed to get project %s: %w", o.Project, err)
+	}
+	result.ProjectNumber = project.ProjectNumber
+
+	provider, err := o.ensureWorkloadIdentityProvider(ctx, l, client, project.ProjectNumber)
+	if err != nil {
+		return nil, err
+	}
+	result.WorkloadIdentityProvider = provider
+
+	poolPrincipal := fmt.Sprintf("principal://iam.googleapis.com/projects/%s/locations/global/workloadIdentityPools/%s", project.ProjectNumber, o.InfraID)
+	for _, component := range componentServiceAccounts {
+		if len(component.serviceAccounts) == 0 {
+			continue
+		}
+		email := result.ServiceAccounts[component.name]
+		err := updateIAMPolicy(ctx, client, fmt.Sprintf("%s/projects/%s/serviceAccounts/%s", iamURL, o.Project, email), func(policy *iamPolicy) bool {
+			changed := false
+			for _, sa := range component.serviceAccounts {
+				if policy.addBinding(workloadIdentityUserRole, poolPrincipal+"/subject/"+sa.subject(o.ControlPlaneNamespace)) {
+					changed = true
+				}
+			}
+			return changed
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to allow workload identity for service account %s: %w", email, err)
+		}
+		l.Info("Allowed workload identity for service account", "email", email)
+	}
+
+	return result, nil
+}
+
+// ensureServiceAccount creates the service account of the component unless it
+// already exists, and returns its email.
+func (o *CreateIAMOptions) ensureServiceAccount(ctx context.Context, l logr.Logger, client restClient, component componentServiceAccount) (string, error) {
+	id := accountID(o.InfraID, component)
+	email := serviceAccountEmail(o.Project, id)
+	request := map[string]interface{}{
+		"accountId": id,
+		"serviceAccount": map[string]string{
+			"displayName": fmt.Sprintf("%s %s", o.InfraID, component.name),
+			"description": fmt.Sprintf("Created by HyperShift for the %s of cluster %s", component.name, o.InfraID),
+		},
+	}
+	err := client.do(ctx, http.MethodPost, fmt.Sprintf("%s/projects/%s/serviceAccounts", iamURL, o.Project), request, nil)
+	if isAlreadyExists(err) {
+		l.Info("Found existing service account", "email", email)
+		return email, nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to create service account %s: %w", id, err)
+	}
+	l.Info("Created service account", "email", email)
+	return email, nil
+}
+
+// ensureWorkloadIdentityProvider creates the workload identity pool of the
+// cluster and its OIDC provider for the issuer unless they already exist, and
+// returns the resource name of the provider, which is the audience of the
+// service account tokens.
+func (o *CreateIAMOptions) ensureWorkloadIdentityProvider(ctx context.Context, l logr.Logger, client restClient, projectNumber string) (string, error) {
+	poolsURL := fmt.Sprintf("%s/projects/%s/locations/global/workloadIdentityPools", iamURL, o.Project)
+	pool := map[string]string{
+		"displayName": o.InfraID,
+		"description": fmt.Sprintf("Created by HyperShift for cluster %s", o.InfraID),
+	}
+	err := iamDo(ctx, client, http.MethodPost, fmt.Sprintf("%s?workloadIdentityPoolId=%s", poolsURL, o.InfraID), pool)
+	switch {
+	case isAlreadyExists(err):
+		l.Info("Found existing workload identity pool", "name", o.InfraID)
+	case err != nil:
+		return "", fmt.Errorf("failed to create workload identity pool %s: %w", o.InfraID, err)
+	default:
+		l.Info("Created workload identity pool", "name", o.InfraID)
+	}
+
+	provider := map[string]interface{}{
+		"displayName": workloadIdentityProviderID,
+		"oidc": map[string]string{
+			"issuerUri": o.IssuerURL,
+		},
+		"attributeMapping": map[string]string{
+			"google.subject": "assertion.sub",
+		},
+	}
+	err = iamDo(ctx, client, http.MethodPost, fmt.Sprintf("%s/%s/providers?workloadIdentityPoolProviderId=%s", poolsURL, o.InfraID, workloadIdentityProviderID), provider)
+	switch {
+	case isAlreadyExists(err):
+		l.Info("Found existing workload identity provider", "name", workloadIdentityProviderID)
+	case err != nil:
+		return "", fmt.Errorf("failed to create workload identity provider for %s: %w", o.IssuerURL, err)
+	default:
+		l.Info("Created workload identity provider", "name", workloadIdentityProviderID, "issuer", o.IssuerURL)
+	}
+
+	return fmt.Sprintf("projects/%s/locations/global/workloadIdentityPools/%s/providers/%s", projectNumber, o.InfraID, workloadIdentityProviderID), nil
+}
+
+// writeOutput writes the IAM output as JSON to the output file, or to stdout if
+// none is given.
+func (o *CreateIAMOptions) writeOutput(l logr.Logger, result *CreateIAMOutput) error {
+	resultSerialized, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to serialize result: %w", err)
+	}
+	if o.OutputFile == "" {
+		_, err := os.Stdout.Write(resultSerialized)
+		return err
+	}
+	if err := ioutil.WriteFile(o.OutputFile, resultSerialized, 0644); err != nil {
+		// Be nice and print the data so it doesn't get lost
+		l.Error(err, "Writing output file failed", "outputfile", o.OutputFile, "data", string(resultSerialized))
+		return fmt.Errorf("failed to write result to --output-file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,221 @@
+package gcp
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/gomega"
+)
+
+// fakeRESTClient is an in-memory restClient. Resources are created by POSTing
+// them to their collection, and IAM policies are stored by resource URL.
+type fakeRESTClient struct {
+	resources map[string]json.RawMessage
+	policies  map[string]*iamPolicy
+	// created are the URLs of the created resources in order
+	created []string
+}
+
+func newFakeRESTClient() *fakeRESTClient {
+	return &fakeRESTClient{
+		resources: map[string]json.RawMessage{},
+		policies:  map[string]*iamPolicy{},
+	}
+}
+
+func (c *fakeRESTClient) do(ctx context.Context, method, rawURL string, in, out interface{}) error {
+	var body json.RawMessage
+	if in != nil {
+		var err error
+		if body, err = json.Marshal(in); err != nil {
+			return err
+		}
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+	resourceURL := u.Scheme + "://" + u.Host + u.Path
+	var response interface{} = map[string]interface{}{"status": operationStatusDone, "done": true}
+
+	switch {
+	case method == http.MethodPost && strings.HasSuffix(resourceURL, ":getIamPolicy"):
+		policy := c.policies[strings.TrimSuffix(resourceURL, ":getIamPolicy")]
+		if policy == nil {
+			policy = &iamPolicy{}
+		}
+		response = policy
+	case method == http.MethodPost && strings.HasSuffix(resourceURL, ":setIamPolicy"):
+		request := struct {
+			Policy *iamPolicy `json:"policy"`
+		}{}
+		if err := json.Unmarshal(body, &request); err != nil {
+			return err
+		}
+		c.policies[strings.TrimSuffix(resourceURL, ":setIamPolicy")] = request.Policy
+	case method == http.MethodPost:
+		name := fakeResourceName(u.Query(), body)
+		key := resourceURL + "/" + name
+		if strings.HasSuffix(resourceURL, "/serviceAccounts") {
+			project := strings.TrimPrefix(strings.TrimSuffix(resourceURL, "/serviceAccounts"), iamURL+"/projects/")
+			key = resourceURL + "/" + serviceAccountEmail(project, name)
+		}
+		if _, exists := c.resources[key]; exists {
+			return &apiError{Code: http.StatusConflict, Message: "already exists"}
+		}
+		c.resources[key] = body
+		c.created = append(c.created, key)
+	case method == http.MethodGet && strings.HasSuffix(resourceURL, "/firewalls"):
+		list := struct {
+			Items []json.RawMessage `json:"items"`
+		}{}
+		for key, resource := range c.resources {
+			if !strings.HasPrefix(key, resourceURL+"/") {
+				continue
+			}
+			rule := &firewall{}
+			if err := json.Unmarshal(resource, rule); err != nil {
+				return err
+			}
+			if u.Query().Get("filter") == `network="`+rule.Network+`"` {
+				list.Items = append(list.Items, resource)
+			}
+		}
+		response = list
+	case method == http.MethodGet && strings.HasPrefix(resourceURL, resourceManagerURL):
+		response = map[string]string{"projectNumber": "123456789"}
+	case method == http.MethodGet:
+		resource, exists := c.resources[resourceURL]
+		if !exists {
+			return &apiError{Code: http.StatusNotFound, Message: "not found"}
+		}
+		response = resource
+	case method == http.MethodDelete:
+		if _, exists := c.resources[resourceURL]; !exists {
+			return &apiError{Code: http.StatusNotFound, Message: "not found"}
+		}
+		// Deleting a resource deletes its child resources
+		for key := range c.resources {
+			if key == resourceURL || strings.HasPrefix(key, resourceURL+"/") {
+				delete(c.resources, key)
+			}
+		}
+	}
+
+	if out == nil {
+		return nil
+	}
+	raw, err := json.Marshal(response)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, out)
+}
+
+// fakeResourceName returns the name of a resource from the ID query parameter
+// of the IAM API or the name or account ID in the body.
+func fakeResourceName(query url.Values, body json.RawMessage) string {
+	for _, param := range []string{"workloadIdentityPoolId", "workloadIdentityPoolProviderId"} {
+		if id := query.Get(param); len(id) > 0 {
+			return id
+		}
+	}
+	resource := struct {
+		Name      string `json:"name"`
+		AccountID string `json:"accountId"`
+	}{}
+	_ = json.Unmarshal(body, &resource)
+	if len(resource.AccountID) > 0 {
+		return resource.AccountID
+	}
+	return resource.Name
+}
+
+func (c *fakeRESTClient) resourceNames() []string {
+	var names []string
+	for key := range c.resources {
+		names = append(names, key)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestCreateInfra(t *testing.T) {
+	g := NewGomegaWithT(t)
+	ctx := context.Background()
+	client := newFakeRESTClient()
+	opts := &CreateInfraOptions{
+		Project:     "project",
+		Region:      "us-east1",
+		InfraID:     "example",
+		MachineCIDR: "10.1.0.0/16",
+	}
+
+	result, err := opts.CreateInfra(ctx, logr.Discard(), client)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(result).To(Equal(&CreateInfraOutput{
+		Project:       "project",
+		Region:        "us-east1",
+		InfraID:       "example",
+		MachineCIDR:   "10.1.0.0/16",
+		NetworkName:   "example-network",
+		SubnetName:    "example-subnet",
+		RouterName:    "example-router",
+		NATName:       "example-nat",
+		FirewallNames: []string{"example-internal", "example-health-checks"},
+	}))
+
+	globalURL := computeURL + "/projects/project/global"
+	regionURL := computeURL + "/projects/project/regions/us-east1"
+	g.Expect(client.created).To(Equal([]string{
+		globalURL + "/networks/example-network",
+		regionURL + "/subnetworks/example-subnet",
+		regionURL + "/routers/example-router",
+		globalURL + "/firewalls/example-internal",
+		globalURL + "/firewalls/example-health-checks",
+	}))
+
+	subnet := &subnetwork{}
+	g.Expect(json.Unmarshal(client.resources[regionURL+"/subnetworks/example-subnet"], subnet)).To(Succeed())
+	g.Expect(subnet.IPCIDRRange).To(Equal("10.1.0.0/16"))
+	g.Expect(subnet.Network).To(Equal(globalURL + "/networks/example-network"))
+
+	internal := &firewall{}
+	g.Expect(json.Unmarshal(client.resources[globalURL+"/firewalls/example-internal"], internal)).To(Succeed())
+	g.Expect(internal.SourceRanges).To(Equal([]string{"10.1.0.0/16"}))
+
+	// Creating the infrastructure again reuses the existing resources
+	_, err = opts.CreateInfra(ctx, logr.Discard(), client)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(client.created).To(HaveLen(5))
+}
+
+func TestDestroyInfra(t *testing.T) {
+	g := NewGomegaWithT(t)
+	ctx := context.Background()
+	client := newFakeRESTClient()
+	_, err := (&CreateInfraOptions{Project: "project", Region: "us-east1", InfraID: "example", MachineCIDR: defaultMachineCIDR}).CreateInfra(ctx, logr.Discard(), client)
+	g.Expect(err).ToNot(HaveOccurred())
+	_, err = (&CreateInfraOptions{Project: "project", Region: "us-east1", InfraID: "other", MachineCIDR: defaultMachineCIDR}).CreateInfra(ctx, logr.Discard(), client)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	// A firewall rule the cloud controller created for a load balancer
+	lbRule := &firewall{Name: "k8s-fw-a1b2c3", Network: computeURL + "/projects/project/global/networks/example-network"}
+	g.Expect(client.do(ctx, http.MethodPost, computeURL+"/projects/project/global/firewalls", lbRule, nil)).To(Succeed())
+
+	opts := &DestroyInfraOptions{Project: "project", Region: "us-east1", InfraID: "example"}
+	g.Expect(opts.DestroyInfra(ctx, logr.Discard(), client)).To(Succeed())
+	for _, name := range client.resourceNames() {
+		g.Expect(name).ToNot(ContainSubstring("example"))
+	}
+	g.Expect(client.resourceNames()).To(HaveLen(5))
+
+	// Destroying the infrastructure again succeeds
+	g.Expect(opts.DestroyInfra(ctx, logr.Discard(), client)).To(Succeed())
+}
@@ -0,0 +1,131 @@
+package gcp
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/go-logr/logr"
+	"github.com/spf13/cobra"
+
+	"github.com/openshift/hypershift/cmd/log"
+)
+
+// DestroyInfraOptions command line options to destroy infra created in Google Cloud
+type DestroyInfraOptions struct {
+	Project         string
+	Region          string
+	InfraID         string
+	CredentialsFile string
+}
+
+func NewDestroyCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:          "gcp",
+		Short:        "Destroys Google Cloud infrastructure resources for a cluster",
+		SilenceUsage: true,
+	}
+
+	opts := DestroyInfraOptions{
+		Region: defaultRegion,
+	}
+
+	cmd.Flags().StringVar(&opts.Project, "project", opts.Project, "ID of the Google Cloud project of the resources (required)")
+	cmd.Flags().StringVar(&opts.Region, "region", opts.Region, "Google Cloud region of the cluster")
+	cmd.Flags().StringVar(&opts.InfraID, "infra-id", opts.InfraID, "Cluster ID the Google Cloud resources are named with (required)")
+	cmd.Flags().StringVar(&opts.CredentialsFile, "gcp-creds", opts.CredentialsFile, "Path to a service account key or credential configuration file. Defaults to the application default credentials")
+
+	cmd.MarkFlagRequired("project")
+	cmd.MarkFlagRequired("infra-id")
+
+	l := log.Log
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		if err := opts.Run(cmd.Context(), l); err != nil {
+			l.Error(err, "Failed to destroy infrastructure")
+			return err
+		}
+		l.Info("Successfully destroyed infrastructure")
+		return nil
+	}
+
+	return cmd
+}
+
+func (o *DestroyInfraOptions) Run(ctx context.Context, l logr.Logger) error {
+	client, err := newRESTClient(ctx, o.CredentialsFile)
+	if err != nil {
+		return err
+	}
+	return o.DestroyInfra(ctx, l, client)
+}
+
+// DestroyInfra deletes the resources created by CreateInfra in the reverse
+// order, skipping the ones that don't exist.
+func (o *DestroyInfraOptions) DestroyInfra(ctx context.Context, l logr.Logger, client restClient) error {
+	names := &CreateInfraOptions{InfraID: o.InfraID}
+	globalURL := fmt.Sprintf("%s/projects/%s/global", computeURL, o.Project)
+	regionURL := fmt.Sprintf("%s/projects/%s/regions/%s", computeURL, o.Project, o.Region)
+
+	// The network is dedicated to the cluster, so all of its firewall rules are
+	// deleted, including the ones the cloud controller created for load balancers
+	firewallNames, err := networkFirewalls(ctx, client, globalURL, names.networkName())
+	if err != nil {
+		return err
+	}
+	for _, name := range firewallNames {
+		if err := deleteResource(ctx, l, client, globalURL+"/firewalls", "firewall rule", name); err != nil {
+			return err
+		}
+	}
+	if err := deleteResource(ctx, l, client, regionURL+"/routers", "router", names.routerName()); err != nil {
+		return err
+	}
+	if err := deleteResource(ctx, l, client, regionURL+"/subnetworks", "subnet", names.subnetName()); err != nil {
+		return err
+	}
+	return deleteResource(ctx, l, client, globalURL+"/networks", "network", names.networkName())
+}
+
+// deleteResource deletes the compute resource with the given name from the
+// collection if it exists.
+func deleteResource(ctx context.Context, l logr.Logger, client restClient, collectionURL, kind, name string) error {
+	err := computeDo(ctx, client, http.MethodDelete, collectionURL+"/"+name, nil)
+	if isNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to delete %s %s: %w", kind, name, err)
+	}
+	l.Info("Deleted "+kind, "name", name)
+	return nil
+}
+
+// networkFirewalls returns the names of the firewall rules of the network.
+func networkFirewalls(ctx context.Context, client restClient, globalURL, networkName string) ([]string, error) {
+	filter := url.QueryEscape(fmt.Sprintf(`network="%s/networks/%s"`, globalURL, networkName))
+	var names []string
+	pageToken := ""
+	for {
+		list := struct {
+			Items []struct {
+				Name string `json:"name"`
+			} `json:"items"`
+			NextPageToken string `json:"nextPageToken"`
+		}{}
+		listURL := fmt.Sprintf("%s/firewalls?filter=%s", globalURL, filter)
+		if len(pageToken) > 0 {
+			listURL += "&pageToken=" + url.QueryEscape(pageToken)
+		}
+		if err := client.do(ctx, http.MethodGet, listURL, nil, &list); err != nil {
+			return nil, fmt.Errorf("failed to list firewall rules of network %s: %w", networkName, err)
+		}
+		for _, item := range list.Items {
+			names = append(names, item.Name)
+		}
+		if len(list.NextPageToken) == 0 {
+			return names, nil
+		}
+		pageToken = list.NextPageToken
+	}
+}
@@ -0,0 +1,96 @@
+package gcp
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/go-logr/logr"
+	"github.com/spf13/cobra"
+
+	"github.com/openshift/hypershift/cmd/log"
+)
+
+type DestroyIAMOptions struct {
+	Project         string
+	InfraID         string
+	CredentialsFile string
+}
+
+func NewDestroyIAMCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:          "gcp",
+		Short:        "Destroys the Google Cloud service accounts of the cluster components",
+		SilenceUsage: true,
+	}
+
+	opts := DestroyIAMOptions{}
+
+	cmd.Flags().StringVar(&opts.Project, "project", opts.Project, "ID of the Google Cloud project of the service accounts (required)")
+	cmd.Flags().StringVar(&opts.InfraID, "infra-id", opts.InfraID, "Cluster ID the service accounts are named with (required)")
+	cmd.Flags().StringVar(&opts.CredentialsFile, "gcp-creds", opts.CredentialsFile, "Path to a service account key or credential configuration file. Defaults to the application default credentials")
+
+	cmd.MarkFlagRequired("project")
+	cmd.MarkFlagRequired("infra-id")
+
+	l := log.Log
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		if err := opts.Run(cmd.Context(), l); err != nil {
+			l.Error(err, "Failed to destroy IAM")
+			return err
+		}
+		l.Info("Successfully destroyed IAM")
+		return nil
+	}
+
+	return cmd
+}
+
+func (o *DestroyIAMOptions) Run(ctx context.Context, l logr.Logger) error {
+	client, err := newRESTClient(ctx, o.CredentialsFile)
+	if err != nil {
+		return err
+	}
+	return o.DestroyIAM(ctx, l, client)
+}
+
+// DestroyIAM removes the project roles of the service accounts created by
+// CreateIAM, and deletes the service accounts and the workload identity pool of
+// the cluster, skipping the ones that don't exist.
+func (o *DestroyIAMOptions) DestroyIAM(ctx context.Context, l logr.Logger, client restClient) error {
+	members := map[string]bool{}
+	for _, component := range componentServiceAccounts {
+		members["serviceAccount:"+serviceAccountEmail(o.Project, accountID(o.InfraID, component))] = true
+	}
+	// Bindings of deleted service accounts are not removed from the project
+	// policy, so they are removed before the service accounts are deleted
+	err := updateIAMPolicy(ctx, client, fmt.Sprintf("%s/projects/%s", resourceManagerURL, o.Project), func(policy *iamPolicy) bool {
+		return policy.removeMembers(members)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to remove the roles of the service accounts on project %s: %w", o.Project, err)
+	}
+
+	for _, component := range componentServiceAccounts {
+		email := serviceAccountEmail(o.Project, accountID(o.InfraID, component))
+		err := client.do(ctx, http.MethodDelete, fmt.Sprintf("%s/projects/%s/serviceAccounts/%s", iamURL, o.Project, email), nil, nil)
+		if isNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to delete service account %s: %w", email, err)
+		}
+		l.Info("Deleted service account", "email", email)
+	}
+
+	// Deleting the pool deletes its providers
+	err = iamDo(ctx, client, http.MethodDelete, fmt.Sprintf("%s/projects/%s/locations/global/workloadIdentityPools/%s", iamURL, o.Project, o.InfraID), nil)
+	if isNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to delete workload identity pool %s: %w", o.InfraID, err)
+	}
+	l.Info("Deleted workload identity pool", "name", o.InfraID)
+	return nil
+}
@@ -0,0 +1,220 @@
+package gcp
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+const (
+	// workloadIdentityProviderID is the ID of the OIDC provider of the
+	// workload identity pool of a cluster.
+	workloadIdentityProviderID = "oidc"
+
+	workloadIdentityUserRole = "roles/iam.workloadIdentityUser"
+
+	// maxAccountIDLength is the maximum length of the ID of a service account.
+	maxAccountIDLength = 30
+)
+
+// serviceAccount is a Kubernetes service account of a component that is allowed
+// to use its Google service account through workload identity federation. An
+// empty namespace is the namespace of the hosted control plane.
+type serviceAccount struct {
+	namespace string
+	name      string
+}
+
+func (sa serviceAccount) subject(controlPlaneNamespace string) string {
+	namespace := sa.namespace
+	if len(namespace) == 0 {
+		namespace = controlPlaneNamespace
+	}
+	return fmt.Sprintf("system:serviceaccount:%s:%s", namespace, sa.name)
+}
+
+// componentServiceAccount is a Google service account of a cluster component with
+// the roles it needs and the Kubernetes service accounts it is used from.
+type componentServiceAccount struct {
+	name string
+	// accountIDSuffix is appended to the infra ID to form the ID of the service
+	// account, which is limited to 30 characters.
+	accountIDSuffix string
+	roles           []string
+	serviceAccounts []serviceAccount
+}
+
+// componentServiceAccounts are the service accounts create iam gcp creates. They
+// are only granted predefined roles that are limited to the resources the
+// component manages.
+var componentServiceAccounts = []componentServiceAccount{
+	{
+		// The cloud provider manages the load balancers of services and their
+		// firewall rules
+		name:            "cloud-controller",
+		accountIDSuffix: "cc",
+		roles:           []string{"roles/compute.loadBalancerAdmin", "roles/compute.securityAdmin", "roles/compute.viewer"},
+		serviceAccounts: []serviceAccount{
+			{name: "cloud-controller-manager"},
+		},
+	},
+	{
+		// The nodes run as the node service account, which requires the
+		// service account user role to attach it to the instances
+		name:            "nodepool-management",
+		accountIDSuffix: "nodepool",
+		roles:           []string{"roles/compute.instanceAdmin.v1", "roles/iam.serviceAccountUser"},
+		serviceAccounts: []serviceAccount{
+			{name: "capi-provider"},
+		},
+	},
+	{
+		name:            "control-plane-operator",
+		accountIDSuffix: "cpo",
+		roles:           []string{"roles/compute.networkViewer"},
+		serviceAccounts: []serviceAccount{
+			{name: "control-plane-operator"},
+		},
+	},
+	{
+		name:            "ingress",
+		accountIDSuffix: "ingress",
+		roles:           []string{"roles/dns.admin"},
+		serviceAccounts: []serviceAccount{
+			{namespace: "openshift-ingress-operator", name: "ingress-operator"},
+		},
+	},
+	{
+		name:            "disk-csi",
+		accountIDSuffix: "csi",
+		roles:           []string{"roles/compute.storageAdmin", "roles/compute.instanceAdmin.v1", "roles/iam.serviceAccountUser"},
+		serviceAccounts: []serviceAccount{
+			{namespace: "openshift-cluster-csi-drivers", name: "gcp-pd-csi-driver-operator"},
+			{namespace: "openshift-cluster-csi-drivers", name: "gcp-pd-csi-driver-controller-sa"},
+		},
+	},
+	{
+		name:            "image-registry",
+		accountIDSuffix: "registry",
+		roles:           []string{"roles/storage.admin"},
+		serviceAccounts: []serviceAccount{
+			{namespace: "openshift-image-registry", name: "cluster-image-registry-operator"},
+			{namespace: "openshift-image-registry", name: "registry"},
+		},
+	},
+	{
+		// The nodes only write logs and metrics
+		name:            "node",
+		accountIDSuffix: "node",
+		roles:           []string{"roles/logging.logWriter", "roles/monitoring.metricWriter"},
+	},
+}
+
+func accountID(infraID string, component componentServiceAccount) string {
+	return infraID + "-" + component.accountIDSuffix
+}
+
+func serviceAccountEmail(project, accountID string) string {
+	return fmt.Sprintf("%s@%s.iam.gserviceaccount.com", accountID, project)
+}
+
+// validateAccountIDs returns an error if the infra ID is too long for the IDs of
+// the service accounts.
+func validateAccountIDs(infraID string) error {
+	for _, component := range componentServiceAccounts {
+		if id := accountID(infraID, component); len(id) > maxAccountIDLength {
+			return fmt.Errorf("the infra ID %s is too long, the ID %s of the %s service account exceeds %d characters", infraID, id, component.name, maxAccountIDLength)
+		}
+	}
+	return nil
+}
+
+// iamPolicy is the IAM policy of a resource.
+type iamPolicy struct {
+	Version  int          `json:"version,omitempty"`
+	Etag     string       `json:"etag,omitempty"`
+	Bindings []iamBinding `json:"bindings,omitempty"`
+}
+
+// iamBinding binds a role to members.
+type iamBinding struct {
+	Role    string   `json:"role"`
+	Members []string `json:"members"`
+}
+
+// addBinding adds the member to the binding of the role, and returns true if the
+// policy changed.
+func (p *iamPolicy) addBinding(role, member string) bool {
+	for i := range p.Bindings {
+		if p.Bindings[i].Role != role {
+			continue
+		}
+		for _, existing := range p.Bindings[i].Members {
+			if existing == member {
+				return false
+			}
+		}
+		p.Bindings[i].Members = append(p.Bindings[i].Members, member)
+		return true
+	}
+	p.Bindings = append(p.Bindings, iamBinding{Role: role, Members: []string{member}})
+	return true
+}
+
+// removeMembers removes the members from all bindings, and returns true if the
+// policy changed.
+func (p *iamPolicy) removeMembers(members map[string]bool) bool {
+	changed := false
+	var bindings []iamBinding
+	for _, binding := range p.Bindings {
+		var kept []string
+		for _, member := range binding.Members {
+			if members[member] {
+				changed = true
+				continue
+			}
+			kept = append(kept, member)
+		}
+		if len(kept) > 0 {
+			binding.Members = kept
+			bindings = append(bindings, binding)
+		}
+	}
+	p.Bindings = bindings
+	return changed
+}
+
+// updateIAMPolicy reads the IAM policy of the resource, applies mutate to it and
+// writes it back if mutate returns true. Writes of a policy that was modified
+// concurrently, and of bindings for service accounts that were just created and
+// are not known to IAM yet, fail and are retried.
+func updateIAMPolicy(ctx context.Context, client restClient, resourceURL string, mutate func(*iamPolicy) bool) error {
+	var lastErr error
+	err := wait.PollImmediate(pollingInterval, operationTimeout, func() (bool, error) {
+		policy := &iamPolicy{}
+		if err := client.do(ctx, http.MethodPost, resourceURL+":getIamPolicy", struct{}{}, policy); err != nil {
+			return false, err
+		}
+		if !mutate(policy) {
+			return true, nil
+		}
+		for i := range policy.Bindings {
+			sort.Strings(policy.Bindings[i].Members)
+		}
+		lastErr = client.do(ctx, http.MethodPost, resourceURL+":setIamPolicy", map[string]interface{}{"policy": policy}, nil)
+		if lastErr == nil {
+			return true, nil
+		}
+		if isAlreadyExists(lastErr) || isBadRequest(lastErr) {
+			return false, nil
+		}
+		return false, lastErr
+	})
+	if err == wait.ErrWaitTimeout && lastErr != nil {
+		err = lastErr
+	}
+	return err
+}
@@ -0,0 +1,93 @@
+package gcp
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/gomega"
+)
+
+func TestValidateAccountIDs(t *testing.T) {
+	g := NewGomegaWithT(t)
+	g.Expect(validateAccountIDs("example-a1b2c")).To(Succeed())
+	g.Expect(validateAccountIDs("a-very-long-example-a1b2c")).ToNot(Succeed())
+}
+
+func TestIAMPolicy(t *testing.T) {
+	g := NewGomegaWithT(t)
+	policy := &iamPolicy{Bindings: []iamBinding{{Role: "roles/viewer", Members: []string{"user:admin@example.com"}}}}
+
+	g.Expect(policy.addBinding("roles/viewer", "serviceAccount:sa@project.iam.gserviceaccount.com")).To(BeTrue())
+	g.Expect(policy.addBinding("roles/viewer", "serviceAccount:sa@project.iam.gserviceaccount.com")).To(BeFalse())
+	g.Expect(policy.addBinding("roles/editor", "serviceAccount:sa@project.iam.gserviceaccount.com")).To(BeTrue())
+	g.Expect(policy.Bindings).To(Equal([]iamBinding{
+		{Role: "roles/viewer", Members: []string{"user:admin@example.com", "serviceAccount:sa@project.iam.gserviceaccount.com"}},
+		{Role: "roles/editor", Members: []string{"serviceAccount:sa@project.iam.gserviceaccount.com"}},
+	}))
+
+	g.Expect(policy.removeMembers(map[string]bool{"serviceAccount:sa@project.iam.gserviceaccount.com": true})).To(BeTrue())
+	g.Expect(policy.removeMembers(map[string]bool{"serviceAccount:sa@project.iam.gserviceaccount.com": true})).To(BeFalse())
+	g.Expect(policy.Bindings).To(Equal([]iamBinding{{Role: "roles/viewer", Members: []string{"user:admin@example.com"}}}))
+}
+
+func TestCreateIAM(t *testing.T) {
+	g := NewGomegaWithT(t)
+	ctx := context.Background()
+	client := newFakeRESTClient()
+	opts := &CreateIAMOptions{
+		Project:               "project",
+		InfraID:               "example",
+		IssuerURL:             "https://oidc.example.com/example",
+		ControlPlaneNamespace: "clusters-example",
+	}
+	g.Expect(opts.Validate()).To(Succeed())
+
+	result, err := opts.CreateIAM(ctx, logr.Discard(), client)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(result.ServiceAccounts).To(HaveLen(len(componentServiceAccounts)))
+	g.Expect(result.ServiceAccounts).To(HaveKeyWithValue("cloud-controller", "example-cc@project.iam.gserviceaccount.com"))
+	g.Expect(result.ProjectNumber).To(Equal("123456789"))
+	g.Expect(result.WorkloadIdentityProvider).To(Equal("projects/123456789/locations/global/workloadIdentityPools/example/providers/oidc"))
+
+	projectPolicy := client.policies[resourceManagerURL+"/projects/project"]
+	g.Expect(projectPolicy.Bindings).To(ContainElement(iamBinding{
+		Role:    "roles/dns.admin",
+		Members: []string{"serviceAccount:example-ingress@project.iam.gserviceaccount.com"},
+	}))
+	g.Expect(projectPolicy.Bindings).ToNot(ContainElement(HaveField("Role", "roles/owner")))
+
+	poolPrincipal := "principal://iam.googleapis.com/projects/123456789/locations/global/workloadIdentityPools/example/subject/"
+	ccPolicy := client.policies[iamURL+"/projects/project/serviceAccounts/example-cc@project.iam.gserviceaccount.com"]
+	g.Expect(ccPolicy.Bindings).To(Equal([]iamBinding{{
+		Role:    workloadIdentityUserRole,
+		Members: []string{poolPrincipal + "system:serviceaccount:clusters-example:cloud-controller-manager"},
+	}}))
+	ingressPolicy := client.policies[iamURL+"/projects/project/serviceAccounts/example-ingress@project.iam.gserviceaccount.com"]
+	g.Expect(ingressPolicy.Bindings).To(Equal([]iamBinding{{
+		Role:    workloadIdentityUserRole,
+		Members: []string{poolPrincipal + "system:serviceaccount:openshift-ingress-operator:ingress-operator"},
+	}}))
+	g.Expect(client.policies).ToNot(HaveKey(iamURL + "/projects/project/serviceAccounts/example-node@project.iam.gserviceaccount.com"))
+
+	// Creating the IAM resources again reuses the existing ones
+	created := len(client.created)
+	_, err = opts.CreateIAM(ctx, logr.Discard(), client)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(client.created).To(HaveLen(created))
+	g.Expect(client.policies[resourceManagerURL+"/projects/project"]).To(Equal(projectPolicy))
+
+	destroyOpts := &DestroyIAMOptions{Project: "project", InfraID: "example"}
+	g.Expect(destroyOpts.DestroyIAM(ctx, logr.Discard(), client)).To(Succeed())
+	g.Expect(client.resourceNames()).To(BeEmpty())
+	g.Expect(client.policies[resourceManagerURL+"/projects/project"].Bindings).To(BeEmpty())
+	g.Expect(destroyOpts.DestroyIAM(ctx, logr.Discard(), client)).To(Succeed())
+}
+
+func TestCreateIAMValidate(t *testing.T) {
+	g := NewGomegaWithT(t)
+	opts := &CreateIAMOptions{Project: "project", InfraID: "example", IssuerURL: "https://oidc.example.com/example"}
+	g.Expect(opts.Validate()).ToNot(Succeed())
+	opts.ControlPlaneNamespace = "clusters-example"
+	g.Expect(opts.Validate()).To(Succeed())
+}
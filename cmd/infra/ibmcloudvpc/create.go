@@ -0,0 +1,533 @@
+package ibmcloudvpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/util/wait"
+	utilpointer "k8s.io/utils/pointer"
+
+	"github.com/IBM/go-sdk-core/v5/core"
+	"github.com/IBM/networking-go-sdk/dnsrecordsv1"
+	"github.com/IBM/networking-go-sdk/zonesv1"
+	"github.com/IBM/platform-services-go-sdk/iamidentityv1"
+	"github.com/IBM/platform-services-go-sdk/resourcecontrollerv2"
+	"github.com/IBM/platform-services-go-sdk/resourcemanagerv2"
+	"github.com/IBM/vpc-go-sdk/vpcv1"
+
+	powervsinfra "github.com/openshift/hypershift/cmd/infra/powervs"
+	hypershiftLog "github.com/openshift/hypershift/cmd/log"
+)
+
+const (
+	// Resource name suffixes for creation
+	vpcNameSuffix           = "vpc"
+	vpcSubnetNameSuffix     = "vpc-subnet"
+	publicGatewayNameSuffix = "pgw"
+
+	// CIS service name as it appears in the CRN of its instances
+	cisService = "internet-svcs"
+
+	// Number of addresses of the subnet of the nodes
+	subnetIpv4AddressCount = 256
+
+	// NodePort range the VPC load balancers forward traffic to
+	nodePortMin = 30000
+	nodePortMax = 32767
+
+	vpcAvailableState = "available"
+
+	pollingInterval    = time.Second * 5
+	vpcCreationTimeout = time.Minute * 5
+)
+
+var vpcDefaultURL = func(region string) string { return fmt.Sprintf("https://%s.iaas.cloud.ibm.com/v1", region) }
+
+var log = func(name string) logr.Logger { return hypershiftLog.Log.WithName(name) }
+
+// CreateInfraOptions command line options for setting up infra in IBM Cloud VPC
+type CreateInfraOptions struct {
+	Name          string
+	BaseDomain    string
+	ResourceGroup string
+	InfraID       string
+	Region        string
+	Zone          string
+	OutputFile    string
+}
+
+// Infra resource info in IBM Cloud VPC for setting up a hypershift cluster
+type Infra struct {
+	ID              string `json:"id"`
+	AccountID       string `json:"accountID"`
+	CisCrn          string `json:"cisCrn"`
+	CisDomainID     string `json:"cisDomainID"`
+	ResourceGroupID string `json:"resourceGroupID"`
+	Region          string `json:"region"`
+	Zone            string `json:"zone"`
+	VpcName         string `json:"vpcName"`
+	VpcID           string `json:"vpcID"`
+	VpcSubnetName   string `json:"vpcSubnetName"`
+	VpcSubnetID     string `json:"vpcSubnetID"`
+	PublicGatewayID string `json:"publicGatewayID"`
+}
+
+func NewCreateCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:          "ibmcloudvpc",
+		Short:        "Creates IBM Cloud VPC infrastructure resources for a cluster",
+		SilenceUsage: true,
+	}
+
+	opts := CreateInfraOptions{
+		Name:   "example",
+		Region: "us-south",
+		Zone:   "us-south-1",
+	}
+
+	cmd.Flags().StringVar(&opts.BaseDomain, "base-domain", opts.BaseDomain, "IBM Cloud CIS Domain")
+	cmd.Flags().StringVar(&opts.ResourceGroup, "resource-group", opts.ResourceGroup, "IBM Cloud Resource Group")
+	cmd.Flags().StringVar(&opts.Name, "name", opts.Name, "A name for the cluster")
+	cmd.Flags().StringVar(&opts.InfraID, "infra-id", opts.InfraID, "Cluster ID with which to tag IBM Cloud resources")
+	cmd.Flags().StringVar(&opts.Region, "region", opts.Region, "IBM Cloud VPC Region")
+	cmd.Flags().StringVar(&opts.Zone, "zone", opts.Zone, "IBM Cloud VPC Zone of the nodes")
+	cmd.Flags().StringVar(&opts.OutputFile, "output-file", opts.OutputFile, "Path to file that will contain output information from infra resources (optional)")
+
+	cmd.MarkFlagRequired("base-domain")
+	cmd.MarkFlagRequired("resource-group")
+	cmd.MarkFlagRequired("infra-id")
+
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		if err := opts.Run(cmd.Context()); err != nil {
+			log(opts.InfraID).Error(err, "Failed to create infrastructure")
+			return err
+		}
+		log(opts.InfraID).Info("Successfully created infrastructure")
+		return nil
+	}
+
+	return cmd
+}
+
+// Run Hypershift Infra Creation
+func (options *CreateInfraOptions) Run(ctx context.Context) error {
+	infra := &Infra{ID: options.InfraID}
+	if err := infra.SetupInfra(options); err != nil {
+		return err
+	}
+
+	out := os.Stdout
+	if len(options.OutputFile) > 0 {
+		var err error
+		out, err = os.Create(options.OutputFile)
+		if err != nil {
+			return fmt.Errorf("cannot create output file: %w", err)
+		}
+		defer out.Close()
+	}
+	outputBytes, err := json.MarshalIndent(infra, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to serialize output infra: %w", err)
+	}
+	if _, err := out.Write(outputBytes); err != nil {
+		return fmt.Errorf("failed to write output infra json: %w", err)
+	}
+	return nil
+}
+
+// SetupInfra creates the VPC, public gateway and subnet of the cluster, or
+// reuses them if they already exist.
+func (infra *Infra) SetupInfra(options *CreateInfraOptions) error {
+	startTime := time.Now()
+	log(options.InfraID).Info("Setup infra started")
+
+	apiKey, err := getAPIKey()
+	if err != nil {
+		return err
+	}
+	auth := &core.IamAuthenticator{ApiKey: apiKey}
+
+	infra.Region = options.Region
+	infra.Zone = options.Zone
+
+	infra.AccountID, err = getAccountID(auth, apiKey)
+	if err != nil {
+		return fmt.Errorf("error retrieving account ID: %w", err)
+	}
+
+	infra.ResourceGroupID, err = getResourceGroupID(auth, options.ResourceGroup, infra.AccountID)
+	if err != nil {
+		return fmt.Errorf("error getting id for resource group %s: %w", options.ResourceGroup, err)
+	}
+
+	infra.CisCrn, infra.CisDomainID, err = getCISDomainDetails(auth, options.BaseDomain)
+	if err != nil {
+		return fmt.Errorf("error retrieving cis domain details: %w", err)
+	}
+	if err := checkForExistingDNSRecord(auth, options.Name, options.BaseDomain, infra.CisCrn, infra.CisDomainID); err != nil {
+		return err
+	}
+	log(options.InfraID).Info("BaseDomain Info Ready", "CRN", infra.CisCrn, "DomainID", infra.CisDomainID)
+
+	v1, err := createVpcService(auth, options.Region)
+	if err != nil {
+		return fmt.Errorf("error creating vpc service: %w", err)
+	}
+
+	if err := infra.setupVpc(options, v1); err != nil {
+		return fmt.Errorf("error setup vpc: %w", err)
+	}
+
+	if err := infra.setupPublicGateway(options, v1); err != nil {
+		return fmt.Errorf("error setup public gateway: %w", err)
+	}
+
+	if err := infra.setupVpcSubnet(options, v1); err != nil {
+		return fmt.Errorf("error setup vpc subnet: %w", err)
+	}
+
+	log(options.InfraID).Info("Setup infra completed in", "duration", time.Since(startTime).String())
+	return nil
+}
+
+func getAPIKey() (string, error) {
+	apiKey, err := powervsinfra.GetAPIKey()
+	if err != nil {
+		return "", fmt.Errorf("error retrieving IBM Cloud API Key: %w", err)
+	}
+	if apiKey == "" {
+		return "", fmt.Errorf("cloud API Key not set. Set it with IBMCLOUD_API_KEY env var or set file path containing API Key credential in IBMCLOUD_CREDENTIALS")
+	}
+	return apiKey, nil
+}
+
+// getAccountID gets the id of the account the API key belongs to
+func getAccountID(auth core.Authenticator, apiKey string) (string, error) {
+	iamv1, err := iamidentityv1.NewIamIdentityV1(&iamidentityv1.IamIdentityV1Options{Authenticator: auth})
+	if err != nil {
+		return "", err
+	}
+	apiKeyDetails, _, err := iamv1.GetAPIKeysDetails(&iamidentityv1.GetAPIKeysDetailsOptions{IamAPIKey: &apiKey})
+	if err != nil {
+		return "", err
+	}
+	if apiKeyDetails == nil || apiKeyDetails.AccountID == nil {
+		return "", fmt.Errorf("could not retrieve account id")
+	}
+	return *apiKeyDetails.AccountID, nil
+}
+
+// getResourceGroupID gets the id of the resource group with the given name
+func getResourceGroupID(auth core.Authenticator, resourceGroup string, accountID string) (string, error) {
+	rmv2, err := resourcemanagerv2.NewResourceManagerV2(&resourcemanagerv2.ResourceManagerV2Options{Authenticator: auth})
+	if err != nil {
+		return "", err
+	}
+	resourceGroups, _, err := rmv2.ListResourceGroups(&resourcemanagerv2.ListResourceGroupsOptions{Name: &resourceGroup, AccountID: &accountID})
+	if err != nil {
+		return "", err
+	}
+	if resourceGroups == nil || len(resourceGroups.Resources) == 0 {
+		return "", fmt.Errorf("could not retrieve resource group id for %s", resourceGroup)
+	}
+	return *resourceGroups.Resources[0].ID, nil
+}
+
+// getCISDomainDetails gets the CRN of the CIS instance hosting the base domain and the id of the domain
+func getCISDomainDetails(auth core.Authenticator, baseDomain string) (string, string, error) {
+	rcv2, err := resourcecontrollerv2.NewResourceControllerV2(&resourcecontrollerv2.ResourceControllerV2Options{Authenticator: auth})
+	if err != nil {
+		return "", "", err
+	}
+
+	var start *string
+	for {
+		resourceList, _, err := rcv2.ListResourceInstances(&resourcecontrollerv2.ListResourceInstancesOptions{Start: start})
+		if err != nil {
+			return "", "", err
+		}
+		for _, resource := range resourceList.Resources {
+			if resource.CRN == nil || !strings.Contains(*resource.CRN, ":"+cisService+":") {
+				continue
+			}
+			// skip the instances whose zones can't be listed, the domain may be in another instance
+			zv1, err := zonesv1.NewZonesV1(&zonesv1.ZonesV1Options{Authenticator: auth, Crn: resource.CRN})
+			if err != nil {
+				continue
+			}
+			zoneList, _, err := zv1.ListZones(&zonesv1.ListZonesOptions{})
+			if err != nil || zoneList == nil {
+				continue
+			}
+			for _, zone := range zoneList.Result {
+				if *zone.Name == baseDomain {
+					return *resource.CRN, *zone.ID, nil
+				}
+			}
+		}
+
+		if resourceList.NextURL == nil || *resourceList.NextURL == "" {
+			break
+		}
+		start, err = getStartToken(*resourceList.NextURL)
+		if err != nil {
+			return "", "", err
+		}
+	}
+
+	return "", "", fmt.Errorf("unable to get cis information with base domain %s", baseDomain)
+}
+
+// getStartToken parses the given url string and gets the 'start' query param
+func getStartToken(nextURL string) (*string, error) {
+	u, err := url.Parse(nextURL)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse next url for getting next resources: %w", err)
+	}
+	start := u.Query().Get("start")
+	return &start, nil
+}
+
+// checkForExistingDNSRecord check for existing DNS record with the cluster name
+func checkForExistingDNSRecord(auth core.Authenticator, name, baseDomain, cisCRN, cisDomainID string) error {
+	dnsRecordsV1, err := dnsrecordsv1.NewDnsRecordsV1(&dnsrecordsv1.DnsRecordsV1Options{Crn: &cisCRN, ZoneIdentifier: &cisDomainID, Authenticator: auth})
+	if err != nil {
+		return fmt.Errorf("error creating dns record client: %w", err)
+	}
+
+	recordName := fmt.Sprintf("*.apps.%s.%s", name, baseDomain)
+	dnsRecords, _, err := dnsRecordsV1.ListAllDnsRecords(&dnsrecordsv1.ListAllDnsRecordsOptions{Name: &recordName})
+	if err != nil {
+		return err
+	}
+	if len(dnsRecords.Result) == 0 {
+		return nil
+	}
+
+	return fmt.Errorf("existing DNS record '%s' found in base domain %s, cannot proceed to cluster creation when dns record already exists with the cluster name", recordName, baseDomain)
+}
+
+// createVpcService creates VpcService of type *vpcv1.VpcV1
+func createVpcService(auth core.Authenticator, region string) (*vpcv1.VpcV1, error) {
+	return vpcv1.NewVpcV1(&vpcv1.VpcV1Options{
+		ServiceName:   "vpcs",
+		Authenticator: auth,
+		URL:           vpcDefaultURL(region),
+	})
+}
+
+// setupVpc creates the vpc of the cluster, or reuses the existing one with the infra name
+func (infra *Infra) setupVpc(options *CreateInfraOptions, v1 *vpcv1.VpcV1) error {
+	log(options.InfraID).Info("Setting up VPC ...")
+	vpcName := fmt.Sprintf("%s-%s", options.InfraID, vpcNameSuffix)
+
+	vpc, err := getVpcByName(vpcName, infra.ResourceGroupID, v1)
+	if err != nil {
+		return err
+	}
+	if vpc != nil {
+		log(options.InfraID).Info("Using existing VPC", "name", vpcName)
+	} else {
+		log(options.InfraID).Info("Creating VPC ...")
+		vpc, _, err = v1.CreateVPC(&vpcv1.CreateVPCOptions{
+			ResourceGroup:           &vpcv1.ResourceGroupIdentity{ID: &infra.ResourceGroupID},
+			Name:                    &vpcName,
+			AddressPrefixManagement: utilpointer.String("auto"),
+		})
+		if err != nil {
+			return err
+		}
+
+		err = wait.PollImmediate(pollingInterval, vpcCreationTimeout, func() (bool, error) {
+			vpc, _, err = v1.GetVPC(&vpcv1.GetVPCOptions{ID: vpc.ID})
+			if err != nil {
+				return false, err
+			}
+			return *vpc.Status == vpcAvailableState, nil
+		})
+		if err != nil {
+			return err
+		}
+
+		// Allow the traffic of the load balancers of the cluster to the NodePorts of the nodes
+		_, _, err = v1.CreateSecurityGroupRule(&vpcv1.CreateSecurityGroupRuleOptions{
+			SecurityGroupID: vpc.DefaultSecurityGroup.ID,
+			SecurityGroupRulePrototype: &vpcv1.SecurityGroupRulePrototype{
+				Direction: utilpointer.String("inbound"),
+				Protocol:  utilpointer.String("tcp"),
+				PortMin:   utilpointer.Int64Ptr(nodePortMin),
+				PortMax:   utilpointer.Int64Ptr(nodePortMax),
+			},
+		})
+		if err != nil {
+			return fmt.Errorf("error attaching inbound security group rule to allow node ports to vpc: %w", err)
+		}
+	}
+
+	infra.VpcName = *vpc.Name
+	infra.VpcID = *vpc.ID
+	log(options.InfraID).Info("VPC Ready", "ID", infra.VpcID)
+	return nil
+}
+
+// getVpcByName returns the vpc with the given name in the resource group, or nil if there is none
+func getVpcByName(name, resourceGroupID string, v1 *vpcv1.VpcV1) (*vpcv1.VPC, error) {
+	var start *string
+	for {
+		vpcList, _, err := v1.ListVpcs(&vpcv1.ListVpcsOptions{ResourceGroupID: &resourceGroupID, Start: start})
+		if err != nil {
+			return nil, err
+		}
+		for i := range vpcList.Vpcs {
+			if *vpcList.Vpcs[i].Name == name {
+				return &vpcList.Vpcs[i], nil
+			}
+		}
+		start, err = vpcList.GetNextStart()
+		if err != nil {
+			return nil, err
+		}
+		if start == nil {
+			return nil, nil
+		}
+	}
+}
+
+// setupPublicGateway creates the public gateway the nodes reach the management cluster and the
+// internet through, or reuses the existing one with the infra name
+func (infra *Infra) setupPublicGateway(options *CreateInfraOptions, v1 *vpcv1.VpcV1) error {
+	log(options.InfraID).Info("Setting up Public Gateway ...")
+	gatewayName := fmt.Sprintf("%s-%s", options.InfraID, publicGatewayNameSuffix)
+
+	gateway, err := getPublicGatewayByName(gatewayName, infra.ResourceGroupID, v1)
+	if err != nil {
+		return err
+	}
+	if gateway != nil {
+		log(options.InfraID).Info("Using existing Public Gateway", "name", gatewayName)
+	} else {
+		gateway, _, err = v1.CreatePublicGateway(&vpcv1.CreatePublicGatewayOptions{
+			VPC:           &vpcv1.VPCIdentityByID{ID: &infra.VpcID},
+			Zone:          &vpcv1.ZoneIdentityByName{Name: &options.Zone},
+			Name:          &gatewayName,
+			ResourceGroup: &vpcv1.ResourceGroupIdentityByID{ID: &infra.ResourceGroupID},
+		})
+		if err != nil {
+			return err
+		}
+
+		err = wait.PollImmediate(pollingInterval, vpcCreationTimeout, func() (bool, error) {
+			gateway, _, err = v1.GetPublicGateway(&vpcv1.GetPublicGatewayOptions{ID: gateway.ID})
+			if err != nil {
+				return false, err
+			}
+			return *gateway.Status == vpcv1.PublicGatewayStatusAvailableConst, nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	infra.PublicGatewayID = *gateway.ID
+	log(options.InfraID).Info("Public Gateway Ready", "ID", infra.PublicGatewayID)
+	return nil
+}
+
+// getPublicGatewayByName returns the public gateway with the given name in the resource group, or nil if there is none
+func getPublicGatewayByName(name, resourceGroupID string, v1 *vpcv1.VpcV1) (*vpcv1.PublicGateway, error) {
+	var start *string
+	for {
+		gatewayList, _, err := v1.ListPublicGateways(&vpcv1.ListPublicGatewaysOptions{ResourceGroupID: &resourceGroupID, Start: start})
+		if err != nil {
+			return nil, err
+		}
+		for i := range gatewayList.PublicGateways {
+			if *gatewayList.PublicGateways[i].Name == name {
+				return &gatewayList.PublicGateways[i], nil
+			}
+		}
+		start, err = gatewayList.GetNextStart()
+		if err != nil {
+			return nil, err
+		}
+		if start == nil {
+			return nil, nil
+		}
+	}
+}
+
+// setupVpcSubnet creates the subnet of the nodes in the zone of the cluster, or reuses the
+// existing one with the infra name
+func (infra *Infra) setupVpcSubnet(options *CreateInfraOptions, v1 *vpcv1.VpcV1) error {
+	log(options.InfraID).Info("Setting up VPC Subnet ...")
+	subnetName := fmt.Sprintf("%s-%s", options.InfraID, vpcSubnetNameSuffix)
+
+	subnet, err := getSubnetByName(subnetName, infra.ResourceGroupID, v1)
+	if err != nil {
+		return err
+	}
+	if subnet != nil {
+		log(options.InfraID).Info("Using existing VPC Subnet", "name", subnetName)
+	} else {
+		subnet, _, err = v1.CreateSubnet(&vpcv1.CreateSubnetOptions{
+			SubnetPrototype: &vpcv1.SubnetPrototypeSubnetByTotalCount{
+				Name:                  &subnetName,
+				IPVersion:             utilpointer.String(vpcv1.SubnetPrototypeSubnetByTotalCountIPVersionIpv4Const),
+				VPC:                   &vpcv1.VPCIdentityByID{ID: &infra.VpcID},
+				Zone:                  &vpcv1.ZoneIdentityByName{Name: &options.Zone},
+				TotalIpv4AddressCount: utilpointer.Int64Ptr(subnetIpv4AddressCount),
+				PublicGateway:         &vpcv1.PublicGatewayIdentityPublicGatewayIdentityByID{ID: &infra.PublicGatewayID},
+				ResourceGroup:         &vpcv1.ResourceGroupIdentityByID{ID: &infra.ResourceGroupID},
+			},
+		})
+		if err != nil {
+			return err
+		}
+
+		err = wait.PollImmediate(pollingInterval, vpcCreationTimeout, func() (bool, error) {
+			subnet, _, err = v1.GetSubnet(&vpcv1.GetSubnetOptions{ID: subnet.ID})
+			if err != nil {
+				return false, err
+			}
+			return *subnet.Status == vpcAvailableState, nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	infra.VpcSubnetName = *subnet.Name
+	infra.VpcSubnetID = *subnet.ID
+	log(options.InfraID).Info("VPC Subnet Ready", "ID", infra.VpcSubnetID)
+	return nil
+}
+
+// getSubnetByName returns the subnet with the given name in the resource group, or nil if there is none
+func getSubnetByName(name, resourceGroupID string, v1 *vpcv1.VpcV1) (*vpcv1.Subnet, error) {
+	var start *string
+	for {
+		subnetList, _, err := v1.ListSubnets(&vpcv1.ListSubnetsOptions{ResourceGroupID: &resourceGroupID, Start: start})
+		if err != nil {
+			return nil, err
+		}
+		for i := range subnetList.Subnets {
+			if *subnetList.Subnets[i].Name == name {
+				return &subnetList.Subnets[i], nil
+			}
+		}
+		start, err = subnetList.GetNextStart()
+		if err != nil {
+			return nil, err
+		}
+		if start == nil {
+			return nil, nil
+		}
+	}
+}
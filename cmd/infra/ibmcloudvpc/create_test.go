@@ -0,0 +1,93 @@
+package ibmcloudvpc
+
+import (
+	"context"
+	"io/ioutil"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestCreateCommandFlagValidation(t *testing.T) {
+	tests := map[string]struct {
+		args        []string
+		expectedErr string
+	}{
+		"Error expected when base domain is missing": {
+			args:        []string{"--resource-group=rg", "--infra-id=test-x7k2p"},
+			expectedErr: `required flag(s) "base-domain" not set`,
+		},
+		"Error expected when resource group is missing": {
+			args:        []string{"--base-domain=example.com", "--infra-id=test-x7k2p"},
+			expectedErr: `required flag(s) "resource-group" not set`,
+		},
+		"Error expected when infra id is missing": {
+			args:        []string{"--base-domain=example.com", "--resource-group=rg"},
+			expectedErr: `required flag(s) "infra-id" not set`,
+		},
+		"Error expected when the API key is not set": {
+			args:        []string{"--base-domain=example.com", "--resource-group=rg", "--infra-id=test-x7k2p"},
+			expectedErr: "cloud API Key not set",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			g := NewGomegaWithT(t)
+			t.Setenv("IBMCLOUD_API_KEY", "")
+			t.Setenv("IBMCLOUD_CREDENTIALS", "")
+
+			cmd := NewCreateCommand()
+			cmd.SetArgs(test.args)
+			cmd.SetOut(ioutil.Discard)
+			cmd.SetErr(ioutil.Discard)
+			err := cmd.ExecuteContext(context.Background())
+			g.Expect(err).To(HaveOccurred())
+			g.Expect(err.Error()).To(ContainSubstring(test.expectedErr))
+		})
+	}
+}
+
+func TestCreateCommandDefaults(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	cmd := NewCreateCommand()
+	g.Expect(cmd.Flags().Lookup("region").DefValue).To(Equal("us-south"))
+	g.Expect(cmd.Flags().Lookup("zone").DefValue).To(Equal("us-south-1"))
+	g.Expect(cmd.Flags().Lookup("name").DefValue).To(Equal("example"))
+}
+
+func TestGetStartToken(t *testing.T) {
+	tests := map[string]struct {
+		nextURL     string
+		expected    string
+		errExpected bool
+	}{
+		"Start token returned from next url": {
+			nextURL:  "/v2/resource_instances?limit=100&start=g1AAAAE",
+			expected: "g1AAAAE",
+		},
+		"Empty start token returned when next url has none": {
+			nextURL:  "/v2/resource_instances?limit=100",
+			expected: "",
+		},
+		"Error expected when next url is invalid": {
+			nextURL:     "%zz",
+			errExpected: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			g := NewGomegaWithT(t)
+
+			start, err := getStartToken(test.nextURL)
+			if test.errExpected {
+				g.Expect(err).To(HaveOccurred())
+				return
+			}
+			g.Expect(err).To(BeNil())
+			g.Expect(*start).To(Equal(test.expected))
+		})
+	}
+}
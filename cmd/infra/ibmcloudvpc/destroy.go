@@ -0,0 +1,310 @@
+package ibmcloudvpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	"github.com/IBM/go-sdk-core/v5/core"
+	"github.com/IBM/networking-go-sdk/dnsrecordsv1"
+	"github.com/IBM/vpc-go-sdk/vpcv1"
+)
+
+const (
+	vpcResourceDeletionTimeout = time.Minute * 5
+
+	// Prefix of the load balancers provisioned by the cloud controller manager
+	vpcLbNamePrefix = "kube"
+)
+
+// DestroyInfraOptions command line options to destroy infra created in IBM Cloud VPC for Hypershift
+type DestroyInfraOptions struct {
+	Name               string
+	InfraID            string
+	InfrastructureJson string
+	BaseDomain         string
+	CISCRN             string
+	CISDomainID        string
+	ResourceGroup      string
+	Region             string
+}
+
+func NewDestroyCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:          "ibmcloudvpc",
+		Short:        "Destroys IBM Cloud VPC infrastructure resources for a cluster",
+		SilenceUsage: true,
+	}
+
+	opts := DestroyInfraOptions{
+		Region: "us-south",
+	}
+
+	cmd.Flags().StringVar(&opts.Name, "name", opts.Name, "Name of the cluster")
+	cmd.Flags().StringVar(&opts.InfraID, "infra-id", opts.InfraID, "Cluster ID with which to tag IBM Cloud resources")
+	cmd.Flags().StringVar(&opts.InfrastructureJson, "infra-json", opts.InfrastructureJson, "Result of ./hypershift infra create ibmcloudvpc")
+	cmd.Flags().StringVar(&opts.BaseDomain, "base-domain", opts.BaseDomain, "The ingress base domain of the cluster")
+	cmd.Flags().StringVar(&opts.ResourceGroup, "resource-group", opts.ResourceGroup, "IBM Cloud Resource Group")
+	cmd.Flags().StringVar(&opts.Region, "region", opts.Region, "IBM Cloud VPC Region")
+
+	cmd.MarkFlagRequired("name")
+	cmd.MarkFlagRequired("resource-group")
+	cmd.MarkFlagRequired("base-domain")
+	cmd.MarkFlagRequired("infra-id")
+
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		if err := opts.Run(cmd.Context()); err != nil {
+			log(opts.InfraID).Error(err, "Failed to destroy infrastructure")
+			return err
+		}
+		log(opts.InfraID).Info("Successfully destroyed infrastructure")
+		return nil
+	}
+
+	return cmd
+}
+
+// Run Hypershift Infra Destroy
+func (options *DestroyInfraOptions) Run(ctx context.Context) error {
+	var infra *Infra
+	if len(options.InfrastructureJson) > 0 {
+		rawInfra, err := ioutil.ReadFile(options.InfrastructureJson)
+		if err != nil {
+			return fmt.Errorf("failed to read infra json file: %w", err)
+		}
+		infra = &Infra{}
+		if err = json.Unmarshal(rawInfra, infra); err != nil {
+			return fmt.Errorf("failed to load infra json: %w", err)
+		}
+	}
+	return options.DestroyInfra(infra)
+}
+
+// DestroyInfra deletes the DNS records, load balancers, subnet, public gateway and VPC of the
+// cluster. When infra is nil, the resources are looked up by the names they are created with.
+func (options *DestroyInfraOptions) DestroyInfra(infra *Infra) error {
+	log(options.InfraID).Info("Destroy Infra Started")
+
+	apiKey, err := getAPIKey()
+	if err != nil {
+		return err
+	}
+	auth := &core.IamAuthenticator{ApiKey: apiKey}
+
+	accountID, err := getAccountID(auth, apiKey)
+	if err != nil {
+		return fmt.Errorf("error retrieving account ID: %w", err)
+	}
+
+	resourceGroupID, err := getResourceGroupID(auth, options.ResourceGroup, accountID)
+	if err != nil {
+		return err
+	}
+
+	if infra == nil {
+		infra = &Infra{}
+	}
+
+	errL := make([]error, 0)
+
+	if err := options.deleteDNSRecords(auth); err != nil {
+		errL = append(errL, fmt.Errorf("error deleting dns record from cis domain: %w", err))
+		log(options.InfraID).Error(err, "error deleting dns record from cis domain")
+	}
+
+	v1, err := createVpcService(auth, options.Region)
+	if err != nil {
+		return err
+	}
+
+	// The subnet has to be deleted before the public gateway attached to it, and both before the VPC.
+	subnetID := infra.VpcSubnetID
+	if subnetID == "" {
+		subnet, err := getSubnetByName(fmt.Sprintf("%s-%s", options.InfraID, vpcSubnetNameSuffix), resourceGroupID, v1)
+		if err != nil {
+			errL = append(errL, err)
+		} else if subnet != nil {
+			subnetID = *subnet.ID
+		}
+	}
+	if subnetID != "" {
+		if err := options.destroyVpcSubnet(subnetID, v1); err != nil {
+			errL = append(errL, fmt.Errorf("error destroying vpc subnet: %w", err))
+			log(options.InfraID).Error(err, "error destroying vpc subnet")
+		}
+	} else {
+		log(options.InfraID).Info("No VPC Subnet available to delete")
+	}
+
+	gatewayID := infra.PublicGatewayID
+	if gatewayID == "" {
+		gateway, err := getPublicGatewayByName(fmt.Sprintf("%s-%s", options.InfraID, publicGatewayNameSuffix), resourceGroupID, v1)
+		if err != nil {
+			errL = append(errL, err)
+		} else if gateway != nil {
+			gatewayID = *gateway.ID
+		}
+	}
+	if gatewayID != "" {
+		if err := options.destroyPublicGateway(gatewayID, v1); err != nil {
+			errL = append(errL, fmt.Errorf("error destroying public gateway: %w", err))
+			log(options.InfraID).Error(err, "error destroying public gateway")
+		}
+	} else {
+		log(options.InfraID).Info("No Public Gateway available to delete")
+	}
+
+	vpcID := infra.VpcID
+	if vpcID == "" {
+		vpc, err := getVpcByName(fmt.Sprintf("%s-%s", options.InfraID, vpcNameSuffix), resourceGroupID, v1)
+		if err != nil {
+			errL = append(errL, err)
+		} else if vpc != nil {
+			vpcID = *vpc.ID
+		}
+	}
+	if vpcID != "" {
+		log(options.InfraID).Info("Deleting VPC", "id", vpcID)
+		if _, err := v1.DeleteVPC(&vpcv1.DeleteVPCOptions{ID: &vpcID}); err != nil {
+			errL = append(errL, fmt.Errorf("error destroying vpc: %w", err))
+			log(options.InfraID).Error(err, "error destroying vpc")
+		}
+	} else {
+		log(options.InfraID).Info("No VPC available to delete")
+	}
+
+	log(options.InfraID).Info("Destroy Infra Completed")
+
+	if err := errors.NewAggregate(errL); err != nil {
+		return fmt.Errorf("error in destroying infra: %w", err)
+	}
+	return nil
+}
+
+// deleteDNSRecords deletes the DNS records of the cluster from the CIS domain
+func (options *DestroyInfraOptions) deleteDNSRecords(auth core.Authenticator) error {
+	if options.CISCRN == "" || options.CISDomainID == "" {
+		var err error
+		options.CISCRN, options.CISDomainID, err = getCISDomainDetails(auth, options.BaseDomain)
+		if err != nil {
+			return fmt.Errorf("error retrieving cis domain details: %w", err)
+		}
+	}
+
+	dnsRecordsV1, err := dnsrecordsv1.NewDnsRecordsV1(&dnsrecordsv1.DnsRecordsV1Options{Crn: &options.CISCRN, ZoneIdentifier: &options.CISDomainID, Authenticator: auth})
+	if err != nil {
+		return fmt.Errorf("error creating dns record service: %w", err)
+	}
+
+	recordName := fmt.Sprintf("*.apps.%s.%s", options.Name, options.BaseDomain)
+	dnsRecords, _, err := dnsRecordsV1.ListAllDnsRecords(&dnsrecordsv1.ListAllDnsRecordsOptions{Name: &recordName})
+	if err != nil {
+		return err
+	}
+	if len(dnsRecords.Result) == 0 {
+		log(options.InfraID).Info("No matching DNS Records present in CIS Domain")
+		return nil
+	}
+
+	for _, record := range dnsRecords.Result {
+		log(options.InfraID).Info("Deleting DNS", "record", recordName)
+		if _, _, err := dnsRecordsV1.DeleteDnsRecord(&dnsrecordsv1.DeleteDnsRecordOptions{DnsrecordIdentifier: record.ID}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// destroyVpcSubnet deletes the load balancers of the cluster in the subnet and then the subnet
+func (options *DestroyInfraOptions) destroyVpcSubnet(id string, v1 *vpcv1.VpcV1) error {
+	if err := options.destroyVpcLBs(id, v1); err != nil {
+		return fmt.Errorf("error destroying VPC Load Balancer: %w", err)
+	}
+
+	log(options.InfraID).Info("Deleting VPC subnet", "subnetId", id)
+	if _, err := v1.DeleteSubnet(&vpcv1.DeleteSubnetOptions{ID: &id}); err != nil {
+		return err
+	}
+
+	return wait.PollImmediate(pollingInterval, vpcResourceDeletionTimeout, func() (bool, error) {
+		_, _, err := v1.GetSubnet(&vpcv1.GetSubnetOptions{ID: &id})
+		if err != nil && strings.Contains(err.Error(), "Subnet not found") {
+			return true, nil
+		}
+		return false, err
+	})
+}
+
+// destroyVpcLBs deletes the load balancers provisioned by the cloud controller manager of the
+// cluster in the subnet
+func (options *DestroyInfraOptions) destroyVpcLBs(subnetID string, v1 *vpcv1.VpcV1) error {
+	lbName := fmt.Sprintf("%s-%s", vpcLbNamePrefix, options.Name)
+	var start *string
+	var lbIDs []string
+	for {
+		lbList, _, err := v1.ListLoadBalancers(&vpcv1.ListLoadBalancersOptions{Start: start})
+		if err != nil {
+			return err
+		}
+		for _, lb := range lbList.LoadBalancers {
+			if !strings.HasPrefix(*lb.Name, lbName) {
+				continue
+			}
+			for _, subnet := range lb.Subnets {
+				if *subnet.ID == subnetID {
+					lbIDs = append(lbIDs, *lb.ID)
+					break
+				}
+			}
+		}
+		start, err = lbList.GetNextStart()
+		if err != nil {
+			return err
+		}
+		if start == nil {
+			break
+		}
+	}
+
+	for _, id := range lbIDs {
+		id := id
+		log(options.InfraID).Info("Deleting VPC LoadBalancer", "id", id)
+		if _, err := v1.DeleteLoadBalancer(&vpcv1.DeleteLoadBalancerOptions{ID: &id}); err != nil {
+			return err
+		}
+		err := wait.PollImmediate(pollingInterval, vpcResourceDeletionTimeout, func() (bool, error) {
+			_, _, err := v1.GetLoadBalancer(&vpcv1.GetLoadBalancerOptions{ID: &id})
+			if err != nil && strings.Contains(err.Error(), "cannot be found") {
+				return true, nil
+			}
+			return false, err
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// destroyPublicGateway deletes the public gateway
+func (options *DestroyInfraOptions) destroyPublicGateway(id string, v1 *vpcv1.VpcV1) error {
+	log(options.InfraID).Info("Deleting Public Gateway", "id", id)
+	if _, err := v1.DeletePublicGateway(&vpcv1.DeletePublicGatewayOptions{ID: &id}); err != nil {
+		return err
+	}
+
+	return wait.PollImmediate(pollingInterval, vpcResourceDeletionTimeout, func() (bool, error) {
+		_, resp, err := v1.GetPublicGateway(&vpcv1.GetPublicGatewayOptions{ID: &id})
+		if err != nil && resp != nil && resp.StatusCode == 404 {
+			return true, nil
+		}
+		return false, err
+	})
+}
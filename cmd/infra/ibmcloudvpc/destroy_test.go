@@ -0,0 +1,60 @@
+package ibmcloudvpc
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestDestroyCommandFlagValidation(t *testing.T) {
+	invalidInfraJSON := filepath.Join(t.TempDir(), "infra.json")
+	if err := ioutil.WriteFile(invalidInfraJSON, []byte("{"), 0600); err != nil {
+		t.Fatalf("failed to write infra json: %v", err)
+	}
+	requiredArgs := []string{"--name=test", "--resource-group=rg", "--base-domain=example.com", "--infra-id=test-x7k2p"}
+
+	tests := map[string]struct {
+		args        []string
+		expectedErr string
+	}{
+		"Error expected when name is missing": {
+			args:        []string{"--resource-group=rg", "--base-domain=example.com", "--infra-id=test-x7k2p"},
+			expectedErr: `required flag(s) "name" not set`,
+		},
+		"Error expected when infra id is missing": {
+			args:        []string{"--name=test", "--resource-group=rg", "--base-domain=example.com"},
+			expectedErr: `required flag(s) "infra-id" not set`,
+		},
+		"Error expected when infra json does not exist": {
+			args:        append(requiredArgs, "--infra-json="+filepath.Join(t.TempDir(), "missing.json")),
+			expectedErr: "failed to read infra json file",
+		},
+		"Error expected when infra json is invalid": {
+			args:        append(requiredArgs, "--infra-json="+invalidInfraJSON),
+			expectedErr: "failed to load infra json",
+		},
+		"Error expected when the API key is not set": {
+			args:        requiredArgs,
+			expectedErr: "cloud API Key not set",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			g := NewGomegaWithT(t)
+			t.Setenv("IBMCLOUD_API_KEY", "")
+			t.Setenv("IBMCLOUD_CREDENTIALS", "")
+
+			cmd := NewDestroyCommand()
+			cmd.SetArgs(test.args)
+			cmd.SetOut(ioutil.Discard)
+			cmd.SetErr(ioutil.Discard)
+			err := cmd.ExecuteContext(context.Background())
+			g.Expect(err).To(HaveOccurred())
+			g.Expect(err.Error()).To(ContainSubstring(test.expectedErr))
+		})
+	}
+}
@@ -0,0 +1,279 @@
+package oci
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/ini.v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+const (
+	// coreAPIVersion is the version of the Core Services API the networking
+	// resources are managed with.
+	coreAPIVersion  = "20160918"
+	pollingInterval = 5 * time.Second
+	stateTimeout    = 10 * time.Minute
+
+	lifecycleStateAvailable  = "AVAILABLE"
+	lifecycleStateTerminated = "TERMINATED"
+)
+
+// restClient sends requests to the Core Services API of a region. The path is
+// relative to the API version, the request body in is marshalled to JSON, and
+// the response is unmarshalled into out if it is not nil.
+type restClient interface {
+	do(ctx context.Context, method, path string, in, out interface{}) error
+}
+
+// apiError is the error returned by the OCI REST APIs.
+type apiError struct {
+	StatusCode int    `json:"-"`
+	Code       string `json:"code"`
+	Message    string `json:"message"`
+}
+
+func (e *apiError) Error() string {
+	return fmt.Sprintf("oci: error %d %s: %s", e.StatusCode, e.Code, e.Message)
+}
+
+func isNotFound(err error) bool {
+	var apiErr *apiError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
+
+// credentials is the API signing key of a user.
+type credentials struct {
+	Tenancy     string
+	User        string
+	Fingerprint string
+	Region      string
+	PrivateKey  *rsa.PrivateKey
+}
+
+func (c *credentials) keyID() string {
+	return fmt.Sprintf("%s/%s/%s", c.Tenancy, c.User, c.Fingerprint)
+}
+
+// defaultConfigFile returns the path of the configuration file of the OCI CLI.
+func defaultConfigFile() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".oci", "config")
+}
+
+// readCredentials reads the credentials of a profile of an OCI CLI
+// configuration file.
+func readCredentials(configFile, profile string) (*credentials, error) {
+	config, err := ini.Load(configFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read OCI config file %s: %w", configFile, err)
+	}
+	section, err := config.GetSection(profile)
+	if err != nil {
+		return nil, fmt.Errorf("OCI config file %s has no profile %s", configFile, profile)
+	}
+	creds := &credentials{
+		Tenancy:     section.Key("tenancy").String(),
+		User:        section.Key("user").String(),
+		Fingerprint: section.Key("fingerprint").String(),
+		Region:      section.Key("region").String(),
+	}
+	keyFile := section.Key("key_file").String()
+	for key, value := range map[string]string{"tenancy": creds.Tenancy, "user": creds.User, "fingerprint": creds.Fingerprint, "key_file": keyFile} {
+		if len(value) == 0 {
+			return nil, fmt.Errorf("profile %s of OCI config file %s has no %s", profile, configFile, key)
+		}
+	}
+	if strings.HasPrefix(keyFile, "~/") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, err
+		}
+		keyFile = filepath.Join(home, keyFile[2:])
+	}
+	rawKey, err := ioutil.ReadFile(keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read private key file: %w", err)
+	}
+	if creds.PrivateKey, err = parsePrivateKey(rawKey); err != nil {
+		return nil, fmt.Errorf("failed to parse private key file %s: %w", keyFile, err)
+	}
+	return creds, nil
+}
+
+// parsePrivateKey parses an unencrypted RSA private key in the PKCS #1 or
+// PKCS #8 PEM format.
+func parsePrivateKey(raw []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(raw)
+	if block == nil {
+		return nil, errors.New("no PEM data found")
+	}
+	if _, encrypted := block.Headers["Proc-Type"]; encrypted || block.Type == "ENCRYPTED PRIVATE KEY" {
+		return nil, errors.New("encrypted private keys are not supported")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	rsaKey, isRSA := key.(*rsa.PrivateKey)
+	if !isRSA {
+		return nil, errors.New("private key is not an RSA key")
+	}
+	return rsaKey, nil
+}
+
+// signRequest signs the request with version 1 of the OCI HTTP signatures. The
+// body of requests that have one is covered by the signature through its
+// digest.
+func signRequest(req *http.Request, body []byte, creds *credentials) error {
+	req.Header.Set("Date", time.Now().UTC().Format(http.TimeFormat))
+	headers := []string{"date", "(request-target)", "host"}
+	if req.Method == http.MethodPost || req.Method == http.MethodPut || req.Method == http.MethodPatch {
+		digest := sha256.Sum256(body)
+		req.Header.Set("X-Content-SHA256", base64.StdEncoding.EncodeToString(digest[:]))
+		req.Header.Set("Content-Length", strconv.Itoa(len(body)))
+		headers = append(headers, "content-length", "content-type", "x-content-sha256")
+	}
+
+	var signingString []string
+	for _, header := range headers {
+		var value string
+		switch header {
+		case "(request-target)":
+			value = fmt.Sprintf("%s %s", strings.ToLower(req.Method), req.URL.RequestURI())
+		case "host":
+			value = req.URL.Host
+		default:
+			value = req.Header.Get(header)
+		}
+		signingString = append(signingString, fmt.Sprintf("%s: %s", header, value))
+	}
+	digest := sha256.Sum256([]byte(strings.Join(signingString, "\n")))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, creds.PrivateKey, crypto.SHA256, digest[:])
+	if err != nil {
+		return fmt.Errorf("failed to sign request: %w", err)
+	}
+	req.Header.Set("Authorization", fmt.Sprintf(`Signature version="1",keyId="%s",algorithm="rsa-sha256",headers="%s",signature="%s"`,
+		creds.keyID(), strings.Join(headers, " "), base64.StdEncoding.EncodeToString(signature)))
+	return nil
+}
+
+// httpRESTClient is a restClient that signs the requests with an API signing
+// key.
+type httpRESTClient struct {
+	client   *http.Client
+	endpoint string
+	creds    *credentials
+}
+
+// newRESTClient returns a client of the Core Services API of the region that
+// authenticates with a profile of an OCI CLI configuration file. The region of
+// the profile is used when region is empty.
+func newRESTClient(configFile, profile, region string) (restClient, string, error) {
+	creds, err := readCredentials(configFile, profile)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(region) == 0 {
+		region = creds.Region
+	}
+	if len(region) == 0 {
+		return nil, "", fmt.Errorf("no region specified, and profile %s of OCI config file %s has no region", profile, configFile)
+	}
+	return &httpRESTClient{
+		client:   &http.Client{Timeout: time.Minute},
+		endpoint: fmt.Sprintf("https://iaas.%s.oraclecloud.com/%s", region, coreAPIVersion),
+		creds:    creds,
+	}, region, nil
+}
+
+func (c *httpRESTClient) do(ctx context.Context, method, path string, in, out interface{}) error {
+	var body []byte
+	if in != nil {
+		var err error
+		if body, err = json.Marshal(in); err != nil {
+			return fmt.Errorf("failed to serialize request: %w", err)
+		}
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.endpoint+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Content-Type", "application/json")
+	if err := signRequest(req, body, c.creds); err != nil {
+		return err
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		apiErr := &apiError{}
+		if err := json.Unmarshal(respBody, apiErr); err != nil || len(apiErr.Code) == 0 {
+			apiErr = &apiError{Message: strings.TrimSpace(string(respBody))}
+		}
+		apiErr.StatusCode = resp.StatusCode
+		return apiErr
+	}
+	if out != nil && len(respBody) > 0 {
+		if err := json.Unmarshal(respBody, out); err != nil {
+			return fmt.Errorf("failed to deserialize response: %w", err)
+		}
+	}
+	return nil
+}
+
+// resource is the part of the networking resources the infra commands use.
+type resource struct {
+	ID             string `json:"id"`
+	DisplayName    string `json:"displayName"`
+	LifecycleState string `json:"lifecycleState"`
+}
+
+// waitForState waits for the resource at path to reach the lifecycle state. A
+// resource that no longer exists is considered terminated.
+func waitForState(ctx context.Context, client restClient, path, state string) error {
+	err := wait.PollImmediate(pollingInterval, stateTimeout, func() (bool, error) {
+		current := &resource{}
+		if err := client.do(ctx, http.MethodGet, path, nil, current); err != nil {
+			if isNotFound(err) && state == lifecycleStateTerminated {
+				return true, nil
+			}
+			return false, err
+		}
+		return current.LifecycleState == state, nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to wait for %s to be %s: %w", path, strings.ToLower(state), err)
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package oci
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestSignRequest(t *testing.T) {
+	g := NewGomegaWithT(t)
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	g.Expect(err).ToNot(HaveOccurred())
+	creds := &credentials{Tenancy: "ocid1.tenancy.oc1..tenancy", User: "ocid1.user.oc1..user", Fingerprint: "12:34", PrivateKey: key}
+
+	body := []byte(`{"displayName":"example-vcn"}`)
+	req, err := http.NewRequest(http.MethodPost, "https://iaas.us-ashburn-1.oraclecloud.com/20160918/vcns", nil)
+	g.Expect(err).ToNot(HaveOccurred())
+	req.Header.Set("Content-Type", "application/json")
+	g.Expect(signRequest(req, body, creds)).To(Succeed())
+
+	authorization := regexp.MustCompile(`^Signature version="1",keyId="([^"]+)",algorithm="rsa-sha256",headers="([^"]+)",signature="([^"]+)"$`).FindStringSubmatch(req.Header.Get("Authorization"))
+	g.Expect(authorization).To(HaveLen(4))
+	g.Expect(authorization[1]).To(Equal("ocid1.tenancy.oc1..tenancy/ocid1.user.oc1..user/12:34"))
+	g.Expect(authorization[2]).To(Equal("date (request-target) host content-length content-type x-content-sha256"))
+
+	bodyDigest := sha256.Sum256(body)
+	signingString := strings.Join([]string{
+		"date: " + req.Header.Get("Date"),
+		"(request-target): post /20160918/vcns",
+		"host: iaas.us-ashburn-1.oraclecloud.com",
+		fmt.Sprintf("content-length: %d", len(body)),
+		"content-type: application/json",
+		"x-content-sha256: " + base64.StdEncoding.EncodeToString(bodyDigest[:]),
+	}, "\n")
+	signature, err := base64.StdEncoding.DecodeString(authorization[3])
+	g.Expect(err).ToNot(HaveOccurred())
+	digest := sha256.Sum256([]byte(signingString))
+	g.Expect(rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, digest[:], signature)).To(Succeed())
+
+	// Requests without a body only sign the date, target and host
+	req, err = http.NewRequest(http.MethodDelete, "https://iaas.us-ashburn-1.oraclecloud.com/20160918/vcns/ocid1.vcn", nil)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(signRequest(req, nil, creds)).To(Succeed())
+	g.Expect(req.Header.Get("Authorization")).To(ContainSubstring(`headers="date (request-target) host"`))
+	g.Expect(req.Header.Get("X-Content-SHA256")).To(BeEmpty())
+}
+
+func TestReadCredentials(t *testing.T) {
+	g := NewGomegaWithT(t)
+	dir := t.TempDir()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	g.Expect(err).ToNot(HaveOccurred())
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(key)
+	g.Expect(err).ToNot(HaveOccurred())
+	keyFile := filepath.Join(dir, "key.pem")
+	g.Expect(ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8}), 0600)).To(Succeed())
+	configFile := filepath.Join(dir, "config")
+	g.Expect(ioutil.WriteFile(configFile, []byte(fmt.Sprintf(`[DEFAULT]
+user=ocid1.user.oc1..user
+fingerprint=12:34
+tenancy=ocid1.tenancy.oc1..tenancy
+region=us-ashburn-1
+key_file=%s
+
+[OTHER]
+user=ocid1.user.oc1..other
+`, keyFile)), 0600)).To(Succeed())
+
+	creds, err := readCredentials(configFile, "DEFAULT")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(creds.keyID()).To(Equal("ocid1.tenancy.oc1..tenancy/ocid1.user.oc1..user/12:34"))
+	g.Expect(creds.Region).To(Equal("us-ashburn-1"))
+	g.Expect(creds.PrivateKey.Equal(key)).To(BeTrue())
+
+	_, err = readCredentials(configFile, "OTHER")
+	g.Expect(err).To(MatchError(ContainSubstring("has no")))
+	_, err = readCredentials(configFile, "MISSING")
+	g.Expect(err).To(HaveOccurred())
+}
@@ -0,0 +1,328 @@
+package oci
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+
+	"github.com/go-logr/logr"
+	"github.com/spf13/cobra"
+
+	"github.com/openshift/hypershift/cmd/log"
+)
+
+const (
+	defaultVCNCIDR = "10.0.0.0/16"
+	defaultProfile = "DEFAULT"
+)
+
+// CreateInfraOptions command line options for setting up infra in OCI
+type CreateInfraOptions struct {
+	CompartmentID string
+	Region        string
+	InfraID       string
+	VCNCIDR       string
+	ConfigFile    string
+	Profile       string
+	OutputFile    string
+}
+
+// CreateInfraOutput is the infrastructure created for a cluster in OCI
+type CreateInfraOutput struct {
+	InfraID                string `json:"infraID"`
+	Region                 string `json:"region"`
+	CompartmentID          string `json:"compartmentID"`
+	VCNCIDR                string `json:"vcnCIDR"`
+	VCNID                  string `json:"vcnID"`
+	SubnetID               string `json:"subnetID"`
+	LoadBalancerSubnetID   string `json:"loadBalancerSubnetID"`
+	NetworkSecurityGroupID string `json:"networkSecurityGroupID"`
+}
+
+func NewCreateCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:          "oci",
+		Short:        "Creates OCI infrastructure resources for a cluster",
+		SilenceUsage: true,
+	}
+
+	opts := CreateInfraOptions{
+		VCNCIDR:    defaultVCNCIDR,
+		ConfigFile: defaultConfigFile(),
+		Profile:    defaultProfile,
+	}
+
+	cmd.Flags().StringVar(&opts.CompartmentID, "compartment-id", opts.CompartmentID, "OCID of the compartment the resources are created in (required)")
+	cmd.Flags().StringVar(&opts.Region, "region", opts.Region, "OCI region of the cluster. Defaults to the region of the profile")
+	cmd.Flags().StringVar(&opts.InfraID, "infra-id", opts.InfraID, "Cluster ID with which to name the OCI resources (required)")
+	cmd.Flags().StringVar(&opts.VCNCIDR, "vcn-cidr", opts.VCNCIDR, "IP range of the VCN, the first half is the subnet of the nodes and the second half the subnet of the load balancers")
+	cmd.Flags().StringVar(&opts.ConfigFile, "oci-config", opts.ConfigFile, "Path to the OCI CLI configuration file with the API signing key")
+	cmd.Flags().StringVar(&opts.Profile, "profile", opts.Profile, "Profile of the OCI CLI configuration file")
+	cmd.Flags().StringVar(&opts.OutputFile, "output-file", opts.OutputFile, "Path to file that will contain output information from infra resources (optional)")
+
+	cmd.MarkFlagRequired("compartment-id")
+	cmd.MarkFlagRequired("infra-id")
+
+	l := log.Log
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		if err := opts.Run(cmd.Context(), l); err != nil {
+			l.Error(err, "Failed to create infrastructure")
+			return err
+		}
+		l.Info("Successfully created infrastructure")
+		return nil
+	}
+
+	return cmd
+}
+
+func (o *CreateInfraOptions) Run(ctx context.Context, l logr.Logger) error {
+	if _, _, err := splitCIDR(o.VCNCIDR); err != nil {
+		return err
+	}
+	client, region, err := newRESTClient(o.ConfigFile, o.Profile, o.Region)
+	if err != nil {
+		return err
+	}
+	o.Region = region
+	result, err := o.CreateInfra(ctx, l, client)
+	if err != nil {
+		return err
+	}
+	return o.Output(result)
+}
+
+func (o *CreateInfraOptions) Output(result *CreateInfraOutput) error {
+	out := os.Stdout
+	if len(o.OutputFile) > 0 {
+		var err error
+		out, err = os.Create(o.OutputFile)
+		if err != nil {
+			return fmt.Errorf("cannot create output file: %w", err)
+		}
+		defer out.Close()
+	}
+	outputBytes, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to serialize result: %w", err)
+	}
+	if _, err := out.Write(outputBytes); err != nil {
+		return fmt.Errorf("failed to write result: %w", err)
+	}
+	return nil
+}
+
+// splitCIDR splits an IPv4 range into its two halves.
+func splitCIDR(cidr string) (string, string, error) {
+	_, ipNet, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid VCN CIDR %q: %w", cidr, err)
+	}
+	ones, bits := ipNet.Mask.Size()
+	if bits != 32 || ones > 29 {
+		return "", "", fmt.Errorf("invalid VCN CIDR %q: must be an IPv4 range of at least /29", cidr)
+	}
+	second := make(net.IP, len(ipNet.IP.To4()))
+	copy(second, ipNet.IP.To4())
+	halfSize := uint32(1) << uint(bits-ones-1)
+	value := uint32(second[0])<<24 | uint32(second[1])<<16 | uint32(second[2])<<8 | uint32(second[3])
+	value += halfSize
+	second[0], second[1], second[2], second[3] = byte(value>>24), byte(value>>16), byte(value>>8), byte(value)
+	return fmt.Sprintf("%s/%d", ipNet.IP.To4(), ones+1), fmt.Sprintf("%s/%d", second, ones+1), nil
+}
+
+func vcnName(infraID string) string {
+	return infraID + "-vcn"
+}
+
+func internetGatewayName(infraID string) string {
+	return infraID + "-igw"
+}
+
+func natGatewayName(infraID string) string {
+	return infraID + "-nat"
+}
+
+func nodesRouteTableName(infraID string) string {
+	return infraID + "-nodes-rt"
+}
+
+func loadBalancerRouteTableName(infraID string) string {
+	return infraID + "-lb-rt"
+}
+
+func nodesSubnetName(infraID string) string {
+	return infraID + "-nodes"
+}
+
+func loadBalancerSubnetName(infraID string) string {
+	return infraID + "-lb"
+}
+
+func networkSecurityGroupName(infraID string) string {
+	return infraID + "-nodes-nsg"
+}
+
+// routeRule routes a destination to a gateway.
+type routeRule struct {
+	Destination     string `json:"destination"`
+	DestinationType string `json:"destinationType"`
+	NetworkEntityID string `json:"networkEntityId"`
+}
+
+// securityRule is a rule of a network security group.
+type securityRule struct {
+	Direction  string `json:"direction"`
+	Protocol   string `json:"protocol"`
+	Source     string `json:"source,omitempty"`
+	SourceType string `json:"sourceType,omitempty"`
+}
+
+// CreateInfra creates the VCN, gateways, route tables, subnets and node network
+// security group of the cluster, or reuses them if they already exist. The
+// display names of OCI resources are not unique, so the resources are looked
+// up by the names they are created with.
+func (o *CreateInfraOptions) CreateInfra(ctx context.Context, l logr.Logger, client restClient) (*CreateInfraOutput, error) {
+	nodesCIDR, loadBalancerCIDR, err := splitCIDR(o.VCNCIDR)
+	if err != nil {
+		return nil, err
+	}
+	result := &CreateInfraOutput{
+		InfraID:       o.InfraID,
+		Region:        o.Region,
+		CompartmentID: o.CompartmentID,
+		VCNCIDR:       o.VCNCIDR,
+	}
+
+	if result.VCNID, err = o.ensureResource(ctx, l, client, "vcns", "", vcnName(o.InfraID), map[string]interface{}{
+		"cidrBlocks": []string{o.VCNCIDR},
+	}); err != nil {
+		return nil, err
+	}
+
+	internetGatewayID, err := o.ensureResource(ctx, l, client, "internetGateways", result.VCNID, internetGatewayName(o.InfraID), map[string]interface{}{
+		"isEnabled": true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	natGatewayID, err := o.ensureResource(ctx, l, client, "natGateways", result.VCNID, natGatewayName(o.InfraID), map[string]interface{}{})
+	if err != nil {
+		return nil, err
+	}
+
+	// The nodes reach the internet through the NAT gateway, the load
+	// balancers are reachable from the internet through the internet gateway
+	nodesRouteTableID, err := o.ensureResource(ctx, l, client, "routeTables", result.VCNID, nodesRouteTableName(o.InfraID), map[string]interface{}{
+		"routeRules": []routeRule{{Destination: "0.0.0.0/0", DestinationType: "CIDR_BLOCK", NetworkEntityID: natGatewayID}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	loadBalancerRouteTableID, err := o.ensureResource(ctx, l, client, "routeTables", result.VCNID, loadBalancerRouteTableName(o.InfraID), map[string]interface{}{
+		"routeRules": []routeRule{{Destination: "0.0.0.0/0", DestinationType: "CIDR_BLOCK", NetworkEntityID: internetGatewayID}},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if result.SubnetID, err = o.ensureResource(ctx, l, client, "subnets", result.VCNID, nodesSubnetName(o.InfraID), map[string]interface{}{
+		"cidrBlock":              nodesCIDR,
+		"routeTableId":           nodesRouteTableID,
+		"prohibitPublicIpOnVnic": true,
+	}); err != nil {
+		return nil, err
+	}
+	if result.LoadBalancerSubnetID, err = o.ensureResource(ctx, l, client, "subnets", result.VCNID, loadBalancerSubnetName(o.InfraID), map[string]interface{}{
+		"cidrBlock":    loadBalancerCIDR,
+		"routeTableId": loadBalancerRouteTableID,
+	}); err != nil {
+		return nil, err
+	}
+
+	if result.NetworkSecurityGroupID, err = o.ensureNetworkSecurityGroup(ctx, l, client, result.VCNID); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// findResource returns the ID of the resource of the collection with the
+// display name, or an empty string if there is none. Resources that are being
+// deleted are ignored.
+func findResource(ctx context.Context, client restClient, collection, compartmentID, vcnID, name string) (string, error) {
+	query := url.Values{"compartmentId": {compartmentID}, "displayName": {name}}
+	if len(vcnID) > 0 {
+		query.Set("vcnId", vcnID)
+	}
+	var existing []resource
+	if err := client.do(ctx, http.MethodGet, fmt.Sprintf("/%s?%s", collection, query.Encode()), nil, &existing); err != nil {
+		return "", fmt.Errorf("failed to list %s: %w", collection, err)
+	}
+	for _, r := range existing {
+		if r.LifecycleState != "TERMINATING" && r.LifecycleState != lifecycleStateTerminated {
+			return r.ID, nil
+		}
+	}
+	return "", nil
+}
+
+// ensureResource creates the resource of the collection with the display name
+// and the fields of spec if it doesn't exist, and waits for it to be available.
+func (o *CreateInfraOptions) ensureResource(ctx context.Context, l logr.Logger, client restClient, collection, vcnID, name string, spec map[string]interface{}) (string, error) {
+	id, err := findResource(ctx, client, collection, o.CompartmentID, vcnID, name)
+	if err != nil {
+		return "", err
+	}
+	if len(id) > 0 {
+		l.Info("Found existing resource", "collection", collection, "name", name, "id", id)
+		return id, nil
+	}
+
+	spec["compartmentId"] = o.CompartmentID
+	spec["displayName"] = name
+	if len(vcnID) > 0 {
+		spec["vcnId"] = vcnID
+	}
+	created := &resource{}
+	if err := client.do(ctx, http.MethodPost, "/"+collection, spec, created); err != nil {
+		return "", fmt.Errorf("failed to create %s: %w", name, err)
+	}
+	if err := waitForState(ctx, client, fmt.Sprintf("/%s/%s", collection, created.ID), lifecycleStateAvailable); err != nil {
+		return "", err
+	}
+	l.Info("Created resource", "collection", collection, "name", name, "id", created.ID)
+	return created.ID, nil
+}
+
+// ensureNetworkSecurityGroup creates the network security group of the nodes,
+// which allows all traffic from the VCN.
+func (o *CreateInfraOptions) ensureNetworkSecurityGroup(ctx context.Context, l logr.Logger, client restClient, vcnID string) (string, error) {
+	name := networkSecurityGroupName(o.InfraID)
+	id, err := o.ensureResource(ctx, l, client, "networkSecurityGroups", vcnID, name, map[string]interface{}{})
+	if err != nil {
+		return "", err
+	}
+
+	var existing []securityRule
+	if err := client.do(ctx, http.MethodGet, fmt.Sprintf("/networkSecurityGroups/%s/securityRules", id), nil, &existing); err != nil {
+		return "", fmt.Errorf("failed to list the rules of network security group %s: %w", name, err)
+	}
+	rule := securityRule{Direction: "INGRESS", Protocol: "all", Source: o.VCNCIDR, SourceType: "CIDR_BLOCK"}
+	for _, r := range existing {
+		if r == rule {
+			return id, nil
+		}
+	}
+	if err := client.do(ctx, http.MethodPost, fmt.Sprintf("/networkSecurityGroups/%s/actions/addSecurityRules", id), map[string]interface{}{
+		"securityRules": []securityRule{rule},
+	}, nil); err != nil {
+		return "", fmt.Errorf("failed to create the rules of network security group %s: %w", name, err)
+	}
+	l.Info("Created network security group rule", "name", name, "cidr", o.VCNCIDR)
+	return id, nil
+}
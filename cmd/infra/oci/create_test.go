@@ -0,0 +1,200 @@
+package oci
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/gomega"
+)
+
+// fakeRESTClient is an in-memory restClient of the networking resources.
+type fakeRESTClient struct {
+	lastID    int
+	resources map[string]map[string]map[string]interface{}
+	rules     map[string][]securityRule
+}
+
+func newFakeRESTClient() *fakeRESTClient {
+	return &fakeRESTClient{
+		resources: map[string]map[string]map[string]interface{}{},
+		rules:     map[string][]securityRule{},
+	}
+}
+
+func roundTrip(in, out interface{}) error {
+	if out == nil {
+		return nil
+	}
+	raw, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, out)
+}
+
+func (c *fakeRESTClient) do(_ context.Context, method, path string, in, out interface{}) error {
+	u, err := url.Parse(path)
+	if err != nil {
+		return err
+	}
+	segments := strings.Split(strings.TrimPrefix(u.Path, "/"), "/")
+	collection := segments[0]
+	notFound := &apiError{StatusCode: http.StatusNotFound, Code: "NotAuthorizedOrNotFound"}
+
+	switch {
+	case len(segments) == 1 && method == http.MethodGet:
+		result := []map[string]interface{}{}
+		for _, item := range c.resources[collection] {
+			matches := true
+			for key := range u.Query() {
+				if item[key] != u.Query().Get(key) {
+					matches = false
+				}
+			}
+			if matches {
+				result = append(result, item)
+			}
+		}
+		return roundTrip(result, out)
+	case len(segments) == 1 && method == http.MethodPost:
+		item := map[string]interface{}{}
+		if err := roundTrip(in, &item); err != nil {
+			return err
+		}
+		c.lastID++
+		item["id"] = fmt.Sprintf("ocid1.%s.%d", strings.ToLower(collection), c.lastID)
+		item["lifecycleState"] = lifecycleStateAvailable
+		if c.resources[collection] == nil {
+			c.resources[collection] = map[string]map[string]interface{}{}
+		}
+		c.resources[collection][item["id"].(string)] = item
+		return roundTrip(item, out)
+	case len(segments) == 2 && method == http.MethodGet:
+		item, exists := c.resources[collection][segments[1]]
+		if !exists {
+			return notFound
+		}
+		return roundTrip(item, out)
+	case len(segments) == 2 && method == http.MethodDelete:
+		id := segments[1]
+		if _, exists := c.resources[collection][id]; !exists {
+			return notFound
+		}
+		for _, items := range c.resources {
+			for otherID, item := range items {
+				raw, err := json.Marshal(item)
+				if err != nil {
+					return err
+				}
+				if otherID != id && strings.Contains(string(raw), fmt.Sprintf("%q", id)) {
+					return &apiError{StatusCode: http.StatusConflict, Code: "Conflict", Message: fmt.Sprintf("%s is referenced by %s", id, otherID)}
+				}
+			}
+		}
+		delete(c.resources[collection], id)
+		delete(c.rules, id)
+		return nil
+	case collection == "networkSecurityGroups" && segments[2] == "securityRules":
+		return roundTrip(c.rules[segments[1]], out)
+	case collection == "networkSecurityGroups" && segments[3] == "addSecurityRules":
+		request := struct {
+			SecurityRules []securityRule `json:"securityRules"`
+		}{}
+		if err := roundTrip(in, &request); err != nil {
+			return err
+		}
+		c.rules[segments[1]] = append(c.rules[segments[1]], request.SecurityRules...)
+		return nil
+	}
+	return fmt.Errorf("unexpected request %s %s", method, path)
+}
+
+func (c *fakeRESTClient) count() int {
+	count := 0
+	for _, items := range c.resources {
+		count += len(items)
+	}
+	return count
+}
+
+func TestSplitCIDR(t *testing.T) {
+	g := NewGomegaWithT(t)
+	first, second, err := splitCIDR("10.0.0.0/16")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(first).To(Equal("10.0.0.0/17"))
+	g.Expect(second).To(Equal("10.0.128.0/17"))
+
+	first, second, err = splitCIDR("192.168.10.0/24")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(first).To(Equal("192.168.10.0/25"))
+	g.Expect(second).To(Equal("192.168.10.128/25"))
+
+	_, _, err = splitCIDR("10.0.0.0/30")
+	g.Expect(err).To(HaveOccurred())
+	_, _, err = splitCIDR("fd00::/64")
+	g.Expect(err).To(HaveOccurred())
+}
+
+func TestCreateInfra(t *testing.T) {
+	g := NewGomegaWithT(t)
+	client := newFakeRESTClient()
+	opts := &CreateInfraOptions{
+		CompartmentID: "ocid1.compartment.oc1..example",
+		Region:        "us-ashburn-1",
+		InfraID:       "example",
+		VCNCIDR:       defaultVCNCIDR,
+	}
+
+	result, err := opts.CreateInfra(context.Background(), logr.Discard(), client)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(client.count()).To(Equal(8))
+
+	vcn := client.resources["vcns"][result.VCNID]
+	g.Expect(vcn["displayName"]).To(Equal("example-vcn"))
+	g.Expect(vcn["compartmentId"]).To(Equal("ocid1.compartment.oc1..example"))
+
+	nodes := client.resources["subnets"][result.SubnetID]
+	g.Expect(nodes["cidrBlock"]).To(Equal("10.0.0.0/17"))
+	g.Expect(nodes["prohibitPublicIpOnVnic"]).To(BeTrue())
+	nodesRouteTable := client.resources["routeTables"][nodes["routeTableId"].(string)]
+	g.Expect(nodesRouteTable["displayName"]).To(Equal("example-nodes-rt"))
+
+	loadBalancers := client.resources["subnets"][result.LoadBalancerSubnetID]
+	g.Expect(loadBalancers["cidrBlock"]).To(Equal("10.0.128.0/17"))
+	g.Expect(loadBalancers).ToNot(HaveKey("prohibitPublicIpOnVnic"))
+
+	g.Expect(client.resources["networkSecurityGroups"][result.NetworkSecurityGroupID]["vcnId"]).To(Equal(result.VCNID))
+	g.Expect(client.rules[result.NetworkSecurityGroupID]).To(Equal([]securityRule{{Direction: "INGRESS", Protocol: "all", Source: defaultVCNCIDR, SourceType: "CIDR_BLOCK"}}))
+
+	// Creating the infrastructure again reuses the existing resources
+	again, err := opts.CreateInfra(context.Background(), logr.Discard(), client)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(again).To(Equal(result))
+	g.Expect(client.count()).To(Equal(8))
+	g.Expect(client.rules[result.NetworkSecurityGroupID]).To(HaveLen(1))
+}
+
+func TestDestroyInfra(t *testing.T) {
+	g := NewGomegaWithT(t)
+	client := newFakeRESTClient()
+	for _, infraID := range []string{"example", "other"} {
+		_, err := (&CreateInfraOptions{CompartmentID: "compartment", InfraID: infraID, VCNCIDR: defaultVCNCIDR}).CreateInfra(context.Background(), logr.Discard(), client)
+		g.Expect(err).ToNot(HaveOccurred())
+	}
+
+	opts := &DestroyInfraOptions{CompartmentID: "compartment", InfraID: "example"}
+	g.Expect(opts.DestroyInfra(context.Background(), logr.Discard(), client)).To(Succeed())
+	g.Expect(client.count()).To(Equal(8))
+	for _, vcn := range client.resources["vcns"] {
+		g.Expect(vcn["displayName"]).To(Equal("other-vcn"))
+	}
+
+	// Destroying the infrastructure again succeeds
+	g.Expect(opts.DestroyInfra(context.Background(), logr.Discard(), client)).To(Succeed())
+}
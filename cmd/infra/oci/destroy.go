@@ -0,0 +1,117 @@
+package oci
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/go-logr/logr"
+	"github.com/spf13/cobra"
+
+	"github.com/openshift/hypershift/cmd/log"
+)
+
+// DestroyInfraOptions command line options to destroy infra created in OCI
+type DestroyInfraOptions struct {
+	CompartmentID string
+	Region        string
+	InfraID       string
+	ConfigFile    string
+	Profile       string
+}
+
+func NewDestroyCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:          "oci",
+		Short:        "Destroys OCI infrastructure resources for a cluster",
+		SilenceUsage: true,
+	}
+
+	opts := DestroyInfraOptions{
+		ConfigFile: defaultConfigFile(),
+		Profile:    defaultProfile,
+	}
+
+	cmd.Flags().StringVar(&opts.CompartmentID, "compartment-id", opts.CompartmentID, "OCID of the compartment the resources were created in (required)")
+	cmd.Flags().StringVar(&opts.Region, "region", opts.Region, "OCI region of the cluster. Defaults to the region of the profile")
+	cmd.Flags().StringVar(&opts.InfraID, "infra-id", opts.InfraID, "Cluster ID the OCI resources are named with (required)")
+	cmd.Flags().StringVar(&opts.ConfigFile, "oci-config", opts.ConfigFile, "Path to the OCI CLI configuration file with the API signing key")
+	cmd.Flags().StringVar(&opts.Profile, "profile", opts.Profile, "Profile of the OCI CLI configuration file")
+
+	cmd.MarkFlagRequired("compartment-id")
+	cmd.MarkFlagRequired("infra-id")
+
+	l := log.Log
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		if err := opts.Run(cmd.Context(), l); err != nil {
+			l.Error(err, "Failed to destroy infrastructure")
+			return err
+		}
+		l.Info("Successfully destroyed infrastructure")
+		return nil
+	}
+
+	return cmd
+}
+
+func (o *DestroyInfraOptions) Run(ctx context.Context, l logr.Logger) error {
+	client, _, err := newRESTClient(o.ConfigFile, o.Profile, o.Region)
+	if err != nil {
+		return err
+	}
+	return o.DestroyInfra(ctx, l, client)
+}
+
+// DestroyInfra deletes the resources created by CreateInfra in the reverse
+// order, skipping the ones that don't exist. The subnets can only be deleted
+// once the instances and load balancers of the cluster are gone.
+func (o *DestroyInfraOptions) DestroyInfra(ctx context.Context, l logr.Logger, client restClient) error {
+	vcnID, err := findResource(ctx, client, "vcns", o.CompartmentID, "", vcnName(o.InfraID))
+	if err != nil {
+		return err
+	}
+	if len(vcnID) == 0 {
+		return nil
+	}
+
+	for _, r := range []struct {
+		collection string
+		name       string
+	}{
+		{collection: "networkSecurityGroups", name: networkSecurityGroupName(o.InfraID)},
+		{collection: "subnets", name: nodesSubnetName(o.InfraID)},
+		{collection: "subnets", name: loadBalancerSubnetName(o.InfraID)},
+		{collection: "routeTables", name: nodesRouteTableName(o.InfraID)},
+		{collection: "routeTables", name: loadBalancerRouteTableName(o.InfraID)},
+		{collection: "natGateways", name: natGatewayName(o.InfraID)},
+		{collection: "internetGateways", name: internetGatewayName(o.InfraID)},
+	} {
+		id, err := findResource(ctx, client, r.collection, o.CompartmentID, vcnID, r.name)
+		if err != nil {
+			return err
+		}
+		if len(id) == 0 {
+			continue
+		}
+		if err := deleteResource(ctx, client, r.collection, id); err != nil {
+			return fmt.Errorf("failed to delete %s: %w", r.name, err)
+		}
+		l.Info("Deleted resource", "collection", r.collection, "name", r.name, "id", id)
+	}
+
+	if err := deleteResource(ctx, client, "vcns", vcnID); err != nil {
+		return fmt.Errorf("failed to delete %s: %w", vcnName(o.InfraID), err)
+	}
+	l.Info("Deleted resource", "collection", "vcns", "name", vcnName(o.InfraID), "id", vcnID)
+	return nil
+}
+
+// deleteResource deletes the resource and waits for it to be terminated, the
+// resources that depend on it can't be deleted before.
+func deleteResource(ctx context.Context, client restClient, collection, id string) error {
+	path := fmt.Sprintf("/%s/%s", collection, id)
+	if err := client.do(ctx, http.MethodDelete, path, nil, nil); err != nil && !isNotFound(err) {
+		return err
+	}
+	return waitForState(ctx, client, path, lifecycleStateTerminated)
+}
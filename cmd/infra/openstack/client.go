@@ -0,0 +1,153 @@
+package openstack
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/gophercloud/gophercloud"
+	"github.com/gophercloud/gophercloud/openstack"
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/extensions/layer3/routers"
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/extensions/security/groups"
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/extensions/security/rules"
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/networks"
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/ports"
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/subnets"
+)
+
+// networkingClient is the subset of the Neutron API the infra commands use.
+type networkingClient interface {
+	listNetworks(opts networks.ListOpts) ([]networks.Network, error)
+	createNetwork(opts networks.CreateOpts) (*networks.Network, error)
+	deleteNetwork(id string) error
+
+	listSubnets(opts subnets.ListOpts) ([]subnets.Subnet, error)
+	createSubnet(opts subnets.CreateOpts) (*subnets.Subnet, error)
+	deleteSubnet(id string) error
+
+	listRouters(opts routers.ListOpts) ([]routers.Router, error)
+	createRouter(opts routers.CreateOpts) (*routers.Router, error)
+	addRouterInterface(routerID string, opts routers.AddInterfaceOpts) error
+	removeRouterInterface(routerID string, opts routers.RemoveInterfaceOpts) error
+	deleteRouter(id string) error
+
+	listPorts(opts ports.ListOpts) ([]ports.Port, error)
+
+	listSecurityGroups(opts groups.ListOpts) ([]groups.SecGroup, error)
+	createSecurityGroup(opts groups.CreateOpts) (*groups.SecGroup, error)
+	deleteSecurityGroup(id string) error
+	createSecurityGroupRule(opts rules.CreateOpts) (*rules.SecGroupRule, error)
+}
+
+// newNetworkingClient returns a client of the Neutron API of the cloud the
+// standard OS_* environment variables of an OpenStack RC file authenticate with.
+func newNetworkingClient(region string) (networkingClient, error) {
+	authOptions, err := openstack.AuthOptionsFromEnv()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the OpenStack credentials from the environment: %w", err)
+	}
+	provider, err := openstack.AuthenticatedClient(authOptions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to authenticate with OpenStack: %w", err)
+	}
+	client, err := openstack.NewNetworkV2(provider, gophercloud.EndpointOpts{Region: region})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the networking client: %w", err)
+	}
+	return &gophercloudNetworkingClient{client: client}, nil
+}
+
+func isNotFound(err error) bool {
+	var notFound gophercloud.ErrDefault404
+	return errors.As(err, &notFound)
+}
+
+// gophercloudNetworkingClient is a networkingClient backed by gophercloud.
+type gophercloudNetworkingClient struct {
+	client *gophercloud.ServiceClient
+}
+
+func (c *gophercloudNetworkingClient) listNetworks(opts networks.ListOpts) ([]networks.Network, error) {
+	pages, err := networks.List(c.client, opts).AllPages()
+	if err != nil {
+		return nil, err
+	}
+	return networks.ExtractNetworks(pages)
+}
+
+func (c *gophercloudNetworkingClient) createNetwork(opts networks.CreateOpts) (*networks.Network, error) {
+	return networks.Create(c.client, opts).Extract()
+}
+
+func (c *gophercloudNetworkingClient) deleteNetwork(id string) error {
+	return networks.Delete(c.client, id).ExtractErr()
+}
+
+func (c *gophercloudNetworkingClient) listSubnets(opts subnets.ListOpts) ([]subnets.Subnet, error) {
+	pages, err := subnets.List(c.client, opts).AllPages()
+	if err != nil {
+		return nil, err
+	}
+	return subnets.ExtractSubnets(pages)
+}
+
+func (c *gophercloudNetworkingClient) createSubnet(opts subnets.CreateOpts) (*subnets.Subnet, error) {
+	return subnets.Create(c.client, opts).Extract()
+}
+
+func (c *gophercloudNetworkingClient) deleteSubnet(id string) error {
+	return subnets.Delete(c.client, id).ExtractErr()
+}
+
+func (c *gophercloudNetworkingClient) listRouters(opts routers.ListOpts) ([]routers.Router, error) {
+	pages, err := routers.List(c.client, opts).AllPages()
+	if err != nil {
+		return nil, err
+	}
+	return routers.ExtractRouters(pages)
+}
+
+func (c *gophercloudNetworkingClient) createRouter(opts routers.CreateOpts) (*routers.Router, error) {
+	return routers.Create(c.client, opts).Extract()
+}
+
+func (c *gophercloudNetworkingClient) addRouterInterface(routerID string, opts routers.AddInterfaceOpts) error {
+	_, err := routers.AddInterface(c.client, routerID, opts).Extract()
+	return err
+}
+
+func (c *gophercloudNetworkingClient) removeRouterInterface(routerID string, opts routers.RemoveInterfaceOpts) error {
+	_, err := routers.RemoveInterface(c.client, routerID, opts).Extract()
+	return err
+}
+
+func (c *gophercloudNetworkingClient) deleteRouter(id string) error {
+	return routers.Delete(c.client, id).ExtractErr()
+}
+
+func (c *gophercloudNetworkingClient) listPorts(opts ports.ListOpts) ([]ports.Port, error) {
+	pages, err := ports.List(c.client, opts).AllPages()
+	if err != nil {
+		return nil, err
+	}
+	return ports.ExtractPorts(pages)
+}
+
+func (c *gophercloudNetworkingClient) listSecurityGroups(opts groups.ListOpts) ([]groups.SecGroup, error) {
+	pages, err := groups.List(c.client, opts).AllPages()
+	if err != nil {
+		return nil, err
+	}
+	return groups.ExtractGroups(pages)
+}
+
+func (c *gophercloudNetworkingClient) createSecurityGroup(opts groups.CreateOpts) (*groups.SecGroup, error) {
+	return groups.Create(c.client, opts).Extract()
+}
+
+func (c *gophercloudNetworkingClient) deleteSecurityGroup(id string) error {
+	return groups.Delete(c.client, id).ExtractErr()
+}
+
+func (c *gophercloudNetworkingClient) createSecurityGroupRule(opts rules.CreateOpts) (*rules.SecGroupRule, error) {
+	return rules.Create(c.client, opts).Extract()
+}
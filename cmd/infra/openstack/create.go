@@ -0,0 +1,303 @@
+package openstack
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+
+	"github.com/go-logr/logr"
+	"github.com/gophercloud/gophercloud"
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/extensions/layer3/routers"
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/extensions/security/groups"
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/extensions/security/rules"
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/networks"
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/ports"
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/subnets"
+	"github.com/spf13/cobra"
+
+	"github.com/openshift/hypershift/cmd/log"
+)
+
+const (
+	defaultMachineCIDR = "10.0.0.0/16"
+	// routerInterfaceOwner is the device owner of the ports of router interfaces.
+	routerInterfaceOwner = "network:router_interface"
+)
+
+// CreateInfraOptions command line options for setting up infra in OpenStack
+type CreateInfraOptions struct {
+	InfraID           string
+	Region            string
+	ExternalNetworkID string
+	MachineCIDR       string
+	DNSNameservers    []string
+	OutputFile        string
+}
+
+// CreateInfraOutput is the infrastructure created for a cluster in OpenStack
+type CreateInfraOutput struct {
+	InfraID           string `json:"infraID"`
+	Region            string `json:"region,omitempty"`
+	MachineCIDR       string `json:"machineCIDR"`
+	ExternalNetworkID string `json:"externalNetworkID"`
+	NetworkID         string `json:"networkID"`
+	SubnetID          string `json:"subnetID"`
+	RouterID          string `json:"routerID"`
+	SecurityGroupID   string `json:"securityGroupID"`
+}
+
+func NewCreateCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:          "openstack",
+		Short:        "Creates OpenStack infrastructure resources for a cluster",
+		SilenceUsage: true,
+	}
+
+	opts := CreateInfraOptions{
+		Region:      os.Getenv("OS_REGION_NAME"),
+		MachineCIDR: defaultMachineCIDR,
+	}
+
+	cmd.Flags().StringVar(&opts.InfraID, "infra-id", opts.InfraID, "Cluster ID with which to name the OpenStack resources (required)")
+	cmd.Flags().StringVar(&opts.Region, "region", opts.Region, "OpenStack region of the cluster. Defaults to OS_REGION_NAME")
+	cmd.Flags().StringVar(&opts.ExternalNetworkID, "external-network-id", opts.ExternalNetworkID, "ID of the external network the router of the cluster is connected to (required)")
+	cmd.Flags().StringVar(&opts.MachineCIDR, "machine-cidr", opts.MachineCIDR, "IP range of the subnet of the nodes")
+	cmd.Flags().StringSliceVar(&opts.DNSNameservers, "dns-nameservers", opts.DNSNameservers, "DNS servers of the subnet of the nodes. Defaults to the DNS servers of the cloud")
+	cmd.Flags().StringVar(&opts.OutputFile, "output-file", opts.OutputFile, "Path to file that will contain output information from infra resources (optional)")
+
+	cmd.MarkFlagRequired("infra-id")
+	cmd.MarkFlagRequired("external-network-id")
+
+	l := log.Log
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		if err := opts.Run(cmd.Context(), l); err != nil {
+			l.Error(err, "Failed to create infrastructure")
+			return err
+		}
+		l.Info("Successfully created infrastructure")
+		return nil
+	}
+
+	return cmd
+}
+
+func (o *CreateInfraOptions) Run(ctx context.Context, l logr.Logger) error {
+	if _, _, err := net.ParseCIDR(o.MachineCIDR); err != nil {
+		return fmt.Errorf("invalid machine CIDR %q: %w", o.MachineCIDR, err)
+	}
+	client, err := newNetworkingClient(o.Region)
+	if err != nil {
+		return err
+	}
+	result, err := o.CreateInfra(ctx, l, client)
+	if err != nil {
+		return err
+	}
+	return o.Output(result)
+}
+
+func (o *CreateInfraOptions) Output(result *CreateInfraOutput) error {
+	out := os.Stdout
+	if len(o.OutputFile) > 0 {
+		var err error
+		out, err = os.Create(o.OutputFile)
+		if err != nil {
+			return fmt.Errorf("cannot create output file: %w", err)
+		}
+		defer out.Close()
+	}
+	outputBytes, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to serialize result: %w", err)
+	}
+	if _, err := out.Write(outputBytes); err != nil {
+		return fmt.Errorf("failed to write result: %w", err)
+	}
+	return nil
+}
+
+func networkName(infraID string) string {
+	return infraID + "-network"
+}
+
+func subnetName(infraID string) string {
+	return infraID + "-subnet"
+}
+
+func routerName(infraID string) string {
+	return infraID + "-router"
+}
+
+func securityGroupName(infraID string) string {
+	return infraID + "-nodes"
+}
+
+func (o *CreateInfraOptions) description() string {
+	return fmt.Sprintf("Created by HyperShift for cluster %s", o.InfraID)
+}
+
+// CreateInfra creates the network, subnet, router and node security group of the
+// cluster, or reuses them if they already exist. Neutron doesn't enforce unique
+// names, so the resources are looked up by the names they are created with.
+func (o *CreateInfraOptions) CreateInfra(ctx context.Context, l logr.Logger, client networkingClient) (*CreateInfraOutput, error) {
+	result := &CreateInfraOutput{
+		InfraID:           o.InfraID,
+		Region:            o.Region,
+		MachineCIDR:       o.MachineCIDR,
+		ExternalNetworkID: o.ExternalNetworkID,
+	}
+
+	networkID, err := o.ensureNetwork(l, client)
+	if err != nil {
+		return nil, err
+	}
+	result.NetworkID = networkID
+
+	subnetID, err := o.ensureSubnet(l, client, networkID)
+	if err != nil {
+		return nil, err
+	}
+	result.SubnetID = subnetID
+
+	routerID, err := o.ensureRouter(l, client, networkID, subnetID)
+	if err != nil {
+		return nil, err
+	}
+	result.RouterID = routerID
+
+	securityGroupID, err := o.ensureSecurityGroup(l, client)
+	if err != nil {
+		return nil, err
+	}
+	result.SecurityGroupID = securityGroupID
+
+	return result, nil
+}
+
+func (o *CreateInfraOptions) ensureNetwork(l logr.Logger, client networkingClient) (string, error) {
+	name := networkName(o.InfraID)
+	existing, err := client.listNetworks(networks.ListOpts{Name: name})
+	if err != nil {
+		return "", fmt.Errorf("failed to list networks: %w", err)
+	}
+	if len(existing) > 0 {
+		l.Info("Found existing network", "name", name, "id", existing[0].ID)
+		return existing[0].ID, nil
+	}
+	network, err := client.createNetwork(networks.CreateOpts{Name: name, Description: o.description()})
+	if err != nil {
+		return "", fmt.Errorf("failed to create network %s: %w", name, err)
+	}
+	l.Info("Created network", "name", name, "id", network.ID)
+	return network.ID, nil
+}
+
+func (o *CreateInfraOptions) ensureSubnet(l logr.Logger, client networkingClient, networkID string) (string, error) {
+	name := subnetName(o.InfraID)
+	existing, err := client.listSubnets(subnets.ListOpts{Name: name, NetworkID: networkID})
+	if err != nil {
+		return "", fmt.Errorf("failed to list subnets: %w", err)
+	}
+	if len(existing) > 0 {
+		l.Info("Found existing subnet", "name", name, "id", existing[0].ID)
+		return existing[0].ID, nil
+	}
+	subnet, err := client.createSubnet(subnets.CreateOpts{
+		Name:           name,
+		Description:    o.description(),
+		NetworkID:      networkID,
+		CIDR:           o.MachineCIDR,
+		IPVersion:      gophercloud.IPv4,
+		DNSNameservers: o.DNSNameservers,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to create subnet %s: %w", name, err)
+	}
+	l.Info("Created subnet", "name", name, "id", subnet.ID)
+	return subnet.ID, nil
+}
+
+// ensureRouter creates a router with a gateway on the external network, and
+// connects the subnet to it so that the nodes can reach the internet and the
+// floating IPs of load balancers can be routed to the subnet.
+func (o *CreateInfraOptions) ensureRouter(l logr.Logger, client networkingClient, networkID, subnetID string) (string, error) {
+	name := routerName(o.InfraID)
+	existing, err := client.listRouters(routers.ListOpts{Name: name})
+	if err != nil {
+		return "", fmt.Errorf("failed to list routers: %w", err)
+	}
+	var routerID string
+	if len(existing) > 0 {
+		routerID = existing[0].ID
+		l.Info("Found existing router", "name", name, "id", routerID)
+	} else {
+		router, err := client.createRouter(routers.CreateOpts{
+			Name:        name,
+			Description: o.description(),
+			GatewayInfo: &routers.GatewayInfo{NetworkID: o.ExternalNetworkID},
+		})
+		if err != nil {
+			return "", fmt.Errorf("failed to create router %s: %w", name, err)
+		}
+		routerID = router.ID
+		l.Info("Created router", "name", name, "id", routerID)
+	}
+
+	interfaces, err := client.listPorts(ports.ListOpts{DeviceID: routerID, DeviceOwner: routerInterfaceOwner, NetworkID: networkID})
+	if err != nil {
+		return "", fmt.Errorf("failed to list the interfaces of router %s: %w", name, err)
+	}
+	for _, port := range interfaces {
+		for _, ip := range port.FixedIPs {
+			if ip.SubnetID == subnetID {
+				return routerID, nil
+			}
+		}
+	}
+	if err := client.addRouterInterface(routerID, routers.AddInterfaceOpts{SubnetID: subnetID}); err != nil {
+		return "", fmt.Errorf("failed to add the subnet to router %s: %w", name, err)
+	}
+	l.Info("Added subnet to router", "router", name, "subnet", subnetID)
+	return routerID, nil
+}
+
+// ensureSecurityGroup creates the security group of the nodes, which allows all
+// traffic from the subnet of the nodes. The load balancers of services are
+// created in the subnet too.
+func (o *CreateInfraOptions) ensureSecurityGroup(l logr.Logger, client networkingClient) (string, error) {
+	name := securityGroupName(o.InfraID)
+	existing, err := client.listSecurityGroups(groups.ListOpts{Name: name})
+	if err != nil {
+		return "", fmt.Errorf("failed to list security groups: %w", err)
+	}
+	var group *groups.SecGroup
+	if len(existing) > 0 {
+		group = &existing[0]
+		l.Info("Found existing security group", "name", name, "id", group.ID)
+	} else {
+		group, err = client.createSecurityGroup(groups.CreateOpts{Name: name, Description: o.description()})
+		if err != nil {
+			return "", fmt.Errorf("failed to create security group %s: %w", name, err)
+		}
+		l.Info("Created security group", "name", name, "id", group.ID)
+	}
+
+	for _, rule := range group.Rules {
+		if rule.Direction == string(rules.DirIngress) && rule.Protocol == "" && rule.RemoteIPPrefix == o.MachineCIDR {
+			return group.ID, nil
+		}
+	}
+	if _, err := client.createSecurityGroupRule(rules.CreateOpts{
+		SecGroupID:     group.ID,
+		Description:    "Traffic from the nodes and load balancers",
+		Direction:      rules.DirIngress,
+		EtherType:      rules.EtherType4,
+		RemoteIPPrefix: o.MachineCIDR,
+	}); err != nil {
+		return "", fmt.Errorf("failed to create the rules of security group %s: %w", name, err)
+	}
+	l.Info("Created security group rule", "name", name, "cidr", o.MachineCIDR)
+	return group.ID, nil
+}
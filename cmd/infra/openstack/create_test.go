@@ -0,0 +1,274 @@
+package openstack
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/gophercloud/gophercloud"
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/extensions/layer3/routers"
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/extensions/security/groups"
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/extensions/security/rules"
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/networks"
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/ports"
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/subnets"
+	. "github.com/onsi/gomega"
+)
+
+// fakeNetworkingClient is an in-memory networkingClient.
+type fakeNetworkingClient struct {
+	lastID   int
+	networks map[string]networks.Network
+	subnets  map[string]subnets.Subnet
+	routers  map[string]routers.Router
+	ports    map[string]ports.Port
+	groups   map[string]groups.SecGroup
+}
+
+func newFakeNetworkingClient() *fakeNetworkingClient {
+	return &fakeNetworkingClient{
+		networks: map[string]networks.Network{},
+		subnets:  map[string]subnets.Subnet{},
+		routers:  map[string]routers.Router{},
+		ports:    map[string]ports.Port{},
+		groups:   map[string]groups.SecGroup{},
+	}
+}
+
+func (c *fakeNetworkingClient) nextID() string {
+	c.lastID++
+	return fmt.Sprintf("id-%d", c.lastID)
+}
+
+func notFound() error {
+	return gophercloud.ErrDefault404{}
+}
+
+func (c *fakeNetworkingClient) listNetworks(opts networks.ListOpts) ([]networks.Network, error) {
+	var result []networks.Network
+	for _, network := range c.networks {
+		if network.Name == opts.Name {
+			result = append(result, network)
+		}
+	}
+	return result, nil
+}
+
+func (c *fakeNetworkingClient) createNetwork(opts networks.CreateOpts) (*networks.Network, error) {
+	network := networks.Network{ID: c.nextID(), Name: opts.Name, Description: opts.Description}
+	c.networks[network.ID] = network
+	return &network, nil
+}
+
+func (c *fakeNetworkingClient) deleteNetwork(id string) error {
+	if _, exists := c.networks[id]; !exists {
+		return notFound()
+	}
+	for _, subnet := range c.subnets {
+		if subnet.NetworkID == id {
+			return fmt.Errorf("network %s has subnet %s", id, subnet.ID)
+		}
+	}
+	delete(c.networks, id)
+	return nil
+}
+
+func (c *fakeNetworkingClient) listSubnets(opts subnets.ListOpts) ([]subnets.Subnet, error) {
+	var result []subnets.Subnet
+	for _, subnet := range c.subnets {
+		if subnet.Name == opts.Name && subnet.NetworkID == opts.NetworkID {
+			result = append(result, subnet)
+		}
+	}
+	return result, nil
+}
+
+func (c *fakeNetworkingClient) createSubnet(opts subnets.CreateOpts) (*subnets.Subnet, error) {
+	subnet := subnets.Subnet{ID: c.nextID(), Name: opts.Name, NetworkID: opts.NetworkID, CIDR: opts.CIDR, DNSNameservers: opts.DNSNameservers}
+	c.subnets[subnet.ID] = subnet
+	return &subnet, nil
+}
+
+func (c *fakeNetworkingClient) deleteSubnet(id string) error {
+	if _, exists := c.subnets[id]; !exists {
+		return notFound()
+	}
+	for _, port := range c.ports {
+		for _, ip := range port.FixedIPs {
+			if ip.SubnetID == id {
+				return fmt.Errorf("subnet %s is in use by port %s", id, port.ID)
+			}
+		}
+	}
+	delete(c.subnets, id)
+	return nil
+}
+
+func (c *fakeNetworkingClient) listRouters(opts routers.ListOpts) ([]routers.Router, error) {
+	var result []routers.Router
+	for _, router := range c.routers {
+		if router.Name == opts.Name {
+			result = append(result, router)
+		}
+	}
+	return result, nil
+}
+
+func (c *fakeNetworkingClient) createRouter(opts routers.CreateOpts) (*routers.Router, error) {
+	router := routers.Router{ID: c.nextID(), Name: opts.Name, GatewayInfo: *opts.GatewayInfo}
+	c.routers[router.ID] = router
+	return &router, nil
+}
+
+func (c *fakeNetworkingClient) addRouterInterface(routerID string, opts routers.AddInterfaceOpts) error {
+	subnet, exists := c.subnets[opts.SubnetID]
+	if !exists {
+		return notFound()
+	}
+	port := ports.Port{
+		ID:          c.nextID(),
+		NetworkID:   subnet.NetworkID,
+		DeviceID:    routerID,
+		DeviceOwner: routerInterfaceOwner,
+		FixedIPs:    []ports.IP{{SubnetID: subnet.ID}},
+	}
+	c.ports[port.ID] = port
+	return nil
+}
+
+func (c *fakeNetworkingClient) removeRouterInterface(routerID string, opts routers.RemoveInterfaceOpts) error {
+	if _, exists := c.ports[opts.PortID]; !exists {
+		return notFound()
+	}
+	delete(c.ports, opts.PortID)
+	return nil
+}
+
+func (c *fakeNetworkingClient) deleteRouter(id string) error {
+	if _, exists := c.routers[id]; !exists {
+		return notFound()
+	}
+	for _, port := range c.ports {
+		if port.DeviceID == id {
+			return fmt.Errorf("router %s has interface %s", id, port.ID)
+		}
+	}
+	delete(c.routers, id)
+	return nil
+}
+
+func (c *fakeNetworkingClient) listPorts(opts ports.ListOpts) ([]ports.Port, error) {
+	var result []ports.Port
+	for _, port := range c.ports {
+		if port.DeviceID == opts.DeviceID && port.DeviceOwner == opts.DeviceOwner && (opts.NetworkID == "" || port.NetworkID == opts.NetworkID) {
+			result = append(result, port)
+		}
+	}
+	return result, nil
+}
+
+func (c *fakeNetworkingClient) listSecurityGroups(opts groups.ListOpts) ([]groups.SecGroup, error) {
+	var result []groups.SecGroup
+	for _, group := range c.groups {
+		if group.Name == opts.Name {
+			result = append(result, group)
+		}
+	}
+	return result, nil
+}
+
+func (c *fakeNetworkingClient) createSecurityGroup(opts groups.CreateOpts) (*groups.SecGroup, error) {
+	group := groups.SecGroup{ID: c.nextID(), Name: opts.Name, Description: opts.Description}
+	c.groups[group.ID] = group
+	return &group, nil
+}
+
+func (c *fakeNetworkingClient) deleteSecurityGroup(id string) error {
+	if _, exists := c.groups[id]; !exists {
+		return notFound()
+	}
+	delete(c.groups, id)
+	return nil
+}
+
+func (c *fakeNetworkingClient) createSecurityGroupRule(opts rules.CreateOpts) (*rules.SecGroupRule, error) {
+	group, exists := c.groups[opts.SecGroupID]
+	if !exists {
+		return nil, notFound()
+	}
+	rule := rules.SecGroupRule{
+		ID:             c.nextID(),
+		Direction:      string(opts.Direction),
+		EtherType:      string(opts.EtherType),
+		SecGroupID:     opts.SecGroupID,
+		Protocol:       string(opts.Protocol),
+		RemoteIPPrefix: opts.RemoteIPPrefix,
+	}
+	group.Rules = append(group.Rules, rule)
+	c.groups[group.ID] = group
+	return &rule, nil
+}
+
+func TestCreateInfra(t *testing.T) {
+	g := NewGomegaWithT(t)
+	client := newFakeNetworkingClient()
+	opts := &CreateInfraOptions{
+		InfraID:           "example",
+		ExternalNetworkID: "external",
+		MachineCIDR:       "10.1.0.0/16",
+		DNSNameservers:    []string{"10.0.0.2"},
+	}
+
+	result, err := opts.CreateInfra(context.Background(), logr.Discard(), client)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(client.networks).To(HaveKey(result.NetworkID))
+	g.Expect(client.networks[result.NetworkID].Name).To(Equal("example-network"))
+
+	subnet := client.subnets[result.SubnetID]
+	g.Expect(subnet.Name).To(Equal("example-subnet"))
+	g.Expect(subnet.NetworkID).To(Equal(result.NetworkID))
+	g.Expect(subnet.CIDR).To(Equal("10.1.0.0/16"))
+	g.Expect(subnet.DNSNameservers).To(Equal([]string{"10.0.0.2"}))
+
+	router := client.routers[result.RouterID]
+	g.Expect(router.Name).To(Equal("example-router"))
+	g.Expect(router.GatewayInfo.NetworkID).To(Equal("external"))
+	g.Expect(client.ports).To(HaveLen(1))
+
+	group := client.groups[result.SecurityGroupID]
+	g.Expect(group.Name).To(Equal("example-nodes"))
+	g.Expect(group.Rules).To(HaveLen(1))
+	g.Expect(group.Rules[0].Direction).To(Equal("ingress"))
+	g.Expect(group.Rules[0].RemoteIPPrefix).To(Equal("10.1.0.0/16"))
+
+	// Creating the infrastructure again reuses the existing resources
+	again, err := opts.CreateInfra(context.Background(), logr.Discard(), client)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(again).To(Equal(result))
+	g.Expect(client.ports).To(HaveLen(1))
+	g.Expect(client.groups[result.SecurityGroupID].Rules).To(HaveLen(1))
+}
+
+func TestDestroyInfra(t *testing.T) {
+	g := NewGomegaWithT(t)
+	client := newFakeNetworkingClient()
+	for _, infraID := range []string{"example", "other"} {
+		_, err := (&CreateInfraOptions{InfraID: infraID, ExternalNetworkID: "external", MachineCIDR: defaultMachineCIDR}).CreateInfra(context.Background(), logr.Discard(), client)
+		g.Expect(err).ToNot(HaveOccurred())
+	}
+
+	opts := &DestroyInfraOptions{InfraID: "example"}
+	g.Expect(opts.DestroyInfra(context.Background(), logr.Discard(), client)).To(Succeed())
+	g.Expect(client.networks).To(HaveLen(1))
+	g.Expect(client.subnets).To(HaveLen(1))
+	g.Expect(client.routers).To(HaveLen(1))
+	g.Expect(client.ports).To(HaveLen(1))
+	g.Expect(client.groups).To(HaveLen(1))
+	for _, network := range client.networks {
+		g.Expect(network.Name).To(Equal("other-network"))
+	}
+
+	// Destroying the infrastructure again succeeds
+	g.Expect(opts.DestroyInfra(context.Background(), logr.Discard(), client)).To(Succeed())
+}
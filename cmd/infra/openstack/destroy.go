@@ -0,0 +1,119 @@
+package openstack
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/go-logr/logr"
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/extensions/layer3/routers"
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/extensions/security/groups"
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/networks"
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/ports"
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/subnets"
+	"github.com/spf13/cobra"
+
+	"github.com/openshift/hypershift/cmd/log"
+)
+
+// DestroyInfraOptions command line options to destroy infra created in OpenStack
+type DestroyInfraOptions struct {
+	InfraID string
+	Region  string
+}
+
+func NewDestroyCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:          "openstack",
+		Short:        "Destroys OpenStack infrastructure resources for a cluster",
+		SilenceUsage: true,
+	}
+
+	opts := DestroyInfraOptions{
+		Region: os.Getenv("OS_REGION_NAME"),
+	}
+
+	cmd.Flags().StringVar(&opts.InfraID, "infra-id", opts.InfraID, "Cluster ID the OpenStack resources are named with (required)")
+	cmd.Flags().StringVar(&opts.Region, "region", opts.Region, "OpenStack region of the cluster. Defaults to OS_REGION_NAME")
+
+	cmd.MarkFlagRequired("infra-id")
+
+	l := log.Log
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		if err := opts.Run(cmd.Context(), l); err != nil {
+			l.Error(err, "Failed to destroy infrastructure")
+			return err
+		}
+		l.Info("Successfully destroyed infrastructure")
+		return nil
+	}
+
+	return cmd
+}
+
+func (o *DestroyInfraOptions) Run(ctx context.Context, l logr.Logger) error {
+	client, err := newNetworkingClient(o.Region)
+	if err != nil {
+		return err
+	}
+	return o.DestroyInfra(ctx, l, client)
+}
+
+// DestroyInfra deletes the resources created by CreateInfra in the reverse
+// order, skipping the ones that don't exist. The network can only be deleted
+// once the instances and load balancers of the cluster are gone.
+func (o *DestroyInfraOptions) DestroyInfra(ctx context.Context, l logr.Logger, client networkingClient) error {
+	existingGroups, err := client.listSecurityGroups(groups.ListOpts{Name: securityGroupName(o.InfraID)})
+	if err != nil {
+		return fmt.Errorf("failed to list security groups: %w", err)
+	}
+	for _, group := range existingGroups {
+		if err := client.deleteSecurityGroup(group.ID); err != nil && !isNotFound(err) {
+			return fmt.Errorf("failed to delete security group %s: %w", group.ID, err)
+		}
+		l.Info("Deleted security group", "id", group.ID)
+	}
+
+	existingNetworks, err := client.listNetworks(networks.ListOpts{Name: networkName(o.InfraID)})
+	if err != nil {
+		return fmt.Errorf("failed to list networks: %w", err)
+	}
+
+	existingRouters, err := client.listRouters(routers.ListOpts{Name: routerName(o.InfraID)})
+	if err != nil {
+		return fmt.Errorf("failed to list routers: %w", err)
+	}
+	for _, router := range existingRouters {
+		interfaces, err := client.listPorts(ports.ListOpts{DeviceID: router.ID, DeviceOwner: routerInterfaceOwner})
+		if err != nil {
+			return fmt.Errorf("failed to list the interfaces of router %s: %w", router.ID, err)
+		}
+		for _, port := range interfaces {
+			if err := client.removeRouterInterface(router.ID, routers.RemoveInterfaceOpts{PortID: port.ID}); err != nil && !isNotFound(err) {
+				return fmt.Errorf("failed to remove interface %s from router %s: %w", port.ID, router.ID, err)
+			}
+		}
+		if err := client.deleteRouter(router.ID); err != nil && !isNotFound(err) {
+			return fmt.Errorf("failed to delete router %s: %w", router.ID, err)
+		}
+		l.Info("Deleted router", "id", router.ID)
+	}
+
+	for _, network := range existingNetworks {
+		existingSubnets, err := client.listSubnets(subnets.ListOpts{Name: subnetName(o.InfraID), NetworkID: network.ID})
+		if err != nil {
+			return fmt.Errorf("failed to list subnets: %w", err)
+		}
+		for _, subnet := range existingSubnets {
+			if err := client.deleteSubnet(subnet.ID); err != nil && !isNotFound(err) {
+				return fmt.Errorf("failed to delete subnet %s: %w", subnet.ID, err)
+			}
+			l.Info("Deleted subnet", "id", subnet.ID)
+		}
+		if err := client.deleteNetwork(network.ID); err != nil && !isNotFound(err) {
+			return fmt.Errorf("failed to delete network %s: %w", network.ID, err)
+		}
+		l.Info("Deleted network", "id", network.ID)
+	}
+	return nil
+}
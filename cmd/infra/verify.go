@@ -0,0 +1,19 @@
+package infra
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/openshift/hypershift/cmd/infra/aws"
+)
+
+func NewVerifyCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:          "infra",
+		Short:        "Commands for verifying HyperShift infra resources",
+		SilenceUsage: true,
+	}
+
+	cmd.AddCommand(aws.NewVerifyCommand())
+
+	return cmd
+}
@@ -63,6 +63,7 @@ func (o HyperShiftNamespace) Build() *corev1.Namespace {
 
 const (
 	awsCredsSecretName            = "hypershift-operator-aws-credentials"
+	serviceAccountTokenFile       = "/var/run/secrets/openshift/serviceaccount/token"
 	oidcProviderS3CredsSecretName = "hypershift-operator-oidc-provider-s3-credentials"
 	externaDNSCredsSecretName     = "external-dns-credentials"
 )
@@ -265,13 +266,16 @@ type HyperShiftOperatorDeployment struct {
 	AWSPrivateSecret               *corev1.Secret
 	AWSPrivateSecretKey            string
 	AWSPrivateRegion               string
+	AWSPrivateRoleARN              string
 	OIDCBucketName                 string
 	OIDCBucketRegion               string
 	OIDCStorageProviderS3Secret    *corev1.Secret
 	OIDCStorageProviderS3SecretKey string
+	OIDCStorageProviderS3RoleARN   string
 	MetricsSet                     metrics.MetricsSet
 	IncludeVersion                 bool
 	UWMTelemetry                   bool
+	NoneEndpointsWebhookURL        string
 }
 
 func (o HyperShiftOperatorDeployment) Build() *appsv1.Deployment {
@@ -284,6 +288,9 @@ func (o HyperShiftOperatorDeployment) Build() *appsv1.Deployment {
 		fmt.Sprintf("--enable-ci-debug-output=%t", o.EnableCIDebugOutput),
 		fmt.Sprintf("--private-platform=%s", o.PrivatePlatform),
 	}
+	if o.NoneEndpointsWebhookURL != "" {
+		args = append(args, fmt.Sprintf("--none-platform-endpoints-webhook-url=%s", o.NoneEndpointsWebhookURL))
+	}
 
 	var volumeMounts []corev1.VolumeMount
 	var volumes []corev1.Volume
@@ -307,6 +314,36 @@ func (o HyperShiftOperatorDeployment) Build() *appsv1.Deployment {
 		metrics.MetricsSetToEnv(o.MetricsSet),
 	}
 
+	// The projected service account token is referenced by the credentials of the
+	// private platform and used to assume roles with web identity, so it is only
+	// added once.
+	tokenVolumeAdded := false
+	addTokenVolume := func() {
+		if tokenVolumeAdded {
+			return
+		}
+		tokenVolumeAdded = true
+		volumeMounts = append(volumeMounts, corev1.VolumeMount{
+			Name:      "token",
+			MountPath: "/var/run/secrets/openshift/serviceaccount",
+		})
+		volumes = append(volumes, corev1.Volume{
+			Name: "token",
+			VolumeSource: corev1.VolumeSource{
+				Projected: &corev1.ProjectedVolumeSource{
+					Sources: []corev1.VolumeProjection{
+						{
+							ServiceAccountToken: &corev1.ServiceAccountTokenProjection{
+								Audience: "openshift",
+								Path:     "token",
+							},
+						},
+					},
+				},
+			},
+		})
+	}
+
 	if o.EnableWebhook {
 		volumeMounts = append(volumeMounts, corev1.VolumeMount{
 			Name:      "serving-cert",
@@ -344,6 +381,14 @@ func (o HyperShiftOperatorDeployment) Build() *appsv1.Deployment {
 				},
 			},
 		})
+	} else if len(o.OIDCBucketName) > 0 && len(o.OIDCBucketRegion) > 0 && len(o.OIDCStorageProviderS3RoleARN) > 0 {
+		args = append(args,
+			"--oidc-storage-provider-s3-bucket-name="+o.OIDCBucketName,
+			"--oidc-storage-provider-s3-region="+o.OIDCBucketRegion,
+			"--oidc-storage-provider-s3-role-arn="+o.OIDCStorageProviderS3RoleARN,
+			"--oidc-storage-provider-s3-web-identity-token-file="+serviceAccountTokenFile,
+		)
+		addTokenVolume()
 	}
 
 	if o.UWMTelemetry {
@@ -366,7 +411,7 @@ func (o HyperShiftOperatorDeployment) Build() *appsv1.Deployment {
 	}
 
 	privatePlatformType := hyperv1.PlatformType(o.PrivatePlatform)
-	if privatePlatformType != hyperv1.NonePlatform {
+	if privatePlatformType != hyperv1.NonePlatform && len(o.AWSPrivateRoleARN) == 0 {
 		// Add generic provider credentials secret volume
 		volumes = append(volumes, corev1.Volume{
 			Name: "credentials",
@@ -380,10 +425,28 @@ func (o HyperShiftOperatorDeployment) Build() *appsv1.Deployment {
 			Name:      "credentials",
 			MountPath: "/etc/provider",
 		})
+	}
 
-		// Add platform specific settings
-		switch privatePlatformType {
-		case hyperv1.AWSPlatform:
+	// Add platform specific settings
+	switch privatePlatformType {
+	case hyperv1.AWSPlatform:
+		if len(o.AWSPrivateRoleARN) > 0 {
+			// The role is assumed with the projected token, so no long-lived
+			// credentials are needed
+			envVars = append(envVars,
+				corev1.EnvVar{
+					Name:  "AWS_ROLE_ARN",
+					Value: o.AWSPrivateRoleARN,
+				},
+				corev1.EnvVar{
+					Name:  "AWS_WEB_IDENTITY_TOKEN_FILE",
+					Value: serviceAccountTokenFile,
+				},
+				corev1.EnvVar{
+					Name:  "AWS_REGION",
+					Value: o.AWSPrivateRegion,
+				})
+		} else {
 			envVars = append(envVars,
 				corev1.EnvVar{
 					Name:  "AWS_SHARED_CREDENTIALS_FILE",
@@ -397,26 +460,8 @@ func (o HyperShiftOperatorDeployment) Build() *appsv1.Deployment {
 					Name:  "AWS_SDK_LOAD_CONFIG",
 					Value: "1",
 				})
-			volumeMounts = append(volumeMounts, corev1.VolumeMount{
-				Name:      "token",
-				MountPath: "/var/run/secrets/openshift/serviceaccount",
-			})
-			volumes = append(volumes, corev1.Volume{
-				Name: "token",
-				VolumeSource: corev1.VolumeSource{
-					Projected: &corev1.ProjectedVolumeSource{
-						Sources: []corev1.VolumeProjection{
-							{
-								ServiceAccountToken: &corev1.ServiceAccountTokenProjection{
-									Audience: "openshift",
-									Path:     "token",
-								},
-							},
-						},
-					},
-				},
-			})
 		}
+		addTokenVolume()
 	}
 
 	deployment := &appsv1.Deployment{
@@ -835,6 +880,11 @@ func (o HyperShiftOperatorClusterRole) Build() *rbacv1.ClusterRole {
 				Resources: []string{"agents"},
 				Verbs:     []string{"*"},
 			},
+			{ // This allows hypershift operator to power on and off the BareMetalHosts of Agent NodePools
+				APIGroups: []string{"metal3.io"},
+				Resources: []string{"baremetalhosts"},
+				Verbs:     []string{"get", "list", "patch"},
+			},
 			{
 				APIGroups: []string{"extensions.hive.openshift.io"},
 				Resources: []string{"agentclusterinstalls"},
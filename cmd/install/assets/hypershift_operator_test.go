@@ -240,6 +240,64 @@ func TestHyperShiftOperatorDeployment_Build(t *testing.T) {
 				},
 			},
 		},
+		"specify aws private and oidc role arns result in a single token volume and no credentials": {
+			inputBuildParameters: HyperShiftOperatorDeployment{
+				Namespace: &corev1.Namespace{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: testNamespace,
+					},
+				},
+				OperatorImage: testOperatorImage,
+				ServiceAccount: &corev1.ServiceAccount{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "hypershift",
+					},
+				},
+				Replicas:                     3,
+				PrivatePlatform:              string(hyperv1.AWSPlatform),
+				AWSPrivateRegion:             "us-east-1",
+				AWSPrivateRoleARN:            "arn:aws:iam::123456789012:role/private",
+				OIDCBucketRegion:             "us-east-1",
+				OIDCBucketName:               "oidc-bucket",
+				OIDCStorageProviderS3RoleARN: "arn:aws:iam::123456789012:role/oidc",
+			},
+			expectedArgs: []string{
+				"run",
+				"--namespace=$(MY_NAMESPACE)",
+				"--pod-name=$(MY_NAME)",
+				"--metrics-addr=:9000",
+				fmt.Sprintf("--enable-ocp-cluster-monitoring=%t", false),
+				fmt.Sprintf("--enable-ci-debug-output=%t", false),
+				fmt.Sprintf("--private-platform=%s", string(hyperv1.AWSPlatform)),
+				"--oidc-storage-provider-s3-bucket-name=" + "oidc-bucket",
+				"--oidc-storage-provider-s3-region=" + "us-east-1",
+				"--oidc-storage-provider-s3-role-arn=" + "arn:aws:iam::123456789012:role/oidc",
+				"--oidc-storage-provider-s3-web-identity-token-file=/var/run/secrets/openshift/serviceaccount/token",
+			},
+			expectedVolumeMounts: []corev1.VolumeMount{
+				{
+					Name:      "token",
+					MountPath: "/var/run/secrets/openshift/serviceaccount",
+				},
+			},
+			expectedVolumes: []corev1.Volume{
+				{
+					Name: "token",
+					VolumeSource: corev1.VolumeSource{
+						Projected: &corev1.ProjectedVolumeSource{
+							Sources: []corev1.VolumeProjection{
+								{
+									ServiceAccountToken: &corev1.ServiceAccountTokenProjection{
+										Audience: "openshift",
+										Path:     "token",
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
 	}
 	for name, test := range tests {
 		t.Run(name, func(t *testing.T) {
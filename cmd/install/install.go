@@ -19,6 +19,7 @@ import (
 	"context"
 	"fmt"
 	"io/ioutil"
+	"net/url"
 	"os"
 	"strings"
 
@@ -60,11 +61,13 @@ type Options struct {
 	AWSPrivateCredentialsSecret               string
 	AWSPrivateCredentialsSecretKey            string
 	AWSPrivateRegion                          string
+	AWSPrivateRoleARN                         string
 	OIDCStorageProviderS3Region               string
 	OIDCStorageProviderS3BucketName           string
 	OIDCStorageProviderS3Credentials          string
 	OIDCStorageProviderS3CredentialsSecret    string
 	OIDCStorageProviderS3CredentialsSecretKey string
+	OIDCStorageProviderS3RoleARN              string
 	ExternalDNSProvider                       string
 	ExternalDNSCredentials                    string
 	ExternalDNSCredentialsSecret              string
@@ -72,6 +75,7 @@ type Options struct {
 	EnableAdminRBACGeneration                 bool
 	EnableUWMTelemetryRemoteWrite             bool
 	MetricsSet                                metrics.MetricsSet
+	NoneEndpointsWebhookURL                   string
 }
 
 func (o *Options) Validate() error {
@@ -79,8 +83,11 @@ func (o *Options) Validate() error {
 
 	switch hyperv1.PlatformType(o.PrivatePlatform) {
 	case hyperv1.AWSPlatform:
-		if (len(o.AWSPrivateCreds) == 0 && len(o.AWSPrivateCredentialsSecret) == 0) || len(o.AWSPrivateRegion) == 0 {
-			errs = append(errs, fmt.Errorf("--aws-private-region and --aws-private-creds or --aws-private-secret are required with --private-platform=%s", hyperv1.AWSPlatform))
+		if (len(o.AWSPrivateCreds) == 0 && len(o.AWSPrivateCredentialsSecret) == 0 && len(o.AWSPrivateRoleARN) == 0) || len(o.AWSPrivateRegion) == 0 {
+			errs = append(errs, fmt.Errorf("--aws-private-region and --aws-private-creds, --aws-private-secret or --aws-private-role-arn are required with --private-platform=%s", hyperv1.AWSPlatform))
+		}
+		if len(o.AWSPrivateRoleARN) > 0 && (len(o.AWSPrivateCreds) > 0 || len(o.AWSPrivateCredentialsSecret) > 0) {
+			errs = append(errs, fmt.Errorf("--aws-private-role-arn can not be specified together with --aws-private-creds or --aws-private-secret"))
 		}
 	case hyperv1.NonePlatform:
 	default:
@@ -90,6 +97,14 @@ func (o *Options) Validate() error {
 	if len(o.OIDCStorageProviderS3CredentialsSecret) > 0 && len(o.OIDCStorageProviderS3Credentials) > 0 {
 		errs = append(errs, fmt.Errorf("only one of --oidc-storage-provider-s3-secret or --oidc-storage-provider-s3-credentials is supported"))
 	}
+	if len(o.OIDCStorageProviderS3RoleARN) > 0 {
+		if len(o.OIDCStorageProviderS3CredentialsSecret) > 0 || len(o.OIDCStorageProviderS3Credentials) > 0 {
+			errs = append(errs, fmt.Errorf("--oidc-storage-provider-s3-role-arn can not be specified together with --oidc-storage-provider-s3-secret or --oidc-storage-provider-s3-credentials"))
+		}
+		if len(o.OIDCStorageProviderS3BucketName) == 0 || len(o.OIDCStorageProviderS3Region) == 0 {
+			errs = append(errs, fmt.Errorf("all required oidc information is not set"))
+		}
+	}
 	if (len(o.OIDCStorageProviderS3CredentialsSecret) > 0 || len(o.OIDCStorageProviderS3Credentials) > 0) &&
 		(len(o.OIDCStorageProviderS3BucketName) == 0 || len(o.OIDCStorageProviderS3Region) == 0 || len(o.OIDCStorageProviderS3CredentialsSecretKey) == 0) {
 		errs = append(errs, fmt.Errorf("all required oidc information is not set"))
@@ -109,6 +124,11 @@ func (o *Options) Validate() error {
 			errs = append(errs, fmt.Errorf("--external-dns-domain-filter is required with --external-dns-provider"))
 		}
 	}
+	if len(o.NoneEndpointsWebhookURL) > 0 {
+		if u, err := url.Parse(o.NoneEndpointsWebhookURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, fmt.Errorf("--none-platform-endpoints-webhook-url must be an http or https URL"))
+		}
+	}
 	if o.HyperShiftImage != version.HyperShiftImage && len(o.ImageRefsFile) > 0 {
 		errs = append(errs, fmt.Errorf("only one of --hypershift-image or --image-refs-file should be specified"))
 	}
@@ -153,11 +173,13 @@ func NewCommand() *cobra.Command {
 	cmd.PersistentFlags().StringVar(&opts.AWSPrivateCredentialsSecret, "aws-private-secret", "", "Name of an existing secret containing the AWS private link credentials.")
 	cmd.PersistentFlags().StringVar(&opts.AWSPrivateCredentialsSecretKey, "aws-private-secret-key", "credentials", "Name of the secret key containing the AWS private link credentials.")
 	cmd.PersistentFlags().StringVar(&opts.AWSPrivateRegion, "aws-private-region", opts.AWSPrivateRegion, "AWS region where private clusters are supported by this operator")
+	cmd.PersistentFlags().StringVar(&opts.AWSPrivateRoleARN, "aws-private-role-arn", opts.AWSPrivateRoleARN, "ARN of a role with privileges sufficient to manage private cluster resources that the operator assumes with its projected service account token, instead of using long-lived credentials from --aws-private-creds or --aws-private-secret. The role must trust the OIDC provider of the management cluster for the openshift audience")
 	cmd.PersistentFlags().StringVar(&opts.OIDCStorageProviderS3Region, "oidc-storage-provider-s3-region", "", "Region of the OIDC bucket. Required for AWS guest clusters")
 	cmd.PersistentFlags().StringVar(&opts.OIDCStorageProviderS3BucketName, "oidc-storage-provider-s3-bucket-name", "", "Name of the bucket in which to store the clusters OIDC discovery information. Required for AWS guest clusters")
 	cmd.PersistentFlags().StringVar(&opts.OIDCStorageProviderS3Credentials, "oidc-storage-provider-s3-credentials", opts.OIDCStorageProviderS3Credentials, "Credentials to use for writing the OIDC documents into the S3 bucket. Required for AWS guest clusters")
 	cmd.PersistentFlags().StringVar(&opts.OIDCStorageProviderS3CredentialsSecret, "oidc-storage-provider-s3-secret", "", "Name of an existing secret containing the OIDC S3 credentials.")
 	cmd.PersistentFlags().StringVar(&opts.OIDCStorageProviderS3CredentialsSecretKey, "oidc-storage-provider-s3-secret-key", "credentials", "Name of the secret key containing the OIDC S3 credentials.")
+	cmd.PersistentFlags().StringVar(&opts.OIDCStorageProviderS3RoleARN, "oidc-storage-provider-s3-role-arn", opts.OIDCStorageProviderS3RoleARN, "ARN of a role allowed to write the OIDC documents into the S3 bucket that the operator assumes with its projected service account token, instead of using long-lived credentials from --oidc-storage-provider-s3-credentials or --oidc-storage-provider-s3-secret. The role must trust the OIDC provider of the management cluster for the openshift audience")
 	cmd.PersistentFlags().StringVar(&opts.ExternalDNSProvider, "external-dns-provider", opts.OIDCStorageProviderS3Credentials, "Provider to use for managing DNS records using external-dns")
 	cmd.PersistentFlags().StringVar(&opts.ExternalDNSCredentials, "external-dns-credentials", opts.OIDCStorageProviderS3Credentials, "Credentials to use for managing DNS records using external-dns")
 	cmd.PersistentFlags().StringVar(&opts.ExternalDNSCredentialsSecret, "external-dns-secret", "", "Name of an existing secret containing the external-dns credentials.")
@@ -166,6 +188,7 @@ func NewCommand() *cobra.Command {
 	cmd.PersistentFlags().StringVar(&opts.ImageRefsFile, "image-refs", opts.ImageRefsFile, "Image references to user in Hypershift installation")
 	cmd.PersistentFlags().StringVar(&opts.AdditionalTrustBundle, "additional-trust-bundle", opts.AdditionalTrustBundle, "Path to a file with user CA bundle")
 	cmd.PersistentFlags().Var(&opts.MetricsSet, "metrics-set", "The set of metrics to produce for each HyperShift control plane. Valid values are: Telemetry, SRE, All")
+	cmd.PersistentFlags().StringVar(&opts.NoneEndpointsWebhookURL, "none-platform-endpoints-webhook-url", opts.NoneEndpointsWebhookURL, "URL that the operator posts the endpoints of None platform clusters to as JSON whenever they change, so that external automation can set up their load balancers and DNS records")
 	cmd.PersistentFlags().BoolVar(&opts.EnableUWMTelemetryRemoteWrite, "enable-uwm-telemetry-remote-write", opts.EnableUWMTelemetryRemoteWrite, "If true, HyperShift operator ensures user workload monitoring is enabled and that it is configured to remote write telemetry metrics from control planes")
 
 	cmd.RunE = func(cmd *cobra.Command, args []string) error {
@@ -420,16 +443,19 @@ func hyperShiftOperatorManifests(opts Options) ([]crclient.Object, error) {
 		EnableWebhook:                  opts.EnableWebhook,
 		PrivatePlatform:                opts.PrivatePlatform,
 		AWSPrivateRegion:               opts.AWSPrivateRegion,
+		AWSPrivateRoleARN:              opts.AWSPrivateRoleARN,
 		AWSPrivateSecret:               operatorCredentialsSecret,
 		AWSPrivateSecretKey:            opts.AWSPrivateCredentialsSecretKey,
 		OIDCBucketName:                 opts.OIDCStorageProviderS3BucketName,
 		OIDCBucketRegion:               opts.OIDCStorageProviderS3Region,
 		OIDCStorageProviderS3Secret:    oidcSecret,
 		OIDCStorageProviderS3SecretKey: opts.OIDCStorageProviderS3CredentialsSecretKey,
+		OIDCStorageProviderS3RoleARN:   opts.OIDCStorageProviderS3RoleARN,
 		Images:                         images,
 		MetricsSet:                     opts.MetricsSet,
 		IncludeVersion:                 !opts.Template,
 		UWMTelemetry:                   opts.EnableUWMTelemetryRemoteWrite,
+		NoneEndpointsWebhookURL:        opts.NoneEndpointsWebhookURL,
 	}.Build()
 	objects = append(objects, operatorDeployment)
 
@@ -34,6 +34,63 @@ func TestOptions_Validate(t *testing.T) {
 			},
 			expectError: false,
 		},
+		"when aws private platform with role arn and region there is no error": {
+			inputOptions: Options{
+				PrivatePlatform:   string(hyperv1.AWSPlatform),
+				AWSPrivateRoleARN: "arn:aws:iam::123456789012:role/private",
+				AWSPrivateRegion:  "us-east-1",
+			},
+			expectError: false,
+		},
+		"when aws private platform with role arn and secret it errors": {
+			inputOptions: Options{
+				PrivatePlatform:             string(hyperv1.AWSPlatform),
+				AWSPrivateRoleARN:           "arn:aws:iam::123456789012:role/private",
+				AWSPrivateCredentialsSecret: "my-secret",
+				AWSPrivateRegion:            "us-east-1",
+			},
+			expectError: true,
+		},
+		"when oidc role arn is specified with credentials it errors": {
+			inputOptions: Options{
+				PrivatePlatform:                  string(hyperv1.NonePlatform),
+				OIDCStorageProviderS3RoleARN:     "arn:aws:iam::123456789012:role/oidc",
+				OIDCStorageProviderS3Credentials: "mycreds",
+				OIDCStorageProviderS3Region:      "us-east-1",
+				OIDCStorageProviderS3BucketName:  "mybucket",
+			},
+			expectError: true,
+		},
+		"when oidc role arn is specified without bucket it errors": {
+			inputOptions: Options{
+				PrivatePlatform:              string(hyperv1.NonePlatform),
+				OIDCStorageProviderS3RoleARN: "arn:aws:iam::123456789012:role/oidc",
+			},
+			expectError: true,
+		},
+		"when oidc role arn is specified with bucket and region there is no error": {
+			inputOptions: Options{
+				PrivatePlatform:                 string(hyperv1.NonePlatform),
+				OIDCStorageProviderS3RoleARN:    "arn:aws:iam::123456789012:role/oidc",
+				OIDCStorageProviderS3Region:     "us-east-1",
+				OIDCStorageProviderS3BucketName: "mybucket",
+			},
+			expectError: false,
+		},
+		"when the endpoints webhook url is not an http url it errors": {
+			inputOptions: Options{
+				PrivatePlatform:         string(hyperv1.NonePlatform),
+				NoneEndpointsWebhookURL: "endpoints.example.com/notify",
+			},
+			expectError: true,
+		},
+		"when the endpoints webhook url is an https url there is no error": {
+			inputOptions: Options{
+				PrivatePlatform:         string(hyperv1.NonePlatform),
+				NoneEndpointsWebhookURL: "https://endpoints.example.com/notify",
+			},
+			expectError: false,
+		},
 		"when empty private platform is specified it errors": {
 			inputOptions: Options{},
 			expectError:  true,
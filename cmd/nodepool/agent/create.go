@@ -2,18 +2,26 @@ package agent
 
 import (
 	"context"
+	"fmt"
 
 	hyperv1 "github.com/openshift/hypershift/api/v1alpha1"
 	"github.com/openshift/hypershift/cmd/nodepool/core"
 	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	crclient "sigs.k8s.io/controller-runtime/pkg/client"
 )
 
-type AgentPlatformCreateOptions struct{}
+type AgentPlatformCreateOptions struct {
+	AgentLabelSelector    string
+	BareMetalHostSelector string
+}
 
 func NewAgentPlatformCreateOptions(cmd *cobra.Command) *AgentPlatformCreateOptions {
 	platformOpts := &AgentPlatformCreateOptions{}
 
+	cmd.Flags().StringVar(&platformOpts.AgentLabelSelector, "agent-label-selector", platformOpts.AgentLabelSelector, "A label selector the Agents of the NodePool must match, e.g. 'size=large,zone in (a,b)'. If unset, any available Agent is used")
+	cmd.Flags().StringVar(&platformOpts.BareMetalHostSelector, "bare-metal-host-selector", platformOpts.BareMetalHostSelector, "A label selector of the BareMetalHosts that are powered on through their BMC when the NodePool needs Agents, e.g. 'pool=workers'. If unset, the hosts have to be booted manually")
+
 	return platformOpts
 }
 
@@ -32,6 +40,25 @@ func NewCreateCommand(coreOpts *core.CreateNodePoolOptions) *cobra.Command {
 }
 
 func (o *AgentPlatformCreateOptions) UpdateNodePool(ctx context.Context, nodePool *hyperv1.NodePool, hcluster *hyperv1.HostedCluster, client crclient.Client) error {
+	if len(o.AgentLabelSelector) == 0 && len(o.BareMetalHostSelector) == 0 {
+		return nil
+	}
+	platform := &hyperv1.AgentNodePoolPlatform{}
+	if len(o.AgentLabelSelector) > 0 {
+		selector, err := metav1.ParseToLabelSelector(o.AgentLabelSelector)
+		if err != nil {
+			return fmt.Errorf("invalid agent label selector %q: %w", o.AgentLabelSelector, err)
+		}
+		platform.AgentLabelSelector = selector
+	}
+	if len(o.BareMetalHostSelector) > 0 {
+		selector, err := metav1.ParseToLabelSelector(o.BareMetalHostSelector)
+		if err != nil {
+			return fmt.Errorf("invalid bare metal host selector %q: %w", o.BareMetalHostSelector, err)
+		}
+		platform.BareMetalHostSelector = selector
+	}
+	nodePool.Spec.Platform.Agent = platform
 	return nil
 }
 
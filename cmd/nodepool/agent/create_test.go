@@ -0,0 +1,72 @@
+package agent
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	hyperv1 "github.com/openshift/hypershift/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestUpdateNodePool(t *testing.T) {
+	testCases := []struct {
+		name                          string
+		selector                      string
+		bareMetalHostSelector         string
+		expectedSelector              *metav1.LabelSelector
+		expectedBareMetalHostSelector *metav1.LabelSelector
+		expectError                   bool
+	}{
+		{
+			name: "no selector",
+		},
+		{
+			name:     "equality and set-based requirements",
+			selector: "size=large,zone in (a,b)",
+			expectedSelector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{"size": "large"},
+				MatchExpressions: []metav1.LabelSelectorRequirement{
+					{Key: "zone", Operator: metav1.LabelSelectorOpIn, Values: []string{"a", "b"}},
+				},
+			},
+		},
+		{
+			name:        "invalid selector",
+			selector:    "size in large",
+			expectError: true,
+		},
+		{
+			name:                  "bare metal host selector",
+			bareMetalHostSelector: "pool=workers",
+			expectedBareMetalHostSelector: &metav1.LabelSelector{
+				MatchLabels:      map[string]string{"pool": "workers"},
+				MatchExpressions: []metav1.LabelSelectorRequirement{},
+			},
+		},
+		{
+			name:                  "invalid bare metal host selector",
+			bareMetalHostSelector: "pool in workers",
+			expectError:           true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewGomegaWithT(t)
+			opts := &AgentPlatformCreateOptions{AgentLabelSelector: tc.selector, BareMetalHostSelector: tc.bareMetalHostSelector}
+			nodePool := &hyperv1.NodePool{}
+			err := opts.UpdateNodePool(context.Background(), nodePool, nil, nil)
+			if tc.expectError {
+				g.Expect(err).To(HaveOccurred())
+				return
+			}
+			g.Expect(err).ToNot(HaveOccurred())
+			if tc.expectedSelector == nil && tc.expectedBareMetalHostSelector == nil {
+				g.Expect(nodePool.Spec.Platform.Agent).To(BeNil())
+				return
+			}
+			g.Expect(nodePool.Spec.Platform.Agent.AgentLabelSelector).To(Equal(tc.expectedSelector))
+			g.Expect(nodePool.Spec.Platform.Agent.BareMetalHostSelector).To(Equal(tc.expectedBareMetalHostSelector))
+		})
+	}
+}
@@ -11,18 +11,24 @@ import (
 )
 
 type AWSPlatformCreateOptions struct {
-	InstanceProfile string
-	SubnetID        string
-	SecurityGroupID string
-	InstanceType    string
-	RootVolumeType  string
-	RootVolumeIOPS  int64
-	RootVolumeSize  int64
+	InstanceProfile       string
+	SubnetID              string
+	SubnetIDs             []string
+	SecurityGroupID       string
+	InstanceType          string
+	FallbackInstanceTypes []string
+	RootVolumeType        string
+	RootVolumeIOPS        int64
+	RootVolumeSize        int64
+	RootVolumeThroughput  int64
+	RootVolumeKMSKey      string
+	MarketType            string
+	SpotMaxPrice          string
+	Tenancy               string
 }
 
 func NewCreateCommand(coreOpts *core.CreateNodePoolOptions) *cobra.Command {
 	platformOpts := &AWSPlatformCreateOptions{
-		InstanceType:   "m5.large",
 		RootVolumeType: "gp3",
 		RootVolumeSize: 120,
 		RootVolumeIOPS: 0,
@@ -33,13 +39,20 @@ func NewCreateCommand(coreOpts *core.CreateNodePoolOptions) *cobra.Command {
 		SilenceUsage: true,
 	}
 
-	cmd.Flags().StringVar(&platformOpts.InstanceType, "instance-type", platformOpts.InstanceType, "The AWS instance type of the NodePool")
+	cmd.Flags().StringVar(&platformOpts.InstanceType, "instance-type", platformOpts.InstanceType, "The AWS instance type of the NodePool. Defaults to m5.large, or g4dn.xlarge for GPU NodePools")
+	cmd.Flags().StringSliceVar(&platformOpts.FallbackInstanceTypes, "fallback-instance-types", platformOpts.FallbackInstanceTypes, "The AWS instance types to fall back to, in order, when there is no capacity for the instance type of the NodePool")
 	cmd.Flags().StringVar(&platformOpts.SubnetID, "subnet-id", platformOpts.SubnetID, "The AWS subnet ID in which to create the NodePool")
+	cmd.Flags().StringSliceVar(&platformOpts.SubnetIDs, "subnet-ids", platformOpts.SubnetIDs, "The AWS subnet IDs to spread the NodePool across, one MachineDeployment per subnet. Cannot be used with --subnet-id")
 	cmd.Flags().StringVar(&platformOpts.SecurityGroupID, "securitygroup-id", platformOpts.SecurityGroupID, "The AWS security group in which to create the NodePool")
 	cmd.Flags().StringVar(&platformOpts.InstanceProfile, "instance-profile", platformOpts.InstanceProfile, "The AWS instance profile for the NodePool")
 	cmd.Flags().StringVar(&platformOpts.RootVolumeType, "root-volume-type", platformOpts.RootVolumeType, "The type of the root volume (e.g. gp3, io2) for machines in the NodePool")
 	cmd.Flags().Int64Var(&platformOpts.RootVolumeIOPS, "root-volume-iops", platformOpts.RootVolumeIOPS, "The iops of the root volume for machines in the NodePool")
 	cmd.Flags().Int64Var(&platformOpts.RootVolumeSize, "root-volume-size", platformOpts.RootVolumeSize, "The size of the root volume (min: 8) for machines in the NodePool")
+	cmd.Flags().Int64Var(&platformOpts.RootVolumeThroughput, "root-volume-throughput", platformOpts.RootVolumeThroughput, "The throughput in MiB/s of the root volume when specifying type:gp3 for machines in the NodePool")
+	cmd.Flags().StringVar(&platformOpts.RootVolumeKMSKey, "root-volume-kms-key", platformOpts.RootVolumeKMSKey, "The ID or ARN of the KMS key to encrypt the root volume of machines in the NodePool with")
+	cmd.Flags().StringVar(&platformOpts.MarketType, "market-type", platformOpts.MarketType, "The EC2 purchasing option of the NodePool instances (OnDemand or Spot)")
+	cmd.Flags().StringVar(&platformOpts.SpotMaxPrice, "spot-max-price", platformOpts.SpotMaxPrice, "The maximum hourly price in USD to pay for the spot instances of the NodePool. Defaults to the on-demand price")
+	cmd.Flags().StringVar(&platformOpts.Tenancy, "tenancy", platformOpts.Tenancy, "The tenancy of the NodePool instances (default, dedicated or host). Defaults to the tenancy of the VPC")
 
 	cmd.RunE = coreOpts.CreateRunFunc(platformOpts)
 
@@ -47,10 +60,19 @@ func NewCreateCommand(coreOpts *core.CreateNodePoolOptions) *cobra.Command {
 }
 
 func (o *AWSPlatformCreateOptions) UpdateNodePool(ctx context.Context, nodePool *hyperv1.NodePool, hcluster *hyperv1.HostedCluster, client crclient.Client) error {
+	if len(o.InstanceType) == 0 {
+		o.InstanceType = "m5.large"
+		if nodePool.Spec.GPU != nil {
+			o.InstanceType = "g4dn.xlarge"
+		}
+	}
 	if len(o.InstanceProfile) == 0 {
 		o.InstanceProfile = fmt.Sprintf("%s-worker", hcluster.Spec.InfraID)
 	}
-	if len(o.SubnetID) == 0 {
+	if len(o.SubnetIDs) > 0 && len(o.SubnetID) > 0 {
+		return fmt.Errorf("only one of --subnet-id or --subnet-ids can be specified")
+	}
+	if len(o.SubnetID) == 0 && len(o.SubnetIDs) == 0 {
 		if hcluster.Spec.Platform.AWS.CloudProviderConfig.Subnet.ID != nil {
 			o.SubnetID = *hcluster.Spec.Platform.AWS.CloudProviderConfig.Subnet.ID
 		} else {
@@ -79,8 +101,9 @@ func (o *AWSPlatformCreateOptions) UpdateNodePool(ctx context.Context, nodePool
 		o.SecurityGroupID = *defaultNodePool.Spec.Platform.AWS.SecurityGroups[0].ID
 	}
 	nodePool.Spec.Platform.AWS = &hyperv1.AWSNodePoolPlatform{
-		InstanceType:    o.InstanceType,
-		InstanceProfile: o.InstanceProfile,
+		InstanceType:          o.InstanceType,
+		FallbackInstanceTypes: o.FallbackInstanceTypes,
+		InstanceProfile:       o.InstanceProfile,
 		Subnet: &hyperv1.AWSResourceReference{
 			ID: &o.SubnetID,
 		},
@@ -94,6 +117,27 @@ func (o *AWSPlatformCreateOptions) UpdateNodePool(ctx context.Context, nodePool
 			Size: o.RootVolumeSize,
 			IOPS: o.RootVolumeIOPS,
 		},
+		MarketType: hyperv1.AWSMarketType(o.MarketType),
+		Tenancy:    hyperv1.AWSTenancy(o.Tenancy),
+	}
+	if len(o.SubnetIDs) > 0 {
+		nodePool.Spec.Platform.AWS.Subnet = nil
+		for i := range o.SubnetIDs {
+			nodePool.Spec.Platform.AWS.Subnets = append(nodePool.Spec.Platform.AWS.Subnets, hyperv1.AWSResourceReference{
+				ID: &o.SubnetIDs[i],
+			})
+		}
+	}
+	if o.RootVolumeThroughput > 0 {
+		nodePool.Spec.Platform.AWS.RootVolume.Throughput = &o.RootVolumeThroughput
+	}
+	if len(o.RootVolumeKMSKey) > 0 {
+		nodePool.Spec.Platform.AWS.RootVolume.EncryptionKey = o.RootVolumeKMSKey
+	}
+	if len(o.SpotMaxPrice) > 0 {
+		nodePool.Spec.Platform.AWS.Spot = &hyperv1.AWSSpotOptions{
+			MaxPrice: &o.SpotMaxPrice,
+		}
 	}
 	return nil
 }
@@ -2,14 +2,19 @@ package azure
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"os"
 	"os/signal"
 	"syscall"
 
+	apifixtures "github.com/openshift/hypershift/api/fixtures"
 	hyperv1 "github.com/openshift/hypershift/api/v1alpha1"
+	azureinfra "github.com/openshift/hypershift/cmd/infra/azure"
 	"github.com/openshift/hypershift/cmd/log"
 	"github.com/openshift/hypershift/cmd/nodepool/core"
 	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
 	crclient "sigs.k8s.io/controller-runtime/pkg/client"
 )
 
@@ -26,6 +31,8 @@ func NewCreateCommand(coreOpts *core.CreateNodePoolOptions) *cobra.Command {
 	cmd.Flags().StringVar(&o.instanceType, "instance-type", o.instanceType, "The instance type to use for the nodepool")
 	cmd.Flags().Int32Var(&o.diskSize, "root-disk-size", o.diskSize, "The size of the root disk for machines in the NodePool (minimum 16)")
 	cmd.Flags().StringVar(&o.availabilityZone, "availability-zone", o.availabilityZone, "The availabilityZone for the nodepool. Must be left unspecified if in a region that doesn't support AZs")
+	cmd.Flags().StringVar(&o.diskEncryptionSetID, "disk-encryption-set-id", o.diskEncryptionSetID, "The ID of a DiskEncryptionSet to encrypt the disks of the nodes with a customer-managed key. Its identity must be allowed to get, wrap and unwrap the key in its Key Vault")
+	cmd.Flags().StringSliceVar(&o.availabilityZones, "availability-zones", o.availabilityZones, "The availability zones to spread the machines of the nodepool across. One NodePool named <name>-<zone> is created per zone, and the node count is split evenly between them")
 
 	cmd.Run = func(cmd *cobra.Command, args []string) {
 		ctx, cancel := context.WithCancel(context.Background())
@@ -36,7 +43,7 @@ func NewCreateCommand(coreOpts *core.CreateNodePoolOptions) *cobra.Command {
 			cancel()
 		}()
 
-		if err := coreOpts.CreateNodePool(ctx, o); err != nil {
+		if err := o.createNodePools(ctx, coreOpts); err != nil {
 			log.Log.Error(err, "Failed to create nodepool")
 			os.Exit(1)
 		}
@@ -46,21 +53,107 @@ func NewCreateCommand(coreOpts *core.CreateNodePoolOptions) *cobra.Command {
 }
 
 type opts struct {
-	instanceType     string
-	diskSize         int32
-	availabilityZone string
+	instanceType        string
+	diskSize            int32
+	availabilityZone    string
+	availabilityZones   []string
+	diskEncryptionSetID string
+}
+
+// createNodePools creates the nodepool, or one nodepool per availability zone
+// if the machines are spread across zones. The machines of a NodePool share a
+// single MachineDeployment, which places all of them in the same zone.
+func (o *opts) createNodePools(ctx context.Context, coreOpts *core.CreateNodePoolOptions) error {
+	if len(o.availabilityZones) == 0 {
+		return coreOpts.CreateNodePool(ctx, o)
+	}
+	if err := validateAvailabilityZones(o.availabilityZone, o.availabilityZones); err != nil {
+		return err
+	}
+	counts := spreadNodeCount(coreOpts.NodeCount, len(o.availabilityZones))
+	for i, zone := range o.availabilityZones {
+		zoneCoreOpts := *coreOpts
+		zoneCoreOpts.Name = fmt.Sprintf("%s-%s", coreOpts.Name, zone)
+		zoneCoreOpts.NodeCount = counts[i]
+		zoneOpts := *o
+		zoneOpts.availabilityZone = zone
+		zoneOpts.availabilityZones = nil
+		if coreOpts.Render && i > 0 {
+			fmt.Println("---")
+		}
+		if err := zoneCoreOpts.CreateNodePool(ctx, &zoneOpts); err != nil {
+			return fmt.Errorf("failed to create nodepool for availability zone %s: %w", zone, err)
+		}
+	}
+	return nil
+}
+
+func validateAvailabilityZones(availabilityZone string, availabilityZones []string) error {
+	if len(availabilityZone) > 0 {
+		return errors.New("--availability-zone and --availability-zones are mutually exclusive")
+	}
+	seen := map[string]bool{}
+	for _, zone := range availabilityZones {
+		if len(zone) == 0 {
+			return errors.New("--availability-zones must not contain empty zones")
+		}
+		if seen[zone] {
+			return fmt.Errorf("availability zone %s is specified more than once", zone)
+		}
+		seen[zone] = true
+	}
+	return nil
+}
+
+// spreadNodeCount splits the node count between the zones, the first zones
+// get one more node when it does not split evenly.
+func spreadNodeCount(nodeCount int32, zones int) []int32 {
+	counts := make([]int32, zones)
+	for i := range counts {
+		counts[i] = nodeCount / int32(zones)
+		if int32(i) < nodeCount%int32(zones) {
+			counts[i]++
+		}
+	}
+	return counts
 }
 
 func (o *opts) UpdateNodePool(ctx context.Context, nodePool *hyperv1.NodePool, hcluster *hyperv1.HostedCluster, client crclient.Client) error {
+	if len(o.diskEncryptionSetID) > 0 {
+		if err := validateDiskEncryptionSet(ctx, hcluster, client, o.diskEncryptionSetID); err != nil {
+			return fmt.Errorf("invalid --disk-encryption-set-id: %w", err)
+		}
+	}
 	nodePool.Spec.Platform.Type = hyperv1.AzurePlatform
 	nodePool.Spec.Platform.Azure = &hyperv1.AzureNodePoolPlatform{
-		VMSize:           o.instanceType,
-		DiskSizeGB:       o.diskSize,
-		AvailabilityZone: o.availabilityZone,
+		VMSize:              o.instanceType,
+		DiskSizeGB:          o.diskSize,
+		AvailabilityZone:    o.availabilityZone,
+		DiskEncryptionSetID: o.diskEncryptionSetID,
 	}
 	return nil
 }
 
+// validateDiskEncryptionSet validates the disk encryption set with the
+// credentials of the HostedCluster, which are the ones its machines are
+// created with.
+func validateDiskEncryptionSet(ctx context.Context, hcluster *hyperv1.HostedCluster, client crclient.Client, id string) error {
+	if hcluster.Spec.Platform.Azure == nil {
+		return fmt.Errorf("the HostedCluster %s has no Azure platform", hcluster.Name)
+	}
+	secret := &corev1.Secret{}
+	if err := client.Get(ctx, crclient.ObjectKey{Namespace: hcluster.Namespace, Name: hcluster.Spec.Platform.Azure.Credentials.Name}, secret); err != nil {
+		return fmt.Errorf("failed to get the credentials of the HostedCluster: %w", err)
+	}
+	creds := &apifixtures.AzureCreds{
+		SubscriptionID: string(secret.Data["AZURE_SUBSCRIPTION_ID"]),
+		TenantID:       string(secret.Data["AZURE_TENANT_ID"]),
+		ClientID:       string(secret.Data["AZURE_CLIENT_ID"]),
+		ClientSecret:   string(secret.Data["AZURE_CLIENT_SECRET"]),
+	}
+	return azureinfra.ValidateDiskEncryptionSet(ctx, creds, id)
+}
+
 func (o opts) Type() hyperv1.PlatformType {
 	return hyperv1.AzurePlatform
 }
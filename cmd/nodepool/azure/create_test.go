@@ -0,0 +1,49 @@
+package azure
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestSpreadNodeCount(t *testing.T) {
+	testCases := []struct {
+		name      string
+		nodeCount int32
+		zones     int
+		expected  []int32
+	}{
+		{
+			name:      "even",
+			nodeCount: 6,
+			zones:     3,
+			expected:  []int32{2, 2, 2},
+		},
+		{
+			name:      "uneven",
+			nodeCount: 5,
+			zones:     3,
+			expected:  []int32{2, 2, 1},
+		},
+		{
+			name:      "fewer nodes than zones",
+			nodeCount: 1,
+			zones:     3,
+			expected:  []int32{1, 0, 0},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewGomegaWithT(t)
+			g.Expect(spreadNodeCount(tc.nodeCount, tc.zones)).To(Equal(tc.expected))
+		})
+	}
+}
+
+func TestValidateAvailabilityZones(t *testing.T) {
+	g := NewGomegaWithT(t)
+	g.Expect(validateAvailabilityZones("", []string{"1", "2", "3"})).To(Succeed())
+	g.Expect(validateAvailabilityZones("1", []string{"2", "3"})).ToNot(Succeed())
+	g.Expect(validateAvailabilityZones("", []string{"1", "1"})).ToNot(Succeed())
+	g.Expect(validateAvailabilityZones("", []string{"1", ""})).ToNot(Succeed())
+}
@@ -4,24 +4,35 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"strings"
 
 	hyperv1 "github.com/openshift/hypershift/api/v1alpha1"
 	"github.com/openshift/hypershift/cmd/log"
 	"github.com/openshift/hypershift/cmd/util"
 	hyperapi "github.com/openshift/hypershift/support/api"
 	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	crclient "sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 type CreateNodePoolOptions struct {
-	Name         string
-	Namespace    string
-	ClusterName  string
-	NodeCount    int32
-	ReleaseImage string
-	Render       bool
+	Name           string
+	Namespace      string
+	ClusterName    string
+	NodeCount      int32
+	ReleaseImage   string
+	NodeLabels     map[string]string
+	Taints         []string
+	GPUs           int32
+	GPUDedicated   bool
+	GPUDriver      string
+	MaxSurge       string
+	MaxUnavailable string
+	MaxPods        int32
+	Render         bool
 }
 
 type PlatformOptions interface {
@@ -70,6 +81,11 @@ func (o *CreateNodePoolOptions) CreateNodePool(ctx context.Context, platformOpts
 		releaseImage = hcluster.Spec.Release.Image
 	}
 
+	taints, err := parseTaints(o.Taints)
+	if err != nil {
+		return err
+	}
+
 	nodePool = &hyperv1.NodePool{
 		TypeMeta: metav1.TypeMeta{
 			Kind:       "NodePool",
@@ -91,9 +107,47 @@ func (o *CreateNodePoolOptions) CreateNodePool(ctx context.Context, platformOpts
 			Platform: hyperv1.NodePoolPlatform{
 				Type: hcluster.Spec.Platform.Type,
 			},
+			NodeLabels: o.NodeLabels,
+			Taints:     taints,
 		},
 	}
 
+	if len(o.MaxSurge) > 0 || len(o.MaxUnavailable) > 0 {
+		// Unset values keep the API defaults.
+		maxSurge, maxUnavailable := intstr.FromInt(1), intstr.FromInt(0)
+		if len(o.MaxSurge) > 0 {
+			maxSurge = intstr.Parse(o.MaxSurge)
+		}
+		if len(o.MaxUnavailable) > 0 {
+			maxUnavailable = intstr.Parse(o.MaxUnavailable)
+		}
+		nodePool.Spec.Management.Replace = &hyperv1.ReplaceUpgrade{
+			Strategy: hyperv1.UpgradeStrategyRollingUpdate,
+			RollingUpdate: &hyperv1.RollingUpdate{
+				MaxSurge:       &maxSurge,
+				MaxUnavailable: &maxUnavailable,
+			},
+		}
+	}
+
+	if o.MaxPods > 0 {
+		nodePool.Spec.KubeletConfig = &hyperv1.NodePoolKubeletConfig{
+			MaxPods: &o.MaxPods,
+		}
+	}
+
+	if o.GPUs > 0 {
+		nodePool.Spec.GPU = &hyperv1.NodePoolGPU{
+			Count:     o.GPUs,
+			Dedicated: o.GPUDedicated,
+		}
+		if len(o.GPUDriver) > 0 {
+			nodePool.Spec.GPU.Drivers = &hyperv1.NodePoolGPUDrivers{
+				DriverImage: o.GPUDriver,
+			}
+		}
+	}
+
 	if err := platformOpts.UpdateNodePool(ctx, nodePool, hcluster, client); err != nil {
 		return err
 	}
@@ -115,3 +169,21 @@ func (o *CreateNodePoolOptions) CreateNodePool(ctx context.Context, platformOpts
 	fmt.Printf("NodePool %s created\n", o.Name)
 	return nil
 }
+
+// parseTaints parses taints in the key=value:Effect or key:Effect format.
+func parseTaints(values []string) ([]hyperv1.Taint, error) {
+	var taints []hyperv1.Taint
+	for _, value := range values {
+		keyValue, effect, ok := strings.Cut(value, ":")
+		if !ok || effect == "" {
+			return nil, fmt.Errorf("invalid taint %q: expected format key=value:Effect", value)
+		}
+		key, taintValue, _ := strings.Cut(keyValue, "=")
+		taints = append(taints, hyperv1.Taint{
+			Key:    key,
+			Value:  taintValue,
+			Effect: corev1.TaintEffect(effect),
+		})
+	}
+	return taints, nil
+}
@@ -8,12 +8,16 @@ import (
 	"github.com/openshift/hypershift/cmd/nodepool/azure"
 	"github.com/openshift/hypershift/cmd/nodepool/core"
 	"github.com/openshift/hypershift/cmd/nodepool/kubevirt"
+	"github.com/openshift/hypershift/cmd/nodepool/nutanix"
+	"github.com/openshift/hypershift/cmd/nodepool/oci"
 )
 
 // The following lines are needed in order to validate that any platform implementing PlatformOptions satisfy the interface
 var _ core.PlatformOptions = &aws.AWSPlatformCreateOptions{}
 var _ core.PlatformOptions = &kubevirt.KubevirtPlatformCreateOptions{}
 var _ core.PlatformOptions = &agent.AgentPlatformCreateOptions{}
+var _ core.PlatformOptions = &nutanix.NutanixPlatformCreateOptions{}
+var _ core.PlatformOptions = &oci.OCIPlatformCreateOptions{}
 
 func NewCreateCommand() *cobra.Command {
 	cmd := &cobra.Command{
@@ -36,12 +40,26 @@ func NewCreateCommand() *cobra.Command {
 	cmd.PersistentFlags().StringVar(&opts.ClusterName, "cluster-name", opts.ClusterName, "The name of the HostedCluster nodes in this pool will join")
 	cmd.PersistentFlags().StringVar(&opts.ReleaseImage, "release-image", opts.ReleaseImage, "The release image for nodes. If empty, defaults to the same release image as the HostedCluster.")
 
+	cmd.PersistentFlags().StringToStringVar(&opts.NodeLabels, "node-labels", opts.NodeLabels, "Labels to set on the nodes of the NodePool (e.g. role=infra,tier=backend)")
+	cmd.PersistentFlags().StringSliceVar(&opts.Taints, "taints", opts.Taints, "Taints to set on the nodes of the NodePool, in the key=value:Effect format (e.g. dedicated=infra:NoSchedule)")
+
+	cmd.PersistentFlags().StringVar(&opts.MaxSurge, "max-surge", opts.MaxSurge, "The maximum number or percentage of nodes created above the desired number of nodes when the NodePool nodes are replaced (default 1)")
+	cmd.PersistentFlags().StringVar(&opts.MaxUnavailable, "max-unavailable", opts.MaxUnavailable, "The maximum number or percentage of nodes that can be unavailable when the NodePool nodes are replaced (default 0)")
+
+	cmd.PersistentFlags().Int32Var(&opts.MaxPods, "max-pods", opts.MaxPods, "The maximum number of pods that can run on a node of the NodePool. Defaults to the kubelet default of the release when unset")
+
+	cmd.PersistentFlags().Int32Var(&opts.GPUs, "gpus", opts.GPUs, "The number of NVIDIA GPUs of the nodes of the NodePool. Configures the NodePool for GPU workloads when set")
+	cmd.PersistentFlags().BoolVar(&opts.GPUDedicated, "gpu-dedicated", opts.GPUDedicated, "Taint the GPU nodes of the NodePool so that only pods tolerating nvidia.com/gpu are scheduled on them")
+	cmd.PersistentFlags().StringVar(&opts.GPUDriver, "gpu-driver-image", opts.GPUDriver, "The NVIDIA driver container image the GPU nodes of the NodePool load the driver with when they boot (e.g. nvcr.io/nvidia/driver:525.105.17-rhcos4.12)")
+
 	cmd.PersistentFlags().BoolVar(&opts.Render, "render", false, "Render output as YAML to stdout instead of applying")
 
 	cmd.AddCommand(kubevirt.NewCreateCommand(opts))
 	cmd.AddCommand(aws.NewCreateCommand(opts))
 	cmd.AddCommand(agent.NewCreateCommand(opts))
 	cmd.AddCommand(azure.NewCreateCommand(opts))
+	cmd.AddCommand(nutanix.NewCreateCommand(opts))
+	cmd.AddCommand(oci.NewCreateCommand(opts))
 
 	return cmd
 }
@@ -18,6 +18,9 @@ type KubevirtPlatformCreateOptions struct {
 	RootVolumeSize         uint32
 	RootVolumeStorageClass string
 	RootVolumeAccessModes  string
+	AdditionalNetworks     []string
+	GPUs                   []string
+	HostDevices            []string
 }
 
 func NewCreateCommand(coreOpts *core.CreateNodePoolOptions) *cobra.Command {
@@ -39,6 +42,11 @@ func NewCreateCommand(coreOpts *core.CreateNodePoolOptions) *cobra.Command {
 	cmd.Flags().StringVar(&platformOpts.RootVolumeAccessModes, "root-volume-access-modes", platformOpts.RootVolumeAccessModes, "The access modes of the root volume to use for machines in the NodePool (comma-delimited list)")
 	cmd.Flags().StringVar(&platformOpts.ContainerDiskImage, "containerdisk", platformOpts.ContainerDiskImage, "A reference to docker image with the embedded disk to be used to create the machines")
 
+	cmd.Flags().StringArrayVar(&platformOpts.AdditionalNetworks, "additional-network", platformOpts.AdditionalNetworks, "The name of a NetworkAttachmentDefinition, as <name> or <namespace>/<name>, to attach the machines in the NodePool to next to the pod network. Can be specified multiple times")
+
+	cmd.Flags().StringArrayVar(&platformOpts.GPUs, "gpu", platformOpts.GPUs, "The resource name of a GPU or vGPU of the infra nodes, e.g. nvidia.com/GA102GL_A10, to pass through to the machines in the NodePool. Can be specified multiple times, once per device")
+	cmd.Flags().StringArrayVar(&platformOpts.HostDevices, "host-device", platformOpts.HostDevices, "The resource name of a host device of the infra nodes to pass through to the machines in the NodePool. Can be specified multiple times, once per device")
+
 	cmd.RunE = coreOpts.CreateRunFunc(platformOpts)
 
 	return cmd
@@ -52,6 +60,9 @@ func (o *KubevirtPlatformCreateOptions) UpdateNodePool(_ context.Context, nodePo
 		RootVolumeSize:         o.RootVolumeSize,
 		RootVolumeStorageClass: o.RootVolumeStorageClass,
 		RootVolumeAccessModes:  o.RootVolumeAccessModes,
+		AdditionalNetworks:     o.AdditionalNetworks,
+		GPUs:                   o.GPUs,
+		HostDevices:            o.HostDevices,
 	})
 	return nil
 }
@@ -0,0 +1,83 @@
+package nutanix
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+
+	hyperv1 "github.com/openshift/hypershift/api/v1alpha1"
+	"github.com/openshift/hypershift/cmd/nodepool/core"
+	"github.com/spf13/cobra"
+	crclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+type NutanixPlatformCreateOptions struct {
+	Cluster           string
+	Subnet            string
+	Image             string
+	VCPUSockets       int32
+	VCPUsPerSocket    int32
+	MemorySizeMiB     int64
+	SystemDiskSizeGiB int64
+}
+
+func NewCreateCommand(coreOpts *core.CreateNodePoolOptions) *cobra.Command {
+	platformOpts := &NutanixPlatformCreateOptions{
+		VCPUSockets:       4,
+		VCPUsPerSocket:    1,
+		MemorySizeMiB:     16384,
+		SystemDiskSizeGiB: 120,
+	}
+	cmd := &cobra.Command{
+		Use:          "nutanix",
+		Short:        "Creates basic functional NodePool resources for Nutanix AHV platform",
+		SilenceUsage: true,
+	}
+
+	cmd.Flags().StringVar(&platformOpts.Cluster, "cluster", platformOpts.Cluster, "The name or UUID of the Prism Element cluster to create the virtual machines in (required)")
+	cmd.Flags().StringVar(&platformOpts.Subnet, "subnet", platformOpts.Subnet, "The name or UUID of the subnet to attach the virtual machines to (required)")
+	cmd.Flags().StringVar(&platformOpts.Image, "image", platformOpts.Image, "The name or UUID of the RHCOS image to boot the virtual machines from (required)")
+	cmd.Flags().Int32Var(&platformOpts.VCPUSockets, "vcpu-sockets", platformOpts.VCPUSockets, "The number of vCPU sockets of the virtual machines")
+	cmd.Flags().Int32Var(&platformOpts.VCPUsPerSocket, "vcpus-per-socket", platformOpts.VCPUsPerSocket, "The number of vCPUs of each socket of the virtual machines")
+	cmd.Flags().Int64Var(&platformOpts.MemorySizeMiB, "memory-size", platformOpts.MemorySizeMiB, "The memory of the virtual machines in MiB (minimum 2048)")
+	cmd.Flags().Int64Var(&platformOpts.SystemDiskSizeGiB, "system-disk-size", platformOpts.SystemDiskSizeGiB, "The size of the system disk of the virtual machines in GiB (minimum 20)")
+
+	cmd.MarkFlagRequired("cluster")
+	cmd.MarkFlagRequired("subnet")
+	cmd.MarkFlagRequired("image")
+
+	cmd.RunE = coreOpts.CreateRunFunc(platformOpts)
+
+	return cmd
+}
+
+var uuidRegexp = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// resourceReference references a Nutanix resource by UUID when the value is a
+// UUID, and by name otherwise.
+func resourceReference(value string) hyperv1.NutanixResourceReference {
+	if uuidRegexp.MatchString(value) {
+		return hyperv1.NutanixResourceReference{Type: hyperv1.NutanixIdentifierUUID, UUID: value}
+	}
+	return hyperv1.NutanixResourceReference{Type: hyperv1.NutanixIdentifierName, Name: value}
+}
+
+func (o *NutanixPlatformCreateOptions) UpdateNodePool(_ context.Context, nodePool *hyperv1.NodePool, hcluster *hyperv1.HostedCluster, _ crclient.Client) error {
+	if hcluster.Spec.Platform.Nutanix == nil {
+		return fmt.Errorf("the HostedCluster %s has no Nutanix platform", hcluster.Name)
+	}
+	nodePool.Spec.Platform.Nutanix = &hyperv1.NutanixNodePoolPlatform{
+		Cluster:           resourceReference(o.Cluster),
+		Subnet:            resourceReference(o.Subnet),
+		Image:             resourceReference(o.Image),
+		VCPUSockets:       o.VCPUSockets,
+		VCPUsPerSocket:    o.VCPUsPerSocket,
+		MemorySizeMiB:     o.MemorySizeMiB,
+		SystemDiskSizeGiB: o.SystemDiskSizeGiB,
+	}
+	return nil
+}
+
+func (o *NutanixPlatformCreateOptions) Type() hyperv1.PlatformType {
+	return hyperv1.NutanixPlatform
+}
@@ -0,0 +1,33 @@
+package nutanix
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	hyperv1 "github.com/openshift/hypershift/api/v1alpha1"
+)
+
+func TestResourceReference(t *testing.T) {
+	testCases := []struct {
+		name     string
+		value    string
+		expected hyperv1.NutanixResourceReference
+	}{
+		{
+			name:     "uuid",
+			value:    "0005a0f4-6a7d-4b6c-8d4e-2f3c1a9b8e7d",
+			expected: hyperv1.NutanixResourceReference{Type: hyperv1.NutanixIdentifierUUID, UUID: "0005a0f4-6a7d-4b6c-8d4e-2f3c1a9b8e7d"},
+		},
+		{
+			name:     "name",
+			value:    "rhcos-4.12",
+			expected: hyperv1.NutanixResourceReference{Type: hyperv1.NutanixIdentifierName, Name: "rhcos-4.12"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewGomegaWithT(t)
+			g.Expect(resourceReference(tc.value)).To(Equal(tc.expected))
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package oci
+
+import (
+	"context"
+	"fmt"
+
+	hyperv1 "github.com/openshift/hypershift/api/v1alpha1"
+	"github.com/openshift/hypershift/cmd/nodepool/core"
+	"github.com/spf13/cobra"
+	crclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+type OCIPlatformCreateOptions struct {
+	Shape              string
+	OCPUs              int32
+	MemoryGB           int32
+	ImageID            string
+	AvailabilityDomain string
+	BootVolumeSizeGB   int64
+}
+
+func NewCreateCommand(coreOpts *core.CreateNodePoolOptions) *cobra.Command {
+	platformOpts := &OCIPlatformCreateOptions{
+		Shape:            "VM.Standard.E4.Flex",
+		OCPUs:            4,
+		MemoryGB:         16,
+		BootVolumeSizeGB: 120,
+	}
+	cmd := &cobra.Command{
+		Use:          "oci",
+		Short:        "Creates basic functional NodePool resources for OCI platform",
+		SilenceUsage: true,
+	}
+
+	cmd.Flags().StringVar(&platformOpts.Shape, "shape", platformOpts.Shape, "The shape of the instances")
+	cmd.Flags().Int32Var(&platformOpts.OCPUs, "ocpus", platformOpts.OCPUs, "The number of OCPUs of the instances, only used with flexible shapes")
+	cmd.Flags().Int32Var(&platformOpts.MemoryGB, "memory", platformOpts.MemoryGB, "The memory of the instances in GB, only used with flexible shapes")
+	cmd.Flags().StringVar(&platformOpts.ImageID, "image-id", platformOpts.ImageID, "The OCID of the RHCOS image the instances boot from (required)")
+	cmd.Flags().StringVar(&platformOpts.AvailabilityDomain, "availability-domain", platformOpts.AvailabilityDomain, "The availability domain of the instances, e.g. Uocm:US-ASHBURN-AD-1. The instances are spread across the availability domains of the region when empty")
+	cmd.Flags().Int64Var(&platformOpts.BootVolumeSizeGB, "boot-volume-size", platformOpts.BootVolumeSizeGB, "The size of the boot volume of the instances in GB (minimum 50)")
+
+	cmd.MarkFlagRequired("image-id")
+
+	cmd.RunE = coreOpts.CreateRunFunc(platformOpts)
+
+	return cmd
+}
+
+func (o *OCIPlatformCreateOptions) UpdateNodePool(_ context.Context, nodePool *hyperv1.NodePool, hcluster *hyperv1.HostedCluster, _ crclient.Client) error {
+	if hcluster.Spec.Platform.OCI == nil {
+		return fmt.Errorf("the HostedCluster %s has no OCI platform", hcluster.Name)
+	}
+	nodePool.Spec.Platform.OCI = &hyperv1.OCINodePoolPlatform{
+		Shape:              o.Shape,
+		OCPUs:              o.OCPUs,
+		MemoryGB:           o.MemoryGB,
+		ImageID:            o.ImageID,
+		AvailabilityDomain: o.AvailabilityDomain,
+		BootVolumeSizeGB:   o.BootVolumeSizeGB,
+	}
+	return nil
+}
+
+func (o *OCIPlatformCreateOptions) Type() hyperv1.PlatformType {
+	return hyperv1.OCIPlatform
+}
@@ -0,0 +1,19 @@
+package verify
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/openshift/hypershift/cmd/infra"
+)
+
+func NewCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:          "verify",
+		Short:        "Commands for verifying HyperShift resources",
+		SilenceUsage: true,
+	}
+
+	cmd.AddCommand(infra.NewVerifyCommand())
+
+	return cmd
+}
@@ -0,0 +1,78 @@
+package ibmcloudvpc
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	hyperv1 "github.com/openshift/hypershift/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestReconcileCCMConfigMap(t *testing.T) {
+	tests := map[string]struct {
+		vpc      *hyperv1.IBMCloudVPCNetwork
+		expected string
+	}{
+		"cloud config names the VPC and subnet of the nodes": {
+			vpc: &hyperv1.IBMCloudVPCNetwork{
+				Name:   "cluster1-vpc",
+				Subnet: hyperv1.IBMCloudVPCSubnet{Name: "cluster1-vpc-subnet"},
+			},
+			expected: `
+[global]
+version = 1.1.0
+[kubernetes]
+config-file = /etc/kubernetes/kubeconfig
+[provider]
+cluster-default-provider = g2
+accountID = account-id
+clusterID = cluster1
+g2workerServiceAccountID = account-id
+g2Credentials = /etc/vpc/ibmcloud_api_key
+g2ResourceGroupName = resource-group
+g2VpcSubnetNames = cluster1-vpc-subnet
+g2VpcName = cluster1-vpc
+region = us-south`,
+		},
+		"cloud config without a VPC leaves the VPC and subnet empty": {
+			expected: `
+[global]
+version = 1.1.0
+[kubernetes]
+config-file = /etc/kubernetes/kubeconfig
+[provider]
+cluster-default-provider = g2
+accountID = account-id
+clusterID = cluster1
+g2workerServiceAccountID = account-id
+g2Credentials = /etc/vpc/ibmcloud_api_key
+g2ResourceGroupName = resource-group
+g2VpcSubnetNames = 
+g2VpcName = 
+region = us-south`,
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			g := NewGomegaWithT(t)
+			hcp := &hyperv1.HostedControlPlane{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "master-cluster1", Name: "cluster1"},
+				Spec: hyperv1.HostedControlPlaneSpec{
+					Platform: hyperv1.PlatformSpec{
+						Type: hyperv1.IBMCloudVPCPlatform,
+						IBMCloudVPC: &hyperv1.IBMCloudVPCPlatformSpec{
+							AccountID:     "account-id",
+							ResourceGroup: "resource-group",
+							Region:        "us-south",
+							VPC:           test.vpc,
+						},
+					},
+				},
+			}
+			ccmConfig := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "master-cluster1", Name: "ccm-config"}}
+			g.Expect(ReconcileCCMConfigMap(ccmConfig, hcp)).To(Succeed())
+			g.Expect(ccmConfig.Data).To(HaveKeyWithValue("ccm-config", test.expected))
+		})
+	}
+}
@@ -0,0 +1,194 @@
+package nutanix
+
+import (
+	"encoding/json"
+	"fmt"
+
+	hyperv1 "github.com/openshift/hypershift/api/v1alpha1"
+	"github.com/openshift/hypershift/support/releaseinfo"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	utilpointer "k8s.io/utils/pointer"
+)
+
+const (
+	// CloudConfigKey is the key of the cloud config in the config map of the
+	// cloud controller manager.
+	CloudConfigKey = "nutanix_config.json"
+	// CredentialsSecretKey is the key of the Prism Central credentials in the
+	// credentials secrets.
+	CredentialsSecretKey = "credentials"
+	// CCMCredentialsNamespace and CCMCredentialsName are the secret in the
+	// guest cluster the cloud controller manager reads its credentials from.
+	CCMCredentialsNamespace = "openshift-cloud-controller-manager"
+	CCMCredentialsName      = "nutanix-credentials"
+
+	ccmContainerName       = "cloud-controller-manager"
+	kubeConfigFileBasePath = "/etc/kubernetes"
+	ccmConfigMapMountPath  = "/etc/nutanix"
+)
+
+// cloudConfig is the configuration of the Nutanix cloud controller manager.
+type cloudConfig struct {
+	PrismCentral         prismEndpoint     `json:"prismCentral"`
+	EnableCustomLabeling bool              `json:"enableCustomLabeling"`
+	TopologyDiscovery    topologyDiscovery `json:"topologyDiscovery"`
+}
+
+type prismEndpoint struct {
+	Address       string        `json:"address"`
+	Port          int32         `json:"port"`
+	CredentialRef credentialRef `json:"credentialRef"`
+}
+
+type credentialRef struct {
+	Kind      string `json:"kind"`
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+}
+
+type topologyDiscovery struct {
+	Type string `json:"type"`
+}
+
+func ReconcileCCMConfigMap(ccmConfig *corev1.ConfigMap, hcp *hyperv1.HostedControlPlane) error {
+	prismCentral := hcp.Spec.Platform.Nutanix.PrismCentral
+	config := cloudConfig{
+		PrismCentral: prismEndpoint{
+			Address: prismCentral.Address,
+			Port:    prismCentral.Port,
+			CredentialRef: credentialRef{
+				Kind:      "secret",
+				Name:      CCMCredentialsName,
+				Namespace: CCMCredentialsNamespace,
+			},
+		},
+		// The zones of the nodes are discovered from the Prism Elements
+		// they run on
+		TopologyDiscovery: topologyDiscovery{Type: "Prism"},
+	}
+
+	configData, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to serialize cloud config: %w", err)
+	}
+
+	if ccmConfig.Data == nil {
+		ccmConfig.Data = map[string]string{}
+	}
+
+	ccmConfig.Data[CloudConfigKey] = string(configData)
+
+	return nil
+}
+
+func ReconcileCCMDeployment(deployment *appsv1.Deployment, hcp *hyperv1.HostedControlPlane, ccmConfig *corev1.ConfigMap, releaseImage *releaseinfo.ReleaseImage) error {
+	commandToExec := []string{
+		"/bin/nutanix-cloud-controller-manager",
+		"--bind-address=$(POD_IP_ADDRESS)",
+		"--configure-cloud-routes=false",
+		"--cloud-provider=nutanix",
+		fmt.Sprintf("--cloud-config=%s/%s", ccmConfigMapMountPath, CloudConfigKey),
+		"--profiling=false",
+		"--leader-elect=true",
+		"--leader-elect-lease-duration=137s",
+		"--leader-elect-renew-deadline=107s",
+		"--leader-elect-retry-period=26s",
+		"--tls-cipher-suites=TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256,TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256,TLS_AES_128_GCM_SHA256,TLS_CHACHA20_POLY1305_SHA256,TLS_AES_256_GCM_SHA384",
+		fmt.Sprintf("--kubeconfig=%s/kubeconfig", kubeConfigFileBasePath),
+		"--use-service-account-credentials=false",
+	}
+
+	deployment.Spec = appsv1.DeploymentSpec{
+		Replicas: utilpointer.Int32Ptr(1),
+		Selector: &metav1.LabelSelector{
+			MatchLabels: map[string]string{"k8s-app": deployment.Name},
+		},
+		Template: corev1.PodTemplateSpec{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{"k8s-app": deployment.Name},
+			},
+			Spec: corev1.PodSpec{
+				TerminationGracePeriodSeconds: utilpointer.Int64Ptr(90),
+				Containers: []corev1.Container{
+					{
+						Name:            ccmContainerName,
+						Image:           releaseImage.ComponentImages()["nutanix-cloud-controller-manager"],
+						ImagePullPolicy: corev1.PullIfNotPresent,
+						Env: []corev1.EnvVar{
+							{
+								Name: "POD_IP_ADDRESS",
+								ValueFrom: &corev1.EnvVarSource{
+									FieldRef: &corev1.ObjectFieldSelector{
+										FieldPath: "status.podIP",
+									},
+								},
+							},
+						},
+						Command: commandToExec,
+						LivenessProbe: &corev1.Probe{
+							ProbeHandler: corev1.ProbeHandler{
+								HTTPGet: &corev1.HTTPGetAction{
+									Path:   "/healthz",
+									Port:   intstr.IntOrString{IntVal: 10258},
+									Scheme: "HTTPS",
+								},
+							},
+							InitialDelaySeconds: 300,
+							TimeoutSeconds:      5,
+						},
+						Ports: []corev1.ContainerPort{
+							{
+								Name:          "https",
+								Protocol:      "TCP",
+								ContainerPort: 10258,
+							},
+						},
+						Resources: corev1.ResourceRequirements{
+							Requests: corev1.ResourceList{
+								"cpu":    resource.MustParse("75m"),
+								"memory": resource.MustParse("60Mi"),
+							},
+						},
+						VolumeMounts: []corev1.VolumeMount{
+							{
+								Name:      hcp.Status.KubeConfig.Name,
+								MountPath: kubeConfigFileBasePath,
+							},
+							{
+								Name:      ccmConfig.Name,
+								MountPath: ccmConfigMapMountPath,
+							},
+						},
+					},
+				},
+				Volumes: []corev1.Volume{
+					{
+						Name: hcp.Status.KubeConfig.Name,
+						VolumeSource: corev1.VolumeSource{
+							Secret: &corev1.SecretVolumeSource{
+								SecretName:  hcp.Status.KubeConfig.Name,
+								DefaultMode: utilpointer.Int32Ptr(400),
+							},
+						},
+					},
+					{
+						Name: ccmConfig.Name,
+						VolumeSource: corev1.VolumeSource{
+							ConfigMap: &corev1.ConfigMapVolumeSource{
+								DefaultMode:          utilpointer.Int32Ptr(420),
+								LocalObjectReference: corev1.LocalObjectReference{Name: ccmConfig.Name},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	return nil
+}
@@ -0,0 +1,43 @@
+package nutanix
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	hyperv1 "github.com/openshift/hypershift/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestReconcileCCMConfigMap(t *testing.T) {
+	g := NewGomegaWithT(t)
+	hcp := &hyperv1.HostedControlPlane{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "master-cluster1", Name: "cluster1"},
+		Spec: hyperv1.HostedControlPlaneSpec{
+			Platform: hyperv1.PlatformSpec{
+				Type: hyperv1.NutanixPlatform,
+				Nutanix: &hyperv1.NutanixPlatformSpec{
+					PrismCentral: hyperv1.NutanixPrismEndpoint{Address: "prism-central.example.com", Port: 9440},
+				},
+			},
+		},
+	}
+	ccmConfig := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "master-cluster1", Name: "ccm-config"}}
+	g.Expect(ReconcileCCMConfigMap(ccmConfig, hcp)).To(Succeed())
+	g.Expect(ccmConfig.Data).To(HaveKey(CloudConfigKey))
+	g.Expect(ccmConfig.Data[CloudConfigKey]).To(MatchJSON(`{
+  "prismCentral": {
+    "address": "prism-central.example.com",
+    "port": 9440,
+    "credentialRef": {
+      "kind": "secret",
+      "name": "nutanix-credentials",
+      "namespace": "openshift-cloud-controller-manager"
+    }
+  },
+  "enableCustomLabeling": false,
+  "topologyDiscovery": {
+    "type": "Prism"
+  }
+}`))
+}
@@ -0,0 +1,229 @@
+package oci
+
+import (
+	"fmt"
+
+	hyperv1 "github.com/openshift/hypershift/api/v1alpha1"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	utilpointer "k8s.io/utils/pointer"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// CloudConfigKey is the key of the cloud provider config in the config
+	// secret of the cloud controller manager. The block volume CSI driver
+	// reads the same config.
+	CloudConfigKey = "cloud-provider.yaml"
+
+	// The keys of the API signing key in the credentials secrets
+	TenancyKey     = "tenancy"
+	UserKey        = "user"
+	FingerprintKey = "fingerprint"
+	PrivateKeyKey  = "privateKey"
+	PassphraseKey  = "passphrase"
+
+	// defaultCCMImage is the OCI cloud controller manager image used when the
+	// HostedControlPlane doesn't override it, the image is not part of the
+	// OpenShift release payload.
+	defaultCCMImage = "ghcr.io/oracle/cloud-provider-oci:v1.25.0"
+
+	ccmContainerName       = "cloud-controller-manager"
+	kubeConfigFileBasePath = "/etc/kubernetes"
+	ccmConfigMountPath     = "/etc/oci"
+)
+
+// cloudConfig is the configuration of the OCI cloud controller manager and
+// block volume CSI driver.
+type cloudConfig struct {
+	UseInstancePrincipals bool               `json:"useInstancePrincipals"`
+	Auth                  authConfig         `json:"auth"`
+	Compartment           string             `json:"compartment"`
+	VCN                   string             `json:"vcn"`
+	LoadBalancer          loadBalancerConfig `json:"loadBalancer"`
+}
+
+type authConfig struct {
+	Region      string `json:"region"`
+	Tenancy     string `json:"tenancy"`
+	User        string `json:"user"`
+	Key         string `json:"key"`
+	Passphrase  string `json:"passphrase,omitempty"`
+	Fingerprint string `json:"fingerprint"`
+}
+
+type loadBalancerConfig struct {
+	Subnet1 string `json:"subnet1"`
+	// SecurityListManagementMode is All so that the cloud controller manager
+	// opens the ports of services in the security lists of the subnets of the
+	// load balancers and nodes.
+	SecurityListManagementMode string `json:"securityListManagementMode"`
+}
+
+// ReconcileCCMConfigSecret renders the cloud provider config from the platform
+// of the HostedControlPlane and its cloud controller credentials. The config
+// includes the API signing key, so it is kept in a secret.
+func ReconcileCCMConfigSecret(ccmConfig *corev1.Secret, hcp *hyperv1.HostedControlPlane, credentials *corev1.Secret) error {
+	platform := hcp.Spec.Platform.OCI
+	auth := authConfig{
+		Region:     platform.Region,
+		Passphrase: string(credentials.Data[PassphraseKey]),
+	}
+	for key, value := range map[string]*string{
+		TenancyKey:     &auth.Tenancy,
+		UserKey:        &auth.User,
+		FingerprintKey: &auth.Fingerprint,
+		PrivateKeyKey:  &auth.Key,
+	} {
+		data, hasKey := credentials.Data[key]
+		if !hasKey {
+			return fmt.Errorf("cloud credentials secret %q is missing the %s key", credentials.Name, key)
+		}
+		*value = string(data)
+	}
+
+	loadBalancerSubnetID := platform.LoadBalancerSubnetID
+	if len(loadBalancerSubnetID) == 0 {
+		loadBalancerSubnetID = platform.SubnetID
+	}
+	config := cloudConfig{
+		Auth:        auth,
+		Compartment: platform.CompartmentID,
+		VCN:         platform.VCNID,
+		LoadBalancer: loadBalancerConfig{
+			Subnet1:                    loadBalancerSubnetID,
+			SecurityListManagementMode: "All",
+		},
+	}
+	configData, err := yaml.Marshal(config)
+	if err != nil {
+		return fmt.Errorf("failed to serialize cloud config: %w", err)
+	}
+
+	if ccmConfig.Data == nil {
+		ccmConfig.Data = map[string][]byte{}
+	}
+	ccmConfig.Type = corev1.SecretTypeOpaque
+	ccmConfig.Data[CloudConfigKey] = configData
+
+	return nil
+}
+
+func ReconcileCCMDeployment(deployment *appsv1.Deployment, hcp *hyperv1.HostedControlPlane, ccmConfig *corev1.Secret) error {
+	image := defaultCCMImage
+	if override, ok := hcp.Annotations[hyperv1.OCICloudControllerManagerImage]; ok {
+		image = override
+	}
+
+	commandToExec := []string{
+		"/usr/local/bin/oci-cloud-controller-manager",
+		"--bind-address=$(POD_IP_ADDRESS)",
+		"--cloud-provider=oci",
+		fmt.Sprintf("--cloud-config=%s/%s", ccmConfigMountPath, CloudConfigKey),
+		"--configure-cloud-routes=false",
+		"--concurrent-service-syncs=3",
+		"--profiling=false",
+		"--leader-elect=true",
+		"--leader-elect-resource-lock=leases",
+		"--leader-elect-lease-duration=137s",
+		"--leader-elect-renew-deadline=107s",
+		"--leader-elect-retry-period=26s",
+		fmt.Sprintf("--kubeconfig=%s/kubeconfig", kubeConfigFileBasePath),
+		fmt.Sprintf("--authentication-kubeconfig=%s/kubeconfig", kubeConfigFileBasePath),
+		fmt.Sprintf("--authorization-kubeconfig=%s/kubeconfig", kubeConfigFileBasePath),
+		"--use-service-account-credentials=false",
+	}
+
+	deployment.Spec = appsv1.DeploymentSpec{
+		Replicas: utilpointer.Int32Ptr(1),
+		Selector: &metav1.LabelSelector{
+			MatchLabels: map[string]string{"k8s-app": deployment.Name},
+		},
+		Template: corev1.PodTemplateSpec{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{"k8s-app": deployment.Name},
+			},
+			Spec: corev1.PodSpec{
+				TerminationGracePeriodSeconds: utilpointer.Int64Ptr(90),
+				Containers: []corev1.Container{
+					{
+						Name:            ccmContainerName,
+						Image:           image,
+						ImagePullPolicy: corev1.PullIfNotPresent,
+						Env: []corev1.EnvVar{
+							{
+								Name: "POD_IP_ADDRESS",
+								ValueFrom: &corev1.EnvVarSource{
+									FieldRef: &corev1.ObjectFieldSelector{
+										FieldPath: "status.podIP",
+									},
+								},
+							},
+						},
+						Command: commandToExec,
+						LivenessProbe: &corev1.Probe{
+							ProbeHandler: corev1.ProbeHandler{
+								HTTPGet: &corev1.HTTPGetAction{
+									Path:   "/healthz",
+									Port:   intstr.IntOrString{IntVal: 10258},
+									Scheme: "HTTPS",
+								},
+							},
+							InitialDelaySeconds: 300,
+							TimeoutSeconds:      5,
+						},
+						Ports: []corev1.ContainerPort{
+							{
+								Name:          "https",
+								Protocol:      "TCP",
+								ContainerPort: 10258,
+							},
+						},
+						Resources: corev1.ResourceRequirements{
+							Requests: corev1.ResourceList{
+								"cpu":    resource.MustParse("75m"),
+								"memory": resource.MustParse("60Mi"),
+							},
+						},
+						VolumeMounts: []corev1.VolumeMount{
+							{
+								Name:      hcp.Status.KubeConfig.Name,
+								MountPath: kubeConfigFileBasePath,
+							},
+							{
+								Name:      ccmConfig.Name,
+								MountPath: ccmConfigMountPath,
+							},
+						},
+					},
+				},
+				Volumes: []corev1.Volume{
+					{
+						Name: hcp.Status.KubeConfig.Name,
+						VolumeSource: corev1.VolumeSource{
+							Secret: &corev1.SecretVolumeSource{
+								SecretName:  hcp.Status.KubeConfig.Name,
+								DefaultMode: utilpointer.Int32Ptr(400),
+							},
+						},
+					},
+					{
+						Name: ccmConfig.Name,
+						VolumeSource: corev1.VolumeSource{
+							Secret: &corev1.SecretVolumeSource{
+								SecretName:  ccmConfig.Name,
+								DefaultMode: utilpointer.Int32Ptr(400),
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	return nil
+}
@@ -0,0 +1,39 @@
+package openstack
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	hyperv1 "github.com/openshift/hypershift/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestReconcileCCMConfigMap(t *testing.T) {
+	g := NewGomegaWithT(t)
+	hcp := &hyperv1.HostedControlPlane{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "master-cluster1", Name: "cluster1"},
+		Spec: hyperv1.HostedControlPlaneSpec{
+			Platform: hyperv1.PlatformSpec{
+				Type: hyperv1.OpenStackPlatform,
+				OpenStack: &hyperv1.OpenStackPlatformSpec{
+					CloudName:         "openstack",
+					ExternalNetworkID: "external-network-id",
+					NetworkID:         "network-id",
+					SubnetID:          "subnet-id",
+				},
+			},
+		},
+	}
+	ccmConfig := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "master-cluster1", Name: "ccm-config"}}
+	g.Expect(ReconcileCCMConfigMap(ccmConfig, hcp)).To(Succeed())
+	g.Expect(ccmConfig.Data).To(HaveKeyWithValue(CloudConfigKey, `[Global]
+use-clouds = true
+clouds-file = /etc/openstack/secret/clouds.yaml
+cloud = openstack
+
+[LoadBalancer]
+floating-network-id = external-network-id
+subnet-id = subnet-id
+`))
+}
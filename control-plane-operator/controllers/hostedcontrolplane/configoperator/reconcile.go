@@ -19,6 +19,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/utils/pointer"
+	kubevirtv1 "kubevirt.io/api/core/v1"
 	capiv1 "sigs.k8s.io/cluster-api/api/v1beta1"
 )
 
@@ -135,6 +136,38 @@ func ReconcileRole(role *rbacv1.Role, ownerRef config.OwnerRef) error {
 				"watch",
 			},
 		},
+		{
+			APIGroups: []string{capiv1.GroupVersion.Group},
+			Resources: []string{
+				"machinedeployments",
+			},
+			Verbs: []string{
+				"get",
+				"patch",
+				"list",
+				"watch",
+			},
+		},
+		{
+			APIGroups: []string{capiv1.GroupVersion.Group},
+			Resources: []string{
+				"machines",
+			},
+			Verbs: []string{
+				"delete",
+			},
+		},
+		{
+			APIGroups: []string{kubevirtv1.SchemeGroupVersion.Group},
+			Resources: []string{
+				"virtualmachineinstances",
+			},
+			Verbs: []string{
+				"get",
+				"list",
+				"watch",
+			},
+		},
 	}
 	return nil
 }
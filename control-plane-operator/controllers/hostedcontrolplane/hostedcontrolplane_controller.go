@@ -17,6 +17,10 @@ import (
 	"github.com/openshift/hypershift/control-plane-operator/controllers/hostedcontrolplane/autoscaler"
 	"github.com/openshift/hypershift/control-plane-operator/controllers/hostedcontrolplane/cloud/aws"
 	"github.com/openshift/hypershift/control-plane-operator/controllers/hostedcontrolplane/cloud/azure"
+	"github.com/openshift/hypershift/control-plane-operator/controllers/hostedcontrolplane/cloud/ibmcloudvpc"
+	"github.com/openshift/hypershift/control-plane-operator/controllers/hostedcontrolplane/cloud/nutanix"
+	"github.com/openshift/hypershift/control-plane-operator/controllers/hostedcontrolplane/cloud/oci"
+	"github.com/openshift/hypershift/control-plane-operator/controllers/hostedcontrolplane/cloud/openstack"
 	"github.com/openshift/hypershift/control-plane-operator/controllers/hostedcontrolplane/cloud/powervs"
 	"github.com/openshift/hypershift/control-plane-operator/controllers/hostedcontrolplane/clusterpolicy"
 	"github.com/openshift/hypershift/control-plane-operator/controllers/hostedcontrolplane/cno"
@@ -2709,6 +2713,68 @@ func (r *HostedControlPlaneReconciler) reconcileCloudControllerManager(ctx conte
 		}); err != nil {
 			return fmt.Errorf("failed to reconcile cloud controller manager deployment: %w", err)
 		}
+	case hyperv1.IBMCloudVPCPlatform:
+		ccmConfig := manifests.IBMCloudVPCCCMConfigMap(hcp.Namespace)
+		if _, err := createOrUpdate(ctx, r, ccmConfig, func() error {
+			return ibmcloudvpc.ReconcileCCMConfigMap(ccmConfig, hcp)
+		}); err != nil {
+			return fmt.Errorf("failed to reconcile cloud controller manager config: %w", err)
+		}
+
+		deployment := manifests.IBMCloudVPCCCMDeployment(hcp.Namespace)
+		if _, err := createOrUpdate(ctx, r, deployment, func() error {
+			return ibmcloudvpc.ReconcileCCMDeployment(deployment, hcp, ccmConfig, releaseImage)
+		}); err != nil {
+			return fmt.Errorf("failed to reconcile cloud controller manager deployment: %w", err)
+		}
+	case hyperv1.OpenStackPlatform:
+		ccmConfig := manifests.OpenStackCCMConfigMap(hcp.Namespace)
+		if _, err := createOrUpdate(ctx, r, ccmConfig, func() error {
+			return openstack.ReconcileCCMConfigMap(ccmConfig, hcp)
+		}); err != nil {
+			return fmt.Errorf("failed to reconcile cloud controller manager config: %w", err)
+		}
+
+		deployment := manifests.OpenStackCCMDeployment(hcp.Namespace)
+		if _, err := createOrUpdate(ctx, r, deployment, func() error {
+			return openstack.ReconcileCCMDeployment(deployment, hcp, ccmConfig, releaseImage)
+		}); err != nil {
+			return fmt.Errorf("failed to reconcile cloud controller manager deployment: %w", err)
+		}
+	case hyperv1.NutanixPlatform:
+		ccmConfig := manifests.NutanixCCMConfigMap(hcp.Namespace)
+		if _, err := createOrUpdate(ctx, r, ccmConfig, func() error {
+			return nutanix.ReconcileCCMConfigMap(ccmConfig, hcp)
+		}); err != nil {
+			return fmt.Errorf("failed to reconcile cloud controller manager config: %w", err)
+		}
+
+		deployment := manifests.NutanixCCMDeployment(hcp.Namespace)
+		if _, err := createOrUpdate(ctx, r, deployment, func() error {
+			return nutanix.ReconcileCCMDeployment(deployment, hcp, ccmConfig, releaseImage)
+		}); err != nil {
+			return fmt.Errorf("failed to reconcile cloud controller manager deployment: %w", err)
+		}
+	case hyperv1.OCIPlatform:
+		credentials := &corev1.Secret{}
+		credentialsName := hcp.Spec.Platform.OCI.KubeCloudControllerCreds.Name
+		if err := r.Get(ctx, client.ObjectKey{Namespace: hcp.Namespace, Name: credentialsName}, credentials); err != nil {
+			return fmt.Errorf("failed to get cloud controller credentials secret %s: %w", credentialsName, err)
+		}
+
+		ccmConfig := manifests.OCICCMConfigSecret(hcp.Namespace)
+		if _, err := createOrUpdate(ctx, r, ccmConfig, func() error {
+			return oci.ReconcileCCMConfigSecret(ccmConfig, hcp, credentials)
+		}); err != nil {
+			return fmt.Errorf("failed to reconcile cloud controller manager config: %w", err)
+		}
+
+		deployment := manifests.OCICCMDeployment(hcp.Namespace)
+		if _, err := createOrUpdate(ctx, r, deployment, func() error {
+			return oci.ReconcileCCMDeployment(deployment, hcp, ccmConfig)
+		}); err != nil {
+			return fmt.Errorf("failed to reconcile cloud controller manager deployment: %w", err)
+		}
 	}
 	return nil
 }
@@ -25,7 +25,10 @@ import (
 	"github.com/openshift/hypershift/control-plane-operator/hostedclusterconfigoperator/controllers/cmca"
 	"github.com/openshift/hypershift/control-plane-operator/hostedclusterconfigoperator/controllers/hcpstatus"
 	"github.com/openshift/hypershift/control-plane-operator/hostedclusterconfigoperator/controllers/inplaceupgrader"
+	"github.com/openshift/hypershift/control-plane-operator/hostedclusterconfigoperator/controllers/kubevirtmigration"
+	"github.com/openshift/hypershift/control-plane-operator/hostedclusterconfigoperator/controllers/nodelabelstaints"
 	"github.com/openshift/hypershift/control-plane-operator/hostedclusterconfigoperator/controllers/resources"
+	"github.com/openshift/hypershift/control-plane-operator/hostedclusterconfigoperator/controllers/spotinterruption"
 	"github.com/openshift/hypershift/control-plane-operator/hostedclusterconfigoperator/operator"
 	"github.com/openshift/hypershift/pkg/version"
 	"github.com/openshift/hypershift/support/labelenforcingclient"
@@ -49,10 +52,13 @@ func NewCommand() *cobra.Command {
 }
 
 var controllerFuncs = map[string]operator.ControllerSetupFunc{
-	"controller-manager-ca":  cmca.Setup,
-	resources.ControllerName: resources.Setup,
-	"inplaceupgrader":        inplaceupgrader.Setup,
-	hcpstatus.ControllerName: hcpstatus.Setup,
+	"controller-manager-ca":          cmca.Setup,
+	resources.ControllerName:         resources.Setup,
+	"inplaceupgrader":                inplaceupgrader.Setup,
+	hcpstatus.ControllerName:         hcpstatus.Setup,
+	kubevirtmigration.ControllerName: kubevirtmigration.Setup,
+	nodelabelstaints.ControllerName:  nodelabelstaints.Setup,
+	spotinterruption.ControllerName:  spotinterruption.Setup,
 }
 
 type HostedClusterConfigOperator struct {
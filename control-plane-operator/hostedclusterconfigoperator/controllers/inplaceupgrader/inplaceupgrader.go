@@ -3,15 +3,20 @@ package inplaceupgrader
 import (
 	"context"
 	"fmt"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/openshift/hypershift/control-plane-operator/hostedclusterconfigoperator/controllers/resources/manifests"
 	"github.com/openshift/hypershift/support/releaseinfo"
 	"github.com/openshift/hypershift/support/upsert"
 	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	kubeclient "k8s.io/client-go/kubernetes"
 	k8sutilspointer "k8s.io/utils/pointer"
 	capiv1 "sigs.k8s.io/cluster-api/api/v1beta1"
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -46,6 +51,17 @@ const (
 	nodePoolAnnotationCurrentConfigVersion   = "hypershift.openshift.io/nodePoolCurrentConfigVersion"
 	nodePoolAnnotationUpgradeInProgressTrue  = "hypershift.openshift.io/nodePoolUpgradeInProgressTrue"
 	nodePoolAnnotationUpgradeInProgressFalse = "hypershift.openshift.io/nodePoolUpgradeInProgressFalse"
+	nodePoolAnnotationMaxUnavailable         = "hypershift.openshift.io/nodePoolUpgradeMaxUnavailable"
+
+	// DrainerStateDrain and DrainerStateUncordon are the verbs of the drain/uncordon requests set by the MCD,
+	// e.g. drain-<config> and uncordon-<config>.
+	DrainerStateDrain    = "drain"
+	DrainerStateUncordon = "uncordon"
+
+	// mirrorPodAnnotation is set on the static pods of a node, which can't be evicted.
+	mirrorPodAnnotation = "kubernetes.io/config.mirror"
+	// drainRequeueInterval is how often a drain is checked while pods are being evicted.
+	drainRequeueInterval = 10 * time.Second
 
 	TokenSecretPayloadKey = "payload"
 	TokenSecretReleaseKey = "release"
@@ -54,9 +70,12 @@ const (
 type Reconciler struct {
 	client             client.Client
 	guestClusterClient client.Client
-	releaseProvider    releaseinfo.Provider
-	hcpName            string
-	hcpNamespace       string
+	// guestClusterAPIReader reads uncached, so listing the pods of a Node doesn't cache every pod of the guest cluster.
+	guestClusterAPIReader client.Reader
+	podEvictor            podEvictor
+	releaseProvider       releaseinfo.Provider
+	hcpName               string
+	hcpNamespace          string
 	upsert.CreateOrUpdateProvider
 }
 
@@ -104,13 +123,21 @@ func (r *Reconciler) Reconcile(ctx context.Context, req reconcile.Request) (reco
 		return ctrl.Result{RequeueAfter: 5 * time.Second}, nil
 	}
 
+	var maxUnavailable *intstr.IntOrString
+	if value, ok := machineSet.Annotations[nodePoolAnnotationMaxUnavailable]; ok {
+		parsed := intstr.Parse(value)
+		maxUnavailable = &parsed
+	}
+
 	nodePoolUpgradeAPI := &nodePoolUpgradeAPI{
 		spec: struct {
 			targetConfigVersion string
 			poolRef             *capiv1.MachineSet
+			maxUnavailable      *intstr.IntOrString
 		}{
 			targetConfigVersion: machineSet.Annotations[nodePoolAnnotationTargetConfigVersion],
 			poolRef:             machineSet,
+			maxUnavailable:      maxUnavailable,
 		},
 		status: struct {
 			currentConfigVersion string
@@ -125,13 +152,14 @@ func (r *Reconciler) Reconcile(ctx context.Context, req reconcile.Request) (reco
 	}
 	log.Info("discovered mco image", "image", mcoImage)
 
-	return ctrl.Result{}, r.reconcileInPlaceUpgrade(ctx, nodePoolUpgradeAPI, tokenSecret, mcoImage)
+	return r.reconcileInPlaceUpgrade(ctx, nodePoolUpgradeAPI, tokenSecret, mcoImage)
 }
 
 type nodePoolUpgradeAPI struct {
 	spec struct {
 		targetConfigVersion string
 		poolRef             *capiv1.MachineSet
+		maxUnavailable      *intstr.IntOrString
 	}
 	status struct {
 		currentConfigVersion string
@@ -139,19 +167,19 @@ type nodePoolUpgradeAPI struct {
 }
 
 // reconcileInPlaceUpgrade loops over all Nodes that belong to a NodePool and performs an in place upgrade if necessary.
-func (r *Reconciler) reconcileInPlaceUpgrade(ctx context.Context, nodePoolUpgradeAPI *nodePoolUpgradeAPI, tokenSecret *corev1.Secret, mcoImage string) error {
+func (r *Reconciler) reconcileInPlaceUpgrade(ctx context.Context, nodePoolUpgradeAPI *nodePoolUpgradeAPI, tokenSecret *corev1.Secret, mcoImage string) (ctrl.Result, error) {
 	log := ctrl.LoggerFrom(ctx)
 
 	currentConfigVersionHash := nodePoolUpgradeAPI.status.currentConfigVersion
 	targetConfigVersionHash := nodePoolUpgradeAPI.spec.targetConfigVersion
 	if targetConfigVersionHash == currentConfigVersionHash {
-		return nil
+		return ctrl.Result{}, nil
 	}
 	machineSet := nodePoolUpgradeAPI.spec.poolRef
 
 	nodes, err := getNodesForMachineSet(ctx, r.client, r.guestClusterClient, machineSet)
 	if err != nil {
-		return err
+		return ctrl.Result{}, err
 	}
 
 	// If all Nodes are atVersion.
@@ -159,7 +187,7 @@ func (r *Reconciler) reconcileInPlaceUpgrade(ctx context.Context, nodePoolUpgrad
 		// This pool should be at steady state, in which case, let's check and delete the upgrade manifests
 		// if any exists
 		if err := deleteUpgradeManifests(ctx, r.guestClusterClient, nodes, nodePoolUpgradeAPI.spec.poolRef.GetName()); err != nil {
-			return err
+			return ctrl.Result{}, err
 		}
 
 		// Signal in-place upgrade complete.
@@ -170,12 +198,12 @@ func (r *Reconciler) reconcileInPlaceUpgrade(ctx context.Context, nodePoolUpgrad
 			return nil
 		})
 		if err != nil {
-			return fmt.Errorf("failed to reconcile MachineSet: %w", err)
+			return ctrl.Result{}, fmt.Errorf("failed to reconcile MachineSet: %w", err)
 		} else {
 			log.Info("Reconciled MachineSet", "result", result)
 		}
 
-		return nil
+		return ctrl.Result{}, nil
 	}
 
 	// This check comes after the completion, so if no upgrades are in progress, if a node is degraded for
@@ -192,12 +220,12 @@ func (r *Reconciler) reconcileInPlaceUpgrade(ctx context.Context, nodePoolUpgrad
 				return nil
 			})
 			if err != nil {
-				return fmt.Errorf("failed to reconcile MachineSet: %w", err)
+				return ctrl.Result{}, fmt.Errorf("failed to reconcile MachineSet: %w", err)
 			} else {
 				log.Info("Reconciled MachineSet", "result", result)
 			}
 
-			return fmt.Errorf("degraded node found, cannot progress in-place upgrade. Degraded reason: %v", node.Annotations[MachineConfigDaemonMessageAnnotationKey])
+			return ctrl.Result{}, fmt.Errorf("degraded node found, cannot progress in-place upgrade. Degraded reason: %v", node.Annotations[MachineConfigDaemonMessageAnnotationKey])
 		}
 
 		if nodeNeedsUpgrade(node, currentConfigVersionHash, targetConfigVersionHash) {
@@ -208,11 +236,11 @@ func (r *Reconciler) reconcileInPlaceUpgrade(ctx context.Context, nodePoolUpgrad
 	// Signal in-place upgrade progress.
 	result, err := r.CreateOrUpdate(ctx, r.client, machineSet, func() error {
 		delete(machineSet.Annotations, nodePoolAnnotationUpgradeInProgressFalse)
-		machineSet.Annotations[nodePoolAnnotationUpgradeInProgressTrue] = fmt.Sprintf("Updating version in progress. Target version: %q. Total Nodes: %d. Upgraded: %d", *machineSet.Spec.Template.Spec.Version, len(nodes), len(nodes)-nodeNeedUpgradeCount)
+		machineSet.Annotations[nodePoolAnnotationUpgradeInProgressTrue] = inPlaceUpgradeProgressMessage(nodes, *machineSet.Spec.Template.Spec.Version, targetConfigVersionHash, nodeNeedUpgradeCount)
 		return nil
 	})
 	if err != nil {
-		return fmt.Errorf("failed to reconcile MachineSet: %w", err)
+		return ctrl.Result{}, fmt.Errorf("failed to reconcile MachineSet: %w", err)
 	} else {
 		log.Info("Reconciled MachineSet", "result", result)
 	}
@@ -220,42 +248,203 @@ func (r *Reconciler) reconcileInPlaceUpgrade(ctx context.Context, nodePoolUpgrad
 	// Create necessary upgrade manifests, if they do not exist
 	err = r.reconcileInPlaceUpgradeManifests(ctx, r.guestClusterClient, targetConfigVersionHash, tokenSecret.Data[TokenSecretPayloadKey], nodePoolUpgradeAPI.spec.poolRef.GetName())
 	if err != nil {
-		return fmt.Errorf("failed to create upgrade manifests in hosted cluster: %w", err)
+		return ctrl.Result{}, fmt.Errorf("failed to create upgrade manifests in hosted cluster: %w", err)
 	}
 
-	// Check the nodes to see if any need our help to progress drain
-	// TODO (jerzhang): actually implement drain logic, likely as separate goroutines to monitor success
+	// Check the nodes to see if any need our help to progress drain.
 	// TODO (jerzhang): consider what happens if the desiredConfig has changed since the node last upgraded
+	drainInProgress := false
 	for idx := range nodes {
-		if _, err := r.CreateOrUpdate(ctx, r.guestClusterClient, nodes[idx], func() error {
-			// TODO (jerzhang): delete the pod after we uncordon
-			// desiredVerb := strings.Split(desiredState, "-")[0]
-			// if desiredVerb == DrainerStateUncordon {
-			// }
-
-			// TODO (jerzhang): actually implement the node draining. For now, just set the singal and pretend we drained.
-			if nodes[idx].Annotations == nil {
-				nodes[idx].Annotations = map[string]string{}
-			}
-			nodes[idx].Annotations[LastAppliedDrainerAnnotationKey] = nodes[idx].Annotations[DesiredDrainerAnnotationKey]
-			return nil
-		}); err != nil {
-			return fmt.Errorf("failed to create upgrade manifests in hosted cluster: %w", err)
+		drained, err := r.reconcileNodeDrain(ctx, nodes[idx], nodePoolUpgradeAPI.spec.poolRef.GetName())
+		if err != nil {
+			return ctrl.Result{}, fmt.Errorf("failed to reconcile drain for node %s: %w", nodes[idx].Name, err)
+		}
+		if !drained {
+			drainInProgress = true
 		}
-		// TODO (jerzhang): in the future, consider exiting here and let future syncs handle post-drain functions
 	}
 
 	// Find nodes that can be upgraded
-	// TODO (jerzhang): add logic to honor maxUnavailable/maxSurge
-	nodesToUpgrade := getNodesToUpgrade(nodes, targetConfigVersionHash, 1)
+	maxUnavailable := getMaxUnavailable(nodePoolUpgradeAPI.spec.maxUnavailable, len(nodes))
+	nodesToUpgrade := getNodesToUpgrade(nodes, targetConfigVersionHash, maxUnavailable)
 	err = r.performNodesUpgrade(ctx, r.guestClusterClient, nodePoolUpgradeAPI.spec.poolRef.GetName(), nodesToUpgrade, targetConfigVersionHash, mcoImage)
 	if err != nil {
-		return fmt.Errorf("failed to set hosted nodes for inplace upgrade: %w", err)
+		return ctrl.Result{}, fmt.Errorf("failed to set hosted nodes for inplace upgrade: %w", err)
+	}
+
+	// Pod evictions don't trigger Node events, so check back on pending drains.
+	if drainInProgress {
+		return ctrl.Result{RequeueAfter: drainRequeueInterval}, nil
+	}
+	return ctrl.Result{}, nil
+}
+
+// reconcileNodeDrain honors the drain and uncordon requests the MCD sets on the Node while it updates it.
+// A drain cordons the Node and evicts its pods. It returns whether the Node has no drain in progress.
+func (r *Reconciler) reconcileNodeDrain(ctx context.Context, node *corev1.Node, poolName string) (bool, error) {
+	log := ctrl.LoggerFrom(ctx)
+
+	desiredState := node.Annotations[DesiredDrainerAnnotationKey]
+	if desiredState == "" || desiredState == node.Annotations[LastAppliedDrainerAnnotationKey] {
+		return true, nil
+	}
+
+	desiredVerb := strings.Split(desiredState, "-")[0]
+	switch desiredVerb {
+	case DrainerStateDrain:
+		if err := r.setNodeUnschedulable(ctx, node, true); err != nil {
+			return false, err
+		}
+		pods, err := podsToEvict(ctx, r.guestClusterAPIReader, node.Name, inPlaceUpgradeNamespace(poolName).Name)
+		if err != nil {
+			return false, err
+		}
+		if len(pods) > 0 {
+			for idx := range pods {
+				if pods[idx].DeletionTimestamp != nil {
+					continue
+				}
+				if err := r.podEvictor.Evict(ctx, &pods[idx]); err != nil {
+					// Evictions are refused with TooManyRequests while they would violate a PodDisruptionBudget.
+					if apierrors.IsTooManyRequests(err) || apierrors.IsNotFound(err) {
+						log.Info("Pod can't be evicted yet", "node", node.Name, "pod", client.ObjectKeyFromObject(&pods[idx]).String(), "reason", err.Error())
+						continue
+					}
+					return false, fmt.Errorf("failed to evict pod %s: %w", client.ObjectKeyFromObject(&pods[idx]).String(), err)
+				}
+			}
+			log.Info("Draining node", "node", node.Name, "pods", len(pods))
+			return false, nil
+		}
+	case DrainerStateUncordon:
+		if err := r.setNodeUnschedulable(ctx, node, false); err != nil {
+			return false, err
+		}
+	default:
+		return false, fmt.Errorf("unknown drain request %q", desiredState)
+	}
+
+	// Signal the MCD that the request has been applied.
+	if _, err := r.CreateOrUpdate(ctx, r.guestClusterClient, node, func() error {
+		node.Annotations[LastAppliedDrainerAnnotationKey] = desiredState
+		return nil
+	}); err != nil {
+		return false, fmt.Errorf("failed to reconcile node drain annotations: %w", err)
 	}
+	log.Info("Applied drain request", "node", node.Name, "request", desiredState)
+	return true, nil
+}
 
+func (r *Reconciler) setNodeUnschedulable(ctx context.Context, node *corev1.Node, unschedulable bool) error {
+	if node.Spec.Unschedulable == unschedulable {
+		return nil
+	}
+	if _, err := r.CreateOrUpdate(ctx, r.guestClusterClient, node, func() error {
+		node.Spec.Unschedulable = unschedulable
+		return nil
+	}); err != nil {
+		return fmt.Errorf("failed to set node unschedulable to %t: %w", unschedulable, err)
+	}
 	return nil
 }
 
+// podsToEvict returns the pods on the Node that a drain has to evict. DaemonSet pods, static pods and
+// the upgrade pods are kept as they are either recreated on the Node or drive the upgrade.
+func podsToEvict(ctx context.Context, c client.Reader, nodeName, upgradeNamespace string) ([]corev1.Pod, error) {
+	podList := &corev1.PodList{}
+	if err := c.List(ctx, podList, client.MatchingFields{"spec.nodeName": nodeName}); err != nil {
+		return nil, fmt.Errorf("failed to list pods on node %s: %w", nodeName, err)
+	}
+
+	var pods []corev1.Pod
+	for _, pod := range podList.Items {
+		if pod.Spec.NodeName != nodeName || pod.Namespace == upgradeNamespace {
+			continue
+		}
+		if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		if _, ok := pod.Annotations[mirrorPodAnnotation]; ok {
+			continue
+		}
+		if owner := metav1.GetControllerOf(&pod); owner != nil && owner.Kind == "DaemonSet" {
+			continue
+		}
+		pods = append(pods, pod)
+	}
+	return pods, nil
+}
+
+// podEvictor evicts pods honoring their PodDisruptionBudgets.
+type podEvictor interface {
+	Evict(ctx context.Context, pod *corev1.Pod) error
+}
+
+type kubeClientPodEvictor struct {
+	client kubeclient.Interface
+}
+
+func (e *kubeClientPodEvictor) Evict(ctx context.Context, pod *corev1.Pod) error {
+	return e.client.PolicyV1().Evictions(pod.Namespace).Evict(ctx, &policyv1.Eviction{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      pod.Name,
+			Namespace: pod.Namespace,
+		},
+	})
+}
+
+// getMaxUnavailable returns how many of the given number of Nodes can be upgraded at the same time.
+func getMaxUnavailable(maxUnavailable *intstr.IntOrString, nodeCount int) int {
+	if maxUnavailable == nil {
+		return 1
+	}
+	value, err := intstr.GetScaledValueFromIntOrPercent(maxUnavailable, nodeCount, false)
+	if err != nil || value < 1 {
+		return 1
+	}
+	return value
+}
+
+// inPlaceUpgradeProgressMessage reports the progress of the upgrade and the state of each Node being upgraded.
+func inPlaceUpgradeProgressMessage(nodes []*corev1.Node, targetVersion, targetConfigVersion string, nodeNeedUpgradeCount int) string {
+	message := fmt.Sprintf("Updating version in progress. Target version: %q. Total Nodes: %d. Upgraded: %d", targetVersion, len(nodes), len(nodes)-nodeNeedUpgradeCount)
+
+	var nodeStates []string
+	for _, node := range nodes {
+		if state := nodeUpgradeState(node, targetConfigVersion); state != "" {
+			nodeStates = append(nodeStates, fmt.Sprintf("%s (%s)", node.Name, state))
+		}
+	}
+	sort.Strings(nodeStates)
+	if len(nodeStates) > 0 {
+		message += fmt.Sprintf(". Updating: %s", strings.Join(nodeStates, ", "))
+	}
+	return message
+}
+
+// nodeUpgradeState returns the state of a Node being upgraded, or an empty string if it's not being upgraded.
+func nodeUpgradeState(node *corev1.Node, targetConfigVersion string) string {
+	if desiredState := node.Annotations[DesiredDrainerAnnotationKey]; desiredState != node.Annotations[LastAppliedDrainerAnnotationKey] {
+		switch strings.Split(desiredState, "-")[0] {
+		case DrainerStateDrain:
+			return "Draining"
+		case DrainerStateUncordon:
+			return "Uncordoning"
+		}
+	}
+	if node.Annotations[DesiredMachineConfigAnnotationKey] != targetConfigVersion {
+		return ""
+	}
+	if node.Annotations[CurrentMachineConfigAnnotationKey] == targetConfigVersion &&
+		node.Annotations[MachineConfigDaemonStateAnnotationKey] == MachineConfigDaemonStateDone {
+		return ""
+	}
+	if state := node.Annotations[MachineConfigDaemonStateAnnotationKey]; state != "" && state != MachineConfigDaemonStateDone {
+		return state
+	}
+	return "Pending"
+}
+
 func (r *Reconciler) performNodesUpgrade(ctx context.Context, hostedClusterClient client.Client, poolName string, nodes []*corev1.Node, targetConfigVersionHash, mcoImage string) error {
 	log := ctrl.LoggerFrom(ctx)
 
@@ -443,6 +632,9 @@ func getNodesToUpgrade(nodes []*corev1.Node, targetConfig string, maxUnavailable
 	if len(candidateNodes) == 0 {
 		return nil
 	}
+	if capacity > len(candidateNodes) {
+		capacity = len(candidateNodes)
+	}
 
 	// Not sure if we need to order this
 	return candidateNodes[:capacity]
@@ -5,8 +5,10 @@ import (
 	"testing"
 
 	. "github.com/onsi/gomega"
+	"github.com/openshift/hypershift/support/upsert"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/utils/pointer"
 	capiv1 "sigs.k8s.io/cluster-api/api/v1beta1"
@@ -363,6 +365,18 @@ func TestGetNodesToUpgrade(t *testing.T) {
 				awaitingNode1,
 			},
 		},
+		{
+			name: "maxUnavailable is greater than the nodes awaiting upgrade",
+			inputNodes: []*corev1.Node{
+				awaitingNode1,
+				completedNode,
+			},
+			targetConfig:   desiredConfigHash,
+			maxUnavailable: 3,
+			selectedNodes: []*corev1.Node{
+				awaitingNode1,
+			},
+		},
 		{
 			name: "pick correct nodes to upgrade",
 			inputNodes: []*corev1.Node{
@@ -386,3 +400,236 @@ func TestGetNodesToUpgrade(t *testing.T) {
 		})
 	}
 }
+
+type fakePodEvictor struct {
+	client  client.Client
+	evicted []string
+}
+
+func (e *fakePodEvictor) Evict(ctx context.Context, pod *corev1.Pod) error {
+	e.evicted = append(e.evicted, client.ObjectKeyFromObject(pod).String())
+	return e.client.Delete(ctx, pod)
+}
+
+func TestReconcileNodeDrain(t *testing.T) {
+	poolName := "pool"
+	drainRequest := "drain-xxx"
+	uncordonRequest := "uncordon-xxx"
+
+	workloadPod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "workload",
+			Name:      "workload",
+		},
+		Spec: corev1.PodSpec{
+			NodeName: "node",
+		},
+	}
+	otherNodePod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "workload",
+			Name:      "other-node",
+		},
+		Spec: corev1.PodSpec{
+			NodeName: "other",
+		},
+	}
+	daemonSetPod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "workload",
+			Name:      "daemonset",
+			OwnerReferences: []metav1.OwnerReference{
+				{
+					Kind:       "DaemonSet",
+					Name:       "daemonset",
+					Controller: pointer.Bool(true),
+				},
+			},
+		},
+		Spec: corev1.PodSpec{
+			NodeName: "node",
+		},
+	}
+	mirrorPod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "workload",
+			Name:      "mirror",
+			Annotations: map[string]string{
+				mirrorPodAnnotation: "",
+			},
+		},
+		Spec: corev1.PodSpec{
+			NodeName: "node",
+		},
+	}
+	upgradePod := inPlaceUpgradePod(inPlaceUpgradeNamespace(poolName).Name, "node")
+	upgradePod.Spec.NodeName = "node"
+
+	testCases := []struct {
+		name                   string
+		desiredState           string
+		unschedulable          bool
+		pods                   []client.Object
+		expectDrained          bool
+		expectEvicted          []string
+		expectUnschedulable    bool
+		expectLastAppliedState string
+	}{
+		{
+			name:                   "no drain request",
+			expectDrained:          true,
+			expectUnschedulable:    false,
+			expectLastAppliedState: "",
+		},
+		{
+			name:                   "drain request cordons the node and evicts its pods",
+			desiredState:           drainRequest,
+			pods:                   []client.Object{workloadPod.DeepCopy(), otherNodePod.DeepCopy(), daemonSetPod.DeepCopy(), mirrorPod.DeepCopy(), upgradePod.DeepCopy()},
+			expectDrained:          false,
+			expectEvicted:          []string{"workload/workload"},
+			expectUnschedulable:    true,
+			expectLastAppliedState: "",
+		},
+		{
+			name:                   "drain request is applied once no pods have to be evicted",
+			desiredState:           drainRequest,
+			pods:                   []client.Object{otherNodePod.DeepCopy(), daemonSetPod.DeepCopy(), mirrorPod.DeepCopy(), upgradePod.DeepCopy()},
+			expectDrained:          true,
+			expectUnschedulable:    true,
+			expectLastAppliedState: drainRequest,
+		},
+		{
+			name:                   "uncordon request uncordons the node",
+			desiredState:           uncordonRequest,
+			unschedulable:          true,
+			expectDrained:          true,
+			expectUnschedulable:    false,
+			expectLastAppliedState: uncordonRequest,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+			node := &corev1.Node{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "node",
+					Annotations: map[string]string{
+						DesiredDrainerAnnotationKey: tc.desiredState,
+					},
+				},
+				Spec: corev1.NodeSpec{
+					Unschedulable: tc.unschedulable,
+				},
+			}
+			c := fake.NewClientBuilder().WithObjects(node).WithObjects(tc.pods...).Build()
+			evictor := &fakePodEvictor{client: c}
+			r := &Reconciler{
+				guestClusterClient:     c,
+				guestClusterAPIReader:  c,
+				podEvictor:             evictor,
+				CreateOrUpdateProvider: upsert.New(false),
+			}
+
+			drained, err := r.reconcileNodeDrain(context.Background(), node, poolName)
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(drained).To(Equal(tc.expectDrained))
+			g.Expect(evictor.evicted).To(Equal(tc.expectEvicted))
+
+			got := &corev1.Node{}
+			g.Expect(c.Get(context.Background(), client.ObjectKeyFromObject(node), got)).To(Succeed())
+			g.Expect(got.Spec.Unschedulable).To(Equal(tc.expectUnschedulable))
+			g.Expect(got.Annotations[LastAppliedDrainerAnnotationKey]).To(Equal(tc.expectLastAppliedState))
+		})
+	}
+}
+
+func TestGetMaxUnavailable(t *testing.T) {
+	testCases := []struct {
+		name           string
+		maxUnavailable *intstr.IntOrString
+		nodeCount      int
+		expected       int
+	}{
+		{
+			name:      "defaults to 1",
+			nodeCount: 10,
+			expected:  1,
+		},
+		{
+			name:           "absolute number",
+			maxUnavailable: intstrPtr(intstr.FromInt(3)),
+			nodeCount:      10,
+			expected:       3,
+		},
+		{
+			name:           "percentage rounds down",
+			maxUnavailable: intstrPtr(intstr.FromString("25%")),
+			nodeCount:      10,
+			expected:       2,
+		},
+		{
+			name:           "percentage has a minimum of 1",
+			maxUnavailable: intstrPtr(intstr.FromString("10%")),
+			nodeCount:      5,
+			expected:       1,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+			g.Expect(getMaxUnavailable(tc.maxUnavailable, tc.nodeCount)).To(Equal(tc.expected))
+		})
+	}
+}
+
+func intstrPtr(value intstr.IntOrString) *intstr.IntOrString {
+	return &value
+}
+
+func TestInPlaceUpgradeProgressMessage(t *testing.T) {
+	g := NewWithT(t)
+	target := "target"
+	nodes := []*corev1.Node{
+		{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "updated",
+				Annotations: map[string]string{
+					CurrentMachineConfigAnnotationKey:     target,
+					DesiredMachineConfigAnnotationKey:     target,
+					MachineConfigDaemonStateAnnotationKey: MachineConfigDaemonStateDone,
+				},
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "working",
+				Annotations: map[string]string{
+					CurrentMachineConfigAnnotationKey:     "current",
+					DesiredMachineConfigAnnotationKey:     target,
+					MachineConfigDaemonStateAnnotationKey: "Working",
+				},
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "draining",
+				Annotations: map[string]string{
+					CurrentMachineConfigAnnotationKey: "current",
+					DesiredMachineConfigAnnotationKey: target,
+					DesiredDrainerAnnotationKey:       "drain-xxx",
+				},
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "waiting",
+				Annotations: map[string]string{
+					CurrentMachineConfigAnnotationKey: "current",
+					DesiredMachineConfigAnnotationKey: "current",
+				},
+			},
+		},
+	}
+	g.Expect(inPlaceUpgradeProgressMessage(nodes, "4.12.0", target, 3)).To(Equal(
+		`Updating version in progress. Target version: "4.12.0". Total Nodes: 4. Upgraded: 1. Updating: draining (Draining), working (Working)`))
+}
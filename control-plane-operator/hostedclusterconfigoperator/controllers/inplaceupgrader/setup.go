@@ -5,6 +5,7 @@ import (
 
 	"github.com/openshift/hypershift/control-plane-operator/hostedclusterconfigoperator/operator"
 	corev1 "k8s.io/api/core/v1"
+	kubeclient "k8s.io/client-go/kubernetes"
 	capiv1 "sigs.k8s.io/cluster-api/api/v1beta1"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
@@ -12,9 +13,15 @@ import (
 )
 
 func Setup(opts *operator.HostedClusterConfigOperatorConfig) error {
+	guestClusterKubeClient, err := kubeclient.NewForConfig(opts.TargetConfig)
+	if err != nil {
+		return fmt.Errorf("failed to construct guest cluster kube client: %w", err)
+	}
 	r := &Reconciler{
 		client:                 opts.CPCluster.GetClient(),
 		guestClusterClient:     opts.Manager.GetClient(),
+		guestClusterAPIReader:  opts.Manager.GetAPIReader(),
+		podEvictor:             &kubeClientPodEvictor{client: guestClusterKubeClient},
 		releaseProvider:        opts.ReleaseProvider,
 		hcpName:                opts.HCPName,
 		hcpNamespace:           opts.Namespace,
@@ -0,0 +1,108 @@
+package kubevirtmigration
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	kubevirtv1 "kubevirt.io/api/core/v1"
+	capiv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const (
+	// cordonedForMigrationAnnotation is set on the guest nodes this controller
+	// cordoned, so that it only uncordons the nodes it cordoned itself.
+	cordonedForMigrationAnnotation = "hypershift.openshift.io/cordoned-for-kubevirt-migration"
+)
+
+// Reconciler cordons the guest nodes whose VMs are live migrated off their infra
+// nodes, so that no new workloads are scheduled onto them until the migration is
+// done, and uncordons them afterwards.
+type Reconciler struct {
+	client             client.Client
+	guestClusterClient client.Client
+}
+
+func (r *Reconciler) Reconcile(ctx context.Context, req reconcile.Request) (reconcile.Result, error) {
+	log := ctrl.LoggerFrom(ctx)
+
+	vmi := &kubevirtv1.VirtualMachineInstance{}
+	if err := r.client.Get(ctx, req.NamespacedName, vmi); err != nil {
+		if apierrors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, fmt.Errorf("failed to get VirtualMachineInstance: %w", err)
+	}
+
+	nodeName, err := r.guestNodeName(ctx, vmi)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if nodeName == "" {
+		log.V(3).Info("VM has no guest node yet. No-op")
+		return ctrl.Result{}, nil
+	}
+	node := &corev1.Node{}
+	if err := r.guestClusterClient.Get(ctx, client.ObjectKey{Name: nodeName}, node); err != nil {
+		if apierrors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, fmt.Errorf("failed to get node %s: %w", nodeName, err)
+	}
+
+	_, cordonedForMigration := node.Annotations[cordonedForMigrationAnnotation]
+	switch {
+	case isMigrating(vmi) && !node.Spec.Unschedulable:
+		log.Info("Cordoning node while its VM is live migrated", "node", node.Name)
+		original := node.DeepCopy()
+		node.Spec.Unschedulable = true
+		if node.Annotations == nil {
+			node.Annotations = map[string]string{}
+		}
+		node.Annotations[cordonedForMigrationAnnotation] = ""
+		if err := r.guestClusterClient.Patch(ctx, node, client.MergeFrom(original)); err != nil {
+			return ctrl.Result{}, fmt.Errorf("failed to cordon node %s: %w", node.Name, err)
+		}
+	case !isMigrating(vmi) && cordonedForMigration:
+		log.Info("Uncordoning node after its VM was live migrated", "node", node.Name)
+		original := node.DeepCopy()
+		node.Spec.Unschedulable = false
+		delete(node.Annotations, cordonedForMigrationAnnotation)
+		if err := r.guestClusterClient.Patch(ctx, node, client.MergeFrom(original)); err != nil {
+			return ctrl.Result{}, fmt.Errorf("failed to uncordon node %s: %w", node.Name, err)
+		}
+	}
+	return ctrl.Result{}, nil
+}
+
+// guestNodeName returns the name of the guest node of the VM from its Machine.
+// The VMs are named after their KubevirtMachines.
+func (r *Reconciler) guestNodeName(ctx context.Context, vmi *kubevirtv1.VirtualMachineInstance) (string, error) {
+	machines := &capiv1.MachineList{}
+	if err := r.client.List(ctx, machines, client.InNamespace(vmi.Namespace)); err != nil {
+		return "", fmt.Errorf("failed to list machines: %w", err)
+	}
+	for _, machine := range machines.Items {
+		if machine.Spec.InfrastructureRef.Kind == "KubevirtMachine" && machine.Spec.InfrastructureRef.Name == vmi.Name {
+			if machine.Status.NodeRef == nil {
+				return "", nil
+			}
+			return machine.Status.NodeRef.Name, nil
+		}
+	}
+	return "", nil
+}
+
+// isMigrating returns whether the VM is being live migrated, or is about to be
+// because its infra node is being drained.
+func isMigrating(vmi *kubevirtv1.VirtualMachineInstance) bool {
+	if vmi.IsMarkedForEviction() {
+		return true
+	}
+	state := vmi.Status.MigrationState
+	return state != nil && !state.Completed && !state.Failed
+}
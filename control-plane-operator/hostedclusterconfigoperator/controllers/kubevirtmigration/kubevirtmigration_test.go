@@ -0,0 +1,122 @@
+package kubevirtmigration
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	kubevirtv1 "kubevirt.io/api/core/v1"
+	capiv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestReconcile(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	_ = capiv1.AddToScheme(scheme)
+	_ = kubevirtv1.AddToScheme(scheme)
+
+	machine := &capiv1.Machine{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "hcp", Name: "machine"},
+		Spec: capiv1.MachineSpec{
+			InfrastructureRef: corev1.ObjectReference{Kind: "KubevirtMachine", Name: "vm"},
+		},
+		Status: capiv1.MachineStatus{
+			NodeRef: &corev1.ObjectReference{Name: "node"},
+		},
+	}
+
+	testCases := []struct {
+		name                  string
+		vmiStatus             kubevirtv1.VirtualMachineInstanceStatus
+		node                  *corev1.Node
+		expectedUnschedulable bool
+		expectedAnnotation    bool
+	}{
+		{
+			name:      "When the VM is marked for eviction it should cordon the node",
+			vmiStatus: kubevirtv1.VirtualMachineInstanceStatus{EvacuationNodeName: "infra"},
+			node: &corev1.Node{
+				ObjectMeta: metav1.ObjectMeta{Name: "node"},
+			},
+			expectedUnschedulable: true,
+			expectedAnnotation:    true,
+		},
+		{
+			name: "When the VM is being migrated it should cordon the node",
+			vmiStatus: kubevirtv1.VirtualMachineInstanceStatus{
+				MigrationState: &kubevirtv1.VirtualMachineInstanceMigrationState{SourceNode: "infra"},
+			},
+			node: &corev1.Node{
+				ObjectMeta: metav1.ObjectMeta{Name: "node"},
+			},
+			expectedUnschedulable: true,
+			expectedAnnotation:    true,
+		},
+		{
+			name: "When the migration completed it should uncordon the node it cordoned",
+			vmiStatus: kubevirtv1.VirtualMachineInstanceStatus{
+				MigrationState: &kubevirtv1.VirtualMachineInstanceMigrationState{Completed: true},
+			},
+			node: &corev1.Node{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "node",
+					Annotations: map[string]string{cordonedForMigrationAnnotation: ""},
+				},
+				Spec: corev1.NodeSpec{Unschedulable: true},
+			},
+			expectedUnschedulable: false,
+			expectedAnnotation:    false,
+		},
+		{
+			name: "When the migration completed it should leave a node cordoned by someone else cordoned",
+			vmiStatus: kubevirtv1.VirtualMachineInstanceStatus{
+				MigrationState: &kubevirtv1.VirtualMachineInstanceMigrationState{Completed: true},
+			},
+			node: &corev1.Node{
+				ObjectMeta: metav1.ObjectMeta{Name: "node"},
+				Spec:       corev1.NodeSpec{Unschedulable: true},
+			},
+			expectedUnschedulable: true,
+			expectedAnnotation:    false,
+		},
+		{
+			name:      "When the VM is not migrating it should not cordon the node",
+			vmiStatus: kubevirtv1.VirtualMachineInstanceStatus{},
+			node: &corev1.Node{
+				ObjectMeta: metav1.ObjectMeta{Name: "node"},
+			},
+			expectedUnschedulable: false,
+			expectedAnnotation:    false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+			vmi := &kubevirtv1.VirtualMachineInstance{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "hcp", Name: "vm"},
+				Status:     tc.vmiStatus,
+			}
+			r := &Reconciler{
+				client:             fake.NewClientBuilder().WithScheme(scheme).WithObjects(vmi, machine.DeepCopy()).Build(),
+				guestClusterClient: fake.NewClientBuilder().WithScheme(scheme).WithObjects(tc.node).Build(),
+			}
+
+			_, err := r.Reconcile(context.Background(), reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "hcp", Name: "vm"}})
+			g.Expect(err).ToNot(HaveOccurred())
+
+			node := &corev1.Node{}
+			g.Expect(r.guestClusterClient.Get(context.Background(), client.ObjectKey{Name: "node"}, node)).To(Succeed())
+			g.Expect(node.Spec.Unschedulable).To(Equal(tc.expectedUnschedulable))
+			_, hasAnnotation := node.Annotations[cordonedForMigrationAnnotation]
+			g.Expect(hasAnnotation).To(Equal(tc.expectedAnnotation))
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package kubevirtmigration
+
+import (
+	"context"
+	"fmt"
+
+	hyperv1 "github.com/openshift/hypershift/api/v1alpha1"
+	"github.com/openshift/hypershift/control-plane-operator/hostedclusterconfigoperator/operator"
+	kubevirtv1 "kubevirt.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+const ControllerName = "kubevirtmigration"
+
+func Setup(opts *operator.HostedClusterConfigOperatorConfig) error {
+	// The VMs only exist for KubeVirt clusters, and their CRDs may not be
+	// installed on the management cluster otherwise.
+	if opts.PlatformType != hyperv1.KubevirtPlatform {
+		return nil
+	}
+	// The VMs of an external infra cluster are not visible from the management
+	// cluster, so their migrations can not be followed.
+	hcp := &hyperv1.HostedControlPlane{}
+	if err := opts.CPCluster.GetAPIReader().Get(context.Background(), client.ObjectKey{Namespace: opts.Namespace, Name: opts.HCPName}, hcp); err != nil {
+		return fmt.Errorf("failed to get hosted control plane: %w", err)
+	}
+	if kubevirt := hcp.Spec.Platform.Kubevirt; kubevirt != nil && kubevirt.Credentials != nil {
+		opts.Logger.Info("Not cordoning the nodes of migrating VMs, which is not supported for VMs on an external infra cluster")
+		return nil
+	}
+	r := &Reconciler{
+		client:             opts.CPCluster.GetClient(),
+		guestClusterClient: opts.Manager.GetClient(),
+	}
+	c, err := controller.New(ControllerName, opts.Manager, controller.Options{Reconciler: r})
+	if err != nil {
+		return fmt.Errorf("failed to construct controller: %w", err)
+	}
+
+	if err := c.Watch(source.NewKindWithCache(&kubevirtv1.VirtualMachineInstance{}, opts.CPCluster.GetCache()), &handler.EnqueueRequestForObject{}); err != nil {
+		return fmt.Errorf("failed to watch VirtualMachineInstances: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,273 @@
+package nodelabelstaints
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	capiv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const (
+	// The NodePool controller sets these annotations on the MachineDeployment or
+	// MachineSet of a NodePool to signal the labels and taints of its nodes.
+	nodePoolAnnotationNodeLabels = "hypershift.openshift.io/nodePoolNodeLabels"
+	nodePoolAnnotationTaints     = "hypershift.openshift.io/nodePoolTaints"
+
+	// managedNodeLabelsAnnotation and managedTaintsAnnotation record on the nodes the
+	// labels and taints set by this controller, so that it only removes those.
+	managedNodeLabelsAnnotation = "hypershift.openshift.io/managed-node-labels"
+	managedTaintsAnnotation     = "hypershift.openshift.io/managed-taints"
+)
+
+// Reconciler keeps the labels and taints of the guest nodes in sync with the
+// NodePool they belong to.
+type Reconciler struct {
+	client             client.Client
+	guestClusterClient client.Client
+}
+
+func (r *Reconciler) Reconcile(ctx context.Context, req reconcile.Request) (reconcile.Result, error) {
+	log := ctrl.LoggerFrom(ctx)
+
+	node := &corev1.Node{}
+	if err := r.guestClusterClient.Get(ctx, req.NamespacedName, node); err != nil {
+		if apierrors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, fmt.Errorf("failed to get node: %w", err)
+	}
+
+	source, err := r.nodePoolSource(ctx, node)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if source == nil {
+		log.V(3).Info("Node does not belong to a NodePool yet. No-op")
+		return ctrl.Result{}, nil
+	}
+
+	var labels map[string]string
+	if value, ok := source.GetAnnotations()[nodePoolAnnotationNodeLabels]; ok {
+		if err := json.Unmarshal([]byte(value), &labels); err != nil {
+			return ctrl.Result{}, fmt.Errorf("failed to unmarshal node labels of %s: %w", client.ObjectKeyFromObject(source), err)
+		}
+	}
+	var taints []corev1.Taint
+	if value, ok := source.GetAnnotations()[nodePoolAnnotationTaints]; ok {
+		if err := json.Unmarshal([]byte(value), &taints); err != nil {
+			return ctrl.Result{}, fmt.Errorf("failed to unmarshal taints of %s: %w", client.ObjectKeyFromObject(source), err)
+		}
+	}
+
+	original := node.DeepCopy()
+	if err := reconcileNodeLabelsAndTaints(node, labels, taints); err != nil {
+		return ctrl.Result{}, err
+	}
+	if equalLabelsAndTaints(original, node) {
+		return ctrl.Result{}, nil
+	}
+	if err := r.guestClusterClient.Patch(ctx, node, client.MergeFrom(original)); err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to patch node %s: %w", node.Name, err)
+	}
+	log.Info("Reconciled node labels and taints", "node", node.Name)
+	return ctrl.Result{}, nil
+}
+
+// nodePoolSource returns the MachineDeployment, or the MachineSet for in-place
+// NodePools, that carries the labels and taints of the NodePool of the node.
+func (r *Reconciler) nodePoolSource(ctx context.Context, node *corev1.Node) (client.Object, error) {
+	machineName, ok := node.GetAnnotations()[capiv1.MachineAnnotation]
+	if !ok {
+		return nil, nil
+	}
+	machineNamespace, ok := node.GetAnnotations()[capiv1.ClusterNamespaceAnnotation]
+	if !ok {
+		return nil, nil
+	}
+
+	machine := &capiv1.Machine{}
+	if err := r.client.Get(ctx, client.ObjectKey{Namespace: machineNamespace, Name: machineName}, machine); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get machine %s/%s: %w", machineNamespace, machineName, err)
+	}
+	machineOwner := metav1.GetControllerOf(machine)
+	if machineOwner == nil || machineOwner.Kind != "MachineSet" {
+		return nil, nil
+	}
+
+	machineSet := &capiv1.MachineSet{}
+	if err := r.client.Get(ctx, client.ObjectKey{Namespace: machineNamespace, Name: machineOwner.Name}, machineSet); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get MachineSet %s/%s: %w", machineNamespace, machineOwner.Name, err)
+	}
+	machineSetOwner := metav1.GetControllerOf(machineSet)
+	if machineSetOwner == nil || machineSetOwner.Kind != "MachineDeployment" {
+		return machineSet, nil
+	}
+
+	machineDeployment := &capiv1.MachineDeployment{}
+	if err := r.client.Get(ctx, client.ObjectKey{Namespace: machineNamespace, Name: machineSetOwner.Name}, machineDeployment); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get MachineDeployment %s/%s: %w", machineNamespace, machineSetOwner.Name, err)
+	}
+	return machineDeployment, nil
+}
+
+// reconcileNodeLabelsAndTaints sets the given labels and taints on the node and removes
+// the ones this controller set before that are no longer wanted.
+func reconcileNodeLabelsAndTaints(node *corev1.Node, labels map[string]string, taints []corev1.Taint) error {
+	if node.Annotations == nil {
+		node.Annotations = map[string]string{}
+	}
+	if node.Labels == nil {
+		node.Labels = map[string]string{}
+	}
+
+	var managedLabels []string
+	if value, ok := node.Annotations[managedNodeLabelsAnnotation]; ok {
+		if err := json.Unmarshal([]byte(value), &managedLabels); err != nil {
+			return fmt.Errorf("failed to unmarshal managed node labels: %w", err)
+		}
+	}
+	for _, key := range managedLabels {
+		if _, ok := labels[key]; !ok {
+			delete(node.Labels, key)
+		}
+	}
+	managedLabels = make([]string, 0, len(labels))
+	for key, value := range labels {
+		node.Labels[key] = value
+		managedLabels = append(managedLabels, key)
+	}
+	sort.Strings(managedLabels)
+
+	var managedTaints []corev1.Taint
+	if value, ok := node.Annotations[managedTaintsAnnotation]; ok {
+		if err := json.Unmarshal([]byte(value), &managedTaints); err != nil {
+			return fmt.Errorf("failed to unmarshal managed taints: %w", err)
+		}
+	}
+	var nodeTaints []corev1.Taint
+	for _, nodeTaint := range node.Spec.Taints {
+		if containsTaint(managedTaints, nodeTaint) && !containsTaint(taints, nodeTaint) {
+			continue
+		}
+		nodeTaints = append(nodeTaints, nodeTaint)
+	}
+	for _, taint := range taints {
+		found := false
+		for i := range nodeTaints {
+			if nodeTaints[i].MatchTaint(&taint) {
+				nodeTaints[i].Value = taint.Value
+				found = true
+				break
+			}
+		}
+		if !found {
+			nodeTaints = append(nodeTaints, taint)
+		}
+	}
+	node.Spec.Taints = nodeTaints
+
+	if len(managedLabels) > 0 {
+		value, err := json.Marshal(managedLabels)
+		if err != nil {
+			return fmt.Errorf("failed to marshal managed node labels: %w", err)
+		}
+		node.Annotations[managedNodeLabelsAnnotation] = string(value)
+	} else {
+		delete(node.Annotations, managedNodeLabelsAnnotation)
+	}
+	if len(taints) > 0 {
+		value, err := json.Marshal(taints)
+		if err != nil {
+			return fmt.Errorf("failed to marshal managed taints: %w", err)
+		}
+		node.Annotations[managedTaintsAnnotation] = string(value)
+	} else {
+		delete(node.Annotations, managedTaintsAnnotation)
+	}
+	return nil
+}
+
+// containsTaint returns true if the taints contain a taint with the key and effect of the given one.
+func containsTaint(taints []corev1.Taint, taint corev1.Taint) bool {
+	for i := range taints {
+		if taints[i].MatchTaint(&taint) {
+			return true
+		}
+	}
+	return false
+}
+
+func equalLabelsAndTaints(a, b *corev1.Node) bool {
+	aJSON, _ := json.Marshal([]interface{}{a.Labels, a.Annotations, a.Spec.Taints})
+	bJSON, _ := json.Marshal([]interface{}{b.Labels, b.Annotations, b.Spec.Taints})
+	return string(aJSON) == string(bJSON)
+}
+
+func (r *Reconciler) machineSetToNodes(o client.Object) []reconcile.Request {
+	machineSet, ok := o.(*capiv1.MachineSet)
+	if !ok {
+		panic(fmt.Sprintf("Expected a MachineSet but got a %T", o))
+	}
+	return r.nodesForMachineSets(machineSet.Namespace, []capiv1.MachineSet{*machineSet})
+}
+
+func (r *Reconciler) machineDeploymentToNodes(o client.Object) []reconcile.Request {
+	machineDeployment, ok := o.(*capiv1.MachineDeployment)
+	if !ok {
+		panic(fmt.Sprintf("Expected a MachineDeployment but got a %T", o))
+	}
+
+	machineSets := &capiv1.MachineSetList{}
+	if err := r.client.List(context.TODO(), machineSets, client.InNamespace(machineDeployment.Namespace)); err != nil {
+		return nil
+	}
+	var owned []capiv1.MachineSet
+	for i := range machineSets.Items {
+		if metav1.IsControlledBy(&machineSets.Items[i], machineDeployment) {
+			owned = append(owned, machineSets.Items[i])
+		}
+	}
+	return r.nodesForMachineSets(machineDeployment.Namespace, owned)
+}
+
+func (r *Reconciler) nodesForMachineSets(namespace string, machineSets []capiv1.MachineSet) []reconcile.Request {
+	if len(machineSets) == 0 {
+		return nil
+	}
+	machines := &capiv1.MachineList{}
+	if err := r.client.List(context.TODO(), machines, client.InNamespace(namespace)); err != nil {
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for i := range machines.Items {
+		machine := &machines.Items[i]
+		if machine.Status.NodeRef == nil {
+			continue
+		}
+		for j := range machineSets {
+			if metav1.IsControlledBy(machine, &machineSets[j]) {
+				requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKey{Name: machine.Status.NodeRef.Name}})
+				break
+			}
+		}
+	}
+	return requests
+}
@@ -0,0 +1,175 @@
+package nodelabelstaints
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/pointer"
+	capiv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestReconcileNodeLabelsAndTaints(t *testing.T) {
+	testCases := []struct {
+		name           string
+		node           *corev1.Node
+		labels         map[string]string
+		taints         []corev1.Taint
+		expectedLabels map[string]string
+		expectedTaints []corev1.Taint
+	}{
+		{
+			name: "When the NodePool has labels and taints it should add them to the node",
+			node: &corev1.Node{
+				ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"existing": "label"}},
+				Spec: corev1.NodeSpec{
+					Taints: []corev1.Taint{{Key: "node.kubernetes.io/not-ready", Effect: corev1.TaintEffectNoSchedule}},
+				},
+			},
+			labels: map[string]string{"role": "infra"},
+			taints: []corev1.Taint{{Key: "dedicated", Value: "infra", Effect: corev1.TaintEffectNoSchedule}},
+			expectedLabels: map[string]string{
+				"existing": "label",
+				"role":     "infra",
+			},
+			expectedTaints: []corev1.Taint{
+				{Key: "node.kubernetes.io/not-ready", Effect: corev1.TaintEffectNoSchedule},
+				{Key: "dedicated", Value: "infra", Effect: corev1.TaintEffectNoSchedule},
+			},
+		},
+		{
+			name: "When a taint value changes it should update the existing taint",
+			node: &corev1.Node{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{managedTaintsAnnotation: `[{"key":"dedicated","value":"infra","effect":"NoSchedule"}]`},
+				},
+				Spec: corev1.NodeSpec{
+					Taints: []corev1.Taint{{Key: "dedicated", Value: "infra", Effect: corev1.TaintEffectNoSchedule}},
+				},
+			},
+			taints:         []corev1.Taint{{Key: "dedicated", Value: "gpu", Effect: corev1.TaintEffectNoSchedule}},
+			expectedLabels: map[string]string{},
+			expectedTaints: []corev1.Taint{{Key: "dedicated", Value: "gpu", Effect: corev1.TaintEffectNoSchedule}},
+		},
+		{
+			name: "When labels and taints are removed from the NodePool it should only remove the ones it set",
+			node: &corev1.Node{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{
+						"existing": "label",
+						"role":     "infra",
+					},
+					Annotations: map[string]string{
+						managedNodeLabelsAnnotation: `["role"]`,
+						managedTaintsAnnotation:     `[{"key":"dedicated","value":"infra","effect":"NoSchedule"}]`,
+					},
+				},
+				Spec: corev1.NodeSpec{
+					Taints: []corev1.Taint{
+						{Key: "dedicated", Value: "infra", Effect: corev1.TaintEffectNoSchedule},
+						{Key: "other", Effect: corev1.TaintEffectNoExecute},
+					},
+				},
+			},
+			expectedLabels: map[string]string{"existing": "label"},
+			expectedTaints: []corev1.Taint{{Key: "other", Effect: corev1.TaintEffectNoExecute}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+			err := reconcileNodeLabelsAndTaints(tc.node, tc.labels, tc.taints)
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(tc.node.Labels).To(Equal(tc.expectedLabels))
+			g.Expect(tc.node.Spec.Taints).To(Equal(tc.expectedTaints))
+			if len(tc.labels) == 0 {
+				g.Expect(tc.node.Annotations).ToNot(HaveKey(managedNodeLabelsAnnotation))
+			}
+			if len(tc.taints) == 0 {
+				g.Expect(tc.node.Annotations).ToNot(HaveKey(managedTaintsAnnotation))
+			}
+		})
+	}
+}
+
+func TestReconcile(t *testing.T) {
+	g := NewWithT(t)
+
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	_ = capiv1.AddToScheme(scheme)
+
+	machineDeployment := &capiv1.MachineDeployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "hcp",
+			Name:      "nodepool",
+			UID:       "md-uid",
+			Annotations: map[string]string{
+				nodePoolAnnotationNodeLabels: `{"role":"infra"}`,
+				nodePoolAnnotationTaints:     `[{"key":"dedicated","value":"infra","effect":"NoSchedule"}]`,
+			},
+		},
+	}
+	machineSet := &capiv1.MachineSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "hcp",
+			Name:      "nodepool-1234",
+			UID:       "ms-uid",
+			OwnerReferences: []metav1.OwnerReference{{
+				APIVersion: capiv1.GroupVersion.String(),
+				Kind:       "MachineDeployment",
+				Name:       machineDeployment.Name,
+				UID:        machineDeployment.UID,
+				Controller: pointer.Bool(true),
+			}},
+		},
+	}
+	machine := &capiv1.Machine{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "hcp",
+			Name:      "nodepool-1234-abcd",
+			OwnerReferences: []metav1.OwnerReference{{
+				APIVersion: capiv1.GroupVersion.String(),
+				Kind:       "MachineSet",
+				Name:       machineSet.Name,
+				UID:        machineSet.UID,
+				Controller: pointer.Bool(true),
+			}},
+		},
+		Status: capiv1.MachineStatus{
+			NodeRef: &corev1.ObjectReference{Name: "node"},
+		},
+	}
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "node",
+			Annotations: map[string]string{
+				capiv1.MachineAnnotation:          machine.Name,
+				capiv1.ClusterNamespaceAnnotation: machine.Namespace,
+			},
+		},
+	}
+
+	r := &Reconciler{
+		client:             fake.NewClientBuilder().WithScheme(scheme).WithObjects(machineDeployment, machineSet, machine).Build(),
+		guestClusterClient: fake.NewClientBuilder().WithScheme(scheme).WithObjects(node).Build(),
+	}
+
+	g.Expect(r.machineDeploymentToNodes(machineDeployment)).To(Equal([]reconcile.Request{{NamespacedName: client.ObjectKey{Name: "node"}}}))
+	g.Expect(r.machineSetToNodes(machineSet)).To(Equal([]reconcile.Request{{NamespacedName: client.ObjectKey{Name: "node"}}}))
+
+	_, err := r.Reconcile(context.Background(), reconcile.Request{NamespacedName: client.ObjectKeyFromObject(node)})
+	g.Expect(err).ToNot(HaveOccurred())
+
+	got := &corev1.Node{}
+	g.Expect(r.guestClusterClient.Get(context.Background(), client.ObjectKeyFromObject(node), got)).To(Succeed())
+	g.Expect(got.Labels).To(HaveKeyWithValue("role", "infra"))
+	g.Expect(got.Spec.Taints).To(Equal([]corev1.Taint{{Key: "dedicated", Value: "infra", Effect: corev1.TaintEffectNoSchedule}}))
+}
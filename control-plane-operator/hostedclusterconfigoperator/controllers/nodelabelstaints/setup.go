@@ -0,0 +1,39 @@
+package nodelabelstaints
+
+import (
+	"fmt"
+
+	"github.com/openshift/hypershift/control-plane-operator/hostedclusterconfigoperator/operator"
+	corev1 "k8s.io/api/core/v1"
+	capiv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+const ControllerName = "nodelabelstaints"
+
+func Setup(opts *operator.HostedClusterConfigOperatorConfig) error {
+	r := &Reconciler{
+		client:             opts.CPCluster.GetClient(),
+		guestClusterClient: opts.Manager.GetClient(),
+	}
+	c, err := controller.New(ControllerName, opts.Manager, controller.Options{Reconciler: r})
+	if err != nil {
+		return fmt.Errorf("failed to construct controller: %w", err)
+	}
+
+	if err := c.Watch(&source.Kind{Type: &corev1.Node{}}, &handler.EnqueueRequestForObject{}); err != nil {
+		return fmt.Errorf("failed to watch Nodes: %w", err)
+	}
+
+	if err := c.Watch(source.NewKindWithCache(&capiv1.MachineDeployment{}, opts.CPCluster.GetCache()), handler.EnqueueRequestsFromMapFunc(r.machineDeploymentToNodes)); err != nil {
+		return fmt.Errorf("failed to watch MachineDeployments: %w", err)
+	}
+
+	if err := c.Watch(source.NewKindWithCache(&capiv1.MachineSet{}, opts.CPCluster.GetCache()), handler.EnqueueRequestsFromMapFunc(r.machineSetToNodes)); err != nil {
+		return fmt.Errorf("failed to watch MachineSets: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,42 @@
+package manifests
+
+import (
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func AWSSpotTerminationHandlerServiceAccount() *corev1.ServiceAccount {
+	return &corev1.ServiceAccount{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "aws-spot-termination-handler",
+			Namespace: "kube-system",
+		},
+	}
+}
+
+func AWSSpotTerminationHandlerClusterRole() *rbacv1.ClusterRole {
+	return &rbacv1.ClusterRole{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "hypershift:aws-spot-termination-handler",
+		},
+	}
+}
+
+func AWSSpotTerminationHandlerClusterRoleBinding() *rbacv1.ClusterRoleBinding {
+	return &rbacv1.ClusterRoleBinding{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "hypershift:aws-spot-termination-handler",
+		},
+	}
+}
+
+func AWSSpotTerminationHandlerDaemonSet() *appsv1.DaemonSet {
+	return &appsv1.DaemonSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "aws-spot-termination-handler",
+			Namespace: "kube-system",
+		},
+	}
+}
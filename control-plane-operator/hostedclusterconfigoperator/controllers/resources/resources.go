@@ -31,6 +31,9 @@ import (
 	operatorv1 "github.com/openshift/api/operator/v1"
 	hyperv1 "github.com/openshift/hypershift/api/v1alpha1"
 	"github.com/openshift/hypershift/control-plane-operator/controllers/hostedcontrolplane/cloud/azure"
+	"github.com/openshift/hypershift/control-plane-operator/controllers/hostedcontrolplane/cloud/nutanix"
+	"github.com/openshift/hypershift/control-plane-operator/controllers/hostedcontrolplane/cloud/oci"
+	"github.com/openshift/hypershift/control-plane-operator/controllers/hostedcontrolplane/cloud/openstack"
 	cpomanifests "github.com/openshift/hypershift/control-plane-operator/controllers/hostedcontrolplane/manifests"
 	alerts "github.com/openshift/hypershift/control-plane-operator/hostedclusterconfigoperator/controllers/resources/alerts"
 	"github.com/openshift/hypershift/control-plane-operator/hostedclusterconfigoperator/controllers/resources/crd"
@@ -46,6 +49,7 @@ import (
 	"github.com/openshift/hypershift/control-plane-operator/hostedclusterconfigoperator/controllers/resources/olm"
 	"github.com/openshift/hypershift/control-plane-operator/hostedclusterconfigoperator/controllers/resources/rbac"
 	"github.com/openshift/hypershift/control-plane-operator/hostedclusterconfigoperator/controllers/resources/registry"
+	"github.com/openshift/hypershift/control-plane-operator/hostedclusterconfigoperator/controllers/resources/spot"
 	"github.com/openshift/hypershift/control-plane-operator/hostedclusterconfigoperator/operator"
 	"github.com/openshift/hypershift/support/config"
 	"github.com/openshift/hypershift/support/globalconfig"
@@ -443,6 +447,9 @@ func (r *reconciler) Reconcile(ctx context.Context, _ ctrl.Request) (ctrl.Result
 
 	if hcp.Spec.Platform.Type == hyperv1.AWSPlatform {
 		errs = append(errs, r.reconcileAWSIdentityWebhook(ctx)...)
+
+		log.Info("reconciling aws spot termination handler")
+		errs = append(errs, r.reconcileAWSSpotTerminationHandler(ctx, releaseImage)...)
 	}
 
 	return ctrl.Result{}, errors.NewAggregate(errs)
@@ -915,11 +922,17 @@ func (r *reconciler) reconcileCloudCredentialSecrets(ctx context.Context, hcp *h
 		}); err != nil {
 			errs = append(errs, fmt.Errorf("failed to reconcile csi driver secret: %w", err))
 		}
-	case hyperv1.PowerVSPlatform:
+	case hyperv1.PowerVSPlatform, hyperv1.IBMCloudVPCPlatform:
+		var ingressCredentialsName string
+		if hcp.Spec.Platform.Type == hyperv1.IBMCloudVPCPlatform {
+			ingressCredentialsName = hcp.Spec.Platform.IBMCloudVPC.IngressOperatorCloudCreds.Name
+		} else {
+			ingressCredentialsName = hcp.Spec.Platform.PowerVS.IngressOperatorCloudCreds.Name
+		}
 		var ingressCredentials corev1.Secret
-		err := r.cpClient.Get(ctx, client.ObjectKey{Namespace: hcp.Namespace, Name: hcp.Spec.Platform.PowerVS.IngressOperatorCloudCreds.Name}, &ingressCredentials)
+		err := r.cpClient.Get(ctx, client.ObjectKey{Namespace: hcp.Namespace, Name: ingressCredentialsName}, &ingressCredentials)
 		if err != nil {
-			errs = append(errs, fmt.Errorf("failed to get ingress operator cloud credentials secret %s from hcp namespace : %w", hcp.Spec.Platform.PowerVS.IngressOperatorCloudCreds.Name, err))
+			errs = append(errs, fmt.Errorf("failed to get ingress operator cloud credentials secret %s from hcp namespace : %w", ingressCredentialsName, err))
 			return errs
 		}
 
@@ -944,7 +957,71 @@ func (r *reconciler) reconcileCloudCredentialSecrets(ctx context.Context, hcp *h
 		})
 
 		if err != nil {
-			errs = append(errs, fmt.Errorf("failed to reconcile powervs cloud credentials secret %w", err))
+			errs = append(errs, fmt.Errorf("failed to reconcile ibm cloud credentials secret %w", err))
+		}
+	case hyperv1.OpenStackPlatform:
+		var cloudCredentials corev1.Secret
+		cloudCredentialsName := hcp.Spec.Platform.OpenStack.KubeCloudControllerCreds.Name
+		if err := r.cpClient.Get(ctx, client.ObjectKey{Namespace: hcp.Namespace, Name: cloudCredentialsName}, &cloudCredentials); err != nil {
+			errs = append(errs, fmt.Errorf("failed to get cloud credentials secret %s from hcp namespace: %w", cloudCredentialsName, err))
+			return errs
+		}
+
+		// The Cinder CSI driver reads the clouds.yaml from its credentials secret
+		csiCredentialSecret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: "openshift-cluster-csi-drivers", Name: "openstack-cloud-credentials"}}
+		if _, err := r.CreateOrUpdate(ctx, r.client, csiCredentialSecret, func() error {
+			clouds, hasClouds := cloudCredentials.Data[openstack.CloudsSecretKey]
+			if !hasClouds {
+				return fmt.Errorf("cloud credentials secret %q is missing the %s key", cloudCredentials.Name, openstack.CloudsSecretKey)
+			}
+			csiCredentialSecret.Type = corev1.SecretTypeOpaque
+			csiCredentialSecret.Data = map[string][]byte{openstack.CloudsSecretKey: clouds}
+			return nil
+		}); err != nil {
+			errs = append(errs, fmt.Errorf("failed to reconcile guest cluster CSI secret: %w", err))
+		}
+	case hyperv1.NutanixPlatform:
+		var cloudCredentials corev1.Secret
+		cloudCredentialsName := hcp.Spec.Platform.Nutanix.KubeCloudControllerCreds.Name
+		if err := r.cpClient.Get(ctx, client.ObjectKey{Namespace: hcp.Namespace, Name: cloudCredentialsName}, &cloudCredentials); err != nil {
+			errs = append(errs, fmt.Errorf("failed to get cloud credentials secret %s from hcp namespace: %w", cloudCredentialsName, err))
+			return errs
+		}
+
+		// The cloud controller manager reads its credentials through the
+		// kubeconfig of the guest cluster
+		ccmCredentialSecret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: nutanix.CCMCredentialsNamespace, Name: nutanix.CCMCredentialsName}}
+		if _, err := r.CreateOrUpdate(ctx, r.client, ccmCredentialSecret, func() error {
+			credentials, hasCredentials := cloudCredentials.Data[nutanix.CredentialsSecretKey]
+			if !hasCredentials {
+				return fmt.Errorf("cloud credentials secret %q is missing the %s key", cloudCredentials.Name, nutanix.CredentialsSecretKey)
+			}
+			ccmCredentialSecret.Type = corev1.SecretTypeOpaque
+			ccmCredentialSecret.Data = map[string][]byte{nutanix.CredentialsSecretKey: credentials}
+			return nil
+		}); err != nil {
+			errs = append(errs, fmt.Errorf("failed to reconcile guest cluster cloud controller manager secret: %w", err))
+		}
+	case hyperv1.OCIPlatform:
+		ccmConfig := cpomanifests.OCICCMConfigSecret(hcp.Namespace)
+		if err := r.cpClient.Get(ctx, client.ObjectKeyFromObject(ccmConfig), ccmConfig); err != nil {
+			errs = append(errs, fmt.Errorf("failed to get cloud controller manager config secret %s from hcp namespace: %w", ccmConfig.Name, err))
+			return errs
+		}
+
+		// The block volume CSI driver reads the same cloud provider config as
+		// the cloud controller manager from its provisioner secret
+		csiConfigSecret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: "kube-system", Name: "oci-volume-provisioner"}}
+		if _, err := r.CreateOrUpdate(ctx, r.client, csiConfigSecret, func() error {
+			config, hasConfig := ccmConfig.Data[oci.CloudConfigKey]
+			if !hasConfig {
+				return fmt.Errorf("cloud controller manager config secret %q is missing the %s key", ccmConfig.Name, oci.CloudConfigKey)
+			}
+			csiConfigSecret.Type = corev1.SecretTypeOpaque
+			csiConfigSecret.Data = map[string][]byte{"config.yaml": config}
+			return nil
+		}); err != nil {
+			errs = append(errs, fmt.Errorf("failed to reconcile guest cluster CSI secret: %w", err))
 		}
 	}
 	return errs
@@ -1178,3 +1255,41 @@ func (r *reconciler) reconcileAWSIdentityWebhook(ctx context.Context) []error {
 
 	return errs
 }
+
+func (r *reconciler) reconcileAWSSpotTerminationHandler(ctx context.Context, releaseImage *releaseinfo.ReleaseImage) []error {
+	var errs []error
+	serviceAccount := manifests.AWSSpotTerminationHandlerServiceAccount()
+	if _, err := r.CreateOrUpdate(ctx, r.client, serviceAccount, func() error {
+		return nil
+	}); err != nil {
+		errs = append(errs, fmt.Errorf("failed to reconcile %T %s: %w", serviceAccount, serviceAccount.Name, err))
+	}
+
+	clusterRole := manifests.AWSSpotTerminationHandlerClusterRole()
+	if _, err := r.CreateOrUpdate(ctx, r.client, clusterRole, func() error {
+		return spot.ReconcileTerminationHandlerClusterRole(clusterRole)
+	}); err != nil {
+		errs = append(errs, fmt.Errorf("failed to reconcile %T %s: %w", clusterRole, clusterRole.Name, err))
+	}
+
+	clusterRoleBinding := manifests.AWSSpotTerminationHandlerClusterRoleBinding()
+	if _, err := r.CreateOrUpdate(ctx, r.client, clusterRoleBinding, func() error {
+		return spot.ReconcileTerminationHandlerClusterRoleBinding(clusterRoleBinding, clusterRole, serviceAccount)
+	}); err != nil {
+		errs = append(errs, fmt.Errorf("failed to reconcile %T %s: %w", clusterRoleBinding, clusterRoleBinding.Name, err))
+	}
+
+	image, ok := releaseImage.ComponentImages()["aws-machine-controllers"]
+	if !ok {
+		errs = append(errs, fmt.Errorf("release image has no aws-machine-controllers image for the spot termination handler"))
+		return errs
+	}
+	daemonSet := manifests.AWSSpotTerminationHandlerDaemonSet()
+	if _, err := r.CreateOrUpdate(ctx, r.client, daemonSet, func() error {
+		return spot.ReconcileTerminationHandlerDaemonSet(daemonSet, serviceAccount, image)
+	}); err != nil {
+		errs = append(errs, fmt.Errorf("failed to reconcile %T %s: %w", daemonSet, daemonSet.Name, err))
+	}
+
+	return errs
+}
@@ -0,0 +1,98 @@
+package spot
+
+import (
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	capiv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+)
+
+func terminationHandlerLabels() map[string]string {
+	return map[string]string{
+		"app": "aws-spot-termination-handler",
+	}
+}
+
+func ReconcileTerminationHandlerClusterRole(role *rbacv1.ClusterRole) error {
+	role.Rules = []rbacv1.PolicyRule{
+		{
+			APIGroups: []string{""},
+			Resources: []string{"nodes"},
+			Verbs:     []string{"get", "list", "watch"},
+		},
+		{
+			APIGroups: []string{""},
+			Resources: []string{"nodes/status"},
+			Verbs:     []string{"patch", "update"},
+		},
+	}
+	return nil
+}
+
+func ReconcileTerminationHandlerClusterRoleBinding(binding *rbacv1.ClusterRoleBinding, role *rbacv1.ClusterRole, serviceAccount *corev1.ServiceAccount) error {
+	binding.RoleRef = rbacv1.RoleRef{
+		APIGroup: "rbac.authorization.k8s.io",
+		Kind:     "ClusterRole",
+		Name:     role.Name,
+	}
+	binding.Subjects = []rbacv1.Subject{{
+		Kind:      "ServiceAccount",
+		Name:      serviceAccount.Name,
+		Namespace: serviceAccount.Namespace,
+	}}
+	return nil
+}
+
+// ReconcileTerminationHandlerDaemonSet runs the termination handler on the nodes of
+// spot instances. It polls the instance metadata service and, when the instance
+// receives an interruption notice, sets the Terminating condition on its node so
+// that the node is drained and replaced.
+func ReconcileTerminationHandlerDaemonSet(daemonSet *appsv1.DaemonSet, serviceAccount *corev1.ServiceAccount, image string) error {
+	daemonSet.Spec = appsv1.DaemonSetSpec{
+		Selector: &metav1.LabelSelector{
+			MatchLabels: terminationHandlerLabels(),
+		},
+		Template: corev1.PodTemplateSpec{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: terminationHandlerLabels(),
+			},
+			Spec: corev1.PodSpec{
+				ServiceAccountName: serviceAccount.Name,
+				// Only spot instances are interrupted.
+				NodeSelector: map[string]string{
+					capiv1.InterruptibleLabel: "",
+				},
+				// The instance metadata service is only reachable from the host network.
+				HostNetwork:       true,
+				PriorityClassName: "system-node-critical",
+				// Run on all spot nodes, including tainted ones.
+				Tolerations: []corev1.Toleration{{Operator: corev1.TolerationOpExists}},
+				Containers: []corev1.Container{{
+					Name:    "termination-handler",
+					Image:   image,
+					Command: []string{"/termination-handler"},
+					Args: []string{
+						"--node-name=$(NODE_NAME)",
+						"--poll-interval-seconds=5",
+					},
+					Env: []corev1.EnvVar{{
+						Name: "NODE_NAME",
+						ValueFrom: &corev1.EnvVarSource{
+							FieldRef: &corev1.ObjectFieldSelector{FieldPath: "spec.nodeName"},
+						},
+					}},
+					Resources: corev1.ResourceRequirements{
+						Requests: corev1.ResourceList{
+							corev1.ResourceCPU:    resource.MustParse("10m"),
+							corev1.ResourceMemory: resource.MustParse("20Mi"),
+						},
+					},
+					TerminationMessagePolicy: corev1.TerminationMessageFallbackToLogsOnError,
+				}},
+			},
+		},
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package spotinterruption
+
+import (
+	"fmt"
+
+	"github.com/openshift/hypershift/control-plane-operator/hostedclusterconfigoperator/operator"
+	corev1 "k8s.io/api/core/v1"
+	capiv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+const ControllerName = "spotinterruption"
+
+func Setup(opts *operator.HostedClusterConfigOperatorConfig) error {
+	r := &Reconciler{
+		client:             opts.CPCluster.GetClient(),
+		guestClusterClient: opts.Manager.GetClient(),
+	}
+	c, err := controller.New(ControllerName, opts.Manager, controller.Options{Reconciler: r})
+	if err != nil {
+		return fmt.Errorf("failed to construct controller: %w", err)
+	}
+
+	// Only nodes of spot instances are interrupted.
+	interruptible := predicate.NewPredicateFuncs(func(o client.Object) bool {
+		_, ok := o.GetLabels()[capiv1.InterruptibleLabel]
+		return ok
+	})
+	if err := c.Watch(&source.Kind{Type: &corev1.Node{}}, &handler.EnqueueRequestForObject{}, interruptible); err != nil {
+		return fmt.Errorf("failed to watch Nodes: %w", err)
+	}
+
+	return nil
+}
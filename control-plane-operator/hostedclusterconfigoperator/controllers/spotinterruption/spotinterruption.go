@@ -0,0 +1,148 @@
+package spotinterruption
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	capiv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const (
+	// nodeTerminatingConditionType is the node condition the spot termination handler
+	// sets when the instance of the node receives an interruption notice.
+	nodeTerminatingConditionType corev1.NodeConditionType = "Terminating"
+
+	// nodePoolAnnotationSpotInterruptions counts on the MachineDeployment, or the
+	// MachineSet for in-place NodePools, the nodes replaced after an interruption
+	// notice. The NodePool controller reports it in the NodePool status.
+	nodePoolAnnotationSpotInterruptions = "hypershift.openshift.io/nodePoolSpotInterruptions"
+
+	// spotInterruptionCountedAnnotation marks a Machine whose interruption was counted,
+	// so that it is counted once however many times its node is reconciled while it is
+	// being deleted.
+	spotInterruptionCountedAnnotation = "hypershift.openshift.io/spotInterruptionCounted"
+)
+
+// Reconciler replaces the nodes of spot instances that received an interruption
+// notice, by deleting their Machine. CAPI drains the node before the instance is
+// terminated, and the MachineSet creates a new Machine in its place.
+//
+// Only interruption notices are acted on: the termination handler doesn't report
+// capacity rebalance recommendations, so nodes are not replaced ahead of them.
+type Reconciler struct {
+	client             client.Client
+	guestClusterClient client.Client
+}
+
+func (r *Reconciler) Reconcile(ctx context.Context, req reconcile.Request) (reconcile.Result, error) {
+	log := ctrl.LoggerFrom(ctx)
+
+	node := &corev1.Node{}
+	if err := r.guestClusterClient.Get(ctx, req.NamespacedName, node); err != nil {
+		if apierrors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, fmt.Errorf("failed to get node: %w", err)
+	}
+
+	if !isTerminating(node) {
+		return ctrl.Result{}, nil
+	}
+
+	machineName, ok := node.GetAnnotations()[capiv1.MachineAnnotation]
+	if !ok {
+		return ctrl.Result{}, nil
+	}
+	machineNamespace, ok := node.GetAnnotations()[capiv1.ClusterNamespaceAnnotation]
+	if !ok {
+		return ctrl.Result{}, nil
+	}
+	machine := &capiv1.Machine{}
+	if err := r.client.Get(ctx, client.ObjectKey{Namespace: machineNamespace, Name: machineName}, machine); err != nil {
+		if apierrors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, fmt.Errorf("failed to get machine %s/%s: %w", machineNamespace, machineName, err)
+	}
+	if _, counted := machine.Annotations[spotInterruptionCountedAnnotation]; counted {
+		return ctrl.Result{}, nil
+	}
+
+	// The machine is deleted before the interruption is counted, so that a failed
+	// deletion isn't counted. If counting fails, the machine is still not marked
+	// as counted and the interruption is counted when the node is reconciled again.
+	if machine.DeletionTimestamp.IsZero() {
+		log.Info("Spot instance received an interruption notice, deleting its machine", "node", node.Name, "machine", client.ObjectKeyFromObject(machine).String())
+		if err := r.client.Delete(ctx, machine); err != nil {
+			if apierrors.IsNotFound(err) {
+				return ctrl.Result{}, nil
+			}
+			return ctrl.Result{}, fmt.Errorf("failed to delete machine %s: %w", client.ObjectKeyFromObject(machine), err)
+		}
+	}
+	if err := r.countInterruption(ctx, machine); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	original := machine.DeepCopy()
+	if machine.Annotations == nil {
+		machine.Annotations = map[string]string{}
+	}
+	machine.Annotations[spotInterruptionCountedAnnotation] = "true"
+	if err := r.client.Patch(ctx, machine, client.MergeFrom(original)); err != nil && !apierrors.IsNotFound(err) {
+		return ctrl.Result{}, fmt.Errorf("failed to mark the interruption of machine %s as counted: %w", client.ObjectKeyFromObject(machine), err)
+	}
+	return ctrl.Result{}, nil
+}
+
+// isTerminating returns true if the spot instance of the node received an interruption notice.
+func isTerminating(node *corev1.Node) bool {
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == nodeTerminatingConditionType {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// countInterruption increments the interruption count of the MachineDeployment of the
+// machine or, for in-place NodePools, of its MachineSet.
+func (r *Reconciler) countInterruption(ctx context.Context, machine *capiv1.Machine) error {
+	machineOwner := metav1.GetControllerOf(machine)
+	if machineOwner == nil || machineOwner.Kind != "MachineSet" {
+		return nil
+	}
+	machineSet := &capiv1.MachineSet{}
+	if err := r.client.Get(ctx, client.ObjectKey{Namespace: machine.Namespace, Name: machineOwner.Name}, machineSet); err != nil {
+		return fmt.Errorf("failed to get MachineSet %s/%s: %w", machine.Namespace, machineOwner.Name, err)
+	}
+
+	var owner client.Object = machineSet
+	if machineSetOwner := metav1.GetControllerOf(machineSet); machineSetOwner != nil && machineSetOwner.Kind == "MachineDeployment" {
+		machineDeployment := &capiv1.MachineDeployment{}
+		if err := r.client.Get(ctx, client.ObjectKey{Namespace: machine.Namespace, Name: machineSetOwner.Name}, machineDeployment); err != nil {
+			return fmt.Errorf("failed to get MachineDeployment %s/%s: %w", machine.Namespace, machineSetOwner.Name, err)
+		}
+		owner = machineDeployment
+	}
+
+	original := owner.DeepCopyObject().(client.Object)
+	annotations := owner.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	count, _ := strconv.Atoi(annotations[nodePoolAnnotationSpotInterruptions])
+	annotations[nodePoolAnnotationSpotInterruptions] = strconv.Itoa(count + 1)
+	owner.SetAnnotations(annotations)
+	if err := r.client.Patch(ctx, owner, client.MergeFromWithOptions(original, client.MergeFromWithOptimisticLock{})); err != nil {
+		return fmt.Errorf("failed to count the interruption on %s: %w", client.ObjectKeyFromObject(owner), err)
+	}
+	return nil
+}
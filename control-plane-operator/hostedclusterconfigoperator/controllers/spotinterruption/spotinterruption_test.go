@@ -0,0 +1,166 @@
+package spotinterruption
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/pointer"
+	capiv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestReconcile(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	_ = capiv1.AddToScheme(scheme)
+
+	testCases := []struct {
+		name                       string
+		nodeConditions             []corev1.NodeCondition
+		existingInterruptions      string
+		machineDeleting            bool
+		machineCounted             bool
+		expectMachineDeleted       bool
+		expectedInterruptionsCount string
+	}{
+		{
+			name:                       "When the node is not terminating it should keep the machine",
+			expectMachineDeleted:       false,
+			expectedInterruptionsCount: "",
+		},
+		{
+			name: "When the node is terminating it should delete the machine and count the interruption",
+			nodeConditions: []corev1.NodeCondition{
+				{Type: nodeTerminatingConditionType, Status: corev1.ConditionTrue},
+			},
+			expectMachineDeleted:       true,
+			expectedInterruptionsCount: "1",
+		},
+		{
+			name: "When the node is terminating it should add to the existing interruption count",
+			nodeConditions: []corev1.NodeCondition{
+				{Type: nodeTerminatingConditionType, Status: corev1.ConditionTrue},
+			},
+			existingInterruptions:      "2",
+			expectMachineDeleted:       true,
+			expectedInterruptionsCount: "3",
+		},
+		{
+			name: "When the machine is already being deleted but the interruption was not counted it should count it",
+			nodeConditions: []corev1.NodeCondition{
+				{Type: nodeTerminatingConditionType, Status: corev1.ConditionTrue},
+			},
+			machineDeleting:            true,
+			expectMachineDeleted:       true,
+			expectedInterruptionsCount: "1",
+		},
+		{
+			name: "When the interruption of the machine was already counted it should not count it again",
+			nodeConditions: []corev1.NodeCondition{
+				{Type: nodeTerminatingConditionType, Status: corev1.ConditionTrue},
+			},
+			existingInterruptions:      "2",
+			machineDeleting:            true,
+			machineCounted:             true,
+			expectMachineDeleted:       true,
+			expectedInterruptionsCount: "2",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			machineDeployment := &capiv1.MachineDeployment{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: "hcp",
+					Name:      "nodepool",
+					UID:       "md-uid",
+				},
+			}
+			if tc.existingInterruptions != "" {
+				machineDeployment.Annotations = map[string]string{nodePoolAnnotationSpotInterruptions: tc.existingInterruptions}
+			}
+			machineSet := &capiv1.MachineSet{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: "hcp",
+					Name:      "nodepool-1234",
+					UID:       "ms-uid",
+					OwnerReferences: []metav1.OwnerReference{{
+						APIVersion: capiv1.GroupVersion.String(),
+						Kind:       "MachineDeployment",
+						Name:       machineDeployment.Name,
+						UID:        machineDeployment.UID,
+						Controller: pointer.Bool(true),
+					}},
+				},
+			}
+			machine := &capiv1.Machine{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: "hcp",
+					Name:      "nodepool-1234-abcd",
+					OwnerReferences: []metav1.OwnerReference{{
+						APIVersion: capiv1.GroupVersion.String(),
+						Kind:       "MachineSet",
+						Name:       machineSet.Name,
+						UID:        machineSet.UID,
+						Controller: pointer.Bool(true),
+					}},
+				},
+			}
+			if tc.machineDeleting {
+				machine.Finalizers = []string{capiv1.MachineFinalizer}
+				machine.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+			}
+			if tc.machineCounted {
+				machine.Annotations = map[string]string{spotInterruptionCountedAnnotation: "true"}
+			}
+			node := &corev1.Node{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:   "node",
+					Labels: map[string]string{capiv1.InterruptibleLabel: ""},
+					Annotations: map[string]string{
+						capiv1.MachineAnnotation:          machine.Name,
+						capiv1.ClusterNamespaceAnnotation: machine.Namespace,
+					},
+				},
+				Status: corev1.NodeStatus{Conditions: tc.nodeConditions},
+			}
+
+			r := &Reconciler{
+				client:             fake.NewClientBuilder().WithScheme(scheme).WithObjects(machineDeployment, machineSet, machine).Build(),
+				guestClusterClient: fake.NewClientBuilder().WithScheme(scheme).WithObjects(node).Build(),
+			}
+			// The node is reconciled twice to check the interruption is counted once.
+			for i := 0; i < 2; i++ {
+				_, err := r.Reconcile(context.Background(), reconcile.Request{NamespacedName: client.ObjectKeyFromObject(node)})
+				g.Expect(err).ToNot(HaveOccurred())
+			}
+
+			gotMachine := &capiv1.Machine{}
+			err := r.client.Get(context.Background(), client.ObjectKeyFromObject(machine), gotMachine)
+			if tc.expectMachineDeleted {
+				if err == nil {
+					g.Expect(gotMachine.DeletionTimestamp.IsZero()).To(BeFalse())
+				} else {
+					g.Expect(apierrors.IsNotFound(err)).To(BeTrue())
+				}
+			} else {
+				g.Expect(err).ToNot(HaveOccurred())
+				g.Expect(gotMachine.DeletionTimestamp.IsZero()).To(BeTrue())
+			}
+
+			got := &capiv1.MachineDeployment{}
+			g.Expect(r.client.Get(context.Background(), client.ObjectKeyFromObject(machineDeployment), got)).To(Succeed())
+			g.Expect(got.Annotations[nodePoolAnnotationSpotInterruptions]).To(Equal(tc.expectedInterruptionsCount))
+		})
+	}
+}
@@ -0,0 +1,254 @@
+package hostedcluster
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+
+	routev1 "github.com/openshift/api/route/v1"
+	hyperv1 "github.com/openshift/hypershift/api/v1alpha1"
+	cpomanifests "github.com/openshift/hypershift/control-plane-operator/controllers/hostedcontrolplane/manifests"
+	"github.com/openshift/hypershift/hypershift-operator/controllers/manifests/ignitionserver"
+	"github.com/openshift/hypershift/support/capabilities"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// endpointsWebhookDeletionTimeout is how long the deletion of a None platform
+// cluster waits for the endpoints webhook to accept the deletion notification.
+const endpointsWebhookDeletionTimeout = 10 * time.Minute
+
+// EndpointsNotification is the body of the requests the endpoints webhook
+// receives. It lists where the services of a None platform cluster are
+// published, so that automation outside of HyperShift can set up the load
+// balancers and DNS records the services need.
+type EndpointsNotification struct {
+	Namespace  string `json:"namespace"`
+	Name       string `json:"name"`
+	InfraID    string `json:"infraID"`
+	BaseDomain string `json:"baseDomain"`
+	// Deleted is set once the cluster is deleted, and the load balancers and
+	// DNS records set up for it can be removed. The endpoints of a deleted
+	// cluster may be incomplete.
+	Deleted   bool                `json:"deleted,omitempty"`
+	Endpoints []PublishedEndpoint `json:"endpoints"`
+}
+
+// PublishedEndpoint is where a service of a cluster is published.
+type PublishedEndpoint struct {
+	Service hyperv1.ServiceType            `json:"service"`
+	Type    hyperv1.PublishingStrategyType `json:"type"`
+	// Host and Port are where the clients of the service reach it.
+	Host string `json:"host"`
+	Port int32  `json:"port"`
+	// NodePort is the port of the service on the nodes of the management
+	// cluster, which a load balancer of the host forwards to.
+	NodePort int32 `json:"nodePort,omitempty"`
+	// Target is the address of the load balancer or router the service is
+	// published on, which a DNS record of the host resolves to.
+	Target string `json:"target,omitempty"`
+}
+
+// EndpointsWebhook sends the endpoints of None platform clusters to a URL
+// whenever they change. Requests that fail are retried with the next
+// reconciliation, so receivers must handle the same notification more than once.
+type EndpointsWebhook struct {
+	URL    string
+	Client *http.Client
+
+	lock sync.Mutex
+	// sent are the last notifications sent by HostedCluster
+	sent map[types.NamespacedName][]byte
+}
+
+func NewEndpointsWebhook(url string) *EndpointsWebhook {
+	return &EndpointsWebhook{
+		URL:    url,
+		Client: &http.Client{Timeout: 10 * time.Second},
+		sent:   map[types.NamespacedName][]byte{},
+	}
+}
+
+// Notify posts the notification to the webhook unless it was the last one sent
+// for the cluster.
+func (w *EndpointsWebhook) Notify(ctx context.Context, notification *EndpointsNotification) error {
+	body, err := json.Marshal(notification)
+	if err != nil {
+		return fmt.Errorf("failed to serialize endpoints: %w", err)
+	}
+	key := types.NamespacedName{Namespace: notification.Namespace, Name: notification.Name}
+
+	// The lock only guards sent, a slow webhook must not block the notifications
+	// of other clusters. Notifications of the same cluster are not sent
+	// concurrently, as the controller doesn't reconcile it concurrently.
+	w.lock.Lock()
+	alreadySent := bytes.Equal(w.sent[key], body)
+	w.lock.Unlock()
+	if alreadySent {
+		return nil
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := w.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		message, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("webhook returned %s: %s", resp.Status, string(message))
+	}
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	w.sent[key] = body
+	return nil
+}
+
+// Forget drops the last notification sent for a cluster once it is gone.
+func (w *EndpointsWebhook) Forget(key types.NamespacedName) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	delete(w.sent, key)
+}
+
+// notifyEndpointsWebhookOfDeletion sends the deletion notification of a None
+// platform cluster and reports whether the deletion has to wait for it to be sent
+// again. The notification is best-effort: once it has failed for
+// endpointsWebhookDeletionTimeout, the deletion proceeds without it.
+func (r *HostedClusterReconciler) notifyEndpointsWebhookOfDeletion(ctx context.Context, hcluster *hyperv1.HostedCluster, controlPlaneNamespace string) bool {
+	log := ctrl.LoggerFrom(ctx)
+	endpoints, err := r.publishedEndpoints(ctx, hcluster, controlPlaneNamespace)
+	if err == nil {
+		err = r.EndpointsWebhook.Notify(ctx, endpointsNotification(hcluster, endpoints))
+	}
+	if err == nil {
+		return false
+	}
+	if r.now().Sub(hcluster.DeletionTimestamp.Time) < endpointsWebhookDeletionTimeout {
+		log.Error(err, "Failed to notify the endpoints webhook of the deletion, retrying")
+		return true
+	}
+	log.Error(err, "Failed to notify the endpoints webhook of the deletion, deleting the cluster without it", "timeout", endpointsWebhookDeletionTimeout)
+	return false
+}
+
+func endpointsNotification(hcluster *hyperv1.HostedCluster, endpoints []PublishedEndpoint) *EndpointsNotification {
+	return &EndpointsNotification{
+		Namespace:  hcluster.Namespace,
+		Name:       hcluster.Name,
+		InfraID:    hcluster.Spec.InfraID,
+		BaseDomain: hcluster.Spec.DNS.BaseDomain,
+		Deleted:    !hcluster.DeletionTimestamp.IsZero(),
+		Endpoints:  endpoints,
+	}
+}
+
+// publishedEndpoints returns where the services nodes and users connect to are
+// published. Services that are not published yet are left out, they are added
+// to a later notification once they are.
+func (r *HostedClusterReconciler) publishedEndpoints(ctx context.Context, hcluster *hyperv1.HostedCluster, controlPlaneNamespace string) ([]PublishedEndpoint, error) {
+	services := []struct {
+		serviceType hyperv1.ServiceType
+		service     *corev1.Service
+		route       *routev1.Route
+	}{
+		{hyperv1.APIServer, cpomanifests.KubeAPIServerService(controlPlaneNamespace), cpomanifests.KubeAPIServerExternalRoute(controlPlaneNamespace)},
+		{hyperv1.Konnectivity, cpomanifests.KonnectivityServerService(controlPlaneNamespace), cpomanifests.KonnectivityServerRoute(controlPlaneNamespace)},
+		{hyperv1.OAuthServer, cpomanifests.OauthServerService(controlPlaneNamespace), cpomanifests.OauthServerRoute(controlPlaneNamespace)},
+		{hyperv1.Ignition, ignitionserver.Service(controlPlaneNamespace), ignitionserver.Route(controlPlaneNamespace)},
+	}
+
+	var endpoints []PublishedEndpoint
+	for _, s := range services {
+		strategy := servicePublishingStrategyByType(hcluster, s.serviceType)
+		if strategy == nil {
+			continue
+		}
+		endpoint := PublishedEndpoint{
+			Service: s.serviceType,
+			Type:    strategy.Type,
+		}
+		switch strategy.Type {
+		case hyperv1.NodePort:
+			if strategy.NodePort == nil {
+				continue
+			}
+			found, err := r.getIfExists(ctx, s.service)
+			if err != nil {
+				return nil, err
+			}
+			if !found || !serviceFirstNodePortAvailable(s.service) {
+				continue
+			}
+			endpoint.Host = strategy.NodePort.Address
+			endpoint.Port = s.service.Spec.Ports[0].NodePort
+			endpoint.NodePort = s.service.Spec.Ports[0].NodePort
+		case hyperv1.LoadBalancer:
+			found, err := r.getIfExists(ctx, s.service)
+			if err != nil {
+				return nil, err
+			}
+			if !found || len(s.service.Spec.Ports) == 0 || len(s.service.Status.LoadBalancer.Ingress) == 0 {
+				continue
+			}
+			ingress := s.service.Status.LoadBalancer.Ingress[0]
+			endpoint.Target = ingress.Hostname
+			if endpoint.Target == "" {
+				endpoint.Target = ingress.IP
+			}
+			endpoint.Host = endpoint.Target
+			if strategy.LoadBalancer != nil && strategy.LoadBalancer.Hostname != "" {
+				endpoint.Host = strategy.LoadBalancer.Hostname
+			}
+			endpoint.Port = s.service.Spec.Ports[0].Port
+		case hyperv1.Route:
+			if !r.ManagementClusterCapabilities.Has(capabilities.CapabilityRoute) {
+				continue
+			}
+			found, err := r.getIfExists(ctx, s.route)
+			if err != nil {
+				return nil, err
+			}
+			if !found {
+				continue
+			}
+			endpoint.Host = s.route.Spec.Host
+			if strategy.Route != nil && strategy.Route.Hostname != "" {
+				endpoint.Host = strategy.Route.Hostname
+			}
+			if len(s.route.Status.Ingress) > 0 {
+				endpoint.Target = s.route.Status.Ingress[0].RouterCanonicalHostname
+			}
+			endpoint.Port = 443
+		default:
+			continue
+		}
+		if endpoint.Host == "" {
+			continue
+		}
+		endpoints = append(endpoints, endpoint)
+	}
+	return endpoints, nil
+}
+
+func (r *HostedClusterReconciler) getIfExists(ctx context.Context, o client.Object) (bool, error) {
+	if err := r.Client.Get(ctx, client.ObjectKeyFromObject(o), o); err != nil {
+		if apierrors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to get %T %s: %w", o, client.ObjectKeyFromObject(o), err)
+	}
+	return true, nil
+}
@@ -0,0 +1,166 @@
+package hostedcluster
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	routev1 "github.com/openshift/api/route/v1"
+	"github.com/openshift/hypershift/api"
+	hyperv1 "github.com/openshift/hypershift/api/v1alpha1"
+	cpomanifests "github.com/openshift/hypershift/control-plane-operator/controllers/hostedcontrolplane/manifests"
+	"github.com/openshift/hypershift/hypershift-operator/controllers/manifests/ignitionserver"
+	fakecapabilities "github.com/openshift/hypershift/support/capabilities/fake"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestPublishedEndpoints(t *testing.T) {
+	const controlPlaneNamespace = "clusters-test"
+	hcluster := &hyperv1.HostedCluster{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "clusters", Name: "test"},
+		Spec: hyperv1.HostedClusterSpec{
+			Services: []hyperv1.ServicePublishingStrategyMapping{
+				{
+					Service: hyperv1.APIServer,
+					ServicePublishingStrategy: hyperv1.ServicePublishingStrategy{
+						Type:         hyperv1.LoadBalancer,
+						LoadBalancer: &hyperv1.LoadBalancerPublishingStrategy{Hostname: "api.test.example.com"},
+					},
+				},
+				{
+					Service: hyperv1.Konnectivity,
+					ServicePublishingStrategy: hyperv1.ServicePublishingStrategy{
+						Type:     hyperv1.NodePort,
+						NodePort: &hyperv1.NodePortPublishingStrategy{Address: "10.0.0.10"},
+					},
+				},
+				{
+					Service: hyperv1.OAuthServer,
+					ServicePublishingStrategy: hyperv1.ServicePublishingStrategy{
+						Type: hyperv1.Route,
+					},
+				},
+				{
+					// Not published yet
+					Service: hyperv1.Ignition,
+					ServicePublishingStrategy: hyperv1.ServicePublishingStrategy{
+						Type:     hyperv1.NodePort,
+						NodePort: &hyperv1.NodePortPublishingStrategy{Address: "10.0.0.10"},
+					},
+				},
+			},
+		},
+	}
+
+	kasService := cpomanifests.KubeAPIServerService(controlPlaneNamespace)
+	kasService.Spec.Ports = []corev1.ServicePort{{Port: 6443}}
+	kasService.Status.LoadBalancer.Ingress = []corev1.LoadBalancerIngress{{IP: "192.0.2.1"}}
+	konnectivityService := cpomanifests.KonnectivityServerService(controlPlaneNamespace)
+	konnectivityService.Spec.Ports = []corev1.ServicePort{{Port: 8091, NodePort: 30091}}
+	oauthRoute := cpomanifests.OauthServerRoute(controlPlaneNamespace)
+	oauthRoute.Spec.Host = "oauth-clusters-test.apps.example.com"
+	oauthRoute.Status.Ingress = []routev1.RouteIngress{{RouterCanonicalHostname: "router-default.apps.example.com"}}
+	ignitionService := ignitionserver.Service(controlPlaneNamespace)
+	ignitionService.Spec.Ports = []corev1.ServicePort{{Port: 443}}
+
+	r := &HostedClusterReconciler{
+		Client:                        fake.NewClientBuilder().WithScheme(api.Scheme).WithObjects(kasService, konnectivityService, oauthRoute, ignitionService).Build(),
+		ManagementClusterCapabilities: &fakecapabilities.FakeSupportAllCapabilities{},
+	}
+
+	g := NewGomegaWithT(t)
+	endpoints, err := r.publishedEndpoints(context.Background(), hcluster, controlPlaneNamespace)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(endpoints).To(Equal([]PublishedEndpoint{
+		{Service: hyperv1.APIServer, Type: hyperv1.LoadBalancer, Host: "api.test.example.com", Port: 6443, Target: "192.0.2.1"},
+		{Service: hyperv1.Konnectivity, Type: hyperv1.NodePort, Host: "10.0.0.10", Port: 30091, NodePort: 30091},
+		{Service: hyperv1.OAuthServer, Type: hyperv1.Route, Host: "oauth-clusters-test.apps.example.com", Port: 443, Target: "router-default.apps.example.com"},
+	}))
+}
+
+func TestEndpointsWebhookNotify(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	var received []EndpointsNotification
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		var notification EndpointsNotification
+		g.Expect(json.NewDecoder(req.Body).Decode(&notification)).To(Succeed())
+		received = append(received, notification)
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	webhook := NewEndpointsWebhook(server.URL)
+	hcluster := &hyperv1.HostedCluster{ObjectMeta: metav1.ObjectMeta{Namespace: "clusters", Name: "test"}}
+	endpoints := []PublishedEndpoint{{Service: hyperv1.APIServer, Type: hyperv1.NodePort, Host: "10.0.0.10", Port: 30443, NodePort: 30443}}
+
+	// Failed notifications are sent again
+	status = http.StatusInternalServerError
+	g.Expect(webhook.Notify(context.Background(), endpointsNotification(hcluster, endpoints))).ToNot(Succeed())
+	status = http.StatusOK
+	g.Expect(webhook.Notify(context.Background(), endpointsNotification(hcluster, endpoints))).To(Succeed())
+	g.Expect(received).To(HaveLen(2))
+
+	// Unchanged endpoints are only sent once
+	g.Expect(webhook.Notify(context.Background(), endpointsNotification(hcluster, endpoints))).To(Succeed())
+	g.Expect(received).To(HaveLen(2))
+
+	// Deletion is sent
+	now := metav1.Now()
+	hcluster.DeletionTimestamp = &now
+	g.Expect(webhook.Notify(context.Background(), endpointsNotification(hcluster, nil))).To(Succeed())
+	g.Expect(received).To(HaveLen(3))
+	g.Expect(received[2].Deleted).To(BeTrue())
+
+	// Forgotten clusters are sent again
+	webhook.Forget(types.NamespacedName{Namespace: "clusters", Name: "test"})
+	g.Expect(webhook.Notify(context.Background(), endpointsNotification(hcluster, nil))).To(Succeed())
+	g.Expect(received).To(HaveLen(4))
+}
+
+func TestNotifyEndpointsWebhookOfDeletion(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	deletionTimestamp := metav1.Now()
+	hcluster := &hyperv1.HostedCluster{ObjectMeta: metav1.ObjectMeta{Namespace: "clusters", Name: "test", DeletionTimestamp: &deletionTimestamp}}
+
+	testCases := []struct {
+		name          string
+		sinceDeletion time.Duration
+		expectRetry   bool
+	}{
+		{
+			name:          "When the webhook fails it should retry",
+			sinceDeletion: time.Minute,
+			expectRetry:   true,
+		},
+		{
+			name:          "When the webhook fails past the timeout it should give up",
+			sinceDeletion: endpointsWebhookDeletionTimeout,
+			expectRetry:   false,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewGomegaWithT(t)
+			r := &HostedClusterReconciler{
+				Client:                        fake.NewClientBuilder().WithScheme(api.Scheme).Build(),
+				ManagementClusterCapabilities: &fakecapabilities.FakeSupportAllCapabilities{},
+				EndpointsWebhook:              NewEndpointsWebhook(server.URL),
+				now:                           func() metav1.Time { return metav1.NewTime(deletionTimestamp.Add(tc.sinceDeletion)) },
+			}
+			g.Expect(r.notifyEndpointsWebhookOfDeletion(context.Background(), hcluster, "clusters-test")).To(Equal(tc.expectRetry))
+		})
+	}
+}
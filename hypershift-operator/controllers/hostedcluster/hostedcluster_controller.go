@@ -15,12 +15,9 @@ package hostedcluster
 import (
 	"bytes"
 	"context"
-	"crypto"
 	"crypto/rsa"
 	"crypto/x509"
 	"crypto/x509/pkix"
-	"encoding/base64"
-	"encoding/json"
 	"encoding/pem"
 	"errors"
 	"fmt"
@@ -70,7 +67,6 @@ import (
 	"github.com/openshift/hypershift/support/util"
 	prometheusoperatorv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
 	"gopkg.in/ini.v1"
-	jose "gopkg.in/square/go-jose.v2"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	networkingv1 "k8s.io/api/networking/v1"
@@ -164,6 +160,10 @@ type HostedClusterReconciler struct {
 
 	MetricsSet metrics.MetricsSet
 
+	// EndpointsWebhook is notified of the endpoints of None platform clusters
+	// if set.
+	EndpointsWebhook *EndpointsWebhook
+
 	overwriteReconcile func(ctx context.Context, req ctrl.Request, log logr.Logger, hcluster *hyperv1.HostedCluster) (ctrl.Result, error)
 	now                func() metav1.Time
 }
@@ -338,6 +338,9 @@ func (r *HostedClusterReconciler) reconcile(ctx context.Context, req ctrl.Reques
 				return ctrl.Result{}, fmt.Errorf("failed to remove finalizer from hostedcluster: %w", err)
 			}
 		}
+		if r.EndpointsWebhook != nil {
+			r.EndpointsWebhook.Forget(req.NamespacedName)
+		}
 		log.Info("Deleted hostedcluster", "name", req.NamespacedName)
 		return ctrl.Result{}, nil
 	}
@@ -1356,6 +1359,18 @@ func (r *HostedClusterReconciler) reconcile(ctx context.Context, req ctrl.Reques
 		}
 	}
 
+	// Notify external automation of the endpoints of None platform clusters,
+	// which HyperShift can not set up load balancers and DNS records for.
+	if hcluster.Spec.Platform.Type == hyperv1.NonePlatform && r.EndpointsWebhook != nil {
+		endpoints, err := r.publishedEndpoints(ctx, hcluster, controlPlaneNamespace.Name)
+		if err != nil {
+			return ctrl.Result{}, fmt.Errorf("failed to get the published endpoints: %w", err)
+		}
+		if err := r.EndpointsWebhook.Notify(ctx, endpointsNotification(hcluster, endpoints)); err != nil {
+			return ctrl.Result{}, fmt.Errorf("failed to notify the endpoints webhook: %w", err)
+		}
+	}
+
 	log.Info("successfully reconciled")
 	return ctrl.Result{}, nil
 }
@@ -1377,6 +1392,7 @@ func reconcileHostedControlPlane(hcp *hyperv1.HostedControlPlane, hcluster *hype
 		hyperv1.IBMCloudKMSProviderImage,
 		hyperv1.AWSKMSProviderImage,
 		hyperv1.PortierisImageAnnotation,
+		hyperv1.OCICloudControllerManagerImage,
 		hyperutil.DebugDeploymentsAnnotation,
 		hyperv1.DisableProfilingAnnotation,
 	}
@@ -3213,6 +3229,12 @@ func (r *HostedClusterReconciler) delete(ctx context.Context, hc *hyperv1.Hosted
 		return false, fmt.Errorf("failed to clean up OIDC bucket data: %w", err)
 	}
 
+	if hc.Spec.Platform.Type == hyperv1.NonePlatform && r.EndpointsWebhook != nil {
+		if retry := r.notifyEndpointsWebhookOfDeletion(ctx, hc, controlPlaneNamespace); retry {
+			return false, nil
+		}
+	}
+
 	// Block until the namespace is deleted, so that if a hostedcluster is deleted and then re-created with the same name
 	// we don't error initially because we can not create new content in a namespace that is being deleted.
 	exists, err = hyperutil.DeleteIfNeeded(ctx, r.Client, &corev1.Namespace{
@@ -4053,10 +4075,6 @@ func generateConfigurationDocument(params oidcGeneratorParams) (io.ReadSeeker, e
 	return strings.NewReader(fmt.Sprintf(discoveryTemplate, params.issuerURL, params.issuerURL, jwksURI)), nil
 }
 
-type KeyResponse struct {
-	Keys []jose.JSONWebKey `json:"keys"`
-}
-
 func generateJWKSDocument(params oidcGeneratorParams) (io.ReadSeeker, error) {
 	block, _ := pem.Decode(params.pubKey)
 	if block == nil || block.Type != "RSA PUBLIC KEY" {
@@ -4066,25 +4084,11 @@ func generateJWKSDocument(params oidcGeneratorParams) (io.ReadSeeker, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse public key: %w", err)
 	}
-	rsaPubKey, ok := pubKey.(*rsa.PublicKey)
-	if !ok {
+	if _, ok := pubKey.(*rsa.PublicKey); !ok {
 		return nil, fmt.Errorf("public key is not RSA")
 	}
 
-	hasher := crypto.SHA256.New()
-	hasher.Write(block.Bytes)
-	hash := hasher.Sum(nil)
-	kid := base64.RawURLEncoding.EncodeToString(hash)
-
-	var keys []jose.JSONWebKey
-	keys = append(keys, jose.JSONWebKey{
-		Key:       rsaPubKey,
-		KeyID:     kid,
-		Algorithm: string(jose.RS256),
-		Use:       "sig",
-	})
-
-	jwks, err := json.MarshalIndent(KeyResponse{Keys: keys}, "", "  ")
+	jwks, err := certs.GenerateJWKS(pubKey)
 	if err != nil {
 		return nil, err
 	}
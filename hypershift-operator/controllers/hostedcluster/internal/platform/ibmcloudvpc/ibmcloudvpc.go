@@ -0,0 +1,254 @@
+package ibmcloudvpc
+
+import (
+	"context"
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	k8sutilspointer "k8s.io/utils/pointer"
+	capiibmv1 "sigs.k8s.io/cluster-api-provider-ibmcloud/api/v1beta1"
+	capiv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	hyperv1 "github.com/openshift/hypershift/api/v1alpha1"
+	"github.com/openshift/hypershift/support/upsert"
+)
+
+const (
+	// TODO: Move to OpenShift built image
+	imageCAPIBM = "k8s.gcr.io/capi-ibmcloud/cluster-api-ibmcloud-controller:v0.2.4"
+)
+
+type IBMCloudVPC struct {
+}
+
+func (p IBMCloudVPC) DeleteCredentials(ctx context.Context, c client.Client, hcluster *hyperv1.HostedCluster, controlPlaneNamespace string) error {
+	return nil
+}
+
+func (p IBMCloudVPC) ReconcileCAPIInfraCR(ctx context.Context, c client.Client, createOrUpdate upsert.CreateOrUpdateFN,
+	hcluster *hyperv1.HostedCluster,
+	controlPlaneNamespace string,
+	apiEndpoint hyperv1.APIEndpoint) (client.Object, error) {
+	platformSpec := hcluster.Spec.Platform.IBMCloudVPC
+	ibmCluster := &capiibmv1.IBMVPCCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: controlPlaneNamespace,
+			Name:      hcluster.Name,
+		},
+	}
+
+	_, err := createOrUpdate(ctx, c, ibmCluster, func() error {
+		ibmCluster.Annotations = map[string]string{
+			capiv1.ManagedByAnnotation: "external",
+		}
+		ibmCluster.Spec.Region = platformSpec.Region
+		ibmCluster.Spec.ResourceGroup = platformSpec.ResourceGroupID
+		ibmCluster.Spec.Zone = platformSpec.Zone
+		if platformSpec.VPC != nil {
+			ibmCluster.Spec.VPC = platformSpec.VPC.Name
+			ibmCluster.Status.VPC = capiibmv1.VPC{
+				ID:   platformSpec.VPC.ID,
+				Name: platformSpec.VPC.Name,
+			}
+		}
+
+		// Set the values for upper level controller
+		ibmCluster.Status.Ready = true
+		ibmCluster.Spec.ControlPlaneEndpoint = capiv1.APIEndpoint{
+			Host: apiEndpoint.Host,
+			Port: apiEndpoint.Port,
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	// reconciliation strips TypeMeta. We repopulate the static values since they are necessary for
+	// downstream reconciliation of the CAPI Cluster resource.
+	ibmCluster.TypeMeta = metav1.TypeMeta{
+		Kind:       "IBMVPCCluster",
+		APIVersion: capiibmv1.GroupVersion.String(),
+	}
+	return ibmCluster, nil
+}
+
+func (p IBMCloudVPC) CAPIProviderDeploymentSpec(hcluster *hyperv1.HostedCluster, _ *hyperv1.HostedControlPlane) (*appsv1.DeploymentSpec, error) {
+	defaultMode := int32(416)
+	deploymentSpec := &appsv1.DeploymentSpec{
+		Template: corev1.PodTemplateSpec{
+			Spec: corev1.PodSpec{
+				TerminationGracePeriodSeconds: k8sutilspointer.Int64Ptr(10),
+				Volumes: []corev1.Volume{
+					{
+						Name: "capi-webhooks-tls",
+						VolumeSource: corev1.VolumeSource{
+							Secret: &corev1.SecretVolumeSource{
+								DefaultMode: &defaultMode,
+								SecretName:  "capi-webhooks-tls",
+							},
+						},
+					},
+					{
+						Name: "credentials",
+						VolumeSource: corev1.VolumeSource{
+							Secret: &corev1.SecretVolumeSource{
+								SecretName: hcluster.Spec.Platform.IBMCloudVPC.NodePoolManagementCreds.Name,
+							},
+						},
+					},
+				},
+				Containers: []corev1.Container{
+					{
+						Name:            "manager",
+						Image:           imageCAPIBM,
+						ImagePullPolicy: corev1.PullIfNotPresent,
+						Resources: corev1.ResourceRequirements{
+							Requests: corev1.ResourceList{
+								corev1.ResourceMemory: resource.MustParse("100Mi"),
+								corev1.ResourceCPU:    resource.MustParse("10m"),
+							},
+						},
+						VolumeMounts: []corev1.VolumeMount{
+							{
+								Name:      "credentials",
+								MountPath: "/home/.ibmcloud",
+							},
+							{
+								Name:      "capi-webhooks-tls",
+								ReadOnly:  true,
+								MountPath: "/tmp/k8s-webhook-server/serving-certs",
+							},
+						},
+						Env: []corev1.EnvVar{
+							{
+								Name: "MY_NAMESPACE",
+								ValueFrom: &corev1.EnvVarSource{
+									FieldRef: &corev1.ObjectFieldSelector{
+										FieldPath: "metadata.namespace",
+									},
+								},
+							},
+							{
+								Name:  "IBM_CREDENTIALS_FILE",
+								Value: "/home/.ibmcloud/ibm-credentials.env",
+							},
+						},
+						Command: []string{"/manager"},
+						Args: []string{"--namespace", "$(MY_NAMESPACE)",
+							"--alsologtostderr",
+							"--v=4",
+							"--leader-elect=true",
+						},
+						Ports: []corev1.ContainerPort{
+							{
+								Name:          "healthz",
+								ContainerPort: 9440,
+								Protocol:      corev1.ProtocolTCP,
+							},
+						},
+						LivenessProbe: &corev1.Probe{
+							ProbeHandler: corev1.ProbeHandler{
+								HTTPGet: &corev1.HTTPGetAction{
+									Path: "/healthz",
+									Port: intstr.FromString("healthz"),
+								},
+							},
+						},
+						ReadinessProbe: &corev1.Probe{
+							ProbeHandler: corev1.ProbeHandler{
+								HTTPGet: &corev1.HTTPGetAction{
+									Path: "/readyz",
+									Port: intstr.FromString("healthz"),
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	return deploymentSpec, nil
+}
+
+func (p IBMCloudVPC) ReconcileCredentials(ctx context.Context, c client.Client, createOrUpdate upsert.CreateOrUpdateFN,
+	hcluster *hyperv1.HostedCluster,
+	controlPlaneNamespace string) error {
+	// Sync the credentials secrets referenced by the HostedCluster into the control
+	// plane namespace.
+	// The ibm-credentials.env key hosts the IBM Cloud credential used by the different
+	// components in the cluster like the CAPI controller. The cloud controller manager
+	// and the ingress operator consume the plain API key from ibmcloud_api_key.
+	platformSpec := hcluster.Spec.Platform.IBMCloudVPC
+	secrets := []struct {
+		description string
+		name        string
+		keys        []string
+	}{
+		{
+			description: "cloud controller provider",
+			name:        platformSpec.KubeCloudControllerCreds.Name,
+			keys:        []string{"ibmcloud_api_key", "ibm-credentials.env"},
+		},
+		{
+			description: "node pool provider",
+			name:        platformSpec.NodePoolManagementCreds.Name,
+			keys:        []string{"ibm-credentials.env"},
+		},
+		{
+			description: "control plane operator provider",
+			name:        platformSpec.ControlPlaneOperatorCreds.Name,
+			keys:        []string{"ibm-credentials.env"},
+		},
+		{
+			description: "ingress operator provider",
+			name:        platformSpec.IngressOperatorCloudCreds.Name,
+			keys:        []string{"ibmcloud_api_key", "ibm-credentials.env"},
+		},
+	}
+	for _, secret := range secrets {
+		var src corev1.Secret
+		if err := c.Get(ctx, client.ObjectKey{Namespace: hcluster.GetNamespace(), Name: secret.name}, &src); err != nil {
+			return fmt.Errorf("failed to get %s creds %s: %w", secret.description, secret.name, err)
+		}
+		dest := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: controlPlaneNamespace,
+				Name:      src.Name,
+			},
+		}
+		_, err := createOrUpdate(ctx, c, dest, func() error {
+			dest.Type = corev1.SecretTypeOpaque
+			if dest.Data == nil {
+				dest.Data = map[string][]byte{}
+			}
+			for _, key := range secret.keys {
+				data, hasData := src.Data[key]
+				if !hasData {
+					return fmt.Errorf("hostedcluster %s credentials secret %q must have a credentials key %s", secret.description, src.Name, key)
+				}
+				dest.Data[key] = data
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to reconcile %s creds: %w", secret.description, err)
+		}
+	}
+	return nil
+}
+
+func (IBMCloudVPC) ReconcileSecretEncryption(ctx context.Context, c client.Client, createOrUpdate upsert.CreateOrUpdateFN,
+	hcluster *hyperv1.HostedCluster,
+	controlPlaneNamespace string) error {
+	return nil
+}
+
+func (IBMCloudVPC) CAPIProviderPolicyRules() []rbacv1.PolicyRule {
+	return nil
+}
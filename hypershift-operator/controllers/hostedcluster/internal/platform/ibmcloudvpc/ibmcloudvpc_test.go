@@ -0,0 +1,204 @@
+package ibmcloudvpc
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	. "github.com/onsi/gomega"
+	"github.com/openshift/hypershift/api"
+	hyperv1 "github.com/openshift/hypershift/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	capiibmv1 "sigs.k8s.io/cluster-api-provider-ibmcloud/api/v1beta1"
+	capiv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+const (
+	fakeControlPlaneNamespace  = "master-cluster1"
+	fakeHostedClusterName      = "cluster1"
+	fakeHostedClusterNamespace = "master"
+)
+
+func fakeHostedCluster() *hyperv1.HostedCluster {
+	return &hyperv1.HostedCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fakeHostedClusterName,
+			Namespace: fakeHostedClusterNamespace,
+		},
+		Spec: hyperv1.HostedClusterSpec{
+			Platform: hyperv1.PlatformSpec{
+				Type: hyperv1.IBMCloudVPCPlatform,
+				IBMCloudVPC: &hyperv1.IBMCloudVPCPlatformSpec{
+					ResourceGroupID: "resource-group-id",
+					Region:          "us-south",
+					Zone:            "us-south-1",
+					VPC: &hyperv1.IBMCloudVPCNetwork{
+						Name: "cluster1-vpc",
+						ID:   "vpc-id",
+					},
+					KubeCloudControllerCreds:  corev1.LocalObjectReference{Name: "cloud-controller-creds"},
+					NodePoolManagementCreds:   corev1.LocalObjectReference{Name: "node-management-creds"},
+					ControlPlaneOperatorCreds: corev1.LocalObjectReference{Name: "control-plane-operator-creds"},
+					IngressOperatorCloudCreds: corev1.LocalObjectReference{Name: "ingress-creds"},
+				},
+			},
+		},
+	}
+}
+
+func TestReconcileCAPIInfraCR(t *testing.T) {
+	fakeAPIEndpoint := hyperv1.APIEndpoint{
+		Host: "example.com",
+		Port: 443,
+	}
+	withoutVPC := fakeHostedCluster()
+	withoutVPC.Spec.Platform.IBMCloudVPC.VPC = nil
+
+	tests := map[string]struct {
+		inputHostedCluster *hyperv1.HostedCluster
+		expectedObject     client.Object
+	}{
+		"when a VPC is specified the CAPI cluster uses it": {
+			inputHostedCluster: fakeHostedCluster(),
+			expectedObject: &capiibmv1.IBMVPCCluster{
+				TypeMeta: metav1.TypeMeta{
+					Kind:       "IBMVPCCluster",
+					APIVersion: capiibmv1.GroupVersion.String(),
+				},
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: fakeControlPlaneNamespace,
+					Name:      fakeHostedClusterName,
+					Annotations: map[string]string{
+						capiv1.ManagedByAnnotation: "external",
+					},
+					// since resource created through fakeclient this is set to 1 to ensure the struct compare works
+					ResourceVersion: "1",
+				},
+				Status: capiibmv1.IBMVPCClusterStatus{
+					Ready: true,
+					VPC: capiibmv1.VPC{
+						ID:   "vpc-id",
+						Name: "cluster1-vpc",
+					},
+				},
+				Spec: capiibmv1.IBMVPCClusterSpec{
+					Region:        "us-south",
+					ResourceGroup: "resource-group-id",
+					Zone:          "us-south-1",
+					VPC:           "cluster1-vpc",
+					ControlPlaneEndpoint: capiv1.APIEndpoint{
+						Port: fakeAPIEndpoint.Port,
+						Host: fakeAPIEndpoint.Host,
+					},
+				},
+			},
+		},
+		"when no VPC is specified the CAPI cluster has none": {
+			inputHostedCluster: withoutVPC,
+			expectedObject: &capiibmv1.IBMVPCCluster{
+				TypeMeta: metav1.TypeMeta{
+					Kind:       "IBMVPCCluster",
+					APIVersion: capiibmv1.GroupVersion.String(),
+				},
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: fakeControlPlaneNamespace,
+					Name:      fakeHostedClusterName,
+					Annotations: map[string]string{
+						capiv1.ManagedByAnnotation: "external",
+					},
+					ResourceVersion: "1",
+				},
+				Status: capiibmv1.IBMVPCClusterStatus{
+					Ready: true,
+				},
+				Spec: capiibmv1.IBMVPCClusterSpec{
+					Region:        "us-south",
+					ResourceGroup: "resource-group-id",
+					Zone:          "us-south-1",
+					ControlPlaneEndpoint: capiv1.APIEndpoint{
+						Port: fakeAPIEndpoint.Port,
+						Host: fakeAPIEndpoint.Host,
+					},
+				},
+			},
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			g := NewGomegaWithT(t)
+			fakeClient := fake.NewClientBuilder().WithScheme(api.Scheme).Build()
+			actualInfraCR, err := IBMCloudVPC{}.ReconcileCAPIInfraCR(context.Background(), fakeClient, controllerutil.CreateOrUpdate, test.inputHostedCluster, fakeControlPlaneNamespace, fakeAPIEndpoint)
+			g.Expect(err).To(Not(HaveOccurred()))
+			if diff := cmp.Diff(actualInfraCR, test.expectedObject); diff != "" {
+				t.Errorf("actual and expected differ: %s", diff)
+			}
+		})
+	}
+}
+
+func TestReconcileCredentials(t *testing.T) {
+	credentialsSecret := func(name string, keys ...string) *corev1.Secret {
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: fakeHostedClusterNamespace, Name: name},
+			Data:       map[string][]byte{},
+		}
+		for _, key := range keys {
+			secret.Data[key] = []byte(name + "/" + key)
+		}
+		return secret
+	}
+
+	tests := map[string]struct {
+		inputSecrets []client.Object
+		expectedErr  bool
+	}{
+		"when all the credentials are present they are synced": {
+			inputSecrets: []client.Object{
+				credentialsSecret("cloud-controller-creds", "ibmcloud_api_key", "ibm-credentials.env"),
+				credentialsSecret("node-management-creds", "ibm-credentials.env"),
+				credentialsSecret("control-plane-operator-creds", "ibm-credentials.env"),
+				credentialsSecret("ingress-creds", "ibmcloud_api_key", "ibm-credentials.env"),
+			},
+		},
+		"when a credentials secret is missing it fails": {
+			inputSecrets: []client.Object{
+				credentialsSecret("cloud-controller-creds", "ibmcloud_api_key", "ibm-credentials.env"),
+				credentialsSecret("node-management-creds", "ibm-credentials.env"),
+				credentialsSecret("control-plane-operator-creds", "ibm-credentials.env"),
+			},
+			expectedErr: true,
+		},
+		"when a credentials key is missing it fails": {
+			inputSecrets: []client.Object{
+				credentialsSecret("cloud-controller-creds", "ibm-credentials.env"),
+				credentialsSecret("node-management-creds", "ibm-credentials.env"),
+				credentialsSecret("control-plane-operator-creds", "ibm-credentials.env"),
+				credentialsSecret("ingress-creds", "ibmcloud_api_key", "ibm-credentials.env"),
+			},
+			expectedErr: true,
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			g := NewGomegaWithT(t)
+			fakeClient := fake.NewClientBuilder().WithScheme(api.Scheme).WithObjects(test.inputSecrets...).Build()
+			err := IBMCloudVPC{}.ReconcileCredentials(context.Background(), fakeClient, controllerutil.CreateOrUpdate, fakeHostedCluster(), fakeControlPlaneNamespace)
+			if test.expectedErr {
+				g.Expect(err).To(HaveOccurred())
+				return
+			}
+			g.Expect(err).To(Not(HaveOccurred()))
+
+			for _, src := range test.inputSecrets {
+				synced := &corev1.Secret{}
+				g.Expect(fakeClient.Get(context.Background(), client.ObjectKey{Namespace: fakeControlPlaneNamespace, Name: src.GetName()}, synced)).To(Succeed())
+				g.Expect(synced.Type).To(Equal(corev1.SecretTypeOpaque))
+				g.Expect(synced.Data).To(Equal(src.(*corev1.Secret).Data))
+			}
+		})
+	}
+}
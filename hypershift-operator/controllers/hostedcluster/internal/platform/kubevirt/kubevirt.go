@@ -2,9 +2,11 @@ package kubevirt
 
 import (
 	"context"
+	"fmt"
 	"os"
 
 	hyperv1 "github.com/openshift/hypershift/api/v1alpha1"
+	hyperutil "github.com/openshift/hypershift/hypershift-operator/controllers/util"
 	"github.com/openshift/hypershift/support/images"
 	"github.com/openshift/hypershift/support/upsert"
 	appsv1 "k8s.io/api/apps/v1"
@@ -22,6 +24,12 @@ import (
 const (
 	hostedClusterAnnotation = "hypershift.openshift.io/cluster"
 	imageCAPK               = "registry.ci.openshift.org/hypershift/cluster-api-kubevirt-controller:0.0.1-prerelease"
+
+	// infraCredentialsSecretName is the secret in the control plane namespace
+	// CAPK reads the kubeconfig and namespace of the infra cluster from.
+	infraCredentialsSecretName = "kubevirt-infra-credentials"
+	infraKubeconfigKey         = "kubeconfig"
+	infraNamespaceKey          = "namespace"
 )
 
 type Kubevirt struct{}
@@ -51,6 +59,16 @@ func reconcileKubevirtCluster(kubevirtCluster *capikubevirt.KubevirtCluster, hcl
 		hostedClusterAnnotation:    client.ObjectKeyFromObject(hcluster).String(),
 		capiv1.ManagedByAnnotation: "external",
 	}
+	if hasInfraCredentials(hcluster) {
+		kubevirtCluster.Spec.InfraClusterSecretRef = &corev1.ObjectReference{
+			APIVersion: "v1",
+			Kind:       "Secret",
+			Namespace:  kubevirtCluster.Namespace,
+			Name:       infraCredentialsSecretName,
+		}
+	} else {
+		kubevirtCluster.Spec.InfraClusterSecretRef = nil
+	}
 	// Set the values for upper level controller
 	kubevirtCluster.Status.Ready = true
 }
@@ -154,9 +172,54 @@ func (p Kubevirt) CAPIProviderDeploymentSpec(hcluster *hyperv1.HostedCluster, _
 func (p Kubevirt) ReconcileCredentials(ctx context.Context, c client.Client, createOrUpdate upsert.CreateOrUpdateFN,
 	hcluster *hyperv1.HostedCluster,
 	controlPlaneNamespace string) error {
+	if !hasInfraCredentials(hcluster) {
+		return nil
+	}
+	credentials := hcluster.Spec.Platform.Kubevirt.Credentials
+	if credentials.InfraKubeConfigSecret == nil || len(credentials.InfraKubeConfigSecret.Name) == 0 {
+		return fmt.Errorf("kubevirt infra credentials have no kubeconfig secret")
+	}
+
+	// Sync the kubeconfig of the infra cluster to the control plane namespace
+	// together with the namespace the VMs are placed in, so that CAPK manages
+	// the VMs on the infra cluster rather than the management cluster.
+	var src corev1.Secret
+	if err := c.Get(ctx, client.ObjectKey{Namespace: hcluster.GetNamespace(), Name: credentials.InfraKubeConfigSecret.Name}, &src); err != nil {
+		return fmt.Errorf("failed to get kubevirt infra kubeconfig secret %s: %w", credentials.InfraKubeConfigSecret.Name, err)
+	}
+	key := credentials.InfraKubeConfigSecret.Key
+	if len(key) == 0 {
+		key = infraKubeconfigKey
+	}
+	kubeconfig, ok := src.Data[key]
+	if !ok {
+		return fmt.Errorf("kubevirt infra kubeconfig secret %q must have a key %s", src.Name, key)
+	}
+	dest := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: controlPlaneNamespace,
+			Name:      infraCredentialsSecretName,
+		},
+	}
+	if _, err := createOrUpdate(ctx, c, dest, func() error {
+		dest.Type = corev1.SecretTypeOpaque
+		dest.Data = map[string][]byte{
+			infraKubeconfigKey: kubeconfig,
+			infraNamespaceKey:  []byte(credentials.InfraNamespace),
+		}
+		return nil
+	}); err != nil {
+		return fmt.Errorf("failed to reconcile kubevirt infra credentials: %w", err)
+	}
 	return nil
 }
 
+// hasInfraCredentials returns whether the worker VMs of the cluster are placed
+// on an infra cluster other than the management cluster.
+func hasInfraCredentials(hcluster *hyperv1.HostedCluster) bool {
+	return hcluster.Spec.Platform.Kubevirt != nil && hcluster.Spec.Platform.Kubevirt.Credentials != nil
+}
+
 func (Kubevirt) ReconcileSecretEncryption(ctx context.Context, c client.Client, createOrUpdate upsert.CreateOrUpdateFN,
 	hcluster *hyperv1.HostedCluster,
 	controlPlaneNamespace string) error {
@@ -179,5 +242,14 @@ func (Kubevirt) CAPIProviderPolicyRules() []rbacv1.PolicyRule {
 }
 
 func (Kubevirt) DeleteCredentials(ctx context.Context, c client.Client, hcluster *hyperv1.HostedCluster, controlPlaneNamespace string) error {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: controlPlaneNamespace,
+			Name:      infraCredentialsSecretName,
+		},
+	}
+	if _, err := hyperutil.DeleteIfNeeded(ctx, c, secret); err != nil {
+		return fmt.Errorf("failed to delete kubevirt infra credentials: %w", err)
+	}
 	return nil
 }
@@ -7,7 +7,9 @@ import (
 
 	"github.com/google/go-cmp/cmp"
 	hyperv1 "github.com/openshift/hypershift/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	capikubevirt "sigs.k8s.io/cluster-api-provider-kubevirt/api/v1alpha1"
 	capiv1 "sigs.k8s.io/cluster-api/api/v1beta1"
@@ -109,6 +111,98 @@ func TestReconcileCredentials(t *testing.T) {
 	}
 }
 
+func TestReconcileInfraCredentials(t *testing.T) {
+	hcluster := &hyperv1.HostedCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "clusters",
+			Name:      "test",
+		},
+		Spec: hyperv1.HostedClusterSpec{
+			InfraID: "testInfraID",
+			Platform: hyperv1.PlatformSpec{
+				Type: hyperv1.KubevirtPlatform,
+				Kubevirt: &hyperv1.KubevirtPlatformSpec{
+					Credentials: &hyperv1.KubevirtPlatformCredentials{
+						InfraKubeConfigSecret: &hyperv1.KubeconfigSecretRef{
+							Name: "infra-kubeconfig",
+							Key:  "kubeconfig",
+						},
+						InfraNamespace: "vms",
+					},
+				},
+			},
+		},
+	}
+	testCases := []struct {
+		name        string
+		src         *corev1.Secret
+		expectedErr bool
+	}{
+		{
+			name: "kubeconfig is synced with the infra namespace",
+			src: &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "clusters", Name: "infra-kubeconfig"},
+				Data:       map[string][]byte{"kubeconfig": []byte("infra")},
+			},
+		},
+		{
+			name: "kubeconfig key is missing",
+			src: &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "clusters", Name: "infra-kubeconfig"},
+				Data:       map[string][]byte{"other": []byte("infra")},
+			},
+			expectedErr: true,
+		},
+		{
+			name:        "secret is missing",
+			expectedErr: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			builder := fake.NewClientBuilder()
+			if tc.src != nil {
+				builder = builder.WithObjects(tc.src)
+			}
+			fakeClient := builder.Build()
+			createOrUpdateFN := func(ctx context.Context, c client.Client, obj client.Object, f controllerutil.MutateFn) (controllerutil.OperationResult, error) {
+				return controllerutil.CreateOrUpdate(ctx, c, obj, f)
+			}
+			err := Kubevirt{}.ReconcileCredentials(context.Background(), fakeClient, createOrUpdateFN, hcluster, "controlPlaneNamespace")
+			if tc.expectedErr {
+				if err == nil {
+					t.Fatalf("ReconcileCredentials: expected to fail")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ReconcileCredentials failed: %v", err)
+			}
+			dest := &corev1.Secret{}
+			if err := fakeClient.Get(context.Background(), client.ObjectKey{Namespace: "controlPlaneNamespace", Name: infraCredentialsSecretName}, dest); err != nil {
+				t.Fatalf("failed to get the infra credentials: %v", err)
+			}
+			expectedData := map[string][]byte{"kubeconfig": []byte("infra"), "namespace": []byte("vms")}
+			if !equality.Semantic.DeepEqual(expectedData, dest.Data) {
+				t.Errorf(cmp.Diff(expectedData, dest.Data))
+			}
+
+			kubevirtCluster := &capikubevirt.KubevirtCluster{ObjectMeta: metav1.ObjectMeta{Namespace: "controlPlaneNamespace"}}
+			reconcileKubevirtCluster(kubevirtCluster, hcluster)
+			if ref := kubevirtCluster.Spec.InfraClusterSecretRef; ref == nil || ref.Name != infraCredentialsSecretName || ref.Namespace != "controlPlaneNamespace" {
+				t.Errorf("KubevirtCluster does not reference the infra credentials: %v", ref)
+			}
+
+			if err := (Kubevirt{}).DeleteCredentials(context.Background(), fakeClient, hcluster, "controlPlaneNamespace"); err != nil {
+				t.Fatalf("DeleteCredentials failed: %v", err)
+			}
+			if err := fakeClient.Get(context.Background(), client.ObjectKeyFromObject(dest), dest); !apierrors.IsNotFound(err) {
+				t.Errorf("expected the infra credentials to be deleted, got: %v", err)
+			}
+		})
+	}
+}
+
 func TestReconcileSecretEncryption(t *testing.T) {
 	kubevirt := Kubevirt{}
 	fakeClient := fake.NewClientBuilder().Build()
@@ -9,6 +9,7 @@ import (
 	"github.com/openshift/hypershift/hypershift-operator/controllers/hostedcluster/internal/platform/aws"
 	"github.com/openshift/hypershift/hypershift-operator/controllers/hostedcluster/internal/platform/azure"
 	"github.com/openshift/hypershift/hypershift-operator/controllers/hostedcluster/internal/platform/ibmcloud"
+	"github.com/openshift/hypershift/hypershift-operator/controllers/hostedcluster/internal/platform/ibmcloudvpc"
 	"github.com/openshift/hypershift/hypershift-operator/controllers/hostedcluster/internal/platform/kubevirt"
 	"github.com/openshift/hypershift/hypershift-operator/controllers/hostedcluster/internal/platform/none"
 	"github.com/openshift/hypershift/hypershift-operator/controllers/hostedcluster/internal/platform/powervs"
@@ -74,6 +75,8 @@ func GetPlatform(hcluster *hyperv1.HostedCluster, utilitiesImage string) (Platfo
 		platform = &azure.Azure{}
 	case hyperv1.PowerVSPlatform:
 		platform = &powervs.PowerVS{}
+	case hyperv1.IBMCloudVPCPlatform:
+		platform = &ibmcloudvpc.IBMCloudVPC{}
 	default:
 		return nil, fmt.Errorf("unsupported platform: %s", hcluster.Spec.Platform.Type)
 	}
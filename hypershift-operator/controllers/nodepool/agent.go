@@ -1,8 +1,25 @@
 package nodepool
 
 import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
 	agentv1 "github.com/openshift/cluster-api-provider-agent/api/v1alpha1"
 	hyperv1 "github.com/openshift/hypershift/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
+	k8sutilspointer "k8s.io/utils/pointer"
+	capiv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 func agentMachineTemplateSpec(nodePool *hyperv1.NodePool) *agentv1.AgentMachineTemplateSpec {
@@ -16,3 +33,232 @@ func agentMachineTemplateSpec(nodePool *hyperv1.NodePool) *agentv1.AgentMachineT
 		},
 	}
 }
+
+const (
+	// agentBareMetalHostLabel is set by the assisted service on the Agents it
+	// creates for BareMetalHosts, to the name of their BareMetalHost.
+	agentBareMetalHostLabel = "agent-install.openshift.io/bmh"
+
+	// agentProviderIDPrefix prefixes the ID of the Agent bound to a Machine in
+	// its ProviderID.
+	agentProviderIDPrefix = "agent://"
+
+	// agentBareMetalHostRequeueInterval is how often the BareMetalHosts are
+	// checked while Machines of the NodePool wait for Agents, since neither
+	// the hosts nor the Agents are watched.
+	agentBareMetalHostRequeueInterval = 30 * time.Second
+)
+
+var (
+	bareMetalHostGVK = schema.GroupVersionKind{Group: "metal3.io", Version: "v1alpha1", Kind: "BareMetalHost"}
+	agentGVK         = schema.GroupVersionKind{Group: "agent-install.openshift.io", Version: "v1beta1", Kind: "Agent"}
+)
+
+// reconcileAgentBareMetalHosts powers on as many of the selected BareMetalHosts
+// as the NodePool has Machines, so they boot the discovery image and register as
+// Agents the Machines can bind. The hosts it powers on are claimed for the
+// NodePool with an annotation. Claimed hosts that are no longer needed are
+// powered off and released once their Agent is not bound to a Machine of the
+// NodePool anymore. It returns whether the hosts need to be checked again later.
+func (r *NodePoolReconciler) reconcileAgentBareMetalHosts(ctx context.Context, hcluster *hyperv1.HostedCluster, nodePool *hyperv1.NodePool, infraID, controlPlaneNamespace string) (bool, error) {
+	log := ctrl.LoggerFrom(ctx)
+
+	if nodePool.Spec.Platform.Agent == nil || nodePool.Spec.Platform.Agent.BareMetalHostSelector == nil {
+		removeStatusCondition(&nodePool.Status.Conditions, hyperv1.NodePoolAgentBareMetalHostsAvailableConditionType)
+		return false, nil
+	}
+	if hcluster.Spec.Platform.Agent == nil {
+		return false, fmt.Errorf("the HostedCluster for this NodePool has no .Spec.Platform.Agent, this is unsupported")
+	}
+	agentNamespace := hcluster.Spec.Platform.Agent.AgentNamespace
+	selector, err := metav1.LabelSelectorAsSelector(nodePool.Spec.Platform.Agent.BareMetalHostSelector)
+	if err != nil {
+		return false, fmt.Errorf("invalid BareMetalHost selector: %w", err)
+	}
+
+	resourcesName := generateName(infraID, nodePool.Spec.ClusterName, nodePool.GetName())
+	machines := &capiv1.MachineList{}
+	if err := r.List(ctx, machines, client.InNamespace(controlPlaneNamespace), client.MatchingLabels{resourcesName: resourcesName}); err != nil {
+		return false, fmt.Errorf("failed to list machines: %w", err)
+	}
+	var agentNames []string
+	for _, machine := range machines.Items {
+		providerID := k8sutilspointer.StringDeref(machine.Spec.ProviderID, "")
+		if strings.HasPrefix(providerID, agentProviderIDPrefix) {
+			agentNames = append(agentNames, strings.TrimPrefix(providerID, agentProviderIDPrefix))
+		}
+	}
+	boundHosts, err := r.agentBareMetalHosts(ctx, agentNamespace, agentNames)
+	if err != nil {
+		return false, err
+	}
+
+	hosts := &unstructured.UnstructuredList{}
+	hosts.SetGroupVersionKind(bareMetalHostGVK.GroupVersion().WithKind(bareMetalHostGVK.Kind + "List"))
+	if err := r.List(ctx, hosts, client.InNamespace(agentNamespace), client.MatchingLabelsSelector{Selector: selector}); err != nil {
+		if meta.IsNoMatchError(err) {
+			setStatusCondition(&nodePool.Status.Conditions, hyperv1.NodePoolCondition{
+				Type:               hyperv1.NodePoolAgentBareMetalHostsAvailableConditionType,
+				Status:             corev1.ConditionFalse,
+				Reason:             hyperv1.NodePoolValidationFailedConditionReason,
+				Message:            "BareMetalHosts are not available on the management cluster, the Bare Metal Operator is not installed",
+				ObservedGeneration: nodePool.Generation,
+			})
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to list BareMetalHosts: %w", err)
+	}
+
+	claim := client.ObjectKeyFromObject(nodePool).String()
+	claimed, free, err := partitionBareMetalHosts(hosts.Items, claim, boundHosts)
+	if err != nil {
+		return false, err
+	}
+
+	needed := len(machines.Items)
+	for i := 0; len(claimed) < needed && i < len(free); i++ {
+		host := &free[i]
+		log.Info("Powering on BareMetalHost for the NodePool", "host", host.GetName())
+		if err := r.patchBareMetalHost(ctx, host, true, claim); err != nil {
+			return false, err
+		}
+		claimed = append(claimed, *host)
+	}
+	for i := 0; len(claimed) > needed && i < len(claimed); {
+		host := &claimed[i]
+		if boundHosts.Has(host.GetName()) {
+			i++
+			continue
+		}
+		log.Info("Powering off BareMetalHost that is no longer needed by the NodePool", "host", host.GetName())
+		if err := r.patchBareMetalHost(ctx, host, false, ""); err != nil {
+			return false, err
+		}
+		claimed = append(claimed[:i], claimed[i+1:]...)
+	}
+
+	condition := hyperv1.NodePoolCondition{
+		Type:               hyperv1.NodePoolAgentBareMetalHostsAvailableConditionType,
+		Status:             corev1.ConditionTrue,
+		Reason:             hyperv1.NodePoolAsExpectedConditionReason,
+		Message:            fmt.Sprintf("%d BareMetalHosts are powered on for the NodePool", len(claimed)),
+		ObservedGeneration: nodePool.Generation,
+	}
+	if len(claimed) < needed {
+		condition.Status = corev1.ConditionFalse
+		condition.Reason = hyperv1.NodePoolNotEnoughBareMetalHostsConditionReason
+		condition.Message = fmt.Sprintf("%d Machines have no powered off BareMetalHost matching the selector to boot", needed-len(claimed))
+	}
+	setStatusCondition(&nodePool.Status.Conditions, condition)
+
+	return len(agentNames) < needed || len(claimed) > needed, nil
+}
+
+// partitionBareMetalHosts returns the hosts claimed for the NodePool, claiming
+// the hosts that back Agents bound to its Machines, and the hosts that are free
+// to be claimed because they are powered off and not claimed by another NodePool.
+func partitionBareMetalHosts(hosts []unstructured.Unstructured, claim string, boundHosts sets.String) ([]unstructured.Unstructured, []unstructured.Unstructured, error) {
+	var claimed, free []unstructured.Unstructured
+	for _, host := range hosts {
+		if !host.GetDeletionTimestamp().IsZero() {
+			continue
+		}
+		owner, isClaimed := host.GetAnnotations()[nodePoolAnnotation]
+		if owner == claim || (!isClaimed && boundHosts.Has(host.GetName())) {
+			claimed = append(claimed, host)
+			continue
+		}
+		if isClaimed {
+			continue
+		}
+		online, _, err := unstructured.NestedBool(host.Object, "spec", "online")
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid BareMetalHost %s: %w", host.GetName(), err)
+		}
+		if !online {
+			free = append(free, host)
+		}
+	}
+	sort.Slice(free, func(i, j int) bool { return free[i].GetName() < free[j].GetName() })
+	return claimed, free, nil
+}
+
+// agentBareMetalHosts returns the names of the BareMetalHosts of the Agents.
+func (r *NodePoolReconciler) agentBareMetalHosts(ctx context.Context, namespace string, agentNames []string) (sets.String, error) {
+	hosts := sets.NewString()
+	for _, name := range agentNames {
+		agent := &unstructured.Unstructured{}
+		agent.SetGroupVersionKind(agentGVK)
+		if err := r.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, agent); err != nil {
+			if apierrors.IsNotFound(err) || meta.IsNoMatchError(err) {
+				continue
+			}
+			return nil, fmt.Errorf("failed to get agent %s: %w", name, err)
+		}
+		if host := agent.GetLabels()[agentBareMetalHostLabel]; host != "" {
+			hosts.Insert(host)
+		}
+	}
+	return hosts, nil
+}
+
+// patchBareMetalHost powers the host on or off and claims it for the NodePool,
+// or releases it when claim is empty.
+func (r *NodePoolReconciler) patchBareMetalHost(ctx context.Context, host *unstructured.Unstructured, online bool, claim string) error {
+	original := host.DeepCopy()
+	if err := unstructured.SetNestedField(host.Object, online, "spec", "online"); err != nil {
+		return fmt.Errorf("failed to set power state of BareMetalHost %s: %w", host.GetName(), err)
+	}
+	annotations := host.GetAnnotations()
+	if claim == "" {
+		delete(annotations, nodePoolAnnotation)
+	} else {
+		if annotations == nil {
+			annotations = map[string]string{}
+		}
+		annotations[nodePoolAnnotation] = claim
+	}
+	host.SetAnnotations(annotations)
+	if err := r.Patch(ctx, host, client.MergeFrom(original)); err != nil {
+		return fmt.Errorf("failed to patch BareMetalHost %s: %w", host.GetName(), err)
+	}
+	return nil
+}
+
+// releaseAgentBareMetalHosts removes the claim of the NodePool from its hosts.
+// The hosts are left powered on, since their Machines may still be draining.
+func (r *NodePoolReconciler) releaseAgentBareMetalHosts(ctx context.Context, nodePool *hyperv1.NodePool) error {
+	if nodePool.Spec.Platform.Agent == nil || nodePool.Spec.Platform.Agent.BareMetalHostSelector == nil {
+		return nil
+	}
+	hcluster, err := GetHostedClusterByName(ctx, r.Client, nodePool.GetNamespace(), nodePool.Spec.ClusterName)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	if hcluster.Spec.Platform.Agent == nil {
+		return nil
+	}
+	hosts := &unstructured.UnstructuredList{}
+	hosts.SetGroupVersionKind(bareMetalHostGVK.GroupVersion().WithKind(bareMetalHostGVK.Kind + "List"))
+	if err := r.List(ctx, hosts, client.InNamespace(hcluster.Spec.Platform.Agent.AgentNamespace)); err != nil {
+		if meta.IsNoMatchError(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to list BareMetalHosts: %w", err)
+	}
+	claim := client.ObjectKeyFromObject(nodePool).String()
+	for i := range hosts.Items {
+		host := &hosts.Items[i]
+		if host.GetAnnotations()[nodePoolAnnotation] != claim {
+			continue
+		}
+		online, _, _ := unstructured.NestedBool(host.Object, "spec", "online")
+		if err := r.patchBareMetalHost(ctx, host, online, ""); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,206 @@
+package nodepool
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	hyperv1 "github.com/openshift/hypershift/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/utils/pointer"
+	capiv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestReconcileAgentBareMetalHosts(t *testing.T) {
+	const (
+		agentNamespace        = "agents"
+		controlPlaneNamespace = "clusters-example"
+		infraID               = "example-abcde"
+		claim                 = "clusters/workers"
+	)
+	resourcesName := generateName(infraID, "example", "workers")
+
+	host := func(name string, online bool, annotations map[string]string) *unstructured.Unstructured {
+		host := &unstructured.Unstructured{}
+		host.SetGroupVersionKind(bareMetalHostGVK)
+		host.SetNamespace(agentNamespace)
+		host.SetName(name)
+		host.SetLabels(map[string]string{"pool": "workers"})
+		host.SetAnnotations(annotations)
+		_ = unstructured.SetNestedField(host.Object, online, "spec", "online")
+		return host
+	}
+	agent := func(name, hostName string) *unstructured.Unstructured {
+		agent := &unstructured.Unstructured{}
+		agent.SetGroupVersionKind(agentGVK)
+		agent.SetNamespace(agentNamespace)
+		agent.SetName(name)
+		agent.SetLabels(map[string]string{agentBareMetalHostLabel: hostName})
+		return agent
+	}
+	machine := func(name, agentName string) *capiv1.Machine {
+		machine := &capiv1.Machine{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: controlPlaneNamespace,
+				Name:      name,
+				Labels:    map[string]string{resourcesName: resourcesName},
+			},
+		}
+		if agentName != "" {
+			machine.Spec.ProviderID = pointer.String(agentProviderIDPrefix + agentName)
+		}
+		return machine
+	}
+
+	testCases := []struct {
+		name              string
+		objects           []client.Object
+		expectedOnline    map[string]bool
+		expectedClaimed   []string
+		expectedStatus    corev1.ConditionStatus
+		expectedReason    string
+		expectedRequeue   bool
+		noHostSelector    bool
+		expectNoCondition bool
+	}{
+		{
+			name: "When Machines wait for Agents it should power on free hosts",
+			objects: []client.Object{
+				machine("m1", ""),
+				machine("m2", ""),
+				host("host-a", false, nil),
+				host("host-b", false, nil),
+				host("host-c", false, nil),
+				host("host-other", false, map[string]string{nodePoolAnnotation: "clusters/other"}),
+			},
+			expectedOnline:  map[string]bool{"host-a": true, "host-b": true, "host-c": false, "host-other": false},
+			expectedClaimed: []string{"host-a", "host-b"},
+			expectedStatus:  corev1.ConditionTrue,
+			expectedReason:  hyperv1.NodePoolAsExpectedConditionReason,
+			expectedRequeue: true,
+		},
+		{
+			name: "When there are not enough free hosts it should report it",
+			objects: []client.Object{
+				machine("m1", ""),
+				machine("m2", ""),
+				host("host-a", false, nil),
+				host("host-b", true, nil),
+			},
+			expectedOnline:  map[string]bool{"host-a": true, "host-b": true},
+			expectedClaimed: []string{"host-a"},
+			expectedStatus:  corev1.ConditionFalse,
+			expectedReason:  hyperv1.NodePoolNotEnoughBareMetalHostsConditionReason,
+			expectedRequeue: true,
+		},
+		{
+			name: "When the NodePool scaled down it should power off the hosts whose Agents are unbound",
+			objects: []client.Object{
+				machine("m1", "agent-a"),
+				agent("agent-a", "host-a"),
+				agent("agent-b", "host-b"),
+				host("host-a", true, map[string]string{nodePoolAnnotation: claim}),
+				host("host-b", true, map[string]string{nodePoolAnnotation: claim}),
+			},
+			expectedOnline:  map[string]bool{"host-a": true, "host-b": false},
+			expectedClaimed: []string{"host-a"},
+			expectedStatus:  corev1.ConditionTrue,
+			expectedReason:  hyperv1.NodePoolAsExpectedConditionReason,
+			expectedRequeue: false,
+		},
+		{
+			name: "When all Machines have Agents it should not requeue",
+			objects: []client.Object{
+				machine("m1", "agent-a"),
+				agent("agent-a", "host-a"),
+				host("host-a", true, map[string]string{nodePoolAnnotation: claim}),
+				host("host-b", false, nil),
+			},
+			expectedOnline:  map[string]bool{"host-a": true, "host-b": false},
+			expectedClaimed: []string{"host-a"},
+			expectedStatus:  corev1.ConditionTrue,
+			expectedReason:  hyperv1.NodePoolAsExpectedConditionReason,
+			expectedRequeue: false,
+		},
+		{
+			name: "When no host selector is set it should not touch the hosts",
+			objects: []client.Object{
+				machine("m1", ""),
+				host("host-a", false, nil),
+			},
+			noHostSelector:    true,
+			expectedOnline:    map[string]bool{"host-a": false},
+			expectNoCondition: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			scheme := runtime.NewScheme()
+			_ = capiv1.AddToScheme(scheme)
+			for _, gvk := range []schema.GroupVersionKind{bareMetalHostGVK, agentGVK} {
+				scheme.AddKnownTypeWithName(gvk, &unstructured.Unstructured{})
+				scheme.AddKnownTypeWithName(gvk.GroupVersion().WithKind(gvk.Kind+"List"), &unstructured.UnstructuredList{})
+			}
+			c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(tc.objects...).Build()
+			r := &NodePoolReconciler{Client: c}
+
+			hcluster := &hyperv1.HostedCluster{
+				Spec: hyperv1.HostedClusterSpec{
+					Platform: hyperv1.PlatformSpec{
+						Type:  hyperv1.AgentPlatform,
+						Agent: &hyperv1.AgentPlatformSpec{AgentNamespace: agentNamespace},
+					},
+				},
+			}
+			nodePool := &hyperv1.NodePool{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "clusters", Name: "workers"},
+				Spec: hyperv1.NodePoolSpec{
+					ClusterName: "example",
+					Platform: hyperv1.NodePoolPlatform{
+						Type: hyperv1.AgentPlatform,
+						Agent: &hyperv1.AgentNodePoolPlatform{
+							BareMetalHostSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"pool": "workers"}},
+						},
+					},
+				},
+			}
+			if tc.noHostSelector {
+				nodePool.Spec.Platform.Agent.BareMetalHostSelector = nil
+			}
+
+			requeue, err := r.reconcileAgentBareMetalHosts(context.Background(), hcluster, nodePool, infraID, controlPlaneNamespace)
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(requeue).To(Equal(tc.expectedRequeue))
+
+			var claimed []string
+			for name, online := range tc.expectedOnline {
+				host := &unstructured.Unstructured{}
+				host.SetGroupVersionKind(bareMetalHostGVK)
+				g.Expect(c.Get(context.Background(), client.ObjectKey{Namespace: agentNamespace, Name: name}, host)).To(Succeed())
+				isOnline, _, _ := unstructured.NestedBool(host.Object, "spec", "online")
+				g.Expect(isOnline).To(Equal(online), "power state of %s", name)
+				if host.GetAnnotations()[nodePoolAnnotation] == claim {
+					claimed = append(claimed, name)
+				}
+			}
+			g.Expect(claimed).To(ConsistOf(tc.expectedClaimed))
+
+			condition := findStatusCondition(nodePool.Status.Conditions, hyperv1.NodePoolAgentBareMetalHostsAvailableConditionType)
+			if tc.expectNoCondition {
+				g.Expect(condition).To(BeNil())
+				return
+			}
+			g.Expect(condition).ToNot(BeNil())
+			g.Expect(condition.Status).To(Equal(tc.expectedStatus))
+			g.Expect(condition.Reason).To(Equal(tc.expectedReason))
+		})
+	}
+}
@@ -1,38 +1,86 @@
 package nodepool
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"strings"
+	"time"
 
 	hyperv1 "github.com/openshift/hypershift/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
 	k8sutilspointer "k8s.io/utils/pointer"
 	capiaws "sigs.k8s.io/cluster-api-provider-aws/api/v1beta1"
+	capiv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 const (
 	// infraLifecycleOwned is the value we use when tagging infra resources to indicate
 	// that the resource is considered owned and managed by the cluster.
 	infraLifecycleOwned = "owned"
+
+	// nodePoolAnnotationAWSInstanceType records the fallback instance type a NodePool
+	// uses because EC2 had no capacity for the previous ones.
+	nodePoolAnnotationAWSInstanceType = "hypershift.openshift.io/nodePoolAWSInstanceType"
+
+	// nodePoolAnnotationAWSInstanceTypes records the instance types of the NodePool when
+	// it fell back, so that it goes back to its instance type when they change.
+	nodePoolAnnotationAWSInstanceTypes = "hypershift.openshift.io/nodePoolAWSInstanceTypes"
+
+	// awsInstanceTypeFallbackRequeueInterval is how often the instances being created are
+	// checked for capacity errors.
+	awsInstanceTypeFallbackRequeueInterval = 1 * time.Minute
 )
 
+// awsInsufficientCapacityErrorCodes are the EC2 error codes returned when there is no
+// capacity for an instance type.
+var awsInsufficientCapacityErrorCodes = []string{
+	"InsufficientInstanceCapacity",
+	"InsufficientCapacity",
+}
+
 // awsClusterCloudProviderTagKey generates the key for infra resources associated to a cluster.
 // https://github.com/kubernetes/cloud-provider-aws/blob/5f394ba297bf280ceb3edfc38922630b4bd83f46/pkg/providers/v2/tags.go#L31-L37
 func awsClusterCloudProviderTagKey(id string) string {
 	return fmt.Sprintf("kubernetes.io/cluster/%s", id)
 }
 
-func awsMachineTemplateSpec(infraName, ami string, hostedCluster *hyperv1.HostedCluster, nodePool *hyperv1.NodePool) *capiaws.AWSMachineTemplateSpec {
+// awsSubnets returns the subnets the instances of the NodePool are spread across.
+func awsSubnets(nodePool *hyperv1.NodePool) []*hyperv1.AWSResourceReference {
+	if len(nodePool.Spec.Platform.AWS.Subnets) == 0 {
+		return []*hyperv1.AWSResourceReference{nodePool.Spec.Platform.AWS.Subnet}
+	}
+	subnets := make([]*hyperv1.AWSResourceReference, 0, len(nodePool.Spec.Platform.AWS.Subnets))
+	for i := range nodePool.Spec.Platform.AWS.Subnets {
+		subnets = append(subnets, &nodePool.Spec.Platform.AWS.Subnets[i])
+	}
+	return subnets
+}
+
+func awsSubnetReference(nodePoolSubnet *hyperv1.AWSResourceReference) *capiaws.AWSResourceReference {
 	subnet := &capiaws.AWSResourceReference{}
-	if nodePool.Spec.Platform.AWS.Subnet != nil {
-		subnet.ID = nodePool.Spec.Platform.AWS.Subnet.ID
-		subnet.ARN = nodePool.Spec.Platform.AWS.Subnet.ARN
-		for k := range nodePool.Spec.Platform.AWS.Subnet.Filters {
+	if nodePoolSubnet != nil {
+		subnet.ID = nodePoolSubnet.ID
+		subnet.ARN = nodePoolSubnet.ARN
+		for k := range nodePoolSubnet.Filters {
 			filter := capiaws.Filter{
-				Name:   nodePool.Spec.Platform.AWS.Subnet.Filters[k].Name,
-				Values: nodePool.Spec.Platform.AWS.Subnet.Filters[k].Values,
+				Name:   nodePoolSubnet.Filters[k].Name,
+				Values: nodePoolSubnet.Filters[k].Values,
 			}
 			subnet.Filters = append(subnet.Filters, filter)
 		}
 	}
+	return subnet
+}
+
+func awsMachineTemplateSpec(infraName, ami string, hostedCluster *hyperv1.HostedCluster, nodePool *hyperv1.NodePool) *capiaws.AWSMachineTemplateSpec {
+	subnet := awsSubnetReference(awsSubnets(nodePool)[0])
 	rootVolume := &capiaws.Volume{
 		Size: EC2VolumeDefaultSize,
 	}
@@ -48,6 +96,13 @@ func awsMachineTemplateSpec(infraName, ami string, hostedCluster *hyperv1.Hosted
 		if nodePool.Spec.Platform.AWS.RootVolume.IOPS > 0 {
 			rootVolume.IOPS = nodePool.Spec.Platform.AWS.RootVolume.IOPS
 		}
+		rootVolume.Throughput = nodePool.Spec.Platform.AWS.RootVolume.Throughput
+		rootVolume.Encrypted = nodePool.Spec.Platform.AWS.RootVolume.Encrypted
+		rootVolume.EncryptionKey = nodePool.Spec.Platform.AWS.RootVolume.EncryptionKey
+		// EC2 only uses the KMS key of encrypted volumes.
+		if rootVolume.EncryptionKey != "" && rootVolume.Encrypted == nil {
+			rootVolume.Encrypted = k8sutilspointer.BoolPtr(true)
+		}
 	}
 
 	securityGroups := []capiaws.AWSResourceReference{}
@@ -71,7 +126,7 @@ func awsMachineTemplateSpec(infraName, ami string, hostedCluster *hyperv1.Hosted
 		instanceProfile = nodePool.Spec.Platform.AWS.InstanceProfile
 	}
 
-	instanceType := nodePool.Spec.Platform.AWS.InstanceType
+	instanceType := awsInstanceType(nodePool)
 
 	tags := capiaws.Tags{}
 	for _, tag := range append(nodePool.Spec.Platform.AWS.ResourceTags, hostedCluster.Spec.Platform.AWS.ResourceTags...) {
@@ -102,9 +157,309 @@ func awsMachineTemplateSpec(infraName, ami string, hostedCluster *hyperv1.Hosted
 				Subnet:                   subnet,
 				RootVolume:               rootVolume,
 				AdditionalTags:           tags,
+				Tenancy:                  string(nodePool.Spec.Platform.AWS.Tenancy),
 			},
 		},
 	}
 
+	if nodePool.Spec.Platform.AWS.MarketType == hyperv1.AWSMarketTypeSpot {
+		spotMarketOptions := &capiaws.SpotMarketOptions{}
+		if nodePool.Spec.Platform.AWS.Spot != nil {
+			spotMarketOptions.MaxPrice = nodePool.Spec.Platform.AWS.Spot.MaxPrice
+		}
+		awsMachineTemplateSpec.Template.Spec.SpotMarketOptions = spotMarketOptions
+	}
+
 	return awsMachineTemplateSpec
 }
+
+// reconcileAWSSubnetMachineTemplates reconciles the AWSMachineTemplates of the subnets of the
+// NodePool after the first one, which uses the NodePool template. It returns the templates of
+// all the subnets and the JSON of their specs, in the order of the subnets.
+func (r *NodePoolReconciler) reconcileAWSSubnetMachineTemplates(ctx context.Context, nodePool *hyperv1.NodePool, template *capiaws.AWSMachineTemplate, machineTemplateSpecJSON string) ([]client.Object, []string, error) {
+	log := ctrl.LoggerFrom(ctx)
+
+	templates := []client.Object{template}
+	machineTemplateSpecJSONs := []string{machineTemplateSpecJSON}
+	for i, subnet := range awsSubnets(nodePool)[1:] {
+		spec := template.Spec.DeepCopy()
+		spec.Template.Spec.Subnet = awsSubnetReference(subnet)
+		specJSON, err := json.Marshal(spec)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		subnetTemplate := &capiaws.AWSMachineTemplate{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: template.Namespace,
+				Name:      subnetResourceName(nodePool, i+1),
+			},
+		}
+		if result, err := r.CreateOrUpdate(ctx, r.Client, subnetTemplate, func() error {
+			subnetTemplate.Spec = *spec
+			if subnetTemplate.Annotations == nil {
+				subnetTemplate.Annotations = make(map[string]string)
+			}
+			subnetTemplate.Annotations[nodePoolAnnotation] = client.ObjectKeyFromObject(nodePool).String()
+			return nil
+		}); err != nil {
+			return nil, nil, fmt.Errorf("failed to reconcile AWSMachineTemplate %q: %w", client.ObjectKeyFromObject(subnetTemplate), err)
+		} else {
+			log.Info("Reconciled subnet Machine template", "name", subnetTemplate.Name, "result", result)
+		}
+		templates = append(templates, subnetTemplate)
+		machineTemplateSpecJSONs = append(machineTemplateSpecJSONs, string(specJSON))
+	}
+	return templates, machineTemplateSpecJSONs, nil
+}
+
+// awsMachineZones returns the number of Machines with an instance in each availability zone.
+func awsMachineZones(machines []capiv1.Machine) []hyperv1.NodePoolZoneStatus {
+	replicas := map[string]int32{}
+	for _, machine := range machines {
+		// AWS provider IDs have the format aws:///<zone>/<instance ID>.
+		zone, _, found := strings.Cut(strings.TrimPrefix(k8sutilspointer.StringDeref(machine.Spec.ProviderID, ""), "aws:///"), "/")
+		if !found || zone == "" {
+			continue
+		}
+		replicas[zone]++
+	}
+	var zones []hyperv1.NodePoolZoneStatus
+	for _, zone := range sets.StringKeySet(replicas).List() {
+		zones = append(zones, hyperv1.NodePoolZoneStatus{Name: zone, Replicas: replicas[zone]})
+	}
+	return zones
+}
+
+func awsPlatformValidation(nodePool *hyperv1.NodePool) error {
+	if nodePool.Spec.Platform.AWS == nil {
+		return fmt.Errorf("nodepool.spec.platform.aws is required")
+	}
+	if nodePool.Spec.Platform.AWS.Spot != nil && nodePool.Spec.Platform.AWS.MarketType != hyperv1.AWSMarketTypeSpot {
+		return fmt.Errorf("spot options can only be set when the market type is %s", hyperv1.AWSMarketTypeSpot)
+	}
+	if nodePool.Spec.Platform.AWS.Tenancy == hyperv1.AWSTenancyHost && nodePool.Spec.Platform.AWS.MarketType == hyperv1.AWSMarketTypeSpot {
+		return fmt.Errorf("spot instances cannot run on dedicated hosts")
+	}
+	if rootVolume := nodePool.Spec.Platform.AWS.RootVolume; rootVolume != nil {
+		if rootVolume.Throughput != nil && rootVolume.Type != "" && rootVolume.Type != string(capiaws.VolumeTypeGP3) {
+			return fmt.Errorf("root volume throughput can only be set for volume type %s", capiaws.VolumeTypeGP3)
+		}
+		if rootVolume.EncryptionKey != "" && rootVolume.Encrypted != nil && !*rootVolume.Encrypted {
+			return fmt.Errorf("root volume encryption key cannot be set for an unencrypted volume")
+		}
+	}
+	if len(nodePool.Spec.Platform.AWS.Subnets) > 0 {
+		if err := validateAWSSubnets(nodePool); err != nil {
+			return err
+		}
+	}
+	instanceTypes := sets.NewString(nodePool.Spec.Platform.AWS.InstanceType)
+	for _, instanceType := range nodePool.Spec.Platform.AWS.FallbackInstanceTypes {
+		if instanceType == "" {
+			return fmt.Errorf("fallback instance types cannot be empty")
+		}
+		if instanceTypes.Has(instanceType) {
+			return fmt.Errorf("instance type %s is listed more than once", instanceType)
+		}
+		instanceTypes.Insert(instanceType)
+	}
+	if nodePool.Spec.GPU != nil {
+		for _, instanceType := range instanceTypes.List() {
+			if !awsGPUInstanceType(instanceType) {
+				return fmt.Errorf("instance type %s has no NVIDIA GPUs, GPU NodePools need one of the %s instance families", instanceType, strings.Join(awsGPUInstanceFamilies.List(), ", "))
+			}
+		}
+	}
+	return nil
+}
+
+// awsGPUInstanceFamilies are the EC2 instance families with NVIDIA GPUs.
+var awsGPUInstanceFamilies = sets.NewString("g3", "g3s", "g4dn", "g5", "g5g", "p2", "p3", "p3dn", "p4d", "p4de", "p5")
+
+// awsGPUInstanceType returns whether the given EC2 instance type has NVIDIA GPUs.
+func awsGPUInstanceType(instanceType string) bool {
+	family, _, _ := strings.Cut(instanceType, ".")
+	return awsGPUInstanceFamilies.Has(family)
+}
+
+func validateAWSSubnets(nodePool *hyperv1.NodePool) error {
+	subnets := nodePool.Spec.Platform.AWS.Subnets
+	if nodePool.Spec.Platform.AWS.Subnet != nil {
+		return fmt.Errorf("only one of subnet or subnets can be set")
+	}
+	if nodePool.Spec.Management.UpgradeType == hyperv1.UpgradeTypeInPlace {
+		return fmt.Errorf("subnets cannot be set with the %s upgrade type", hyperv1.UpgradeTypeInPlace)
+	}
+	for i := range subnets {
+		for j := range subnets[:i] {
+			if equality.Semantic.DeepEqual(subnets[i], subnets[j]) {
+				return fmt.Errorf("subnet %d is the same as subnet %d", i, j)
+			}
+		}
+	}
+	if nodePool.Spec.AutoScaling != nil {
+		if int(nodePool.Spec.AutoScaling.Max) < len(subnets) {
+			return fmt.Errorf("autoscaling max must be equal or greater than the number of subnets. Max: %v, Subnets: %v", nodePool.Spec.AutoScaling.Max, len(subnets))
+		}
+		if int(nodePool.Spec.AutoScaling.Min) < len(subnets) {
+			capacity := nodeCapacity(nodePool)
+			if capacity.CPU == nil || capacity.Memory == nil {
+				return fmt.Errorf("an autoscaling min lower than the number of subnets scales some subnets from zero, which requires the CPU and memory of the nodes, set them in .spec.autoScaling.capacity")
+			}
+		}
+	}
+	return nil
+}
+
+// awsInstanceTypes returns InstanceType followed by the FallbackInstanceTypes of the NodePool.
+func awsInstanceTypes(nodePool *hyperv1.NodePool) []string {
+	return append([]string{nodePool.Spec.Platform.AWS.InstanceType}, nodePool.Spec.Platform.AWS.FallbackInstanceTypes...)
+}
+
+// awsInstanceType returns the instance type of the NodePool instances: InstanceType, or
+// the fallback instance type the NodePool fell back to for lack of capacity as long as
+// InstanceType and FallbackInstanceTypes didn't change since.
+func awsInstanceType(nodePool *hyperv1.NodePool) string {
+	annotations := nodePool.GetAnnotations()
+	instanceType := annotations[nodePoolAnnotationAWSInstanceType]
+	if annotations[nodePoolAnnotationAWSInstanceTypes] != strings.Join(awsInstanceTypes(nodePool), ",") {
+		return nodePool.Spec.Platform.AWS.InstanceType
+	}
+	for _, fallback := range nodePool.Spec.Platform.AWS.FallbackInstanceTypes {
+		if fallback == instanceType {
+			return instanceType
+		}
+	}
+	return nodePool.Spec.Platform.AWS.InstanceType
+}
+
+// nextAWSInstanceType returns the instance type to fall back to when there is no capacity
+// for the given one, or an empty string if there is none left.
+func nextAWSInstanceType(nodePool *hyperv1.NodePool, instanceType string) string {
+	instanceTypes := awsInstanceTypes(nodePool)
+	for i := range instanceTypes[:len(instanceTypes)-1] {
+		if instanceTypes[i] == instanceType {
+			return instanceTypes[i+1]
+		}
+	}
+	return ""
+}
+
+// awsMachineLacksCapacity returns true if the instance of the AWSMachine could not be
+// created because EC2 has no capacity for its instance type.
+func awsMachineLacksCapacity(awsMachine *capiaws.AWSMachine) bool {
+	if awsMachine.Spec.ProviderID != nil {
+		return false
+	}
+	for _, condition := range awsMachine.Status.Conditions {
+		if condition.Type != capiaws.InstanceReadyCondition || condition.Status != corev1.ConditionFalse ||
+			condition.Reason != capiaws.InstanceProvisionFailedReason {
+			continue
+		}
+		for _, code := range awsInsufficientCapacityErrorCodes {
+			if strings.Contains(condition.Message, code) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// reconcileAWSInstanceTypeFallback falls back to the next instance type of the NodePool when
+// its instances fail to be created for lack of capacity. It returns true while instances
+// are being created, so that they are checked again.
+//
+// The fallback applies to the whole NodePool until InstanceType or FallbackInstanceTypes
+// change, or the fallback annotation is removed, which rolls the nodes back to InstanceType.
+func (r *NodePoolReconciler) reconcileAWSInstanceTypeFallback(ctx context.Context, nodePool *hyperv1.NodePool, controlPlaneNamespace, resourcesName string) (bool, error) {
+	log := ctrl.LoggerFrom(ctx)
+
+	if len(nodePool.Spec.Platform.AWS.FallbackInstanceTypes) == 0 {
+		delete(nodePool.Annotations, nodePoolAnnotationAWSInstanceType)
+		delete(nodePool.Annotations, nodePoolAnnotationAWSInstanceTypes)
+		removeStatusCondition(&nodePool.Status.Conditions, hyperv1.NodePoolFallbackInstanceTypeConditionType)
+		return false, nil
+	}
+
+	instanceType := awsInstanceType(nodePool)
+	if instanceType == nodePool.Spec.Platform.AWS.InstanceType {
+		delete(nodePool.Annotations, nodePoolAnnotationAWSInstanceType)
+		delete(nodePool.Annotations, nodePoolAnnotationAWSInstanceTypes)
+	}
+	machines := &capiv1.MachineList{}
+	if err := r.List(ctx, machines, client.InNamespace(controlPlaneNamespace), client.MatchingLabels{resourcesName: resourcesName}); err != nil {
+		return false, fmt.Errorf("failed to list machines: %w", err)
+	}
+	creatingInstances := false
+	fallBack := false
+	var machinesLackingCapacity []*capiv1.Machine
+	machineInstanceTypes := map[string]string{}
+	for i := range machines.Items {
+		machine := &machines.Items[i]
+		if machine.Spec.InfrastructureRef.Kind != "AWSMachine" || machine.Spec.ProviderID != nil {
+			continue
+		}
+		creatingInstances = true
+		awsMachine := &capiaws.AWSMachine{}
+		if err := r.Get(ctx, client.ObjectKey{Namespace: machine.Namespace, Name: machine.Spec.InfrastructureRef.Name}, awsMachine); err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			return false, fmt.Errorf("failed to get AWSMachine %s/%s: %w", machine.Namespace, machine.Spec.InfrastructureRef.Name, err)
+		}
+		machineInstanceTypes[machine.Name] = awsMachine.Spec.InstanceType
+		if awsMachineLacksCapacity(awsMachine) {
+			machinesLackingCapacity = append(machinesLackingCapacity, machine)
+			if awsMachine.Spec.InstanceType == instanceType {
+				fallBack = true
+			}
+		}
+	}
+
+	if fallBack {
+		if next := nextAWSInstanceType(nodePool, instanceType); next == "" {
+			log.Info("No capacity for the last fallback instance type", "instanceType", instanceType)
+		} else {
+			log.Info("No capacity for instance type, falling back", "instanceType", instanceType, "fallback", next)
+			if nodePool.Annotations == nil {
+				nodePool.Annotations = make(map[string]string)
+			}
+			nodePool.Annotations[nodePoolAnnotationAWSInstanceType] = next
+			nodePool.Annotations[nodePoolAnnotationAWSInstanceTypes] = strings.Join(awsInstanceTypes(nodePool), ",")
+			instanceType = next
+		}
+	}
+
+	// A rolling update replaces the Machines of a MachineDeployment, but a MachineSet
+	// only creates Machines with the new instance type in place of deleted ones.
+	if nodePool.Spec.Management.UpgradeType == hyperv1.UpgradeTypeInPlace {
+		for _, machine := range machinesLackingCapacity {
+			if machineInstanceTypes[machine.Name] == instanceType {
+				continue
+			}
+			if err := r.Delete(ctx, machine); err != nil && !apierrors.IsNotFound(err) {
+				return false, fmt.Errorf("failed to delete machine %s: %w", client.ObjectKeyFromObject(machine), err)
+			}
+		}
+	}
+
+	if instanceType == nodePool.Spec.Platform.AWS.InstanceType {
+		setStatusCondition(&nodePool.Status.Conditions, hyperv1.NodePoolCondition{
+			Type:               hyperv1.NodePoolFallbackInstanceTypeConditionType,
+			Status:             corev1.ConditionFalse,
+			Reason:             hyperv1.NodePoolAsExpectedConditionReason,
+			Message:            fmt.Sprintf("Using instance type %s", instanceType),
+			ObservedGeneration: nodePool.Generation,
+		})
+	} else {
+		setStatusCondition(&nodePool.Status.Conditions, hyperv1.NodePoolCondition{
+			Type:               hyperv1.NodePoolFallbackInstanceTypeConditionType,
+			Status:             corev1.ConditionTrue,
+			Reason:             hyperv1.NodePoolInsufficientCapacityConditionReason,
+			Message:            fmt.Sprintf("Using fallback instance type %s because EC2 had no capacity for the previous instance types", instanceType),
+			ObservedGeneration: nodePool.Generation,
+		})
+	}
+	return creatingInstances, nil
+}
@@ -1,13 +1,21 @@
 package nodepool
 
 import (
+	"context"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
+	. "github.com/onsi/gomega"
+	api "github.com/openshift/hypershift/api"
 	hyperv1 "github.com/openshift/hypershift/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/equality"
+	apiresource "k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	k8sutilspointer "k8s.io/utils/pointer"
 	capiaws "sigs.k8s.io/cluster-api-provider-aws/api/v1beta1"
+	capiv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 )
 
 const amiName = "ami"
@@ -91,6 +99,60 @@ func TestAWSMachineTemplate(t *testing.T) {
 				tmpl.Spec.Template.Spec.AdditionalTags["nodepool-only"] = "value"
 			}),
 		},
+		{
+			name: "Spot market type uses spot instances",
+			nodePool: hyperv1.NodePoolSpec{Platform: hyperv1.NodePoolPlatform{AWS: &hyperv1.AWSNodePoolPlatform{
+				MarketType: hyperv1.AWSMarketTypeSpot,
+			}}},
+
+			expected: defaultAWSMachineTemplate(func(tmpl *capiaws.AWSMachineTemplate) {
+				tmpl.Spec.Template.Spec.SpotMarketOptions = &capiaws.SpotMarketOptions{}
+			}),
+		},
+		{
+			name: "Spot max price gets copied",
+			nodePool: hyperv1.NodePoolSpec{Platform: hyperv1.NodePoolPlatform{AWS: &hyperv1.AWSNodePoolPlatform{
+				MarketType: hyperv1.AWSMarketTypeSpot,
+				Spot:       &hyperv1.AWSSpotOptions{MaxPrice: k8sutilspointer.String("0.05")},
+			}}},
+
+			expected: defaultAWSMachineTemplate(func(tmpl *capiaws.AWSMachineTemplate) {
+				tmpl.Spec.Template.Spec.SpotMarketOptions = &capiaws.SpotMarketOptions{MaxPrice: k8sutilspointer.String("0.05")}
+			}),
+		},
+		{
+			name: "Root volume throughput and encryption key get copied",
+			nodePool: hyperv1.NodePoolSpec{Platform: hyperv1.NodePoolPlatform{AWS: &hyperv1.AWSNodePoolPlatform{
+				RootVolume: &hyperv1.Volume{
+					Size:          120,
+					Type:          "gp3",
+					IOPS:          4000,
+					Throughput:    k8sutilspointer.Int64(250),
+					EncryptionKey: "arn:aws:kms:us-east-1:123456789012:key/abcd",
+				},
+			}}},
+
+			expected: defaultAWSMachineTemplate(func(tmpl *capiaws.AWSMachineTemplate) {
+				tmpl.Spec.Template.Spec.RootVolume = &capiaws.Volume{
+					Size:          120,
+					Type:          capiaws.VolumeTypeGP3,
+					IOPS:          4000,
+					Throughput:    k8sutilspointer.Int64(250),
+					Encrypted:     k8sutilspointer.Bool(true),
+					EncryptionKey: "arn:aws:kms:us-east-1:123456789012:key/abcd",
+				}
+			}),
+		},
+		{
+			name: "Tenancy gets copied",
+			nodePool: hyperv1.NodePoolSpec{Platform: hyperv1.NodePoolPlatform{AWS: &hyperv1.AWSNodePoolPlatform{
+				Tenancy: hyperv1.AWSTenancyDedicated,
+			}}},
+
+			expected: defaultAWSMachineTemplate(func(tmpl *capiaws.AWSMachineTemplate) {
+				tmpl.Spec.Template.Spec.Tenancy = "dedicated"
+			}),
+		},
 	}
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
@@ -108,6 +170,467 @@ func TestAWSMachineTemplate(t *testing.T) {
 	}
 }
 
+func TestAWSPlatformValidation(t *testing.T) {
+	testCases := []struct {
+		name        string
+		platform    *hyperv1.AWSNodePoolPlatform
+		expectError bool
+	}{
+		{
+			name:        "When on-demand instances are used it should not fail",
+			platform:    &hyperv1.AWSNodePoolPlatform{},
+			expectError: false,
+		},
+		{
+			name: "When spot options are set for spot instances it should not fail",
+			platform: &hyperv1.AWSNodePoolPlatform{
+				MarketType: hyperv1.AWSMarketTypeSpot,
+				Spot:       &hyperv1.AWSSpotOptions{MaxPrice: k8sutilspointer.String("0.05")},
+			},
+			expectError: false,
+		},
+		{
+			name: "When spot options are set for on-demand instances it should fail",
+			platform: &hyperv1.AWSNodePoolPlatform{
+				MarketType: hyperv1.AWSMarketTypeOnDemand,
+				Spot:       &hyperv1.AWSSpotOptions{MaxPrice: k8sutilspointer.String("0.05")},
+			},
+			expectError: true,
+		},
+		{
+			name: "When dedicated hosts are used for on-demand instances it should not fail",
+			platform: &hyperv1.AWSNodePoolPlatform{
+				Tenancy: hyperv1.AWSTenancyHost,
+			},
+			expectError: false,
+		},
+		{
+			name: "When dedicated instances are used for spot instances it should not fail",
+			platform: &hyperv1.AWSNodePoolPlatform{
+				MarketType: hyperv1.AWSMarketTypeSpot,
+				Tenancy:    hyperv1.AWSTenancyDedicated,
+			},
+			expectError: false,
+		},
+		{
+			name: "When dedicated hosts are used for spot instances it should fail",
+			platform: &hyperv1.AWSNodePoolPlatform{
+				MarketType: hyperv1.AWSMarketTypeSpot,
+				Tenancy:    hyperv1.AWSTenancyHost,
+			},
+			expectError: true,
+		},
+		{
+			name: "When root volume throughput is set for a gp3 volume it should not fail",
+			platform: &hyperv1.AWSNodePoolPlatform{
+				RootVolume: &hyperv1.Volume{Type: "gp3", Throughput: k8sutilspointer.Int64(250)},
+			},
+			expectError: false,
+		},
+		{
+			name: "When root volume throughput is set for an io2 volume it should fail",
+			platform: &hyperv1.AWSNodePoolPlatform{
+				RootVolume: &hyperv1.Volume{Type: "io2", Throughput: k8sutilspointer.Int64(250)},
+			},
+			expectError: true,
+		},
+		{
+			name: "When a root volume encryption key is set for an unencrypted volume it should fail",
+			platform: &hyperv1.AWSNodePoolPlatform{
+				RootVolume: &hyperv1.Volume{Type: "gp3", Encrypted: k8sutilspointer.Bool(false), EncryptionKey: "key"},
+			},
+			expectError: true,
+		},
+		{
+			name: "When fallback instance types are set it should not fail",
+			platform: &hyperv1.AWSNodePoolPlatform{
+				InstanceType:          "m5.large",
+				FallbackInstanceTypes: []string{"m5a.large", "m6i.large"},
+			},
+			expectError: false,
+		},
+		{
+			name: "When a fallback instance type is empty it should fail",
+			platform: &hyperv1.AWSNodePoolPlatform{
+				InstanceType:          "m5.large",
+				FallbackInstanceTypes: []string{""},
+			},
+			expectError: true,
+		},
+		{
+			name: "When a fallback instance type is repeated it should fail",
+			platform: &hyperv1.AWSNodePoolPlatform{
+				InstanceType:          "m5.large",
+				FallbackInstanceTypes: []string{"m5a.large", "m5.large"},
+			},
+			expectError: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			nodePool := &hyperv1.NodePool{Spec: hyperv1.NodePoolSpec{Platform: hyperv1.NodePoolPlatform{AWS: tc.platform}}}
+			err := awsPlatformValidation(nodePool)
+			if tc.expectError != (err != nil) {
+				t.Errorf("expected error: %v, got: %v", tc.expectError, err)
+			}
+		})
+	}
+}
+
+func TestAWSGPUPlatformValidation(t *testing.T) {
+	testCases := []struct {
+		name        string
+		platform    *hyperv1.AWSNodePoolPlatform
+		expectError bool
+	}{
+		{
+			name:        "When the instance type has NVIDIA GPUs it should not fail",
+			platform:    &hyperv1.AWSNodePoolPlatform{InstanceType: "g4dn.xlarge", FallbackInstanceTypes: []string{"g5.xlarge"}},
+			expectError: false,
+		},
+		{
+			name:        "When the instance type has no GPUs it should fail",
+			platform:    &hyperv1.AWSNodePoolPlatform{InstanceType: "m5.large"},
+			expectError: true,
+		},
+		{
+			name:        "When a fallback instance type has no GPUs it should fail",
+			platform:    &hyperv1.AWSNodePoolPlatform{InstanceType: "p3.2xlarge", FallbackInstanceTypes: []string{"c5.2xlarge"}},
+			expectError: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			nodePool := &hyperv1.NodePool{Spec: hyperv1.NodePoolSpec{
+				Platform: hyperv1.NodePoolPlatform{AWS: tc.platform},
+				GPU:      &hyperv1.NodePoolGPU{Count: 1},
+			}}
+			err := awsPlatformValidation(nodePool)
+			if tc.expectError != (err != nil) {
+				t.Errorf("expected error: %v, got: %v", tc.expectError, err)
+			}
+		})
+	}
+}
+
+func TestAWSInstanceTypeFallback(t *testing.T) {
+	platform := hyperv1.NodePoolPlatform{
+		AWS: &hyperv1.AWSNodePoolPlatform{
+			InstanceType:          "m5.large",
+			FallbackInstanceTypes: []string{"m5a.large", "m6i.large"},
+		},
+	}
+	testCases := []struct {
+		name                 string
+		annotation           string
+		instanceTypes        string
+		expectedInstanceType string
+		expectedNext         string
+	}{
+		{
+			name:                 "When there is no fallback annotation it should use the instance type",
+			expectedInstanceType: "m5.large",
+			expectedNext:         "m5a.large",
+		},
+		{
+			name:                 "When the annotation is a fallback instance type it should use it",
+			annotation:           "m5a.large",
+			instanceTypes:        "m5.large,m5a.large,m6i.large",
+			expectedInstanceType: "m5a.large",
+			expectedNext:         "m6i.large",
+		},
+		{
+			name:                 "When the last fallback instance type is used there should be no next one",
+			annotation:           "m6i.large",
+			instanceTypes:        "m5.large,m5a.large,m6i.large",
+			expectedInstanceType: "m6i.large",
+			expectedNext:         "",
+		},
+		{
+			name:                 "When the annotation is not a fallback instance type it should use the instance type",
+			annotation:           "c5.large",
+			instanceTypes:        "m5.large,m5a.large,m6i.large",
+			expectedInstanceType: "m5.large",
+			expectedNext:         "m5a.large",
+		},
+		{
+			name:                 "When the instance types changed since the fallback it should use the instance type",
+			annotation:           "m5a.large",
+			instanceTypes:        "c5.large,m5a.large,m6i.large",
+			expectedInstanceType: "m5.large",
+			expectedNext:         "m5a.large",
+		},
+	}
+	hostedCluster := &hyperv1.HostedCluster{Spec: hyperv1.HostedClusterSpec{Platform: hyperv1.PlatformSpec{AWS: &hyperv1.AWSPlatformSpec{}}}}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+			nodePool := &hyperv1.NodePool{Spec: hyperv1.NodePoolSpec{Platform: platform}}
+			if tc.annotation != "" {
+				nodePool.Annotations = map[string]string{
+					nodePoolAnnotationAWSInstanceType:  tc.annotation,
+					nodePoolAnnotationAWSInstanceTypes: tc.instanceTypes,
+				}
+			}
+			instanceType := awsInstanceType(nodePool)
+			g.Expect(instanceType).To(Equal(tc.expectedInstanceType))
+			g.Expect(nextAWSInstanceType(nodePool, instanceType)).To(Equal(tc.expectedNext))
+			g.Expect(awsMachineTemplateSpec(infraName, amiName, hostedCluster, nodePool).Template.Spec.InstanceType).To(Equal(tc.expectedInstanceType))
+		})
+	}
+}
+
+func TestReconcileAWSInstanceTypeFallback(t *testing.T) {
+	const (
+		controlPlaneNamespace = "clusters-example"
+		resourcesName         = "example-nodepool"
+	)
+	lackingCapacity := capiv1.Conditions{{
+		Type:    capiaws.InstanceReadyCondition,
+		Status:  corev1.ConditionFalse,
+		Reason:  capiaws.InstanceProvisionFailedReason,
+		Message: "InsufficientInstanceCapacity: We currently do not have sufficient m5.large capacity in the Availability Zone you requested",
+	}}
+	testCases := []struct {
+		name                 string
+		annotations          map[string]string
+		instanceType         string
+		conditions           capiv1.Conditions
+		expectedInstanceType string
+		expectedAnnotations  map[string]string
+		expectedStatus       corev1.ConditionStatus
+	}{
+		{
+			name:                 "When there is no capacity for the instance type it should fall back to the next one",
+			instanceType:         "m5.large",
+			conditions:           lackingCapacity,
+			expectedInstanceType: "m5a.large",
+			expectedAnnotations: map[string]string{
+				nodePoolAnnotationAWSInstanceType:  "m5a.large",
+				nodePoolAnnotationAWSInstanceTypes: "m5.large,m5a.large",
+			},
+			expectedStatus: corev1.ConditionTrue,
+		},
+		{
+			name:                 "When the instances are created it should keep the instance type",
+			instanceType:         "m5.large",
+			expectedInstanceType: "m5.large",
+			expectedStatus:       corev1.ConditionFalse,
+		},
+		{
+			name: "When the instance types changed since the fallback it should go back to the instance type",
+			annotations: map[string]string{
+				nodePoolAnnotationAWSInstanceType:  "m5a.large",
+				nodePoolAnnotationAWSInstanceTypes: "c5.large,m5a.large",
+			},
+			instanceType:         "m5a.large",
+			expectedInstanceType: "m5.large",
+			expectedStatus:       corev1.ConditionFalse,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+			nodePool := &hyperv1.NodePool{
+				ObjectMeta: metav1.ObjectMeta{Annotations: tc.annotations},
+				Spec: hyperv1.NodePoolSpec{
+					Platform: hyperv1.NodePoolPlatform{
+						AWS: &hyperv1.AWSNodePoolPlatform{
+							InstanceType:          "m5.large",
+							FallbackInstanceTypes: []string{"m5a.large"},
+						},
+					},
+				},
+			}
+			machine := &capiv1.Machine{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: controlPlaneNamespace,
+					Name:      "example-nodepool-1234",
+					Labels:    map[string]string{resourcesName: resourcesName},
+				},
+				Spec: capiv1.MachineSpec{
+					InfrastructureRef: corev1.ObjectReference{Kind: "AWSMachine", Name: "example-nodepool-1234"},
+				},
+			}
+			awsMachine := &capiaws.AWSMachine{
+				ObjectMeta: metav1.ObjectMeta{Namespace: controlPlaneNamespace, Name: "example-nodepool-1234"},
+				Spec:       capiaws.AWSMachineSpec{InstanceType: tc.instanceType},
+				Status:     capiaws.AWSMachineStatus{Conditions: tc.conditions},
+			}
+			r := NodePoolReconciler{
+				Client: fake.NewClientBuilder().WithScheme(api.Scheme).WithObjects(machine, awsMachine).Build(),
+			}
+
+			creatingInstances, err := r.reconcileAWSInstanceTypeFallback(context.Background(), nodePool, controlPlaneNamespace, resourcesName)
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(creatingInstances).To(BeTrue())
+			g.Expect(awsInstanceType(nodePool)).To(Equal(tc.expectedInstanceType))
+			if tc.expectedAnnotations == nil {
+				g.Expect(nodePool.Annotations).To(BeEmpty())
+			} else {
+				g.Expect(nodePool.Annotations).To(Equal(tc.expectedAnnotations))
+			}
+			condition := findStatusCondition(nodePool.Status.Conditions, hyperv1.NodePoolFallbackInstanceTypeConditionType)
+			g.Expect(condition).ToNot(BeNil())
+			g.Expect(condition.Status).To(Equal(tc.expectedStatus))
+		})
+	}
+}
+
+func TestAWSMachineLacksCapacity(t *testing.T) {
+	testCases := []struct {
+		name       string
+		providerID *string
+		conditions capiv1.Conditions
+		expected   bool
+	}{
+		{
+			name:     "When the instance is not being provisioned it should not lack capacity",
+			expected: false,
+		},
+		{
+			name: "When the provisioning failed for lack of capacity it should lack capacity",
+			conditions: capiv1.Conditions{{
+				Type:    capiaws.InstanceReadyCondition,
+				Status:  corev1.ConditionFalse,
+				Reason:  capiaws.InstanceProvisionFailedReason,
+				Message: "InsufficientInstanceCapacity: We currently do not have sufficient m5.large capacity in the Availability Zone you requested",
+			}},
+			expected: true,
+		},
+		{
+			name: "When the provisioning failed for another reason it should not lack capacity",
+			conditions: capiv1.Conditions{{
+				Type:    capiaws.InstanceReadyCondition,
+				Status:  corev1.ConditionFalse,
+				Reason:  capiaws.InstanceProvisionFailedReason,
+				Message: "UnauthorizedOperation: You are not authorized to perform this operation",
+			}},
+			expected: false,
+		},
+		{
+			name:       "When the instance exists it should not lack capacity",
+			providerID: k8sutilspointer.String("aws:///us-east-1a/i-1234"),
+			conditions: capiv1.Conditions{{
+				Type:    capiaws.InstanceReadyCondition,
+				Status:  corev1.ConditionFalse,
+				Reason:  capiaws.InstanceProvisionFailedReason,
+				Message: "InsufficientInstanceCapacity",
+			}},
+			expected: false,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+			awsMachine := &capiaws.AWSMachine{
+				Spec:   capiaws.AWSMachineSpec{ProviderID: tc.providerID},
+				Status: capiaws.AWSMachineStatus{Conditions: tc.conditions},
+			}
+			g.Expect(awsMachineLacksCapacity(awsMachine)).To(Equal(tc.expected))
+		})
+	}
+}
+
+func TestValidateAWSSubnets(t *testing.T) {
+	subnets := []hyperv1.AWSResourceReference{
+		{ID: k8sutilspointer.String("subnet-a")},
+		{ID: k8sutilspointer.String("subnet-b")},
+		{ID: k8sutilspointer.String("subnet-c")},
+	}
+	capacity := &hyperv1.NodePoolCapacity{
+		CPU:    apiresource.NewQuantity(4, apiresource.DecimalSI),
+		Memory: apiresource.NewQuantity(16*1024*1024*1024, apiresource.BinarySI),
+	}
+	testCases := []struct {
+		name        string
+		platform    *hyperv1.AWSNodePoolPlatform
+		upgradeType hyperv1.UpgradeType
+		autoScaling *hyperv1.NodePoolAutoScaling
+		expectError bool
+	}{
+		{
+			name:        "When subnets are set it should not fail",
+			platform:    &hyperv1.AWSNodePoolPlatform{Subnets: subnets},
+			upgradeType: hyperv1.UpgradeTypeReplace,
+			expectError: false,
+		},
+		{
+			name: "When both subnet and subnets are set it should fail",
+			platform: &hyperv1.AWSNodePoolPlatform{
+				Subnet:  &hyperv1.AWSResourceReference{ID: k8sutilspointer.String("subnet-a")},
+				Subnets: subnets,
+			},
+			upgradeType: hyperv1.UpgradeTypeReplace,
+			expectError: true,
+		},
+		{
+			name:        "When subnets are set for in-place upgrades it should fail",
+			platform:    &hyperv1.AWSNodePoolPlatform{Subnets: subnets},
+			upgradeType: hyperv1.UpgradeTypeInPlace,
+			expectError: true,
+		},
+		{
+			name:        "When a subnet is repeated it should fail",
+			platform:    &hyperv1.AWSNodePoolPlatform{Subnets: append(subnets, subnets[0])},
+			upgradeType: hyperv1.UpgradeTypeReplace,
+			expectError: true,
+		},
+		{
+			name:        "When autoscaling max is lower than the number of subnets it should fail",
+			platform:    &hyperv1.AWSNodePoolPlatform{Subnets: subnets},
+			upgradeType: hyperv1.UpgradeTypeReplace,
+			autoScaling: &hyperv1.NodePoolAutoScaling{Min: 3, Max: 2},
+			expectError: true,
+		},
+		{
+			name:        "When autoscaling min is lower than the number of subnets and the node capacity is unknown it should fail",
+			platform:    &hyperv1.AWSNodePoolPlatform{Subnets: subnets},
+			upgradeType: hyperv1.UpgradeTypeReplace,
+			autoScaling: &hyperv1.NodePoolAutoScaling{Min: 1, Max: 6},
+			expectError: true,
+		},
+		{
+			name:        "When autoscaling min is lower than the number of subnets and the node capacity is set it should not fail",
+			platform:    &hyperv1.AWSNodePoolPlatform{Subnets: subnets},
+			upgradeType: hyperv1.UpgradeTypeReplace,
+			autoScaling: &hyperv1.NodePoolAutoScaling{Min: 1, Max: 6, Capacity: capacity},
+			expectError: false,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			nodePool := &hyperv1.NodePool{Spec: hyperv1.NodePoolSpec{
+				Platform:    hyperv1.NodePoolPlatform{Type: hyperv1.AWSPlatform, AWS: tc.platform},
+				Management:  hyperv1.NodePoolManagement{UpgradeType: tc.upgradeType},
+				AutoScaling: tc.autoScaling,
+			}}
+			err := awsPlatformValidation(nodePool)
+			if tc.expectError != (err != nil) {
+				t.Errorf("expected error: %v, got: %v", tc.expectError, err)
+			}
+		})
+	}
+}
+
+func TestAWSMachineZones(t *testing.T) {
+	g := NewWithT(t)
+	machine := func(providerID *string) capiv1.Machine {
+		return capiv1.Machine{Spec: capiv1.MachineSpec{ProviderID: providerID}}
+	}
+	machines := []capiv1.Machine{
+		machine(k8sutilspointer.String("aws:///us-east-1b/i-1")),
+		machine(k8sutilspointer.String("aws:///us-east-1a/i-2")),
+		machine(k8sutilspointer.String("aws:///us-east-1b/i-3")),
+		machine(nil),
+	}
+	g.Expect(awsMachineZones(machines)).To(Equal([]hyperv1.NodePoolZoneStatus{
+		{Name: "us-east-1a", Replicas: 1},
+		{Name: "us-east-1b", Replicas: 2},
+	}))
+	g.Expect(awsMachineZones(nil)).To(BeEmpty())
+}
+
 func withRootVolume(v *hyperv1.Volume) func(*capiaws.AWSMachineTemplate) {
 	return func(template *capiaws.AWSMachineTemplate) {
 		template.Spec.Template.Spec.RootVolume = &capiaws.Volume{
@@ -5,7 +5,9 @@ import (
 	"crypto/rsa"
 	"encoding/base64"
 	"fmt"
+	"strings"
 
+	"github.com/Azure/go-autorest/autorest/azure"
 	"golang.org/x/crypto/ssh"
 	utilpointer "k8s.io/utils/pointer"
 	capiazure "sigs.k8s.io/cluster-api-provider-azure/api/v1beta1"
@@ -31,6 +33,7 @@ func azureMachineTemplateSpec(hcluster *hyperv1.HostedCluster, nodePool *hyperv1
 			DiskSizeGB: utilpointer.Int32Ptr(nodePool.Spec.Platform.Azure.DiskSizeGB),
 			ManagedDisk: &capiazure.ManagedDiskParameters{
 				StorageAccountType: nodePool.Spec.Platform.Azure.DiskStorageAccountType,
+				DiskEncryptionSet:  diskEncryptionSet(nodePool),
 			},
 		},
 		SubnetName:             hcluster.Spec.Platform.Azure.SubnetName,
@@ -41,6 +44,29 @@ func azureMachineTemplateSpec(hcluster *hyperv1.HostedCluster, nodePool *hyperv1
 	}}}, nil
 }
 
+// azurePlatformValidation validates the Azure platform of the NodePool against
+// its HostedCluster. Whether the identity of the disk encryption set can use its
+// key is only known to Azure, which reports it when the disks are created.
+func azurePlatformValidation(hcluster *hyperv1.HostedCluster, nodePool *hyperv1.NodePool) error {
+	if nodePool.Spec.Platform.Azure == nil {
+		return fmt.Errorf("nodepool.spec.platform.azure is required")
+	}
+	if id := nodePool.Spec.Platform.Azure.DiskEncryptionSetID; id != "" {
+		resource, err := azure.ParseResourceID(id)
+		if err != nil {
+			return fmt.Errorf("invalid disk encryption set ID %s: %w", id, err)
+		}
+		if !strings.EqualFold(resource.Provider, "Microsoft.Compute") || !strings.EqualFold(resource.ResourceType, "diskEncryptionSets") {
+			return fmt.Errorf("%s is not the ID of a Microsoft.Compute/diskEncryptionSets resource", id)
+		}
+		// Machines can only use disk encryption sets of their own subscription
+		if !strings.EqualFold(resource.SubscriptionID, hcluster.Spec.Platform.Azure.SubscriptionID) {
+			return fmt.Errorf("disk encryption set %s is not in the subscription %s of the HostedCluster", id, hcluster.Spec.Platform.Azure.SubscriptionID)
+		}
+	}
+	return nil
+}
+
 func generateSSHPubkey() (string, error) {
 	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
 	if err != nil {
@@ -68,3 +94,10 @@ func failureDomain(nodepool *hyperv1.NodePool) *string {
 	}
 	return utilpointer.String(nodepool.Spec.Platform.Azure.AvailabilityZone)
 }
+
+func diskEncryptionSet(nodepool *hyperv1.NodePool) *capiazure.DiskEncryptionSetParameters {
+	if nodepool.Spec.Platform.Azure.DiskEncryptionSetID == "" {
+		return nil
+	}
+	return &capiazure.DiskEncryptionSetParameters{ID: nodepool.Spec.Platform.Azure.DiskEncryptionSetID}
+}
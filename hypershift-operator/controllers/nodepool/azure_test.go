@@ -3,6 +3,9 @@ package nodepool
 import (
 	"testing"
 
+	. "github.com/onsi/gomega"
+	capiazure "sigs.k8s.io/cluster-api-provider-azure/api/v1beta1"
+
 	hyperv1 "github.com/openshift/hypershift/api/v1alpha1"
 )
 
@@ -40,3 +43,65 @@ func TestBootImage(t *testing.T) {
 		})
 	}
 }
+
+func TestAzurePlatformValidation(t *testing.T) {
+	testCases := []struct {
+		name                string
+		diskEncryptionSetID string
+		expectErr           bool
+	}{
+		{
+			name: "when no disk encryption set is set it should pass",
+		},
+		{
+			name:                "when the disk encryption set is in the subscription of the cluster it should pass",
+			diskEncryptionSetID: "/subscriptions/123-123/resourceGroups/rg-name/providers/Microsoft.Compute/diskEncryptionSets/des-name",
+		},
+		{
+			name:                "when the disk encryption set is in another subscription it should fail",
+			diskEncryptionSetID: "/subscriptions/456-456/resourceGroups/rg-name/providers/Microsoft.Compute/diskEncryptionSets/des-name",
+			expectErr:           true,
+		},
+		{
+			name:                "when the ID is not a disk encryption set it should fail",
+			diskEncryptionSetID: "/subscriptions/123-123/resourceGroups/rg-name/providers/Microsoft.KeyVault/vaults/vault-name",
+			expectErr:           true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+			hcluster := &hyperv1.HostedCluster{Spec: hyperv1.HostedClusterSpec{Platform: hyperv1.PlatformSpec{Azure: &hyperv1.AzurePlatformSpec{
+				SubscriptionID: "123-123",
+			}}}}
+			nodePool := &hyperv1.NodePool{Spec: hyperv1.NodePoolSpec{Platform: hyperv1.NodePoolPlatform{Azure: &hyperv1.AzureNodePoolPlatform{
+				DiskEncryptionSetID: tc.diskEncryptionSetID,
+			}}}}
+			err := azurePlatformValidation(hcluster, nodePool)
+			if tc.expectErr {
+				g.Expect(err).To(HaveOccurred())
+			} else {
+				g.Expect(err).ToNot(HaveOccurred())
+			}
+		})
+	}
+}
+
+func TestAzureMachineTemplateSpecDiskEncryptionSet(t *testing.T) {
+	g := NewWithT(t)
+	hcluster := &hyperv1.HostedCluster{Spec: hyperv1.HostedClusterSpec{Platform: hyperv1.PlatformSpec{Azure: &hyperv1.AzurePlatformSpec{
+		SubscriptionID:    "123-123",
+		ResourceGroupName: "rg-name",
+	}}}}
+	nodePool := &hyperv1.NodePool{Spec: hyperv1.NodePoolSpec{Platform: hyperv1.NodePoolPlatform{Azure: &hyperv1.AzureNodePoolPlatform{}}}}
+	existing := capiazure.AzureMachineTemplateSpec{Template: capiazure.AzureMachineTemplateResource{Spec: capiazure.AzureMachineSpec{SSHPublicKey: "key"}}}
+
+	spec, err := azureMachineTemplateSpec(hcluster, nodePool, existing)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(spec.Template.Spec.OSDisk.ManagedDisk.DiskEncryptionSet).To(BeNil())
+
+	nodePool.Spec.Platform.Azure.DiskEncryptionSetID = "/subscriptions/123-123/resourceGroups/rg-name/providers/Microsoft.Compute/diskEncryptionSets/des-name"
+	spec, err = azureMachineTemplateSpec(hcluster, nodePool, existing)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(spec.Template.Spec.OSDisk.ManagedDisk.DiskEncryptionSet).To(Equal(&capiazure.DiskEncryptionSetParameters{ID: nodePool.Spec.Platform.Azure.DiskEncryptionSetID}))
+}
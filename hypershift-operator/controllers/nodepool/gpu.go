@@ -0,0 +1,194 @@
+package nodepool
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/clarketm/json"
+	ignitionapi "github.com/coreos/ignition/v2/config/v3_2/types"
+	api "github.com/openshift/hypershift/api"
+	hyperv1 "github.com/openshift/hypershift/api/v1alpha1"
+	"github.com/openshift/hypershift/control-plane-operator/controllers/hostedcontrolplane/ignition"
+	mcfgv1 "github.com/openshift/hypershift/thirdparty/machineconfigoperator/pkg/apis/machineconfiguration.openshift.io/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+)
+
+const (
+	gpuResourceName             = "nvidia.com/gpu"
+	gpuPresentLabel             = "nvidia.com/gpu.present"
+	gpuDriverRoot               = "/run/nvidia/driver"
+	defaultGPUDevicePluginImage = "nvcr.io/nvidia/k8s-device-plugin:v0.14.0"
+)
+
+// gpuDriverUnit runs the NVIDIA driver container, which builds and loads the driver
+// and keeps running to expose it under /run/nvidia/driver.
+const gpuDriverUnit = `[Unit]
+Description=NVIDIA driver container
+Wants=network-online.target
+After=network-online.target
+Before=kubelet.service
+
+[Service]
+ExecStartPre=-/bin/podman rm --force nvidia-driver
+ExecStart=/bin/podman run --name nvidia-driver --authfile /var/lib/kubelet/config.json --privileged --pid=host --net=host --volume /run/nvidia:/run/nvidia:shared --volume /var/log:/var/log --volume /etc/os-release:/host-etc/os-release:ro %s
+ExecStop=/bin/podman stop nvidia-driver
+Restart=on-failure
+RestartSec=10
+
+[Install]
+WantedBy=multi-user.target
+`
+
+// gpuCount returns the number of GPUs of a node of a NodePool with GPUs.
+func gpuCount(gpu *hyperv1.NodePoolGPU) int32 {
+	if gpu.Count < 1 {
+		return 1
+	}
+	return gpu.Count
+}
+
+// gpuNodeLabelsAndTaints returns the node labels and taints of the NodePool
+// including the ones of its GPU configuration.
+func gpuNodeLabelsAndTaints(nodePool *hyperv1.NodePool) (map[string]string, []hyperv1.Taint) {
+	if nodePool.Spec.GPU == nil {
+		return nodePool.Spec.NodeLabels, nodePool.Spec.Taints
+	}
+	labels := make(map[string]string, len(nodePool.Spec.NodeLabels)+1)
+	for key, value := range nodePool.Spec.NodeLabels {
+		labels[key] = value
+	}
+	labels[gpuPresentLabel] = "true"
+
+	taints := nodePool.Spec.Taints
+	if nodePool.Spec.GPU.Dedicated {
+		dedicated := hyperv1.Taint{Key: gpuResourceName, Value: "present", Effect: corev1.TaintEffectNoSchedule}
+		found := false
+		for _, taint := range taints {
+			if taint.Key == dedicated.Key && taint.Effect == dedicated.Effect {
+				found = true
+				break
+			}
+		}
+		if !found {
+			taints = append(append([]hyperv1.Taint{}, taints...), dedicated)
+		}
+	}
+	return labels, taints
+}
+
+// gpuDriversMachineConfig returns the MachineConfig that loads the NVIDIA driver and
+// runs the NVIDIA device plugin as a static pod on the nodes of a NodePool.
+func gpuDriversMachineConfig(drivers *hyperv1.NodePoolGPUDrivers) (string, error) {
+	devicePluginImage := drivers.DevicePluginImage
+	if devicePluginImage == "" {
+		devicePluginImage = defaultGPUDevicePluginImage
+	}
+	devicePluginPod, err := gpuDevicePluginPod(devicePluginImage)
+	if err != nil {
+		return "", fmt.Errorf("failed to serialize nvidia device plugin pod: %w", err)
+	}
+
+	config := &ignitionapi.Config{}
+	config.Ignition.Version = ignitionapi.MaxVersion.String()
+	config.Storage.Files = []ignitionapi.File{
+		fileFromBytes("/etc/kubernetes/manifests/nvidia-device-plugin.yaml", 0644, devicePluginPod),
+	}
+	unit := fmt.Sprintf(gpuDriverUnit, drivers.DriverImage)
+	config.Systemd.Units = []ignitionapi.Unit{
+		{
+			Name:     "nvidia-driver.service",
+			Contents: &unit,
+			Enabled:  pointer.BoolPtr(true),
+		},
+	}
+	serializedConfig, err := json.Marshal(config)
+	if err != nil {
+		return "", fmt.Errorf("failed to serialize nvidia gpu ignition config: %w", err)
+	}
+
+	machineConfig := &mcfgv1.MachineConfig{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "50-nvidia-gpu",
+		},
+	}
+	machineConfig.APIVersion = mcfgv1.SchemeGroupVersion.String()
+	machineConfig.Kind = "MachineConfig"
+	ignition.SetMachineConfigLabels(machineConfig)
+	machineConfig.Spec.Config.Raw = serializedConfig
+
+	buf := &bytes.Buffer{}
+	if err := api.YamlSerializer.Encode(machineConfig, buf); err != nil {
+		return "", fmt.Errorf("failed to serialize nvidia gpu machine config: %w", err)
+	}
+	return buf.String(), nil
+}
+
+func gpuDevicePluginPod(image string) ([]byte, error) {
+	hostPathDirectory := corev1.HostPathDirectoryOrCreate
+	pod := &corev1.Pod{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: corev1.SchemeGroupVersion.String(),
+			Kind:       "Pod",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "nvidia-device-plugin",
+			Namespace: "kube-system",
+			Labels: map[string]string{
+				"k8s-app": "nvidia-device-plugin",
+			},
+		},
+		Spec: corev1.PodSpec{
+			PriorityClassName: "system-node-critical",
+			Containers: []corev1.Container{{
+				Name:  "nvidia-device-plugin",
+				Image: image,
+				Env: []corev1.EnvVar{
+					{
+						Name:  "NVIDIA_DRIVER_ROOT",
+						Value: gpuDriverRoot,
+					},
+				},
+				SecurityContext: &corev1.SecurityContext{
+					Privileged: pointer.BoolPtr(true),
+				},
+				VolumeMounts: []corev1.VolumeMount{
+					{
+						Name:      "device-plugins",
+						MountPath: "/var/lib/kubelet/device-plugins",
+					},
+					{
+						Name:      "driver-root",
+						MountPath: gpuDriverRoot,
+						ReadOnly:  true,
+					},
+				},
+			}},
+			Volumes: []corev1.Volume{
+				{
+					Name: "device-plugins",
+					VolumeSource: corev1.VolumeSource{
+						HostPath: &corev1.HostPathVolumeSource{
+							Path: "/var/lib/kubelet/device-plugins",
+						},
+					},
+				},
+				{
+					Name: "driver-root",
+					VolumeSource: corev1.VolumeSource{
+						HostPath: &corev1.HostPathVolumeSource{
+							Path: gpuDriverRoot,
+							Type: &hostPathDirectory,
+						},
+					},
+				},
+			},
+		},
+	}
+	out := &bytes.Buffer{}
+	if err := api.YamlSerializer.Encode(pod, out); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
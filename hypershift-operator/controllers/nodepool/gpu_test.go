@@ -0,0 +1,114 @@
+package nodepool
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	hyperv1 "github.com/openshift/hypershift/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	apiresource "k8s.io/apimachinery/pkg/api/resource"
+)
+
+func TestGPUNodeLabelsAndTaints(t *testing.T) {
+	dedicated := hyperv1.Taint{Key: gpuResourceName, Value: "present", Effect: corev1.TaintEffectNoSchedule}
+	testCases := []struct {
+		name           string
+		spec           hyperv1.NodePoolSpec
+		expectedLabels map[string]string
+		expectedTaints []hyperv1.Taint
+	}{
+		{
+			name: "When the NodePool has no GPUs it should return its labels and taints",
+			spec: hyperv1.NodePoolSpec{
+				NodeLabels: map[string]string{"role": "infra"},
+				Taints:     []hyperv1.Taint{{Key: "dedicated", Value: "infra", Effect: corev1.TaintEffectNoSchedule}},
+			},
+			expectedLabels: map[string]string{"role": "infra"},
+			expectedTaints: []hyperv1.Taint{{Key: "dedicated", Value: "infra", Effect: corev1.TaintEffectNoSchedule}},
+		},
+		{
+			name: "When the NodePool has GPUs it should label the nodes",
+			spec: hyperv1.NodePoolSpec{
+				NodeLabels: map[string]string{"role": "ml"},
+				GPU:        &hyperv1.NodePoolGPU{Count: 1},
+			},
+			expectedLabels: map[string]string{"role": "ml", gpuPresentLabel: "true"},
+		},
+		{
+			name: "When the GPU nodes are dedicated it should taint them",
+			spec: hyperv1.NodePoolSpec{
+				Taints: []hyperv1.Taint{{Key: "dedicated", Value: "ml", Effect: corev1.TaintEffectNoSchedule}},
+				GPU:    &hyperv1.NodePoolGPU{Count: 1, Dedicated: true},
+			},
+			expectedLabels: map[string]string{gpuPresentLabel: "true"},
+			expectedTaints: []hyperv1.Taint{{Key: "dedicated", Value: "ml", Effect: corev1.TaintEffectNoSchedule}, dedicated},
+		},
+		{
+			name: "When the GPU nodes are dedicated and already tainted for GPUs it should keep the taint",
+			spec: hyperv1.NodePoolSpec{
+				Taints: []hyperv1.Taint{{Key: gpuResourceName, Effect: corev1.TaintEffectNoSchedule}},
+				GPU:    &hyperv1.NodePoolGPU{Count: 1, Dedicated: true},
+			},
+			expectedLabels: map[string]string{gpuPresentLabel: "true"},
+			expectedTaints: []hyperv1.Taint{{Key: gpuResourceName, Effect: corev1.TaintEffectNoSchedule}},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+			nodePool := &hyperv1.NodePool{Spec: tc.spec}
+			labels, taints := gpuNodeLabelsAndTaints(nodePool)
+			g.Expect(labels).To(Equal(tc.expectedLabels))
+			g.Expect(taints).To(Equal(tc.expectedTaints))
+			g.Expect(nodePool.Spec).To(Equal(tc.spec))
+		})
+	}
+}
+
+func TestGPUNodeCapacity(t *testing.T) {
+	g := NewWithT(t)
+	nodePool := &hyperv1.NodePool{
+		Spec: hyperv1.NodePoolSpec{
+			Platform: hyperv1.NodePoolPlatform{Type: hyperv1.AWSPlatform},
+			AutoScaling: &hyperv1.NodePoolAutoScaling{
+				Min: 0,
+				Max: 3,
+				Capacity: &hyperv1.NodePoolCapacity{
+					CPU:    apiresource.NewQuantity(4, apiresource.DecimalSI),
+					Memory: apiresource.NewQuantity(16*1024*1024*1024, apiresource.BinarySI),
+				},
+			},
+			GPU: &hyperv1.NodePoolGPU{Count: 4},
+		},
+	}
+	annotations := map[string]string{}
+	setAutoscalerCapacityAnnotations(nodePool, annotations)
+	g.Expect(annotations).To(Equal(map[string]string{
+		autoscalerCapacityCPUAnnotation:      "4",
+		autoscalerCapacityMemoryAnnotation:   "16Gi",
+		autoscalerCapacityGPUCountAnnotation: "4",
+		autoscalerCapacityGPUTypeAnnotation:  gpuResourceName,
+		autoscalerCapacityLabelsAnnotation:   "kubernetes.io/arch=amd64,nvidia.com/gpu.present=true",
+	}))
+}
+
+func TestGPUDriversMachineConfig(t *testing.T) {
+	g := NewWithT(t)
+
+	config, err := gpuDriversMachineConfig(&hyperv1.NodePoolGPUDrivers{
+		DriverImage: "nvcr.io/nvidia/driver:525.105.17-rhcos4.12",
+	})
+	g.Expect(err).ToNot(HaveOccurred())
+
+	manifest, err := defaultAndValidateConfigManifest([]byte(config))
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(string(manifest)).To(ContainSubstring("machineconfiguration.openshift.io/role: worker"))
+	g.Expect(string(manifest)).To(ContainSubstring("nvidia-driver.service"))
+	g.Expect(string(manifest)).To(ContainSubstring("nvcr.io/nvidia/driver:525.105.17-rhcos4.12"))
+	g.Expect(string(manifest)).To(ContainSubstring("/etc/kubernetes/manifests/nvidia-device-plugin.yaml"))
+
+	devicePluginPod, err := gpuDevicePluginPod(defaultGPUDevicePluginImage)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(string(devicePluginPod)).To(ContainSubstring("image: " + defaultGPUDevicePluginImage))
+	g.Expect(string(devicePluginPod)).To(ContainSubstring("path: " + gpuDriverRoot))
+}
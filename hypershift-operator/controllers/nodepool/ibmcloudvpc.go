@@ -0,0 +1,26 @@
+package nodepool
+
+import (
+	hyperv1 "github.com/openshift/hypershift/api/v1alpha1"
+	capiibm "sigs.k8s.io/cluster-api-provider-ibmcloud/api/v1beta1"
+)
+
+func ibmVPCMachineTemplateSpec(hcluster *hyperv1.HostedCluster, nodePool *hyperv1.NodePool) *capiibm.IBMVPCMachineTemplateSpec {
+	platformSpec := hcluster.Spec.Platform.IBMCloudVPC
+	var subnetID string
+	if platformSpec.VPC != nil {
+		subnetID = platformSpec.VPC.Subnet.ID
+	}
+	return &capiibm.IBMVPCMachineTemplateSpec{
+		Template: capiibm.IBMVPCMachineTemplateResource{
+			Spec: capiibm.IBMVPCMachineSpec{
+				Image:   nodePool.Spec.Platform.IBMCloudVPC.Image,
+				Zone:    platformSpec.Zone,
+				Profile: nodePool.Spec.Platform.IBMCloudVPC.Profile,
+				PrimaryNetworkInterface: capiibm.NetworkInterface{
+					Subnet: subnetID,
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,47 @@
+package nodepool
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	hyperv1 "github.com/openshift/hypershift/api/v1alpha1"
+)
+
+func TestIBMVPCMachineTemplateSpec(t *testing.T) {
+	g := NewWithT(t)
+	hcluster := &hyperv1.HostedCluster{
+		Spec: hyperv1.HostedClusterSpec{
+			Platform: hyperv1.PlatformSpec{
+				Type: hyperv1.IBMCloudVPCPlatform,
+				IBMCloudVPC: &hyperv1.IBMCloudVPCPlatformSpec{
+					Zone: "us-south-1",
+					VPC: &hyperv1.IBMCloudVPCNetwork{
+						Name: "example-vpc",
+						ID:   "vpc-id",
+						Subnet: hyperv1.IBMCloudVPCSubnet{
+							Name: "example-vpc-subnet",
+							ID:   "subnet-id",
+						},
+					},
+				},
+			},
+		},
+	}
+	nodePool := &hyperv1.NodePool{
+		Spec: hyperv1.NodePoolSpec{
+			Platform: hyperv1.NodePoolPlatform{
+				Type: hyperv1.IBMCloudVPCPlatform,
+				IBMCloudVPC: &hyperv1.IBMCloudVPCNodePoolPlatform{
+					Profile: "bx2-4x16",
+					Image:   "image-id",
+				},
+			},
+		},
+	}
+
+	spec := ibmVPCMachineTemplateSpec(hcluster, nodePool).Template.Spec
+	g.Expect(spec.Image).To(Equal("image-id"))
+	g.Expect(spec.Profile).To(Equal("bx2-4x16"))
+	g.Expect(spec.Zone).To(Equal("us-south-1"))
+	g.Expect(spec.PrimaryNetworkInterface.Subnet).To(Equal("subnet-id"))
+}
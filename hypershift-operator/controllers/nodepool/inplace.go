@@ -29,11 +29,22 @@ func (r *NodePoolReconciler) reconcileMachineSet(ctx context.Context,
 		machineSet.Annotations = map[string]string{}
 	}
 	machineSet.Annotations[nodePoolAnnotation] = client.ObjectKeyFromObject(nodePool).String()
+	// Node labels and taints are propagated to the nodes by the hosted cluster config operator.
+	if err := setNodeLabelsAndTaintsAnnotations(nodePool, machineSet.Annotations); err != nil {
+		return err
+	}
 	if machineSet.GetLabels() == nil {
 		machineSet.Labels = map[string]string{}
 	}
 	machineSet.Labels[capiv1.ClusterLabelName] = CAPIClusterName
 
+	// Signal how many Nodes can be upgraded at the same time.
+	if nodePool.Spec.Management.InPlace != nil && nodePool.Spec.Management.InPlace.MaxUnavailable != nil {
+		machineSet.Annotations[nodePoolAnnotationMaxUnavailable] = nodePool.Spec.Management.InPlace.MaxUnavailable.String()
+	} else {
+		delete(machineSet.Annotations, nodePoolAnnotationMaxUnavailable)
+	}
+
 	resourcesName := generateName(CAPIClusterName, nodePool.Spec.ClusterName, nodePool.GetName())
 	machineSet.Spec.MinReadySeconds = int32(0)
 
@@ -166,6 +177,7 @@ func (r *NodePoolReconciler) reconcileMachineSet(ctx context.Context,
 
 	// Bubble up AvailableReplicas and Ready condition from MachineSet.
 	nodePool.Status.Replicas = machineSet.Status.AvailableReplicas
+	nodePool.Status.SpotInterruptions = spotInterruptions(machineSet.Annotations)
 	for _, c := range machineSet.Status.Conditions {
 		// This condition should aggregate and summarise readiness from underlying MachineSets and Machines
 		// https://github.com/kubernetes-sigs/cluster-api/issues/3486.
@@ -204,18 +216,20 @@ func setMachineSetReplicas(nodePool *hyperv1.NodePool, machineSet *capiv1.Machin
 
 	if isAutoscalingEnabled(nodePool) {
 		if k8sutilspointer.Int32PtrDerefOr(machineSet.Spec.Replicas, 0) == 0 {
-			// if autoscaling is enabled and the MachineSet does not exist yet or it has 0 replicas
-			// we set it to 1 replica as the autoscaler does not support scaling from zero yet.
-			machineSet.Spec.Replicas = k8sutilspointer.Int32Ptr(int32(1))
+			// if autoscaling is enabled and the MachineSet does not exist yet or it has 0 replicas,
+			// e.g. when resuming from hibernation, we set it to its minimum, as the autoscaler doesn't scale
+			// it up to it. A MachineSet that can scale from zero is left at zero.
+			machineSet.Spec.Replicas = k8sutilspointer.Int32Ptr(nodePool.Spec.AutoScaling.Min)
 		}
 		machineSet.Annotations[autoscalerMaxAnnotation] = strconv.Itoa(int(nodePool.Spec.AutoScaling.Max))
 		machineSet.Annotations[autoscalerMinAnnotation] = strconv.Itoa(int(nodePool.Spec.AutoScaling.Min))
 	}
+	setAutoscalerCapacityAnnotations(nodePool, machineSet.Annotations)
 
 	// If autoscaling is NOT enabled we reset min/max annotations and reconcile replicas.
 	if !isAutoscalingEnabled(nodePool) {
 		machineSet.Annotations[autoscalerMaxAnnotation] = "0"
 		machineSet.Annotations[autoscalerMinAnnotation] = "0"
-		machineSet.Spec.Replicas = k8sutilspointer.Int32Ptr(k8sutilspointer.Int32PtrDerefOr(nodePool.Spec.Replicas, 0))
+		machineSet.Spec.Replicas = k8sutilspointer.Int32Ptr(desiredReplicas(nodePool))
 	}
 }
@@ -0,0 +1,205 @@
+package nodepool
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	api "github.com/openshift/hypershift/api"
+	hyperv1 "github.com/openshift/hypershift/api/v1alpha1"
+	mcfgv1 "github.com/openshift/hypershift/thirdparty/machineconfigoperator/pkg/apis/machineconfiguration.openshift.io/v1"
+	corev1 "k8s.io/api/core/v1"
+	apiresource "k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	nodePoolKubeletConfigName = "nodepool-kubelet-config"
+	workerPoolSelectorLabel   = "pools.operator.machineconfiguration.openshift.io/worker"
+)
+
+// kubeletEvictionSignals are the eviction signals supported by the kubelet.
+var kubeletEvictionSignals = sets.NewString(
+	"memory.available",
+	"nodefs.available",
+	"nodefs.inodesFree",
+	"imagefs.available",
+	"imagefs.inodesFree",
+	"pid.available",
+)
+
+// kubeletReservableResources are the resources the kubelet can reserve for
+// the system daemons.
+var kubeletReservableResources = sets.NewString(
+	string(corev1.ResourceCPU),
+	string(corev1.ResourceMemory),
+	string(corev1.ResourceEphemeralStorage),
+	"pid",
+)
+
+// kubeletConfiguration is the subset of the kubelet configuration set by the
+// KubeletConfig, the PerformanceProfile and the taints of a NodePool.
+type kubeletConfiguration struct {
+	MaxPods                 *int32            `json:"maxPods,omitempty"`
+	SystemReserved          map[string]string `json:"systemReserved,omitempty"`
+	EvictionHard            map[string]string `json:"evictionHard,omitempty"`
+	EvictionSoft            map[string]string `json:"evictionSoft,omitempty"`
+	EvictionSoftGracePeriod map[string]string `json:"evictionSoftGracePeriod,omitempty"`
+	TopologyManagerPolicy   string            `json:"topologyManagerPolicy,omitempty"`
+	ReservedSystemCPUs      string            `json:"reservedSystemCPUs,omitempty"`
+	CPUManagerPolicy        string            `json:"cpuManagerPolicy,omitempty"`
+	RegisterWithTaints      []corev1.Taint    `json:"registerWithTaints,omitempty"`
+}
+
+// validateKubeletConfig does additional backend validation of the KubeletConfig
+// of a NodePool that the API validation can't express.
+func validateKubeletConfig(config *hyperv1.NodePoolKubeletConfig) error {
+	var errs []error
+	reservedResources := sets.NewString()
+	for resource := range config.SystemReserved {
+		reservedResources.Insert(string(resource))
+	}
+	for _, resource := range reservedResources.List() {
+		if !kubeletReservableResources.Has(resource) {
+			errs = append(errs, fmt.Errorf("systemReserved: unsupported resource %q, must be one of %v", resource, kubeletReservableResources.List()))
+		}
+	}
+
+	validateThresholds := func(field string, thresholds map[string]string) {
+		for _, signal := range sets.StringKeySet(thresholds).List() {
+			if !kubeletEvictionSignals.Has(signal) {
+				errs = append(errs, fmt.Errorf("%s: unsupported eviction signal %q, must be one of %v", field, signal, kubeletEvictionSignals.List()))
+				continue
+			}
+			if err := validateEvictionThreshold(thresholds[signal]); err != nil {
+				errs = append(errs, fmt.Errorf("%s: invalid threshold for %q: %w", field, signal, err))
+			}
+		}
+	}
+	validateThresholds("evictionHard", config.EvictionHard)
+	validateThresholds("evictionSoft", config.EvictionSoft)
+
+	for _, signal := range sets.StringKeySet(config.EvictionSoft).List() {
+		if _, ok := config.EvictionSoftGracePeriod[signal]; !ok {
+			errs = append(errs, fmt.Errorf("evictionSoftGracePeriod: missing grace period for soft eviction signal %q", signal))
+		}
+	}
+	for _, signal := range sets.StringKeySet(config.EvictionSoftGracePeriod).List() {
+		if _, ok := config.EvictionSoft[signal]; !ok {
+			errs = append(errs, fmt.Errorf("evictionSoftGracePeriod: signal %q has no soft eviction threshold", signal))
+			continue
+		}
+		gracePeriod, err := time.ParseDuration(config.EvictionSoftGracePeriod[signal])
+		if err != nil {
+			errs = append(errs, fmt.Errorf("evictionSoftGracePeriod: invalid grace period for %q: %w", signal, err))
+		} else if gracePeriod < 0 {
+			errs = append(errs, fmt.Errorf("evictionSoftGracePeriod: grace period for %q must not be negative", signal))
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// validateEvictionThreshold validates an eviction threshold, which is either a
+// percentage or a quantity.
+func validateEvictionThreshold(threshold string) error {
+	if strings.HasSuffix(threshold, "%") {
+		percentage, err := strconv.ParseFloat(strings.TrimSuffix(threshold, "%"), 64)
+		if err != nil {
+			return err
+		}
+		if percentage < 0 || percentage > 100 {
+			return fmt.Errorf("percentage %s must be between 0%% and 100%%", threshold)
+		}
+		return nil
+	}
+	quantity, err := apiresource.ParseQuantity(threshold)
+	if err != nil {
+		return err
+	}
+	if quantity.Sign() < 0 {
+		return fmt.Errorf("quantity %s must not be negative", threshold)
+	}
+	return nil
+}
+
+// hasKubeletConfig returns true if the kubelet of the nodes of a NodePool is
+// configured by its KubeletConfig, its PerformanceProfile or its taints.
+func hasKubeletConfig(nodePool *hyperv1.NodePool) bool {
+	_, taints := gpuNodeLabelsAndTaints(nodePool)
+	return nodePool.Spec.KubeletConfig != nil ||
+		(nodePool.Spec.PerformanceProfile != nil && nodePool.Spec.PerformanceProfile.ReservedCPUs != "") ||
+		len(taints) > 0
+}
+
+// nodePoolKubeletConfig returns a serialized KubeletConfig for the worker pool
+// that applies the KubeletConfig and the reserved CPUs of the PerformanceProfile
+// of a NodePool to its nodes. The taints of the NodePool are registered by the
+// kubelet, so that nodes are tainted before any pod can be scheduled on them.
+func nodePoolKubeletConfig(nodePool *hyperv1.NodePool) (string, error) {
+	kubeletConfig := kubeletConfiguration{}
+	if config := nodePool.Spec.KubeletConfig; config != nil {
+		if err := validateKubeletConfig(config); err != nil {
+			return "", fmt.Errorf("invalid kubelet config: %w", err)
+		}
+		kubeletConfig.MaxPods = config.MaxPods
+		kubeletConfig.EvictionHard = config.EvictionHard
+		kubeletConfig.EvictionSoft = config.EvictionSoft
+		kubeletConfig.EvictionSoftGracePeriod = config.EvictionSoftGracePeriod
+		kubeletConfig.TopologyManagerPolicy = config.TopologyManagerPolicy
+		if len(config.SystemReserved) > 0 {
+			kubeletConfig.SystemReserved = make(map[string]string, len(config.SystemReserved))
+			for resource, quantity := range config.SystemReserved {
+				kubeletConfig.SystemReserved[string(resource)] = quantity.String()
+			}
+		}
+	}
+	if profile := nodePool.Spec.PerformanceProfile; profile != nil && profile.ReservedCPUs != "" {
+		kubeletConfig.ReservedSystemCPUs = profile.ReservedCPUs
+		kubeletConfig.CPUManagerPolicy = staticCPUManagerPolicy
+	}
+	if _, taints := gpuNodeLabelsAndTaints(nodePool); len(taints) > 0 {
+		kubeletConfig.RegisterWithTaints = nodeTaints(taints)
+	}
+	serializedKubeletConfig, err := json.Marshal(kubeletConfig)
+	if err != nil {
+		return "", fmt.Errorf("failed to serialize kubelet configuration: %w", err)
+	}
+
+	obj := &mcfgv1.KubeletConfig{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: nodePoolKubeletConfigName,
+		},
+		Spec: mcfgv1.KubeletConfigSpec{
+			MachineConfigPoolSelector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{
+					workerPoolSelectorLabel: "",
+				},
+			},
+		},
+	}
+	obj.APIVersion = mcfgv1.SchemeGroupVersion.String()
+	obj.Kind = "KubeletConfig"
+	obj.Spec.KubeletConfig = &runtime.RawExtension{Raw: serializedKubeletConfig}
+
+	buf := &bytes.Buffer{}
+	if err := api.YamlSerializer.Encode(obj, buf); err != nil {
+		return "", fmt.Errorf("failed to serialize kubelet config: %w", err)
+	}
+	return buf.String(), nil
+}
+
+// isKubeletConfigManifest returns true if a config manifest is a KubeletConfig.
+func isKubeletConfigManifest(manifest []byte) bool {
+	typeMeta := metav1.TypeMeta{}
+	if err := yaml.Unmarshal(manifest, &typeMeta); err != nil {
+		return false
+	}
+	return typeMeta.Kind == "KubeletConfig"
+}
@@ -0,0 +1,183 @@
+package nodepool
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	hyperv1 "github.com/openshift/hypershift/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	apiresource "k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/utils/pointer"
+)
+
+func TestValidateKubeletConfig(t *testing.T) {
+	testCases := []struct {
+		name        string
+		config      *hyperv1.NodePoolKubeletConfig
+		expectError bool
+	}{
+		{
+			name: "When the kubelet config is valid it should pass",
+			config: &hyperv1.NodePoolKubeletConfig{
+				MaxPods: pointer.Int32Ptr(500),
+				SystemReserved: corev1.ResourceList{
+					corev1.ResourceCPU:    apiresource.MustParse("500m"),
+					corev1.ResourceMemory: apiresource.MustParse("1Gi"),
+				},
+				EvictionHard:            map[string]string{"memory.available": "500Mi", "nodefs.available": "10%"},
+				EvictionSoft:            map[string]string{"memory.available": "1Gi"},
+				EvictionSoftGracePeriod: map[string]string{"memory.available": "1m30s"},
+				TopologyManagerPolicy:   "single-numa-node",
+			},
+		},
+		{
+			name: "When a system reserved resource is not supported it should fail",
+			config: &hyperv1.NodePoolKubeletConfig{
+				SystemReserved: corev1.ResourceList{
+					"nvidia.com/gpu": apiresource.MustParse("1"),
+				},
+			},
+			expectError: true,
+		},
+		{
+			name: "When an eviction signal is not supported it should fail",
+			config: &hyperv1.NodePoolKubeletConfig{
+				EvictionHard: map[string]string{"memory.free": "500Mi"},
+			},
+			expectError: true,
+		},
+		{
+			name: "When an eviction threshold is not a quantity nor a percentage it should fail",
+			config: &hyperv1.NodePoolKubeletConfig{
+				EvictionHard: map[string]string{"memory.available": "lots"},
+			},
+			expectError: true,
+		},
+		{
+			name: "When an eviction threshold percentage is over 100% it should fail",
+			config: &hyperv1.NodePoolKubeletConfig{
+				EvictionHard: map[string]string{"nodefs.available": "110%"},
+			},
+			expectError: true,
+		},
+		{
+			name: "When a soft eviction signal has no grace period it should fail",
+			config: &hyperv1.NodePoolKubeletConfig{
+				EvictionSoft: map[string]string{"memory.available": "1Gi"},
+			},
+			expectError: true,
+		},
+		{
+			name: "When a grace period has no soft eviction signal it should fail",
+			config: &hyperv1.NodePoolKubeletConfig{
+				EvictionSoftGracePeriod: map[string]string{"memory.available": "1m"},
+			},
+			expectError: true,
+		},
+		{
+			name: "When a grace period is not a duration it should fail",
+			config: &hyperv1.NodePoolKubeletConfig{
+				EvictionSoft:            map[string]string{"memory.available": "1Gi"},
+				EvictionSoftGracePeriod: map[string]string{"memory.available": "soon"},
+			},
+			expectError: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+			err := validateKubeletConfig(tc.config)
+			if tc.expectError {
+				g.Expect(err).To(HaveOccurred())
+				return
+			}
+			g.Expect(err).ToNot(HaveOccurred())
+		})
+	}
+}
+
+func TestNodePoolKubeletConfig(t *testing.T) {
+	g := NewWithT(t)
+
+	config, err := nodePoolKubeletConfig(&hyperv1.NodePool{
+		Spec: hyperv1.NodePoolSpec{
+			KubeletConfig: &hyperv1.NodePoolKubeletConfig{
+				MaxPods: pointer.Int32Ptr(500),
+				SystemReserved: corev1.ResourceList{
+					corev1.ResourceMemory: apiresource.MustParse("1Gi"),
+				},
+				EvictionHard:          map[string]string{"memory.available": "500Mi"},
+				TopologyManagerPolicy: "best-effort",
+			},
+		},
+	})
+	g.Expect(err).ToNot(HaveOccurred())
+
+	manifest, err := defaultAndValidateConfigManifest([]byte(config))
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(isKubeletConfigManifest(manifest)).To(BeTrue())
+	g.Expect(string(manifest)).To(ContainSubstring(workerPoolSelectorLabel + `: ""`))
+	g.Expect(string(manifest)).To(ContainSubstring("maxPods: 500"))
+	g.Expect(string(manifest)).To(ContainSubstring("memory: 1Gi"))
+	g.Expect(string(manifest)).To(ContainSubstring("memory.available: 500Mi"))
+	g.Expect(string(manifest)).To(ContainSubstring("topologyManagerPolicy: best-effort"))
+
+	_, err = nodePoolKubeletConfig(&hyperv1.NodePool{
+		Spec: hyperv1.NodePoolSpec{
+			KubeletConfig: &hyperv1.NodePoolKubeletConfig{
+				EvictionSoft: map[string]string{"memory.available": "1Gi"},
+			},
+		},
+	})
+	g.Expect(err).To(HaveOccurred())
+}
+
+func TestNodePoolKubeletConfigWithReservedCPUs(t *testing.T) {
+	g := NewWithT(t)
+
+	nodePool := &hyperv1.NodePool{
+		Spec: hyperv1.NodePoolSpec{
+			PerformanceProfile: &hyperv1.NodePoolPerformanceProfile{
+				ReservedCPUs: "0-1",
+				IsolatedCPUs: "2-15",
+			},
+		},
+	}
+	g.Expect(hasKubeletConfig(nodePool)).To(BeTrue())
+	config, err := nodePoolKubeletConfig(nodePool)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(config).To(ContainSubstring("reservedSystemCPUs: 0-1"))
+	g.Expect(config).To(ContainSubstring("cpuManagerPolicy: static"))
+
+	nodePool.Spec.PerformanceProfile.ReservedCPUs = ""
+	g.Expect(hasKubeletConfig(nodePool)).To(BeFalse())
+}
+
+func TestNodePoolKubeletConfigWithTaints(t *testing.T) {
+	g := NewWithT(t)
+
+	nodePool := &hyperv1.NodePool{
+		Spec: hyperv1.NodePoolSpec{
+			Taints: []hyperv1.Taint{
+				{Key: "dedicated", Value: "infra", Effect: corev1.TaintEffectNoSchedule},
+			},
+		},
+	}
+	g.Expect(hasKubeletConfig(nodePool)).To(BeTrue())
+	config, err := nodePoolKubeletConfig(nodePool)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(config).To(ContainSubstring("registerWithTaints:"))
+	g.Expect(config).To(ContainSubstring("key: dedicated"))
+	g.Expect(config).To(ContainSubstring("value: infra"))
+	g.Expect(config).To(ContainSubstring("effect: NoSchedule"))
+
+	nodePool.Spec.Taints = nil
+	g.Expect(hasKubeletConfig(nodePool)).To(BeFalse())
+}
+
+func TestIsKubeletConfigManifest(t *testing.T) {
+	g := NewWithT(t)
+	g.Expect(isKubeletConfigManifest([]byte("apiVersion: machineconfiguration.openshift.io/v1\nkind: KubeletConfig\n"))).To(BeTrue())
+	g.Expect(isKubeletConfigManifest([]byte("apiVersion: machineconfiguration.openshift.io/v1\nkind: MachineConfig\n"))).To(BeFalse())
+	g.Expect(isKubeletConfigManifest([]byte("not: [yaml"))).To(BeFalse())
+}
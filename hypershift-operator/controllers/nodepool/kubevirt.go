@@ -1,17 +1,26 @@
 package nodepool
 
 import (
+	"context"
 	"fmt"
+	"sort"
 	"strings"
+	"time"
 
 	hyperv1 "github.com/openshift/hypershift/api/v1alpha1"
 	"github.com/openshift/hypershift/support/releaseinfo"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	apiresource "k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
 	kubevirtv1 "kubevirt.io/api/core/v1"
 	"kubevirt.io/containerized-data-importer-api/pkg/apis/core/v1beta1"
 	capikubevirt "sigs.k8s.io/cluster-api-provider-kubevirt/api/v1alpha1"
+	capiv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 func getKubeVirtImage(nodePool *hyperv1.NodePool, releaseImage *releaseinfo.ReleaseImage) (string, error) {
@@ -40,9 +49,109 @@ func kubevirtPlatformValidation(nodePool *hyperv1.NodePool) error {
 		}
 	}
 
+	names := map[string]bool{}
+	for _, network := range kvPlatform.AdditionalNetworks {
+		parts := strings.Split(network.Name, "/")
+		if len(parts) > 2 {
+			return fmt.Errorf("the kubevirt additional network name %q must be in the form <name> or <namespace>/<name>", network.Name)
+		}
+		for _, part := range parts {
+			if errs := validation.IsDNS1123Subdomain(part); len(errs) > 0 {
+				return fmt.Errorf("the kubevirt additional network name %q is invalid: %s", network.Name, strings.Join(errs, ", "))
+			}
+		}
+		if names[network.Name] {
+			return fmt.Errorf("the kubevirt additional network %s is specified more than once", network.Name)
+		}
+		names[network.Name] = true
+	}
+
+	for _, device := range kvPlatform.HostDevices {
+		if errs := validation.IsQualifiedName(device.DeviceName); len(errs) > 0 {
+			return fmt.Errorf("the kubevirt host device name %q is invalid: %s", device.DeviceName, strings.Join(errs, ", "))
+		}
+		if device.Count < 0 {
+			return fmt.Errorf("the kubevirt host device %s count must not be negative", device.DeviceName)
+		}
+	}
+
 	return nil
 }
 
+// hostDevices returns the GPUs and other host devices of the VMs. The
+// virt-launcher pods request the devices as resources, so the VMs are only
+// scheduled onto nodes exposing them.
+func hostDevices(devices []hyperv1.KubevirtHostDevice) ([]kubevirtv1.GPU, []kubevirtv1.HostDevice) {
+	var gpus []kubevirtv1.GPU
+	var others []kubevirtv1.HostDevice
+	for _, device := range devices {
+		count := int(device.Count)
+		if count == 0 {
+			count = 1
+		}
+		for i := 0; i < count; i++ {
+			switch device.Type {
+			case hyperv1.KubevirtHostDeviceTypeHostDevice:
+				others = append(others, kubevirtv1.HostDevice{
+					Name:       fmt.Sprintf("hostdevice%d", len(others)+1),
+					DeviceName: device.DeviceName,
+				})
+			default:
+				gpus = append(gpus, kubevirtv1.GPU{
+					Name:       fmt.Sprintf("gpu%d", len(gpus)+1),
+					DeviceName: device.DeviceName,
+				})
+			}
+		}
+	}
+	return gpus, others
+}
+
+// kubevirtNodeCapacity returns the capacity of the VMs of the NodePool.
+func kubevirtNodeCapacity(kvPlatform *hyperv1.KubevirtNodePoolPlatform) hyperv1.NodePoolCapacity {
+	capacity := hyperv1.NodePoolCapacity{Architecture: "amd64"}
+	if kvPlatform.Compute != nil {
+		if kvPlatform.Compute.Cores != nil {
+			capacity.CPU = apiresource.NewQuantity(int64(*kvPlatform.Compute.Cores), apiresource.DecimalSI)
+		}
+		if kvPlatform.Compute.Memory != nil {
+			memory := kvPlatform.Compute.Memory.DeepCopy()
+			capacity.Memory = &memory
+		}
+	}
+	gpus, _ := hostDevices(kvPlatform.HostDevices)
+	capacity.GPU = int32(len(gpus))
+	return capacity
+}
+
+// additionalNetworkInterfaces returns the interfaces and networks of the
+// additional networks of the VMs. The pod network is always the first one, so
+// that the nodes keep using it for the cluster traffic.
+func additionalNetworkInterfaces(networks []hyperv1.KubevirtNetwork) ([]kubevirtv1.Interface, []kubevirtv1.Network) {
+	var interfaces []kubevirtv1.Interface
+	var vmNetworks []kubevirtv1.Network
+	for i, network := range networks {
+		name := fmt.Sprintf("iface%d", i+1)
+		iface := kubevirtv1.Interface{Name: name}
+		switch network.Binding {
+		case hyperv1.KubevirtNetworkBindingSRIOV:
+			iface.SRIOV = &kubevirtv1.InterfaceSRIOV{}
+		default:
+			iface.Bridge = &kubevirtv1.InterfaceBridge{}
+		}
+		interfaces = append(interfaces, iface)
+		vmNetworks = append(vmNetworks, kubevirtv1.Network{
+			Name: name,
+			NetworkSource: kubevirtv1.NetworkSource{
+				Multus: &kubevirtv1.MultusNetwork{
+					NetworkName: network.Name,
+				},
+			},
+		})
+	}
+	return interfaces, vmNetworks
+}
+
 func virtualMachineTemplateBase(image string, kvPlatform *hyperv1.KubevirtNodePoolPlatform) *capikubevirt.VirtualMachineTemplateSpec {
 
 	var memory apiresource.Quantity
@@ -51,6 +160,9 @@ func virtualMachineTemplateBase(image string, kvPlatform *hyperv1.KubevirtNodePo
 
 	rootVolumeName := "rhcos"
 	runAlways := kubevirtv1.RunStrategyAlways
+	// Live migrate the VMs off infra nodes that are drained, rather than
+	// shutting them down together with the workloads of their guest nodes.
+	liveMigrate := kubevirtv1.EvictionStrategyLiveMigrate
 	pullMethod := v1beta1.RegistryPullNode
 
 	if kvPlatform.Compute != nil {
@@ -77,6 +189,7 @@ func virtualMachineTemplateBase(image string, kvPlatform *hyperv1.KubevirtNodePo
 			RunStrategy: &runAlways,
 			Template: &kubevirtv1.VirtualMachineInstanceTemplateSpec{
 				Spec: kubevirtv1.VirtualMachineInstanceSpec{
+					EvictionStrategy: &liveMigrate,
 					Domain: kubevirtv1.DomainSpec{
 						CPU:    &kubevirtv1.CPU{Cores: cores},
 						Memory: &kubevirtv1.Memory{Guest: &memory},
@@ -104,6 +217,11 @@ func virtualMachineTemplateBase(image string, kvPlatform *hyperv1.KubevirtNodePo
 		},
 	}
 
+	interfaces, networks := additionalNetworkInterfaces(kvPlatform.AdditionalNetworks)
+	template.Spec.Template.Spec.Domain.Devices.Interfaces = append(template.Spec.Template.Spec.Domain.Devices.Interfaces, interfaces...)
+	template.Spec.Template.Spec.Networks = append(template.Spec.Template.Spec.Networks, networks...)
+	template.Spec.Template.Spec.Domain.Devices.GPUs, template.Spec.Template.Spec.Domain.Devices.HostDevices = hostDevices(kvPlatform.HostDevices)
+
 	template.Spec.Template.Spec.Domain.Devices.Disks = []kubevirtv1.Disk{
 		{
 			Name: rootVolumeName,
@@ -180,8 +298,16 @@ func virtualMachineTemplateBase(image string, kvPlatform *hyperv1.KubevirtNodePo
 	return template
 }
 
+const (
+	kubevirtNodePoolNameLabelKey = "hypershift.kubevirt.io/node-pool-name"
+
+	// kubevirtLiveMigrationRequeueInterval is how often the VMs are checked
+	// while they are migrating or cannot be migrated.
+	kubevirtLiveMigrationRequeueInterval = 30 * time.Second
+)
+
 func kubevirtMachineTemplateSpec(image string, nodePool *hyperv1.NodePool) *capikubevirt.KubevirtMachineTemplateSpec {
-	nodePoolNameLabelKey := "hypershift.kubevirt.io/node-pool-name"
+	nodePoolNameLabelKey := kubevirtNodePoolNameLabelKey
 
 	vmTemplate := virtualMachineTemplateBase(image, nodePool.Spec.Platform.Kubevirt)
 
@@ -225,3 +351,134 @@ func kubevirtMachineTemplateSpec(image string, nodePool *hyperv1.NodePool) *capi
 		},
 	}
 }
+
+// kubevirtLiveMigrationCondition returns whether the VMs of the NodePool can be
+// live migrated off their infra nodes, and whether any of them is migrating.
+// VMs that are not running yet do not report whether they are migratable.
+func kubevirtLiveMigrationCondition(nodePool *hyperv1.NodePool, vmis []kubevirtv1.VirtualMachineInstance) (hyperv1.NodePoolCondition, bool) {
+	var notMigratable []string
+	migrating := 0
+	for _, vmi := range vmis {
+		for _, condition := range vmi.Status.Conditions {
+			if condition.Type == kubevirtv1.VirtualMachineInstanceIsMigratable && condition.Status == corev1.ConditionFalse {
+				notMigratable = append(notMigratable, fmt.Sprintf("%s: %s", vmi.Name, condition.Message))
+			}
+		}
+		if isKubevirtMigrating(&vmi) {
+			migrating++
+		}
+	}
+	sort.Strings(notMigratable)
+
+	condition := hyperv1.NodePoolCondition{
+		Type:               hyperv1.NodePoolKubevirtLiveMigratableConditionType,
+		Status:             corev1.ConditionTrue,
+		Reason:             hyperv1.NodePoolAsExpectedConditionReason,
+		ObservedGeneration: nodePool.Generation,
+	}
+	if len(notMigratable) > 0 {
+		condition.Status = corev1.ConditionFalse
+		condition.Reason = hyperv1.NodePoolNotLiveMigratableConditionReason
+		condition.Message = fmt.Sprintf("VMs that are replaced rather than live migrated when their infra nodes are drained: %s", strings.Join(notMigratable, "; "))
+	}
+	if migrating > 0 {
+		if condition.Message != "" {
+			condition.Message += ". "
+		}
+		condition.Message += fmt.Sprintf("%d VMs are being live migrated", migrating)
+	}
+	return condition, migrating > 0
+}
+
+// isKubevirtMigrating returns whether the VM is being live migrated, or is
+// about to be because its infra node is being drained.
+func isKubevirtMigrating(vmi *kubevirtv1.VirtualMachineInstance) bool {
+	if vmi.IsMarkedForEviction() {
+		return true
+	}
+	state := vmi.Status.MigrationState
+	return state != nil && !state.Completed && !state.Failed
+}
+
+// reconcileKubevirtLiveMigration reports whether the VMs of the NodePool can be
+// live migrated, and replaces the VMs that cannot be when their infra node is
+// drained. KubeVirt refuses to evict them, which would block the drain, so their
+// Machines are deleted instead, which drains their guest nodes before the VMs
+// are shut down. It returns whether the VMs need to be checked again later,
+// since the VMs are not watched. The VMs and infra nodes of an external infra
+// cluster are not visible from the management cluster, so live migration is
+// reported as not checked for them.
+func (r *NodePoolReconciler) reconcileKubevirtLiveMigration(ctx context.Context, nodePool *hyperv1.NodePool, hcluster *hyperv1.HostedCluster, controlPlaneNamespace string) (bool, error) {
+	log := ctrl.LoggerFrom(ctx)
+
+	if kubevirt := hcluster.Spec.Platform.Kubevirt; kubevirt != nil && kubevirt.Credentials != nil {
+		setStatusCondition(&nodePool.Status.Conditions, hyperv1.NodePoolCondition{
+			Type:               hyperv1.NodePoolKubevirtLiveMigratableConditionType,
+			Status:             corev1.ConditionUnknown,
+			Reason:             hyperv1.NodePoolExternalInfraClusterConditionReason,
+			Message:            "Live migration is not checked for VMs on an external infra cluster: VMs that cannot be live migrated are not replaced when their infra nodes are drained",
+			ObservedGeneration: nodePool.Generation,
+		})
+		return false, nil
+	}
+
+	vmis := &kubevirtv1.VirtualMachineInstanceList{}
+	if err := r.List(ctx, vmis, client.InNamespace(controlPlaneNamespace), client.MatchingLabels{kubevirtNodePoolNameLabelKey: nodePool.Name}); err != nil {
+		if meta.IsNoMatchError(err) {
+			// KubeVirt is not installed on the management cluster
+			removeStatusCondition(&nodePool.Status.Conditions, hyperv1.NodePoolKubevirtLiveMigratableConditionType)
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to list VirtualMachineInstances: %w", err)
+	}
+
+	condition, migrating := kubevirtLiveMigrationCondition(nodePool, vmis.Items)
+	setStatusCondition(&nodePool.Status.Conditions, condition)
+	if condition.Status == corev1.ConditionTrue {
+		return migrating, nil
+	}
+
+	for i := range vmis.Items {
+		vmi := &vmis.Items[i]
+		if vmi.IsMigratable() || vmi.Status.NodeName == "" {
+			continue
+		}
+		node := &corev1.Node{}
+		if err := r.Get(ctx, client.ObjectKey{Name: vmi.Status.NodeName}, node); err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			return false, fmt.Errorf("failed to get infra node %s: %w", vmi.Status.NodeName, err)
+		}
+		if !node.Spec.Unschedulable {
+			continue
+		}
+		machine, err := kubevirtMachineForVM(ctx, r.Client, controlPlaneNamespace, vmi.Name)
+		if err != nil {
+			return false, err
+		}
+		if machine == nil || !machine.DeletionTimestamp.IsZero() {
+			continue
+		}
+		log.Info("Replacing VM that cannot be live migrated off its drained infra node", "vm", vmi.Name, "node", node.Name, "machine", machine.Name)
+		if err := r.Delete(ctx, machine); err != nil && !apierrors.IsNotFound(err) {
+			return false, fmt.Errorf("failed to delete machine %s: %w", machine.Name, err)
+		}
+	}
+	return true, nil
+}
+
+// kubevirtMachineForVM returns the Machine of the VM, which is named after its
+// KubevirtMachine.
+func kubevirtMachineForVM(ctx context.Context, c client.Client, namespace, vmName string) (*capiv1.Machine, error) {
+	machines := &capiv1.MachineList{}
+	if err := c.List(ctx, machines, client.InNamespace(namespace)); err != nil {
+		return nil, fmt.Errorf("failed to list machines: %w", err)
+	}
+	for i, machine := range machines.Items {
+		if machine.Spec.InfrastructureRef.Kind == "KubevirtMachine" && machine.Spec.InfrastructureRef.Name == vmName {
+			return &machines.Items[i], nil
+		}
+	}
+	return nil, nil
+}
@@ -1,6 +1,7 @@
 package nodepool
 
 import (
+	"context"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
@@ -10,9 +11,13 @@ import (
 	"k8s.io/apimachinery/pkg/api/equality"
 	apiresource "k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	kubevirtv1 "kubevirt.io/api/core/v1"
 	"kubevirt.io/containerized-data-importer-api/pkg/apis/core/v1beta1"
 	capikubevirt "sigs.k8s.io/cluster-api-provider-kubevirt/api/v1alpha1"
+	capiv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 )
 
 func TestKubevirtMachineTemplate(t *testing.T) {
@@ -93,6 +98,7 @@ func generateKubevirtPlatform(memory string, cores uint32, image string, volumeS
 
 func generateNodeTemplate(memory string, cpu uint32, image string, volumeSize string) *capikubevirt.VirtualMachineTemplateSpec {
 	runAlways := kubevirtv1.RunStrategyAlways
+	liveMigrate := kubevirtv1.EvictionStrategyLiveMigrate
 	guestQuantity := apiresource.MustParse(memory)
 	volumeSizeQuantity := apiresource.MustParse(volumeSize)
 	nodePoolNameLabelKey := "hypershift.kubevirt.io/node-pool-name"
@@ -136,7 +142,7 @@ func generateNodeTemplate(memory string, cpu uint32, image string, volumeSize st
 					},
 				},
 				Spec: kubevirtv1.VirtualMachineInstanceSpec{
-
+					EvictionStrategy: &liveMigrate,
 					Affinity: &corev1.Affinity{
 						PodAntiAffinity: &corev1.PodAntiAffinity{
 							PreferredDuringSchedulingIgnoredDuringExecution: []corev1.WeightedPodAffinityTerm{
@@ -207,3 +213,284 @@ func generateNodeTemplate(memory string, cpu uint32, image string, volumeSize st
 		},
 	}
 }
+
+func TestKubevirtAdditionalNetworks(t *testing.T) {
+	testCases := []struct {
+		name               string
+		networks           []hyperv1.KubevirtNetwork
+		expectedInterfaces []kubevirtv1.Interface
+		expectedNetworks   []kubevirtv1.Network
+		expectError        bool
+	}{
+		{
+			name: "bridge and SR-IOV networks are attached after the pod network",
+			networks: []hyperv1.KubevirtNetwork{
+				{Name: "vlan100"},
+				{Name: "infra/sriov-net", Binding: hyperv1.KubevirtNetworkBindingSRIOV},
+			},
+			expectedInterfaces: []kubevirtv1.Interface{
+				{Name: "default", InterfaceBindingMethod: kubevirtv1.InterfaceBindingMethod{Bridge: &kubevirtv1.InterfaceBridge{}}},
+				{Name: "iface1", InterfaceBindingMethod: kubevirtv1.InterfaceBindingMethod{Bridge: &kubevirtv1.InterfaceBridge{}}},
+				{Name: "iface2", InterfaceBindingMethod: kubevirtv1.InterfaceBindingMethod{SRIOV: &kubevirtv1.InterfaceSRIOV{}}},
+			},
+			expectedNetworks: []kubevirtv1.Network{
+				{Name: "default", NetworkSource: kubevirtv1.NetworkSource{Pod: &kubevirtv1.PodNetwork{}}},
+				{Name: "iface1", NetworkSource: kubevirtv1.NetworkSource{Multus: &kubevirtv1.MultusNetwork{NetworkName: "vlan100"}}},
+				{Name: "iface2", NetworkSource: kubevirtv1.NetworkSource{Multus: &kubevirtv1.MultusNetwork{NetworkName: "infra/sriov-net"}}},
+			},
+		},
+		{
+			name:        "name with too many segments",
+			networks:    []hyperv1.KubevirtNetwork{{Name: "a/b/c"}},
+			expectError: true,
+		},
+		{
+			name:        "invalid name",
+			networks:    []hyperv1.KubevirtNetwork{{Name: "VLAN_100"}},
+			expectError: true,
+		},
+		{
+			name:        "duplicate network",
+			networks:    []hyperv1.KubevirtNetwork{{Name: "vlan100"}, {Name: "vlan100", Binding: hyperv1.KubevirtNetworkBindingSRIOV}},
+			expectError: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+			nodePool := &hyperv1.NodePool{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "my-pool",
+				},
+				Spec: hyperv1.NodePoolSpec{
+					Platform: hyperv1.NodePoolPlatform{
+						Type:     hyperv1.KubevirtPlatform,
+						Kubevirt: generateKubevirtPlatform("5Gi", 4, "testimage", "32Gi"),
+					},
+				},
+			}
+			nodePool.Spec.Platform.Kubevirt.AdditionalNetworks = tc.networks
+
+			err := kubevirtPlatformValidation(nodePool)
+			if tc.expectError {
+				g.Expect(err).To(HaveOccurred())
+				return
+			}
+			g.Expect(err).ToNot(HaveOccurred())
+
+			result := kubevirtMachineTemplateSpec("", nodePool)
+			vmiSpec := result.Template.Spec.VirtualMachineTemplate.Spec.Template.Spec
+			g.Expect(vmiSpec.Domain.Devices.Interfaces).To(Equal(tc.expectedInterfaces))
+			g.Expect(vmiSpec.Networks).To(Equal(tc.expectedNetworks))
+		})
+	}
+}
+
+func TestKubevirtHostDevices(t *testing.T) {
+	testCases := []struct {
+		name                string
+		devices             []hyperv1.KubevirtHostDevice
+		expectedGPUs        []kubevirtv1.GPU
+		expectedHostDevices []kubevirtv1.HostDevice
+		expectError         bool
+	}{
+		{
+			name: "GPUs and host devices are passed through",
+			devices: []hyperv1.KubevirtHostDevice{
+				{DeviceName: "nvidia.com/GA102GL_A10", Type: hyperv1.KubevirtHostDeviceTypeGPU, Count: 2},
+				{DeviceName: "nvidia.com/NVIDIA_A10-4Q"},
+				{DeviceName: "intel.com/qat", Type: hyperv1.KubevirtHostDeviceTypeHostDevice},
+			},
+			expectedGPUs: []kubevirtv1.GPU{
+				{Name: "gpu1", DeviceName: "nvidia.com/GA102GL_A10"},
+				{Name: "gpu2", DeviceName: "nvidia.com/GA102GL_A10"},
+				{Name: "gpu3", DeviceName: "nvidia.com/NVIDIA_A10-4Q"},
+			},
+			expectedHostDevices: []kubevirtv1.HostDevice{
+				{Name: "hostdevice1", DeviceName: "intel.com/qat"},
+			},
+		},
+		{
+			name: "no devices",
+		},
+		{
+			name:        "invalid device name",
+			devices:     []hyperv1.KubevirtHostDevice{{DeviceName: "nvidia.com/a/b"}},
+			expectError: true,
+		},
+		{
+			name:        "negative count",
+			devices:     []hyperv1.KubevirtHostDevice{{DeviceName: "nvidia.com/GA102GL_A10", Count: -1}},
+			expectError: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+			nodePool := &hyperv1.NodePool{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "my-pool",
+				},
+				Spec: hyperv1.NodePoolSpec{
+					Platform: hyperv1.NodePoolPlatform{
+						Type:     hyperv1.KubevirtPlatform,
+						Kubevirt: generateKubevirtPlatform("5Gi", 4, "testimage", "32Gi"),
+					},
+				},
+			}
+			nodePool.Spec.Platform.Kubevirt.HostDevices = tc.devices
+
+			err := kubevirtPlatformValidation(nodePool)
+			if tc.expectError {
+				g.Expect(err).To(HaveOccurred())
+				return
+			}
+			g.Expect(err).ToNot(HaveOccurred())
+
+			result := kubevirtMachineTemplateSpec("", nodePool)
+			devices := result.Template.Spec.VirtualMachineTemplate.Spec.Template.Spec.Domain.Devices
+			g.Expect(devices.GPUs).To(Equal(tc.expectedGPUs))
+			g.Expect(devices.HostDevices).To(Equal(tc.expectedHostDevices))
+		})
+	}
+}
+
+func TestKubevirtLiveMigrationCondition(t *testing.T) {
+	notMigratable := func(name, message string) kubevirtv1.VirtualMachineInstance {
+		return kubevirtv1.VirtualMachineInstance{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Status: kubevirtv1.VirtualMachineInstanceStatus{
+				Conditions: []kubevirtv1.VirtualMachineInstanceCondition{
+					{Type: kubevirtv1.VirtualMachineInstanceIsMigratable, Status: corev1.ConditionFalse, Message: message},
+				},
+			},
+		}
+	}
+	migrating := kubevirtv1.VirtualMachineInstance{
+		ObjectMeta: metav1.ObjectMeta{Name: "migrating"},
+		Status: kubevirtv1.VirtualMachineInstanceStatus{
+			MigrationState: &kubevirtv1.VirtualMachineInstanceMigrationState{SourceNode: "infra-0"},
+		},
+	}
+
+	testCases := []struct {
+		name              string
+		vmis              []kubevirtv1.VirtualMachineInstance
+		expectedStatus    corev1.ConditionStatus
+		expectedReason    string
+		expectedMessage   string
+		expectedMigrating bool
+	}{
+		{
+			name:           "no VMs",
+			expectedStatus: corev1.ConditionTrue,
+			expectedReason: hyperv1.NodePoolAsExpectedConditionReason,
+		},
+		{
+			name:              "a VM is migrating",
+			vmis:              []kubevirtv1.VirtualMachineInstance{migrating},
+			expectedStatus:    corev1.ConditionTrue,
+			expectedReason:    hyperv1.NodePoolAsExpectedConditionReason,
+			expectedMessage:   "1 VMs are being live migrated",
+			expectedMigrating: true,
+		},
+		{
+			name: "VMs are not migratable",
+			vmis: []kubevirtv1.VirtualMachineInstance{
+				notMigratable("vm-b", "PVC rhcos is not shared"),
+				notMigratable("vm-a", "cannot migrate VMI with host devices"),
+				migrating,
+			},
+			expectedStatus:    corev1.ConditionFalse,
+			expectedReason:    hyperv1.NodePoolNotLiveMigratableConditionReason,
+			expectedMessage:   "VMs that are replaced rather than live migrated when their infra nodes are drained: vm-a: cannot migrate VMI with host devices; vm-b: PVC rhcos is not shared. 1 VMs are being live migrated",
+			expectedMigrating: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+			nodePool := &hyperv1.NodePool{ObjectMeta: metav1.ObjectMeta{Name: "my-pool", Generation: 2}}
+
+			condition, isMigrating := kubevirtLiveMigrationCondition(nodePool, tc.vmis)
+			g.Expect(condition.Type).To(Equal(hyperv1.NodePoolKubevirtLiveMigratableConditionType))
+			g.Expect(condition.Status).To(Equal(tc.expectedStatus))
+			g.Expect(condition.Reason).To(Equal(tc.expectedReason))
+			g.Expect(condition.Message).To(Equal(tc.expectedMessage))
+			g.Expect(condition.ObservedGeneration).To(Equal(int64(2)))
+			g.Expect(isMigrating).To(Equal(tc.expectedMigrating))
+		})
+	}
+}
+
+func TestKubevirtMachineForVM(t *testing.T) {
+	g := NewWithT(t)
+	scheme := runtime.NewScheme()
+	_ = capiv1.AddToScheme(scheme)
+	machines := []client.Object{
+		&capiv1.Machine{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "hcp", Name: "other"},
+			Spec: capiv1.MachineSpec{
+				InfrastructureRef: corev1.ObjectReference{Kind: "AWSMachine", Name: "vm"},
+			},
+		},
+		&capiv1.Machine{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "hcp", Name: "machine"},
+			Spec: capiv1.MachineSpec{
+				InfrastructureRef: corev1.ObjectReference{Kind: "KubevirtMachine", Name: "vm"},
+			},
+		},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(machines...).Build()
+
+	machine, err := kubevirtMachineForVM(context.Background(), c, "hcp", "vm")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(machine).ToNot(BeNil())
+	g.Expect(machine.Name).To(Equal("machine"))
+
+	machine, err = kubevirtMachineForVM(context.Background(), c, "hcp", "missing")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(machine).To(BeNil())
+}
+
+func TestReconcileKubevirtLiveMigrationExternalInfraCluster(t *testing.T) {
+	g := NewWithT(t)
+	r := NodePoolReconciler{Client: fake.NewClientBuilder().Build()}
+	nodePool := &hyperv1.NodePool{ObjectMeta: metav1.ObjectMeta{Namespace: "clusters", Name: "pool", Generation: 2}}
+	hcluster := &hyperv1.HostedCluster{
+		Spec: hyperv1.HostedClusterSpec{
+			Platform: hyperv1.PlatformSpec{
+				Type: hyperv1.KubevirtPlatform,
+				Kubevirt: &hyperv1.KubevirtPlatformSpec{
+					Credentials: &hyperv1.KubevirtPlatformCredentials{
+						InfraKubeConfigSecret: &hyperv1.KubeconfigSecretRef{Name: "infra-kubeconfig", Key: "kubeconfig"},
+						InfraNamespace:        "guest",
+					},
+				},
+			},
+		},
+	}
+
+	checkAgain, err := r.reconcileKubevirtLiveMigration(context.Background(), nodePool, hcluster, "hcp")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(checkAgain).To(BeFalse())
+	condition := findStatusCondition(nodePool.Status.Conditions, hyperv1.NodePoolKubevirtLiveMigratableConditionType)
+	g.Expect(condition).ToNot(BeNil())
+	g.Expect(condition.Status).To(Equal(corev1.ConditionUnknown))
+	g.Expect(condition.Reason).To(Equal(hyperv1.NodePoolExternalInfraClusterConditionReason))
+}
+
+func TestKubevirtNodeCapacity(t *testing.T) {
+	g := NewWithT(t)
+	kvPlatform := generateKubevirtPlatform("16Gi", 4, "testimage", "32Gi")
+	kvPlatform.HostDevices = []hyperv1.KubevirtHostDevice{
+		{DeviceName: "nvidia.com/GA102GL_A10", Type: hyperv1.KubevirtHostDeviceTypeGPU, Count: 2},
+		{DeviceName: "intel.com/qat", Type: hyperv1.KubevirtHostDeviceTypeHostDevice},
+	}
+
+	capacity := kubevirtNodeCapacity(kvPlatform)
+	g.Expect(capacity.CPU.String()).To(Equal("4"))
+	g.Expect(capacity.Memory.String()).To(Equal("16Gi"))
+	g.Expect(capacity.GPU).To(Equal(int32(2)))
+	g.Expect(capacity.Architecture).To(Equal("amd64"))
+}
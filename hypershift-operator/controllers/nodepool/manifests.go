@@ -23,6 +23,16 @@ func machineDeployment(nodePool *hyperv1.NodePool, controlPlaneNamespace string)
 	}
 }
 
+// subnetResourceName returns the name of the MachineDeployment and machine template of the
+// subnet at the given index of a NodePool spread across subnets. The first subnet uses the
+// NodePool name, so that a NodePool keeps its resources when it is spread across more subnets.
+func subnetResourceName(nodePool *hyperv1.NodePool, index int) string {
+	if index == 0 {
+		return nodePool.GetName()
+	}
+	return getName(nodePool.GetName(), fmt.Sprintf("subnet-%d", index), 63)
+}
+
 func machineSet(nodePool *hyperv1.NodePool, controlPlaneNamespace string) *capiv1.MachineSet {
 	return &capiv1.MachineSet{
 		ObjectMeta: metav1.ObjectMeta{
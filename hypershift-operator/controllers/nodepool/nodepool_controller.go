@@ -8,6 +8,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"hash/fnv"
+	"io"
 	"sort"
 	"strconv"
 	"strings"
@@ -39,6 +40,8 @@ import (
 	serializer "k8s.io/apimachinery/pkg/runtime/serializer/json"
 	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation"
 	"k8s.io/client-go/tools/record"
 	"k8s.io/client-go/util/workqueue"
 	k8sutilspointer "k8s.io/utils/pointer"
@@ -63,12 +66,21 @@ const (
 	finalizer                                = "hypershift.openshift.io/finalizer"
 	autoscalerMaxAnnotation                  = "cluster.x-k8s.io/cluster-api-autoscaler-node-group-max-size"
 	autoscalerMinAnnotation                  = "cluster.x-k8s.io/cluster-api-autoscaler-node-group-min-size"
+	autoscalerCapacityCPUAnnotation          = "capacity.cluster-autoscaler.kubernetes.io/cpu"
+	autoscalerCapacityMemoryAnnotation       = "capacity.cluster-autoscaler.kubernetes.io/memory"
+	autoscalerCapacityGPUCountAnnotation     = "capacity.cluster-autoscaler.kubernetes.io/gpu-count"
+	autoscalerCapacityGPUTypeAnnotation      = "capacity.cluster-autoscaler.kubernetes.io/gpu-type"
+	autoscalerCapacityLabelsAnnotation       = "capacity.cluster-autoscaler.kubernetes.io/labels"
 	nodePoolAnnotation                       = "hypershift.openshift.io/nodePool"
 	nodePoolAnnotationCurrentConfig          = "hypershift.openshift.io/nodePoolCurrentConfig"
 	nodePoolAnnotationCurrentConfigVersion   = "hypershift.openshift.io/nodePoolCurrentConfigVersion"
 	nodePoolAnnotationTargetConfigVersion    = "hypershift.openshift.io/nodePoolTargetConfigVersion"
 	nodePoolAnnotationUpgradeInProgressTrue  = "hypershift.openshift.io/nodePoolUpgradeInProgressTrue"
 	nodePoolAnnotationUpgradeInProgressFalse = "hypershift.openshift.io/nodePoolUpgradeInProgressFalse"
+	nodePoolAnnotationMaxUnavailable         = "hypershift.openshift.io/nodePoolUpgradeMaxUnavailable"
+	nodePoolAnnotationNodeLabels             = "hypershift.openshift.io/nodePoolNodeLabels"
+	nodePoolAnnotationTaints                 = "hypershift.openshift.io/nodePoolTaints"
+	nodePoolAnnotationSpotInterruptions      = "hypershift.openshift.io/nodePoolSpotInterruptions"
 
 	nodePoolAnnotationPlatformMachineTemplate = "hypershift.openshift.io/nodePoolPlatformMachineTemplate"
 	nodePoolCoreIgnitionConfigLabel           = "hypershift.openshift.io/core-ignition-config"
@@ -99,8 +111,9 @@ func (r *NodePoolReconciler) SetupWithManager(mgr ctrl.Manager) error {
 		Watches(&source.Kind{Type: &capiaws.AWSMachineTemplate{}}, handler.EnqueueRequestsFromMapFunc(enqueueParentNodePool)).
 		Watches(&source.Kind{Type: &agentv1.AgentMachineTemplate{}}, handler.EnqueueRequestsFromMapFunc(enqueueParentNodePool)).
 		Watches(&source.Kind{Type: &capiazure.AzureMachineTemplate{}}, handler.EnqueueRequestsFromMapFunc(enqueueParentNodePool)).
-		// We want to reconcile when the user data Secret or the token Secret is unexpectedly changed out of band.
-		Watches(&source.Kind{Type: &corev1.Secret{}}, handler.EnqueueRequestsFromMapFunc(enqueueParentNodePool)).
+		// We want to reconcile when the user data Secret or the token Secret is unexpectedly changed out of band,
+		// and when the Secrets referenced by the spec.pullSecret change.
+		Watches(&source.Kind{Type: &corev1.Secret{}}, handler.EnqueueRequestsFromMapFunc(r.enqueueNodePoolsForSecret)).
 		// We want to reconcile when the ConfigMaps referenced by the spec.config and also the core ones change.
 		Watches(&source.Kind{Type: &corev1.ConfigMap{}}, handler.EnqueueRequestsFromMapFunc(r.enqueueNodePoolsForConfig)).
 		WithOptions(controller.Options{
@@ -188,7 +201,7 @@ func (r *NodePoolReconciler) Reconcile(ctx context.Context, req ctrl.Request) (c
 	}
 
 	log.Info("Successfully reconciled")
-	return ctrl.Result{}, nil
+	return result, nil
 }
 
 func (r *NodePoolReconciler) reconcile(ctx context.Context, hcluster *hyperv1.HostedCluster, nodePool *hyperv1.NodePool) (ctrl.Result, error) {
@@ -277,6 +290,27 @@ func (r *NodePoolReconciler) reconcile(ctx context.Context, hcluster *hyperv1.Ho
 		ObservedGeneration: nodePool.Generation,
 	})
 
+	// Validate node labels and taints input.
+	if err := validateNodeLabelsAndTaints(nodePool); err != nil {
+		setStatusCondition(&nodePool.Status.Conditions, hyperv1.NodePoolCondition{
+			Type:               hyperv1.NodePoolValidNodeLabelsAndTaintsConditionType,
+			Status:             corev1.ConditionFalse,
+			Message:            err.Error(),
+			Reason:             hyperv1.NodePoolValidationFailedConditionReason,
+			ObservedGeneration: nodePool.Generation,
+		})
+		// We don't return the error here as reconciling won't solve the input problem.
+		// An update event will trigger reconciliation.
+		log.Error(err, "validating node labels and taints failed")
+		return reconcile.Result{}, nil
+	}
+	setStatusCondition(&nodePool.Status.Conditions, hyperv1.NodePoolCondition{
+		Type:               hyperv1.NodePoolValidNodeLabelsAndTaintsConditionType,
+		Status:             corev1.ConditionTrue,
+		Reason:             hyperv1.NodePoolAsExpectedConditionReason,
+		ObservedGeneration: nodePool.Generation,
+	})
+
 	// Validate IgnitionEndpoint.
 	if ignEndpoint == "" {
 		setStatusCondition(&nodePool.Status.Conditions, hyperv1.NodePoolCondition{
@@ -350,6 +384,17 @@ func (r *NodePoolReconciler) reconcile(ctx context.Context, hcluster *hyperv1.Ho
 		if hcluster.Spec.Platform.AWS == nil {
 			return ctrl.Result{}, fmt.Errorf("the HostedCluster for this NodePool has no .Spec.Platform.AWS, this is unsupported")
 		}
+		if err := awsPlatformValidation(nodePool); err != nil {
+			setStatusCondition(&nodePool.Status.Conditions, hyperv1.NodePoolCondition{
+				Type:               hyperv1.NodePoolValidAWSConfigConditionType,
+				Status:             corev1.ConditionFalse,
+				Reason:             hyperv1.NodePoolValidationFailedConditionReason,
+				Message:            fmt.Sprintf("validation of NodePool AWS platform failed: %s", err.Error()),
+				ObservedGeneration: nodePool.Generation,
+			})
+			return ctrl.Result{}, fmt.Errorf("validation of NodePool AWS platform failed: %w", err)
+		}
+		removeStatusCondition(&nodePool.Status.Conditions, hyperv1.NodePoolValidAWSConfigConditionType)
 		if nodePool.Spec.Platform.AWS.AMI != "" {
 			ami = nodePool.Spec.Platform.AWS.AMI
 			// User-defined AMIs cannot be validated
@@ -382,6 +427,18 @@ func (r *NodePoolReconciler) reconcile(ctx context.Context, hcluster *hyperv1.Ho
 	var powervsImageRegion string
 	var powervsBootImage string
 	if nodePool.Spec.Platform.Type == hyperv1.PowerVSPlatform {
+		if err := powerVSPlatformValidation(nodePool); err != nil {
+			setStatusCondition(&nodePool.Status.Conditions, hyperv1.NodePoolCondition{
+				Type:               hyperv1.NodePoolValidPowerVSConfigConditionType,
+				Status:             corev1.ConditionFalse,
+				Reason:             hyperv1.NodePoolValidationFailedConditionReason,
+				Message:            fmt.Sprintf("validation of NodePool PowerVS platform failed: %s", err.Error()),
+				ObservedGeneration: nodePool.Generation,
+			})
+			return ctrl.Result{}, fmt.Errorf("validation of NodePool PowerVS platform failed: %w", err)
+		}
+		removeStatusCondition(&nodePool.Status.Conditions, hyperv1.NodePoolValidPowerVSConfigConditionType)
+
 		coreOSPowerVSImage, powervsImageRegion, err = getPowerVSImage(hcluster.Spec.Platform.PowerVS.Region, releaseImage)
 		if err != nil {
 			setStatusCondition(&nodePool.Status.Conditions, hyperv1.NodePoolCondition{
@@ -403,8 +460,27 @@ func (r *NodePoolReconciler) reconcile(ctx context.Context, hcluster *hyperv1.Ho
 		})
 	}
 
+	// Validate Azure platform specific input.
+	if nodePool.Spec.Platform.Type == hyperv1.AzurePlatform {
+		if hcluster.Spec.Platform.Azure == nil {
+			return ctrl.Result{}, fmt.Errorf("the HostedCluster for this NodePool has no .Spec.Platform.Azure, this is unsupported")
+		}
+		if err := azurePlatformValidation(hcluster, nodePool); err != nil {
+			setStatusCondition(&nodePool.Status.Conditions, hyperv1.NodePoolCondition{
+				Type:               hyperv1.NodePoolValidAzureConfigConditionType,
+				Status:             corev1.ConditionFalse,
+				Reason:             hyperv1.NodePoolValidationFailedConditionReason,
+				Message:            fmt.Sprintf("validation of NodePool Azure platform failed: %s", err.Error()),
+				ObservedGeneration: nodePool.Generation,
+			})
+			return ctrl.Result{}, fmt.Errorf("validation of NodePool Azure platform failed: %w", err)
+		}
+		removeStatusCondition(&nodePool.Status.Conditions, hyperv1.NodePoolValidAzureConfigConditionType)
+	}
+
 	// Validate KubeVirt platform specific format
 	var kubevirtBootImage string
+	var requeueAfter time.Duration
 	if nodePool.Spec.Platform.Type == hyperv1.KubevirtPlatform {
 		if err := kubevirtPlatformValidation(nodePool); err != nil {
 			setStatusCondition(&nodePool.Status.Conditions, hyperv1.NodePoolCondition{
@@ -436,6 +512,14 @@ func (r *NodePoolReconciler) reconcile(ctx context.Context, hcluster *hyperv1.Ho
 			Message:            fmt.Sprintf("Bootstrap KubeVirt Image is %q", kubevirtBootImage),
 			ObservedGeneration: nodePool.Generation,
 		})
+
+		checkLiveMigration, err := r.reconcileKubevirtLiveMigration(ctx, nodePool, hcluster, controlPlaneNamespace)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		if checkLiveMigration {
+			requeueAfter = kubevirtLiveMigrationRequeueInterval
+		}
 	}
 
 	// Validate config input.
@@ -476,6 +560,25 @@ func (r *NodePoolReconciler) reconcile(ctx context.Context, hcluster *hyperv1.Ho
 		ObservedGeneration: nodePool.Generation,
 	})
 
+	// Check for config drift. With a Manual ConfigUpdatePolicy, keep rolling out the current config
+	// until the drifted one is approved.
+	latestConfig := config
+	latestConfigHash := hashStruct(latestConfig)
+	configUpdatePendingApproval := isConfigUpdatePendingApproval(nodePool, latestConfigHash)
+	if configUpdatePendingApproval {
+		config, err = r.getCurrentConfig(ctx, nodePool, controlPlaneNamespace)
+		if err != nil {
+			return ctrl.Result{}, fmt.Errorf("failed to get current config: %w", err)
+		}
+		log.Info("NodePool config update is pending approval",
+			"current", nodePool.GetAnnotations()[nodePoolAnnotationCurrentConfig],
+			"target", latestConfigHash)
+	}
+	nodePool.Status.Config = &hyperv1.NodePoolConfigStatus{
+		CurrentHash: nodePool.GetAnnotations()[nodePoolAnnotationCurrentConfig],
+		TargetHash:  latestConfigHash,
+	}
+
 	// Check if config needs to be updated.
 	targetConfigHash := hashStruct(config)
 	isUpdatingConfig := isUpdatingConfig(nodePool, targetConfigHash)
@@ -582,6 +685,7 @@ func (r *NodePoolReconciler) reconcile(ctx context.Context, hcluster *hyperv1.Ho
 			nodePool.Annotations[nodePoolAnnotationCurrentConfig] = targetConfigHash
 		}
 		nodePool.Annotations[nodePoolAnnotationCurrentConfigVersion] = targetConfigVersionHash
+		setNodesUpToDateCondition(nodePool, nil, latestConfigHash, configUpdatePendingApproval)
 		return ctrl.Result{}, nil
 	}
 
@@ -599,6 +703,16 @@ func (r *NodePoolReconciler) reconcile(ctx context.Context, hcluster *hyperv1.Ho
 		}
 	}
 
+	if nodePool.Spec.Platform.Type == hyperv1.AWSPlatform {
+		creatingInstances, err := r.reconcileAWSInstanceTypeFallback(ctx, nodePool, controlPlaneNamespace, generateName(infraID, nodePool.Spec.ClusterName, nodePool.GetName()))
+		if err != nil {
+			return ctrl.Result{}, fmt.Errorf("failed to reconcile instance type fallback: %w", err)
+		}
+		if creatingInstances {
+			requeueAfter = awsInstanceTypeFallbackRequeueInterval
+		}
+	}
+
 	// Reconcile (Platform)MachineTemplate.
 	template, mutateTemplate, machineTemplateSpecJSON, err := machineTemplateBuilders(hcluster, nodePool, infraID, ami, powervsBootImage, kubevirtBootImage)
 	if err != nil {
@@ -612,6 +726,7 @@ func (r *NodePoolReconciler) reconcile(ctx context.Context, hcluster *hyperv1.Ho
 		log.Info("Reconciled Machine template", "result", result)
 	}
 
+	inPlaceRolloutComplete := true
 	if nodePool.Spec.Management.UpgradeType == hyperv1.UpgradeTypeInPlace {
 		ms := machineSet(nodePool, controlPlaneNamespace)
 		if result, err := controllerutil.CreateOrPatch(ctx, r.Client, ms, func() error {
@@ -628,23 +743,80 @@ func (r *NodePoolReconciler) reconcile(ctx context.Context, hcluster *hyperv1.Ho
 		} else {
 			log.Info("Reconciled MachineSet", "result", result)
 		}
+		inPlaceRolloutComplete = machineSetInPlaceRolloutIsComplete(ms)
 	}
 
 	if nodePool.Spec.Management.UpgradeType == hyperv1.UpgradeTypeReplace {
-		md := machineDeployment(nodePool, controlPlaneNamespace)
-		if result, err := controllerutil.CreateOrPatch(ctx, r.Client, md, func() error {
-			return r.reconcileMachineDeployment(
-				log,
-				md, nodePool,
-				userDataSecret,
-				template,
-				infraID,
-				targetVersion, targetConfigHash, targetConfigVersionHash, machineTemplateSpecJSON)
-		}); err != nil {
-			return ctrl.Result{}, fmt.Errorf("failed to reconcile MachineDeployment %q: %w",
-				client.ObjectKeyFromObject(md).String(), err)
-		} else {
-			log.Info("Reconciled MachineDeployment", "result", result)
+		// A NodePool spread across subnets has a MachineDeployment and a machine template per subnet.
+		templates := []client.Object{template}
+		machineTemplateSpecJSONs := []string{machineTemplateSpecJSON}
+		if nodePool.Spec.Platform.Type == hyperv1.AWSPlatform {
+			templates, machineTemplateSpecJSONs, err = r.reconcileAWSSubnetMachineTemplates(ctx, nodePool, template.(*capiaws.AWSMachineTemplate), machineTemplateSpecJSON)
+			if err != nil {
+				return ctrl.Result{}, err
+			}
+		}
+
+		var mds []*capiv1.MachineDeployment
+		propagatingUserData := false
+		for i := range templates {
+			md := machineDeployment(nodePool, controlPlaneNamespace)
+			md.Name = subnetResourceName(nodePool, i)
+			if result, err := controllerutil.CreateOrPatch(ctx, r.Client, md, func() error {
+				if userDataSecret.Name != k8sutilspointer.StringPtrDerefOr(md.Spec.Template.Spec.Bootstrap.DataSecretName, "") {
+					propagatingUserData = true
+				}
+				return r.reconcileMachineDeployment(
+					log,
+					md, nodePool,
+					userDataSecret,
+					templates[i],
+					infraID,
+					targetVersion, targetConfigHash, targetConfigVersionHash, machineTemplateSpecJSONs[i],
+					i, len(templates))
+			}); err != nil {
+				return ctrl.Result{}, fmt.Errorf("failed to reconcile MachineDeployment %q: %w",
+					client.ObjectKeyFromObject(md).String(), err)
+			} else {
+				log.Info("Reconciled MachineDeployment", "name", md.Name, "result", result)
+			}
+			mds = append(mds, md)
+		}
+		if err := r.deleteStaleMachineDeployments(ctx, nodePool, controlPlaneNamespace, mds); err != nil {
+			return ctrl.Result{}, err
+		}
+
+		// The MachineDeployments that got a new user data Secret have not observed it yet,
+		// so their status is reconciled in the next reconciling loop.
+		if !propagatingUserData {
+			setMachineDeploymentsStatus(log, nodePool, mds, targetVersion, targetConfigHash, targetConfigVersionHash)
+		}
+	}
+
+	// Report the nodes that don't run the latest config and version.
+	// In-place upgrades don't change the Machines, so all of them are out of date until the MachineSet is updated.
+	machines := &capiv1.MachineList{}
+	resourcesName := generateName(infraID, nodePool.Spec.ClusterName, nodePool.GetName())
+	if err := r.List(ctx, machines, client.InNamespace(controlPlaneNamespace), client.MatchingLabels{resourcesName: resourcesName}); err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to list machines: %w", err)
+	}
+	latestUserDataSecret := IgnitionUserDataSecret(controlPlaneNamespace, nodePool.GetName(), hashStruct(latestConfig+targetVersion))
+	allMachinesOutOfDate := nodePool.Spec.Management.UpgradeType == hyperv1.UpgradeTypeInPlace &&
+		(configUpdatePendingApproval || !inPlaceRolloutComplete)
+	setNodesUpToDateCondition(nodePool, outOfDateNodes(machines.Items, latestUserDataSecret.Name, allMachinesOutOfDate),
+		latestConfigHash, configUpdatePendingApproval)
+	if nodePool.Spec.Platform.Type == hyperv1.AWSPlatform {
+		nodePool.Status.Zones = awsMachineZones(machines.Items)
+	}
+	setHibernatingCondition(nodePool, len(machines.Items))
+
+	if nodePool.Spec.Platform.Type == hyperv1.AgentPlatform {
+		checkBareMetalHosts, err := r.reconcileAgentBareMetalHosts(ctx, hcluster, nodePool, infraID, controlPlaneNamespace)
+		if err != nil {
+			return ctrl.Result{}, fmt.Errorf("failed to reconcile BareMetalHosts: %w", err)
+		}
+		if checkBareMetalHosts {
+			requeueAfter = agentBareMetalHostRequeueInterval
 		}
 	}
 
@@ -681,7 +853,7 @@ func (r *NodePoolReconciler) reconcile(ctx context.Context, hcluster *hyperv1.Ho
 			ObservedGeneration: nodePool.Generation,
 		})
 	}
-	return ctrl.Result{}, nil
+	return ctrl.Result{RequeueAfter: requeueAfter}, nil
 }
 
 func deleteMachineDeployment(ctx context.Context, c client.Client, md *capiv1.MachineDeployment) error {
@@ -705,6 +877,47 @@ func deleteMachineDeployment(ctx context.Context, c client.Client, md *capiv1.Ma
 	return nil
 }
 
+// deleteStaleMachineDeployments deletes the MachineDeployments of the NodePool other than the given ones,
+// along with their machine templates, e.g. after the NodePool is spread across fewer subnets.
+func (r *NodePoolReconciler) deleteStaleMachineDeployments(ctx context.Context, nodePool *hyperv1.NodePool, controlPlaneNamespace string, mds []*capiv1.MachineDeployment) error {
+	keep := sets.NewString()
+	for _, md := range mds {
+		keep.Insert(md.Name)
+	}
+	mdList := &capiv1.MachineDeploymentList{}
+	if err := r.List(ctx, mdList, client.InNamespace(controlPlaneNamespace)); err != nil {
+		return fmt.Errorf("failed to list MachineDeployments: %w", err)
+	}
+	stale := sets.NewString()
+	for i := range mdList.Items {
+		md := &mdList.Items[i]
+		if md.Annotations[nodePoolAnnotation] != client.ObjectKeyFromObject(nodePool).String() || keep.Has(md.Name) {
+			continue
+		}
+		if err := deleteMachineDeployment(ctx, r.Client, md); err != nil {
+			return fmt.Errorf("failed to delete MachineDeployment %q: %w", client.ObjectKeyFromObject(md).String(), err)
+		}
+		stale.Insert(md.Name)
+	}
+	if stale.Len() == 0 {
+		return nil
+	}
+
+	machineTemplates, err := r.listMachineTemplates(nodePool)
+	if err != nil {
+		return fmt.Errorf("failed to list MachineTemplates: %w", err)
+	}
+	for _, machineTemplate := range machineTemplates {
+		if !stale.Has(machineTemplate.GetName()) {
+			continue
+		}
+		if err := r.Delete(ctx, machineTemplate); err != nil && !apierrors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete MachineTemplate: %w", err)
+		}
+	}
+	return nil
+}
+
 func deleteMachineSet(ctx context.Context, c client.Client, ms *capiv1.MachineSet) error {
 	err := c.Get(ctx, client.ObjectKeyFromObject(ms), ms)
 	if err != nil {
@@ -775,6 +988,10 @@ func (r *NodePoolReconciler) delete(ctx context.Context, nodePool *hyperv1.NodeP
 	if err := deleteMachineDeployment(ctx, r.Client, md); err != nil {
 		return fmt.Errorf("failed to delete MachineDeployment: %w", err)
 	}
+	// Delete the MachineDeployments of the other subnets of a NodePool spread across subnets.
+	if err := r.deleteStaleMachineDeployments(ctx, nodePool, controlPlaneNamespace, nil); err != nil {
+		return err
+	}
 
 	if err := deleteMachineSet(ctx, r.Client, ms); err != nil {
 		return fmt.Errorf("failed to delete MachineSet: %w", err)
@@ -783,6 +1000,10 @@ func (r *NodePoolReconciler) delete(ctx context.Context, nodePool *hyperv1.NodeP
 	if err := deleteMachineHealthCheck(ctx, r.Client, mhc); err != nil {
 		return fmt.Errorf("failed to delete MachineHealthCheck: %w", err)
 	}
+
+	if err := r.releaseAgentBareMetalHosts(ctx, nodePool); err != nil {
+		return fmt.Errorf("failed to release BareMetalHosts: %w", err)
+	}
 	return nil
 }
 
@@ -843,13 +1064,19 @@ func (r *NodePoolReconciler) reconcileMachineDeployment(log logr.Logger,
 	machineTemplateCR client.Object,
 	CAPIClusterName string,
 	targetVersion,
-	targetConfigHash, targetConfigVersionHash, machineTemplateSpecJSON string) error {
+	targetConfigHash, targetConfigVersionHash, machineTemplateSpecJSON string,
+	index, count int) error {
 
 	// Set annotations and labels
 	if machineDeployment.GetAnnotations() == nil {
 		machineDeployment.Annotations = map[string]string{}
 	}
 	machineDeployment.Annotations[nodePoolAnnotation] = client.ObjectKeyFromObject(nodePool).String()
+	// Node labels and taints are propagated to the nodes by the hosted cluster config operator.
+	// They are not set on the Machine template so changing them does not roll out new Machines.
+	if err := setNodeLabelsAndTaintsAnnotations(nodePool, machineDeployment.Annotations); err != nil {
+		return err
+	}
 	if machineDeployment.GetLabels() == nil {
 		machineDeployment.Labels = map[string]string{}
 	}
@@ -912,10 +1139,7 @@ func (r *NodePoolReconciler) reconcileMachineDeployment(log logr.Logger,
 	machineDeployment.Spec.Strategy = &capiv1.MachineDeploymentStrategy{}
 	machineDeployment.Spec.Strategy.Type = capiv1.MachineDeploymentStrategyType(nodePool.Spec.Management.Replace.Strategy)
 	if nodePool.Spec.Management.Replace.RollingUpdate != nil {
-		machineDeployment.Spec.Strategy.RollingUpdate = &capiv1.MachineRollingUpdateDeployment{
-			MaxUnavailable: nodePool.Spec.Management.Replace.RollingUpdate.MaxUnavailable,
-			MaxSurge:       nodePool.Spec.Management.Replace.RollingUpdate.MaxSurge,
-		}
+		machineDeployment.Spec.Strategy.RollingUpdate = rollingUpdateShare(nodePool.Spec.Management.Replace.RollingUpdate, index, count)
 	}
 
 	// Propagate version and userData Secret to the machineDeployment.
@@ -942,14 +1166,25 @@ func (r *NodePoolReconciler) reconcileMachineDeployment(log logr.Logger,
 		// Before persisting, if the NodePool is brand new we want to make sure the replica number is set so the machineDeployment controller
 		// does not panic.
 		if machineDeployment.Spec.Replicas == nil {
-			setMachineDeploymentReplicas(nodePool, machineDeployment)
+			setMachineDeploymentReplicas(nodePool, machineDeployment, index, count)
 		}
 		return nil
 	}
 
-	// If the MachineDeployment is no processing we know
-	// is at the expected version (spec.version) and config (userData Secret) so we reconcile status and annotation.
-	if MachineDeploymentComplete(machineDeployment) {
+	setMachineDeploymentReplicas(nodePool, machineDeployment, index, count)
+	return nil
+}
+
+// setMachineDeploymentsStatus reconciles the NodePool status and annotations from the MachineDeployments of the NodePool.
+func setMachineDeploymentsStatus(log logr.Logger, nodePool *hyperv1.NodePool, machineDeployments []*capiv1.MachineDeployment,
+	targetVersion, targetConfigHash, targetConfigVersionHash string) {
+	// If the MachineDeployments are no processing we know
+	// they are at the expected version (spec.version) and config (userData Secret) so we reconcile status and annotation.
+	complete := true
+	for _, machineDeployment := range machineDeployments {
+		complete = complete && MachineDeploymentComplete(machineDeployment)
+	}
+	if complete {
 		if nodePool.Status.Version != targetVersion {
 			log.Info("Version update complete",
 				"previous", nodePool.Status.Version, "new", targetVersion)
@@ -967,42 +1202,85 @@ func (r *NodePoolReconciler) reconcileMachineDeployment(log logr.Logger,
 		nodePool.Annotations[nodePoolAnnotationCurrentConfigVersion] = targetConfigVersionHash
 	}
 
-	setMachineDeploymentReplicas(nodePool, machineDeployment)
-
-	// Bubble up AvailableReplicas and Ready condition from MachineDeployment.
-	nodePool.Status.Replicas = machineDeployment.Status.AvailableReplicas
-	for _, c := range machineDeployment.Status.Conditions {
-		// This condition should aggregate and summarise readiness from underlying MachineSets and Machines
-		// https://github.com/kubernetes-sigs/cluster-api/issues/3486.
-		if c.Type == capiv1.ReadyCondition {
-			// this is so api server does not complain
-			// invalid value: \"\": status.conditions.reason in body should be at least 1 chars long"
-			reason := hyperv1.NodePoolAsExpectedConditionReason
-			if c.Reason != "" {
-				reason = c.Reason
+	// Bubble up AvailableReplicas and Ready condition from the MachineDeployments.
+	// The NodePool is only ready if all of them are, so the first one that is not ready wins.
+	nodePool.Status.Replicas = 0
+	nodePool.Status.SpotInterruptions = 0
+	var ready *capiv1.Condition
+	for _, machineDeployment := range machineDeployments {
+		nodePool.Status.Replicas += machineDeployment.Status.AvailableReplicas
+		nodePool.Status.SpotInterruptions += spotInterruptions(machineDeployment.Annotations)
+		for i, c := range machineDeployment.Status.Conditions {
+			// This condition should aggregate and summarise readiness from underlying MachineSets and Machines
+			// https://github.com/kubernetes-sigs/cluster-api/issues/3486.
+			if c.Type == capiv1.ReadyCondition && (ready == nil || ready.Status == corev1.ConditionTrue) {
+				ready = &machineDeployment.Status.Conditions[i]
 			}
-
-			setStatusCondition(&nodePool.Status.Conditions, hyperv1.NodePoolCondition{
-				Type:               hyperv1.NodePoolReadyConditionType,
-				Status:             c.Status,
-				ObservedGeneration: nodePool.Generation,
-				Message:            c.Message,
-				Reason:             reason,
-			})
-			break
 		}
 	}
+	if c := ready; c != nil {
+		// this is so api server does not complain
+		// invalid value: \"\": status.conditions.reason in body should be at least 1 chars long"
+		reason := hyperv1.NodePoolAsExpectedConditionReason
+		if c.Reason != "" {
+			reason = c.Reason
+		}
 
-	return nil
+		setStatusCondition(&nodePool.Status.Conditions, hyperv1.NodePoolCondition{
+			Type:               hyperv1.NodePoolReadyConditionType,
+			Status:             c.Status,
+			ObservedGeneration: nodePool.Generation,
+			Message:            c.Message,
+			Reason:             reason,
+		})
+	}
 }
 
 func (r *NodePoolReconciler) reconcileMachineHealthCheck(mhc *capiv1.MachineHealthCheck,
 	nodePool *hyperv1.NodePool,
 	CAPIClusterName string) error {
-	// Opinionated spec based on
+	// Opinionated defaults based on
 	// https://github.com/openshift/managed-cluster-config/blob/14d4255ec75dc263ffd3d897dfccc725cb2b7072/deploy/osd-machine-api/011-machine-api.srep-worker-healthcheck.MachineHealthCheck.yaml
-	// TODO (alberto): possibly expose this config at the nodePool API.
+	// which can be overridden through the NodePool autoRepairConfig.
 	maxUnhealthy := intstr.FromInt(2)
+	unhealthyConditions := []capiv1.UnhealthyCondition{
+		{
+			Type:   corev1.NodeReady,
+			Status: corev1.ConditionFalse,
+			Timeout: metav1.Duration{
+				Duration: 8 * time.Minute,
+			},
+		},
+		{
+			Type:   corev1.NodeReady,
+			Status: corev1.ConditionUnknown,
+			Timeout: metav1.Duration{
+				Duration: 8 * time.Minute,
+			},
+		},
+	}
+	nodeStartupTimeout := metav1.Duration{
+		Duration: 20 * time.Minute,
+	}
+	if config := nodePool.Spec.Management.AutoRepairConfig; config != nil {
+		if config.MaxUnhealthy != nil {
+			maxUnhealthy = *config.MaxUnhealthy
+		}
+		if len(config.UnhealthyConditions) > 0 {
+			unhealthyConditions = make([]capiv1.UnhealthyCondition, 0, len(config.UnhealthyConditions))
+			for _, condition := range config.UnhealthyConditions {
+				unhealthyConditions = append(unhealthyConditions, capiv1.UnhealthyCondition{
+					Type:    condition.Type,
+					Status:  condition.Status,
+					Timeout: condition.Timeout,
+				})
+			}
+		}
+		if config.NodeStartupTimeout != nil {
+			nodeStartupTimeout = *config.NodeStartupTimeout
+		}
+	}
+
 	resourcesName := generateName(CAPIClusterName, nodePool.Spec.ClusterName, nodePool.GetName())
 	mhc.Spec = capiv1.MachineHealthCheckSpec{
 		ClusterName: CAPIClusterName,
@@ -1011,53 +1289,78 @@ func (r *NodePoolReconciler) reconcileMachineHealthCheck(mhc *capiv1.MachineHeal
 				resourcesName: resourcesName,
 			},
 		},
-		UnhealthyConditions: []capiv1.UnhealthyCondition{
-			{
-				Type:   corev1.NodeReady,
-				Status: corev1.ConditionFalse,
-				Timeout: metav1.Duration{
-					Duration: 8 * time.Minute,
-				},
-			},
-			{
-				Type:   corev1.NodeReady,
-				Status: corev1.ConditionUnknown,
-				Timeout: metav1.Duration{
-					Duration: 8 * time.Minute,
-				},
-			},
-		},
-		MaxUnhealthy: &maxUnhealthy,
-		NodeStartupTimeout: &metav1.Duration{
-			Duration: 20 * time.Minute,
-		},
+		UnhealthyConditions: unhealthyConditions,
+		MaxUnhealthy:        &maxUnhealthy,
+		NodeStartupTimeout:  &nodeStartupTimeout,
 	}
 	return nil
 }
 
 // setMachineDeploymentReplicas sets wanted replicas:
 // If autoscaling is enabled we reconcile min/max annotations and leave replicas untouched.
-func setMachineDeploymentReplicas(nodePool *hyperv1.NodePool, machineDeployment *capiv1.MachineDeployment) {
+// A NodePool spread across count MachineDeployments gets its share of replicas and min/max
+// for the MachineDeployment at the given index.
+func setMachineDeploymentReplicas(nodePool *hyperv1.NodePool, machineDeployment *capiv1.MachineDeployment, index, count int) {
 	if machineDeployment.Annotations == nil {
 		machineDeployment.Annotations = make(map[string]string)
 	}
 
 	if isAutoscalingEnabled(nodePool) {
+		min := replicasShare(nodePool.Spec.AutoScaling.Min, index, count)
+		max := replicasShare(nodePool.Spec.AutoScaling.Max, index, count)
 		if k8sutilspointer.Int32PtrDerefOr(machineDeployment.Spec.Replicas, 0) == 0 {
-			// if autoscaling is enabled and the machineDeployment does not exist yet or it has 0 replicas
-			// we set it to 1 replica as the autoscaler does not support scaling from zero yet.
-			machineDeployment.Spec.Replicas = k8sutilspointer.Int32Ptr(int32(1))
+			// if autoscaling is enabled and the machineDeployment does not exist yet or it has 0 replicas,
+			// e.g. when resuming from hibernation, we set it to its minimum, as the autoscaler doesn't scale
+			// it up to it. A MachineDeployment that can scale from zero is left at zero.
+			machineDeployment.Spec.Replicas = k8sutilspointer.Int32Ptr(min)
 		}
-		machineDeployment.Annotations[autoscalerMaxAnnotation] = strconv.Itoa(int(nodePool.Spec.AutoScaling.Max))
-		machineDeployment.Annotations[autoscalerMinAnnotation] = strconv.Itoa(int(nodePool.Spec.AutoScaling.Min))
+		machineDeployment.Annotations[autoscalerMaxAnnotation] = strconv.Itoa(int(max))
+		machineDeployment.Annotations[autoscalerMinAnnotation] = strconv.Itoa(int(min))
 	}
+	setAutoscalerCapacityAnnotations(nodePool, machineDeployment.Annotations)
 
 	// If autoscaling is NOT enabled we reset min/max annotations and reconcile replicas.
 	if !isAutoscalingEnabled(nodePool) {
 		machineDeployment.Annotations[autoscalerMaxAnnotation] = "0"
 		machineDeployment.Annotations[autoscalerMinAnnotation] = "0"
-		machineDeployment.Spec.Replicas = k8sutilspointer.Int32Ptr(k8sutilspointer.Int32PtrDerefOr(nodePool.Spec.Replicas, 0))
+		machineDeployment.Spec.Replicas = k8sutilspointer.Int32Ptr(replicasShare(desiredReplicas(nodePool), index, count))
+	}
+}
+
+// rollingUpdateShare returns the rolling update of the MachineDeployment at the given index of count
+// MachineDeployments of a NodePool. Absolute MaxUnavailable and MaxSurge are divided like the replicas,
+// so that the NodePool as a whole honours them, and percentages apply to each MachineDeployment as is.
+// A MachineDeployment whose shares are both zero gets a MaxSurge of 1, so that it can still roll out.
+func rollingUpdateShare(rollingUpdate *hyperv1.RollingUpdate, index, count int) *capiv1.MachineRollingUpdateDeployment {
+	share := func(value *intstr.IntOrString) *intstr.IntOrString {
+		if value == nil || value.Type != intstr.Int || count == 1 {
+			return value
+		}
+		shared := intstr.FromInt(int(replicasShare(value.IntVal, index, count)))
+		return &shared
+	}
+	result := &capiv1.MachineRollingUpdateDeployment{
+		MaxUnavailable: share(rollingUpdate.MaxUnavailable),
+		MaxSurge:       share(rollingUpdate.MaxSurge),
+	}
+	isZero := func(value *intstr.IntOrString) bool {
+		return value != nil && value.Type == intstr.Int && value.IntVal == 0
+	}
+	if count > 1 && (result.MaxUnavailable == nil || isZero(result.MaxUnavailable)) && isZero(result.MaxSurge) {
+		surge := intstr.FromInt(1)
+		result.MaxSurge = &surge
+	}
+	return result
+}
+
+// replicasShare divides total evenly across count MachineDeployments and returns the share of the one
+// at the given index. The first MachineDeployments get the remainder.
+func replicasShare(total int32, index, count int) int32 {
+	share := total / int32(count)
+	if int32(index) < total%int32(count) {
+		share++
 	}
+	return share
 }
 
 func ignConfig(encodedCACert, encodedToken, endpoint string, proxy *configv1.Proxy) ignitionapi.Config {
@@ -1166,6 +1469,10 @@ func (r *NodePoolReconciler) getConfig(ctx context.Context,
 			errors = append(errors, err)
 			continue
 		}
+		if hasKubeletConfig(nodePool) && isKubeletConfigManifest([]byte(configConfigMap.Data[TokenSecretConfigKey])) {
+			errors = append(errors, fmt.Errorf("configmap %q contains a KubeletConfig, which can't be combined with the NodePool kubeletConfig, reservedCPUs or taints", configConfigMap.Name))
+			continue
+		}
 		configs = append(configs, *configConfigMap)
 	}
 
@@ -1180,6 +1487,60 @@ func (r *NodePoolReconciler) getConfig(ctx context.Context,
 		allConfigPlainText = append(allConfigPlainText, string(manifest))
 	}
 
+	if nodePool.Spec.Platform.Type == hyperv1.PowerVSPlatform && nodePool.Spec.Platform.PowerVS != nil && nodePool.Spec.Platform.PowerVS.SMTLevel != nil {
+		smtConfig, err := powerVSSMTMachineConfig(*nodePool.Spec.Platform.PowerVS.SMTLevel)
+		if err != nil {
+			errors = append(errors, err)
+		} else {
+			allConfigPlainText = append(allConfigPlainText, smtConfig)
+		}
+	}
+
+	if nodePool.Spec.GPU != nil && nodePool.Spec.GPU.Drivers != nil {
+		gpuConfig, err := gpuDriversMachineConfig(nodePool.Spec.GPU.Drivers)
+		if err != nil {
+			errors = append(errors, err)
+		} else {
+			allConfigPlainText = append(allConfigPlainText, gpuConfig)
+		}
+	}
+
+	if hasKubeletConfig(nodePool) {
+		kubeletConfig, err := nodePoolKubeletConfig(nodePool)
+		if err != nil {
+			errors = append(errors, err)
+		} else {
+			allConfigPlainText = append(allConfigPlainText, kubeletConfig)
+		}
+	}
+
+	if nodePool.Spec.PerformanceProfile != nil {
+		performanceConfig, err := performanceProfileMachineConfig(nodePool.Spec.PerformanceProfile)
+		if err != nil {
+			errors = append(errors, err)
+		} else if performanceConfig != "" {
+			allConfigPlainText = append(allConfigPlainText, performanceConfig)
+		}
+	}
+
+	if len(nodePool.Spec.RegistryMirrors) > 0 {
+		mirrorsConfig, err := registryMirrorsImageContentSourcePolicy(nodePool.Spec.RegistryMirrors)
+		if err != nil {
+			errors = append(errors, err)
+		} else {
+			allConfigPlainText = append(allConfigPlainText, mirrorsConfig)
+		}
+	}
+
+	if nodePool.Spec.PullSecret != nil {
+		pullSecretConfig, err := r.getPullSecretConfig(ctx, nodePool, hcluster)
+		if err != nil {
+			errors = append(errors, err)
+		} else {
+			allConfigPlainText = append(allConfigPlainText, pullSecretConfig)
+		}
+	}
+
 	// These configs are the input to a hash func whose output is used as part of the name of the user-data secret,
 	// so our output must be deterministic.
 	sort.Strings(allConfigPlainText)
@@ -1190,8 +1551,21 @@ func (r *NodePoolReconciler) getConfig(ctx context.Context,
 // validateManagement does additional backend validation. API validation/default should
 // prevent this from ever fail.
 func validateManagement(nodePool *hyperv1.NodePool) error {
-	// TODO actually validate the inplace upgrade type
+	if err := validateAutoRepairConfig(nodePool.Spec.Management.AutoRepairConfig); err != nil {
+		return err
+	}
+
 	if nodePool.Spec.Management.UpgradeType == hyperv1.UpgradeTypeInPlace {
+		if nodePool.Spec.Management.InPlace != nil && nodePool.Spec.Management.InPlace.MaxUnavailable != nil {
+			maxUnavailable := nodePool.Spec.Management.InPlace.MaxUnavailable
+			value, err := intstr.GetScaledValueFromIntOrPercent(maxUnavailable, 100, false)
+			if err != nil {
+				return fmt.Errorf("invalid %q upgrade type maxUnavailable %q: %w", hyperv1.UpgradeTypeInPlace, maxUnavailable.String(), err)
+			}
+			if value < 1 {
+				return fmt.Errorf("invalid %q upgrade type maxUnavailable %q: must be greater than zero", hyperv1.UpgradeTypeInPlace, maxUnavailable.String())
+			}
+		}
 		return nil
 	}
 
@@ -1214,10 +1588,150 @@ func validateManagement(nodePool *hyperv1.NodePool) error {
 		return fmt.Errorf("this is unsupported. %q upgrade type with strategy %q require a MaxUnavailable and MaxSurge",
 			hyperv1.UpgradeTypeReplace, hyperv1.UpgradeStrategyRollingUpdate)
 	}
+	if nodePool.Spec.Management.Replace.Strategy == hyperv1.UpgradeStrategyRollingUpdate {
+		return validateRollingUpdate(nodePool.Spec.Management.Replace.RollingUpdate)
+	}
+
+	return nil
+}
+
+// validateRollingUpdate validates what the CAPI MachineDeployment webhook would, as it is not run
+// for the MachineDeployments of NodePools. Unset MaxUnavailable and MaxSurge default to 0 and 1.
+func validateRollingUpdate(rollingUpdate *hyperv1.RollingUpdate) error {
+	scale := func(name string, value *intstr.IntOrString, defaultValue int) (int, error) {
+		if value == nil {
+			return defaultValue, nil
+		}
+		scaled, err := intstr.GetScaledValueFromIntOrPercent(value, 100, false)
+		if err != nil {
+			return 0, fmt.Errorf("invalid %q upgrade type %s %q: %w", hyperv1.UpgradeTypeReplace, name, value.String(), err)
+		}
+		if scaled < 0 {
+			return 0, fmt.Errorf("invalid %q upgrade type %s %q: must not be negative", hyperv1.UpgradeTypeReplace, name, value.String())
+		}
+		return scaled, nil
+	}
+	maxUnavailable, err := scale("maxUnavailable", rollingUpdate.MaxUnavailable, 0)
+	if err != nil {
+		return err
+	}
+	maxSurge, err := scale("maxSurge", rollingUpdate.MaxSurge, 1)
+	if err != nil {
+		return err
+	}
+	if maxUnavailable == 0 && maxSurge == 0 {
+		return fmt.Errorf("invalid %q upgrade type rolling update: maxUnavailable and maxSurge cannot both be zero", hyperv1.UpgradeTypeReplace)
+	}
+	return nil
+}
+
+// validateAutoRepairConfig validates what the API can't, i.e. that the auto-repair
+// timeouts are positive and that maxUnhealthy is a valid number or percentage.
+func validateAutoRepairConfig(config *hyperv1.AutoRepairConfig) error {
+	if config == nil {
+		return nil
+	}
+	if config.MaxUnhealthy != nil {
+		maxUnhealthy, err := intstr.GetScaledValueFromIntOrPercent(config.MaxUnhealthy, 100, false)
+		if err != nil {
+			return fmt.Errorf("invalid autoRepairConfig maxUnhealthy %q: %w", config.MaxUnhealthy.String(), err)
+		}
+		if maxUnhealthy < 0 {
+			return fmt.Errorf("invalid autoRepairConfig maxUnhealthy %q: must not be negative", config.MaxUnhealthy.String())
+		}
+	}
+	if config.NodeStartupTimeout != nil && config.NodeStartupTimeout.Duration <= 0 {
+		return fmt.Errorf("invalid autoRepairConfig nodeStartupTimeout %q: must be positive", config.NodeStartupTimeout.Duration)
+	}
+	for _, condition := range config.UnhealthyConditions {
+		if condition.Timeout.Duration <= 0 {
+			return fmt.Errorf("invalid autoRepairConfig timeout %q for condition %s=%s: must be positive",
+				condition.Timeout.Duration, condition.Type, condition.Status)
+		}
+	}
+	return nil
+}
+
+// validateNodeLabelsAndTaints validates what the API can't, i.e. that the node labels and
+// taints are valid for the guest cluster nodes.
+func validateNodeLabelsAndTaints(nodePool *hyperv1.NodePool) error {
+	var errs []error
+	for key, value := range nodePool.Spec.NodeLabels {
+		for _, msg := range validation.IsQualifiedName(key) {
+			errs = append(errs, fmt.Errorf("invalid node label key %q: %s", key, msg))
+		}
+		for _, msg := range validation.IsValidLabelValue(value) {
+			errs = append(errs, fmt.Errorf("invalid node label value %q for key %q: %s", value, key, msg))
+		}
+	}
+
+	seenTaints := sets.NewString()
+	for _, taint := range nodePool.Spec.Taints {
+		for _, msg := range validation.IsQualifiedName(taint.Key) {
+			errs = append(errs, fmt.Errorf("invalid taint key %q: %s", taint.Key, msg))
+		}
+		for _, msg := range validation.IsValidLabelValue(taint.Value) {
+			errs = append(errs, fmt.Errorf("invalid taint value %q for key %q: %s", taint.Value, taint.Key, msg))
+		}
+		id := fmt.Sprintf("%s:%s", taint.Key, taint.Effect)
+		if seenTaints.Has(id) {
+			errs = append(errs, fmt.Errorf("duplicate taint with key %q and effect %q", taint.Key, taint.Effect))
+		}
+		seenTaints.Insert(id)
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// setNodeLabelsAndTaintsAnnotations signals the node labels and taints of the NodePool
+// to the hosted cluster config operator, which reconciles them on the nodes.
+func setNodeLabelsAndTaintsAnnotations(nodePool *hyperv1.NodePool, annotations map[string]string) error {
+	labels, taints := gpuNodeLabelsAndTaints(nodePool)
+	if len(labels) > 0 {
+		nodeLabels, err := json.Marshal(labels)
+		if err != nil {
+			return fmt.Errorf("failed to marshal node labels: %w", err)
+		}
+		annotations[nodePoolAnnotationNodeLabels] = string(nodeLabels)
+	} else {
+		delete(annotations, nodePoolAnnotationNodeLabels)
+	}
 
+	if len(taints) > 0 {
+		taintsJSON, err := json.Marshal(nodeTaints(taints))
+		if err != nil {
+			return fmt.Errorf("failed to marshal taints: %w", err)
+		}
+		annotations[nodePoolAnnotationTaints] = string(taintsJSON)
+	} else {
+		delete(annotations, nodePoolAnnotationTaints)
+	}
 	return nil
 }
 
+// nodeTaints converts the taints of a NodePool to node taints.
+func nodeTaints(taints []hyperv1.Taint) []corev1.Taint {
+	result := make([]corev1.Taint, 0, len(taints))
+	for _, taint := range taints {
+		result = append(result, corev1.Taint{
+			Key:    taint.Key,
+			Value:  taint.Value,
+			Effect: taint.Effect,
+		})
+	}
+	return result
+}
+
+// spotInterruptions returns the number of nodes the hosted cluster config operator
+// replaced after a spot interruption notice, as counted in the given MachineDeployment
+// or MachineSet annotations.
+func spotInterruptions(annotations map[string]string) int32 {
+	count, err := strconv.ParseInt(annotations[nodePoolAnnotationSpotInterruptions], 10, 32)
+	if err != nil {
+		return 0
+	}
+	return int32(count)
+}
+
 func defaultAndValidateConfigManifest(manifest []byte) ([]byte, error) {
 	scheme := runtime.NewScheme()
 	mcfgv1.Install(scheme)
@@ -1278,12 +1792,209 @@ func isUpdatingVersion(nodePool *hyperv1.NodePool, targetVersion string) bool {
 	return targetVersion != nodePool.Status.Version
 }
 
+// isConfigUpdatePendingApproval returns true when the NodePool config drifted from the current one
+// and the NodePool requires config updates to be approved before rolling them out.
+func isConfigUpdatePendingApproval(nodePool *hyperv1.NodePool, latestConfigHash string) bool {
+	currentConfigHash := nodePool.GetAnnotations()[nodePoolAnnotationCurrentConfig]
+	if nodePool.Spec.Management.ConfigUpdatePolicy != hyperv1.ConfigUpdatePolicyManual ||
+		currentConfigHash == "" || currentConfigHash == latestConfigHash {
+		return false
+	}
+	return nodePool.GetAnnotations()[hyperv1.ApproveConfigAnnotation] != latestConfigHash
+}
+
+// getCurrentConfig returns the config rolled out to the NodePool from its current token Secret.
+func (r *NodePoolReconciler) getCurrentConfig(ctx context.Context, nodePool *hyperv1.NodePool, controlPlaneNamespace string) (string, error) {
+	tokenSecret := TokenSecret(controlPlaneNamespace, nodePool.Name, nodePool.GetAnnotations()[nodePoolAnnotationCurrentConfigVersion])
+	if err := r.Get(ctx, client.ObjectKeyFromObject(tokenSecret), tokenSecret); err != nil {
+		return "", fmt.Errorf("failed to get token Secret: %w", err)
+	}
+	config, err := decompress(tokenSecret.Data[TokenSecretConfigKey])
+	if err != nil {
+		return "", fmt.Errorf("failed to decompress config from token Secret %q: %w", client.ObjectKeyFromObject(tokenSecret).String(), err)
+	}
+	return string(config), nil
+}
+
+// outOfDateNodes returns the names of the nodes, or of the Machines without a node yet, that don't use
+// the given user data Secret.
+func outOfDateNodes(machines []capiv1.Machine, userDataSecretName string, all bool) []string {
+	var names []string
+	for _, machine := range machines {
+		if !all && k8sutilspointer.StringDeref(machine.Spec.Bootstrap.DataSecretName, "") == userDataSecretName {
+			continue
+		}
+		if machine.Status.NodeRef != nil {
+			names = append(names, machine.Status.NodeRef.Name)
+		} else {
+			names = append(names, machine.Name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// maxListedOutOfDateNodes caps the nodes listed in the NodesUpToDate condition message.
+const maxListedOutOfDateNodes = 10
+
+func setNodesUpToDateCondition(nodePool *hyperv1.NodePool, outOfDateNodes []string, latestConfigHash string, configUpdatePendingApproval bool) {
+	var messages []string
+	reason := hyperv1.NodePoolAsExpectedConditionReason
+	if configUpdatePendingApproval {
+		reason = hyperv1.NodePoolConfigUpdatePendingApprovalConditionReason
+		messages = append(messages, fmt.Sprintf("Config update from %s to %s is pending approval, set the %s annotation to %s to roll it out.",
+			nodePool.GetAnnotations()[nodePoolAnnotationCurrentConfig], latestConfigHash, hyperv1.ApproveConfigAnnotation, latestConfigHash))
+	} else if len(outOfDateNodes) > 0 {
+		reason = hyperv1.NodePoolNodesOutOfDateConditionReason
+	}
+	if len(outOfDateNodes) > 0 {
+		listed := outOfDateNodes
+		if len(listed) > maxListedOutOfDateNodes {
+			listed = listed[:maxListedOutOfDateNodes]
+		}
+		message := fmt.Sprintf("Out of date nodes: %s", strings.Join(listed, ", "))
+		if len(outOfDateNodes) > len(listed) {
+			message += fmt.Sprintf(" and %d more", len(outOfDateNodes)-len(listed))
+		}
+		messages = append(messages, message+".")
+	}
+
+	status := corev1.ConditionTrue
+	if reason != hyperv1.NodePoolAsExpectedConditionReason {
+		status = corev1.ConditionFalse
+	}
+	setStatusCondition(&nodePool.Status.Conditions, hyperv1.NodePoolCondition{
+		Type:               hyperv1.NodePoolNodesUpToDateConditionType,
+		Status:             status,
+		Reason:             reason,
+		Message:            strings.Join(messages, " "),
+		ObservedGeneration: nodePool.Generation,
+	})
+}
+
 func isUpdatingConfig(nodePool *hyperv1.NodePool, targetConfigHash string) bool {
 	return targetConfigHash != nodePool.GetAnnotations()[nodePoolAnnotationCurrentConfig]
 }
 
 func isAutoscalingEnabled(nodePool *hyperv1.NodePool) bool {
-	return nodePool.Spec.AutoScaling != nil
+	return nodePool.Spec.AutoScaling != nil && !isHibernating(nodePool)
+}
+
+// isHibernating returns whether the NodePool is scaled to zero nodes to hibernate.
+func isHibernating(nodePool *hyperv1.NodePool) bool {
+	return nodePool.Spec.PowerState == hyperv1.NodePoolPowerStateHibernating
+}
+
+// setHibernatingCondition reports whether the NodePool is hibernating. It is only
+// hibernating once all its machines are deleted, until then it is in progress.
+func setHibernatingCondition(nodePool *hyperv1.NodePool, machines int) {
+	switch {
+	case !isHibernating(nodePool):
+		setStatusCondition(&nodePool.Status.Conditions, hyperv1.NodePoolCondition{
+			Type:               hyperv1.NodePoolHibernatingConditionType,
+			Status:             corev1.ConditionFalse,
+			Reason:             hyperv1.NodePoolAsExpectedConditionReason,
+			ObservedGeneration: nodePool.Generation,
+		})
+	case machines > 0:
+		setStatusCondition(&nodePool.Status.Conditions, hyperv1.NodePoolCondition{
+			Type:               hyperv1.NodePoolHibernatingConditionType,
+			Status:             corev1.ConditionFalse,
+			Reason:             hyperv1.NodePoolHibernationInProgressConditionReason,
+			Message:            fmt.Sprintf("Scaling down to zero nodes, %d machines left", machines),
+			ObservedGeneration: nodePool.Generation,
+		})
+	default:
+		setStatusCondition(&nodePool.Status.Conditions, hyperv1.NodePoolCondition{
+			Type:               hyperv1.NodePoolHibernatingConditionType,
+			Status:             corev1.ConditionTrue,
+			Reason:             hyperv1.NodePoolAsExpectedConditionReason,
+			Message:            "The NodePool is scaled to zero nodes",
+			ObservedGeneration: nodePool.Generation,
+		})
+	}
+}
+
+// desiredReplicas returns the replicas of a NodePool without autoscaling.
+func desiredReplicas(nodePool *hyperv1.NodePool) int32 {
+	if isHibernating(nodePool) {
+		return 0
+	}
+	return k8sutilspointer.Int32PtrDerefOr(nodePool.Spec.Replicas, 0)
+}
+
+// nodeCapacity returns the capacity of a node of the NodePool, derived from its
+// platform where possible and overridden by .spec.autoScaling.capacity.
+func nodeCapacity(nodePool *hyperv1.NodePool) hyperv1.NodePoolCapacity {
+	capacity := hyperv1.NodePoolCapacity{Architecture: "amd64"}
+	switch nodePool.Spec.Platform.Type {
+	case hyperv1.PowerVSPlatform:
+		capacity.Architecture = "ppc64le"
+	case hyperv1.KubevirtPlatform:
+		if nodePool.Spec.Platform.Kubevirt != nil {
+			capacity = kubevirtNodeCapacity(nodePool.Spec.Platform.Kubevirt)
+		}
+	}
+	if nodePool.Spec.GPU != nil {
+		capacity.GPU = gpuCount(nodePool.Spec.GPU)
+		capacity.GPUType = gpuResourceName
+	}
+	if nodePool.Spec.AutoScaling == nil || nodePool.Spec.AutoScaling.Capacity == nil {
+		return capacity
+	}
+	override := nodePool.Spec.AutoScaling.Capacity
+	if override.CPU != nil {
+		capacity.CPU = override.CPU
+	}
+	if override.Memory != nil {
+		capacity.Memory = override.Memory
+	}
+	if override.GPU != 0 {
+		capacity.GPU = override.GPU
+	}
+	if override.GPUType != "" {
+		capacity.GPUType = override.GPUType
+	}
+	if override.Architecture != "" {
+		capacity.Architecture = override.Architecture
+	}
+	return capacity
+}
+
+// setAutoscalerCapacityAnnotations publishes the capacity of a node of the
+// NodePool, which the autoscaler needs to scale up a MachineDeployment or
+// MachineSet without any Machines. The annotations are removed when autoscaling
+// is disabled or the CPU or memory of the nodes are unknown.
+func setAutoscalerCapacityAnnotations(nodePool *hyperv1.NodePool, annotations map[string]string) {
+	for _, key := range []string{
+		autoscalerCapacityCPUAnnotation,
+		autoscalerCapacityMemoryAnnotation,
+		autoscalerCapacityGPUCountAnnotation,
+		autoscalerCapacityGPUTypeAnnotation,
+		autoscalerCapacityLabelsAnnotation,
+	} {
+		delete(annotations, key)
+	}
+	if !isAutoscalingEnabled(nodePool) {
+		return
+	}
+	capacity := nodeCapacity(nodePool)
+	if capacity.CPU == nil || capacity.Memory == nil {
+		return
+	}
+	annotations[autoscalerCapacityCPUAnnotation] = capacity.CPU.String()
+	annotations[autoscalerCapacityMemoryAnnotation] = capacity.Memory.String()
+	if capacity.GPU > 0 {
+		annotations[autoscalerCapacityGPUCountAnnotation] = strconv.Itoa(int(capacity.GPU))
+		if capacity.GPUType != "" {
+			annotations[autoscalerCapacityGPUTypeAnnotation] = capacity.GPUType
+		}
+	}
+	labels := fmt.Sprintf("%s=%s", corev1.LabelArchStable, capacity.Architecture)
+	if nodePool.Spec.GPU != nil {
+		labels += fmt.Sprintf(",%s=true", gpuPresentLabel)
+	}
+	annotations[autoscalerCapacityLabelsAnnotation] = labels
 }
 
 func validateAutoscaling(nodePool *hyperv1.NodePool) error {
@@ -1299,8 +2010,15 @@ func validateAutoscaling(nodePool *hyperv1.NodePool) error {
 			return fmt.Errorf("max must be equal or greater than min. Max: %v, Min: %v", max, min)
 		}
 
-		if max == 0 || min == 0 {
-			return fmt.Errorf("max and min must be not zero. Max: %v, Min: %v", max, min)
+		if max == 0 {
+			return fmt.Errorf("max must be not zero. Max: %v, Min: %v", max, min)
+		}
+
+		if min == 0 {
+			capacity := nodeCapacity(nodePool)
+			if capacity.CPU == nil || capacity.Memory == nil {
+				return fmt.Errorf("scaling from zero requires the CPU and memory of the nodes, set them in .spec.autoScaling.capacity")
+			}
 		}
 	}
 
@@ -1435,6 +2153,41 @@ func (r *NodePoolReconciler) enqueueNodePoolsForConfig(obj client.Object) []reco
 	return result
 }
 
+func (r *NodePoolReconciler) enqueueNodePoolsForSecret(obj client.Object) []reconcile.Request {
+	result := enqueueParentNodePool(obj)
+
+	// NodePools with a pull secret merge it with the pull secret of their HostedCluster,
+	// so they are reconciled when the HostedCluster pull secret changes too.
+	hostedClusterList := &hyperv1.HostedClusterList{}
+	if err := r.List(context.Background(), hostedClusterList, client.InNamespace(obj.GetNamespace())); err != nil {
+		return result
+	}
+	clustersUsingSecret := sets.NewString()
+	for key := range hostedClusterList.Items {
+		if hostedClusterList.Items[key].Spec.PullSecret.Name == obj.GetName() {
+			clustersUsingSecret.Insert(hostedClusterList.Items[key].Name)
+		}
+	}
+
+	// Reconcile NodePools which are referencing the given Secret as pull secret.
+	nodePoolList := &hyperv1.NodePoolList{}
+	if err := r.List(context.Background(), nodePoolList, client.InNamespace(obj.GetNamespace())); err != nil {
+		return result
+	}
+	for key := range nodePoolList.Items {
+		pullSecret := nodePoolList.Items[key].Spec.PullSecret
+		if pullSecret == nil {
+			continue
+		}
+		if pullSecret.Name == obj.GetName() || clustersUsingSecret.Has(nodePoolList.Items[key].Spec.ClusterName) {
+			result = append(result,
+				reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&nodePoolList.Items[key])},
+			)
+		}
+	}
+	return result
+}
+
 func enqueueParentNodePool(obj client.Object) []reconcile.Request {
 	var nodePoolName string
 	if obj.GetAnnotations() != nil {
@@ -1492,6 +2245,11 @@ func (r *NodePoolReconciler) listMachineTemplates(nodePool *hyperv1.NodePool) ([
 		if err != nil {
 			return nil, err
 		}
+	case hyperv1.IBMCloudVPCPlatform:
+		gvk, err = apiutil.GVKForObject(&capipowervs.IBMVPCMachineTemplate{}, api.Scheme)
+		if err != nil {
+			return nil, err
+		}
 	default:
 		// need a default path that returns a value that does not cause the hypershift operator to crash
 		// if no explicit machineTemplate is defined safe to assume none exist
@@ -1534,6 +2292,22 @@ func compress(content []byte) ([]byte, error) {
 	return b.Bytes(), nil
 }
 
+func decompress(content []byte) ([]byte, error) {
+	if len(content) == 0 {
+		return nil, nil
+	}
+	gz, err := gzip.NewReader(bytes.NewReader(content))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress content: %w", err)
+	}
+	defer gz.Close()
+	decompressed, err := io.ReadAll(gz)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress content: %w", err)
+	}
+	return decompressed, nil
+}
+
 func hashStruct(o interface{}) string {
 	hash := fnv.New32a()
 	hash.Write([]byte(fmt.Sprintf("%v", o)))
@@ -1679,6 +2453,18 @@ func machineTemplateBuilders(hcluster *hyperv1.HostedCluster, nodePool *hyperv1.
 			o.Annotations[nodePoolAnnotation] = client.ObjectKeyFromObject(nodePool).String()
 			return nil
 		}
+	case hyperv1.IBMCloudVPCPlatform:
+		template = &capipowervs.IBMVPCMachineTemplate{}
+		machineTemplateSpec = ibmVPCMachineTemplateSpec(hcluster, nodePool)
+		mutateTemplate = func(object client.Object) error {
+			o, _ := object.(*capipowervs.IBMVPCMachineTemplate)
+			o.Spec = *machineTemplateSpec.(*capipowervs.IBMVPCMachineTemplateSpec)
+			if o.Annotations == nil {
+				o.Annotations = make(map[string]string)
+			}
+			o.Annotations[nodePoolAnnotation] = client.ObjectKeyFromObject(nodePool).String()
+			return nil
+		}
 	default:
 		// TODO(alberto): Consider signal in a condition.
 		return nil, nil, "", fmt.Errorf("unsupported platform type: %s", nodePool.Spec.Platform.Type)
@@ -20,6 +20,7 @@ import (
 	"github.com/openshift/hypershift/support/util/fakeimagemetadataprovider"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	apiresource "k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	k8sutilspointer "k8s.io/utils/pointer"
@@ -222,6 +223,53 @@ func TestValidateAutoscaling(t *testing.T) {
 			},
 			error: false,
 		},
+		{
+			name: "passes when min is zero and the capacity of the nodes is set",
+			nodePool: &hyperv1.NodePool{
+				Spec: hyperv1.NodePoolSpec{
+					AutoScaling: &hyperv1.NodePoolAutoScaling{
+						Min: 0,
+						Max: 2,
+						Capacity: &hyperv1.NodePoolCapacity{
+							CPU:    apiresource.NewQuantity(4, apiresource.DecimalSI),
+							Memory: apiresource.NewQuantity(16*1024*1024*1024, apiresource.BinarySI),
+						},
+					},
+				},
+			},
+			error: false,
+		},
+		{
+			name: "passes when min is zero and the capacity of the nodes is derived from the platform",
+			nodePool: &hyperv1.NodePool{
+				Spec: hyperv1.NodePoolSpec{
+					Platform: hyperv1.NodePoolPlatform{
+						Type:     hyperv1.KubevirtPlatform,
+						Kubevirt: generateKubevirtPlatform("8Gi", 4, "testimage", "32Gi"),
+					},
+					AutoScaling: &hyperv1.NodePoolAutoScaling{
+						Min: 0,
+						Max: 2,
+					},
+				},
+			},
+			error: false,
+		},
+		{
+			name: "fails when min is zero and only the CPU of the nodes is known",
+			nodePool: &hyperv1.NodePool{
+				Spec: hyperv1.NodePoolSpec{
+					AutoScaling: &hyperv1.NodePoolAutoScaling{
+						Min: 0,
+						Max: 2,
+						Capacity: &hyperv1.NodePoolCapacity{
+							CPU: apiresource.NewQuantity(4, apiresource.DecimalSI),
+						},
+					},
+				},
+			},
+			error: true,
+		},
 	}
 
 	for _, tc := range testCases {
@@ -585,6 +633,37 @@ spec:
 			expect: kubeletConfig1,
 			error:  false,
 		},
+		{
+			name: "fails if a KubeletConfig is combined with the NodePool kubeletConfig",
+			nodePool: &hyperv1.NodePool{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: namespace,
+				},
+				Spec: hyperv1.NodePoolSpec{
+					Config: []corev1.LocalObjectReference{
+						{
+							Name: "kubeletconfig-1",
+						},
+					},
+					KubeletConfig: &hyperv1.NodePoolKubeletConfig{
+						MaxPods: k8sutilspointer.Int32Ptr(500),
+					},
+				},
+			},
+			config: []client.Object{
+				&corev1.ConfigMap{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "kubeletconfig-1",
+						Namespace: namespace,
+					},
+					Data: map[string]string{
+						TokenSecretConfigKey: kubeletConfig1,
+					},
+				},
+			},
+			expect: "",
+			error:  true,
+		},
 		{
 			name: "gets a single valid MachineConfig with a core MachineConfig",
 			nodePool: &hyperv1.NodePool{
@@ -929,20 +1008,266 @@ func TestSetMachineDeploymentReplicas(t *testing.T) {
 				autoscalerMaxAnnotation: "5",
 			},
 		},
+		{
+			name: "it keeps zero replicas and publishes the capacity of the nodes when the NodePool can scale from zero",
+			nodePool: &hyperv1.NodePool{
+				ObjectMeta: metav1.ObjectMeta{},
+				Spec: hyperv1.NodePoolSpec{
+					AutoScaling: &hyperv1.NodePoolAutoScaling{
+						Min: 0,
+						Max: 5,
+						Capacity: &hyperv1.NodePoolCapacity{
+							CPU:          apiresource.NewQuantity(8, apiresource.DecimalSI),
+							Memory:       apiresource.NewQuantity(32*1024*1024*1024, apiresource.BinarySI),
+							GPU:          1,
+							GPUType:      "nvidia.com/gpu",
+							Architecture: "arm64",
+						},
+					},
+				},
+			},
+			machineDeployment: &capiv1.MachineDeployment{},
+			expectReplicas:    0,
+			expectAutoscalerAnnotations: map[string]string{
+				autoscalerMinAnnotation:              "0",
+				autoscalerMaxAnnotation:              "5",
+				autoscalerCapacityCPUAnnotation:      "8",
+				autoscalerCapacityMemoryAnnotation:   "32Gi",
+				autoscalerCapacityGPUCountAnnotation: "1",
+				autoscalerCapacityGPUTypeAnnotation:  "nvidia.com/gpu",
+				autoscalerCapacityLabelsAnnotation:   "kubernetes.io/arch=arm64",
+			},
+		},
+		{
+			name: "it scales to zero replicas when the NodePool is hibernating",
+			nodePool: &hyperv1.NodePool{
+				ObjectMeta: metav1.ObjectMeta{},
+				Spec: hyperv1.NodePoolSpec{
+					Replicas:   k8sutilspointer.Int32Ptr(3),
+					PowerState: hyperv1.NodePoolPowerStateHibernating,
+				},
+			},
+			machineDeployment: &capiv1.MachineDeployment{
+				Spec: capiv1.MachineDeploymentSpec{
+					Replicas: k8sutilspointer.Int32Ptr(3),
+				},
+			},
+			expectReplicas: 0,
+			expectAutoscalerAnnotations: map[string]string{
+				autoscalerMinAnnotation: "0",
+				autoscalerMaxAnnotation: "0",
+			},
+		},
+		{
+			name: "it scales to zero replicas and disables autoscaling when an autoscaled NodePool is hibernating",
+			nodePool: &hyperv1.NodePool{
+				ObjectMeta: metav1.ObjectMeta{},
+				Spec: hyperv1.NodePoolSpec{
+					AutoScaling: &hyperv1.NodePoolAutoScaling{
+						Min: 1,
+						Max: 5,
+					},
+					PowerState: hyperv1.NodePoolPowerStateHibernating,
+				},
+			},
+			machineDeployment: &capiv1.MachineDeployment{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{
+						autoscalerMinAnnotation: "1",
+						autoscalerMaxAnnotation: "5",
+					},
+				},
+				Spec: capiv1.MachineDeploymentSpec{
+					Replicas: k8sutilspointer.Int32Ptr(4),
+				},
+			},
+			expectReplicas: 0,
+			expectAutoscalerAnnotations: map[string]string{
+				autoscalerMinAnnotation: "0",
+				autoscalerMaxAnnotation: "0",
+			},
+		},
+		{
+			name: "it resumes an autoscaled NodePool with its minimum replicas when it is no longer hibernating",
+			nodePool: &hyperv1.NodePool{
+				ObjectMeta: metav1.ObjectMeta{},
+				Spec: hyperv1.NodePoolSpec{
+					AutoScaling: &hyperv1.NodePoolAutoScaling{
+						Min: 3,
+						Max: 5,
+					},
+					PowerState: hyperv1.NodePoolPowerStateRunning,
+				},
+			},
+			machineDeployment: &capiv1.MachineDeployment{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{
+						autoscalerMinAnnotation: "0",
+						autoscalerMaxAnnotation: "0",
+					},
+				},
+				Spec: capiv1.MachineDeploymentSpec{
+					Replicas: k8sutilspointer.Int32Ptr(0),
+				},
+			},
+			expectReplicas: 3,
+			expectAutoscalerAnnotations: map[string]string{
+				autoscalerMinAnnotation: "3",
+				autoscalerMaxAnnotation: "5",
+			},
+		},
+		{
+			name: "it removes the capacity of the nodes when autoscaling is disabled",
+			nodePool: &hyperv1.NodePool{
+				ObjectMeta: metav1.ObjectMeta{},
+				Spec: hyperv1.NodePoolSpec{
+					Replicas: k8sutilspointer.Int32Ptr(2),
+				},
+			},
+			machineDeployment: &capiv1.MachineDeployment{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{
+						autoscalerCapacityCPUAnnotation:    "8",
+						autoscalerCapacityMemoryAnnotation: "32Gi",
+						autoscalerCapacityLabelsAnnotation: "kubernetes.io/arch=amd64",
+					},
+				},
+			},
+			expectReplicas: 2,
+			expectAutoscalerAnnotations: map[string]string{
+				autoscalerMinAnnotation: "0",
+				autoscalerMaxAnnotation: "0",
+			},
+		},
 	}
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
 			g := NewWithT(t)
-			setMachineDeploymentReplicas(tc.nodePool, tc.machineDeployment)
+			setMachineDeploymentReplicas(tc.nodePool, tc.machineDeployment, 0, 1)
 			g.Expect(*tc.machineDeployment.Spec.Replicas).To(Equal(tc.expectReplicas))
 			g.Expect(tc.machineDeployment.Annotations).To(Equal(tc.expectAutoscalerAnnotations))
 		})
 	}
 }
 
+func TestSetMachineDeploymentReplicasAcrossSubnets(t *testing.T) {
+	g := NewWithT(t)
+	nodePool := &hyperv1.NodePool{
+		Spec: hyperv1.NodePoolSpec{
+			Replicas: k8sutilspointer.Int32Ptr(5),
+		},
+	}
+	var replicas []int32
+	for i := 0; i < 3; i++ {
+		md := &capiv1.MachineDeployment{}
+		setMachineDeploymentReplicas(nodePool, md, i, 3)
+		replicas = append(replicas, *md.Spec.Replicas)
+	}
+	g.Expect(replicas).To(Equal([]int32{2, 2, 1}))
+
+	nodePool = &hyperv1.NodePool{
+		Spec: hyperv1.NodePoolSpec{
+			AutoScaling: &hyperv1.NodePoolAutoScaling{
+				Min: 2,
+				Max: 7,
+			},
+		},
+	}
+	var annotations []map[string]string
+	replicas = nil
+	for i := 0; i < 3; i++ {
+		md := &capiv1.MachineDeployment{}
+		setMachineDeploymentReplicas(nodePool, md, i, 3)
+		replicas = append(replicas, *md.Spec.Replicas)
+		annotations = append(annotations, md.Annotations)
+	}
+	// The last MachineDeployment has no minimum share, so it scales from zero.
+	g.Expect(replicas).To(Equal([]int32{1, 1, 0}))
+	g.Expect(annotations).To(Equal([]map[string]string{
+		{autoscalerMinAnnotation: "1", autoscalerMaxAnnotation: "3"},
+		{autoscalerMinAnnotation: "1", autoscalerMaxAnnotation: "2"},
+		{autoscalerMinAnnotation: "0", autoscalerMaxAnnotation: "2"},
+	}))
+}
+
+func TestRollingUpdateShare(t *testing.T) {
+	intstrPtr := func(value intstr.IntOrString) *intstr.IntOrString {
+		return &value
+	}
+	testCases := []struct {
+		name          string
+		rollingUpdate *hyperv1.RollingUpdate
+		count         int
+		expected      []*capiv1.MachineRollingUpdateDeployment
+	}{
+		{
+			name: "it keeps the settings of a single MachineDeployment",
+			rollingUpdate: &hyperv1.RollingUpdate{
+				MaxUnavailable: intstrPtr(intstr.FromInt(0)),
+				MaxSurge:       intstrPtr(intstr.FromInt(5)),
+			},
+			count: 1,
+			expected: []*capiv1.MachineRollingUpdateDeployment{
+				{MaxUnavailable: intstrPtr(intstr.FromInt(0)), MaxSurge: intstrPtr(intstr.FromInt(5))},
+			},
+		},
+		{
+			name: "it divides absolute settings across MachineDeployments",
+			rollingUpdate: &hyperv1.RollingUpdate{
+				MaxUnavailable: intstrPtr(intstr.FromInt(4)),
+				MaxSurge:       intstrPtr(intstr.FromInt(5)),
+			},
+			count: 3,
+			expected: []*capiv1.MachineRollingUpdateDeployment{
+				{MaxUnavailable: intstrPtr(intstr.FromInt(2)), MaxSurge: intstrPtr(intstr.FromInt(2))},
+				{MaxUnavailable: intstrPtr(intstr.FromInt(1)), MaxSurge: intstrPtr(intstr.FromInt(2))},
+				{MaxUnavailable: intstrPtr(intstr.FromInt(1)), MaxSurge: intstrPtr(intstr.FromInt(1))},
+			},
+		},
+		{
+			name: "it keeps percentages and surges by one where the shares are zero",
+			rollingUpdate: &hyperv1.RollingUpdate{
+				MaxUnavailable: intstrPtr(intstr.FromInt(0)),
+				MaxSurge:       intstrPtr(intstr.FromInt(1)),
+			},
+			count: 2,
+			expected: []*capiv1.MachineRollingUpdateDeployment{
+				{MaxUnavailable: intstrPtr(intstr.FromInt(0)), MaxSurge: intstrPtr(intstr.FromInt(1))},
+				{MaxUnavailable: intstrPtr(intstr.FromInt(0)), MaxSurge: intstrPtr(intstr.FromInt(1))},
+			},
+		},
+		{
+			name: "it keeps percentages as is",
+			rollingUpdate: &hyperv1.RollingUpdate{
+				MaxUnavailable: intstrPtr(intstr.FromString("25%")),
+				MaxSurge:       intstrPtr(intstr.FromInt(0)),
+			},
+			count: 2,
+			expected: []*capiv1.MachineRollingUpdateDeployment{
+				{MaxUnavailable: intstrPtr(intstr.FromString("25%")), MaxSurge: intstrPtr(intstr.FromInt(0))},
+				{MaxUnavailable: intstrPtr(intstr.FromString("25%")), MaxSurge: intstrPtr(intstr.FromInt(0))},
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+			var result []*capiv1.MachineRollingUpdateDeployment
+			for i := 0; i < tc.count; i++ {
+				result = append(result, rollingUpdateShare(tc.rollingUpdate, i, tc.count))
+			}
+			g.Expect(result).To(Equal(tc.expected))
+		})
+	}
+}
+
 func TestValidateManagement(t *testing.T) {
 	intstrPointer1 := intstr.FromInt(1)
+	intstrPercent := intstr.FromString("40%")
+	intstrBadPercent := intstr.FromString("forty")
+	intstrZero := intstr.FromInt(0)
+	intstrNegative := intstr.FromInt(-1)
 	testCases := []struct {
 		name     string
 		nodePool *hyperv1.NodePool
@@ -1031,57 +1356,344 @@ func TestValidateManagement(t *testing.T) {
 			error: false,
 		},
 		{
-			name: "it passes with Replace type and OnDelete strategy",
+			name: "it passes with Replace type, RollingUpdate strategy and percentage RollingUpdate settings",
 			nodePool: &hyperv1.NodePool{
 				ObjectMeta: metav1.ObjectMeta{},
 				Spec: hyperv1.NodePoolSpec{
 					Management: hyperv1.NodePoolManagement{
 						UpgradeType: hyperv1.UpgradeTypeReplace,
 						Replace: &hyperv1.ReplaceUpgrade{
-							Strategy: hyperv1.UpgradeStrategyOnDelete,
+							Strategy: hyperv1.UpgradeStrategyRollingUpdate,
+							RollingUpdate: &hyperv1.RollingUpdate{
+								MaxUnavailable: &intstrPercent,
+								MaxSurge:       &intstrZero,
+							},
 						},
 					},
 				},
 			},
 			error: false,
 		},
-	}
-
-	for _, tc := range testCases {
-		t.Run(tc.name, func(t *testing.T) {
-			g := NewWithT(t)
-			err := validateManagement(tc.nodePool)
-			if tc.error {
-				g.Expect(err).Should(HaveOccurred())
-				return
-			}
-			g.Expect(err).ShouldNot(HaveOccurred())
-		})
-	}
-}
-
-// It returns a expected machineTemplateSpecJSON
-// and a template and mutateTemplate able to produce an expected target template.
-func RunTestMachineTemplateBuilders(t *testing.T, preCreateMachineTemplate bool) {
-	g := NewWithT(t)
-	c := fake.NewClientBuilder().WithScheme(api.Scheme).WithObjects().Build()
-	r := &NodePoolReconciler{
-		Client:                 c,
-		CreateOrUpdateProvider: upsert.New(false),
-	}
-
-	infraID := "test"
-	ami := "test"
-	hcluster := &hyperv1.HostedCluster{
-		TypeMeta:   metav1.TypeMeta{},
-		ObjectMeta: metav1.ObjectMeta{},
-		Spec: hyperv1.HostedClusterSpec{
-			Release: hyperv1.Release{},
-			InfraID: infraID,
-			Platform: hyperv1.PlatformSpec{
-				Type: hyperv1.AWSPlatform,
-				AWS: &hyperv1.AWSPlatformSpec{
-					ResourceTags: nil,
+		{
+			name: "it fails with Replace type, RollingUpdate strategy and a bad maxSurge",
+			nodePool: &hyperv1.NodePool{
+				ObjectMeta: metav1.ObjectMeta{},
+				Spec: hyperv1.NodePoolSpec{
+					Management: hyperv1.NodePoolManagement{
+						UpgradeType: hyperv1.UpgradeTypeReplace,
+						Replace: &hyperv1.ReplaceUpgrade{
+							Strategy: hyperv1.UpgradeStrategyRollingUpdate,
+							RollingUpdate: &hyperv1.RollingUpdate{
+								MaxSurge: &intstrBadPercent,
+							},
+						},
+					},
+				},
+			},
+			error: true,
+		},
+		{
+			name: "it fails with Replace type, RollingUpdate strategy and a negative maxUnavailable",
+			nodePool: &hyperv1.NodePool{
+				ObjectMeta: metav1.ObjectMeta{},
+				Spec: hyperv1.NodePoolSpec{
+					Management: hyperv1.NodePoolManagement{
+						UpgradeType: hyperv1.UpgradeTypeReplace,
+						Replace: &hyperv1.ReplaceUpgrade{
+							Strategy: hyperv1.UpgradeStrategyRollingUpdate,
+							RollingUpdate: &hyperv1.RollingUpdate{
+								MaxUnavailable: &intstrNegative,
+								MaxSurge:       &intstrPointer1,
+							},
+						},
+					},
+				},
+			},
+			error: true,
+		},
+		{
+			name: "it fails with Replace type, RollingUpdate strategy and zero maxUnavailable and maxSurge",
+			nodePool: &hyperv1.NodePool{
+				ObjectMeta: metav1.ObjectMeta{},
+				Spec: hyperv1.NodePoolSpec{
+					Management: hyperv1.NodePoolManagement{
+						UpgradeType: hyperv1.UpgradeTypeReplace,
+						Replace: &hyperv1.ReplaceUpgrade{
+							Strategy: hyperv1.UpgradeStrategyRollingUpdate,
+							RollingUpdate: &hyperv1.RollingUpdate{
+								MaxUnavailable: &intstrZero,
+								MaxSurge:       &intstrZero,
+							},
+						},
+					},
+				},
+			},
+			error: true,
+		},
+		{
+			name: "it passes with Replace type and OnDelete strategy",
+			nodePool: &hyperv1.NodePool{
+				ObjectMeta: metav1.ObjectMeta{},
+				Spec: hyperv1.NodePoolSpec{
+					Management: hyperv1.NodePoolManagement{
+						UpgradeType: hyperv1.UpgradeTypeReplace,
+						Replace: &hyperv1.ReplaceUpgrade{
+							Strategy: hyperv1.UpgradeStrategyOnDelete,
+						},
+					},
+				},
+			},
+			error: false,
+		},
+		{
+			name: "it passes with InPlace type and maxUnavailable",
+			nodePool: &hyperv1.NodePool{
+				ObjectMeta: metav1.ObjectMeta{},
+				Spec: hyperv1.NodePoolSpec{
+					Management: hyperv1.NodePoolManagement{
+						UpgradeType: hyperv1.UpgradeTypeInPlace,
+						InPlace: &hyperv1.InPlaceUpgrade{
+							MaxUnavailable: &intstrPercent,
+						},
+					},
+				},
+			},
+			error: false,
+		},
+		{
+			name: "it fails with InPlace type and zero maxUnavailable",
+			nodePool: &hyperv1.NodePool{
+				ObjectMeta: metav1.ObjectMeta{},
+				Spec: hyperv1.NodePoolSpec{
+					Management: hyperv1.NodePoolManagement{
+						UpgradeType: hyperv1.UpgradeTypeInPlace,
+						InPlace: &hyperv1.InPlaceUpgrade{
+							MaxUnavailable: &intstrZero,
+						},
+					},
+				},
+			},
+			error: true,
+		},
+		{
+			name: "it fails with a bad autoRepairConfig maxUnhealthy",
+			nodePool: &hyperv1.NodePool{
+				ObjectMeta: metav1.ObjectMeta{},
+				Spec: hyperv1.NodePoolSpec{
+					Management: hyperv1.NodePoolManagement{
+						UpgradeType: hyperv1.UpgradeTypeReplace,
+						Replace: &hyperv1.ReplaceUpgrade{
+							Strategy: hyperv1.UpgradeStrategyOnDelete,
+						},
+						AutoRepairConfig: &hyperv1.AutoRepairConfig{
+							MaxUnhealthy: &intstrBadPercent,
+						},
+					},
+				},
+			},
+			error: true,
+		},
+		{
+			name: "it fails with a non positive autoRepairConfig timeout",
+			nodePool: &hyperv1.NodePool{
+				ObjectMeta: metav1.ObjectMeta{},
+				Spec: hyperv1.NodePoolSpec{
+					Management: hyperv1.NodePoolManagement{
+						UpgradeType: hyperv1.UpgradeTypeInPlace,
+						AutoRepairConfig: &hyperv1.AutoRepairConfig{
+							UnhealthyConditions: []hyperv1.UnhealthyCondition{
+								{
+									Type:   corev1.NodeReady,
+									Status: corev1.ConditionFalse,
+								},
+							},
+						},
+					},
+				},
+			},
+			error: true,
+		},
+		{
+			name: "it passes with a valid autoRepairConfig",
+			nodePool: &hyperv1.NodePool{
+				ObjectMeta: metav1.ObjectMeta{},
+				Spec: hyperv1.NodePoolSpec{
+					Management: hyperv1.NodePoolManagement{
+						UpgradeType: hyperv1.UpgradeTypeReplace,
+						Replace: &hyperv1.ReplaceUpgrade{
+							Strategy: hyperv1.UpgradeStrategyOnDelete,
+						},
+						AutoRepairConfig: &hyperv1.AutoRepairConfig{
+							MaxUnhealthy:       &intstrPercent,
+							NodeStartupTimeout: &metav1.Duration{Duration: 30 * time.Minute},
+							UnhealthyConditions: []hyperv1.UnhealthyCondition{
+								{
+									Type:    corev1.NodeReady,
+									Status:  corev1.ConditionFalse,
+									Timeout: metav1.Duration{Duration: 5 * time.Minute},
+								},
+							},
+						},
+					},
+				},
+			},
+			error: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+			err := validateManagement(tc.nodePool)
+			if tc.error {
+				g.Expect(err).Should(HaveOccurred())
+				return
+			}
+			g.Expect(err).ShouldNot(HaveOccurred())
+		})
+	}
+}
+
+func TestReconcileMachineHealthCheck(t *testing.T) {
+	defaultMaxUnhealthy := intstr.FromInt(2)
+	maxUnhealthy := intstr.FromString("40%")
+	testCases := []struct {
+		name     string
+		config   *hyperv1.AutoRepairConfig
+		expected capiv1.MachineHealthCheckSpec
+	}{
+		{
+			name: "it uses the defaults without autoRepairConfig",
+			expected: capiv1.MachineHealthCheckSpec{
+				UnhealthyConditions: []capiv1.UnhealthyCondition{
+					{
+						Type:    corev1.NodeReady,
+						Status:  corev1.ConditionFalse,
+						Timeout: metav1.Duration{Duration: 8 * time.Minute},
+					},
+					{
+						Type:    corev1.NodeReady,
+						Status:  corev1.ConditionUnknown,
+						Timeout: metav1.Duration{Duration: 8 * time.Minute},
+					},
+				},
+				MaxUnhealthy:       &defaultMaxUnhealthy,
+				NodeStartupTimeout: &metav1.Duration{Duration: 20 * time.Minute},
+			},
+		},
+		{
+			name: "it uses the autoRepairConfig values",
+			config: &hyperv1.AutoRepairConfig{
+				UnhealthyConditions: []hyperv1.UnhealthyCondition{
+					{
+						Type:    corev1.NodeReady,
+						Status:  corev1.ConditionUnknown,
+						Timeout: metav1.Duration{Duration: 3 * time.Minute},
+					},
+					{
+						Type:    corev1.NodeDiskPressure,
+						Status:  corev1.ConditionTrue,
+						Timeout: metav1.Duration{Duration: 10 * time.Minute},
+					},
+				},
+				NodeStartupTimeout: &metav1.Duration{Duration: 45 * time.Minute},
+				MaxUnhealthy:       &maxUnhealthy,
+			},
+			expected: capiv1.MachineHealthCheckSpec{
+				UnhealthyConditions: []capiv1.UnhealthyCondition{
+					{
+						Type:    corev1.NodeReady,
+						Status:  corev1.ConditionUnknown,
+						Timeout: metav1.Duration{Duration: 3 * time.Minute},
+					},
+					{
+						Type:    corev1.NodeDiskPressure,
+						Status:  corev1.ConditionTrue,
+						Timeout: metav1.Duration{Duration: 10 * time.Minute},
+					},
+				},
+				MaxUnhealthy:       &maxUnhealthy,
+				NodeStartupTimeout: &metav1.Duration{Duration: 45 * time.Minute},
+			},
+		},
+		{
+			name: "it keeps the defaults for unset autoRepairConfig fields",
+			config: &hyperv1.AutoRepairConfig{
+				MaxUnhealthy: &maxUnhealthy,
+			},
+			expected: capiv1.MachineHealthCheckSpec{
+				UnhealthyConditions: []capiv1.UnhealthyCondition{
+					{
+						Type:    corev1.NodeReady,
+						Status:  corev1.ConditionFalse,
+						Timeout: metav1.Duration{Duration: 8 * time.Minute},
+					},
+					{
+						Type:    corev1.NodeReady,
+						Status:  corev1.ConditionUnknown,
+						Timeout: metav1.Duration{Duration: 8 * time.Minute},
+					},
+				},
+				MaxUnhealthy:       &maxUnhealthy,
+				NodeStartupTimeout: &metav1.Duration{Duration: 20 * time.Minute},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+			nodePool := &hyperv1.NodePool{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "nodepool",
+				},
+				Spec: hyperv1.NodePoolSpec{
+					ClusterName: "cluster",
+					Management: hyperv1.NodePoolManagement{
+						AutoRepair:       true,
+						AutoRepairConfig: tc.config,
+					},
+				},
+			}
+			infraID := "infra"
+			resourcesName := generateName(infraID, nodePool.Spec.ClusterName, nodePool.Name)
+			tc.expected.ClusterName = infraID
+			tc.expected.Selector = metav1.LabelSelector{
+				MatchLabels: map[string]string{
+					resourcesName: resourcesName,
+				},
+			}
+
+			mhc := &capiv1.MachineHealthCheck{}
+			r := &NodePoolReconciler{}
+			g.Expect(r.reconcileMachineHealthCheck(mhc, nodePool, infraID)).To(Succeed())
+			g.Expect(mhc.Spec).To(Equal(tc.expected))
+		})
+	}
+}
+
+// It returns a expected machineTemplateSpecJSON
+// and a template and mutateTemplate able to produce an expected target template.
+func RunTestMachineTemplateBuilders(t *testing.T, preCreateMachineTemplate bool) {
+	g := NewWithT(t)
+	c := fake.NewClientBuilder().WithScheme(api.Scheme).WithObjects().Build()
+	r := &NodePoolReconciler{
+		Client:                 c,
+		CreateOrUpdateProvider: upsert.New(false),
+	}
+
+	infraID := "test"
+	ami := "test"
+	hcluster := &hyperv1.HostedCluster{
+		TypeMeta:   metav1.TypeMeta{},
+		ObjectMeta: metav1.ObjectMeta{},
+		Spec: hyperv1.HostedClusterSpec{
+			Release: hyperv1.Release{},
+			InfraID: infraID,
+			Platform: hyperv1.PlatformSpec{
+				Type: hyperv1.AWSPlatform,
+				AWS: &hyperv1.AWSPlatformSpec{
+					ResourceTags: nil,
 				},
 			},
 		},
@@ -1369,3 +1981,329 @@ func TestNodepoolDeletionDoesntRequireHCluster(t *testing.T) {
 		t.Errorf("expected to get NotFound after deleted nodePool was reconciled, got %v", err)
 	}
 }
+
+func TestIsConfigUpdatePendingApproval(t *testing.T) {
+	testCases := []struct {
+		name        string
+		policy      hyperv1.ConfigUpdatePolicy
+		annotations map[string]string
+		expect      bool
+	}{
+		{
+			name:   "it is not pending with an Automatic policy",
+			policy: hyperv1.ConfigUpdatePolicyAutomatic,
+			annotations: map[string]string{
+				nodePoolAnnotationCurrentConfig: "current",
+			},
+			expect: false,
+		},
+		{
+			name:   "it is not pending for a new NodePool",
+			policy: hyperv1.ConfigUpdatePolicyManual,
+			expect: false,
+		},
+		{
+			name:   "it is not pending without config drift",
+			policy: hyperv1.ConfigUpdatePolicyManual,
+			annotations: map[string]string{
+				nodePoolAnnotationCurrentConfig: "latest",
+			},
+			expect: false,
+		},
+		{
+			name:   "it is pending with config drift",
+			policy: hyperv1.ConfigUpdatePolicyManual,
+			annotations: map[string]string{
+				nodePoolAnnotationCurrentConfig: "current",
+			},
+			expect: true,
+		},
+		{
+			name:   "it is pending when another config is approved",
+			policy: hyperv1.ConfigUpdatePolicyManual,
+			annotations: map[string]string{
+				nodePoolAnnotationCurrentConfig: "current",
+				hyperv1.ApproveConfigAnnotation: "other",
+			},
+			expect: true,
+		},
+		{
+			name:   "it is not pending when the config is approved",
+			policy: hyperv1.ConfigUpdatePolicyManual,
+			annotations: map[string]string{
+				nodePoolAnnotationCurrentConfig: "current",
+				hyperv1.ApproveConfigAnnotation: "latest",
+			},
+			expect: false,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+			nodePool := &hyperv1.NodePool{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: tc.annotations,
+				},
+				Spec: hyperv1.NodePoolSpec{
+					Management: hyperv1.NodePoolManagement{
+						ConfigUpdatePolicy: tc.policy,
+					},
+				},
+			}
+			g.Expect(isConfigUpdatePendingApproval(nodePool, "latest")).To(Equal(tc.expect))
+		})
+	}
+}
+
+func TestGetCurrentConfig(t *testing.T) {
+	g := NewWithT(t)
+	nodePool := &hyperv1.NodePool{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "nodepool",
+			Namespace: "clusters",
+			Annotations: map[string]string{
+				nodePoolAnnotationCurrentConfigVersion: "configversion",
+			},
+		},
+	}
+	config := "current config"
+	compressedConfig, err := compress([]byte(config))
+	g.Expect(err).ToNot(HaveOccurred())
+	tokenSecret := TokenSecret("clusters-cluster", nodePool.Name, "configversion")
+	tokenSecret.Data = map[string][]byte{
+		TokenSecretConfigKey: compressedConfig,
+	}
+
+	r := &NodePoolReconciler{
+		Client: fake.NewClientBuilder().WithScheme(api.Scheme).WithObjects(tokenSecret).Build(),
+	}
+	currentConfig, err := r.getCurrentConfig(context.Background(), nodePool, "clusters-cluster")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(currentConfig).To(Equal(config))
+	g.Expect(hashStruct(currentConfig)).To(Equal(hashStruct(config)))
+}
+
+func TestOutOfDateNodes(t *testing.T) {
+	machines := []capiv1.Machine{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "up-to-date"},
+			Spec: capiv1.MachineSpec{
+				Bootstrap: capiv1.Bootstrap{DataSecretName: k8sutilspointer.String("latest")},
+			},
+			Status: capiv1.MachineStatus{
+				NodeRef: &corev1.ObjectReference{Name: "up-to-date-node"},
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "out-of-date"},
+			Spec: capiv1.MachineSpec{
+				Bootstrap: capiv1.Bootstrap{DataSecretName: k8sutilspointer.String("previous")},
+			},
+			Status: capiv1.MachineStatus{
+				NodeRef: &corev1.ObjectReference{Name: "out-of-date-node"},
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "no-node"},
+			Spec: capiv1.MachineSpec{
+				Bootstrap: capiv1.Bootstrap{DataSecretName: k8sutilspointer.String("previous")},
+			},
+		},
+	}
+
+	g := NewWithT(t)
+	g.Expect(outOfDateNodes(machines, "latest", false)).To(Equal([]string{"no-node", "out-of-date-node"}))
+	g.Expect(outOfDateNodes(machines, "latest", true)).To(Equal([]string{"no-node", "out-of-date-node", "up-to-date-node"}))
+}
+
+func TestSetNodesUpToDateCondition(t *testing.T) {
+	manyNodes := []string{"n01", "n02", "n03", "n04", "n05", "n06", "n07", "n08", "n09", "n10", "n11", "n12"}
+	testCases := []struct {
+		name            string
+		outOfDateNodes  []string
+		pendingApproval bool
+		expected        hyperv1.NodePoolCondition
+	}{
+		{
+			name: "all nodes are up to date",
+			expected: hyperv1.NodePoolCondition{
+				Type:   hyperv1.NodePoolNodesUpToDateConditionType,
+				Status: corev1.ConditionTrue,
+				Reason: hyperv1.NodePoolAsExpectedConditionReason,
+			},
+		},
+		{
+			name:           "it lists the out of date nodes",
+			outOfDateNodes: []string{"a", "b"},
+			expected: hyperv1.NodePoolCondition{
+				Type:    hyperv1.NodePoolNodesUpToDateConditionType,
+				Status:  corev1.ConditionFalse,
+				Reason:  hyperv1.NodePoolNodesOutOfDateConditionReason,
+				Message: "Out of date nodes: a, b.",
+			},
+		},
+		{
+			name:           "it caps the listed nodes",
+			outOfDateNodes: manyNodes,
+			expected: hyperv1.NodePoolCondition{
+				Type:    hyperv1.NodePoolNodesUpToDateConditionType,
+				Status:  corev1.ConditionFalse,
+				Reason:  hyperv1.NodePoolNodesOutOfDateConditionReason,
+				Message: "Out of date nodes: n01, n02, n03, n04, n05, n06, n07, n08, n09, n10 and 2 more.",
+			},
+		},
+		{
+			name:            "it reports config updates pending approval",
+			outOfDateNodes:  []string{"a"},
+			pendingApproval: true,
+			expected: hyperv1.NodePoolCondition{
+				Type:    hyperv1.NodePoolNodesUpToDateConditionType,
+				Status:  corev1.ConditionFalse,
+				Reason:  hyperv1.NodePoolConfigUpdatePendingApprovalConditionReason,
+				Message: "Config update from current to latest is pending approval, set the hypershift.openshift.io/approve-config annotation to latest to roll it out. Out of date nodes: a.",
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+			nodePool := &hyperv1.NodePool{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{
+						nodePoolAnnotationCurrentConfig: "current",
+					},
+				},
+			}
+			setNodesUpToDateCondition(nodePool, tc.outOfDateNodes, "latest", tc.pendingApproval)
+			condition := findStatusCondition(nodePool.Status.Conditions, hyperv1.NodePoolNodesUpToDateConditionType)
+			g.Expect(condition).ToNot(BeNil())
+			condition.LastTransitionTime = metav1.Time{}
+			g.Expect(*condition).To(Equal(tc.expected))
+		})
+	}
+}
+
+func TestValidateNodeLabelsAndTaints(t *testing.T) {
+	testCases := []struct {
+		name        string
+		nodeLabels  map[string]string
+		taints      []hyperv1.Taint
+		expectError bool
+	}{
+		{
+			name:       "When labels and taints are valid it should not fail",
+			nodeLabels: map[string]string{"node-role.kubernetes.io/infra": ""},
+			taints: []hyperv1.Taint{
+				{Key: "dedicated", Value: "infra", Effect: corev1.TaintEffectNoSchedule},
+				{Key: "dedicated", Value: "infra", Effect: corev1.TaintEffectNoExecute},
+			},
+			expectError: false,
+		},
+		{
+			name:        "When a label key is invalid it should fail",
+			nodeLabels:  map[string]string{"-invalid": "value"},
+			expectError: true,
+		},
+		{
+			name:        "When a label value is invalid it should fail",
+			nodeLabels:  map[string]string{"role": "not valid"},
+			expectError: true,
+		},
+		{
+			name:        "When a taint key is invalid it should fail",
+			taints:      []hyperv1.Taint{{Key: "not valid", Effect: corev1.TaintEffectNoSchedule}},
+			expectError: true,
+		},
+		{
+			name: "When two taints have the same key and effect it should fail",
+			taints: []hyperv1.Taint{
+				{Key: "dedicated", Value: "infra", Effect: corev1.TaintEffectNoSchedule},
+				{Key: "dedicated", Value: "gpu", Effect: corev1.TaintEffectNoSchedule},
+			},
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+			nodePool := &hyperv1.NodePool{
+				Spec: hyperv1.NodePoolSpec{
+					NodeLabels: tc.nodeLabels,
+					Taints:     tc.taints,
+				},
+			}
+			err := validateNodeLabelsAndTaints(nodePool)
+			if tc.expectError {
+				g.Expect(err).To(HaveOccurred())
+				return
+			}
+			g.Expect(err).ToNot(HaveOccurred())
+		})
+	}
+}
+
+func TestSetNodeLabelsAndTaintsAnnotations(t *testing.T) {
+	g := NewWithT(t)
+	nodePool := &hyperv1.NodePool{
+		Spec: hyperv1.NodePoolSpec{
+			NodeLabels: map[string]string{"role": "infra"},
+			Taints:     []hyperv1.Taint{{Key: "dedicated", Value: "infra", Effect: corev1.TaintEffectNoSchedule}},
+		},
+	}
+	annotations := map[string]string{}
+
+	g.Expect(setNodeLabelsAndTaintsAnnotations(nodePool, annotations)).To(Succeed())
+	g.Expect(annotations).To(Equal(map[string]string{
+		nodePoolAnnotationNodeLabels: `{"role":"infra"}`,
+		nodePoolAnnotationTaints:     `[{"key":"dedicated","value":"infra","effect":"NoSchedule"}]`,
+	}))
+
+	nodePool.Spec.NodeLabels = nil
+	nodePool.Spec.Taints = nil
+	g.Expect(setNodeLabelsAndTaintsAnnotations(nodePool, annotations)).To(Succeed())
+	g.Expect(annotations).To(BeEmpty())
+}
+
+func TestSetHibernatingCondition(t *testing.T) {
+	testCases := []struct {
+		name           string
+		powerState     hyperv1.NodePoolPowerState
+		machines       int
+		expectedStatus corev1.ConditionStatus
+		expectedReason string
+	}{
+		{
+			name:           "When the NodePool is running it should not be hibernating",
+			powerState:     hyperv1.NodePoolPowerStateRunning,
+			machines:       3,
+			expectedStatus: corev1.ConditionFalse,
+			expectedReason: hyperv1.NodePoolAsExpectedConditionReason,
+		},
+		{
+			name:           "When the NodePool still has machines it should be hibernating in progress",
+			powerState:     hyperv1.NodePoolPowerStateHibernating,
+			machines:       2,
+			expectedStatus: corev1.ConditionFalse,
+			expectedReason: hyperv1.NodePoolHibernationInProgressConditionReason,
+		},
+		{
+			name:           "When the NodePool has no machines left it should be hibernating",
+			powerState:     hyperv1.NodePoolPowerStateHibernating,
+			machines:       0,
+			expectedStatus: corev1.ConditionTrue,
+			expectedReason: hyperv1.NodePoolAsExpectedConditionReason,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+			nodePool := &hyperv1.NodePool{Spec: hyperv1.NodePoolSpec{PowerState: tc.powerState}}
+			setHibernatingCondition(nodePool, tc.machines)
+			condition := findStatusCondition(nodePool.Status.Conditions, hyperv1.NodePoolHibernatingConditionType)
+			g.Expect(condition).ToNot(BeNil())
+			g.Expect(condition.Status).To(Equal(tc.expectedStatus))
+			g.Expect(condition.Reason).To(Equal(tc.expectedReason))
+		})
+	}
+}
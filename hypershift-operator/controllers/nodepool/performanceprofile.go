@@ -0,0 +1,137 @@
+package nodepool
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+
+	api "github.com/openshift/hypershift/api"
+	hyperv1 "github.com/openshift/hypershift/api/v1alpha1"
+	"github.com/openshift/hypershift/control-plane-operator/controllers/hostedcontrolplane/ignition"
+	mcfgv1 "github.com/openshift/hypershift/thirdparty/machineconfigoperator/pkg/apis/machineconfiguration.openshift.io/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+const (
+	staticCPUManagerPolicy = "static"
+	realtimeKernelType     = "realtime"
+)
+
+// validatePerformanceProfile does additional backend validation of the
+// PerformanceProfile of a NodePool that the API validation can't express.
+func validatePerformanceProfile(profile *hyperv1.NodePoolPerformanceProfile) error {
+	var errs []error
+	sizes := sets.NewString()
+	for _, hugePages := range profile.HugePages {
+		if sizes.Has(hugePages.Size) {
+			errs = append(errs, fmt.Errorf("hugePages: size %s is set more than once", hugePages.Size))
+		}
+		sizes.Insert(hugePages.Size)
+	}
+	if profile.DefaultHugePagesSize != "" && !sizes.Has(profile.DefaultHugePagesSize) {
+		errs = append(errs, fmt.Errorf("defaultHugePagesSize: size %s must be one of the sizes of hugePages", profile.DefaultHugePagesSize))
+	}
+
+	var reserved, isolated sets.Int
+	if profile.ReservedCPUs != "" {
+		var err error
+		if reserved, err = parseCPUSet(profile.ReservedCPUs); err != nil {
+			errs = append(errs, fmt.Errorf("reservedCPUs: %w", err))
+		}
+	}
+	if profile.IsolatedCPUs != "" {
+		var err error
+		if isolated, err = parseCPUSet(profile.IsolatedCPUs); err != nil {
+			errs = append(errs, fmt.Errorf("isolatedCPUs: %w", err))
+		}
+		if profile.ReservedCPUs == "" {
+			errs = append(errs, fmt.Errorf("isolatedCPUs: requires reservedCPUs"))
+		}
+	}
+	if overlap := reserved.Intersection(isolated); overlap.Len() > 0 {
+		errs = append(errs, fmt.Errorf("isolatedCPUs: CPUs %v are also reserved", overlap.List()))
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// parseCPUSet parses a set of CPUs in the Linux CPU list format, e.g. 0-3,8.
+func parseCPUSet(cpus string) (sets.Int, error) {
+	cpuSet := sets.NewInt()
+	for _, cpuRange := range strings.Split(cpus, ",") {
+		bounds := strings.SplitN(strings.TrimSpace(cpuRange), "-", 2)
+		first, err := strconv.Atoi(bounds[0])
+		if err != nil || first < 0 {
+			return nil, fmt.Errorf("invalid CPU list %q", cpus)
+		}
+		last := first
+		if len(bounds) == 2 {
+			last, err = strconv.Atoi(bounds[1])
+			if err != nil || last < first {
+				return nil, fmt.Errorf("invalid CPU list %q", cpus)
+			}
+		}
+		for cpu := first; cpu <= last; cpu++ {
+			cpuSet.Insert(cpu)
+		}
+	}
+	return cpuSet, nil
+}
+
+// performanceProfileKernelArguments returns the kernel arguments that allocate
+// the hugepages and isolate the CPUs of a PerformanceProfile.
+func performanceProfileKernelArguments(profile *hyperv1.NodePoolPerformanceProfile) []string {
+	var kernelArguments []string
+	if profile.DefaultHugePagesSize != "" {
+		kernelArguments = append(kernelArguments, "default_hugepagesz="+profile.DefaultHugePagesSize)
+	}
+	for _, hugePages := range profile.HugePages {
+		kernelArguments = append(kernelArguments,
+			"hugepagesz="+hugePages.Size,
+			fmt.Sprintf("hugepages=%d", hugePages.Count),
+		)
+	}
+	if profile.IsolatedCPUs != "" {
+		kernelArguments = append(kernelArguments,
+			"isolcpus=managed_irq,"+profile.IsolatedCPUs,
+			"nohz_full="+profile.IsolatedCPUs,
+			"rcu_nocbs="+profile.IsolatedCPUs,
+		)
+	}
+	return kernelArguments
+}
+
+// performanceProfileMachineConfig returns a serialized MachineConfig that boots
+// the nodes with the kernel arguments and kernel type of a PerformanceProfile,
+// or an empty string if the PerformanceProfile needs neither.
+func performanceProfileMachineConfig(profile *hyperv1.NodePoolPerformanceProfile) (string, error) {
+	if err := validatePerformanceProfile(profile); err != nil {
+		return "", fmt.Errorf("invalid performance profile: %w", err)
+	}
+	kernelArguments := performanceProfileKernelArguments(profile)
+	if len(kernelArguments) == 0 && !profile.RealtimeKernel {
+		return "", nil
+	}
+
+	machineConfig := &mcfgv1.MachineConfig{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "50-performance-profile",
+		},
+	}
+	machineConfig.APIVersion = mcfgv1.SchemeGroupVersion.String()
+	machineConfig.Kind = "MachineConfig"
+	ignition.SetMachineConfigLabels(machineConfig)
+	machineConfig.Spec.Config.Raw = []byte(`{"ignition":{"version":"3.2.0"}}`)
+	machineConfig.Spec.KernelArguments = kernelArguments
+	if profile.RealtimeKernel {
+		machineConfig.Spec.KernelType = realtimeKernelType
+	}
+
+	buf := &bytes.Buffer{}
+	if err := api.YamlSerializer.Encode(machineConfig, buf); err != nil {
+		return "", fmt.Errorf("failed to serialize performance profile machine config: %w", err)
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,138 @@
+package nodepool
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	hyperv1 "github.com/openshift/hypershift/api/v1alpha1"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+func TestValidatePerformanceProfile(t *testing.T) {
+	testCases := []struct {
+		name        string
+		profile     *hyperv1.NodePoolPerformanceProfile
+		expectError bool
+	}{
+		{
+			name: "When the performance profile is valid it should pass",
+			profile: &hyperv1.NodePoolPerformanceProfile{
+				HugePages:            []hyperv1.NodePoolHugePages{{Size: "1G", Count: 16}, {Size: "2M", Count: 1024}},
+				DefaultHugePagesSize: "1G",
+				ReservedCPUs:         "0-1",
+				IsolatedCPUs:         "2-15",
+				RealtimeKernel:       true,
+			},
+		},
+		{
+			name: "When a hugepages size is set more than once it should fail",
+			profile: &hyperv1.NodePoolPerformanceProfile{
+				HugePages: []hyperv1.NodePoolHugePages{{Size: "1G", Count: 16}, {Size: "1G", Count: 8}},
+			},
+			expectError: true,
+		},
+		{
+			name: "When the default hugepages size has no hugepages it should fail",
+			profile: &hyperv1.NodePoolPerformanceProfile{
+				HugePages:            []hyperv1.NodePoolHugePages{{Size: "2M", Count: 1024}},
+				DefaultHugePagesSize: "1G",
+			},
+			expectError: true,
+		},
+		{
+			name: "When the isolated CPUs are set without reserved CPUs it should fail",
+			profile: &hyperv1.NodePoolPerformanceProfile{
+				IsolatedCPUs: "2-15",
+			},
+			expectError: true,
+		},
+		{
+			name: "When the isolated CPUs overlap with the reserved CPUs it should fail",
+			profile: &hyperv1.NodePoolPerformanceProfile{
+				ReservedCPUs: "0-3",
+				IsolatedCPUs: "2-15",
+			},
+			expectError: true,
+		},
+		{
+			name: "When the reserved CPUs are not a CPU list it should fail",
+			profile: &hyperv1.NodePoolPerformanceProfile{
+				ReservedCPUs: "first",
+			},
+			expectError: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+			err := validatePerformanceProfile(tc.profile)
+			if tc.expectError {
+				g.Expect(err).To(HaveOccurred())
+				return
+			}
+			g.Expect(err).ToNot(HaveOccurred())
+		})
+	}
+}
+
+func TestParseCPUSet(t *testing.T) {
+	testCases := []struct {
+		cpus        string
+		expected    sets.Int
+		expectError bool
+	}{
+		{cpus: "0", expected: sets.NewInt(0)},
+		{cpus: "0-3,8", expected: sets.NewInt(0, 1, 2, 3, 8)},
+		{cpus: "2-3, 6-7", expected: sets.NewInt(2, 3, 6, 7)},
+		{cpus: "3-1", expectError: true},
+		{cpus: "0,", expectError: true},
+		{cpus: "-1", expectError: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.cpus, func(t *testing.T) {
+			g := NewWithT(t)
+			cpuSet, err := parseCPUSet(tc.cpus)
+			if tc.expectError {
+				g.Expect(err).To(HaveOccurred())
+				return
+			}
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(cpuSet).To(Equal(tc.expected))
+		})
+	}
+}
+
+func TestPerformanceProfileMachineConfig(t *testing.T) {
+	g := NewWithT(t)
+
+	profile := &hyperv1.NodePoolPerformanceProfile{
+		HugePages:            []hyperv1.NodePoolHugePages{{Size: "1G", Count: 16}},
+		DefaultHugePagesSize: "1G",
+		ReservedCPUs:         "0-1",
+		IsolatedCPUs:         "2-15",
+		RealtimeKernel:       true,
+	}
+	g.Expect(performanceProfileKernelArguments(profile)).To(Equal([]string{
+		"default_hugepagesz=1G",
+		"hugepagesz=1G",
+		"hugepages=16",
+		"isolcpus=managed_irq,2-15",
+		"nohz_full=2-15",
+		"rcu_nocbs=2-15",
+	}))
+
+	config, err := performanceProfileMachineConfig(profile)
+	g.Expect(err).ToNot(HaveOccurred())
+	manifest, err := defaultAndValidateConfigManifest([]byte(config))
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(string(manifest)).To(ContainSubstring("machineconfiguration.openshift.io/role: worker"))
+	g.Expect(string(manifest)).To(ContainSubstring("- hugepages=16"))
+	g.Expect(string(manifest)).To(ContainSubstring("kernelType: realtime"))
+
+	config, err = performanceProfileMachineConfig(&hyperv1.NodePoolPerformanceProfile{ReservedCPUs: "0-1"})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(config).To(BeEmpty())
+
+	_, err = performanceProfileMachineConfig(&hyperv1.NodePoolPerformanceProfile{IsolatedCPUs: "2-15"})
+	g.Expect(err).To(HaveOccurred())
+}
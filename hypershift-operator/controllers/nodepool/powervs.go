@@ -1,11 +1,16 @@
 package nodepool
 
 import (
+	"bytes"
 	"fmt"
+	"math"
 	"strconv"
 
+	api "github.com/openshift/hypershift/api"
 	hyperv1 "github.com/openshift/hypershift/api/v1alpha1"
+	"github.com/openshift/hypershift/control-plane-operator/controllers/hostedcontrolplane/ignition"
 	"github.com/openshift/hypershift/support/releaseinfo"
+	mcfgv1 "github.com/openshift/hypershift/thirdparty/machineconfigoperator/pkg/apis/machineconfiguration.openshift.io/v1"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	capipowervs "sigs.k8s.io/cluster-api-provider-ibmcloud/api/v1beta1"
@@ -14,8 +19,81 @@ import (
 
 const (
 	defaultCOSRegion = "us-south"
+
+	powerVSProcessorTypeDedicated = "dedicated"
+	powerVSMinMemoryGiB           = 32
 )
 
+// powerVSSystemTypeLimits are the maximum processors and memory in GiB of a
+// virtual machine on the system types they are known for.
+var powerVSSystemTypeLimits = map[string]struct {
+	processors float64
+	memoryGiB  int32
+}{
+	"s922": {processors: 15, memoryGiB: 942},
+	"e880": {processors: 143, memoryGiB: 7463},
+	"e980": {processors: 143, memoryGiB: 15307},
+}
+
+// powerVSPlatformValidation validates the compute options of the NodePool
+// against its processor type and system type. Limits are only enforced for the
+// system types they are known for.
+func powerVSPlatformValidation(nodePool *hyperv1.NodePool) error {
+	platform := nodePool.Spec.Platform.PowerVS
+	if platform == nil {
+		return fmt.Errorf("nodepool.spec.platform.powervs is required")
+	}
+
+	processors, err := strconv.ParseFloat(platform.Processors.String(), 64)
+	if err != nil {
+		return fmt.Errorf("the powervs processors %q must be a number: %w", platform.Processors.String(), err)
+	}
+	if platform.ProcessorType == powerVSProcessorTypeDedicated {
+		if processors < 1 || processors != math.Trunc(processors) {
+			return fmt.Errorf("the powervs processors must be a whole number of at least 1 for processor type %q, got %v", platform.ProcessorType, processors)
+		}
+	} else {
+		if processors < 0.5 || processors*4 != math.Trunc(processors*4) {
+			return fmt.Errorf("the powervs processors must be at least 0.5 in increments of 0.25 for processor type %q, got %v", platform.ProcessorType, processors)
+		}
+	}
+
+	if platform.MemoryGiB < powerVSMinMemoryGiB {
+		return fmt.Errorf("the powervs memoryGiB must be at least %d, got %d", powerVSMinMemoryGiB, platform.MemoryGiB)
+	}
+
+	if limits, ok := powerVSSystemTypeLimits[platform.SystemType]; ok {
+		if processors > limits.processors {
+			return fmt.Errorf("the powervs processors must be at most %v for system type %q, got %v", limits.processors, platform.SystemType, processors)
+		}
+		if platform.MemoryGiB > limits.memoryGiB {
+			return fmt.Errorf("the powervs memoryGiB must be at most %d for system type %q, got %d", limits.memoryGiB, platform.SystemType, platform.MemoryGiB)
+		}
+	}
+	return nil
+}
+
+// powerVSSMTMachineConfig returns a serialized MachineConfig that boots the
+// nodes with the given SMT level.
+func powerVSSMTMachineConfig(smtLevel int32) (string, error) {
+	machineConfig := &mcfgv1.MachineConfig{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "50-powervs-smt",
+		},
+	}
+	machineConfig.APIVersion = mcfgv1.SchemeGroupVersion.String()
+	machineConfig.Kind = "MachineConfig"
+	ignition.SetMachineConfigLabels(machineConfig)
+	machineConfig.Spec.Config.Raw = []byte(`{"ignition":{"version":"3.2.0"}}`)
+	machineConfig.Spec.KernelArguments = []string{fmt.Sprintf("smt-enabled=%d", smtLevel)}
+
+	buf := &bytes.Buffer{}
+	if err := api.YamlSerializer.Encode(machineConfig, buf); err != nil {
+		return "", fmt.Errorf("failed to serialize powervs smt machine config: %w", err)
+	}
+	return buf.String(), nil
+}
+
 // getImageRegion returns the nearest region os IBM COS bucket for the RHCOS images
 func getImageRegion(region string) string {
 	switch region {
@@ -0,0 +1,134 @@
+package nodepool
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	hyperv1 "github.com/openshift/hypershift/api/v1alpha1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func TestPowerVSPlatformValidation(t *testing.T) {
+	testCases := []struct {
+		name          string
+		systemType    string
+		processorType string
+		processors    intstr.IntOrString
+		memoryGiB     int32
+		expectErr     bool
+	}{
+		{
+			name:          "when shared processors are fractional it should pass",
+			systemType:    "s922",
+			processorType: "shared",
+			processors:    intstr.FromString("0.75"),
+			memoryGiB:     32,
+		},
+		{
+			name:          "when dedicated processors are whole it should pass",
+			systemType:    "e980",
+			processorType: "dedicated",
+			processors:    intstr.FromInt(4),
+			memoryGiB:     128,
+		},
+		{
+			name:          "when the system type has no known limits it should pass",
+			systemType:    "s1022",
+			processorType: "capped",
+			processors:    intstr.FromInt(200),
+			memoryGiB:     20000,
+		},
+		{
+			name:          "when dedicated processors are fractional it should fail",
+			systemType:    "s922",
+			processorType: "dedicated",
+			processors:    intstr.FromString("1.5"),
+			memoryGiB:     32,
+			expectErr:     true,
+		},
+		{
+			name:          "when shared processors are not in increments of 0.25 it should fail",
+			systemType:    "s922",
+			processorType: "shared",
+			processors:    intstr.FromString("0.6"),
+			memoryGiB:     32,
+			expectErr:     true,
+		},
+		{
+			name:          "when shared processors are below the minimum it should fail",
+			systemType:    "s922",
+			processorType: "shared",
+			processors:    intstr.FromString("0.25"),
+			memoryGiB:     32,
+			expectErr:     true,
+		},
+		{
+			name:          "when processors are not a number it should fail",
+			systemType:    "s922",
+			processorType: "shared",
+			processors:    intstr.FromString("many"),
+			memoryGiB:     32,
+			expectErr:     true,
+		},
+		{
+			name:          "when processors exceed the system type maximum it should fail",
+			systemType:    "s922",
+			processorType: "dedicated",
+			processors:    intstr.FromInt(16),
+			memoryGiB:     32,
+			expectErr:     true,
+		},
+		{
+			name:          "when memory exceeds the system type maximum it should fail",
+			systemType:    "s922",
+			processorType: "shared",
+			processors:    intstr.FromString("0.5"),
+			memoryGiB:     1024,
+			expectErr:     true,
+		},
+		{
+			name:          "when memory is below the minimum it should fail",
+			systemType:    "s922",
+			processorType: "shared",
+			processors:    intstr.FromString("0.5"),
+			memoryGiB:     16,
+			expectErr:     true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+			nodePool := &hyperv1.NodePool{
+				Spec: hyperv1.NodePoolSpec{
+					Platform: hyperv1.NodePoolPlatform{
+						Type: hyperv1.PowerVSPlatform,
+						PowerVS: &hyperv1.PowerVSNodePoolPlatform{
+							SystemType:    tc.systemType,
+							ProcessorType: tc.processorType,
+							Processors:    tc.processors,
+							MemoryGiB:     tc.memoryGiB,
+						},
+					},
+				},
+			}
+			err := powerVSPlatformValidation(nodePool)
+			if tc.expectErr {
+				g.Expect(err).To(HaveOccurred())
+			} else {
+				g.Expect(err).ToNot(HaveOccurred())
+			}
+		})
+	}
+}
+
+func TestPowerVSSMTMachineConfig(t *testing.T) {
+	g := NewWithT(t)
+
+	config, err := powerVSSMTMachineConfig(4)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	manifest, err := defaultAndValidateConfigManifest([]byte(config))
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(string(manifest)).To(ContainSubstring("smt-enabled=4"))
+	g.Expect(string(manifest)).To(ContainSubstring("machineconfiguration.openshift.io/role: worker"))
+}
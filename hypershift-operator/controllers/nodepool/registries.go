@@ -0,0 +1,137 @@
+package nodepool
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+
+	ignitionapi "github.com/coreos/ignition/v2/config/v3_2/types"
+	operatorv1alpha1 "github.com/openshift/api/operator/v1alpha1"
+	api "github.com/openshift/hypershift/api"
+	hyperv1 "github.com/openshift/hypershift/api/v1alpha1"
+	"github.com/openshift/hypershift/control-plane-operator/controllers/hostedcontrolplane/ignition"
+	mcfgv1 "github.com/openshift/hypershift/thirdparty/machineconfigoperator/pkg/apis/machineconfiguration.openshift.io/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	registryMirrorsPolicyName = "nodepool-registry-mirrors"
+	kubeletPullSecretPath     = "/var/lib/kubelet/config.json"
+)
+
+// registryMirrorsImageContentSourcePolicy returns a serialized
+// ImageContentSourcePolicy with the RegistryMirrors of a NodePool.
+func registryMirrorsImageContentSourcePolicy(mirrors []hyperv1.ImageContentSource) (string, error) {
+	icsp := &operatorv1alpha1.ImageContentSourcePolicy{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "ImageContentSourcePolicy",
+			APIVersion: operatorv1alpha1.GroupVersion.String(),
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name: registryMirrorsPolicyName,
+			Labels: map[string]string{
+				"machineconfiguration.openshift.io/role": "worker",
+			},
+		},
+	}
+	for _, mirror := range mirrors {
+		if len(mirror.Mirrors) == 0 {
+			return "", fmt.Errorf("registry mirrors for %q must not be empty", mirror.Source)
+		}
+		icsp.Spec.RepositoryDigestMirrors = append(icsp.Spec.RepositoryDigestMirrors, operatorv1alpha1.RepositoryDigestMirrors{
+			Source:  mirror.Source,
+			Mirrors: mirror.Mirrors,
+		})
+	}
+
+	buf := &bytes.Buffer{}
+	if err := api.YamlSerializer.Encode(icsp, buf); err != nil {
+		return "", fmt.Errorf("failed to serialize registry mirrors image content source policy: %w", err)
+	}
+	return buf.String(), nil
+}
+
+// dockerConfigJSON is the content of a .dockerconfigjson pull secret.
+type dockerConfigJSON struct {
+	Auths map[string]json.RawMessage `json:"auths"`
+}
+
+// mergePullSecrets returns the pull secret with the registry credentials of the
+// supplemental pull secret, which replace the ones for the same registries.
+func mergePullSecrets(pullSecret, supplementalPullSecret []byte) ([]byte, error) {
+	merged := dockerConfigJSON{}
+	if err := json.Unmarshal(pullSecret, &merged); err != nil {
+		return nil, fmt.Errorf("failed to parse pull secret: %w", err)
+	}
+	supplemental := dockerConfigJSON{}
+	if err := json.Unmarshal(supplementalPullSecret, &supplemental); err != nil {
+		return nil, fmt.Errorf("failed to parse supplemental pull secret: %w", err)
+	}
+	if merged.Auths == nil {
+		merged.Auths = make(map[string]json.RawMessage, len(supplemental.Auths))
+	}
+	for registry, auth := range supplemental.Auths {
+		merged.Auths[registry] = auth
+	}
+	return json.Marshal(merged)
+}
+
+// pullSecretMachineConfig returns a serialized MachineConfig that writes the
+// pull secret the kubelet and CRI-O pull images with. It is named to override
+// the pull secret written by the MachineConfigs of the release.
+func pullSecretMachineConfig(pullSecret []byte) (string, error) {
+	config := &ignitionapi.Config{}
+	config.Ignition.Version = ignitionapi.MaxVersion.String()
+	config.Storage.Files = []ignitionapi.File{
+		fileFromBytes(kubeletPullSecretPath, 0600, pullSecret),
+	}
+	serializedConfig, err := json.Marshal(config)
+	if err != nil {
+		return "", fmt.Errorf("failed to serialize pull secret ignition config: %w", err)
+	}
+
+	machineConfig := &mcfgv1.MachineConfig{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "99-nodepool-pull-secret",
+		},
+	}
+	machineConfig.APIVersion = mcfgv1.SchemeGroupVersion.String()
+	machineConfig.Kind = "MachineConfig"
+	ignition.SetMachineConfigLabels(machineConfig)
+	machineConfig.Spec.Config.Raw = serializedConfig
+
+	buf := &bytes.Buffer{}
+	if err := api.YamlSerializer.Encode(machineConfig, buf); err != nil {
+		return "", fmt.Errorf("failed to serialize pull secret machine config: %w", err)
+	}
+	return buf.String(), nil
+}
+
+// getPullSecretConfig returns a serialized MachineConfig with the pull secret of
+// the HostedCluster merged with the PullSecret of the NodePool.
+func (r *NodePoolReconciler) getPullSecretConfig(ctx context.Context, nodePool *hyperv1.NodePool, hcluster *hyperv1.HostedCluster) (string, error) {
+	pullSecret := &corev1.Secret{}
+	if err := r.Get(ctx, client.ObjectKey{Namespace: hcluster.Namespace, Name: hcluster.Spec.PullSecret.Name}, pullSecret); err != nil {
+		return "", fmt.Errorf("cannot get pull secret %s/%s: %w", hcluster.Namespace, hcluster.Spec.PullSecret.Name, err)
+	}
+	if _, hasKey := pullSecret.Data[corev1.DockerConfigJsonKey]; !hasKey {
+		return "", fmt.Errorf("pull secret %s/%s missing %q key", pullSecret.Namespace, pullSecret.Name, corev1.DockerConfigJsonKey)
+	}
+
+	supplementalPullSecret := &corev1.Secret{}
+	if err := r.Get(ctx, client.ObjectKey{Namespace: nodePool.Namespace, Name: nodePool.Spec.PullSecret.Name}, supplementalPullSecret); err != nil {
+		return "", fmt.Errorf("cannot get NodePool pull secret %s/%s: %w", nodePool.Namespace, nodePool.Spec.PullSecret.Name, err)
+	}
+	if _, hasKey := supplementalPullSecret.Data[corev1.DockerConfigJsonKey]; !hasKey {
+		return "", fmt.Errorf("NodePool pull secret %s/%s missing %q key", supplementalPullSecret.Namespace, supplementalPullSecret.Name, corev1.DockerConfigJsonKey)
+	}
+
+	merged, err := mergePullSecrets(pullSecret.Data[corev1.DockerConfigJsonKey], supplementalPullSecret.Data[corev1.DockerConfigJsonKey])
+	if err != nil {
+		return "", err
+	}
+	return pullSecretMachineConfig(merged)
+}
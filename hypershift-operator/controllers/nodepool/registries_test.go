@@ -0,0 +1,177 @@
+package nodepool
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	api "github.com/openshift/hypershift/api"
+	hyperv1 "github.com/openshift/hypershift/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestRegistryMirrorsImageContentSourcePolicy(t *testing.T) {
+	g := NewWithT(t)
+
+	config, err := registryMirrorsImageContentSourcePolicy([]hyperv1.ImageContentSource{
+		{
+			Source:  "quay.io/openshift-release-dev/ocp-release",
+			Mirrors: []string{"mirror.site-a.example.com/ocp-release"},
+		},
+	})
+	g.Expect(err).ToNot(HaveOccurred())
+
+	manifest, err := defaultAndValidateConfigManifest([]byte(config))
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(string(manifest)).To(ContainSubstring("kind: ImageContentSourcePolicy"))
+	g.Expect(string(manifest)).To(ContainSubstring("machineconfiguration.openshift.io/role: worker"))
+	g.Expect(string(manifest)).To(ContainSubstring("source: quay.io/openshift-release-dev/ocp-release"))
+	g.Expect(string(manifest)).To(ContainSubstring("- mirror.site-a.example.com/ocp-release"))
+
+	_, err = registryMirrorsImageContentSourcePolicy([]hyperv1.ImageContentSource{
+		{
+			Source: "quay.io/openshift-release-dev/ocp-release",
+		},
+	})
+	g.Expect(err).To(HaveOccurred())
+}
+
+func TestMergePullSecrets(t *testing.T) {
+	testCases := []struct {
+		name                   string
+		pullSecret             string
+		supplementalPullSecret string
+		expected               string
+		expectError            bool
+	}{
+		{
+			name:                   "When the supplemental pull secret has other registries it should add them",
+			pullSecret:             `{"auths":{"quay.io":{"auth":"cXVheQ=="}}}`,
+			supplementalPullSecret: `{"auths":{"mirror.example.com":{"auth":"bWlycm9y"}}}`,
+			expected:               `{"auths":{"mirror.example.com":{"auth":"bWlycm9y"},"quay.io":{"auth":"cXVheQ=="}}}`,
+		},
+		{
+			name:                   "When the supplemental pull secret has the same registries it should replace them",
+			pullSecret:             `{"auths":{"quay.io":{"auth":"cXVheQ=="}}}`,
+			supplementalPullSecret: `{"auths":{"quay.io":{"auth":"b3RoZXI="}}}`,
+			expected:               `{"auths":{"quay.io":{"auth":"b3RoZXI="}}}`,
+		},
+		{
+			name:                   "When the pull secret has no registries it should use the supplemental ones",
+			pullSecret:             `{}`,
+			supplementalPullSecret: `{"auths":{"mirror.example.com":{"auth":"bWlycm9y"}}}`,
+			expected:               `{"auths":{"mirror.example.com":{"auth":"bWlycm9y"}}}`,
+		},
+		{
+			name:                   "When the supplemental pull secret is not valid JSON it should fail",
+			pullSecret:             `{"auths":{"quay.io":{"auth":"cXVheQ=="}}}`,
+			supplementalPullSecret: `auths`,
+			expectError:            true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+			merged, err := mergePullSecrets([]byte(tc.pullSecret), []byte(tc.supplementalPullSecret))
+			if tc.expectError {
+				g.Expect(err).To(HaveOccurred())
+				return
+			}
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(string(merged)).To(Equal(tc.expected))
+		})
+	}
+}
+
+func TestGetPullSecretConfig(t *testing.T) {
+	g := NewWithT(t)
+
+	hcluster := &hyperv1.HostedCluster{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "clusters", Name: "example"},
+		Spec: hyperv1.HostedClusterSpec{
+			PullSecret: corev1.LocalObjectReference{Name: "pull-secret"},
+		},
+	}
+	nodePool := &hyperv1.NodePool{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "clusters", Name: "site-a"},
+		Spec: hyperv1.NodePoolSpec{
+			PullSecret: &corev1.LocalObjectReference{Name: "site-a-pull-secret"},
+		},
+	}
+	r := NodePoolReconciler{
+		Client: fake.NewClientBuilder().WithObjects(
+			&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "clusters", Name: "pull-secret"},
+				Data: map[string][]byte{
+					corev1.DockerConfigJsonKey: []byte(`{"auths":{"quay.io":{"auth":"cXVheQ=="}}}`),
+				},
+			},
+			&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "clusters", Name: "site-a-pull-secret"},
+				Data: map[string][]byte{
+					corev1.DockerConfigJsonKey: []byte(`{"auths":{"mirror.site-a.example.com":{"auth":"bWlycm9y"}}}`),
+				},
+			},
+		).Build(),
+	}
+
+	config, err := r.getPullSecretConfig(context.Background(), nodePool, hcluster)
+	g.Expect(err).ToNot(HaveOccurred())
+	manifest, err := defaultAndValidateConfigManifest([]byte(config))
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(string(manifest)).To(ContainSubstring("name: 99-nodepool-pull-secret"))
+	g.Expect(string(manifest)).To(ContainSubstring("path: " + kubeletPullSecretPath))
+	g.Expect(string(manifest)).To(ContainSubstring("mode: 384"))
+
+	nodePool.Spec.PullSecret.Name = "missing"
+	_, err = r.getPullSecretConfig(context.Background(), nodePool, hcluster)
+	g.Expect(err).To(HaveOccurred())
+}
+
+func TestEnqueueNodePoolsForSecret(t *testing.T) {
+	g := NewWithT(t)
+
+	r := NodePoolReconciler{
+		Client: fake.NewClientBuilder().WithScheme(api.Scheme).WithObjects(
+			&hyperv1.NodePool{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "clusters", Name: "site-a"},
+				Spec: hyperv1.NodePoolSpec{
+					ClusterName: "example",
+					PullSecret:  &corev1.LocalObjectReference{Name: "site-a-pull-secret"},
+				},
+			},
+			&hyperv1.NodePool{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "clusters", Name: "default"},
+				Spec: hyperv1.NodePoolSpec{
+					ClusterName: "example",
+				},
+			},
+			&hyperv1.HostedCluster{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "clusters", Name: "example"},
+				Spec: hyperv1.HostedClusterSpec{
+					PullSecret: corev1.LocalObjectReference{Name: "pull-secret"},
+				},
+			},
+		).Build(),
+	}
+
+	requests := r.enqueueNodePoolsForSecret(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "clusters", Name: "site-a-pull-secret"},
+	})
+	g.Expect(requests).To(HaveLen(1))
+	g.Expect(requests[0].Name).To(Equal("site-a"))
+
+	// Only the NodePools with a pull secret use the HostedCluster pull secret in their config.
+	requests = r.enqueueNodePoolsForSecret(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "clusters", Name: "pull-secret"},
+	})
+	g.Expect(requests).To(HaveLen(1))
+	g.Expect(requests[0].Name).To(Equal("site-a"))
+
+	requests = r.enqueueNodePoolsForSecret(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "clusters", Name: "other"},
+	})
+	g.Expect(requests).To(BeEmpty())
+}
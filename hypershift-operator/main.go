@@ -88,7 +88,10 @@ type StartOptions struct {
 	OIDCStorageProviderS3BucketName  string
 	OIDCStorageProviderS3Region      string
 	OIDCStorageProviderS3Credentials string
+	OIDCStorageProviderS3RoleARN     string
+	OIDCStorageProviderS3TokenFile   string
 	EnableUWMTelemetryRemoteWrite    bool
+	NoneEndpointsWebhookURL          string
 }
 
 func NewStartCommand() *cobra.Command {
@@ -125,7 +128,10 @@ func NewStartCommand() *cobra.Command {
 	cmd.Flags().StringVar(&opts.PrivatePlatform, "private-platform", opts.PrivatePlatform, "Platform on which private clusters are supported by this operator (supports \"AWS\" or \"None\")")
 	cmd.Flags().StringVar(&opts.OIDCStorageProviderS3BucketName, "oidc-storage-provider-s3-bucket-name", "", "Name of the bucket in which to store the clusters OIDC discovery information. Required for AWS guest clusters")
 	cmd.Flags().StringVar(&opts.OIDCStorageProviderS3Region, "oidc-storage-provider-s3-region", opts.OIDCStorageProviderS3Region, "Region in which the OIDC bucket is located. Required for AWS guest clusters")
-	cmd.Flags().StringVar(&opts.OIDCStorageProviderS3Credentials, "oidc-storage-provider-s3-credentials", opts.OIDCStorageProviderS3Credentials, "Location of the credentials file for the OIDC bucket. Required for AWS guest clusters.")
+	cmd.Flags().StringVar(&opts.OIDCStorageProviderS3Credentials, "oidc-storage-provider-s3-credentials", opts.OIDCStorageProviderS3Credentials, "Location of the credentials file for the OIDC bucket. Required for AWS guest clusters unless --oidc-storage-provider-s3-web-identity-token-file is set.")
+	cmd.Flags().StringVar(&opts.OIDCStorageProviderS3RoleARN, "oidc-storage-provider-s3-role-arn", opts.OIDCStorageProviderS3RoleARN, "ARN of the role to assume for the OIDC bucket with the token given by --oidc-storage-provider-s3-web-identity-token-file")
+	cmd.Flags().StringVar(&opts.OIDCStorageProviderS3TokenFile, "oidc-storage-provider-s3-web-identity-token-file", opts.OIDCStorageProviderS3TokenFile, "Location of a web identity token to assume the role given by --oidc-storage-provider-s3-role-arn with instead of using a credentials file. The token is read again whenever the temporary credentials are refreshed")
+	cmd.Flags().StringVar(&opts.NoneEndpointsWebhookURL, "none-platform-endpoints-webhook-url", opts.NoneEndpointsWebhookURL, "URL that the endpoints of None platform clusters are posted to as JSON whenever they change, so that external automation can set up their load balancers and DNS records")
 	cmd.Flags().BoolVar(&opts.EnableUWMTelemetryRemoteWrite, "enable-uwm-telemetry-remote-write", opts.EnableUWMTelemetryRemoteWrite, "If true, enables a controller that ensures user workload monitoring is enabled and that it is configured to remote write telemetry metrics from control planes")
 
 	cmd.Run = func(cmd *cobra.Command, args []string) {
@@ -226,8 +232,14 @@ func run(ctx context.Context, opts *StartOptions, log logr.Logger) error {
 		ImageMetadataProvider:      &util.RegistryClientImageMetadataProvider{},
 		MetricsSet:                 metricsSet,
 	}
+	if opts.NoneEndpointsWebhookURL != "" {
+		hostedClusterReconciler.EndpointsWebhook = hostedcluster.NewEndpointsWebhook(opts.NoneEndpointsWebhookURL)
+	}
 	if opts.OIDCStorageProviderS3BucketName != "" {
-		awsSession := awsutil.NewSession("hypershift-operator-oidc-bucket", opts.OIDCStorageProviderS3Credentials, "", "", opts.OIDCStorageProviderS3Region)
+		if (opts.OIDCStorageProviderS3TokenFile == "") != (opts.OIDCStorageProviderS3RoleARN == "") {
+			return fmt.Errorf("--oidc-storage-provider-s3-role-arn and --oidc-storage-provider-s3-web-identity-token-file must be specified together")
+		}
+		awsSession := awsutil.NewSessionWithWebIdentity("hypershift-operator-oidc-bucket", opts.OIDCStorageProviderS3Credentials, "", "", opts.OIDCStorageProviderS3TokenFile, opts.OIDCStorageProviderS3RoleARN, opts.OIDCStorageProviderS3Region)
 		awsConfig := awsutil.NewConfig()
 		s3Client := s3.New(awsSession, awsConfig)
 		hostedClusterReconciler.S3Client = s3Client
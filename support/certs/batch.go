@@ -0,0 +1,73 @@
+package certs
+
+import (
+	"crypto/rsa"
+	"crypto/x509"
+	"fmt"
+	"runtime"
+	"sort"
+	"sync"
+
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+)
+
+// KeyPair is a generated private key and its certificate.
+type KeyPair struct {
+	Key  *rsa.PrivateKey
+	Cert *x509.Certificate
+}
+
+// GenerateBatch generates a keypair for every config in cfgs concurrently, using
+// at most workers goroutines. If workers is not positive, runtime.NumCPU() is used.
+// Certificates are signed by the given CA, or self-signed if caKey is nil. The
+// result is keyed by the same names as cfgs and only contains successfully generated
+// keypairs. All failures are aggregated in the returned error.
+func GenerateBatch(caKey *rsa.PrivateKey, caCert *x509.Certificate, cfgs map[string]*CertCfg, workers int) (map[string]KeyPair, error) {
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	names := make([]string, 0, len(cfgs))
+	for name := range cfgs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var (
+		lock    sync.Mutex
+		wg      sync.WaitGroup
+		results = make(map[string]KeyPair, len(cfgs))
+		errs    []error
+		work    = make(chan string)
+	)
+	for i := 0; i < workers && i < len(names); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for name := range work {
+				var keyPair KeyPair
+				var err error
+				if caKey == nil {
+					keyPair.Key, keyPair.Cert, err = GenerateSelfSignedCertificate(cfgs[name])
+				} else {
+					keyPair.Key, keyPair.Cert, err = GenerateSignedCertificate(caKey, caCert, cfgs[name])
+				}
+
+				lock.Lock()
+				if err != nil {
+					errs = append(errs, fmt.Errorf("failed to generate keypair %s: %w", name, err))
+				} else {
+					results[name] = keyPair
+				}
+				lock.Unlock()
+			}
+		}()
+	}
+	for _, name := range names {
+		work <- name
+	}
+	close(work)
+	wg.Wait()
+
+	return results, utilerrors.NewAggregate(errs)
+}
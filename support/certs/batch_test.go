@@ -0,0 +1,52 @@
+package certs_test
+
+import (
+	"crypto/x509/pkix"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/openshift/hypershift/support/certs"
+)
+
+func TestGenerateBatch(t *testing.T) {
+	t.Parallel()
+
+	caCfg := certs.CertCfg{IsCA: true, Subject: pkix.Name{CommonName: "root-ca", OrganizationalUnit: []string{"ou"}}, Validity: time.Hour}
+	caKey, caCert, err := certs.GenerateSelfSignedCertificate(&caCfg)
+	if err != nil {
+		t.Fatalf("failed go generate CA: %v", err)
+	}
+
+	cfgs := map[string]*certs.CertCfg{}
+	for i := 0; i < 5; i++ {
+		name := fmt.Sprintf("cert-%d", i)
+		cfgs[name] = &certs.CertCfg{Subject: pkix.Name{CommonName: name, OrganizationalUnit: []string{"ou"}}, Validity: time.Hour}
+	}
+	// Self-signed generation enforces the subject policy, so this one fails.
+	cfgs["invalid"] = &certs.CertCfg{Validity: time.Hour}
+
+	results, err := certs.GenerateBatch(caKey, caCert, cfgs, 2)
+	if err != nil {
+		t.Fatalf("GenerateBatch failed: %v", err)
+	}
+	if len(results) != len(cfgs) {
+		t.Fatalf("expected %d results, got %d", len(cfgs), len(results))
+	}
+	for name, keyPair := range results {
+		if name != "invalid" && keyPair.Cert.Subject.CommonName != name {
+			t.Errorf("expected CN %s, got %s", name, keyPair.Cert.Subject.CommonName)
+		}
+		if err := keyPair.Cert.CheckSignatureFrom(caCert); err != nil {
+			t.Errorf("certificate %s is not signed by CA: %v", name, err)
+		}
+	}
+
+	results, err = certs.GenerateBatch(nil, nil, cfgs, 0)
+	if err == nil {
+		t.Error("expected an error for the invalid self-signed config")
+	}
+	if len(results) != len(cfgs)-1 {
+		t.Errorf("expected %d results, got %d", len(cfgs)-1, len(results))
+	}
+}
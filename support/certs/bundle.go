@@ -0,0 +1,58 @@
+package certs
+
+import (
+	"bytes"
+	"crypto/x509"
+	"time"
+)
+
+// MergeCABundles combines the given PEM encoded bundles into a single bundle.
+// Identical certificates are only included once, certificates that are expired
+// at the given time are dropped and non-certificate PEM blocks are ignored. The
+// order of first appearance is preserved so the output is stable across calls.
+func MergeCABundles(now time.Time, bundles ...[]byte) ([]byte, error) {
+	var certificates []*x509.Certificate
+	for _, bundle := range bundles {
+		parsed, err := ParseCertificateBundle(bundle)
+		if err != nil {
+			return nil, err
+		}
+		certificates = append(certificates, parsed...)
+	}
+
+	certificates = PruneExpiredCertificates(DeduplicateCertificates(certificates), now)
+
+	var out bytes.Buffer
+	for _, cert := range certificates {
+		out.Write(CertToPem(cert))
+	}
+	return out.Bytes(), nil
+}
+
+// DeduplicateCertificates returns the certificates with byte-identical duplicates
+// removed, preserving the order of first appearance.
+func DeduplicateCertificates(certificates []*x509.Certificate) []*x509.Certificate {
+	seen := make(map[string]struct{}, len(certificates))
+	var result []*x509.Certificate
+	for _, cert := range certificates {
+		if _, ok := seen[string(cert.Raw)]; ok {
+			continue
+		}
+		seen[string(cert.Raw)] = struct{}{}
+		result = append(result, cert)
+	}
+	return result
+}
+
+// PruneExpiredCertificates returns the certificates that are not yet expired at
+// the given time.
+func PruneExpiredCertificates(certificates []*x509.Certificate, now time.Time) []*x509.Certificate {
+	var result []*x509.Certificate
+	for _, cert := range certificates {
+		if now.After(cert.NotAfter) {
+			continue
+		}
+		result = append(result, cert)
+	}
+	return result
+}
@@ -0,0 +1,75 @@
+package certs_test
+
+import (
+	"bytes"
+	"crypto/x509/pkix"
+	"testing"
+	"time"
+
+	"github.com/openshift/hypershift/support/certs"
+)
+
+func TestMergeCABundles(t *testing.T) {
+	t.Parallel()
+
+	generate := func(cn string, validity time.Duration) []byte {
+		_, cert, err := certs.GenerateSelfSignedCertificate(&certs.CertCfg{IsCA: true, Subject: pkix.Name{CommonName: cn, OrganizationalUnit: []string{"ou"}}, Validity: validity})
+		if err != nil {
+			t.Fatalf("failed to generate certificate: %v", err)
+		}
+		return certs.CertToPem(cert)
+	}
+	first := generate("first", time.Hour)
+	second := generate("second", time.Hour)
+	expired := generate("expired", time.Minute)
+	key, _, _ := certs.GenerateSelfSignedCertificate(&certs.CertCfg{Subject: pkix.Name{CommonName: "key", OrganizationalUnit: []string{"ou"}}})
+
+	bundleA := append(append([]byte{}, first...), certs.PrivateKeyToPem(key)...)
+	bundleB := append(append(append([]byte{}, second...), first...), expired...)
+
+	merged, err := certs.MergeCABundles(time.Now().Add(10*time.Minute), bundleA, bundleB)
+	if err != nil {
+		t.Fatalf("MergeCABundles failed: %v", err)
+	}
+	expected := append(append([]byte{}, first...), second...)
+	if !bytes.Equal(merged, expected) {
+		t.Errorf("unexpected merged bundle:\n%s\nexpected:\n%s", merged, expected)
+	}
+}
+
+func TestParseBundles(t *testing.T) {
+	t.Parallel()
+
+	var data []byte
+	var expectedCNs []string
+	for _, cn := range []string{"a", "b", "c"} {
+		key, cert, err := certs.GenerateSelfSignedCertificate(&certs.CertCfg{Subject: pkix.Name{CommonName: cn, OrganizationalUnit: []string{"ou"}}, Validity: time.Hour})
+		if err != nil {
+			t.Fatalf("failed to generate certificate: %v", err)
+		}
+		data = append(data, certs.PrivateKeyToPem(key)...)
+		data = append(data, certs.CertToPem(cert)...)
+		expectedCNs = append(expectedCNs, cn)
+	}
+
+	certificates, err := certs.ParseCertificateBundle(data)
+	if err != nil {
+		t.Fatalf("ParseCertificateBundle failed: %v", err)
+	}
+	if len(certificates) != len(expectedCNs) {
+		t.Fatalf("expected %d certificates, got %d", len(expectedCNs), len(certificates))
+	}
+	for i, cert := range certificates {
+		if cert.Subject.CommonName != expectedCNs[i] {
+			t.Errorf("expected certificate %d to have CN %s, got %s", i, expectedCNs[i], cert.Subject.CommonName)
+		}
+	}
+
+	keys, err := certs.ParsePrivateKeys(data)
+	if err != nil {
+		t.Fatalf("ParsePrivateKeys failed: %v", err)
+	}
+	if len(keys) != len(expectedCNs) {
+		t.Errorf("expected %d keys, got %d", len(expectedCNs), len(keys))
+	}
+}
@@ -0,0 +1,192 @@
+package certs
+
+import (
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net"
+	"path"
+	"time"
+
+	"github.com/pkg/errors"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/utils/clock"
+)
+
+// CSRSigningPolicy restricts what SignCSR is willing to sign.
+type CSRSigningPolicy struct {
+	// AllowedDNSNames is a list of glob patterns (as understood by path.Match) that
+	// every DNS name in the CSR must match. If empty, no DNS names are allowed.
+	AllowedDNSNames []string
+	// AllowedIPNets is a list of networks that every IP address in the CSR must be
+	// contained in. If empty, no IP addresses are allowed.
+	AllowedIPNets []net.IPNet
+	// AllowedCommonNames is a list of glob patterns the subject CN must match. If
+	// empty, any CN is allowed.
+	AllowedCommonNames []string
+	// AllowedOrganizations is the set of organizations the subject may contain. If
+	// empty, any organization is allowed.
+	AllowedOrganizations []string
+	// KeyUsages are the key usages set on the issued certificate.
+	KeyUsages x509.KeyUsage
+	// ExtKeyUsages are the extended key usages set on the issued certificate.
+	ExtKeyUsages []x509.ExtKeyUsage
+	// Validity is the validity of the issued certificate. It is capped at the
+	// expiry of the CA.
+	Validity time.Duration
+	// MinimumKeySize is the minimum RSA modulus size in bits. Defaults to keySize.
+	MinimumKeySize int
+	// SignatureAlgorithm is the algorithm used to sign the issued certificate,
+	// like CertCfg.SignatureAlgorithm.
+	SignatureAlgorithm x509.SignatureAlgorithm
+	// DisableSHA1 ensures SHA-1 is not used anywhere, like CertCfg.DisableSHA1.
+	DisableSHA1 bool
+	// Clock is used to determine the validity period. Defaults to the real clock.
+	Clock clock.PassiveClock
+	// Rand is the source of randomness for serials and signatures. Defaults to
+	// crypto/rand.Reader.
+	Rand io.Reader
+}
+
+// SignCSR validates the PEM encoded certificate request against the policy and, if
+// it is compliant, returns a certificate signed by the given CA. The CA key never has
+// to leave the caller, which allows signing externally generated CSRs, e.g. kubelet
+// serving CSRs from hosted clusters.
+func SignCSR(caKey *rsa.PrivateKey, caCert *x509.Certificate, csrPEM []byte, policy *CSRSigningPolicy) (*x509.Certificate, error) {
+	if policy == nil {
+		return nil, errors.New("a signing policy is required")
+	}
+	csr, err := PemToCSR(csrPEM)
+	if err != nil {
+		return nil, err
+	}
+	if err := csr.CheckSignature(); err != nil {
+		return nil, errors.Wrap(err, "invalid certificate request signature")
+	}
+	if err := policy.validate(csr); err != nil {
+		return nil, errors.Wrap(err, "certificate request violates signing policy")
+	}
+
+	random := randReader(policy.Rand)
+	serial, err := randomSerial(random)
+	if err != nil {
+		return nil, err
+	}
+	certTmpl := x509.Certificate{
+		DNSNames:              csr.DNSNames,
+		ExtKeyUsage:           policy.ExtKeyUsages,
+		IPAddresses:           csr.IPAddresses,
+		KeyUsage:              policy.KeyUsages,
+		NotAfter:              clockNow(policy.Clock).Add(policy.Validity),
+		NotBefore:             caCert.NotBefore,
+		SerialNumber:          serial,
+		Subject:               csr.Subject,
+		SignatureAlgorithm:    policy.SignatureAlgorithm,
+		Version:               3,
+		BasicConstraintsValid: true,
+	}
+	// A certificate can't outlive the CA that signed it.
+	if certTmpl.NotAfter.After(caCert.NotAfter) {
+		certTmpl.NotAfter = caCert.NotAfter
+	}
+	certTmpl.SubjectKeyId, err = generateSubjectKeyID(csr.PublicKey, policy.DisableSHA1)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to set subject key identifier")
+	}
+	certBytes, err := x509.CreateCertificate(random, &certTmpl, caCert, csr.PublicKey, caKey)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create x509 certificate")
+	}
+	return x509.ParseCertificate(certBytes)
+}
+
+// PemToCSR converts a data block to x509.CertificateRequest.
+func PemToCSR(data []byte) (*x509.CertificateRequest, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.Errorf("could not find a PEM block in the certificate request")
+	}
+	return x509.ParseCertificateRequest(block.Bytes)
+}
+
+func (p *CSRSigningPolicy) validate(csr *x509.CertificateRequest) error {
+	var errs []error
+
+	if p.Validity <= 0 {
+		errs = append(errs, errors.New("policy validity must be positive"))
+	}
+	if err := validateSignatureAlgorithm(p.SignatureAlgorithm, p.DisableSHA1); err != nil {
+		errs = append(errs, err)
+	}
+
+	minimumKeySize := p.MinimumKeySize
+	if minimumKeySize == 0 {
+		minimumKeySize = keySize
+	}
+	switch pub := csr.PublicKey.(type) {
+	case *rsa.PublicKey:
+		if pub.N.BitLen() < minimumKeySize {
+			errs = append(errs, fmt.Errorf("key size %d is smaller than the minimum %d", pub.N.BitLen(), minimumKeySize))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("public key type %T is not supported", csr.PublicKey))
+	}
+
+	if len(p.AllowedCommonNames) > 0 && !matchesAny(csr.Subject.CommonName, p.AllowedCommonNames) {
+		errs = append(errs, fmt.Errorf("common name %q is not allowed", csr.Subject.CommonName))
+	}
+	if len(p.AllowedOrganizations) > 0 {
+		for _, org := range csr.Subject.Organization {
+			if !contains(p.AllowedOrganizations, org) {
+				errs = append(errs, fmt.Errorf("organization %q is not allowed", org))
+			}
+		}
+	}
+	for _, name := range csr.DNSNames {
+		if !matchesAny(name, p.AllowedDNSNames) {
+			errs = append(errs, fmt.Errorf("dns name %q is not allowed", name))
+		}
+	}
+	for _, ip := range csr.IPAddresses {
+		if !ipNetsContain(p.AllowedIPNets, ip) {
+			errs = append(errs, fmt.Errorf("ip address %s is not allowed", ip))
+		}
+	}
+	if len(csr.EmailAddresses) > 0 {
+		errs = append(errs, fmt.Errorf("email addresses are not allowed: %v", csr.EmailAddresses))
+	}
+	if len(csr.URIs) > 0 {
+		errs = append(errs, fmt.Errorf("uris are not allowed: %v", csr.URIs))
+	}
+
+	return utilerrors.NewAggregate(errs)
+}
+
+func matchesAny(value string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if matched, err := path.Match(pattern, value); err == nil && matched {
+			return true
+		}
+	}
+	return false
+}
+
+func ipNetsContain(nets []net.IPNet, ip net.IP) bool {
+	for _, n := range nets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,186 @@
+package certs_test
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/openshift/hypershift/support/certs"
+)
+
+func TestSignCSR(t *testing.T) {
+	t.Parallel()
+
+	caCfg := certs.CertCfg{IsCA: true, Subject: pkix.Name{CommonName: "root-ca", OrganizationalUnit: []string{"ou"}}, Validity: time.Hour}
+	caKey, caCert, err := certs.GenerateSelfSignedCertificate(&caCfg)
+	if err != nil {
+		t.Fatalf("failed go generate CA: %v", err)
+	}
+	key, err := certs.PrivateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	_, allowedNet, _ := net.ParseCIDR("10.0.0.0/16")
+	policy := &certs.CSRSigningPolicy{
+		AllowedDNSNames:      []string{"*.example.com"},
+		AllowedIPNets:        []net.IPNet{*allowedNet},
+		AllowedCommonNames:   []string{"system:node:*"},
+		AllowedOrganizations: []string{"system:nodes"},
+		KeyUsages:            x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsages:         []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		Validity:             time.Hour,
+	}
+
+	testCases := []struct {
+		name        string
+		csr         x509.CertificateRequest
+		expectError bool
+	}{
+		{
+			name: "Compliant",
+			csr: x509.CertificateRequest{
+				Subject:     pkix.Name{CommonName: "system:node:a", Organization: []string{"system:nodes"}},
+				DNSNames:    []string{"node.example.com"},
+				IPAddresses: []net.IP{net.ParseIP("10.0.1.1")},
+			},
+		},
+		{
+			name: "Disallowed DNS name",
+			csr: x509.CertificateRequest{
+				Subject:  pkix.Name{CommonName: "system:node:a", Organization: []string{"system:nodes"}},
+				DNSNames: []string{"kubernetes.default.svc"},
+			},
+			expectError: true,
+		},
+		{
+			name: "Disallowed IP",
+			csr: x509.CertificateRequest{
+				Subject:     pkix.Name{CommonName: "system:node:a", Organization: []string{"system:nodes"}},
+				IPAddresses: []net.IP{net.ParseIP("172.30.0.1")},
+			},
+			expectError: true,
+		},
+		{
+			name: "Disallowed organization",
+			csr: x509.CertificateRequest{
+				Subject: pkix.Name{CommonName: "system:node:a", Organization: []string{"system:masters"}},
+			},
+			expectError: true,
+		},
+		{
+			name: "Disallowed common name",
+			csr: x509.CertificateRequest{
+				Subject: pkix.Name{CommonName: "system:admin", Organization: []string{"system:nodes"}},
+			},
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			csrBytes, err := x509.CreateCertificateRequest(rand.Reader, &tc.csr, key)
+			if err != nil {
+				t.Fatalf("failed to create CSR: %v", err)
+			}
+			csr, err := x509.ParseCertificateRequest(csrBytes)
+			if err != nil {
+				t.Fatalf("failed to parse CSR: %v", err)
+			}
+
+			cert, err := certs.SignCSR(caKey, caCert, certs.CSRToPem(csr), policy)
+			if tc.expectError {
+				if err == nil {
+					t.Error("expected an error, got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("SignCSR failed: %v", err)
+			}
+			if err := cert.CheckSignatureFrom(caCert); err != nil {
+				t.Errorf("certificate is not signed by CA: %v", err)
+			}
+			if len(cert.ExtKeyUsage) != 1 || cert.ExtKeyUsage[0] != x509.ExtKeyUsageServerAuth {
+				t.Errorf("unexpected ext key usages: %v", cert.ExtKeyUsage)
+			}
+		})
+	}
+}
+
+func TestSignCSRValidityIsCappedAtCAExpiry(t *testing.T) {
+	t.Parallel()
+
+	caCfg := certs.CertCfg{IsCA: true, Subject: pkix.Name{CommonName: "root-ca", OrganizationalUnit: []string{"ou"}}, Validity: time.Hour}
+	caKey, caCert, err := certs.GenerateSelfSignedCertificate(&caCfg)
+	if err != nil {
+		t.Fatalf("failed go generate CA: %v", err)
+	}
+	key, err := certs.PrivateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	csrBytes, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{Subject: pkix.Name{CommonName: "system:node:a"}}, key)
+	if err != nil {
+		t.Fatalf("failed to create CSR: %v", err)
+	}
+	csr, err := x509.ParseCertificateRequest(csrBytes)
+	if err != nil {
+		t.Fatalf("failed to parse CSR: %v", err)
+	}
+
+	cert, err := certs.SignCSR(caKey, caCert, certs.CSRToPem(csr), &certs.CSRSigningPolicy{Validity: certs.ValidityOneYear})
+	if err != nil {
+		t.Fatalf("SignCSR failed: %v", err)
+	}
+	if !cert.NotAfter.Equal(caCert.NotAfter) {
+		t.Errorf("expected certificate to expire with the CA at %s, got %s", caCert.NotAfter, cert.NotAfter)
+	}
+}
+
+func TestSignCSRDisableSHA1(t *testing.T) {
+	t.Parallel()
+
+	caCfg := certs.CertCfg{IsCA: true, Subject: pkix.Name{CommonName: "root-ca", OrganizationalUnit: []string{"ou"}}, Validity: time.Hour}
+	caKey, caCert, err := certs.GenerateSelfSignedCertificate(&caCfg)
+	if err != nil {
+		t.Fatalf("failed go generate CA: %v", err)
+	}
+	key, err := certs.PrivateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	csrBytes, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{Subject: pkix.Name{CommonName: "system:node:a"}}, key)
+	if err != nil {
+		t.Fatalf("failed to create CSR: %v", err)
+	}
+	csr, err := x509.ParseCertificateRequest(csrBytes)
+	if err != nil {
+		t.Fatalf("failed to parse CSR: %v", err)
+	}
+
+	policy := &certs.CSRSigningPolicy{Validity: time.Hour, SignatureAlgorithm: x509.SHA384WithRSAPSS, DisableSHA1: true}
+	cert, err := certs.SignCSR(caKey, caCert, certs.CSRToPem(csr), policy)
+	if err != nil {
+		t.Fatalf("SignCSR failed: %v", err)
+	}
+	if cert.SignatureAlgorithm != x509.SHA384WithRSAPSS {
+		t.Errorf("expected signature algorithm %s, got %s", x509.SHA384WithRSAPSS, cert.SignatureAlgorithm)
+	}
+	expectedKeyID := sha256.Sum256(x509.MarshalPKCS1PublicKey(&key.PublicKey))
+	if !bytes.Equal(cert.SubjectKeyId, expectedKeyID[:20]) {
+		t.Errorf("expected the truncated SHA-256 subject key identifier %x, got %x", expectedKeyID[:20], cert.SubjectKeyId)
+	}
+
+	for _, algorithm := range []x509.SignatureAlgorithm{x509.SHA1WithRSA, x509.ECDSAWithSHA256} {
+		policy.SignatureAlgorithm = algorithm
+		if _, err := certs.SignCSR(caKey, caCert, certs.CSRToPem(csr), policy); err == nil {
+			t.Errorf("expected an error when requesting a %s signature", algorithm)
+		}
+	}
+}
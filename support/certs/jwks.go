@@ -0,0 +1,100 @@
+package certs
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+
+	"github.com/pkg/errors"
+	jose "gopkg.in/square/go-jose.v2"
+)
+
+// KeyResponse is a JSON Web Key Set document as served on an OIDC jwks_uri.
+type KeyResponse struct {
+	Keys []jose.JSONWebKey `json:"keys"`
+}
+
+// GenerateJWKS converts the given RSA and/or ECDSA public keys into an indented
+// JSON Web Key Set document. The key ID of each key is the unpadded base64url
+// encoded SHA-256 hash of its PKIX DER encoding.
+func GenerateJWKS(keys ...crypto.PublicKey) ([]byte, error) {
+	response := KeyResponse{Keys: make([]jose.JSONWebKey, 0, len(keys))}
+	for _, key := range keys {
+		jwk, err := PublicKeyToJWK(key)
+		if err != nil {
+			return nil, err
+		}
+		response.Keys = append(response.Keys, jwk)
+	}
+	return json.MarshalIndent(response, "", "  ")
+}
+
+// PemToJWKS converts all PEM encoded public keys in data into a JSON Web Key Set
+// document.
+func PemToJWKS(data []byte) ([]byte, error) {
+	var keys []crypto.PublicKey
+	for block, rest := pem.Decode(data); block != nil; block, rest = pem.Decode(rest) {
+		if block.Type != "RSA PUBLIC KEY" && block.Type != "PUBLIC KEY" {
+			continue
+		}
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to parse public key")
+		}
+		keys = append(keys, key)
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("could not find a PEM block containing a public key")
+	}
+	return GenerateJWKS(keys...)
+}
+
+// PublicKeyToJWK converts an RSA or ECDSA public key into a signing JSON Web Key.
+func PublicKeyToJWK(key crypto.PublicKey) (jose.JSONWebKey, error) {
+	var algorithm jose.SignatureAlgorithm
+	switch key := key.(type) {
+	case *rsa.PublicKey:
+		algorithm = jose.RS256
+	case *ecdsa.PublicKey:
+		switch key.Curve {
+		case elliptic.P256():
+			algorithm = jose.ES256
+		case elliptic.P384():
+			algorithm = jose.ES384
+		case elliptic.P521():
+			algorithm = jose.ES512
+		default:
+			return jose.JSONWebKey{}, fmt.Errorf("unsupported elliptic curve %s", key.Curve.Params().Name)
+		}
+	default:
+		return jose.JSONWebKey{}, fmt.Errorf("public key type %T is not supported", key)
+	}
+
+	kid, err := KeyID(key)
+	if err != nil {
+		return jose.JSONWebKey{}, err
+	}
+	return jose.JSONWebKey{
+		Key:       key,
+		KeyID:     kid,
+		Algorithm: string(algorithm),
+		Use:       "sig",
+	}, nil
+}
+
+// KeyID computes the key ID of a public key as used in JSON Web Key Sets.
+func KeyID(key crypto.PublicKey) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to MarshalPKIXPublicKey")
+	}
+	hash := sha256.Sum256(der)
+	return base64.RawURLEncoding.EncodeToString(hash[:]), nil
+}
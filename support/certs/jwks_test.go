@@ -0,0 +1,60 @@
+package certs_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"testing"
+
+	"github.com/openshift/hypershift/support/certs"
+)
+
+func TestGenerateJWKS(t *testing.T) {
+	t.Parallel()
+
+	rsaKey, err := certs.PrivateKey()
+	if err != nil {
+		t.Fatalf("failed to generate RSA key: %v", err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate ECDSA key: %v", err)
+	}
+
+	pubPEM, err := certs.PublicKeyToPem(&rsaKey.PublicKey)
+	if err != nil {
+		t.Fatalf("failed to encode public key: %v", err)
+	}
+	fromPEM, err := certs.PemToJWKS(pubPEM)
+	if err != nil {
+		t.Fatalf("PemToJWKS failed: %v", err)
+	}
+	fromKey, err := certs.GenerateJWKS(&rsaKey.PublicKey)
+	if err != nil {
+		t.Fatalf("GenerateJWKS failed: %v", err)
+	}
+	if string(fromPEM) != string(fromKey) {
+		t.Errorf("JWKS generated from PEM and key differ:\n%s\n%s", fromPEM, fromKey)
+	}
+
+	document, err := certs.GenerateJWKS(&rsaKey.PublicKey, &ecKey.PublicKey)
+	if err != nil {
+		t.Fatalf("GenerateJWKS failed: %v", err)
+	}
+	var response certs.KeyResponse
+	if err := json.Unmarshal(document, &response); err != nil {
+		t.Fatalf("failed to unmarshal JWKS: %v", err)
+	}
+	if len(response.Keys) != 2 {
+		t.Fatalf("expected 2 keys, got %d", len(response.Keys))
+	}
+	for i, expected := range []string{"RS256", "ES256"} {
+		if response.Keys[i].Algorithm != expected {
+			t.Errorf("expected key %d to have algorithm %s, got %s", i, expected, response.Keys[i].Algorithm)
+		}
+		if response.Keys[i].KeyID == "" || response.Keys[i].Use != "sig" {
+			t.Errorf("key %d has unexpected kid %q or use %q", i, response.Keys[i].KeyID, response.Keys[i].Use)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package certs
+
+import (
+	"crypto/rsa"
+	"crypto/x509"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// CASecretCertKey is the key under which ReconcileTLSSecret stores the CA certificate.
+const CASecretCertKey = "ca.crt"
+
+// TLSSecretReconcileResult describes what ReconcileTLSSecret did to a secret.
+type TLSSecretReconcileResult string
+
+const (
+	// TLSSecretUnchanged means the existing keypair was valid and left untouched.
+	TLSSecretUnchanged TLSSecretReconcileResult = "Unchanged"
+	// TLSSecretGenerated means the secret had no keypair and a new one was generated.
+	TLSSecretGenerated TLSSecretReconcileResult = "Generated"
+	// TLSSecretRotated means the existing keypair was invalid, close to expiry or
+	// not signed by the CA and was replaced.
+	TLSSecretRotated TLSSecretReconcileResult = "Rotated"
+)
+
+// ReconcileTLSSecret ensures the secret contains a keypair matching cfg that is signed
+// by the given CA and valid for at least minimumRemainingValidity. The keypair is
+// stored under the standard tls.crt and tls.key keys, the CA certificate under ca.crt.
+// The secret is only mutated if the keypair needs to be (re)generated or the CA
+// certificate changed.
+func ReconcileTLSSecret(secret *corev1.Secret, cfg *CertCfg, caKey *rsa.PrivateKey, caCert *x509.Certificate, minimumRemainingValidity time.Duration) (TLSSecretReconcileResult, error) {
+	if secret.Type == "" {
+		secret.Type = corev1.SecretTypeTLS
+	}
+	if secret.Data == nil {
+		secret.Data = map[string][]byte{}
+	}
+	secret.Data[CASecretCertKey] = CertToPem(caCert)
+
+	existingCert, hasCert := secret.Data[corev1.TLSCertKey]
+	existingKey, hasKey := secret.Data[corev1.TLSPrivateKeyKey]
+	result := TLSSecretGenerated
+	if hasCert || hasKey {
+		result = TLSSecretRotated
+		if err := ValidateKeyPair(existingKey, existingCert, cfg, minimumRemainingValidity); err == nil && signedBy(existingCert, caCert) {
+			return TLSSecretUnchanged, nil
+		}
+	}
+
+	key, cert, err := GenerateSignedCertificate(caKey, caCert, cfg)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate keypair for secret %s/%s: %w", secret.Namespace, secret.Name, err)
+	}
+	secret.Data[corev1.TLSCertKey] = CertToPem(cert)
+	secret.Data[corev1.TLSPrivateKeyKey] = PrivateKeyToPem(key)
+	return result, nil
+}
+
+func signedBy(pemCertificate []byte, caCert *x509.Certificate) bool {
+	cert, err := PemToCertificate(pemCertificate)
+	if err != nil {
+		return false
+	}
+	return cert.CheckSignatureFrom(caCert) == nil
+}
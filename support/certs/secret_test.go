@@ -0,0 +1,62 @@
+package certs_test
+
+import (
+	"bytes"
+	"crypto/x509/pkix"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/openshift/hypershift/support/certs"
+)
+
+func TestReconcileTLSSecret(t *testing.T) {
+	t.Parallel()
+
+	generateCA := func() *certs.CertCfg {
+		return &certs.CertCfg{IsCA: true, Subject: pkix.Name{CommonName: "root-ca", OrganizationalUnit: []string{"ou"}}, Validity: time.Hour}
+	}
+	caKey, caCert, err := certs.GenerateSelfSignedCertificate(generateCA())
+	if err != nil {
+		t.Fatalf("failed go generate CA: %v", err)
+	}
+	otherCAKey, otherCACert, err := certs.GenerateSelfSignedCertificate(generateCA())
+	if err != nil {
+		t.Fatalf("failed go generate CA: %v", err)
+	}
+
+	cfg := &certs.CertCfg{Subject: pkix.Name{CommonName: "server"}, DNSNames: []string{"server.example.com"}, Validity: time.Hour}
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "server"}}
+
+	result, err := certs.ReconcileTLSSecret(secret, cfg, caKey, caCert, time.Minute)
+	if err != nil || result != certs.TLSSecretGenerated {
+		t.Fatalf("expected %s, got %s: %v", certs.TLSSecretGenerated, result, err)
+	}
+	if secret.Type != corev1.SecretTypeTLS {
+		t.Errorf("expected secret type %s, got %s", corev1.SecretTypeTLS, secret.Type)
+	}
+	generated := append([]byte(nil), secret.Data[corev1.TLSCertKey]...)
+
+	result, err = certs.ReconcileTLSSecret(secret, cfg, caKey, caCert, time.Minute)
+	if err != nil || result != certs.TLSSecretUnchanged {
+		t.Fatalf("expected %s, got %s: %v", certs.TLSSecretUnchanged, result, err)
+	}
+	if !bytes.Equal(generated, secret.Data[corev1.TLSCertKey]) {
+		t.Error("expected certificate to be left untouched")
+	}
+
+	result, err = certs.ReconcileTLSSecret(secret, cfg, caKey, caCert, 2*time.Hour)
+	if err != nil || result != certs.TLSSecretRotated {
+		t.Fatalf("expected %s when below minimum validity, got %s: %v", certs.TLSSecretRotated, result, err)
+	}
+
+	result, err = certs.ReconcileTLSSecret(secret, cfg, otherCAKey, otherCACert, time.Minute)
+	if err != nil || result != certs.TLSSecretRotated {
+		t.Fatalf("expected %s after CA change, got %s: %v", certs.TLSSecretRotated, result, err)
+	}
+	if !bytes.Equal(secret.Data[certs.CASecretCertKey], certs.CertToPem(otherCACert)) {
+		t.Error("expected CA certificate to be updated")
+	}
+}
@@ -0,0 +1,136 @@
+package certs
+
+import (
+	"crypto/rsa"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"k8s.io/utils/clock"
+)
+
+const (
+	// SPIFFEScheme is the URI scheme of SPIFFE IDs.
+	SPIFFEScheme = "spiffe"
+
+	// DefaultSVIDValidity is the validity of SVIDs if none is specified. SVIDs are
+	// meant to be short-lived and rotated frequently.
+	DefaultSVIDValidity = time.Hour
+)
+
+var spiffeTrustDomainRegexp = regexp.MustCompile(`^[a-z0-9._-]+$`)
+
+// SVIDCfg contains all needed fields to configure a new X.509 SVID.
+type SVIDCfg struct {
+	// TrustDomain is the SPIFFE trust domain, e.g. the hosted cluster's infra ID.
+	TrustDomain string
+	// Path identifies the workload within the trust domain, e.g. /ns/foo/sa/bar.
+	Path string
+	// DNSNames are optional additional DNS SANs.
+	DNSNames []string
+	// Validity defaults to DefaultSVIDValidity.
+	Validity time.Duration
+	// SignatureAlgorithm is the algorithm used to sign the SVID, like
+	// CertCfg.SignatureAlgorithm.
+	SignatureAlgorithm x509.SignatureAlgorithm
+	// DisableSHA1 ensures SHA-1 is not used anywhere, like CertCfg.DisableSHA1.
+	DisableSHA1 bool
+	// Clock is used to determine the validity period. Defaults to the real clock.
+	Clock clock.PassiveClock
+	// Rand is the source of randomness for keys, serials and signatures, with the
+	// same limitations as CertCfg.Rand. Defaults to crypto/rand.Reader.
+	Rand io.Reader
+}
+
+// SPIFFEID returns the SPIFFE ID described by cfg, or an error if it is invalid.
+func (c *SVIDCfg) SPIFFEID() (*url.URL, error) {
+	if !spiffeTrustDomainRegexp.MatchString(c.TrustDomain) {
+		return nil, fmt.Errorf("invalid SPIFFE trust domain %q: must only contain lowercase letters, digits, dots, dashes and underscores", c.TrustDomain)
+	}
+	if c.Path != "" {
+		if !strings.HasPrefix(c.Path, "/") || strings.HasSuffix(c.Path, "/") {
+			return nil, fmt.Errorf("invalid SPIFFE path %q: must start and must not end with a slash", c.Path)
+		}
+		for _, segment := range strings.Split(c.Path[1:], "/") {
+			if segment == "" || segment == "." || segment == ".." {
+				return nil, fmt.Errorf("invalid SPIFFE path %q: empty, '.' and '..' segments are not allowed", c.Path)
+			}
+		}
+	}
+	return &url.URL{Scheme: SPIFFEScheme, Host: c.TrustDomain, Path: c.Path}, nil
+}
+
+// GenerateSVID generates a key and an X.509 SVID for the SPIFFE ID described by cfg,
+// signed by the given CA. The SVID carries the SPIFFE ID as its only URI SAN and is
+// usable for both client and server authentication.
+func GenerateSVID(caKey *rsa.PrivateKey, caCert *x509.Certificate, cfg *SVIDCfg) (*rsa.PrivateKey, *x509.Certificate, error) {
+	id, err := cfg.SPIFFEID()
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := validateSignatureAlgorithm(cfg.SignatureAlgorithm, cfg.DisableSHA1); err != nil {
+		return nil, nil, err
+	}
+	validity := cfg.Validity
+	if validity == 0 {
+		validity = DefaultSVIDValidity
+	}
+
+	random := randReader(cfg.Rand)
+	key, err := privateKey(random)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to generate private key")
+	}
+	serial, err := randomSerial(random)
+	if err != nil {
+		return nil, nil, err
+	}
+	now := clockNow(cfg.Clock)
+	certTmpl := x509.Certificate{
+		DNSNames:              cfg.DNSNames,
+		URIs:                  []*url.URL{id},
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		KeyUsage:              x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature | x509.KeyUsageKeyAgreement,
+		NotAfter:              now.Add(validity),
+		NotBefore:             now.Add(-time.Minute),
+		SerialNumber:          serial,
+		SignatureAlgorithm:    cfg.SignatureAlgorithm,
+		Version:               3,
+		BasicConstraintsValid: true,
+	}
+	if certTmpl.NotAfter.After(caCert.NotAfter) {
+		certTmpl.NotAfter = caCert.NotAfter
+	}
+	certTmpl.SubjectKeyId, err = generateSubjectKeyID(key.Public(), cfg.DisableSHA1)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to set subject key identifier")
+	}
+	certBytes, err := x509.CreateCertificate(random, &certTmpl, caCert, key.Public(), caKey)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to create x509 certificate")
+	}
+	cert, err := x509.ParseCertificate(certBytes)
+	if err != nil {
+		return nil, nil, err
+	}
+	return key, cert, nil
+}
+
+// SPIFFEIDFromCertificate returns the SPIFFE ID of an X.509 SVID.
+func SPIFFEIDFromCertificate(cert *x509.Certificate) (*url.URL, error) {
+	var ids []*url.URL
+	for _, uri := range cert.URIs {
+		if uri.Scheme == SPIFFEScheme {
+			ids = append(ids, uri)
+		}
+	}
+	if len(ids) != 1 {
+		return nil, fmt.Errorf("expected exactly one SPIFFE ID URI SAN, found %d", len(ids))
+	}
+	return ids[0], nil
+}
@@ -0,0 +1,156 @@
+package certs_test
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"testing"
+	"time"
+
+	clocktesting "k8s.io/utils/clock/testing"
+
+	"github.com/openshift/hypershift/support/certs"
+)
+
+func TestGenerateSVID(t *testing.T) {
+	t.Parallel()
+
+	caCfg := certs.CertCfg{IsCA: true, Subject: pkix.Name{CommonName: "root-ca", OrganizationalUnit: []string{"ou"}}, Validity: 2 * time.Hour, KeyUsages: x509.KeyUsageCertSign}
+	caKey, caCert, err := certs.GenerateSelfSignedCertificate(&caCfg)
+	if err != nil {
+		t.Fatalf("failed go generate CA: %v", err)
+	}
+
+	testCases := []struct {
+		name        string
+		cfg         certs.SVIDCfg
+		expectedID  string
+		expectError bool
+	}{
+		{
+			name:       "Valid",
+			cfg:        certs.SVIDCfg{TrustDomain: "cluster.local", Path: "/ns/foo/sa/bar"},
+			expectedID: "spiffe://cluster.local/ns/foo/sa/bar",
+		},
+		{
+			name:        "Uppercase trust domain",
+			cfg:         certs.SVIDCfg{TrustDomain: "Cluster.local", Path: "/ns/foo"},
+			expectError: true,
+		},
+		{
+			name:        "Trailing slash",
+			cfg:         certs.SVIDCfg{TrustDomain: "cluster.local", Path: "/ns/foo/"},
+			expectError: true,
+		},
+		{
+			name:        "Dot segment",
+			cfg:         certs.SVIDCfg{TrustDomain: "cluster.local", Path: "/ns/../foo"},
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			_, cert, err := certs.GenerateSVID(caKey, caCert, &tc.cfg)
+			if tc.expectError {
+				if err == nil {
+					t.Error("expected an error, got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GenerateSVID failed: %v", err)
+			}
+			id, err := certs.SPIFFEIDFromCertificate(cert)
+			if err != nil {
+				t.Fatalf("SPIFFEIDFromCertificate failed: %v", err)
+			}
+			if id.String() != tc.expectedID {
+				t.Errorf("expected SPIFFE ID %s, got %s", tc.expectedID, id)
+			}
+			if cert.IsCA {
+				t.Error("SVID must not be a CA")
+			}
+			if cert.NotAfter.Sub(cert.NotBefore) > certs.DefaultSVIDValidity+time.Minute {
+				t.Errorf("SVID validity %s exceeds default", cert.NotAfter.Sub(cert.NotBefore))
+			}
+			pool := x509.NewCertPool()
+			pool.AddCert(caCert)
+			if _, err := cert.Verify(x509.VerifyOptions{Roots: pool, KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}}); err != nil {
+				t.Errorf("failed to verify SVID: %v", err)
+			}
+		})
+	}
+}
+
+// countingReader counts the bytes read from crypto/rand.
+type countingReader struct {
+	read int
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := rand.Read(p)
+	r.read += n
+	return n, err
+}
+
+func TestGenerateSVIDUsesInjectedClockAndRand(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	caCfg := certs.CertCfg{IsCA: true, Subject: pkix.Name{CommonName: "root-ca", OrganizationalUnit: []string{"ou"}}, Validity: 2 * time.Hour, KeyUsages: x509.KeyUsageCertSign, Clock: clocktesting.NewFakePassiveClock(now)}
+	caKey, caCert, err := certs.GenerateSelfSignedCertificate(&caCfg)
+	if err != nil {
+		t.Fatalf("failed go generate CA: %v", err)
+	}
+
+	random := &countingReader{}
+	_, cert, err := certs.GenerateSVID(caKey, caCert, &certs.SVIDCfg{
+		TrustDomain: "cluster.local",
+		Path:        "/ns/foo/sa/bar",
+		Clock:       clocktesting.NewFakePassiveClock(now.Add(10 * time.Minute)),
+		Rand:        random,
+	})
+	if err != nil {
+		t.Fatalf("GenerateSVID failed: %v", err)
+	}
+	if !cert.NotBefore.Equal(now.Add(9*time.Minute)) || !cert.NotAfter.Equal(now.Add(10*time.Minute+certs.DefaultSVIDValidity)) {
+		t.Errorf("unexpected validity period %s - %s", cert.NotBefore, cert.NotAfter)
+	}
+	if random.read == 0 {
+		t.Error("expected the injected Rand to be used")
+	}
+}
+
+func TestGenerateSVIDDisableSHA1(t *testing.T) {
+	t.Parallel()
+
+	caCfg := certs.CertCfg{IsCA: true, Subject: pkix.Name{CommonName: "root-ca", OrganizationalUnit: []string{"ou"}}, Validity: 2 * time.Hour, KeyUsages: x509.KeyUsageCertSign}
+	caKey, caCert, err := certs.GenerateSelfSignedCertificate(&caCfg)
+	if err != nil {
+		t.Fatalf("failed go generate CA: %v", err)
+	}
+
+	cfg := &certs.SVIDCfg{TrustDomain: "cluster.local", Path: "/ns/foo/sa/bar", SignatureAlgorithm: x509.SHA384WithRSAPSS, DisableSHA1: true}
+	key, cert, err := certs.GenerateSVID(caKey, caCert, cfg)
+	if err != nil {
+		t.Fatalf("GenerateSVID failed: %v", err)
+	}
+	if cert.SignatureAlgorithm != x509.SHA384WithRSAPSS {
+		t.Errorf("expected signature algorithm %s, got %s", x509.SHA384WithRSAPSS, cert.SignatureAlgorithm)
+	}
+	expectedKeyID := sha256.Sum256(x509.MarshalPKCS1PublicKey(&key.PublicKey))
+	if !bytes.Equal(cert.SubjectKeyId, expectedKeyID[:20]) {
+		t.Errorf("expected the truncated SHA-256 subject key identifier %x, got %x", expectedKeyID[:20], cert.SubjectKeyId)
+	}
+
+	for _, algorithm := range []x509.SignatureAlgorithm{x509.SHA1WithRSA, x509.ECDSAWithSHA256} {
+		cfg.SignatureAlgorithm = algorithm
+		if _, _, err := certs.GenerateSVID(caKey, caCert, cfg); err == nil {
+			t.Errorf("expected an error when requesting a %s signature", algorithm)
+		}
+	}
+}
@@ -8,21 +8,25 @@ import (
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/sha1"
+	"crypto/sha256"
 	"crypto/x509"
 	"crypto/x509/pkix"
 	"encoding/asn1"
 	"encoding/base64"
 	"encoding/pem"
 	"fmt"
+	"io"
 	"math"
 	"math/big"
 	"net"
+	"strings"
 	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
 	"github.com/pkg/errors"
 	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/utils/clock"
 )
 
 const (
@@ -42,6 +46,95 @@ type CertCfg struct {
 	Subject      pkix.Name
 	Validity     time.Duration
 	IsCA         bool
+	// ExtraExtensions are added verbatim to the certificate, e.g. to embed
+	// custom OIDs like a cluster identity. Extensions in the id-ce arc
+	// (2.5.29) are reserved for the standard fields above.
+	ExtraExtensions []pkix.Extension
+	// StrictExtraExtensions makes keypair validation reject certificates with
+	// extensions outside of the id-ce arc that are not in ExtraExtensions. By
+	// default only the configured extensions are compared, so that extensions added
+	// by an external CA, e.g. authority information access or CT SCTs, don't cause
+	// a mismatch. It has no effect on generation.
+	StrictExtraExtensions bool
+	// SignatureAlgorithm is the algorithm used to sign the certificate, e.g.
+	// x509.SHA384WithRSAPSS. It must be an RSA algorithm, as all keys are RSA
+	// keys. Defaults to the x509 package default for the signing key.
+	SignatureAlgorithm x509.SignatureAlgorithm
+	// DisableSHA1 ensures SHA-1 is not used anywhere, including the subject key
+	// identifier, as required in FIPS and modern crypto-policy environments.
+	DisableSHA1 bool
+	// AllowSANSuperset makes keypair validation accept certificates whose DNS names
+	// and IP addresses are a superset of the configured ones, e.g. user-augmented
+	// certificates with additional API server SANs. It has no effect on generation.
+	AllowSANSuperset bool
+	// SubjectPolicy controls which subject fields SelfSignedCertificate requires.
+	// Defaults to SubjectPolicyRequireCNAndOU.
+	SubjectPolicy SubjectPolicy
+	// Clock is used to determine the validity period and to check remaining
+	// validity. Defaults to the real clock.
+	Clock clock.PassiveClock
+	// Rand is the source of randomness for keys, serials and signatures.
+	// Defaults to crypto/rand.Reader. A deterministic Rand only makes the serial
+	// and, for a fixed key and a PKCS #1 v1.5 signature algorithm, the signature
+	// reproducible: rsa.GenerateKey deliberately doesn't produce the same key for
+	// the same reader, so use SelfSignedCertificate with a fixed key in tests.
+	Rand io.Reader
+}
+
+func (c *CertCfg) now() time.Time {
+	return clockNow(c.Clock)
+}
+
+func (c *CertCfg) rand() io.Reader {
+	return randReader(c.Rand)
+}
+
+// clockNow returns the current time of the clock, or of the real clock if it is nil.
+func clockNow(c clock.PassiveClock) time.Time {
+	if c == nil {
+		return time.Now()
+	}
+	return c.Now()
+}
+
+// randReader returns random, or crypto/rand.Reader if it is nil.
+func randReader(random io.Reader) io.Reader {
+	if random == nil {
+		return rand.Reader
+	}
+	return random
+}
+
+// randomSerial returns a random certificate serial number.
+func randomSerial(random io.Reader) (*big.Int, error) {
+	return rand.Int(random, new(big.Int).SetInt64(math.MaxInt64))
+}
+
+// SubjectPolicy describes which subject fields must be set on a certificate.
+type SubjectPolicy string
+
+const (
+	// SubjectPolicyRequireCNAndOU requires both CommonName and OrganizationalUnit.
+	SubjectPolicyRequireCNAndOU SubjectPolicy = ""
+	// SubjectPolicyRequireCN only requires CommonName, matching upstream Kubernetes conventions.
+	SubjectPolicyRequireCN SubjectPolicy = "RequireCN"
+)
+
+// validateSubject verifies that the subject satisfies the configured SubjectPolicy.
+func (c *CertCfg) validateSubject() error {
+	switch c.SubjectPolicy {
+	case SubjectPolicyRequireCNAndOU:
+		if len(c.Subject.CommonName) == 0 || len(c.Subject.OrganizationalUnit) == 0 {
+			return errors.Errorf("certification's subject is not set, or invalid")
+		}
+	case SubjectPolicyRequireCN:
+		if len(c.Subject.CommonName) == 0 {
+			return errors.Errorf("certification's subject common name is not set")
+		}
+	default:
+		return errors.Errorf("unknown subject policy %q", c.SubjectPolicy)
+	}
+	return nil
 }
 
 // rsaPublicKey reflects the ASN.1 structure of a PKCS#1 public key.
@@ -52,7 +145,7 @@ type rsaPublicKey struct {
 
 // GenerateSelfSignedCertificate generates a key/cert pair defined by CertCfg.
 func GenerateSelfSignedCertificate(cfg *CertCfg) (*rsa.PrivateKey, *x509.Certificate, error) {
-	key, err := PrivateKey()
+	key, err := privateKey(cfg.rand())
 	if err != nil {
 		return nil, nil, errors.Wrap(err, "failed to generate private key")
 	}
@@ -69,14 +162,14 @@ func GenerateSignedCertificate(caKey *rsa.PrivateKey, caCert *x509.Certificate,
 	cfg *CertCfg) (*rsa.PrivateKey, *x509.Certificate, error) {
 
 	// create a private key
-	key, err := PrivateKey()
+	key, err := privateKey(cfg.rand())
 	if err != nil {
 		return nil, nil, errors.Wrap(err, "failed to generate private key")
 	}
 
 	// create a CSR
 	csrTmpl := x509.CertificateRequest{Subject: cfg.Subject, DNSNames: cfg.DNSNames, IPAddresses: cfg.IPAddresses}
-	csrBytes, err := x509.CreateCertificateRequest(rand.Reader, &csrTmpl, key)
+	csrBytes, err := x509.CreateCertificateRequest(cfg.rand(), &csrTmpl, key)
 	if err != nil {
 		return nil, nil, errors.Wrap(err, "failed to create certificate request")
 	}
@@ -95,7 +188,11 @@ func GenerateSignedCertificate(caKey *rsa.PrivateKey, caCert *x509.Certificate,
 
 // PrivateKey generates an RSA Private key and returns the value
 func PrivateKey() (*rsa.PrivateKey, error) {
-	rsaKey, err := rsa.GenerateKey(rand.Reader, keySize)
+	return privateKey(rand.Reader)
+}
+
+func privateKey(random io.Reader) (*rsa.PrivateKey, error) {
+	rsaKey, err := rsa.GenerateKey(random, keySize)
 	if err != nil {
 		return nil, errors.Wrap(err, "error generating RSA private key")
 	}
@@ -105,29 +202,34 @@ func PrivateKey() (*rsa.PrivateKey, error) {
 
 // SelfSignedCertificate creates a self signed certificate
 func SelfSignedCertificate(cfg *CertCfg, key *rsa.PrivateKey) (*x509.Certificate, error) {
-	serial, err := rand.Int(rand.Reader, new(big.Int).SetInt64(math.MaxInt64))
+	serial, err := randomSerial(cfg.rand())
 	if err != nil {
 		return nil, err
 	}
+	now := cfg.now()
 	cert := x509.Certificate{
 		BasicConstraintsValid: true,
 		IsCA:                  cfg.IsCA,
 		KeyUsage:              cfg.KeyUsages,
-		NotAfter:              time.Now().Add(cfg.Validity),
-		NotBefore:             time.Now(),
+		NotAfter:              now.Add(cfg.Validity),
+		NotBefore:             now,
 		SerialNumber:          serial,
 		Subject:               cfg.Subject,
+		ExtraExtensions:       cfg.ExtraExtensions,
+		SignatureAlgorithm:    cfg.SignatureAlgorithm,
+	}
+	if err := cfg.validateSubject(); err != nil {
+		return nil, err
 	}
-	// verifies that the CN and/or OU for the cert is set
-	if len(cfg.Subject.CommonName) == 0 || len(cfg.Subject.OrganizationalUnit) == 0 {
-		return nil, errors.Errorf("certification's subject is not set, or invalid")
+	if err := cfg.validateSignatureAlgorithm(); err != nil {
+		return nil, err
 	}
 	pub := key.Public()
-	cert.SubjectKeyId, err = generateSubjectKeyID(pub)
+	cert.SubjectKeyId, err = generateSubjectKeyID(pub, cfg.DisableSHA1)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to set subject key identifier")
 	}
-	certBytes, err := x509.CreateCertificate(rand.Reader, &cert, &cert, key.Public(), key)
+	certBytes, err := x509.CreateCertificate(cfg.rand(), &cert, &cert, key.Public(), key)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to create certificate")
 	}
@@ -142,7 +244,7 @@ func signedCertificate(
 	caCert *x509.Certificate,
 	caKey *rsa.PrivateKey,
 ) (*x509.Certificate, error) {
-	serial, err := rand.Int(rand.Reader, new(big.Int).SetInt64(math.MaxInt64))
+	serial, err := randomSerial(cfg.rand())
 	if err != nil {
 		return nil, err
 	}
@@ -152,28 +254,34 @@ func signedCertificate(
 		ExtKeyUsage:           cfg.ExtKeyUsages,
 		IPAddresses:           csr.IPAddresses,
 		KeyUsage:              cfg.KeyUsages,
-		NotAfter:              time.Now().Add(cfg.Validity),
+		NotAfter:              cfg.now().Add(cfg.Validity),
 		NotBefore:             caCert.NotBefore,
 		SerialNumber:          serial,
 		Subject:               csr.Subject,
 		IsCA:                  cfg.IsCA,
+		ExtraExtensions:       cfg.ExtraExtensions,
+		SignatureAlgorithm:    cfg.SignatureAlgorithm,
 		Version:               3,
 		BasicConstraintsValid: true,
 	}
+	if err := cfg.validateSignatureAlgorithm(); err != nil {
+		return nil, err
+	}
 	pub := caCert.PublicKey.(*rsa.PublicKey)
-	certTmpl.SubjectKeyId, err = generateSubjectKeyID(pub)
+	certTmpl.SubjectKeyId, err = generateSubjectKeyID(pub, cfg.DisableSHA1)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to set subject key identifier")
 	}
-	certBytes, err := x509.CreateCertificate(rand.Reader, &certTmpl, caCert, key.Public(), caKey)
+	certBytes, err := x509.CreateCertificate(cfg.rand(), &certTmpl, caCert, key.Public(), caKey)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to create x509 certificate")
 	}
 	return x509.ParseCertificate(certBytes)
 }
 
-// generateSubjectKeyID generates a SHA-1 hash of the subject public key.
-func generateSubjectKeyID(pub crypto.PublicKey) ([]byte, error) {
+// generateSubjectKeyID generates a SHA-1 hash of the subject public key, or the
+// leftmost 160 bits of its SHA-256 hash (RFC 7093, method 1) if disableSHA1 is set.
+func generateSubjectKeyID(pub crypto.PublicKey, disableSHA1 bool) ([]byte, error) {
 	var publicKeyBytes []byte
 	var err error
 
@@ -189,10 +297,53 @@ func generateSubjectKeyID(pub crypto.PublicKey) ([]byte, error) {
 		return nil, errors.New("only RSA and ECDSA public keys supported")
 	}
 
+	if disableSHA1 {
+		hash := sha256.Sum256(publicKeyBytes)
+		return hash[:sha1.Size], nil
+	}
 	hash := sha1.Sum(publicKeyBytes)
 	return hash[:], nil
 }
 
+// rsaSignatureAlgorithms are the signature algorithms usable with the RSA keys
+// all certificates are signed with. The zero value selects the x509 package
+// default for the signing key.
+var rsaSignatureAlgorithms = map[x509.SignatureAlgorithm]bool{
+	x509.UnknownSignatureAlgorithm: true,
+	x509.SHA1WithRSA:               true,
+	x509.SHA256WithRSA:             true,
+	x509.SHA384WithRSA:             true,
+	x509.SHA512WithRSA:             true,
+	x509.SHA256WithRSAPSS:          true,
+	x509.SHA384WithRSAPSS:          true,
+	x509.SHA512WithRSAPSS:          true,
+}
+
+// sha1SignatureAlgorithms are the signature algorithms that are rejected if
+// DisableSHA1 is set.
+var sha1SignatureAlgorithms = map[x509.SignatureAlgorithm]bool{
+	x509.SHA1WithRSA:   true,
+	x509.DSAWithSHA1:   true,
+	x509.ECDSAWithSHA1: true,
+}
+
+// validateSignatureAlgorithm verifies the configured signature algorithm is allowed.
+func (c *CertCfg) validateSignatureAlgorithm() error {
+	return validateSignatureAlgorithm(c.SignatureAlgorithm, c.DisableSHA1)
+}
+
+// validateSignatureAlgorithm verifies the signature algorithm can be used with
+// an RSA key and doesn't use SHA-1 if disableSHA1 is set.
+func validateSignatureAlgorithm(algorithm x509.SignatureAlgorithm, disableSHA1 bool) error {
+	if !rsaSignatureAlgorithms[algorithm] {
+		return errors.Errorf("signature algorithm %s is not supported, keys are RSA keys", algorithm)
+	}
+	if disableSHA1 && sha1SignatureAlgorithms[algorithm] {
+		return errors.Errorf("signature algorithm %s uses SHA-1 which is disabled", algorithm)
+	}
+	return nil
+}
+
 // PrivateKeyToPem converts an rsa.PrivateKey object to pem string
 func PrivateKeyToPem(key *rsa.PrivateKey) []byte {
 	keyInBytes := x509.MarshalPKCS1PrivateKey(key)
@@ -205,6 +356,20 @@ func PrivateKeyToPem(key *rsa.PrivateKey) []byte {
 	return keyinPem
 }
 
+// EncryptedPrivateKeyToPem converts an rsa.PrivateKey object to a pem string encrypted
+// with the given passphrase. It is meant for exporting keys, e.g. CA keys for offline storage.
+func EncryptedPrivateKeyToPem(key *rsa.PrivateKey, passphrase []byte) ([]byte, error) {
+	if len(passphrase) == 0 {
+		return nil, errors.New("passphrase must not be empty")
+	}
+	//nolint:staticcheck // legacy PEM encryption is what openssl and friends understand for PKCS#1 keys
+	block, err := x509.EncryptPEMBlock(rand.Reader, "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(key), passphrase, x509.PEMCipherAES256)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to encrypt private key")
+	}
+	return pem.EncodeToMemory(block), nil
+}
+
 // CertToPem converts an x509.Certificate object to a pem string
 func CertToPem(cert *x509.Certificate) []byte {
 	certInPem := pem.EncodeToMemory(
@@ -251,6 +416,29 @@ func PemToPrivateKey(data []byte) (*rsa.PrivateKey, error) {
 	return x509.ParsePKCS1PrivateKey(block.Bytes)
 }
 
+// PemToPrivateKeyWithPassphrase converts a data block to rsa.PrivateKey, decrypting it
+// with the given passphrase if the block is encrypted. An empty passphrase behaves like
+// PemToPrivateKey.
+func PemToPrivateKeyWithPassphrase(data, passphrase []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.Errorf("could not find a PEM block in the private key")
+	}
+	//nolint:staticcheck // see EncryptedPrivateKeyToPem
+	if !x509.IsEncryptedPEMBlock(block) {
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	}
+	if len(passphrase) == 0 {
+		return nil, errors.New("private key is encrypted but no passphrase was supplied")
+	}
+	//nolint:staticcheck // see EncryptedPrivateKeyToPem
+	der, err := x509.DecryptPEMBlock(block, passphrase)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to decrypt private key")
+	}
+	return x509.ParsePKCS1PrivateKey(der)
+}
+
 // PemToCertificate converts a data block to x509.Certificate.
 func PemToCertificate(data []byte) (*x509.Certificate, error) {
 	block, _ := pem.Decode(data)
@@ -260,12 +448,55 @@ func PemToCertificate(data []byte) (*x509.Certificate, error) {
 	return x509.ParseCertificate(block.Bytes)
 }
 
+// ParseCertificateBundle converts all CERTIFICATE blocks in data to x509.Certificates,
+// in the order they appear. Other PEM blocks are skipped.
+func ParseCertificateBundle(data []byte) ([]*x509.Certificate, error) {
+	var certificates []*x509.Certificate
+	for block, rest := pem.Decode(data); block != nil; block, rest = pem.Decode(rest) {
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to parse certificate")
+		}
+		certificates = append(certificates, cert)
+	}
+	return certificates, nil
+}
+
+// ParsePrivateKeys converts all private key blocks in data to private keys, in the
+// order they appear. PKCS#1, PKCS#8 and SEC 1 EC keys are supported, other PEM blocks
+// are skipped.
+func ParsePrivateKeys(data []byte) ([]crypto.PrivateKey, error) {
+	var keys []crypto.PrivateKey
+	for block, rest := pem.Decode(data); block != nil; block, rest = pem.Decode(rest) {
+		var key crypto.PrivateKey
+		var err error
+		switch block.Type {
+		case "RSA PRIVATE KEY":
+			key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+		case "PRIVATE KEY":
+			key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+		case "EC PRIVATE KEY":
+			key, err = x509.ParseECPrivateKey(block.Bytes)
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to parse %s", strings.ToLower(block.Type))
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
 func Base64(data []byte) string {
 	return base64.StdEncoding.EncodeToString(data)
 }
 
-func parsePemKeypair(key, certificate []byte) (*rsa.PrivateKey, *x509.Certificate, error) {
-	privKey, err := PemToPrivateKey(key)
+func parsePemKeypair(key, certificate, passphrase []byte) (*rsa.PrivateKey, *x509.Certificate, error) {
+	privKey, err := PemToPrivateKeyWithPassphrase(key, passphrase)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -287,46 +518,201 @@ func parsePemKeypair(key, certificate []byte) (*rsa.PrivateKey, *x509.Certificat
 }
 
 func ValidateKeyPair(pemKey, pemCertificate []byte, cfg *CertCfg, minimumRemainingValidity time.Duration) error {
-	_, cert, err := parsePemKeypair(pemKey, pemCertificate)
+	return ValidateEncryptedKeyPair(pemKey, pemCertificate, nil, cfg, minimumRemainingValidity)
+}
+
+// ValidateEncryptedKeyPair is like ValidateKeyPair but decrypts the key with the
+// given passphrase if it is encrypted.
+func ValidateEncryptedKeyPair(pemKey, pemCertificate, passphrase []byte, cfg *CertCfg, minimumRemainingValidity time.Duration) error {
+	_, err := ValidateKeyPairWithReport(pemKey, pemCertificate, passphrase, cfg, minimumRemainingValidity)
+	return err
+}
+
+// ValidationReport describes the outcome of validating a keypair against a CertCfg.
+type ValidationReport struct {
+	// ParseError is set if the keypair could not be parsed. No other fields are
+	// populated in that case.
+	ParseError error
+	// Mismatches lists all fields of the certificate that differ from the config.
+	Mismatches []FieldMismatch
+	// RemainingValidity is the time left until the certificate expires. It is
+	// negative for expired certificates.
+	RemainingValidity time.Duration
+	// MinimumRemainingValidity is the threshold the keypair was validated against.
+	MinimumRemainingValidity time.Duration
+}
+
+// FieldMismatch describes a single CertCfg field whose value differs in the certificate.
+type FieldMismatch struct {
+	// Field is the name of the CertCfg field.
+	Field string
+	// Message describes the difference.
+	Message string
+}
+
+// Valid returns true if the keypair could be parsed, matches the config and has
+// at least the minimum remaining validity.
+func (r *ValidationReport) Valid() bool {
+	return r.ParseError == nil && len(r.Mismatches) == 0 && !r.BelowMinimumValidity()
+}
+
+// BelowMinimumValidity returns true if the certificate expires sooner than the
+// minimum remaining validity.
+func (r *ValidationReport) BelowMinimumValidity() bool {
+	return r.ParseError == nil && r.RemainingValidity < r.MinimumRemainingValidity
+}
+
+// ExpiresWithin returns true if the certificate expires within the given duration.
+// Callers can use this to e.g. warn about a pending rotation before it is required.
+func (r *ValidationReport) ExpiresWithin(d time.Duration) bool {
+	return r.ParseError == nil && r.RemainingValidity < d
+}
+
+// Err aggregates all problems of the report into a single error, or nil if it is valid.
+func (r *ValidationReport) Err() error {
+	if r.ParseError != nil {
+		return fmt.Errorf("failed to parse keypair: %w", r.ParseError)
+	}
+	var errs []error
+	for _, mismatch := range r.Mismatches {
+		errs = append(errs, errors.New(mismatch.Message))
+	}
+	if r.BelowMinimumValidity() {
+		errs = append(errs, fmt.Errorf("remaining validity %s is smaller than the minimum remaining validity %s", r.RemainingValidity, r.MinimumRemainingValidity))
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// oidCertificateExtension is the id-ce arc which contains all standard certificate extensions.
+var oidCertificateExtension = asn1.ObjectIdentifier{2, 5, 29}
+
+// extraExtensions returns the extensions of the certificate that are not standard
+// certificate extensions. Unless strict is set, only the extensions with the OID of
+// one of the expected extensions are returned, as others may have been added by the
+// CA rather than come from CertCfg.ExtraExtensions.
+func extraExtensions(cert *x509.Certificate, expected []pkix.Extension, strict bool) []pkix.Extension {
+	var result []pkix.Extension
+	for _, extension := range cert.Extensions {
+		if len(extension.Id) > len(oidCertificateExtension) && extension.Id[:len(oidCertificateExtension)].Equal(oidCertificateExtension) {
+			continue
+		}
+		if !strict && !containsExtension(expected, extension.Id) {
+			continue
+		}
+		result = append(result, extension)
+	}
+	return result
+}
+
+// containsExtension returns true if one of the extensions has the given OID.
+func containsExtension(extensions []pkix.Extension, id asn1.ObjectIdentifier) bool {
+	for _, extension := range extensions {
+		if extension.Id.Equal(id) {
+			return true
+		}
+	}
+	return false
+}
+
+// missingDNSNames returns the expected names that are not in actual.
+func missingDNSNames(actual, expected []string) []string {
+	var missing []string
+	for _, name := range expected {
+		if !contains(actual, name) {
+			missing = append(missing, name)
+		}
+	}
+	return missing
+}
+
+// missingIPAddresses returns the expected addresses that are not in actual.
+func missingIPAddresses(actual, expected []net.IP) []net.IP {
+	var missing []net.IP
+	for _, ip := range expected {
+		found := false
+		for _, actualIP := range actual {
+			if actualIP.Equal(ip) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			missing = append(missing, ip)
+		}
+	}
+	return missing
+}
+
+// ValidateKeyPairWithReport validates the keypair like ValidateEncryptedKeyPair and
+// additionally returns a structured report, so callers can act on specific problems
+// without inspecting the error text.
+func ValidateKeyPairWithReport(pemKey, pemCertificate, passphrase []byte, cfg *CertCfg, minimumRemainingValidity time.Duration) (*ValidationReport, error) {
+	report := &ValidationReport{MinimumRemainingValidity: minimumRemainingValidity}
+	_, cert, err := parsePemKeypair(pemKey, pemCertificate, passphrase)
 	if err != nil {
-		return fmt.Errorf("failed to parse keypair: %w", err)
+		report.ParseError = err
+		return report, report.Err()
 	}
 
-	var errs []error
+	mismatch := func(field, format string, args ...interface{}) {
+		report.Mismatches = append(report.Mismatches, FieldMismatch{Field: field, Message: fmt.Sprintf(format, args...)})
+	}
 	stringLessFN := func(a, b string) bool { return a < b }
 
-	dnsNamesDiff := cmp.Diff(cert.DNSNames, cfg.DNSNames, cmpopts.SortSlices(stringLessFN))
-	if dnsNamesDiff != "" {
-		errs = append(errs, fmt.Errorf("actual dns names differ from expected: %s", dnsNamesDiff))
+	if cfg.AllowSANSuperset {
+		if missing := missingDNSNames(cert.DNSNames, cfg.DNSNames); len(missing) > 0 {
+			mismatch("DNSNames", "actual dns names are missing expected names: %v", missing)
+		}
+	} else {
+		dnsNamesDiff := cmp.Diff(cert.DNSNames, cfg.DNSNames, cmpopts.SortSlices(stringLessFN))
+		if dnsNamesDiff != "" {
+			mismatch("DNSNames", "actual dns names differ from expected: %s", dnsNamesDiff)
+		}
 	}
 
 	extUsageDiff := cmp.Diff(cert.ExtKeyUsage, cfg.ExtKeyUsages, cmpopts.SortSlices(func(a, b x509.ExtKeyUsage) bool { return a < b }))
 	if extUsageDiff != "" {
-		errs = append(errs, fmt.Errorf("actual extended key usages differ from expected: %s", extUsageDiff))
+		mismatch("ExtKeyUsages", "actual extended key usages differ from expected: %s", extUsageDiff)
 	}
 
-	ipAddressDiff := cmp.Diff(cert.IPAddresses, cfg.IPAddresses, cmpopts.SortSlices(func(a, b []byte) bool { return bytes.Compare(a, b) == -1 }))
-	if ipAddressDiff != "" {
-		errs = append(errs, fmt.Errorf("actual ip addresses differ from expected: %s", ipAddressDiff))
+	if cfg.AllowSANSuperset {
+		if missing := missingIPAddresses(cert.IPAddresses, cfg.IPAddresses); len(missing) > 0 {
+			mismatch("IPAddresses", "actual ip addresses are missing expected addresses: %v", missing)
+		}
+	} else {
+		ipAddressDiff := cmp.Diff(cert.IPAddresses, cfg.IPAddresses, cmpopts.SortSlices(func(a, b []byte) bool { return bytes.Compare(a, b) == -1 }))
+		if ipAddressDiff != "" {
+			mismatch("IPAddresses", "actual ip addresses differ from expected: %s", ipAddressDiff)
+		}
 	}
 
 	if cert.KeyUsage != cfg.KeyUsages {
-		errs = append(errs, fmt.Errorf("actual key usage %d differs from expected %d", cert.KeyUsage, cfg.KeyUsages))
+		mismatch("KeyUsages", "actual key usage %d differs from expected %d", cert.KeyUsage, cfg.KeyUsages)
 	}
 
 	// subjectDiff ignores the "Names" field, as it contains the parsed attributes but is ignored during marshalling.
 	subjectDiff := cmp.Diff(cert.Subject, cfg.Subject, cmpopts.SortSlices(stringLessFN), cmpopts.IgnoreFields(pkix.Name{}, "Names"))
 	if subjectDiff != "" {
-		errs = append(errs, fmt.Errorf("actual subject differs from expected: %s", subjectDiff))
+		mismatch("Subject", "actual subject differs from expected: %s", subjectDiff)
 	}
 
-	if remainingvalidity := time.Until(cert.NotAfter); remainingvalidity < minimumRemainingValidity {
-		errs = append(errs, fmt.Errorf("remaining validity %s is smaller than the minimum remaining validity %s", remainingvalidity, minimumRemainingValidity))
+	extensionsDiff := cmp.Diff(extraExtensions(cert, cfg.ExtraExtensions, cfg.StrictExtraExtensions), cfg.ExtraExtensions, cmpopts.EquateEmpty(), cmpopts.SortSlices(func(a, b pkix.Extension) bool { return a.Id.String() < b.Id.String() }))
+	if extensionsDiff != "" {
+		mismatch("ExtraExtensions", "actual extra extensions differ from expected: %s", extensionsDiff)
 	}
 
+	if cfg.SignatureAlgorithm != x509.UnknownSignatureAlgorithm && cert.SignatureAlgorithm != cfg.SignatureAlgorithm {
+		mismatch("SignatureAlgorithm", "actual signature algorithm %s differs from expected %s", cert.SignatureAlgorithm, cfg.SignatureAlgorithm)
+	}
+	if cfg.DisableSHA1 && sha1SignatureAlgorithms[cert.SignatureAlgorithm] {
+		mismatch("DisableSHA1", "actual signature algorithm %s uses SHA-1 which is disabled", cert.SignatureAlgorithm)
+	}
+
+	report.RemainingValidity = cert.NotAfter.Sub(cfg.now())
+
 	if cert.IsCA != cfg.IsCA {
-		errs = append(errs, fmt.Errorf("actual isCA %t does not match expected %t", cert.IsCA, cfg.IsCA))
+		mismatch("IsCA", "actual isCA %t does not match expected %t", cert.IsCA, cfg.IsCA)
 	}
 
-	return utilerrors.NewAggregate(errs)
+	return report, report.Err()
 }
@@ -1,8 +1,12 @@
 package certs_test
 
 import (
+	"bytes"
 	"crypto/x509"
 	"crypto/x509/pkix"
+	"encoding/asn1"
+	"io"
+	mathrand "math/rand"
 	"net"
 	"reflect"
 	"strconv"
@@ -10,6 +14,8 @@ import (
 	"time"
 
 	fuzz "github.com/google/gofuzz"
+	"k8s.io/utils/clock"
+	clocktesting "k8s.io/utils/clock/testing"
 
 	"github.com/openshift/hypershift/support/certs"
 )
@@ -39,6 +45,13 @@ func TestValidateKeyPairConsidersAllFields(t *testing.T) {
 		if cfgReflectType.Field(i).Name == "Validity" {
 			continue
 		}
+		// SubjectPolicy, Clock, Rand and DisableSHA1 only govern how a cert is generated and AllowSANSuperset
+		// and StrictExtraExtensions only change how validation compares the cert, none of them are reflected
+		// in the cert in a way that can be compared.
+		switch cfgReflectType.Field(i).Name {
+		case "SubjectPolicy", "Clock", "Rand", "AllowSANSuperset", "StrictExtraExtensions", "DisableSHA1":
+			continue
+		}
 
 		t.Run(cfgReflectType.Field(i).Name, func(t *testing.T) {
 			cfg := &certs.CertCfg{}
@@ -115,6 +128,9 @@ func fuzzer() *fuzz.Fuzzer {
 			// just leave them empty, it is sufficient to test that changes in the parent struct cause
 			// a diff.
 			func(_ *[]pkix.AttributeTypeAndValue, _ fuzz.Continue) {},
+			// Clock and Rand are interfaces the fuzzer can't fill either, nil means the defaults.
+			func(_ *clock.PassiveClock, _ fuzz.Continue) {},
+			func(_ *io.Reader, _ fuzz.Continue) {},
 			func(s *string, c fuzz.Continue) { c.FuzzNoCustom(s); *s = certs.Base64([]byte(*s)) },
 			func(ip *net.IP, c fuzz.Continue) {
 				var segments []byte
@@ -135,6 +151,21 @@ func fuzzer() *fuzz.Fuzzer {
 				c.FuzzNoCustom(e)
 				*e = x509.KeyUsage(abs(int(*e)) % 8)
 			},
+			// Extensions need a valid OID outside of the standard id-ce arc and DER encoded values.
+			func(e *[]pkix.Extension, c fuzz.Continue) {
+				*e = nil
+				for i := 0; i < 1+c.Intn(3); i++ {
+					var value string
+					c.Fuzz(&value)
+					der, _ := asn1.Marshal(value)
+					*e = append(*e, pkix.Extension{Id: asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 99999, i, c.Intn(1000)}, Value: der})
+				}
+			},
+			// x509.SignatureAlgorithm, needs to be usable with the RSA CA key
+			func(a *x509.SignatureAlgorithm, c fuzz.Continue) {
+				algorithms := []x509.SignatureAlgorithm{x509.SHA256WithRSA, x509.SHA384WithRSA, x509.SHA512WithRSA, x509.SHA256WithRSAPSS, x509.SHA384WithRSAPSS, x509.SHA512WithRSAPSS}
+				*a = algorithms[c.Intn(len(algorithms))]
+			},
 			// Make sure durations are positive
 			func(d *time.Duration, c fuzz.Continue) { c.FuzzNoCustom(d); *d = time.Duration(abs(int(*d))) },
 		)
@@ -172,3 +203,371 @@ func abs(i int) int {
 	}
 	return i
 }
+
+func TestSelfSignedCertificateSubjectPolicy(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name        string
+		cfg         certs.CertCfg
+		expectError bool
+	}{
+		{
+			name:        "Default policy requires OU",
+			cfg:         certs.CertCfg{Subject: pkix.Name{CommonName: "cn"}},
+			expectError: true,
+		},
+		{
+			name: "Default policy with CN and OU",
+			cfg:  certs.CertCfg{Subject: pkix.Name{CommonName: "cn", OrganizationalUnit: []string{"ou"}}},
+		},
+		{
+			name: "RequireCN policy allows CN only",
+			cfg:  certs.CertCfg{Subject: pkix.Name{CommonName: "cn"}, SubjectPolicy: certs.SubjectPolicyRequireCN},
+		},
+		{
+			name:        "RequireCN policy requires CN",
+			cfg:         certs.CertCfg{Subject: pkix.Name{OrganizationalUnit: []string{"ou"}}, SubjectPolicy: certs.SubjectPolicyRequireCN},
+			expectError: true,
+		},
+		{
+			name:        "Unknown policy",
+			cfg:         certs.CertCfg{Subject: pkix.Name{CommonName: "cn"}, SubjectPolicy: "Bogus"},
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			_, _, err := certs.GenerateSelfSignedCertificate(&tc.cfg)
+			if (err != nil) != tc.expectError {
+				t.Errorf("expected error: %t, got: %v", tc.expectError, err)
+			}
+		})
+	}
+}
+
+func TestCertificateGenerationUsesInjectedClock(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	fakeClock := clocktesting.NewFakePassiveClock(now)
+	cfg := &certs.CertCfg{
+		IsCA:     true,
+		Subject:  pkix.Name{CommonName: "root-ca", OrganizationalUnit: []string{"ou"}},
+		Validity: time.Hour,
+		Clock:    fakeClock,
+	}
+	key, cert, err := certs.GenerateSelfSignedCertificate(cfg)
+	if err != nil {
+		t.Fatalf("failed go generate CA: %v", err)
+	}
+	if !cert.NotBefore.Equal(now) || !cert.NotAfter.Equal(now.Add(time.Hour)) {
+		t.Errorf("unexpected validity period %s - %s", cert.NotBefore, cert.NotAfter)
+	}
+
+	if err := certs.ValidateKeyPair(certs.PrivateKeyToPem(key), certs.CertToPem(cert), cfg, 30*time.Minute); err != nil {
+		t.Errorf("expected keypair to be valid: %v", err)
+	}
+	fakeClock.SetTime(now.Add(45 * time.Minute))
+	if err := certs.ValidateKeyPair(certs.PrivateKeyToPem(key), certs.CertToPem(cert), cfg, 30*time.Minute); err == nil {
+		t.Error("expected keypair to require rotation after advancing the clock")
+	}
+}
+
+func TestCertificateGenerationIsDeterministicForAFixedKey(t *testing.T) {
+	t.Parallel()
+
+	key, err := certs.PrivateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	generate := func() *x509.Certificate {
+		cfg := &certs.CertCfg{
+			IsCA:     true,
+			Subject:  pkix.Name{CommonName: "root-ca", OrganizationalUnit: []string{"ou"}},
+			Validity: time.Hour,
+			Clock:    clocktesting.NewFakePassiveClock(now),
+			Rand:     mathrand.New(mathrand.NewSource(1)),
+		}
+		cert, err := certs.SelfSignedCertificate(cfg, key)
+		if err != nil {
+			t.Fatalf("failed to generate certificate: %v", err)
+		}
+		return cert
+	}
+
+	first, second := generate(), generate()
+	if first.SerialNumber.Cmp(second.SerialNumber) != 0 {
+		t.Errorf("expected the same serial, got %s and %s", first.SerialNumber, second.SerialNumber)
+	}
+	if !bytes.Equal(first.Signature, second.Signature) {
+		t.Error("expected the same signature")
+	}
+	if !bytes.Equal(first.Raw, second.Raw) {
+		t.Error("expected the same certificate")
+	}
+}
+
+func TestValidateKeyPairWithReport(t *testing.T) {
+	t.Parallel()
+
+	caCfg := certs.CertCfg{IsCA: true, Subject: pkix.Name{CommonName: "root-ca", OrganizationalUnit: []string{"ou"}}}
+	caKey, caCert, err := certs.GenerateSelfSignedCertificate(&caCfg)
+	if err != nil {
+		t.Fatalf("failed go generate CA: %v", err)
+	}
+	cfg := &certs.CertCfg{Subject: pkix.Name{CommonName: "cn"}, DNSNames: []string{"a.example.com"}, Validity: time.Hour}
+	key, cert, err := certs.GenerateSignedCertificate(caKey, caCert, cfg)
+	if err != nil {
+		t.Fatalf("GenerateSignedCertificate failed: %v", err)
+	}
+
+	report, err := certs.ValidateKeyPairWithReport(certs.PrivateKeyToPem(key), certs.CertToPem(cert), nil, cfg, 30*time.Minute)
+	if err != nil || !report.Valid() {
+		t.Fatalf("expected valid report, got error: %v", err)
+	}
+	if !report.ExpiresWithin(2*time.Hour) || report.ExpiresWithin(30*time.Minute) {
+		t.Errorf("unexpected remaining validity %s", report.RemainingValidity)
+	}
+
+	changed := *cfg
+	changed.DNSNames = []string{"b.example.com"}
+	report, err = certs.ValidateKeyPairWithReport(certs.PrivateKeyToPem(key), certs.CertToPem(cert), nil, &changed, 2*time.Hour)
+	if err == nil || report.Valid() {
+		t.Fatal("expected invalid report")
+	}
+	if len(report.Mismatches) != 1 || report.Mismatches[0].Field != "DNSNames" {
+		t.Errorf("expected a single DNSNames mismatch, got %v", report.Mismatches)
+	}
+	if !report.BelowMinimumValidity() {
+		t.Error("expected report to be below minimum validity")
+	}
+
+	report, _ = certs.ValidateKeyPairWithReport([]byte("garbage"), certs.CertToPem(cert), nil, cfg, 0)
+	if report.ParseError == nil {
+		t.Error("expected a parse error")
+	}
+}
+
+func TestValidateKeyPairAllowSANSuperset(t *testing.T) {
+	t.Parallel()
+
+	caCfg := certs.CertCfg{IsCA: true, Subject: pkix.Name{CommonName: "root-ca", OrganizationalUnit: []string{"ou"}}}
+	caKey, caCert, err := certs.GenerateSelfSignedCertificate(&caCfg)
+	if err != nil {
+		t.Fatalf("failed go generate CA: %v", err)
+	}
+	generated := &certs.CertCfg{
+		Subject:     pkix.Name{CommonName: "cn"},
+		DNSNames:    []string{"api.example.com", "api.extra.example.com"},
+		IPAddresses: []net.IP{net.ParseIP("10.0.0.1"), net.ParseIP("10.0.0.2")},
+		Validity:    time.Hour,
+	}
+	key, cert, err := certs.GenerateSignedCertificate(caKey, caCert, generated)
+	if err != nil {
+		t.Fatalf("GenerateSignedCertificate failed: %v", err)
+	}
+
+	testCases := []struct {
+		name        string
+		dnsNames    []string
+		ips         []net.IP
+		superset    bool
+		expectValid bool
+	}{
+		{
+			name:        "Subset without superset option",
+			dnsNames:    []string{"api.example.com"},
+			ips:         []net.IP{net.ParseIP("10.0.0.1")},
+			expectValid: false,
+		},
+		{
+			name:        "Subset with superset option",
+			dnsNames:    []string{"api.example.com"},
+			ips:         []net.IP{net.ParseIP("10.0.0.1")},
+			superset:    true,
+			expectValid: true,
+		},
+		{
+			name:        "Missing name with superset option",
+			dnsNames:    []string{"other.example.com"},
+			superset:    true,
+			expectValid: false,
+		},
+		{
+			name:        "Missing ip with superset option",
+			ips:         []net.IP{net.ParseIP("10.0.0.3")},
+			superset:    true,
+			expectValid: false,
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := *generated
+			cfg.DNSNames = tc.dnsNames
+			cfg.IPAddresses = tc.ips
+			cfg.AllowSANSuperset = tc.superset
+			err := certs.ValidateKeyPair(certs.PrivateKeyToPem(key), certs.CertToPem(cert), &cfg, 0)
+			if isValid := err == nil; isValid != tc.expectValid {
+				t.Errorf("expected valid: %t, actual valid: %t, error from ValidateKeyPair: %v", tc.expectValid, isValid, err)
+			}
+		})
+	}
+}
+
+func TestValidateKeyPairIgnoresUnknownExtensions(t *testing.T) {
+	t.Parallel()
+
+	caCfg := certs.CertCfg{IsCA: true, Subject: pkix.Name{CommonName: "root-ca", OrganizationalUnit: []string{"ou"}}}
+	caKey, caCert, err := certs.GenerateSelfSignedCertificate(&caCfg)
+	if err != nil {
+		t.Fatalf("failed go generate CA: %v", err)
+	}
+
+	// An authority information access extension pointing at an OCSP responder, as
+	// added by many external CAs.
+	type accessDescription struct {
+		Method   asn1.ObjectIdentifier
+		Location asn1.RawValue
+	}
+	aia, err := asn1.Marshal([]accessDescription{{
+		Method:   asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 48, 1},
+		Location: asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 6, Bytes: []byte("http://ocsp.example.com")},
+	}})
+	if err != nil {
+		t.Fatalf("failed to marshal authority information access: %v", err)
+	}
+	clusterID, err := asn1.Marshal("cluster-id")
+	if err != nil {
+		t.Fatalf("failed to marshal cluster id: %v", err)
+	}
+	aiaExtension := pkix.Extension{Id: asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 1, 1}, Value: aia}
+	clusterIDExtension := pkix.Extension{Id: asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 99999, 1}, Value: clusterID}
+
+	generated := &certs.CertCfg{
+		Subject:         pkix.Name{CommonName: "cn"},
+		Validity:        time.Hour,
+		ExtraExtensions: []pkix.Extension{clusterIDExtension, aiaExtension},
+	}
+	key, cert, err := certs.GenerateSignedCertificate(caKey, caCert, generated)
+	if err != nil {
+		t.Fatalf("GenerateSignedCertificate failed: %v", err)
+	}
+
+	testCases := []struct {
+		name        string
+		extensions  []pkix.Extension
+		strict      bool
+		expectValid bool
+	}{
+		{
+			name:        "Unknown extension is ignored",
+			extensions:  []pkix.Extension{clusterIDExtension},
+			expectValid: true,
+		},
+		{
+			name:        "No expected extensions",
+			expectValid: true,
+		},
+		{
+			name:        "Unknown extension with strict comparison",
+			extensions:  []pkix.Extension{clusterIDExtension},
+			strict:      true,
+			expectValid: false,
+		},
+		{
+			name:        "All extensions with strict comparison",
+			extensions:  []pkix.Extension{clusterIDExtension, aiaExtension},
+			strict:      true,
+			expectValid: true,
+		},
+		{
+			name:        "Expected extension differs",
+			extensions:  []pkix.Extension{{Id: clusterIDExtension.Id, Value: aia}},
+			expectValid: false,
+		},
+		{
+			name:        "Expected extension is missing",
+			extensions:  []pkix.Extension{{Id: asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 99999, 2}, Value: clusterID}},
+			expectValid: false,
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := *generated
+			cfg.ExtraExtensions = tc.extensions
+			cfg.StrictExtraExtensions = tc.strict
+			err := certs.ValidateKeyPair(certs.PrivateKeyToPem(key), certs.CertToPem(cert), &cfg, 0)
+			if isValid := err == nil; isValid != tc.expectValid {
+				t.Errorf("expected valid: %t, actual valid: %t, error from ValidateKeyPair: %v", tc.expectValid, isValid, err)
+			}
+		})
+	}
+}
+
+func TestDisableSHA1(t *testing.T) {
+	t.Parallel()
+
+	cfg := &certs.CertCfg{
+		IsCA:               true,
+		Subject:            pkix.Name{CommonName: "root-ca", OrganizationalUnit: []string{"ou"}},
+		Validity:           time.Hour,
+		SignatureAlgorithm: x509.SHA384WithRSAPSS,
+		DisableSHA1:        true,
+	}
+	_, cert, err := certs.GenerateSelfSignedCertificate(cfg)
+	if err != nil {
+		t.Fatalf("failed go generate CA: %v", err)
+	}
+	if cert.SignatureAlgorithm != x509.SHA384WithRSAPSS {
+		t.Errorf("expected signature algorithm %s, got %s", x509.SHA384WithRSAPSS, cert.SignatureAlgorithm)
+	}
+
+	cfg.SignatureAlgorithm = x509.SHA1WithRSA
+	if _, _, err := certs.GenerateSelfSignedCertificate(cfg); err == nil {
+		t.Error("expected an error when requesting a SHA-1 signature with SHA-1 disabled")
+	}
+
+	cfg.SignatureAlgorithm = x509.ECDSAWithSHA256
+	if _, _, err := certs.GenerateSelfSignedCertificate(cfg); err == nil {
+		t.Error("expected an error when requesting an ECDSA signature for an RSA key")
+	}
+}
+
+func TestEncryptedPrivateKeyRoundTrip(t *testing.T) {
+	t.Parallel()
+	cfg := &certs.CertCfg{IsCA: true, Subject: pkix.Name{CommonName: "root-ca", OrganizationalUnit: []string{"ou"}}, Validity: time.Hour}
+	key, cert, err := certs.GenerateSelfSignedCertificate(cfg)
+	if err != nil {
+		t.Fatalf("failed go generate CA: %v", err)
+	}
+
+	passphrase := []byte("secret")
+	encrypted, err := certs.EncryptedPrivateKeyToPem(key, passphrase)
+	if err != nil {
+		t.Fatalf("EncryptedPrivateKeyToPem failed: %v", err)
+	}
+
+	if _, err := certs.PemToPrivateKeyWithPassphrase(encrypted, nil); err == nil {
+		t.Error("expected an error when decoding an encrypted key without passphrase")
+	}
+	if _, err := certs.PemToPrivateKeyWithPassphrase(encrypted, []byte("wrong")); err == nil {
+		t.Error("expected an error when decoding an encrypted key with the wrong passphrase")
+	}
+	decoded, err := certs.PemToPrivateKeyWithPassphrase(encrypted, passphrase)
+	if err != nil {
+		t.Fatalf("PemToPrivateKeyWithPassphrase failed: %v", err)
+	}
+	if !decoded.Equal(key) {
+		t.Error("decoded key does not match original key")
+	}
+
+	if err := certs.ValidateEncryptedKeyPair(encrypted, certs.CertToPem(cert), passphrase, cfg, 0); err != nil {
+		t.Errorf("ValidateEncryptedKeyPair failed: %v", err)
+	}
+}
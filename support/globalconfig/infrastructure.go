@@ -22,19 +22,30 @@ func InfrastructureConfig() *configv1.Infrastructure {
 func ReconcileInfrastructure(infra *configv1.Infrastructure, hcp *hyperv1.HostedControlPlane) {
 
 	platformType := hcp.Spec.Platform.Type
+	configPlatformType := configv1.PlatformType(platformType)
+	if platformType == hyperv1.IBMCloudVPCPlatform {
+		// Self-managed IBM Cloud VPC clusters run on the IBMCloud platform of
+		// OpenShift.
+		configPlatformType = configv1.IBMCloudPlatformType
+	}
+	if platformType == hyperv1.OCIPlatform {
+		// OpenShift has no OCI platform, the cloud provider of OCI clusters is
+		// external to OpenShift.
+		configPlatformType = configv1.NonePlatformType
+	}
 
 	apiServerAddress := hcp.Status.ControlPlaneEndpoint.Host
 	apiServerPort := hcp.Status.ControlPlaneEndpoint.Port
 
-	infra.Spec.PlatformSpec.Type = configv1.PlatformType(platformType)
+	infra.Spec.PlatformSpec.Type = configPlatformType
 	infra.Status.APIServerInternalURL = fmt.Sprintf("https://%s:%d", apiServerAddress, apiServerPort)
 	infra.Status.APIServerURL = fmt.Sprintf("https://%s:%d", apiServerAddress, apiServerPort)
 	infra.Status.EtcdDiscoveryDomain = BaseDomain(hcp)
 	infra.Status.InfrastructureName = hcp.Spec.InfraID
 	infra.Status.ControlPlaneTopology = configv1.ExternalTopologyMode
-	infra.Status.Platform = configv1.PlatformType(platformType)
+	infra.Status.Platform = configPlatformType
 	infra.Status.PlatformStatus = &configv1.PlatformStatus{
-		Type: configv1.PlatformType(platformType),
+		Type: configPlatformType,
 	}
 
 	switch hcp.Spec.InfrastructureAvailabilityPolicy {
@@ -75,5 +86,23 @@ func ReconcileInfrastructure(infra *configv1.Infrastructure, hcp *hyperv1.Hosted
 			Zone:           hcp.Spec.Platform.PowerVS.Zone,
 			CISInstanceCRN: hcp.Spec.Platform.PowerVS.CISInstanceCRN,
 		}
+	case hyperv1.IBMCloudVPCPlatform:
+		infra.Status.PlatformStatus.IBMCloud = &configv1.IBMCloudPlatformStatus{
+			Location:          hcp.Spec.Platform.IBMCloudVPC.Region,
+			ResourceGroupName: hcp.Spec.Platform.IBMCloudVPC.ResourceGroup,
+			ProviderType:      configv1.IBMCloudProviderTypeVPC,
+			CISInstanceCRN:    hcp.Spec.Platform.IBMCloudVPC.CISInstanceCRN,
+		}
+	case hyperv1.GCPPlatform:
+		infra.Status.PlatformStatus.GCP = &configv1.GCPPlatformStatus{
+			ProjectID: hcp.Spec.Platform.GCP.Project,
+			Region:    hcp.Spec.Platform.GCP.Region,
+		}
+	case hyperv1.OpenStackPlatform:
+		infra.Status.PlatformStatus.OpenStack = &configv1.OpenStackPlatformStatus{
+			CloudName: hcp.Spec.Platform.OpenStack.CloudName,
+		}
+	case hyperv1.NutanixPlatform:
+		infra.Status.PlatformStatus.Nutanix = &configv1.NutanixPlatformStatus{}
 	}
 }
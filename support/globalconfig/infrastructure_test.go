@@ -0,0 +1,121 @@
+package globalconfig
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	configv1 "github.com/openshift/api/config/v1"
+	hyperv1 "github.com/openshift/hypershift/api/v1alpha1"
+)
+
+func TestReconcileInfrastructureIBMCloudVPC(t *testing.T) {
+	g := NewWithT(t)
+	hcp := &hyperv1.HostedControlPlane{
+		Spec: hyperv1.HostedControlPlaneSpec{
+			Platform: hyperv1.PlatformSpec{
+				Type: hyperv1.IBMCloudVPCPlatform,
+				IBMCloudVPC: &hyperv1.IBMCloudVPCPlatformSpec{
+					Region:         "us-south",
+					ResourceGroup:  "default",
+					CISInstanceCRN: "crn:v1:bluemix:public:internet-svcs:global:a/account:instance::",
+				},
+			},
+		},
+	}
+	infra := InfrastructureConfig()
+	ReconcileInfrastructure(infra, hcp)
+
+	g.Expect(infra.Spec.PlatformSpec.Type).To(Equal(configv1.IBMCloudPlatformType))
+	g.Expect(infra.Status.Platform).To(Equal(configv1.IBMCloudPlatformType))
+	g.Expect(infra.Status.PlatformStatus.Type).To(Equal(configv1.IBMCloudPlatformType))
+	g.Expect(infra.Status.PlatformStatus.IBMCloud).To(Equal(&configv1.IBMCloudPlatformStatus{
+		Location:          "us-south",
+		ResourceGroupName: "default",
+		ProviderType:      configv1.IBMCloudProviderTypeVPC,
+		CISInstanceCRN:    "crn:v1:bluemix:public:internet-svcs:global:a/account:instance::",
+	}))
+}
+
+func TestReconcileInfrastructureGCP(t *testing.T) {
+	g := NewWithT(t)
+	hcp := &hyperv1.HostedControlPlane{
+		Spec: hyperv1.HostedControlPlaneSpec{
+			Platform: hyperv1.PlatformSpec{
+				Type: hyperv1.GCPPlatform,
+				GCP: &hyperv1.GCPPlatformSpec{
+					Project: "my-project",
+					Region:  "us-central1",
+				},
+			},
+		},
+	}
+	infra := InfrastructureConfig()
+	ReconcileInfrastructure(infra, hcp)
+
+	g.Expect(infra.Status.Platform).To(Equal(configv1.GCPPlatformType))
+	g.Expect(infra.Status.PlatformStatus.GCP).To(Equal(&configv1.GCPPlatformStatus{
+		ProjectID: "my-project",
+		Region:    "us-central1",
+	}))
+}
+
+func TestReconcileInfrastructureOpenStack(t *testing.T) {
+	g := NewWithT(t)
+	hcp := &hyperv1.HostedControlPlane{
+		Spec: hyperv1.HostedControlPlaneSpec{
+			Platform: hyperv1.PlatformSpec{
+				Type: hyperv1.OpenStackPlatform,
+				OpenStack: &hyperv1.OpenStackPlatformSpec{
+					CloudName: "openstack",
+				},
+			},
+		},
+	}
+	infra := InfrastructureConfig()
+	ReconcileInfrastructure(infra, hcp)
+
+	g.Expect(infra.Status.Platform).To(Equal(configv1.OpenStackPlatformType))
+	g.Expect(infra.Status.PlatformStatus.OpenStack).To(Equal(&configv1.OpenStackPlatformStatus{
+		CloudName: "openstack",
+	}))
+}
+
+func TestReconcileInfrastructureNutanix(t *testing.T) {
+	g := NewWithT(t)
+	hcp := &hyperv1.HostedControlPlane{
+		Spec: hyperv1.HostedControlPlaneSpec{
+			Platform: hyperv1.PlatformSpec{
+				Type: hyperv1.NutanixPlatform,
+				Nutanix: &hyperv1.NutanixPlatformSpec{
+					PrismCentral: hyperv1.NutanixPrismEndpoint{Address: "prism-central.example.com", Port: 9440},
+				},
+			},
+		},
+	}
+	infra := InfrastructureConfig()
+	ReconcileInfrastructure(infra, hcp)
+
+	g.Expect(infra.Status.Platform).To(Equal(configv1.NutanixPlatformType))
+	g.Expect(infra.Status.PlatformStatus.Nutanix).To(Equal(&configv1.NutanixPlatformStatus{}))
+}
+
+func TestReconcileInfrastructureOCI(t *testing.T) {
+	g := NewWithT(t)
+	hcp := &hyperv1.HostedControlPlane{
+		Spec: hyperv1.HostedControlPlaneSpec{
+			Platform: hyperv1.PlatformSpec{
+				Type: hyperv1.OCIPlatform,
+				OCI: &hyperv1.OCIPlatformSpec{
+					Region:        "us-ashburn-1",
+					CompartmentID: "ocid1.compartment.oc1..example",
+				},
+			},
+		},
+	}
+	infra := InfrastructureConfig()
+	ReconcileInfrastructure(infra, hcp)
+
+	g.Expect(infra.Spec.PlatformSpec.Type).To(Equal(configv1.NonePlatformType))
+	g.Expect(infra.Status.Platform).To(Equal(configv1.NonePlatformType))
+	g.Expect(infra.Status.PlatformStatus.Type).To(Equal(configv1.NonePlatformType))
+}